@@ -0,0 +1,118 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bql
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// NormalizeQuery returns the normal form of the provided query: its tokens
+// separated by single spaces. Queries that only differ in their spacing have
+// the same normal form.
+func NormalizeQuery(query string) (string, error) {
+	stms, err := SplitStatements(query)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(stms, " "), nil
+}
+
+// PreparedCache is a least recently used cache of prepared statements indexed
+// by their normalized query. Servers running the same statements over and
+// over only lex and parse each of them once. Since parameter values are bound
+// when a statement is executed, the same entry serves all of them. The cache
+// is safe for concurrent use.
+type PreparedCache struct {
+	mu      sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+	hits    int64
+	misses  int64
+}
+
+// preparedEntry contains a cached prepared statement.
+type preparedEntry struct {
+	key string
+	p   *Prepared
+}
+
+// NewPreparedCache returns a new cache that holds up to the provided number
+// of prepared statements.
+func NewPreparedCache(size int) *PreparedCache {
+	return &PreparedCache{
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Prepare returns the cached prepared statement for the provided query,
+// preparing and caching it if needed. Queries that fail to parse are not
+// cached.
+func (c *PreparedCache) Prepare(query string) (*Prepared, error) {
+	key, err := NormalizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.hits++
+		c.mu.Unlock()
+		return e.Value.(*preparedEntry).p, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	p, err := Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	if c.size <= 0 {
+		return p, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		// Another caller prepared the same query concurrently.
+		c.lru.MoveToFront(e)
+		return e.Value.(*preparedEntry).p, nil
+	}
+	c.entries[key] = c.lru.PushFront(&preparedEntry{key: key, p: p})
+	for c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*preparedEntry).key)
+	}
+	return p, nil
+}
+
+// Len returns the number of prepared statements in the cache.
+func (c *PreparedCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns the number of queries that were found in the cache and the
+// number of queries that had to be prepared.
+func (c *PreparedCache) Stats() (hits, misses int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
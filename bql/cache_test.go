@@ -0,0 +1,122 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bql
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	testTable := []struct {
+		q1, q2 string
+		same   bool
+	}{
+		{
+			q1:   `select ?o from ?test where {$who "parent_of"@[] ?o};`,
+			q2:   "select ?o\n\tfrom ?test\n\twhere { $who  \"parent_of\"@[]  ?o } ;",
+			same: true,
+		},
+		{
+			q1:   `select ?s from ?test where {?s "name"@[] "Peter Pan"^^type:text};`,
+			q2:   `select ?s from ?test where {?s "name"@[] "Peter  Pan"^^type:text};`,
+			same: false,
+		},
+		{
+			q1:   `select ?o from ?test where {$who "parent_of"@[] ?o};`,
+			q2:   `select ?o from ?test where {$who "parent_of"@[] ?x};`,
+			same: false,
+		},
+	}
+	for _, entry := range testTable {
+		n1, err := NormalizeQuery(entry.q1)
+		if err != nil {
+			t.Fatalf("NormalizeQuery(%q) failed with error %v", entry.q1, err)
+		}
+		n2, err := NormalizeQuery(entry.q2)
+		if err != nil {
+			t.Fatalf("NormalizeQuery(%q) failed with error %v", entry.q2, err)
+		}
+		if got := n1 == n2; got != entry.same {
+			t.Errorf("NormalizeQuery returned %q and %q for %q and %q; want same normal form %v", n1, n2, entry.q1, entry.q2, entry.same)
+		}
+	}
+}
+
+func TestPreparedCache(t *testing.T) {
+	ctx := context.Background()
+	s := testStore(ctx, t)
+	c := NewPreparedCache(2)
+	q := `select ?o from ?test where {$who "parent_of"@[] ?o . filter(?o != /u<nobody>)};`
+	p1, err := c.Prepare(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := c.Prepare("select ?o from ?test\n where { $who \"parent_of\"@[] ?o .\n filter( ?o != /u<nobody> ) };")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p1 != p2 {
+		t.Errorf("PreparedCache.Prepare returned a different statement for the same normalized query")
+	}
+	if hits, misses := c.Stats(); hits != 1 || misses != 1 {
+		t.Errorf("PreparedCache.Stats returned %d hits and %d misses; want 1 hit and 1 miss", hits, misses)
+	}
+	if _, err := c.Prepare(`select ?o from ?test where {$who "parent_of"@[] ?o}`); err == nil {
+		t.Errorf("PreparedCache.Prepare should have failed for an invalid query")
+	}
+	// The cached statement can be executed concurrently with different
+	// parameter values.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		who := "/u<joe>"
+		if i%2 == 1 {
+			who = "/u<mary>"
+		}
+		wg.Add(1)
+		go func(who string) {
+			defer wg.Done()
+			tbl, err := p1.Execute(ctx, s, map[string]interface{}{"who": mustNode(t, who)}, 0, 10, nil)
+			if err != nil {
+				t.Errorf("Execute for %s failed with error %v", who, err)
+				return
+			}
+			want := 2
+			if who == "/u<mary>" {
+				want = 0
+			}
+			if got := tbl.NumRows(); got != want {
+				t.Errorf("Execute for %s returned the wrong number of rows; got %d, want %d", who, got, want)
+			}
+		}(who)
+	}
+	wg.Wait()
+	// The least recently used statements are evicted.
+	for _, q := range []string{
+		`select ?s from ?test where {?s "name"@[] $name};`,
+		`select ?s, ?a from ?test where {?s "age"@[] ?a} having ?a > $min;`,
+	} {
+		if _, err := c.Prepare(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := c.Len(), 2; got != want {
+		t.Errorf("PreparedCache.Len returned %d; want %d", got, want)
+	}
+	if p, err := c.Prepare(q); err != nil || p == p1 {
+		t.Errorf("PreparedCache.Prepare(%q) should have prepared the evicted statement again; got %v, %v", q, p, err)
+	}
+}
@@ -41,36 +41,21 @@ func BQL() *Grammar {
 					NewSymbol("HAVING"),
 					NewSymbol("GLOBAL_TIME_BOUND"),
 					NewSymbol("LIMIT"),
+					NewSymbol("OFFSET"),
 					NewTokenType(lexer.ItemSemicolon),
 				},
 			},
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemInsert),
-					NewTokenType(lexer.ItemData),
-					NewTokenType(lexer.ItemInto),
-					NewSymbol("OUTPUT_GRAPHS"),
-					NewTokenType(lexer.ItemLBracket),
-					NewTokenType(lexer.ItemNode),
-					NewTokenType(lexer.ItemPredicate),
-					NewSymbol("INSERT_OBJECT"),
-					NewSymbol("INSERT_DATA"),
-					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("INSERT_STATEMENT"),
 					NewTokenType(lexer.ItemSemicolon),
 				},
 			},
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemDelete),
-					NewTokenType(lexer.ItemData),
-					NewTokenType(lexer.ItemFrom),
-					NewSymbol("INPUT_GRAPHS"),
-					NewTokenType(lexer.ItemLBracket),
-					NewTokenType(lexer.ItemNode),
-					NewTokenType(lexer.ItemPredicate),
-					NewSymbol("DELETE_OBJECT"),
-					NewSymbol("DELETE_DATA"),
-					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("DELETE_STATEMENT"),
 					NewTokenType(lexer.ItemSemicolon),
 				},
 			},
@@ -92,8 +77,7 @@ func BQL() *Grammar {
 				Elements: []Element{
 					NewTokenType(lexer.ItemConstruct),
 					NewSymbol("CONSTRUCT_FACTS"),
-					NewTokenType(lexer.ItemInto),
-					NewSymbol("OUTPUT_GRAPHS"),
+					NewSymbol("CONSTRUCT_INTO"),
 					NewTokenType(lexer.ItemFrom),
 					NewSymbol("INPUT_GRAPHS"),
 					NewSymbol("WHERE"),
@@ -121,23 +105,189 @@ func BQL() *Grammar {
 					NewTokenType(lexer.ItemSemicolon),
 				},
 			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAsk),
+					NewSymbol("ASK_QUERY"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDescribe),
+					NewSymbol("DESCRIBE_NODE"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemCount),
+					NewSymbol("COUNT_TRIPLES"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemExplain),
+					NewSymbol("START"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBegin),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemCommit),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemRollback),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+		},
+		"DESCRIBE_NODE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("DESCRIBE_GRAPHS"),
+					NewSymbol("DESCRIBE_DEPTH"),
+				},
+			},
+		},
+		"DESCRIBE_GRAPHS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemIn),
+					NewSymbol("INPUT_GRAPHS"),
+				},
+			},
+			{},
+		},
+		"DESCRIBE_DEPTH": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDepth),
+					NewTokenType(lexer.ItemLiteral),
+				},
+			},
+			{},
+		},
+		"ASK_QUERY": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("INPUT_GRAPHS"),
+					NewSymbol("WHERE"),
+					NewSymbol("GLOBAL_TIME_BOUND"),
+				},
+			},
+		},
+		"INSERT_STATEMENT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemData),
+					NewTokenType(lexer.ItemInto),
+					NewSymbol("OUTPUT_GRAPHS"),
+					NewTokenType(lexer.ItemLBracket),
+					NewTokenType(lexer.ItemNode),
+					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("INSERT_OBJECT"),
+					NewSymbol("INSERT_DATA"),
+					NewTokenType(lexer.ItemRBracket),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("CONSTRUCT_TRIPLES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewTokenType(lexer.ItemInto),
+					NewSymbol("OUTPUT_GRAPHS"),
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("INPUT_GRAPHS"),
+					NewSymbol("WHERE"),
+					NewSymbol("HAVING"),
+				},
+			},
+		},
+		"DELETE_STATEMENT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemData),
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("INPUT_GRAPHS"),
+					NewTokenType(lexer.ItemLBracket),
+					NewTokenType(lexer.ItemNode),
+					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("DELETE_OBJECT"),
+					NewSymbol("DELETE_DATA"),
+					NewTokenType(lexer.ItemRBracket),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("DECONSTRUCT_TRIPLES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("INPUT_GRAPHS"),
+					NewSymbol("WHERE"),
+					NewSymbol("HAVING"),
+				},
+			},
 		},
 		"CREATE_GRAPHS": []*Clause{
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemGraph),
+					NewSymbol("IF_NOT_EXISTS"),
 					NewSymbol("GRAPHS"),
 				},
 			},
 		},
+		"IF_NOT_EXISTS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemIf),
+					NewTokenType(lexer.ItemNot),
+					NewTokenType(lexer.ItemExists),
+				},
+			},
+			{},
+		},
 		"DROP_GRAPHS": []*Clause{
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemGraph),
+					NewSymbol("IF_EXISTS"),
 					NewSymbol("GRAPHS"),
+					NewSymbol("CASCADE"),
 				},
 			},
 		},
+		"CASCADE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemCascade),
+				},
+			},
+			{},
+		},
+		"IF_EXISTS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemIf),
+					NewTokenType(lexer.ItemExists),
+				},
+			},
+			{},
+		},
 		"VARS": []*Clause{
 			{
 				Elements: []Element{
@@ -169,7 +319,52 @@ func BQL() *Grammar {
 					NewSymbol("MORE_VARS"),
 				},
 			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemFunction),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("VARS_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_VARS"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemType),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("VARS_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_VARS"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("VARS_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_VARS"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemIf),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("VARS_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_VARS"),
+				},
+			},
 		},
+		"VARS_EXPRESSION": expressionClauses("VARS_EXPRESSION"),
 		"COUNT_DISTINCT": []*Clause{
 			{
 				Elements: []Element{
@@ -254,13 +449,126 @@ func BQL() *Grammar {
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemWhere),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("WHERE_PATTERN"),
+					NewTokenType(lexer.ItemRBracket),
+				},
+			},
+		},
+		"WHERE_PATTERN": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemGraph),
+					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("GRAPH_CLAUSE"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
 					NewTokenType(lexer.ItemLBracket),
 					NewSymbol("FIRST_CLAUSE"),
 					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_UNIONS"),
+				},
+			},
+		},
+		"MORE_UNIONS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemUnion),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("FIRST_CLAUSE"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_UNIONS"),
+				},
+			},
+			{},
+		},
+		"FIRST_CLAUSE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemGraph),
+					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("GRAPH_CLAUSE"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+		},
+		"MORE_CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDot),
+					NewSymbol("CLAUSES"),
+				},
+			},
+			{},
+		},
+		"CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemFilter),
+					NewSymbol("FILTER_CLAUSE"),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("SUBQUERY"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemOptional),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("OPTIONAL_CLAUSE"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_CLAUSES"),
 				},
 			},
-		},
-		"FIRST_CLAUSE": []*Clause{
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemNode),
@@ -279,33 +587,25 @@ func BQL() *Grammar {
 					NewSymbol("MORE_CLAUSES"),
 				},
 			},
-		},
-		"MORE_CLAUSES": []*Clause{
-			{
-				Elements: []Element{
-					NewTokenType(lexer.ItemDot),
-					NewSymbol("CLAUSES"),
-				},
-			},
-			{},
-		},
-		"CLAUSES": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemOptional),
+					NewTokenType(lexer.ItemGraph),
+					NewTokenType(lexer.ItemBinding),
 					NewTokenType(lexer.ItemLBracket),
-					NewSymbol("OPTIONAL_CLAUSE"),
+					NewSymbol("GRAPH_CLAUSE"),
 					NewTokenType(lexer.ItemRBracket),
 					NewSymbol("MORE_CLAUSES"),
 				},
 			},
+		},
+		"GRAPH_CLAUSE": []*Clause{
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemNode),
 					NewSymbol("SUBJECT_EXTRACT"),
 					NewSymbol("PREDICATE"),
 					NewSymbol("OBJECT"),
-					NewSymbol("MORE_CLAUSES"),
+					NewSymbol("GRAPH_MORE_CLAUSES"),
 				},
 			},
 			{
@@ -314,10 +614,46 @@ func BQL() *Grammar {
 					NewSymbol("SUBJECT_EXTRACT"),
 					NewSymbol("PREDICATE"),
 					NewSymbol("OBJECT"),
-					NewSymbol("MORE_CLAUSES"),
+					NewSymbol("GRAPH_MORE_CLAUSES"),
+				},
+			},
+		},
+		"GRAPH_MORE_CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDot),
+					NewSymbol("GRAPH_CLAUSE"),
+				},
+			},
+			{},
+		},
+		"SUBQUERY": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemQuery),
+					NewSymbol("VARS"),
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("INPUT_GRAPHS"),
+					NewSymbol("WHERE"),
+					NewSymbol("GROUP_BY"),
+					NewSymbol("ORDER_BY"),
+					NewSymbol("HAVING"),
+					NewSymbol("GLOBAL_TIME_BOUND"),
+					NewSymbol("LIMIT"),
+					NewSymbol("OFFSET"),
+				},
+			},
+		},
+		"FILTER_CLAUSE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("FILTER_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
 				},
 			},
 		},
+		"FILTER_EXPRESSION": expressionClauses("FILTER_EXPRESSION"),
 		"OPTIONAL_CLAUSE": []*Clause{
 			{
 				Elements: []Element{
@@ -382,6 +718,7 @@ func BQL() *Grammar {
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("PREDICATE_PATH"),
 					NewSymbol("PREDICATE_AS"),
 					NewSymbol("PREDICATE_ID"),
 					NewSymbol("PREDICATE_AT"),
@@ -404,6 +741,38 @@ func BQL() *Grammar {
 				},
 			},
 		},
+		"PREDICATE_PATH": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemPlus),
+					NewSymbol("PREDICATE_PATH_NEXT"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemMul),
+					NewSymbol("PREDICATE_PATH_NEXT"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDiv),
+					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("PREDICATE_PATH"),
+				},
+			},
+			{},
+		},
+		"PREDICATE_PATH_NEXT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDiv),
+					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("PREDICATE_PATH"),
+				},
+			},
+			{},
+		},
 		"PREDICATE_AS": []*Clause{
 			{
 				Elements: []Element{
@@ -463,12 +832,14 @@ func BQL() *Grammar {
 				Elements: []Element{
 					NewTokenType(lexer.ItemLiteral),
 					NewSymbol("OBJECT_LITERAL_AS"),
+					NewSymbol("OBJECT_REIFIED"),
 				},
 			},
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemNode),
 					NewSymbol("OBJECT_SUBJECT_EXTRACT"),
+					NewSymbol("OBJECT_REIFIED"),
 				},
 			},
 			{
@@ -477,6 +848,7 @@ func BQL() *Grammar {
 					NewSymbol("OBJECT_PREDICATE_AS"),
 					NewSymbol("OBJECT_PREDICATE_ID"),
 					NewSymbol("OBJECT_PREDICATE_AT"),
+					NewSymbol("OBJECT_REIFIED"),
 				},
 			},
 			{
@@ -485,6 +857,7 @@ func BQL() *Grammar {
 					NewSymbol("OBJECT_PREDICATE_AS"),
 					NewSymbol("OBJECT_PREDICATE_ID"),
 					NewSymbol("OBJECT_PREDICATE_BOUND_AT"),
+					NewSymbol("OBJECT_REIFIED"),
 				},
 			},
 			{
@@ -494,9 +867,20 @@ func BQL() *Grammar {
 					NewSymbol("OBJECT_LITERAL_BINDING_TYPE"),
 					NewSymbol("OBJECT_LITERAL_BINDING_ID"),
 					NewSymbol("OBJECT_LITERAL_BINDING_AT"),
+					NewSymbol("OBJECT_REIFIED"),
 				},
 			},
 		},
+		"OBJECT_REIFIED": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemReified),
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
 		"OBJECT_SUBJECT_EXTRACT": []*Clause{
 			{
 				Elements: []Element{
@@ -643,7 +1027,7 @@ func BQL() *Grammar {
 				Elements: []Element{
 					NewTokenType(lexer.ItemGroup),
 					NewTokenType(lexer.ItemBy),
-					NewTokenType(lexer.ItemBinding),
+					NewSymbol("GROUP_BY_KEY"),
 					NewSymbol("GROUP_BY_BINDINGS"),
 				},
 			},
@@ -653,18 +1037,65 @@ func BQL() *Grammar {
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemComma),
-					NewTokenType(lexer.ItemBinding),
+					NewSymbol("GROUP_BY_KEY"),
 					NewSymbol("GROUP_BY_BINDINGS"),
 				},
 			},
 			{},
 		},
+		"GROUP_BY_KEY": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemFunction),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("GROUP_BY_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemType),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("GROUP_BY_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("GROUP_BY_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemIf),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("GROUP_BY_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("GROUP_BY_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+				},
+			},
+		},
+		"GROUP_BY_EXPRESSION": expressionClauses("GROUP_BY_EXPRESSION"),
 		"ORDER_BY": []*Clause{
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemOrder),
 					NewTokenType(lexer.ItemBy),
-					NewTokenType(lexer.ItemBinding),
+					NewSymbol("ORDER_BY_KEY"),
 					NewSymbol("ORDER_BY_DIRECTION"),
 					NewSymbol("ORDER_BY_BINDINGS"),
 				},
@@ -688,13 +1119,52 @@ func BQL() *Grammar {
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemComma),
-					NewTokenType(lexer.ItemBinding),
+					NewSymbol("ORDER_BY_KEY"),
 					NewSymbol("ORDER_BY_DIRECTION"),
 					NewSymbol("ORDER_BY_BINDINGS"),
 				},
 			},
 			{},
 		},
+		"ORDER_BY_KEY": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemFunction),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("ORDER_BY_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemType),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("ORDER_BY_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("ORDER_BY_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("ORDER_BY_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+				},
+			},
+		},
+		"ORDER_BY_EXPRESSION": expressionClauses("ORDER_BY_EXPRESSION"),
 		"HAVING": []*Clause{
 			{
 				Elements: []Element{
@@ -763,21 +1233,68 @@ func BQL() *Grammar {
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemBefore),
-					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("TIME_POINT"),
+					NewSymbol("TIME_BOUND_MODIFIER"),
 				},
 			},
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemAfter),
-					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("TIME_POINT"),
+					NewSymbol("TIME_BOUND_MODIFIER"),
 				},
 			},
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemBetween),
-					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("TIME_POINT"),
 					NewTokenType(lexer.ItemComma),
+					NewSymbol("TIME_POINT"),
+					NewSymbol("TIME_BOUND_MODIFIER"),
+				},
+			},
+			{},
+		},
+		"TIME_POINT": []*Clause{
+			{
+				Elements: []Element{
 					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("TIME_OFFSET"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemFunction),
+					NewTokenType(lexer.ItemLPar),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("TIME_OFFSET"),
+				},
+			},
+		},
+		"TIME_OFFSET": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemPlus),
+					NewTokenType(lexer.ItemDuration),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemMinus),
+					NewTokenType(lexer.ItemDuration),
+				},
+			},
+			{},
+		},
+		"TIME_BOUND_MODIFIER": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemInclusive),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemExclusive),
 				},
 			},
 			{},
@@ -791,6 +1308,15 @@ func BQL() *Grammar {
 			},
 			{},
 		},
+		"OFFSET": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemOffset),
+					NewTokenType(lexer.ItemLiteral),
+				},
+			},
+			{},
+		},
 		"INSERT_OBJECT": []*Clause{
 			{
 				Elements: []Element{
@@ -849,6 +1375,15 @@ func BQL() *Grammar {
 			},
 			{},
 		},
+		"CONSTRUCT_INTO": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemInto),
+					NewSymbol("OUTPUT_GRAPHS"),
+				},
+			},
+			{},
+		},
 		"CONSTRUCT_FACTS": []*Clause{
 			{
 				Elements: []Element{
@@ -988,21 +1523,117 @@ func BQL() *Grammar {
 					NewTokenType(lexer.ItemGraphs),
 				},
 			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemPredicates),
+					NewTokenType(lexer.ItemIn),
+					NewSymbol("INPUT_GRAPHS"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemStats),
+					NewTokenType(lexer.ItemIn),
+					NewSymbol("INPUT_GRAPHS"),
+				},
+			},
+		},
+		"COUNT_TRIPLES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemTriples),
+					NewTokenType(lexer.ItemIn),
+					NewSymbol("INPUT_GRAPHS"),
+				},
+			},
+		},
+	}
+}
+
+// expressionTokens contains the tokens that can be part of an expression.
+var expressionTokens = []lexer.TokenType{
+	lexer.ItemBinding, lexer.ItemNumber, lexer.ItemLiteral, lexer.ItemNode,
+	lexer.ItemPredicate, lexer.ItemNot, lexer.ItemAnd, lexer.ItemOr,
+	lexer.ItemEQ, lexer.ItemNEQ, lexer.ItemLT, lexer.ItemLEQ, lexer.ItemGT,
+	lexer.ItemGEQ, lexer.ItemPlus, lexer.ItemMinus, lexer.ItemMul,
+	lexer.ItemDiv, lexer.ItemFunction, lexer.ItemType, lexer.ItemID,
+	lexer.ItemIf, lexer.ItemComma,
+}
+
+// expressionClauses returns the clauses for the provided symbol that accept
+// any sequence of expression tokens with balanced parenthesis. The structure
+// of the expression is validated by the semantic hooks.
+func expressionClauses(sym semantic.Symbol) []*Clause {
+	cls := []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemLPar),
+				NewSymbol(sym),
+				NewTokenType(lexer.ItemRPar),
+				NewSymbol(sym),
+			},
 		},
 	}
+	for _, tt := range expressionTokens {
+		cls = append(cls, &Clause{
+			Elements: []Element{
+				NewTokenType(tt),
+				NewSymbol(sym),
+			},
+		})
+	}
+	return append(cls, &Clause{})
 }
 
-func setClauseHook(g *Grammar, symbols []semantic.Symbol, start, end semantic.ClauseHook) {
+func setClauseHook(g *Grammar, symbols []semantic.Symbol, start, end semantic.ClauseHook, cnd condition) {
 	for _, sym := range symbols {
 		for _, cls := range (*g)[sym] {
-			cls.ProcessStart = start
-			cls.ProcessEnd = end
+			if cnd == nil || cnd(cls) {
+				cls.ProcessStart = start
+				cls.ProcessEnd = end
+			}
 		}
 	}
 }
 
 type condition func(*Clause) bool
 
+// isDataClause returns true if the clause operates on explicit data.
+func isDataClause(cls *Clause) bool {
+	return cls.Elements[0].Token() == lexer.ItemData
+}
+
+// isTemplateClause returns true if the clause operates on a triple template
+// populated by a graph pattern.
+func isTemplateClause(cls *Clause) bool {
+	return cls.Elements[0].Token() == lexer.ItemLBracket
+}
+
+// isConditionalClause returns true if the clause makes a graph statement
+// conditional on the existence of the graphs.
+func isConditionalClause(cls *Clause) bool {
+	return len(cls.Elements) > 0 && cls.Elements[0].Token() == lexer.ItemIf
+}
+
+// isTokenClause returns a condition that holds for clauses starting with the
+// provided token.
+func isTokenClause(tt lexer.TokenType) condition {
+	return func(cls *Clause) bool {
+		return len(cls.Elements) > 0 && cls.Elements[0].Token() == tt
+	}
+}
+
+// isCascadeClause returns true if the clause makes a drop statement also drop
+// the graphs derived from the dropped ones.
+func isCascadeClause(cls *Clause) bool {
+	return len(cls.Elements) > 0 && cls.Elements[0].Token() == lexer.ItemCascade
+}
+
+// isExplainClause returns true if the clause explains another statement.
+func isExplainClause(cls *Clause) bool {
+	return cls.Elements[0].Token() == lexer.ItemExplain
+}
+
 func setElementHook(g *Grammar, symbols []semantic.Symbol, hook semantic.ElementHook, cnd condition) {
 	for _, sym := range symbols {
 		for _, cls := range (*g)[sym] {
@@ -1019,8 +1650,10 @@ func SemanticBQL() *Grammar {
 	dataAcc := semantic.DataAccumulatorHook()
 
 	// Create and Drop semantic hooks for type.
-	setClauseHook(semanticBQL, []semantic.Symbol{"CREATE_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.Create))
-	setClauseHook(semanticBQL, []semantic.Symbol{"DROP_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.Drop))
+	setClauseHook(semanticBQL, []semantic.Symbol{"CREATE_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.Create), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"DROP_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.Drop), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"IF_NOT_EXISTS", "IF_EXISTS"}, nil, semantic.ConditionalClauseHook(), isConditionalClause)
+	setClauseHook(semanticBQL, []semantic.Symbol{"CASCADE"}, nil, semantic.CascadeClauseHook(), isCascadeClause)
 
 	// Add graph binding collection to GRAPHS and MORE_GRAPHS clauses.
 	graphSymbols := []semantic.Symbol{"GRAPHS", "MORE_GRAPHS"}
@@ -1039,24 +1672,28 @@ func SemanticBQL() *Grammar {
 		"INSERT_OBJECT", "INSERT_DATA", "DELETE_OBJECT", "DELETE_DATA",
 	}
 	setElementHook(semanticBQL, insertSymbols, dataAcc, nil)
-	setClauseHook(semanticBQL, []semantic.Symbol{"INSERT_OBJECT"}, nil, semantic.TypeBindingClauseHook(semantic.Insert))
-	setClauseHook(semanticBQL, []semantic.Symbol{"DELETE_OBJECT"}, nil, semantic.TypeBindingClauseHook(semantic.Delete))
+	setClauseHook(semanticBQL, []semantic.Symbol{"INSERT_OBJECT"}, nil, semantic.TypeBindingClauseHook(semantic.Insert), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"DELETE_OBJECT"}, nil, semantic.TypeBindingClauseHook(semantic.Delete), nil)
 
 	// Query semantic hooks.
-	setClauseHook(semanticBQL, []semantic.Symbol{"WHERE"}, semantic.WhereInitWorkingClauseHook(), semantic.VarBindingsGraphChecker())
+	setClauseHook(semanticBQL, []semantic.Symbol{"WHERE"}, semantic.WhereInitWorkingClauseHook(), semantic.VarBindingsGraphChecker(), nil)
 
 	clauseSymbols := []semantic.Symbol{
-		"FIRST_CLAUSE", "CLAUSES", "MORE_CLAUSES",
+		"WHERE_PATTERN", "FIRST_CLAUSE", "CLAUSES", "MORE_CLAUSES", "GRAPH_CLAUSE", "GRAPH_MORE_CLAUSES",
 	}
-	setClauseHook(semanticBQL, clauseSymbols, semantic.WhereNextWorkingClauseHook(), semantic.WhereNextWorkingClauseHook())
+	setClauseHook(semanticBQL, clauseSymbols, semantic.WhereNextWorkingClauseHook(), semantic.WhereNextWorkingClauseHook(), nil)
+	setElementHook(semanticBQL, []semantic.Symbol{"MORE_UNIONS"}, semantic.WhereUnionHook(), nil)
+	setElementHook(semanticBQL, []semantic.Symbol{"FILTER_CLAUSE", "FILTER_EXPRESSION"}, semantic.WhereFilterHook(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"FILTER_CLAUSE"}, nil, semantic.WhereFilterBuilderHook(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"SUBQUERY"}, semantic.SubqueryStartHook(), semantic.SubqueryEndHook(), nil)
 
 	subSymbols := []semantic.Symbol{
-		"FIRST_CLAUSE", "CLAUSES", "OPTIONAL_CLAUSE", "SUBJECT_EXTRACT", "SUBJECT_TYPE", "SUBJECT_ID",
+		"WHERE_PATTERN", "FIRST_CLAUSE", "CLAUSES", "OPTIONAL_CLAUSE", "GRAPH_CLAUSE", "SUBJECT_EXTRACT", "SUBJECT_TYPE", "SUBJECT_ID",
 	}
 	setElementHook(semanticBQL, subSymbols, semantic.WhereSubjectClauseHook(), nil)
 
 	predSymbols := []semantic.Symbol{
-		"PREDICATE", "PREDICATE_AS", "PREDICATE_ID", "PREDICATE_AT",
+		"PREDICATE", "PREDICATE_PATH", "PREDICATE_PATH_NEXT", "PREDICATE_AS", "PREDICATE_ID", "PREDICATE_AT",
 		"PREDICATE_BOUND_AT", "PREDICATE_BOUND_AT_BINDINGS", "PREDICATE_BOUND_AT_BINDINGS_END",
 	}
 	setElementHook(semanticBQL, predSymbols, semantic.WherePredicateClauseHook(), nil)
@@ -1070,61 +1707,90 @@ func SemanticBQL() *Grammar {
 		"OBJECT_LITERAL_BINDING_ID", "OBJECT_LITERAL_BINDING_AT",
 	}
 	setElementHook(semanticBQL, objSymbols, semantic.WhereObjectClauseHook(), nil)
+	setElementHook(semanticBQL, []semantic.Symbol{"OBJECT_REIFIED"}, semantic.WhereReifiedClauseHook(), nil)
 
 	// Collect binding variables variables.
 	varSymbols := []semantic.Symbol{
 		"VARS", "VARS_AS", "MORE_VARS", "COUNT_DISTINCT",
 	}
 	setElementHook(semanticBQL, varSymbols, semantic.VarAccumulatorHook(), nil)
+	setElementHook(semanticBQL, []semantic.Symbol{"VARS_EXPRESSION"}, semantic.VarExpressionHook(), nil)
 
 	// Collect and validate group by bindings.
-	grpSymbols := []semantic.Symbol{"GROUP_BY", "GROUP_BY_BINDINGS"}
+	grpSymbols := []semantic.Symbol{"GROUP_BY", "GROUP_BY_BINDINGS", "GROUP_BY_KEY"}
 	setElementHook(semanticBQL, grpSymbols, semantic.GroupByBindings(), nil)
-	setClauseHook(semanticBQL, []semantic.Symbol{"GROUP_BY"}, nil, semantic.GroupByBindingsChecker())
+	setElementHook(semanticBQL, []semantic.Symbol{"GROUP_BY_EXPRESSION"}, semantic.GroupByExpressionHook(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"GROUP_BY_KEY"}, nil, semantic.GroupByExpressionBuilderHook(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"GROUP_BY"}, nil, semantic.GroupByBindingsChecker(), nil)
 
 	// Collect and validate order by bindings.
-	ordSymbols := []semantic.Symbol{"ORDER_BY", "ORDER_BY_DIRECTION", "ORDER_BY_BINDINGS"}
+	ordSymbols := []semantic.Symbol{"ORDER_BY", "ORDER_BY_DIRECTION", "ORDER_BY_BINDINGS", "ORDER_BY_KEY"}
 	setElementHook(semanticBQL, ordSymbols, semantic.OrderByBindings(), nil)
-	setClauseHook(semanticBQL, []semantic.Symbol{"ORDER_BY"}, nil, semantic.OrderByBindingsChecker())
+	setElementHook(semanticBQL, []semantic.Symbol{"ORDER_BY_EXPRESSION"}, semantic.OrderByExpressionHook(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"ORDER_BY_KEY"}, nil, semantic.OrderByExpressionBuilderHook(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"ORDER_BY"}, nil, semantic.OrderByBindingsChecker(), nil)
 
 	// Collect the tokens that form the having clause and build the function
 	// that will evaluate the result rows.
 	havingSymbols := []semantic.Symbol{"HAVING", "HAVING_CLAUSE", "HAVING_CLAUSE_BINARY_COMPOSITE"}
 	setElementHook(semanticBQL, havingSymbols, semantic.HavingExpression(), nil)
-	setClauseHook(semanticBQL, []semantic.Symbol{"HAVING"}, nil, semantic.HavingExpressionBuilder())
+	setClauseHook(semanticBQL, []semantic.Symbol{"HAVING"}, nil, semantic.HavingExpressionBuilder(), nil)
 
 	// Global time bound semantic hooks addition.
-	globalSymbols := []semantic.Symbol{"GLOBAL_TIME_BOUND"}
+	globalSymbols := []semantic.Symbol{"GLOBAL_TIME_BOUND", "TIME_POINT", "TIME_OFFSET", "TIME_BOUND_MODIFIER"}
 	setElementHook(semanticBQL, globalSymbols, semantic.CollectGlobalBounds(), nil)
 
 	// LIMIT clause semantic hook addition.
 	limitSymbols := []semantic.Symbol{"LIMIT"}
 	setElementHook(semanticBQL, limitSymbols, semantic.LimitCollection(), nil)
 
+	// OFFSET clause semantic hook addition.
+	offsetSymbols := []semantic.Symbol{"OFFSET"}
+	setElementHook(semanticBQL, offsetSymbols, semantic.OffsetCollection(), nil)
+
 	// Global data accumulator hook.
-	setElementHook(semanticBQL, []semantic.Symbol{"START"}, dataAcc,
-		func(cls *Clause) bool {
-			if t := cls.Elements[0].Token(); t != lexer.ItemInsert && t != lexer.ItemDelete {
-				return false
-			}
-			return true
-		})
-	setClauseHook(semanticBQL, []semantic.Symbol{"START"}, nil, semantic.GroupByBindingsChecker())
+	setElementHook(semanticBQL, []semantic.Symbol{"INSERT_STATEMENT", "DELETE_STATEMENT"}, dataAcc, isDataClause)
+	setClauseHook(semanticBQL, []semantic.Symbol{"START"}, nil, semantic.GroupByBindingsChecker(), nil)
 
 	// CONSTRUCT and DECONSTRUCT clauses semantic hooks.
-	setClauseHook(semanticBQL, []semantic.Symbol{"CONSTRUCT_FACTS"}, semantic.InitWorkingConstructClauseHook(), semantic.TypeBindingClauseHook(semantic.Construct))
-	setClauseHook(semanticBQL, []semantic.Symbol{"DECONSTRUCT_FACTS"}, semantic.InitWorkingConstructClauseHook(), semantic.TypeBindingClauseHook(semantic.Deconstruct))
+	setClauseHook(semanticBQL, []semantic.Symbol{"CONSTRUCT_FACTS"}, semantic.InitWorkingConstructClauseHook(), semantic.TypeBindingClauseHook(semantic.Construct), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"DECONSTRUCT_FACTS"}, semantic.InitWorkingConstructClauseHook(), semantic.TypeBindingClauseHook(semantic.Deconstruct), nil)
+
+	// INSERT ... WHERE statements construct the templated triples directly into
+	// the output graphs.
+	setClauseHook(semanticBQL, []semantic.Symbol{"INSERT_STATEMENT"}, semantic.InitWorkingConstructClauseHook(), semantic.TypeBindingClauseHook(semantic.Construct), isTemplateClause)
+
+	// DELETE ... WHERE statements deconstruct the templated triples from the
+	// same graphs the where clause is evaluated against.
+	setClauseHook(semanticBQL, []semantic.Symbol{"DELETE_STATEMENT"}, semantic.InitWorkingConstructClauseHook(), semantic.DeleteTemplateClauseHook(), isTemplateClause)
 	constructAndDeconstructTriplesSymbols := []semantic.Symbol{"CONSTRUCT_TRIPLES", "MORE_CONSTRUCT_TRIPLES", "DECONSTRUCT_TRIPLES", "MORE_DECONSTRUCT_TRIPLES"}
-	setClauseHook(semanticBQL, constructAndDeconstructTriplesSymbols, semantic.NextWorkingConstructClauseHook(), semantic.NextWorkingConstructClauseHook())
-	setClauseHook(semanticBQL, []semantic.Symbol{"CONSTRUCT_PREDICATE"}, semantic.NextWorkingConstructPredicateObjectPairClauseHook(), nil)
-	setClauseHook(semanticBQL, []semantic.Symbol{"CONSTRUCT_OBJECT"}, nil, semantic.NextWorkingConstructPredicateObjectPairClauseHook())
+	setClauseHook(semanticBQL, constructAndDeconstructTriplesSymbols, semantic.NextWorkingConstructClauseHook(), semantic.NextWorkingConstructClauseHook(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"CONSTRUCT_PREDICATE"}, semantic.NextWorkingConstructPredicateObjectPairClauseHook(), nil, nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"CONSTRUCT_OBJECT"}, nil, semantic.NextWorkingConstructPredicateObjectPairClauseHook(), nil)
 
 	setElementHook(semanticBQL, []semantic.Symbol{"CONSTRUCT_TRIPLES", "DECONSTRUCT_TRIPLES"}, semantic.ConstructSubjectHook(), nil)
 	setElementHook(semanticBQL, []semantic.Symbol{"CONSTRUCT_PREDICATE"}, semantic.ConstructPredicateHook(), nil)
 	setElementHook(semanticBQL, []semantic.Symbol{"CONSTRUCT_OBJECT"}, semantic.ConstructObjectHook(), nil)
 
-	// SHOW GRAPHS clause semantic hooks.
-	setClauseHook(semanticBQL, []semantic.Symbol{"GRAPH_SHOW"}, nil, semantic.ShowClauseHook())
+	// ASK clause semantic hooks.
+	setClauseHook(semanticBQL, []semantic.Symbol{"ASK_QUERY"}, nil, semantic.TypeBindingClauseHook(semantic.Ask), nil)
+
+	// DESCRIBE clause semantic hooks.
+	setElementHook(semanticBQL, []semantic.Symbol{"DESCRIBE_NODE", "DESCRIBE_DEPTH"}, semantic.DescribeCollection(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"DESCRIBE_NODE"}, nil, semantic.TypeBindingClauseHook(semantic.Describe), nil)
+
+	// SHOW and COUNT TRIPLES clause semantic hooks.
+	showSymbols := []semantic.Symbol{"GRAPH_SHOW", "COUNT_TRIPLES"}
+	setElementHook(semanticBQL, showSymbols, semantic.ShowCollection(), nil)
+	setClauseHook(semanticBQL, showSymbols, nil, semantic.ShowClauseHook(), nil)
+
+	// EXPLAIN clause semantic hooks.
+	setClauseHook(semanticBQL, []semantic.Symbol{"START"}, semantic.ExplainClauseHook(), nil, isExplainClause)
+
+	// Transaction control statements semantic hooks.
+	setClauseHook(semanticBQL, []semantic.Symbol{"START"}, nil, semantic.TypeBindingClauseHook(semantic.Begin), isTokenClause(lexer.ItemBegin))
+	setClauseHook(semanticBQL, []semantic.Symbol{"START"}, nil, semantic.TypeBindingClauseHook(semantic.Commit), isTokenClause(lexer.ItemCommit))
+	setClauseHook(semanticBQL, []semantic.Symbol{"START"}, nil, semantic.TypeBindingClauseHook(semantic.Rollback), isTokenClause(lexer.ItemRollback))
 
 	return semanticBQL
 }
@@ -32,6 +32,24 @@ func TestAcceptByParse(t *testing.T) {
 		`select ?a as ?b, ?c as ?d from ?e where{?s ?p ?o};`,
 		`select count(?a) as ?b, sum(?c) as ?d, ?e as ?f from ?g where{?s ?p ?o};`,
 		`select count(distinct ?a) as ?b from ?c where{?s ?p ?o};`,
+		`select median(?a) as ?b, ?c as ?d from ?e where{?s ?p ?o};`,
+		// Test union of graph patterns.
+		`select ?s from ?g where{{?s ?p ?o} union {?s "foo"@[] ?o . ?o ?p2 ?o2}};`,
+		`select ?s from ?g where{{?s ?p ?o} union {?s ?p ?o} UNION {?s ?p ?o . optional {?s ?p2 ?o2}}};`,
+		// Test filter expressions.
+		`select ?s from ?g where{?s ?p ?o . filter(?o > 10 && ?s != ?p)};`,
+		`select ?s from ?g where{?s ?p ?o . FILTER((?o + 1) * 2 <= 3.5 || !(?o = "foo"^^type:text))};`,
+		`select ?s from ?g where{?s ?p ?o . filter(?s = /u<joe>) . ?o ?p2 ?o2};`,
+		`select ?s from ?g where{?s ?p ?o . filter(regex(?o, "^a.*"^^type:text, "i"^^type:text))};`,
+		`select ?s from ?g where{?s ?p ?o . filter(id(?s) = "joe"^^type:text && type(?o) != str(?p))};`,
+		`select id(?s) as ?id, type(?s) as ?t, str(?o) as ?v from ?g where{?s ?p ?o};`,
+		`select ?s from ?g where{?s ?p ?o} order by id(?s), type(?s) desc, str(?o);`,
+		`select coalesce(?o, "none"^^type:text) as ?v, if(?o > 1, ?s, ?p) as ?w from ?g where{?s ?p ?o . filter(coalesce(?o, 0) > 1 && if(?o > 2, ?o, 1) = 2)};`,
+		`select ?s, abs(?o + 1) as ?a, IF((?o > 1) || ?s = ?p, str(?s), id(?s)) as ?b from ?g where{?s ?p ?o};`,
+		// Test subqueries.
+		`select ?s from ?g where{?s ?p ?o . {select ?o from ?g where{?o ?p2 ?o2}}};`,
+		`select ?s, ?n from ?g where{?s ?p ?o . {select ?s, count(?o) as ?n from ?g where{?s ?p ?o} group by ?s} . filter(?n > 1)};`,
+		`select ?s from ?g where{?s ?p ?o . {select ?o from ?g where{?o ?p2 ?o2 . {select ?o2 from ?g where{?o2 ?p3 ?o3}}} limit "1"^^type:int64}};`,
 		// Test multiple graphs are accepted.
 		`select ?a from ?b where{?s ?p ?o};`,
 		`select ?a from ?b, ?c where{?s ?p ?o};`,
@@ -67,6 +85,7 @@ func TestAcceptByParse(t *testing.T) {
 		`select ?a from ?b where{?s ?p ?o} order by ?a desc;`,
 		`select ?a from ?b where{?s ?p ?o} order by ?a asc, ?b desc;`,
 		`select ?a from ?b where{?s ?p ?o} order by ?a desc, ?b desc, ?c asc;`,
+		`select ?a from ?b where{?s ?p ?o} order by abs(?a) desc, (?b + 1), ?c;`,
 		// Test having clause.
 		`select ?a from ?b where {?a ?p ?o} having not ?b;`,
 		`select ?a from ?b where {?a ?p ?o} having (not ?b);`,
@@ -83,6 +102,9 @@ func TestAcceptByParse(t *testing.T) {
 		`select ?a from ?b where {?s ?p ?o} between ""@["123"], ""@["123"];`,
 		// Test limit clause.
 		`select ?a from ?b where {?s ?p ?o} limit "10"^^type:int64;`,
+		// Test offset clause.
+		`select ?a from ?b where {?s ?p ?o} offset "10"^^type:int64;`,
+		`select ?a from ?b where {?s ?p ?o} order by ?a limit "10"^^type:int64 offset "20"^^type:int64;`,
 		// Test optional clauses.
 		`select ?a from ?b where {
 			?s ?p ?o .
@@ -119,6 +141,12 @@ func TestAcceptByParse(t *testing.T) {
 		// Drop graphs.
 		`drop graph ?a;`,
 		`drop graph ?a, ?b, ?c;`,
+		// Conditional create and drop graphs.
+		`create graph if not exists ?a, ?b;`,
+		`drop graph if exists ?a, ?b;`,
+		// Drop graphs and the graphs derived from them.
+		`drop graph ?a cascade;`,
+		`drop graph if exists ?a, ?b cascade;`,
 		// Issue 39 (https://github.com/google/badwolf/issues/39)
 		`insert data into ?world {/room<000> "named"@[] "Hallway"^^type:text.
 		                          /room<000> "connects_to"@[] /room<001>};`,
@@ -155,6 +183,38 @@ func TestAcceptByParse(t *testing.T) {
 		 from ?b where {?s "old_predicate_1"@[,] ?o1.
 				?s "old_predicate_2"@[,] ?o2.
 				?s "old_predicate_3"@[,] ?o3};`,
+		// Test insert clauses populated by a where clause.
+		`insert {?s "new_predicate"@[] ?o} into ?a from ?b where {?s "old_predicate"@[,] ?o} having ?s = ?o;`,
+		`insert {?s "new_predicate"@[] ?o; "other_predicate"@[] /_<foo>} into ?a, ?b from ?c where {?s "old_predicate"@[,] ?o};`,
+		// Test delete clauses populated by a where clause.
+		`delete {?s "old_predicate"@[] ?o} from ?a where {?s "old_predicate"@[] ?o} having ?s = ?o;`,
+		`delete {?s ?p ?o} from ?a, ?b where {?s "old_predicate"@[,2015-07-19T13:12:04.669618843-07:00] as ?p ?o};`,
+		// Test construct clauses returning the constructed triples.
+		`construct {?s "new_predicate"@[] ?o} from ?b where {?s "old_predicate"@[,] ?o};`,
+		// Test ask clauses.
+		`ask from ?a where {?s "foo"@[,] ?o};`,
+		`ask from ?a, ?b where {?s "foo"@[,] ?o . ?o "bar"@[] /_<baz>} before ""@[2016-01-01T00:00:00-08:00];`,
+		// Test relative and exclusive global time bounds.
+		`select ?s from ?a where {?s "foo"@[,] ?o} after NOW() - 24h;`,
+		`select ?s from ?a where {?s "foo"@[,] ?o} before now();`,
+		`select ?s from ?a where {?s "foo"@[,] ?o} before ""@[2016-01-01T00:00:00-08:00] + 1h30m exclusive;`,
+		`select ?s from ?a where {?s "foo"@[,] ?o} between NOW() - 48h, NOW() - 24h inclusive;`,
+		`select ?s from ?a where {?s "foo"@[,] ?o} between ""@[2016-01-01T00:00:00-08:00], NOW() exclusive limit "1"^^type:int64;`,
+		// Test predicate paths.
+		`select ?s, ?o from ?a where {?s "knows"@[]+ ?o};`,
+		`select ?s, ?o from ?a where {?s "knows"@[]* ?o};`,
+		`select ?s, ?o from ?a where {?s "knows"@[]/"name"@[] ?o};`,
+		`select ?s, ?o from ?a where {?s "knows"@[]+/"works_at"@[] / "name"@[]* ?o};`,
+		// Test graph blocks.
+		`select ?g, ?o from ?a, ?b where {graph ?g {?s "foo"@[] ?o}};`,
+		`select ?g, ?o from ?a, ?b where {graph ?g {?s "foo"@[] ?x . ?x "bar"@[] ?o}};`,
+		`select ?g, ?o from ?a, ?b where {?s "foo"@[] ?o . graph ?g {?o "bar"@[] /_<baz>} . ?s "baz"@[] ?x};`,
+		`select ?g, ?o from ?a, ?b where {{graph ?g {?s "foo"@[] ?o}} union {?s "bar"@[] ?o . graph ?g {?o "bar"@[] ?s}}};`,
+		// Test describe clauses.
+		`describe /u<joe>;`,
+		`describe /u<joe> in ?a, ?b;`,
+		`describe /u<joe> in ?a depth "2"^^type:int64;`,
+		`describe /u<joe> depth "2"^^type:int64;`,
 		// Test Deconstruct clause.
 		`deconstruct {?s "new_predicate"@[] ?o} in ?a from ?b where {?s "old_predicate"@[,] ?o} having ?s = ?o;`,
 		`deconstruct {?s "new_predicate"@[] ?o} in ?a from ?b where {?s "old_predicate"@[,] ?o};`,
@@ -169,6 +229,22 @@ func TestAcceptByParse(t *testing.T) {
 			?n "_object"@[] ?o};`,
 		// Show the graphs.
 		`show graphs;`,
+		// Catalog statements.
+		`show predicates in ?a;`,
+		`show predicates in ?a, ?b;`,
+		`show stats in ?a, ?b;`,
+		`count triples in ?a, ?b;`,
+		// Transaction control statements.
+		`begin;`,
+		`COMMIT;`,
+		`rollback;`,
+		// Explain statements.
+		`explain select ?s from ?a where {?s ?p ?o};`,
+		`explain ask from ?a where {?s ?p ?o};`,
+		`EXPLAIN show graphs;`,
+		// Reified clauses.
+		`select ?r from ?a where {/u<joe> "met"@[?t] ?o reified as ?r};`,
+		`select ?r from ?a where {?s "knows"@[] /u<mary> as ?o reified as ?r . optional {?s "age"@[] ?age reified as ?ra}};`,
 	}
 	p, err := NewParser(BQL())
 	if err != nil {
@@ -190,6 +266,16 @@ func TestRejectByParse(t *testing.T) {
 		`select ?a as ?b, from ?b;`,
 		`select count(?a as ?b, from ?b;`,
 		`select count(distinct) as ?a, from ?c;`,
+		`select median(distinct ?a) as ?b from ?c where{?s ?p ?o};`,
+		`select median(?a) from ?c where{?s ?p ?o};`,
+		`select ?s from ?g where{{?s ?p ?o} union};`,
+		`select ?s from ?g where{?s ?p ?o union {?s ?p ?o}};`,
+		`select ?s from ?g where{{?s ?p ?o} {?s ?p ?o}};`,
+		`select ?s from ?g where{?s ?p ?o . filter};`,
+		`select ?s from ?g where{?s ?p ?o . filter(?o > 10};`,
+		`select ?s from ?g where{?s ?p ?o . {select ?o from ?g where{?o ?p2 ?o2};}};`,
+		`select ?s from ?g where{?s ?p ?o . {select ?o where{?o ?p2 ?o2}}};`,
+		`select ?s from ?g where{?s ?p ?o . {?o ?p2 ?o2}};`,
 		// Reject missing comas on var bindings or missing graphs.
 		`select ?a from ?b ?c;`,
 		`select ?a from ?b,;`,
@@ -215,6 +301,8 @@ func TestRejectByParse(t *testing.T) {
 		`select ?a from ?b where{?s ?p ?o} by ?a;`,
 		`select ?a from ?b where{?s ?p ?o} order by ?a, a;`,
 		`select ?a from ?b where{?s ?p ?o} order by ?a, ?b, desc;`,
+		`select ?a from ?b where{?s ?p ?o} order by abs ?a;`,
+		`select ?a from ?b where{?s ?p ?o} order by abs(?a;`,
 		// Reject invalid having clauses.
 		`select ?a from ?b where {?a ?p ?o} having not ;`,
 		`select ?a from ?b where {?a ?p ?o} having not ?b ?b;`,
@@ -237,6 +325,10 @@ func TestRejectByParse(t *testing.T) {
 		// Test limit clause.
 		`select ?a from ?b where {?s ?p ?o} limit ?b;`,
 		`select ?a from ?b where {?s ?p ?o} limit ;`,
+		// Test offset clause.
+		`select ?a from ?b where {?s ?p ?o} offset ?b;`,
+		`select ?a from ?b where {?s ?p ?o} offset ;`,
+		`select ?a from ?b where {?s ?p ?o} offset "10"^^type:int64 limit "10"^^type:int64;`,
 		// Test optional clauses.
 		`select ?a from ?b where {
 			optional {?x ?w ?z }
@@ -271,9 +363,64 @@ func TestRejectByParse(t *testing.T) {
 		// Drop graphs.
 		`drop graph ;`,
 		`drop graph ?a ?b, ?c;`,
+		// Conditional create and drop graphs with the wrong conditions.
+		`create graph if exists ?a;`,
+		`create graph if not ?a;`,
+		`drop graph if not exists ?a;`,
+		`drop graph if ?a;`,
+		// Cascade only applies to dropped graphs, after them.
+		`create graph ?a cascade;`,
+		`drop graph cascade ?a;`,
+		`drop graph ?a cascade, ?b;`,
+		// Insert clause without source, destination or where clause.
+		`insert {?s "foo"@[,] ?o} into ?a where{?s "foo"@[,] ?o};`,
+		`insert {?s "foo"@[,] ?o} from ?b where{?s "foo"@[,] ?o};`,
+		`insert {?s "foo"@[,] ?o} into ?a from ?b;`,
+		// Delete clause without graphs, where clause or using blank nodes.
+		`delete {?s "foo"@[,] ?o} where{?s "foo"@[,] ?o};`,
+		`delete {?s "foo"@[,] ?o} from ?a;`,
+		`delete {?s "foo"@[] ?o; "bar"@[] ?o} from ?a where{?s "foo"@[,] ?o};`,
 		// Construct clause without source.
 		`construct {?s "foo"@[,] ?o} into ?a where{?s "foo"@[,] ?o} having ?s = ?o;`,
-		// Construct clause without destination.
+		// Ask clause without graphs, where clause, or with projections.
+		`ask where {?s "foo"@[,] ?o};`,
+		`ask from ?a;`,
+		`ask ?s from ?a where {?s "foo"@[,] ?o};`,
+		`ask from ?a where {?s "foo"@[,] ?o} limit "1"^^type:int64;`,
+		// Relative global time bounds with missing durations or misplaced modifiers.
+		`select ?s from ?a where {?s "foo"@[,] ?o} after NOW() - ;`,
+		`select ?s from ?a where {?s "foo"@[,] ?o} after NOW() 24h;`,
+		`select ?s from ?a where {?s "foo"@[,] ?o} after NOW;`,
+		`select ?s from ?a where {?s "foo"@[,] ?o} between NOW() exclusive, NOW();`,
+		`select ?s from ?a where {?s "foo"@[,] ?o} exclusive;`,
+		// Predicate paths with repeated modifiers, missing steps, or
+		// non-predicate steps.
+		`select ?s, ?o from ?a where {?s "knows"@[]+* ?o};`,
+		`select ?s, ?o from ?a where {?s "knows"@[]/ ?o};`,
+		`select ?s, ?o from ?a where {?s ?p+ ?o};`,
+		`select ?s, ?o from ?a where {?s "knows"@[,]+ ?o};`,
+		`select ?s, ?o from ?a where {?s "knows"@[]/?p ?o};`,
+		// Graph blocks without a binding, empty, or nesting other blocks.
+		`select ?o from ?a where {graph {?s "foo"@[] ?o}};`,
+		`select ?o from ?a where {graph /_<foo> {?s "foo"@[] ?o}};`,
+		`select ?g from ?a where {graph ?g {}};`,
+		`select ?g, ?o from ?a where {graph ?g {graph ?h {?s "foo"@[] ?o}}};`,
+		`select ?g, ?o from ?a where {graph ?g {?s "foo"@[] ?o . filter(?o > 1)}};`,
+		// Describe clause with bindings, missing nodes, or missing depth.
+		`describe ?s in ?a;`,
+		`describe in ?a;`,
+		`describe /u<joe> in ?a depth;`,
+		`describe /u<joe> depth "2"^^type:int64 in ?a;`,
+		// Catalog statements without graphs or with unknown targets.
+		`show predicates;`,
+		`show stats;`,
+		`show triples in ?a;`,
+		`count triples;`,
+		`count predicates in ?a;`,
+		// Explain without a statement to explain.
+		`explain;`,
+		`explain ?s;`,
+		// Construct clause with a bound predicate in the template.
 		`construct {?s "foo"@[,] ?o} from ?b where{?s "foo"@[,] ?o} having ?s = ?o;`,
 		// Construct clause with badly formed blank node.
 		`construct {?s ?p ?o.
@@ -352,6 +499,8 @@ func TestAcceptGraphOpsByParseAndSemantic(t *testing.T) {
 		{`create graph ?foo1, ?bar1;`, []string{"?foo1", "?bar1"}, empty, empty, 0},
 		// Drop graphs. All graphs are regular graphs.
 		{`drop graph ?foo2, ?bar2;`, []string{"?foo2", "?bar2"}, empty, empty, 0},
+		{`create graph if not exists ?foo1, ?bar1;`, []string{"?foo1", "?bar1"}, empty, empty, 0},
+		{`drop graph if exists ?foo2;`, []string{"?foo2"}, empty, empty, 0},
 
 		// Insert data. All graphs are output graphs.
 		{`insert data into ?a {/_<foo> "bar"@[1975-01-01T00:01:01.999999999Z] /_<foo>};`, empty, empty, []string{"?a"}, 1},
@@ -401,6 +550,41 @@ func TestAcceptGraphOpsByParseAndSemantic(t *testing.T) {
 			[]string{"?a", "?b"},
 			0},
 
+		// Insert data populated by a where clause. Graphs can be input or output graphs.
+		{`insert {?s "predicate_1"@[] ?o1}
+		  into ?a
+		  from ?b
+		  where {?s "old_predicate_1"@[,] ?o1};`,
+			empty,
+			[]string{"?b"},
+			[]string{"?a"},
+			0},
+
+		// Delete data populated by a where clause. Graphs are both input and output graphs.
+		{`delete {?s "predicate_1"@[] ?o1}
+		  from ?a, ?b
+		  where {?s "predicate_1"@[] ?o1};`,
+			empty,
+			[]string{"?a", "?b"},
+			[]string{"?a", "?b"},
+			0},
+
+		// Construct data returning the constructed triples.
+		{`construct {?s "predicate_1"@[] ?o1}
+		  from ?b
+		  where {?s "old_predicate_1"@[,] ?o1};`,
+			empty,
+			[]string{"?b"},
+			empty,
+			0},
+
+		// Describe nodes. All graphs are input graphs.
+		{`describe /u<joe> in ?a, ?b;`, empty, []string{"?a", "?b"}, empty, 0},
+		{`describe /u<joe>;`, empty, empty, empty, 0},
+		{`show predicates in ?a, ?b;`, empty, []string{"?a", "?b"}, empty, 0},
+		{`show stats in ?a;`, empty, []string{"?a"}, empty, 0},
+		{`count triples in ?a;`, empty, []string{"?a"}, empty, 0},
+
 		// Deconstruct data. Graphs can be input or output graphs.
 		{`deconstruct {?s "predicate_1"@[] ?o1}
 		  in ?a
@@ -449,10 +633,43 @@ func TestAcceptGraphOpsByParseAndSemantic(t *testing.T) {
 	}
 }
 
+func TestCascadeBySemanticParse(t *testing.T) {
+	table := []struct {
+		query   string
+		cascade bool
+	}{
+		{`drop graph ?a;`, false},
+		{`drop graph ?a, ?b cascade;`, true},
+		{`drop graph if exists ?a CASCADE;`, true},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: Failed to accept %q with error %v", entry.query, err)
+			continue
+		}
+		if got, want := st.IsCascade(), entry.cascade; got != want {
+			t.Errorf("Statement.IsCascade for %q returned %v; want %v", entry.query, got, want)
+		}
+	}
+}
+
 func TestAcceptQueryBySemanticParse(t *testing.T) {
 	table := []string{
 		// Test well type literals are accepted.
 		`select ?s from ?g where{?s ?p "1"^^type:int64};`,
+		// Test unions of graph patterns are accepted.
+		`select ?s, ?o from ?g where{{?s "foo"@[] ?o} union {?s "bar"@[] ?x}};`,
+		// Test filters are accepted.
+		`select ?s, ?o from ?g where{?s "foo"@[] ?o . filter(?o >= 10 && ?s != ?o)};`,
+		`select ?s, ?o from ?g where{{?s "foo"@[] ?o . filter(?o < 1)} union {?s "bar"@[] ?o}};`,
+		// Test subqueries are accepted.
+		`select ?s, ?n from ?g where{?s "foo"@[] ?o . {select ?s, count(?o) as ?n from ?g where{?s "bar"@[] ?o} group by ?s}};`,
+		`select ?s, ?x from ?g where{?s "foo"@[] ?o . {select ?o as ?x from ?g where{?s "bar"@[] ?o}} . filter(?x != ?o)};`,
 		// Test predicates are accepted.
 		// Test invalid predicate time anchor are rejected.
 		`select ?s from ?b where{/_<foo> as ?s "id"@[2015] ?o};`,
@@ -477,10 +694,21 @@ func TestAcceptQueryBySemanticParse(t *testing.T) {
 		// Test group by acceptance.
 		`select ?s from ?g where{/_<foo> as ?s  ?p "id"@[?foo, ?bar] as ?o} group by ?s;`,
 		`select count(?s) as ?a, sum(?o) as ?b, ?o as ?c from ?g where{?s ?p ?o} group by ?c;`,
+		// Test group by expressions acceptance.
+		`select ?s, if(?o > 1, ?o, 0) as ?v, count(?o) as ?n from ?g where{?s ?p ?o} group by ?s, ?v;`,
+		`select hour(?p) as ?h, count(?o) as ?n from ?g where{?s ?p ?o} group by HOUR(?p);`,
+		`select ?s, count(?o) as ?n from ?g where{?s ?p ?o} group by ?s, day(?p), (?o + 1), type(?o), id(?s), if(?o > 1, 1, 0);`,
 		// Test order by acceptance.
 		`select ?s from ?g where{/_<foo> as ?s  ?p "id"@[?foo, ?bar] as ?o} order by ?s;`,
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} order by ?a ASC, ?b DESC;`,
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} order by ?a ASC, ?b DESC, ?a ASC, ?b DESC, ?c;`,
+		`select ?s, ?o from ?g where{?s ?p ?o} order by abs(?o) desc;`,
+		`select ?s, ?o from ?g where{?s ?p ?o} order by ?s, (?o * 2 - 1) asc, ROUND(?o / 3) DESC;`,
+		// Test predicate paths are accepted.
+		`select ?s, ?o from ?g where{?s "knows"@[]+/"name"@[] ?o};`,
+		`select ?o from ?g where{/u<joe> "knows"@[]* ?o . ?o "knows"@[]/"knows"@[] /u<mary>};`,
+		// Test explain statements are accepted.
+		`explain select ?s, ?o from ?g where{?s "foo"@[] ?o} order by ?s limit "10"^^type:int64;`,
 	}
 	p, err := NewParser(SemanticBQL())
 	if err != nil {
@@ -507,11 +735,53 @@ func TestRejectByParseAndSemantic(t *testing.T) {
 		`select count(?s) as ?a, sum(?o) as ?b, ?o as ?c from ?g where{?s ?p ?o};`,
 		`select count(?s) as ?a, sum(?o) as ?b, ?o as ?c from ?g where{?s ?p ?o} group by ?b;`,
 		`select count(?s) as ?a, sum(?o) as ?b, ?o as ?c from ?g where{?s ?p ?o} group by ?a;`,
+		// Reject unregistered aggregation functions.
+		`select unknown_function(?s) as ?a, ?o as ?c from ?g where{?s ?p ?o} group by ?c;`,
 		// Reject order by acceptance.
 		`select ?s from ?g where{/_<foo> as ?s  ?p "id"@[?foo, ?bar] as ?o} order by ?unknown_s;`,
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} order by ?a ASC, ?a DESC;`,
+		`select ?s from ?g where{?s ?p ?o} order by abs(?o);`,
+		`select ?s, ?o from ?g where{?s ?p ?o} order by unknown(?o);`,
+		`select ?s, ?o from ?g where{?s ?p ?o} order by abs(?o) asc, abs(?o) desc;`,
+		// Reject invalid subqueries or unknown subquery bindings.
+		`select ?s, ?o2 from ?g where{?s ?p ?o . {select ?o from ?g where{?o ?p2 ?o2}}};`,
+		`select ?s from ?g where{?s ?p ?o . {select ?x from ?g where{?o ?p2 ?o2}}};`,
+		`select ?s from ?g where{?s ?p ?o . {select count(?o) as ?n from ?g where{?o ?p2 ?o2}}};`,
+		// Reject filters with unknown bindings or invalid expressions.
+		`select ?s from ?g where{?s ?p ?o . filter(?x > 1)};`,
+		`select ?s from ?g where{?s ?p ?o . filter(?o >)};`,
+		`select ?s from ?g where{?s ?p ?o . filter(?o 1)};`,
+		`select ?s from ?g where{?s ?p ?o . filter(regex(?o, "("^^type:text))};`,
+		`select ?s from ?g where{?s ?p ?o . filter(match(?o, "a"^^type:text))};`,
+		`select ?s from ?g where{?s ?p ?o . filter(id(?s, ?o) = ?p)};`,
+		`select abs(?o) as ?a from ?g where{?s ?p ?o . filter(?o > round(?o, ?o))};`,
+		`select unknown(?o) as ?a from ?g where{?s ?p ?o};`,
+		`select if(?o) as ?v from ?g where{?s ?p ?o};`,
+		`select coalesce(?x, ?o) as ?v from ?g where{?s ?p ?o};`,
+		`select ?s, if(?o > 1, ?o, 0) as ?v from ?g where{?s ?p ?o} group by ?s;`,
+		`select ?s, count(?o) as ?n from ?g where{?s ?p ?o} group by ?s, hour(?x);`,
+		`select ?s, count(?o) as ?n from ?g where{?s ?p ?o} group by ?s, unknown(?o);`,
 		// Wrong limit literal.
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} LIMIT "true"^^type:bool;`,
+		// Wrong offset literal.
+		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} OFFSET "true"^^type:bool;`,
+		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} OFFSET "-1"^^type:int64;`,
+		// Reject unknown functions or invalid durations in global time bounds.
+		`select ?s from ?g where{?s "foo"@[,] ?o} after yesterday();`,
+		`select ?s from ?g where{?s "foo"@[,] ?o} after NOW() - 1y;`,
+		// Reject aliased predicate paths or path steps with time anchor bindings.
+		`select ?s, ?o from ?g where{?s "knows"@[]+ as ?p ?o};`,
+		`select ?s, ?o from ?g where{?s "knows"@[?t]+ ?o};`,
+		`select ?s, ?o from ?g where{?s "knows"@[]/"name"@[?t] ?o};`,
+		// Wrong describe depth literal.
+		`describe /u<joe> in ?a depth "0"^^type:int64;`,
+		`describe /u<joe> in ?a depth "1"^^type:float64;`,
+		// Explain statements cannot be explained.
+		`explain explain select ?s from ?g where{?s ?p ?o};`,
+		// Reject reified clauses without a fixed predicate ID or time anchor.
+		`select ?r from ?g where{?s ?p ?o reified as ?r};`,
+		`select ?r from ?g where{?s "knows"@[]+ ?o reified as ?r};`,
+		`select ?r from ?g where{?s "met"@[,] ?o reified as ?r};`,
 	}
 	p, err := NewParser(SemanticBQL())
 	if err != nil {
@@ -542,6 +812,10 @@ func TestSemanticStatementGraphClausesLengthCorrectness(t *testing.T) {
 			query: `SELECT ?o,?l FROM ?bbacl WHERE { ?o "some_id"@[,] ?x . ?x "some_id"@[,] ?y . ?y "some_id"@[,] ?l } LIMIT "20"^^type:int64;`,
 			want:  3,
 		},
+		{
+			query: `SELECT ?o,?c FROM ?bbacl WHERE { /u<joe> "met"@[?t] ?o REIFIED AS ?r . ?r "confidence"@[] ?c };`,
+			want:  5,
+		},
 	}
 	p, err := NewParser(SemanticBQL())
 	if err != nil {
@@ -558,6 +832,48 @@ func TestSemanticStatementGraphClausesLengthCorrectness(t *testing.T) {
 	}
 }
 
+func TestSemanticStatementGraphBindings(t *testing.T) {
+	table := []struct {
+		query string
+		want  []string
+	}{
+		{
+			query: `select ?g, ?o from ?a, ?b where {graph ?g {?s "foo"@[] ?o}};`,
+			want:  []string{"?g"},
+		},
+		{
+			query: `select ?g, ?o from ?a, ?b where {graph ?g {?s "foo"@[] ?x . ?x "bar"@[] ?o}};`,
+			want:  []string{"?g", "?g"},
+		},
+		{
+			query: `select ?g, ?o from ?a, ?b where {?s "foo"@[] ?x . graph ?g {?x "bar"@[] ?o} . ?o "baz"@[] ?y};`,
+			want:  []string{"", "?g", ""},
+		},
+		{
+			query: `select ?g, ?h, ?o from ?a, ?b where {graph ?g {?s "foo"@[] ?o} . graph ?h {?o "bar"@[] ?s}};`,
+			want:  []string{"?g", "?h"},
+		},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.query, err)
+			continue
+		}
+		var got []string
+		for _, cls := range st.GraphPatternClauses() {
+			got = append(got, cls.GraphBinding)
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("Invalid graph bindings for query %q; got %q, want %q", entry.query, got, entry.want)
+		}
+	}
+}
+
 func TestSemanticStatementConstructDeconstructClausesLengthCorrectness(t *testing.T) {
 	table := []struct {
 		query string
@@ -723,3 +1039,45 @@ func TestSemanticStatementPredicateObjectPairsLengthCorrectness(t *testing.T) {
 		}
 	}
 }
+
+func TestSemanticStatementCheck(t *testing.T) {
+	table := []struct {
+		query string
+		want  int
+	}{
+		{`select ?s from ?g where{?s "foo"@[] ?o};`, 0},
+		{`select ?s from ?g where{?s "foo"@[] ?o . ?o "bar"@[] ?x};`, 0},
+		{`select ?s from ?g where{?s ?p ?o . filter(?o > 10 && ?s = /u<joe>)};`, 0},
+		{`select ?s from ?g where{?s ?p ?o . filter(regex(?o, "^a"^^type:text) && coalesce(?o, 0) > 1)};`, 0},
+		{`select ?s, ?t from ?g where{?s "foo"@[,] AT ?t ?o . ?x "bar"@[,] AT ?t ?o . filter(?s != ?x)};`, 0},
+		// Bindings holding values of incompatible kinds.
+		{`select ?s from ?g where{?s "foo"@[] ?o . ?o ?s ?x};`, 1},
+		{`select ?s from ?g where{?s ID ?i "foo"@[] ?o . ?i "bar"@[] ?x};`, 1},
+		{`select ?s from ?g where{?s "foo"@[,] AT ?t ?o . ?s "bar"@[] ?t};`, 1},
+		// Type errors in filters.
+		{`select ?s from ?g where{?s ?p ?o . filter(?s > 10)};`, 1},
+		{`select ?s from ?g where{?s ?p ?o . filter(?p + 1 > 2)};`, 1},
+		{`select ?s from ?g where{?s ?p ?o . filter(?o > "a"^^type:text + 1)};`, 1},
+		{`select ?s from ?g where{?s ?p ?o . filter(1 / 0 > ?o)};`, 1},
+		{`select ?s from ?g where{?s ?p ?o . filter(regex(?s, "^a"^^type:text))};`, 1},
+		{`select ?s from ?g where{?s ?p ?o . filter("a"^^type:text)};`, 1},
+		{`select ?s from ?g where{?s ?p ?o . filter(?s = ?p || ?o > /u<joe>)};`, 1},
+		// Problems in union alternatives and subqueries.
+		{`select ?s from ?g where{{?s "foo"@[] ?o . filter(?s > 1)} union {?s "bar"@[] ?o . filter(?o > 1)}};`, 1},
+		{`select ?s, ?n from ?g where{?s ?p ?o . {select ?s, count(?o) as ?n from ?g where{?s ?p ?o . filter(?p < 1)} group by ?s}};`, 1},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.query, err)
+			continue
+		}
+		if got := st.Check(); len(got) != entry.want {
+			t.Errorf("Statement.Check for query %q returned %v; want %d problems", entry.query, got, entry.want)
+		}
+	}
+}
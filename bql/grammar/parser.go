@@ -16,6 +16,8 @@ package grammar
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/google/badwolf/bql/lexer"
 	"github.com/google/badwolf/bql/semantic"
@@ -67,6 +69,83 @@ type Clause struct {
 // text.
 type Grammar map[semantic.Symbol][]*Clause
 
+// ParseError describes why the parser failed to accept the provided input. It
+// locates the offending token and, when the parser knows it, lists the tokens
+// that would have been accepted instead.
+type ParseError struct {
+	// Line and Col locate the offending token in the input. Both start at 1.
+	// They are 0 if the location is unknown.
+	Line int
+	Col  int
+	// Token is the token that could not be processed.
+	Token lexer.Token
+	// Symbol is the grammar symbol being derived when the error was found.
+	Symbol semantic.Symbol
+	// Expected contains the token types that would have been accepted instead
+	// of the offending token. It is empty if the token was rejected by the
+	// semantic hooks or by the lexer.
+	Expected []lexer.TokenType
+	// Msg describes the error.
+	Msg string
+}
+
+// Error returns a readable description of the parsing error.
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	b.WriteString("grammar: ")
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d, column %d: ", e.Line, e.Col)
+	}
+	b.WriteString(e.Msg)
+	switch len(e.Expected) {
+	case 0:
+	case 1:
+		fmt.Fprintf(&b, "; expected %s", e.Expected[0])
+	default:
+		var ss []string
+		for _, tt := range e.Expected {
+			ss = append(ss, tt.String())
+		}
+		fmt.Fprintf(&b, "; expected one of %s", strings.Join(ss, ", "))
+	}
+	return b.String()
+}
+
+// newParseError returns a parse error located at the provided token.
+func newParseError(tkn *lexer.Token, s semantic.Symbol, expected []lexer.TokenType, msg string) *ParseError {
+	e := &ParseError{
+		Line:     tkn.Line,
+		Col:      tkn.Col,
+		Token:    *tkn,
+		Symbol:   s,
+		Expected: expected,
+		Msg:      msg,
+	}
+	if tkn.Type == lexer.ItemError {
+		e.Expected, e.Msg = nil, tkn.ErrorMessage
+	}
+	return e
+}
+
+// unexpectedToken returns a parse error for the provided token, which does not
+// belong at its location.
+func unexpectedToken(tkn *lexer.Token, s semantic.Symbol, expected []lexer.TokenType) *ParseError {
+	msg := fmt.Sprintf("unexpected %s", tkn.Type)
+	if tkn.Text != "" {
+		msg = fmt.Sprintf("unexpected %s %q", tkn.Type, tkn.Text)
+	}
+	return newParseError(tkn, s, expected, msg)
+}
+
+// hookError returns a parse error located at the provided token for the error
+// returned by a semantic hook. Parse errors are returned unchanged.
+func hookError(tkn *lexer.Token, s semantic.Symbol, err error) error {
+	if _, ok := err.(*ParseError); ok {
+		return err
+	}
+	return newParseError(tkn, s, nil, err.Error())
+}
+
 // Parser implements a LLk recursive descent parser for left factorized grammars.
 type Parser struct {
 	grammar *Grammar
@@ -96,14 +175,15 @@ func NewParser(grammar *Grammar) (*Parser, error) {
 	}, nil
 }
 
-// Parse attempts to run the parser for the given input.
+// Parse attempts to run the parser for the given input. Errors found while
+// parsing are returned as *ParseError.
 func (p *Parser) Parse(llk *LLk, st *semantic.Statement) error {
 	b, err := p.consume(llk, st, "START")
 	if err != nil {
 		return err
 	}
 	if !b {
-		return fmt.Errorf("Parser.Parse: inconsitent parser, no error found, and no tokens were consumed")
+		return newParseError(llk.Current(), "START", nil, "inconsistent parser, no error found, and no tokens were consumed")
 	}
 	return nil
 }
@@ -123,25 +203,49 @@ func (p *Parser) consume(llk *LLk, st *semantic.Statement, s semantic.Symbol) (b
 			return p.expect(llk, st, s, clause)
 		}
 	}
-	return false, fmt.Errorf("Parser.consume: could not consume token %s in production %s", llk.Current(), s)
+	return false, unexpectedToken(llk.Current(), s, p.firstTokens(s))
+}
+
+// firstTokens returns the sorted token types that can start a derivation of
+// the provided symbol.
+func (p *Parser) firstTokens(s semantic.Symbol) []lexer.TokenType {
+	var res []lexer.TokenType
+	seen := make(map[lexer.TokenType]bool)
+	for _, clause := range (*p.grammar)[s] {
+		if len(clause.Elements) == 0 || clause.Elements[0].isSymbol {
+			continue
+		}
+		if tt := clause.Elements[0].Token(); !seen[tt] {
+			seen[tt] = true
+			res = append(res, tt)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
 }
 
 // expect given the input, symbol, and clause attempts to satisfy all elements.
+// Hooks are always called on the active statement, so the hooks of nested
+// subqueries update the subquery being parsed instead of the outer statement.
 func (p *Parser) expect(llk *LLk, st *semantic.Statement, s semantic.Symbol, cls *Clause) (bool, error) {
 	if cls.ProcessStart != nil {
-		if _, err := cls.ProcessStart(st, s); err != nil {
-			return false, err
+		if _, err := cls.ProcessStart(st.ActiveStatement(), s); err != nil {
+			return false, hookError(llk.Current(), s, err)
 		}
 	}
 	for _, elem := range cls.Elements {
 		tkn := llk.Current()
 		if elem.isSymbol {
-			if b, err := p.consume(llk, st, elem.Symbol()); !b || err != nil {
-				return false, fmt.Errorf("Parser.parse: Failed to consume symbol %v, with error %v", elem.Symbol(), err)
+			b, err := p.consume(llk, st, elem.Symbol())
+			if err != nil {
+				return false, err
+			}
+			if !b {
+				return false, newParseError(tkn, elem.Symbol(), nil, fmt.Sprintf("failed to consume symbol %v", elem.Symbol()))
 			}
 		} else {
 			if !llk.Consume(elem.Token()) {
-				return false, fmt.Errorf("Parser.parse: Failed to consume %s, got %s instead", elem.Token(), llk.Current().Type)
+				return false, unexpectedToken(tkn, s, []lexer.TokenType{elem.Token()})
 			}
 		}
 		if cls.ProcessedElement != nil {
@@ -151,14 +255,14 @@ func (p *Parser) expect(llk *LLk, st *semantic.Statement, s semantic.Symbol, cls
 			} else {
 				ce = semantic.NewConsumedToken(tkn)
 			}
-			if _, err := cls.ProcessedElement(st, ce); err != nil {
-				return false, err
+			if _, err := cls.ProcessedElement(st.ActiveStatement(), ce); err != nil {
+				return false, hookError(tkn, s, err)
 			}
 		}
 	}
 	if cls.ProcessEnd != nil {
-		if _, err := cls.ProcessEnd(st, s); err != nil {
-			return false, err
+		if _, err := cls.ProcessEnd(st.ActiveStatement(), s); err != nil {
+			return false, hookError(llk.Current(), s, err)
 		}
 	}
 	return true, nil
@@ -227,3 +227,90 @@ func TestComplexGrammarParse(t *testing.T) {
 		t.Errorf("Parser.consume: failed to accept derivation tokens; %v", err)
 	}
 }
+
+func TestParseErrors(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	table := []struct {
+		input    string
+		line     int
+		col      int
+		token    lexer.TokenType
+		expected lexer.TokenType
+	}{
+		// Lexer errors.
+		{"select ?s\nform ?g where {?s ?p ?o};", 2, 1, lexer.ItemError, lexer.ItemError},
+		// Unexpected tokens.
+		{"select ?s from ?g where {?s ?p ?o}", 1, 35, lexer.ItemEOF, lexer.ItemSemicolon},
+		{"select ?s from ?g\n  where ?s ?p ?o};", 2, 9, lexer.ItemBinding, lexer.ItemLBracket},
+		{"create graph ;", 1, 14, lexer.ItemSemicolon, lexer.ItemBinding},
+		// Semantic errors.
+		{`select ?s from ?g where {?s ?p ?o} limit "a"^^type:text;`, 1, 42, lexer.ItemLiteral, lexer.ItemError},
+	}
+	for _, entry := range table {
+		err := p.Parse(NewLLk(entry.input, 1), &semantic.Statement{})
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("Parser.Parse(%q) should have returned a *ParseError; got %v", entry.input, err)
+			continue
+		}
+		if perr.Line != entry.line || perr.Col != entry.col {
+			t.Errorf("Parser.Parse(%q) returned the wrong location %d:%d; want %d:%d", entry.input, perr.Line, perr.Col, entry.line, entry.col)
+		}
+		if perr.Token.Type != entry.token {
+			t.Errorf("Parser.Parse(%q) returned the wrong offending token %v; want %v", entry.input, perr.Token, entry.token)
+		}
+		if entry.expected == lexer.ItemError {
+			if len(perr.Expected) != 0 {
+				t.Errorf("Parser.Parse(%q) should not have returned any expected tokens; got %v", entry.input, perr.Expected)
+			}
+			continue
+		}
+		found := false
+		for _, tt := range perr.Expected {
+			if tt == entry.expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Parser.Parse(%q) returned expected tokens %v; want them to include %v", entry.input, perr.Expected, entry.expected)
+		}
+	}
+}
+
+func TestParseErrorString(t *testing.T) {
+	table := []struct {
+		err  *ParseError
+		want string
+	}{
+		{
+			err:  &ParseError{Msg: "failed"},
+			want: "grammar: failed",
+		},
+		{
+			err: &ParseError{
+				Line:     2,
+				Col:      7,
+				Expected: []lexer.TokenType{lexer.ItemSemicolon},
+				Msg:      `unexpected EOF`,
+			},
+			want: "grammar: line 2, column 7: unexpected EOF; expected SEMICOLON",
+		},
+		{
+			err: &ParseError{
+				Line:     1,
+				Col:      3,
+				Expected: []lexer.TokenType{lexer.ItemBinding, lexer.ItemLBracket},
+				Msg:      `unexpected NODE "/u<joe>"`,
+			},
+			want: `grammar: line 1, column 3: unexpected NODE "/u<joe>"; expected one of BINDING, LEFT_BRACKET`,
+		},
+	}
+	for _, entry := range table {
+		if got := entry.err.Error(); got != entry.want {
+			t.Errorf("ParseError.Error() = %q; want %q", got, entry.want)
+		}
+	}
+}
@@ -20,9 +20,14 @@ package lexer
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
 )
 
 // TokenType list all the possible tokens returned by a lexer.
@@ -137,6 +142,64 @@ const (
 	ItemGraphs
 	// ItemOptional identifies optional graph pattern clauses.
 	ItemOptional
+	// ItemFunction represents a call to a registered aggregation function in BQL.
+	ItemFunction
+	// ItemUnion represents the union of graph patterns in BQL.
+	ItemUnion
+	// ItemFilter represents the filter keyword in BQL.
+	ItemFilter
+	// ItemNumber represents a bare numeric constant in BQL expressions.
+	ItemNumber
+	// ItemNEQ represents the not equal operator in BQL.
+	ItemNEQ
+	// ItemLEQ represents the less than or equal operator in BQL.
+	ItemLEQ
+	// ItemGEQ represents the greater than or equal operator in BQL.
+	ItemGEQ
+	// ItemPlus represents the addition operator in BQL.
+	ItemPlus
+	// ItemMinus represents the subtraction operator in BQL.
+	ItemMinus
+	// ItemMul represents the multiplication operator in BQL.
+	ItemMul
+	// ItemDiv represents the division operator in BQL.
+	ItemDiv
+	// ItemOffset represents the offset clause in BQL.
+	ItemOffset
+	// ItemAsk represents the ask keyword in BQL.
+	ItemAsk
+	// ItemDescribe represents the describe keyword in BQL.
+	ItemDescribe
+	// ItemDepth represents the depth keyword in BQL.
+	ItemDepth
+	// ItemDuration represents a time duration constant in BQL such as 24h.
+	ItemDuration
+	// ItemInclusive represents the inclusive time bound modifier in BQL.
+	ItemInclusive
+	// ItemExclusive represents the exclusive time bound modifier in BQL.
+	ItemExclusive
+	// ItemExplain represents the explain keyword in BQL.
+	ItemExplain
+	// ItemPredicates represents the predicates keyword in BQL.
+	ItemPredicates
+	// ItemTriples represents the triples keyword in BQL.
+	ItemTriples
+	// ItemIf represents the if keyword in BQL.
+	ItemIf
+	// ItemExists represents the exists keyword in BQL.
+	ItemExists
+	// ItemBegin represents the begin keyword in BQL.
+	ItemBegin
+	// ItemCommit represents the commit keyword in BQL.
+	ItemCommit
+	// ItemRollback represents the rollback keyword in BQL.
+	ItemRollback
+	// ItemStats represents the stats keyword in BQL.
+	ItemStats
+	// ItemReified represents the reified keyword in BQL.
+	ItemReified
+	// ItemCascade represents the cascade keyword in BQL.
+	ItemCascade
 )
 
 func (tt TokenType) String() string {
@@ -249,6 +312,64 @@ func (tt TokenType) String() string {
 		return "GRAPHS"
 	case ItemOptional:
 		return "OPTIONAL"
+	case ItemFunction:
+		return "FUNCTION"
+	case ItemUnion:
+		return "UNION"
+	case ItemFilter:
+		return "FILTER"
+	case ItemNumber:
+		return "NUMBER"
+	case ItemNEQ:
+		return "NEQ"
+	case ItemLEQ:
+		return "LEQ"
+	case ItemGEQ:
+		return "GEQ"
+	case ItemPlus:
+		return "PLUS"
+	case ItemMinus:
+		return "MINUS"
+	case ItemMul:
+		return "MUL"
+	case ItemDiv:
+		return "DIV"
+	case ItemOffset:
+		return "OFFSET"
+	case ItemAsk:
+		return "ASK"
+	case ItemDescribe:
+		return "DESCRIBE"
+	case ItemDepth:
+		return "DEPTH"
+	case ItemDuration:
+		return "DURATION"
+	case ItemInclusive:
+		return "INCLUSIVE"
+	case ItemExclusive:
+		return "EXCLUSIVE"
+	case ItemExplain:
+		return "EXPLAIN"
+	case ItemPredicates:
+		return "PREDICATES"
+	case ItemTriples:
+		return "TRIPLES"
+	case ItemIf:
+		return "IF"
+	case ItemExists:
+		return "EXISTS"
+	case ItemBegin:
+		return "BEGIN"
+	case ItemCommit:
+		return "COMMIT"
+	case ItemRollback:
+		return "ROLLBACK"
+	case ItemStats:
+		return "STATS"
+	case ItemReified:
+		return "REIFIED"
+	case ItemCascade:
+		return "CASCADE"
 	default:
 		return "UNKNOWN"
 	}
@@ -258,6 +379,7 @@ func (tt TokenType) String() string {
 const (
 	eof            = rune(-1)
 	binding        = rune('?')
+	parameter      = rune('$')
 	leftBracket    = rune('{')
 	rightBracket   = rune('}')
 	leftPar        = rune('(')
@@ -273,10 +395,18 @@ const (
 	lt             = rune('<')
 	gt             = rune('>')
 	eq             = rune('=')
+	bang           = rune('!')
+	plus           = rune('+')
+	minus          = rune('-')
+	star           = rune('*')
 	quote          = rune('"')
 	hat            = rune('^')
 	at             = rune('@')
 	newLine        = rune('\n')
+	hash           = rune('#')
+	lineComment    = "//"
+	blockComment   = "/*"
+	blockEnd       = "*/"
 	query          = "select"
 	insert         = "insert"
 	delete         = "delete"
@@ -290,6 +420,13 @@ const (
 	from           = "from"
 	where          = "where"
 	optional       = "optional"
+	union          = "union"
+	filter         = "filter"
+	neq            = "!="
+	leq            = "<="
+	geq            = ">="
+	andSymbol      = "&&"
+	orSymbol       = "||"
 	as             = "as"
 	before         = "before"
 	after          = "after"
@@ -304,6 +441,7 @@ const (
 	asc            = "asc"
 	desc           = "desc"
 	limit          = "limit"
+	offset         = "offset"
 	not            = "not"
 	and            = "and"
 	or             = "or"
@@ -313,6 +451,23 @@ const (
 	inKeyword      = "in"
 	showKeyword    = "show"
 	graphsKeyword  = "graphs"
+	askKeyword     = "ask"
+	describe       = "describe"
+	depth          = "depth"
+	inclusive      = "inclusive"
+	exclusive      = "exclusive"
+	explain        = "explain"
+	predicates     = "predicates"
+	triples        = "triples"
+	ifKeyword      = "if"
+	exists         = "exists"
+	begin          = "begin"
+	commit         = "commit"
+	rollback       = "rollback"
+	stats          = "stats"
+	prefixKeyword  = "prefix"
+	reified        = "reified"
+	cascade        = "cascade"
 	anchor         = "\"@["
 	literalType    = "\"^^type:"
 	literalBool    = "bool"
@@ -320,13 +475,38 @@ const (
 	literalFloat   = "float64"
 	literalText    = "text"
 	literalBlob    = "blob"
+	literalDecimal = "decimal"
+	literalDate    = "date"
 )
 
+// keywords lists all the BQL keywords.
+var keywords = []string{
+	query, insert, delete, create, construct, deconstruct, drop, graph, data,
+	into, from, where, as, before, after, between, count, distinct, sum, group,
+	by, order, asc, desc, having, limit, offset, not, and, or, id, optional,
+	union, filter, typeKeyword, atKeyword, inKeyword, showKeyword, graphsKeyword,
+	askKeyword, describe, depth, inclusive, exclusive, explain, predicates,
+	triples, ifKeyword, exists, begin, commit, rollback, stats, reified,
+	prefixKeyword, cascade,
+}
+
+// Keywords returns the sorted list of BQL keywords in lower case. Keywords are
+// matched regardless of their case.
+func Keywords() []string {
+	kws := append([]string{}, keywords...)
+	sort.Strings(kws)
+	return kws
+}
+
 // Token contains the type and text collected around the captured token.
 type Token struct {
 	Type         TokenType
 	Text         string
 	ErrorMessage string
+	// Line and Col locate the beginning of the token in the input. Both start
+	// at 1.
+	Line int
+	Col  int
 }
 
 // String returns a readable form of the token.
@@ -340,22 +520,27 @@ type stateFn func(*lexer) stateFn
 
 // lexer holds the state of the scanner.
 type lexer struct {
-	input    string     // the string being scanned.
-	start    int        // start position of this item.
-	pos      int        // current position in the input.
-	width    int        // width of last rune read from input.
-	line     int        // current line number for error reporting.
-	lastLine int        // last line number for error reporting.
-	col      int        // current column number for error reporting.
-	lastCol  int        // last column number for error reporting.
-	tokens   chan Token // channel of scanned items.
+	input     string     // the string being scanned.
+	start     int        // start position of this item.
+	pos       int        // current position in the input.
+	width     int        // width of last rune read from input.
+	line      int        // current line number for error reporting.
+	lastLine  int        // last line number for error reporting.
+	col       int        // current column number for error reporting.
+	lastCol   int        // last column number for error reporting.
+	startLine int        // line number of the start position of this item.
+	startCol  int        // column number of the start position of this item.
+	tokens    chan Token // channel of scanned items.
+	// prefixes maps the labels declared with PREFIX to their IRIs.
+	prefixes map[string]string
 }
 
 // lex creates a new lexer for the given input
 func lex(input string, capacity int) (*lexer, <-chan Token) {
 	l := &lexer{
-		input:  input,
-		tokens: make(chan Token, capacity),
+		input:    input,
+		tokens:   make(chan Token, capacity),
+		prefixes: make(map[string]string),
 	}
 	go l.run() // Concurrently run state machine.
 	return l, l.tokens
@@ -377,20 +562,57 @@ func lexToken(l *lexer) stateFn {
 		{
 			r := l.peek()
 			switch r {
-			case binding:
+			case binding, parameter:
+				// Parameters are bindings whose values are provided at execution
+				// time.
 				l.next()
 				return lexBinding
+			case hash:
+				return lexLineComment
 			case slash:
+				if strings.HasPrefix(l.input[l.pos:], lineComment) {
+					return lexLineComment
+				}
+				if strings.HasPrefix(l.input[l.pos:], blockComment) {
+					return lexBlockComment
+				}
+				if isDivision(l) {
+					l.next()
+					l.emit(ItemDiv)
+					return lexSpace
+				}
 				return lexNode
 			case underscore:
 				l.next()
 				return lexBlankNode
 			case quote:
 				return lexPredicateOrLiteral
+			case lt:
+				if _, ok := leadingIRI(l.input[l.pos:]); ok {
+					return lexIRI
+				}
 			}
 			if unicode.IsLetter(r) {
 				return lexKeyword
 			}
+			if unicode.IsDigit(r) {
+				return lexNumber
+			}
+		}
+		if state := isDoubleSymbolToken(l, ItemNEQ, neq); state != nil {
+			return state
+		}
+		if state := isDoubleSymbolToken(l, ItemLEQ, leq); state != nil {
+			return state
+		}
+		if state := isDoubleSymbolToken(l, ItemGEQ, geq); state != nil {
+			return state
+		}
+		if state := isDoubleSymbolToken(l, ItemAnd, andSymbol); state != nil {
+			return state
+		}
+		if state := isDoubleSymbolToken(l, ItemOr, orSymbol); state != nil {
+			return state
 		}
 		if state := isSingleSymbolToken(l, ItemLBracket, leftBracket); state != nil {
 			return state
@@ -422,6 +644,18 @@ func lexToken(l *lexer) stateFn {
 		if state := isSingleSymbolToken(l, ItemEQ, eq); state != nil {
 			return state
 		}
+		if state := isSingleSymbolToken(l, ItemNot, bang); state != nil {
+			return state
+		}
+		if state := isSingleSymbolToken(l, ItemPlus, plus); state != nil {
+			return state
+		}
+		if state := isSingleSymbolToken(l, ItemMinus, minus); state != nil {
+			return state
+		}
+		if state := isSingleSymbolToken(l, ItemMul, star); state != nil {
+			return state
+		}
 		{
 			r := l.next()
 			if unicode.IsSpace(r) {
@@ -433,10 +667,41 @@ func lexToken(l *lexer) stateFn {
 			}
 		}
 	}
+	// Place the EOF token right after the last character of the input.
+	l.ignore()
+	l.startLine = strings.Count(l.input, string(newLine))
+	l.startCol = utf8.RuneCountInString(l.input[strings.LastIndex(l.input, string(newLine))+1:])
 	l.emit(ItemEOF) // Useful to make EOF a token.
 	return nil      // Stop the run loop.
 }
 
+// lexLineComment skips a comment starting with # or // until the end of the
+// line without emitting any token.
+func lexLineComment(l *lexer) stateFn {
+	for {
+		if r := l.next(); r == newLine || r == eof {
+			break
+		}
+	}
+	l.ignore()
+	return lexSpace
+}
+
+// lexBlockComment skips a comment enclosed between /* and */ without emitting
+// any token. Block comments do not nest.
+func lexBlockComment(l *lexer) stateFn {
+	l.consume(blockComment)
+	for !strings.HasPrefix(l.input[l.pos:], blockEnd) {
+		if l.next() == eof {
+			l.emitError("block comments need to be terminated; missing */")
+			return nil
+		}
+	}
+	l.consume(blockEnd)
+	l.ignore()
+	return lexSpace
+}
+
 // isSingleSymbolToken checks if a single char should be lexed.
 func isSingleSymbolToken(l *lexer, tt TokenType, symbol rune) stateFn {
 	if r := l.peek(); r == symbol {
@@ -447,6 +712,56 @@ func isSingleSymbolToken(l *lexer, tt TokenType, symbol rune) stateFn {
 	return nil
 }
 
+// isDoubleSymbolToken checks if a two char symbol should be lexed.
+func isDoubleSymbolToken(l *lexer, tt TokenType, symbol string) stateFn {
+	if strings.HasPrefix(l.input[l.pos:], symbol) {
+		for range symbol {
+			l.next()
+		}
+		l.emit(tt)
+		return lexSpace // Next state.
+	}
+	return nil
+}
+
+// isDivision returns true if the slash at the current position is a division
+// operator instead of the beginning of a node. Division operators need to be
+// followed by a space, a binding, a parenthesis, or the quote opening the next
+// predicate of a path.
+func isDivision(l *lexer) bool {
+	rest := l.input[l.pos:]
+	if len(rest) < 2 {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(rest[1:])
+	return unicode.IsSpace(r) || r == binding || r == leftPar || r == quote
+}
+
+// lexNumber lexes a bare numeric constant. Numbers are formed by digits and
+// an optional decimal part. Numbers immediately followed by a unit, such as
+// 24h or 1h30m, are lexed as durations.
+func lexNumber(l *lexer) stateFn {
+	digits := func() {
+		for unicode.IsDigit(l.peek()) {
+			l.next()
+		}
+	}
+	digits()
+	if rest := l.input[l.pos:]; len(rest) > 1 && rest[0] == byte(dot) && unicode.IsDigit(rune(rest[1])) {
+		l.next()
+		digits()
+	}
+	if unicode.IsLetter(l.peek()) {
+		for r := l.peek(); unicode.IsLetter(r) || unicode.IsDigit(r) || r == dot; r = l.peek() {
+			l.next()
+		}
+		l.emit(ItemDuration)
+		return lexSpace
+	}
+	l.emit(ItemNumber)
+	return lexSpace
+}
+
 // lexBinding lexes a binding variable.
 func lexBinding(l *lexer) stateFn {
 	for {
@@ -480,6 +795,14 @@ func lexKeyword(l *lexer) stateFn {
 	if idx := strings.IndexFunc(input, f); idx >= 0 {
 		input = input[:idx]
 	}
+	ident := identifier(l.input[l.pos:])
+	if strings.HasPrefix(l.input[l.pos+len(ident):], string(colon)) {
+		return lexPrefixedName
+	}
+	if ident != input {
+		// Keywords are only formed by letters.
+		return lexFunction
+	}
 	if strings.EqualFold(input, query) {
 		consumeKeyword(l, ItemQuery)
 		return lexSpace
@@ -584,6 +907,10 @@ func lexKeyword(l *lexer) stateFn {
 		consumeKeyword(l, ItemLimit)
 		return lexSpace
 	}
+	if strings.EqualFold(input, offset) {
+		consumeKeyword(l, ItemOffset)
+		return lexSpace
+	}
 	if strings.EqualFold(input, not) {
 		consumeKeyword(l, ItemNot)
 		return lexSpace
@@ -604,6 +931,14 @@ func lexKeyword(l *lexer) stateFn {
 		consumeKeyword(l, ItemOptional)
 		return lexSpace
 	}
+	if strings.EqualFold(input, union) {
+		consumeKeyword(l, ItemUnion)
+		return lexSpace
+	}
+	if strings.EqualFold(input, filter) {
+		consumeKeyword(l, ItemFilter)
+		return lexSpace
+	}
 	if strings.EqualFold(input, typeKeyword) {
 		consumeKeyword(l, ItemType)
 		return lexSpace
@@ -624,6 +959,100 @@ func lexKeyword(l *lexer) stateFn {
 		consumeKeyword(l, ItemGraphs)
 		return lexSpace
 	}
+	if strings.EqualFold(input, askKeyword) {
+		consumeKeyword(l, ItemAsk)
+		return lexSpace
+	}
+	if strings.EqualFold(input, describe) {
+		consumeKeyword(l, ItemDescribe)
+		return lexSpace
+	}
+	if strings.EqualFold(input, depth) {
+		consumeKeyword(l, ItemDepth)
+		return lexSpace
+	}
+	if strings.EqualFold(input, inclusive) {
+		consumeKeyword(l, ItemInclusive)
+		return lexSpace
+	}
+	if strings.EqualFold(input, exclusive) {
+		consumeKeyword(l, ItemExclusive)
+		return lexSpace
+	}
+	if strings.EqualFold(input, explain) {
+		consumeKeyword(l, ItemExplain)
+		return lexSpace
+	}
+	if strings.EqualFold(input, predicates) {
+		consumeKeyword(l, ItemPredicates)
+		return lexSpace
+	}
+	if strings.EqualFold(input, triples) {
+		consumeKeyword(l, ItemTriples)
+		return lexSpace
+	}
+	if strings.EqualFold(input, ifKeyword) {
+		consumeKeyword(l, ItemIf)
+		return lexSpace
+	}
+	if strings.EqualFold(input, exists) {
+		consumeKeyword(l, ItemExists)
+		return lexSpace
+	}
+	if strings.EqualFold(input, begin) {
+		consumeKeyword(l, ItemBegin)
+		return lexSpace
+	}
+	if strings.EqualFold(input, commit) {
+		consumeKeyword(l, ItemCommit)
+		return lexSpace
+	}
+	if strings.EqualFold(input, rollback) {
+		consumeKeyword(l, ItemRollback)
+		return lexSpace
+	}
+	if strings.EqualFold(input, stats) {
+		consumeKeyword(l, ItemStats)
+		return lexSpace
+	}
+	if strings.EqualFold(input, reified) {
+		consumeKeyword(l, ItemReified)
+		return lexSpace
+	}
+	if strings.EqualFold(input, cascade) {
+		consumeKeyword(l, ItemCascade)
+		return lexSpace
+	}
+	if strings.EqualFold(input, prefixKeyword) {
+		return lexPrefix
+	}
+	return lexFunction
+}
+
+// identifier returns the leading identifier of the provided input. Identifiers
+// are formed by letters, digits, and underscores.
+func identifier(input string) string {
+	f := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != underscore
+	}
+	if idx := strings.IndexFunc(input, f); idx >= 0 {
+		return input[:idx]
+	}
+	return input
+}
+
+// lexFunction lexes the name of a function call. Function names are
+// identifiers that are not keywords and are followed by a left parenthesis.
+func lexFunction(l *lexer) stateFn {
+	input := l.input[l.pos:]
+	ident := identifier(input)
+	if strings.HasPrefix(strings.TrimLeftFunc(input[len(ident):], unicode.IsSpace), string(leftPar)) {
+		for range ident {
+			l.next()
+		}
+		l.emit(ItemFunction)
+		return lexSpace
+	}
 	for {
 		r := l.next()
 		if unicode.IsSpace(r) || r == eof {
@@ -661,6 +1090,105 @@ func lexNode(l *lexer) stateFn {
 	return lexSpace
 }
 
+// leadingIRI returns the IRI enclosed between < and > at the beginning of the
+// provided input. It returns false if the input does not start with an
+// absolute IRI, so < can still be lexed as the less than operator.
+func leadingIRI(input string) (string, bool) {
+	end := strings.IndexRune(input, gt)
+	if len(input) == 0 || rune(input[0]) != lt || end < 0 {
+		return "", false
+	}
+	iri := input[1:end]
+	if node.ValidateIRI(iri) != nil {
+		return "", false
+	}
+	return iri, true
+}
+
+// lexIRI lexes an IRI written as <iri> into the node of the IRI.
+func lexIRI(l *lexer) stateFn {
+	iri, _ := leadingIRI(l.input[l.pos:])
+	for range "<" + iri + ">" {
+		l.next()
+	}
+	return emitIRI(l, iri)
+}
+
+// emitIRI emits the node of the provided IRI.
+func emitIRI(l *lexer, iri string) stateFn {
+	n, err := node.NewIRI(iri)
+	if err != nil {
+		l.emitError(err.Error())
+		return nil
+	}
+	l.emitText(ItemNode, n.String())
+	return lexSpace
+}
+
+// localName returns the local part of the prefixed name at the beginning of
+// the provided input. Local names are formed by letters, digits, and any of
+// _-.#/%~, but cannot end with a dot, so prefixed names can end a clause.
+func localName(input string) string {
+	f := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_-.#/%~", r)
+	}
+	if idx := strings.IndexFunc(input, f); idx >= 0 {
+		input = input[:idx]
+	}
+	return strings.TrimRight(input, string(dot))
+}
+
+// lexPrefixedName lexes a prefixed name such as foaf:Person into the node of
+// the IRI formed by the IRI declared for the prefix followed by the local name.
+func lexPrefixedName(l *lexer) stateFn {
+	label := identifier(l.input[l.pos:])
+	for range label + string(colon) {
+		l.next()
+	}
+	local := localName(l.input[l.pos:])
+	for range local {
+		l.next()
+	}
+	ns, ok := l.prefixes[label]
+	if !ok {
+		l.emitError(fmt.Sprintf("prefix %q is not declared; declare it first with PREFIX %s: <iri>", label, label))
+		return nil
+	}
+	return emitIRI(l, ns+local)
+}
+
+// lexPrefix lexes a prefix declaration such as PREFIX foaf: <iri>. Prefix
+// declarations do not emit any token; the prefixed names found after them are
+// lexed as the nodes of the IRIs they stand for.
+func lexPrefix(l *lexer) stateFn {
+	l.consume(prefixKeyword)
+	lexSpace(l)
+	label := identifier(l.input[l.pos:])
+	if r, _ := utf8.DecodeRuneInString(label); !unicode.IsLetter(r) {
+		l.emitError("prefix declarations should be followed by a label starting with a letter")
+		return nil
+	}
+	for range label {
+		l.next()
+	}
+	if !l.accept(colon) {
+		l.emitError("prefix labels should end with :")
+		return nil
+	}
+	lexSpace(l)
+	iri, ok := leadingIRI(l.input[l.pos:])
+	if !ok {
+		l.emitError(fmt.Sprintf("prefix %q should be followed by an absolute IRI enclosed in < and >", label))
+		return nil
+	}
+	for range "<" + iri + ">" {
+		l.next()
+	}
+	l.prefixes[label] = iri
+	l.ignore()
+	return lexSpace
+}
+
 // lexBlankNode tries to lex a blank node out of the input
 func lexBlankNode(l *lexer) stateFn {
 	if r := l.next(); r != colon {
@@ -685,7 +1213,15 @@ func lexBlankNode(l *lexer) stateFn {
 func lexPredicateOrLiteral(l *lexer) stateFn {
 	text := l.input[l.pos:]
 	// Fix issue 39 (https://github.com/google/badwolf/issues/39)
-	pIdx, lIdx := strings.Index(text, "\"@["), strings.Index(text, "\"^^type:")
+	if idx := closingQuote(text); idx > 0 {
+		switch rest := text[idx:]; {
+		case strings.HasPrefix(rest, anchor):
+			return lexPredicate
+		case strings.HasPrefix(rest, literalType):
+			return lexLiteral
+		}
+	}
+	pIdx, lIdx := strings.Index(text, anchor), strings.Index(text, literalType)
 	if pIdx < 0 && lIdx < 0 {
 		l.emitError("failed to parse predicate or literal for opening \" delimiter")
 		return nil
@@ -696,15 +1232,30 @@ func lexPredicateOrLiteral(l *lexer) stateFn {
 	return lexLiteral
 }
 
+// closingQuote returns the index of the first quote that is not escaped with
+// a backslash after the opening quote of the provided text, or -1 if there is
+// none.
+func closingQuote(text string) int {
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case byte(backSlash):
+			i++
+		case byte(quote):
+			return i
+		}
+	}
+	return -1
+}
+
 // lexPredicate lexes a predicate out of the input.
 func lexPredicate(l *lexer) stateFn {
 	l.next()
 	for done := false; !done; {
 		switch r := l.next(); r {
 		case backSlash:
-			if nr := l.peek(); nr == quote {
+			// Escaped characters never terminate the predicate or literal.
+			if nr := l.peek(); nr != eof {
 				l.next()
-				continue
 			}
 		case quote:
 			l.backup()
@@ -753,9 +1304,9 @@ func lexLiteral(l *lexer) stateFn {
 	for done := false; !done; {
 		switch r := l.next(); r {
 		case backSlash:
-			if nr := l.peek(); nr == quote {
+			// Escaped characters never terminate the predicate or literal.
+			if nr := l.peek(); nr != eof {
 				l.next()
-				continue
 			}
 		case quote:
 			l.backup()
@@ -773,11 +1324,18 @@ func lexLiteral(l *lexer) stateFn {
 			}
 			literalT = strings.ToLower(literalT)
 			switch literalT {
-			case literalBool, literalInt, literalFloat, literalText, literalBlob:
+			case literalBool, literalInt, literalFloat, literalText, literalBlob, literalDecimal, literalDate:
 				l.backup()
 				l.emit(ItemLiteral)
 				done = true
 			default:
+				if _, ok := literal.TypeByName(literalT); ok {
+					// Types registered by the embedding program.
+					l.backup()
+					l.emit(ItemLiteral)
+					done = true
+					break
+				}
 				l.emitError("invalid literal type " + literalT)
 				return nil
 			}
@@ -810,11 +1368,19 @@ func (l *lexer) run() {
 
 // emit passes an item back to the client.
 func (l *lexer) emit(t TokenType) {
+	l.emitText(t, l.input[l.start:l.pos])
+}
+
+// emitText passes an item with the provided text back to the client instead
+// of the text scanned for it.
+func (l *lexer) emitText(t TokenType, text string) {
 	l.tokens <- Token{
 		Type: t,
-		Text: l.input[l.start:l.pos],
+		Text: text,
+		Line: l.startLine + 1,
+		Col:  l.startCol + 1,
 	}
-	l.start = l.pos
+	l.ignore()
 }
 
 // emitError passes and error to the client with proper error messaging.
@@ -823,13 +1389,16 @@ func (l *lexer) emitError(msg string) {
 		Type:         ItemError,
 		Text:         l.input[l.start:l.pos],
 		ErrorMessage: fmt.Sprintf("[lexer:%d:%d] %s", l.line, l.col, msg),
+		Line:         l.startLine + 1,
+		Col:          l.startCol + 1,
 	}
-	l.start = l.pos
+	l.ignore()
 }
 
 // ignore skips over the pending input before this point.
 func (l *lexer) ignore() {
 	l.start = l.pos
+	l.startLine, l.startCol = l.line, l.col
 }
 
 // backup steps back one rune. Can be called only once per call of next.
@@ -880,3 +1449,51 @@ func (l *lexer) consume(text string) bool {
 	}
 	return true
 }
+
+// Unescape replaces the escape sequences found in the text of a literal or
+// predicate with the characters they represent. Supported sequences are \",
+// \\, \n, \r, \t, \uXXXX, and \UXXXXXXXX. Backslashes followed by any other
+// character are kept as they are.
+func Unescape(s string) (string, error) {
+	if !strings.ContainsRune(s, backSlash) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != byte(backSlash) || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch c := s[i+1]; c {
+		case '"', '\\':
+			b.WriteByte(c)
+			i++
+		case 'n':
+			b.WriteByte('\n')
+			i++
+		case 'r':
+			b.WriteByte('\r')
+			i++
+		case 't':
+			b.WriteByte('\t')
+			i++
+		case 'u', 'U':
+			n := 4
+			if c == 'U' {
+				n = 8
+			}
+			if i+2+n > len(s) {
+				return "", fmt.Errorf("invalid unicode escape sequence %q", s[i:])
+			}
+			v, err := strconv.ParseUint(s[i+2:i+2+n], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(v)) {
+				return "", fmt.Errorf("invalid unicode escape sequence %q", s[i:i+2+n])
+			}
+			b.WriteRune(rune(v))
+			i += 1 + n
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -14,7 +14,26 @@
 
 package lexer
 
-import "testing"
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/triple/literal"
+)
+
+func init() {
+	// A custom literal type, to check that the lexer accepts registered
+	// types.
+	_, err := literal.Register(literal.CustomType{
+		Name:   "point",
+		Parse:  func(s string) (interface{}, error) { return s, nil },
+		Format: func(v interface{}) string { return v.(string) },
+	})
+	if err != nil {
+		panic(err)
+	}
+}
 
 func TestTokenTypeString(t *testing.T) {
 	table := []struct {
@@ -75,6 +94,35 @@ func TestTokenTypeString(t *testing.T) {
 		{ItemShow, "SHOW"},
 		{ItemGraphs, "GRAPHS"},
 		{ItemOptional, "OPTIONAL"},
+		{ItemFunction, "FUNCTION"},
+		{ItemUnion, "UNION"},
+		{ItemFilter, "FILTER"},
+		{ItemNumber, "NUMBER"},
+		{ItemNEQ, "NEQ"},
+		{ItemLEQ, "LEQ"},
+		{ItemGEQ, "GEQ"},
+		{ItemPlus, "PLUS"},
+		{ItemMinus, "MINUS"},
+		{ItemMul, "MUL"},
+		{ItemDiv, "DIV"},
+		{ItemOffset, "OFFSET"},
+		{ItemAsk, "ASK"},
+		{ItemDescribe, "DESCRIBE"},
+		{ItemDepth, "DEPTH"},
+		{ItemDuration, "DURATION"},
+		{ItemInclusive, "INCLUSIVE"},
+		{ItemExclusive, "EXCLUSIVE"},
+		{ItemExplain, "EXPLAIN"},
+		{ItemPredicates, "PREDICATES"},
+		{ItemTriples, "TRIPLES"},
+		{ItemIf, "IF"},
+		{ItemExists, "EXISTS"},
+		{ItemBegin, "BEGIN"},
+		{ItemCommit, "COMMIT"},
+		{ItemRollback, "ROLLBACK"},
+		{ItemStats, "STATS"},
+		{ItemReified, "REIFIED"},
+		{ItemCascade, "CASCADE"},
 		{TokenType(-1), "UNKNOWN"},
 	}
 
@@ -102,9 +150,64 @@ func TestIndividualTokens(t *testing.T) {
 				{Type: ItemDot, Text: "."},
 				{Type: ItemSemicolon, Text: ";"},
 				{Type: ItemComma, Text: ","},
+				{Type: ItemLT, Text: "<"},
+				{Type: ItemGEQ, Text: ">="},
+				{Type: ItemEOF}}},
+		{"< > = != <= >= && || ! + - * / ?a/?b",
+			[]Token{
 				{Type: ItemLT, Text: "<"},
 				{Type: ItemGT, Text: ">"},
 				{Type: ItemEQ, Text: "="},
+				{Type: ItemNEQ, Text: "!="},
+				{Type: ItemLEQ, Text: "<="},
+				{Type: ItemGEQ, Text: ">="},
+				{Type: ItemAnd, Text: "&&"},
+				{Type: ItemOr, Text: "||"},
+				{Type: ItemNot, Text: "!"},
+				{Type: ItemPlus, Text: "+"},
+				{Type: ItemMinus, Text: "-"},
+				{Type: ItemMul, Text: "*"},
+				{Type: ItemDiv, Text: "/"},
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemDiv, Text: "/"},
+				{Type: ItemBinding, Text: "?b"},
+				{Type: ItemEOF}}},
+		{`"knows"@[]+/"name"@[]* /u<joe>`,
+			[]Token{
+				{Type: ItemPredicate, Text: `"knows"@[]`},
+				{Type: ItemPlus, Text: "+"},
+				{Type: ItemDiv, Text: "/"},
+				{Type: ItemPredicate, Text: `"name"@[]`},
+				{Type: ItemMul, Text: "*"},
+				{Type: ItemNode, Text: "/u<joe>"},
+				{Type: ItemEOF}}},
+		{"NOW() - 24h + 1h30m 1.5s",
+			[]Token{
+				{Type: ItemFunction, Text: "NOW"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemMinus, Text: "-"},
+				{Type: ItemDuration, Text: "24h"},
+				{Type: ItemPlus, Text: "+"},
+				{Type: ItemDuration, Text: "1h30m"},
+				{Type: ItemDuration, Text: "1.5s"},
+				{Type: ItemEOF}}},
+		{"10 3.14 7. 1.x",
+			[]Token{
+				{Type: ItemNumber, Text: "10"},
+				{Type: ItemNumber, Text: "3.14"},
+				{Type: ItemNumber, Text: "7"},
+				{Type: ItemDot, Text: "."},
+				{Type: ItemNumber, Text: "1"},
+				{Type: ItemDot, Text: "."},
+				{Type: ItemError, Text: "x",
+					ErrorMessage: "[lexer:0:13] found unknown keyword"},
+				{Type: ItemEOF}}},
+		{"$foo ?foo $foo_bar",
+			[]Token{
+				{Type: ItemBinding, Text: "$foo"},
+				{Type: ItemBinding, Text: "?foo"},
+				{Type: ItemBinding, Text: "$foo_bar"},
 				{Type: ItemEOF}}},
 		{"?foo ?bar ?1234 ?foo_bar ?bar_foo",
 			[]Token{
@@ -116,7 +219,9 @@ func TestIndividualTokens(t *testing.T) {
 				{Type: ItemEOF}}},
 		{`SeLeCt FrOm WhErE As BeFoRe AfTeR BeTwEeN CoUnT SuM GrOuP bY HaViNg LiMiT
 		  OrDeR AsC DeSc NoT AnD Or Id TyPe At DiStInCt InSeRt DeLeTe DaTa InTo
-		  cONsTruCT CrEaTe DrOp GrApH OpTiOnAl`,
+		  cONsTruCT CrEaTe DrOp GrApH OpTiOnAl UnIoN FiLtEr OfFsEt aSk DeScRiBe DePtH
+		  InClUsIvE eXcLuSiVe ExPlAiN PrEdIcAtEs TrIpLeS iF ExIsTs
+		  BeGiN CoMmIt RoLlBaCk StAtS ReIfIeD CaScAdE`,
 			[]Token{
 				{Type: ItemQuery, Text: "SeLeCt"},
 				{Type: ItemFrom, Text: "FrOm"},
@@ -150,6 +255,49 @@ func TestIndividualTokens(t *testing.T) {
 				{Type: ItemDrop, Text: "DrOp"},
 				{Type: ItemGraph, Text: "GrApH"},
 				{Type: ItemOptional, Text: "OpTiOnAl"},
+				{Type: ItemUnion, Text: "UnIoN"},
+				{Type: ItemFilter, Text: "FiLtEr"},
+				{Type: ItemOffset, Text: "OfFsEt"},
+				{Type: ItemAsk, Text: "aSk"},
+				{Type: ItemDescribe, Text: "DeScRiBe"},
+				{Type: ItemDepth, Text: "DePtH"},
+				{Type: ItemInclusive, Text: "InClUsIvE"},
+				{Type: ItemExclusive, Text: "eXcLuSiVe"},
+				{Type: ItemExplain, Text: "ExPlAiN"},
+				{Type: ItemPredicates, Text: "PrEdIcAtEs"},
+				{Type: ItemTriples, Text: "TrIpLeS"},
+				{Type: ItemIf, Text: "iF"},
+				{Type: ItemExists, Text: "ExIsTs"},
+				{Type: ItemBegin, Text: "BeGiN"},
+				{Type: ItemCommit, Text: "CoMmIt"},
+				{Type: ItemRollback, Text: "RoLlBaCk"},
+				{Type: ItemStats, Text: "StAtS"},
+				{Type: ItemReified, Text: "ReIfIeD"},
+				{Type: ItemCascade, Text: "CaScAdE"},
+				{Type: ItemEOF}}},
+		{"median(?foo) top_k (?bar) sum_of(?x) count(?y)",
+			[]Token{
+				{Type: ItemFunction, Text: "median"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?foo"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemFunction, Text: "top_k"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?bar"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemFunction, Text: "sum_of"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?x"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemCount, Text: "count"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?y"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemEOF}}},
+		{"median ?foo",
+			[]Token{
+				{Type: ItemError, Text: "median",
+					ErrorMessage: "[lexer:0:6] found unknown keyword"},
 				{Type: ItemEOF}}},
 		{"/_<foo>/_<bar>",
 			[]Token{
@@ -204,6 +352,18 @@ func TestIndividualTokens(t *testing.T) {
 			[]Token{
 				{Type: ItemLiteral, Text: `"[1 2 3 4]"^^type:blob`},
 				{Type: ItemEOF}}},
+		{`"-12.50"^^type:decimal`,
+			[]Token{
+				{Type: ItemLiteral, Text: `"-12.50"^^type:decimal`},
+				{Type: ItemEOF}}},
+		{`"2016-04-10"^^type:date`,
+			[]Token{
+				{Type: ItemLiteral, Text: `"2016-04-10"^^type:date`},
+				{Type: ItemEOF}}},
+		{`"(1, 2)"^^type:Point`,
+			[]Token{
+				{Type: ItemLiteral, Text: `"(1, 2)"^^type:Point`},
+				{Type: ItemEOF}}},
 		{"\"1\"^type:int64",
 			[]Token{
 				{Type: ItemError,
@@ -255,6 +415,47 @@ func TestIndividualTokens(t *testing.T) {
 			[]Token{
 				{Type: ItemLiteral, Text: `"Hallway\"1\""^^type:text`},
 				{Type: ItemEOF}}},
+		{`"a\"@[]\\"^^type:text "b\\"@[] "\u00e9"^^type:text`,
+			[]Token{
+				{Type: ItemLiteral, Text: `"a\"@[]\\"^^type:text`},
+				{Type: ItemPredicate, Text: `"b\\"@[]`},
+				{Type: ItemLiteral, Text: `"\u00e9"^^type:text`},
+				{Type: ItemEOF}}},
+		{"# A comment.\n?foo // Another comment.\n/* A\nblock */ ?bar /**/;#",
+			[]Token{
+				{Type: ItemBinding, Text: "?foo"},
+				{Type: ItemBinding, Text: "?bar"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF}}},
+		{"PREFIX foaf: <http://xmlns.com/foaf/0.1/> prefix ex:<urn:ex:> foaf:Person <http://example.com/a#b> ex:a.b. ?a<?b",
+			[]Token{
+				{Type: ItemNode, Text: "/iri<http://xmlns.com/foaf/0.1/Person>"},
+				{Type: ItemNode, Text: "/iri<http://example.com/a#b>"},
+				{Type: ItemNode, Text: "/iri<urn:ex:a.b>"},
+				{Type: ItemDot, Text: "."},
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemLT, Text: "<"},
+				{Type: ItemBinding, Text: "?b"},
+				{Type: ItemEOF}}},
+		{"foaf:Person",
+			[]Token{
+				{Type: ItemError,
+					Text:         "foaf:Person",
+					ErrorMessage: "[lexer:0:11] prefix \"foaf\" is not declared; declare it first with PREFIX foaf: <iri>"},
+				{Type: ItemEOF}}},
+		{"PREFIX foaf: <foaf>",
+			[]Token{
+				{Type: ItemError,
+					Text:         "",
+					ErrorMessage: "[lexer:0:13] prefix \"foaf\" should be followed by an absolute IRI enclosed in < and >"},
+				{Type: ItemEOF}}},
+		{"?foo /* unterminated",
+			[]Token{
+				{Type: ItemBinding, Text: "?foo"},
+				{Type: ItemError,
+					Text:         "/* unterminated",
+					ErrorMessage: "[lexer:0:20] block comments need to be terminated; missing */"},
+				{Type: ItemEOF}}},
 	}
 
 	for _, test := range table {
@@ -264,6 +465,8 @@ func TestIndividualTokens(t *testing.T) {
 			if idx >= len(test.tokens) {
 				t.Fatalf("lex(%q) has not finished producing tokens when it should have.", test.input)
 			}
+			// Token positions are checked in TestTokenPositions.
+			got.Line, got.Col = 0, 0
 			if want := test.tokens[idx]; got != want {
 				t.Errorf("lex(%q) failed to provide %+v, got %+v instead", test.input, want, got)
 			}
@@ -328,10 +531,84 @@ func TestValidTokenQuery(t *testing.T) {
 				t.Fatalf("lex(%q) has not finished producing tokens when it should have.", test.input)
 			}
 			if want := test.tokens[idx]; got.Type != want {
-				t.Errorf("lex(%q) failed to provide token %s; got %s instead", test.input, got, want)
+				t.Errorf("lex(%q) failed to provide token %s; got %s instead", test.input, want, got.Type)
 			}
 			idx++
 		}
 	}
 
 }
+
+func TestUnescape(t *testing.T) {
+	table := []struct {
+		in, want string
+		fail     bool
+	}{
+		{in: `plain`, want: `plain`},
+		{in: `say \"hi\"`, want: `say "hi"`},
+		{in: `a\\b`, want: `a\b`},
+		{in: `a\nb\tc\rd`, want: "a\nb\tc\rd"},
+		{in: `caf\u00e9 \U0001F600`, want: "caf\u00e9 \U0001F600"},
+		{in: `C:\path\`, want: `C:\path\`},
+		{in: `\u00`, fail: true},
+		{in: `\uzzzz`, fail: true},
+		{in: `\UFFFFFFFF`, fail: true},
+	}
+	for _, entry := range table {
+		got, err := Unescape(entry.in)
+		if entry.fail {
+			if err == nil {
+				t.Errorf("Unescape(%q) should have failed; got %q", entry.in, got)
+			}
+			continue
+		}
+		if err != nil || got != entry.want {
+			t.Errorf("Unescape(%q) = %q, %v; want %q, nil", entry.in, got, err, entry.want)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "select ?s\n  from ?g # comment\n\twhere {?s \"p\"@[] /u<x>};"
+	want := []Token{
+		{Type: ItemQuery, Text: "select", Line: 1, Col: 1},
+		{Type: ItemBinding, Text: "?s", Line: 1, Col: 8},
+		{Type: ItemFrom, Text: "from", Line: 2, Col: 3},
+		{Type: ItemBinding, Text: "?g", Line: 2, Col: 8},
+		{Type: ItemWhere, Text: "where", Line: 3, Col: 2},
+		{Type: ItemLBracket, Text: "{", Line: 3, Col: 8},
+		{Type: ItemBinding, Text: "?s", Line: 3, Col: 9},
+		{Type: ItemPredicate, Text: `"p"@[]`, Line: 3, Col: 12},
+		{Type: ItemNode, Text: "/u<x>", Line: 3, Col: 19},
+		{Type: ItemRBracket, Text: "}", Line: 3, Col: 24},
+		{Type: ItemSemicolon, Text: ";", Line: 3, Col: 25},
+		{Type: ItemEOF, Line: 3, Col: 26},
+	}
+	idx := 0
+	for got := range New(input, 0) {
+		if idx >= len(want) {
+			t.Fatalf("New(%q) has not finished producing tokens when it should have.", input)
+		}
+		if got != want[idx] {
+			t.Errorf("New(%q) failed to provide %+v, got %+v instead", input, want[idx], got)
+		}
+		idx++
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	kws := Keywords()
+	if !sort.StringsAreSorted(kws) {
+		t.Errorf("Keywords() = %v; want a sorted list", kws)
+	}
+	for _, kw := range kws {
+		for _, in := range []string{kw, strings.ToUpper(kw)} {
+			// Prefix declarations require a label after the keyword, and do
+			// not produce any token.
+			_, c := lex(in+" ex: <http://example.com/>", 1)
+			if tkn := <-c; tkn.Type == ItemError || tkn.Type != ItemEOF && tkn.Text != in {
+				t.Errorf("Keywords() returned %q, but lex(%q) returned %v", kw, in, tkn)
+			}
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/bql/table"
+)
+
+// Options contains the limits enforced while executing a plan and how its
+// work is distributed. The zero value does not enforce any limit.
+type Options struct {
+	// MaxExecutionTime is the maximum time a plan can run before it is
+	// aborted.
+	MaxExecutionTime time.Duration
+
+	// MaxIntermediateRows is the maximum number of rows the tables built
+	// while solving the graph pattern of a statement can hold.
+	MaxIntermediateRows int
+
+	// Parallelism is the maximum number of graph pattern clauses fetched
+	// concurrently. Only consecutive clauses that do not share bindings are
+	// fetched concurrently. Values lower than 2 fetch one clause at a time.
+	Parallelism int
+
+	// ResultCache, if set, caches the results of query statements. Repeated
+	// queries are answered from the cache while the graphs they query do not
+	// change.
+	ResultCache *ResultCache
+
+	// Hooks contains the callbacks notified while query plans run.
+	Hooks Hooks
+
+	// MaxMemory is the maximum number of bytes the tables built while
+	// solving the graph pattern of a statement can use. The memory used by
+	// tables is estimated out of their number of rows and bindings.
+	MaxMemory int64
+
+	// Memory, if set, accounts for the memory used by all the plans sharing
+	// it and enforces its global limit.
+	Memory *MemoryAccountant
+}
+
+// ErrQueryBudgetExceeded is returned when the execution of a plan exceeds one
+// of the limits set in its options. Only the exceeded limit is set.
+type ErrQueryBudgetExceeded struct {
+	MaxExecutionTime    time.Duration
+	MaxIntermediateRows int
+	MaxMemory           int64
+	// MaxGlobalMemory is set if the memory accountant shared by the running
+	// plans ran out of memory.
+	MaxGlobalMemory int64
+}
+
+// Error returns a description of the exceeded limit.
+func (e *ErrQueryBudgetExceeded) Error() string {
+	if e.MaxMemory > 0 {
+		return fmt.Sprintf("planner: query budget exceeded; intermediate results need more than %d bytes", e.MaxMemory)
+	}
+	if e.MaxGlobalMemory > 0 {
+		return fmt.Sprintf("planner: memory budget exceeded; running queries need more than %d bytes", e.MaxGlobalMemory)
+	}
+	if e.MaxIntermediateRows > 0 {
+		return fmt.Sprintf("planner: query budget exceeded; intermediate results are larger than %d rows", e.MaxIntermediateRows)
+	}
+	return fmt.Sprintf("planner: query budget exceeded; execution took longer than %v", e.MaxExecutionTime)
+}
+
+// budgetPlan aborts the execution of the wrapped plan if it runs for longer
+// than the allowed time.
+type budgetPlan struct {
+	plan             Executor
+	maxExecutionTime time.Duration
+}
+
+// Type returns the type of the wrapped plan.
+func (p *budgetPlan) Type() string {
+	return p.plan.Type()
+}
+
+// Execute runs the wrapped plan. It returns ErrQueryBudgetExceeded if the
+// plan does not finish in time.
+func (p *budgetPlan) Execute(ctx context.Context) (*table.Table, error) {
+	tctx, cancel := context.WithTimeout(ctx, p.maxExecutionTime)
+	defer cancel()
+	tbl, err := p.plan.Execute(tctx)
+	if err != nil && ctx.Err() == nil && tctx.Err() == context.DeadlineExceeded {
+		return nil, &ErrQueryBudgetExceeded{MaxExecutionTime: p.maxExecutionTime}
+	}
+	return tbl, err
+}
+
+// String returns the description of the wrapped plan.
+func (p *budgetPlan) String(ctx context.Context) string {
+	return p.plan.String(ctx)
+}
+
+// checkRows returns ErrQueryBudgetExceeded if the provided number of rows is
+// larger than allowed.
+func (p *queryPlan) checkRows(n int) error {
+	if p.maxRows > 0 && n > p.maxRows {
+		return &ErrQueryBudgetExceeded{MaxIntermediateRows: p.maxRows}
+	}
+	return nil
+}
+
+// setOptions configures the query plan using the provided options.
+func (p *queryPlan) setOptions(opts Options) {
+	p.maxRows, p.workers, p.hooks = opts.MaxIntermediateRows, opts.Parallelism, opts.Hooks
+	p.maxMemory, p.memory = opts.MaxMemory, opts.Memory
+}
+
+// Estimated memory used by tables. Rows are maps whose entries point to
+// cells, which in turn point to the values they hold.
+const (
+	rowBytes  = 48
+	cellBytes = 96
+)
+
+// estimateTableBytes returns the estimated number of bytes used by a table
+// with the provided number of rows and bindings.
+func estimateTableBytes(rows, bindings int64) int64 {
+	return rows * (rowBytes + bindings*cellBytes)
+}
+
+// MemoryAccountant keeps track of the memory used by the intermediate tables
+// of all the plans sharing it. Plans reserve memory as their tables grow and
+// release it once they finish. A plan that cannot reserve the memory it needs
+// fails with ErrQueryBudgetExceeded instead of exhausting the memory of the
+// process. It is safe for concurrent use.
+type MemoryAccountant struct {
+	mu    sync.Mutex
+	limit int64
+	used  int64
+}
+
+// NewMemoryAccountant returns an accountant that allows up to the provided
+// number of bytes to be reserved at the same time.
+func NewMemoryAccountant(limit int64) *MemoryAccountant {
+	return &MemoryAccountant{limit: limit}
+}
+
+// InUse returns the number of bytes currently reserved.
+func (a *MemoryAccountant) InUse() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.used
+}
+
+// reserve reserves the provided number of bytes. It returns false if they do
+// not fit in the limit.
+func (a *MemoryAccountant) reserve(n int64) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.used+n > a.limit {
+		return false
+	}
+	a.used += n
+	return true
+}
+
+// release returns the provided number of bytes to the accountant.
+func (a *MemoryAccountant) release(n int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.used -= n
+}
+
+// checkTable returns ErrQueryBudgetExceeded if the provided table has more
+// rows than allowed or does not fit in the memory budgets.
+func (p *queryPlan) checkTable(t *table.Table) error {
+	if err := p.checkRows(t.NumRows()); err != nil {
+		return err
+	}
+	return p.checkMemory(int64(t.NumRows()), int64(len(t.Bindings())))
+}
+
+// checkGrowth returns ErrQueryBudgetExceeded if the table of the plan does not
+// fit in the budgets once the provided number of rows are added to it.
+func (p *queryPlan) checkGrowth(n int) error {
+	rows := p.tbl.NumRows() + n
+	if err := p.checkRows(rows); err != nil {
+		return err
+	}
+	return p.checkMemory(int64(rows), int64(len(p.tbl.Bindings())))
+}
+
+// checkMemory returns ErrQueryBudgetExceeded if a table with the provided
+// number of rows and bindings does not fit in the memory budgets. The plan
+// keeps the memory reserved for its largest table until it finishes.
+func (p *queryPlan) checkMemory(rows, bindings int64) error {
+	b := estimateTableBytes(rows, bindings)
+	p.budgetMu.Lock()
+	defer p.budgetMu.Unlock()
+	if p.maxMemory > 0 && b > p.maxMemory {
+		return &ErrQueryBudgetExceeded{MaxMemory: p.maxMemory}
+	}
+	if p.memory == nil || b <= p.reserved {
+		return nil
+	}
+	if !p.memory.reserve(b - p.reserved) {
+		return &ErrQueryBudgetExceeded{MaxGlobalMemory: p.memory.limit}
+	}
+	p.reserved = b
+	return nil
+}
+
+// releaseMemory returns the memory reserved by the plan.
+func (p *queryPlan) releaseMemory() {
+	p.budgetMu.Lock()
+	defer p.budgetMu.Unlock()
+	if p.memory != nil && p.reserved > 0 {
+		p.memory.release(p.reserved)
+	}
+	p.reserved = 0
+}
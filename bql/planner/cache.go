@@ -0,0 +1,223 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+)
+
+// ResultCache is a least recently used cache of query results. Results are
+// indexed by the normalized plan of the query and are only returned while the
+// epochs of all the graphs queried stay the same. Queries on graphs that do
+// not implement storage.EpochProvider are never cached. A cache can be shared
+// by any number of plans running concurrently, as long as all of them query
+// the same store.
+type ResultCache struct {
+	mu      sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+	hits    int64
+	misses  int64
+}
+
+// cacheEntry contains a cached result and the epochs of the graphs it was
+// computed from.
+type cacheEntry struct {
+	key    string
+	epochs []uint64
+	tbl    *table.Table
+}
+
+// NewResultCache returns a new cache that holds up to the provided number of
+// results.
+func NewResultCache(size int) *ResultCache {
+	return &ResultCache{
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Len returns the number of results in the cache.
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns the number of lookups that were answered by the cache and
+// the number of lookups that were not.
+func (c *ResultCache) Stats() (hits, misses int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Purge removes all the results from the cache.
+func (c *ResultCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// get returns a copy of the result cached for the provided key if it was
+// computed out of graphs with the same epochs. Stale results are removed.
+func (c *ResultCache) get(key string, epochs []uint64) (*table.Table, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	ce := e.Value.(*cacheEntry)
+	if !equalEpochs(ce.epochs, epochs) {
+		c.lru.Remove(e)
+		delete(c.entries, key)
+		c.misses++
+		return nil, false
+	}
+	tbl, err := copyTable(ce.tbl)
+	if err != nil {
+		c.misses++
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	c.hits++
+	return tbl, true
+}
+
+// add caches a copy of the provided result, evicting the least recently used
+// results if the cache is full.
+func (c *ResultCache) add(key string, epochs []uint64, tbl *table.Table) {
+	if c.size <= 0 {
+		return
+	}
+	ctbl, err := copyTable(tbl)
+	if err != nil {
+		return
+	}
+	ce := &cacheEntry{key: key, epochs: epochs, tbl: ctbl}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value = ce
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(ce)
+	for c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*cacheEntry).key)
+	}
+}
+
+// equalEpochs returns true if both lists contain the same epochs.
+func equalEpochs(e1, e2 []uint64) bool {
+	if len(e1) != len(e2) {
+		return false
+	}
+	for i := range e1 {
+		if e1[i] != e2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// copyTable returns a copy of the provided table. Rows are copied so changes
+// to the returned table do not alter the cached one. Cells are shared since
+// they are never modified.
+func copyTable(t *table.Table) (*table.Table, error) {
+	res, err := table.New(t.Bindings())
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range t.Rows() {
+		nr := make(table.Row, len(r))
+		for k, v := range r {
+			nr[k] = v
+		}
+		res.AddRow(nr)
+	}
+	return res, nil
+}
+
+// cacheKey returns the key identifying the results of the plan and the
+// epochs of all the graphs it queries, including the ones of its subqueries.
+// It returns false if the results of the plan cannot be cached. The plan
+// graphs need to be already initialized.
+func (p *queryPlan) cacheKey(ctx context.Context) (string, []uint64, bool) {
+	var epochs []uint64
+	for _, g := range p.grfs {
+		e, err := storage.Epoch(ctx, g)
+		if err != nil {
+			return "", nil, false
+		}
+		epochs = append(epochs, e)
+	}
+	sqs := append([]*semantic.Statement{}, p.stm.Subqueries()...)
+	for _, usqs := range p.stm.GraphPatternUnionSubqueries() {
+		sqs = append(sqs, usqs...)
+	}
+	for len(sqs) > 0 {
+		sq := sqs[0]
+		sqs = sqs[1:]
+		for _, n := range sq.InputGraphNames() {
+			g, err := p.store.Graph(ctx, n)
+			if err != nil {
+				return "", nil, false
+			}
+			e, err := storage.Epoch(ctx, g)
+			if err != nil {
+				return "", nil, false
+			}
+			epochs = append(epochs, e)
+		}
+		sqs = append(sqs, sq.Subqueries()...)
+		for _, usqs := range sq.GraphPatternUnionSubqueries() {
+			sqs = append(sqs, usqs...)
+		}
+	}
+	// The plan description normalizes the query text. The global lookup
+	// options and the parameter values are not part of it.
+	var b bytes.Buffer
+	b.WriteString(p.String(ctx))
+	b.WriteString(p.stm.GlobalLookupOptions().String())
+	params := p.stm.ParameterValues()
+	var ks []string
+	for k := range params {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	for _, k := range ks {
+		b.WriteString("\n")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(params[k].String())
+	}
+	return b.String(), epochs, true
+}
@@ -0,0 +1,170 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/badwolf/bql/planner/tracer"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+)
+
+// statistics returns the merged statistics of all the input graphs. It
+// returns nil if any of the graphs does not provide them.
+func (p *queryPlan) statistics(ctx context.Context) *storage.GraphStatistics {
+	if len(p.grfs) == 0 {
+		return nil
+	}
+	res := &storage.GraphStatistics{}
+	for _, g := range p.grfs {
+		s, err := storage.Statistics(ctx, g)
+		if err != nil {
+			return nil
+		}
+		res.Merge(s)
+	}
+	return res
+}
+
+// orderGraphPattern reorders the clauses of the graph pattern and of each of
+// its unions by their estimated cardinality. The graph pattern is left as is
+// if the input graphs do not provide statistics.
+func (p *queryPlan) orderGraphPattern(ctx context.Context) {
+	s := p.statistics(ctx)
+	if s == nil {
+		return
+	}
+	p.cls = orderClauses(p.cls, p.stm.ParameterValues(), s)
+	var us [][]*semantic.GraphClause
+	for _, u := range p.unions {
+		us = append(us, orderClauses(u, p.stm.ParameterValues(), s))
+	}
+	p.unions = us
+	tracer.Trace(p.tracer, func() []string {
+		var res []string
+		for i, cls := range p.cls {
+			res = append(res, fmt.Sprintf("Clause %d ordered by estimated cardinality: %v", i, cls))
+		}
+		return res
+	})
+}
+
+// orderClauses returns the clauses sorted so the ones expected to match fewer
+// triples run first. Clauses are picked greedily: among the clauses sharing a
+// binding with the ones already picked, the one with the lowest estimated
+// cardinality goes next, which avoids cross products whenever possible. The
+// bindings of the provided parameters are bound from the start. Optional
+// clauses depend on the clauses before them, so they keep their position and
+// only the clauses between them are reordered.
+func orderClauses(cls []*semantic.GraphClause, params table.Row, s *storage.GraphStatistics) []*semantic.GraphClause {
+	bound := make(map[string]bool)
+	for k := range params {
+		bound[k] = true
+	}
+	res := make([]*semantic.GraphClause, 0, len(cls))
+	for i := 0; i < len(cls); {
+		if cls[i].Optional {
+			res = append(res, cls[i])
+			for _, b := range cls[i].Bindings() {
+				bound[b] = true
+			}
+			i++
+			continue
+		}
+		j := i
+		for j < len(cls) && !cls[j].Optional {
+			j++
+		}
+		res = append(res, orderRun(cls[i:j], bound, s)...)
+		i = j
+	}
+	return res
+}
+
+// orderRun greedily sorts a run of non optional clauses. The bindings of the
+// sorted clauses are added to the provided bound set.
+func orderRun(cls []*semantic.GraphClause, bound map[string]bool, s *storage.GraphStatistics) []*semantic.GraphClause {
+	left := append([]*semantic.GraphClause{}, cls...)
+	res := make([]*semantic.GraphClause, 0, len(cls))
+	for len(left) > 0 {
+		best, connected := -1, false
+		var bestEst float64
+		for i, c := range left {
+			cnt := isConnected(c, bound)
+			if connected && !cnt {
+				continue
+			}
+			est := estimateClause(c, bound, s)
+			// Ties are broken by textual order.
+			if best < 0 || (cnt && !connected) || est < bestEst {
+				best, bestEst, connected = i, est, cnt
+			}
+		}
+		c := left[best]
+		res = append(res, c)
+		for _, b := range c.Bindings() {
+			bound[b] = true
+		}
+		left = append(left[:best], left[best+1:]...)
+	}
+	return res
+}
+
+// isConnected returns true if the clause uses any of the bound bindings.
+func isConnected(c *semantic.GraphClause, bound map[string]bool) bool {
+	for _, b := range c.Bindings() {
+		if bound[b] {
+			return true
+		}
+	}
+	return false
+}
+
+// estimateClause returns the estimated number of triples the clause will
+// match for each row of the bindings already bound. Subjects, predicates, and
+// objects are considered fixed if they are provided in the clause or their
+// bindings are already bound. A fixed subject is expected to have as many
+// triples as the median subject degree, and fixed objects are assumed to be
+// uniformly distributed.
+func estimateClause(c *semantic.GraphClause, bound map[string]bool, s *storage.GraphStatistics) float64 {
+	if s.Triples == 0 {
+		return 0
+	}
+	est := float64(s.Triples)
+	switch {
+	case c.HasPath():
+		// Paths may traverse any number of predicates.
+	case c.P != nil:
+		est = float64(s.Predicates[string(c.P.ID())])
+	case c.PID != "":
+		est = float64(s.Predicates[c.PID])
+	case c.PBinding != "" && bound[c.PBinding] && len(s.Predicates) > 0:
+		est /= float64(len(s.Predicates))
+	}
+	if c.S != nil || (c.SBinding != "" && bound[c.SBinding]) {
+		d := s.MedianSubjectDegree()
+		if d < 1 {
+			d = 1
+		}
+		est = est * float64(d) / float64(s.Triples)
+	}
+	if (c.O != nil || (c.OBinding != "" && bound[c.OBinding])) && s.Objects > 0 {
+		est /= float64(s.Objects)
+	}
+	return est
+}
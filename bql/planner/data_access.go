@@ -34,9 +34,10 @@ import (
 // provided graph clause.
 func updateTimeBounds(lo *storage.LookupOptions, cls *semantic.GraphClause) *storage.LookupOptions {
 	nlo := &storage.LookupOptions{
-		MaxElements: lo.MaxElements,
-		LowerAnchor: lo.LowerAnchor,
-		UpperAnchor: lo.UpperAnchor,
+		MaxElements:   lo.MaxElements,
+		LowerAnchor:   lo.LowerAnchor,
+		UpperAnchor:   lo.UpperAnchor,
+		LiteralFilter: lo.LiteralFilter,
 	}
 	if cls.PLowerBound != nil {
 		if lo.LowerAnchor == nil || (lo.LowerAnchor != nil && cls.PLowerBound.After(*lo.LowerAnchor)) {
@@ -103,10 +104,263 @@ func simpleExist(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 	return unfeasible, tbl, nil
 }
 
+// nodeTriples returns all the triples in the graph where the provided node is
+// used either as the subject or the object.
+func nodeTriples(ctx context.Context, g storage.Graph, n *node.Node, lo *storage.LookupOptions, chanSize int) ([]*triple.Triple, error) {
+	lookups := []func(chan<- *triple.Triple) error{
+		func(ts chan<- *triple.Triple) error {
+			return g.TriplesForSubject(ctx, n, lo, ts)
+		},
+		func(ts chan<- *triple.Triple) error {
+			return g.TriplesForObject(ctx, triple.NewNodeObject(n), lo, ts)
+		},
+	}
+	var res []*triple.Triple
+	for _, lookup := range lookups {
+		var (
+			wg   sync.WaitGroup
+			lErr error
+		)
+		ts := make(chan *triple.Triple, chanSize)
+		wg.Add(1)
+		go func(lookup func(chan<- *triple.Triple) error) {
+			defer wg.Done()
+			lErr = lookup(ts)
+		}(lookup)
+		for t := range ts {
+			res = append(res, t)
+		}
+		wg.Wait()
+		if lErr != nil {
+			return nil, lErr
+		}
+	}
+	return res, nil
+}
+
+// pathObjects returns the objects reachable from the provided subject by
+// traversing the predicate path. Repeated steps are evaluated iteratively
+// until no new objects are reached.
+func pathObjects(ctx context.Context, gs []storage.Graph, s *node.Node, path []*semantic.PathStep, lo *storage.LookupOptions, chanSize int) ([]*triple.Object, error) {
+	frontier := []*triple.Object{triple.NewNodeObject(s)}
+	for _, step := range path {
+		var (
+			next []*triple.Object
+			seen = make(map[string]bool)
+		)
+		if step.Modifier == semantic.PathZeroOrMore {
+			for _, o := range frontier {
+				seen[o.String()] = true
+				next = append(next, o)
+			}
+		}
+		for visit := frontier; len(visit) > 0; {
+			var reached []*triple.Object
+			for _, o := range visit {
+				n, err := o.Node()
+				if err != nil {
+					// Only nodes can be traversed further.
+					continue
+				}
+				os, err := objects(ctx, gs, n, step.P, lo, chanSize)
+				if err != nil {
+					return nil, err
+				}
+				for _, no := range os {
+					if k := no.String(); !seen[k] {
+						seen[k] = true
+						reached = append(reached, no)
+					}
+				}
+			}
+			next = append(next, reached...)
+			if step.Modifier == semantic.PathOne {
+				break
+			}
+			visit = reached
+		}
+		frontier = next
+	}
+	return frontier, nil
+}
+
+// objects returns the objects for the provided subject and predicate across
+// all the provided graphs.
+func objects(ctx context.Context, gs []storage.Graph, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, chanSize int) ([]*triple.Object, error) {
+	var res []*triple.Object
+	for _, g := range gs {
+		var (
+			wg   sync.WaitGroup
+			lErr error
+		)
+		os := make(chan *triple.Object, chanSize)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lErr = g.Objects(ctx, s, p, lo, os)
+		}()
+		for o := range os {
+			res = append(res, o)
+		}
+		wg.Wait()
+		if lErr != nil {
+			return nil, lErr
+		}
+	}
+	return res, nil
+}
+
+// pathTriples returns one triple for each subject and object connected by the
+// predicate path of the provided clause. The returned triples use the first
+// predicate of the path so they can be turned into rows using the clause
+// bindings. If the subject is not known, the traversal starts from all the
+// subjects of the first predicate of the path.
+func pathTriples(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, chanSize int, w io.Writer) ([]*triple.Triple, error) {
+	// Intermediate steps cannot be capped by the statement limit.
+	nlo := &storage.LookupOptions{
+		LowerAnchor:  lo.LowerAnchor,
+		UpperAnchor:  lo.UpperAnchor,
+		LatestAnchor: lo.LatestAnchor,
+	}
+	p := cls.PPath[0].P
+	ss := []*node.Node{cls.S}
+	if cls.S == nil {
+		ss = nil
+		seen := make(map[string]bool)
+		for _, g := range gs {
+			var (
+				wg   sync.WaitGroup
+				lErr error
+			)
+			ts := make(chan *triple.Triple, chanSize)
+			wg.Add(1)
+			go func(g storage.Graph) {
+				defer wg.Done()
+				lErr = g.TriplesForPredicate(ctx, p, nlo, ts)
+			}(g)
+			for t := range ts {
+				if k := t.Subject().String(); !seen[k] {
+					seen[k] = true
+					ss = append(ss, t.Subject())
+				}
+			}
+			wg.Wait()
+			if lErr != nil {
+				return nil, lErr
+			}
+		}
+	}
+	tracer.Trace(w, func() []string {
+		return []string{fmt.Sprintf("Traversing path %v from %d subjects", cls.PPath, len(ss))}
+	})
+	var res []*triple.Triple
+	for _, s := range ss {
+		os, err := pathObjects(ctx, gs, s, cls.PPath, nlo, chanSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range os {
+			if cls.O != nil && o.String() != cls.O.String() {
+				continue
+			}
+			t, err := triple.New(s, p, o)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// triplesToTable returns a table with the rows for the provided triples using
+// the bindings of the graph clause.
+func triplesToTable(trpls []*triple.Triple, cls *semantic.GraphClause) (*table.Table, error) {
+	tbl, err := table.New(cls.Bindings())
+	if err != nil {
+		return nil, err
+	}
+	ts := make(chan *triple.Triple, len(trpls))
+	for _, t := range trpls {
+		ts <- t
+	}
+	close(ts)
+	if err := addTriples(ts, cls, tbl); err != nil {
+		return nil, err
+	}
+	return tbl, nil
+}
+
+// pathFetch returns a table containing the subjects and objects connected by
+// the predicate path of the provided graph clause.
+func pathFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, stmLimit int64, chanSize int, w io.Writer) (*table.Table, error) {
+	trpls, err := pathTriples(ctx, gs, cls, lo, chanSize, w)
+	if err != nil {
+		return nil, err
+	}
+	tbl, err := triplesToTable(trpls, cls)
+	if err != nil {
+		return nil, err
+	}
+	if stmLimit > 0 {
+		tbl.Limit(stmLimit)
+	}
+	return tbl, nil
+}
+
+// graphFetch returns a table containing the data specified by the graph
+// clause where the clause graph binding is bound to the name of the graph
+// each row was retrieved from.
+func graphFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, stmLimit int64, chanSize int, w io.Writer) (*table.Table, error) {
+	tbl, err := table.New(cls.Bindings())
+	if err != nil {
+		return nil, err
+	}
+	gcls := *cls
+	gcls.GraphBinding = ""
+	if len(gcls.Bindings()) == 0 {
+		// Rows without bindings are dropped, hence the subject is temporarily
+		// bound to the graph binding, which gets overwritten below.
+		gcls.SAlias = cls.GraphBinding
+	}
+	for _, g := range gs {
+		gc := &table.Cell{S: table.CellString(g.ID(ctx))}
+		gtbl, err := simpleFetch(ctx, []storage.Graph{g}, &gcls, lo, stmLimit, chanSize, w)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range gtbl.Rows() {
+			r[cls.GraphBinding] = gc
+			tbl.AddRow(r)
+		}
+	}
+	if stmLimit > 0 {
+		tbl.Limit(stmLimit)
+	}
+	return tbl, nil
+}
+
+// graphsNamed returns the graphs with the provided name.
+func graphsNamed(ctx context.Context, gs []storage.Graph, name string) []storage.Graph {
+	var res []storage.Graph
+	for _, g := range gs {
+		if g.ID(ctx) == name {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
 // simpleFetch returns a table containing the data specified by the graph
 // clause by querying the provided stora. Will return an error if it had poblems
 // retrieveing the data.
 func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, stmLimit int64, chanSize int, w io.Writer) (*table.Table, error) {
+	if cls.GraphBinding != "" {
+		return graphFetch(ctx, gs, cls, lo, stmLimit, chanSize, w)
+	}
+	if cls.HasPath() {
+		return pathFetch(ctx, gs, cls, lo, stmLimit, chanSize, w)
+	}
 	s, p, o := cls.S, cls.P, cls.O
 	lo = updateTimeBounds(lo, cls)
 	tbl, err := table.New(cls.Bindings())
@@ -410,53 +664,7 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 // bindings to set.
 func addTriples(ts <-chan *triple.Triple, cls *semantic.GraphClause, tbl *table.Table) error {
 	for t := range ts {
-		if cls.PID != "" {
-			// The triples need to be filtered.
-			if string(t.Predicate().ID()) != cls.PID {
-				continue
-			}
-			if cls.PTemporal {
-				if t.Predicate().Type() != predicate.Temporal {
-					continue
-				}
-				ta, err := t.Predicate().TimeAnchor()
-				if err != nil {
-					return fmt.Errorf("failed to retrieve time anchor from time predicate in triple %s with error %v", t, err)
-				}
-				// Need to check the bounds of the triple.
-				if cls.PLowerBound != nil && cls.PLowerBound.After(*ta) {
-					continue
-				}
-				if cls.PUpperBound != nil && cls.PUpperBound.Before(*ta) {
-					continue
-				}
-			}
-		}
-		if cls.OID != "" {
-			if p, err := t.Object().Predicate(); err == nil {
-				// The triples need to be filtered.
-				if string(p.ID()) != cls.OID {
-					continue
-				}
-				if cls.OTemporal {
-					if p.Type() != predicate.Temporal {
-						continue
-					}
-					ta, err := p.TimeAnchor()
-					if err != nil {
-						return fmt.Errorf("failed to retrieve time anchor from time predicate in triple %s with error %v", t, err)
-					}
-					// Need to check the bounds of the triple.
-					if cls.OLowerBound != nil && cls.OLowerBound.After(*ta) {
-						continue
-					}
-					if cls.OUpperBound != nil && cls.OUpperBound.Before(*ta) {
-						continue
-					}
-				}
-			}
-		}
-		r, err := tripleToRow(t, cls)
+		r, err := clauseRow(t, cls)
 		if err != nil {
 			return err
 		}
@@ -467,6 +675,45 @@ func addTriples(ts <-chan *triple.Triple, cls *semantic.GraphClause, tbl *table.
 	return nil
 }
 
+// clauseRow returns the row for the provided triple using the bindings of the
+// clause. It returns a nil row if the triple does not match the predicate or
+// object constraints of the clause.
+func clauseRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error) {
+	if cls.PID != "" {
+		// The triples need to be filtered.
+		if string(t.Predicate().ID()) != cls.PID {
+			return nil, nil
+		}
+		if cls.PTemporal {
+			if t.Predicate().Type() != predicate.Temporal {
+				return nil, nil
+			}
+			// Need to check the bounds of the triple.
+			if !t.Predicate().Overlaps(cls.PLowerBound, cls.PUpperBound) {
+				return nil, nil
+			}
+		}
+	}
+	if cls.OID != "" {
+		if p, err := t.Object().Predicate(); err == nil {
+			// The triples need to be filtered.
+			if string(p.ID()) != cls.OID {
+				return nil, nil
+			}
+			if cls.OTemporal {
+				if p.Type() != predicate.Temporal {
+					return nil, nil
+				}
+				// Need to check the bounds of the triple.
+				if !p.Overlaps(cls.OLowerBound, cls.OUpperBound) {
+					return nil, nil
+				}
+			}
+		}
+	}
+	return tripleToRow(t, cls)
+}
+
 // objectToCell returns a cell containing the data boxed in the object.
 func objectToCell(o *triple.Object) (*table.Cell, error) {
 	c := &table.Cell{}
@@ -0,0 +1,189 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DOTExporter is implemented by the executors that can describe their plan as
+// a Graphviz DOT graph.
+type DOTExporter interface {
+	// ToDOT returns the operator tree of the plan in DOT format. Each operator
+	// includes its estimated number of rows, if available, and the number of
+	// rows it produced if the plan was already executed.
+	ToDOT(ctx context.Context) (string, error)
+}
+
+// stepKey returns the key used to record the rows produced by a step of the
+// provided graph pattern.
+func stepKey(pattern int, op, target string) string {
+	return fmt.Sprintf("%d\x00%s\x00%s", pattern, op, target)
+}
+
+// recordRows records the number of rows produced by a step of the graph
+// pattern being processed. Rows are only recorded while executing the plan.
+func (p *queryPlan) recordRows(op, target string, n int) {
+	if p.rows == nil {
+		return
+	}
+	p.rows[stepKey(p.pattern, op, target)] = n
+}
+
+// ToDOT returns the operator tree of the query plan in DOT format.
+func (p *queryPlan) ToDOT(ctx context.Context) (string, error) {
+	stps, err := p.Steps(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.dot(stps), nil
+}
+
+// ToDOT returns the operator tree of the ask plan in DOT format.
+func (p *askPlan) ToDOT(ctx context.Context) (string, error) {
+	return p.queryPlan.ToDOT(ctx)
+}
+
+// ToDOT returns the operator tree of the construct plan in DOT format.
+func (p *constructPlan) ToDOT(ctx context.Context) (string, error) {
+	stps, err := p.Steps(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.queryPlan.dot(stps), nil
+}
+
+// ToDOT returns the operator tree of the wrapped plan in DOT format.
+func (p *budgetPlan) ToDOT(ctx context.Context) (string, error) {
+	e, ok := p.plan.(DOTExporter)
+	if !ok {
+		return "", fmt.Errorf("planner: %s plans cannot be exported as DOT", p.plan.Type())
+	}
+	return e.ToDOT(ctx)
+}
+
+// patternOperations contains the operations of the steps that belong to a
+// graph pattern.
+var patternOperations = map[string]bool{
+	"union":    true,
+	"clause":   true,
+	"subquery": true,
+	"filter":   true,
+}
+
+// dot returns the DOT graph of the provided steps. Rows flow from the leaves,
+// the clauses, to the root of the tree, the last step of the plan.
+func (p *queryPlan) dot(stps []*Step) string {
+	g := &dotGraph{}
+	g.b.WriteString("digraph plan {\n\trankdir=BT;\n\tnode [shape=box];\n")
+	var (
+		pattern  int
+		cur      = -1
+		patterns []int
+		closed   bool
+	)
+	// actual returns the label line with the rows produced by the step, if
+	// it was executed.
+	actual := func(pattern int, op, target string) string {
+		n, ok := p.rows[stepKey(pattern, op, target)]
+		if !ok {
+			return ""
+		}
+		return fmt.Sprintf("actual rows: %d", n)
+	}
+	// closePattern combines the graph patterns and projects their rows.
+	closePattern := func() {
+		if closed {
+			return
+		}
+		closed = true
+		if cur >= 0 {
+			patterns = append(patterns, cur)
+		}
+		if pattern > 0 {
+			cur = g.node([]string{"union", actual(0, "union", "")}, patterns...)
+		}
+		// The rest of steps process the rows of all the graph patterns.
+		pattern = 0
+		var prjs []string
+		for _, prj := range p.stm.Projection() {
+			prjs = append(prjs, prj.String())
+		}
+		cur = g.node([]string{"project", strings.Join(prjs, ", "), actual(0, "project", "")}, cur)
+	}
+	for _, s := range stps {
+		if !patternOperations[s.Operation] {
+			closePattern()
+		}
+		switch s.Operation {
+		case "union":
+			if cur >= 0 {
+				patterns = append(patterns, cur)
+			}
+			pattern, cur = pattern+1, -1
+		case "clause":
+			lbl := []string{"clause", s.Target, "lookup: " + s.Lookup}
+			if s.Estimate >= 0 {
+				lbl = append(lbl, fmt.Sprintf("estimated rows: %d", s.Estimate))
+			}
+			rows := actual(pattern, s.Operation, s.Target)
+			if cur < 0 {
+				cur = g.node(append(lbl, rows))
+				continue
+			}
+			cur = g.node([]string{s.Strategy, rows}, cur, g.node(lbl))
+		case "subquery":
+			sq := g.node([]string{"subquery", s.Target})
+			cur = g.node([]string{s.Strategy, actual(pattern, s.Operation, s.Target)}, cur, sq)
+		default:
+			cur = g.node([]string{s.Operation, s.Target, actual(pattern, s.Operation, s.Target)}, cur)
+		}
+	}
+	closePattern()
+	g.b.WriteString("}\n")
+	return g.b.String()
+}
+
+// dotGraph builds the text of a DOT graph.
+type dotGraph struct {
+	b bytes.Buffer
+	n int
+}
+
+// dotEscaper escapes the text of DOT labels.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// node adds a new node with the provided label lines and the edges from its
+// children to it. Empty lines are skipped. It returns the ID of the node.
+func (g *dotGraph) node(lbl []string, children ...int) int {
+	id := g.n
+	g.n++
+	var ls []string
+	for _, l := range lbl {
+		if l != "" {
+			ls = append(ls, dotEscaper.Replace(l))
+		}
+	}
+	g.b.WriteString(fmt.Sprintf("\tn%d [label=\"%s\"];\n", id, strings.Join(ls, `\n`)))
+	for _, c := range children {
+		if c >= 0 {
+			g.b.WriteString(fmt.Sprintf("\tn%d -> n%d;\n", c, id))
+		}
+	}
+	return id
+}
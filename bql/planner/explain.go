@@ -0,0 +1,412 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// Bindings used in the table returned by EXPLAIN statements.
+const (
+	StepBinding      = "?step"
+	OperationBinding = "?operation"
+	TargetBinding    = "?target"
+	StrategyBinding  = "?strategy"
+	LookupBinding    = "?lookup"
+	EstimateBinding  = "?estimate"
+)
+
+// explainEstimateLimit caps the number of triples retrieved to estimate the
+// cardinality of a clause.
+const explainEstimateLimit = 1000
+
+// Step describes one of the steps an execution plan will run.
+type Step struct {
+	// Operation is the kind of step, for instance clause or filter.
+	Operation string
+	// Target is the readable version of the element processed by the step.
+	Target string
+	// Strategy describes how the step results are combined with the rows
+	// retrieved by previous steps.
+	Strategy string
+	// Lookup is the storage lookup used by the step, if any.
+	Lookup string
+	// Estimate is the estimated number of rows the step retrieves. It is set
+	// to -1 if no estimate is available.
+	Estimate int64
+}
+
+// Explainer is implemented by the executors that are able to list the steps
+// of their plan.
+type Explainer interface {
+	// Steps returns the ordered list of steps the plan will run.
+	Steps(ctx context.Context) ([]*Step, error)
+}
+
+// explainPlan returns the description of the plan of another statement
+// instead of executing it.
+type explainPlan struct {
+	plan   Executor
+	tracer io.Writer
+}
+
+// Type returns the type of plan used by the executor.
+func (p *explainPlan) Type() string {
+	return "EXPLAIN"
+}
+
+// Execute returns a table describing the steps of the explained plan.
+func (p *explainPlan) Execute(ctx context.Context) (*table.Table, error) {
+	var (
+		stps []*Step
+		err  error
+	)
+	if e, ok := p.plan.(Explainer); ok {
+		stps, err = e.Steps(ctx)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		stps = []*Step{{
+			Operation: p.plan.Type(),
+			Target:    planSummary(ctx, p.plan),
+			Estimate:  -1,
+		}}
+	}
+	return stepsToTable(stps)
+}
+
+// String returns a readable description of the execution plan.
+func (p *explainPlan) String(ctx context.Context) string {
+	return fmt.Sprintf("EXPLAIN plan:\n\n%v", p.plan.String(ctx))
+}
+
+// planSummary returns the plan description without its header as a single
+// line.
+func planSummary(ctx context.Context, p Executor) string {
+	var ls []string
+	for _, l := range strings.Split(p.String(ctx), "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasSuffix(l, " plan:") {
+			ls = append(ls, l)
+		}
+	}
+	return strings.Join(ls, "; ")
+}
+
+// stepsToTable returns the table containing the provided steps.
+func stepsToTable(stps []*Step) (*table.Table, error) {
+	t, err := table.New([]string{StepBinding, OperationBinding, TargetBinding, StrategyBinding, LookupBinding, EstimateBinding})
+	if err != nil {
+		return nil, err
+	}
+	b := literal.DefaultBuilder()
+	for i, s := range stps {
+		id, err := b.Build(literal.Int64, int64(i))
+		if err != nil {
+			return nil, err
+		}
+		est := &table.Cell{}
+		if s.Estimate >= 0 {
+			l, err := b.Build(literal.Int64, s.Estimate)
+			if err != nil {
+				return nil, err
+			}
+			est = &table.Cell{L: l}
+		}
+		t.AddRow(table.Row{
+			StepBinding:      &table.Cell{L: id},
+			OperationBinding: &table.Cell{S: table.CellString(s.Operation)},
+			TargetBinding:    &table.Cell{S: table.CellString(s.Target)},
+			StrategyBinding:  &table.Cell{S: table.CellString(s.Strategy)},
+			LookupBinding:    &table.Cell{S: table.CellString(s.Lookup)},
+			EstimateBinding:  est,
+		})
+	}
+	return t, nil
+}
+
+// Steps returns the ordered list of steps the query plan will run.
+func (p *queryPlan) Steps(ctx context.Context) ([]*Step, error) {
+	if err := p.stm.Init(ctx, p.store); err != nil {
+		return nil, err
+	}
+	p.grfs = p.stm.InputGraphs()
+	p.orderGraphPattern(ctx)
+	lo := p.stm.GlobalLookupOptions()
+	var stps []*Step
+	if len(p.unions) == 0 {
+		cs, err := p.patternSteps(ctx, p.cls, p.stm.Subqueries(), p.stm.Filters(), lo)
+		if err != nil {
+			return nil, err
+		}
+		stps = append(stps, cs...)
+	}
+	ufs, usqs := p.stm.GraphPatternUnionFilters(), p.stm.GraphPatternUnionSubqueries()
+	for i, u := range p.unions {
+		stps = append(stps, &Step{
+			Operation: "union",
+			Target:    fmt.Sprintf("graph pattern %d", i),
+			Strategy:  "union",
+			Estimate:  -1,
+		})
+		cs, err := p.patternSteps(ctx, u, usqs[i], ufs[i], lo)
+		if err != nil {
+			return nil, err
+		}
+		stps = append(stps, cs...)
+	}
+	if gb := p.stm.GroupBy(); len(gb) > 0 {
+		stps = append(stps, &Step{
+			Operation: "group by",
+			Target:    strings.Join(gb, ", "),
+			Strategy:  "aggregate",
+			Estimate:  -1,
+		})
+	}
+	if ob := p.stm.OrderByConfig(); len(ob) > 0 {
+		stps = append(stps, &Step{
+			Operation: "order by",
+			Target:    ob.String(),
+			Strategy:  "sort",
+			Estimate:  -1,
+		})
+	}
+	if p.stm.HasHavingClause() {
+		stps = append(stps, &Step{
+			Operation: "having",
+			Target:    havingTarget(p.stm),
+			Strategy:  "row filter",
+			Estimate:  -1,
+		})
+	}
+	if p.stm.IsOffsetSet() {
+		stps = append(stps, &Step{
+			Operation: "offset",
+			Target:    fmt.Sprintf("%d", p.stm.Offset()),
+			Strategy:  "skip rows",
+			Estimate:  -1,
+		})
+	}
+	if p.stm.IsLimitSet() {
+		stps = append(stps, &Step{
+			Operation: "limit",
+			Target:    fmt.Sprintf("%d", p.stm.Limit()),
+			Strategy:  "truncate rows",
+			Estimate:  -1,
+		})
+	}
+	return stps, nil
+}
+
+// patternSteps returns the steps used to resolve a graph pattern. It follows
+// the same decisions processClause takes based on the bindings available
+// after each clause.
+func (p *queryPlan) patternSteps(ctx context.Context, cls []*semantic.GraphClause, sqs []*semantic.Statement, fs []*semantic.Filter, lo *storage.LookupOptions) ([]*Step, error) {
+	bound := make(map[string]bool)
+	for k := range p.stm.ParameterValues() {
+		bound[k] = true
+	}
+	var stps []*Step
+	for _, c := range cls {
+		est, err := p.estimate(ctx, c, lo)
+		if err != nil {
+			return nil, err
+		}
+		stps = append(stps, &Step{
+			Operation: "clause",
+			Target:    c.String(),
+			Strategy:  clauseStrategy(c, bound),
+			Lookup:    clauseLookup(c, bound),
+			Estimate:  est,
+		})
+		for _, b := range c.Bindings() {
+			bound[b] = true
+		}
+	}
+	for _, sq := range sqs {
+		stps = append(stps, &Step{
+			Operation: "subquery",
+			Target:    subqueryTarget(sq),
+			Strategy:  "inner join",
+			Estimate:  -1,
+		})
+	}
+	for _, f := range fs {
+		stps = append(stps, &Step{
+			Operation: "filter",
+			Target:    f.String(),
+			Strategy:  "row filter",
+			Estimate:  -1,
+		})
+	}
+	return stps, nil
+}
+
+// havingTarget returns the readable version of the having clause of the
+// statement.
+func havingTarget(stm *semantic.Statement) string {
+	var b bytes.Buffer
+	for _, ce := range stm.HavingExpression() {
+		if tkn := ce.Token(); tkn != nil {
+			b.WriteString(tkn.Text)
+			b.WriteString(" ")
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// subqueryTarget returns the readable version of the graph pattern of the
+// subquery.
+func subqueryTarget(sq *semantic.Statement) string {
+	var ts []string
+	for _, c := range sq.GraphPatternClauses() {
+		ts = append(ts, c.String())
+	}
+	return strings.Join(ts, " . ")
+}
+
+// clauseStrategy returns how the rows of the clause are combined with the
+// rows retrieved by the previous clauses.
+func clauseStrategy(c *semantic.GraphClause, bound map[string]bool) string {
+	if c.Specificity() == 3 && c.GraphBinding == "" {
+		if c.Optional && !c.HasAlias() {
+			return "skip"
+		}
+		return "existence check"
+	}
+	var existing []string
+	for _, b := range c.Bindings() {
+		if bound[b] {
+			existing = append(existing, b)
+		}
+	}
+	if len(existing) > 0 {
+		sort.Strings(existing)
+		return "nested loop join on " + strings.Join(existing, ", ")
+	}
+	switch {
+	case len(bound) == 0:
+		return "scan"
+	case c.Optional:
+		return "left optional join"
+	default:
+		return "cross product"
+	}
+}
+
+// clauseLookup returns the storage lookup used to retrieve the clause
+// triples.
+func clauseLookup(c *semantic.GraphClause, bound map[string]bool) string {
+	known := func(v bool, bs ...string) bool {
+		for _, b := range bs {
+			if b != "" && bound[b] {
+				return true
+			}
+		}
+		return v
+	}
+	s := known(c.S != nil, c.SBinding, c.SAlias)
+	pr := known(c.P != nil, c.PBinding, c.PAlias) || (c.PID != "" && known(false, c.PAnchorBinding))
+	o := known(c.O != nil, c.OBinding, c.OAlias) || (c.OID != "" && known(false, c.OAnchorBinding))
+	var l string
+	switch {
+	case c.HasPath() && s:
+		l = "Objects along path"
+	case c.HasPath():
+		l = "TriplesForPredicate along path"
+	case s && pr && o:
+		l = "Exist"
+	case s && pr:
+		l = "Objects"
+	case s && o:
+		l = "PredicatesForSubjectAndObject"
+	case pr && o:
+		l = "Subjects"
+	case s:
+		l = "TriplesForSubject"
+	case pr:
+		l = "TriplesForPredicate"
+	case o:
+		l = "TriplesForObject"
+	default:
+		l = "Triples"
+	}
+	if c.GraphBinding != "" {
+		l += " per graph"
+	}
+	return l
+}
+
+// estimate returns the number of triples matching the values the clause
+// specifies without considering the rows retrieved by other clauses. The
+// estimate is capped to explainEstimateLimit triples per lookup.
+func (p *queryPlan) estimate(ctx context.Context, c *semantic.GraphClause, lo *storage.LookupOptions) (int64, error) {
+	cls := *c
+	if cls.Specificity() == 3 && !cls.HasPath() && cls.GraphBinding == "" {
+		t, err := triple.New(cls.S, cls.P, cls.O)
+		if err != nil {
+			return 0, err
+		}
+		unfeasible, _, err := simpleExist(ctx, p.grfs, &cls, t)
+		if err != nil || unfeasible {
+			return 0, err
+		}
+		return 1, nil
+	}
+	elo := *lo
+	elo.MaxElements = explainEstimateLimit
+	tbl, err := simpleFetch(ctx, p.grfs, &cls, &elo, explainEstimateLimit, p.chanSize, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int64(tbl.NumRows()), nil
+}
+
+// Steps returns the ordered list of steps the ask plan will run.
+func (p *askPlan) Steps(ctx context.Context) ([]*Step, error) {
+	return p.queryPlan.Steps(ctx)
+}
+
+// Steps returns the ordered list of steps the construct plan will run.
+func (p *constructPlan) Steps(ctx context.Context) ([]*Step, error) {
+	stps, err := p.queryPlan.Steps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	op := "construct"
+	if !p.construct {
+		op = "deconstruct"
+	}
+	for _, c := range p.stm.ConstructClauses() {
+		stps = append(stps, &Step{
+			Operation: op,
+			Target:    c.String(),
+			Strategy:  "per row",
+			Estimate:  -1,
+		})
+	}
+	return stps, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"time"
+
+	"github.com/google/badwolf/bql/semantic"
+)
+
+// Hooks contains the callbacks invoked while a query plan runs. They allow
+// reporting the progress of long running queries and collecting metrics for
+// each clause. Nil callbacks are skipped. Callbacks are called from the
+// goroutine executing the plan, so they should return quickly. The clauses of
+// subqueries and of the queries streamed one row at a time are not reported.
+type Hooks struct {
+	// OnClauseStart is called before retrieving the data of a graph pattern
+	// clause.
+	OnClauseStart func(ClauseEvent)
+
+	// OnClauseDone is called once the rows of a graph pattern clause are
+	// joined with the rows retrieved so far.
+	OnClauseDone func(ClauseEvent)
+}
+
+// ClauseEvent describes the processing of a graph pattern clause.
+type ClauseEvent struct {
+	// Pattern is the graph pattern the clause belongs to: zero for the main
+	// graph pattern and i+1 for the i-th alternative of a union.
+	Pattern int
+	// Index is the position of the clause in the order the graph pattern
+	// clauses are processed.
+	Index int
+	// Total is the number of clauses in the graph pattern.
+	Total int
+	// Clause is the clause being processed.
+	Clause *semantic.GraphClause
+	// Rows is the number of rows after processing the clause. It is only set
+	// once the clause is done.
+	Rows int
+	// Duration is the time spent processing the clause. It is only set once
+	// the clause is done.
+	Duration time.Duration
+	// Err is the error that stopped the processing of the clause, if any.
+	Err error
+}
+
+// clauseStart notifies that a clause is about to be processed. It returns
+// the time the clause started.
+func (p *queryPlan) clauseStart(idx, total int, cls *semantic.GraphClause) time.Time {
+	if p.hooks.OnClauseStart != nil {
+		p.hooks.OnClauseStart(ClauseEvent{
+			Pattern: p.pattern,
+			Index:   idx,
+			Total:   total,
+			Clause:  cls,
+		})
+	}
+	return time.Now()
+}
+
+// clauseDone records the rows produced by the clause and notifies that it
+// was processed.
+func (p *queryPlan) clauseDone(idx, total int, cls *semantic.GraphClause, start time.Time, rows int, err error) {
+	if err == nil {
+		p.recordRows("clause", cls.String(), rows)
+	}
+	if p.hooks.OnClauseDone != nil {
+		p.hooks.OnClauseDone(ClauseEvent{
+			Pattern:  p.pattern,
+			Index:    idx,
+			Total:    total,
+			Clause:   cls,
+			Rows:     rows,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+}
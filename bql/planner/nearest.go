@@ -0,0 +1,143 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/badwolf/bql/planner/tracer"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// needsRanking returns true if any of the provided filters needs all the rows
+// before evaluating any of them.
+func needsRanking(fs []*semantic.Filter) bool {
+	for _, f := range fs {
+		if f.NeedsRanking() {
+			return true
+		}
+	}
+	return false
+}
+
+// nearestClause returns the clause whose triples bind the provided binding
+// as object of a predicate with a fixed ID, if any. Optional clauses and
+// predicate paths are not considered.
+func nearestClause(clss []*semantic.GraphClause, b string) (*semantic.GraphClause, string) {
+	for _, cls := range clss {
+		if cls.OBinding != b || cls.Optional || cls.HasPath() || cls.PBinding != "" {
+			continue
+		}
+		if cls.P != nil {
+			return cls, string(cls.P.ID())
+		}
+		if cls.PID != "" {
+			return cls, cls.PID
+		}
+	}
+	return nil, ""
+}
+
+// nearestObjects returns the union of the vectors found by the vector indices
+// of all the graphs of the plan. The boolean is false if any of the graphs
+// does not index the vectors of the predicate.
+func (p *queryPlan) nearestObjects(ctx context.Context, id string, q []float64, k int) ([]*triple.Object, bool, error) {
+	var (
+		res  []*triple.Object
+		seen = make(map[string]bool)
+	)
+	for _, g := range p.grfs {
+		os, err := storage.NearestObjects(ctx, g, id, q, k)
+		if err == storage.ErrNoVectorIndex {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		for _, o := range os {
+			if u := o.UUID().String(); !seen[u] {
+				seen[u] = true
+				res = append(res, o)
+			}
+		}
+	}
+	return res, true, nil
+}
+
+// seedNearest binds the vectors searched by the NEAREST calls of the provided
+// filters to the vectors found by the vector indices of the graphs, so the
+// clause binding them only looks up the triples holding those vectors. It is
+// only done if the vectors are bound as objects of a predicate whose vectors
+// are indexed in all the graphs. Indices search all the vectors of the
+// predicate, so if other clauses discard some of the rows of the vectors
+// found, fewer than k vectors may be returned.
+func (p *queryPlan) seedNearest(ctx context.Context, clss []*semantic.GraphClause, fs []*semantic.Filter) error {
+	for _, f := range fs {
+		for _, ns := range f.NearestSearches() {
+			if p.tbl.HasBinding(ns.Binding) {
+				continue
+			}
+			cls, id := nearestClause(clss, ns.Binding)
+			if cls == nil {
+				continue
+			}
+			q := ns.Query
+			if q == nil {
+				c, ok := p.stm.ParameterValues()[ns.QueryBinding]
+				if !ok || c.L == nil {
+					continue
+				}
+				v, err := c.L.Vector()
+				if err != nil {
+					continue
+				}
+				q = v
+			}
+			os, ok, err := p.nearestObjects(ctx, id, q, ns.K)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			tracer.Trace(p.tracer, func() []string {
+				return []string{fmt.Sprintf("Binding %s to the %d nearest vectors found by the vector indices of predicate %q", ns.Binding, len(os), id)}
+			})
+			seed, err := table.New([]string{ns.Binding})
+			if err != nil {
+				return err
+			}
+			for _, o := range os {
+				c, err := objectToCell(o)
+				if err != nil {
+					return err
+				}
+				seed.AddRow(table.Row{ns.Binding: c})
+			}
+			if len(p.tbl.Bindings()) == 0 {
+				p.tbl = seed
+				continue
+			}
+			if err := p.tbl.DotProduct(seed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
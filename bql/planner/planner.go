@@ -22,10 +22,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/badwolf/bql/lexer"
 	"github.com/google/badwolf/bql/planner/tracer"
@@ -34,6 +38,7 @@ import (
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
 	"github.com/google/badwolf/triple/predicate"
 )
 
@@ -70,6 +75,14 @@ func (p *createPlan) Execute(ctx context.Context) (*table.Table, error) {
 	}
 	errs := []string{}
 	for _, g := range p.stm.GraphNames() {
+		if p.stm.IsConditional() {
+			if _, err := p.store.Graph(ctx, g); err == nil {
+				tracer.Trace(p.tracer, func() []string {
+					return []string{"Skipping existing graph \"" + g + "\""}
+				})
+				continue
+			}
+		}
 		tracer.Trace(p.tracer, func() []string {
 			return []string{"Creating new graph \"" + g + "\""}
 		})
@@ -101,14 +114,29 @@ func (p *dropPlan) Type() string {
 	return "DROP"
 }
 
-// Execute drops the indicated graphs.
+// Execute drops the indicated graphs, and the graphs derived from them when
+// cascading.
 func (p *dropPlan) Execute(ctx context.Context) (*table.Table, error) {
 	t, err := table.New([]string{})
 	if err != nil {
 		return nil, err
 	}
+	var deps []string
+	if p.stm.IsCascade() {
+		if deps, err = derivedGraphs(ctx, p.store, p.stm.GraphNames()); err != nil {
+			return nil, err
+		}
+	}
 	errs := []string{}
 	for _, g := range p.stm.GraphNames() {
+		if p.stm.IsConditional() {
+			if _, err := p.store.Graph(ctx, g); err != nil {
+				tracer.Trace(p.tracer, func() []string {
+					return []string{"Skipping missing graph \"" + g + "\""}
+				})
+				continue
+			}
+		}
 		tracer.Trace(p.tracer, func() []string {
 			return []string{"Deleting graph \"" + g + "\""}
 		})
@@ -116,6 +144,14 @@ func (p *dropPlan) Execute(ctx context.Context) (*table.Table, error) {
 			errs = append(errs, err.Error())
 		}
 	}
+	for _, g := range deps {
+		tracer.Trace(p.tracer, func() []string {
+			return []string{"Deleting derived graph \"" + g + "\""}
+		})
+		if err := p.store.DeleteGraph(ctx, g); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
 	if len(errs) > 0 {
 		return nil, errors.New(strings.Join(errs, "; "))
 	}
@@ -124,9 +160,120 @@ func (p *dropPlan) Execute(ctx context.Context) (*table.Table, error) {
 
 // String returns a readable description of the execution plan.
 func (p *dropPlan) String(ctx context.Context) string {
+	if p.stm.IsCascade() {
+		return fmt.Sprintf("DROP plan:\n\nstore(%q).DeleteGraph(_, %v and the graphs derived from them)", p.store.Name(nil), p.stm.Graphs())
+	}
 	return fmt.Sprintf("DROP plan:\n\nstore(%q).DeleteGraph(_, %v)", p.store.Name(nil), p.stm.Graphs())
 }
 
+// DerivedFromLabel is the label of the graphs filled by construct statements
+// listing the IDs of the graphs their triples were derived from, separated by
+// commas. Dropping a graph with CASCADE also drops the graphs derived from it.
+const DerivedFromLabel = "derived_from"
+
+// derivedFrom returns the IDs of the graphs listed in the DerivedFromLabel of
+// the provided metadata.
+func derivedFrom(md *storage.GraphMetadata) map[string]bool {
+	srcs := make(map[string]bool)
+	for _, id := range strings.Split(md.Labels[DerivedFromLabel], ",") {
+		if id != "" {
+			srcs[id] = true
+		}
+	}
+	return srcs
+}
+
+// recordDerivation adds the input graphs of the statement to the
+// DerivedFromLabel of its output graphs. Derivations are not recorded in
+// stores that do not keep graph metadata.
+func recordDerivation(ctx context.Context, store storage.Store, stm *semantic.Statement) error {
+	for _, out := range stm.OutputGraphNames() {
+		md, err := storage.GetGraphMetadata(ctx, store, out)
+		if err == storage.ErrNoMetadata {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		srcs, n := derivedFrom(md), 0
+		for _, in := range stm.InputGraphNames() {
+			if in != out && !srcs[in] {
+				srcs[in] = true
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		var ids []string
+		for id := range srcs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		labels := map[string]string{DerivedFromLabel: strings.Join(ids, ",")}
+		for k, v := range md.Labels {
+			if k != DerivedFromLabel {
+				labels[k] = v
+			}
+		}
+		md.Labels = labels
+		if err := storage.SetGraphMetadata(ctx, store, out, md); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// derivedGraphs returns the IDs of the graphs of the store derived, directly
+// or through other graphs, from the provided ones. Stores that do not keep
+// graph metadata have no derived graphs.
+func derivedGraphs(ctx context.Context, store storage.Store, ids []string) ([]string, error) {
+	names, errs := make(chan string), make(chan error, 1)
+	go func() {
+		errs <- store.GraphNames(ctx, names)
+	}()
+	var all []string
+	for id := range names {
+		all = append(all, id)
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	sort.Strings(all)
+	srcs := make(map[string]map[string]bool)
+	for _, id := range all {
+		md, err := storage.GetGraphMetadata(ctx, store, id)
+		if err == storage.ErrNoMetadata {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		srcs[id] = derivedFrom(md)
+	}
+	dropped := make(map[string]bool)
+	for _, id := range ids {
+		dropped[id] = true
+	}
+	var deps []string
+	for found := true; found; {
+		found = false
+		for _, id := range all {
+			if dropped[id] {
+				continue
+			}
+			for src := range srcs[id] {
+				if dropped[src] {
+					dropped[id], found = true, true
+					deps = append(deps, id)
+					break
+				}
+			}
+		}
+	}
+	return deps, nil
+}
+
 // insertPlan encapsulates the sequence of instructions that need to be
 // executed in order to satisfy the execution of a valid insert BQL statement.
 type insertPlan struct {
@@ -143,6 +290,9 @@ func (p *insertPlan) Type() string {
 type updater func(storage.Graph, []*triple.Triple) error
 
 func update(ctx context.Context, ts []*triple.Triple, gbs []string, store storage.Store, f updater) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	var (
 		mu   sync.Mutex
 		wg   sync.WaitGroup
@@ -258,9 +408,61 @@ type queryPlan struct {
 	grfsNames []string
 	grfs      []storage.Graph
 	cls       []*semantic.GraphClause
+	unions    [][]*semantic.GraphClause
 	tbl       *table.Table
+	regexps   *regexpCache
 	chanSize  int
 	tracer    io.Writer
+	// ask is set when the plan only needs to find one solution.
+	ask bool
+	// maxRows is the maximum number of rows intermediate tables can hold. Zero
+	// means no limit.
+	maxRows int
+	// workers is the maximum number of independent clauses fetched
+	// concurrently.
+	workers int
+	// pattern is the graph pattern being processed: zero for the main graph
+	// pattern and i+1 for the i-th union.
+	pattern int
+	// rows contains the number of rows produced by each step of the last
+	// execution of the plan indexed by stepKey.
+	rows map[string]int
+	// cache contains the results of previous executions of queries, if set.
+	cache *ResultCache
+	// hooks contains the callbacks notified while processing the clauses.
+	hooks Hooks
+	// maxMemory is the maximum number of bytes intermediate tables can use.
+	// Zero means no limit.
+	maxMemory int64
+	// memory, if set, accounts for the memory used by all the plans sharing
+	// it. The plan holds reserved bytes until it finishes. Reservations are
+	// serialized by budgetMu, since joins add rows concurrently.
+	memory   *MemoryAccountant
+	reserved int64
+	budgetMu sync.Mutex
+}
+
+// regexpCache keeps the regular expressions compiled while running a plan
+// so they are only compiled once regardless of the number of rows filtered.
+type regexpCache struct {
+	mu sync.Mutex
+	m  map[string]*regexp.Regexp
+}
+
+// compile returns the cached regular expression for the provided pattern,
+// compiling it if needed.
+func (c *regexpCache) compile(expr string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if re, ok := c.m[expr]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	c.m[expr] = re
+	return re, nil
 }
 
 // Type returns the type of plan used by the executor.
@@ -274,28 +476,57 @@ func newQueryPlan(ctx context.Context, store storage.Store, stm *semantic.Statem
 	for _, b := range stm.Bindings() {
 		bs = append(bs, b)
 	}
-	t, err := table.New([]string{})
+	t, err := parametersTable(stm)
 	if err != nil {
 		return nil, err
 	}
+	rc := &regexpCache{m: make(map[string]*regexp.Regexp)}
+	for _, f := range stm.Filters() {
+		f.SetRegexpCompiler(rc.compile)
+	}
 	return &queryPlan{
 		stm:       stm,
 		store:     store,
 		bndgs:     bs,
 		grfsNames: stm.InputGraphNames(),
 		cls:       stm.GraphPatternClauses(),
+		unions:    stm.GraphPatternUnions(),
 		tbl:       t,
+		regexps:   rc,
 		chanSize:  chanSize,
 		tracer:    w,
 	}, nil
 }
 
+// parametersTable returns the table used to start processing a graph pattern.
+// If the statement has parameters bound, the table contains a single row with
+// their values, so clauses using them are specified by the provided values.
+func parametersTable(stm *semantic.Statement) (*table.Table, error) {
+	pv := stm.ParameterValues()
+	var bs []string
+	for k := range pv {
+		bs = append(bs, k)
+	}
+	t, err := table.New(bs)
+	if err != nil {
+		return nil, err
+	}
+	if len(pv) > 0 {
+		r := make(table.Row, len(pv))
+		for k, v := range pv {
+			r[k] = v
+		}
+		t.AddRow(r)
+	}
+	return t, nil
+}
+
 // processClause retrieves the triples for the provided triple given the
 // information available.
 func (p *queryPlan) processClause(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions) (bool, error) {
 	// This method decides how to process the clause based on the current
 	// list of bindings solved and data available.
-	if cls.Specificity() == 3 {
+	if cls.Specificity() == 3 && cls.GraphBinding == "" {
 		tracer.Trace(p.tracer, func() []string {
 			return []string{"Clause is fully specified"}
 		})
@@ -305,6 +536,17 @@ func (p *queryPlan) processClause(ctx context.Context, cls *semantic.GraphClause
 			})
 			return false, nil
 		}
+		if cls.HasPath() {
+			ts, err := pathTriples(ctx, p.grfs, cls, lo, p.chanSize, p.tracer)
+			if err != nil {
+				return false, err
+			}
+			tbl, err := triplesToTable(ts, cls)
+			if err != nil {
+				return false, err
+			}
+			return len(ts) == 0, p.tbl.AppendTable(tbl)
+		}
 		t, err := triple.New(cls.S, cls.P, cls.O)
 		if err != nil {
 			return false, err
@@ -334,25 +576,11 @@ func (p *queryPlan) processClause(ctx context.Context, cls *semantic.GraphClause
 			return []string{fmt.Sprintf("None of the clause binding exist %v/%v", cls.Bindings(), existing)}
 		})
 		// Data is new.
-		stmLimit := int64(0)
-		if len(p.stm.GraphPatternClauses()) == 1 && len(p.stm.GroupBy()) == 0 && len(p.stm.HavingExpression()) == 0 {
-			stmLimit = p.stm.Limit()
-		}
-		tbl, err := simpleFetch(ctx, p.grfs, cls, lo, stmLimit, p.chanSize, p.tracer)
+		tbl, err := simpleFetch(ctx, p.grfs, cls, lo, p.fetchLimit(), p.chanSize, p.tracer)
 		if err != nil {
 			return true, err
 		}
-
-		if len(p.tbl.Bindings()) > 0 {
-			if cls.Optional {
-				tracer.Trace(p.tracer, func() []string {
-					return []string{fmt.Sprintf("Processing optional clause of disjoint bindings %v", cls)}
-				})
-				return false, p.tbl.LeftOptionalJoin(tbl)
-			}
-			return false, p.tbl.DotProduct(tbl)
-		}
-		return false, p.tbl.AppendTable(tbl)
+		return false, p.joinDisjoint(cls, tbl)
 	}
 
 	tracer.Trace(p.tracer, func() []string {
@@ -361,6 +589,34 @@ func (p *queryPlan) processClause(ctx context.Context, cls *semantic.GraphClause
 	return false, p.specifyClauseWithTable(ctx, cls, lo)
 }
 
+// joinDisjoint joins the rows retrieved so far with the table fetched for a
+// clause whose bindings are not bound yet.
+func (p *queryPlan) joinDisjoint(cls *semantic.GraphClause, tbl *table.Table) error {
+	if err := p.checkTable(tbl); err != nil {
+		return err
+	}
+	if len(p.tbl.Bindings()) > 0 {
+		// The bindings are disjoint, so the result is the product of both
+		// tables. Check its size before building it, since it may not fit
+		// in memory.
+		n, m := p.tbl.NumRows(), tbl.NumRows()
+		if p.maxRows > 0 && m > 0 && n > p.maxRows/m {
+			return &ErrQueryBudgetExceeded{MaxIntermediateRows: p.maxRows}
+		}
+		if err := p.checkMemory(int64(n)*int64(m), int64(len(p.tbl.Bindings())+len(tbl.Bindings()))); err != nil {
+			return err
+		}
+		if cls.Optional {
+			tracer.Trace(p.tracer, func() []string {
+				return []string{fmt.Sprintf("Processing optional clause of disjoint bindings %v", cls)}
+			})
+			return p.tbl.LeftOptionalJoin(tbl)
+		}
+		return p.tbl.DotProduct(tbl)
+	}
+	return p.tbl.AppendTable(tbl)
+}
+
 // getBoundValueForComponent return the unique bound value if available on
 // the provided row.
 func getBoundValueForComponent(r table.Row, bs []string) *table.Cell {
@@ -440,16 +696,21 @@ func (p *queryPlan) addSpecifiedData(ctx context.Context, r table.Row, cls *sema
 		return []string{fmt.Sprintf("Corrected clause: %v", &cls)}
 	})
 
-	stmLimit := int64(0)
-	if len(p.stm.GraphPatternClauses()) == 1 && len(p.stm.GroupBy()) == 0 && len(p.stm.HavingExpression()) == 0 {
-		stmLimit = p.stm.Limit()
+	gs := p.grfs
+	if v, ok := r[cls.GraphBinding]; ok && v.S != nil {
+		gs = graphsNamed(ctx, gs, *v.S)
 	}
-	tbl, err := simpleFetch(ctx, p.grfs, cls, lo, stmLimit, p.chanSize, p.tracer)
+	tbl, err := simpleFetch(ctx, gs, cls, lo, p.fetchLimit(), p.chanSize, p.tracer)
 	if err != nil {
 		return err
 	}
 
 	p.tbl.AddBindings(tbl.Bindings())
+	// The budgets are checked before adding the rows, so joins producing
+	// too many of them fail before exhausting the memory.
+	if err := p.checkGrowth(tbl.NumRows()); err != nil {
+		return err
+	}
 	if tbl.NumRows() == 0 && cls.Optional {
 		nr := make(table.Row)
 		for _, k := range tbl.Bindings() {
@@ -477,6 +738,10 @@ func (p *queryPlan) specifyClauseWithTable(ctx context.Context, cls *semantic.Gr
 		wg   sync.WaitGroup
 	)
 	for _, tmpRow := range rws {
+		if ctx.Err() != nil {
+			// Stop issuing requests once the context is canceled.
+			break
+		}
 		wg.Add(1)
 		go func(r table.Row) {
 			defer wg.Done()
@@ -490,6 +755,9 @@ func (p *queryPlan) specifyClauseWithTable(ctx context.Context, cls *semantic.Gr
 		}(tmpRow)
 	}
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return gErr
 }
 
@@ -517,6 +785,53 @@ func cellToObject(c *table.Cell) (*triple.Object, error) {
 	return nil, fmt.Errorf("invalid cell %v", c)
 }
 
+// Bindings used by the tables returned by CONSTRUCT statements without output
+// graphs and DESCRIBE statements. Each row of the table contains one triple.
+const (
+	SubjectBinding   = "?s"
+	PredicateBinding = "?p"
+	ObjectBinding    = "?o"
+)
+
+// spoRow returns the row representation of a triple using the subject,
+// predicate, and object bindings.
+func spoRow(t *triple.Triple) table.Row {
+	o, err := objectToCell(t.Object())
+	if err != nil {
+		o = &table.Cell{}
+	}
+	return table.Row{
+		SubjectBinding:   &table.Cell{N: t.Subject()},
+		PredicateBinding: &table.Cell{P: t.Predicate()},
+		ObjectBinding:    o,
+	}
+}
+
+// Triples returns the triples contained in a table returned by a CONSTRUCT
+// statement without output graphs or a DESCRIBE statement.
+func Triples(tbl *table.Table) ([]*triple.Triple, error) {
+	var ts []*triple.Triple
+	for _, r := range tbl.Rows() {
+		s, p := r[SubjectBinding], r[PredicateBinding]
+		if s == nil || s.N == nil {
+			return nil, fmt.Errorf("row %v requires a node for binding %q", r, SubjectBinding)
+		}
+		if p == nil || p.P == nil {
+			return nil, fmt.Errorf("row %v requires a predicate for binding %q", r, PredicateBinding)
+		}
+		o, err := cellToObject(r[ObjectBinding])
+		if err != nil {
+			return nil, err
+		}
+		t, err := triple.New(s.N, p.P, o)
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
 // filterOnExistence removes rows based on the existence of the fully qualified
 // triple after the biding of the clause.
 func (p *queryPlan) filterOnExistence(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions) error {
@@ -529,8 +844,9 @@ func (p *queryPlan) filterOnExistence(ctx context.Context, cls *semantic.GraphCl
 	)
 	for _, tmp := range data {
 		mu.RLock()
-		if gErr != nil {
-			// Try to stop early if an error occurred and we are still ussuing requests.
+		if gErr != nil || ctx.Err() != nil {
+			// Try to stop early if an error occurred, or the context was canceled,
+			// and we are still ussuing requests.
 			mu.RUnlock()
 			break
 		}
@@ -653,7 +969,7 @@ func (p *queryPlan) filterOnExistence(ctx context.Context, cls *semantic.GraphCl
 				return
 			}
 			exist := false
-			for _, g := range p.stm.InputGraphs() {
+			for _, g := range p.grfs {
 				t, err := triple.New(sbj, prd, obj)
 				if err != nil {
 					mu.Lock()
@@ -679,34 +995,275 @@ func (p *queryPlan) filterOnExistence(ctx context.Context, cls *semantic.GraphCl
 		}(tmp, *cls)
 	}
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return gErr
 }
 
 // processGraphPattern process the query graph pattern to retrieve the
 // data from the specified graphs.
 func (p *queryPlan) processGraphPattern(ctx context.Context, lo *storage.LookupOptions) error {
+	if len(p.unions) == 0 {
+		if err := p.seedNearest(ctx, p.cls, p.stm.Filters()); err != nil {
+			return err
+		}
+		if err := p.processClauses(ctx, p.cls, p.stm.Filters(), lo); err != nil {
+			return err
+		}
+		if err := p.joinSubqueries(ctx, p.stm.Subqueries()); err != nil {
+			return err
+		}
+		p.filter(p.stm.Filters())
+		return nil
+	}
+	res, err := table.New([]string{})
+	if err != nil {
+		return err
+	}
+	fs, sqs := p.stm.GraphPatternUnionFilters(), p.stm.GraphPatternUnionSubqueries()
+	for i, cls := range p.unions {
+		i := i
+		tracer.Trace(p.tracer, func() []string {
+			return []string{fmt.Sprintf("Processing union graph pattern %d", i)}
+		})
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		t, err := parametersTable(p.stm)
+		if err != nil {
+			return err
+		}
+		p.tbl, p.pattern = t, i+1
+		if err := p.seedNearest(ctx, cls, fs[i]); err != nil {
+			return err
+		}
+		if err := p.processClauses(ctx, cls, fs[i], lo); err != nil {
+			return err
+		}
+		if err := p.joinSubqueries(ctx, sqs[i]); err != nil {
+			return err
+		}
+		p.filter(fs[i])
+		res.Union(p.tbl)
+		if err := p.checkTable(res); err != nil {
+			return err
+		}
+		if p.ask && res.NumRows() > 0 {
+			tracer.Trace(p.tracer, func() []string {
+				return []string{fmt.Sprintf("Solution found in union graph pattern %d; skipping the rest", i)}
+			})
+			break
+		}
+	}
+	p.tbl, p.pattern = res, 0
+	p.recordRows("union", "", res.NumRows())
+	return nil
+}
+
+// joinSubqueries executes the provided subqueries and joins their results
+// with the rows retrieved so far using the shared bindings.
+func (p *queryPlan) joinSubqueries(ctx context.Context, sqs []*semantic.Statement) error {
+	for i, sq := range sqs {
+		i := i
+		tracer.Trace(p.tracer, func() []string {
+			return []string{fmt.Sprintf("Processing subquery %d", i)}
+		})
+		sp, err := newQueryPlan(ctx, p.store, sq, p.chanSize, p.tracer)
+		if err != nil {
+			return err
+		}
+		sp.maxRows, sp.workers = p.maxRows, p.workers
+		sp.maxMemory, sp.memory = p.maxMemory, p.memory
+		t, err := sp.Execute(ctx)
+		if err != nil {
+			return err
+		}
+		tracer.Trace(p.tracer, func() []string {
+			return []string{fmt.Sprintf("Joining %d rows returned by subquery %d", t.NumRows(), i)}
+		})
+		if err := p.tbl.InnerJoin(t); err != nil {
+			return err
+		}
+		p.recordRows("subquery", subqueryTarget(sq), p.tbl.NumRows())
+		if err := p.checkTable(p.tbl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filter removes the rows for which any of the provided filters does not
+// evaluate to true. Filters that need it rank the rows first.
+func (p *queryPlan) filter(fs []*semantic.Filter) {
+	for _, f := range fs {
+		f := f
+		tracer.Trace(p.tracer, func() []string {
+			return []string{"Filtering rows using " + f.String()}
+		})
+		if f.NeedsRanking() {
+			f.Rank(p.tbl.Rows())
+		}
+		p.tbl.Filter(func(r table.Row) bool {
+			ok, err := f.Evaluate(r)
+			return err != nil || !ok
+		})
+		p.recordRows("filter", f.String(), p.tbl.NumRows())
+	}
+}
+
+// processClauses process the provided graph pattern clauses to retrieve the
+// data from the specified graphs. The simple constraints of the provided
+// filters are pushed down to the storage lookups.
+func (p *queryPlan) processClauses(ctx context.Context, clss []*semantic.GraphClause, fs []*semantic.Filter, lo *storage.LookupOptions) error {
 	tracer.Trace(p.tracer, func() []string {
 		var res []string
-		for i, cls := range p.cls {
+		for i, cls := range clss {
 			res = append(res, fmt.Sprintf("Clause %d to process: %v", i, cls))
 		}
 		return res
 	})
-	for i, c := range p.cls {
-		i, cls := i, *c
-		tracer.Trace(p.tracer, func() []string {
-			return []string{fmt.Sprintf("Processing clause %d: %v", i, &cls)}
-		})
-		// The current planner is based on naively executing clauses by
-		// specificity.
-		unresolvable, err := p.processClause(ctx, &cls, lo)
+	for next := 0; next < len(clss); next++ {
+		i, cls := next, *clss[next]
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var (
+			unresolvable bool
+			err          error
+		)
+		if ind := p.independentClauses(clss[i:]); len(ind) > 1 {
+			tracer.Trace(p.tracer, func() []string {
+				return []string{fmt.Sprintf("Processing clauses %d to %d concurrently", i, i+len(ind)-1)}
+			})
+			err = p.processIndependentClauses(ctx, ind, fs, lo, i, len(clss))
+			next += len(ind) - 1
+		} else {
+			tracer.Trace(p.tracer, func() []string {
+				return []string{fmt.Sprintf("Processing clause %d: %v", i, &cls)}
+			})
+			// The current planner is based on naively executing clauses by
+			// specificity.
+			clo := pushdownFilters(&cls, fs, lo)
+			if clo != lo {
+				tracer.Trace(p.tracer, func() []string {
+					return []string{fmt.Sprintf("Pushing filters down to the lookups of clause %d as %v", i, clo)}
+				})
+			}
+			start := p.clauseStart(i, len(clss), clss[i])
+			unresolvable, err = p.processClause(ctx, &cls, clo)
+			n := p.tbl.NumRows()
+			if unresolvable {
+				n = 0
+			}
+			p.clauseDone(i, len(clss), clss[i], start, n, err)
+		}
 		if err != nil {
 			return err
 		}
+		if err := p.checkTable(p.tbl); err != nil {
+			return err
+		}
 		if unresolvable {
 			p.tbl.Truncate()
 			return nil
 		}
+		if len(p.tbl.Bindings()) > 0 && p.tbl.NumRows() == 0 {
+			// No further clause can add rows to an empty table.
+			tracer.Trace(p.tracer, func() []string {
+				return []string{fmt.Sprintf("No rows left after clause %d; skipping the rest", i)}
+			})
+			return nil
+		}
+	}
+	return nil
+}
+
+// independentClauses returns the clauses at the beginning of the provided
+// list that can be fetched concurrently: consecutive non optional clauses
+// that do not share bindings among them nor with the rows retrieved so far.
+// Fully specified clauses are excluded since they only check the existence of
+// a triple. It returns nil if the plan does not allow concurrent fetches.
+func (p *queryPlan) independentClauses(clss []*semantic.GraphClause) []*semantic.GraphClause {
+	if p.workers < 2 {
+		return nil
+	}
+	bound := make(map[string]bool)
+	for _, b := range p.tbl.Bindings() {
+		bound[b] = true
+	}
+	var res []*semantic.GraphClause
+	for _, cls := range clss {
+		if cls.Optional || (cls.Specificity() == 3 && cls.GraphBinding == "") {
+			break
+		}
+		bs := cls.Bindings()
+		for _, b := range bs {
+			if bound[b] {
+				return res
+			}
+		}
+		for _, b := range bs {
+			bound[b] = true
+		}
+		res = append(res, cls)
+	}
+	return res
+}
+
+// processIndependentClauses fetches the provided independent clauses
+// concurrently using at most as many goroutines as workers the plan has. The
+// fetched tables are joined with the rows retrieved so far in the order of
+// the clauses once all of them are available. The clauses start at the
+// provided index of a graph pattern with total clauses.
+func (p *queryPlan) processIndependentClauses(ctx context.Context, clss []*semantic.GraphClause, fs []*semantic.Filter, lo *storage.LookupOptions, first, total int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		tbls = make([]*table.Table, len(clss))
+		errs = make([]error, len(clss))
+		sem  = make(chan bool, p.workers)
+		wg   sync.WaitGroup
+	)
+	starts := make([]time.Time, len(clss))
+	for i, cls := range clss {
+		starts[i] = p.clauseStart(first+i, total, cls)
+	}
+	for i, cls := range clss {
+		wg.Add(1)
+		sem <- true
+		go func(i int, cls *semantic.GraphClause) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			tbls[i], errs[i] = simpleFetch(ctx, p.grfs, cls, pushdownFilters(cls, fs, lo), 0, p.chanSize, p.tracer)
+			if errs[i] != nil {
+				// There is no point on fetching the rest of clauses.
+				cancel()
+			}
+		}(i, cls)
+	}
+	wg.Wait()
+	// Report the error that canceled the rest of fetches, if any.
+	var ferr error
+	for _, err := range errs {
+		if err != nil && (ferr == nil || ferr == context.Canceled) {
+			ferr = err
+		}
+	}
+	if ferr != nil {
+		for i, cls := range clss {
+			p.clauseDone(first+i, total, cls, starts[i], 0, ferr)
+		}
+		return ferr
+	}
+	for i, cls := range clss {
+		err := p.joinDisjoint(cls, tbls[i])
+		p.clauseDone(first+i, total, cls, starts[i], p.tbl.NumRows(), err)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -722,10 +1279,17 @@ func (p *queryPlan) projectAndGroupBy() error {
 		p.tbl.AddBindings(p.stm.OutputBindings())
 		// For each row, copy each input binding value to its appropriate alias.
 		for _, prj := range p.stm.Projections() {
+			if prj.Expression != nil {
+				continue
+			}
 			for _, row := range p.tbl.Rows() {
 				row[prj.Alias] = row[prj.Binding]
 			}
 		}
+		p.applyExpressionProjections()
+		if err := p.applyScalarProjections(); err != nil {
+			return err
+		}
 		tracer.Trace(p.tracer, func() []string {
 			return []string{fmt.Sprintf("Output bindings projected %v", p.stm.OutputBindings())}
 		})
@@ -735,6 +1299,9 @@ func (p *queryPlan) projectAndGroupBy() error {
 		return []string{"Starting group reduce and projection"}
 	})
 	// The table needs to be group reduced.
+	if err := p.computeGroupingKeys(); err != nil {
+		return err
+	}
 	// Project only binding involved in the group operation.
 	tmpBindings := []string{}
 	mapBindings := make(map[string]bool)
@@ -745,21 +1312,26 @@ func (p *queryPlan) projectAndGroupBy() error {
 		tracer.Trace(p.tracer, func() []string {
 			return []string{"Analysing projection " + prj.String()}
 		})
+		// Computed projections were already stored under their alias.
+		in := prj.Binding
+		if prj.Scalar != "" || prj.Expression != nil {
+			in = prj.Alias
+		}
 		// Only include used incoming bindings.
-		tmpBindings = append(tmpBindings, prj.Binding)
+		tmpBindings = append(tmpBindings, in)
 		// Update sorting configuration.
 		found := false
 		for _, g := range p.stm.GroupByBindings() {
-			if prj.Binding == g {
+			if in == g {
 				found = true
 			}
 		}
-		if found && !mapBindings[prj.Binding] {
-			cfg = append(cfg, table.SortConfig{{Binding: prj.Binding}}...)
-			mapBindings[prj.Binding] = true
+		if found && !mapBindings[in] {
+			cfg = append(cfg, table.SortConfig{{Binding: in}}...)
+			mapBindings[in] = true
 		}
 		aap := table.AliasAccPair{
-			InAlias: prj.Binding,
+			InAlias: in,
 		}
 		if prj.Alias == "" {
 			aap.OutAlias = prj.Binding
@@ -777,19 +1349,36 @@ func (p *queryPlan) projectAndGroupBy() error {
 		case lexer.ItemSum:
 			cell := p.tbl.Rows()[0][prj.Binding]
 			if cell.L == nil {
-				return fmt.Errorf("can only sum int64 and float64 literals; found %s instead for binding %q", cell, prj.Binding)
+				return fmt.Errorf("can only sum int64, float64, and decimal literals; found %s instead for binding %q", cell, prj.Binding)
 			}
 			switch cell.L.Type() {
 			case literal.Int64:
 				aap.Acc = table.NewSumInt64LiteralAccumulator(0)
 			case literal.Float64:
 				aap.Acc = table.NewSumFloat64LiteralAccumulator(0)
+			case literal.Decimal:
+				aap.Acc = table.NewSumDecimalLiteralAccumulator(new(big.Rat))
 			default:
-				return fmt.Errorf("can only sum int64 and float64 literals; found literal type %s instead for binding %q", cell.L.Type(), prj.Binding)
+				return fmt.Errorf("can only sum int64, float64, and decimal literals; found literal type %s instead for binding %q", cell.L.Type(), prj.Binding)
 			}
+		case lexer.ItemFunction:
+			f, ok := table.LookupAccumulator(prj.Function)
+			if !ok {
+				return fmt.Errorf("unknown aggregation function %q for binding %q", prj.Function, prj.Binding)
+			}
+			aap.Acc = f()
 		}
 		aaps = append(aaps, aap)
 	}
+	// Group by expressions not used by any projection are only grouping keys.
+	gbes := p.stm.GroupByExpressions()
+	for _, g := range p.stm.GroupByBindings() {
+		if _, ok := gbes[g]; ok && !mapBindings[g] {
+			tmpBindings = append(tmpBindings, g)
+			cfg = append(cfg, table.SortConfig{{Binding: g}}...)
+			mapBindings[g] = true
+		}
+	}
 	tracer.Trace(p.tracer, func() []string {
 		return []string{fmt.Sprintf("Projecting %v", tmpBindings)}
 	})
@@ -799,21 +1388,172 @@ func (p *queryPlan) projectAndGroupBy() error {
 	tracer.Trace(p.tracer, func() []string {
 		return []string{"Reducing the table using configuration " + cfg.String()}
 	})
-	p.tbl.Reduce(cfg, aaps)
-	return nil
+	if err := p.tbl.Reduce(cfg, aaps); err != nil {
+		return err
+	}
+	if len(gbes) == 0 {
+		return nil
+	}
+	return p.tbl.ProjectBindings(p.stm.OutputBindings())
 }
 
-// orderBy takes the resulting table and sorts its contents according to the
-// specifications of the ORDER BY clause.
-func (p *queryPlan) orderBy() {
-	order := p.stm.OrderByConfig()
-	if len(order) <= 0 {
-		return
-	}
-	tracer.Trace(p.tracer, func() []string {
-		return []string{"Ordering by " + order.String()}
+// computeGroupingKeys stores the values of the computed projections under
+// their alias and the values of the group by expressions under their
+// binding, so they can be used as grouping keys.
+func (p *queryPlan) computeGroupingKeys() error {
+	var bs []string
+	for _, prj := range p.stm.Projections() {
+		if prj.Scalar == "" && prj.Expression == nil {
+			continue
+		}
+		bs = append(bs, prj.Alias)
+		if prj.Scalar == "" {
+			continue
+		}
+		for _, row := range p.tbl.Rows() {
+			row[prj.Alias] = row[prj.Binding]
+		}
+	}
+	p.applyExpressionProjections()
+	if err := p.applyScalarProjections(); err != nil {
+		return err
+	}
+	for _, g := range p.stm.GroupByBindings() {
+		e, ok := p.stm.GroupByExpressions()[g]
+		if !ok {
+			continue
+		}
+		tracer.Trace(p.tracer, func() []string {
+			return []string{fmt.Sprintf("Computing grouping key %q", g)}
+		})
+		bs = append(bs, g)
+		for _, row := range p.tbl.Rows() {
+			c, err := e.Value(row)
+			if err != nil {
+				c = &table.Cell{}
+			}
+			row[g] = c
+		}
+	}
+	p.tbl.AddBindings(bs)
+	return nil
+}
+
+// applyExpressionProjections computes the values of the projections defined
+// by expressions. Rows for which the expression cannot be computed, for
+// instance because a binding is unbound, get an empty value.
+func (p *queryPlan) applyExpressionProjections() {
+	for _, prj := range p.stm.Projections() {
+		if prj.Expression == nil {
+			continue
+		}
+		tracer.Trace(p.tracer, func() []string {
+			return []string{fmt.Sprintf("Computing %q for binding %q", prj.Expression, prj.Alias)}
+		})
+		for _, row := range p.tbl.Rows() {
+			projectExpression(prj, row)
+		}
+	}
+}
+
+// projectExpression sets the alias of the provided expression projection to
+// the value of the expression for the row. Values that cannot be computed are
+// left unbound.
+func projectExpression(prj *semantic.Projection, row table.Row) {
+	c, err := prj.Expression.Value(row)
+	if err != nil {
+		c = &table.Cell{}
+	}
+	row[prj.Alias] = c
+}
+
+// applyScalarProjections replaces the projected values with the result of
+// applying the scalar functions requested by the projections.
+func (p *queryPlan) applyScalarProjections() error {
+	for _, prj := range p.stm.Projections() {
+		if prj.Scalar == "" {
+			continue
+		}
+		fn, _, ok := semantic.LookupFunction(prj.Scalar)
+		if !ok {
+			return fmt.Errorf("unknown function %q for binding %q", prj.Scalar, prj.Binding)
+		}
+		out := prj.Alias
+		if out == "" {
+			out = prj.Binding
+		}
+		tracer.Trace(p.tracer, func() []string {
+			return []string{fmt.Sprintf("Applying function %q to binding %q", prj.Scalar, out)}
+		})
+		for _, row := range p.tbl.Rows() {
+			if err := projectScalar(prj, fn, out, row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// projectScalar replaces the value of the output binding of the row with the
+// result of applying the scalar function of the projection to it.
+func projectScalar(prj *semantic.Projection, fn semantic.Function, out string, row table.Row) error {
+	v := row[out]
+	if v == nil || (v.S == nil && v.N == nil && v.P == nil && v.L == nil && v.T == nil) {
+		// Unbound values, like the ones of optional clauses, stay unbound.
+		return nil
+	}
+	c, err := fn([]*table.Cell{v})
+	if err != nil {
+		return fmt.Errorf("function %q failed for binding %q; %v", prj.Scalar, prj.Binding, err)
+	}
+	row[out] = c
+	return nil
+}
+
+// orderBy takes the resulting table and sorts its contents according to the
+// specifications of the ORDER BY clause.
+// Expressions are computed into temporary sort key columns that are removed
+// once the table is sorted.
+func (p *queryPlan) orderBy() error {
+	order := p.stm.OrderByConfig()
+	if len(order) <= 0 {
+		return nil
+	}
+	exps := p.stm.OrderByExpressions()
+	if len(exps) > 0 && p.tbl.NumRows() == 0 {
+		return nil
+	}
+	var keys []string
+	for _, cfg := range order {
+		e, ok := exps[cfg.Binding]
+		if !ok || p.tbl.HasBinding(cfg.Binding) {
+			continue
+		}
+		for _, r := range p.tbl.Rows() {
+			c, err := e.Value(r)
+			if err != nil {
+				// Rows that cannot be evaluated sort as empty values.
+				c = &table.Cell{}
+			}
+			r[cfg.Binding] = c
+		}
+		keys = append(keys, cfg.Binding)
+	}
+	bs := p.tbl.Bindings()
+	p.tbl.AddBindings(keys)
+	tracer.Trace(p.tracer, func() []string {
+		return []string{"Ordering by " + order.String()}
 	})
 	p.tbl.Sort(order)
+	if len(keys) == 0 {
+		return nil
+	}
+	for _, r := range p.tbl.Rows() {
+		for _, k := range keys {
+			delete(r, k)
+		}
+	}
+	return p.tbl.ProjectBindings(bs)
 }
 
 // having runs the filtering based on the having clause if needed.
@@ -839,6 +1579,33 @@ func (p *queryPlan) having() error {
 	return nil
 }
 
+// fetchLimit returns the number of rows that can be pushed down to the
+// storage lookups. It returns 0 if no limit can be pushed down, since the
+// final number of rows depends on later stages of the plan. When an offset is
+// also set, the skipped rows need to be fetched too.
+func (p *queryPlan) fetchLimit() int64 {
+	if !p.ask && !p.stm.IsLimitSet() {
+		return 0
+	}
+	if len(p.stm.GraphPatternClauses()) != 1 || len(p.stm.Filters()) != 0 || len(p.stm.Subqueries()) != 0 || len(p.stm.GroupBy()) != 0 || len(p.stm.HavingExpression()) != 0 || len(p.stm.OrderByConfig()) != 0 {
+		return 0
+	}
+	if p.ask {
+		return 1
+	}
+	return p.stm.Limit() + p.stm.Offset()
+}
+
+// offset skips the first rows of the table if the offset clause is available.
+func (p *queryPlan) offset() {
+	if p.stm.IsOffsetSet() {
+		tracer.Trace(p.tracer, func() []string {
+			return []string{"Skip the first " + strconv.Itoa(int(p.stm.Offset())) + " results"}
+		})
+		p.tbl.Offset(p.stm.Offset())
+	}
+}
+
 // limit truncates the table if the limit clause if available.
 func (p *queryPlan) limit() {
 	if p.stm.IsLimitSet() {
@@ -859,23 +1626,130 @@ func (p *queryPlan) Execute(ctx context.Context) (*table.Table, error) {
 		return nil, err
 	}
 	p.grfs = p.stm.InputGraphs()
+	release, err := p.snapshotGraphs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer p.releaseMemory()
+	if p.cache == nil {
+		return p.execute(ctx)
+	}
+	key, epochs, ok := p.cacheKey(ctx)
+	if !ok {
+		tracer.Trace(p.tracer, func() []string {
+			return []string{"Results cannot be cached since not all the graphs provide an epoch"}
+		})
+		return p.execute(ctx)
+	}
+	if tbl, ok := p.cache.get(key, epochs); ok {
+		tracer.Trace(p.tracer, func() []string {
+			return []string{fmt.Sprintf("Returning %d cached rows", tbl.NumRows())}
+		})
+		return tbl, nil
+	}
+	tbl, err := p.execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.cache.add(key, epochs, tbl)
+	return tbl, nil
+}
+
+// snapshotGraphs replaces the input graphs that provide snapshots with a
+// snapshot of them, so all the clauses of the plan see the same triples even
+// if the graphs change while the plan runs. It returns the function that
+// releases the snapshots taken.
+func (p *queryPlan) snapshotGraphs(ctx context.Context) (func(), error) {
+	var snaps []storage.GraphSnapshot
+	release := func() {
+		for _, s := range snaps {
+			s.Release(ctx)
+		}
+	}
+	grfs := make([]storage.Graph, len(p.grfs))
+	for i, g := range p.grfs {
+		s, err := storage.Snapshot(ctx, g)
+		if err == storage.ErrNoSnapshots {
+			grfs[i] = g
+			continue
+		}
+		if err != nil {
+			release()
+			return nil, err
+		}
+		snaps = append(snaps, s)
+		grfs[i] = s
+	}
+	p.grfs = grfs
+	tracer.Trace(p.tracer, func() []string {
+		return []string{fmt.Sprintf("Reading %d of %d graphs from snapshots", len(snaps), len(grfs))}
+	})
+	return release, nil
+}
+
+// execute runs the plan once its graphs are initialized.
+func (p *queryPlan) execute(ctx context.Context) (*table.Table, error) {
+	p.orderGraphPattern(ctx)
+	p.rows = make(map[string]int)
 	// Retrieve the data.
 	lo := p.stm.GlobalLookupOptions()
 	tracer.Trace(p.tracer, func() []string {
 		return []string{"Setting global lookup options to " + lo.String()}
 	})
+	if p.stm.IsLimitSet() && p.streamable() {
+		// Only the rows within the limit need to be computed.
+		tracer.Trace(p.tracer, func() []string {
+			return []string{"Streaming the rows of the graph pattern up to the limit"}
+		})
+		it, err := p.pipeline(ctx, lo)
+		if err != nil {
+			return nil, err
+		}
+		tbl, err := streamTable(ctx, it)
+		if err != nil {
+			return nil, err
+		}
+		p.recordRows("limit", fmt.Sprintf("%d", p.stm.Limit()), tbl.NumRows())
+		return tbl, nil
+	}
 	if err := p.processGraphPattern(ctx, lo); err != nil {
 		return nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if err := p.projectAndGroupBy(); err != nil {
 		return nil, err
 	}
-	p.orderBy()
+	p.recordRows("project", "", p.tbl.NumRows())
+	if gb := p.stm.GroupBy(); len(gb) > 0 {
+		p.recordRows("group by", strings.Join(gb, ", "), p.tbl.NumRows())
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.orderBy(); err != nil {
+		return nil, err
+	}
+	if ob := p.stm.OrderByConfig(); len(ob) > 0 {
+		p.recordRows("order by", ob.String(), p.tbl.NumRows())
+	}
 	err := p.having()
 	if err != nil {
 		return nil, err
 	}
+	if p.stm.HasHavingClause() {
+		p.recordRows("having", havingTarget(p.stm), p.tbl.NumRows())
+	}
+	p.offset()
+	if p.stm.IsOffsetSet() {
+		p.recordRows("offset", fmt.Sprintf("%d", p.stm.Offset()), p.tbl.NumRows())
+	}
 	p.limit()
+	if p.stm.IsLimitSet() {
+		p.recordRows("limit", fmt.Sprintf("%d", p.stm.Limit()), p.tbl.NumRows())
+	}
 	if p.tbl.NumRows() == 0 {
 		// Correct the bindings.
 		t, err := table.New(p.stm.OutputBindings())
@@ -887,16 +1761,58 @@ func (p *queryPlan) Execute(ctx context.Context) (*table.Table, error) {
 	return p.tbl, nil
 }
 
+// writeSubqueries writes the indented plans of the provided subqueries.
+func (p *queryPlan) writeSubqueries(ctx context.Context, b *bytes.Buffer, sqs []*semantic.Statement) {
+	for _, sq := range sqs {
+		b.WriteString("\tjoin subquery\n")
+		sp, err := newQueryPlan(ctx, p.store, sq, p.chanSize, p.tracer)
+		if err != nil {
+			b.WriteString(fmt.Sprintf("\t\tinvalid subquery: %v\n", err))
+			continue
+		}
+		for _, l := range strings.Split(strings.TrimSpace(sp.String(ctx)), "\n") {
+			b.WriteString("\t\t")
+			b.WriteString(l)
+			b.WriteString("\n")
+		}
+	}
+}
+
 // String returns a readable description of the execution plan.
 func (p *queryPlan) String(ctx context.Context) string {
 	b := bytes.NewBufferString("QUERY plan:\n\n")
 	b.WriteString("using store(\"")
 	b.WriteString(p.store.Name(nil))
 	b.WriteString(fmt.Sprintf("\") graphs %v\nresolve\n", p.grfsNames))
-	for _, c := range p.cls {
-		b.WriteString("\t")
-		b.WriteString(c.String())
-		b.WriteString("\n")
+	if len(p.unions) == 0 {
+		for _, c := range p.cls {
+			b.WriteString("\t")
+			b.WriteString(c.String())
+			b.WriteString("\n")
+		}
+		p.writeSubqueries(ctx, b, p.stm.Subqueries())
+		for _, f := range p.stm.Filters() {
+			b.WriteString("\tfilter ")
+			b.WriteString(f.String())
+			b.WriteString("\n")
+		}
+	}
+	ufs, usqs := p.stm.GraphPatternUnionFilters(), p.stm.GraphPatternUnionSubqueries()
+	for i, u := range p.unions {
+		if i > 0 {
+			b.WriteString("union\n")
+		}
+		for _, c := range u {
+			b.WriteString("\t")
+			b.WriteString(c.String())
+			b.WriteString("\n")
+		}
+		p.writeSubqueries(ctx, b, usqs[i])
+		for _, f := range ufs[i] {
+			b.WriteString("\tfilter ")
+			b.WriteString(f.String())
+			b.WriteString("\n")
+		}
 	}
 	b.WriteString("project results using\n")
 	for _, p := range p.stm.Projection() {
@@ -930,6 +1846,11 @@ func (p *queryPlan) String(ctx context.Context) string {
 		b.WriteString(fmt.Sprintf("%d", p.stm.Limit()))
 		b.WriteString(" rows\n")
 	}
+	if p.stm.IsOffsetSet() {
+		b.WriteString("skip the first ")
+		b.WriteString(fmt.Sprintf("%d", p.stm.Offset()))
+		b.WriteString(" rows\n")
+	}
 	return b.String()
 }
 
@@ -1039,6 +1960,15 @@ func (p *constructPlan) Execute(ctx context.Context) (*table.Table, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Construct statements without output graphs return the constructed
+	// triples instead of inserting them.
+	var res *table.Table
+	if p.construct && len(p.stm.OutputGraphNames()) == 0 {
+		res, err = table.New([]string{SubjectBinding, PredicateBinding, ObjectBinding})
+		if err != nil {
+			return nil, err
+		}
+	}
 	// The buffered channel has capacity to accommodate twice the amount of triples stored in a single call.
 	tripChan := make(chan *triple.Triple, 2*p.bulkSize)
 	done := make(chan bool)
@@ -1059,30 +1989,68 @@ func (p *constructPlan) Execute(ctx context.Context) (*table.Table, error) {
 				return g.AddTriples(ctx, d)
 			}
 		}
+		seen := make(map[string]bool)
+		flush := func(ts []*triple.Triple) {
+			if res == nil {
+				update(ctx, ts, p.stm.OutputGraphNames(), p.store, updateFunc)
+				return
+			}
+			// The returned triples behave as a set, as graphs do.
+			for _, t := range ts {
+				if id := t.UUID().String(); !seen[id] {
+					seen[id] = true
+					res.AddRow(spoRow(t))
+				}
+			}
+		}
 		for elem := range tripChan {
 			ts = append(ts, elem)
 			if len(ts) >= p.bulkSize {
-				update(ctx, ts, p.stm.OutputGraphNames(), p.store, updateFunc)
+				flush(ts)
 				ts = []*triple.Triple{}
 			}
 		}
 		if len(ts) > 0 {
-			update(ctx, ts, p.stm.OutputGraphNames(), p.store, updateFunc)
+			flush(ts)
 		}
 		done <- true
 	}()
 
+	err = p.constructTriples(ctx, tbl, tripChan)
+	close(tripChan)
+	// Wait until all triples are added to the store.
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	if res != nil {
+		return res, nil
+	}
+	if p.construct {
+		if err := recordDerivation(ctx, p.store, p.stm); err != nil {
+			return nil, err
+		}
+	}
+	return tbl, nil
+}
+
+// constructTriples sends the triples built out of the rows of the provided
+// table to the channel. It stops if the context is canceled.
+func (p *constructPlan) constructTriples(ctx context.Context, tbl *table.Table, tripChan chan<- *triple.Triple) error {
 	for _, cc := range p.stm.ConstructClauses() {
 		for _, r := range tbl.Rows() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			t, err := p.processConstructClause(cc, tbl, r)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			if len(cc.PredicateObjectPairs()) > 1 {
 				// We need to reify a blank node.
 				rts, bn, err := t.Reify()
 				if err != nil {
-					return nil, fmt.Errorf("triple.Reify failed to reify %v with error %v", t, err)
+					return fmt.Errorf("triple.Reify failed to reify %v with error %v", t, err)
 				}
 				for _, trpl := range rts[1:] {
 					tripChan <- trpl
@@ -1090,11 +2058,11 @@ func (p *constructPlan) Execute(ctx context.Context) (*table.Table, error) {
 				for _, pop := range cc.PredicateObjectPairs()[1:] {
 					rprd, robj, err := p.processPredicateObjectPair(pop, tbl, r)
 					if err != nil {
-						return nil, err
+						return err
 					}
 					rt, err := triple.New(bn, rprd, robj)
 					if err != nil {
-						return nil, err
+						return err
 					}
 					tripChan <- rt
 				}
@@ -1103,10 +2071,7 @@ func (p *constructPlan) Execute(ctx context.Context) (*table.Table, error) {
 			}
 		}
 	}
-	close(tripChan)
-	// Wait until all triples are added to the store.
-	<-done
-	return tbl, nil
+	return nil
 }
 
 // String returns a readable description of the execution plan.
@@ -1123,6 +2088,9 @@ func (p *constructPlan) String(ctx context.Context) string {
 	for _, gn := range p.stm.OutputGraphNames() {
 		b.WriteString(fmt.Sprintf("\t%v\n", gn))
 	}
+	if p.construct && len(p.stm.OutputGraphNames()) == 0 {
+		b.WriteString("\treturn constructed triples\n")
+	}
 	b.WriteString("Construct clauses:\n")
 	for _, cc := range p.stm.ConstructClauses() {
 		b.WriteString(fmt.Sprintf("\t%v\n", cc))
@@ -1131,6 +2099,218 @@ func (p *constructPlan) String(ctx context.Context) string {
 	return b.String()
 }
 
+// AskBinding is the binding of the table returned by ASK statements.
+const AskBinding = "?ask"
+
+// askPlan encapsulates the sequence of instructions that need to be executed
+// to decide if a graph pattern has at least one solution.
+type askPlan struct {
+	stm       *semantic.Statement
+	store     storage.Store
+	tracer    io.Writer
+	queryPlan *queryPlan
+}
+
+// Type returns the type of plan used by the executor.
+func (p *askPlan) Type() string {
+	return "ASK"
+}
+
+// Execute resolves the graph pattern and returns a single row table with a
+// boolean literal stating if any solution was found.
+func (p *askPlan) Execute(ctx context.Context) (*table.Table, error) {
+	if err := p.stm.Init(ctx, p.store); err != nil {
+		return nil, err
+	}
+	qp := p.queryPlan
+	qp.grfs = p.stm.InputGraphs()
+	release, err := qp.snapshotGraphs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	lo := p.stm.GlobalLookupOptions()
+	var found bool
+	if len(p.stm.Bindings()) == 0 {
+		b, err := p.existWithoutBindings(ctx, lo)
+		if err != nil {
+			return nil, err
+		}
+		found = b
+	} else {
+		if err := qp.processGraphPattern(ctx, lo); err != nil {
+			return nil, err
+		}
+		found = qp.tbl.NumRows() > 0
+	}
+	tracer.Trace(p.tracer, func() []string {
+		return []string{fmt.Sprintf("Solution found: %v", found)}
+	})
+	l, err := literal.DefaultBuilder().Build(literal.Bool, found)
+	if err != nil {
+		return nil, err
+	}
+	t, err := table.New([]string{AskBinding})
+	if err != nil {
+		return nil, err
+	}
+	t.AddRow(table.Row{
+		AskBinding: &table.Cell{L: l},
+	})
+	return t, nil
+}
+
+// existWithoutBindings checks if any of the graph patterns, which contain no
+// bindings, has a matching triple for each of its clauses. Clauses without
+// bindings do not produce rows, hence the subject of each clause is aliased
+// to a synthetic binding to check if any triple was fetched.
+func (p *askPlan) existWithoutBindings(ctx context.Context, lo *storage.LookupOptions) (bool, error) {
+	ptrns := p.stm.GraphPatternUnions()
+	if len(ptrns) == 0 {
+		ptrns = [][]*semantic.GraphClause{p.stm.GraphPatternClauses()}
+	}
+	for _, clss := range ptrns {
+		found := true
+		for _, c := range clss {
+			if c.Optional {
+				continue
+			}
+			cls := *c
+			cls.SAlias = AskBinding
+			tbl, err := simpleFetch(ctx, p.queryPlan.grfs, &cls, lo, 1, p.queryPlan.chanSize, p.tracer)
+			if err != nil {
+				return false, err
+			}
+			if tbl.NumRows() == 0 {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// String returns a readable description of the execution plan.
+func (p *askPlan) String(ctx context.Context) string {
+	return fmt.Sprintf("ASK plan:\n\nstop at the first solution\n\n%v", p.queryPlan.String(ctx))
+}
+
+// describePlan encapsulates the sequence of instructions that need to be
+// executed to retrieve the neighborhood of a node.
+type describePlan struct {
+	stm      *semantic.Statement
+	store    storage.Store
+	chanSize int
+	tracer   io.Writer
+}
+
+// Type returns the type of plan used by the executor.
+func (p *describePlan) Type() string {
+	return "DESCRIBE"
+}
+
+// graphs returns the graphs to describe the node in. If no graphs were
+// provided, all the graphs in the store are used.
+func (p *describePlan) graphs(ctx context.Context) ([]storage.Graph, error) {
+	if err := p.stm.Init(ctx, p.store); err != nil {
+		return nil, err
+	}
+	if len(p.stm.InputGraphNames()) > 0 {
+		return p.stm.InputGraphs(), nil
+	}
+	errs := make(chan error, 1)
+	names := make(chan string)
+	go func() {
+		errs <- p.store.GraphNames(ctx, names)
+	}()
+	var gns []string
+	for name := range names {
+		gns = append(gns, name)
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	var gs []storage.Graph
+	for _, gn := range gns {
+		g, err := p.store.Graph(ctx, gn)
+		if err != nil {
+			return nil, err
+		}
+		gs = append(gs, g)
+	}
+	return gs, nil
+}
+
+// Execute retrieves all the triples where the described node appears as
+// subject or object, expanding the neighborhood up to the requested depth.
+func (p *describePlan) Execute(ctx context.Context) (*table.Table, error) {
+	gs, err := p.graphs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := table.New([]string{SubjectBinding, PredicateBinding, ObjectBinding})
+	if err != nil {
+		return nil, err
+	}
+	lo := p.stm.GlobalLookupOptions()
+	n := p.stm.DescribeNode()
+	visited, seen := map[string]bool{n.String(): true}, make(map[string]bool)
+	frontier := []*node.Node{n}
+	for d := int64(0); d < p.stm.DescribeDepth() && len(frontier) > 0; d++ {
+		d := d
+		tracer.Trace(p.tracer, func() []string {
+			return []string{fmt.Sprintf("Describing %d nodes at depth %d", len(frontier), d+1)}
+		})
+		var next []*node.Node
+		for _, fn := range frontier {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			for _, g := range gs {
+				ts, err := nodeTriples(ctx, g, fn, lo, p.chanSize)
+				if err != nil {
+					return nil, err
+				}
+				for _, t := range ts {
+					if id := t.UUID().String(); !seen[id] {
+						seen[id] = true
+						res.AddRow(spoRow(t))
+					}
+					nbs := []*node.Node{t.Subject()}
+					if on, err := t.Object().Node(); err == nil {
+						nbs = append(nbs, on)
+					}
+					for _, nb := range nbs {
+						if !visited[nb.String()] {
+							visited[nb.String()] = true
+							next = append(next, nb)
+						}
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return res, nil
+}
+
+// String returns a readable description of the execution plan.
+func (p *describePlan) String(ctx context.Context) string {
+	b := bytes.NewBufferString("DESCRIBE plan:\n\n")
+	b.WriteString(fmt.Sprintf("describe node %v up to depth %d\n", p.stm.DescribeNode(), p.stm.DescribeDepth()))
+	b.WriteString("Input graphs:\n")
+	if len(p.stm.InputGraphNames()) == 0 {
+		b.WriteString(fmt.Sprintf("\tall graphs in store(%q)\n", p.store.Name(ctx)))
+	}
+	for _, gn := range p.stm.InputGraphNames() {
+		b.WriteString(fmt.Sprintf("\t%v\n", gn))
+	}
+	return b.String()
+}
+
 // showPlan creates a plan to show all the graphs available.
 type showPlan struct {
 	stm    *semantic.Statement
@@ -1145,7 +2325,28 @@ func (p *showPlan) Type() string {
 
 // Execute the show statement.
 func (p *showPlan) Execute(ctx context.Context) (*table.Table, error) {
-	t, err := table.New([]string{"?graph_id"})
+	switch p.stm.ShowType() {
+	case semantic.ShowPredicates:
+		return p.predicates(ctx)
+	case semantic.ShowTripleCount:
+		return p.tripleCount(ctx)
+	case semantic.ShowStats:
+		return p.stats(ctx)
+	default:
+		return p.graphNames(ctx)
+	}
+}
+
+// graphNames returns the table listing all the graphs in the store. If the
+// store keeps graph metadata, the table also lists the creation time,
+// description, and labels of each graph.
+func (p *showPlan) graphNames(ctx context.Context) (*table.Table, error) {
+	_, withMetadata := p.store.(storage.MetadataProvider)
+	bs := []string{"?graph_id"}
+	if withMetadata {
+		bs = append(bs, "?created", "?description", "?labels")
+	}
+	t, err := table.New(bs)
 	if err != nil {
 		return nil, err
 	}
@@ -1156,30 +2357,191 @@ func (p *showPlan) Execute(ctx context.Context) (*table.Table, error) {
 		close(errs)
 	}()
 
+	var ids []string
 	for name := range names {
-		id := name
+		ids = append(ids, name)
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		r := table.Row{
+			"?graph_id": &table.Cell{S: table.CellString(id)},
+		}
+		if withMetadata {
+			md, err := storage.GetGraphMetadata(ctx, p.store, id)
+			if err != nil {
+				return nil, err
+			}
+			created := md.Created
+			r["?created"] = &table.Cell{T: &created}
+			r["?description"] = &table.Cell{S: table.CellString(md.Description)}
+			r["?labels"] = &table.Cell{S: table.CellString(formatLabels(md.Labels))}
+		}
+		t.AddRow(r)
+	}
+	return t, nil
+}
+
+// formatLabels returns the labels of a graph as a comma separated list of
+// key=value pairs sorted by key.
+func formatLabels(labels map[string]string) string {
+	var kvs []string
+	for k, v := range labels {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+	return strings.Join(kvs, ", ")
+}
+
+// predicates returns the table listing the predicate IDs used in each of the
+// input graphs.
+func (p *showPlan) predicates(ctx context.Context) (*table.Table, error) {
+	if err := p.stm.Init(ctx, p.store); err != nil {
+		return nil, err
+	}
+	t, err := table.New([]string{"?graph_id", "?predicate_id"})
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range p.stm.InputGraphs() {
+		ids, err := storage.PredicateIDs(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			t.AddRow(table.Row{
+				"?graph_id":     &table.Cell{S: table.CellString(g.ID(ctx))},
+				"?predicate_id": &table.Cell{S: table.CellString(id)},
+			})
+		}
+	}
+	return t, nil
+}
+
+// tripleCount returns the table with the number of triples in each of the
+// input graphs.
+func (p *showPlan) tripleCount(ctx context.Context) (*table.Table, error) {
+	if err := p.stm.Init(ctx, p.store); err != nil {
+		return nil, err
+	}
+	t, err := table.New([]string{"?graph_id", "?count"})
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range p.stm.InputGraphs() {
+		cnt, err := storage.CountTriples(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		l, err := literal.DefaultBuilder().Build(literal.Int64, cnt)
+		if err != nil {
+			return nil, err
+		}
 		t.AddRow(table.Row{
-			"?graph_id": &table.Cell{
-				S: &id,
-			},
+			"?graph_id": &table.Cell{S: table.CellString(g.ID(ctx))},
+			"?count":    &table.Cell{L: l},
 		})
 	}
-	if <-errs != nil {
+	return t, nil
+}
+
+// stats returns the table with the statistics of each of the input graphs.
+// Graphs without a time anchored triple have empty anchor cells.
+func (p *showPlan) stats(ctx context.Context) (*table.Table, error) {
+	if err := p.stm.Init(ctx, p.store); err != nil {
+		return nil, err
+	}
+	t, err := table.New([]string{"?graph_id", "?triples", "?subjects", "?predicates", "?objects", "?first_anchor", "?last_anchor", "?memory_bytes"})
+	if err != nil {
 		return nil, err
 	}
+	for _, g := range p.stm.InputGraphs() {
+		s, err := storage.ScanStatistics(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		r := table.Row{
+			"?graph_id":     &table.Cell{S: table.CellString(g.ID(ctx))},
+			"?first_anchor": &table.Cell{T: s.FirstAnchor},
+			"?last_anchor":  &table.Cell{T: s.LastAnchor},
+		}
+		for b, v := range map[string]int64{
+			"?triples":      s.Triples,
+			"?subjects":     s.Subjects,
+			"?predicates":   int64(len(s.Predicates)),
+			"?objects":      s.Objects,
+			"?memory_bytes": s.MemoryBytes,
+		} {
+			l, err := literal.DefaultBuilder().Build(literal.Int64, v)
+			if err != nil {
+				return nil, err
+			}
+			r[b] = &table.Cell{L: l}
+		}
+		t.AddRow(r)
+	}
 	return t, nil
 }
 
 // String returns a readable description of the execution plan.
 func (p *showPlan) String(ctx context.Context) string {
-	return fmt.Sprintf("SHOW plan:\n\nstore(%q).GraphNames(_, _)", p.store.Name(ctx))
+	switch p.stm.ShowType() {
+	case semantic.ShowPredicates:
+		return fmt.Sprintf("SHOW plan:\n\nstorage.PredicateIDs(_) for graphs %v", p.stm.InputGraphNames())
+	case semantic.ShowTripleCount:
+		return fmt.Sprintf("SHOW plan:\n\nstorage.CountTriples(_) for graphs %v", p.stm.InputGraphNames())
+	case semantic.ShowStats:
+		return fmt.Sprintf("SHOW plan:\n\nstorage.ScanStatistics(_) for graphs %v", p.stm.InputGraphNames())
+	default:
+		return fmt.Sprintf("SHOW plan:\n\nstore(%q).GraphNames(_, _)", p.store.Name(ctx))
+	}
 }
 
-// New create a new executable plan given a semantic BQL statement.
+// New create a new executable plan given a semantic BQL statement. If the
+// statement is being explained, the plan returns the description of the
+// steps the statement would run instead.
 func New(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize, bulkSize int, w io.Writer) (Executor, error) {
+	return NewWithOptions(ctx, store, stm, chanSize, bulkSize, w, Options{})
+}
+
+// NewWithOptions creates a new executable plan like New does, but the plan
+// enforces the limits set in the provided options when executed.
+func NewWithOptions(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize, bulkSize int, w io.Writer, opts Options) (Executor, error) {
+	if opts.MaxExecutionTime < 0 || opts.MaxIntermediateRows < 0 || opts.Parallelism < 0 || opts.MaxMemory < 0 {
+		return nil, fmt.Errorf("planner.New: invalid negative limits in options %+v", opts)
+	}
+	pln, err := newPlan(ctx, store, stm, chanSize, bulkSize, w, opts)
+	if err != nil {
+		return nil, err
+	}
+	if stm.IsExplain() {
+		return &explainPlan{
+			plan:   pln,
+			tracer: w,
+		}, nil
+	}
+	if opts.MaxExecutionTime > 0 {
+		return &budgetPlan{
+			plan:             pln,
+			maxExecutionTime: opts.MaxExecutionTime,
+		}, nil
+	}
+	return pln, nil
+}
+
+// newPlan creates the executable plan for the provided statement type. The
+// query plans built use the provided options.
+func newPlan(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize, bulkSize int, w io.Writer, opts Options) (Executor, error) {
 	switch stm.Type() {
 	case semantic.Query:
-		return newQueryPlan(ctx, store, stm, chanSize, w)
+		qp, err := newQueryPlan(ctx, store, stm, chanSize, w)
+		if err != nil {
+			return nil, err
+		}
+		qp.setOptions(opts)
+		qp.cache = opts.ResultCache
+		return qp, nil
 	case semantic.Insert:
 		return &insertPlan{
 			stm:    stm,
@@ -1206,6 +2568,7 @@ func New(ctx context.Context, store storage.Store, stm *semantic.Statement, chan
 		}, nil
 	case semantic.Construct:
 		qp, _ := newQueryPlan(ctx, store, stm, chanSize, w)
+		qp.setOptions(opts)
 		return &constructPlan{
 			stm:       stm,
 			store:     store,
@@ -1216,6 +2579,7 @@ func New(ctx context.Context, store storage.Store, stm *semantic.Statement, chan
 		}, nil
 	case semantic.Deconstruct:
 		qp, _ := newQueryPlan(ctx, store, stm, chanSize, w)
+		qp.setOptions(opts)
 		return &constructPlan{
 			stm:       stm,
 			store:     store,
@@ -1224,12 +2588,34 @@ func New(ctx context.Context, store storage.Store, stm *semantic.Statement, chan
 			queryPlan: qp,
 			construct: false,
 		}, nil
+	case semantic.Ask:
+		qp, err := newQueryPlan(ctx, store, stm, chanSize, w)
+		if err != nil {
+			return nil, err
+		}
+		qp.ask = true
+		qp.setOptions(opts)
+		return &askPlan{
+			stm:       stm,
+			store:     store,
+			tracer:    w,
+			queryPlan: qp,
+		}, nil
+	case semantic.Describe:
+		return &describePlan{
+			stm:      stm,
+			store:    store,
+			chanSize: chanSize,
+			tracer:   w,
+		}, nil
 	case semantic.Show:
 		return &showPlan{
 			stm:    stm,
 			store:  store,
 			tracer: w,
 		}, nil
+	case semantic.Begin, semantic.Commit, semantic.Rollback:
+		return nil, fmt.Errorf("planner.New: %s statements can only be run as part of a script", stm.Type())
 	default:
 		return nil, fmt.Errorf("planner.New: unknown statement type in statement %v", stm)
 	}
@@ -18,16 +18,24 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/badwolf/bql/grammar"
 	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
 	"github.com/google/badwolf/io"
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/storage/memory"
 	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
 )
 
 const (
@@ -210,6 +218,105 @@ func TestPlannerCreateGraph(t *testing.T) {
 	}
 }
 
+func TestPlannerConditionalGraphStatements(t *testing.T) {
+	testTable := []struct {
+		q       string
+		wantErr bool
+		exist   map[string]bool
+	}{
+		{`create graph ?foo;`, true, map[string]bool{"?foo": true, "?bar": false}},
+		{`create graph if not exists ?foo, ?bar;`, false, map[string]bool{"?foo": true, "?bar": true}},
+		{`drop graph ?bar;`, true, map[string]bool{"?foo": true, "?bar": false}},
+		{`drop graph if exists ?foo, ?bar;`, false, map[string]bool{"?foo": false, "?bar": false}},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range testTable {
+		s, ctx := memory.NewStore(), context.Background()
+		if _, err := s.NewGraph(ctx, "?foo"); err != nil {
+			t.Fatal(err)
+		}
+		stm := &semantic.Statement{}
+		if err = p.Parse(grammar.NewLLk(entry.q, 1), stm); err != nil {
+			t.Fatalf("Parser.consume: failed to accept BQL %q with error %v", entry.q, err)
+		}
+		pln, err := New(ctx, s, stm, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New: should have not failed to create a plan for statement %v with error %v", stm, err)
+		}
+		if _, err := pln.Execute(ctx); (err != nil) != entry.wantErr {
+			t.Errorf("planner.Execute(%q) returned error %v; want error %v", entry.q, err, entry.wantErr)
+		}
+		for g, want := range entry.exist {
+			if _, err := s.Graph(ctx, g); (err == nil) != want {
+				t.Errorf("planner.Execute(%q) left graph %q with the wrong existence; want %v", entry.q, g, want)
+			}
+		}
+	}
+}
+
+func TestPlannerDropGraphCascade(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	for _, g := range []string{"?src", "?view", "?view_of_view", "?other"} {
+		if _, err := s.NewGraph(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	run := func(q string) error {
+		stm := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), stm); err != nil {
+			t.Fatalf("Parser.consume: failed to accept BQL %q with error %v", q, err)
+		}
+		pln, err := New(ctx, s, stm, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New: should have not failed to create a plan for statement %v with error %v", stm, err)
+		}
+		_, err = pln.Execute(ctx)
+		return err
+	}
+	for _, q := range []string{
+		`insert data into ?src, ?other {/u<joe> "knows"@[] /u<mary>};`,
+		`construct {?s "met"@[] ?o} into ?view from ?src where {?s "knows"@[] ?o};`,
+		`construct {?o "met"@[] ?s} into ?view_of_view from ?view where {?s "met"@[] ?o};`,
+	} {
+		if err := run(q); err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error %v", q, err)
+		}
+	}
+	md, err := storage.GetGraphMetadata(ctx, s, "?view_of_view")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := md.Labels[DerivedFromLabel], "?view"; got != want {
+		t.Errorf("construct recorded ?view_of_view as derived from %q; want %q", got, want)
+	}
+
+	testTable := []struct {
+		q     string
+		exist map[string]bool
+	}{
+		{`drop graph ?other cascade;`, map[string]bool{"?src": true, "?view": true, "?view_of_view": true, "?other": false}},
+		{`drop graph ?src cascade;`, map[string]bool{"?src": false, "?view": false, "?view_of_view": false}},
+	}
+	for _, entry := range testTable {
+		if err := run(entry.q); err != nil {
+			t.Errorf("planner.Execute(%q) failed with error %v", entry.q, err)
+		}
+		for g, want := range entry.exist {
+			if _, err := s.Graph(ctx, g); (err == nil) != want {
+				t.Errorf("planner.Execute(%q) left graph %q with the wrong existence; want %v", entry.q, g, want)
+			}
+		}
+	}
+}
+
 func TestPlannerDropGraph(t *testing.T) {
 	ctx := context.Background()
 	memory.DefaultStore.DeleteGraph(ctx, "?foo")
@@ -464,7 +571,17 @@ func TestPlannerQuery(t *testing.T) {
 		},
 		{
 			q:    `SHOW GRAPHS;`,
-			nbs:  1,
+			nbs:  4,
+			nrws: 1,
+		},
+		{
+			q:    `SHOW PREDICATES IN ?test;`,
+			nbs:  2,
+			nrws: 6,
+		},
+		{
+			q:    `COUNT TRIPLES IN ?test;`,
+			nbs:  2,
 			nrws: 1,
 		},
 		/*
@@ -527,6 +644,76 @@ func TestPlannerQuery(t *testing.T) {
 			nbs:  2,
 			nrws: 4,
 		},
+		{
+			q:    `select ?x from ?test where { { /u<joe> "parent_of"@[] ?x } union { /u<peter> "parent_of"@[] ?x } };`,
+			nbs:  1,
+			nrws: 4,
+		},
+		{
+			q:    `select ?child, ?car from ?test where { { /u<peter> "parent_of"@[] ?child } UNION { ?car "is_a"@[] /t<car> } };`,
+			nbs:  2,
+			nrws: 6,
+		},
+		{
+			q:    `select ?x from ?test where { { /u<joe> "parent_of"@[] ?o . ?o "parent_of"@[] ?x } union { /room<Kitchen> "connects_to"@[] ?x } union { /u<nobody> "parent_of"@[] ?x } };`,
+			nbs:  1,
+			nrws: 5,
+		},
+		{
+			q: `SELECT ?cars, ?owner
+				    FROM ?test
+				    WHERE {
+					   ?cars "is_a"@[] /t<car> .
+					   OPTIONAL { ?owner "never_owned"@[] ?thing }
+					};`,
+			nbs:  2,
+			nrws: 4,
+		},
+		{
+			q:    `select ?p, ?c, ?n from ?test where { ?p "parent_of"@[] ?c . { select ?c, count(?x) as ?n from ?test where { ?c "parent_of"@[] ?x } group by ?c } };`,
+			nbs:  3,
+			nrws: 1,
+		},
+		{
+			q:    `select ?p, ?n from ?test where { ?p "bought"@[?t] ?car . { select ?p, count(?car) as ?n from ?test where { ?p "bought"@[?t] ?car } group by ?p } . filter(?n > 3) };`,
+			nbs:  2,
+			nrws: 4,
+		},
+		{
+			q:    `select ?p, ?c from ?test where { ?p "parent_of"@[] ?c . { select ?c from ?test where { ?c "is_a"@[] /t<car> } } };`,
+			nbs:  2,
+			nrws: 0,
+		},
+		{
+			q:    `select ?x from ?test where { { /u<joe> "parent_of"@[] ?x . { select ?x from ?test where { ?x "parent_of"@[] ?y } } } union { /room<Kitchen> "connects_to"@[] ?x } };`,
+			nbs:  1,
+			nrws: 5,
+		},
+		{
+			q:    `select ?s, ?o from ?test where { ?s "parent_of"@[] ?o . filter(?o != /u<john>) };`,
+			nbs:  2,
+			nrws: 3,
+		},
+		{
+			q:    `select ?s, ?o from ?test where { ?s "parent_of"@[] ?o . filter(?s = /u<peter> && ?o != /u<john>) };`,
+			nbs:  2,
+			nrws: 1,
+		},
+		{
+			q:    `select ?c1, ?c2 from ?test where { /u<peter> "bought"@[?t1] ?c1 . /u<peter> "bought"@[?t2] ?c2 . filter(?t1 < ?t2) };`,
+			nbs:  2,
+			nrws: 6,
+		},
+		{
+			q:    `select ?x from ?test where { { /u<joe> "parent_of"@[] ?x . filter(?x = /u<mary>) } union { /u<peter> "parent_of"@[] ?x } };`,
+			nbs:  1,
+			nrws: 3,
+		},
+		{
+			q:    `select ?s, ?o from ?test where { ?s "parent_of"@[] ?o . filter(?o = /u<nobody> || !(?s = /u<joe>)) } LIMIT "1"^^type:int64;`,
+			nbs:  2,
+			nrws: 1,
+		},
 	}
 
 	s, ctx := memory.NewStore(), context.Background()
@@ -558,342 +745,2535 @@ func TestPlannerQuery(t *testing.T) {
 	}
 }
 
-func TestPlannerConstructAddsCorrectNumberofTriples(t *testing.T) {
-	sts, dts := len(strings.Split(constructTestSrcTriples, "\n"))-1, len(strings.Split(constructTestDestTriples, "\n"))-1
+func TestPlannerRegexFilter(t *testing.T) {
+	trpls := `/u<joe> "name"@[] "Joe Smith"^^type:text
+		/u<mary> "name"@[] "Mary Jones"^^type:text
+		/u<peter> "name"@[] "peter smith"^^type:text
+		/u<eve> "age"@[] "20"^^type:int64
+		`
 	testTable := []struct {
-		s    string
-		trps int
+		q    string
+		nrws int
 	}{
 		{
-			s: `construct {?s ?p ?o}
-			    into ?dest
-			    from ?src
-			    where {?s ?p ?o};`,
-			trps: sts + dts,
-		},
-		{
-			s: `construct {?s "met"@[] ?o; "location"@[] /city<New York>}
-			    into ?dest
-			    from ?src
-			    where {?s "met"@[] ?o};`,
-			// 3 matching triples * 4 new triples per matched triple due to reification + 1 triple in dest graph.
-			trps: 3*4 + dts,
-		},
-		{
-			s: `construct {?s "met"@[] ?o; "location"@[] /city<New York>;
-			                               "outcome"@[] "good"^^type:text }
-			    into ?dest
-			    from ?src
-			    where {?s "met"@[] ?o};`,
-			// 3 matching triples * 5 new triples per matched triple due to reification + 1 triple in dest graph.
-			trps: 3*5 + dts,
+			q:    `select ?u from ?test where { ?u "name"@[] ?n . filter(regex(?n, "Smith$"^^type:text)) };`,
+			nrws: 1,
 		},
 		{
-			s: `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
-			                                 "outcome"@[] "good"^^type:text .
-			               ?s "connected_to"@[] ?o}
-			    into ?dest
-			    from ?src
-			    where {?s "met"@[] ?o.
-			           ?s "met_at"@[?t] ?o};`,
-			// 2 matching triples * (5 new triples due to reification + 1 explicitly constructed triple per matched triple) +
-			// 1 triple in dest graph.
-			trps: 2*6 + dts,
+			q:    `select ?u from ?test where { ?u "name"@[] ?n . filter(REGEX(?n, "smith"^^type:text, "i"^^type:text)) };`,
+			nrws: 2,
 		},
 		{
-			s: `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
-			                                 "outcome"@[] "good"^^type:text .
-			               ?s "connected_to"@[] ?o; "at"@[?t] /city<New York> }
-			    into ?dest
-			    from ?src
-			    where {?s "met"@[] ?o.
-			           ?s "met_at"@[?t] ?o};`,
-			// 2 matching triples * 9 new triples due to reification + 1 triple in dest graph.
-			trps: 2*9 + dts,
+			q:    `select ?u from ?test where { ?u ?p ?n . filter(!regex(?n, "^M"^^type:text)) };`,
+			nrws: 3,
 		},
 		{
-			s: `construct {?d2 "is_2_hops_from"@[] ?s1 }
-			    into ?dest
-			    from ?src
-			    where {?s1 "is_connected_to"@[] ?d1.
-			           ?d1 "is_connected_to"@[] ?d2};`,
-			// 2 new triples (/city<A> "is_2_hops_from"@[] /city<D>, /city<A> "is_2_hops_from"@[] /city<E>) +  1 triple in dest graph.
-			trps: 3,
+			q:    `select ?u from ?test where { ?u ?p ?n . filter(regex(?n, ".*"^^type:text)) };`,
+			nrws: 3,
 		},
 	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", trpls, t)
 	p, err := grammar.NewParser(grammar.SemanticBQL())
 	if err != nil {
-		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
 	}
 	for _, entry := range testTable {
-
-		s, ctx := memory.NewStore(), context.Background()
-		populateStoreWithTriples(ctx, s, "?src", constructTestSrcTriples, t)
-		populateStoreWithTriples(ctx, s, "?dest", constructTestDestTriples, t)
-
 		st := &semantic.Statement{}
-		if err := p.Parse(grammar.NewLLk(entry.s, 1), st); err != nil {
-			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.s, err)
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
 		}
 		plnr, err := New(ctx, s, st, 0, 10, nil)
 		if err != nil {
-			t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
 		}
-		_, err = plnr.Execute(ctx)
+		tbl, err := plnr.Execute(ctx)
 		if err != nil {
-			t.Errorf("planner.Execute failed for query %q with error %v", entry.s, err)
-			continue
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
 		}
-
-		g, err := s.Graph(ctx, "?dest")
-		if err != nil {
-			t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+		if got, want := len(tbl.Rows()), entry.nrws; got != want {
+			t.Errorf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", entry.q, got, want, tbl)
 		}
-
-		i := 0
-		ts := make(chan *triple.Triple)
-		go func() {
-			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
-				t.Error(err)
-			}
-		}()
-		for range ts {
-			i++
+		qp, ok := plnr.(*queryPlan)
+		if !ok {
+			t.Fatalf("planner.New returned the wrong plan type %T for query %q", plnr, entry.q)
 		}
-		if i != entry.trps {
-			t.Errorf("g.Triples should have returned %v triples, returned %v instead", entry.trps, i)
+		if got, want := len(qp.regexps.m), 1; got != want {
+			t.Errorf("planner.Execute should have cached %d compiled pattern for query %q; got %d", want, entry.q, got)
 		}
 	}
-
 }
 
-func TestPlannerConstructAddsCorrectTriples(t *testing.T) {
-	bql := `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
-	                                     "outcome"@[] "good"^^type:text.
-	                   ?s "connected_to"@[] ?o }
-	        into ?dest
-	        from ?src
-	        where {?s "met"@[] ?o.
-		       ?s "met_at"@[?t] ?o};`
-	p, err := grammar.NewParser(grammar.SemanticBQL())
-	if err != nil {
-		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+func TestPlannerFilterPushdown(t *testing.T) {
+	trpls := originalTriples + `/u<joe> "age"@[] "40"^^type:int64
+		/u<mary> "age"@[] "12"^^type:int64
+		/u<peter> "age"@[] "35.5"^^type:float64
+		/u<eve> "age"@[] "unknown"^^type:text
+		`
+	testTable := []struct {
+		q      string
+		nrws   int
+		pushed bool
+	}{
+		{
+			q:      `select ?u from ?test where { ?u "age"@[] ?a . filter(?a > 20) };`,
+			nrws:   2,
+			pushed: true,
+		},
+		{
+			q:      `select ?u from ?test where { ?u "age"@[] ?a . filter(?a >= 12 && 35.5 > ?a && ?u != /u<joe>) };`,
+			nrws:   1,
+			pushed: true,
+		},
+		{
+			q:      `select ?u from ?test where { ?u "age"@[] ?a . filter(?a = "unknown"^^type:text) };`,
+			nrws:   1,
+			pushed: true,
+		},
+		{
+			q:      `select ?u from ?test where { ?u "age"@[] ?a . filter(?a < 20 || ?a > 36) };`,
+			nrws:   2,
+			pushed: false,
+		},
+		{
+			q:      `select ?p, ?a from ?test where { ?p "parent_of"@[] ?c . optional { ?p "age"@[] ?a } . filter(?a > 20) };`,
+			nrws:   4,
+			pushed: false,
+		},
+		{
+			q:      `select ?c from ?test where { /u<peter> "bought"@[?t] ?c . filter(?t >= time("2016-02-01T00:00:00-08:00"^^type:text) && ?t < time("2016-04-01T00:00:00-08:00"^^type:text)) };`,
+			nrws:   2,
+			pushed: true,
+		},
+		{
+			q:      `select ?c from ?test where { /u<peter> "bought"@[?t] ?c . filter(?t > time("2016-02-01T00:00:00-08:00"^^type:text)) };`,
+			nrws:   2,
+			pushed: true,
+		},
 	}
 	s, ctx := memory.NewStore(), context.Background()
-	populateStoreWithTriples(ctx, s, "?src", constructTestSrcTriples, t)
-	populateStoreWithTriples(ctx, s, "?dest", "", t)
-
-	st := &semantic.Statement{}
-	if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
-		t.Errorf("Parser.consume: failed to parse query %q with error %v", bql, err)
-	}
-	plnr, err := New(ctx, s, st, 0, 10, nil)
+	populateStoreWithTriples(ctx, s, "?test", trpls, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
 	if err != nil {
-		t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
 	}
-	_, err = plnr.Execute(ctx)
-	if err != nil {
-		t.Errorf("planner.Execute failed for query %q with error %v", bql, err)
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		pushed := false
+		for _, c := range st.GraphPatternClauses() {
+			if lo := storage.DefaultLookup; pushdownFilters(c, st.Filters(), lo) != lo {
+				pushed = true
+			}
+		}
+		if pushed != entry.pushed {
+			t.Errorf("pushdownFilters for query %q pushed filters down to storage = %v; want %v", entry.q, pushed, entry.pushed)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		if got, want := len(tbl.Rows()), entry.nrws; got != want {
+			t.Errorf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", entry.q, got, want, tbl)
+		}
 	}
+}
 
-	g, err := s.Graph(ctx, "?dest")
-	if err != nil {
-		t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+func TestPlannerFilterPushdownMatchesFilters(t *testing.T) {
+	trpls := `/u<big> "age"@[] "9007199254740993"^^type:int64
+		/u<round> "age"@[] "9007199254740992"^^type:float64
+		/u<nan> "age"@[] "NaN"^^type:float64
+		/u<one> "age"@[] "1"^^type:int64
+		/u<half> "age"@[] "0.5"^^type:float64
+		`
+	testTable := []struct {
+		filter string
+		want   []string
+	}{
+		{
+			filter: `?a <= 9007199254740992.0`,
+			want:   []string{"/u<half>", "/u<nan>", "/u<one>", "/u<round>"},
+		},
+		{
+			filter: `?a > 9007199254740992.0`,
+			want:   []string{"/u<big>"},
+		},
+		{
+			filter: `?a = 9007199254740993`,
+			want:   []string{"/u<big>"},
+		},
+		{
+			filter: `?a > 1.0`,
+			want:   []string{"/u<big>", "/u<round>"},
+		},
+		{
+			filter: `?a < 1`,
+			want:   []string{"/u<half>", "/u<nan>"},
+		},
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", trpls, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	run := func(q string) []string {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", q, err)
+		}
+		var res []string
+		for _, r := range tbl.Rows() {
+			res = append(res, r["?u"].N.String())
+		}
+		sort.Strings(res)
+		return res
+	}
+	for _, entry := range testTable {
+		// The disjunction keeps the filter from being pushed down to storage,
+		// so only the filter evaluator decides which rows pass.
+		pushed := run(fmt.Sprintf(`select ?u from ?test where { ?u "age"@[] ?a . filter(%s) };`, entry.filter))
+		evaluated := run(fmt.Sprintf(`select ?u from ?test where { ?u "age"@[] ?a . filter(%s || ?u = /u<nobody>) };`, entry.filter))
+		if !reflect.DeepEqual(pushed, entry.want) {
+			t.Errorf("planner.Execute with filter %q pushed down returned %v; want %v", entry.filter, pushed, entry.want)
+		}
+		if !reflect.DeepEqual(evaluated, entry.want) {
+			t.Errorf("planner.Execute with filter %q evaluated on the rows returned %v; want %v", entry.filter, evaluated, entry.want)
+		}
+	}
+}
+
+func TestPlannerOrderByExpression(t *testing.T) {
+	trpls := `/u<a> "delta"@[] "-10"^^type:int64
+		/u<b> "delta"@[] "3"^^type:int64
+		/u<c> "delta"@[] "-1"^^type:int64
+		/u<d> "delta"@[] "7"^^type:int64
+		`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `select ?u, ?d from ?test where { ?u "delta"@[] ?d } order by abs(?d) desc;`,
+			want: []string{"/u<a>", "/u<d>", "/u<b>", "/u<c>"},
+		},
+		{
+			q:    `select ?u, ?d from ?test where { ?u "delta"@[] ?d } order by ABS(?d);`,
+			want: []string{"/u<c>", "/u<b>", "/u<d>", "/u<a>"},
+		},
+		{
+			q:    `select ?u, ?d as ?x from ?test where { ?u "delta"@[] ?d } order by (?x * ?x + 1) desc;`,
+			want: []string{"/u<a>", "/u<d>", "/u<b>", "/u<c>"},
+		},
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", trpls, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		if got, want := len(tbl.Bindings()), 2; got != want {
+			t.Errorf("planner.Execute returned the wrong number of bindings for query %q; got %v, want %d", entry.q, tbl.Bindings(), want)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?u"].String())
+			for k := range r {
+				if k != "" && !strings.HasPrefix(k, "?") {
+					t.Errorf("planner.Execute left temporary sort key %q in row %v for query %q", k, r, entry.q)
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned the wrong order for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerOffset(t *testing.T) {
+	trpls := `/u<a> "rank"@[] "1"^^type:int64
+		/u<b> "rank"@[] "2"^^type:int64
+		/u<c> "rank"@[] "3"^^type:int64
+		/u<d> "rank"@[] "4"^^type:int64
+		`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `select ?u from ?test where { ?u "rank"@[] ?r } order by ?u limit "2"^^type:int64 offset "0"^^type:int64;`,
+			want: []string{"/u<a>", "/u<b>"},
+		},
+		{
+			q:    `select ?u from ?test where { ?u "rank"@[] ?r } order by ?u limit "2"^^type:int64 offset "2"^^type:int64;`,
+			want: []string{"/u<c>", "/u<d>"},
+		},
+		{
+			q:    `select ?u from ?test where { ?u "rank"@[] ?r } order by ?u desc offset "1"^^type:int64;`,
+			want: []string{"/u<c>", "/u<b>", "/u<a>"},
+		},
+		{
+			q:    `select ?u from ?test where { ?u "rank"@[] ?r } order by ?u limit "2"^^type:int64 offset "3"^^type:int64;`,
+			want: []string{"/u<d>"},
+		},
+		{
+			q: `select ?u from ?test where { ?u "rank"@[] ?r } order by ?u offset "10"^^type:int64;`,
+		},
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", trpls, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?u"].String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned the wrong page for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+
+	// Without an order by clause the limit is pushed down to the storage and
+	// needs to account for the skipped rows.
+	q := `select ?u from ?test where { ?u "rank"@[] ?r } limit "2"^^type:int64 offset "1"^^type:int64;`
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for query %q with error %v", q, err)
+	}
+	if got, want := tbl.NumRows(), 2; got != want {
+		t.Errorf("planner.Execute returned the wrong number of rows for query %q; got %d, want %d", q, got, want)
+	}
+}
+
+func TestPlannerDeleteWhereRemovesMatchingTriples(t *testing.T) {
+	trpls := `/u<joe> "follows"@[2010-01-01T00:00:00-08:00] /u<mary>
+		/u<joe> "follows"@[2016-01-01T00:00:00-08:00] /u<peter>
+		/u<mary> "follows"@[2012-01-01T00:00:00-08:00] /u<joe>
+		/u<mary> "likes"@[] /u<joe>
+		`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q: `delete {?s ?p ?o} from ?test where {?s "follows"@[,2015-01-01T00:00:00-08:00] as ?p ?o};`,
+			want: []string{
+				"/u<joe>\t\"follows\"@[2016-01-01T00:00:00-08:00]\t/u<peter>",
+				"/u<mary>\t\"likes\"@[]\t/u<joe>",
+			},
+		},
+		{
+			q: `delete {?s "likes"@[] ?o} from ?test where {?s "follows"@[,] ?o . ?s "likes"@[] ?o};`,
+			want: []string{
+				"/u<joe>\t\"follows\"@[2010-01-01T00:00:00-08:00]\t/u<mary>",
+				"/u<joe>\t\"follows\"@[2016-01-01T00:00:00-08:00]\t/u<peter>",
+				"/u<mary>\t\"follows\"@[2012-01-01T00:00:00-08:00]\t/u<joe>",
+			},
+		},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		s, ctx := memory.NewStore(), context.Background()
+		populateStoreWithTriples(ctx, s, "?test", trpls, t)
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		if _, err := plnr.Execute(ctx); err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		g, err := s.Graph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts := make(chan *triple.Triple)
+		go func() {
+			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+				t.Error(err)
+			}
+		}()
+		var got []string
+		for trp := range ts {
+			got = append(got, trp.String())
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute left the wrong triples for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerAsk(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want bool
+	}{
+		{`ask from ?test where { /u<joe> "parent_of"@[] ?x };`, true},
+		{`ask from ?test where { /u<joe> "parent_of"@[] /u<mary> };`, true},
+		{`ask from ?test where { /u<joe> "parent_of"@[] /u<nobody> };`, false},
+		{`ask from ?test where { /u<nobody> "parent_of"@[] ?x . ?x "parent_of"@[] ?y };`, false},
+		{`ask from ?test where { ?x "parent_of"@[] ?y . ?y "parent_of"@[] ?z };`, true},
+		{`ask from ?test where { ?x "parent_of"@[] ?y . filter(?y = /u<nobody>) };`, false},
+		{`ask from ?test where { { /u<nobody> "parent_of"@[] ?x } union { /u<joe> "parent_of"@[] ?x } };`, true},
+		{`ask from ?test where { { /u<joe> "parent_of"@[] ?x } union { /u<nobody> "parent_of"@[] ?x } };`, true},
+		{`ask from ?test where { /u<peter> "bought"@[,] ?c } before ""@[2016-01-01T00:00:00-08:00];`, true},
+		{`ask from ?test where { /u<peter> "bought"@[,] ?c } after ""@[2017-01-01T00:00:00-08:00];`, false},
+		{`ask from ?test where { /u<peter> "bought"@[,] /c<mini> } after ""@[2017-01-01T00:00:00-08:00];`, false},
+		{`ask from ?test where { /u<peter> "bought"@[,] /c<mini> . /c<mini> "is_a"@[] /t<car> };`, true},
+		{`ask from ?test where { { /u<peter> "bought"@[,] /c<nothing> } union { /c<mini> "is_a"@[] /t<car> } };`, true},
+		{`ask from ?test where { /u<joe> "parent_of"@[]+ /u<eve> };`, true},
+		{`ask from ?test where { /u<eve> "parent_of"@[]+ /u<joe> };`, false},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		if got, want := plnr.Type(), "ASK"; got != want {
+			t.Errorf("planner.New returned the wrong plan type for query %q; got %q, want %q", entry.q, got, want)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		if got, want := tbl.NumRows(), 1; got != want {
+			t.Fatalf("planner.Execute returned the wrong number of rows for query %q; got %d, want %d", entry.q, got, want)
+		}
+		r, _ := tbl.Row(0)
+		got, err := r[AskBinding].L.Bool()
+		if err != nil {
+			t.Fatalf("planner.Execute did not return a boolean for query %q; got %v", entry.q, r)
+		}
+		if got != entry.want {
+			t.Errorf("planner.Execute returned the wrong answer for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerCatalog(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	populateStoreWithTriples(ctx, s, "?other", constructTestDestTriples, t)
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `show predicates in ?test;`,
+			want: []string{"?test bought", "?test connects_to", "?test in", "?test is_a", "?test parent_of", "?test predicate"},
+		},
+		{
+			q:    `show predicates in ?other, ?test;`,
+			want: []string{"?other met", "?test bought", "?test connects_to", "?test in", "?test is_a", "?test parent_of", "?test predicate"},
+		},
+		{
+			q:    `count triples in ?test, ?other;`,
+			want: []string{"?test " + strconv.Itoa(len(strings.Split(strings.TrimSpace(testTriples), "\n"))), "?other 1"},
+		},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			v := r["?predicate_id"]
+			if c, ok := r["?count"]; ok {
+				n, err := c.L.Int64()
+				if err != nil {
+					t.Fatal(err)
+				}
+				v = &table.Cell{S: table.CellString(strconv.FormatInt(n, 10))}
+			}
+			got = append(got, *r["?graph_id"].S+" "+*v.S)
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned the wrong rows for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerShowStats(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	populateStoreWithTriples(ctx, s, "?other", constructTestDestTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	q := `show stats in ?test, ?other;`
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for query %q with error %v", q, err)
+	}
+	if got, want := tbl.NumRows(), 2; got != want {
+		t.Fatalf("planner.Execute returned %d rows for query %q; want %d", got, q, want)
+	}
+	for _, r := range tbl.Rows() {
+		id := *r["?graph_id"].S
+		g, err := s.Graph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := storage.Statistics(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for b, v := range map[string]int64{
+			"?triples":    want.Triples,
+			"?subjects":   want.Subjects,
+			"?predicates": int64(len(want.Predicates)),
+			"?objects":    want.Objects,
+		} {
+			if got, err := r[b].L.Int64(); err != nil || got != v {
+				t.Errorf("planner.Execute returned %s %d for graph %s, %v; want %d", b, got, id, err, v)
+			}
+		}
+		if got, err := r["?memory_bytes"].L.Int64(); err != nil || got <= 0 {
+			t.Errorf("planner.Execute returned ?memory_bytes %d for graph %s, %v; want a positive estimate", got, id, err)
+		}
+		if got, want := r["?first_anchor"].T, want.FirstAnchor; !reflect.DeepEqual(got, want) {
+			t.Errorf("planner.Execute returned ?first_anchor %v for graph %s; want %v", got, id, want)
+		}
+		if got, want := r["?last_anchor"].T, want.LastAnchor; !reflect.DeepEqual(got, want) {
+			t.Errorf("planner.Execute returned ?last_anchor %v for graph %s; want %v", got, id, want)
+		}
+		if id == "?other" && r["?first_anchor"].T != nil {
+			t.Errorf("planner.Execute returned an anchor for graph %s without temporal triples", id)
+		}
+	}
+}
+
+func TestPlannerSumDecimal(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", `/u<shop> "price"@[] "0.1"^^type:decimal
+/u<shop> "price"@[] "0.2"^^type:decimal
+/u<shop> "price"@[] "0.3"^^type:decimal
+/u<shop> "price"@[] "19.99"^^type:decimal
+`, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	q := `select ?s, sum(?price) as ?total from ?test where {?s "price"@[] ?price} group by ?s;`
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for query %q with error %v", q, err)
+	}
+	if got, want := tbl.NumRows(), 1; got != want {
+		t.Fatalf("planner.Execute returned %d rows for query %q; want %d", got, q, want)
+	}
+	if got, want := tbl.Rows()[0]["?total"].L.String(), `"20.59"^^type:decimal`; got != want {
+		t.Errorf("planner.Execute returned ?total %s for query %q; want %s", got, q, want)
+	}
+}
+
+func TestPlannerIRIPrefixes(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", `/iri<http://example.com/people#john> "type"@[] /iri<http://xmlns.com/foaf/0.1/Person>
+/iri<http://example.com/people#mary> "type"@[] /iri<http://xmlns.com/foaf/0.1/Person>
+/iri<http://example.com/acme> "type"@[] /iri<http://xmlns.com/foaf/0.1/Organization>
+`, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, q := range []string{
+		`prefix foaf: <http://xmlns.com/foaf/0.1/> select ?s from ?test where {?s "type"@[] foaf:Person};`,
+		`select ?s from ?test where {?s "type"@[] <http://xmlns.com/foaf/0.1/Person>};`,
+		`select ?s from ?test where {?s "type"@[] /iri<http://xmlns.com/foaf/0.1/Person>};`,
+	} {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", q, err)
+		}
+		if got, want := tbl.NumRows(), 2; got != want {
+			t.Errorf("planner.Execute returned %d rows for query %q; want %d", got, q, want)
+		}
+	}
+}
+
+func TestPlannerReifiedClauses(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ts []*triple.Triple
+	for _, entry := range []struct {
+		t, confidence string
+	}{
+		{`/u<joe> "met"@[2016-01-01T00:00:00Z] /u<mary>`, `"0.9"^^type:float64`},
+		{`/u<joe> "met"@[2017-01-01T00:00:00Z] /u<peter>`, `"0.5"^^type:float64`},
+		{`/u<joe> "met"@[2018-01-01T00:00:00Z] /u<sue>`, ""},
+	} {
+		trpl, err := triple.Parse(entry.t, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, trpl)
+		if entry.confidence == "" {
+			continue
+		}
+		rts, bn, err := trpl.Reify()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := triple.Parse(bn.String()+"\t\"confidence\"@[]\t"+entry.confidence, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, append(rts[1:], c)...)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	testTable := []struct {
+		q    string
+		want map[string]string
+	}{
+		{
+			q:    `select ?o, ?c from ?test where {/u<joe> "met"@[?t] ?o reified as ?r . ?r "confidence"@[] ?c};`,
+			want: map[string]string{"/u<mary>": `"0.9"^^type:float64`, "/u<peter>": `"0.5"^^type:float64`},
+		},
+		{
+			q:    `select ?o, ?c from ?test where {/u<joe> "met"@[2017-01-01T00:00:00Z] ?o reified as ?r . ?r "confidence"@[] ?c};`,
+			want: map[string]string{"/u<peter>": `"0.5"^^type:float64`},
+		},
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		got := make(map[string]string)
+		for _, r := range tbl.Rows() {
+			got[r["?o"].String()] = r["?c"].String()
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned %v for query %q; want %v", got, entry.q, entry.want)
+		}
+	}
+}
+
+func TestPlannerShowGraphsMetadata(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	start := time.Now()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	populateStoreWithTriples(ctx, s, "?other", constructTestDestTriples, t)
+	md := &storage.GraphMetadata{
+		Description: "family tree",
+		Labels:      map[string]string{"tenant": "acme", "env": "prod"},
+	}
+	if err := storage.SetGraphMetadata(ctx, s, "?test", md); err != nil {
+		t.Fatalf("storage.SetGraphMetadata failed with error %v", err)
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	run := func(s storage.Store) *table.Table {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(`SHOW GRAPHS;`, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse SHOW GRAPHS with error %v", err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for SHOW GRAPHS with error %v", err)
+		}
+		return tbl
+	}
+
+	tbl := run(s)
+	if got, want := tbl.Bindings(), []string{"?graph_id", "?created", "?description", "?labels"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SHOW GRAPHS returned bindings %v; want %v", got, want)
+	}
+	want := map[string][2]string{
+		"?test":  {"family tree", "env=prod, tenant=acme"},
+		"?other": {"", ""},
+	}
+	if got := tbl.NumRows(); got != len(want) {
+		t.Errorf("SHOW GRAPHS returned %d rows; want %d", got, len(want))
+	}
+	for _, r := range tbl.Rows() {
+		id := *r["?graph_id"].S
+		if got := [2]string{*r["?description"].S, *r["?labels"].S}; got != want[id] {
+			t.Errorf("SHOW GRAPHS returned metadata %q for graph %s; want %q", got, id, want[id])
+		}
+		if c := r["?created"].T; c == nil || c.Before(start) {
+			t.Errorf("SHOW GRAPHS returned creation time %v for graph %s; want a time after %v", c, id, start)
+		}
+	}
+
+	// Stores without metadata only list the graph IDs.
+	tbl = run(struct{ storage.Store }{s})
+	if got, want := tbl.Bindings(), []string{"?graph_id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SHOW GRAPHS returned bindings %v for a store without metadata; want %v", got, want)
+	}
+}
+
+func TestPlannerExplain(t *testing.T) {
+	type step struct {
+		op, strategy, lookup string
+		estimate             int64
+	}
+	testTable := []struct {
+		q    string
+		want []step
+	}{
+		{
+			q: `explain select ?x, ?y from ?test where { /u<joe> "parent_of"@[] ?x . ?x "parent_of"@[] ?y };`,
+			want: []step{
+				{"clause", "scan", "Objects", 2},
+				{"clause", "nested loop join on ?x", "Objects", 4},
+			},
+		},
+		{
+			q: `explain select ?x from ?test where { /u<joe> "parent_of"@[] /u<mary> . ?x "is_a"@[] /t<car> } limit "1"^^type:int64;`,
+			want: []step{
+				{"clause", "existence check", "Exist", 1},
+				{"clause", "scan", "Subjects", 4},
+				{"limit", "truncate rows", "", -1},
+			},
+		},
+		{
+			q: `explain select ?x, ?c from ?test where { ?x "parent_of"@[] /u<eve> . /c<mini> "is_a"@[] ?c . filter(?x != ?c) };`,
+			want: []step{
+				{"clause", "scan", "Objects", 1},
+				{"clause", "cross product", "Subjects", 1},
+				{"filter", "row filter", "", -1},
+			},
+		},
+		{
+			q: `explain ask from ?test where { ?s ?p ?o };`,
+			want: []step{
+				{"clause", "scan", "Triples", int64(len(strings.Split(strings.TrimSpace(testTriples), "\n")))},
+			},
+		},
+		{
+			q: `explain show graphs;`,
+			want: []step{
+				{"SHOW", "", "", -1},
+			},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		if got, want := plnr.Type(), "EXPLAIN"; got != want {
+			t.Errorf("planner.New returned the wrong plan type for query %q; got %q, want %q", entry.q, got, want)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		var got []step
+		for _, r := range tbl.Rows() {
+			stp := step{
+				op:       *r[OperationBinding].S,
+				strategy: *r[StrategyBinding].S,
+				lookup:   *r[LookupBinding].S,
+				estimate: -1,
+			}
+			if l := r[EstimateBinding].L; l != nil {
+				if stp.estimate, err = l.Int64(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got = append(got, stp)
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned the wrong steps for query %q; got %v, want %v\n%s", entry.q, got, entry.want, tbl)
+		}
+	}
+}
+
+func TestPlannerDescribe(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want int
+	}{
+		{`describe /room<Bathroom> in ?test;`, 2},
+		{`describe /room<Bathroom> in ?test depth "1"^^type:int64;`, 2},
+		{`describe /room<Bathroom> in ?test depth "2"^^type:int64;`, 8},
+		{`describe /room<Bathroom> in ?test depth "10"^^type:int64;`, 11},
+		{`describe /room<Bathroom>;`, 3},
+		{`describe /room<Nowhere> in ?test;`, 0},
+		{`describe /u<joe> in ?other;`, 1},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", tripleFromIssue40, t)
+	populateStoreWithTriples(ctx, s, "?other", `/u<joe> "cleans"@[] /room<Bathroom>
+		`, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		if got, want := tbl.NumRows(), entry.want; got != want {
+			t.Errorf("planner.Execute returned the wrong number of triples for query %q; got %d, want %d\n%v", entry.q, got, want, tbl)
+		}
+		if _, err := Triples(tbl); err != nil {
+			t.Errorf("planner.Triples failed to extract the described triples for query %q with error %v", entry.q, err)
+		}
+	}
+}
+
+func TestPlannerPropertyPaths(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `select ?x from ?test where { /u<joe> "parent_of"@[]+ ?x };`,
+			want: []string{"/u<eve>", "/u<john>", "/u<mary>", "/u<peter>"},
+		},
+		{
+			q:    `select ?x from ?test where { /u<joe> "parent_of"@[]* ?x };`,
+			want: []string{"/u<eve>", "/u<joe>", "/u<john>", "/u<mary>", "/u<peter>"},
+		},
+		{
+			q:    `select ?x from ?test where { /u<joe> "parent_of"@[]/"parent_of"@[] ?x };`,
+			want: []string{"/u<eve>", "/u<john>"},
+		},
+		{
+			q:    `select ?x from ?test where { ?x "parent_of"@[]+ /u<eve> };`,
+			want: []string{"/u<joe>", "/u<peter>"},
+		},
+		{
+			q:    `select ?x from ?test where { /u<joe> "parent_of"@[] ?c . ?c "parent_of"@[]* ?x };`,
+			want: []string{"/u<eve>", "/u<john>", "/u<mary>", "/u<peter>"},
+		},
+		{
+			q:    `select ?x from ?test where { /u<joe> "parent_of"@[]+/"bought"@[2016-01-01T00:00:00-08:00]/"is_a"@[] ?x };`,
+			want: []string{"/t<car>"},
+		},
+		{
+			q:    `select ?x from ?test where { /room<Bathroom> "connects_to"@[]+ ?x };`,
+			want: []string{"/room<Bathroom>", "/room<Bedroom>", "/room<Fire Escape>", "/room<Hallway>", "/room<Kitchen>"},
+		},
+		{
+			q:    `select ?x from ?test where { /u<joe> "parent_of"@[]+ /u<eve> . /u<peter> "parent_of"@[] ?x };`,
+			want: []string{"/u<eve>", "/u<john>"},
+		},
+		{
+			q: `select ?x from ?test where { /u<eve> "parent_of"@[]+ /u<joe> . /u<peter> "parent_of"@[] ?x };`,
+		},
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?x"].String())
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned the wrong nodes for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerGraphPatterns(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `select ?g, ?o from ?a, ?b where { graph ?g { /u<joe> "knows"@[] ?o } };`,
+			want: []string{"?a /u<mary>", "?b /u<peter>"},
+		},
+		{
+			q:    `select ?g from ?a, ?b where { graph ?g { /u<joe> "knows"@[] /u<peter> } };`,
+			want: []string{"?b"},
+		},
+		{
+			q:    `select ?g, ?o from ?a, ?b where { graph ?g { /u<joe> "knows"@[] ?x . ?x "knows"@[] ?o } };`,
+			want: []string{"?a /u<peter>"},
+		},
+		{
+			q:    `select ?g, ?o from ?a, ?b where { /u<joe> "knows"@[] ?o . graph ?g { ?o "knows"@[] /u<peter> } };`,
+			want: []string{"?a /u<mary>"},
+		},
+		{
+			q:    `select ?g1, ?g2 from ?a, ?b where { graph ?g1 { /u<joe> "knows"@[] ?o } . graph ?g2 { ?o "likes"@[] /u<joe> } };`,
+			want: []string{"?b ?b"},
+		},
+		{
+			q: `select ?g from ?a, ?b where { graph ?g { /u<peter> "knows"@[] ?o } };`,
+		},
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?a", `/u<joe> "knows"@[] /u<mary>
+		/u<mary> "knows"@[] /u<peter>
+		`, t)
+	populateStoreWithTriples(ctx, s, "?b", `/u<joe> "knows"@[] /u<peter>
+		/u<peter> "likes"@[] /u<joe>
+		`, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range tbl.Bindings() {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned the wrong rows for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerGlobalTimeBounds(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want int
+	}{
+		{`select ?c from ?test where { /u<peter> "bought"@[,] ?c } between ""@[2016-01-01T00:00:00-08:00], ""@[2016-03-01T00:00:00-08:00];`, 3},
+		{`select ?c from ?test where { /u<peter> "bought"@[,] ?c } between ""@[2016-01-01T00:00:00-08:00], ""@[2016-03-01T00:00:00-08:00] inclusive;`, 3},
+		{`select ?c from ?test where { /u<peter> "bought"@[,] ?c } between ""@[2016-01-01T00:00:00-08:00], ""@[2016-03-01T00:00:00-08:00] exclusive;`, 1},
+		{`select ?c from ?test where { /u<peter> "bought"@[,] ?c } after ""@[2016-03-01T00:00:00-08:00];`, 2},
+		{`select ?c from ?test where { /u<peter> "bought"@[,] ?c } after ""@[2016-03-01T00:00:00-08:00] exclusive;`, 1},
+		{`select ?c from ?test where { /u<peter> "bought"@[,] ?c } before ""@[2016-03-01T00:00:00-08:00] - 24h;`, 2},
+		{`select ?c from ?test where { /u<peter> "bought"@[,] ?c } between ""@[2016-02-01T00:00:00-08:00] - 1ns, ""@[2016-02-01T00:00:00-08:00] + 1ns exclusive;`, 1},
+		{`select ?c from ?test where { /u<peter> "bought"@[,] ?c } before NOW();`, 4},
+		{`select ?c from ?test where { /u<peter> "bought"@[,] ?c } after NOW() - 24h;`, 0},
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		if got, want := tbl.NumRows(), entry.want; got != want {
+			t.Errorf("planner.Execute returned the wrong number of rows for query %q; got %d, want %d\n%v", entry.q, got, want, tbl)
+		}
+	}
+}
+
+func TestPlannerConstructAddsCorrectNumberofTriples(t *testing.T) {
+	sts, dts := len(strings.Split(constructTestSrcTriples, "\n"))-1, len(strings.Split(constructTestDestTriples, "\n"))-1
+	testTable := []struct {
+		s    string
+		trps int
+	}{
+		{
+			s: `construct {?s ?p ?o}
+			    into ?dest
+			    from ?src
+			    where {?s ?p ?o};`,
+			trps: sts + dts,
+		},
+		{
+			s: `construct {?s "met"@[] ?o; "location"@[] /city<New York>}
+			    into ?dest
+			    from ?src
+			    where {?s "met"@[] ?o};`,
+			// 3 matching triples * 4 new triples per matched triple due to reification + 1 triple in dest graph.
+			trps: 3*4 + dts,
+		},
+		{
+			s: `construct {?s "met"@[] ?o; "location"@[] /city<New York>;
+			                               "outcome"@[] "good"^^type:text }
+			    into ?dest
+			    from ?src
+			    where {?s "met"@[] ?o};`,
+			// 3 matching triples * 5 new triples per matched triple due to reification + 1 triple in dest graph.
+			trps: 3*5 + dts,
+		},
+		{
+			s: `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
+			                                 "outcome"@[] "good"^^type:text .
+			               ?s "connected_to"@[] ?o}
+			    into ?dest
+			    from ?src
+			    where {?s "met"@[] ?o.
+			           ?s "met_at"@[?t] ?o};`,
+			// 2 matching triples * (5 new triples due to reification + 1 explicitly constructed triple per matched triple) +
+			// 1 triple in dest graph.
+			trps: 2*6 + dts,
+		},
+		{
+			s: `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
+			                                 "outcome"@[] "good"^^type:text .
+			               ?s "connected_to"@[] ?o; "at"@[?t] /city<New York> }
+			    into ?dest
+			    from ?src
+			    where {?s "met"@[] ?o.
+			           ?s "met_at"@[?t] ?o};`,
+			// 2 matching triples * 9 new triples due to reification + 1 triple in dest graph.
+			trps: 2*9 + dts,
+		},
+		{
+			s: `construct {?d2 "is_2_hops_from"@[] ?s1 }
+			    into ?dest
+			    from ?src
+			    where {?s1 "is_connected_to"@[] ?d1.
+			           ?d1 "is_connected_to"@[] ?d2};`,
+			// 2 new triples (/city<A> "is_2_hops_from"@[] /city<D>, /city<A> "is_2_hops_from"@[] /city<E>) +  1 triple in dest graph.
+			trps: 3,
+		},
+		{
+			s: `insert {?d2 "is_2_hops_from"@[] ?s1 }
+			    into ?dest
+			    from ?src
+			    where {?s1 "is_connected_to"@[] ?d1.
+			           ?d1 "is_connected_to"@[] ?d2};`,
+			// Same as the construct above, written as an insert populated by a where clause.
+			trps: 3,
+		},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range testTable {
+
+		s, ctx := memory.NewStore(), context.Background()
+		populateStoreWithTriples(ctx, s, "?src", constructTestSrcTriples, t)
+		populateStoreWithTriples(ctx, s, "?dest", constructTestDestTriples, t)
+
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.s, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.s, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		_, err = plnr.Execute(ctx)
+		if err != nil {
+			t.Errorf("planner.Execute failed for query %q with error %v", entry.s, err)
+			continue
+		}
+
+		g, err := s.Graph(ctx, "?dest")
+		if err != nil {
+			t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+		}
+
+		i := 0
+		ts := make(chan *triple.Triple)
+		go func() {
+			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+				t.Error(err)
+			}
+		}()
+		for range ts {
+			i++
+		}
+		if i != entry.trps {
+			t.Errorf("g.Triples should have returned %v triples, returned %v instead", entry.trps, i)
+		}
+	}
+
+}
+
+func TestPlannerConstructReturnsTriples(t *testing.T) {
+	bql := `construct {?d2 "is_2_hops_from"@[] ?s1 }
+	        from ?src
+	        where {?s1 "is_connected_to"@[] ?d1.
+	               ?d1 "is_connected_to"@[] ?d2};`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?src", constructTestSrcTriples, t)
+
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", bql, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for query %q with error %v", bql, err)
+	}
+	if got, want := tbl.Bindings(), []string{SubjectBinding, PredicateBinding, ObjectBinding}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planner.Execute returned the wrong bindings; got %v, want %v", got, want)
+	}
+	ts, err := Triples(tbl)
+	if err != nil {
+		t.Fatalf("planner.Triples failed to extract the triples from %v with error %v", tbl, err)
+	}
+	var got []string
+	for _, trp := range ts {
+		got = append(got, trp.String())
+	}
+	sort.Strings(got)
+	want := []string{
+		"/city<D>\t\"is_2_hops_from\"@[]\t/city<A>",
+		"/city<E>\t\"is_2_hops_from\"@[]\t/city<A>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planner.Execute returned the wrong triples; got %v, want %v", got, want)
+	}
+	gns := make(chan string, 10)
+	if err := s.GraphNames(ctx, gns); err != nil {
+		t.Fatal(err)
+	}
+	cnt := 0
+	for range gns {
+		cnt++
+	}
+	if cnt != 1 {
+		t.Errorf("construct statements without output graphs should not create graphs; found %d graphs", cnt)
+	}
+}
+
+func TestPlannerConstructAddsCorrectTriples(t *testing.T) {
+	bql := `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
+	                                     "outcome"@[] "good"^^type:text.
+	                   ?s "connected_to"@[] ?o }
+	        into ?dest
+	        from ?src
+	        where {?s "met"@[] ?o.
+		       ?s "met_at"@[?t] ?o};`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?src", constructTestSrcTriples, t)
+	populateStoreWithTriples(ctx, s, "?dest", "", t)
+
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
+		t.Errorf("Parser.consume: failed to parse query %q with error %v", bql, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	_, err = plnr.Execute(ctx)
+	if err != nil {
+		t.Errorf("planner.Execute failed for query %q with error %v", bql, err)
+	}
+
+	g, err := s.Graph(ctx, "?dest")
+	if err != nil {
+		t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+	}
+
+	ts := make(chan *triple.Triple)
+	go func() {
+		if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	bnm := make(map[string]map[string]bool)
+	bns := make(map[string]string)
+	bna := map[string]bool{
+		"/_<b1>": true,
+		"/_<b2>": true,
+	}
+	dtm := map[string]bool{
+		fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"connected_to"@[]`, `/person<B>`):                                 false,
+		fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"connected_to"@[]`, `/person<C>`):                                 false,
+		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"_subject"@[2016-04-10T04:25:00Z]`, `/person<A>`):                     false,
+		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"_predicate"@[2016-04-10T04:25:00Z]`, `"met"@[2016-04-10T04:25:00Z]`): false,
+		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"_object"@[2016-04-10T04:25:00Z]`, `/person<B>`):                      false,
+		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"location"@[]`, `/city<New York>`):                                    false,
+		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"outcome"@[]`, `"good"^^type:text`):                                   false,
+		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"_subject"@[2016-04-10T04:25:00Z]`, `/person<B>`):                     false,
+		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"_predicate"@[2016-04-10T04:25:00Z]`, `"met"@[2016-04-10T04:25:00Z]`): false,
+		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"_object"@[2016-04-10T04:25:00Z]`, `/person<C>`):                      false,
+		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"location"@[]`, `/city<New York>`):                                    false,
+		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"outcome"@[]`, `"good"^^type:text`):                                   false,
+	}
+
+	// First, we map each blank node generated to a potential blank node placeholder (such as b1 or b2.)
+	sts := []*triple.Triple{}
+	for elem := range ts {
+		sts = append(sts, elem)
+		if elem.Subject().Type().String() == "/_" {
+			for k := range dtm {
+				trp, err := triple.Parse(k, literal.DefaultBuilder())
+				if err != nil {
+					t.Errorf("Unable to parse triple: %v with error %v", k, err)
+				}
+				if trp.Subject().Type().String() == "/_" &&
+					trp.Predicate().String() == elem.Predicate().String() &&
+					trp.Object().String() == elem.Object().String() {
+					if mp, ok := bnm[elem.Subject().String()]; !ok {
+						bnm[elem.Subject().String()] = map[string]bool{
+							trp.Subject().String(): true,
+						}
+					} else {
+						mp[trp.Subject().String()] = true
+					}
+				}
+			}
+
+		}
+	}
+
+	// Then, we decide which place holder blank nodes can be used to substiute for a given blank node
+	// by substituting the place holder in every triple where the given blank node is the subject and
+	// checking if the triple exists in the map of expected triples.
+	for _, t := range sts {
+		if t.Subject().Type().String() == "/_" {
+			for bn := range bnm[t.Subject().String()] {
+				rep := fmt.Sprintf("%s\t%s\t%s", bn, t.Predicate().String(), t.Object().String())
+				if _, ok := dtm[rep]; !ok {
+					bnm[t.Subject().String()][bn] = false
+				}
+			}
+		}
+	}
+
+	// Finally, we assign a blank node to a place-holder blank node, if the place-holder blank node is
+	// not used to substitute any other blank node.
+	for k, v := range bnm {
+		for bn, p := range v {
+			if p && bna[bn] {
+				bns[k] = bn
+				bna[bn] = false
+				break
+			}
+		}
+	}
+	if len(sts) != len(dtm) {
+		t.Errorf("g.Triples should have returned %v triples, returned %v instead", len(dtm), len(sts))
+	}
+	for _, elem := range sts {
+		if elem.Subject().Type().String() == "/_" {
+			if val, ok := bns[elem.Subject().String()]; ok {
+				// Substitute the blank node with the mapped place holder blank node id.
+				rep := fmt.Sprintf("%s\t%s\t%s", val, elem.Predicate().String(), elem.Object().String())
+				if _, ok := dtm[rep]; !ok {
+					t.Errorf("unexpected triple: %v added to graph", elem)
+				}
+				dtm[rep] = true
+			} else {
+				t.Errorf("unexpected triple: %v added to graph", elem)
+			}
+		} else {
+			sr := elem.String()
+			if _, ok := dtm[sr]; !ok {
+				t.Errorf("unexpected triple: %v added to graph", elem)
+			}
+			dtm[sr] = true
+		}
+	}
+	for k, v := range dtm {
+		if v == false {
+			t.Errorf("g.Triples did not return triple: %v", k)
+		}
 	}
+}
 
-	ts := make(chan *triple.Triple)
-	go func() {
-		if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
-			t.Error(err)
+func TestPlannerDeconstructRemovesCorrectTriples(t *testing.T) {
+	testTable := []struct {
+		s    string
+		trps []string
+	}{
+		{
+			s: `deconstruct {?p1 "met"@[] ?p2}
+			    in ?dest
+			    from ?src
+			    where {?p1 "lives_in"@[] /city<A>.
+				   ?p2 "lives_in"@[] /city<B>};`,
+			trps: []string{fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<C>`),
+				fmt.Sprintf("%s\t%s\t%s", `/person<D>`, `"met"@[]`, `/person<A>`),
+				fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"met"@[]`, `/person<C>`),
+				fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<D>`)},
+		},
+		{
+			s: `deconstruct {?p1 "met"@[] ?p2.
+		                         ?p2 "met"@[] ?p1}
+			    in ?dest
+			    from ?src
+			    where {?p1 "lives_in"@[] /city<A>.
+				   ?p2 "lives_in"@[] /city<B>};`,
+			trps: []string{fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"met"@[]`, `/person<C>`),
+				fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<D>`)},
+		},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range testTable {
+
+		s, ctx := memory.NewStore(), context.Background()
+		populateStoreWithTriples(ctx, s, "?src", deconstructTestSrcTriples, t)
+		populateStoreWithTriples(ctx, s, "?dest", deconstructTestDestTriples, t)
+
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.s, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.s, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		_, err = plnr.Execute(ctx)
+		if err != nil {
+			t.Errorf("planner.Execute failed for query %q with error %v", entry.s, err)
+			continue
+		}
+
+		g, err := s.Graph(ctx, "?dest")
+		if err != nil {
+			t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+		}
+
+		ts := make(chan *triple.Triple)
+		go func() {
+			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		dt := make(map[string]bool)
+		for _, trp := range entry.trps {
+			dt[trp] = false
+		}
+
+		i := 0
+		for trp := range ts {
+			if val, ok := dt[trp.String()]; ok {
+				if !val {
+					i++
+				}
+				dt[trp.String()] = true
+			} else {
+				t.Errorf("unexpected triple: %v added to graph", trp)
+			}
+		}
+		if i != len(entry.trps) {
+			t.Errorf("g.Triples did not return some of the triples.")
+		}
+	}
+
+}
+
+func TestPlannerRegisteredAccumulator(t *testing.T) {
+	if err := table.RegisterAccumulator("test_children", func() table.Accumulator { return table.NewCountAccumulator() }); err != nil {
+		t.Fatalf("table.RegisterAccumulator failed with error %v", err)
+	}
+	defer table.UnregisterAccumulator("test_children")
+
+	q := `select ?grandparent, test_children(?name) as ?grandchildren from ?test where {/u<joe> as ?grandparent "parent_of"@[] ?offspring . ?offspring "parent_of"@[] ?name} group by ?grandparent;`
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for query %q with error %v", q, err)
+	}
+	rws := tbl.Rows()
+	if got, want := len(rws), 1; got != want {
+		t.Fatalf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", q, got, want, tbl)
+	}
+	c := rws[0]["?grandchildren"]
+	if c == nil || c.L == nil {
+		t.Fatalf("planner.Execute returned an invalid aggregated cell for query %q; got %v", q, c)
+	}
+	if got, err := c.L.Int64(); err != nil || got != 2 {
+		t.Errorf("planner.Execute returned the wrong aggregated value for query %q; got %v, want 2", q, c.L)
+	}
+}
+
+func TestPlannerScalarFunctions(t *testing.T) {
+	q := `select id(?r) as ?room, time(?p) as ?at, type(?r) as ?type from ?test where {/item/book<000> ?p ?r . filter(type(?p) = "TEMPORAL"^^type:text && time(?p) > time("2016-04-10T04:22:00Z"^^type:text))} order by ?at;`
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for query %q with error %v", q, err)
+	}
+	rws := tbl.Rows()
+	if got, want := len(rws), 2; got != want {
+		t.Fatalf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", q, got, want, tbl)
+	}
+	for i, want := range []struct {
+		room string
+		at   time.Time
+	}{
+		{"Kitchen", time.Date(2016, 4, 10, 4, 23, 0, 0, time.UTC)},
+		{"Bedroom", time.Date(2016, 4, 10, 4, 25, 0, 0, time.UTC)},
+	} {
+		r := rws[i]
+		if r["?room"].L == nil || r["?room"].L.Interface() != want.room {
+			t.Errorf("planner.Execute returned the wrong ?room for row %d; got %v, want %q", i, r["?room"], want.room)
+		}
+		if r["?at"].T == nil || !r["?at"].T.Equal(want.at) {
+			t.Errorf("planner.Execute returned the wrong ?at for row %d; got %v, want %v", i, r["?at"], want.at)
+		}
+		if r["?type"].L == nil || r["?type"].L.Interface() != "/room" {
+			t.Errorf("planner.Execute returned the wrong ?type for row %d; got %v, want /room", i, r["?type"])
+		}
+	}
+}
+
+func TestPlannerConditionalExpressions(t *testing.T) {
+	q := `select ?c, coalesce(?g, ?c) as ?who, if(?g = /u<eve>, "yes"^^type:text, "no"^^type:text) as ?is_eve
+	      from ?test
+	      where { ?s "parent_of"@[] ?c . optional { ?c "parent_of"@[] ?g } };`
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for query %q with error %v", q, err)
+	}
+	if got, want := tbl.NumRows(), 5; got != want {
+		t.Fatalf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", q, got, want, tbl)
+	}
+	self, eve := 0, 0
+	for _, r := range tbl.Rows() {
+		if r["?who"].String() == r["?c"].String() {
+			self++
+		}
+		if r["?is_eve"].L != nil && r["?is_eve"].L.Interface() == "yes" {
+			eve++
+		}
+	}
+	if self != 3 || eve != 1 {
+		t.Errorf("planner.Execute returned the wrong conditional values for query %q; got %d coalesced and %d matching rows, want 3 and 1\nGot:\n%v\n", q, self, eve, tbl)
+	}
+}
+
+func TestPlannerGroupByExpressions(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want map[string]string
+	}{
+		{
+			q: `select hour(?p) as ?h, count(?r) as ?n from ?test where {/item/book<000> ?p ?r} group by hour(?p);`,
+			want: map[string]string{
+				"2016-04-10T04:00:00Z": `"3"^^type:int64`,
+			},
+		},
+		{
+			q: `select month(?p) as ?m, count(?c) as ?n from ?test where {/u<peter> "bought"@[,] as ?p ?c} group by ?m;`,
+			want: map[string]string{
+				"2016-01-01T00:00:00Z": `"1"^^type:int64`,
+				"2016-02-01T00:00:00Z": `"1"^^type:int64`,
+				"2016-03-01T00:00:00Z": `"1"^^type:int64`,
+				"2016-04-01T00:00:00Z": `"1"^^type:int64`,
+			},
+		},
+		{
+			q: `select type(?p) as ?t, count(?c) as ?n from ?test where {/u<peter> ?p ?c} group by type(?p);`,
+			want: map[string]string{
+				`"IMMUTABLE"^^type:text`: `"2"^^type:int64`,
+				`"TEMPORAL"^^type:text`:  `"4"^^type:int64`,
+			},
+		},
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		bs := tbl.Bindings()
+		if len(bs) != 2 {
+			t.Fatalf("planner.Execute returned the wrong bindings for query %q; got %v, want 2 bindings", entry.q, bs)
+		}
+		got := make(map[string]string)
+		for _, r := range tbl.Rows() {
+			got[r[bs[0]].String()] = r[bs[1]].String()
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned the wrong groups for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerGroupByHiddenExpression(t *testing.T) {
+	q := `select count(?c) as ?n from ?test where {/u<peter> ?p ?c} group by year(?p);`
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for query %q with error %v", q, err)
+	}
+	if got, want := tbl.Bindings(), []string{"?n"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planner.Execute returned the wrong bindings for query %q; got %v, want %v", q, got, want)
+	}
+	var got []string
+	for _, r := range tbl.Rows() {
+		got = append(got, r["?n"].String())
+	}
+	sort.Strings(got)
+	if want := []string{`"2"^^type:int64`, `"4"^^type:int64`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planner.Execute returned the wrong counts for query %q; got %v, want %v", q, got, want)
+	}
+}
+
+func TestPlannerCanceledContext(t *testing.T) {
+	testTable := []string{
+		`select ?s, ?o from ?test where {?s "parent_of"@[] ?x . ?x "parent_of"@[] ?o};`,
+		`construct {?s "grandparent_of"@[] ?o} into ?test from ?test where {?s "parent_of"@[] ?x . ?x "parent_of"@[] ?o};`,
+		`describe /u<joe> in ?test depth "2"^^type:int64;`,
+		`insert data into ?test {/u<joe> "parent_of"@[] /u<zoe>};`,
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, q := range testTable {
+		s, ctx := memory.NewStore(), context.Background()
+		populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+		g, err := s.Graph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		before, err := storage.CountTriples(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+		}
+		cctx, cancel := context.WithCancel(ctx)
+		plnr, err := New(cctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		cancel()
+		if _, err := plnr.Execute(cctx); err != context.Canceled {
+			t.Errorf("planner.Execute(%q) should have been canceled; got error %v", q, err)
+		}
+		after, err := storage.CountTriples(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if before != after {
+			t.Errorf("planner.Execute(%q) should not have changed the graph once canceled; got %d triples, want %d", q, after, before)
+		}
+	}
+}
+
+func TestPlannerBudgets(t *testing.T) {
+	cross := `select ?p, ?c from ?test where {?p "parent_of"@[] ?x . ?c "is_a"@[] ?t};`
+	testTable := []struct {
+		q    string
+		opts Options
+		fail bool
+	}{
+		{q: cross, opts: Options{}},
+		{q: cross, opts: Options{MaxIntermediateRows: 16}},
+		{q: cross, opts: Options{MaxIntermediateRows: 15}, fail: true},
+		{q: `select ?s from ?test where {?s ?p ?o};`, opts: Options{MaxIntermediateRows: 3}, fail: true},
+		{q: `ask from ?test where {?p "parent_of"@[] ?x . ?c "is_a"@[] ?t};`, opts: Options{MaxIntermediateRows: 10}, fail: true},
+		{q: cross, opts: Options{MaxExecutionTime: time.Hour}},
+		{q: cross, opts: Options{MaxExecutionTime: time.Nanosecond}, fail: true},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, entry.opts)
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error %v", err)
+		}
+		_, err = plnr.Execute(ctx)
+		if !entry.fail {
+			if err != nil {
+				t.Errorf("planner.Execute(%q) with options %+v failed with error %v", entry.q, entry.opts, err)
+			}
+			continue
+		}
+		berr, ok := err.(*ErrQueryBudgetExceeded)
+		if !ok {
+			t.Errorf("planner.Execute(%q) with options %+v should have returned ErrQueryBudgetExceeded; got %v", entry.q, entry.opts, err)
+			continue
+		}
+		if berr.MaxExecutionTime != entry.opts.MaxExecutionTime || berr.MaxIntermediateRows != entry.opts.MaxIntermediateRows {
+			t.Errorf("planner.Execute(%q) returned the wrong exceeded budget %+v; want %+v", entry.q, berr, entry.opts)
 		}
-	}()
-
-	bnm := make(map[string]map[string]bool)
-	bns := make(map[string]string)
-	bna := map[string]bool{
-		"/_<b1>": true,
-		"/_<b2>": true,
 	}
-	dtm := map[string]bool{
-		fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"connected_to"@[]`, `/person<B>`):                                 false,
-		fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"connected_to"@[]`, `/person<C>`):                                 false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"_subject"@[2016-04-10T04:25:00Z]`, `/person<A>`):                     false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"_predicate"@[2016-04-10T04:25:00Z]`, `"met"@[2016-04-10T04:25:00Z]`): false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"_object"@[2016-04-10T04:25:00Z]`, `/person<B>`):                      false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"location"@[]`, `/city<New York>`):                                    false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"outcome"@[]`, `"good"^^type:text`):                                   false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"_subject"@[2016-04-10T04:25:00Z]`, `/person<B>`):                     false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"_predicate"@[2016-04-10T04:25:00Z]`, `"met"@[2016-04-10T04:25:00Z]`): false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"_object"@[2016-04-10T04:25:00Z]`, `/person<C>`):                      false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"location"@[]`, `/city<New York>`):                                    false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"outcome"@[]`, `"good"^^type:text`):                                   false,
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(cross, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", cross, err)
 	}
+	if _, err := NewWithOptions(ctx, s, st, 0, 10, nil, Options{MaxIntermediateRows: -1}); err == nil {
+		t.Errorf("planner.NewWithOptions should have rejected negative limits")
+	}
+}
 
-	// First, we map each blank node generated to a potential blank node placeholder (such as b1 or b2.)
-	sts := []*triple.Triple{}
-	for elem := range ts {
-		sts = append(sts, elem)
-		if elem.Subject().Type().String() == "/_" {
-			for k := range dtm {
-				trp, err := triple.Parse(k, literal.DefaultBuilder())
-				if err != nil {
-					t.Errorf("Unable to parse triple: %v with error %v", k, err)
-				}
-				if trp.Subject().Type().String() == "/_" &&
-					trp.Predicate().String() == elem.Predicate().String() &&
-					trp.Object().String() == elem.Object().String() {
-					if mp, ok := bnm[elem.Subject().String()]; !ok {
-						bnm[elem.Subject().String()] = map[string]bool{
-							trp.Subject().String(): true,
-						}
-					} else {
-						mp[trp.Subject().String()] = true
-					}
+func TestPlannerOrderClauses(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want []int
+	}{
+		{
+			q:    `select ?x from ?test where { ?x "parent_of"@[] ?y . ?y "bought"@[,] ?c . ?c "is_a"@[] /t<car> };`,
+			want: []int{2, 1, 0},
+		},
+		{
+			q:    `select ?x from ?test where { ?x "parent_of"@[] ?y . /u<joe> "parent_of"@[] ?x . optional { ?y "bought"@[,] ?c } . ?c "is_a"@[] ?t . ?c ?p /t<car> };`,
+			want: []int{1, 0, 2, 4, 3},
+		},
+		{
+			q:    `select ?x from ?test where { /u<peter> "parent_of"@[] ?x . ?x "parent_of"@[] ?y };`,
+			want: []int{0, 1},
+		},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		qp, err := newQueryPlan(ctx, s, st, 0, nil)
+		if err != nil {
+			t.Fatalf("newQueryPlan failed to create a valid query plan with error %v", err)
+		}
+		if err := st.Init(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+		qp.grfs = st.InputGraphs()
+		qp.orderGraphPattern(ctx)
+		var got []int
+		for _, c := range qp.cls {
+			for i, oc := range st.GraphPatternClauses() {
+				if c == oc {
+					got = append(got, i)
 				}
 			}
-
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("orderGraphPattern for %q returned clauses in order %v; want %v", entry.q, got, entry.want)
+		}
+		if _, err := qp.Execute(ctx); err != nil {
+			t.Errorf("planner.Execute(%q) failed with error %v", entry.q, err)
 		}
 	}
+}
 
-	// Then, we decide which place holder blank nodes can be used to substiute for a given blank node
-	// by substituting the place holder in every triple where the given blank node is the subject and
-	// checking if the triple exists in the map of expected triples.
-	for _, t := range sts {
-		if t.Subject().Type().String() == "/_" {
-			for bn := range bnm[t.Subject().String()] {
-				rep := fmt.Sprintf("%s\t%s\t%s", bn, t.Predicate().String(), t.Object().String())
-				if _, ok := dtm[rep]; !ok {
-					bnm[t.Subject().String()][bn] = false
+func TestPlannerParallelism(t *testing.T) {
+	testTable := []struct {
+		q     string
+		group int
+	}{
+		{`select ?p, ?c from ?test where {?p "parent_of"@[] ?x . ?c "is_a"@[] ?t};`, 2},
+		{`select ?a, ?b, ?c from ?test where {?a "parent_of"@[] ?x . ?b "bought"@[,] ?y . ?c "is_a"@[] ?t . ?a "parent_of"@[] /u<mary>};`, 3},
+		{`select ?a, ?c from ?test where {?a "parent_of"@[] ?x . optional {?c "is_a"@[] ?t} . ?d "bought"@[,] ?y};`, 0},
+		{`select ?x, ?y from ?test where {/u<joe> "parent_of"@[] ?x . ?x "parent_of"@[] ?y};`, 0},
+		{`select ?a, ?c from ?test where {?a "parent_of"@[] ?x . ?c "is_a"@[] /t<nothing>};`, 2},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	// rows returns the sorted text representation of the rows of the table.
+	rows := func(tbl *table.Table) []string {
+		var res []string
+		for _, r := range tbl.Rows() {
+			var cs []string
+			for _, b := range tbl.Bindings() {
+				cs = append(cs, r[b].String())
+			}
+			res = append(res, strings.Join(cs, "\t"))
+		}
+		sort.Strings(res)
+		return res
+	}
+	for _, entry := range testTable {
+		var want []string
+		for _, par := range []int{0, 4} {
+			st := &semantic.Statement{}
+			if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+				t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+			}
+			plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, Options{Parallelism: par})
+			if err != nil {
+				t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error %v", err)
+			}
+			if par > 0 {
+				qp := plnr.(*queryPlan)
+				if got := len(qp.independentClauses(st.GraphPatternClauses())); got != entry.group && !(got < 2 && entry.group == 0) {
+					t.Errorf("independentClauses for %q returned %d clauses; want %d", entry.q, got, entry.group)
 				}
 			}
+			tbl, err := plnr.Execute(ctx)
+			if err != nil {
+				t.Fatalf("planner.Execute(%q) with parallelism %d failed with error %v", entry.q, par, err)
+			}
+			if par == 0 {
+				want = rows(tbl)
+				continue
+			}
+			if got := rows(tbl); !reflect.DeepEqual(got, want) {
+				t.Errorf("planner.Execute(%q) with parallelism %d returned %v; want %v", entry.q, par, got, want)
+			}
 		}
 	}
+}
 
-	// Finally, we assign a blank node to a place-holder blank node, if the place-holder blank node is
-	// not used to substitute any other blank node.
-	for k, v := range bnm {
-		for bn, p := range v {
-			if p && bna[bn] {
-				bns[k] = bn
-				bna[bn] = false
-				break
+// countingStore wraps a store counting the triple lookups done in its graphs,
+// keyed by the method and the ID of the predicate looked up, if any.
+type countingStore struct {
+	storage.Store
+	mu      sync.Mutex
+	lookups map[string]int
+}
+
+func (s *countingStore) count(method string, p *predicate.Predicate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := method
+	if p != nil {
+		k += " " + string(p.ID())
+	}
+	s.lookups[k]++
+}
+
+func (s *countingStore) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	g, err := s.Store.Graph(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &countingGraph{Graph: g, s: s}, nil
+}
+
+// countingGraph counts the triple lookups done in a graph of a countingStore.
+type countingGraph struct {
+	storage.Graph
+	s *countingStore
+}
+
+func (g *countingGraph) Objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	g.s.count("Objects", p)
+	return g.Graph.Objects(ctx, s, p, lo, objs)
+}
+
+func (g *countingGraph) Subjects(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, subjs chan<- *node.Node) error {
+	g.s.count("Subjects", p)
+	return g.Graph.Subjects(ctx, p, o, lo, subjs)
+}
+
+func (g *countingGraph) PredicatesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	g.s.count("PredicatesForSubject", nil)
+	return g.Graph.PredicatesForSubject(ctx, s, lo, prds)
+}
+
+func (g *countingGraph) PredicatesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	g.s.count("PredicatesForObject", nil)
+	return g.Graph.PredicatesForObject(ctx, o, lo, prds)
+}
+
+func (g *countingGraph) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	g.s.count("PredicatesForSubjectAndObject", nil)
+	return g.Graph.PredicatesForSubjectAndObject(ctx, s, o, lo, prds)
+}
+
+func (g *countingGraph) TriplesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	g.s.count("TriplesForSubject", nil)
+	return g.Graph.TriplesForSubject(ctx, s, lo, trpls)
+}
+
+func (g *countingGraph) TriplesForPredicate(ctx context.Context, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	g.s.count("TriplesForPredicate", p)
+	return g.Graph.TriplesForPredicate(ctx, p, lo, trpls)
+}
+
+func (g *countingGraph) TriplesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	g.s.count("TriplesForObject", nil)
+	return g.Graph.TriplesForObject(ctx, o, lo, trpls)
+}
+
+func (g *countingGraph) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	g.s.count("TriplesForSubjectAndPredicate", p)
+	return g.Graph.TriplesForSubjectAndPredicate(ctx, s, p, lo, trpls)
+}
+
+func (g *countingGraph) TriplesForPredicateAndObject(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	g.s.count("TriplesForPredicateAndObject", p)
+	return g.Graph.TriplesForPredicateAndObject(ctx, p, o, lo, trpls)
+}
+
+func (g *countingGraph) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
+	g.s.count("Exist", t.Predicate())
+	return g.Graph.Exist(ctx, t)
+}
+
+func (g *countingGraph) Triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	g.s.count("Triples", nil)
+	return g.Graph.Triples(ctx, lo, trpls)
+}
+
+func TestPlannerParallelismLooksUpClausesOnce(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want map[string]int
+	}{
+		{
+			q: `select ?p, ?c from ?test where {?p "parent_of"@[] ?x . ?c "is_a"@[] ?t};`,
+			want: map[string]int{
+				"TriplesForPredicate parent_of": 1,
+				"TriplesForPredicate is_a":      1,
+			},
+		},
+		{
+			q: `select ?a, ?b, ?c from ?test where {?a "parent_of"@[] ?x . ?b "bought"@[,] ?y . ?c "is_a"@[] ?t};`,
+			want: map[string]int{
+				"TriplesForPredicate parent_of": 1,
+				"Triples":                       1,
+				"TriplesForPredicate is_a":      1,
+			},
+		},
+		{
+			q: `select ?s, ?c from ?test where {?s "bought"@[,] ?c . ?x "parent_of"@[] ?y . ?t "is_a"@[] /t<car>};`,
+			want: map[string]int{
+				"Triples":                       1,
+				"TriplesForPredicate parent_of": 1,
+				"Subjects is_a":                 1,
+			},
+		},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	ms, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, ms, "?test", originalTriples, t)
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		s := &countingStore{Store: ms, lookups: make(map[string]int)}
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, Options{Parallelism: 4})
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error %v", err)
+		}
+		if _, err := plnr.Execute(ctx); err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error %v", entry.q, err)
+		}
+		if !reflect.DeepEqual(s.lookups, entry.want) {
+			t.Errorf("planner.Execute(%q) did lookups %v; want %v", entry.q, s.lookups, entry.want)
+		}
+	}
+}
+
+func TestPlannerStream(t *testing.T) {
+	testTable := []struct {
+		q     string
+		limit int64
+	}{
+		{`select ?s, ?p, ?o from ?test where {?s ?p ?o};`, 0},
+		{`select ?p as ?p1, ?o as ?o1 from ?test where {/u<joe> ?p ?o};`, 0},
+		{`select ?x, ?y from ?test where {/u<joe> "parent_of"@[] ?x . ?x "parent_of"@[] ?y};`, 0},
+		{`select ?p, ?c from ?test where {?p "parent_of"@[] ?x . ?c "is_a"@[] ?t};`, 0},
+		{`select ?p, ?a from ?test where { ?p "parent_of"@[] ?c . optional { ?p "age"@[] ?a } };`, 0},
+		{`select ?c, coalesce(?g, ?c) as ?who from ?test where { ?s "parent_of"@[] ?c . optional { ?c "parent_of"@[] ?g } };`, 0},
+		{`select id(?o) as ?id from ?test where {/u<joe> "parent_of"@[] ?o . filter(?o != /u<mary>)};`, 0},
+		{`select ?s, ?o from ?test where {?s "parent_of"@[] ?o} limit "2"^^type:int64;`, 2},
+		{`select ?p, ?c from ?test where {?p "parent_of"@[] ?x . ?c "is_a"@[] ?t} limit "3"^^type:int64;`, 3},
+		{`select ?p, ?c from ?test where {?p "parent_of"@[] ?x . ?c "is_a"@[] ?t} limit "3"^^type:int64 offset "2"^^type:int64;`, 3},
+		{`select ?s, ?o from ?test where {?s "parent_of"@[] ?o} group by ?s, ?o;`, 0},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	// rows returns the sorted text representation of the provided rows.
+	rows := func(bs []string, rs []table.Row) []string {
+		var res []string
+		for _, r := range rs {
+			var cs []string
+			for _, b := range bs {
+				cs = append(cs, r[b].String())
 			}
+			res = append(res, strings.Join(cs, "\t"))
 		}
+		sort.Strings(res)
+		return res
 	}
-	if len(sts) != len(dtm) {
-		t.Errorf("g.Triples should have returned %v triples, returned %v instead", len(dtm), len(sts))
+	plan := func(q string) Executor {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		return plnr
 	}
-	for _, elem := range sts {
-		if elem.Subject().Type().String() == "/_" {
-			if val, ok := bns[elem.Subject().String()]; ok {
-				// Substitute the blank node with the mapped place holder blank node id.
-				rep := fmt.Sprintf("%s\t%s\t%s", val, elem.Predicate().String(), elem.Object().String())
-				if _, ok := dtm[rep]; !ok {
-					t.Errorf("unexpected triple: %v added to graph", elem)
+	for _, entry := range testTable {
+		// The rows streamed need to be the same ones returned by the fully
+		// materialized execution of the query without its limit and offset.
+		q := entry.q
+		if i := strings.Index(q, "} "); i > 0 && entry.limit > 0 {
+			q = q[:i+1] + ";"
+		}
+		tbl, err := plan(q).Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error %v", q, err)
+		}
+		all := rows(tbl.Bindings(), tbl.Rows())
+		it, err := plan(entry.q).(Streamer).Stream(ctx)
+		if err != nil {
+			t.Fatalf("planner.Stream(%q) failed with error %v", entry.q, err)
+		}
+		stbl, err := streamTable(ctx, it)
+		if err != nil {
+			t.Fatalf("planner.Stream(%q) failed to return the rows with error %v", entry.q, err)
+		}
+		if got, want := stbl.Bindings(), tbl.Bindings(); !reflect.DeepEqual(got, want) {
+			t.Errorf("planner.Stream(%q) returned bindings %v; want %v", entry.q, got, want)
+		}
+		got := rows(stbl.Bindings(), stbl.Rows())
+		if entry.limit == 0 {
+			if !reflect.DeepEqual(got, all) {
+				t.Errorf("planner.Stream(%q) returned %v; want %v", entry.q, got, all)
+			}
+			continue
+		}
+		// Queries with a limit may return any subset of the rows.
+		ltbl, err := plan(entry.q).Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error %v", entry.q, err)
+		}
+		for _, rs := range [][]string{got, rows(ltbl.Bindings(), ltbl.Rows())} {
+			if int64(len(rs)) != entry.limit {
+				t.Errorf("planner for %q returned %d rows; want %d", entry.q, len(rs), entry.limit)
+			}
+			for _, r := range rs {
+				found := false
+				for _, a := range all {
+					found = found || a == r
+				}
+				if !found {
+					t.Errorf("planner for %q returned unexpected row %q", entry.q, r)
 				}
-				dtm[rep] = true
-			} else {
-				t.Errorf("unexpected triple: %v added to graph", elem)
 			}
-		} else {
-			sr := elem.String()
-			if _, ok := dtm[sr]; !ok {
-				t.Errorf("unexpected triple: %v added to graph", elem)
+		}
+	}
+}
+
+func TestPlannerToDOT(t *testing.T) {
+	testTable := []struct {
+		q     string
+		nodes int
+		want  []string
+	}{
+		{
+			q:     `select ?x, ?y from ?test where {/u<joe> "parent_of"@[] ?x . ?x "parent_of"@[] ?y};`,
+			nodes: 4,
+			want: []string{
+				`lookup: Objects`,
+				`nested loop join on ?x`,
+				`/u<joe> \"parent_of\"@[] ?x`,
+				`project\n?x as ?x, ?y as ?y\nactual rows: `,
+			},
+		},
+		{
+			q:     `select ?x from ?test where { { /u<joe> "parent_of"@[] ?x } union { /u<peter> "parent_of"@[] ?x } } limit "1"^^type:int64;`,
+			nodes: 5,
+			want: []string{
+				`union\nactual rows: `,
+				`limit\n1\nactual rows: 1`,
+			},
+		},
+		{
+			q:     `select ?p, ?a from ?test where { ?p "parent_of"@[] ?c . optional { ?p "age"@[] ?a } . filter(?c != /u<mary>) };`,
+			nodes: 5,
+			want: []string{
+				`opt=true ?p \"age\"@[] ?a`,
+				`nested loop join on ?p`,
+				`filter\n( ?c != /u<mary> )\nactual rows: `,
+			},
+		},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		e, ok := plnr.(DOTExporter)
+		if !ok {
+			t.Fatalf("planner.New(%q) returned a plan that cannot be exported as DOT", entry.q)
+		}
+		before, err := e.ToDOT(ctx)
+		if err != nil {
+			t.Fatalf("planner.ToDOT(%q) failed with error %v", entry.q, err)
+		}
+		if strings.Contains(before, "actual rows") {
+			t.Errorf("planner.ToDOT(%q) returned actual rows before executing the plan:\n%s", entry.q, before)
+		}
+		if _, err := plnr.Execute(ctx); err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error %v", entry.q, err)
+		}
+		got, err := e.ToDOT(ctx)
+		if err != nil {
+			t.Fatalf("planner.ToDOT(%q) failed with error %v", entry.q, err)
+		}
+		if !strings.HasPrefix(got, "digraph plan {") || !strings.HasSuffix(got, "}\n") {
+			t.Errorf("planner.ToDOT(%q) returned an invalid DOT graph:\n%s", entry.q, got)
+		}
+		// The operators form a tree.
+		if n, edges := strings.Count(got, "[label="), strings.Count(got, " -> "); n != entry.nodes || edges != n-1 {
+			t.Errorf("planner.ToDOT(%q) returned %d nodes and %d edges; want %d nodes and %d edges:\n%s", entry.q, n, edges, entry.nodes, entry.nodes-1, got)
+		}
+		for _, w := range entry.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("planner.ToDOT(%q) does not contain %q:\n%s", entry.q, w, got)
 			}
-			dtm[sr] = true
 		}
 	}
-	for k, v := range dtm {
-		if v == false {
-			t.Errorf("g.Triples did not return triple: %v", k)
+}
+
+func TestPlannerResultCache(t *testing.T) {
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	c := NewResultCache(2)
+	run := func(q string) *table.Table {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+		}
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, Options{ResultCache: c})
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error %v", q, err)
+		}
+		return tbl
+	}
+	checkStats := func(step string, hits, misses int64) {
+		if gh, gm := c.Stats(); gh != hits || gm != misses {
+			t.Errorf("%s: ResultCache.Stats returned %d hits and %d misses; want %d hits and %d misses", step, gh, gm, hits, misses)
 		}
 	}
+	q := `select ?x from ?test where {/u<joe> "parent_of"@[] ?x};`
+	first := run(q)
+	checkStats("first run", 0, 1)
+	// Modifying the returned table does not alter the cached result.
+	first.Truncate()
+	if got, want := run(`select ?x   from ?test
+		where { /u<joe> "parent_of"@[] ?x };`).NumRows(), 2; got != want {
+		t.Errorf("planner.Execute returned %d cached rows; want %d", got, want)
+	}
+	checkStats("repeated run", 1, 1)
+	// Changing the graph invalidates the cached result.
+	g, err := s.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadIntoGraph(ctx, g, bytes.NewBufferString(`/u<joe> "parent_of"@[] /u<alice>`), literal.DefaultBuilder()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := run(q).NumRows(), 3; got != want {
+		t.Errorf("planner.Execute returned %d rows after changing the graph; want %d", got, want)
+	}
+	checkStats("run after changing the graph", 1, 2)
+	// The least recently used results are evicted.
+	run(`select ?x from ?test where {/u<peter> "parent_of"@[] ?x};`)
+	run(`select ?x from ?test where {/u<mary> "parent_of"@[] ?x};`)
+	if got, want := c.Len(), 2; got != want {
+		t.Errorf("ResultCache.Len returned %d; want %d", got, want)
+	}
+	run(q)
+	checkStats("run after eviction", 1, 5)
 }
 
-func TestPlannerDeconstructRemovesCorrectTriples(t *testing.T) {
+func TestPlannerHooks(t *testing.T) {
 	testTable := []struct {
-		s    string
-		trps []string
+		q     string
+		par   int
+		total int
+		rows  []int
 	}{
 		{
-			s: `deconstruct {?p1 "met"@[] ?p2}
-			    in ?dest
-			    from ?src
-			    where {?p1 "lives_in"@[] /city<A>.
-				   ?p2 "lives_in"@[] /city<B>};`,
-			trps: []string{fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<C>`),
-				fmt.Sprintf("%s\t%s\t%s", `/person<D>`, `"met"@[]`, `/person<A>`),
-				fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"met"@[]`, `/person<C>`),
-				fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<D>`)},
+			q:     `select ?x, ?y from ?test where {/u<joe> "parent_of"@[] ?x . ?x "parent_of"@[] ?y};`,
+			total: 2,
+			rows:  []int{2, 2},
 		},
 		{
-			s: `deconstruct {?p1 "met"@[] ?p2.
-		                         ?p2 "met"@[] ?p1}
-			    in ?dest
-			    from ?src
-			    where {?p1 "lives_in"@[] /city<A>.
-				   ?p2 "lives_in"@[] /city<B>};`,
-			trps: []string{fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"met"@[]`, `/person<C>`),
-				fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<D>`)},
+			q:     `select ?p, ?c from ?test where {/u<joe> "parent_of"@[] ?p . /u<peter> "parent_of"@[] ?c};`,
+			par:   2,
+			total: 2,
+			rows:  []int{2, 4},
+		},
+		{
+			q:     `select ?x from ?test where { { /u<joe> "parent_of"@[] ?x } union { /u<peter> "parent_of"@[] ?x } };`,
+			total: 1,
+			rows:  []int{2, 2},
 		},
 	}
 	p, err := grammar.NewParser(grammar.SemanticBQL())
 	if err != nil {
-		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
 	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
 	for _, entry := range testTable {
-
-		s, ctx := memory.NewStore(), context.Background()
-		populateStoreWithTriples(ctx, s, "?src", deconstructTestSrcTriples, t)
-		populateStoreWithTriples(ctx, s, "?dest", deconstructTestDestTriples, t)
-
+		var starts, dones []ClauseEvent
+		hooks := Hooks{
+			OnClauseStart: func(e ClauseEvent) {
+				starts = append(starts, e)
+			},
+			OnClauseDone: func(e ClauseEvent) {
+				dones = append(dones, e)
+			},
+		}
 		st := &semantic.Statement{}
-		if err := p.Parse(grammar.NewLLk(entry.s, 1), st); err != nil {
-			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.s, err)
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
 		}
-		plnr, err := New(ctx, s, st, 0, 10, nil)
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, Options{Parallelism: entry.par, Hooks: hooks})
 		if err != nil {
-			t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+			t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error %v", err)
 		}
-		_, err = plnr.Execute(ctx)
-		if err != nil {
-			t.Errorf("planner.Execute failed for query %q with error %v", entry.s, err)
-			continue
+		if _, err := plnr.Execute(ctx); err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error %v", entry.q, err)
+		}
+		if len(starts) != len(entry.rows) || len(dones) != len(entry.rows) {
+			t.Fatalf("planner.Execute(%q) notified %d starts and %d dones; want %d of each", entry.q, len(starts), len(dones), len(entry.rows))
+		}
+		var rows []int
+		for i, e := range dones {
+			if e.Total != entry.total || e.Index != i%entry.total || e.Clause == nil || e.Err != nil {
+				t.Errorf("planner.Execute(%q) notified invalid event %+v for clause %d", entry.q, e, i)
+			}
+			if e.Clause != starts[i].Clause || e.Pattern != starts[i].Pattern {
+				t.Errorf("planner.Execute(%q) notified clause %v done after starting %v", entry.q, e.Clause, starts[i].Clause)
+			}
+			rows = append(rows, e.Rows)
+		}
+		if !reflect.DeepEqual(rows, entry.rows) {
+			t.Errorf("planner.Execute(%q) notified clauses done with %v rows; want %v", entry.q, rows, entry.rows)
 		}
+	}
+}
 
-		g, err := s.Graph(ctx, "?dest")
+func TestPlannerMemoryBudget(t *testing.T) {
+	// The product of 4 parents and 4 typed nodes uses 16 rows with 4
+	// bindings.
+	cross := `select ?p, ?c from ?test where {?p "parent_of"@[] ?x . ?c "is_a"@[] ?t};`
+	need := estimateTableBytes(16, 4)
+	// Joining the 2 children of joe on ?x with their own 2 children uses 2
+	// rows with 2 bindings, while the children of joe alone fit in 2 rows
+	// with 1 binding.
+	join := `select ?x, ?y from ?test where {/u<joe> "parent_of"@[] ?x . ?x "parent_of"@[] ?y};`
+	joinNeed := estimateTableBytes(2, 2)
+	testTable := []struct {
+		q    string
+		opts Options
+		want *ErrQueryBudgetExceeded
+	}{
+		{q: cross, opts: Options{MaxMemory: need}},
+		{q: cross, opts: Options{MaxMemory: need - 1}, want: &ErrQueryBudgetExceeded{MaxMemory: need - 1}},
+		{q: cross, opts: Options{Memory: NewMemoryAccountant(need)}},
+		{q: cross, opts: Options{Memory: NewMemoryAccountant(need - 1)}, want: &ErrQueryBudgetExceeded{MaxGlobalMemory: need - 1}},
+		{q: join, opts: Options{MaxMemory: joinNeed}},
+		{q: join, opts: Options{MaxMemory: joinNeed - 1}, want: &ErrQueryBudgetExceeded{MaxMemory: joinNeed - 1}},
+		{q: join, opts: Options{Memory: NewMemoryAccountant(joinNeed - 1)}, want: &ErrQueryBudgetExceeded{MaxGlobalMemory: joinNeed - 1}},
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, entry.opts)
 		if err != nil {
-			t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+			t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error %v", err)
+		}
+		_, err = plnr.Execute(ctx)
+		if entry.want == nil {
+			if err != nil {
+				t.Errorf("planner.Execute(%q) with options %+v failed with error %v", entry.q, entry.opts, err)
+			}
+		} else if berr, ok := err.(*ErrQueryBudgetExceeded); !ok || *berr != *entry.want {
+			t.Errorf("planner.Execute(%q) with options %+v returned error %v; want %v", entry.q, entry.opts, err, entry.want)
 		}
+		if m := entry.opts.Memory; m != nil && m.InUse() != 0 {
+			t.Errorf("planner.Execute(%q) left %d bytes reserved; want 0", entry.q, m.InUse())
+		}
+	}
 
-		ts := make(chan *triple.Triple)
-		go func() {
-			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
-				t.Error(err)
+	// The join fails while adding its rows, before the clause is done.
+	var clauseErr error
+	opts := Options{
+		MaxMemory: joinNeed - 1,
+		Hooks: Hooks{OnClauseDone: func(e ClauseEvent) {
+			if e.Err != nil {
+				clauseErr = e.Err
 			}
-		}()
+		}},
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(join, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", join, err)
+	}
+	plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, opts)
+	if err != nil {
+		t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error %v", err)
+	}
+	if _, err := plnr.Execute(ctx); err == nil {
+		t.Errorf("planner.Execute(%q) should have exceeded its memory budget", join)
+	}
+	if _, ok := clauseErr.(*ErrQueryBudgetExceeded); !ok {
+		t.Errorf("planner.Execute(%q) reported clause error %v; want ErrQueryBudgetExceeded", join, clauseErr)
+	}
 
-		dt := make(map[string]bool)
-		for _, trp := range entry.trps {
-			dt[trp] = false
-		}
+	st = &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(cross, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", cross, err)
+	}
+	if _, err := NewWithOptions(ctx, s, st, 0, 10, nil, Options{MaxMemory: -1}); err == nil {
+		t.Errorf("planner.NewWithOptions should have rejected negative memory limits")
+	}
+}
 
-		i := 0
-		for trp := range ts {
-			if val, ok := dt[trp.String()]; ok {
-				if !val {
-					i++
+func TestPlannerReadsSnapshots(t *testing.T) {
+	q := `select ?x, ?y from ?test where {/u<joe> "parent_of"@[] ?x . ?x "parent_of"@[] ?y};`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	g, err := s.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rm []*triple.Triple
+	for _, ts := range []string{
+		"/u<peter>\t\"parent_of\"@[]\t/u<john>",
+		"/u<peter>\t\"parent_of\"@[]\t/u<eve>",
+	} {
+		trpl, err := triple.Parse(ts, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rm = append(rm, trpl)
+	}
+	// The children of peter are removed once the first clause is solved, but
+	// the query keeps reading the triples the graph had when it started.
+	hooks := Hooks{
+		OnClauseDone: func(e ClauseEvent) {
+			if e.Index == 0 {
+				if err := g.RemoveTriples(ctx, rm); err != nil {
+					t.Error(err)
 				}
-				dt[trp.String()] = true
-			} else {
-				t.Errorf("unexpected triple: %v added to graph", trp)
 			}
-		}
-		if i != len(entry.trps) {
-			t.Errorf("g.Triples did not return some of the triples.")
-		}
+		},
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+	}
+	plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, Options{Hooks: hooks})
+	if err != nil {
+		t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute(%q) failed with error %v", q, err)
+	}
+	if got, want := tbl.NumRows(), 2; got != want {
+		t.Errorf("planner.Execute(%q) returned %d rows; want %d", q, got, want)
+	}
+	if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != int64(len(strings.Split(originalTriples, "\n"))-3) {
+		t.Errorf("the graph should have changed while the query ran; got %d triples, %v", cnt, err)
 	}
-
 }
 
 func TestTreeTraversalToRoot(t *testing.T) {
@@ -1143,3 +3523,230 @@ func BenchmarkReg2(b *testing.B) {
 func BenchmarkAs2(b *testing.B) {
 	benchmarkQuery(`select ?s as ?s1, ?p as ?p1, ?o as ?o1 from ?test where {?s ?p ?o};`, b)
 }
+
+func init() {
+	// A custom literal type for revisions like r9, ordered by their number.
+	_, err := literal.Register(literal.CustomType{
+		Name: "rev",
+		Parse: func(s string) (interface{}, error) {
+			if !strings.HasPrefix(s, "r") {
+				return nil, fmt.Errorf("invalid revision %q", s)
+			}
+			return strconv.Atoi(s[1:])
+		},
+		Format: func(v interface{}) string {
+			return "r" + strconv.Itoa(v.(int))
+		},
+		Compare: func(a, b interface{}) int {
+			return a.(int) - b.(int)
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestPlannerCustomLiterals(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", `/u<alpha> "release"@[] "r10"^^type:rev
+/u<beta> "release"@[] "r9"^^type:rev
+/u<gamma> "release"@[] "r2"^^type:rev
+`, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{`select ?r from ?test where {?s "release"@[] ?r} order by ?r;`, []string{"r2", "r9", "r10"}},
+		{`select ?r from ?test where {?s "release"@[] ?r} order by ?r desc;`, []string{"r10", "r9", "r2"}},
+		{`select ?s from ?test where {?s "release"@[] "r10"^^type:rev};`, []string{"/u<alpha>"}},
+		{`select ?s from ?test where {?s "release"@[] ?v . filter(?v = "r10"^^type:rev)};`, []string{"/u<alpha>"}},
+		{`select ?s from ?test where {?s "release"@[] ?v . filter(?v > "r9"^^type:rev)};`, []string{"/u<alpha>"}},
+		{`select ?s from ?test where {?s "release"@[] ?v . filter(?v <= "r9"^^type:rev || ?s = /u<nobody>)} order by ?s;`, []string{"/u<beta>", "/u<gamma>"}},
+		{`select ?s from ?test where {?s "release"@[] ?v . filter(?v > "r9"^^type:text)};`, nil},
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			if c, ok := r["?r"]; ok {
+				_, text, err := c.L.Custom()
+				if err != nil {
+					t.Fatalf("planner.Execute returned %v for query %q; want a custom literal", c, entry.q)
+				}
+				got = append(got, text)
+			} else {
+				got = append(got, r["?s"].N.String())
+			}
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned %v for query %q; want %v", got, entry.q, entry.want)
+		}
+	}
+}
+
+func TestPlannerIntervalPredicates(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", `/u<alice> "employed_by"@[2010-01-01T00:00:00Z/2014-01-01T00:00:00Z] /org<acme>
+/u<bob> "employed_by"@[2013-01-01T00:00:00Z/2016-01-01T00:00:00Z] /org<acme>
+/u<carol> "employed_by"@[2015-01-01T00:00:00Z/2018-01-01T00:00:00Z] /org<acme>
+/u<dave> "employed_by"@[2015-06-01T00:00:00Z] /org<acme>
+`, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{`select ?s from ?test where {?s "employed_by"@[2013-06-01T00:00:00Z,2013-07-01T00:00:00Z] /org<acme>} order by ?s;`, []string{"/u<alice>", "/u<bob>"}},
+		{`select ?s from ?test where {?s "employed_by"@[2014-01-01T00:00:00Z,] /org<acme>} order by ?s;`, []string{"/u<bob>", "/u<carol>", "/u<dave>"}},
+		{`select ?s from ?test where {?s "employed_by"@[,2012-01-01T00:00:00Z] /org<acme>} order by ?s;`, []string{"/u<alice>"}},
+		{`select ?s from ?test where {?s "employed_by"@[2010-01-01T00:00:00Z/2014-01-01T00:00:00Z] /org<acme>} order by ?s;`, []string{"/u<alice>"}},
+		{`select ?s from ?test where {?s ?p /org<acme> . filter(end(?p) > time("2017-01-01T00:00:00Z"^^type:text))} order by ?s;`, []string{"/u<carol>"}},
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?s"].N.String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned %v for query %q; want %v", got, entry.q, entry.want)
+		}
+	}
+}
+
+func TestPlannerNearest(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", `/item<a> "embedding"@[] "[1,0]"^^type:vector
+/item<b> "embedding"@[] "[1,0.5]"^^type:vector
+/item<c> "embedding"@[] "[0,1]"^^type:vector
+/item<d> "embedding"@[] "[-1,0]"^^type:vector
+/item<e> "embedding"@[] "[1,0]"^^type:vector
+/item<c> "color"@[] "red"^^type:text
+/item<d> "color"@[] "red"^^type:text
+`, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	run := func(q string) []string {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?s"].N.String())
+		}
+		return got
+	}
+	nearest := `select ?s from ?test where {?s "embedding"@[] ?v . filter(nearest(?v, "[2,0.1]"^^type:vector, 2))} order by ?s;`
+	red := `select ?s from ?test where {?s "embedding"@[] ?v . ?s "color"@[] "red"^^type:text . filter(nearest(?v, "[2,0.1]"^^type:vector, 1))} order by ?s;`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{nearest, []string{"/item<a>", "/item<b>", "/item<e>"}},
+		{red, []string{"/item<c>"}},
+		{`select ?s, ?v from ?test where {?s "embedding"@[] ?v} order by cosine(?v, "[0,1]"^^type:vector) desc, ?s limit "2"^^type:int64;`, []string{"/item<c>", "/item<b>"}},
+	}
+	for _, entry := range testTable {
+		if got := run(entry.q); !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned %v for query %q; want %v", got, entry.q, entry.want)
+		}
+	}
+
+	// With an index, the nearest vectors are searched among all the vectors
+	// of the predicate before joining them with the rest of the clauses.
+	g, err := s.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.IndexVectors(ctx, g, "embedding"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := run(nearest), []string{"/item<a>", "/item<b>", "/item<e>"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planner.Execute returned %v using the vector index; want %v", got, want)
+	}
+	if got := run(red); len(got) != 0 {
+		t.Errorf("planner.Execute returned %v using the vector index; want no rows", got)
+	}
+}
+
+func TestPlannerFuzzyMatching(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", `/u<john> "name"@[] "John Smith"^^type:text
+/u<jon> "name"@[] "Jon Smyth"^^type:text
+/u<joan> "name"@[] "Joan Smithson"^^type:text
+/u<mary> "name"@[] "Mary Jones"^^type:text
+`, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{`select ?s from ?test where {?s "name"@[] ?n . filter(levenshtein(?n, "Jhn Smith"^^type:text) <= 1)} order by ?s;`, []string{"/u<john>"}},
+		{`select ?s from ?test where {?s "name"@[] ?n . filter(levenshtein(?s, /u<jhon>) < 3)} order by ?s;`, []string{"/u<joan>", "/u<john>", "/u<jon>"}},
+		{`select ?s from ?test where {?s "name"@[] ?n . filter(soundex(?n) = soundex("Jon Smith"^^type:text))} order by ?s;`, []string{"/u<joan>", "/u<john>", "/u<jon>"}},
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?s"].N.String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute returned %v for query %q; want %v", got, entry.q, entry.want)
+		}
+	}
+}
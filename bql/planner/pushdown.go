@@ -0,0 +1,85 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/storage"
+)
+
+// pushdownFilters returns the lookup options to use for the provided clause
+// extended with the filter constraints that storage drivers can apply while
+// retrieving triples: ranges of literal objects and ranges of predicate time
+// anchors. Rows without a value for a constrained binding never pass a
+// filter comparison, so discarding those triples does not change the result.
+// Filters are still applied to the resulting rows since the pushed down
+// constraints may be looser than the filters they come from.
+//
+// Optional clauses and predicate paths are left untouched, as well as time
+// anchors when only the latest anchor is requested.
+func pushdownFilters(cls *semantic.GraphClause, fs []*semantic.Filter, lo *storage.LookupOptions) *storage.LookupOptions {
+	if cls.Optional || cls.HasPath() || (cls.OBinding == "" && cls.PAnchorBinding == "") {
+		return lo
+	}
+	nlo, pushed := *lo, false
+	for _, f := range fs {
+		for _, c := range f.Constraints() {
+			switch {
+			case c.Binding == cls.OBinding && c.Value.L != nil:
+				nlo.LiteralFilter, pushed = narrowLiteralFilter(nlo.LiteralFilter, c), true
+			case c.Binding == cls.PAnchorBinding && c.Value.T != nil && !lo.LatestAnchor:
+				t := *c.Value.T
+				pushed = true
+				if c.Op != lexer.ItemLT && c.Op != lexer.ItemLEQ && (nlo.LowerAnchor == nil || t.After(*nlo.LowerAnchor)) {
+					nlo.LowerAnchor = &t
+				}
+				if c.Op != lexer.ItemGT && c.Op != lexer.ItemGEQ && (nlo.UpperAnchor == nil || t.Before(*nlo.UpperAnchor)) {
+					nlo.UpperAnchor = &t
+				}
+			}
+		}
+	}
+	if !pushed {
+		return lo
+	}
+	return &nlo
+}
+
+// narrowLiteralFilter returns the literal filter resulting of adding the
+// provided constraint to the current one. Bounds that are not comparable with
+// the current ones are ignored, which keeps the filter looser than required.
+func narrowLiteralFilter(lf *storage.LiteralFilter, c *semantic.Constraint) *storage.LiteralFilter {
+	nlf := &storage.LiteralFilter{}
+	if lf != nil {
+		*nlf = *lf
+	}
+	l, strict := c.Value.L, c.Op == lexer.ItemLT || c.Op == lexer.ItemGT
+	if c.Op != lexer.ItemLT && c.Op != lexer.ItemLEQ {
+		if nlf.Lower == nil {
+			nlf.Lower, nlf.LowerStrict = l, strict
+		} else if cmp, ok := storage.CompareLiterals(l, nlf.Lower); ok && (cmp > 0 || (cmp == 0 && strict)) {
+			nlf.Lower, nlf.LowerStrict = l, strict
+		}
+	}
+	if c.Op != lexer.ItemGT && c.Op != lexer.ItemGEQ {
+		if nlf.Upper == nil {
+			nlf.Upper, nlf.UpperStrict = l, strict
+		} else if cmp, ok := storage.CompareLiterals(l, nlf.Upper); ok && (cmp < 0 || (cmp == 0 && strict)) {
+			nlf.Upper, nlf.UpperStrict = l, strict
+		}
+	}
+	return nlf
+}
@@ -0,0 +1,584 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/badwolf/bql/planner/tracer"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// RowIterator returns the rows of a result one at a time. Rows are computed
+// as they are requested.
+type RowIterator interface {
+	// Bindings returns the bindings of the rows returned.
+	Bindings() []string
+
+	// Next returns the next row of the result. It returns io.EOF once all the
+	// rows were returned.
+	Next(ctx context.Context) (table.Row, error)
+
+	// Close releases the resources used by the iterator. It needs to be
+	// called even if not all the rows were consumed.
+	Close()
+}
+
+// Streamer is implemented by the executors that can return their results as
+// they are computed instead of materializing them in a table.
+type Streamer interface {
+	// Stream returns an iterator over the rows of the result.
+	Stream(ctx context.Context) (RowIterator, error)
+}
+
+// Stream returns an iterator over the rows of the query result. Graph
+// patterns without unions nor subqueries whose results do not need to be
+// grouped or sorted are solved one row at a time, so only the work required
+// for the rows requested is done. Other queries are executed before returning
+// the iterator.
+func (p *queryPlan) Stream(ctx context.Context) (RowIterator, error) {
+	if !p.streamable() {
+		tbl, err := p.Execute(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &streamIterator{
+			bindings: tbl.Bindings(),
+			op:       &rowsOperator{rows: tbl.Rows()},
+		}, nil
+	}
+	if err := p.stm.Init(ctx, p.store); err != nil {
+		return nil, err
+	}
+	p.grfs = p.stm.InputGraphs()
+	release, err := p.snapshotGraphs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.orderGraphPattern(ctx)
+	it, err := p.pipeline(ctx, p.stm.GlobalLookupOptions())
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// The snapshots are used until the iterator is closed.
+	it.release = release
+	return it, nil
+}
+
+// streamable returns true if the query can be solved one row at a time.
+// Filters that rank the rows need all of them before keeping any.
+func (p *queryPlan) streamable() bool {
+	return !p.ask && len(p.unions) == 0 && len(p.stm.Subqueries()) == 0 &&
+		len(p.stm.GroupByBindings()) == 0 && len(p.stm.OrderByConfig()) == 0 &&
+		!p.stm.HasHavingClause() && !needsRanking(p.stm.Filters())
+}
+
+// pipeline returns an iterator chaining the operators that solve the query
+// one row at a time.
+func (p *queryPlan) pipeline(ctx context.Context, lo *storage.LookupOptions) (*streamIterator, error) {
+	params, err := parametersTable(p.stm)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		op operator = &rowsOperator{rows: []table.Row{{}}}
+		bs          = params.Bindings()
+		fs          = p.stm.Filters()
+	)
+	if params.NumRows() > 0 {
+		op = &rowsOperator{rows: params.Rows()}
+	}
+	for i, cls := range p.cls {
+		i, cls := i, cls
+		clo := pushdownFilters(cls, fs, lo)
+		if len(bs) == 0 && scannable(cls) {
+			tracer.Trace(p.tracer, func() []string {
+				return []string{fmt.Sprintf("Streaming clause %d: %v", i, cls)}
+			})
+			op = newScanOperator(ctx, p.grfs, cls, clo, p.chanSize, p.tracer)
+		} else {
+			tracer.Trace(p.tracer, func() []string {
+				return []string{fmt.Sprintf("Streaming joins of clause %d: %v", i, cls)}
+			})
+			op = newJoinOperator(p, op, bs, cls, clo)
+		}
+		bs = appendBindings(bs, cls.Bindings())
+	}
+	if len(fs) > 0 {
+		op = &filterOperator{left: op, fs: fs}
+	}
+	op = &projectOperator{p: p, left: op}
+	if p.stm.IsOffsetSet() || p.stm.IsLimitSet() {
+		l := &limitOperator{left: op, limit: -1}
+		if p.stm.IsOffsetSet() {
+			l.offset = p.stm.Offset()
+		}
+		if p.stm.IsLimitSet() {
+			l.limit = p.stm.Limit()
+		}
+		op = l
+	}
+	return &streamIterator{bindings: p.stm.OutputBindings(), op: op}, nil
+}
+
+// streamTable drains the provided iterator into a table.
+func streamTable(ctx context.Context, it RowIterator) (*table.Table, error) {
+	defer it.Close()
+	tbl, err := table.New(it.Bindings())
+	if err != nil {
+		return nil, err
+	}
+	for {
+		r, err := it.Next(ctx)
+		if err == io.EOF {
+			return tbl, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		tbl.AddRow(r)
+	}
+}
+
+// appendBindings returns the provided bindings followed by the new ones that
+// were not already present.
+func appendBindings(bs, nbs []string) []string {
+	res := append([]string{}, bs...)
+	seen := make(map[string]bool)
+	for _, b := range bs {
+		seen[b] = true
+	}
+	for _, b := range nbs {
+		if !seen[b] {
+			seen[b] = true
+			res = append(res, b)
+		}
+	}
+	return res
+}
+
+// streamIterator implements RowIterator on top of a chain of operators.
+type streamIterator struct {
+	bindings []string
+	op       operator
+	// release, if set, releases the graph snapshots read by the operators.
+	release func()
+}
+
+// Bindings returns the bindings of the rows returned.
+func (it *streamIterator) Bindings() []string {
+	return it.bindings
+}
+
+// Next returns the next row of the result.
+func (it *streamIterator) Next(ctx context.Context) (table.Row, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return it.op.next(ctx)
+}
+
+// Close releases the resources used by the operators and the graph snapshots
+// they read.
+func (it *streamIterator) Close() {
+	it.op.close()
+	if it.release != nil {
+		it.release()
+		it.release = nil
+	}
+}
+
+// operator produces rows on demand. Operators are chained so each one pulls
+// the rows it needs from the previous one.
+type operator interface {
+	// next returns the next row. It returns io.EOF once no more rows are
+	// available.
+	next(ctx context.Context) (table.Row, error)
+
+	// close releases the resources used by the operator and the operators it
+	// pulls rows from.
+	close()
+}
+
+// rowsOperator returns an already computed list of rows.
+type rowsOperator struct {
+	rows []table.Row
+}
+
+func (o *rowsOperator) next(ctx context.Context) (table.Row, error) {
+	if len(o.rows) == 0 {
+		return nil, io.EOF
+	}
+	r := o.rows[0]
+	o.rows = o.rows[1:]
+	return r, nil
+}
+
+func (o *rowsOperator) close() {}
+
+// scannable returns true if the triples matching the clause can be retrieved
+// with a single triple lookup.
+func scannable(cls *semantic.GraphClause) bool {
+	return cls.GraphBinding == "" && !cls.HasPath() && !(cls.S != nil && cls.O != nil)
+}
+
+// scanOperator streams the rows of a clause as the triples matching it are
+// retrieved from the graphs.
+type scanOperator struct {
+	rows   chan table.Row
+	errs   chan error
+	cancel context.CancelFunc
+	done   bool
+	err    error
+}
+
+// newScanOperator starts retrieving the triples of the provided clause. The
+// retrieval blocks until the rows are requested.
+func newScanOperator(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, chanSize int, w io.Writer) *scanOperator {
+	ctx, cancel := context.WithCancel(ctx)
+	o := &scanOperator{
+		rows:   make(chan table.Row, chanSize),
+		errs:   make(chan error, 1),
+		cancel: cancel,
+	}
+	go func() {
+		defer close(o.rows)
+		o.errs <- scanClause(ctx, gs, cls, lo, chanSize, w, func(r table.Row) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case o.rows <- r:
+				return nil
+			}
+		})
+	}()
+	return o
+}
+
+func (o *scanOperator) next(ctx context.Context) (table.Row, error) {
+	if o.done {
+		return nil, o.err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r, ok := <-o.rows:
+		if ok {
+			return r, nil
+		}
+	}
+	o.done, o.err = true, io.EOF
+	if err := <-o.errs; err != nil {
+		o.err = err
+	}
+	return nil, o.err
+}
+
+func (o *scanOperator) close() {
+	o.cancel()
+}
+
+// scanClause retrieves the triples matching the provided clause and passes
+// the resulting rows to the emit function.
+func scanClause(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, chanSize int, w io.Writer, emit func(table.Row) error) error {
+	lo = updateTimeBounds(lo, cls)
+	for _, g := range gs {
+		ts := make(chan *triple.Triple, chanSize)
+		errs := make(chan error, 1)
+		go func(g storage.Graph) {
+			errs <- lookupTriples(ctx, g, cls, lo, ts, w)
+		}(g)
+		var eErr error
+		for t := range ts {
+			if eErr != nil {
+				// Keep draining the channel so the lookup can finish.
+				continue
+			}
+			r, err := clauseRow(t, cls)
+			if err != nil {
+				eErr = err
+				continue
+			}
+			if r != nil {
+				eErr = emit(r)
+			}
+		}
+		if err := <-errs; err != nil {
+			return err
+		}
+		if eErr != nil {
+			return eErr
+		}
+	}
+	return nil
+}
+
+// lookupTriples pushes to the provided channel the triples of the graph
+// matching the fixed subject, predicate, and object of the clause.
+func lookupTriples(ctx context.Context, g storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, ts chan<- *triple.Triple, w io.Writer) error {
+	s, p, o := cls.S, cls.P, cls.O
+	switch {
+	case s != nil && p != nil:
+		tracer.Trace(w, func() []string {
+			return []string{fmt.Sprintf("g.TriplesForSubjectAndPredicate(%v, %v, %v)", s, p, lo)}
+		})
+		return g.TriplesForSubjectAndPredicate(ctx, s, p, lo, ts)
+	case p != nil && o != nil:
+		tracer.Trace(w, func() []string {
+			return []string{fmt.Sprintf("g.TriplesForPredicateAndObject(%v, %v, %v)", p, o, lo)}
+		})
+		return g.TriplesForPredicateAndObject(ctx, p, o, lo, ts)
+	case s != nil:
+		tracer.Trace(w, func() []string {
+			return []string{fmt.Sprintf("g.TriplesForSubject(%v, %v)", s, lo)}
+		})
+		return g.TriplesForSubject(ctx, s, lo, ts)
+	case p != nil:
+		tracer.Trace(w, func() []string {
+			return []string{fmt.Sprintf("g.TriplesForPredicate(%v, %v)", p, lo)}
+		})
+		return g.TriplesForPredicate(ctx, p, lo, ts)
+	case o != nil:
+		tracer.Trace(w, func() []string {
+			return []string{fmt.Sprintf("g.TriplesForObject(%v, %v)", o, lo)}
+		})
+		return g.TriplesForObject(ctx, o, lo, ts)
+	}
+	tracer.Trace(w, func() []string {
+		return []string{fmt.Sprintf("g.Triples(%v)", lo)}
+	})
+	return g.Triples(ctx, lo, ts)
+}
+
+// joinOperator joins each row pulled from the previous operator with the
+// rows matching a clause.
+type joinOperator struct {
+	p        *queryPlan
+	left     operator
+	bindings []string
+	cls      *semantic.GraphClause
+	lo       *storage.LookupOptions
+	// disjoint is set if the clause does not use any of the bindings of the
+	// rows joined. Its rows are then fetched only once.
+	disjoint bool
+	fetched  *table.Table
+	buf      []table.Row
+}
+
+// newJoinOperator returns an operator joining the rows of the provided
+// operator, which contain the given bindings, with the clause.
+func newJoinOperator(p *queryPlan, left operator, bs []string, cls *semantic.GraphClause, lo *storage.LookupOptions) *joinOperator {
+	o := &joinOperator{
+		p:        p,
+		left:     left,
+		bindings: bs,
+		cls:      cls,
+		lo:       lo,
+		disjoint: len(bs) > 0 && !(cls.Specificity() == 3 && cls.GraphBinding == ""),
+	}
+	bound := make(map[string]bool)
+	for _, b := range bs {
+		bound[b] = true
+	}
+	for _, b := range cls.Bindings() {
+		if bound[b] {
+			o.disjoint = false
+		}
+	}
+	return o
+}
+
+func (o *joinOperator) next(ctx context.Context) (table.Row, error) {
+	for len(o.buf) == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r, err := o.left.next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if o.buf, err = o.join(ctx, r); err != nil {
+			return nil, err
+		}
+	}
+	r := o.buf[0]
+	o.buf = o.buf[1:]
+	return r, nil
+}
+
+// join returns the rows resulting of joining the provided row with the
+// clause. It reuses the same strategies used to join whole tables.
+func (o *joinOperator) join(ctx context.Context, r table.Row) ([]table.Row, error) {
+	tbl, err := table.New(o.bindings)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.bindings) > 0 {
+		tbl.AddRow(r)
+	}
+	rp := &queryPlan{
+		stm:      o.p.stm,
+		store:    o.p.store,
+		grfs:     o.p.grfs,
+		tbl:      tbl,
+		chanSize: o.p.chanSize,
+		tracer:   o.p.tracer,
+		maxRows:  o.p.maxRows,
+	}
+	if o.disjoint {
+		if o.fetched == nil {
+			if o.fetched, err = simpleFetch(ctx, o.p.grfs, o.cls, o.lo, 0, o.p.chanSize, o.p.tracer); err != nil {
+				return nil, err
+			}
+		}
+		if err := rp.joinDisjoint(o.cls, o.fetched); err != nil {
+			return nil, err
+		}
+		return rp.tbl.Rows(), nil
+	}
+	cls := *o.cls
+	unresolvable, err := rp.processClause(ctx, &cls, o.lo)
+	if err != nil {
+		return nil, err
+	}
+	if unresolvable {
+		return nil, nil
+	}
+	return rp.tbl.Rows(), nil
+}
+
+func (o *joinOperator) close() {
+	o.left.close()
+}
+
+// filterOperator only returns the rows accepted by all the filters.
+type filterOperator struct {
+	left operator
+	fs   []*semantic.Filter
+}
+
+func (o *filterOperator) next(ctx context.Context) (table.Row, error) {
+	for {
+		r, err := o.left.next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if o.accept(r) {
+			return r, nil
+		}
+	}
+}
+
+// accept returns true if the row passes all the filters.
+func (o *filterOperator) accept(r table.Row) bool {
+	for _, f := range o.fs {
+		if ok, err := f.Evaluate(r); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *filterOperator) close() {
+	o.left.close()
+}
+
+// projectOperator computes the projections of the query for each row and
+// only keeps the output bindings.
+type projectOperator struct {
+	p    *queryPlan
+	left operator
+}
+
+func (o *projectOperator) next(ctx context.Context) (table.Row, error) {
+	r, err := o.left.next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prjs := o.p.stm.Projections()
+	for _, prj := range prjs {
+		if prj.Expression == nil {
+			r[prj.Alias] = r[prj.Binding]
+		}
+	}
+	for _, prj := range prjs {
+		if prj.Expression != nil {
+			projectExpression(prj, r)
+		}
+	}
+	for _, prj := range prjs {
+		if prj.Scalar == "" {
+			continue
+		}
+		fn, _, ok := semantic.LookupFunction(prj.Scalar)
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q for binding %q", prj.Scalar, prj.Binding)
+		}
+		out := prj.Alias
+		if out == "" {
+			out = prj.Binding
+		}
+		if err := projectScalar(prj, fn, out, r); err != nil {
+			return nil, err
+		}
+	}
+	res := make(table.Row)
+	for _, b := range o.p.stm.OutputBindings() {
+		if c, ok := r[b]; ok {
+			res[b] = c
+		}
+	}
+	return res, nil
+}
+
+func (o *projectOperator) close() {
+	o.left.close()
+}
+
+// limitOperator skips the first offset rows and stops after returning limit
+// rows. A negative limit returns all the rows.
+type limitOperator struct {
+	left   operator
+	offset int64
+	limit  int64
+	n      int64
+}
+
+func (o *limitOperator) next(ctx context.Context) (table.Row, error) {
+	for ; o.offset > 0; o.offset-- {
+		if _, err := o.left.next(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if o.limit >= 0 && o.n >= o.limit {
+		return nil, io.EOF
+	}
+	r, err := o.left.next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	o.n++
+	return r, nil
+}
+
+func (o *limitOperator) close() {
+	o.left.close()
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bql provides a high level entry point to BQL that allows preparing
+// statements once and executing them several times with different parameter
+// values.
+package bql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/planner"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// Prepared contains a parsed BQL statement whose parameters are bound at
+// execution time.
+type Prepared struct {
+	query  string
+	stm    *semantic.Statement
+	params map[string]bool
+}
+
+// Prepare parses the provided query. Parameters are introduced in the query
+// using $name bindings, and they get their value when the prepared statement
+// is executed. Values are never parsed as BQL, hence they cannot alter the
+// structure of the query.
+func Prepare(query string) (*Prepared, error) {
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize a valid BQL parser with error %v", err)
+	}
+	stm := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(query, 1), stm); err != nil {
+		return nil, fmt.Errorf("failed to parse BQL statement with error %v", err)
+	}
+	params := make(map[string]bool)
+	for _, prm := range stm.Parameters() {
+		params[prm] = true
+	}
+	return &Prepared{
+		query:  query,
+		stm:    stm,
+		params: params,
+	}, nil
+}
+
+// Parameters returns the sorted list of parameters of the prepared statement.
+func (p *Prepared) Parameters() []string {
+	return p.stm.Parameters()
+}
+
+// String returns the query used to prepare the statement.
+func (p *Prepared) String() string {
+	return p.query
+}
+
+// Execute runs the prepared statement against the provided store using the
+// provided parameter values. Parameter names may be provided with or without
+// the leading $. All the parameters of the statement need to be provided.
+func (p *Prepared) Execute(ctx context.Context, store storage.Store, params map[string]interface{}, chanSize, bulkSize int, w io.Writer) (*table.Table, error) {
+	r := make(table.Row, len(params))
+	for k, v := range params {
+		if !strings.HasPrefix(k, "$") {
+			k = "$" + k
+		}
+		if !p.params[k] {
+			return nil, fmt.Errorf("unknown parameter %q for statement %q", k, p.query)
+		}
+		c, err := toCell(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for parameter %q; %v", k, err)
+		}
+		r[k] = c
+	}
+	for k := range p.params {
+		if _, ok := r[k]; !ok {
+			return nil, fmt.Errorf("missing value for parameter %q for statement %q", k, p.query)
+		}
+	}
+	pln, err := planner.New(ctx, store, p.stm.BindParameters(r), chanSize, bulkSize, w)
+	if err != nil {
+		return nil, err
+	}
+	return pln.Execute(ctx)
+}
+
+// toCell converts the provided value into a table cell. Strings are always
+// converted into text literals.
+func toCell(v interface{}) (*table.Cell, error) {
+	var (
+		l   *literal.Literal
+		err error
+	)
+	b := literal.DefaultBuilder()
+	switch tv := v.(type) {
+	case *table.Cell:
+		return tv, nil
+	case *node.Node:
+		return &table.Cell{N: tv}, nil
+	case *predicate.Predicate:
+		return &table.Cell{P: tv}, nil
+	case *literal.Literal:
+		return &table.Cell{L: tv}, nil
+	case time.Time:
+		return &table.Cell{T: &tv}, nil
+	case string:
+		l, err = b.Build(literal.Text, tv)
+	case int:
+		l, err = b.Build(literal.Int64, int64(tv))
+	case int64:
+		l, err = b.Build(literal.Int64, tv)
+	case float64:
+		l, err = b.Build(literal.Float64, tv)
+	case *big.Rat:
+		l, err = b.Build(literal.Decimal, tv)
+	case bool:
+		l, err = b.Build(literal.Bool, tv)
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &table.Cell{L: l}, nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bql
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/io"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+)
+
+const testTriples = `/u<joe> "parent_of"@[] /u<mary>
+	/u<joe> "parent_of"@[] /u<peter>
+	/u<peter> "parent_of"@[] /u<john>
+	/u<peter> "parent_of"@[] /u<eve>
+	/u<peter> "name"@[] "Peter"^^type:text
+	/u<mary> "name"@[] "Mary"^^type:text
+	/u<joe> "age"@[] "40"^^type:int64
+	/u<peter> "age"@[] "15"^^type:int64`
+
+func testStore(ctx context.Context, t *testing.T) storage.Store {
+	s := memory.NewStore()
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadIntoGraph(ctx, g, strings.NewReader(testTriples), literal.DefaultBuilder()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func mustNode(t *testing.T, s string) *node.Node {
+	n, err := node.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestPrepareAndExecute(t *testing.T) {
+	ctx := context.Background()
+	s := testStore(ctx, t)
+	testTable := []struct {
+		q      string
+		params map[string]interface{}
+		nrws   int
+	}{
+		{
+			q:      `select ?o from ?test where {$who "parent_of"@[] ?o};`,
+			params: map[string]interface{}{"$who": mustNode(t, "/u<joe>")},
+			nrws:   2,
+		},
+		{
+			q:      `select ?o from ?test where {$who "parent_of"@[] ?o};`,
+			params: map[string]interface{}{"who": mustNode(t, "/u<peter>")},
+			nrws:   2,
+		},
+		{
+			q:      `select ?o from ?test where {$who "parent_of"@[] ?o};`,
+			params: map[string]interface{}{"who": mustNode(t, "/u<mary>")},
+			nrws:   0,
+		},
+		{
+			q:      `select ?s from ?test where {?s "name"@[] $name};`,
+			params: map[string]interface{}{"name": "Peter"},
+			nrws:   1,
+		},
+		{
+			q:      `select ?s from ?test where {?s "name"@[] $name};`,
+			params: map[string]interface{}{"name": `Peter"^^type:text}; delete data from ?test {/u<joe> "age"@[] "40"^^type:int64`},
+			nrws:   0,
+		},
+		{
+			q:      `select ?s, ?a from ?test where {?s "age"@[] ?a} having ?a > $min;`,
+			params: map[string]interface{}{"min": 18},
+			nrws:   1,
+		},
+		{
+			q:      `select ?s, ?a from ?test where {?s "age"@[] ?a} having ?a > $min;`,
+			params: map[string]interface{}{"min": int64(10)},
+			nrws:   2,
+		},
+	}
+	for _, entry := range testTable {
+		p, err := Prepare(entry.q)
+		if err != nil {
+			t.Errorf("Prepare(%q) failed with error %v", entry.q, err)
+			continue
+		}
+		tbl, err := p.Execute(ctx, s, entry.params, 0, 10, nil)
+		if err != nil {
+			t.Errorf("Execute(%v) for %q failed with error %v", entry.params, entry.q, err)
+			continue
+		}
+		if got, want := tbl.NumRows(), entry.nrws; got != want {
+			t.Errorf("Execute(%v) for %q returned the wrong number of rows; got %d, want %d\n%s", entry.params, entry.q, got, want, tbl)
+		}
+	}
+}
+
+func TestPreparedReuse(t *testing.T) {
+	ctx := context.Background()
+	s := testStore(ctx, t)
+	p, err := Prepare(`select ?o from ?test where {$who "parent_of"@[] ?o};`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Parameters(), []string{"$who"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parameters returned the wrong parameters; got %v, want %v", got, want)
+	}
+	for _, who := range []string{"/u<joe>", "/u<peter>", "/u<joe>"} {
+		tbl, err := p.Execute(ctx, s, map[string]interface{}{"who": mustNode(t, who)}, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("Execute for %s failed with error %v", who, err)
+		}
+		if got, want := tbl.NumRows(), 2; got != want {
+			t.Errorf("Execute for %s returned the wrong number of rows; got %d, want %d", who, got, want)
+		}
+	}
+}
+
+func TestPreparedErrors(t *testing.T) {
+	ctx := context.Background()
+	s := testStore(ctx, t)
+	if p, err := Prepare(`select ?o from ?test where {$who "parent_of"@[] ?o}`); err == nil {
+		t.Errorf("Prepare should have failed for an invalid query; got %v", p)
+	}
+	p, err := Prepare(`select ?o from ?test where {$who "parent_of"@[] ?o};`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTable := []map[string]interface{}{
+		{},
+		{"who": mustNode(t, "/u<joe>"), "other": 1},
+		{"who": struct{}{}},
+	}
+	for _, params := range testTable {
+		if _, err := p.Execute(ctx, s, params, 0, 10, nil); err == nil {
+			t.Errorf("Execute(%v) should have failed", params)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/bql/planner"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+)
+
+// SplitStatements returns the statements of the provided script. Statements
+// are separated by semicolons, which are kept at the end of each statement.
+// The text of each statement is rebuilt out of its tokens, so semicolons in
+// literals, predicates, and node IDs do not split statements.
+func SplitStatements(script string) ([]string, error) {
+	var (
+		stms []string
+		txt  []string
+	)
+	for tkn := range lexer.New(script, 0) {
+		switch tkn.Type {
+		case lexer.ItemError:
+			return nil, fmt.Errorf("failed to split script; %s", tkn.ErrorMessage)
+		case lexer.ItemEOF:
+			if len(txt) > 0 {
+				stms = append(stms, strings.Join(txt, " "))
+			}
+			return stms, nil
+		case lexer.ItemSemicolon:
+			stms = append(stms, strings.Join(txt, " ")+";")
+			txt = nil
+		default:
+			txt = append(txt, tkn.Text)
+		}
+	}
+	return stms, nil
+}
+
+// RunScript runs all the statements of the provided script in order against
+// the provided store. Statements between BEGIN and COMMIT are run in a single
+// transaction: if any of them fails, the changes done by the previous ones are
+// rolled back. A ROLLBACK statement explicitly undoes the changes of the
+// current transaction. Statements outside transactions are applied as they
+// run.
+//
+// All the statements are parsed before running any of them. RunScript returns
+// the tables produced by the statements run before stopping at the first
+// failure, if any. Transaction control statements produce nil tables.
+func RunScript(ctx context.Context, store storage.Store, script string, chanSize, bulkSize int, w io.Writer) ([]*table.Table, error) {
+	txts, err := SplitStatements(script)
+	if err != nil {
+		return nil, err
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize a valid BQL parser with error %v", err)
+	}
+	var stms []*semantic.Statement
+	for i, txt := range txts {
+		stm := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(txt, 1), stm); err != nil {
+			return nil, fmt.Errorf("failed to parse statement %d %q with error %v", i+1, txt, err)
+		}
+		stms = append(stms, stm)
+	}
+
+	var (
+		res []*table.Table
+		tx  storage.Transaction
+	)
+	// abort rolls back the open transaction, if any, and returns the provided
+	// error.
+	abort := func(err error) ([]*table.Table, error) {
+		if tx == nil {
+			return res, err
+		}
+		if rerr := tx.Rollback(ctx); rerr != nil {
+			return res, fmt.Errorf("%v; %v", err, rerr)
+		}
+		return res, err
+	}
+	for i, stm := range stms {
+		switch stm.Type() {
+		case semantic.Begin:
+			if tx != nil {
+				return abort(fmt.Errorf("statement %d: nested transactions are not supported", i+1))
+			}
+			if tx, err = storage.Begin(ctx, store); err != nil {
+				return res, fmt.Errorf("statement %d: failed to begin transaction; %v", i+1, err)
+			}
+			res = append(res, nil)
+		case semantic.Commit, semantic.Rollback:
+			if tx == nil {
+				return res, fmt.Errorf("statement %d: %s requires a previous BEGIN", i+1, stm.Type())
+			}
+			if stm.Type() == semantic.Commit {
+				err = tx.Commit(ctx)
+			} else {
+				err = tx.Rollback(ctx)
+			}
+			tx = nil
+			if err != nil {
+				return res, fmt.Errorf("statement %d: %v", i+1, err)
+			}
+			res = append(res, nil)
+		default:
+			s := store
+			if tx != nil {
+				s = tx
+			}
+			pln, err := planner.New(ctx, s, stm, chanSize, bulkSize, w)
+			if err != nil {
+				return abort(fmt.Errorf("statement %d %q: %v", i+1, txts[i], err))
+			}
+			tbl, err := pln.Execute(ctx)
+			if err != nil {
+				return abort(fmt.Errorf("statement %d %q: %v", i+1, txts[i], err))
+			}
+			res = append(res, tbl)
+		}
+	}
+	if tx != nil {
+		return abort(errors.New("missing COMMIT for the open transaction; all its changes were rolled back"))
+	}
+	return res, nil
+}
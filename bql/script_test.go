@@ -0,0 +1,158 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+)
+
+func TestSplitStatements(t *testing.T) {
+	testTable := []struct {
+		script string
+		want   []string
+	}{
+		{
+			script: `begin; create graph ?a;
+			         insert data into ?a {/u<joe> "says"@[] "a;b"^^type:text};
+			         commit;`,
+			want: []string{
+				`begin;`,
+				`create graph ?a;`,
+				`insert data into ?a { /u<joe> "says"@[] "a;b"^^type:text };`,
+				`commit;`,
+			},
+		},
+		{
+			script: `select ?s from ?g where {?s ?p ?o}`,
+			want:   []string{`select ?s from ?g where { ?s ?p ?o }`},
+		},
+		{
+			script: "  \n ",
+			want:   nil,
+		},
+	}
+	for _, entry := range testTable {
+		got, err := SplitStatements(entry.script)
+		if err != nil {
+			t.Errorf("SplitStatements(%q) failed with error %v", entry.script, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("SplitStatements(%q) returned the wrong statements; got %q, want %q", entry.script, got, entry.want)
+		}
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	countTriples := func(ctx context.Context, s storage.Store, id string) int64 {
+		g, err := s.Graph(ctx, id)
+		if err != nil {
+			return -1
+		}
+		cnt, err := storage.CountTriples(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cnt
+	}
+	testTable := []struct {
+		script  string
+		fail    bool
+		results int
+		want    map[string]int64
+	}{
+		{
+			script: `BEGIN;
+			         CREATE GRAPH ?new;
+			         INSERT DATA INTO ?new {/u<joe> "knows"@[] /u<mary>};
+			         DELETE DATA FROM ?test {/u<joe> "parent_of"@[] /u<mary>};
+			         COMMIT;`,
+			results: 5,
+			want:    map[string]int64{"?new": 1, "?test": 7},
+		},
+		{
+			script: `BEGIN;
+			         CREATE GRAPH ?new;
+			         INSERT DATA INTO ?new {/u<joe> "knows"@[] /u<mary>};
+			         DELETE DATA FROM ?test {/u<joe> "parent_of"@[] /u<mary>};
+			         DROP GRAPH ?missing;
+			         COMMIT;`,
+			fail:    true,
+			results: 4,
+			want:    map[string]int64{"?new": -1, "?test": 8},
+		},
+		{
+			script: `begin;
+			         drop graph ?test;
+			         rollback;
+			         select ?s from ?test where {?s "age"@[] ?a};`,
+			results: 4,
+			want:    map[string]int64{"?test": 8},
+		},
+		{
+			script: `create graph ?new;
+			         begin;
+			         insert data into ?new {/u<joe> "knows"@[] /u<mary>};`,
+			fail:    true,
+			results: 3,
+			want:    map[string]int64{"?new": 0},
+		},
+		{
+			script:  `create graph ?new; drop graph ?missing; create graph ?other;`,
+			fail:    true,
+			results: 1,
+			want:    map[string]int64{"?new": 0, "?other": -1},
+		},
+		{
+			script:  `commit;`,
+			fail:    true,
+			results: 0,
+		},
+		{
+			script:  `begin; begin; commit;`,
+			fail:    true,
+			results: 1,
+		},
+		{
+			script:  `create graph ?new; select from;`,
+			fail:    true,
+			results: 0,
+			want:    map[string]int64{"?new": -1},
+		},
+	}
+	for _, entry := range testTable {
+		ctx := context.Background()
+		s := testStore(ctx, t)
+		res, err := RunScript(ctx, s, entry.script, 0, 10, nil)
+		if entry.fail && err == nil {
+			t.Errorf("RunScript(%q) should have failed", entry.script)
+		}
+		if !entry.fail && err != nil {
+			t.Errorf("RunScript(%q) failed with error %v", entry.script, err)
+		}
+		if got, want := len(res), entry.results; got != want {
+			t.Errorf("RunScript(%q) returned the wrong number of results; got %d, want %d", entry.script, got, want)
+		}
+		for id, want := range entry.want {
+			if got := countTriples(ctx, s, id); got != want {
+				t.Errorf("RunScript(%q) left graph %s with %d triples; want %d", entry.script, id, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"fmt"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// valueKind describes the kind of the values held by a binding or computed by
+// an expression. The empty kind is used when the kind is not known until the
+// statement is run.
+type valueKind string
+
+const (
+	kindUnknown   valueKind = ""
+	kindNode      valueKind = "node"
+	kindPredicate valueKind = "predicate"
+	// kindObject is the kind of the objects of the triples, which may be
+	// nodes, predicates, or literals.
+	kindObject valueKind = "object"
+	// kindString is the kind of the IDs, types, and graph names bound by the
+	// graph patterns.
+	kindString valueKind = "string"
+	kindTime   valueKind = "time anchor"
+	kindNumber valueKind = "number"
+)
+
+// literalKind returns the kind of the values of the provided literal type.
+func literalKind(t literal.Type) valueKind {
+	switch t {
+	case literal.Int64, literal.Float64, literal.Decimal:
+		return kindNumber
+	}
+	return valueKind(t.String() + " literal")
+}
+
+// cellKind returns the kind of the value in the provided cell.
+func cellKind(c *table.Cell) valueKind {
+	switch {
+	case c.L != nil:
+		return literalKind(c.L.Type())
+	case c.N != nil:
+		return kindNode
+	case c.P != nil:
+		return kindPredicate
+	case c.T != nil:
+		return kindTime
+	case c.S != nil:
+		return kindString
+	}
+	return kindUnknown
+}
+
+// compatible returns true if values of the provided kinds may be equal.
+func compatible(a, b valueKind) bool {
+	switch {
+	case a == kindUnknown || b == kindUnknown || a == b:
+		return true
+	case a == kindObject:
+		return b != kindString && b != kindTime
+	case b == kindObject:
+		return a != kindString && a != kindTime
+	}
+	return false
+}
+
+// bindingKind pairs a binding with the kind of the values it holds.
+type bindingKind struct {
+	binding string
+	kind    valueKind
+}
+
+// bindingKinds returns the bindings of the clause paired with the kind of the
+// values they hold.
+func (c *GraphClause) bindingKinds() []bindingKind {
+	return []bindingKind{
+		{c.GraphBinding, kindString},
+		{c.SBinding, kindNode},
+		{c.SAlias, kindNode},
+		{c.STypeAlias, kindString},
+		{c.SIDAlias, kindString},
+		{c.PBinding, kindPredicate},
+		{c.PAlias, kindPredicate},
+		{c.PIDAlias, kindString},
+		{c.PAnchorBinding, kindTime},
+		{c.PAnchorAlias, kindTime},
+		{c.PLowerBoundAlias, kindTime},
+		{c.PUpperBoundAlias, kindTime},
+		{c.OBinding, kindObject},
+		{c.OAlias, kindObject},
+		{c.OTypeAlias, kindString},
+		{c.OIDAlias, kindString},
+		{c.OAnchorBinding, kindTime},
+		{c.OAnchorAlias, kindTime},
+		{c.OLowerBoundAlias, kindTime},
+		{c.OUpperBoundAlias, kindTime},
+		{c.ReifiedBinding, kindNode},
+	}
+}
+
+// checkClauses checks that each binding of the provided clauses holds values
+// of compatible kinds, since otherwise the clauses can never match. It records
+// the kind of each binding in the provided map.
+func checkClauses(cls []*GraphClause, kinds map[string]valueKind) []error {
+	var errs []error
+	for _, c := range cls {
+		for _, bk := range c.bindingKinds() {
+			b, k := bk.binding, bk.kind
+			if b == "" {
+				continue
+			}
+			prev, ok := kinds[b]
+			switch {
+			case !ok:
+				kinds[b] = k
+			case !compatible(prev, k):
+				errs = append(errs, fmt.Errorf("binding %s holds both %s and %s values in clause %v, so the graph pattern never matches", b, prev, k, c))
+			case prev == kindObject:
+				// The binding is narrowed to the more specific kind.
+				kinds[b] = k
+			}
+		}
+	}
+	return errs
+}
+
+// Check looks for problems in the statement that do not prevent it from being
+// parsed, but make it fail or never return any result when run, such as
+// bindings holding values of incompatible kinds in the graph pattern, or type
+// errors in filters and expressions. It returns all the problems found, or nil
+// if there are none.
+func (s *Statement) Check() []error {
+	var errs []error
+	kinds := make(map[string]valueKind)
+	if us := s.GraphPatternUnions(); len(us) > 0 {
+		fs := s.GraphPatternUnionFilters()
+		for i, u := range us {
+			// Each alternative binds its own values.
+			uks := make(map[string]valueKind)
+			errs = append(errs, checkClauses(u, uks)...)
+			for _, f := range fs[i] {
+				errs = append(errs, f.check(uks)...)
+			}
+		}
+		// The kinds of the bindings depend on the alternative matching.
+		kinds = nil
+	} else {
+		errs = append(errs, checkClauses(s.pattern, kinds)...)
+		for _, f := range s.filters {
+			errs = append(errs, f.check(kinds)...)
+		}
+	}
+	for _, sq := range s.Subqueries() {
+		for _, err := range sq.Check() {
+			errs = append(errs, fmt.Errorf("subquery: %v", err))
+		}
+	}
+	for _, p := range s.projection {
+		if p.Expression != nil {
+			errs = append(errs, checkExpression(p.Expression.v, p.Expression.String(), kinds)...)
+		}
+	}
+	for _, e := range s.groupByExpressions {
+		errs = append(errs, checkExpression(e.v, e.String(), kinds)...)
+	}
+	for _, e := range s.orderByExpressions {
+		errs = append(errs, checkExpression(e.v, e.String(), kinds)...)
+	}
+	return errs
+}
+
+// check looks for type errors in the filter expression, using the provided
+// kinds of the bindings.
+func (f *Filter) check(kinds map[string]valueKind) []error {
+	c := &checker{expr: f.String(), kinds: kinds}
+	c.evaluator(f.Evaluator)
+	return c.errs
+}
+
+// checkExpression looks for type errors in the provided value expression,
+// using the provided kinds of the bindings.
+func checkExpression(v valueNode, expr string, kinds map[string]valueKind) []error {
+	c := &checker{expr: expr, kinds: kinds}
+	c.value(v)
+	return c.errs
+}
+
+// checker collects the type errors found in an expression.
+type checker struct {
+	expr  string
+	kinds map[string]valueKind
+	errs  []error
+}
+
+// errorf records a type error.
+func (c *checker) errorf(format string, args ...interface{}) {
+	c.errs = append(c.errs, fmt.Errorf("%s in expression %q", fmt.Sprintf(format, args...), c.expr))
+}
+
+// evaluator checks the provided boolean expression.
+func (c *checker) evaluator(e Evaluator) {
+	switch n := e.(type) {
+	case *booleanNode:
+		if n.lE != nil {
+			c.evaluator(n.lE)
+		}
+		if n.rE != nil {
+			c.evaluator(n.rE)
+		}
+	case *comparisonNode:
+		lk, rk := c.value(n.l), c.value(n.r)
+		if !comparableKinds(lk, rk) {
+			c.errorf("cannot compare %s and %s values", lk, rk)
+		}
+	case *truthNode:
+		if k := c.value(n.v); !compatible(k, literalKind(literal.Bool)) {
+			c.errorf("%s values used as a condition are never true", k)
+		}
+	case *regexNode:
+		if k := c.value(n.v); k != kindString && !compatible(k, literalKind(literal.Text)) {
+			c.errorf("REGEX never matches %s values", k)
+		}
+		c.value(n.pattern)
+		if n.flags != nil {
+			c.value(n.flags)
+		}
+	case *nearestNode:
+		c.value(n.v)
+		c.value(n.q)
+	}
+}
+
+// comparableKinds returns true if values of the provided kinds can be
+// compared.
+func comparableKinds(a, b valueKind) bool {
+	if a == kindObject || b == kindObject {
+		return compatible(a, b)
+	}
+	return a == kindUnknown || b == kindUnknown || a == b
+}
+
+// value checks the provided value expression, and returns the kind of the
+// values it computes.
+func (c *checker) value(v valueNode) valueKind {
+	if isConstant(v) {
+		cl, err := v.value(nil)
+		if err != nil {
+			c.errorf("%v", err)
+			return kindUnknown
+		}
+		return cellKind(cl)
+	}
+	switch n := v.(type) {
+	case *bindingNode:
+		return c.kinds[n.b]
+	case *arithmeticNode:
+		for _, o := range []valueNode{n.l, n.r} {
+			if k := c.value(o); !compatible(k, kindNumber) {
+				c.errorf("arithmetic on %s values", k)
+			}
+		}
+		return kindNumber
+	case *negateNode:
+		if k := c.value(n.v); !compatible(k, kindNumber) {
+			c.errorf("cannot negate %s values", k)
+		}
+		return kindNumber
+	case *functionNode:
+		for _, a := range n.args {
+			c.value(a)
+		}
+	case *coalesceNode:
+		for _, a := range n.args {
+			c.value(a)
+		}
+	case *ifNode:
+		c.evaluator(n.cond)
+		c.value(n.then)
+		c.value(n.els)
+	}
+	return kindUnknown
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
 	"github.com/google/badwolf/triple/node"
 	"github.com/google/badwolf/triple/predicate"
@@ -105,5 +106,40 @@ func ToLiteral(ce ConsumedElement) (*literal.Literal, error) {
 	if tkn.Type != lexer.ItemLiteral {
 		return nil, fmt.Errorf("semantic.ToLiteral cannot convert token type %s to a literal", tkn.Type)
 	}
-	return literal.DefaultBuilder().Parse(tkn.Text)
+	return parseLiteral(tkn.Text, literal.DefaultBuilder())
+}
+
+// parseLiteral parses the text of a literal token using the provided builder.
+// Escape sequences in text literals are replaced by the characters they
+// represent.
+func parseLiteral(s string, b literal.Builder) (*literal.Literal, error) {
+	l, err := b.Parse(s)
+	if err != nil || l == nil || l.Type() != literal.Text {
+		return l, err
+	}
+	txt, err := l.Text()
+	if err != nil {
+		return nil, err
+	}
+	utxt, err := lexer.Unescape(txt)
+	if err != nil {
+		return nil, fmt.Errorf("semantic.ToLiteral failed to unescape %s: %v", s, err)
+	}
+	if utxt == txt {
+		return l, nil
+	}
+	return b.Build(literal.Text, utxt)
+}
+
+// toObject converts the node, predicate, or literal token into a triple
+// object.
+func toObject(tkn *lexer.Token, b literal.Builder) (*triple.Object, error) {
+	if tkn.Type != lexer.ItemLiteral {
+		return triple.ParseObject(tkn.Text, b)
+	}
+	l, err := parseLiteral(tkn.Text, b)
+	if err != nil {
+		return nil, err
+	}
+	return triple.NewLiteralObject(l), nil
 }
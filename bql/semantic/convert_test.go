@@ -78,6 +78,11 @@ func TestToLiteral(t *testing.T) {
 	if l, err := ToLiteral(ce); err != nil || l.String() != `"true"^^type:bool` {
 		t.Errorf("semantic.ToLiteral failed to properly convert %+v; err=%v, literal=%v", ce, err, l)
 	}
+	// Unescape text literals.
+	tkn.Text = `"say \"h\u00ed\"\n"^^type:text`
+	if l, err := ToLiteral(ce); err != nil || l.String() != "\"say \"hí\"\n\"^^type:text" {
+		t.Errorf("semantic.ToLiteral failed to properly unescape %+v; err=%v, literal=%v", ce, err, l)
+	}
 	// Reject invalid tokens.
 	tkn.Text = `"incomplete"^^`
 	ice := NewConsumedToken(tkn)
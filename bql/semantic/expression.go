@@ -22,6 +22,7 @@ import (
 
 	"github.com/google/badwolf/bql/lexer"
 	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
 )
 
 // Evaluator interface computes the evaluation of a boolean expression.
@@ -107,9 +108,6 @@ func (e *evaluationNode) Evaluate(r table.Row) (bool, error) {
 	}
 
 	cs := func(c *table.Cell) string {
-		if c.L != nil {
-			return strings.TrimSpace(c.L.ToComparableString())
-		}
 		return strings.TrimSpace(c.String())
 	}
 
@@ -117,6 +115,18 @@ func (e *evaluationNode) Evaluate(r table.Row) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	// Literals are compared using the total order across literals.
+	if eL.L != nil && eR.L != nil {
+		c := literal.Compare(eL.L, eR.L)
+		switch e.op {
+		case EQ:
+			return c == 0, nil
+		case LT:
+			return c < 0, nil
+		case GT:
+			return c > 0, nil
+		}
+	}
 	csEL, csER := cs(eL), cs(eR)
 	switch e.op {
 	case EQ:
@@ -0,0 +1,1042 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// Filter contains a boolean expression used to filter the rows obtained from
+// a graph pattern. Comparisons that cannot be evaluated for a row, for
+// instance because one of the bindings is unbound or the values are of
+// incomparable types, evaluate to false.
+type Filter struct {
+	Evaluator
+	expression string
+	bindings   []string
+	compiler   RegexpCompiler
+	// nearest contains the NEAREST calls of the expression, which need the
+	// rows to be ranked before evaluating them.
+	nearest []*nearestNode
+}
+
+// RegexpCompiler compiles the regular expressions used by the REGEX filter
+// function.
+type RegexpCompiler func(expr string) (*regexp.Regexp, error)
+
+// SetRegexpCompiler sets the compiler used to build the regular expressions
+// required by the filter. It allows callers to cache compiled expressions
+// across rows. By default, regexp.Compile is used.
+func (f *Filter) SetRegexpCompiler(c RegexpCompiler) {
+	f.compiler = c
+}
+
+// compile returns the compiled regular expression for the provided pattern.
+func (f *Filter) compile(expr string) (*regexp.Regexp, error) {
+	if f.compiler == nil {
+		return regexp.Compile(expr)
+	}
+	return f.compiler(expr)
+}
+
+// String returns a readable form of the filter expression.
+func (f *Filter) String() string {
+	return f.expression
+}
+
+// Bindings returns the list of bindings used in the filter expression.
+func (f *Filter) Bindings() []string {
+	return f.bindings
+}
+
+// Constraint is a comparison between a binding and a constant value.
+type Constraint struct {
+	// Binding is the binding being compared.
+	Binding string
+	// Op is the comparison operator. It is one of lexer.ItemEQ, lexer.ItemLT,
+	// lexer.ItemLEQ, lexer.ItemGT, or lexer.ItemGEQ, and the binding is
+	// always its left operand.
+	Op lexer.TokenType
+	// Value is the constant the binding is compared with.
+	Value *table.Cell
+}
+
+// Constraints returns the comparisons between a binding and a constant value
+// that any row accepted by the filter satisfies. Only the comparisons joined
+// by AND at the top level of the expression are considered. They allow
+// discarding values before building the rows, but the filter still needs to
+// be evaluated since it may contain other conditions.
+func (f *Filter) Constraints() []*Constraint {
+	return constraints(f.Evaluator)
+}
+
+// constraints collects the constraints of the provided expression.
+func constraints(e Evaluator) []*Constraint {
+	switch n := e.(type) {
+	case *booleanNode:
+		if n.op == AND {
+			return append(constraints(n.lE), constraints(n.rE)...)
+		}
+	case *comparisonNode:
+		if c := n.constraint(); c != nil {
+			return []*Constraint{c}
+		}
+	}
+	return nil
+}
+
+// mirroredComparisons contains the operator to use when swapping the operands
+// of a comparison.
+var mirroredComparisons = map[lexer.TokenType]lexer.TokenType{
+	lexer.ItemEQ:  lexer.ItemEQ,
+	lexer.ItemLT:  lexer.ItemGT,
+	lexer.ItemLEQ: lexer.ItemGEQ,
+	lexer.ItemGT:  lexer.ItemLT,
+	lexer.ItemGEQ: lexer.ItemLEQ,
+}
+
+// constraint returns the constraint expressed by the comparison, or nil if it
+// does not compare a binding with a constant value.
+func (n *comparisonNode) constraint() *Constraint {
+	op, ok := mirroredComparisons[n.op]
+	if !ok {
+		return nil
+	}
+	b, isBnd := n.l.(*bindingNode)
+	v := n.r
+	if isBnd {
+		op = n.op
+	} else if b, isBnd = n.r.(*bindingNode); isBnd {
+		v = n.l
+	} else {
+		return nil
+	}
+	if !isConstant(v) {
+		return nil
+	}
+	c, err := v.value(nil)
+	if err != nil {
+		return nil
+	}
+	return &Constraint{Binding: b.b, Op: op, Value: c}
+}
+
+// isConstant returns true if the value does not depend on the row.
+func isConstant(v valueNode) bool {
+	switch n := v.(type) {
+	case *constantNode:
+		return true
+	case *negateNode:
+		return isConstant(n.v)
+	case *arithmeticNode:
+		return isConstant(n.l) && isConstant(n.r)
+	case *functionNode:
+		for _, a := range n.args {
+			if !isConstant(a) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// NewFilter builds a filter out of the sequence of tokens that form the
+// expression. It will return a descriptive error if the expression is not
+// valid.
+func NewFilter(ces []ConsumedElement) (*Filter, error) {
+	f := &Filter{}
+	p, err := newFilterParser(ces)
+	if err != nil {
+		return nil, err
+	}
+	p.f = f
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkConsumed(); err != nil {
+		return nil, err
+	}
+	f.Evaluator, f.expression, f.bindings = e, p.String(), p.bindings
+	return f, nil
+}
+
+// Expression contains a value expression that computes a new value out of
+// the values bound in a row.
+type Expression struct {
+	v          valueNode
+	expression string
+	bindings   []string
+}
+
+// NewExpression builds a value expression out of the sequence of tokens that
+// form it. It will return a descriptive error if the expression is not valid.
+func NewExpression(ces []ConsumedElement) (*Expression, error) {
+	p, err := newFilterParser(ces)
+	if err != nil {
+		return nil, err
+	}
+	v, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkConsumed(); err != nil {
+		return nil, err
+	}
+	return &Expression{
+		v:          v,
+		expression: p.String(),
+		bindings:   p.bindings,
+	}, nil
+}
+
+// Value computes the value of the expression for the provided row.
+func (e *Expression) Value(r table.Row) (*table.Cell, error) {
+	return e.v.value(r)
+}
+
+// String returns a readable form of the expression.
+func (e *Expression) String() string {
+	return e.expression
+}
+
+// Bindings returns the list of bindings used in the expression.
+func (e *Expression) Bindings() []string {
+	return e.bindings
+}
+
+// filterParser implements a recursive descent parser for filter expressions.
+// The precedence, from lower to higher, is: or, and, not, comparisons,
+// additive operators, multiplicative operators, and unary minus.
+type filterParser struct {
+	f        *Filter
+	tkns     []*lexer.Token
+	pos      int
+	bindings []string
+}
+
+// newFilterParser returns a parser for the tokens in the provided consumed
+// elements.
+func newFilterParser(ces []ConsumedElement) (*filterParser, error) {
+	p := &filterParser{}
+	seen := make(map[string]bool)
+	for _, ce := range ces {
+		if ce.IsSymbol() {
+			continue
+		}
+		tkn := ce.Token()
+		p.tkns = append(p.tkns, tkn)
+		if tkn.Type == lexer.ItemBinding && !seen[tkn.Text] {
+			seen[tkn.Text] = true
+			p.bindings = append(p.bindings, tkn.Text)
+		}
+	}
+	if len(p.tkns) == 0 {
+		return nil, errors.New("cannot create an expression from an empty list of tokens")
+	}
+	return p, nil
+}
+
+// String returns the text of the tokens being parsed.
+func (p *filterParser) String() string {
+	var txt []string
+	for _, tkn := range p.tkns {
+		txt = append(txt, tkn.Text)
+	}
+	return strings.Join(txt, " ")
+}
+
+// checkConsumed returns an error if not all the tokens were consumed.
+func (p *filterParser) checkConsumed() error {
+	if tkn := p.peek(); tkn != nil {
+		return fmt.Errorf("failed to consume all the expression tokens; left over %v", p.tkns[p.pos:])
+	}
+	return nil
+}
+
+// peek returns the next token without consuming it, or nil if none is left.
+func (p *filterParser) peek() *lexer.Token {
+	if p.pos >= len(p.tkns) {
+		return nil
+	}
+	return p.tkns[p.pos]
+}
+
+// next consumes and returns the next token, or nil if none is left.
+func (p *filterParser) next() *lexer.Token {
+	tkn := p.peek()
+	if tkn != nil {
+		p.pos++
+	}
+	return tkn
+}
+
+// peekIs returns true if the next token is of any of the provided types.
+func (p *filterParser) peekIs(tts ...lexer.TokenType) bool {
+	tkn := p.peek()
+	if tkn == nil {
+		return false
+	}
+	for _, tt := range tts {
+		if tkn.Type == tt {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	comparisonOperators = []lexer.TokenType{lexer.ItemEQ, lexer.ItemNEQ, lexer.ItemLT, lexer.ItemLEQ, lexer.ItemGT, lexer.ItemGEQ}
+	valueOperators      = append([]lexer.TokenType{lexer.ItemPlus, lexer.ItemMinus, lexer.ItemMul, lexer.ItemDiv}, comparisonOperators...)
+)
+
+func (p *filterParser) parseOr() (Evaluator, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekIs(lexer.ItemOr) {
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if l, err = NewBinaryBooleanExpression(OR, l, r); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (p *filterParser) parseAnd() (Evaluator, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekIs(lexer.ItemAnd) {
+		p.next()
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if l, err = NewBinaryBooleanExpression(AND, l, r); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (p *filterParser) parseNot() (Evaluator, error) {
+	if p.peekIs(lexer.ItemNot) {
+		p.next()
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return NewUnaryBooleanExpression(NOT, e)
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (Evaluator, error) {
+	// Parenthesis may group either a boolean expression or a value one. Try
+	// the boolean one first and backtrack if it is used as a value.
+	if p.peekIs(lexer.ItemLPar) {
+		start := p.pos
+		p.next()
+		if e, err := p.parseOr(); err == nil && p.peekIs(lexer.ItemRPar) {
+			p.next()
+			if !p.peekIs(valueOperators...) {
+				return e, nil
+			}
+		}
+		p.pos = start
+	}
+	if tkn := p.peek(); tkn != nil && tkn.Type == lexer.ItemFunction && strings.EqualFold(tkn.Text, "regex") {
+		return p.parseRegex()
+	}
+	if tkn := p.peek(); tkn != nil && tkn.Type == lexer.ItemFunction && strings.EqualFold(tkn.Text, "nearest") {
+		return p.parseNearest()
+	}
+	l, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekIs(comparisonOperators...) {
+		return &truthNode{v: l}, nil
+	}
+	op := p.next().Type
+	r, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return &comparisonNode{op: op, l: boundDistance(l, r), r: boundDistance(r, l)}, nil
+}
+
+// parseRegex parses a call to the REGEX boolean function.
+func (p *filterParser) parseRegex() (Evaluator, error) {
+	fn := p.next()
+	if p.f == nil {
+		return nil, fmt.Errorf("function %q can only be used in filters", fn.Text)
+	}
+	args, err := p.parseArguments(fn)
+	if err != nil {
+		return nil, err
+	}
+	return newRegexNode(p.f, args)
+}
+
+// parseIf parses a conditional expression. Its first argument is a boolean
+// expression, followed by the values to use when it holds and when it does
+// not.
+func (p *filterParser) parseIf(fn *lexer.Token) (valueNode, error) {
+	if !p.peekIs(lexer.ItemLPar) {
+		return nil, fmt.Errorf("missing left parenthesis after function %q", fn.Text)
+	}
+	p.next()
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	var vs []valueNode
+	for p.peekIs(lexer.ItemComma) {
+		p.next()
+		v, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	if !p.peekIs(lexer.ItemRPar) {
+		return nil, fmt.Errorf("missing right parenthesis in function %q; found %v instead", fn.Text, p.peek())
+	}
+	p.next()
+	if len(vs) != 2 {
+		return nil, fmt.Errorf("function %q requires a condition and 2 values; got %d values instead", fn.Text, len(vs))
+	}
+	return &ifNode{cond: cond, then: vs[0], els: vs[1]}, nil
+}
+
+// parseArguments parses the parenthesized list of arguments of a function
+// call.
+func (p *filterParser) parseArguments(fn *lexer.Token) ([]valueNode, error) {
+	if !p.peekIs(lexer.ItemLPar) {
+		return nil, fmt.Errorf("missing left parenthesis after function %q", fn.Text)
+	}
+	p.next()
+	var args []valueNode
+	for {
+		v, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+		if !p.peekIs(lexer.ItemComma) {
+			break
+		}
+		p.next()
+	}
+	if !p.peekIs(lexer.ItemRPar) {
+		return nil, fmt.Errorf("missing right parenthesis in function %q; found %v instead", fn.Text, p.peek())
+	}
+	p.next()
+	return args, nil
+}
+
+func (p *filterParser) parseAdditive() (valueNode, error) {
+	l, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekIs(lexer.ItemPlus, lexer.ItemMinus) {
+		op := p.next().Type
+		r, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		l = &arithmeticNode{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *filterParser) parseMultiplicative() (valueNode, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekIs(lexer.ItemMul, lexer.ItemDiv) {
+		op := p.next().Type
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &arithmeticNode{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *filterParser) parseUnary() (valueNode, error) {
+	if p.peekIs(lexer.ItemMinus) {
+		p.next()
+		v, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateNode{v: v}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (valueNode, error) {
+	tkn := p.next()
+	if tkn == nil {
+		return nil, errors.New("incomplete filter expression; missing operand")
+	}
+	ce := NewConsumedToken(tkn)
+	switch tkn.Type {
+	case lexer.ItemLPar:
+		v, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekIs(lexer.ItemRPar) {
+			return nil, fmt.Errorf("missing right parenthesis in filter expression; found %v instead", p.peek())
+		}
+		p.next()
+		return v, nil
+	case lexer.ItemBinding:
+		return &bindingNode{b: tkn.Text}, nil
+	case lexer.ItemIf:
+		return p.parseIf(tkn)
+	case lexer.ItemFunction, lexer.ItemType, lexer.ItemID:
+		if strings.EqualFold(tkn.Text, "coalesce") {
+			args, err := p.parseArguments(tkn)
+			if err != nil {
+				return nil, err
+			}
+			return &coalesceNode{args: args}, nil
+		}
+		fn, ok := lookupValueFunction(tkn.Text)
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q in expression", tkn.Text)
+		}
+		args, err := p.parseArguments(tkn)
+		if err != nil {
+			return nil, err
+		}
+		if fn.arity >= 0 && len(args) != fn.arity {
+			return nil, fmt.Errorf("function %q requires %d arguments; got %d instead", tkn.Text, fn.arity, len(args))
+		}
+		return &functionNode{name: tkn.Text, fn: fn, args: args}, nil
+	case lexer.ItemNumber:
+		l, err := numberToLiteral(tkn.Text)
+		if err != nil {
+			return nil, err
+		}
+		return &constantNode{c: &table.Cell{L: l}}, nil
+	case lexer.ItemLiteral:
+		l, err := ToLiteral(ce)
+		if err != nil {
+			return nil, err
+		}
+		return &constantNode{c: &table.Cell{L: l}}, nil
+	case lexer.ItemNode, lexer.ItemBlankNode:
+		n, err := ToNode(ce)
+		if err != nil {
+			return nil, err
+		}
+		return &constantNode{c: &table.Cell{N: n}}, nil
+	case lexer.ItemPredicate:
+		pred, err := ToPredicate(ce)
+		if err != nil {
+			return nil, err
+		}
+		return &constantNode{c: &table.Cell{P: pred}}, nil
+	}
+	return nil, fmt.Errorf("unexpected token %v in filter expression", tkn)
+}
+
+// numberToLiteral converts a bare number into an int64 or float64 literal.
+func numberToLiteral(s string) (*literal.Literal, error) {
+	if !strings.Contains(s, ".") {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q in filter expression; %v", s, err)
+		}
+		return literal.DefaultBuilder().Build(literal.Int64, i)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q in filter expression; %v", s, err)
+	}
+	return literal.DefaultBuilder().Build(literal.Float64, f)
+}
+
+// valueNode computes a value out of a table row.
+type valueNode interface {
+	value(r table.Row) (*table.Cell, error)
+}
+
+// bindingNode returns the value bound to a binding.
+type bindingNode struct {
+	b string
+}
+
+func (n *bindingNode) value(r table.Row) (*table.Cell, error) {
+	c, ok := r[n.b]
+	if !ok || isUnbound(c) {
+		return nil, fmt.Errorf("binding %q is unbound", n.b)
+	}
+	return c, nil
+}
+
+// isUnbound returns true if the cell does not contain any value.
+func isUnbound(c *table.Cell) bool {
+	return c == nil || c.S == nil && c.N == nil && c.P == nil && c.L == nil && c.T == nil
+}
+
+// constantNode always returns the same value.
+type constantNode struct {
+	c *table.Cell
+}
+
+func (n *constantNode) value(table.Row) (*table.Cell, error) {
+	return n.c, nil
+}
+
+// numeric returns the numeric value of the cell. The boolean is true if the
+// value is an int64. Decimals are approximated by a float64.
+func numeric(c *table.Cell) (int64, float64, bool, error) {
+	if c.L != nil {
+		switch c.L.Type() {
+		case literal.Int64:
+			i, err := c.L.Int64()
+			return i, float64(i), true, err
+		case literal.Float64:
+			f, err := c.L.Float64()
+			return 0, f, false, err
+		case literal.Decimal:
+			d, err := c.L.Decimal()
+			if err != nil {
+				return 0, 0, false, err
+			}
+			f, _ := d.Float64()
+			return 0, f, false, nil
+		}
+	}
+	return 0, 0, false, fmt.Errorf("%v is not a numeric value", c)
+}
+
+// decimals returns the exact values of the provided cells if at least one of
+// them is a decimal and the other one a decimal or an int64. Arithmetic on
+// such values is exact.
+func decimals(l, r *table.Cell) (*big.Rat, *big.Rat, bool) {
+	if l.L == nil || r.L == nil {
+		return nil, nil, false
+	}
+	if l.L.Type() != literal.Decimal && r.L.Type() != literal.Decimal {
+		return nil, nil, false
+	}
+	if l.L.Type() == literal.Float64 || r.L.Type() == literal.Float64 {
+		return nil, nil, false
+	}
+	lr, lok := l.L.Rat()
+	rr, rok := r.L.Rat()
+	return lr, rr, lok && rok
+}
+
+// arithmeticNode computes the arithmetic operation of two numeric values.
+type arithmeticNode struct {
+	op   lexer.TokenType
+	l, r valueNode
+}
+
+func (n *arithmeticNode) value(r table.Row) (*table.Cell, error) {
+	lc, err := n.l.value(r)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := n.r.value(r)
+	if err != nil {
+		return nil, err
+	}
+	li, lf, lInt, err := numeric(lc)
+	if err != nil {
+		return nil, err
+	}
+	ri, rf, rInt, err := numeric(rc)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == lexer.ItemDiv && rf == 0 {
+		return nil, errors.New("division by zero")
+	}
+	if lr, rr, ok := decimals(lc, rc); ok {
+		v := new(big.Rat)
+		switch n.op {
+		case lexer.ItemPlus:
+			v.Add(lr, rr)
+		case lexer.ItemMinus:
+			v.Sub(lr, rr)
+		case lexer.ItemMul:
+			v.Mul(lr, rr)
+		case lexer.ItemDiv:
+			v.Quo(lr, rr)
+		}
+		// Quotients without an exact decimal value, like 1/3, are computed
+		// as float64 below.
+		if l, err := literal.DefaultBuilder().Build(literal.Decimal, v); err == nil {
+			return &table.Cell{L: l}, nil
+		}
+	}
+	if lInt && rInt && n.op != lexer.ItemDiv {
+		var v int64
+		switch n.op {
+		case lexer.ItemPlus:
+			v = li + ri
+		case lexer.ItemMinus:
+			v = li - ri
+		case lexer.ItemMul:
+			v = li * ri
+		}
+		l, err := literal.DefaultBuilder().Build(literal.Int64, v)
+		return &table.Cell{L: l}, err
+	}
+	var v float64
+	switch n.op {
+	case lexer.ItemPlus:
+		v = lf + rf
+	case lexer.ItemMinus:
+		v = lf - rf
+	case lexer.ItemMul:
+		v = lf * rf
+	case lexer.ItemDiv:
+		v = lf / rf
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Float64, v)
+	return &table.Cell{L: l}, err
+}
+
+// negateNode computes the negation of a numeric value.
+type negateNode struct {
+	v valueNode
+}
+
+func (n *negateNode) value(r table.Row) (*table.Cell, error) {
+	c, err := n.v.value(r)
+	if err != nil {
+		return nil, err
+	}
+	i, f, isInt, err := numeric(c)
+	if err != nil {
+		return nil, err
+	}
+	if isInt {
+		l, err := literal.DefaultBuilder().Build(literal.Int64, -i)
+		return &table.Cell{L: l}, err
+	}
+	if c.L.Type() == literal.Decimal {
+		d, _ := c.L.Decimal()
+		l, err := literal.DefaultBuilder().Build(literal.Decimal, d.Neg(d))
+		return &table.Cell{L: l}, err
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Float64, -f)
+	return &table.Cell{L: l}, err
+}
+
+// valueFunction computes a new value out of the values of its arguments. A
+// negative arity indicates that the function accepts any number of arguments.
+type valueFunction struct {
+	arity int
+	f     Function
+}
+
+// numericFunction returns a single argument function that applies the
+// provided functions to int64 and float64 values respectively.
+func numericFunction(fi func(int64) int64, ff func(float64) float64) valueFunction {
+	return valueFunction{
+		arity: 1,
+		f: func(cs []*table.Cell) (*table.Cell, error) {
+			i, f, isInt, err := numeric(cs[0])
+			if err != nil {
+				return nil, err
+			}
+			if isInt {
+				l, err := literal.DefaultBuilder().Build(literal.Int64, fi(i))
+				return &table.Cell{L: l}, err
+			}
+			l, err := literal.DefaultBuilder().Build(literal.Float64, ff(f))
+			return &table.Cell{L: l}, err
+		},
+	}
+}
+
+// identity returns the provided int64 unchanged.
+func identity(i int64) int64 {
+	return i
+}
+
+// functionNode computes the value of a function call.
+type functionNode struct {
+	name string
+	fn   valueFunction
+	args []valueNode
+}
+
+func (n *functionNode) value(r table.Row) (*table.Cell, error) {
+	var cs []*table.Cell
+	for _, a := range n.args {
+		c, err := a.value(r)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+	}
+	c, err := n.fn.f(cs)
+	if err != nil {
+		return nil, fmt.Errorf("function %q failed; %v", n.name, err)
+	}
+	return c, nil
+}
+
+// coalesceNode returns the value of the first of its arguments that can be
+// computed.
+type coalesceNode struct {
+	args []valueNode
+}
+
+func (n *coalesceNode) value(r table.Row) (*table.Cell, error) {
+	for _, a := range n.args {
+		if c, err := a.value(r); err == nil && !isUnbound(c) {
+			return c, nil
+		}
+	}
+	return nil, errors.New("none of the COALESCE arguments could be computed")
+}
+
+// ifNode returns one of two values depending on the evaluation of a boolean
+// condition.
+type ifNode struct {
+	cond      Evaluator
+	then, els valueNode
+}
+
+func (n *ifNode) value(r table.Row) (*table.Cell, error) {
+	ok, err := n.cond.Evaluate(r)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return n.then.value(r)
+	}
+	return n.els.value(r)
+}
+
+// compareCells returns -1, 0, or 1 if the left cell is smaller, equal, or
+// greater than the right one. It returns an error if the cells are not
+// comparable. Literals are compared with literal.Compare, as done by the
+// literal filters pushed down to the storage.
+func compareCells(l, r *table.Cell) (int, error) {
+	cmp := func(b bool, e bool) int {
+		if e {
+			return 0
+		}
+		if b {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case l.L != nil && r.L != nil:
+		if !literal.Comparable(l.L, r.L) {
+			return 0, fmt.Errorf("cannot compare literals of type %s and %s", l.L.Type(), r.L.Type())
+		}
+		return literal.Compare(l.L, r.L), nil
+	case l.T != nil && r.T != nil:
+		return cmp(l.T.Before(*r.T), l.T.Equal(*r.T)), nil
+	case l.N != nil && r.N != nil:
+		return strings.Compare(l.N.String(), r.N.String()), nil
+	case l.P != nil && r.P != nil:
+		return strings.Compare(l.P.String(), r.P.String()), nil
+	case l.S != nil && r.S != nil:
+		return strings.Compare(*l.S, *r.S), nil
+	}
+	return 0, fmt.Errorf("cannot compare %v and %v", l, r)
+}
+
+// comparisonNode compares two values.
+type comparisonNode struct {
+	op   lexer.TokenType
+	l, r valueNode
+}
+
+// Evaluate the comparison for the provided row.
+func (n *comparisonNode) Evaluate(r table.Row) (bool, error) {
+	lc, err := n.l.value(r)
+	if err != nil {
+		return false, nil
+	}
+	rc, err := n.r.value(r)
+	if err != nil {
+		return false, nil
+	}
+	c, err := compareCells(lc, rc)
+	if err != nil {
+		// Values of different types are never equal.
+		return n.op == lexer.ItemNEQ, nil
+	}
+	switch n.op {
+	case lexer.ItemEQ:
+		return c == 0, nil
+	case lexer.ItemNEQ:
+		return c != 0, nil
+	case lexer.ItemLT:
+		return c < 0, nil
+	case lexer.ItemLEQ:
+		return c <= 0, nil
+	case lexer.ItemGT:
+		return c > 0, nil
+	case lexer.ItemGEQ:
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("unknown comparison operator %s", n.op)
+}
+
+// truthNode evaluates a single value as a boolean. Only true bool literals
+// evaluate to true.
+type truthNode struct {
+	v valueNode
+}
+
+// Evaluate the truth value for the provided row.
+func (n *truthNode) Evaluate(r table.Row) (bool, error) {
+	c, err := n.v.value(r)
+	if err != nil || c.L == nil || c.L.Type() != literal.Bool {
+		return false, nil
+	}
+	return c.L.Bool()
+}
+
+// regexFlags contains the flags supported by the REGEX filter function.
+const regexFlags = "imsU"
+
+// regexNode checks if a text value matches a regular expression.
+type regexNode struct {
+	f                 *Filter
+	v, pattern, flags valueNode
+}
+
+// newRegexNode returns a regex node for the provided function arguments. If
+// the pattern and flags are constant, they are validated upfront.
+func newRegexNode(f *Filter, args []valueNode) (*regexNode, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("REGEX requires a value, a pattern, and optional flags; got %d arguments instead", len(args))
+	}
+	n := &regexNode{f: f, v: args[0], pattern: args[1]}
+	if len(args) == 3 {
+		n.flags = args[2]
+	}
+	if _, ok := n.pattern.(*constantNode); !ok {
+		return n, nil
+	}
+	if _, ok := n.flags.(*constantNode); n.flags != nil && !ok {
+		return n, nil
+	}
+	expr, err := n.expression(nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := regexp.Compile(expr); err != nil {
+		return nil, fmt.Errorf("invalid REGEX pattern %q; %v", expr, err)
+	}
+	return n, nil
+}
+
+// text returns the string contained in the cell, if any.
+func text(c *table.Cell) (string, bool) {
+	if c.L != nil && c.L.Type() == literal.Text {
+		s, err := c.L.Text()
+		return s, err == nil
+	}
+	if c.S != nil {
+		return *c.S, true
+	}
+	return "", false
+}
+
+// expression returns the regular expression to use for the provided row,
+// including the requested flags.
+func (n *regexNode) expression(r table.Row) (string, error) {
+	pc, err := n.pattern.value(r)
+	if err != nil {
+		return "", err
+	}
+	expr, ok := text(pc)
+	if !ok {
+		return "", fmt.Errorf("REGEX pattern should be a text literal; got %v instead", pc)
+	}
+	if n.flags == nil {
+		return expr, nil
+	}
+	fc, err := n.flags.value(r)
+	if err != nil {
+		return "", err
+	}
+	flags, ok := text(fc)
+	if !ok {
+		return "", fmt.Errorf("REGEX flags should be a text literal; got %v instead", fc)
+	}
+	if flags == "" {
+		return expr, nil
+	}
+	for _, c := range flags {
+		if !strings.ContainsRune(regexFlags, c) {
+			return "", fmt.Errorf("invalid REGEX flag %q; supported flags are %q", c, regexFlags)
+		}
+	}
+	return "(?" + flags + ")" + expr, nil
+}
+
+// Evaluate checks if the value matches the regular expression for the
+// provided row. Values that are not text always evaluate to false.
+func (n *regexNode) Evaluate(r table.Row) (bool, error) {
+	c, err := n.v.value(r)
+	if err != nil {
+		return false, nil
+	}
+	s, ok := text(c)
+	if !ok {
+		return false, nil
+	}
+	expr, err := n.expression(r)
+	if err != nil {
+		return false, nil
+	}
+	re, err := n.f.compile(expr)
+	if err != nil {
+		return false, nil
+	}
+	return re.MatchString(s), nil
+}
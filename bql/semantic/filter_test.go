@@ -0,0 +1,386 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// filterTokens returns the consumed elements for the provided expression.
+func filterTokens(t *testing.T, expr string) []ConsumedElement {
+	var ces []ConsumedElement
+	for tkn := range lexer.New(expr, 0) {
+		tkn := tkn
+		if tkn.Type == lexer.ItemError {
+			t.Fatalf("lexer.New(%q) failed with error %v", expr, tkn.ErrorMessage)
+		}
+		if tkn.Type == lexer.ItemEOF {
+			break
+		}
+		ces = append(ces, NewConsumedToken(&tkn))
+	}
+	return ces
+}
+
+func TestNewFilter(t *testing.T) {
+	intCell := func(i int64) *table.Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Int64, i)
+		return &table.Cell{L: l}
+	}
+	floatCell := func(f float64) *table.Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Float64, f)
+		return &table.Cell{L: l}
+	}
+	textCell := func(s string) *table.Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Text, s)
+		return &table.Cell{L: l}
+	}
+	boolCell := func(b bool) *table.Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Bool, b)
+		return &table.Cell{L: l}
+	}
+	decimalCell := func(a, b int64) *table.Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Decimal, big.NewRat(a, b))
+		return &table.Cell{L: l}
+	}
+	dateCell := func(s string) *table.Cell {
+		l, _ := literal.DefaultBuilder().Parse(`"` + s + `"^^type:date`)
+		return &table.Cell{L: l}
+	}
+	n, err := node.Parse("/u<joe>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t1 := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	tp, err := predicate.NewTemporal("knows", t1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip, err := predicate.NewImmutable("is")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := table.Row{
+		"?a":     intCell(15),
+		"?b":     textCell("foo"),
+		"?c":     textCell("bar"),
+		"?f":     floatCell(2.5),
+		"?big":   intCell(1<<53 + 1),
+		"?nan":   floatCell(math.NaN()),
+		"?d":     decimalCell(1999, 100),
+		"?e":     decimalCell(1, 100),
+		"?born":  dateCell("1969-07-20"),
+		"?hired": dateCell("2016-04-10"),
+		"?n":     &table.Cell{N: n},
+		"?t1":    &table.Cell{T: &t1},
+		"?t2":    &table.Cell{T: &t2},
+		"?true":  boolCell(true),
+		"?false": boolCell(false),
+		"?empty": &table.Cell{},
+		"?tp":    &table.Cell{P: tp},
+		"?ip":    &table.Cell{P: ip},
+	}
+	testTable := []struct {
+		expr string
+		want bool
+	}{
+		{`(?a > 10)`, true},
+		{`(?a > 10 && ?b != ?c)`, true},
+		{`(?a > 10 and ?b = ?c)`, false},
+		{`(?a < 10 || ?b > ?c)`, true},
+		{`(?a <= 15 && ?a >= 15)`, true},
+		{`(!(?a = 15))`, false},
+		{`(not ?a = 16)`, true},
+		{`(?a + 5 = 20)`, true},
+		{`(?a - 5 * 2 = 5)`, true},
+		{`((?a - 5) * 2 = 20)`, true},
+		{`(?a / 2 = 7.5)`, true},
+		{`(-?a < 0)`, true},
+		{`(?f * 2 = 5)`, true},
+		{`(?f < ?a)`, true},
+		{`(?big <= 9007199254740992.0)`, false},
+		{`(?big > 9007199254740992.0)`, true},
+		{`(?nan > 1.0)`, false},
+		{`(?nan < 1.0)`, true},
+		{`(?nan = ?nan)`, true},
+		{`(?d + ?e = "20"^^type:decimal)`, true},
+		{`(?d * 100 = 1999)`, true},
+		{`(?d > ?a && ?d < 20.5)`, true},
+		{`("0.1"^^type:decimal + "0.2"^^type:decimal = "0.3"^^type:decimal)`, true},
+		{`(?e / 3 > 0)`, true},
+		{`(?d = "19.990"^^type:decimal)`, true},
+		{`(?d = ?b)`, false},
+		{`(str(?d) = "19.99"^^type:text)`, true},
+		{`(type(?d) = "decimal"^^type:text)`, true},
+		{`(?born < ?hired)`, true},
+		{`(?hired = "2016-04-10"^^type:date)`, true},
+		{`(?hired > "2016-04-09"^^type:date && ?hired <= "2016-04-10"^^type:date)`, true},
+		{`(str(?born) = "1969-07-20"^^type:text)`, true},
+		{`(?born < ?t1)`, false},
+		{`(?born = "1969-07-20"^^type:text)`, false},
+		{`(?a = "15"^^type:int64)`, true},
+		{`(?b = "foo"^^type:text)`, true},
+		{`(?n = /u<joe>)`, true},
+		{`(?n != /u<mary>)`, true},
+		{`(?t1 < ?t2)`, true},
+		{`(?true)`, true},
+		{`(?false)`, false},
+		{`(?true && !?false)`, true},
+		{`(?a)`, false},
+		{`(?a = ?b)`, false},
+		{`(?a != ?b)`, true},
+		{`(?a < ?b)`, false},
+		{`(?missing = 1)`, false},
+		{`(?empty = 1)`, false},
+		{`(?empty != 1)`, false},
+		{`(?a / 0 = 1)`, false},
+		{`((?a > 10) && (?b = ?c || ?a = 15))`, true},
+		{`(regex(?b, "^f"^^type:text))`, true},
+		{`(REGEX(?b, "^F"^^type:text))`, false},
+		{`(regex(?b, "^F"^^type:text, "i"^^type:text))`, true},
+		{`(regex(?b, "o+"^^type:text) && !regex(?c, "o"^^type:text))`, true},
+		{`(regex(?b, ?c))`, false},
+		{`(regex(?a, "1"^^type:text))`, false},
+		{`(regex(?missing, ".*"^^type:text))`, false},
+		{`(id(?n) = "joe"^^type:text)`, true},
+		{`(type(?n) = "/u"^^type:text)`, true},
+		{`(str(?n) = "/u<joe>"^^type:text)`, true},
+		{`(STR(?a) = "15"^^type:text)`, true},
+		{`(str(?b) = ?b)`, true},
+		{`(type(?a) = "int64"^^type:text)`, true},
+		{`(type(?b) = type(?c))`, true},
+		{`(id(?tp) = "knows"^^type:text)`, true},
+		{`(type(?tp) = "TEMPORAL"^^type:text)`, true},
+		{`(type(?ip) = "IMMUTABLE"^^type:text)`, true},
+		{`(time(?tp) = ?t1)`, true},
+		{`(time(?tp) < ?t2)`, true},
+		{`(time(?tp) = time("2016-01-01T00:00:00Z"^^type:text))`, true},
+		{`(time(?ip) = ?t1)`, false},
+		{`(id(?a) = "15"^^type:text)`, false},
+		{`(regex(str(?n), "joe"^^type:text))`, true},
+		{`(coalesce(?missing, ?empty, ?a) = 15)`, true},
+		{`(COALESCE(?b, ?a) = ?b)`, true},
+		{`(coalesce(?missing, "none"^^type:text) = "none"^^type:text)`, true},
+		{`(coalesce(?missing, ?empty) = 1)`, false},
+		{`(if(?a > 10, ?b, ?c) = "foo"^^type:text)`, true},
+		{`(IF(?a > 20, ?b, ?c) = ?c)`, true},
+		{`(if(?true && !?false, 1, 2) = 1)`, true},
+		{`(if(?missing = 1, 1, 2) = 2)`, true},
+		{`(if(coalesce(?missing, ?false), 1, 2) = 2)`, true},
+		{`(if(?a > 10, ?missing, 1) = 1)`, false},
+	}
+	for _, entry := range testTable {
+		f, err := NewFilter(filterTokens(t, entry.expr))
+		if err != nil {
+			t.Errorf("NewFilter(%q) failed with error %v", entry.expr, err)
+			continue
+		}
+		got, err := f.Evaluate(r)
+		if err != nil {
+			t.Errorf("filter %q failed to evaluate with error %v", entry.expr, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("filter %q evaluated to %v; want %v", entry.expr, got, entry.want)
+		}
+	}
+}
+
+func TestNewFilterErrors(t *testing.T) {
+	testTable := []string{
+		``,
+		`()`,
+		`(?a >)`,
+		`(?a > 10`,
+		`(?a > 10))`,
+		`(?a 10)`,
+		`(&& ?a)`,
+		`(?a + )`,
+		`(unknown(?a))`,
+		`(regex(?a))`,
+		`(regex(?a, "("^^type:text))`,
+		`(regex(?a, "a"^^type:text, "x"^^type:text))`,
+		`(regex(?a, "a"^^type:text, "i"^^type:text, ?b))`,
+		`(regex(?a, "a"^^type:text)`,
+	}
+	for _, expr := range testTable {
+		if f, err := NewFilter(filterTokens(t, expr)); err == nil {
+			t.Errorf("NewFilter(%q) should have failed; instead returned %v", expr, f)
+		}
+	}
+}
+
+func TestFilterBindings(t *testing.T) {
+	f, err := NewFilter(filterTokens(t, `(?a > 10 && ?b != ?a)`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.Bindings(), []string{"?a", "?b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("filter.Bindings returned the wrong bindings; got %v, want %v", got, want)
+	}
+	if got, want := f.String(), "( ?a > 10 && ?b != ?a )"; got != want {
+		t.Errorf("filter.String returned the wrong expression; got %q, want %q", got, want)
+	}
+}
+
+func TestFilterConstraints(t *testing.T) {
+	type constraint struct {
+		b     string
+		op    lexer.TokenType
+		value string
+	}
+	testTable := []struct {
+		expr string
+		want []constraint
+	}{
+		{`(?a > 10 && ?b != ?a)`, []constraint{{"?a", lexer.ItemGT, `"10"^^type:int64`}}},
+		{`(10 >= ?a)`, []constraint{{"?a", lexer.ItemLEQ, `"10"^^type:int64`}}},
+		{`(?a < -2 + 1 and ?b = "x"^^type:text)`, []constraint{{"?a", lexer.ItemLT, `"-1"^^type:int64`}, {"?b", lexer.ItemEQ, `"x"^^type:text`}}},
+		{`(?a = abs(-3) && (?b <= 2.5 && ?c))`, []constraint{{"?a", lexer.ItemEQ, `"3"^^type:int64`}, {"?b", lexer.ItemLEQ, `"2.5"^^type:float64`}}},
+		{`(?a = 1 || ?b = 2)`, nil},
+		{`(!(?a < 3))`, nil},
+		{`(?a < ?b + 1)`, nil},
+		{`(?a != 3)`, nil},
+	}
+	for _, entry := range testTable {
+		f, err := NewFilter(filterTokens(t, entry.expr))
+		if err != nil {
+			t.Fatalf("NewFilter(%q) failed with error %v", entry.expr, err)
+		}
+		var got []constraint
+		for _, c := range f.Constraints() {
+			got = append(got, constraint{c.Binding, c.Op, c.Value.L.String()})
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("filter.Constraints for %q returned %v; want %v", entry.expr, got, entry.want)
+		}
+	}
+}
+
+func TestFilterRegexpCompiler(t *testing.T) {
+	f, err := NewFilter(filterTokens(t, `(regex(?a, "^f"^^type:text, "i"^^type:text))`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exprs []string
+	f.SetRegexpCompiler(func(expr string) (*regexp.Regexp, error) {
+		exprs = append(exprs, expr)
+		return regexp.Compile(expr)
+	})
+	l, err := literal.DefaultBuilder().Build(literal.Text, "Foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.Evaluate(table.Row{"?a": &table.Cell{L: l}})
+	if err != nil || !got {
+		t.Errorf("filter %v should have matched; got %v, %v", f, got, err)
+	}
+	if want := []string{"(?i)^f"}; !reflect.DeepEqual(exprs, want) {
+		t.Errorf("filter %v used the wrong regular expressions; got %v, want %v", f, exprs, want)
+	}
+}
+
+func TestNewExpression(t *testing.T) {
+	intCell := func(i int64) *table.Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Int64, i)
+		return &table.Cell{L: l}
+	}
+	floatCell := func(f float64) *table.Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Float64, f)
+		return &table.Cell{L: l}
+	}
+	decimalCell := func(a, b int64) *table.Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Decimal, big.NewRat(a, b))
+		return &table.Cell{L: l}
+	}
+	r := table.Row{
+		"?a": intCell(-15),
+		"?f": floatCell(2.5),
+		"?d": decimalCell(1999, 100),
+	}
+	testTable := []struct {
+		expr string
+		want *table.Cell
+	}{
+		{`?a`, intCell(-15)},
+		{`abs(?a)`, intCell(15)},
+		{`ABS(?a + 5)`, intCell(10)},
+		{`abs(?f - 5)`, floatCell(2.5)},
+		{`ceil(?f)`, floatCell(3)},
+		{`floor(?f)`, floatCell(2)},
+		{`round(?f * 3)`, floatCell(8)},
+		{`ceil(?a)`, intCell(-15)},
+		{`(?a + 1) * -2`, intCell(28)},
+		{`?d - "0.01"^^type:decimal`, decimalCell(1998, 100)},
+		{`?d * ?a`, decimalCell(-29985, 100)},
+		{`-?d`, decimalCell(-1999, 100)},
+		{`?d / 4`, decimalCell(19990, 4000)},
+		{`?f * "0.5"^^type:decimal`, floatCell(1.25)},
+	}
+	for _, entry := range testTable {
+		e, err := NewExpression(filterTokens(t, entry.expr))
+		if err != nil {
+			t.Errorf("NewExpression(%q) failed with error %v", entry.expr, err)
+			continue
+		}
+		got, err := e.Value(r)
+		if err != nil {
+			t.Errorf("expression %q failed to evaluate with error %v", entry.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("expression %q evaluated to %v; want %v", entry.expr, got, entry.want)
+		}
+	}
+	if _, err := NewExpression(filterTokens(t, `abs(?missing)`)); err != nil {
+		t.Fatalf("NewExpression should accept unknown bindings; got error %v", err)
+	}
+}
+
+func TestNewExpressionErrors(t *testing.T) {
+	testTable := []string{
+		``,
+		`?a >`,
+		`?a > 1`,
+		`abs(?a, ?b)`,
+		`abs()`,
+		`unknown(?a)`,
+		`id(?a, ?b)`,
+		`type()`,
+		`coalesce()`,
+		`if(?a > 1, 2)`,
+		`if(?a > 1, 2, 3, 4)`,
+		`if(?a)`,
+		`regex(?a, "a"^^type:text)`,
+		`abs(?a`,
+	}
+	for _, expr := range testTable {
+		if e, err := NewExpression(filterTokens(t, expr)); err == nil {
+			t.Errorf("NewExpression(%q) should have failed; instead returned %v", expr, e)
+		}
+	}
+}
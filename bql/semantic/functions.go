@@ -0,0 +1,273 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// Function computes a new cell out of the cells provided as arguments. Scalar
+// functions can be used in FILTER, HAVING, and ORDER BY expressions and in
+// the projections of SELECT statements.
+type Function func(args []*table.Cell) (*table.Cell, error)
+
+var (
+	// fnMu protects the registered functions.
+	fnMu sync.RWMutex
+	// valueFunctions contains the functions available in expressions indexed
+	// by their lower cased name.
+	valueFunctions = map[string]valueFunction{
+		"abs": numericFunction(func(i int64) int64 {
+			if i < 0 {
+				return -i
+			}
+			return i
+		}, math.Abs),
+		"ceil":  numericFunction(identity, math.Ceil),
+		"floor": numericFunction(identity, math.Floor),
+		"round": numericFunction(identity, math.Round),
+		"str":   {arity: 1, f: strFunction},
+		"type":  {arity: 1, f: typeFunction},
+		"time":  {arity: 1, f: timeFunction},
+		"end":   {arity: 1, f: endFunction},
+		"id":    {arity: 1, f: idFunction},
+		"year": truncateFunction(func(t time.Time) time.Time {
+			return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		}),
+		"month": truncateFunction(func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}),
+		"day": truncateFunction(func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		}),
+		"hour": truncateFunction(func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.UTC)
+		}),
+		"minute": truncateFunction(func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+		}),
+		"cosine":      {arity: 2, f: cosineFunction},
+		"levenshtein": {arity: 2, f: levenshteinFunction(-1)},
+		"soundex":     {arity: 1, f: soundexFunction},
+	}
+)
+
+// RegisterFunction makes the provided scalar function available to BQL
+// expressions under the given name. Names are case insensitive. The arity
+// is the number of arguments the function requires; a negative arity
+// indicates that the function accepts any number of arguments. Registering
+// an empty name, a nil function, an already registered name, or the name of
+// a built-in form like coalesce, if, regex, or nearest will fail.
+func RegisterFunction(name string, arity int, f Function) error {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if n == "" {
+		return errors.New("semantic.RegisterFunction requires a non empty name")
+	}
+	if f == nil {
+		return fmt.Errorf("semantic.RegisterFunction requires a non nil function for %q", name)
+	}
+	fnMu.Lock()
+	defer fnMu.Unlock()
+	if _, ok := valueFunctions[n]; ok || n == "coalesce" || n == "regex" || n == "nearest" || n == "if" {
+		return fmt.Errorf("semantic.RegisterFunction: function %q is already registered", name)
+	}
+	valueFunctions[n] = valueFunction{arity: arity, f: f}
+	return nil
+}
+
+// UnregisterFunction removes the function registered under the provided
+// name. Unregistering an unknown name is a no-op.
+func UnregisterFunction(name string) {
+	fnMu.Lock()
+	defer fnMu.Unlock()
+	delete(valueFunctions, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// LookupFunction returns the function registered under the provided name and
+// its arity. The boolean will be false if no function was registered with
+// such name.
+func LookupFunction(name string) (Function, int, bool) {
+	fn, ok := lookupValueFunction(name)
+	return fn.f, fn.arity, ok
+}
+
+// lookupValueFunction returns the function registered under the provided
+// name.
+func lookupValueFunction(name string) (valueFunction, bool) {
+	fnMu.RLock()
+	defer fnMu.RUnlock()
+	fn, ok := valueFunctions[strings.ToLower(strings.TrimSpace(name))]
+	return fn, ok
+}
+
+// textCell returns a cell containing the provided string as a text literal.
+func textCell(s string) (*table.Cell, error) {
+	l, err := literal.DefaultBuilder().Build(literal.Text, s)
+	if err != nil {
+		return nil, err
+	}
+	return &table.Cell{L: l}, nil
+}
+
+// strFunction returns the string form of the provided value as a text
+// literal. Literals are converted using their value, not their BQL form.
+func strFunction(cs []*table.Cell) (*table.Cell, error) {
+	c := cs[0]
+	switch {
+	case c.L != nil && c.L.Type() == literal.Text:
+		return c, nil
+	case c.L != nil && c.L.Type() == literal.Blob:
+		b, err := c.L.Blob()
+		if err != nil {
+			return nil, err
+		}
+		return textCell(string(b))
+	case c.L != nil && (c.L.Type() == literal.Decimal || c.L.Type() == literal.Date):
+		// The BQL form of decimals and dates has the digits of their value,
+		// unlike the fraction and the time held by the literal.
+		s := c.L.String()
+		return textCell(s[1:strings.LastIndex(s, `"^^type:`)])
+	case c.L != nil:
+		return textCell(fmt.Sprint(c.L.Interface()))
+	case c.N != nil:
+		return textCell(c.N.String())
+	case c.P != nil:
+		return textCell(c.P.String())
+	case c.T != nil:
+		return textCell(c.T.Format(time.RFC3339Nano))
+	case c.S != nil:
+		return textCell(*c.S)
+	}
+	return nil, fmt.Errorf("cannot convert %v to a string", c)
+}
+
+// typeFunction returns the type of the provided node, predicate, or literal.
+// Predicates are either immutable or temporal.
+func typeFunction(cs []*table.Cell) (*table.Cell, error) {
+	c := cs[0]
+	switch {
+	case c.N != nil:
+		return textCell(c.N.Type().String())
+	case c.P != nil:
+		return textCell(c.P.Type().String())
+	case c.L != nil:
+		return textCell(c.L.Type().String())
+	}
+	return nil, fmt.Errorf("only nodes, predicates, and literals have a type; got %v instead", c)
+}
+
+// timeFunction returns the time anchor of the provided temporal predicate.
+// Time values and text literals in RFC3339 format are also accepted.
+func timeFunction(cs []*table.Cell) (*table.Cell, error) {
+	c := cs[0]
+	if c.T != nil {
+		return c, nil
+	}
+	if c.P != nil {
+		ta, err := c.P.TimeAnchor()
+		if err != nil {
+			return nil, err
+		}
+		t := *ta
+		return &table.Cell{T: &t}, nil
+	}
+	if s, ok := text(c); ok {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		return &table.Cell{T: &t}, nil
+	}
+	return nil, fmt.Errorf("only temporal predicates have a time anchor; got %v instead", c)
+}
+
+// endFunction returns the exclusive end of the interval of the provided
+// interval predicate. Other temporal predicates, and the values accepted by
+// timeFunction, end at their time anchor.
+func endFunction(cs []*table.Cell) (*table.Cell, error) {
+	if c := cs[0]; c.P != nil {
+		if _, end, err := c.P.Interval(); err == nil {
+			t := *end
+			return &table.Cell{T: &t}, nil
+		}
+	}
+	return timeFunction(cs)
+}
+
+// truncateFunction returns a single argument function that truncates the
+// time anchor of its argument, as returned by timeFunction, using the
+// provided function. Times are truncated in UTC.
+func truncateFunction(tf func(time.Time) time.Time) valueFunction {
+	return valueFunction{
+		arity: 1,
+		f: func(cs []*table.Cell) (*table.Cell, error) {
+			c, err := timeFunction(cs)
+			if err != nil {
+				return nil, err
+			}
+			t := tf(c.T.UTC())
+			return &table.Cell{T: &t}, nil
+		},
+	}
+}
+
+// idFunction returns the ID of the provided node or predicate.
+func idFunction(cs []*table.Cell) (*table.Cell, error) {
+	c := cs[0]
+	switch {
+	case c.N != nil:
+		return textCell(c.N.ID().String())
+	case c.P != nil:
+		id := c.P.ID()
+		return textCell(string(id))
+	}
+	return nil, fmt.Errorf("only nodes and predicates have an ID; got %v instead", c)
+}
+
+// vector returns the vector contained in the cell, if any.
+func vector(c *table.Cell) ([]float64, bool) {
+	if c.L == nil || c.L.Type() != literal.VectorType {
+		return nil, false
+	}
+	v, err := c.L.Vector()
+	return v, err == nil
+}
+
+// cosineFunction returns the cosine similarity of the two provided vectors as
+// a float64 literal.
+func cosineFunction(cs []*table.Cell) (*table.Cell, error) {
+	a, aok := vector(cs[0])
+	b, bok := vector(cs[1])
+	if !aok || !bok {
+		return nil, fmt.Errorf("cosine requires two vector literals; got %v and %v instead", cs[0], cs[1])
+	}
+	sim, err := literal.CosineSimilarity(a, b)
+	if err != nil {
+		return nil, err
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Float64, sim)
+	if err != nil {
+		return nil, err
+	}
+	return &table.Cell{L: l}, nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+func TestBuiltinFunctions(t *testing.T) {
+	txt := func(s string) *table.Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Text, s)
+		return &table.Cell{L: l}
+	}
+	n, err := node.Parse("/u<joe>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	tp, err := predicate.NewTemporal("knows", ta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip, err := predicate.NewImmutable("is")
+	if err != nil {
+		t.Fatal(err)
+	}
+	te := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	vp, err := predicate.NewInterval("employed", ta, te)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := time.Date(2016, 3, 9, 18, 30, 15, 5, time.UTC)
+	minute := time.Date(2016, 3, 9, 18, 30, 0, 0, time.UTC)
+	hour := time.Date(2016, 3, 9, 18, 0, 0, 0, time.UTC)
+	day := time.Date(2016, 3, 9, 0, 0, 0, 0, time.UTC)
+	month := time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+	l, err := literal.DefaultBuilder().Build(literal.Bool, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTable := []struct {
+		fn   string
+		arg  *table.Cell
+		want *table.Cell
+	}{
+		{"str", &table.Cell{N: n}, txt("/u<joe>")},
+		{"str", &table.Cell{P: tp}, txt(`"knows"@[2016-01-01T00:00:00Z]`)},
+		{"str", &table.Cell{L: l}, txt("true")},
+		{"str", &table.Cell{T: &ta}, txt("2016-01-01T00:00:00Z")},
+		{"str", &table.Cell{S: table.CellString("foo")}, txt("foo")},
+		{"type", &table.Cell{N: n}, txt("/u")},
+		{"type", &table.Cell{P: ip}, txt("IMMUTABLE")},
+		{"type", &table.Cell{L: l}, txt("bool")},
+		{"time", &table.Cell{P: tp}, &table.Cell{T: &ta}},
+		{"time", &table.Cell{T: &ta}, &table.Cell{T: &ta}},
+		{"time", &table.Cell{P: vp}, &table.Cell{T: &ta}},
+		{"end", &table.Cell{P: vp}, &table.Cell{T: &te}},
+		{"end", &table.Cell{P: tp}, &table.Cell{T: &ta}},
+		{"id", &table.Cell{N: n}, txt("joe")},
+		{"id", &table.Cell{P: tp}, txt("knows")},
+		{"hour", &table.Cell{T: &tm}, &table.Cell{T: &hour}},
+		{"day", &table.Cell{T: &tm}, &table.Cell{T: &day}},
+		{"month", txt("2016-03-09T10:30:15-08:00"), &table.Cell{T: &month}},
+		{"year", &table.Cell{P: tp}, &table.Cell{T: &ta}},
+		{"minute", &table.Cell{T: &tm}, &table.Cell{T: &minute}},
+	}
+	for _, entry := range testTable {
+		f, arity, ok := LookupFunction(entry.fn)
+		if !ok || arity != 1 {
+			t.Fatalf("LookupFunction(%q) returned arity %d, found %v; want arity 1, found true", entry.fn, arity, ok)
+		}
+		got, err := f([]*table.Cell{entry.arg})
+		if err != nil {
+			t.Errorf("%s(%v) failed with error %v", entry.fn, entry.arg, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("%s(%v) returned %v; want %v", entry.fn, entry.arg, got, entry.want)
+		}
+	}
+	failures := []struct {
+		fn  string
+		arg *table.Cell
+	}{
+		{"str", &table.Cell{}},
+		{"type", &table.Cell{T: &ta}},
+		{"time", &table.Cell{P: ip}},
+		{"time", &table.Cell{N: n}},
+		{"end", &table.Cell{P: ip}},
+		{"id", &table.Cell{L: l}},
+		{"hour", &table.Cell{P: ip}},
+	}
+	for _, entry := range failures {
+		f, _, _ := LookupFunction(entry.fn)
+		if got, err := f([]*table.Cell{entry.arg}); err == nil {
+			t.Errorf("%s(%v) should have failed; returned %v instead", entry.fn, entry.arg, got)
+		}
+	}
+}
+
+func TestCosineFunction(t *testing.T) {
+	vec := func(s string) *table.Cell {
+		l, err := literal.ParseCustom("vector", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &table.Cell{L: l}
+	}
+	f, arity, ok := LookupFunction("COSINE")
+	if !ok || arity != 2 {
+		t.Fatalf("LookupFunction(\"COSINE\") returned arity %d, %v; want 2, true", arity, ok)
+	}
+	got, err := f([]*table.Cell{vec("[1,0]"), vec("[3,0]")})
+	if err != nil {
+		t.Fatalf("cosine failed with error %v", err)
+	}
+	if v, err := got.L.Float64(); err != nil || v != 1 {
+		t.Errorf("cosine returned %v; want 1", got)
+	}
+	i, err := literal.DefaultBuilder().Build(literal.Int64, int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]*table.Cell{
+		{vec("[1,0]"), {L: i}},
+		{vec("[1,0]"), vec("[1,0,0]")},
+		{vec("[0,0]"), vec("[1,0]")},
+	} {
+		if got, err := f(args); err == nil {
+			t.Errorf("cosine(%v, %v) should have failed; returned %v instead", args[0], args[1], got)
+		}
+	}
+}
+
+func TestRegisterFunction(t *testing.T) {
+	upper := func(cs []*table.Cell) (*table.Cell, error) {
+		s, _ := text(cs[0])
+		return textCell(strings.ToUpper(s))
+	}
+	if err := RegisterFunction("Test_Upper", 1, upper); err != nil {
+		t.Fatalf("RegisterFunction failed to register function with error %v", err)
+	}
+	defer UnregisterFunction("test_upper")
+	if err := RegisterFunction("test_UPPER", 1, upper); err == nil {
+		t.Errorf("RegisterFunction should have failed to register an already registered function")
+	}
+	if err := RegisterFunction("str", 1, upper); err == nil {
+		t.Errorf("RegisterFunction should have failed to register a built-in function name")
+	}
+	if err := RegisterFunction("coalesce", -1, upper); err == nil {
+		t.Errorf("RegisterFunction should have failed to register a built-in form name")
+	}
+	if err := RegisterFunction("", 1, upper); err == nil {
+		t.Errorf("RegisterFunction should have failed to register an empty name")
+	}
+	if err := RegisterFunction("nil_function", 1, nil); err == nil {
+		t.Errorf("RegisterFunction should have failed to register a nil function")
+	}
+	e, err := NewExpression(filterTokens(t, `test_upper(?a)`))
+	if err != nil {
+		t.Fatalf("NewExpression failed to use registered function with error %v", err)
+	}
+	got, err := e.Value(table.Row{"?a": &table.Cell{S: table.CellString("foo")}})
+	if err != nil {
+		t.Fatalf("registered function failed with error %v", err)
+	}
+	if s, _ := text(got); s != "FOO" {
+		t.Errorf("registered function returned %v; want FOO", got)
+	}
+	UnregisterFunction("TEST_upper")
+	if _, _, ok := LookupFunction("test_upper"); ok {
+		t.Errorf("LookupFunction should have not found unregistered function %q", "test_upper")
+	}
+}
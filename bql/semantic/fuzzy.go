@@ -0,0 +1,191 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// fuzzyText returns the text compared by the fuzzy matching functions: the
+// value of text literals and the ID of nodes.
+func fuzzyText(c *table.Cell) (string, bool) {
+	if c.N != nil {
+		return c.N.ID().String(), true
+	}
+	return text(c)
+}
+
+// levenshtein returns the minimum number of rune insertions, deletions, and
+// substitutions needed to turn a into b. If max is not negative, it stops as
+// soon as the distance is known to be bigger than max and returns max+1. Since
+// the distance is at least the difference between the lengths of the strings,
+// strings whose lengths differ by more than max are not compared at all.
+func levenshtein(a, b []rune, max int) int {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	if max >= 0 && len(a)-len(b) > max {
+		return max + 1
+	}
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		best := row[0]
+		for j := 1; j <= len(b); j++ {
+			cur := row[j]
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			row[j] = prev + cost
+			if row[j-1]+1 < row[j] {
+				row[j] = row[j-1] + 1
+			}
+			if cur+1 < row[j] {
+				row[j] = cur + 1
+			}
+			if row[j] < best {
+				best = row[j]
+			}
+			prev = cur
+		}
+		// Distances never decrease from one row to the next.
+		if max >= 0 && best > max {
+			return max + 1
+		}
+	}
+	if max >= 0 && row[len(b)] > max {
+		return max + 1
+	}
+	return row[len(b)]
+}
+
+// levenshteinFunction returns a function computing the edit distance between
+// the text of its two arguments as an int64 literal. If max is not negative,
+// distances bigger than max are returned as max+1.
+func levenshteinFunction(max int) Function {
+	return func(cs []*table.Cell) (*table.Cell, error) {
+		a, aok := fuzzyText(cs[0])
+		b, bok := fuzzyText(cs[1])
+		if !aok || !bok {
+			return nil, fmt.Errorf("levenshtein requires two text literals or nodes; got %v and %v instead", cs[0], cs[1])
+		}
+		l, err := literal.DefaultBuilder().Build(literal.Int64, int64(levenshtein([]rune(a), []rune(b), max)))
+		if err != nil {
+			return nil, err
+		}
+		return &table.Cell{L: l}, nil
+	}
+}
+
+// soundexCodes contains the digit of each consonant in a Soundex code.
+// Vowels and the letters H, W, and Y have no digit.
+var soundexCodes = map[rune]byte{
+	'B': '1', 'F': '1', 'P': '1', 'V': '1',
+	'C': '2', 'G': '2', 'J': '2', 'K': '2', 'Q': '2', 'S': '2', 'X': '2', 'Z': '2',
+	'D': '3', 'T': '3',
+	'L': '4',
+	'M': '5', 'N': '5',
+	'R': '6',
+}
+
+// soundex returns the American Soundex code of the provided string: its first
+// letter followed by three digits encoding the consonants that follow it, so
+// names that sound alike in English share their code. Characters other than
+// ASCII letters are ignored. The boolean is false if the string contains no
+// letter.
+func soundex(s string) (string, bool) {
+	var (
+		code []byte
+		last byte
+	)
+	for _, r := range strings.ToUpper(s) {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			continue
+		}
+		d := soundexCodes[r]
+		if code == nil {
+			code, last = []byte{byte(r)}, d
+			continue
+		}
+		switch {
+		case d != 0 && d != last:
+			code = append(code, d)
+			last = d
+		case r != 'H' && r != 'W':
+			// Vowels separate consonants with the same digit, but H and W
+			// do not.
+			last = d
+		}
+		if len(code) == 4 {
+			break
+		}
+	}
+	if code == nil {
+		return "", false
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code[:4]), true
+}
+
+// soundexFunction returns the Soundex code of the text of its argument as a
+// text literal.
+func soundexFunction(cs []*table.Cell) (*table.Cell, error) {
+	s, ok := fuzzyText(cs[0])
+	if !ok {
+		return nil, fmt.Errorf("soundex requires a text literal or a node; got %v instead", cs[0])
+	}
+	code, ok := soundex(s)
+	if !ok {
+		return nil, fmt.Errorf("soundex requires a text with at least one letter; got %q instead", s)
+	}
+	return textCell(code)
+}
+
+// boundDistance returns the value to use for v when it is compared with
+// other. Calls to LEVENSHTEIN compared with a constant integer stop computing
+// the distance as soon as it is known to be bigger than the constant; any
+// comparison with the constant gives the same result for such distances.
+func boundDistance(v, other valueNode) valueNode {
+	fn, ok := v.(*functionNode)
+	if !ok || !strings.EqualFold(fn.name, "levenshtein") {
+		return v
+	}
+	c, ok := other.(*constantNode)
+	if !ok || c.c.L == nil || c.c.L.Type() != literal.Int64 {
+		return v
+	}
+	max, err := c.c.L.Int64()
+	if err != nil || max < 0 || max > math.MaxInt32 {
+		return v
+	}
+	return &functionNode{
+		name: fn.name,
+		fn:   valueFunction{arity: 2, f: levenshteinFunction(int(max))},
+		args: fn.args,
+	}
+}
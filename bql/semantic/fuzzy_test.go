@@ -0,0 +1,125 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"testing"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+)
+
+func TestLevenshtein(t *testing.T) {
+	testTable := []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{"kitten", "sitting", -1, 3},
+		{"sitting", "kitten", -1, 3},
+		{"", "abc", -1, 3},
+		{"abc", "abc", -1, 0},
+		{"café", "cafe", -1, 1},
+		{"flaw", "lawn", -1, 2},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 2, 3},
+		{"kitten", "sitting", 0, 1},
+		{"a", "abcdef", 2, 3},
+	}
+	for _, entry := range testTable {
+		if got := levenshtein([]rune(entry.a), []rune(entry.b), entry.max); got != entry.want {
+			t.Errorf("levenshtein(%q, %q, %d) = %d; want %d", entry.a, entry.b, entry.max, got, entry.want)
+		}
+	}
+}
+
+func TestSoundex(t *testing.T) {
+	testTable := []struct {
+		s, want string
+	}{
+		{"Robert", "R163"},
+		{"Rupert", "R163"},
+		{"Rubin", "R150"},
+		{"Ashcraft", "A261"},
+		{"Tymczak", "T522"},
+		{"Pfister", "P236"},
+		{"Honeyman", "H555"},
+		{"lee", "L000"},
+		{"  o'Hara ", "O600"},
+	}
+	for _, entry := range testTable {
+		if got, ok := soundex(entry.s); !ok || got != entry.want {
+			t.Errorf("soundex(%q) = %q, %v; want %q, true", entry.s, got, ok, entry.want)
+		}
+	}
+	for _, s := range []string{"", "123", "ñ"} {
+		if got, ok := soundex(s); ok {
+			t.Errorf("soundex(%q) should have failed; got %q", s, got)
+		}
+	}
+}
+
+func TestFuzzyFilters(t *testing.T) {
+	txt := func(s string) *table.Cell {
+		l, err := literal.DefaultBuilder().Build(literal.Text, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &table.Cell{L: l}
+	}
+	n, err := node.Parse("/person<smyth>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := literal.DefaultBuilder().Build(literal.Int64, int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []table.Row{
+		{"?name": txt("smith")},
+		{"?name": txt("Smith")},
+		{"?name": txt("smithson")},
+		{"?name": txt("jones")},
+		{"?name": {N: n}},
+		{"?name": {L: i}},
+	}
+	testTable := []struct {
+		expr string
+		want []bool
+	}{
+		{`levenshtein(?name, "smith"^^type:text) <= 1`, []bool{true, true, false, false, true, false}},
+		{`levenshtein(?name, "smith"^^type:text) < 1`, []bool{true, false, false, false, false, false}},
+		{`levenshtein(?name, "smith"^^type:text) > 1`, []bool{false, false, true, true, false, false}},
+		{`2 >= levenshtein(?name, "smith"^^type:text)`, []bool{true, true, false, false, true, false}},
+		{`levenshtein(?name, "smith"^^type:text) = 3`, []bool{false, false, true, false, false, false}},
+		{`soundex(?name) = soundex("Smith"^^type:text)`, []bool{true, true, false, false, true, false}},
+	}
+	for _, entry := range testTable {
+		f, err := NewFilter(filterTokens(t, entry.expr))
+		if err != nil {
+			t.Fatalf("NewFilter(%q) failed with error %v", entry.expr, err)
+		}
+		for j, r := range rows {
+			got, err := f.Evaluate(r)
+			if err != nil {
+				t.Fatalf("Evaluate(%v) for %q failed with error %v", r, entry.expr, err)
+			}
+			if got != entry.want[j] {
+				t.Errorf("Evaluate(%v) for %q = %v; want %v", r, entry.expr, got, entry.want[j])
+			}
+		}
+	}
+}
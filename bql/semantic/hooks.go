@@ -42,6 +42,9 @@ var (
 
 	// boundRegexp contains the regular expression for not fully defined predicate bounds.
 	boundRegexp = regexp.MustCompile(`^"(.+)"@\["?([^\]"]*)"?,"?([^\]"]*)"?\]$`)
+
+	// now returns the current time used to resolve NOW() in global time bounds.
+	now = time.Now
 )
 
 // DataAccumulatorHook returns the singleton for data accumulation.
@@ -74,6 +77,35 @@ func WhereNextWorkingClauseHook() ClauseHook {
 	return whereNextWorkingClause()
 }
 
+// WhereUnionHook returns the singleton for starting a new graph pattern
+// alternative after a UNION keyword.
+func WhereUnionHook() ElementHook {
+	return whereUnion()
+}
+
+// WhereFilterHook returns the singleton for collecting the tokens that form a
+// filter expression.
+func WhereFilterHook() ElementHook {
+	return whereFilter()
+}
+
+// WhereFilterBuilderHook returns the singleton for building a filter out of the
+// collected filter expression tokens.
+func WhereFilterBuilderHook() ClauseHook {
+	return whereFilterBuilder()
+}
+
+// SubqueryStartHook returns the singleton for starting a new nested subquery.
+func SubqueryStartHook() ClauseHook {
+	return subqueryStart()
+}
+
+// SubqueryEndHook returns the singleton for validating and closing the
+// current nested subquery.
+func SubqueryEndHook() ClauseHook {
+	return subqueryEnd()
+}
+
 // WhereSubjectClauseHook returns the singleton for working clause hooks that
 // populates the subject.
 func WhereSubjectClauseHook() ElementHook {
@@ -92,12 +124,24 @@ func WhereObjectClauseHook() ElementHook {
 	return whereObjectClause()
 }
 
+// WhereReifiedClauseHook returns the singleton for working clause hooks that
+// populates the binding of the node reifying the clause.
+func WhereReifiedClauseHook() ElementHook {
+	return whereReifiedClause()
+}
+
 // VarAccumulatorHook returns the singleton for accumulating variable
 // projections.
 func VarAccumulatorHook() ElementHook {
 	return varAccumulator()
 }
 
+// VarExpressionHook returns the singleton for collecting the tokens of the
+// expressions used as function arguments in projections.
+func VarExpressionHook() ElementHook {
+	return varExpression()
+}
+
 // VarBindingsGraphChecker returns the singleton for checking a query statement
 // for valid bindings in the select variables.
 func VarBindingsGraphChecker() ClauseHook {
@@ -110,6 +154,18 @@ func GroupByBindings() ElementHook {
 	return groupByBindings()
 }
 
+// GroupByExpressionHook returns the singleton for collecting the tokens that
+// form a group by expression.
+func GroupByExpressionHook() ElementHook {
+	return groupByExpression()
+}
+
+// GroupByExpressionBuilderHook returns the singleton for building the
+// collected group by expression.
+func GroupByExpressionBuilderHook() ClauseHook {
+	return groupByExpressionBuilder()
+}
+
 // GroupByBindingsChecker returns the singleton to check that the group by
 // bindings are valid.
 func GroupByBindingsChecker() ClauseHook {
@@ -122,6 +178,18 @@ func OrderByBindings() ElementHook {
 	return orderByBindings()
 }
 
+// OrderByExpressionHook returns the singleton for collecting the tokens that
+// form an order by expression.
+func OrderByExpressionHook() ElementHook {
+	return orderByExpression()
+}
+
+// OrderByExpressionBuilderHook returns the singleton for building the
+// collected order by expression.
+func OrderByExpressionBuilderHook() ClauseHook {
+	return orderByExpressionBuilder()
+}
+
 // OrderByBindingsChecker returns the singleton to check that the group by
 // bindings are valid.
 func OrderByBindingsChecker() ClauseHook {
@@ -145,6 +213,17 @@ func LimitCollection() ElementHook {
 	return limitCollection()
 }
 
+// OffsetCollection returns the offset collection hook.
+func OffsetCollection() ElementHook {
+	return offsetCollection()
+}
+
+// DescribeCollection returns the hook that collects the node and depth of a
+// describe statement.
+func DescribeCollection() ElementHook {
+	return describeCollection()
+}
+
 // CollectGlobalBounds returns the global temporary bounds hook.
 func CollectGlobalBounds() ElementHook {
 	return collectGlobalBounds()
@@ -195,6 +274,21 @@ func TypeBindingClauseHook(t StatementType) ClauseHook {
 	return f
 }
 
+// DeleteTemplateClauseHook returns a ClauseHook that turns a delete statement
+// populated by a where clause into a deconstruct statement. The triples are
+// removed from the same graphs the where clause is evaluated against.
+func DeleteTemplateClauseHook() ClauseHook {
+	var f ClauseHook
+	f = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		s.BindType(Deconstruct)
+		for _, g := range s.InputGraphNames() {
+			s.AddOutputGraph(g)
+		}
+		return f, nil
+	}
+	return f
+}
+
 // dataAccumulator creates a element hook that tracks fully formed triples and
 // adds them to the Statement when fully formed.
 func dataAccumulator(b literal.Builder) ElementHook {
@@ -236,7 +330,7 @@ func dataAccumulator(b literal.Builder) ElementHook {
 			return hook, nil
 		}
 		if o == nil {
-			tmp, err := triple.ParseObject(tkn.Text, b)
+			tmp, err := toObject(tkn, b)
 			if err != nil {
 				return nil, err
 			}
@@ -341,6 +435,77 @@ func whereInitWorkingClause() ClauseHook {
 	return f
 }
 
+// whereUnion returns an element hook that closes the current graph pattern
+// alternative every time a UNION keyword is found.
+func whereUnion() ElementHook {
+	var f ElementHook
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		if ce.Token().Type == lexer.ItemUnion {
+			st.AddGraphPatternUnion()
+		}
+		return f, nil
+	}
+	return f
+}
+
+// whereFilter returns an element hook that collects the tokens that form a
+// filter expression.
+func whereFilter() ElementHook {
+	var f ElementHook
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		st.workingFilter = append(st.workingFilter, ce)
+		return f, nil
+	}
+	return f
+}
+
+// whereFilterBuilder returns a clause hook that builds the filter out of the
+// collected filter expression tokens.
+func whereFilterBuilder() ClauseHook {
+	var f ClauseHook
+	f = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		if err := s.AddWorkingFilter(); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return f
+}
+
+// subqueryStart returns a clause hook that starts a new subquery nested in the
+// current statement.
+func subqueryStart() ClauseHook {
+	var f ClauseHook
+	f = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		s.StartSubquery()
+		return f, nil
+	}
+	return f
+}
+
+// subqueryEnd returns a clause hook that validates the group by bindings of
+// the current subquery and adds it to the graph pattern of its parent.
+func subqueryEnd() ClauseHook {
+	var f ClauseHook
+	gbc := groupByBindingsChecker()
+	f = func(s *Statement, sym Symbol) (ClauseHook, error) {
+		if _, err := gbc(s, sym); err != nil {
+			return nil, err
+		}
+		if err := s.EndSubquery(); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return f
+}
+
 // whereSubjectClause returns an element hook that updates the subject
 // modifiers on the working graph clause.
 func whereSubjectClause() ElementHook {
@@ -360,11 +525,17 @@ func whereSubjectClause() ElementHook {
 			return f, nil
 		case lexer.ItemRBracket:
 			lastNopToken = nil
+			// Graph blocks only contain graph clauses, hence any closing bracket
+			// also closes the active graph block.
+			st.workingGraphBinding = ""
 			return f, nil
 		case lexer.ItemOptional:
 			c.Optional = true
 			lastNopToken = nil
 			return f, nil
+		case lexer.ItemFilter:
+			lastNopToken = nil
+			return f, nil
 		case lexer.ItemNode:
 			if c.S != nil {
 				return nil, fmt.Errorf("invalid node in where clause that already has a subject; current %v, got %v", c.S, tkn.Type)
@@ -385,6 +556,11 @@ func whereSubjectClause() ElementHook {
 				lastNopToken = nil
 				return f, nil
 			}
+			if lastNopToken.Type == lexer.ItemGraph {
+				st.workingGraphBinding = tkn.Text
+				lastNopToken = nil
+				return f, nil
+			}
 			if lastNopToken.Type == lexer.ItemAs {
 				if c.SAlias != "" {
 					return nil, fmt.Errorf("AS alias binding for subject has already being assigned on %v", st)
@@ -511,6 +687,18 @@ func wherePredicateClause() ElementHook {
 		c := st.WorkingClause()
 		switch tkn.Type {
 		case lexer.ItemPredicate:
+			if lastNopToken != nil && lastNopToken.Type == lexer.ItemDiv {
+				lastNopToken = nil
+				p, _, pAnchorBinding, _, err := processPredicate(ce)
+				if err != nil {
+					return nil, err
+				}
+				if pAnchorBinding != "" {
+					return nil, fmt.Errorf("predicate %s in a path cannot bind its time anchor", tkn.Text)
+				}
+				c.PPath = append(c.PPath, &PathStep{P: p})
+				return f, nil
+			}
 			lastNopToken = nil
 			if c.P != nil {
 				return nil, fmt.Errorf("invalid predicate %s on graph clause since already set to %s", tkn.Text, c.P)
@@ -532,6 +720,28 @@ func wherePredicateClause() ElementHook {
 			}
 			c.PID, c.PLowerBoundAlias, c.PUpperBoundAlias, c.PLowerBound, c.PUpperBound, c.PTemporal = pID, pLowerBoundAlias, pUpperBoundAlias, pLowerBound, pUpperBound, pTemp
 			return f, nil
+		case lexer.ItemPlus, lexer.ItemMul, lexer.ItemDiv:
+			if c.P == nil {
+				return nil, fmt.Errorf("path operator %s requires a preceding predicate on %v", tkn.Text, st)
+			}
+			if c.PAnchorBinding != "" {
+				return nil, fmt.Errorf("predicate %s in a path cannot bind its time anchor", c.P)
+			}
+			if !c.HasPath() {
+				c.PPath = []*PathStep{{P: c.P}}
+			}
+			if tkn.Type != lexer.ItemDiv {
+				s := c.PPath[len(c.PPath)-1]
+				if s.Modifier != PathOne {
+					return nil, fmt.Errorf("path step %s already has a repetition modifier", s)
+				}
+				s.Modifier = PathOneOrMore
+				if tkn.Type == lexer.ItemMul {
+					s.Modifier = PathZeroOrMore
+				}
+			}
+			lastNopToken = tkn
+			return f, nil
 		case lexer.ItemBinding:
 			if lastNopToken == nil {
 				if c.PBinding != "" {
@@ -540,6 +750,9 @@ func wherePredicateClause() ElementHook {
 				c.PBinding = tkn.Text
 				return f, nil
 			}
+			if c.HasPath() {
+				return nil, fmt.Errorf("binding %q cannot alias the predicate path of the clause", tkn.Text)
+			}
 			switch lastNopToken.Type {
 			case lexer.ItemAs:
 				if c.PAlias != "" {
@@ -587,7 +800,7 @@ func whereObjectClause() ElementHook {
 			if c.O != nil {
 				return nil, fmt.Errorf("invalid object %s for object on graph clause since already set to %s", tkn.Text, c.O)
 			}
-			obj, err := triple.ParseObject(tkn.Text, literal.DefaultBuilder())
+			obj, err := toObject(tkn, literal.DefaultBuilder())
 			if err != nil {
 				return nil, err
 			}
@@ -664,6 +877,31 @@ func whereObjectClause() ElementHook {
 	return f
 }
 
+// whereReifiedClause returns an element hook that sets the binding of the node
+// reifying the working graph clause.
+func whereReifiedClause() ElementHook {
+	var f ElementHook
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		tkn := ce.Token()
+		if tkn.Type != lexer.ItemBinding {
+			return f, nil
+		}
+		c := st.WorkingClause()
+		if c.PBinding != "" || c.HasPath() {
+			return nil, fmt.Errorf("cannot reify clause %v as %s; reified clauses need a fixed predicate ID", c, tkn.Text)
+		}
+		if c.P == nil && c.PTemporal && c.PAnchorBinding == "" {
+			return nil, fmt.Errorf("cannot reify clause %v as %s; reified clauses with a temporal predicate need a fixed time anchor or an anchor binding", c, tkn.Text)
+		}
+		c.ReifiedBinding = tkn.Text
+		return f, nil
+	}
+	return f
+}
+
 // varAccumulator returns an element hook that updates the object
 // modifiers on the working graph clause.
 func varAccumulator() ElementHook {
@@ -679,7 +917,7 @@ func varAccumulator() ElementHook {
 		p := st.WorkingProjection()
 		switch tkn.Type {
 		case lexer.ItemBinding:
-			if p.Binding == "" {
+			if p.Binding == "" && p.Expression == nil {
 				p.Binding = tkn.Text
 			} else {
 				if lastNopToken != nil && lastNopToken.Type == lexer.ItemAs {
@@ -691,9 +929,20 @@ func varAccumulator() ElementHook {
 				}
 			}
 		case lexer.ItemAs:
+			if err := st.resolveWorkingProjection(); err != nil {
+				return nil, err
+			}
 			lastNopToken = tkn
 		case lexer.ItemSum, lexer.ItemCount:
 			p.OP = tkn.Type
+		case lexer.ItemFunction, lexer.ItemType, lexer.ItemID, lexer.ItemIf:
+			st.addWorkingProjectionToken(ce)
+		case lexer.ItemLPar, lexer.ItemRPar:
+			if len(st.workingProjectionTokens) > 0 {
+				st.addWorkingProjectionToken(ce)
+			} else {
+				lastNopToken = nil
+			}
 		case lexer.ItemDistinct:
 			p.Modifier = tkn.Type
 		case lexer.ItemComma:
@@ -706,6 +955,20 @@ func varAccumulator() ElementHook {
 	return f
 }
 
+// varExpression returns an element hook that collects the tokens of the
+// expressions used as function arguments in projections.
+func varExpression() ElementHook {
+	var f ElementHook
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		st.addWorkingProjectionToken(ce)
+		return f, nil
+	}
+	return f
+}
+
 // bindingsGraphChecker validate that all input bindings are provided by the
 // graph pattern.
 func bindingsGraphChecker() ClauseHook {
@@ -719,6 +982,13 @@ func bindingsGraphChecker() ClauseHook {
 				return nil, fmt.Errorf("specified binding %s not found in where clause, only %v bindings are available", b, s.Bindings())
 			}
 		}
+		for _, flt := range s.Filters() {
+			for _, b := range flt.Bindings() {
+				if _, ok := bs[b]; !ok && !IsParameter(b) {
+					return nil, fmt.Errorf("filter binding %s not found in where clause, only %v bindings are available", b, s.Bindings())
+				}
+			}
+		}
 		return f, nil
 	}
 	return f
@@ -732,8 +1002,38 @@ func groupByBindings() ElementHook {
 			return f, nil
 		}
 		tkn := ce.Token()
-		if tkn.Type == lexer.ItemBinding {
+		switch tkn.Type {
+		case lexer.ItemBinding:
 			st.groupBy = append(st.groupBy, tkn.Text)
+		case lexer.ItemFunction, lexer.ItemType, lexer.ItemID, lexer.ItemIf, lexer.ItemLPar, lexer.ItemRPar:
+			st.workingGroupByExpression = append(st.workingGroupByExpression, ce)
+		}
+		return f, nil
+	}
+	return f
+}
+
+// groupByExpression returns an element hook that collects the tokens that form
+// a group by expression.
+func groupByExpression() ElementHook {
+	var f ElementHook
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		st.workingGroupByExpression = append(st.workingGroupByExpression, ce)
+		return f, nil
+	}
+	return f
+}
+
+// groupByExpressionBuilder returns a clause hook that builds the group by
+// expression out of the collected tokens.
+func groupByExpressionBuilder() ClauseHook {
+	var f ClauseHook
+	f = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		if err := s.AddWorkingGroupByExpression(); err != nil {
+			return nil, err
 		}
 		return f, nil
 	}
@@ -748,8 +1048,30 @@ func groupByBindingsChecker() ClauseHook {
 		// Force working projection flush.
 		var idxs map[int]bool
 		idxs = make(map[int]bool)
-		for _, gb := range s.groupBy {
+		for i, gb := range s.groupBy {
 			found := false
+			if e, ok := s.groupByExpressions[gb]; ok {
+				// Expressions computed by a projection group by its alias.
+				for idx, prj := range s.projection {
+					if prj.key == gb && prj.OP == lexer.ItemError && prj.Alias != "" {
+						s.groupBy[i] = prj.Alias
+						delete(s.groupByExpressions, gb)
+						idxs[idx] = true
+						found = true
+						break
+					}
+				}
+				if found {
+					continue
+				}
+				bs := s.BindingsMap()
+				for _, b := range e.Bindings() {
+					if _, ok := bs[b]; !ok {
+						return nil, fmt.Errorf("GROUP BY expression %q uses binding %s not found in where clause", gb, b)
+					}
+				}
+				continue
+			}
 			for idx, prj := range s.projection {
 				if gb == prj.Alias || (prj.Alias == "" && gb == prj.Binding) {
 					if prj.OP != lexer.ItemError || prj.Modifier != lexer.ItemError {
@@ -768,7 +1090,11 @@ func groupByBindingsChecker() ClauseHook {
 				continue
 			}
 			if len(s.groupBy) > 0 && prj.OP == lexer.ItemError {
-				return nil, fmt.Errorf("Binding %q not listed on GROUP BY requires an aggregation function", prj.Binding)
+				b := prj.Binding
+				if b == "" {
+					b = prj.Alias
+				}
+				return nil, fmt.Errorf("Binding %q not listed on GROUP BY requires an aggregation function", b)
 			}
 			if len(s.groupBy) == 0 && prj.OP != lexer.ItemError {
 				s := prj.Alias
@@ -794,6 +1120,8 @@ func orderByBindings() ElementHook {
 		switch tkn.Type {
 		case lexer.ItemBinding:
 			st.orderBy = append(st.orderBy, table.SortConfig{{Binding: tkn.Text}}...)
+		case lexer.ItemFunction, lexer.ItemType, lexer.ItemID, lexer.ItemLPar, lexer.ItemRPar:
+			st.workingOrderByExpression = append(st.workingOrderByExpression, ce)
 		case lexer.ItemAsc:
 			st.orderBy[len(st.orderBy)-1].Desc = false
 		case lexer.ItemDesc:
@@ -804,6 +1132,33 @@ func orderByBindings() ElementHook {
 	return f
 }
 
+// orderByExpression returns an element hook that collects the tokens that form
+// an order by expression.
+func orderByExpression() ElementHook {
+	var f ElementHook
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		st.workingOrderByExpression = append(st.workingOrderByExpression, ce)
+		return f, nil
+	}
+	return f
+}
+
+// orderByExpressionBuilder returns a clause hook that builds the order by
+// expression out of the collected tokens.
+func orderByExpressionBuilder() ClauseHook {
+	var f ClauseHook
+	f = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		if err := s.AddWorkingOrderByExpression(); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return f
+}
+
 // orderByBindingsChecker checks that all order by bindings are valid output
 // bindings.
 func orderByBindingsChecker() ClauseHook {
@@ -825,6 +1180,15 @@ func orderByBindingsChecker() ClauseHook {
 			} else {
 				seen[cfg.Binding] = cfg.Desc
 			}
+			// Check that the bindings used by expressions exist.
+			if e, ok := s.orderByExpressions[cfg.Binding]; ok {
+				for _, b := range e.Bindings() {
+					if _, ok := outs[b]; !ok {
+						return nil, fmt.Errorf("order by expression %q uses unknown binding %q; available bindings are %v", e, b, s.OutputBindings())
+					}
+				}
+				continue
+			}
 			// Check that the binding exist.
 			if _, ok := outs[cfg.Binding]; !ok {
 				return nil, fmt.Errorf("order by binding %q unknown; available bindings are %v", cfg.Binding, s.OutputBindings())
@@ -904,15 +1268,114 @@ func limitCollection() ElementHook {
 	return f
 }
 
+// offsetCollection collects the number of rows to skip as indicated by the
+// OFFSET clause.
+func offsetCollection() ElementHook {
+	var f func(st *Statement, ce ConsumedElement) (ElementHook, error)
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() || ce.token.Type == lexer.ItemOffset {
+			return f, nil
+		}
+		if ce.token.Type != lexer.ItemLiteral {
+			return nil, fmt.Errorf("offset clause required an int64 literal; found %v instead", ce.token)
+		}
+		l, err := literal.DefaultBuilder().Parse(ce.token.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse offset literal %q with error %v", ce.token.Text, err)
+		}
+		if l.Type() != literal.Int64 {
+			return nil, fmt.Errorf("offset required an int64 value; found %s instead", l)
+		}
+		ov, err := l.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve the int64 value for literal %v with error %v", l, err)
+		}
+		if ov < 0 {
+			return nil, fmt.Errorf("offset required a non negative value; found %d instead", ov)
+		}
+		st.offsetSet, st.offset = true, ov
+		return f, nil
+	}
+	return f
+}
+
+// describeCollection collects the node to describe and the optional depth
+// of the neighborhood to return.
+func describeCollection() ElementHook {
+	var f func(st *Statement, ce ConsumedElement) (ElementHook, error)
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		tkn := ce.Token()
+		switch tkn.Type {
+		case lexer.ItemNode:
+			n, err := node.Parse(tkn.Text)
+			if err != nil {
+				return nil, err
+			}
+			st.describeNode, st.describeDepth = n, 1
+		case lexer.ItemDepth:
+		case lexer.ItemLiteral:
+			l, err := literal.DefaultBuilder().Parse(tkn.Text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse depth literal %q with error %v", tkn.Text, err)
+			}
+			if l.Type() != literal.Int64 {
+				return nil, fmt.Errorf("depth required an int64 value; found %s instead", l)
+			}
+			d, err := l.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("failed to retrieve the int64 value for literal %v with error %v", l, err)
+			}
+			if d < 1 {
+				return nil, fmt.Errorf("depth required a positive value; found %d instead", d)
+			}
+			st.describeDepth = d
+		default:
+			return nil, fmt.Errorf("describe clause does not support %v", tkn)
+		}
+		return f, nil
+	}
+	return f
+}
+
 // collectGlobalBounds collects the global time bounds that should be applied
-// to all temporal predicates.
+// to all temporal predicates. Time points can be either the time anchor of
+// an empty predicate or NOW(), optionally shifted by a duration. Bounds are
+// inclusive unless the EXCLUSIVE modifier is provided.
 func collectGlobalBounds() ElementHook {
 	var (
 		f         func(st *Statement, ce ConsumedElement) (ElementHook, error)
+		collect   func(st *Statement, ce ConsumedElement) (ElementHook, error)
 		opToken   *lexer.Token
 		lastToken *lexer.Token
+		boundOp   lexer.TokenType
+		anchor    *time.Time
+		sign      time.Duration
 	)
+	setAnchor := func(st *Statement, ta *time.Time) {
+		anchor, sign = ta, 0
+		if lastToken.Type == lexer.ItemComma || lastToken.Type == lexer.ItemBefore {
+			st.lookupOptions.UpperAnchor = ta
+			opToken, lastToken = nil, nil
+		} else {
+			st.lookupOptions.LowerAnchor = ta
+			if opToken.Type != lexer.ItemBetween {
+				opToken, lastToken = nil, nil
+			}
+		}
+	}
 	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		h, err := collect(st, ce)
+		if err != nil {
+			// The hook is shared across statements, hence a failed bound should
+			// not leak into the next one.
+			opToken, lastToken, anchor, sign = nil, nil, nil, 0
+		}
+		return h, err
+	}
+	collect = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
 		if ce.IsSymbol() {
 			return f, nil
 		}
@@ -922,7 +1385,7 @@ func collectGlobalBounds() ElementHook {
 			if lastToken != nil {
 				return nil, fmt.Errorf("invalid token %v after already valid token %v", tkn, lastToken)
 			}
-			opToken, lastToken = tkn, tkn
+			opToken, lastToken, boundOp, anchor = tkn, tkn, tkn.Type, nil
 		case lexer.ItemComma:
 			if lastToken == nil || opToken.Type != lexer.ItemBetween {
 				return nil, fmt.Errorf("token %v can only be used in a between clause; previous token %v instead", tkn, lastToken)
@@ -943,14 +1406,47 @@ func collectGlobalBounds() ElementHook {
 			if err != nil {
 				return nil, err
 			}
-			if lastToken.Type == lexer.ItemComma || lastToken.Type == lexer.ItemBefore {
-				st.lookupOptions.UpperAnchor = ta
-				opToken, lastToken = nil, nil
-			} else {
-				st.lookupOptions.LowerAnchor = ta
-				if opToken.Type != lexer.ItemBetween {
-					opToken, lastToken = nil, nil
-				}
+			setAnchor(st, ta)
+		case lexer.ItemFunction:
+			if lastToken == nil {
+				return nil, fmt.Errorf("invalid token %v without a global time modifier", tkn)
+			}
+			if !strings.EqualFold(tkn.Text, "now") {
+				return nil, fmt.Errorf("global time bounds only support the NOW() function; found %s instead", tkn.Text)
+			}
+			ta := now()
+			setAnchor(st, &ta)
+		case lexer.ItemLPar, lexer.ItemRPar:
+			// Part of the NOW() function call.
+		case lexer.ItemPlus, lexer.ItemMinus:
+			if anchor == nil {
+				return nil, fmt.Errorf("invalid token %v without a time point to shift", tkn)
+			}
+			sign = 1
+			if tkn.Type == lexer.ItemMinus {
+				sign = -1
+			}
+		case lexer.ItemDuration:
+			if anchor == nil || sign == 0 {
+				return nil, fmt.Errorf("invalid duration %v without a time point to shift", tkn)
+			}
+			d, err := time.ParseDuration(tkn.Text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration %q with error %v", tkn.Text, err)
+			}
+			// The anchor was created for this bound, so it can be shifted in place.
+			*anchor, sign = anchor.Add(sign*d), 0
+		case lexer.ItemInclusive:
+		case lexer.ItemExclusive:
+			// Time anchors have nanosecond resolution, so exclusive bounds can be
+			// turned into inclusive ones and still be used in storage lookups.
+			if boundOp != lexer.ItemBefore && st.lookupOptions.LowerAnchor != nil {
+				la := st.lookupOptions.LowerAnchor.Add(time.Nanosecond)
+				st.lookupOptions.LowerAnchor = &la
+			}
+			if boundOp != lexer.ItemAfter && st.lookupOptions.UpperAnchor != nil {
+				ua := st.lookupOptions.UpperAnchor.Add(-time.Nanosecond)
+				st.lookupOptions.UpperAnchor = &ua
 			}
 		default:
 			return nil, fmt.Errorf("global bound found unexpected token %v", tkn)
@@ -1068,7 +1564,7 @@ func constructObject() ElementHook {
 		}
 		switch tkn.Type {
 		case lexer.ItemNode, lexer.ItemBlankNode, lexer.ItemLiteral:
-			obj, err := triple.ParseObject(tkn.Text, literal.DefaultBuilder())
+			obj, err := toObject(tkn, literal.DefaultBuilder())
 			if err != nil {
 				return nil, err
 			}
@@ -1105,6 +1601,29 @@ func NextWorkingConstructPredicateObjectPair() ClauseHook {
 	return f
 }
 
+// ShowCollection returns the hook that collects the kind of catalog
+// information requested by a show statement.
+func ShowCollection() ElementHook {
+	var f ElementHook
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		switch tkn := ce.Token(); tkn.Type {
+		case lexer.ItemGraphs:
+			st.showType = ShowGraphs
+		case lexer.ItemPredicates:
+			st.showType = ShowPredicates
+		case lexer.ItemTriples:
+			st.showType = ShowTripleCount
+		case lexer.ItemStats:
+			st.showType = ShowStats
+		}
+		return f, nil
+	}
+	return f
+}
+
 // ShowClauseHook returns a clause hook for the show statement.
 func ShowClauseHook() ClauseHook {
 	var f ClauseHook
@@ -1114,3 +1633,39 @@ func ShowClauseHook() ClauseHook {
 	}
 	return f
 }
+
+// ConditionalClauseHook returns a clause hook that marks create and drop
+// statements as conditional on the existence of the graphs.
+func ConditionalClauseHook() ClauseHook {
+	var f ClauseHook
+	f = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		s.conditional = true
+		return f, nil
+	}
+	return f
+}
+
+// CascadeClauseHook returns a clause hook that marks drop statements as also
+// dropping the graphs derived from the dropped ones.
+func CascadeClauseHook() ClauseHook {
+	var f ClauseHook
+	f = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		s.cascade = true
+		return f, nil
+	}
+	return f
+}
+
+// ExplainClauseHook returns a clause hook that marks the statement as one that
+// should be explained instead of executed.
+func ExplainClauseHook() ClauseHook {
+	var f ClauseHook
+	f = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		if s.explain {
+			return nil, fmt.Errorf("explain statements cannot be explained")
+		}
+		s.explain = true
+		return f, nil
+	}
+	return f
+}
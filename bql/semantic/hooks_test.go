@@ -175,6 +175,20 @@ func TestTypeBindingClauseHook(t *testing.T) {
 	}
 }
 
+func TestDeleteTemplateClauseHook(t *testing.T) {
+	f := DeleteTemplateClauseHook()
+	st := &Statement{}
+	st.AddInputGraph("?a")
+	st.AddInputGraph("?b")
+	f(st, Symbol("FOO"))
+	if got, want := st.Type(), Deconstruct; got != want {
+		t.Errorf("semantic.DeleteTemplateClauseHook failed to set the right type; got %s, want %s", got, want)
+	}
+	if got, want := st.OutputGraphNames(), []string{"?a", "?b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("semantic.DeleteTemplateClauseHook failed to set the output graphs; got %v, want %v", got, want)
+	}
+}
+
 func TestWhereInitClauseHook(t *testing.T) {
 	f := whereInitWorkingClause()
 	st := &Statement{}
@@ -599,6 +613,87 @@ func TestWherePredicateClauseHook(t *testing.T) {
 	})
 }
 
+func TestWherePredicatePathClauseHook(t *testing.T) {
+	predicateToken := func(text string) ConsumedElement {
+		return NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemPredicate,
+			Text: text,
+		})
+	}
+	opToken := func(tt lexer.TokenType, text string) ConsumedElement {
+		return NewConsumedToken(&lexer.Token{
+			Type: tt,
+			Text: text,
+		})
+	}
+	knows, err := predicate.Parse(`"knows"@[]`)
+	if err != nil {
+		t.Fatalf("predicate.Parse failed with error %v", err)
+	}
+	name, err := predicate.Parse(`"name"@[]`)
+	if err != nil {
+		t.Fatalf("predicate.Parse failed with error %v", err)
+	}
+	runTabulatedClauseHookTest(t, "semantic.wherePredicateClause", wherePredicateClause(), []testClauseTable{
+		{
+			valid: true,
+			id:    "valid predicate path",
+			ces: []ConsumedElement{
+				NewConsumedSymbol("FOO"),
+				predicateToken(`"knows"@[]`),
+				NewConsumedSymbol("FOO"),
+				opToken(lexer.ItemPlus, "+"),
+				NewConsumedSymbol("FOO"),
+				opToken(lexer.ItemDiv, "/"),
+				predicateToken(`"name"@[]`),
+				NewConsumedSymbol("FOO"),
+				opToken(lexer.ItemMul, "*"),
+				NewConsumedSymbol("FOO"),
+			},
+			want: &GraphClause{
+				P: knows,
+				PPath: []*PathStep{
+					{P: knows, Modifier: PathOneOrMore},
+					{P: name, Modifier: PathZeroOrMore},
+				},
+			},
+		},
+	})
+	invalid := []testClauseTable{
+		{
+			id: "repeated modifiers",
+			ces: []ConsumedElement{
+				predicateToken(`"knows"@[]`),
+				opToken(lexer.ItemPlus, "+"),
+				opToken(lexer.ItemMul, "*"),
+			},
+		},
+		{
+			id: "path step with an anchor binding",
+			ces: []ConsumedElement{
+				predicateToken(`"knows"@[]`),
+				opToken(lexer.ItemDiv, "/"),
+				predicateToken(`"name"@[?t]`),
+			},
+		},
+		{
+			id: "path with an alias",
+			ces: []ConsumedElement{
+				predicateToken(`"knows"@[]`),
+				opToken(lexer.ItemPlus, "+"),
+				opToken(lexer.ItemAs, "as"),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?p",
+				}),
+			},
+		},
+	}
+	for _, entry := range invalid {
+		runTabulatedClauseHookTest(t, "semantic.wherePredicateClause", wherePredicateClause(), []testClauseTable{entry})
+	}
+}
+
 func TestWhereObjectClauseHook(t *testing.T) {
 	st := &Statement{}
 	f := whereObjectClause()
@@ -1794,6 +1889,70 @@ func TestLimitCollection(t *testing.T) {
 	}
 }
 
+func TestDescribeCollection(t *testing.T) {
+	nodeToken := func(text string) ConsumedElement {
+		return NewConsumedToken(&lexer.Token{Type: lexer.ItemNode, Text: text})
+	}
+	depth := NewConsumedToken(&lexer.Token{Type: lexer.ItemDepth, Text: "depth"})
+	lit := func(text string) ConsumedElement {
+		return NewConsumedToken(&lexer.Token{Type: lexer.ItemLiteral, Text: text})
+	}
+	testTable := []struct {
+		in    []ConsumedElement
+		node  string
+		depth int64
+		fail  bool
+	}{
+		{
+			in:    []ConsumedElement{nodeToken("/u<joe>"), NewConsumedSymbol("FOO")},
+			node:  "/u<joe>",
+			depth: 1,
+		},
+		{
+			in:    []ConsumedElement{nodeToken("/u<joe>"), NewConsumedSymbol("FOO"), depth, lit(`"3"^^type:int64`)},
+			node:  "/u<joe>",
+			depth: 3,
+		},
+		{
+			in:   []ConsumedElement{nodeToken("/u<joe>"), depth, lit(`"0"^^type:int64`)},
+			fail: true,
+		},
+		{
+			in:   []ConsumedElement{nodeToken("/u<joe>"), depth, lit(`"true"^^type:bool`)},
+			fail: true,
+		},
+		{
+			in:   []ConsumedElement{NewConsumedToken(&lexer.Token{Type: lexer.ItemBinding, Text: "?foo"})},
+			fail: true,
+		},
+	}
+	for _, entry := range testTable {
+		f, st := describeCollection(), &Statement{}
+		var err error
+		for _, ce := range entry.in {
+			if _, err = f(st, ce); err != nil {
+				break
+			}
+		}
+		if entry.fail {
+			if err == nil {
+				t.Errorf("semantic.describeCollection should have failed for %v", entry.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("semantic.describeCollection failed for %v with error %v", entry.in, err)
+			continue
+		}
+		if got, want := st.DescribeNode().String(), entry.node; got != want {
+			t.Errorf("semantic.describeCollection collected the wrong node; got %v, want %v", got, want)
+		}
+		if got, want := st.DescribeDepth(), entry.depth; got != want {
+			t.Errorf("semantic.describeCollection collected the wrong depth; got %d, want %d", got, want)
+		}
+	}
+}
+
 func TestCollectGlobalBounds(t *testing.T) {
 	f := collectGlobalBounds()
 	date := "2015-07-19T13:12:04.669618843-07:00"
@@ -1802,6 +1961,22 @@ func TestCollectGlobalBounds(t *testing.T) {
 		t.Fatalf("time.Parse failed to parse valid time %s with error %v", date, err)
 	}
 	pretty, invalid := fmt.Sprintf("\"\"@[%s]", date), fmt.Sprintf("\"INVALID\"@[%s]", date)
+	defer func(f func() time.Time) {
+		now = f
+	}(now)
+	now = func() time.Time {
+		return pd
+	}
+	tkn := func(tt lexer.TokenType, text string) ConsumedElement {
+		return NewConsumedToken(&lexer.Token{
+			Type: tt,
+			Text: text,
+		})
+	}
+	at := func(d time.Duration) *time.Time {
+		t := pd.Add(d)
+		return &t
+	}
 	testTable := []struct {
 		id   string
 		in   []ConsumedElement
@@ -1875,6 +2050,67 @@ func TestCollectGlobalBounds(t *testing.T) {
 			},
 			fail: false,
 		},
+		{
+			id: "after NOW() - 24h",
+			in: []ConsumedElement{
+				tkn(lexer.ItemAfter, "after"),
+				tkn(lexer.ItemFunction, "NOW"),
+				tkn(lexer.ItemLPar, "("),
+				tkn(lexer.ItemRPar, ")"),
+				tkn(lexer.ItemMinus, "-"),
+				tkn(lexer.ItemDuration, "24h"),
+			},
+			want: storage.LookupOptions{
+				LowerAnchor: at(-24 * time.Hour),
+			},
+		},
+		{
+			id: "before X + 1h30m exclusive",
+			in: []ConsumedElement{
+				tkn(lexer.ItemBefore, "before"),
+				tkn(lexer.ItemPredicate, pretty),
+				tkn(lexer.ItemPlus, "+"),
+				tkn(lexer.ItemDuration, "1h30m"),
+				tkn(lexer.ItemExclusive, "exclusive"),
+			},
+			want: storage.LookupOptions{
+				UpperAnchor: at(90*time.Minute - time.Nanosecond),
+			},
+		},
+		{
+			id: "between NOW() - 1h, X inclusive",
+			in: []ConsumedElement{
+				tkn(lexer.ItemBetween, "between"),
+				tkn(lexer.ItemFunction, "now"),
+				tkn(lexer.ItemLPar, "("),
+				tkn(lexer.ItemRPar, ")"),
+				tkn(lexer.ItemMinus, "-"),
+				tkn(lexer.ItemDuration, "1h"),
+				tkn(lexer.ItemComma, ","),
+				tkn(lexer.ItemPredicate, pretty),
+				tkn(lexer.ItemInclusive, "inclusive"),
+			},
+			want: storage.LookupOptions{
+				LowerAnchor: at(-time.Hour),
+				UpperAnchor: &pd,
+			},
+		},
+		{
+			id: "between X, NOW() exclusive",
+			in: []ConsumedElement{
+				tkn(lexer.ItemBetween, "between"),
+				tkn(lexer.ItemPredicate, pretty),
+				tkn(lexer.ItemComma, ","),
+				tkn(lexer.ItemFunction, "NOW"),
+				tkn(lexer.ItemLPar, "("),
+				tkn(lexer.ItemRPar, ")"),
+				tkn(lexer.ItemExclusive, "exclusive"),
+			},
+			want: storage.LookupOptions{
+				LowerAnchor: at(time.Nanosecond),
+				UpperAnchor: at(-time.Nanosecond),
+			},
+		},
 		{
 			id: "before INVALID_X",
 			in: []ConsumedElement{
@@ -1932,6 +2168,24 @@ func TestCollectGlobalBounds(t *testing.T) {
 			},
 			fail: true,
 		},
+		{
+			id: "after unknown function",
+			in: []ConsumedElement{
+				tkn(lexer.ItemAfter, "after"),
+				tkn(lexer.ItemFunction, "yesterday"),
+			},
+			fail: true,
+		},
+		{
+			id: "before X with invalid duration",
+			in: []ConsumedElement{
+				tkn(lexer.ItemBefore, "before"),
+				tkn(lexer.ItemPredicate, pretty),
+				tkn(lexer.ItemMinus, "-"),
+				tkn(lexer.ItemDuration, "1y"),
+			},
+			fail: true,
+		},
 	}
 	for _, entry := range testTable {
 		st := &Statement{}
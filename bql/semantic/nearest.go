@@ -0,0 +1,203 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// NearestSearch is a similarity search requested by a NEAREST filter function
+// joined by AND at the top level of a filter expression. Any row accepted by
+// the filter binds one of the k distinct vectors most similar to the query
+// among the rows filtered.
+type NearestSearch struct {
+	// Binding is the binding holding the vectors searched.
+	Binding string
+	// Query is the vector searched, if it is a constant. Otherwise, the vector
+	// is bound to QueryBinding, usually a query parameter.
+	Query        []float64
+	QueryBinding string
+	// K is the number of distinct vectors kept.
+	K int
+}
+
+// NearestSearches returns the similarity searches that any row accepted by
+// the filter satisfies. Only the NEAREST calls on a binding joined by AND at
+// the top level of the expression are considered.
+func (f *Filter) NearestSearches() []*NearestSearch {
+	return nearestSearches(f.Evaluator)
+}
+
+// nearestSearches collects the similarity searches of the provided
+// expression.
+func nearestSearches(e Evaluator) []*NearestSearch {
+	switch n := e.(type) {
+	case *booleanNode:
+		if n.op == AND {
+			return append(nearestSearches(n.lE), nearestSearches(n.rE)...)
+		}
+	case *nearestNode:
+		b, ok := n.v.(*bindingNode)
+		if !ok {
+			return nil
+		}
+		ns := &NearestSearch{Binding: b.b, K: n.k}
+		switch q := n.q.(type) {
+		case *bindingNode:
+			ns.QueryBinding = q.b
+		case *constantNode:
+			v, ok := vector(q.c)
+			if !ok {
+				return nil
+			}
+			ns.Query = v
+		default:
+			return nil
+		}
+		return []*NearestSearch{ns}
+	}
+	return nil
+}
+
+// NeedsRanking returns true if the filter uses functions, like NEAREST, whose
+// result for a row depends on the rest of the rows filtered. Such filters
+// need the rows to be ranked before evaluating them.
+func (f *Filter) NeedsRanking() bool {
+	return len(f.nearest) > 0
+}
+
+// Rank prepares the functions of the filter whose result depends on all the
+// rows filtered for the provided rows. It needs to be called with all the
+// rows before evaluating the filter for any of them.
+func (f *Filter) Rank(rows []table.Row) {
+	for _, n := range f.nearest {
+		n.rank(rows)
+	}
+}
+
+// nearestNode checks if the vector of a row is one of the k distinct vectors
+// most similar to a query vector among all the rows filtered. Rows whose
+// vectors are tied with the k-th one are kept too.
+type nearestNode struct {
+	v, q valueNode
+	k    int
+	// min contains the lowest similarity of the rows kept for each query
+	// vector. It is nil until the rows are ranked.
+	min map[string]float64
+}
+
+// parseNearest parses a call to the NEAREST boolean function.
+func (p *filterParser) parseNearest() (Evaluator, error) {
+	fn := p.next()
+	if p.f == nil {
+		return nil, fmt.Errorf("function %q can only be used in filters", fn.Text)
+	}
+	args, err := p.parseArguments(fn)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 3 {
+		return nil, fmt.Errorf("NEAREST requires a value, a query vector, and the number of vectors to keep; got %d arguments instead", len(args))
+	}
+	kc, ok := args[2].(*constantNode)
+	if !ok || kc.c.L == nil || kc.c.L.Type() != literal.Int64 {
+		return nil, fmt.Errorf("the number of vectors to keep in NEAREST should be a constant integer")
+	}
+	k, err := kc.c.L.Int64()
+	if err != nil {
+		return nil, err
+	}
+	if k < 1 {
+		return nil, fmt.Errorf("NEAREST requires keeping at least one vector; got %d instead", k)
+	}
+	if qc, ok := args[1].(*constantNode); ok {
+		if _, ok := vector(qc.c); !ok {
+			return nil, fmt.Errorf("NEAREST query should be a vector literal; got %v instead", qc.c)
+		}
+	}
+	n := &nearestNode{v: args[0], q: args[1], k: int(k)}
+	p.f.nearest = append(p.f.nearest, n)
+	return n, nil
+}
+
+// similarity returns the cosine similarity between the vector of the row and
+// the query vector, and the text of the query vector. The boolean is false if
+// any of them is not a vector, or the similarity is undefined.
+func (n *nearestNode) similarity(r table.Row) (float64, string, bool) {
+	vc, err := n.v.value(r)
+	if err != nil {
+		return 0, "", false
+	}
+	qc, err := n.q.value(r)
+	if err != nil {
+		return 0, "", false
+	}
+	v, vok := vector(vc)
+	q, qok := vector(qc)
+	if !vok || !qok {
+		return 0, "", false
+	}
+	sim, err := literal.CosineSimilarity(v, q)
+	if err != nil {
+		return 0, "", false
+	}
+	return sim, qc.L.String(), true
+}
+
+// rank computes the lowest similarity of the rows kept for each of the query
+// vectors of the provided rows.
+func (n *nearestNode) rank(rows []table.Row) {
+	sims := make(map[string][]float64)
+	seen := make(map[string]bool)
+	for _, r := range rows {
+		sim, q, ok := n.similarity(r)
+		if !ok {
+			continue
+		}
+		// The value exists since the similarity could be computed.
+		vc, _ := n.v.value(r)
+		if k := q + "\x00" + vc.L.String(); !seen[k] {
+			seen[k] = true
+			sims[q] = append(sims[q], sim)
+		}
+	}
+	n.min = make(map[string]float64, len(sims))
+	for q, ss := range sims {
+		sort.Sort(sort.Reverse(sort.Float64Slice(ss)))
+		i := n.k - 1
+		if i >= len(ss) {
+			i = len(ss) - 1
+		}
+		n.min[q] = ss[i]
+	}
+}
+
+// Evaluate checks if the vector of the row is one of the most similar to the
+// query vector. Rows need to be ranked before evaluating them.
+func (n *nearestNode) Evaluate(r table.Row) (bool, error) {
+	if n.min == nil {
+		return false, fmt.Errorf("NEAREST cannot be evaluated before ranking the rows")
+	}
+	sim, q, ok := n.similarity(r)
+	if !ok {
+		return false, nil
+	}
+	min, ok := n.min[q]
+	return ok && sim >= min, nil
+}
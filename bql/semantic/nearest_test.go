@@ -0,0 +1,119 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+)
+
+func TestNearest(t *testing.T) {
+	vec := func(s string) *table.Cell {
+		l, err := literal.ParseCustom("vector", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &table.Cell{L: l}
+	}
+	txt, err := literal.DefaultBuilder().Build(literal.Text, "[1,0]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFilter(filterTokens(t, `nearest(?v, "[1, 0]"^^type:vector, 2)`))
+	if err != nil {
+		t.Fatalf("NewFilter failed with error %v", err)
+	}
+	if !f.NeedsRanking() {
+		t.Errorf("f.NeedsRanking() = false for a NEAREST filter; want true")
+	}
+	rows := []table.Row{
+		{"?v": vec("[0,1]")},
+		{"?v": vec("[1,0]")},
+		{"?v": vec("[-1,0]")},
+		{"?v": vec("[2,0.2]")},
+		// Repeated vectors only count once.
+		{"?v": vec("[1,0]")},
+		{"?v": vec("[1,0,0]")},
+		{"?v": {L: txt}},
+		{"?v": {}},
+	}
+	if _, err := f.Evaluate(rows[0]); err == nil {
+		t.Errorf("f.Evaluate should fail before ranking the rows")
+	}
+	f.Rank(rows)
+	var got []int
+	for i, r := range rows {
+		ok, err := f.Evaluate(r)
+		if err != nil {
+			t.Fatalf("f.Evaluate(%v) failed with error %v", r, err)
+		}
+		if ok {
+			got = append(got, i)
+		}
+	}
+	if want := []int{1, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NEAREST kept rows %v; want %v", got, want)
+	}
+
+	// Value expressions cannot use NEAREST.
+	if _, err := NewExpression(filterTokens(t, `nearest(?v, "[1,0]"^^type:vector, 2)`)); err == nil {
+		t.Errorf("NEAREST should only be available in filters")
+	}
+	for _, expr := range []string{
+		`nearest(?v, "[1,0]"^^type:vector)`,
+		`nearest(?v, "[1,0]"^^type:vector, ?k)`,
+		`nearest(?v, "[1,0]"^^type:vector, 0)`,
+		`nearest(?v, "[1,0]"^^type:vector, 1.5)`,
+		`nearest(?v, "[1,0]"^^type:text, 2)`,
+	} {
+		if _, err := NewFilter(filterTokens(t, expr)); err == nil {
+			t.Errorf("NewFilter(%q) should have failed", expr)
+		}
+	}
+}
+
+func TestNearestSearches(t *testing.T) {
+	testTable := []struct {
+		expr string
+		want []*NearestSearch
+	}{
+		{
+			expr: `nearest(?v, "[1,0]"^^type:vector, 2) AND ?x > 1`,
+			want: []*NearestSearch{{Binding: "?v", Query: []float64{1, 0}, K: 2}},
+		},
+		{
+			expr: `?x > 1 AND nearest(?v, ?q, 3)`,
+			want: []*NearestSearch{{Binding: "?v", QueryBinding: "?q", K: 3}},
+		},
+		{
+			expr: `nearest(?v, "[1,0]"^^type:vector, 2) OR ?x > 1`,
+		},
+		{
+			expr: `NOT nearest(?v, "[1,0]"^^type:vector, 2)`,
+		},
+	}
+	for _, entry := range testTable {
+		f, err := NewFilter(filterTokens(t, entry.expr))
+		if err != nil {
+			t.Fatalf("NewFilter(%q) failed with error %v", entry.expr, err)
+		}
+		if got := f.NearestSearches(); !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("NearestSearches for %q returned %+v; want %+v", entry.expr, got, entry.want)
+		}
+	}
+}
@@ -21,9 +21,11 @@ package semantic
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/badwolf/bql/lexer"
@@ -54,6 +56,16 @@ const (
 	Deconstruct
 	// Show statement.
 	Show
+	// Ask statement.
+	Ask
+	// Describe statement.
+	Describe
+	// Begin statement starting a transaction.
+	Begin
+	// Commit statement finishing a transaction.
+	Commit
+	// Rollback statement aborting a transaction.
+	Rollback
 )
 
 // String provides a readable version of the StatementType.
@@ -75,6 +87,47 @@ func (t StatementType) String() string {
 		return "DECONSTRUCT"
 	case Show:
 		return "SHOW"
+	case Ask:
+		return "ASK"
+	case Describe:
+		return "DESCRIBE"
+	case Begin:
+		return "BEGIN"
+	case Commit:
+		return "COMMIT"
+	case Rollback:
+		return "ROLLBACK"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ShowType represents the kind of catalog information a show statement
+// returns.
+type ShowType int8
+
+const (
+	// ShowGraphs lists the graphs available in the store.
+	ShowGraphs ShowType = iota
+	// ShowPredicates lists the predicate IDs used in the input graphs.
+	ShowPredicates
+	// ShowTripleCount counts the triples in the input graphs.
+	ShowTripleCount
+	// ShowStats returns the statistics of the input graphs.
+	ShowStats
+)
+
+// String provides a readable version of the ShowType.
+func (t ShowType) String() string {
+	switch t {
+	case ShowGraphs:
+		return "GRAPHS"
+	case ShowPredicates:
+		return "PREDICATES"
+	case ShowTripleCount:
+		return "TRIPLES"
+	case ShowStats:
+		return "STATS"
 	default:
 		return "UNKNOWN"
 	}
@@ -91,23 +144,47 @@ type Statement struct {
 	outputGraphs              []storage.Graph
 	data                      []*triple.Triple
 	pattern                   []*GraphClause
+	unions                    [][]*GraphClause
 	workingClause             *GraphClause
+	workingGraphBinding       string
+	filters                   []*Filter
+	unionFilters              [][]*Filter
+	workingFilter             []ConsumedElement
+	subqueries                []*Statement
+	unionSubqueries           [][]*Statement
+	parent                    *Statement
+	activeSubquery            *Statement
 	constructClauses          []*ConstructClause
 	workingConstructClause    *ConstructClause
 	projection                []*Projection
 	workingProjection         *Projection
+	workingProjectionTokens   []ConsumedElement
 	groupBy                   []string
+	groupByExpressions        map[string]*Expression
+	workingGroupByExpression  []ConsumedElement
 	orderBy                   table.SortConfig
+	orderByExpressions        map[string]*Expression
+	workingOrderByExpression  []ConsumedElement
 	havingExpression          []ConsumedElement
 	havingExpressionEvaluator Evaluator
 	limitSet                  bool
 	limit                     int64
+	offsetSet                 bool
+	offset                    int64
+	describeNode              *node.Node
+	describeDepth             int64
 	lookupOptions             storage.LookupOptions
+	parameterValues           table.Row
+	explain                   bool
+	showType                  ShowType
+	conditional               bool
+	cascade                   bool
 }
 
 // GraphClause represents a clause of a graph pattern in a where clause.
 type GraphClause struct {
-	Optional bool // This will be set to true if the clause is optional.
+	Optional     bool   // This will be set to true if the clause is optional.
+	GraphBinding string // Binding for the name of the graph matching the clause.
 
 	S          *node.Node
 	SBinding   string
@@ -127,6 +204,7 @@ type GraphClause struct {
 	PLowerBoundAlias string
 	PUpperBoundAlias string
 	PTemporal        bool
+	PPath            []*PathStep // Only set if the predicate is a path expression.
 
 	O                *triple.Object
 	OBinding         string
@@ -141,6 +219,47 @@ type GraphClause struct {
 	OLowerBoundAlias string
 	OUpperBoundAlias string
 	OTemporal        bool
+
+	// ReifiedBinding is the binding for the node reifying the triples matching
+	// the clause, as created by triple.Reify.
+	ReifiedBinding string
+}
+
+// PathModifier represents how many times the predicate of a path step can be
+// traversed.
+type PathModifier uint8
+
+const (
+	// PathOne requires the predicate to be traversed exactly once.
+	PathOne PathModifier = iota
+	// PathOneOrMore requires the predicate to be traversed at least once.
+	PathOneOrMore
+	// PathZeroOrMore allows the predicate to be traversed any number of times,
+	// including none.
+	PathZeroOrMore
+)
+
+// String returns the BQL operator of the path modifier.
+func (m PathModifier) String() string {
+	switch m {
+	case PathOneOrMore:
+		return "+"
+	case PathZeroOrMore:
+		return "*"
+	default:
+		return ""
+	}
+}
+
+// PathStep represents one of the predicates chained in a predicate path.
+type PathStep struct {
+	P        *predicate.Predicate
+	Modifier PathModifier
+}
+
+// String returns a readable representation of a path step.
+func (s *PathStep) String() string {
+	return s.P.String() + s.Modifier.String()
 }
 
 // ConstructClause represents a singular clause within a construct statement.
@@ -175,6 +294,11 @@ func (c *GraphClause) HasAlias() bool {
 		c.OTypeAlias != "" || c.OLowerBoundAlias != "" || c.OUpperBoundAlias != ""
 }
 
+// HasPath returns true if the predicate of the clause is a path expression.
+func (c *GraphClause) HasPath() bool {
+	return len(c.PPath) > 0
+}
+
 // String returns a readable representation of a graph clause.
 func (c *GraphClause) String() string {
 	b := bytes.NewBufferString("{ ")
@@ -184,6 +308,13 @@ func (c *GraphClause) String() string {
 	b.WriteString(fmt.Sprint(c.Optional))
 	b.WriteString(" ")
 
+	// Graph section.
+	if c.GraphBinding != "" {
+		b.WriteString("GRAPH ")
+		b.WriteString(c.GraphBinding)
+		b.WriteString(" ")
+	}
+
 	// Subject section.
 	if c.S != nil {
 		b.WriteString(c.S.String())
@@ -205,7 +336,16 @@ func (c *GraphClause) String() string {
 
 	// Predicate section.
 	predicate := false
-	if c.P != nil {
+	if c.HasPath() {
+		b.WriteString(" ")
+		for i, s := range c.PPath {
+			if i > 0 {
+				b.WriteString("/")
+			}
+			b.WriteString(s.String())
+		}
+		predicate = true
+	} else if c.P != nil {
 		b.WriteString(" ")
 		b.WriteString(c.P.String())
 		predicate = true
@@ -338,11 +478,53 @@ func (c *GraphClause) String() string {
 		b.WriteString(" ID ")
 		b.WriteString(c.OIDAlias)
 	}
+	if c.ReifiedBinding != "" {
+		b.WriteString(" REIFIED AS ")
+		b.WriteString(c.ReifiedBinding)
+	}
 
 	b.WriteString(" }")
 	return b.String()
 }
 
+// reifications returns the clauses matching the triples that reify the ones
+// matching the clause. Reification predicates share the time anchor of the
+// predicate of the clause, as done by triple.Reify.
+func (c *GraphClause) reifications() []*GraphClause {
+	newClause := func(id string) *GraphClause {
+		rc := &GraphClause{
+			Optional:     c.Optional,
+			GraphBinding: c.GraphBinding,
+			SBinding:     c.ReifiedBinding,
+		}
+		if c.P != nil {
+			rc.P, _ = c.P.WithID(id)
+			return rc
+		}
+		rc.PID, rc.PAnchorBinding, rc.PTemporal = id, c.PAnchorBinding, c.PTemporal
+		rc.PLowerBound, rc.PUpperBound = c.PLowerBound, c.PUpperBound
+		return rc
+	}
+
+	sc := newClause("_subject")
+	if c.S != nil {
+		sc.O = triple.NewNodeObject(c.S)
+	} else {
+		sc.OBinding = c.SBinding
+	}
+	pc := newClause("_predicate")
+	if c.P != nil {
+		pc.O = triple.NewPredicateObject(c.P)
+	} else {
+		pc.OID, pc.OAnchorBinding, pc.OTemporal = c.PID, c.PAnchorBinding, c.PTemporal
+		pc.OLowerBound, pc.OUpperBound = c.PLowerBound, c.PUpperBound
+	}
+	oc := newClause("_object")
+	oc.O, oc.OBinding, oc.OID, oc.OAnchorBinding, oc.OTemporal = c.O, c.OBinding, c.OID, c.OAnchorBinding, c.OTemporal
+	oc.OLowerBound, oc.OUpperBound = c.OLowerBound, c.OUpperBound
+	return []*GraphClause{sc, pc, oc}
+}
+
 // Specificity return
 func (c *GraphClause) Specificity() int {
 	s := 0
@@ -362,6 +544,7 @@ func (c *GraphClause) Specificity() int {
 func (c *GraphClause) BindingsMap() map[string]int {
 	bm := make(map[string]int)
 
+	addToBindings(bm, c.GraphBinding)
 	addToBindings(bm, c.SBinding)
 	addToBindings(bm, c.SAlias)
 	addToBindings(bm, c.STypeAlias)
@@ -588,9 +771,38 @@ func (s *Statement) Data() []*triple.Triple {
 	return s.data
 }
 
-// GraphPatternClauses returns the list of graph pattern clauses
+// GraphPatternClauses returns the list of graph pattern clauses. If the graph
+// pattern is a union, the clauses of all the alternatives are returned.
 func (s *Statement) GraphPatternClauses() []*GraphClause {
-	return s.pattern
+	if len(s.unions) == 0 {
+		return s.pattern
+	}
+	var cls []*GraphClause
+	for _, u := range s.GraphPatternUnions() {
+		cls = append(cls, u...)
+	}
+	return cls
+}
+
+// AddGraphPatternUnion closes the current graph pattern alternative and starts
+// a new one.
+func (s *Statement) AddGraphPatternUnion() {
+	s.AddWorkingGraphClause()
+	s.unions = append(s.unions, s.pattern)
+	s.unionFilters = append(s.unionFilters, s.filters)
+	s.unionSubqueries = append(s.unionSubqueries, s.subqueries)
+	s.pattern, s.filters, s.subqueries = nil, nil, nil
+}
+
+// GraphPatternUnions returns the list of alternative graph patterns joined by
+// UNION. It returns nil if the graph pattern is not a union.
+func (s *Statement) GraphPatternUnions() [][]*GraphClause {
+	if len(s.unions) == 0 {
+		return nil
+	}
+	res := make([][]*GraphClause, 0, len(s.unions)+1)
+	res = append(res, s.unions...)
+	return append(res, s.pattern)
 }
 
 // ResetWorkingGraphClause resets the current working graph clause.
@@ -607,11 +819,117 @@ func (s *Statement) WorkingClause() *GraphClause {
 // clauses that form the graph pattern.
 func (s *Statement) AddWorkingGraphClause() {
 	if s.workingClause != nil && !s.workingClause.IsEmpty() {
+		s.workingClause.GraphBinding = s.workingGraphBinding
 		s.pattern = append(s.pattern, s.workingClause)
+		if s.workingClause.ReifiedBinding != "" {
+			s.pattern = append(s.pattern, s.workingClause.reifications()...)
+		}
 	}
 	s.ResetWorkingGraphClause()
 }
 
+// Filters returns the list of filters of the graph pattern. If the graph
+// pattern is a union, the filters of all the alternatives are returned.
+func (s *Statement) Filters() []*Filter {
+	if len(s.unions) == 0 {
+		return s.filters
+	}
+	var fs []*Filter
+	for _, u := range s.GraphPatternUnionFilters() {
+		fs = append(fs, u...)
+	}
+	return fs
+}
+
+// GraphPatternUnionFilters returns the list of filters for each of the
+// alternative graph patterns returned by GraphPatternUnions.
+func (s *Statement) GraphPatternUnionFilters() [][]*Filter {
+	if len(s.unions) == 0 {
+		return nil
+	}
+	res := make([][]*Filter, 0, len(s.unionFilters)+1)
+	res = append(res, s.unionFilters...)
+	return append(res, s.filters)
+}
+
+// AddWorkingFilter builds the filter out of the collected filter expression
+// tokens and adds it to the current graph pattern.
+func (s *Statement) AddWorkingFilter() error {
+	f, err := NewFilter(s.workingFilter)
+	s.workingFilter = nil
+	if err != nil {
+		return err
+	}
+	s.filters = append(s.filters, f)
+	return nil
+}
+
+// Subqueries returns the list of subqueries of the graph pattern. If the graph
+// pattern is a union, the subqueries of all the alternatives are returned.
+func (s *Statement) Subqueries() []*Statement {
+	if len(s.unions) == 0 {
+		return s.subqueries
+	}
+	var sqs []*Statement
+	for _, u := range s.GraphPatternUnionSubqueries() {
+		sqs = append(sqs, u...)
+	}
+	return sqs
+}
+
+// GraphPatternUnionSubqueries returns the list of subqueries for each of the
+// alternative graph patterns returned by GraphPatternUnions.
+func (s *Statement) GraphPatternUnionSubqueries() [][]*Statement {
+	if len(s.unions) == 0 {
+		return nil
+	}
+	res := make([][]*Statement, 0, len(s.unionSubqueries)+1)
+	res = append(res, s.unionSubqueries...)
+	return append(res, s.subqueries)
+}
+
+// root returns the outermost statement.
+func (s *Statement) root() *Statement {
+	for s.parent != nil {
+		s = s.parent
+	}
+	return s
+}
+
+// ActiveStatement returns the statement currently being built. It is the
+// statement itself unless a subquery is being parsed, in which case the
+// innermost subquery is returned.
+func (s *Statement) ActiveStatement() *Statement {
+	if r := s.root(); r.activeSubquery != nil {
+		return r.activeSubquery
+	}
+	return s
+}
+
+// StartSubquery creates a new subquery nested in the current statement and
+// makes it the active statement.
+func (s *Statement) StartSubquery() {
+	s.root().activeSubquery = &Statement{
+		sType:  Query,
+		parent: s,
+	}
+}
+
+// EndSubquery adds the subquery to the graph pattern of its parent statement
+// and makes the parent the active statement again.
+func (s *Statement) EndSubquery() error {
+	if s.parent == nil {
+		return errors.New("cannot end a subquery on a statement that is not a subquery")
+	}
+	s.parent.subqueries = append(s.parent.subqueries, s)
+	r := s.root()
+	r.activeSubquery = s.parent
+	if s.parent == r {
+		r.activeSubquery = nil
+	}
+	return nil
+}
+
 // Projection returns the available projections in the statement.
 func (s *Statement) Projection() []*Projection {
 	return s.projection
@@ -649,8 +967,9 @@ func addToBindings(bs map[string]int, b string) {
 func (s *Statement) BindingsMap() map[string]int {
 	bm := make(map[string]int)
 
-	for _, cls := range s.pattern {
+	for _, cls := range s.GraphPatternClauses() {
 		if cls != nil {
+			addToBindings(bm, cls.GraphBinding)
 			addToBindings(bm, cls.SBinding)
 			addToBindings(bm, cls.SAlias)
 			addToBindings(bm, cls.STypeAlias)
@@ -672,6 +991,11 @@ func (s *Statement) BindingsMap() map[string]int {
 			addToBindings(bm, cls.OUpperBoundAlias)
 		}
 	}
+	for _, sq := range s.Subqueries() {
+		for _, b := range sq.OutputBindings() {
+			addToBindings(bm, b)
+		}
+	}
 	return bm
 }
 
@@ -685,6 +1009,86 @@ func (s *Statement) Bindings() []string {
 	return bs
 }
 
+// IsParameter returns true if the provided binding is a statement parameter.
+// Parameters are bindings starting with '$' whose values are provided when
+// the statement is executed.
+func IsParameter(b string) bool {
+	return strings.HasPrefix(b, "$")
+}
+
+// Parameters returns the sorted list of parameters used by the graph pattern
+// and filters of the statement.
+func (s *Statement) Parameters() []string {
+	pm := make(map[string]bool)
+	for b := range s.BindingsMap() {
+		if IsParameter(b) {
+			pm[b] = true
+		}
+	}
+	for _, f := range s.Filters() {
+		for _, b := range f.Bindings() {
+			if IsParameter(b) {
+				pm[b] = true
+			}
+		}
+	}
+	for _, ce := range s.havingExpression {
+		if tkn := ce.Token(); tkn != nil && tkn.Type == lexer.ItemBinding && IsParameter(tkn.Text) {
+			pm[tkn.Text] = true
+		}
+	}
+	var ps []string
+	for p := range pm {
+		ps = append(ps, p)
+	}
+	sort.Strings(ps)
+	return ps
+}
+
+// BindParameters returns a copy of the statement with the provided parameter
+// values bound. The original statement is left untouched so it can be bound
+// again with different values. Filters and subqueries are copied too, so
+// statements bound out of the same one can be executed concurrently.
+func (s *Statement) BindParameters(r table.Row) *Statement {
+	ns := *s
+	ns.parameterValues = r
+	ns.filters = copyFilters(s.filters)
+	ns.subqueries = copySubqueries(s.subqueries)
+	ns.unionFilters, ns.unionSubqueries = nil, nil
+	for _, fs := range s.unionFilters {
+		ns.unionFilters = append(ns.unionFilters, copyFilters(fs))
+	}
+	for _, sqs := range s.unionSubqueries {
+		ns.unionSubqueries = append(ns.unionSubqueries, copySubqueries(sqs))
+	}
+	return &ns
+}
+
+// copyFilters returns a copy of each of the provided filters.
+func copyFilters(fs []*Filter) []*Filter {
+	var res []*Filter
+	for _, f := range fs {
+		nf := *f
+		res = append(res, &nf)
+	}
+	return res
+}
+
+// copySubqueries returns a copy of each of the provided subqueries keeping
+// their parameter values.
+func copySubqueries(sqs []*Statement) []*Statement {
+	var res []*Statement
+	for _, sq := range sqs {
+		res = append(res, sq.BindParameters(sq.parameterValues))
+	}
+	return res
+}
+
+// ParameterValues returns the values bound to the statement parameters.
+func (s *Statement) ParameterValues() table.Row {
+	return s.parameterValues
+}
+
 // bySpecificity type helps sort clauses by Specificity.
 type bySpecificity []*GraphClause
 
@@ -707,7 +1111,7 @@ func (s bySpecificity) Less(i, j int) bool {
 func (s *Statement) SortedGraphPatternClauses() []*GraphClause {
 	var ptrns []*GraphClause
 	// Filter empty clauses.
-	for _, cls := range s.pattern {
+	for _, cls := range s.GraphPatternClauses() {
 		if cls != nil && !cls.IsEmpty() {
 			ptrns = append(ptrns, cls)
 		}
@@ -724,16 +1128,34 @@ type Projection struct {
 	Alias    string
 	OP       lexer.TokenType // The information about what function to use.
 	Modifier lexer.TokenType // The modifier for the selected op.
+	Function string          // The name of the registered accumulator to use.
+	Scalar   string          // The name of the scalar function to apply, if any.
+	// Expression computes the projected value when the projection is not a
+	// plain binding, an aggregation, or a scalar function of a binding.
+	Expression *Expression
+	// key identifies the function call computing the projection, if any.
+	key string
 }
 
 // String returns a readable form of the projection.
 func (p *Projection) String() string {
+	if p.Expression != nil {
+		return p.Expression.String() + " as " + p.Alias
+	}
 	b := bytes.NewBufferString(p.Binding)
 	b.WriteString(" as ")
 	b.WriteString(p.Binding)
+	if p.Scalar != "" {
+		b.WriteString(" via ")
+		b.WriteString(p.Scalar)
+	}
 	if p.OP != lexer.ItemError {
 		b.WriteString(" via ")
 		b.WriteString(p.OP.String())
+		if p.Function != "" {
+			b.WriteString(" ")
+			b.WriteString(p.Function)
+		}
 		if p.Modifier != lexer.ItemError {
 			b.WriteString(" ")
 			b.WriteString(p.Modifier.String())
@@ -744,7 +1166,7 @@ func (p *Projection) String() string {
 
 // IsEmpty checks if the given projection is empty.
 func (p *Projection) IsEmpty() bool {
-	return p.Binding == "" && p.Alias == "" && p.OP == lexer.ItemError && p.Modifier == lexer.ItemError
+	return p.Binding == "" && p.Alias == "" && p.OP == lexer.ItemError && p.Modifier == lexer.ItemError && p.Function == "" && p.Scalar == "" && p.Expression == nil
 }
 
 // ResetProjection resets the current working variable projection.
@@ -760,6 +1182,61 @@ func (s *Statement) WorkingProjection() *Projection {
 	return s.workingProjection
 }
 
+// addWorkingProjectionToken collects a token of the function call used by the
+// working projection.
+func (s *Statement) addWorkingProjectionToken(ce ConsumedElement) {
+	s.workingProjectionTokens = append(s.workingProjectionTokens, ce)
+}
+
+// resolveWorkingProjection decides how the function call collected for the
+// working projection is computed. Calls of a registered accumulator or a
+// single argument scalar function on a binding are kept as such. Any other
+// call is built as an expression.
+func (s *Statement) resolveWorkingProjection() error {
+	ces, p := s.workingProjectionTokens, s.WorkingProjection()
+	s.workingProjectionTokens = nil
+	if len(ces) == 0 {
+		return nil
+	}
+	p.key = expressionKey(ces)
+	if len(ces) == 4 && !ces[2].IsSymbol() && ces[2].Token().Type == lexer.ItemBinding {
+		fn, arg := ces[0].Token(), ces[2].Token().Text
+		if _, ok := table.LookupAccumulator(fn.Text); ok && fn.Type == lexer.ItemFunction {
+			p.OP, p.Function, p.Binding = fn.Type, fn.Text, arg
+			return nil
+		}
+		if f, ok := lookupValueFunction(fn.Text); ok && (f.arity < 0 || f.arity == 1) {
+			p.Scalar, p.Binding = strings.ToLower(fn.Text), arg
+			return nil
+		}
+	}
+	e, err := NewExpression(ces)
+	if err != nil {
+		return err
+	}
+	p.Expression = e
+	return nil
+}
+
+// expressionKey returns the text identifying the expression formed by the
+// provided tokens. Function names are case insensitive.
+func expressionKey(ces []ConsumedElement) string {
+	var txt []string
+	for _, ce := range ces {
+		if ce.IsSymbol() {
+			continue
+		}
+		tkn := ce.Token()
+		switch tkn.Type {
+		case lexer.ItemFunction, lexer.ItemType, lexer.ItemID, lexer.ItemIf:
+			txt = append(txt, strings.ToLower(tkn.Text))
+		default:
+			txt = append(txt, tkn.Text)
+		}
+	}
+	return strings.Join(txt, " ")
+}
+
 // AddWorkingProjection adds the current projection variable to the set of
 // projects that this statement.
 func (s *Statement) AddWorkingProjection() {
@@ -781,6 +1258,9 @@ func (s *Statement) InputBindings() []string {
 		if p.Binding != "" {
 			res = append(res, p.Binding)
 		}
+		if p.Expression != nil {
+			res = append(res, p.Expression.Bindings()...)
+		}
 	}
 	for _, c := range s.constructClauses {
 		if c.SBinding != "" {
@@ -856,6 +1336,61 @@ func (s *Statement) OrderByConfig() table.SortConfig {
 	return s.orderBy
 }
 
+// OrderByExpressions returns the expressions used in the order by statement
+// indexed by the binding used for them in the sort configuration.
+func (s *Statement) OrderByExpressions() map[string]*Expression {
+	return s.orderByExpressions
+}
+
+// GroupByExpressions returns the expressions used as grouping keys that are
+// not computed by any projection, indexed by the binding used for them in the
+// group by bindings.
+func (s *Statement) GroupByExpressions() map[string]*Expression {
+	return s.groupByExpressions
+}
+
+// AddWorkingGroupByExpression builds the expression out of the collected
+// group by expression tokens and appends it to the group by bindings. The
+// expression text is used as its binding.
+func (s *Statement) AddWorkingGroupByExpression() error {
+	if len(s.workingGroupByExpression) == 0 {
+		return nil
+	}
+	ces := s.workingGroupByExpression
+	s.workingGroupByExpression = nil
+	e, err := NewExpression(ces)
+	if err != nil {
+		return err
+	}
+	if s.groupByExpressions == nil {
+		s.groupByExpressions = make(map[string]*Expression)
+	}
+	k := expressionKey(ces)
+	s.groupByExpressions[k] = e
+	s.groupBy = append(s.groupBy, k)
+	return nil
+}
+
+// AddWorkingOrderByExpression builds the expression out of the collected
+// order by expression tokens and appends it to the sort configuration. The
+// expression text is used as its binding in the sort configuration.
+func (s *Statement) AddWorkingOrderByExpression() error {
+	if len(s.workingOrderByExpression) == 0 {
+		return nil
+	}
+	e, err := NewExpression(s.workingOrderByExpression)
+	s.workingOrderByExpression = nil
+	if err != nil {
+		return err
+	}
+	if s.orderByExpressions == nil {
+		s.orderByExpressions = make(map[string]*Expression)
+	}
+	s.orderByExpressions[e.String()] = e
+	s.orderBy = append(s.orderBy, table.SortConfig{{Binding: e.String()}}...)
+	return nil
+}
+
 // HasHavingClause returns true if there is a having clause.
 func (s *Statement) HasHavingClause() bool {
 	return len(s.havingExpression) > 0
@@ -877,6 +1412,51 @@ func (s *Statement) Limit() int64 {
 	return s.limit
 }
 
+// DescribeNode returns the node to describe in a describe statement.
+func (s *Statement) DescribeNode() *node.Node {
+	return s.describeNode
+}
+
+// DescribeDepth returns the number of hops around the described node to
+// include in a describe statement.
+func (s *Statement) DescribeDepth() int64 {
+	return s.describeDepth
+}
+
+// IsConditional returns true if a create statement should skip the graphs
+// that already exist, or a drop statement should skip the graphs that do not
+// exist.
+func (s *Statement) IsConditional() bool {
+	return s.conditional
+}
+
+// IsCascade returns true if a drop statement should also drop the graphs
+// derived from the dropped ones.
+func (s *Statement) IsCascade() bool {
+	return s.cascade
+}
+
+// ShowType returns the kind of catalog information a show statement returns.
+func (s *Statement) ShowType() ShowType {
+	return s.showType
+}
+
+// IsExplain returns true if the statement should only be explained instead of
+// executed.
+func (s *Statement) IsExplain() bool {
+	return s.explain
+}
+
+// IsOffsetSet returns true if the offset is set.
+func (s *Statement) IsOffsetSet() bool {
+	return s.offsetSet
+}
+
+// Offset returns the offset value set in the offset clause.
+func (s *Statement) Offset() int64 {
+	return s.offset
+}
+
 // GlobalLookupOptions returns the global lookup options available in the
 // statement.
 func (s *Statement) GlobalLookupOptions() *storage.LookupOptions {
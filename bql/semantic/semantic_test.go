@@ -18,6 +18,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/google/badwolf/bql/table"
 	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
 	"github.com/google/badwolf/triple/node"
@@ -38,6 +39,8 @@ func TestStamentTypeString(t *testing.T) {
 		{Construct, "CONSTRUCT"},
 		{Deconstruct, "DECONSTRUCT"},
 		{Show, "SHOW"},
+		{Ask, "ASK"},
+		{Describe, "DESCRIBE"},
 		{StatementType(-1), "UNKNOWN"},
 	}
 
@@ -211,6 +214,91 @@ func TestSortedGraphPatternClauses(t *testing.T) {
 	}
 }
 
+func TestGraphPatternUnions(t *testing.T) {
+	s := &Statement{}
+	if got := s.GraphPatternUnions(); got != nil {
+		t.Errorf("statement.GraphPatternUnions should return nil for statements without unions; got %v", got)
+	}
+	s.ResetWorkingGraphClause()
+	s.WorkingClause().SBinding = "?a"
+	s.AddWorkingGraphClause()
+	s.WorkingClause().SBinding = "?b"
+	s.AddGraphPatternUnion()
+	s.WorkingClause().SBinding = "?c"
+	s.AddWorkingGraphClause()
+	us := s.GraphPatternUnions()
+	if got, want := len(us), 2; got != want {
+		t.Fatalf("statement.GraphPatternUnions returned the wrong number of alternatives; got %d, want %d", got, want)
+	}
+	if got, want := len(us[0]), 2; got != want {
+		t.Errorf("statement.GraphPatternUnions returned the wrong number of clauses for the first alternative; got %d, want %d", got, want)
+	}
+	if got, want := len(us[1]), 1; got != want {
+		t.Errorf("statement.GraphPatternUnions returned the wrong number of clauses for the second alternative; got %d, want %d", got, want)
+	}
+	if got, want := len(s.GraphPatternClauses()), 3; got != want {
+		t.Errorf("statement.GraphPatternClauses returned the wrong number of clauses; got %d, want %d", got, want)
+	}
+	bs := s.BindingsMap()
+	for _, b := range []string{"?a", "?b", "?c"} {
+		if _, ok := bs[b]; !ok {
+			t.Errorf("statement.BindingsMap should contain binding %q; got %v", b, bs)
+		}
+	}
+}
+
+func TestSubqueries(t *testing.T) {
+	s := &Statement{}
+	if got, want := s.ActiveStatement(), s; got != want {
+		t.Errorf("statement.ActiveStatement should return the statement itself; got %v, want %v", got, want)
+	}
+	s.StartSubquery()
+	sq := s.ActiveStatement()
+	if sq == s {
+		t.Fatal("statement.StartSubquery should have made the new subquery the active statement")
+	}
+	sq.StartSubquery()
+	nsq := s.ActiveStatement()
+	if nsq == sq || nsq == s {
+		t.Fatal("statement.StartSubquery should have made the nested subquery the active statement")
+	}
+	nsq.WorkingProjection().Binding = "?n"
+	nsq.AddWorkingProjection()
+	if err := nsq.EndSubquery(); err != nil {
+		t.Fatalf("statement.EndSubquery failed with error %v", err)
+	}
+	if got, want := s.ActiveStatement(), sq; got != want {
+		t.Errorf("statement.EndSubquery should have restored the parent as active statement; got %v, want %v", got, want)
+	}
+	sq.WorkingProjection().Binding = "?a"
+	sq.AddWorkingProjection()
+	if err := sq.EndSubquery(); err != nil {
+		t.Fatalf("statement.EndSubquery failed with error %v", err)
+	}
+	if got, want := s.ActiveStatement(), s; got != want {
+		t.Errorf("statement.EndSubquery should have restored the outer statement as active; got %v, want %v", got, want)
+	}
+	if got, want := s.Subqueries(), []*Statement{sq}; !reflect.DeepEqual(got, want) {
+		t.Errorf("statement.Subqueries returned the wrong subqueries; got %v, want %v", got, want)
+	}
+	if got, want := sq.Subqueries(), []*Statement{nsq}; !reflect.DeepEqual(got, want) {
+		t.Errorf("statement.Subqueries returned the wrong nested subqueries; got %v, want %v", got, want)
+	}
+	if _, ok := s.BindingsMap()["?a"]; !ok {
+		t.Errorf("statement.BindingsMap should contain the subquery output binding ?a; got %v", s.BindingsMap())
+	}
+	if err := s.EndSubquery(); err == nil {
+		t.Error("statement.EndSubquery should fail for statements that are not subqueries")
+	}
+	s.AddGraphPatternUnion()
+	if got, want := len(s.GraphPatternUnionSubqueries()), 2; got != want {
+		t.Fatalf("statement.GraphPatternUnionSubqueries returned the wrong number of alternatives; got %d, want %d", got, want)
+	}
+	if got, want := s.Subqueries(), []*Statement{sq}; !reflect.DeepEqual(got, want) {
+		t.Errorf("statement.Subqueries returned the wrong subqueries for a union; got %v, want %v", got, want)
+	}
+}
+
 func TestProjectionIsEmpty(t *testing.T) {
 	s := &Statement{}
 	s.ResetProjection()
@@ -380,3 +468,27 @@ func TestHasAlias(t *testing.T) {
 		}
 	}
 }
+
+func TestStatementParameters(t *testing.T) {
+	s := &Statement{}
+	s.ResetWorkingGraphClause()
+	s.WorkingClause().SBinding = "$who"
+	s.WorkingClause().PBinding = "?p"
+	s.WorkingClause().OBinding = "$what"
+	s.AddWorkingGraphClause()
+	if got, want := s.Parameters(), []string{"$what", "$who"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("s.Parameters returned the wrong parameters; got %v, want %v", got, want)
+	}
+	n, err := node.Parse("/u<joe>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := table.Row{"$who": &table.Cell{N: n}}
+	bs := s.BindParameters(r)
+	if got := bs.ParameterValues(); !reflect.DeepEqual(got, r) {
+		t.Errorf("bs.ParameterValues returned the wrong values; got %v, want %v", got, r)
+	}
+	if got := s.ParameterValues(); got != nil {
+		t.Errorf("BindParameters should not modify the original statement; got values %v", got)
+	}
+}
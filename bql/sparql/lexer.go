@@ -0,0 +1,259 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sparql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenType is the type of the lexical tokens of SPARQL queries.
+type tokenType int
+
+const (
+	// tokenEOF marks the end of the query.
+	tokenEOF tokenType = iota
+	// tokenIRI is an IRI reference written between angle brackets. Its text
+	// is the IRI.
+	tokenIRI
+	// tokenPName is a prefixed name, as in foaf:name.
+	tokenPName
+	// tokenVar is a variable, as in ?name or $name. Its text is the name.
+	tokenVar
+	// tokenBlank is a blank node label, as in _:b0. Its text is the label.
+	tokenBlank
+	// tokenString is a string. Its text is the unescaped value.
+	tokenString
+	// tokenLang is the language tag of a string, as in @en.
+	tokenLang
+	// tokenNumber is a numeric literal, as written.
+	tokenNumber
+	// tokenWord is a keyword, a function name, or any of a, true, and false.
+	tokenWord
+	// tokenPunct is a punctuation mark or an operator.
+	tokenPunct
+)
+
+// token is a lexical token of a SPARQL query.
+type token struct {
+	typ  tokenType
+	text string
+	pos  int
+}
+
+// puncts are the punctuation marks and operators, longest first.
+var puncts = []string{"^^", "&&", "||", "!=", "<=", ">=", "{", "}", "(", ")", "[", "]", ".", ";", ",", "*", "=", "<", ">", "!", "+", "-", "/", "|", "^"}
+
+// lex splits the query into tokens.
+func lex(q string) ([]token, error) {
+	var toks []token
+	for i := 0; ; {
+		for i < len(q) {
+			r, n := utf8.DecodeRuneInString(q[i:])
+			if r == '#' {
+				for i < len(q) && q[i] != '\n' {
+					i++
+				}
+				continue
+			}
+			if !unicode.IsSpace(r) {
+				break
+			}
+			i += n
+		}
+		if i >= len(q) {
+			return append(toks, token{typ: tokenEOF, pos: i}), nil
+		}
+		tkn, n, err := lexToken(q[i:])
+		if err != nil {
+			return nil, fmt.Errorf("sparql: %v at offset %d", err, i)
+		}
+		tkn.pos = i
+		toks = append(toks, tkn)
+		i += n
+	}
+}
+
+// lexToken returns the token at the beginning of s and its length.
+func lexToken(s string) (token, int, error) {
+	c := s[0]
+	switch {
+	case c == '<':
+		// An IRI cannot contain spaces, so anything else is an operator.
+		if end := strings.IndexAny(s[1:], "<>\"{}|^` \t\r\n"); end >= 0 && s[1+end] == '>' {
+			return token{typ: tokenIRI, text: s[1 : 1+end]}, end + 2, nil
+		}
+	case c == '?' || c == '$':
+		n := nameLength(s[1:])
+		if n == 0 {
+			return token{}, 0, fmt.Errorf("invalid variable name")
+		}
+		return token{typ: tokenVar, text: s[1 : 1+n]}, n + 1, nil
+	case strings.HasPrefix(s, "_:"):
+		n := nameLength(s[2:])
+		if n == 0 {
+			return token{}, 0, fmt.Errorf("invalid blank node label")
+		}
+		return token{typ: tokenBlank, text: s[2 : 2+n]}, n + 2, nil
+	case c == '"' || c == '\'':
+		return lexString(s)
+	case c == '@':
+		n := 1
+		for n < len(s) && (isLetter(s[n]) || s[n] >= '0' && s[n] <= '9' || s[n] == '-') {
+			n++
+		}
+		if n == 1 {
+			return token{}, 0, fmt.Errorf("invalid language tag")
+		}
+		return token{typ: tokenLang, text: s[1:n]}, n, nil
+	case c >= '0' && c <= '9' || c == '.' && len(s) > 1 && s[1] >= '0' && s[1] <= '9':
+		return lexNumber(s)
+	}
+	if r, _ := utf8.DecodeRuneInString(s); unicode.IsLetter(r) || c == ':' {
+		n := nameLength(s)
+		if n < len(s) && s[n] == ':' {
+			// Prefixed names are formed by the prefix, the colon and the
+			// local name, which may contain dots but not end with one.
+			l := n + 1
+			for l < len(s) {
+				r, rn := utf8.DecodeRuneInString(s[l:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_-.:%", r) {
+					break
+				}
+				l += rn
+			}
+			for l > n+1 && s[l-1] == '.' {
+				l--
+			}
+			return token{typ: tokenPName, text: s[:l]}, l, nil
+		}
+		return token{typ: tokenWord, text: s[:n]}, n, nil
+	}
+	for _, p := range puncts {
+		if strings.HasPrefix(s, p) {
+			return token{typ: tokenPunct, text: p}, len(p), nil
+		}
+	}
+	return token{}, 0, fmt.Errorf("unexpected character %q", c)
+}
+
+// isLetter returns true if c is an ASCII letter.
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// nameLength returns the length of the name formed by the letters, digits,
+// and underscores at the beginning of s.
+func nameLength(s string) int {
+	n := 0
+	for n < len(s) {
+		r, rn := utf8.DecodeRuneInString(s[n:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			break
+		}
+		n += rn
+	}
+	return n
+}
+
+// lexString lexes the string at the beginning of s, delimited by single or
+// double quotes, or by three of them for long strings.
+func lexString(s string) (token, int, error) {
+	delim := s[:1]
+	if strings.HasPrefix(s, strings.Repeat(delim, 3)) {
+		delim = s[:3]
+	}
+	var b strings.Builder
+	for i := len(delim); i < len(s); {
+		if strings.HasPrefix(s[i:], delim) {
+			return token{typ: tokenString, text: b.String()}, i + len(delim), nil
+		}
+		c := s[i]
+		if (c == '\n' || c == '\r') && len(delim) == 1 {
+			break
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		if i+1 >= len(s) {
+			break
+		}
+		switch e := s[i+1]; e {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case '"', '\'', '\\':
+			b.WriteByte(e)
+		case 'u', 'U':
+			n := 4
+			if e == 'U' {
+				n = 8
+			}
+			if i+2+n > len(s) {
+				return token{}, 0, fmt.Errorf("invalid escape sequence in string")
+			}
+			r, err := strconv.ParseUint(s[i+2:i+2+n], 16, 32)
+			if err != nil {
+				return token{}, 0, fmt.Errorf("invalid escape sequence in string")
+			}
+			b.WriteRune(rune(r))
+			i += n
+		default:
+			return token{}, 0, fmt.Errorf("invalid escape sequence \\%c in string", e)
+		}
+		i += 2
+	}
+	return token{}, 0, fmt.Errorf("string is not terminated with %s", delim)
+}
+
+// lexNumber lexes the integer, decimal, or double at the beginning of s.
+func lexNumber(s string) (token, int, error) {
+	n := 0
+	digits := func() {
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+	}
+	digits()
+	// A dot not followed by a digit ends the triple instead.
+	if n+1 < len(s) && s[n] == '.' && s[n+1] >= '0' && s[n+1] <= '9' {
+		n++
+		digits()
+	}
+	if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+		n++
+		if n < len(s) && (s[n] == '+' || s[n] == '-') {
+			n++
+		}
+		start := n
+		digits()
+		if n == start {
+			return token{}, 0, fmt.Errorf("invalid exponent in number")
+		}
+	}
+	return token{typ: tokenNumber, text: s[:n]}, n, nil
+}
@@ -0,0 +1,704 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sparql translates SPARQL 1.1 queries into BQL statements, so tools
+// speaking SPARQL can query BadWolf graphs.
+//
+// Only the subset of SPARQL with a BQL counterpart is supported: SELECT and ASK
+// queries, with PREFIX and BASE declarations, FROM clauses, basic graph
+// patterns, OPTIONAL, UNION and FILTER, the DISTINCT modifier, and ORDER BY,
+// LIMIT and OFFSET. RDF terms are mapped to BadWolf nodes, predicates, and
+// literals as done when reading N-Triples, so IRIs in the predicate position
+// stand for immutable predicates whose ID is the IRI, and the rest for IRI
+// nodes. Queries using any other feature fail to translate.
+package sparql
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	bwio "github.com/google/badwolf/io"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// ErrNoDataset is returned by Translate when the query does not name the
+// graphs to query and none were provided.
+var ErrNoDataset = errors.New("sparql: no graph to query; use a FROM clause or provide the default graphs")
+
+// rdfType is the IRI of the predicate abbreviated by the a keyword.
+const rdfType = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+
+// Query is a SPARQL query translated into BQL.
+type Query struct {
+	// BQL is the BQL statement equivalent to the query.
+	BQL string
+
+	// Ask is true for ASK queries, whose result is the ?ask binding of the
+	// single row of the BQL results.
+	Ask bool
+}
+
+// translator keeps the state of the translation of a query.
+type translator struct {
+	toks     []token
+	pos      int
+	base     *url.URL
+	prefixes map[string]string
+	vars     []string
+	seen     map[string]bool
+	blanks   map[string]string
+}
+
+// Translate returns the BQL statement equivalent to the provided SPARQL
+// query. The graphs queried are the ones named in the FROM clauses, unless the
+// graphs are provided, as the default graphs of the SPARQL protocol do. Graphs
+// are named by their IRIs, which stand for the graphs named after them, or
+// for the graphs they name if they start with io.GraphIRIPrefix, as done for
+// N-Quads.
+func Translate(q string, graphs []string) (*Query, error) {
+	toks, err := lex(q)
+	if err != nil {
+		return nil, err
+	}
+	t := &translator{
+		toks:     toks,
+		prefixes: make(map[string]string),
+		seen:     make(map[string]bool),
+		blanks:   make(map[string]string),
+	}
+	return t.query(graphs)
+}
+
+// peek returns the current token.
+func (t *translator) peek() token {
+	return t.toks[t.pos]
+}
+
+// next returns the current token and moves to the following one.
+func (t *translator) next() token {
+	tkn := t.toks[t.pos]
+	if tkn.typ != tokenEOF {
+		t.pos++
+	}
+	return tkn
+}
+
+// keyword consumes the current token if it is the provided keyword.
+func (t *translator) keyword(k string) bool {
+	if tkn := t.peek(); tkn.typ == tokenWord && strings.EqualFold(tkn.text, k) {
+		t.pos++
+		return true
+	}
+	return false
+}
+
+// punct consumes the current token if it is the provided punctuation mark.
+func (t *translator) punct(p string) bool {
+	if tkn := t.peek(); tkn.typ == tokenPunct && tkn.text == p {
+		t.pos++
+		return true
+	}
+	return false
+}
+
+// errorf returns an error locating the current token.
+func (t *translator) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("sparql: %s at offset %d", fmt.Sprintf(format, args...), t.peek().pos)
+}
+
+// expect consumes the provided punctuation mark, failing if it is not the
+// current token.
+func (t *translator) expect(p string) error {
+	if !t.punct(p) {
+		return t.errorf("expected %q, got %q", p, t.peek().text)
+	}
+	return nil
+}
+
+// query translates the whole query.
+func (t *translator) query(graphs []string) (*Query, error) {
+	if err := t.prologue(); err != nil {
+		return nil, err
+	}
+	var (
+		ask, distinct, star bool
+		proj                []string
+	)
+	switch {
+	case t.keyword("SELECT"):
+		distinct = t.keyword("DISTINCT")
+		t.keyword("REDUCED")
+		star = t.punct("*")
+		for !star && t.peek().typ == tokenVar {
+			proj = append(proj, "?"+t.next().text)
+		}
+		if !star && len(proj) == 0 {
+			return nil, t.errorf("expected the variables to select; expressions are not supported")
+		}
+	case t.keyword("ASK"):
+		ask = true
+	default:
+		return nil, t.errorf("only SELECT and ASK queries are supported")
+	}
+
+	var from []string
+	for t.keyword("FROM") {
+		if t.keyword("NAMED") {
+			return nil, t.errorf("named graphs are not supported")
+		}
+		iri, err := t.iri()
+		if err != nil {
+			return nil, err
+		}
+		from = append(from, iri)
+	}
+	if graphs == nil {
+		graphs = from
+	}
+	if len(graphs) == 0 {
+		return nil, ErrNoDataset
+	}
+	var names []string
+	for _, g := range graphs {
+		n, err := graphName(g)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+
+	t.keyword("WHERE")
+	where, err := t.group()
+	if err != nil {
+		return nil, err
+	}
+	if star {
+		proj = t.vars
+		if len(proj) == 0 {
+			return nil, fmt.Errorf("sparql: the query has no variables to select")
+		}
+	}
+
+	var b strings.Builder
+	if ask {
+		fmt.Fprintf(&b, "ASK FROM %s WHERE { %s }", strings.Join(names, ", "), where)
+	} else {
+		fmt.Fprintf(&b, "SELECT %s FROM %s WHERE { %s }", strings.Join(proj, ", "), strings.Join(names, ", "), where)
+		if distinct {
+			// BQL removes duplicated rows by grouping them.
+			fmt.Fprintf(&b, " GROUP BY %s", strings.Join(proj, ", "))
+		}
+		mods, err := t.modifiers()
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(mods)
+	}
+	if tkn := t.peek(); tkn.typ != tokenEOF {
+		return nil, t.errorf("unexpected %q", tkn.text)
+	}
+	b.WriteString(";")
+	return &Query{BQL: b.String(), Ask: ask}, nil
+}
+
+// prologue reads the BASE and PREFIX declarations.
+func (t *translator) prologue() error {
+	for {
+		switch {
+		case t.keyword("BASE"):
+			iri, err := t.iri()
+			if err != nil {
+				return err
+			}
+			if t.base, err = url.Parse(iri); err != nil {
+				return t.errorf("invalid base IRI %q", iri)
+			}
+		case t.keyword("PREFIX"):
+			tkn := t.next()
+			if tkn.typ != tokenPName || !strings.HasSuffix(tkn.text, ":") {
+				return t.errorf("expected a prefix label, got %q", tkn.text)
+			}
+			iri, err := t.iri()
+			if err != nil {
+				return err
+			}
+			t.prefixes[strings.TrimSuffix(tkn.text, ":")] = iri
+		default:
+			return nil
+		}
+	}
+}
+
+// iri reads an IRI reference or a prefixed name, and returns the IRI.
+func (t *translator) iri() (string, error) {
+	tkn := t.next()
+	switch tkn.typ {
+	case tokenIRI:
+		if t.base == nil {
+			return tkn.text, nil
+		}
+		u, err := url.Parse(tkn.text)
+		if err != nil {
+			return "", fmt.Errorf("sparql: invalid IRI %q; %v", tkn.text, err)
+		}
+		return t.base.ResolveReference(u).String(), nil
+	case tokenPName:
+		i := strings.Index(tkn.text, ":")
+		ns, ok := t.prefixes[tkn.text[:i]]
+		if !ok {
+			return "", fmt.Errorf("sparql: prefix %q of %q is not declared", tkn.text[:i], tkn.text)
+		}
+		return ns + tkn.text[i+1:], nil
+	}
+	return "", fmt.Errorf("sparql: expected an IRI at offset %d, got %q", tkn.pos, tkn.text)
+}
+
+// graphName returns the BQL name of the graph standing for the provided IRI.
+func graphName(iri string) (string, error) {
+	name := iri
+	if strings.HasPrefix(iri, bwio.GraphIRIPrefix) {
+		var err error
+		if name, err = url.PathUnescape(iri[len(bwio.GraphIRIPrefix):]); err != nil {
+			return "", fmt.Errorf("sparql: invalid graph IRI %q; %v", iri, err)
+		}
+	}
+	if !IsBQLGraphName(name) {
+		return "", fmt.Errorf("sparql: graph %q cannot be queried since its name is not a BQL binding", name)
+	}
+	return name, nil
+}
+
+// IsBQLGraphName returns true if the provided graph name can be used in BQL
+// statements, which name graphs using bindings.
+func IsBQLGraphName(name string) bool {
+	if len(name) < 2 || name[0] != '?' {
+		return false
+	}
+	for _, r := range name[1:] {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// group translates the group graph pattern at the current position into the
+// BQL clauses it contains, without the enclosing brackets.
+func (t *translator) group() (string, error) {
+	if err := t.expect("{"); err != nil {
+		return "", err
+	}
+	var clauses []string
+	for !t.punct("}") {
+		tkn := t.peek()
+		switch {
+		case tkn.typ == tokenEOF:
+			return "", t.errorf("group graph pattern is not terminated with }")
+		case t.punct("."):
+		case t.keyword("OPTIONAL"):
+			g, err := t.group()
+			if err != nil {
+				return "", err
+			}
+			clauses = append(clauses, "OPTIONAL { "+g+" }")
+		case t.keyword("FILTER"):
+			f, err := t.filter()
+			if err != nil {
+				return "", err
+			}
+			clauses = append(clauses, "FILTER("+f+")")
+		case tkn.typ == tokenPunct && tkn.text == "{":
+			g, err := t.group()
+			if err != nil {
+				return "", err
+			}
+			alts := []string{"{ " + g + " }"}
+			for t.keyword("UNION") {
+				if g, err = t.group(); err != nil {
+					return "", err
+				}
+				alts = append(alts, "{ "+g+" }")
+			}
+			if len(alts) == 1 {
+				// Nested groups without alternatives are just part of the
+				// enclosing one.
+				if g != "" {
+					clauses = append(clauses, g)
+				}
+				continue
+			}
+			clauses = append(clauses, strings.Join(alts, " UNION "))
+		case tkn.typ == tokenWord && !strings.EqualFold(tkn.text, "a") && !strings.EqualFold(tkn.text, "true") && !strings.EqualFold(tkn.text, "false"):
+			return "", t.errorf("%s is not supported", strings.ToUpper(tkn.text))
+		default:
+			cs, err := t.triples()
+			if err != nil {
+				return "", err
+			}
+			clauses = append(clauses, cs...)
+		}
+	}
+	return strings.Join(clauses, " . "), nil
+}
+
+// triples translates the triple patterns sharing the subject at the current
+// position, including the ones abbreviated using ; and ,.
+func (t *translator) triples() ([]string, error) {
+	s, err := t.term(false)
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for {
+		p, err := t.verb()
+		if err != nil {
+			return nil, err
+		}
+		for {
+			o, err := t.term(true)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, s+" "+p+" "+o)
+			if !t.punct(",") {
+				break
+			}
+		}
+		if !t.punct(";") {
+			return res, nil
+		}
+		// A trailing ; may end the triples.
+		if tkn := t.peek(); tkn.typ == tokenPunct && (tkn.text == "." || tkn.text == "}") {
+			return res, nil
+		}
+	}
+}
+
+// variable returns the BQL binding for the provided SPARQL variable, keeping
+// track of the order in which they appear.
+func (t *translator) variable(name string) string {
+	b := "?" + name
+	if !t.seen[b] {
+		t.seen[b] = true
+		t.vars = append(t.vars, b)
+	}
+	return b
+}
+
+// blank returns the BQL binding standing for the provided blank node label.
+// Blank nodes in queries behave as variables which cannot be selected.
+func (t *translator) blank(label string) string {
+	b, ok := t.blanks[label]
+	if !ok {
+		b = fmt.Sprintf("?_blank%d", len(t.blanks))
+		t.blanks[label] = b
+	}
+	return b
+}
+
+// verb translates the predicate of a triple pattern.
+func (t *translator) verb() (string, error) {
+	var p string
+	switch tkn := t.peek(); {
+	case tkn.typ == tokenVar:
+		p = t.variable(t.next().text)
+	case tkn.typ == tokenWord && tkn.text == "a":
+		t.next()
+		pr, err := triple.ParsePredicateTerm("<" + rdfType + ">")
+		if err != nil {
+			return "", err
+		}
+		p = pr.String()
+	case tkn.typ == tokenIRI || tkn.typ == tokenPName:
+		iri, err := t.iri()
+		if err != nil {
+			return "", err
+		}
+		pr, err := triple.ParsePredicateTerm("<" + iri + ">")
+		if err != nil {
+			return "", fmt.Errorf("sparql: invalid predicate %q; %v", iri, err)
+		}
+		p = pr.String()
+	default:
+		return "", t.errorf("expected a predicate, got %q", tkn.text)
+	}
+	if tkn := t.peek(); tkn.typ == tokenPunct && strings.Contains("/|^*+?", tkn.text) {
+		return "", t.errorf("property paths are not supported")
+	}
+	return p, nil
+}
+
+// term translates the subject, or the object, of a triple pattern.
+func (t *translator) term(object bool) (string, error) {
+	tkn := t.peek()
+	switch tkn.typ {
+	case tokenVar:
+		return t.variable(t.next().text), nil
+	case tokenBlank:
+		return t.blank(t.next().text), nil
+	case tokenIRI, tokenPName:
+		iri, err := t.iri()
+		if err != nil {
+			return "", err
+		}
+		return objectTerm("<" + iri + ">")
+	case tokenString, tokenNumber, tokenWord:
+		if !object {
+			return "", t.errorf("literals cannot be the subject of a triple")
+		}
+		nt, err := t.literal()
+		if err != nil {
+			return "", err
+		}
+		return objectTerm(nt)
+	case tokenPunct:
+		if tkn.text == "[" || tkn.text == "(" {
+			return "", t.errorf("blank node property lists and collections are not supported")
+		}
+	}
+	return "", t.errorf("expected a node or a literal, got %q", tkn.text)
+}
+
+// literal reads the literal at the current position, and returns its
+// N-Triples term.
+func (t *translator) literal() (string, error) {
+	tkn := t.next()
+	switch tkn.typ {
+	case tokenString:
+		v := `"` + quote(tkn.text) + `"`
+		if t.punct("^^") {
+			dt, err := t.iri()
+			if err != nil {
+				return "", err
+			}
+			return v + "^^<" + dt + ">", nil
+		}
+		if t.peek().typ == tokenLang {
+			return v + "@" + t.next().text, nil
+		}
+		return v, nil
+	case tokenNumber:
+		dt := "integer"
+		switch {
+		case strings.ContainsAny(tkn.text, "eE"):
+			dt = "double"
+		case strings.Contains(tkn.text, "."):
+			dt = "decimal"
+		}
+		return `"` + tkn.text + `"^^<` + triple.XSD + dt + ">", nil
+	case tokenWord:
+		if v := strings.ToLower(tkn.text); v == "true" || v == "false" {
+			return `"` + v + `"^^<` + triple.XSD + "boolean>", nil
+		}
+	}
+	return "", fmt.Errorf("sparql: expected a literal at offset %d, got %q", tkn.pos, tkn.text)
+}
+
+// quote escapes the provided string to be written as an N-Triples string.
+func quote(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// objectTerm returns the BQL text of the node, predicate, or literal standing
+// for the provided N-Triples term.
+func objectTerm(nt string) (string, error) {
+	o, err := triple.ParseObjectTerm(nt, literal.DefaultBuilder())
+	if err != nil {
+		return "", fmt.Errorf("sparql: invalid term %s; %v", nt, err)
+	}
+	if l, err := o.Literal(); err == nil && l.Type() == literal.Text {
+		// Text literals are written unescaped, so they are quoted again as
+		// BQL does.
+		s, _ := l.Text()
+		return `"` + bqlEscaper.Replace(s) + `"^^type:text`, nil
+	}
+	return o.String(), nil
+}
+
+// bqlEscaper escapes the characters that cannot be written as they are in
+// BQL strings.
+var bqlEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// filter translates the constraint of a FILTER into a BQL expression.
+func (t *translator) filter() (string, error) {
+	// Constraints are either enclosed by parenthesis, or a function call.
+	if t.peek().typ == tokenWord {
+		return t.expression(0)
+	}
+	if err := t.expect("("); err != nil {
+		return "", err
+	}
+	return t.expression(1)
+}
+
+// expression translates the tokens of an expression until the parenthesis
+// open, as indicated by depth, are closed. The last closing parenthesis is
+// only included if it was opened by the expression itself, as done for
+// function calls.
+func (t *translator) expression(depth int) (string, error) {
+	var (
+		parts []string
+		open  = depth > 0
+	)
+	for {
+		tkn := t.peek()
+		switch tkn.typ {
+		case tokenEOF:
+			return "", t.errorf("expression is not terminated with )")
+		case tokenVar:
+			parts = append(parts, "?"+t.next().text)
+		case tokenIRI, tokenPName:
+			iri, err := t.iri()
+			if err != nil {
+				return "", err
+			}
+			o, err := objectTerm("<" + iri + ">")
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, o)
+		case tokenNumber:
+			// BQL accepts numbers as they are written.
+			parts = append(parts, t.next().text)
+		case tokenWord:
+			if v := strings.ToLower(tkn.text); v != "true" && v != "false" {
+				t.next()
+				if n := t.peek(); n.typ != tokenPunct || n.text != "(" {
+					return "", fmt.Errorf("sparql: unexpected %q in expression at offset %d", tkn.text, tkn.pos)
+				}
+				parts = append(parts, v)
+				continue
+			}
+			fallthrough
+		case tokenString:
+			nt, err := t.literal()
+			if err != nil {
+				return "", err
+			}
+			o, err := objectTerm(nt)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, o)
+		case tokenPunct:
+			switch tkn.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			case "{", "}", "[", "]", ".", ";", "^^", "|", "^":
+				return "", t.errorf("unexpected %q in expression", tkn.text)
+			}
+			t.next()
+			if depth == 0 && tkn.text == ")" {
+				if !open {
+					parts = append(parts, ")")
+				}
+				return strings.Join(parts, " "), nil
+			}
+			parts = append(parts, tkn.text)
+		default:
+			return "", t.errorf("unexpected %q in expression", tkn.text)
+		}
+	}
+}
+
+// modifiers translates the ORDER BY, LIMIT and OFFSET clauses.
+func (t *translator) modifiers() (string, error) {
+	var b strings.Builder
+	if tkn := t.peek(); tkn.typ == tokenWord && (strings.EqualFold(tkn.text, "GROUP") || strings.EqualFold(tkn.text, "HAVING")) {
+		return "", t.errorf("%s is not supported", strings.ToUpper(tkn.text))
+	}
+	if t.keyword("ORDER") {
+		if !t.keyword("BY") {
+			return "", t.errorf("expected BY after ORDER")
+		}
+		var keys []string
+		for {
+			if tkn := t.peek(); tkn.typ == tokenVar {
+				keys = append(keys, "?"+t.next().text)
+				continue
+			}
+			dir := "ASC"
+			if !t.keyword("ASC") {
+				if !t.keyword("DESC") {
+					break
+				}
+				dir = "DESC"
+			}
+			if err := t.expect("("); err != nil {
+				return "", err
+			}
+			tkn := t.next()
+			if tkn.typ != tokenVar {
+				return "", fmt.Errorf("sparql: only variables can be used to sort, got %q at offset %d", tkn.text, tkn.pos)
+			}
+			if err := t.expect(")"); err != nil {
+				return "", err
+			}
+			keys = append(keys, "?"+tkn.text+" "+dir)
+		}
+		if len(keys) == 0 {
+			return "", t.errorf("only variables can be used to sort")
+		}
+		fmt.Fprintf(&b, " ORDER BY %s", strings.Join(keys, ", "))
+	}
+	var limit, offset string
+	for {
+		var v *string
+		switch {
+		case t.keyword("LIMIT"):
+			v = &limit
+		case t.keyword("OFFSET"):
+			v = &offset
+		default:
+			if limit != "" {
+				fmt.Fprintf(&b, " LIMIT \"%s\"^^type:int64", limit)
+			}
+			if offset != "" {
+				fmt.Fprintf(&b, " OFFSET \"%s\"^^type:int64", offset)
+			}
+			return b.String(), nil
+		}
+		tkn := t.next()
+		if tkn.typ != tokenNumber || strings.ContainsAny(tkn.text, ".eE") || *v != "" {
+			return "", fmt.Errorf("sparql: expected an integer at offset %d, got %q", tkn.pos, tkn.text)
+		}
+		*v = tkn.text
+	}
+}
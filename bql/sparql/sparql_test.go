@@ -0,0 +1,109 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sparql
+
+import (
+	"testing"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/semantic"
+)
+
+func TestTranslate(t *testing.T) {
+	table := []struct {
+		q      string
+		graphs []string
+		want   string
+		ask    bool
+	}{
+		{
+			q:    `SELECT ?c FROM <?family> WHERE { <urn:badwolf:node:/u#peter> <parent_of> ?c }`,
+			want: `SELECT ?c FROM ?family WHERE { /u<peter> "parent_of"@[] ?c };`,
+		},
+		{
+			q: `PREFIX foaf: <http://xmlns.com/foaf/0.1/>
+				SELECT DISTINCT * WHERE {
+					?p a foaf:Person ; foaf:name ?n , "Joe" .
+					OPTIONAL { ?p foaf:age ?age }
+					FILTER (?age >= 18 && regex(?n, "^j", "i"))
+				}
+				ORDER BY DESC(?n) ?p LIMIT 10 OFFSET 20`,
+			graphs: []string{"urn:badwolf:graph:%3Fpeople", "?more"},
+			want: `SELECT ?p, ?n, ?age FROM ?people, ?more WHERE { ` +
+				`?p "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"@[] /iri<http://xmlns.com/foaf/0.1/Person> . ` +
+				`?p "http://xmlns.com/foaf/0.1/name"@[] ?n . ` +
+				`?p "http://xmlns.com/foaf/0.1/name"@[] "Joe"^^type:text . ` +
+				`OPTIONAL { ?p "http://xmlns.com/foaf/0.1/age"@[] ?age } . ` +
+				`FILTER(?age >= 18 && regex ( ?n , "^j"^^type:text , "i"^^type:text )) } ` +
+				`GROUP BY ?p, ?n, ?age ORDER BY ?n DESC, ?p LIMIT "10"^^type:int64 OFFSET "20"^^type:int64;`,
+		},
+		{
+			q:    `BASE <http://example.com/> ASK FROM <urn:badwolf:graph:%3Fg> { { ?s <knows> _:x } UNION { _:x <knows> ?s . FILTER regex(?s, 'a\'b') } }`,
+			want: `ASK FROM ?g WHERE { { ?s "http://example.com/knows"@[] ?_blank0 } UNION { ?_blank0 "http://example.com/knows"@[] ?s . FILTER(regex ( ?s , "a'b"^^type:text )) } };`,
+			ask:  true,
+		},
+		{
+			q:    `SELECT ?s FROM <?g> WHERE { ?s <p> 1.5, 2, true, "1"^^<http://www.w3.org/2001/XMLSchema#int>, "x\"y"@en }`,
+			want: `SELECT ?s FROM ?g WHERE { ?s "p"@[] "1.5"^^type:decimal . ?s "p"@[] "2"^^type:int64 . ?s "p"@[] "true"^^type:bool . ?s "p"@[] "1"^^type:int64 . ?s "p"@[] "x\"y"^^type:text };`,
+		},
+		{
+			q:    `SELECT ?s FROM <?g> WHERE { ?s ?p ?o FILTER(?s<<http://example.com/x> || !(?o = "x")) }`,
+			want: `SELECT ?s FROM ?g WHERE { ?s ?p ?o . FILTER(?s < /iri<http://example.com/x> || ! ( ?o = "x"^^type:text )) };`,
+		},
+		// Unsupported or invalid queries.
+		{q: `SELECT ?s WHERE { ?s ?p ?o }`},
+		{q: `SELECT ?s FROM <http://example.com/g> WHERE { ?s ?p ?o }`},
+		{q: `CONSTRUCT { ?s ?p ?o } FROM <?g> WHERE { ?s ?p ?o }`},
+		{q: `SELECT (count(?s) AS ?n) FROM <?g> WHERE { ?s ?p ?o }`},
+		{q: `SELECT ?s FROM NAMED <?g> WHERE { ?s ?p ?o }`},
+		{q: `SELECT ?s FROM <?g> WHERE { ?s <p>/<q> ?o }`},
+		{q: `SELECT ?s FROM <?g> WHERE { ?s foaf:name ?o }`},
+		{q: `SELECT ?s FROM <?g> WHERE { ?s ?p ?o . MINUS { ?s ?p 1 } }`},
+		{q: `SELECT ?s FROM <?g> WHERE { "s" ?p ?o }`},
+		{q: `SELECT ?s FROM <?g> WHERE { ?s ?p ?o } ORDER BY str(?s)`},
+		{q: `SELECT ?s FROM <?g> WHERE { ?s ?p ?o } LIMIT 1.5`},
+		{q: `SELECT ?s FROM <?g> WHERE { ?s ?p ?o `},
+		{q: `SELECT ?s FROM <?g> WHERE { ?s ?p "unterminated }`},
+	}
+	for _, entry := range table {
+		got, err := Translate(entry.q, entry.graphs)
+		if entry.want == "" {
+			if err == nil {
+				t.Errorf("sparql.Translate(%q) should have failed; got %q", entry.q, got.BQL)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("sparql.Translate(%q) failed with error %v", entry.q, err)
+			continue
+		}
+		if got.BQL != entry.want || got.Ask != entry.ask {
+			t.Errorf("sparql.Translate(%q) returned %q (ask %v); want %q (ask %v)", entry.q, got.BQL, got.Ask, entry.want, entry.ask)
+		}
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser failed with error %v", err)
+		}
+		if err := p.Parse(grammar.NewLLk(got.BQL, 1), &semantic.Statement{}); err != nil {
+			t.Errorf("the translation of %q, %q, is not valid BQL; %v", entry.q, got.BQL, err)
+		}
+	}
+}
+
+func TestTranslateNoDataset(t *testing.T) {
+	if _, err := Translate(`SELECT ?s WHERE { ?s ?p ?o }`, nil); err != ErrNoDataset {
+		t.Errorf("sparql.Translate without graphs returned error %v; want %v", err, ErrNoDataset)
+	}
+}
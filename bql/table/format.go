@@ -0,0 +1,370 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/badwolf/triple"
+)
+
+// Format is a serialization format of tables.
+type Format string
+
+const (
+	// FormatTable serializes tables as readable text with tab separated cells,
+	// as returned by String.
+	FormatTable Format = "table"
+
+	// FormatCSV serializes tables as comma separated values, with the bindings
+	// as header.
+	FormatCSV Format = "csv"
+
+	// FormatTSV serializes tables as tab separated values, with the bindings as
+	// header. Tabs, new lines, and backslashes in cells are escaped.
+	FormatTSV Format = "tsv"
+
+	// FormatJSON serializes tables as a single JSON object, as written by
+	// ToJSON.
+	FormatJSON Format = "json"
+
+	// FormatSPARQLJSON serializes tables using the SPARQL 1.1 query results
+	// JSON format, mapping the cells to RDF terms as done for N-Triples.
+	FormatSPARQLJSON Format = "sparql-json"
+
+	// FormatNDJSON serializes tables as newline delimited JSON, writing one
+	// JSON object per row.
+	FormatNDJSON Format = "ndjson"
+
+	// FormatSPARQLXML serializes tables using the SPARQL query results XML
+	// format, mapping the cells to RDF terms as done for N-Triples.
+	FormatSPARQLXML Format = "sparql-xml"
+)
+
+// Formats lists the supported serialization formats.
+var Formats = []Format{FormatTable, FormatCSV, FormatTSV, FormatJSON, FormatSPARQLJSON, FormatNDJSON, FormatSPARQLXML}
+
+// ParseFormat returns the format with the provided name.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	var fs []string
+	for _, f := range Formats {
+		fs = append(fs, string(f))
+	}
+	return "", fmt.Errorf("table.ParseFormat: unknown format %q; valid formats are {%s}", s, strings.Join(fs, "|"))
+}
+
+// Write serializes the table into the writer using the provided format.
+func (t *Table) Write(w io.Writer, f Format) error {
+	switch f {
+	case FormatTable:
+		_, err := io.WriteString(w, t.String())
+		return err
+	case FormatCSV:
+		return t.WriteCSV(w)
+	case FormatTSV:
+		return t.WriteTSV(w)
+	case FormatJSON:
+		bw := bufio.NewWriter(w)
+		t.ToJSON(bw)
+		bw.WriteString("\n")
+		return bw.Flush()
+	case FormatSPARQLJSON:
+		return t.WriteSPARQLJSON(w)
+	case FormatNDJSON:
+		return t.WriteNDJSON(w)
+	case FormatSPARQLXML:
+		return t.WriteSPARQLXML(w)
+	}
+	return fmt.Errorf("table.Write: unknown format %q", f)
+}
+
+// values returns the values of the cells of the row for the provided bindings
+// using the provided function. Unbound cells have empty values.
+func (r Row) values(bs []string, f func(*Cell) string) []string {
+	vs := make([]string, len(bs))
+	for i, b := range bs {
+		if c, ok := r[b]; ok && c != nil {
+			vs[i] = f(c)
+		}
+	}
+	return vs
+}
+
+// WriteCSV serializes the table into the writer as comma separated values.
+// The first record contains the bindings, and unbound cells are empty.
+func (t *Table) WriteCSV(w io.Writer) error {
+	return t.writeRows(w, FormatCSV)
+}
+
+// tsvEscaper escapes the characters that cannot appear in a TSV cell.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// WriteTSV serializes the table into the writer as tab separated values. The
+// first line contains the bindings, and unbound cells are empty.
+func (t *Table) WriteTSV(w io.Writer) error {
+	return t.writeRows(w, FormatTSV)
+}
+
+// writeRows serializes the rows of the table using a RowWriter.
+func (t *Table) writeRows(w io.Writer, f Format) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rw, err := NewRowWriter(w, f, t.AvailableBindings)
+	if err != nil {
+		return err
+	}
+	for _, r := range t.Data {
+		if err := rw.Write(r); err != nil {
+			return err
+		}
+	}
+	return rw.Flush()
+}
+
+// RowWriter serializes rows one at a time, so they can be written as they are
+// computed instead of collecting them in a table first. Only the formats that
+// serialize each row independently are supported: FormatCSV, FormatTSV, and
+// FormatNDJSON.
+type RowWriter struct {
+	bw    *bufio.Writer
+	write func(Row) error
+	flush func() error
+}
+
+// NewRowWriter returns a writer serializing rows with the provided bindings
+// into w using the provided format. The header of the format, if any, is
+// written before the first row. Writes are buffered until Flush is called.
+func NewRowWriter(w io.Writer, f Format, bindings []string) (*RowWriter, error) {
+	rw := &RowWriter{
+		bw: bufio.NewWriter(w),
+	}
+	rw.flush = rw.bw.Flush
+	switch f {
+	case FormatCSV:
+		cw := csv.NewWriter(rw.bw)
+		if err := cw.Write(bindings); err != nil {
+			return nil, err
+		}
+		rw.write = func(r Row) error {
+			return cw.Write(r.values(bindings, (*Cell).String))
+		}
+		rw.flush = func() error {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			return rw.bw.Flush()
+		}
+	case FormatTSV:
+		rw.bw.WriteString(strings.Join(bindings, "\t") + "\n")
+		rw.write = func(r Row) error {
+			vs := r.values(bindings, func(c *Cell) string {
+				return tsvEscaper.Replace(c.String())
+			})
+			_, err := rw.bw.WriteString(strings.Join(vs, "\t") + "\n")
+			return err
+		}
+	case FormatNDJSON:
+		e := json.NewEncoder(rw.bw)
+		e.SetEscapeHTML(false)
+		rw.write = func(r Row) error {
+			obj := make(map[string]interface{}, len(bindings))
+			for _, b := range bindings {
+				obj[b] = nil
+				if c, ok := r[b]; ok && c != nil {
+					obj[b] = c.jsonValue()
+				}
+			}
+			return e.Encode(obj)
+		}
+	default:
+		return nil, fmt.Errorf("table.NewRowWriter: format %q cannot be written one row at a time", f)
+	}
+	return rw, nil
+}
+
+// Write serializes the provided row.
+func (rw *RowWriter) Write(r Row) error {
+	return rw.write(r)
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (rw *RowWriter) Flush() error {
+	return rw.flush()
+}
+
+// jsonValue returns the JSON object for the cell, keyed by the kind of value
+// as done by ToJSON.
+func (c *Cell) jsonValue() map[string]string {
+	switch {
+	case c.S != nil:
+		return map[string]string{"string": *c.S}
+	case c.N != nil:
+		return map[string]string{"node": c.N.String()}
+	case c.P != nil:
+		return map[string]string{"pred": c.P.String()}
+	case c.L != nil:
+		return map[string]string{"lit": c.L.String()}
+	case c.T != nil:
+		return map[string]string{"anchor": c.T.Format(time.RFC3339Nano)}
+	}
+	return nil
+}
+
+// WriteNDJSON serializes the table into the writer as newline delimited JSON.
+// Each row is written as a JSON object on its own line, mapping the bindings
+// to the values of the cells as written by ToJSON. Unbound cells are null.
+func (t *Table) WriteNDJSON(w io.Writer) error {
+	return t.writeRows(w, FormatNDJSON)
+}
+
+// sparqlTerm is an RDF term in the SPARQL 1.1 query results JSON format.
+type sparqlTerm struct {
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Datatype string `json:"datatype,omitempty"`
+}
+
+// sparqlTerm returns the RDF term for the cell. Nodes and predicates are
+// mapped to IRIs and blank nodes, and literals to typed literals, as done for
+// N-Triples.
+func (c *Cell) sparqlTerm() (*sparqlTerm, error) {
+	term := func(s string) *sparqlTerm {
+		if strings.HasPrefix(s, "_:") {
+			return &sparqlTerm{Type: "bnode", Value: s[2:]}
+		}
+		return &sparqlTerm{Type: "uri", Value: s[1 : len(s)-1]}
+	}
+	switch {
+	case c.S != nil:
+		return &sparqlTerm{Type: "literal", Value: *c.S}, nil
+	case c.N != nil:
+		return term(triple.NodeTerm(c.N)), nil
+	case c.P != nil:
+		return term(triple.PredicateTerm(c.P, false)), nil
+	case c.L != nil:
+		v, dt, err := triple.LiteralValue(c.L)
+		if err != nil {
+			return nil, err
+		}
+		return &sparqlTerm{Type: "literal", Value: v, Datatype: dt}, nil
+	case c.T != nil:
+		return &sparqlTerm{Type: "literal", Value: c.T.Format(time.RFC3339Nano), Datatype: triple.XSD + "dateTime"}, nil
+	}
+	return nil, nil
+}
+
+// WriteSPARQLJSON serializes the table into the writer using the SPARQL 1.1
+// query results JSON format. Variables are named after the bindings without
+// the leading ?, and unbound cells are omitted.
+func (t *Table) WriteSPARQLJSON(w io.Writer) error {
+	vars, bindings, err := t.sparqlRows()
+	if err != nil {
+		return err
+	}
+	res := map[string]interface{}{
+		"head":    map[string]interface{}{"vars": vars},
+		"results": map[string]interface{}{"bindings": bindings},
+	}
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	return e.Encode(res)
+}
+
+// sparqlRows returns the SPARQL variables of the table, named after the
+// bindings without the leading ?, and the RDF terms bound to them in each row.
+// Unbound cells are omitted.
+func (t *Table) sparqlRows() ([]string, []map[string]*sparqlTerm, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	vars := make([]string, 0, len(t.AvailableBindings))
+	for _, b := range t.AvailableBindings {
+		vars = append(vars, strings.TrimPrefix(b, "?"))
+	}
+	rows := make([]map[string]*sparqlTerm, 0, len(t.Data))
+	for _, r := range t.Data {
+		row := make(map[string]*sparqlTerm)
+		for i, b := range t.AvailableBindings {
+			c, ok := r[b]
+			if !ok || c == nil {
+				continue
+			}
+			term, err := c.sparqlTerm()
+			if err != nil {
+				return nil, nil, err
+			}
+			if term != nil {
+				row[vars[i]] = term
+			}
+		}
+		rows = append(rows, row)
+	}
+	return vars, rows, nil
+}
+
+// WriteSPARQLXML serializes the table into the writer using the SPARQL query
+// results XML format. Variables are named after the bindings without the
+// leading ?, and unbound cells are omitted.
+func (t *Table) WriteSPARQLXML(w io.Writer) error {
+	vars, rows, err := t.sparqlRows()
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	esc := func(s string) string {
+		var buf bytes.Buffer
+		xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	}
+	bw.WriteString(xml.Header)
+	bw.WriteString("<sparql xmlns=\"http://www.w3.org/2005/sparql-results#\">\n  <head>\n")
+	for _, v := range vars {
+		fmt.Fprintf(bw, "    <variable name=\"%s\"/>\n", esc(v))
+	}
+	bw.WriteString("  </head>\n  <results>\n")
+	for _, row := range rows {
+		bw.WriteString("    <result>\n")
+		for _, v := range vars {
+			term, ok := row[v]
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(bw, "      <binding name=\"%s\">", esc(v))
+			switch {
+			case term.Type == "literal" && term.Datatype != "":
+				fmt.Fprintf(bw, "<literal datatype=\"%s\">%s</literal>", esc(term.Datatype), esc(term.Value))
+			default:
+				fmt.Fprintf(bw, "<%s>%s</%s>", term.Type, esc(term.Value), term.Type)
+			}
+			bw.WriteString("</binding>\n")
+		}
+		bw.WriteString("    </result>\n")
+	}
+	bw.WriteString("  </results>\n</sparql>\n")
+	return bw.Flush()
+}
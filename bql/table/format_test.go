@@ -0,0 +1,167 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// formatTable returns the table used to test the serialization formats.
+func formatTable(t *testing.T) *Table {
+	tbl, err := New([]string{"?s", "?p", "?o"})
+	if err != nil {
+		t.Fatalf("table.New failed with error %v", err)
+	}
+	n, err := node.Parse("/u<john>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := node.Parse("/_<b1>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := predicate.Parse(`"knows"@[]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := literal.DefaultBuilder().Parse(`"42"^^type:int64`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta := time.Date(2016, 4, 10, 4, 21, 0, 0, time.UTC)
+	tbl.AddRow(Row{"?s": &Cell{N: n}, "?p": &Cell{P: p}, "?o": &Cell{L: l}})
+	tbl.AddRow(Row{"?s": &Cell{N: b}, "?p": &Cell{T: &ta}, "?o": &Cell{S: CellString("a,\"b\"\tc")}})
+	tbl.AddRow(Row{"?s": &Cell{N: n}})
+	return tbl
+}
+
+func TestWriteFormats(t *testing.T) {
+	table := []struct {
+		f    Format
+		want string
+	}{
+		{
+			f: FormatCSV,
+			want: "?s,?p,?o\n" +
+				"/u<john>,\"\"\"knows\"\"@[]\",\"\"\"42\"\"^^type:int64\"\n" +
+				"/_<b1>,2016-04-10T04:21:00Z,\"a,\"\"b\"\"\tc\"\n" +
+				"/u<john>,,\n",
+		},
+		{
+			f: FormatTSV,
+			want: "?s\t?p\t?o\n" +
+				"/u<john>\t\"knows\"@[]\t\"42\"^^type:int64\n" +
+				"/_<b1>\t2016-04-10T04:21:00Z\ta,\"b\"\\tc\n" +
+				"/u<john>\t\t\n",
+		},
+		{
+			f: FormatNDJSON,
+			want: `{"?o":{"lit":"\"42\"^^type:int64"},"?p":{"pred":"\"knows\"@[]"},"?s":{"node":"/u<john>"}}` + "\n" +
+				`{"?o":{"string":"a,\"b\"\tc"},"?p":{"anchor":"2016-04-10T04:21:00Z"},"?s":{"node":"/_<b1>"}}` + "\n" +
+				`{"?o":null,"?p":null,"?s":{"node":"/u<john>"}}` + "\n",
+		},
+		{
+			f: FormatSPARQLJSON,
+			want: `{"head":{"vars":["s","p","o"]},"results":{"bindings":[` +
+				`{"o":{"type":"literal","value":"42","datatype":"http://www.w3.org/2001/XMLSchema#integer"},"p":{"type":"uri","value":"urn:badwolf:predicate:knows"},"s":{"type":"uri","value":"urn:badwolf:node:/u#john"}},` +
+				`{"o":{"type":"literal","value":"a,\"b\"\tc"},"p":{"type":"literal","value":"2016-04-10T04:21:00Z","datatype":"http://www.w3.org/2001/XMLSchema#dateTime"},"s":{"type":"bnode","value":"b1"}},` +
+				`{"s":{"type":"uri","value":"urn:badwolf:node:/u#john"}}]}}` + "\n",
+		},
+		{
+			f: FormatSPARQLXML,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<sparql xmlns="http://www.w3.org/2005/sparql-results#">
+  <head>
+    <variable name="s"/>
+    <variable name="p"/>
+    <variable name="o"/>
+  </head>
+  <results>
+    <result>
+      <binding name="s"><uri>urn:badwolf:node:/u#john</uri></binding>
+      <binding name="p"><uri>urn:badwolf:predicate:knows</uri></binding>
+      <binding name="o"><literal datatype="http://www.w3.org/2001/XMLSchema#integer">42</literal></binding>
+    </result>
+    <result>
+      <binding name="s"><bnode>b1</bnode></binding>
+      <binding name="p"><literal datatype="http://www.w3.org/2001/XMLSchema#dateTime">2016-04-10T04:21:00Z</literal></binding>
+      <binding name="o"><literal>a,&#34;b&#34;&#x9;c</literal></binding>
+    </result>
+    <result>
+      <binding name="s"><uri>urn:badwolf:node:/u#john</uri></binding>
+    </result>
+  </results>
+</sparql>
+`,
+		},
+	}
+	tbl := formatTable(t)
+	for _, entry := range table {
+		var b bytes.Buffer
+		if err := tbl.Write(&b, entry.f); err != nil {
+			t.Fatalf("tbl.Write(%q) failed with error %v", entry.f, err)
+		}
+		if got := b.String(); got != entry.want {
+			t.Errorf("tbl.Write(%q) returned\n%s\nwant\n%s", entry.f, got, entry.want)
+		}
+	}
+}
+
+func TestRowWriter(t *testing.T) {
+	tbl := formatTable(t)
+	for _, f := range []Format{FormatCSV, FormatTSV, FormatNDJSON} {
+		var want, got bytes.Buffer
+		if err := tbl.Write(&want, f); err != nil {
+			t.Fatalf("table.Write(%q) failed with error %v", f, err)
+		}
+		rw, err := NewRowWriter(&got, f, tbl.Bindings())
+		if err != nil {
+			t.Fatalf("table.NewRowWriter(%q) failed with error %v", f, err)
+		}
+		for _, r := range tbl.Rows() {
+			if err := rw.Write(r); err != nil {
+				t.Fatalf("RowWriter.Write(%q) failed with error %v", f, err)
+			}
+		}
+		if err := rw.Flush(); err != nil {
+			t.Fatalf("RowWriter.Flush(%q) failed with error %v", f, err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("RowWriter(%q) wrote %q; want %q", f, got.String(), want.String())
+		}
+	}
+	for _, f := range []Format{FormatTable, FormatJSON, FormatSPARQLJSON, FormatSPARQLXML} {
+		if _, err := NewRowWriter(&bytes.Buffer{}, f, tbl.Bindings()); err == nil {
+			t.Errorf("table.NewRowWriter(%q) should have failed", f)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		if got, err := ParseFormat(string(f)); err != nil || got != f {
+			t.Errorf("ParseFormat(%q) returned (%q, %v); want (%q, nil)", f, got, err, f)
+		}
+	}
+	if got, err := ParseFormat("xml"); err == nil {
+		t.Errorf("ParseFormat(\"xml\") returned %q; want an error", got)
+	}
+}
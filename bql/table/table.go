@@ -21,8 +21,10 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math/big"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -240,6 +242,34 @@ func (t *Table) AppendTable(t2 *Table) error {
 	return nil
 }
 
+// Union appends the rows of the provided table aligning the bindings of both
+// tables. Rows missing any of the resulting bindings are extended with empty
+// cells.
+func (t *Table) Union(t2 *Table) {
+	if t2 == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t2.mu.RLock()
+	defer t2.mu.RUnlock()
+	ubs := unionBindings(t.mbs, t2.mbs)
+	if !equalBindings(t.mbs, ubs) {
+		for i, r := range t.Data {
+			t.Data[i] = extendRow(r, ubs)
+		}
+		for _, b := range t2.AvailableBindings {
+			if !t.mbs[b] {
+				t.AvailableBindings = append(t.AvailableBindings, b)
+			}
+		}
+		t.mbs = ubs
+	}
+	for _, r := range t2.Data {
+		t.Data = append(t.Data, extendRow(r, ubs))
+	}
+}
+
 // disjointBinding returns true if they are not overlapping bindings, false
 // otherwise.
 func disjointBindings(b1, b2 map[string]bool) bool {
@@ -297,6 +327,54 @@ func (t *Table) DotProduct(t2 *Table) error {
 	return nil
 }
 
+// InnerJoin joins the table with the provided one. Only the rows that agree
+// on the values of the shared bindings are kept. If the tables do not share
+// any binding, the result is the dot product of both tables.
+func (t *Table) InnerJoin(t2 *Table) error {
+	if disjointBindings(t.mbs, t2.mbs) {
+		return t.DotProduct(t2)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t2.mu.RLock()
+	defer t2.mu.RUnlock()
+	ibs := intersectBindings(t.mbs, t2.mbs)
+	var sbs []string
+	for k := range ibs {
+		sbs = append(sbs, k)
+	}
+	key := func(r Row) string {
+		b := bytes.NewBufferString("")
+		for _, k := range sbs {
+			if c := r[k]; c != nil {
+				b.WriteString(c.String())
+			}
+			b.WriteString(";")
+		}
+		return b.String()
+	}
+	idx := make(map[string][]Row)
+	for _, r := range t2.Data {
+		k := key(r)
+		idx[k] = append(idx[k], r)
+	}
+	var res []Row
+	for _, r1 := range t.Data {
+		for _, r2 := range idx[key(r1)] {
+			if joinable(r1, r2, ibs) {
+				res = append(res, MergeRows([]Row{r1, r2}))
+			}
+		}
+	}
+	t.mbs = unionBindings(t.mbs, t2.mbs)
+	t.AvailableBindings = nil
+	for k := range t.mbs {
+		t.AvailableBindings = append(t.AvailableBindings, k)
+	}
+	t.Data = res
+	return nil
+}
+
 // LeftOptionalJoin does a left join using the provided right table.
 func (t *Table) LeftOptionalJoin(t2 *Table) error {
 	if equalBindings(t.mbs, t2.mbs) || len(t2.mbs) == 0 {
@@ -307,7 +385,15 @@ func (t *Table) LeftOptionalJoin(t2 *Table) error {
 	}
 	if disjointBindings(t.mbs, t2.mbs) {
 		// The tables has nothing in commnon. Hence, we are going to treat it
-		// as a regular cross product.
+		// as a regular cross product. If the right table has no rows, the left
+		// rows are kept with the right bindings left unbound.
+		if len(t2.Data) == 0 {
+			t2 = &Table{
+				AvailableBindings: t2.AvailableBindings,
+				mbs:               t2.mbs,
+				Data:              []Row{extendRow(Row{}, t2.mbs)},
+			}
+		}
 		return t.DotProduct(t2)
 	}
 	// There are some overlapping bindings. That requires to sort both tables
@@ -492,6 +578,22 @@ func (t *Table) Limit(i int64) {
 	}
 }
 
+// Offset skips the first i rows of the table.
+func (t *Table) Offset(i int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i <= 0 {
+		return
+	}
+	if int64(len(t.Data)) <= i {
+		t.Data = nil
+		return
+	}
+	td := make([]Row, int64(len(t.Data))-i) // Preallocate resulting table.
+	copy(td, t.Data[i:])
+	t.Data = td
+}
+
 // SortConfig contains the sorting information. Contains the binding order
 // to use while sorting as well as the direction for each of them to use.
 type SortConfig []sortConfig
@@ -563,6 +665,11 @@ func rowLess(ri, rj Row, c SortConfig) bool {
 		log.Fatalf("Could not retrieve binding %q! %v %v", cfg.Binding, ri, rj)
 	}
 	si, sj := "", ""
+	// Cells of different kinds, including unbound ones, are sorted by kind so
+	// rows with equal values always end up together.
+	if ki, kj := cellKind(ci), cellKind(cj); ki != kj {
+		si, sj = strconv.Itoa(ki), strconv.Itoa(kj)
+	}
 	// Check if it has a string.
 	if ci.S != nil && cj.S != nil {
 		si, sj = *ci.S, *cj.S
@@ -575,15 +682,18 @@ func rowLess(ri, rj Row, c SortConfig) bool {
 	if ci.P != nil && cj.P != nil {
 		si, sj = ci.P.String(), cj.P.String()
 	}
-	// Check if it has a literal.
-	if ci.L != nil && cj.L != nil {
-		si, sj = ci.L.ToComparableString(), cj.L.ToComparableString()
-	}
 	// Check if it has a time anchor.
 	if ci.T != nil && cj.T != nil {
 		si, sj = ci.T.Format(time.RFC3339Nano), cj.T.Format(time.RFC3339Nano)
 	}
 	l := stringLess(si, sj, cfg.Desc)
+	// Literals are sorted using the total order across literals.
+	if ci.L != nil && cj.L != nil {
+		l = literal.Compare(ci.L, cj.L)
+		if cfg.Desc {
+			l = -l
+		}
+	}
 	if l < 0 {
 		return true
 	}
@@ -593,6 +703,24 @@ func rowLess(ri, rj Row, c SortConfig) bool {
 	return rowLess(ri, rj, c[1:])
 }
 
+// cellKind returns the position of the kind of value stored in the cell when
+// sorting cells of different kinds. Unbound cells go first.
+func cellKind(c *Cell) int {
+	switch {
+	case c.S != nil:
+		return 1
+	case c.N != nil:
+		return 2
+	case c.P != nil:
+		return 3
+	case c.L != nil:
+		return 4
+	case c.T != nil:
+		return 5
+	}
+	return 0
+}
+
 // Less returns true if the i row is less than j one.
 func (c bySortConfig) Less(i, j int) bool {
 	ri, rj, cfg := c.rows[i], c.rows[j], c.cfg
@@ -614,32 +742,83 @@ func (t *Table) Sort(cfg SortConfig) {
 	t.mu.Unlock()
 }
 
-// Accumulator type represents a generic accumulator for independent values
-// expressed as the element of the array slice. Returns the values after being
-// accumulated. If the wrong type is passed in, it will crash casting the
-// interface.
+// Accumulator type represents a generic accumulator for the cells of a
+// column. Returns the values after being accumulated. If a cell of the wrong
+// type is passed in, an error is returned. The accumulated value may be a
+// cell, node, predicate, literal, time, or any Go value that can be turned into
+// a literal.
 type Accumulator interface {
-	// Accumulate takes the given value and accumulates it to the current state.
-	Accumulate(interface{}) (interface{}, error)
+	// Accumulate takes the given cell and accumulates it to the current state.
+	Accumulate(*Cell) (interface{}, error)
 
 	// Resets the current state back to the original one.
 	Reset()
 }
 
+// AccumulatorFactory returns a new accumulator ready to be used for a single
+// aggregation.
+type AccumulatorFactory func() Accumulator
+
+var (
+	// accMu protects the registered accumulators.
+	accMu sync.RWMutex
+	// accFactories contains the registered accumulator factories indexed by
+	// their lower case name.
+	accFactories = make(map[string]AccumulatorFactory)
+)
+
+// RegisterAccumulator makes the accumulator built by the provided factory
+// available to BQL queries under the given name. Names are case insensitive.
+// Registering an empty name, a nil factory, or an already registered name
+// will fail. Names that collide with BQL keywords, like count or sum, will
+// never be reachable from a query.
+func RegisterAccumulator(name string, f AccumulatorFactory) error {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if n == "" {
+		return errors.New("table.RegisterAccumulator requires a non empty name")
+	}
+	if f == nil {
+		return fmt.Errorf("table.RegisterAccumulator requires a non nil factory for accumulator %q", name)
+	}
+	accMu.Lock()
+	defer accMu.Unlock()
+	if _, ok := accFactories[n]; ok {
+		return fmt.Errorf("table.RegisterAccumulator: accumulator %q is already registered", name)
+	}
+	accFactories[n] = f
+	return nil
+}
+
+// UnregisterAccumulator removes the accumulator registered under the provided
+// name. Unregistering an unknown name is a no-op.
+func UnregisterAccumulator(name string) {
+	accMu.Lock()
+	defer accMu.Unlock()
+	delete(accFactories, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// LookupAccumulator returns the accumulator factory registered under the
+// provided name. The boolean will be false if no accumulator was registered
+// with such name.
+func LookupAccumulator(name string) (AccumulatorFactory, bool) {
+	accMu.RLock()
+	defer accMu.RUnlock()
+	f, ok := accFactories[strings.ToLower(strings.TrimSpace(name))]
+	return f, ok
+}
+
 // sumInt64 implements an accumulator that sum int64 values.
 type sumInt64 struct {
 	initialState int64
 	state        int64
 }
 
-// Accumulate takes the given value and accumulates it to the current state.
-func (s *sumInt64) Accumulate(v interface{}) (interface{}, error) {
-	c := v.(*Cell)
-	l := c.L
-	if l == nil {
+// Accumulate takes the given cell and accumulates it to the current state.
+func (s *sumInt64) Accumulate(c *Cell) (interface{}, error) {
+	if c == nil || c.L == nil {
 		return nil, fmt.Errorf("not a valid literal it cell %v", c)
 	}
-	iv, err := l.Int64()
+	iv, err := c.L.Int64()
 	if err != nil {
 		return s.state, err
 	}
@@ -663,14 +842,12 @@ type sumFloat64 struct {
 	state        float64
 }
 
-// Accumulate takes the given value and accumulates it to the current state.
-func (s *sumFloat64) Accumulate(v interface{}) (interface{}, error) {
-	c := v.(*Cell)
-	l := c.L
-	if l == nil {
+// Accumulate takes the given cell and accumulates it to the current state.
+func (s *sumFloat64) Accumulate(c *Cell) (interface{}, error) {
+	if c == nil || c.L == nil {
 		return nil, fmt.Errorf("not a valid literal it cell %v", c)
 	}
-	iv, err := l.Float64()
+	iv, err := c.L.Float64()
 	if err != nil {
 		return s.state, err
 	}
@@ -688,13 +865,42 @@ func NewSumFloat64LiteralAccumulator(s float64) Accumulator {
 	return &sumFloat64{s, s}
 }
 
+// sumDecimal implements an accumulator that sums decimal values exactly.
+type sumDecimal struct {
+	initialState *big.Rat
+	state        *big.Rat
+}
+
+// Accumulate takes the given cell and accumulates it to the current state.
+func (s *sumDecimal) Accumulate(c *Cell) (interface{}, error) {
+	if c == nil || c.L == nil {
+		return nil, fmt.Errorf("not a valid literal it cell %v", c)
+	}
+	dv, err := c.L.Decimal()
+	if err != nil {
+		return s.state, err
+	}
+	s.state.Add(s.state, dv)
+	return new(big.Rat).Set(s.state), nil
+}
+
+// Resets the current state back to the original one.
+func (s *sumDecimal) Reset() {
+	s.state = new(big.Rat).Set(s.initialState)
+}
+
+// NewSumDecimalLiteralAccumulator accumulates the decimal types of a literal.
+func NewSumDecimalLiteralAccumulator(s *big.Rat) Accumulator {
+	return &sumDecimal{new(big.Rat).Set(s), new(big.Rat).Set(s)}
+}
+
 // countAcc implements an accumulator that count accumulation occurrences.
 type countAcc struct {
 	state int64
 }
 
-// Accumulate takes the given value and accumulates it to the current state.
-func (c *countAcc) Accumulate(v interface{}) (interface{}, error) {
+// Accumulate takes the given cell and accumulates it to the current state.
+func (c *countAcc) Accumulate(*Cell) (interface{}, error) {
 	c.state++
 	return c.state, nil
 }
@@ -714,8 +920,8 @@ type countDistinctAcc struct {
 	state map[string]int64
 }
 
-// Accumulate takes the given value and accumulates it to the current state.
-func (c *countDistinctAcc) Accumulate(v interface{}) (interface{}, error) {
+// Accumulate takes the given cell and accumulates it to the current state.
+func (c *countDistinctAcc) Accumulate(v *Cell) (interface{}, error) {
 	vs := fmt.Sprintf("%v", v)
 	c.state[vs]++
 	return int64(len(c.state)), nil
@@ -732,6 +938,170 @@ func NewCountDistinctAccumulator() Accumulator {
 	return &countDistinctAcc{make(map[string]int64)}
 }
 
+// anyAcc implements an accumulator that computes the logical OR of the
+// accumulated bool literals.
+type anyAcc struct {
+	state bool
+}
+
+// Accumulate takes the given cell and accumulates it to the current state.
+func (a *anyAcc) Accumulate(c *Cell) (interface{}, error) {
+	if c == nil || c.L == nil {
+		return nil, fmt.Errorf("not a valid literal it cell %v", c)
+	}
+	bv, err := c.L.Bool()
+	if err != nil {
+		return a.state, err
+	}
+	a.state = a.state || bv
+	return a.state, nil
+}
+
+// Resets the current state back to the original one.
+func (a *anyAcc) Reset() {
+	a.state = false
+}
+
+// NewAnyAccumulator returns true if any of the accumulated bool literals is
+// true.
+func NewAnyAccumulator() Accumulator {
+	return &anyAcc{false}
+}
+
+// allAcc implements an accumulator that computes the logical AND of the
+// accumulated bool literals.
+type allAcc struct {
+	state bool
+}
+
+// Accumulate takes the given cell and accumulates it to the current state.
+func (a *allAcc) Accumulate(c *Cell) (interface{}, error) {
+	if c == nil || c.L == nil {
+		return nil, fmt.Errorf("not a valid literal it cell %v", c)
+	}
+	bv, err := c.L.Bool()
+	if err != nil {
+		return a.state, err
+	}
+	a.state = a.state && bv
+	return a.state, nil
+}
+
+// Resets the current state back to the original one.
+func (a *allAcc) Reset() {
+	a.state = true
+}
+
+// NewAllAccumulator returns true if all the accumulated bool literals are
+// true.
+func NewAllAccumulator() Accumulator {
+	return &allAcc{true}
+}
+
+// extremeAcc implements an accumulator that keeps the lowest or the greatest
+// of the accumulated literals, using the order defined by literal.Compare.
+type extremeAcc struct {
+	max   bool
+	state *literal.Literal
+}
+
+// Accumulate takes the given cell and accumulates it to the current state.
+func (a *extremeAcc) Accumulate(c *Cell) (interface{}, error) {
+	if c == nil || c.L == nil {
+		return nil, fmt.Errorf("not a valid literal it cell %v", c)
+	}
+	if a.state == nil {
+		a.state = c.L
+	} else if cmp := literal.Compare(c.L, a.state); (a.max && cmp > 0) || (!a.max && cmp < 0) {
+		a.state = c.L
+	}
+	return a.state, nil
+}
+
+// Resets the current state back to the original one.
+func (a *extremeAcc) Reset() {
+	a.state = nil
+}
+
+// NewMinAccumulator returns the lowest of the accumulated literals.
+func NewMinAccumulator() Accumulator {
+	return &extremeAcc{max: false}
+}
+
+// NewMaxAccumulator returns the greatest of the accumulated literals.
+func NewMaxAccumulator() Accumulator {
+	return &extremeAcc{max: true}
+}
+
+func init() {
+	RegisterAccumulator("any", NewAnyAccumulator)
+	RegisterAccumulator("all", NewAllAccumulator)
+	RegisterAccumulator("min", NewMinAccumulator)
+	RegisterAccumulator("max", NewMaxAccumulator)
+}
+
+// accumulatedCell wraps the value returned by an accumulator into a cell.
+// Accumulators may return cells, nodes, predicates, literals, times, or any of
+// the Go types supported by literals (bool, int64, float64, string, []byte,
+// and *big.Rat).
+func accumulatedCell(v interface{}) (*Cell, error) {
+	switch tv := v.(type) {
+	case *Cell:
+		if tv == nil {
+			break
+		}
+		return tv, nil
+	case *node.Node:
+		if tv == nil {
+			break
+		}
+		return &Cell{N: tv}, nil
+	case *predicate.Predicate:
+		if tv == nil {
+			break
+		}
+		return &Cell{P: tv}, nil
+	case *literal.Literal:
+		if tv == nil {
+			break
+		}
+		return &Cell{L: tv}, nil
+	case time.Time:
+		return &Cell{T: &tv}, nil
+	case *time.Time:
+		if tv == nil {
+			break
+		}
+		return &Cell{T: tv}, nil
+	case bool:
+		return literalCell(literal.Bool, tv)
+	case int64:
+		return literalCell(literal.Int64, tv)
+	case float64:
+		return literalCell(literal.Float64, tv)
+	case string:
+		return literalCell(literal.Text, tv)
+	case []byte:
+		return literalCell(literal.Blob, tv)
+	case *big.Rat:
+		if tv == nil {
+			break
+		}
+		return literalCell(literal.Decimal, tv)
+	}
+	return nil, fmt.Errorf("accumulator returned unknown value %v of type %T", v, v)
+}
+
+// literalCell returns a cell containing the literal of the provided type and
+// value.
+func literalCell(t literal.Type, v interface{}) (*Cell, error) {
+	l, err := literal.DefaultBuilder().Build(t, v)
+	if err != nil {
+		return nil, err
+	}
+	return &Cell{L: l}, nil
+}
+
 // groupRangeReduce takes a sorted range and generates a new row containing
 // the aggregated columns and the non aggregated ones.
 func (t *Table) groupRangeReduce(i, j int, alias map[string]string, acc map[string]Accumulator) (Row, error) {
@@ -773,23 +1143,11 @@ func (t *Table) groupRangeReduce(i, j int, alias map[string]string, acc map[stri
 			if !ok {
 				return nil, fmt.Errorf("aggregated bindings require and alias; binding %s missing alias", b)
 			}
-			// Accumulators currently only can return numeric literals.
-			switch acc.(type) {
-			case int64:
-				l, err := literal.DefaultBuilder().Build(literal.Int64, acc)
-				if err != nil {
-					return nil, err
-				}
-				newRow[a] = &Cell{L: l}
-			case float64:
-				l, err := literal.DefaultBuilder().Build(literal.Float64, acc)
-				if err != nil {
-					return nil, err
-				}
-				newRow[a] = &Cell{L: l}
-			default:
-				return nil, fmt.Errorf("aggregation of binding %s returned unknown value %v or type", b, acc)
+			c, err := accumulatedCell(acc)
+			if err != nil {
+				return nil, fmt.Errorf("aggregation of binding %s failed; %v", b, err)
 			}
+			newRow[a] = c
 		}
 	}
 	return newRow, nil
@@ -808,8 +1166,15 @@ func (t *Table) unsafeFullGroupRangeReduce(i, j int, acc map[string]map[string]A
 	if i > j {
 		return nil, fmt.Errorf("cannot aggregate empty ranges [%d, %d)", i, j)
 	}
-	// Initialize the range and accumulator results.
-	rng := t.Data[i:j]
+	return reduceRows(t.Data[i:j], acc)
+}
+
+// reduceRows generates a new row containing the aggregated columns and the non
+// aggregated ones for the provided group of rows.
+func reduceRows(rng []Row, acc map[string]map[string]AliasAccPair) (Row, error) {
+	if len(rng) == 0 {
+		return nil, errors.New("cannot aggregate an empty group of rows")
+	}
 	// Reset the accumulators.
 	for _, aap := range acc {
 		for _, a := range aap {
@@ -845,23 +1210,11 @@ func (t *Table) unsafeFullGroupRangeReduce(i, j int, acc map[string]map[string]A
 			if app.Acc == nil {
 				newRow[app.OutAlias] = v
 			} else {
-				// Accumulators currently only can return numeric literals.
-				switch vaccs[app.InAlias][app.OutAlias].(type) {
-				case int64:
-					l, err := literal.DefaultBuilder().Build(literal.Int64, vaccs[app.InAlias][app.OutAlias])
-					if err != nil {
-						return nil, err
-					}
-					newRow[app.OutAlias] = &Cell{L: l}
-				case float64:
-					l, err := literal.DefaultBuilder().Build(literal.Float64, vaccs[app.InAlias][app.OutAlias])
-					if err != nil {
-						return nil, err
-					}
-					newRow[app.OutAlias] = &Cell{L: l}
-				default:
-					return nil, fmt.Errorf("aggregation of binding %s returned unknown value %v or type", b, acc)
+				c, err := accumulatedCell(vaccs[app.InAlias][app.OutAlias])
+				if err != nil {
+					return nil, fmt.Errorf("aggregation of binding %s failed; %v", b, err)
 				}
+				newRow[app.OutAlias] = c
 			}
 		}
 	}
@@ -871,6 +1224,31 @@ func (t *Table) unsafeFullGroupRangeReduce(i, j int, acc map[string]map[string]A
 	return newRow, nil
 }
 
+// withGroupingBindings returns the provided alias and acc pairs prefixed with
+// an identity pair for each grouping binding that is not already listed as an
+// input binding.
+func withGroupingBindings(cfg SortConfig, aaps []AliasAccPair) []AliasAccPair {
+	in := make(map[string]bool)
+	for _, aap := range aaps {
+		in[aap.InAlias] = true
+	}
+	var res []AliasAccPair
+	for _, c := range cfg {
+		if in[c.Binding] {
+			continue
+		}
+		in[c.Binding] = true
+		res = append(res, AliasAccPair{
+			InAlias:  c.Binding,
+			OutAlias: c.Binding,
+		})
+	}
+	if len(res) == 0 {
+		return aaps
+	}
+	return append(res, aaps...)
+}
+
 // toMap converts a list of alias and acc pairs into a nested map. The first
 // key is the input binding, the second one is the output binding.
 func toMap(aaps []AliasAccPair) map[string]map[string]AliasAccPair {
@@ -889,29 +1267,15 @@ func toMap(aaps []AliasAccPair) map[string]map[string]AliasAccPair {
 // Reduce alters the table by sorting and then range grouping the table data.
 // In order to group reduce the table, we sort the table and then apply the
 // accumulator functions to each group. Finally, the table metadata gets
-// updated to reflect the reduce operation.
+// updated to reflect the reduce operation. Grouping bindings in the sort
+// configuration that do not appear in the provided alias accumulator pairs are
+// kept as is.
 func (t *Table) Reduce(cfg SortConfig, aaps []AliasAccPair) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	maaps := toMap(aaps)
-	// Input validation tests.
-	if len(t.AvailableBindings) != len(maaps) {
-		return fmt.Errorf("table.Reduce cannot project bindings; current %v, requested %v", t.AvailableBindings, aaps)
-	}
-	for _, b := range t.AvailableBindings {
-		if _, ok := maaps[b]; !ok {
-			return fmt.Errorf("table.Reduce missing binding alias for %q", b)
-		}
-	}
-	cnt := 0
-	for b := range maaps {
-		if _, ok := t.mbs[b]; !ok {
-			return fmt.Errorf("table.Reduce unknown reducer binding %q; available bindings %v", b, t.AvailableBindings)
-		}
-		cnt++
-	}
-	if cnt != len(t.AvailableBindings) {
-		return fmt.Errorf("table.Reduce invalid reduce configuration in cfg=%v, aap=%v for table with binding %v", cfg, aaps, t.AvailableBindings)
+	aaps, maaps, err := t.unsafeReduceConfig(cfg, aaps)
+	if err != nil {
+		return err
 	}
 	// Valid reduce configuration. Reduce sorts the table and then reduces
 	// contiguous groups row groups.
@@ -920,16 +1284,9 @@ func (t *Table) Reduce(cfg SortConfig, aaps []AliasAccPair) error {
 	}
 	t.unsafeSort(cfg)
 	last, lastIdx, current, newData := "", 0, "", []Row{}
-	id := func(r Row) string {
-		res := bytes.NewBufferString("")
-		for _, c := range cfg {
-			res.WriteString(r[c.Binding].String())
-			res.WriteString(";")
-		}
-		return res.String()
-	}
+	id := bytes.NewBufferString("")
 	for idx, r := range t.Data {
-		current = id(r)
+		current = groupKey(id, r, cfg)
 		// First time.
 		if last == "" {
 			last, lastIdx = current, idx
@@ -952,7 +1309,110 @@ func (t *Table) Reduce(cfg SortConfig, aaps []AliasAccPair) error {
 		return err
 	}
 	newData = append(newData, nr)
-	// Update the table.
+	t.unsafeUpdateReduced(aaps, newData)
+	return nil
+}
+
+// HashReduce alters the table by grouping the table data using the bindings
+// in the provided sort configuration and applying the accumulator functions to
+// each group. Unlike Reduce, it does not sort the table; rows are grouped in a
+// single pass and the resulting groups keep the order in which they were
+// first found. The sort direction of the configuration is ignored.
+func (t *Table) HashReduce(cfg SortConfig, aaps []AliasAccPair) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	aaps, maaps, err := t.unsafeReduceConfig(cfg, aaps)
+	if err != nil {
+		return err
+	}
+	if len(t.Data) == 0 {
+		return nil
+	}
+	var (
+		keys   []string
+		groups = make(map[string][]Row)
+		id     = bytes.NewBufferString("")
+	)
+	for _, r := range t.Data {
+		k := groupKey(id, r, cfg)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+	newData := make([]Row, 0, len(keys))
+	for _, k := range keys {
+		nr, err := reduceRows(groups[k], maaps)
+		if err != nil {
+			return err
+		}
+		newData = append(newData, nr)
+	}
+	t.unsafeUpdateReduced(aaps, newData)
+	return nil
+}
+
+// groupKey returns the key of the group of the row for the bindings in the
+// provided sort configuration, using the provided buffer to build it. Each
+// value is written prefixed by its kind and length, so rows with different
+// values never share the same key.
+func groupKey(buf *bytes.Buffer, r Row, cfg SortConfig) string {
+	buf.Reset()
+	for _, c := range cfg {
+		k, v := byte('-'), ""
+		if cell := r[c.Binding]; cell != nil {
+			v = cell.String()
+			switch {
+			case cell.S != nil:
+				k = 's'
+			case cell.N != nil:
+				k = 'n'
+			case cell.P != nil:
+				k = 'p'
+			case cell.L != nil:
+				k = 'l'
+			case cell.T != nil:
+				k = 't'
+			}
+		}
+		buf.WriteByte(k)
+		buf.WriteString(strconv.Itoa(len(v)))
+		buf.WriteByte(':')
+		buf.WriteString(v)
+	}
+	return buf.String()
+}
+
+// unsafeReduceConfig validates the provided reduce configuration against the
+// table bindings and returns the full list of alias and acc pairs to use and
+// its nested map version. This call bypasses the lock.
+func (t *Table) unsafeReduceConfig(cfg SortConfig, aaps []AliasAccPair) ([]AliasAccPair, map[string]map[string]AliasAccPair, error) {
+	aaps = withGroupingBindings(cfg, aaps)
+	maaps := toMap(aaps)
+	if len(t.AvailableBindings) != len(maaps) {
+		return nil, nil, fmt.Errorf("table.Reduce cannot project bindings; current %v, requested %v", t.AvailableBindings, aaps)
+	}
+	for _, b := range t.AvailableBindings {
+		if _, ok := maaps[b]; !ok {
+			return nil, nil, fmt.Errorf("table.Reduce missing binding alias for %q", b)
+		}
+	}
+	cnt := 0
+	for b := range maaps {
+		if _, ok := t.mbs[b]; !ok {
+			return nil, nil, fmt.Errorf("table.Reduce unknown reducer binding %q; available bindings %v", b, t.AvailableBindings)
+		}
+		cnt++
+	}
+	if cnt != len(t.AvailableBindings) {
+		return nil, nil, fmt.Errorf("table.Reduce invalid reduce configuration in cfg=%v, aap=%v for table with binding %v", cfg, aaps, t.AvailableBindings)
+	}
+	return aaps, maaps, nil
+}
+
+// unsafeUpdateReduced replaces the table data with the reduced rows and
+// updates the table metadata accordingly. This call bypasses the lock.
+func (t *Table) unsafeUpdateReduced(aaps []AliasAccPair, data []Row) {
 	t.AvailableBindings, t.mbs = []string{}, make(map[string]bool)
 	for _, aap := range aaps {
 		if !t.mbs[aap.OutAlias] {
@@ -960,8 +1420,7 @@ func (t *Table) Reduce(cfg SortConfig, aaps []AliasAccPair) error {
 		}
 		t.mbs[aap.OutAlias] = true
 	}
-	t.Data = newData
-	return nil
+	t.Data = data
 }
 
 // Filter removes all the rows where the provided function returns true.
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
 	"testing"
@@ -691,6 +692,66 @@ func TestDotProductContent(t *testing.T) {
 	}
 }
 
+func TestInnerJoin(t *testing.T) {
+	newTable := func(bs []string, rows ...[]string) *Table {
+		tbl, err := New(bs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, vs := range rows {
+			r := Row{}
+			for i, v := range vs {
+				r[bs[i]] = &Cell{S: CellString(v)}
+			}
+			tbl.AddRow(r)
+		}
+		return tbl
+	}
+	testTable := []struct {
+		t, t2 *Table
+		nbs   int
+		want  []Row
+	}{
+		{
+			t:   newTable([]string{"?a", "?b"}, []string{"a1", "b1"}, []string{"a2", "b2"}, []string{"a3", "b1"}),
+			t2:  newTable([]string{"?b", "?c"}, []string{"b1", "c1"}, []string{"b1", "c2"}, []string{"b3", "c3"}),
+			nbs: 3,
+			want: []Row{
+				{"?a": &Cell{S: CellString("a1")}, "?b": &Cell{S: CellString("b1")}, "?c": &Cell{S: CellString("c1")}},
+				{"?a": &Cell{S: CellString("a1")}, "?b": &Cell{S: CellString("b1")}, "?c": &Cell{S: CellString("c2")}},
+				{"?a": &Cell{S: CellString("a3")}, "?b": &Cell{S: CellString("b1")}, "?c": &Cell{S: CellString("c1")}},
+				{"?a": &Cell{S: CellString("a3")}, "?b": &Cell{S: CellString("b1")}, "?c": &Cell{S: CellString("c2")}},
+			},
+		},
+		{
+			t:    newTable([]string{"?a", "?b"}, []string{"a1", "b1"}),
+			t2:   newTable([]string{"?b"}, []string{"b2"}),
+			nbs:  2,
+			want: nil,
+		},
+		{
+			t:   newTable([]string{"?a"}, []string{"a1"}, []string{"a2"}),
+			t2:  newTable([]string{"?b"}, []string{"b1"}),
+			nbs: 2,
+			want: []Row{
+				{"?a": &Cell{S: CellString("a1")}, "?b": &Cell{S: CellString("b1")}},
+				{"?a": &Cell{S: CellString("a2")}, "?b": &Cell{S: CellString("b1")}},
+			},
+		},
+	}
+	for _, entry := range testTable {
+		if err := entry.t.InnerJoin(entry.t2); err != nil {
+			t.Errorf("Failed to inner join %s to %s with error %v", entry.t2, entry.t, err)
+		}
+		if got, want := len(entry.t.Bindings()), entry.nbs; got != want {
+			t.Errorf("InnerJoin returned the wrong number of bindings; got %d, want %d", got, want)
+		}
+		if got, want := entry.t.Rows(), entry.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("InnerJoin returned the wrong rows; got %v, want %v", got, want)
+		}
+	}
+}
+
 func TestDeleteRow(t *testing.T) {
 	testTable := []struct {
 		t   *Table
@@ -770,6 +831,33 @@ func Limit(t *testing.T) {
 	}
 }
 
+func TestOffset(t *testing.T) {
+	testTable := []struct {
+		in   int64
+		want int
+	}{
+		{100, 0},
+		{4, 0},
+		{3, 0},
+		{2, 1},
+		{1, 2},
+		{0, 3},
+		{-1, 3},
+	}
+	for _, entry := range testTable {
+		tbl := testDotTable(t, []string{"?foo"}, 3)
+		rws := tbl.Rows()
+		tbl.Offset(entry.in)
+		if got, want := len(tbl.Rows()), entry.want; got != want {
+			t.Errorf("tbl.Offset(%d) returned the wrong number of rows; got %d, want %d", entry.in, got, want)
+			continue
+		}
+		if entry.want > 0 && !reflect.DeepEqual(tbl.Rows()[0], rws[len(rws)-entry.want]) {
+			t.Errorf("tbl.Offset(%d) skipped the wrong rows; got %v, want %v", entry.in, tbl.Rows()[0], rws[len(rws)-entry.want])
+		}
+	}
+}
+
 func TestStringLess(t *testing.T) {
 	testTable := []struct {
 		i    string
@@ -897,6 +985,169 @@ func TestSumAccumulators(t *testing.T) {
 	if got, want := fv.(float64), float64(10); got != want {
 		t.Errorf("Int64 sum accumulator failed; got %f, want %f", got, want)
 	}
+	// decimal sum accumulator.
+	var (
+		dv interface{}
+		da = NewSumDecimalLiteralAccumulator(new(big.Rat))
+	)
+	for i := 0; i < 10; i++ {
+		l, _ := literal.DefaultBuilder().Parse(`"0.1"^^type:decimal`)
+		dv, _ = da.Accumulate(&Cell{L: l})
+	}
+	if got, want := dv.(*big.Rat).RatString(), "1"; got != want {
+		t.Errorf("Decimal sum accumulator failed; got %s, want %s", got, want)
+	}
+	da.Reset()
+	l, _ := literal.DefaultBuilder().Parse(`"0.25"^^type:decimal`)
+	if dv, _ = da.Accumulate(&Cell{L: l}); dv.(*big.Rat).RatString() != "1/4" {
+		t.Errorf("Decimal sum accumulator failed to reset; got %v, want 1/4", dv)
+	}
+	c, err := accumulatedCell(dv)
+	if err != nil || c.L == nil || c.L.Type() != literal.Decimal {
+		t.Errorf("accumulatedCell(%v) returned %v, %v; want a decimal literal", dv, c, err)
+	}
+}
+
+func TestBoolAccumulators(t *testing.T) {
+	boolCell := func(b bool) *Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Bool, b)
+		return &Cell{L: l}
+	}
+	testTable := []struct {
+		vs      []bool
+		any     bool
+		all     bool
+		comment string
+	}{
+		{[]bool{false, false, false}, false, false, "all false"},
+		{[]bool{false, true, false}, true, false, "some true"},
+		{[]bool{true, true, true}, true, true, "all true"},
+		{[]bool{true}, true, true, "single true"},
+	}
+	anyA, allA := NewAnyAccumulator(), NewAllAccumulator()
+	for _, entry := range testTable {
+		anyA.Reset()
+		allA.Reset()
+		var anyV, allV interface{}
+		for _, v := range entry.vs {
+			var err error
+			if anyV, err = anyA.Accumulate(boolCell(v)); err != nil {
+				t.Fatalf("Any accumulator failed for %s with error %v", entry.comment, err)
+			}
+			if allV, err = allA.Accumulate(boolCell(v)); err != nil {
+				t.Fatalf("All accumulator failed for %s with error %v", entry.comment, err)
+			}
+		}
+		if got, want := anyV.(bool), entry.any; got != want {
+			t.Errorf("Any accumulator failed for %s; got %v, want %v", entry.comment, got, want)
+		}
+		if got, want := allV.(bool), entry.all; got != want {
+			t.Errorf("All accumulator failed for %s; got %v, want %v", entry.comment, got, want)
+		}
+	}
+	l, _ := literal.DefaultBuilder().Build(literal.Int64, int64(1))
+	if _, err := NewAnyAccumulator().Accumulate(&Cell{L: l}); err == nil {
+		t.Errorf("Any accumulator should have failed to accumulate a non bool literal")
+	}
+	if _, err := NewAllAccumulator().Accumulate(&Cell{S: CellString("foo")}); err == nil {
+		t.Errorf("All accumulator should have failed to accumulate a non literal cell")
+	}
+	if _, err := NewAllAccumulator().Accumulate(nil); err == nil {
+		t.Errorf("All accumulator should have failed to accumulate a nil cell")
+	}
+	for _, n := range []string{"any", "ALL"} {
+		if _, ok := LookupAccumulator(n); !ok {
+			t.Errorf("LookupAccumulator(%q) should have returned a registered accumulator", n)
+		}
+	}
+}
+
+func TestExtremeAccumulators(t *testing.T) {
+	b := literal.DefaultBuilder()
+	i, _ := b.Build(literal.Int64, int64(2))
+	f, _ := b.Build(literal.Float64, 10.5)
+	d, _ := b.Build(literal.Decimal, big.NewRat(-1, 4))
+	s, _ := b.Build(literal.Text, "a")
+	testTable := []struct {
+		vs       []*literal.Literal
+		min, max *literal.Literal
+		comment  string
+	}{
+		{[]*literal.Literal{i}, i, i, "single value"},
+		{[]*literal.Literal{i, f, d}, d, f, "mixed numbers"},
+		{[]*literal.Literal{f, s, i}, i, s, "mixed kinds"},
+	}
+	minA, maxA := NewMinAccumulator(), NewMaxAccumulator()
+	for _, entry := range testTable {
+		minA.Reset()
+		maxA.Reset()
+		var minV, maxV interface{}
+		for _, v := range entry.vs {
+			var err error
+			if minV, err = minA.Accumulate(&Cell{L: v}); err != nil {
+				t.Fatalf("Min accumulator failed for %s with error %v", entry.comment, err)
+			}
+			if maxV, err = maxA.Accumulate(&Cell{L: v}); err != nil {
+				t.Fatalf("Max accumulator failed for %s with error %v", entry.comment, err)
+			}
+		}
+		if got, want := minV.(*literal.Literal), entry.min; got != want {
+			t.Errorf("Min accumulator failed for %s; got %v, want %v", entry.comment, got, want)
+		}
+		if got, want := maxV.(*literal.Literal), entry.max; got != want {
+			t.Errorf("Max accumulator failed for %s; got %v, want %v", entry.comment, got, want)
+		}
+	}
+	if _, err := NewMinAccumulator().Accumulate(&Cell{S: CellString("foo")}); err == nil {
+		t.Errorf("Min accumulator should have failed to accumulate a non literal cell")
+	}
+	for _, n := range []string{"min", "MAX"} {
+		if _, ok := LookupAccumulator(n); !ok {
+			t.Errorf("LookupAccumulator(%q) should have returned a registered accumulator", n)
+		}
+	}
+}
+
+func TestSortLiteralsByValue(t *testing.T) {
+	b := literal.DefaultBuilder()
+	var ls []*literal.Literal
+	for _, v := range []struct {
+		t literal.Type
+		v interface{}
+	}{
+		{literal.Int64, int64(10)},
+		{literal.Text, "a"},
+		{literal.Float64, 2.5},
+		{literal.Int64, int64(-3)},
+		{literal.Decimal, big.NewRat(1, 4)},
+		{literal.Bool, true},
+	} {
+		l, err := b.Build(v.t, v.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ls = append(ls, l)
+	}
+	tbl, err := New([]string{"?k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range ls {
+		tbl.AddRow(Row{"?k": &Cell{L: l}})
+	}
+	want := []*literal.Literal{ls[5], ls[3], ls[4], ls[2], ls[0], ls[1]}
+	for _, desc := range []bool{false, true} {
+		tbl.Sort(SortConfig{{Binding: "?k", Desc: desc}})
+		for i, r := range tbl.Rows() {
+			w := want[i]
+			if desc {
+				w = want[len(want)-1-i]
+			}
+			if got := r["?k"].L; got != w {
+				t.Errorf("tbl.Sort(desc=%v) returned %v at position %d; want %v", desc, got, i, w)
+			}
+		}
+	}
 }
 
 func TestCountAccumulators(t *testing.T) {
@@ -906,7 +1157,8 @@ func TestCountAccumulators(t *testing.T) {
 		ca = NewCountAccumulator()
 	)
 	for i := int64(0); i < 5; i++ {
-		cv, _ = ca.Accumulate(i)
+		l, _ := literal.DefaultBuilder().Build(literal.Int64, i)
+		cv, _ = ca.Accumulate(&Cell{L: l})
 	}
 	if got, want := cv.(int64), int64(5); got != want {
 		t.Errorf("Count accumulator failed; got %d, want %d", got, want)
@@ -918,13 +1170,109 @@ func TestCountAccumulators(t *testing.T) {
 	)
 	for i := int64(0); i < 10; i++ {
 		l, _ := literal.DefaultBuilder().Build(literal.Int64, i%2)
-		dv, _ = da.Accumulate(l)
+		dv, _ = da.Accumulate(&Cell{L: l})
 	}
 	if got, want := dv.(int64), int64(2); got != want {
 		t.Errorf("Count distinct accumulator failed; got %d, want %d", got, want)
 	}
 }
 
+func TestRegisterAccumulator(t *testing.T) {
+	f := func() Accumulator { return NewCountAccumulator() }
+	if err := RegisterAccumulator("Test_Count", f); err != nil {
+		t.Fatalf("table.RegisterAccumulator failed to register accumulator with error %v", err)
+	}
+	defer UnregisterAccumulator("test_count")
+	if err := RegisterAccumulator("test_COUNT", f); err == nil {
+		t.Errorf("table.RegisterAccumulator should have failed to register an already registered accumulator")
+	}
+	if err := RegisterAccumulator("", f); err == nil {
+		t.Errorf("table.RegisterAccumulator should have failed to register an empty name")
+	}
+	if err := RegisterAccumulator("nil_factory", nil); err == nil {
+		t.Errorf("table.RegisterAccumulator should have failed to register a nil factory")
+	}
+	got, ok := LookupAccumulator("TEST_count")
+	if !ok {
+		t.Fatalf("table.LookupAccumulator failed to find registered accumulator %q", "test_count")
+	}
+	if _, ok := got().(*countAcc); !ok {
+		t.Errorf("table.LookupAccumulator returned the wrong factory; got %T", got())
+	}
+	UnregisterAccumulator("test_count")
+	if _, ok := LookupAccumulator("test_count"); ok {
+		t.Errorf("table.LookupAccumulator should have not found unregistered accumulator %q", "test_count")
+	}
+}
+
+func TestAccumulatedCell(t *testing.T) {
+	n, err := node.Parse("/some/type<some id>")
+	if err != nil {
+		t.Fatalf("node.Parse failed with error %v", err)
+	}
+	p, err := predicate.Parse(`"foo"@[]`)
+	if err != nil {
+		t.Fatalf("predicate.Parse failed with error %v", err)
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Text, "foo")
+	if err != nil {
+		t.Fatalf("literal.Build failed with error %v", err)
+	}
+	bl, _ := literal.DefaultBuilder().Build(literal.Bool, true)
+	il, _ := literal.DefaultBuilder().Build(literal.Int64, int64(1))
+	fl, _ := literal.DefaultBuilder().Build(literal.Float64, float64(1))
+	bbl, _ := literal.DefaultBuilder().Build(literal.Blob, []byte("bar"))
+	tm := time.Now()
+	testTable := []struct {
+		v    interface{}
+		want *Cell
+	}{
+		{&Cell{S: CellString("foo")}, &Cell{S: CellString("foo")}},
+		{n, &Cell{N: n}},
+		{p, &Cell{P: p}},
+		{l, &Cell{L: l}},
+		{tm, &Cell{T: &tm}},
+		{&tm, &Cell{T: &tm}},
+		{true, &Cell{L: bl}},
+		{int64(1), &Cell{L: il}},
+		{float64(1), &Cell{L: fl}},
+		{"foo", &Cell{L: l}},
+		{[]byte("bar"), &Cell{L: bbl}},
+	}
+	for _, entry := range testTable {
+		got, err := accumulatedCell(entry.v)
+		if err != nil {
+			t.Errorf("accumulatedCell(%v) failed with error %v", entry.v, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("accumulatedCell(%v) returned the wrong cell; got %v, want %v", entry.v, got, entry.want)
+		}
+	}
+	for _, v := range []interface{}{nil, 1, (*node.Node)(nil), struct{}{}} {
+		if got, err := accumulatedCell(v); err == nil {
+			t.Errorf("accumulatedCell(%v) should have failed; instead it returned %v", v, got)
+		}
+	}
+}
+
+// firstAcc keeps the first accumulated cell. Used to test accumulators that
+// return non numeric values.
+type firstAcc struct {
+	state *Cell
+}
+
+func (f *firstAcc) Accumulate(c *Cell) (interface{}, error) {
+	if f.state == nil {
+		f.state = c
+	}
+	return f.state, nil
+}
+
+func (f *firstAcc) Reset() {
+	f.state = nil
+}
+
 func TestGroupRangeReduce(t *testing.T) {
 	int64LiteralCell := func(i int64) *Cell {
 		l, _ := literal.DefaultBuilder().Build(literal.Int64, i)
@@ -1085,12 +1433,194 @@ func TestTableReduce(t *testing.T) {
 		l, _ := literal.DefaultBuilder().Build(literal.Int64, i)
 		return &Cell{L: l}
 	}
+	boolLiteralCell := func(b bool) *Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Bool, b)
+		return &Cell{L: l}
+	}
 	testTable := []struct {
 		tbl  *Table
 		cfg  SortConfig
 		aap  []AliasAccPair
 		want *Table
 	}{
+		{
+			tbl: &Table{
+				AvailableBindings: []string{"?foo", "?bar"},
+				mbs: map[string]bool{
+					"?foo": true,
+					"?bar": true,
+				},
+				Data: []Row{
+					{
+						"?foo": &Cell{S: CellString("foo")},
+						"?bar": &Cell{S: CellString("bar")},
+					},
+					{
+						"?foo": &Cell{S: CellString("foo")},
+						"?bar": &Cell{S: CellString("bar")},
+					},
+					{
+						"?foo": &Cell{S: CellString("foo2")},
+						"?bar": &Cell{S: CellString("bar")},
+					},
+				},
+			},
+			cfg: SortConfig{{"?foo", false}},
+			aap: []AliasAccPair{
+				{
+					InAlias:  "?bar",
+					OutAlias: "?bar_alias",
+					Acc:      NewCountAccumulator(),
+				},
+			},
+			want: &Table{
+				AvailableBindings: []string{"?foo", "?bar_alias"},
+				mbs: map[string]bool{
+					"?foo":       true,
+					"?bar_alias": true,
+				},
+				Data: []Row{
+					{
+						"?foo":       &Cell{S: CellString("foo")},
+						"?bar_alias": int64LiteralCell(int64(2)),
+					},
+					{
+						"?foo":       &Cell{S: CellString("foo2")},
+						"?bar_alias": int64LiteralCell(int64(1)),
+					},
+				},
+			},
+		},
+		{
+			tbl: &Table{
+				AvailableBindings: []string{"?foo", "?bar", "?baz"},
+				mbs: map[string]bool{
+					"?foo": true,
+					"?bar": true,
+					"?baz": true,
+				},
+				Data: []Row{
+					{
+						"?foo": &Cell{S: CellString("foo")},
+						"?bar": &Cell{S: CellString("bar")},
+						"?baz": &Cell{S: CellString("baz")},
+					},
+				},
+			},
+			cfg: SortConfig{{"?foo", false}},
+			aap: []AliasAccPair{
+				{
+					InAlias:  "?bar",
+					OutAlias: "?bar_alias",
+					Acc:      NewCountAccumulator(),
+				},
+			},
+			want: nil,
+		},
+		{
+			tbl: &Table{
+				AvailableBindings: []string{"?foo", "?bar"},
+				mbs: map[string]bool{
+					"?foo": true,
+					"?bar": true,
+				},
+				Data: []Row{
+					{
+						"?foo": &Cell{S: CellString("foo")},
+						"?bar": &Cell{S: CellString("bar1")},
+					},
+					{
+						"?foo": &Cell{S: CellString("foo")},
+						"?bar": &Cell{S: CellString("bar2")},
+					},
+				},
+			},
+			cfg: SortConfig{{"?foo", false}},
+			aap: []AliasAccPair{
+				{
+					InAlias:  "?foo",
+					OutAlias: "?foo",
+				},
+				{
+					InAlias:  "?bar",
+					OutAlias: "?first",
+					Acc:      &firstAcc{},
+				},
+			},
+			want: &Table{
+				AvailableBindings: []string{"?foo", "?first"},
+				mbs: map[string]bool{
+					"?foo":   true,
+					"?first": true,
+				},
+				Data: []Row{
+					{
+						"?foo":   &Cell{S: CellString("foo")},
+						"?first": &Cell{S: CellString("bar1")},
+					},
+				},
+			},
+		},
+		{
+			tbl: &Table{
+				AvailableBindings: []string{"?device", "?exceeded"},
+				mbs: map[string]bool{
+					"?device":   true,
+					"?exceeded": true,
+				},
+				Data: []Row{
+					{
+						"?device":   &Cell{S: CellString("d1")},
+						"?exceeded": boolLiteralCell(false),
+					},
+					{
+						"?device":   &Cell{S: CellString("d1")},
+						"?exceeded": boolLiteralCell(true),
+					},
+					{
+						"?device":   &Cell{S: CellString("d2")},
+						"?exceeded": boolLiteralCell(true),
+					},
+				},
+			},
+			cfg: SortConfig{{"?device", false}},
+			aap: []AliasAccPair{
+				{
+					InAlias:  "?device",
+					OutAlias: "?device",
+				},
+				{
+					InAlias:  "?exceeded",
+					OutAlias: "?any",
+					Acc:      NewAnyAccumulator(),
+				},
+				{
+					InAlias:  "?exceeded",
+					OutAlias: "?all",
+					Acc:      NewAllAccumulator(),
+				},
+			},
+			want: &Table{
+				AvailableBindings: []string{"?device", "?any", "?all"},
+				mbs: map[string]bool{
+					"?device": true,
+					"?any":    true,
+					"?all":    true,
+				},
+				Data: []Row{
+					{
+						"?device": &Cell{S: CellString("d1")},
+						"?any":    boolLiteralCell(true),
+						"?all":    boolLiteralCell(false),
+					},
+					{
+						"?device": &Cell{S: CellString("d2")},
+						"?any":    boolLiteralCell(true),
+						"?all":    boolLiteralCell(true),
+					},
+				},
+			},
+		},
 		{
 			tbl: &Table{
 				AvailableBindings: []string{"?foo", "?bar"},
@@ -1560,6 +2090,29 @@ func TestLeftOptionalJoin(t *testing.T) {
 			right: cleanTable(),
 			want:  table(),
 		},
+		{
+			left: table(),
+			right: &Table{
+				AvailableBindings: []string{"?x"},
+				mbs:               map[string]bool{"?x": true},
+			},
+			want: cleanTable(
+				Row{
+					"?s": &Cell{S: CellString("1s")},
+					"?t": &Cell{S: CellString("1t")},
+					"?x": &Cell{},
+				},
+				Row{
+					"?s": &Cell{S: CellString("2s")},
+					"?t": &Cell{S: CellString("2t")},
+					"?x": &Cell{},
+				},
+				Row{
+					"?s": &Cell{S: CellString("3s")},
+					"?t": &Cell{S: CellString("3t")},
+					"?x": &Cell{},
+				}),
+		},
 	}
 
 	for i, entry := range entries {
@@ -1603,3 +2156,212 @@ func TestExtendRowWith(t *testing.T) {
 		t.Errorf("failed to extend a fully binded row; got %v, want %v", got, want)
 	}
 }
+
+func TestSortMixedCellKinds(t *testing.T) {
+	t1, t2 := time.Unix(0, 0), time.Unix(100, 0)
+	tbl, err := New([]string{"?k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Cell{{T: &t2}, {}, {T: &t1}, {}, {S: CellString("a")}, {T: &t2}, {}} {
+		tbl.AddRow(Row{"?k": c})
+	}
+	tbl.Sort(SortConfig{{Binding: "?k"}})
+	var got []string
+	for _, r := range tbl.Rows() {
+		got = append(got, r["?k"].String())
+	}
+	ts1, ts2 := t1.Format(time.RFC3339Nano), t2.Format(time.RFC3339Nano)
+	if want := []string{"<NULL>", "<NULL>", "<NULL>", "a", ts1, ts2, ts2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tbl.Sort failed to group cells of different kinds; got %v, want %v", got, want)
+	}
+}
+
+func TestTableHashReduce(t *testing.T) {
+	int64LiteralCell := func(i int64) *Cell {
+		l, _ := literal.DefaultBuilder().Build(literal.Int64, i)
+		return &Cell{L: l}
+	}
+	tbl := &Table{
+		AvailableBindings: []string{"?foo", "?bar"},
+		mbs: map[string]bool{
+			"?foo": true,
+			"?bar": true,
+		},
+		Data: []Row{
+			{
+				"?foo": &Cell{S: CellString("foo2")},
+				"?bar": &Cell{S: CellString("bar2")},
+			},
+			{
+				"?foo": &Cell{S: CellString("foo")},
+				"?bar": &Cell{S: CellString("bar")},
+			},
+			{
+				"?foo": &Cell{S: CellString("foo2")},
+				"?bar": &Cell{S: CellString("bar2")},
+			},
+			{
+				"?foo": &Cell{S: CellString("foo3")},
+				"?bar": &Cell{S: CellString("bar3")},
+			},
+			{
+				"?foo": &Cell{S: CellString("foo")},
+				"?bar": &Cell{S: CellString("bar")},
+			},
+			{
+				"?foo": &Cell{S: CellString("foo2")},
+				"?bar": &Cell{S: CellString("bar2")},
+			},
+		},
+	}
+	aaps := []AliasAccPair{
+		{
+			InAlias:  "?foo",
+			OutAlias: "?foo_alias",
+		},
+		{
+			InAlias:  "?bar",
+			OutAlias: "?bar_alias",
+			Acc:      NewCountAccumulator(),
+		},
+	}
+	if err := tbl.HashReduce(SortConfig{{"?foo", false}}, aaps); err != nil {
+		t.Fatalf("table.HashReduce failed with error %v", err)
+	}
+	want := &Table{
+		AvailableBindings: []string{"?foo_alias", "?bar_alias"},
+		mbs: map[string]bool{
+			"?foo_alias": true,
+			"?bar_alias": true,
+		},
+		Data: []Row{
+			{
+				"?foo_alias": &Cell{S: CellString("foo2")},
+				"?bar_alias": int64LiteralCell(int64(3)),
+			},
+			{
+				"?foo_alias": &Cell{S: CellString("foo")},
+				"?bar_alias": int64LiteralCell(int64(2)),
+			},
+			{
+				"?foo_alias": &Cell{S: CellString("foo3")},
+				"?bar_alias": int64LiteralCell(int64(1)),
+			},
+		},
+	}
+	if !reflect.DeepEqual(tbl, want) {
+		t.Errorf("table.HashReduce failed to produce correct reduce rows; got\n%s, want\n%s", tbl, want)
+	}
+	bad := &Table{
+		AvailableBindings: []string{"?foo", "?bar"},
+		mbs: map[string]bool{
+			"?foo": true,
+			"?bar": true,
+		},
+	}
+	if err := bad.HashReduce(SortConfig{{"?baz", false}}, aaps[1:]); err == nil {
+		t.Errorf("table.HashReduce should have failed for an invalid configuration")
+	}
+}
+
+func TestTableReduceGroupKeys(t *testing.T) {
+	newTable := func() *Table {
+		return &Table{
+			AvailableBindings: []string{"?foo", "?bar"},
+			mbs: map[string]bool{
+				"?foo": true,
+				"?bar": true,
+			},
+			Data: []Row{
+				{
+					"?foo": &Cell{S: CellString("a;")},
+					"?bar": &Cell{S: CellString("b")},
+				},
+				{
+					"?foo": &Cell{S: CellString("a")},
+					"?bar": &Cell{S: CellString(";b")},
+				},
+				{
+					"?foo": &Cell{S: CellString("a;")},
+					"?bar": &Cell{S: CellString("b")},
+				},
+			},
+		}
+	}
+	aaps := []AliasAccPair{
+		{
+			InAlias:  "?foo",
+			OutAlias: "?foo",
+		},
+		{
+			InAlias:  "?bar",
+			OutAlias: "?bar",
+		},
+	}
+	cfg := SortConfig{{"?foo", false}, {"?bar", false}}
+	for _, entry := range []struct {
+		name   string
+		reduce func(*Table) error
+	}{
+		{
+			name: "Reduce",
+			reduce: func(tbl *Table) error {
+				return tbl.Reduce(cfg, aaps)
+			},
+		},
+		{
+			name: "HashReduce",
+			reduce: func(tbl *Table) error {
+				return tbl.HashReduce(cfg, aaps)
+			},
+		},
+	} {
+		tbl := newTable()
+		if err := entry.reduce(tbl); err != nil {
+			t.Fatalf("table.%s failed with error %v", entry.name, err)
+		}
+		if got, want := tbl.NumRows(), 2; got != want {
+			t.Errorf("table.%s returned %d groups for rows with different values; want %d\n%s", entry.name, got, want, tbl)
+		}
+	}
+}
+
+func TestUnion(t *testing.T) {
+	t1, err := New([]string{"?foo", "?bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t1.AddRow(Row{
+		"?foo": &Cell{S: CellString("foo1")},
+		"?bar": &Cell{S: CellString("bar1")},
+	})
+	t2, err := New([]string{"?foo", "?baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2.AddRow(Row{
+		"?foo": &Cell{S: CellString("foo2")},
+		"?baz": &Cell{S: CellString("baz2")},
+	})
+	t1.Union(t2)
+	t1.Union(nil)
+	if got, want := t1.Bindings(), []string{"?foo", "?bar", "?baz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("t.Union returned the wrong bindings; got %v, want %v", got, want)
+	}
+	want := []Row{
+		{
+			"?foo": &Cell{S: CellString("foo1")},
+			"?bar": &Cell{S: CellString("bar1")},
+			"?baz": &Cell{},
+		},
+		{
+			"?foo": &Cell{S: CellString("foo2")},
+			"?bar": &Cell{},
+			"?baz": &Cell{S: CellString("baz2")},
+		},
+	}
+	if got := t1.Rows(); !reflect.DeepEqual(got, want) {
+		t.Errorf("t.Union returned the wrong rows; got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/bql/planner"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+)
+
+// ErrNotIncremental is returned by NewView for the queries whose results
+// cannot be maintained from the changes of the graph they query.
+var ErrNotIncremental = errors.New("bql: the results of the query cannot be maintained incrementally")
+
+// View keeps the results of a standing query up to date by applying the
+// changes of the graph it queries, instead of evaluating it again. Only
+// queries whose rows are each computed from a single triple are supported:
+// they query one graph with one non optional clause, and have no subqueries,
+// unions, aggregations, HAVING, LIMIT, OFFSET, or filters ranking rows. The
+// rows added or removed by a change are then the results of the query over
+// the triples of the change alone.
+type View struct {
+	query    string
+	graph    string
+	cls      *semantic.GraphClause
+	chanSize int
+	bulkSize int
+	// present contains the UUIDs of the triples of the graph the results
+	// account for, so changes already accounted for are skipped.
+	present map[string]bool
+}
+
+// NewView returns the view of the provided SELECT query. It returns
+// ErrNotIncremental if its results cannot be maintained incrementally.
+func NewView(query string, chanSize, bulkSize int) (*View, error) {
+	stm, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
+	if stm.Type() != semantic.Query {
+		return nil, fmt.Errorf("only SELECT statements can be viewed, got %q", query)
+	}
+	if !incremental(stm) {
+		return nil, ErrNotIncremental
+	}
+	return &View{
+		query:    query,
+		graph:    stm.InputGraphNames()[0],
+		cls:      stm.GraphPatternClauses()[0],
+		chanSize: chanSize,
+		bulkSize: bulkSize,
+		present:  make(map[string]bool),
+	}, nil
+}
+
+// parse returns the statement of the provided query.
+func parse(query string) (*semantic.Statement, error) {
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize a valid BQL parser with error %v", err)
+	}
+	stm := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(query, 1), stm); err != nil {
+		return nil, fmt.Errorf("failed to parse BQL statement with error %v", err)
+	}
+	return stm, nil
+}
+
+// incremental returns true if each row of the results of the query is
+// computed from a single triple of a single graph.
+func incremental(stm *semantic.Statement) bool {
+	cls := stm.GraphPatternClauses()
+	if len(stm.InputGraphNames()) != 1 || len(cls) != 1 || stm.GraphPatternUnions() != nil || len(stm.Subqueries()) > 0 {
+		return false
+	}
+	if c := cls[0]; c.Optional || c.GraphBinding != "" || c.ReifiedBinding != "" || len(c.PPath) > 0 {
+		return false
+	}
+	if len(stm.GroupByBindings()) > 0 || stm.HasHavingClause() || stm.IsLimitSet() || stm.IsOffsetSet() || stm.IsExplain() || len(stm.Parameters()) > 0 {
+		return false
+	}
+	for _, prj := range stm.Projections() {
+		if prj.OP != lexer.ItemError {
+			return false
+		}
+	}
+	for _, f := range stm.Filters() {
+		if f.NeedsRanking() {
+			return false
+		}
+	}
+	return true
+}
+
+// Graph returns the name of the graph queried by the view.
+func (v *View) Graph() string {
+	return v.graph
+}
+
+// Init returns the results of the query computed from the triples currently
+// in the provided graph. The graph should be watched before calling Init, so
+// no change is missed; the changes already accounted for by the results are
+// skipped by Apply. The triples that may match the clause are kept in memory
+// while computing the results.
+func (v *View) Init(ctx context.Context, g storage.Graph) (*table.Table, error) {
+	ts, err := v.lookup(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	v.present = make(map[string]bool)
+	for _, t := range ts {
+		v.present[t.UUID().String()] = true
+	}
+	return v.eval(ctx, ts)
+}
+
+// lookup returns the triples of the graph that may match the clause of the
+// query, looked up by its specified components.
+func (v *View) lookup(ctx context.Context, g storage.Graph) ([]*triple.Triple, error) {
+	var (
+		cls  = v.cls
+		lo   = storage.DefaultLookup
+		trps = make(chan *triple.Triple, v.chanSize)
+		errc = make(chan error, 1)
+	)
+	go func() {
+		switch {
+		case cls.S != nil && cls.P != nil:
+			errc <- g.TriplesForSubjectAndPredicate(ctx, cls.S, cls.P, lo, trps)
+		case cls.P != nil && cls.O != nil:
+			errc <- g.TriplesForPredicateAndObject(ctx, cls.P, cls.O, lo, trps)
+		case cls.S != nil:
+			errc <- g.TriplesForSubject(ctx, cls.S, lo, trps)
+		case cls.P != nil:
+			errc <- g.TriplesForPredicate(ctx, cls.P, lo, trps)
+		case cls.O != nil:
+			errc <- g.TriplesForObject(ctx, cls.O, lo, trps)
+		default:
+			errc <- g.Triples(ctx, lo, trps)
+		}
+	}()
+	var ts []*triple.Triple
+	for t := range trps {
+		ts = append(ts, t)
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Apply returns the rows the provided change adds to the results of the query
+// if it adds triples, or the rows it removes if it removes triples. The
+// triples whose addition or removal is already accounted for are skipped.
+func (v *View) Apply(ctx context.Context, c *storage.Change) (*table.Table, error) {
+	var ts []*triple.Triple
+	for _, t := range c.Triples {
+		id := t.UUID().String()
+		if v.present[id] == (c.Op == storage.Added) {
+			continue
+		}
+		if c.Op == storage.Added {
+			v.present[id] = true
+		} else {
+			delete(v.present, id)
+		}
+		ts = append(ts, t)
+	}
+	return v.eval(ctx, ts)
+}
+
+// eval returns the results of the query over a graph only containing the
+// provided triples.
+func (v *View) eval(ctx context.Context, ts []*triple.Triple) (*table.Table, error) {
+	s := memory.NewStore()
+	g, err := s.NewGraph(ctx, v.graph)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		return nil, err
+	}
+	// Statements cannot be planned twice, so the query is parsed again for
+	// every evaluation.
+	stm, err := parse(v.query)
+	if err != nil {
+		return nil, err
+	}
+	pln, err := planner.New(ctx, s, stm, v.chanSize, v.bulkSize, nil)
+	if err != nil {
+		return nil, err
+	}
+	return pln.Execute(ctx)
+}
@@ -0,0 +1,169 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bql
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+func TestNewView(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want error
+	}{
+		{`select ?s, ?o from ?test where {?s "parent_of"@[] ?o};`, nil},
+		{`select ?o from ?test where {/u<joe> "parent_of"@[] ?o};`, nil},
+		{`select ?s, ?p, ?o from ?test where {?s ?p ?o . filter(?o != /u<mary>)} order by ?s;`, nil},
+		{`select ?s, ?a from ?test where {?s "age"@[] ?a} having not(?s = ?s);`, ErrNotIncremental},
+		{`select ?s, ?g from ?test where {?s "parent_of"@[] ?c . ?c "parent_of"@[] ?g};`, ErrNotIncremental},
+		{`select ?s, ?o from ?test where {?s "parent_of"@[] ?o} limit "1"^^type:int64;`, ErrNotIncremental},
+		{`select ?s, count(?o) as ?n from ?test where {?s "parent_of"@[] ?o} group by ?s;`, ErrNotIncremental},
+		{`select ?s, ?o from ?test, ?other where {?s "parent_of"@[] ?o};`, ErrNotIncremental},
+		{`select ?s, ?n from ?test where {?s "parent_of"@[] ?o . optional {?o "name"@[] ?n}};`, ErrNotIncremental},
+	}
+	for _, entry := range testTable {
+		v, err := NewView(entry.q, 0, 10)
+		if err != entry.want {
+			t.Errorf("NewView(%q) returned error %v; want %v", entry.q, err, entry.want)
+		}
+		if err == nil && v.Graph() != "?test" {
+			t.Errorf("NewView(%q).Graph() returned %q; want ?test", entry.q, v.Graph())
+		}
+	}
+	if _, err := NewView(`create graph ?test;`, 0, 10); err == nil {
+		t.Errorf("NewView should have rejected a statement that is not a query")
+	}
+}
+
+// countRows returns the number of times each row of the table appears, keyed
+// by its text line.
+func countRows(tbl *table.Table) map[string]int {
+	res := make(map[string]int)
+	for _, r := range tbl.Rows() {
+		var buf bytes.Buffer
+		r.ToTextLine(&buf, tbl.Bindings(), "\t")
+		res[buf.String()]++
+	}
+	return res
+}
+
+func TestViewApply(t *testing.T) {
+	queries := []string{
+		`select ?s, ?o from ?test where {?s "parent_of"@[] ?o};`,
+		`select ?o from ?test where {/u<peter> "parent_of"@[] ?o};`,
+		`select ?s from ?test where {?s "parent_of"@[] ?o . filter(?o != /u<mary>)};`,
+		`select ?s, ?p from ?test where {?s ?p "Mary"^^type:text};`,
+	}
+	changes := []struct {
+		op storage.ChangeOp
+		ts string
+	}{
+		{storage.Added, `/u<mary> "parent_of"@[] /u<amy>
+			/u<peter> "parent_of"@[] /u<tom>`},
+		{storage.Removed, `/u<joe> "parent_of"@[] /u<mary>
+			/u<peter> "name"@[] "Peter"^^type:text`},
+		{storage.Added, `/u<joe> "parent_of"@[] /u<mary>
+			/u<amy> "name"@[] "Mary"^^type:text`},
+		{storage.Removed, `/u<peter> "parent_of"@[] /u<tom>
+			/u<peter> "parent_of"@[] /u<john>
+			/u<peter> "parent_of"@[] /u<eve>`},
+	}
+	for _, q := range queries {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := testStore(ctx, t)
+		g, err := s.Graph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := NewView(q, 0, 10)
+		if err != nil {
+			t.Fatalf("NewView(%q) failed with error %v", q, err)
+		}
+		cs, err := storage.Watch(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tbl, err := v.Init(ctx, g)
+		if err != nil {
+			t.Fatalf("View.Init(%q) failed with error %v", q, err)
+		}
+		rows := countRows(tbl)
+		for _, c := range changes {
+			var ts []*triple.Triple
+			for _, l := range strings.Split(c.ts, "\n") {
+				trpl, err := triple.Parse(strings.TrimSpace(l), literal.DefaultBuilder())
+				if err != nil {
+					t.Fatal(err)
+				}
+				ts = append(ts, trpl)
+			}
+			if c.op == storage.Added {
+				err = g.AddTriples(ctx, ts)
+			} else {
+				err = g.RemoveTriples(ctx, ts)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Graphs may notify the triples of a change in several ones.
+			for n := 0; n < len(ts); {
+				ch := <-cs
+				n += len(ch.Triples)
+				tbl, err := v.Apply(ctx, ch)
+				if err != nil {
+					t.Fatalf("View.Apply(%q) failed with error %v", q, err)
+				}
+				for r, n := range countRows(tbl) {
+					if c.op == storage.Removed {
+						n = -n
+					}
+					if rows[r] += n; rows[r] == 0 {
+						delete(rows, r)
+					}
+				}
+			}
+			// Changes already accounted for do not change the results.
+			tbl, err = v.Apply(ctx, &storage.Change{Op: c.op, Triples: ts})
+			if err != nil {
+				t.Fatalf("View.Apply(%q) failed with error %v", q, err)
+			}
+			if n := len(tbl.Rows()); n != 0 {
+				t.Errorf("View.Apply(%q) of a change already applied returned %d rows; want none", q, n)
+			}
+
+			p, err := Prepare(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := p.Execute(ctx, s, nil, 0, 10, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := rows, countRows(want); !reflect.DeepEqual(got, want) {
+				t.Errorf("View(%q) after %v changes returned rows %v; want %v", q, c.op, got, want)
+			}
+		}
+		cancel()
+	}
+}
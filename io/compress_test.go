@@ -0,0 +1,111 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple/literal"
+)
+
+const compressedTriple = "/u<john>\t\"knows\"@[]\t/u<mary>\n"
+
+// bzip2Triple is compressedTriple compressed using bzip2.
+var bzip2Triple = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x32, 0xc8,
+	0x6a, 0x56, 0x00, 0x00, 0x02, 0xdf, 0x80, 0x00, 0x30, 0x10, 0x00, 0x80,
+	0x05, 0x40, 0x00, 0x00, 0x0a, 0x20, 0x5b, 0x9a, 0xa0, 0x20, 0x00, 0x22,
+	0x9a, 0x1a, 0x34, 0x03, 0x35, 0x34, 0x28, 0x1a, 0x68, 0x64, 0x64, 0xc4,
+	0x34, 0x26, 0x9d, 0x08, 0x41, 0x5d, 0x33, 0xa6, 0x1c, 0x32, 0x50, 0x30,
+	0x1f, 0xd0, 0xbb, 0xfc, 0x5d, 0xc9, 0x14, 0xe1, 0x42, 0x40, 0xcb, 0x21,
+	0xa9, 0x58,
+}
+
+// gzipped returns the provided strings compressed using gzip, each of them as
+// its own gzip member.
+func gzipped(t *testing.T, ss ...string) []byte {
+	var buffer bytes.Buffer
+	for _, s := range ss {
+		w := gzip.NewWriter(&buffer)
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("gzip.Write failed with error %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("gzip.Close failed with error %v", err)
+		}
+	}
+	return buffer.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	table := []struct {
+		in   []byte
+		want string
+	}{
+		{in: []byte(compressedTriple), want: compressedTriple},
+		{in: []byte{}, want: ""},
+		{in: []byte("B"), want: "B"},
+		{in: gzipped(t, compressedTriple), want: compressedTriple},
+		{in: gzipped(t, compressedTriple, compressedTriple), want: compressedTriple + compressedTriple},
+		{in: bzip2Triple, want: compressedTriple},
+	}
+	for _, entry := range table {
+		r, err := Decompress(bytes.NewReader(entry.in))
+		if err != nil {
+			t.Errorf("io.Decompress(%q) failed with error %v", entry.in, err)
+			continue
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("io.Decompress(%q) failed to read with error %v", entry.in, err)
+			continue
+		}
+		if string(got) != entry.want {
+			t.Errorf("io.Decompress(%q) read %q; want %q", entry.in, got, entry.want)
+		}
+	}
+	if _, err := Decompress(bytes.NewReader([]byte{0x1f, 0x8b, 0x00})); err == nil {
+		t.Errorf("io.Decompress should have failed for a truncated gzip header")
+	}
+}
+
+func TestReadCompressed(t *testing.T) {
+	ctx := context.Background()
+	for _, in := range [][]byte{gzipped(t, compressedTriple), bzip2Triple} {
+		g, err := memory.NewStore().NewGraph(ctx, "test")
+		if err != nil {
+			t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+		}
+		if cnt, err := ReadIntoGraph(ctx, g, bytes.NewReader(in), literal.DefaultBuilder()); err != nil || cnt != 1 {
+			t.Errorf("io.ReadIntoGraph(%q) returned (%d, %v); want (1, nil)", in, cnt, err)
+		}
+	}
+	nt := "<http://example.com/a> <http://example.com/b> <http://example.com/c> .\n"
+	if got, err := readNTriples(string(gzipped(t, nt)), nil); err != nil || len(got) != 1 {
+		t.Errorf("io.ReadNTriples(%q) returned (%v, %v); want one triple", nt, got, err)
+	}
+	if got, err := readTurtle(string(gzipped(t, nt)), nil); err != nil || len(got) != 1 {
+		t.Errorf("io.ReadTurtle(%q) returned (%v, %v); want one triple", nt, got, err)
+	}
+	jld := `{"@id": "http://example.com/a", "http://example.com/b": {"@id": "http://example.com/c"}}`
+	if got, err := readJSONLD(string(gzipped(t, jld)), nil); err != nil || len(got) != 1 {
+		t.Errorf("io.ReadJSONLD(%q) returned (%v, %v); want one triple", jld, got, err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// TriplesSince sends to the provided channel the triples of the graph whose
+// predicate time anchor is after the provided one, and closes the channel once
+// done. Interval predicates are anchored at the start of their interval.
+// Immutable triples have no time anchor, and are never sent. Graphs do not
+// track when triples were added, so triples added with old time anchors after
+// a previous export are not sent either.
+func TriplesSince(ctx context.Context, g storage.Graph, since time.Time, ts chan<- *triple.Triple) error {
+	defer close(ts)
+	var (
+		wg   sync.WaitGroup
+		gErr error
+	)
+	trpls := make(chan *triple.Triple)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		gErr = g.Triples(ctx, &storage.LookupOptions{LowerAnchor: &since}, trpls)
+	}()
+	var err error
+	for t := range trpls {
+		if err != nil {
+			continue
+		}
+		ta, tErr := t.Predicate().TimeAnchor()
+		if tErr != nil || !ta.After(since) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case ts <- t:
+		}
+	}
+	wg.Wait()
+	if gErr != nil {
+		return gErr
+	}
+	return err
+}
+
+// WriteGraphSince serializes into the writer, one per line, the triples of the
+// graph whose predicate time anchor is after the provided one, as selected by
+// TriplesSince. It returns the number of triples serialized, and the latest
+// time anchor among them, or since if none was serialized. Periodic sync jobs
+// can use the returned time anchor as the starting point of the next export,
+// only paying for the triples added in between.
+func WriteGraphSince(ctx context.Context, w io.Writer, g storage.Graph, since time.Time) (int, time.Time, error) {
+	var (
+		wg   sync.WaitGroup
+		tErr error
+		wErr error
+	)
+	latest, cnt, ts := since, 0, make(chan *triple.Triple)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tErr = TriplesSince(ctx, g, since, ts)
+	}()
+	for t := range ts {
+		if wErr != nil {
+			continue
+		}
+		if _, err := io.WriteString(w, fmt.Sprintf("%s\n", t.String())); err != nil {
+			wErr = err
+			continue
+		}
+		if ta, _ := t.Predicate().TimeAnchor(); ta.After(latest) {
+			latest = *ta
+		}
+		cnt++
+	}
+	wg.Wait()
+	if tErr != nil {
+		return 0, since, tErr
+	}
+	if wErr != nil {
+		return 0, since, wErr
+	}
+	return cnt, latest, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/storage/memory"
+)
+
+func TestWriteGraphSince(t *testing.T) {
+	ctx := context.Background()
+	g, err := memory.NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	ts := parseTriples(t,
+		"/u<john>\t\"knows\"@[]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-01-01T00:00:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2017-01-01T00:00:00Z]\t/u<peter>",
+		"/u<john>\t\"lives_in\"@[2015-01-01T00:00:00Z/2018-01-01T00:00:00Z]\t/city<paris>",
+		"/u<john>\t\"lives_in\"@[2018-01-01T00:00:00Z/2019-01-01T00:00:00Z]\t/city<rome>",
+	)
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(%v) failed with error %v", ts, err)
+	}
+	date := func(y int) time.Time {
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	table := []struct {
+		since  time.Time
+		want   []string
+		latest time.Time
+	}{
+		{
+			since:  date(2000),
+			want:   []string{ts[1].String(), ts[2].String(), ts[3].String(), ts[4].String()},
+			latest: date(2018),
+		},
+		{
+			since:  date(2016),
+			want:   []string{ts[2].String(), ts[4].String()},
+			latest: date(2018),
+		},
+		{
+			since:  date(2018),
+			latest: date(2018),
+		},
+	}
+	for _, entry := range table {
+		var buffer bytes.Buffer
+		cnt, latest, err := WriteGraphSince(ctx, &buffer, g, entry.since)
+		if err != nil {
+			t.Fatalf("io.WriteGraphSince(%v) failed with error %v", entry.since, err)
+		}
+		var got []string
+		if s := strings.TrimSpace(buffer.String()); s != "" {
+			got = strings.Split(s, "\n")
+		}
+		sort.Strings(got)
+		sort.Strings(entry.want)
+		if strings.Join(got, "\n") != strings.Join(entry.want, "\n") || cnt != len(entry.want) {
+			t.Errorf("io.WriteGraphSince(%v) wrote %d triples %v; want %v", entry.since, cnt, got, entry.want)
+		}
+		if !latest.Equal(entry.latest) {
+			t.Errorf("io.WriteGraphSince(%v) returned latest anchor %v; want %v", entry.since, latest, entry.latest)
+		}
+	}
+}
@@ -16,11 +16,9 @@
 package io
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io"
-	"strings"
 	"sync"
 
 	"github.com/google/badwolf/storage"
@@ -30,23 +28,33 @@ import (
 
 // ReadIntoGraph reads a graph out of the provided reader. The data on the
 // reader is interpret as text. Each line represents one triple using the
-// standard serialized format. ReadIntoGraph will stop if fails to Parse
-// a triple on the stream. The triples read till then would have also been
-// added to the graph. The int value returns the number of triples added.
+// standard serialized format. Lines are parsed and added concurrently as done
+// by LoadGraph using the default options. Malformed lines do not stop the
+// load; if there are any, the error of the first one is returned once the rest
+// of the triples have been added to the graph. Compressed readers are
+// decompressed as done by Decompress. The int value returns the number of
+// triples added.
 func ReadIntoGraph(ctx context.Context, g storage.Graph, r io.Reader, b literal.Builder) (int, error) {
-	cnt, scanner := 0, bufio.NewScanner(r)
-	scanner.Split(bufio.ScanLines)
-	for scanner.Scan() {
-		text := strings.TrimSpace(scanner.Text())
-		if text == "" {
-			continue
-		}
-		t, err := triple.Parse(text, b)
-		if err != nil {
+	rep, err := LoadGraph(ctx, g, r, b, nil)
+	if err != nil {
+		return rep.Triples, err
+	}
+	if len(rep.Errors) > 0 {
+		return rep.Triples, rep.Errors[0]
+	}
+	return rep.Triples, nil
+}
+
+// WriteTriples serializes the provided triples into the writer where each
+// triple is marshaled into a separate line. If there is an error writing the
+// serialization will stop. It returns the number of triples serialized.
+func WriteTriples(w io.Writer, ts []*triple.Triple) (int, error) {
+	cnt := 0
+	for _, t := range ts {
+		if _, err := io.WriteString(w, fmt.Sprintf("%s\n", t.String())); err != nil {
 			return cnt, err
 		}
 		cnt++
-		g.AddTriples(ctx, []*triple.Triple{t})
 	}
 	return cnt, nil
 }
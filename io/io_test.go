@@ -74,6 +74,25 @@ func TestWriteIntoGraph(t *testing.T) {
 	}
 }
 
+func TestWriteTriples(t *testing.T) {
+	var buffer bytes.Buffer
+	ts := getTestTriples(t)
+	cnt, err := WriteTriples(&buffer, ts)
+	if err != nil {
+		t.Fatalf("io.WriteTriples failed to write %v with error %v", ts, err)
+	}
+	if cnt != len(ts) {
+		t.Errorf("io.WriteTriples should have been able to write %d triples not %d", len(ts), cnt)
+	}
+	var want bytes.Buffer
+	for _, trpl := range ts {
+		want.WriteString(fmt.Sprintf("%s\n", trpl.String()))
+	}
+	if got, want := buffer.String(), want.String(); got != want {
+		t.Errorf("io.WriteTriples wrote the wrong serialization; got %q, want %q", got, want)
+	}
+}
+
 func TestSerializationContents(t *testing.T) {
 	var buffer bytes.Buffer
 	ts, ctx := getTestTriples(t), context.Background()
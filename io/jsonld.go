@@ -0,0 +1,740 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+)
+
+// JSONLDOptions configures how triples are mapped to and from JSON-LD.
+type JSONLDOptions struct {
+	NTriplesOptions
+	// Context maps terms to the IRIs they stand for. WriteJSONLD writes it as
+	// the context of the document and uses it to compact IRIs, either as
+	// terms or as compact IRIs such as foaf:name. ReadJSONLD applies it before
+	// the contexts of the document.
+	Context map[string]string
+}
+
+// jsonldTerm is the definition of a term of a JSON-LD context.
+type jsonldTerm struct {
+	iri string
+	// typ is the type the values of the term are coerced to: @id, @vocab,
+	// or the IRI of a datatype.
+	typ       string
+	container string
+	reverse   bool
+}
+
+// jsonldContext is an active JSON-LD context.
+type jsonldContext struct {
+	base  *url.URL
+	vocab string
+	terms map[string]*jsonldTerm
+}
+
+// clone returns a copy of the context which can be modified.
+func (c *jsonldContext) clone() *jsonldContext {
+	nc := &jsonldContext{
+		base:  c.base,
+		vocab: c.vocab,
+		terms: make(map[string]*jsonldTerm, len(c.terms)),
+	}
+	for k, v := range c.terms {
+		nc.terms[k] = v
+	}
+	return nc
+}
+
+// expand returns the IRI for the provided value, which can also be a blank
+// node identifier or a keyword. Terms and the vocabulary mapping are only used
+// for vocabulary relative values, such as properties and types, while
+// document relative values, such as node identifiers, are resolved against the
+// base. It returns false if the value cannot be expanded.
+func (c *jsonldContext) expand(v string, vocab bool) (string, bool) {
+	if strings.HasPrefix(v, "@") {
+		return v, true
+	}
+	if t, ok := c.terms[v]; ok && vocab {
+		return t.iri, t.iri != ""
+	}
+	if i := strings.Index(v, ":"); i >= 0 {
+		pfx, sfx := v[:i], v[i+1:]
+		if pfx == "_" || strings.HasPrefix(sfx, "//") {
+			return v, true
+		}
+		if t, ok := c.terms[pfx]; ok && t.iri != "" {
+			return t.iri + sfx, true
+		}
+		if node.ValidateIRI(v) == nil {
+			return v, true
+		}
+	}
+	if vocab {
+		return c.vocab + v, c.vocab != ""
+	}
+	if c.base == nil {
+		return "", false
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return "", false
+	}
+	return c.base.ResolveReference(u).String(), true
+}
+
+// merge returns the context resulting of processing the provided local
+// context, which can be an object, null, or an array of them. Remote contexts
+// are not supported.
+func (c *jsonldContext) merge(lc interface{}) (*jsonldContext, error) {
+	switch v := lc.(type) {
+	case nil:
+		return &jsonldContext{base: c.base, terms: make(map[string]*jsonldTerm)}, nil
+	case []interface{}:
+		nc := c
+		for _, e := range v {
+			var err error
+			if nc, err = nc.merge(e); err != nil {
+				return nil, err
+			}
+		}
+		return nc, nil
+	case string:
+		return nil, fmt.Errorf("remote context %q is not supported", v)
+	case map[string]interface{}:
+		nc := c.clone()
+		if b, ok := v["@base"]; ok {
+			switch b := b.(type) {
+			case nil:
+				nc.base = nil
+			case string:
+				u, err := url.Parse(b)
+				if err != nil {
+					return nil, fmt.Errorf("invalid @base %q; %v", b, err)
+				}
+				if nc.base != nil {
+					u = nc.base.ResolveReference(u)
+				}
+				nc.base = u
+			default:
+				return nil, fmt.Errorf("invalid @base %v", b)
+			}
+		}
+		if vb, ok := v["@vocab"]; ok {
+			switch vb := vb.(type) {
+			case nil:
+				nc.vocab = ""
+			case string:
+				iri, ok := nc.expand(vb, true)
+				if !ok {
+					return nil, fmt.Errorf("invalid @vocab %q", vb)
+				}
+				nc.vocab = iri
+			default:
+				return nil, fmt.Errorf("invalid @vocab %v", vb)
+			}
+		}
+		// Terms can be defined using other terms of the same context, so they
+		// are added first and expanded afterwards.
+		var ks []string
+		raw := make(map[string]map[string]interface{})
+		for k, d := range v {
+			if strings.HasPrefix(k, "@") {
+				continue
+			}
+			switch d := d.(type) {
+			case nil:
+				nc.terms[k] = &jsonldTerm{}
+			case string:
+				raw[k] = map[string]interface{}{"@id": d}
+			case map[string]interface{}:
+				raw[k] = d
+			default:
+				return nil, fmt.Errorf("invalid definition of term %q", k)
+			}
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		for _, k := range ks {
+			if id, ok := raw[k]["@id"].(string); ok {
+				nc.terms[k] = &jsonldTerm{iri: id}
+			}
+		}
+		for _, k := range ks {
+			d, ok := raw[k]
+			if !ok {
+				continue
+			}
+			t, err := nc.define(k, d)
+			if err != nil {
+				return nil, err
+			}
+			nc.terms[k] = t
+		}
+		return nc, nil
+	}
+	return nil, fmt.Errorf("invalid context %v", lc)
+}
+
+// define returns the definition of the provided term.
+func (c *jsonldContext) define(k string, d map[string]interface{}) (*jsonldTerm, error) {
+	t := &jsonldTerm{}
+	id, ok := d["@id"]
+	if r, rok := d["@reverse"]; rok {
+		id, ok, t.reverse = r, true, true
+	}
+	if !ok {
+		id = k
+	}
+	s, isString := id.(string)
+	if !isString {
+		return nil, fmt.Errorf("invalid IRI for term %q", k)
+	}
+	// Terms defined using themselves would expand to the raw definition.
+	delete(c.terms, k)
+	iri, ok := c.expand(s, true)
+	if !ok {
+		return nil, fmt.Errorf("cannot expand the IRI of term %q", k)
+	}
+	t.iri = iri
+	if ty, ok := d["@type"].(string); ok {
+		if t.typ, ok = c.expand(ty, true); !ok {
+			return nil, fmt.Errorf("cannot expand the type of term %q", k)
+		}
+	}
+	if ct, ok := d["@container"].(string); ok {
+		t.container = ct
+	}
+	return t, nil
+}
+
+// jsonldParser emits the statements of a JSON-LD document.
+type jsonldParser struct {
+	b     literal.Builder
+	scope *node.BlankNodeScope
+	sink  *tripleSink
+}
+
+// blank returns the term of a new blank node.
+func (p *jsonldParser) blank() string {
+	if p.scope != nil {
+		return "_:" + p.scope.NewBlankNode().ID().String()
+	}
+	return "_:" + node.NewBlankNode().ID().String()
+}
+
+// ref returns the term for the provided expanded IRI or blank node identifier.
+func (p *jsonldParser) ref(iri string) string {
+	if strings.HasPrefix(iri, "_:") {
+		if p.scope != nil {
+			return "_:" + p.scope.Labeled(iri[2:]).ID().String()
+		}
+		return iri
+	}
+	return "<" + iri + ">"
+}
+
+// emit parses the statement for the provided terms and adds it to the sink.
+func (p *jsonldParser) emit(s, v, o string) error {
+	t, err := triple.ParseNTriple(s+" "+v+" "+o+" .", p.b)
+	if err != nil {
+		return fmt.Errorf("io.ReadJSONLD: %v", err)
+	}
+	return p.sink.add(t)
+}
+
+// sortedKeys returns the keys of the provided object in order, so documents
+// are always read the same way.
+func sortedKeys(m map[string]interface{}) []string {
+	var ks []string
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// node emits the statements of the provided node object and returns the term
+// of its subject. Nested node objects without an @id become blank nodes.
+func (p *jsonldParser) node(m map[string]interface{}, c *jsonldContext) (string, error) {
+	if lc, ok := m["@context"]; ok {
+		var err error
+		if c, err = c.merge(lc); err != nil {
+			return "", fmt.Errorf("io.ReadJSONLD: %v", err)
+		}
+	}
+	s := ""
+	if id, ok := m["@id"]; ok {
+		ids, isString := id.(string)
+		if !isString {
+			return "", fmt.Errorf("io.ReadJSONLD: invalid @id %v", id)
+		}
+		iri, ok := c.expand(ids, false)
+		if !ok {
+			return "", fmt.Errorf("io.ReadJSONLD: cannot expand @id %q", ids)
+		}
+		s = p.ref(iri)
+	} else {
+		s = p.blank()
+	}
+	for _, k := range sortedKeys(m) {
+		v := m[k]
+		switch k {
+		case "@context", "@id", "@index":
+		case "@type":
+			ts, ok := v.([]interface{})
+			if !ok {
+				ts = []interface{}{v}
+			}
+			for _, t := range ts {
+				tv, isString := t.(string)
+				if !isString {
+					return "", fmt.Errorf("io.ReadJSONLD: invalid @type %v", t)
+				}
+				iri, ok := c.expand(tv, true)
+				if !ok {
+					return "", fmt.Errorf("io.ReadJSONLD: cannot expand @type %q", tv)
+				}
+				if err := p.emit(s, "<"+rdfType+">", p.ref(iri)); err != nil {
+					return "", err
+				}
+			}
+		case "@graph":
+			// Named graphs are flattened into the triples read.
+			if err := p.nodes(v, c); err != nil {
+				return "", err
+			}
+		case "@reverse":
+			rm, ok := v.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("io.ReadJSONLD: invalid @reverse %v", v)
+			}
+			for _, rk := range sortedKeys(rm) {
+				if err := p.property(s, rk, rm[rk], c, true); err != nil {
+					return "", err
+				}
+			}
+		default:
+			if err := p.property(s, k, v, c, false); err != nil {
+				return "", err
+			}
+		}
+	}
+	return s, nil
+}
+
+// property emits the statements for the values of the provided property of
+// the subject. Reverse properties use the subject as the object of the
+// statements.
+func (p *jsonldParser) property(s, k string, v interface{}, c *jsonldContext, reverse bool) error {
+	t, ok := c.terms[k]
+	if !ok {
+		iri, ok := c.expand(k, true)
+		if !ok || strings.HasPrefix(iri, "@") {
+			// Properties which cannot be expanded are dropped.
+			return nil
+		}
+		t = &jsonldTerm{iri: iri}
+	}
+	if t.iri == "" || strings.HasPrefix(t.iri, "@") {
+		return nil
+	}
+	reverse = reverse != t.reverse
+	var os []string
+	if vs, ok := v.([]interface{}); ok && t.container == "@list" {
+		o, err := p.list(vs, t, c)
+		if err != nil {
+			return err
+		}
+		os = []string{o}
+	} else {
+		var err error
+		if os, err = p.values(v, t, c); err != nil {
+			return err
+		}
+	}
+	for _, o := range os {
+		var err error
+		if reverse {
+			if o[0] == '"' {
+				return fmt.Errorf("io.ReadJSONLD: reverse property %q cannot have literal values", k)
+			}
+			err = p.emit(o, "<"+t.iri+">", s)
+		} else {
+			err = p.emit(s, "<"+t.iri+">", o)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// list emits the statements describing the provided list and returns the
+// term of its first element.
+func (p *jsonldParser) list(vs []interface{}, t *jsonldTerm, c *jsonldContext) (string, error) {
+	var items []string
+	for _, v := range vs {
+		os, err := p.values(v, t, c)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, os...)
+	}
+	head := "<" + rdfNil + ">"
+	for i := len(items) - 1; i >= 0; i-- {
+		b := p.blank()
+		if err := p.emit(b, "<"+rdfFirst+">", items[i]); err != nil {
+			return "", err
+		}
+		if err := p.emit(b, "<"+rdfRest+">", head); err != nil {
+			return "", err
+		}
+		head = b
+	}
+	return head, nil
+}
+
+// values returns the terms of the provided value of a property defined by the
+// provided term. Arrays and sets return a term per element, and null none.
+func (p *jsonldParser) values(v interface{}, t *jsonldTerm, c *jsonldContext) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		var os []string
+		for _, e := range v {
+			eos, err := p.values(e, t, c)
+			if err != nil {
+				return nil, err
+			}
+			os = append(os, eos...)
+		}
+		return os, nil
+	case string:
+		switch t.typ {
+		case "@id", "@vocab":
+			iri, ok := c.expand(v, t.typ == "@vocab")
+			if !ok {
+				return nil, fmt.Errorf("io.ReadJSONLD: cannot expand IRI %q", v)
+			}
+			return []string{p.ref(iri)}, nil
+		case "":
+			return []string{quote(v)}, nil
+		}
+		return []string{quote(v) + "^^<" + t.typ + ">"}, nil
+	case json.Number, bool:
+		return []string{scalarTerm(v, t.typ)}, nil
+	case map[string]interface{}:
+		if vv, ok := v["@value"]; ok {
+			dt := ""
+			if ty, ok := v["@type"].(string); ok {
+				if dt, ok = c.expand(ty, true); !ok {
+					return nil, fmt.Errorf("io.ReadJSONLD: cannot expand @type %q", ty)
+				}
+			}
+			switch vv := vv.(type) {
+			case nil:
+				return nil, nil
+			case string:
+				if dt == "" {
+					return []string{quote(vv)}, nil
+				}
+				return []string{quote(vv) + "^^<" + dt + ">"}, nil
+			case json.Number, bool:
+				return []string{scalarTerm(vv, dt)}, nil
+			}
+			return nil, fmt.Errorf("io.ReadJSONLD: invalid @value %v", vv)
+		}
+		if l, ok := v["@list"]; ok {
+			ls, isArray := l.([]interface{})
+			if !isArray {
+				ls = []interface{}{l}
+			}
+			o, err := p.list(ls, t, c)
+			if err != nil {
+				return nil, err
+			}
+			return []string{o}, nil
+		}
+		if s, ok := v["@set"]; ok {
+			return p.values(s, t, c)
+		}
+		o, err := p.node(v, c)
+		if err != nil {
+			return nil, err
+		}
+		return []string{o}, nil
+	}
+	return nil, fmt.Errorf("io.ReadJSONLD: invalid value %v", v)
+}
+
+// scalarTerm returns the term for the provided number or boolean, using the
+// provided datatype if any.
+func scalarTerm(v interface{}, dt string) string {
+	s := fmt.Sprint(v)
+	if dt != "" {
+		return quote(s) + "^^<" + dt + ">"
+	}
+	switch {
+	case s == "true" || s == "false":
+		dt = "boolean"
+	case strings.ContainsAny(s, ".eE"):
+		dt = "double"
+	default:
+		dt = "integer"
+	}
+	return quote(s) + "^^<" + triple.XSD + dt + ">"
+}
+
+// nodes emits the statements of the provided node object or array of node
+// objects.
+func (p *jsonldParser) nodes(v interface{}, c *jsonldContext) error {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, e := range v {
+			if err := p.nodes(e, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		if g, ok := v["@graph"]; ok && len(v) == 1 || ok && len(v) == 2 && v["@context"] != nil {
+			// Documents whose only content is a graph describe its nodes.
+			if lc, ok := v["@context"]; ok {
+				var err error
+				if c, err = c.merge(lc); err != nil {
+					return fmt.Errorf("io.ReadJSONLD: %v", err)
+				}
+			}
+			return p.nodes(g, c)
+		}
+		_, err := p.node(v, c)
+		return err
+	}
+	return fmt.Errorf("io.ReadJSONLD: expected a node object; got %v", v)
+}
+
+// ReadJSONLD reads the JSON-LD document of the reader and pushes the resulting
+// triples into the provided channel, which is closed once done. The document is
+// expanded using the context of the options and its own local contexts, which
+// support terms, compact IRIs, @vocab, @base, type coercion, @list containers,
+// and reverse properties; remote contexts are not supported. Nested node
+// objects are flattened into their own triples, using blank nodes when they
+// have no @id, and named graphs are flattened into the triples read. Properties
+// that cannot be expanded are dropped. Compressed readers are decompressed as
+// done by Decompress. IRIs, literals, and time anchors are mapped as done by
+// ReadNTriples. The options can be nil to use the defaults. It returns the
+// number of triples pushed.
+func ReadJSONLD(ctx context.Context, r io.Reader, b literal.Builder, opts *JSONLDOptions, ts chan<- *triple.Triple) (int, error) {
+	defer close(ts)
+	if opts == nil {
+		opts = &JSONLDOptions{}
+	}
+	sink, err := newTripleSink(&opts.NTriplesOptions, pushTo(ctx, ts))
+	if err != nil {
+		return 0, fmt.Errorf("io.ReadJSONLD: %v", err)
+	}
+	c := &jsonldContext{terms: make(map[string]*jsonldTerm)}
+	if len(opts.Context) > 0 {
+		lc := make(map[string]interface{}, len(opts.Context))
+		for k, v := range opts.Context {
+			lc[k] = v
+		}
+		if c, err = c.merge(lc); err != nil {
+			return 0, fmt.Errorf("io.ReadJSONLD: %v", err)
+		}
+	}
+	dr, err := Decompress(r)
+	if err != nil {
+		return 0, err
+	}
+	d := json.NewDecoder(dr)
+	d.UseNumber()
+	var doc interface{}
+	if err := d.Decode(&doc); err != nil {
+		return 0, fmt.Errorf("io.ReadJSONLD: invalid JSON; %v", err)
+	}
+	p := &jsonldParser{b: b, scope: opts.Scope, sink: sink}
+	if err := p.nodes(doc, c); err != nil {
+		return sink.cnt, err
+	}
+	return sink.cnt, sink.close()
+}
+
+// jsonldWriter compacts the IRIs written using the context of the options.
+type jsonldWriter struct {
+	ctx map[string]string
+}
+
+// compact returns the compacted form of the provided IRI. Terms are only used
+// for vocabulary relative IRIs.
+func (w *jsonldWriter) compact(iri string, vocab bool) string {
+	best := ""
+	for t, ns := range w.ctx {
+		if vocab && ns == iri {
+			return t
+		}
+		if ns != "" && strings.HasPrefix(iri, ns) && len(iri) > len(ns) && !strings.HasPrefix(iri[len(ns):], "//") {
+			if best == "" || len(ns) > len(w.ctx[best]) || len(ns) == len(w.ctx[best]) && t < best {
+				best = t
+			}
+		}
+	}
+	if best == "" {
+		return iri
+	}
+	return best + ":" + iri[len(w.ctx[best]):]
+}
+
+// value returns the JSON-LD value for the provided N-Triples object term.
+func (w *jsonldWriter) value(o string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(o, "_:"):
+		return map[string]interface{}{"@id": o}, nil
+	case strings.HasPrefix(o, "<"):
+		return map[string]interface{}{"@id": w.compact(o[1:len(o)-1], false)}, nil
+	}
+	lex, dt := o, ""
+	if i := strings.LastIndex(o, `"^^<`); i >= 0 && o[len(o)-1] == '>' {
+		lex, dt = o[:i+1], o[i+4:len(o)-1]
+	}
+	// N-Triples escapes are valid Go escapes.
+	v, err := strconv.Unquote(lex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid literal %s; %v", o, err)
+	}
+	switch dt {
+	case "":
+		return v, nil
+	case triple.XSD + "integer":
+		return json.Number(v), nil
+	case triple.XSD + "boolean":
+		return v == "true", nil
+	}
+	return map[string]interface{}{"@value": v, "@type": w.compact(dt, true)}, nil
+}
+
+// WriteJSONLD serializes the triples read from the channel into the writer as
+// a JSON-LD document till the channel is closed. The document contains the
+// context of the options, if any, and a graph with a node object for each
+// sequence of consecutive triples about the same subject. IRIs are compacted
+// using the context. Integers, booleans, and strings are written as native
+// JSON values, while other literals are written as value objects with their
+// datatype. Time anchors are mapped as done by WriteNTriples. The options can
+// be nil to use the defaults. It returns the number of triples serialized.
+func WriteJSONLD(ctx context.Context, w io.Writer, ts <-chan *triple.Triple, opts *JSONLDOptions) (int, error) {
+	if opts == nil {
+		opts = &JSONLDOptions{}
+	}
+	jw, bw := &jsonldWriter{ctx: opts.Context}, bufio.NewWriter(w)
+	bw.WriteString("{\n")
+	if len(opts.Context) > 0 {
+		bs, err := json.MarshalIndent(opts.Context, "  ", "  ")
+		if err != nil {
+			for range ts {
+			}
+			return 0, fmt.Errorf("io.WriteJSONLD: %v", err)
+		}
+		bw.WriteString(`  "@context": ` + string(bs) + ",\n")
+	}
+	bw.WriteString(`  "@graph": [`)
+	var (
+		cur   map[string]interface{}
+		props []string
+		nodes int
+	)
+	flush := func() error {
+		if cur == nil {
+			return nil
+		}
+		// Properties with a single value are written without an array.
+		for _, k := range props {
+			if vs := cur[k].([]interface{}); len(vs) == 1 {
+				cur[k] = vs[0]
+			}
+		}
+		bs, err := json.MarshalIndent(cur, "    ", "  ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n    "
+		if nodes == 0 {
+			sep = "\n    "
+		}
+		nodes++
+		cur, props = nil, nil
+		_, err = bw.WriteString(sep + string(bs))
+		return err
+	}
+	cnt, err := writeStatements(ctx, ts, &opts.NTriplesOptions, func(sts [][3]string) error {
+		for _, st := range sts {
+			id := st[0]
+			if strings.HasPrefix(id, "<") {
+				id = jw.compact(id[1:len(id)-1], false)
+			}
+			if cur == nil || cur["@id"] != id {
+				if err := flush(); err != nil {
+					return err
+				}
+				cur = map[string]interface{}{"@id": id}
+			}
+			k := "@type"
+			if st[1] != "<"+rdfType+">" || !strings.HasPrefix(st[2], "<") {
+				k = jw.compact(st[1][1:len(st[1])-1], true)
+			}
+			var v interface{}
+			if k == "@type" {
+				v = jw.compact(st[2][1:len(st[2])-1], true)
+			} else {
+				var err error
+				if v, err = jw.value(st[2]); err != nil {
+					return err
+				}
+			}
+			if _, ok := cur[k]; !ok {
+				cur[k] = []interface{}{}
+				if k != "@type" {
+					props = append(props, k)
+				}
+			}
+			cur[k] = append(cur[k].([]interface{}), v)
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return cnt, fmt.Errorf("io.WriteJSONLD: %v", err)
+	}
+	if nodes > 0 {
+		bw.WriteString("\n  ")
+	}
+	bw.WriteString("]\n}\n")
+	return cnt, bw.Flush()
+}
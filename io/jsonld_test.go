@@ -0,0 +1,255 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+)
+
+// readJSONLD returns the sorted serializations of the triples read by
+// ReadJSONLD.
+func readJSONLD(s string, opts *JSONLDOptions) ([]string, error) {
+	c := make(chan *triple.Triple)
+	var (
+		cnt int
+		err error
+	)
+	done := make(chan bool)
+	go func() {
+		cnt, err = ReadJSONLD(context.Background(), strings.NewReader(s), literal.DefaultBuilder(), opts, c)
+		close(done)
+	}()
+	var got []string
+	for trpl := range c {
+		got = append(got, trpl.String())
+	}
+	<-done
+	if err == nil && cnt != len(got) {
+		err = fmt.Errorf("io.ReadJSONLD returned %d triples but pushed %d", cnt, len(got))
+	}
+	sort.Strings(got)
+	return got, err
+}
+
+func TestReadJSONLD(t *testing.T) {
+	in := `{
+  "@context": {
+    "@base": "http://example.com/people/",
+    "@vocab": "http://example.com/vocab#",
+    "foaf": "http://xmlns.com/foaf/0.1/",
+    "xsd": "http://www.w3.org/2001/XMLSchema#",
+    "name": "foaf:name",
+    "knows": {"@id": "foaf:knows", "@type": "@id"},
+    "born": {"@id": "http://example.com/born", "@type": "xsd:date"},
+    "children": {"@id": "http://example.com/children", "@container": "@list"},
+    "parent": {"@reverse": "http://example.com/children"}
+  },
+  "@graph": [
+    {
+      "@id": "john",
+      "@type": ["foaf:Person", "Author"],
+      "name": ["John", {"@value": "Juan", "@language": "es"}],
+      "knows": "mary",
+      "born": "1970-01-01",
+      "age": 42,
+      "height": 1.8,
+      "alive": true,
+      "children": [{"@id": "alice"}, {"@id": "bob"}],
+      "address": {"http://example.com/city": "Paris", "ignored": null},
+      "weight": {"@value": "75", "@type": "xsd:double"}
+    },
+    {
+      "@context": {"@vocab": null},
+      "@id": "_:m",
+      "dropped": "no vocabulary",
+      "parent": {"@id": "mary"}
+    }
+  ]
+}`
+	scope := node.NewBlankNodeScope("test")
+	// Properties are read in order, so the address is allocated before the
+	// list of children, whose nodes are allocated from the last one.
+	addr, l2, l1 := scope.NewBlankNode(), scope.NewBlankNode(), scope.NewBlankNode()
+	john, p := "/iri<http://example.com/people/john>\t", "\t\"http://example.com/"
+	want := []string{
+		"/iri<http://example.com/people/mary>\t\"http://example.com/children\"@[]\t" + scope.Labeled("m").String(),
+		john + "\"http://example.com/born\"@[]\t\"1970-01-01\"^^type:date",
+		john + "\"http://example.com/children\"@[]\t" + l1.String(),
+		john + "\"http://example.com/vocab#address\"@[]\t" + addr.String(),
+		john + "\"http://example.com/vocab#age\"@[]\t\"42\"^^type:int64",
+		john + "\"http://example.com/vocab#alive\"@[]\t\"true\"^^type:bool",
+		john + "\"http://example.com/vocab#height\"@[]\t\"1.8\"^^type:float64",
+		john + "\"http://example.com/vocab#weight\"@[]\t\"75\"^^type:float64",
+		john + "\"http://www.w3.org/1999/02/22-rdf-syntax-ns#type\"@[]\t/iri<http://example.com/vocab#Author>",
+		john + "\"http://www.w3.org/1999/02/22-rdf-syntax-ns#type\"@[]\t/iri<http://xmlns.com/foaf/0.1/Person>",
+		john + "\"http://xmlns.com/foaf/0.1/knows\"@[]\t/iri<http://example.com/people/mary>",
+		john + "\"http://xmlns.com/foaf/0.1/name\"@[]\t\"John\"^^type:text",
+		john + "\"http://xmlns.com/foaf/0.1/name\"@[]\t\"Juan\"^^type:text",
+		addr.String() + p + "city\"@[]\t\"Paris\"^^type:text",
+		l1.String() + "\t\"http://www.w3.org/1999/02/22-rdf-syntax-ns#first\"@[]\t/iri<http://example.com/people/alice>",
+		l1.String() + "\t\"http://www.w3.org/1999/02/22-rdf-syntax-ns#rest\"@[]\t" + l2.String(),
+		l2.String() + "\t\"http://www.w3.org/1999/02/22-rdf-syntax-ns#first\"@[]\t/iri<http://example.com/people/bob>",
+		l2.String() + "\t\"http://www.w3.org/1999/02/22-rdf-syntax-ns#rest\"@[]\t/iri<http://www.w3.org/1999/02/22-rdf-syntax-ns#nil>",
+	}
+	sort.Strings(want)
+	opts := &JSONLDOptions{}
+	opts.Scope = node.NewBlankNodeScope("test")
+	got, err := readJSONLD(in, opts)
+	if err != nil {
+		t.Fatalf("io.ReadJSONLD failed to read %q with error %v", in, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("io.ReadJSONLD returned\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadJSONLDErrors(t *testing.T) {
+	table := []struct {
+		in   string
+		want string
+	}{
+		{in: `{"@context": "http://schema.org/", "name": "John"}`, want: "remote context"},
+		{in: `{"@id": 42}`, want: "invalid @id"},
+		{in: `{"@id": "http://example.com/a", "@reverse": {"http://example.com/b": "c"}}`, want: "cannot have literal values"},
+		{in: `{"@id": "http://example.com/a"`, want: "invalid JSON"},
+		{in: `[42]`, want: "expected a node object"},
+	}
+	for _, entry := range table {
+		_, err := readJSONLD(entry.in, nil)
+		if err == nil || !strings.Contains(err.Error(), entry.want) {
+			t.Errorf("io.ReadJSONLD(%q) returned error %v; want an error containing %q", entry.in, err, entry.want)
+		}
+	}
+}
+
+// writeJSONLD serializes the provided triples using WriteJSONLD.
+func writeJSONLD(t *testing.T, ts []*triple.Triple, opts *JSONLDOptions) string {
+	var buffer bytes.Buffer
+	c := make(chan *triple.Triple, len(ts))
+	for _, trpl := range ts {
+		c <- trpl
+	}
+	close(c)
+	cnt, err := WriteJSONLD(context.Background(), &buffer, c, opts)
+	if err != nil {
+		t.Fatalf("io.WriteJSONLD failed to write %v with error %v", ts, err)
+	}
+	if cnt != len(ts) {
+		t.Errorf("io.WriteJSONLD wrote %d triples; want %d", cnt, len(ts))
+	}
+	return buffer.String()
+}
+
+func TestWriteJSONLD(t *testing.T) {
+	ts := parseTriples(t,
+		`/iri<http://example.com/john>	"http://www.w3.org/1999/02/22-rdf-syntax-ns#type"@[]	/iri<http://xmlns.com/foaf/0.1/Person>`,
+		`/iri<http://example.com/john>	"http://xmlns.com/foaf/0.1/name"@[]	"John"^^type:text`,
+		`/iri<http://example.com/john>	"http://xmlns.com/foaf/0.1/knows"@[]	/iri<http://example.com/mary>`,
+		`/iri<http://example.com/john>	"http://xmlns.com/foaf/0.1/knows"@[]	/_<b1>`,
+		`/_<b1>	"http://xmlns.com/foaf/0.1/age"@[]	"42"^^type:int64`,
+		`/_<b1>	"http://example.com/height"@[]	"1.8"^^type:float64`,
+	)
+	got := writeJSONLD(t, ts, &JSONLDOptions{Context: map[string]string{
+		"ex":   "http://example.com/",
+		"foaf": "http://xmlns.com/foaf/0.1/",
+		"name": "http://xmlns.com/foaf/0.1/name",
+		"xsd":  "http://www.w3.org/2001/XMLSchema#",
+	}})
+	want := `{
+  "@context": {
+    "ex": "http://example.com/",
+    "foaf": "http://xmlns.com/foaf/0.1/",
+    "name": "http://xmlns.com/foaf/0.1/name",
+    "xsd": "http://www.w3.org/2001/XMLSchema#"
+  },
+  "@graph": [
+    {
+      "@id": "ex:john",
+      "@type": [
+        "foaf:Person"
+      ],
+      "foaf:knows": [
+        {
+          "@id": "ex:mary"
+        },
+        {
+          "@id": "_:b1"
+        }
+      ],
+      "name": "John"
+    },
+    {
+      "@id": "_:b1",
+      "ex:height": {
+        "@type": "xsd:double",
+        "@value": "1.8E+00"
+      },
+      "foaf:age": 42
+    }
+  ]
+}
+`
+	if got != want {
+		t.Errorf("io.WriteJSONLD wrote\n%s\nwant\n%s", got, want)
+	}
+	if got, want := writeJSONLD(t, nil, nil), "{\n  \"@graph\": []\n}\n"; got != want {
+		t.Errorf("io.WriteJSONLD wrote %q for no triples; want %q", got, want)
+	}
+}
+
+func TestJSONLDRoundTrip(t *testing.T) {
+	ts := parseTriples(t,
+		`/u<john>	"knows"@[]	/u<mary>`,
+		`/u<john>	"met"@[2016-04-10T04:21:00Z]	/u<mary>`,
+		`/u<john>	"lived_in"@[2010-01-01T00:00:00Z/2015-01-01T00:00:00Z]	/city<Paris>`,
+		`/iri<http://example.com/john>	"http://xmlns.com/foaf/0.1/name"@[]	"John \"Johnny\"\nSmith"^^type:text`,
+		`/iri<http://example.com/john>	"http://example.com/height"@[2016-04-10T04:21:00Z]	"1.8"^^type:float64`,
+		`/iri<http://example.com/john>	"http://example.com/age"@[]	"42"^^type:int64`,
+		`/iri<http://example.com/john>	"http://example.com/alive"@[]	"false"^^type:bool`,
+		`/_<b1>	"http://example.com/about"@[]	"met"@[2015-04-10T04:21:00Z]`,
+		`/u<john>	"http://www.w3.org/1999/02/22-rdf-syntax-ns#type"@[]	/iri<http://xmlns.com/foaf/0.1/Person>`,
+	)
+	var want []string
+	for _, trpl := range ts {
+		want = append(want, trpl.String())
+	}
+	sort.Strings(want)
+	ctx := map[string]string{"ex": "http://example.com/", "foaf": "http://xmlns.com/foaf/0.1/", "name": "http://xmlns.com/foaf/0.1/name"}
+	for _, opts := range []*JSONLDOptions{
+		nil,
+		{Context: ctx},
+		{NTriplesOptions: NTriplesOptions{Anchors: AnchorReified}, Context: ctx},
+	} {
+		doc := writeJSONLD(t, ts, opts)
+		got, err := readJSONLD(doc, opts)
+		if err != nil {
+			t.Errorf("io.ReadJSONLD failed to read %q with error %v", doc, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("io.ReadJSONLD(io.WriteJSONLD(%v)) returned %v; want %v", ts, got, want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// loadChunkSize is the number of lines parsed together by the workers of
+// LoadGraph.
+const loadChunkSize = 256
+
+// LoadOptions configures how LoadGraph reads triples into a graph.
+type LoadOptions struct {
+	// Workers is the number of goroutines parsing lines, and the number of
+	// batches of triples written concurrently. It defaults to the number of
+	// CPUs that can be used.
+	Workers int
+
+	// BatchSize is the number of triples written in each batch. It defaults to
+	// storage.DefaultBulkBatchSize.
+	BatchSize int
+
+	// Offset is the number of lines to skip before loading. It allows resuming
+	// a load using the Resume value of the report of a previous one.
+	Offset int
+
+	// MaxErrors, if positive, is the number of malformed lines tolerated. The
+	// load is aborted once more are found.
+	MaxErrors int
+
+	// Progress, if set, is called once for every batch written, as done by
+	// storage.BulkLoadOptions.
+	Progress func(*storage.BatchResult)
+}
+
+// LineError reports a line that could not be parsed into a triple.
+type LineError struct {
+	// Line is the number of the line, starting at one.
+	Line int
+
+	// Text is the content of the line.
+	Text string
+
+	// Err is the error returned parsing the line.
+	Err error
+}
+
+// Error returns the line number and the parsing error.
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// LoadReport summarizes the outcome of LoadGraph.
+type LoadReport struct {
+	// Lines is the number of lines read, including the skipped ones.
+	Lines int
+
+	// Triples is the number of triples added to the graph.
+	Triples int
+
+	// Errors contains the malformed lines found, in order.
+	Errors []*LineError
+
+	// Resume is the offset to use to resume the load. All the lines before it
+	// were processed, and their triples added to the graph.
+	Resume int
+}
+
+// lineChunk is a group of consecutive lines parsed together.
+type lineChunk struct {
+	first int
+	lines []string
+	ts    []*triple.Triple
+	tl    []int
+	errs  []*LineError
+	done  chan struct{}
+}
+
+// parse parses the lines of the chunk into triples.
+func (c *lineChunk) parse(b literal.Builder) {
+	defer close(c.done)
+	for i, l := range c.lines {
+		text := strings.TrimSpace(l)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		t, err := triple.Parse(text, b)
+		if err != nil {
+			c.errs = append(c.errs, &LineError{Line: c.first + i, Text: l, Err: err})
+			continue
+		}
+		c.ts = append(c.ts, t)
+		c.tl = append(c.tl, c.first+i)
+	}
+}
+
+// LoadGraph reads triples out of the provided reader into the graph. Each line
+// represents one triple using the standard serialized format, and lines
+// starting with # are treated as comments. Lines are parsed by opts.Workers
+// concurrent workers, and the triples are added in batches as done by
+// storage.BulkLoad, preserving the order of the lines. Malformed lines
+// do not stop the load; they are collected into the returned report along
+// with their line numbers. Compressed readers are decompressed as done by
+// Decompress.
+//
+// LoadGraph returns an error if the reader or the graph fail, the context is
+// done, or too many malformed lines are found. The report is always returned,
+// and its Resume value can be used as opts.Offset to resume the load.
+func LoadGraph(ctx context.Context, g storage.Graph, r io.Reader, b literal.Builder, opts *LoadOptions) (*LoadReport, error) {
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	workers, size := opts.Workers, opts.BatchSize
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if size <= 0 {
+		size = storage.DefaultBulkBatchSize
+	}
+	rep := &LoadReport{Resume: opts.Offset}
+	r, err := Decompress(r)
+	if err != nil {
+		return rep, fmt.Errorf("io.LoadGraph: %v", err)
+	}
+	pctx := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Parse the lines concurrently, and keep the chunks in reading order.
+	var (
+		wg     sync.WaitGroup
+		rErr   error
+		chunks = make(chan *lineChunk)
+		queue  = make(chan *lineChunk, workers)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				c.parse(b)
+			}
+		}()
+	}
+	go func() {
+		defer close(queue)
+		defer close(chunks)
+		n, scanner := 0, bufio.NewScanner(r)
+		scanner.Split(bufio.ScanLines)
+		c := &lineChunk{first: opts.Offset + 1, done: make(chan struct{})}
+		send := func() bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case queue <- c:
+			}
+			chunks <- c
+			return true
+		}
+		for scanner.Scan() {
+			n++
+			if n <= opts.Offset {
+				continue
+			}
+			c.lines = append(c.lines, scanner.Text())
+			if len(c.lines) < loadChunkSize {
+				continue
+			}
+			if !send() {
+				rep.Lines = n
+				return
+			}
+			c = &lineChunk{first: n + 1, done: make(chan struct{})}
+		}
+		rep.Lines, rErr = n, scanner.Err()
+		if len(c.lines) > 0 {
+			send()
+		}
+	}()
+
+	// Track the last line of each batch, so the offset to resume from can be
+	// advanced as batches are written.
+	var (
+		mu        sync.Mutex
+		lastLines = make(map[int]int)
+		written   = make(map[int]bool)
+		next      int
+	)
+	progress := func(res *storage.BatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		rep.Triples = int(res.Loaded)
+		if opts.Progress != nil {
+			opts.Progress(res)
+		}
+		if res.Err != nil {
+			return
+		}
+		written[res.Batch] = true
+		for written[next] {
+			rep.Resume = lastLines[next]
+			delete(written, next)
+			delete(lastLines, next)
+			next++
+		}
+	}
+	var bErr error
+	ts, loaded := make(chan *triple.Triple), make(chan struct{})
+	go func() {
+		defer close(loaded)
+		bErr = storage.BulkLoad(ctx, g, ts, &storage.BulkLoadOptions{
+			BatchSize: size,
+			Workers:   workers,
+			Progress:  progress,
+		})
+		cancel()
+	}()
+
+	var pErr error
+	sent, last := 0, 0
+	for c := range queue {
+		if pErr != nil {
+			continue
+		}
+		<-c.done
+		rep.Errors = append(rep.Errors, c.errs...)
+		if opts.MaxErrors > 0 && len(rep.Errors) > opts.MaxErrors {
+			pErr = fmt.Errorf("io.LoadGraph: found more than %d malformed lines", opts.MaxErrors)
+			cancel()
+			continue
+		}
+		for i, t := range c.ts {
+			sent++
+			last = c.tl[i]
+			if sent%size == 0 {
+				mu.Lock()
+				lastLines[sent/size-1] = last
+				mu.Unlock()
+			}
+			select {
+			case <-ctx.Done():
+				pErr = ctx.Err()
+			case ts <- t:
+			}
+			if pErr != nil {
+				break
+			}
+		}
+	}
+	if sent%size != 0 {
+		mu.Lock()
+		lastLines[sent/size] = last
+		mu.Unlock()
+	}
+	close(ts)
+	<-loaded
+	wg.Wait()
+
+	switch {
+	case pErr != nil && pErr != context.Canceled:
+		// Too many malformed lines; the load was canceled because of it.
+		return rep, pErr
+	case bErr != nil:
+		return rep, fmt.Errorf("io.LoadGraph: %v", bErr)
+	case pctx.Err() != nil:
+		return rep, fmt.Errorf("io.LoadGraph: %v", pctx.Err())
+	case rErr != nil:
+		return rep, fmt.Errorf("io.LoadGraph: %v", rErr)
+	}
+	rep.Triples, rep.Resume = sent, rep.Lines
+	return rep, nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// loadLines are the lines used to test LoadGraph. Lines 3 and 6 are malformed.
+var loadLines = strings.Join([]string{
+	"/u<john>\t\"knows\"@[]\t/u<mary>",
+	"/u<john>\t\"knows\"@[]\t/u<peter>",
+	"/u<john>\t\"knows\"",
+	"",
+	"/u<mary>\t\"knows\"@[]\t/u<andrew>",
+	"not a triple",
+	"/u<mary>\t\"knows\"@[]\t/u<kim>",
+	"/u<mary>\t\"knows\"@[]\t/u<alice>",
+}, "\n")
+
+// failingGraph is a graph that fails to add triples whose object is
+// /u<alice>.
+type failingGraph struct {
+	storage.Graph
+}
+
+// AddTriples fails if any of the triples has /u<alice> as object.
+func (g *failingGraph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	for _, t := range ts {
+		if t.Object().String() == "/u<alice>" {
+			return errors.New("failed to add /u<alice>")
+		}
+	}
+	return g.Graph.AddTriples(ctx, ts)
+}
+
+func TestLoadGraph(t *testing.T) {
+	table := []struct {
+		opts    *LoadOptions
+		fail    bool
+		triples int
+		errors  []int
+		resume  int
+		err     bool
+	}{
+		{
+			opts:    nil,
+			triples: 5,
+			errors:  []int{3, 6},
+			resume:  8,
+		},
+		{
+			opts:    &LoadOptions{Workers: 4, BatchSize: 2},
+			triples: 5,
+			errors:  []int{3, 6},
+			resume:  8,
+		},
+		{
+			opts:    &LoadOptions{Offset: 4},
+			triples: 3,
+			errors:  []int{6},
+			resume:  8,
+		},
+		{
+			opts:    &LoadOptions{Offset: 8},
+			triples: 0,
+			resume:  8,
+		},
+		{
+			opts:   &LoadOptions{MaxErrors: 1},
+			errors: []int{3, 6},
+			err:    true,
+		},
+		{
+			opts:    &LoadOptions{Workers: 1, BatchSize: 1},
+			fail:    true,
+			triples: 4,
+			errors:  []int{3, 6},
+			resume:  7,
+			err:     true,
+		},
+	}
+	for i, entry := range table {
+		ctx := context.Background()
+		g, err := memory.NewStore().NewGraph(ctx, "test")
+		if err != nil {
+			t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+		}
+		if entry.fail {
+			g = &failingGraph{g}
+		}
+		rep, err := LoadGraph(ctx, g, strings.NewReader(loadLines), literal.DefaultBuilder(), entry.opts)
+		if got, want := err != nil, entry.err; got != want {
+			t.Errorf("[case %d] io.LoadGraph returned error %v; want error %v", i, err, want)
+		}
+		var lines []int
+		for _, le := range rep.Errors {
+			lines = append(lines, le.Line)
+		}
+		if !reflect.DeepEqual(lines, entry.errors) {
+			t.Errorf("[case %d] io.LoadGraph reported malformed lines %v; want %v", i, lines, entry.errors)
+		}
+		if entry.opts != nil && entry.opts.MaxErrors > 0 {
+			if err == nil || !strings.Contains(err.Error(), "malformed lines") {
+				t.Errorf("[case %d] io.LoadGraph returned error %v; want too many malformed lines", i, err)
+			}
+			continue
+		}
+		if rep.Lines != 8 {
+			t.Errorf("[case %d] io.LoadGraph read %d lines; want 8", i, rep.Lines)
+		}
+		if got, want := rep.Triples, entry.triples; got != want {
+			t.Errorf("[case %d] io.LoadGraph added %d triples; want %d", i, got, want)
+		}
+		if got, want := rep.Resume, entry.resume; got != want {
+			t.Errorf("[case %d] io.LoadGraph returned resume offset %d; want %d", i, got, want)
+		}
+		if cnt, err := storage.CountTriples(ctx, g); err != nil || int(cnt) != entry.triples {
+			t.Errorf("[case %d] the graph contains (%d, %v) triples; want %d", i, cnt, err, entry.triples)
+		}
+	}
+}
+
+func TestLoadGraphLineErrors(t *testing.T) {
+	ctx := context.Background()
+	g, err := memory.NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	cnt, err := ReadIntoGraph(ctx, g, strings.NewReader(loadLines), literal.DefaultBuilder())
+	if cnt != 5 {
+		t.Errorf("io.ReadIntoGraph added %d triples; want 5", cnt)
+	}
+	le, ok := err.(*LineError)
+	if !ok || le.Line != 3 || le.Text != "/u<john>\t\"knows\"" {
+		t.Fatalf("io.ReadIntoGraph returned error %v; want the error of line 3", err)
+	}
+	if got := le.Error(); !strings.HasPrefix(got, "line 3: ") {
+		t.Errorf("LineError.Error() = %q; want it to start with the line number", got)
+	}
+}
+
+func TestLoadGraphComments(t *testing.T) {
+	ctx := context.Background()
+	g, err := memory.NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	var batches int
+	in := "# people\n/u<joe>\t\"knows\"@[]\t/u<mary>\n\t# more people\n/u<mary>\t\"knows\"@[]\t/u<joe>\n"
+	rep, err := LoadGraph(ctx, g, strings.NewReader(in), literal.DefaultBuilder(), &LoadOptions{
+		BatchSize: 1,
+		Progress:  func(*storage.BatchResult) { batches++ },
+	})
+	if err != nil || len(rep.Errors) != 0 {
+		t.Fatalf("io.LoadGraph failed with (%v, %v); want comments to be skipped", err, rep.Errors)
+	}
+	if rep.Triples != 2 || batches != 2 {
+		t.Errorf("io.LoadGraph added %d triples in %d batches; want 2 in 2", rep.Triples, batches)
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+)
+
+// GraphIRIPrefix prefixes the IRIs standing for the names of graphs which are
+// not IRIs in N-Quads. The prefix is followed by the escaped graph name, as in
+// urn:badwolf:graph:%3Fpeople.
+const GraphIRIPrefix = "urn:badwolf:graph:"
+
+// NQuadsOptions configures how triples and their graphs are mapped to and
+// from N-Quads.
+type NQuadsOptions struct {
+	NTriplesOptions
+	// DefaultGraph is the graph of the lines without a graph name. Triples of
+	// the default graph are written without a graph name. If empty, reading a
+	// line without a graph name fails.
+	DefaultGraph string
+}
+
+// Quad is a triple and the name of the graph it belongs to.
+type Quad struct {
+	Graph  string
+	Triple *triple.Triple
+}
+
+// graphTerm returns the N-Quads term for the provided graph name.
+func graphTerm(g string) string {
+	if node.ValidateIRI(g) == nil && !strings.HasPrefix(g, GraphIRIPrefix) {
+		return "<" + g + ">"
+	}
+	return "<" + GraphIRIPrefix + url.PathEscape(g) + ">"
+}
+
+// graphName returns the graph name for the provided N-Quads graph name, as
+// returned by triple.ParseNQuad.
+func graphName(g string) (string, error) {
+	if !strings.HasPrefix(g, GraphIRIPrefix) {
+		return g, nil
+	}
+	return url.PathUnescape(g[len(GraphIRIPrefix):])
+}
+
+// ReadNQuads reads the N-Quads lines of the reader and pushes the resulting
+// triples, and the names of their graphs, into the provided channel, which is
+// closed once done. Graph IRIs starting with GraphIRIPrefix are mapped back to
+// the graph name they stand for, while other IRIs are used as graph names as
+// they are. Compressed readers are decompressed as done by Decompress. Triples
+// and time anchors are mapped as done by ReadNTriples, collecting reified
+// statements per graph. The options can be nil to use the defaults. ReadNQuads
+// stops at the first line it cannot parse. It returns the number of quads
+// pushed.
+func ReadNQuads(ctx context.Context, r io.Reader, b literal.Builder, opts *NQuadsOptions, qs chan<- *Quad) (int, error) {
+	defer close(qs)
+	if opts == nil {
+		opts = &NQuadsOptions{}
+	}
+	if _, err := newTripleSink(&opts.NTriplesOptions, nil); err != nil {
+		return 0, fmt.Errorf("io.ReadNQuads: %v", err)
+	}
+	cnt, sinks := 0, make(map[string]*tripleSink)
+	sink := func(g string) *tripleSink {
+		s, ok := sinks[g]
+		if !ok {
+			s, _ = newTripleSink(&opts.NTriplesOptions, func(t *triple.Triple) error {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case qs <- &Quad{Graph: g, Triple: t}:
+					cnt++
+					return nil
+				}
+			})
+			sinks[g] = s
+		}
+		return s
+	}
+	dr, err := Decompress(r)
+	if err != nil {
+		return 0, err
+	}
+	br, ln := bufio.NewReader(dr), 0
+	for {
+		line, rerr := br.ReadString('\n')
+		if rerr != nil && rerr != io.EOF {
+			return cnt, rerr
+		}
+		ln++
+		if text := strings.TrimSpace(line); text != "" && text[0] != '#' {
+			t, g, err := triple.ParseNQuad(text, b)
+			if err != nil {
+				return cnt, fmt.Errorf("io.ReadNQuads: line %d: %v", ln, err)
+			}
+			if g == "" {
+				g = opts.DefaultGraph
+			}
+			if g == "" {
+				return cnt, fmt.Errorf("io.ReadNQuads: line %d: missing graph name and no default graph provided", ln)
+			}
+			if g, err = graphName(g); err != nil {
+				return cnt, fmt.Errorf("io.ReadNQuads: line %d: invalid graph name; %v", ln, err)
+			}
+			if opts.Scope != nil {
+				if t, err = relabel(t, opts.Scope); err != nil {
+					return cnt, fmt.Errorf("io.ReadNQuads: line %d: %v", ln, err)
+				}
+			}
+			if err := sink(g).add(t); err != nil {
+				return cnt, err
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+	}
+	var gs []string
+	for g := range sinks {
+		gs = append(gs, g)
+	}
+	sort.Strings(gs)
+	for _, g := range gs {
+		if err := sinks[g].close(); err != nil {
+			return cnt, err
+		}
+	}
+	return cnt, nil
+}
+
+// LoadNQuads reads the N-Quads lines of the reader, as done by ReadNQuads, and
+// adds each triple to the graph of the store it names. Graphs that do not
+// exist are created. The triples of each graph are loaded in batches, several
+// of them at once, using storage.BulkLoad. If the load fails you may end up
+// with partially loaded data. It returns the number of triples loaded.
+func LoadNQuads(ctx context.Context, st storage.Store, r io.Reader, b literal.Builder, opts *NQuadsOptions) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if first == nil {
+			first = err
+		}
+		cancel()
+	}
+	qs, gs := make(chan *Quad, storage.DefaultBulkBatchSize), make(map[string]chan *triple.Triple)
+	rErr := make(chan error, 1)
+	go func() {
+		_, err := ReadNQuads(ctx, r, b, opts, qs)
+		rErr <- err
+	}()
+	cnt := 0
+	for q := range qs {
+		ts, ok := gs[q.Graph]
+		if !ok {
+			g, err := st.Graph(ctx, q.Graph)
+			if err != nil {
+				if g, err = st.NewGraph(ctx, q.Graph); err != nil {
+					fail(fmt.Errorf("io.LoadNQuads: failed to create graph %q; %v", q.Graph, err))
+					break
+				}
+			}
+			ts = make(chan *triple.Triple, storage.DefaultBulkBatchSize)
+			gs[q.Graph] = ts
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				err := storage.BulkLoad(ctx, g, ts, &storage.BulkLoadOptions{Workers: runtime.NumCPU()})
+				if err != nil {
+					fail(fmt.Errorf("io.LoadNQuads: failed to load triples into graph %q; %v", id, err))
+				}
+			}(q.Graph)
+		}
+		select {
+		case <-ctx.Done():
+		case ts <- q.Triple:
+			cnt++
+		}
+	}
+	// Drain the quads left if the load stopped early.
+	for range qs {
+	}
+	for _, ts := range gs {
+		close(ts)
+	}
+	wg.Wait()
+	// Failed loads also stop the reader, so they are the errors to report.
+	if err := <-rErr; err != nil && first == nil {
+		return cnt, err
+	}
+	return cnt, first
+}
+
+// WriteNQuads serializes the triples of the provided graphs of the store into
+// the writer as N-Quads lines, using the name of each graph as the graph name
+// of its triples. If no graphs are provided, all the graphs of the store are
+// written, which makes a single file backup that LoadNQuads can restore.
+// Empty graphs have no lines, so they are not restored. Graphs providing
+// snapshots are written out of a snapshot taken when the graph is reached, so
+// they can keep changing while they are written. Graph names which are
+// not IRIs are written as IRIs starting with GraphIRIPrefix. Triples and time
+// anchors are mapped as done by WriteNTriples. The options can be nil to use
+// the defaults. It returns the number of triples serialized.
+func WriteNQuads(ctx context.Context, w io.Writer, st storage.Store, graphs []string, opts *NQuadsOptions) (int, error) {
+	if opts == nil {
+		opts = &NQuadsOptions{}
+	}
+	if len(graphs) == 0 {
+		var (
+			err error
+			wg  sync.WaitGroup
+		)
+		names := make(chan string)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err = st.GraphNames(ctx, names)
+		}()
+		for n := range names {
+			graphs = append(graphs, n)
+		}
+		wg.Wait()
+		if err != nil {
+			return 0, fmt.Errorf("io.WriteNQuads: failed to list graphs; %v", err)
+		}
+		sort.Strings(graphs)
+	}
+	cnt, bw := 0, bufio.NewWriter(w)
+	for _, id := range graphs {
+		g, err := st.Graph(ctx, id)
+		if err != nil {
+			return cnt, fmt.Errorf("io.WriteNQuads: %v", err)
+		}
+		snap, err := storage.Snapshot(ctx, g)
+		switch err {
+		case nil:
+			g = snap
+		case storage.ErrNoSnapshots:
+		default:
+			return cnt, fmt.Errorf("io.WriteNQuads: %v", err)
+		}
+		gt := " " + graphTerm(id)
+		if id == opts.DefaultGraph {
+			gt = ""
+		}
+		var (
+			tErr error
+			wg   sync.WaitGroup
+		)
+		ts := make(chan *triple.Triple)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tErr = g.Triples(ctx, storage.DefaultLookup, ts)
+		}()
+		n, err := writeStatements(ctx, ts, &opts.NTriplesOptions, func(sts [][3]string) error {
+			for _, st := range sts {
+				if _, err := bw.WriteString(st[0] + " " + st[1] + " " + st[2] + gt + " .\n"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		wg.Wait()
+		if snap != nil {
+			snap.Release(ctx)
+		}
+		cnt += n
+		if tErr != nil {
+			return cnt, fmt.Errorf("io.WriteNQuads: failed to read graph %q; %v", id, tErr)
+		}
+		if err != nil {
+			return cnt, fmt.Errorf("io.WriteNQuads: %v", err)
+		}
+	}
+	return cnt, bw.Flush()
+}
@@ -0,0 +1,168 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// graphTriples returns the sorted serializations of the triples of each graph
+// of the store.
+func graphTriples(t *testing.T, st storage.Store) map[string][]string {
+	ctx, gs := context.Background(), make(map[string][]string)
+	names := make(chan string)
+	go func() {
+		if err := st.GraphNames(ctx, names); err != nil {
+			t.Errorf("storage.GraphNames failed with error %v", err)
+		}
+	}()
+	var ids []string
+	for n := range names {
+		ids = append(ids, n)
+	}
+	for _, id := range ids {
+		g, err := st.Graph(ctx, id)
+		if err != nil {
+			t.Fatalf("storage.Graph(%q) failed with error %v", id, err)
+		}
+		ts := make(chan *triple.Triple)
+		go func() {
+			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+				t.Errorf("g.Triples failed to retrieve triples with error %v", err)
+			}
+		}()
+		var trpls []string
+		for trpl := range ts {
+			trpls = append(trpls, trpl.String())
+		}
+		sort.Strings(trpls)
+		gs[id] = trpls
+	}
+	return gs
+}
+
+// readNQuads returns the graphs and the serializations of the triples read
+// by ReadNQuads.
+func readNQuads(s string, opts *NQuadsOptions) ([]string, error) {
+	c := make(chan *Quad)
+	var err error
+	done := make(chan bool)
+	go func() {
+		_, err = ReadNQuads(context.Background(), strings.NewReader(s), literal.DefaultBuilder(), opts, c)
+		close(done)
+	}()
+	var got []string
+	for q := range c {
+		got = append(got, q.Graph+" "+q.Triple.String())
+	}
+	<-done
+	return got, err
+}
+
+func TestReadNQuads(t *testing.T) {
+	in := `<http://example.com/john> <http://example.com/knows> <http://example.com/mary> <http://example.com/people> .
+<http://example.com/john> <http://example.com/age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> <urn:badwolf:graph:%3Fpeople> .
+# A triple in the default graph.
+<http://example.com/john> <http://example.com/knows> <http://example.com/peter> .
+`
+	got, err := readNQuads(in, &NQuadsOptions{DefaultGraph: "?default"})
+	if err != nil {
+		t.Fatalf("io.ReadNQuads failed to read %q with error %v", in, err)
+	}
+	want := []string{
+		`http://example.com/people /iri<http://example.com/john>	"http://example.com/knows"@[]	/iri<http://example.com/mary>`,
+		`?people /iri<http://example.com/john>	"http://example.com/age"@[]	"42"^^type:int64`,
+		`?default /iri<http://example.com/john>	"http://example.com/knows"@[]	/iri<http://example.com/peter>`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("io.ReadNQuads(%q) returned %v; want %v", in, got, want)
+	}
+
+	if _, err = readNQuads(in, nil); err == nil || !strings.Contains(err.Error(), "line 4: missing graph name") {
+		t.Errorf("io.ReadNQuads(%q) returned error %v; want a missing graph name error on line 4", in, err)
+	}
+}
+
+func TestNQuadsBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	graphs := map[string][]*triple.Triple{
+		"?people": parseTriples(t,
+			`/u<john>	"knows"@[]	/u<mary>`,
+			`/u<john>	"met"@[2016-04-10T04:21:00Z]	/u<mary>`,
+		),
+		"http://example.com/cities": parseTriples(t,
+			`/city<Paris>	"in"@[]	/country<France>`,
+			`/u<john>	"lived_in"@[2010-01-01T00:00:00Z/2015-01-01T00:00:00Z]	/city<Paris>`,
+		),
+	}
+	src := memory.NewStore()
+	for id, ts := range graphs {
+		g, err := src.NewGraph(ctx, id)
+		if err != nil {
+			t.Fatalf("memory.NewStore().NewGraph(%q) failed with error %v", id, err)
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatalf("g.AddTriples(%v) failed with error %v", ts, err)
+		}
+	}
+	for _, opts := range []*NQuadsOptions{
+		nil,
+		{NTriplesOptions: NTriplesOptions{Anchors: AnchorReified}},
+		{DefaultGraph: "?people"},
+	} {
+		var buffer bytes.Buffer
+		cnt, err := WriteNQuads(ctx, &buffer, src, nil, opts)
+		if err != nil {
+			t.Fatalf("io.WriteNQuads failed with error %v", err)
+		}
+		if cnt != 4 {
+			t.Errorf("io.WriteNQuads wrote %d triples; want 4", cnt)
+		}
+		dst := memory.NewStore()
+		// Existing graphs are loaded into, and missing ones are created.
+		if _, err := dst.NewGraph(ctx, "?people"); err != nil {
+			t.Fatalf("memory.NewStore().NewGraph failed with error %v", err)
+		}
+		cnt, err = LoadNQuads(ctx, dst, &buffer, literal.DefaultBuilder(), opts)
+		if err != nil {
+			t.Fatalf("io.LoadNQuads failed with error %v", err)
+		}
+		if cnt != 4 {
+			t.Errorf("io.LoadNQuads loaded %d triples; want 4", cnt)
+		}
+		if got, want := graphTriples(t, dst), graphTriples(t, src); !reflect.DeepEqual(got, want) {
+			t.Errorf("io.LoadNQuads(io.WriteNQuads()) restored %v; want %v", got, want)
+		}
+	}
+}
+
+func TestLoadNQuadsErrors(t *testing.T) {
+	in := `<http://example.com/john> <http://example.com/knows> <http://example.com/mary> <http://example.com/people> .
+<http://example.com/john> <http://example.com/knows> .`
+	_, err := LoadNQuads(context.Background(), memory.NewStore(), strings.NewReader(in), literal.DefaultBuilder(), nil)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("io.LoadNQuads(%q) returned error %v; want an error on line 2", in, err)
+	}
+}
@@ -0,0 +1,488 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// AnchorConvention describes how the time anchors of temporal triples are
+// mapped to N-Triples, which has no notion of them.
+type AnchorConvention uint8
+
+const (
+	// AnchorInIRI keeps the time anchor in the IRI of the predicate, as done
+	// by triple.ToNTriple. It is lossless, but other RDF tools see a different
+	// predicate for each anchor.
+	AnchorInIRI AnchorConvention = iota
+	// AnchorReified writes temporal triples as RDF reified statements whose
+	// time anchor is attached to the statement using the anchor and end IRIs
+	// of the options. RDF tools see the plain predicate, and the reified
+	// statements are turned back into temporal triples when read.
+	AnchorReified
+)
+
+const (
+	// DefaultAnchorIRI is the predicate used to attach the time anchor to
+	// reified statements if none is provided.
+	DefaultAnchorIRI = "urn:badwolf:anchor"
+	// DefaultEndIRI is the predicate used to attach the end of the validity
+	// interval to reified statements if none is provided.
+	DefaultEndIRI = "urn:badwolf:end"
+
+	rdf          = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	rdfType      = rdf + "type"
+	rdfStatement = rdf + "Statement"
+	rdfSubject   = rdf + "subject"
+	rdfPredicate = rdf + "predicate"
+	rdfObject    = rdf + "object"
+)
+
+// NTriplesOptions configures how triples are mapped to and from N-Triples.
+// The zero value keeps time anchors in the predicate IRIs.
+type NTriplesOptions struct {
+	// Anchors is the convention used to map time anchors.
+	Anchors AnchorConvention
+	// AnchorIRI and EndIRI are the predicates attaching the time anchor and
+	// the end of the validity interval to reified statements. They default to
+	// DefaultAnchorIRI and DefaultEndIRI, but can be set to follow other
+	// conventions, such as the start and end time qualifiers of Wikidata.
+	AnchorIRI string
+	EndIRI    string
+	// Scope, if provided, is used to allocate the blank nodes read. Otherwise,
+	// the label of a blank node is used as its ID.
+	Scope *node.BlankNodeScope
+}
+
+// anchorIRIs returns the anchor and end IRIs of the options.
+func (o *NTriplesOptions) anchorIRIs() (string, string, error) {
+	a, e := DefaultAnchorIRI, DefaultEndIRI
+	if o.AnchorIRI != "" {
+		a = o.AnchorIRI
+	}
+	if o.EndIRI != "" {
+		e = o.EndIRI
+	}
+	if err := node.ValidateIRI(a); err != nil {
+		return "", "", fmt.Errorf("invalid anchor IRI %q; %v", a, err)
+	}
+	if err := node.ValidateIRI(e); err != nil {
+		return "", "", fmt.Errorf("invalid end IRI %q; %v", e, err)
+	}
+	if a == e {
+		return "", "", fmt.Errorf("anchor and end IRIs cannot be the same %q", a)
+	}
+	return a, e, nil
+}
+
+// WriteNTriples serializes the triples read from the channel into the writer
+// as N-Triples lines till the channel is closed. Time anchors are mapped using
+// the convention of the provided options, which can be nil to use the
+// defaults. If there is an error writing the serialization will stop, but the
+// channel is still drained. It returns the number of triples serialized.
+func WriteNTriples(ctx context.Context, w io.Writer, ts <-chan *triple.Triple, opts *NTriplesOptions) (int, error) {
+	bw := bufio.NewWriter(w)
+	cnt, err := writeStatements(ctx, ts, opts, func(sts [][3]string) error {
+		for _, st := range sts {
+			if _, err := bw.WriteString(st[0] + " " + st[1] + " " + st[2] + " .\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return cnt, fmt.Errorf("io.WriteNTriples: %v", err)
+	}
+	return cnt, bw.Flush()
+}
+
+// writeStatements calls write with the RDF statements of each triple read from
+// the channel till it is closed. Each statement is made of the N-Triples terms
+// of its subject, predicate, and object. If there is an error the channel is
+// drained and the error returned. It returns the number of triples written.
+func writeStatements(ctx context.Context, ts <-chan *triple.Triple, opts *NTriplesOptions, write func([][3]string) error) (int, error) {
+	if opts == nil {
+		opts = &NTriplesOptions{}
+	}
+	var (
+		anchor, end string
+		err         error
+	)
+	if opts.Anchors == AnchorReified {
+		anchor, end, err = opts.anchorIRIs()
+	}
+	cnt := 0
+	for t := range ts {
+		if err != nil {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			continue
+		}
+		var sts [][3]string
+		if _, terr := t.Predicate().TimeAnchor(); terr == nil && opts.Anchors == AnchorReified {
+			sts, err = reifiedStatements(t, anchor, end)
+		} else {
+			var st [3]string
+			st[0], st[1], st[2], err = t.NTriplesTerms()
+			sts = [][3]string{st}
+		}
+		if err != nil {
+			continue
+		}
+		if err = write(sts); err == nil {
+			cnt++
+		}
+	}
+	return cnt, err
+}
+
+// reifiedStatements returns the statements of the RDF reified statement for
+// the provided temporal triple. The blank node of the statement is the one
+// returned by triple.Reify.
+func reifiedStatements(t *triple.Triple, anchor, end string) ([][3]string, error) {
+	b, err := node.NewNodeFromStrings("/_", t.UUID().String())
+	if err != nil {
+		return nil, err
+	}
+	p := t.Predicate()
+	po := triple.NewPredicateObject(p)
+	if iri, err := node.NewIRI(string(p.ID())); err == nil {
+		po = triple.NewNodeObject(iri)
+	} else if ip, err := predicate.NewImmutable(string(p.ID())); err == nil {
+		po = triple.NewPredicateObject(ip)
+	}
+	stmt, err := node.NewIRI(rdfStatement)
+	if err != nil {
+		return nil, err
+	}
+	var sts [][3]string
+	for _, s := range []struct {
+		p string
+		o *triple.Object
+	}{
+		{rdfType, triple.NewNodeObject(stmt)},
+		{rdfSubject, triple.NewNodeObject(t.Subject())},
+		{rdfPredicate, po},
+		{rdfObject, t.Object()},
+	} {
+		sp, err := predicate.NewImmutable(s.p)
+		if err != nil {
+			return nil, err
+		}
+		rt, err := triple.New(b, sp, s.o)
+		if err != nil {
+			return nil, err
+		}
+		var st [3]string
+		if st[0], st[1], st[2], err = rt.NTriplesTerms(); err != nil {
+			return nil, err
+		}
+		sts = append(sts, st)
+	}
+	bl := "_:" + b.ID().String()
+	ta, _ := p.TimeAnchor()
+	sts = append(sts, [3]string{bl, "<" + anchor + ">", dateTimeTerm(*ta)})
+	if _, te, err := p.Interval(); err == nil {
+		sts = append(sts, [3]string{bl, "<" + end + ">", dateTimeTerm(*te)})
+	}
+	return sts, nil
+}
+
+// dateTimeTerm returns the N-Triples term of the provided time.
+func dateTimeTerm(t time.Time) string {
+	return "\"" + t.Format(time.RFC3339Nano) + "\"^^<" + triple.XSD + "dateTime>"
+}
+
+// reifiedStatement collects the statements describing a reified temporal
+// triple while reading N-Triples.
+type reifiedStatement struct {
+	raw    []*triple.Triple
+	s      *node.Node
+	p      string
+	o      *triple.Object
+	anchor *time.Time
+	end    *time.Time
+}
+
+// add tries to collect the provided triple, returning false if it is not
+// part of a reified statement.
+func (r *reifiedStatement) add(t *triple.Triple, anchor, end string) bool {
+	p := t.Predicate()
+	if _, err := p.TimeAnchor(); err == nil {
+		return false
+	}
+	o := t.Object()
+	switch string(p.ID()) {
+	case rdfType:
+		n, err := o.Node()
+		if err != nil || n.String() != "/iri<"+rdfStatement+">" {
+			return false
+		}
+	case rdfSubject:
+		n, err := o.Node()
+		if err != nil {
+			return false
+		}
+		r.s = n
+	case rdfPredicate:
+		if n, err := o.Node(); err == nil && n.IsIRI() {
+			r.p = n.ID().String()
+		} else if op, err := o.Predicate(); err == nil {
+			r.p = string(op.ID())
+		} else {
+			return false
+		}
+	case rdfObject:
+		r.o = o
+	case anchor, end:
+		l, err := o.Literal()
+		if err != nil {
+			return false
+		}
+		v, err := l.Text()
+		if err != nil {
+			return false
+		}
+		ta, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return false
+		}
+		if string(p.ID()) == anchor {
+			r.anchor = &ta
+		} else {
+			r.end = &ta
+		}
+	default:
+		return false
+	}
+	r.raw = append(r.raw, t)
+	return true
+}
+
+// complete returns true if the statements collected describe a temporal
+// triple.
+func (r *reifiedStatement) complete() bool {
+	return r.s != nil && r.p != "" && r.o != nil && r.anchor != nil
+}
+
+// triples returns the temporal triple of the reified statement, or the
+// statements collected if they do not describe a complete one.
+func (r *reifiedStatement) triples() []*triple.Triple {
+	if !r.complete() {
+		return r.raw
+	}
+	var (
+		p   *predicate.Predicate
+		err error
+	)
+	if r.end == nil {
+		p, err = predicate.NewTemporal(r.p, *r.anchor)
+	} else {
+		p, err = predicate.NewInterval(r.p, *r.anchor, *r.end)
+	}
+	if err != nil {
+		return r.raw
+	}
+	t, err := triple.New(r.s, p, r.o)
+	if err != nil {
+		return r.raw
+	}
+	return []*triple.Triple{triple.Intern(t)}
+}
+
+// relabel returns the triple using the blank nodes of the provided scope.
+func relabel(t *triple.Triple, scope *node.BlankNodeScope) (*triple.Triple, error) {
+	s, o := t.Subject(), t.Object()
+	changed := false
+	if s.Type().String() == "/_" {
+		s, changed = scope.Labeled(s.ID().String()), true
+	}
+	if n, err := o.Node(); err == nil && n.Type().String() == "/_" {
+		o, changed = triple.NewNodeObject(scope.Labeled(n.ID().String())), true
+	}
+	if !changed {
+		return t, nil
+	}
+	return triple.New(s, t.Predicate(), o)
+}
+
+// tripleSink emits the triples read. If requested, the
+// statements about a blank node are collected and, if they describe a reified
+// statement with a time anchor, turned into a temporal triple once a triple
+// about another subject is added or the sink is closed.
+type tripleSink struct {
+	emit        func(*triple.Triple) error
+	reified     bool
+	anchor, end string
+	pending     map[string]*reifiedStatement
+	last        string
+	cnt         int
+}
+
+// newTripleSink returns a sink calling emit for each triple, which maps time
+// anchors as requested by the options, which can be nil.
+func newTripleSink(opts *NTriplesOptions, emit func(*triple.Triple) error) (*tripleSink, error) {
+	s := &tripleSink{
+		emit:    emit,
+		pending: make(map[string]*reifiedStatement),
+	}
+	if opts != nil && opts.Anchors == AnchorReified {
+		var err error
+		if s.anchor, s.end, err = opts.anchorIRIs(); err != nil {
+			return nil, err
+		}
+		s.reified = true
+	}
+	return s, nil
+}
+
+// push emits the provided triple.
+func (s *tripleSink) push(t *triple.Triple) error {
+	if err := s.emit(t); err != nil {
+		return err
+	}
+	s.cnt++
+	return nil
+}
+
+// flush pushes the temporal triple of the reified statement about the
+// provided subject if it is complete. Incomplete statements are kept since
+// later triples may complete them.
+func (s *tripleSink) flush(k string) error {
+	rs, ok := s.pending[k]
+	if !ok || !rs.complete() {
+		return nil
+	}
+	delete(s.pending, k)
+	for _, t := range rs.triples() {
+		if err := s.push(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add adds the provided triple to the sink.
+func (s *tripleSink) add(t *triple.Triple) error {
+	if !s.reified {
+		return s.push(t)
+	}
+	k := t.Subject().String()
+	if k != s.last {
+		if err := s.flush(s.last); err != nil {
+			return err
+		}
+		s.last = k
+	}
+	rs, ok := s.pending[k]
+	if !ok {
+		rs = &reifiedStatement{}
+	}
+	if t.Subject().Type().String() == "/_" && rs.add(t, s.anchor, s.end) {
+		s.pending[k] = rs
+		return nil
+	}
+	return s.push(t)
+}
+
+// close pushes the reified statements still pending.
+func (s *tripleSink) close() error {
+	var ks []string
+	for k := range s.pending {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	for _, k := range ks {
+		for _, t := range s.pending[k].triples() {
+			if err := s.push(t); err != nil {
+				return err
+			}
+		}
+	}
+	s.pending = make(map[string]*reifiedStatement)
+	return nil
+}
+
+// pushTo returns a function sending triples to the provided channel.
+func pushTo(ctx context.Context, ts chan<- *triple.Triple) func(*triple.Triple) error {
+	return func(t *triple.Triple) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ts <- t:
+			return nil
+		}
+	}
+}
+
+// ReadNTriples reads the N-Triples lines of the reader and pushes the
+// resulting triples into the provided channel, which is closed once done.
+// Empty lines and comments are skipped, and compressed readers are
+// decompressed as done by Decompress. Time anchors are mapped using the
+// convention of the provided options, which can be nil to use the defaults.
+// When reading reified statements, the statements about a blank node are
+// turned into a temporal triple once a line about another subject is read or
+// the reader is exhausted. Reified statements without a time anchor are
+// pushed as they are. ReadNTriples stops at the first line it cannot parse.
+// It returns the number of triples pushed.
+func ReadNTriples(ctx context.Context, r io.Reader, b literal.Builder, opts *NTriplesOptions, ts chan<- *triple.Triple) (int, error) {
+	defer close(ts)
+	sink, err := newTripleSink(opts, pushTo(ctx, ts))
+	if err != nil {
+		return 0, fmt.Errorf("io.ReadNTriples: %v", err)
+	}
+	dr, err := Decompress(r)
+	if err != nil {
+		return 0, err
+	}
+	br, ln := bufio.NewReader(dr), 0
+	for {
+		line, rerr := br.ReadString('\n')
+		if rerr != nil && rerr != io.EOF {
+			return sink.cnt, rerr
+		}
+		ln++
+		if text := strings.TrimSpace(line); text != "" && text[0] != '#' {
+			t, err := triple.ParseNTriple(text, b)
+			if err != nil {
+				return sink.cnt, fmt.Errorf("io.ReadNTriples: line %d: %v", ln, err)
+			}
+			if opts != nil && opts.Scope != nil {
+				if t, err = relabel(t, opts.Scope); err != nil {
+					return sink.cnt, fmt.Errorf("io.ReadNTriples: line %d: %v", ln, err)
+				}
+			}
+			if err := sink.add(t); err != nil {
+				return sink.cnt, err
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+	}
+	return sink.cnt, sink.close()
+}
@@ -0,0 +1,219 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+)
+
+// parseTriples returns the triples for the provided lines.
+func parseTriples(t *testing.T, ss ...string) []*triple.Triple {
+	var ts []*triple.Triple
+	for _, s := range ss {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %s with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+// writeNTriples serializes the provided triples using WriteNTriples.
+func writeNTriples(t *testing.T, ts []*triple.Triple, opts *NTriplesOptions) string {
+	var buffer bytes.Buffer
+	c := make(chan *triple.Triple, len(ts))
+	for _, trpl := range ts {
+		c <- trpl
+	}
+	close(c)
+	cnt, err := WriteNTriples(context.Background(), &buffer, c, opts)
+	if err != nil {
+		t.Fatalf("io.WriteNTriples failed to write %v with error %v", ts, err)
+	}
+	if cnt != len(ts) {
+		t.Errorf("io.WriteNTriples wrote %d triples; want %d", cnt, len(ts))
+	}
+	return buffer.String()
+}
+
+// readNTriples returns the sorted serializations of the triples read by
+// ReadNTriples.
+func readNTriples(s string, opts *NTriplesOptions) ([]string, error) {
+	c := make(chan *triple.Triple)
+	var (
+		cnt int
+		err error
+	)
+	done := make(chan bool)
+	go func() {
+		cnt, err = ReadNTriples(context.Background(), strings.NewReader(s), literal.DefaultBuilder(), opts, c)
+		close(done)
+	}()
+	var got []string
+	for trpl := range c {
+		got = append(got, trpl.String())
+	}
+	<-done
+	if err == nil && cnt != len(got) {
+		err = fmt.Errorf("io.ReadNTriples returned %d triples but pushed %d", cnt, len(got))
+	}
+	sort.Strings(got)
+	return got, err
+}
+
+func TestNTriplesRoundTrip(t *testing.T) {
+	ts := parseTriples(t,
+		`/u<john>	"knows"@[]	/u<mary>`,
+		`/u<john>	"met"@[2016-04-10T04:21:00Z]	/u<mary>`,
+		`/u<john>	"met"@[2016-04-10T04:21:00Z]	/u<peter>`,
+		`/u<john>	"met"@[2017-04-10T04:21:00Z]	/u<mary>`,
+		`/u<john>	"lived_in"@[2010-01-01T00:00:00Z/2015-01-01T00:00:00Z]	/city<Paris>`,
+		`/iri<http://example.com/john>	"http://xmlns.com/foaf/0.1/name"@[2016-04-10T04:21:00Z]	"John"^^type:text`,
+		`/u<john>	"height"@[2016-04-10T04:21:00Z]	"1.8"^^type:float64`,
+		`/_<b1>	"met"@[2016-04-10T04:21:00Z]	"met"@[2015-04-10T04:21:00Z]`,
+	)
+	var want []string
+	for _, trpl := range ts {
+		want = append(want, trpl.String())
+	}
+	sort.Strings(want)
+	for _, opts := range []*NTriplesOptions{
+		nil,
+		{Anchors: AnchorInIRI},
+		{Anchors: AnchorReified},
+		{Anchors: AnchorReified, AnchorIRI: "http://www.wikidata.org/prop/qualifier/P580", EndIRI: "http://www.wikidata.org/prop/qualifier/P582"},
+	} {
+		nt := writeNTriples(t, ts, opts)
+		got, err := readNTriples(nt, opts)
+		if err != nil {
+			t.Errorf("io.ReadNTriples failed to read %q with error %v", nt, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("io.ReadNTriples(io.WriteNTriples(%v), %+v) returned %v; want %v", ts, opts, got, want)
+		}
+	}
+}
+
+func TestWriteNTriplesReified(t *testing.T) {
+	ts := parseTriples(t, `/iri<http://example.com/john>	"http://example.com/met"@[2016-04-10T04:21:00Z]	/iri<http://example.com/mary>`)
+	b := "_:" + ts[0].UUID().String()
+	want := b + " <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/1999/02/22-rdf-syntax-ns#Statement> .\n" +
+		b + " <http://www.w3.org/1999/02/22-rdf-syntax-ns#subject> <http://example.com/john> .\n" +
+		b + " <http://www.w3.org/1999/02/22-rdf-syntax-ns#predicate> <http://example.com/met> .\n" +
+		b + " <http://www.w3.org/1999/02/22-rdf-syntax-ns#object> <http://example.com/mary> .\n" +
+		b + " <urn:badwolf:anchor> \"2016-04-10T04:21:00Z\"^^<http://www.w3.org/2001/XMLSchema#dateTime> .\n"
+	if got := writeNTriples(t, ts, &NTriplesOptions{Anchors: AnchorReified}); got != want {
+		t.Errorf("io.WriteNTriples wrote the wrong serialization; got %q, want %q", got, want)
+	}
+}
+
+func TestReadNTriples(t *testing.T) {
+	reified := &NTriplesOptions{Anchors: AnchorReified}
+	table := []struct {
+		in   string
+		opts *NTriplesOptions
+		want []string
+	}{
+		{
+			in: `# A comment.
+
+<http://example.com/john> <http://example.com/knows> <http://example.com/mary> .
+<http://example.com/john> <http://example.com/name> "John"@en .
+`,
+			want: []string{
+				`/iri<http://example.com/john>	"http://example.com/knows"@[]	/iri<http://example.com/mary>`,
+				`/iri<http://example.com/john>	"http://example.com/name"@[]	"John"^^type:text`,
+			},
+		},
+		{
+			// Reified statements without a time anchor are kept as they are.
+			in: `_:s <http://www.w3.org/1999/02/22-rdf-syntax-ns#subject> <http://example.com/john> .
+_:s <http://www.w3.org/1999/02/22-rdf-syntax-ns#source> <http://example.com/news> .`,
+			opts: reified,
+			want: []string{
+				`/_<s>	"http://www.w3.org/1999/02/22-rdf-syntax-ns#source"@[]	/iri<http://example.com/news>`,
+				`/_<s>	"http://www.w3.org/1999/02/22-rdf-syntax-ns#subject"@[]	/iri<http://example.com/john>`,
+			},
+		},
+		{
+			// Statements can be interleaved with other lines.
+			in: `_:s <http://www.w3.org/1999/02/22-rdf-syntax-ns#subject> <http://example.com/john> .
+_:s <http://www.w3.org/1999/02/22-rdf-syntax-ns#predicate> <http://example.com/met> .
+<http://example.com/john> <http://example.com/knows> <http://example.com/mary> .
+_:s <http://www.w3.org/1999/02/22-rdf-syntax-ns#object> <http://example.com/mary> .
+_:s <urn:badwolf:anchor> "2016-04-10T04:21:00Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .
+_:s <urn:badwolf:note> "first met" .`,
+			opts: reified,
+			want: []string{
+				`/_<s>	"urn:badwolf:note"@[]	"first met"^^type:text`,
+				`/iri<http://example.com/john>	"http://example.com/knows"@[]	/iri<http://example.com/mary>`,
+				`/iri<http://example.com/john>	"http://example.com/met"@[2016-04-10T04:21:00Z]	/iri<http://example.com/mary>`,
+			},
+		},
+	}
+	for _, entry := range table {
+		got, err := readNTriples(entry.in, entry.opts)
+		if err != nil {
+			t.Errorf("io.ReadNTriples failed to read %q with error %v", entry.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("io.ReadNTriples(%q) returned %v; want %v", entry.in, got, entry.want)
+		}
+	}
+}
+
+func TestReadNTriplesScope(t *testing.T) {
+	in := `_:b1 <http://example.com/knows> _:b2 .`
+	scope := node.NewBlankNodeScope("test")
+	got, err := readNTriples(in, &NTriplesOptions{Scope: scope})
+	if err != nil {
+		t.Fatalf("io.ReadNTriples failed to read %q with error %v", in, err)
+	}
+	want := []string{scope.Labeled("b1").String() + "\t\"http://example.com/knows\"@[]\t" + scope.Labeled("b2").String()}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("io.ReadNTriples(%q) returned %v; want %v", in, got, want)
+	}
+}
+
+func TestReadNTriplesErrors(t *testing.T) {
+	table := []struct {
+		in   string
+		opts *NTriplesOptions
+		want string
+	}{
+		{in: "<http://example.com/a> <http://example.com/b> <http://example.com/c> .\n<http://example.com/a> <http://example.com/b>", want: "line 2"},
+		{in: "", opts: &NTriplesOptions{Anchors: AnchorReified, AnchorIRI: "not an IRI"}, want: "invalid anchor IRI"},
+		{in: "", opts: &NTriplesOptions{Anchors: AnchorReified, AnchorIRI: "urn:a", EndIRI: "urn:a"}, want: "cannot be the same"},
+	}
+	for _, entry := range table {
+		_, err := readNTriples(entry.in, entry.opts)
+		if err == nil || !strings.Contains(err.Error(), entry.want) {
+			t.Errorf("io.ReadNTriples(%q) returned error %v; want an error containing %q", entry.in, err, entry.want)
+		}
+	}
+}
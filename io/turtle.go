@@ -0,0 +1,782 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+)
+
+const (
+	rdfFirst = rdf + "first"
+	rdfRest  = rdf + "rest"
+	rdfNil   = rdf + "nil"
+)
+
+// TurtleOptions configures how triples are mapped to and from Turtle.
+type TurtleOptions struct {
+	NTriplesOptions
+	// Prefixes maps prefix names to the IRIs they stand for. WriteTurtle
+	// declares them and uses them to abbreviate IRIs, and ReadTurtle makes
+	// them available before any prefix declaration is read.
+	Prefixes map[string]string
+}
+
+// isNameByte returns true if the provided byte can be part of a prefix name,
+// a local name, or a blank node label. Bytes of multi-byte runes are always
+// accepted.
+func isNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c >= utf8.RuneSelf
+}
+
+// turtleParser reads the Turtle statements of a reader.
+type turtleParser struct {
+	r        *bufio.Reader
+	line     int
+	b        literal.Builder
+	scope    *node.BlankNodeScope
+	base     *url.URL
+	prefixes map[string]string
+	sink     *tripleSink
+}
+
+// errorf returns an error locating the current line.
+func (p *turtleParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("io.ReadTurtle: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// peek returns the next n bytes without consuming them. It returns less bytes
+// if the reader is exhausted.
+func (p *turtleParser) peek(n int) []byte {
+	bs, _ := p.r.Peek(n)
+	return bs
+}
+
+// next returns the next byte, or 0 if the reader is exhausted.
+func (p *turtleParser) next() byte {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return 0
+	}
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// expect consumes the provided byte or fails.
+func (p *turtleParser) expect(c byte) error {
+	p.skipSpace()
+	if bs := p.peek(1); len(bs) == 0 || bs[0] != c {
+		return p.errorf("expected %q", c)
+	}
+	p.next()
+	return nil
+}
+
+// skipSpace skips white space and comments.
+func (p *turtleParser) skipSpace() {
+	for {
+		bs := p.peek(1)
+		if len(bs) == 0 {
+			return
+		}
+		switch bs[0] {
+		case ' ', '\t', '\r', '\n':
+			p.next()
+		case '#':
+			for c := p.next(); c != '\n' && c != 0; c = p.next() {
+			}
+		default:
+			return
+		}
+	}
+}
+
+// keyword returns true if the next bytes are the provided keyword, ignoring
+// case if requested, followed by a byte that cannot be part of a name.
+func (p *turtleParser) keyword(k string, fold bool) bool {
+	bs := p.peek(len(k) + 1)
+	if len(bs) < len(k) {
+		return false
+	}
+	if w := string(bs[:len(k)]); w != k && (!fold || !strings.EqualFold(w, k)) {
+		return false
+	}
+	return len(bs) == len(k) || !isNameByte(bs[len(k)]) && bs[len(k)] != ':' && bs[len(k)] != '.'
+}
+
+// unescape reads the escape sequence right after a backslash. Only numeric
+// escapes are allowed in IRIs.
+func (p *turtleParser) unescape(str bool) (string, error) {
+	c := p.next()
+	n := 0
+	switch c {
+	case 'u':
+		n = 4
+	case 'U':
+		n = 8
+	default:
+		if i := strings.IndexByte(`tbnrf"'\`, c); str && i >= 0 {
+			return string("\t\b\n\r\f\"'\\"[i]), nil
+		}
+		return "", p.errorf("invalid escape sequence \\%c", c)
+	}
+	var h strings.Builder
+	for i := 0; i < n; i++ {
+		h.WriteByte(p.next())
+	}
+	v, err := strconv.ParseUint(h.String(), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(v)) {
+		return "", p.errorf("invalid escape sequence \\%c%s", c, h.String())
+	}
+	return string(rune(v)), nil
+}
+
+// iriRef reads an IRI enclosed between < and >, resolving relative IRIs
+// against the base.
+func (p *turtleParser) iriRef() (string, error) {
+	p.next()
+	var b strings.Builder
+	for {
+		c := p.next()
+		switch c {
+		case 0, '\n':
+			return "", p.errorf("IRI is not terminated with >")
+		case '>':
+			return p.resolve(b.String())
+		case '\\':
+			s, err := p.unescape(false)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// resolve resolves the provided IRI against the base.
+func (p *turtleParser) resolve(iri string) (string, error) {
+	if p.base == nil {
+		return iri, nil
+	}
+	u, err := url.Parse(iri)
+	if err != nil {
+		return "", p.errorf("invalid IRI %q; %v", iri, err)
+	}
+	if u.IsAbs() {
+		// Resolving absolute IRIs would drop their empty fragments.
+		return iri, nil
+	}
+	return p.base.ResolveReference(u).String(), nil
+}
+
+// name reads a name made of name bytes. Dots are allowed if they are not the
+// last byte of the name. Local names may also contain colons, percent
+// encoded bytes, and escaped characters.
+func (p *turtleParser) name(local bool) (string, error) {
+	var b strings.Builder
+	for {
+		bs := p.peek(2)
+		if len(bs) == 0 {
+			return b.String(), nil
+		}
+		c := bs[0]
+		switch {
+		case isNameByte(c), local && c == ':':
+			b.WriteByte(p.next())
+		case c == '.' && len(bs) == 2 && (isNameByte(bs[1]) || bs[1] == '.' || local && (bs[1] == ':' || bs[1] == '%' || bs[1] == '\\')):
+			b.WriteByte(p.next())
+		case local && c == '%':
+			b.WriteByte(p.next())
+			for i := 0; i < 2; i++ {
+				h := p.next()
+				if !strings.ContainsRune("0123456789abcdefABCDEF", rune(h)) || h == 0 {
+					return "", p.errorf("invalid percent encoding in local name")
+				}
+				b.WriteByte(h)
+			}
+		case local && c == '\\':
+			p.next()
+			e := p.next()
+			if !strings.ContainsRune("_~.-!$&'()*+,;=/?#@%", rune(e)) || e == 0 {
+				return "", p.errorf("invalid escape sequence \\%c in local name", e)
+			}
+			b.WriteByte(e)
+		default:
+			return b.String(), nil
+		}
+	}
+}
+
+// prefixedName reads a prefixed name and returns the IRI it stands for.
+func (p *turtleParser) prefixedName() (string, error) {
+	pfx, err := p.name(false)
+	if err != nil {
+		return "", err
+	}
+	if bs := p.peek(1); len(bs) == 0 || bs[0] != ':' {
+		return "", p.errorf("expected an IRI, a prefixed name, a blank node, or a literal")
+	}
+	p.next()
+	ns, ok := p.prefixes[pfx]
+	if !ok {
+		return "", p.errorf("undeclared prefix %q", pfx)
+	}
+	local, err := p.name(true)
+	if err != nil {
+		return "", err
+	}
+	return ns + local, nil
+}
+
+// iri reads an IRI or a prefixed name and returns its term.
+func (p *turtleParser) iri() (string, error) {
+	var (
+		iri string
+		err error
+	)
+	if bs := p.peek(1); len(bs) > 0 && bs[0] == '<' {
+		iri, err = p.iriRef()
+	} else {
+		iri, err = p.prefixedName()
+	}
+	if err != nil {
+		return "", err
+	}
+	return "<" + iri + ">", nil
+}
+
+// blank returns the term of a new anonymous blank node.
+func (p *turtleParser) blank() string {
+	if p.scope != nil {
+		return "_:" + p.scope.NewBlankNode().ID().String()
+	}
+	return "_:" + node.NewBlankNode().ID().String()
+}
+
+// labeled reads a labeled blank node and returns its term.
+func (p *turtleParser) labeled() (string, error) {
+	p.next()
+	p.next()
+	l, err := p.name(false)
+	if err != nil {
+		return "", err
+	}
+	if l == "" {
+		return "", p.errorf("empty blank node label")
+	}
+	if p.scope != nil {
+		return "_:" + p.scope.Labeled(l).ID().String(), nil
+	}
+	return "_:" + l, nil
+}
+
+// quote returns the N-Triples string for s.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// str reads a string literal, either short or long, using single or double
+// quotes, and returns its term.
+func (p *turtleParser) str() (string, error) {
+	q := p.next()
+	long := false
+	if bs := p.peek(2); len(bs) == 2 && bs[0] == q && bs[1] == q {
+		p.next()
+		p.next()
+		long = true
+	}
+	var b strings.Builder
+	for done := false; !done; {
+		c := p.next()
+		switch {
+		case c == 0:
+			return "", p.errorf("string is not terminated with %c", q)
+		case c == '\\':
+			s, err := p.unescape(true)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		case c == q && !long:
+			done = true
+		case c == q:
+			if bs := p.peek(2); len(bs) == 2 && bs[0] == q && bs[1] == q {
+				// Quotes right before the closing ones are part of the string.
+				if bs3 := p.peek(3); len(bs3) < 3 || bs3[2] != q {
+					p.next()
+					p.next()
+					done = true
+					continue
+				}
+			}
+			b.WriteByte(c)
+		case (c == '\n' || c == '\r') && !long:
+			return "", p.errorf("short strings cannot span lines")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	t := quote(b.String())
+	bs := p.peek(2)
+	switch {
+	case len(bs) > 0 && bs[0] == '@':
+		// Language tags are dropped.
+		p.next()
+		for bs := p.peek(1); len(bs) > 0 && (isNameByte(bs[0]) && bs[0] != '_'); bs = p.peek(1) {
+			p.next()
+		}
+	case len(bs) == 2 && bs[0] == '^' && bs[1] == '^':
+		p.next()
+		p.next()
+		dt, err := p.iri()
+		if err != nil {
+			return "", err
+		}
+		t += "^^" + dt
+	}
+	return t, nil
+}
+
+// number reads a numeric literal and returns its term.
+func (p *turtleParser) number() (string, error) {
+	var b strings.Builder
+	digits := func() int {
+		n := 0
+		for bs := p.peek(1); len(bs) > 0 && bs[0] >= '0' && bs[0] <= '9'; bs = p.peek(1) {
+			b.WriteByte(p.next())
+			n++
+		}
+		return n
+	}
+	if bs := p.peek(1); bs[0] == '+' || bs[0] == '-' {
+		b.WriteByte(p.next())
+	}
+	dt, n := "integer", digits()
+	if bs := p.peek(2); len(bs) == 2 && bs[0] == '.' && bs[1] >= '0' && bs[1] <= '9' {
+		b.WriteByte(p.next())
+		dt, n = "decimal", n+digits()
+	}
+	if bs := p.peek(1); len(bs) > 0 && (bs[0] == 'e' || bs[0] == 'E') {
+		b.WriteByte(p.next())
+		if bs := p.peek(1); len(bs) > 0 && (bs[0] == '+' || bs[0] == '-') {
+			b.WriteByte(p.next())
+		}
+		if digits() == 0 {
+			return "", p.errorf("invalid double %q", b.String())
+		}
+		dt = "double"
+	}
+	if n == 0 {
+		return "", p.errorf("invalid number %q", b.String())
+	}
+	return quote(b.String()) + "^^<" + triple.XSD + dt + ">", nil
+}
+
+// collection reads a collection and returns the term of its first element,
+// emitting the statements describing the list.
+func (p *turtleParser) collection() (string, error) {
+	p.next()
+	var items []string
+	for {
+		p.skipSpace()
+		bs := p.peek(1)
+		if len(bs) == 0 {
+			return "", p.errorf("collection is not terminated with )")
+		}
+		if bs[0] == ')' {
+			p.next()
+			break
+		}
+		o, err := p.object()
+		if err != nil {
+			return "", err
+		}
+		items = append(items, o)
+	}
+	head := "<" + rdfNil + ">"
+	for i := len(items) - 1; i >= 0; i-- {
+		b := p.blank()
+		if err := p.emit(b, "<"+rdfFirst+">", items[i]); err != nil {
+			return "", err
+		}
+		if err := p.emit(b, "<"+rdfRest+">", head); err != nil {
+			return "", err
+		}
+		head = b
+	}
+	return head, nil
+}
+
+// propertyList reads a blank node property list and returns the term of its
+// blank node, emitting the statements about it.
+func (p *turtleParser) propertyList() (string, error) {
+	p.next()
+	b := p.blank()
+	p.skipSpace()
+	if bs := p.peek(1); len(bs) > 0 && bs[0] == ']' {
+		p.next()
+		return b, nil
+	}
+	if err := p.predicateObjects(b); err != nil {
+		return "", err
+	}
+	if err := p.expect(']'); err != nil {
+		return "", err
+	}
+	return b, nil
+}
+
+// subject reads the subject of a statement and returns its term.
+func (p *turtleParser) subject() (string, error) {
+	bs := p.peek(2)
+	switch {
+	case bs[0] == '(':
+		return p.collection()
+	case bs[0] == '_' && len(bs) == 2 && bs[1] == ':':
+		return p.labeled()
+	}
+	return p.iri()
+}
+
+// object reads an object and returns its term.
+func (p *turtleParser) object() (string, error) {
+	p.skipSpace()
+	bs := p.peek(2)
+	if len(bs) == 0 {
+		return "", p.errorf("expected an object")
+	}
+	switch c := bs[0]; {
+	case c == '[':
+		return p.propertyList()
+	case c == '(':
+		return p.collection()
+	case c == '"' || c == '\'':
+		return p.str()
+	case c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.' && len(bs) == 2 && bs[1] >= '0' && bs[1] <= '9':
+		return p.number()
+	case p.keyword("true", false) || p.keyword("false", false):
+		v, _ := p.name(false)
+		return quote(v) + "^^<" + triple.XSD + "boolean>", nil
+	}
+	return p.subject()
+}
+
+// predicateObjects reads a predicate object list about the provided subject
+// and emits its statements.
+func (p *turtleParser) predicateObjects(s string) error {
+	for {
+		p.skipSpace()
+		var (
+			v   string
+			err error
+		)
+		if p.keyword("a", false) {
+			p.next()
+			v = "<" + rdfType + ">"
+		} else if v, err = p.iri(); err != nil {
+			return err
+		}
+		for {
+			o, err := p.object()
+			if err != nil {
+				return err
+			}
+			if err := p.emit(s, v, o); err != nil {
+				return err
+			}
+			p.skipSpace()
+			if bs := p.peek(1); len(bs) == 0 || bs[0] != ',' {
+				break
+			}
+			p.next()
+		}
+		// Predicate object lists can have repeated and trailing semicolons.
+		semicolon := false
+		for bs := p.peek(1); len(bs) > 0 && bs[0] == ';'; bs = p.peek(1) {
+			p.next()
+			p.skipSpace()
+			semicolon = true
+		}
+		if bs := p.peek(1); !semicolon || len(bs) == 0 || bs[0] == '.' || bs[0] == ']' {
+			return nil
+		}
+	}
+}
+
+// emit parses the statement for the provided terms and adds it to the sink.
+func (p *turtleParser) emit(s, v, o string) error {
+	t, err := triple.ParseNTriple(s+" "+v+" "+o+" .", p.b)
+	if err != nil {
+		return p.errorf("%v", err)
+	}
+	return p.sink.add(t)
+}
+
+// directive reads a prefix or base declaration. Turtle declarations start
+// with @ and end with a dot, while SPARQL ones do not.
+func (p *turtleParser) directive() error {
+	sparql := true
+	if bs := p.peek(1); bs[0] == '@' {
+		p.next()
+		sparql = false
+	}
+	if p.keyword("prefix", sparql) {
+		for i := 0; i < len("prefix"); i++ {
+			p.next()
+		}
+		p.skipSpace()
+		pfx, err := p.name(false)
+		if err != nil {
+			return err
+		}
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if bs := p.peek(1); len(bs) == 0 || bs[0] != '<' {
+			return p.errorf("expected the IRI of prefix %q", pfx)
+		}
+		iri, err := p.iriRef()
+		if err != nil {
+			return err
+		}
+		p.prefixes[pfx] = iri
+	} else if p.keyword("base", sparql) {
+		for i := 0; i < len("base"); i++ {
+			p.next()
+		}
+		p.skipSpace()
+		if bs := p.peek(1); len(bs) == 0 || bs[0] != '<' {
+			return p.errorf("expected the base IRI")
+		}
+		iri, err := p.iriRef()
+		if err != nil {
+			return err
+		}
+		if p.base, err = url.Parse(iri); err != nil {
+			return p.errorf("invalid base IRI %q; %v", iri, err)
+		}
+	} else {
+		return p.errorf("unknown directive")
+	}
+	if sparql {
+		return nil
+	}
+	return p.expect('.')
+}
+
+// statement reads the next directive or triples statement. It returns false
+// once the reader is exhausted.
+func (p *turtleParser) statement() (bool, error) {
+	p.skipSpace()
+	bs := p.peek(1)
+	if len(bs) == 0 {
+		return false, nil
+	}
+	if bs[0] == '@' || p.keyword("prefix", true) || p.keyword("base", true) {
+		return true, p.directive()
+	}
+	if bs[0] == '[' {
+		s, err := p.propertyList()
+		if err != nil {
+			return false, err
+		}
+		p.skipSpace()
+		if bs := p.peek(1); len(bs) > 0 && bs[0] == '.' {
+			p.next()
+			return true, nil
+		}
+		if err := p.predicateObjects(s); err != nil {
+			return false, err
+		}
+		return true, p.expect('.')
+	}
+	s, err := p.subject()
+	if err != nil {
+		return false, err
+	}
+	if err := p.predicateObjects(s); err != nil {
+		return false, err
+	}
+	return true, p.expect('.')
+}
+
+// ReadTurtle reads the Turtle document of the reader and pushes the resulting
+// triples into the provided channel, which is closed once done. Prefixes,
+// relative IRIs, blank node property lists, and collections are supported.
+// Collections are described using the RDF first, rest, and nil IRIs, as
+// usual. Compressed readers are decompressed as done by Decompress. IRIs,
+// literals, and time anchors are mapped as done by ReadNTriples.
+// The options can be nil to use the defaults. If a blank node scope is
+// provided, it allocates all the blank nodes read. ReadTurtle stops at the
+// first statement it cannot parse. It returns the number of triples pushed.
+func ReadTurtle(ctx context.Context, r io.Reader, b literal.Builder, opts *TurtleOptions, ts chan<- *triple.Triple) (int, error) {
+	defer close(ts)
+	if opts == nil {
+		opts = &TurtleOptions{}
+	}
+	sink, err := newTripleSink(&opts.NTriplesOptions, pushTo(ctx, ts))
+	if err != nil {
+		return 0, fmt.Errorf("io.ReadTurtle: %v", err)
+	}
+	dr, err := Decompress(r)
+	if err != nil {
+		return 0, err
+	}
+	p := &turtleParser{
+		r:        bufio.NewReader(dr),
+		line:     1,
+		b:        b,
+		scope:    opts.Scope,
+		prefixes: make(map[string]string),
+		sink:     sink,
+	}
+	for k, v := range opts.Prefixes {
+		p.prefixes[k] = v
+	}
+	for {
+		more, err := p.statement()
+		if err != nil {
+			return sink.cnt, err
+		}
+		if !more {
+			break
+		}
+	}
+	return sink.cnt, sink.close()
+}
+
+// isLocalName returns true if the provided string can be written as the local
+// part of a prefixed name without escaping.
+func isLocalName(s string) bool {
+	if s == "" || s[0] == '-' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !isNameByte(c) || c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// turtleWriter abbreviates the terms written using the declared prefixes.
+type turtleWriter struct {
+	pfxs []string
+	nss  map[string]string
+}
+
+// abbreviate returns the provided term using a prefixed name if possible.
+func (w *turtleWriter) abbreviate(t string) string {
+	if t == "" {
+		return t
+	}
+	if i := strings.LastIndex(t, `"^^<`); i >= 0 && t[0] == '"' && t[len(t)-1] == '>' {
+		return t[:i+3] + w.abbreviate(t[i+3:])
+	}
+	if t[0] != '<' {
+		return t
+	}
+	iri, best, found := t[1:len(t)-1], "", false
+	for _, pfx := range w.pfxs {
+		ns := w.nss[pfx]
+		if strings.HasPrefix(iri, ns) && isLocalName(iri[len(ns):]) && (!found || len(ns) > len(w.nss[best])) {
+			best, found = pfx, true
+		}
+	}
+	if !found {
+		return t
+	}
+	return best + ":" + iri[len(w.nss[best]):]
+}
+
+// WriteTurtle serializes the triples read from the channel into the writer as
+// a Turtle document till the channel is closed. The prefixes of the options
+// are declared first and used to abbreviate IRIs. Consecutive triples about
+// the same subject are written together. Time anchors are mapped as done by
+// WriteNTriples. The options can be nil to use the defaults. If there is an
+// error writing the serialization will stop, but the channel is still drained.
+// It returns the number of triples serialized.
+func WriteTurtle(ctx context.Context, w io.Writer, ts <-chan *triple.Triple, opts *TurtleOptions) (int, error) {
+	if opts == nil {
+		opts = &TurtleOptions{}
+	}
+	tw := &turtleWriter{nss: make(map[string]string)}
+	for pfx, ns := range opts.Prefixes {
+		if pfx != "" && (!isLocalName(pfx) || pfx[0] >= '0' && pfx[0] <= '9') {
+			for range ts {
+			}
+			return 0, fmt.Errorf("io.WriteTurtle: invalid prefix name %q", pfx)
+		}
+		tw.pfxs = append(tw.pfxs, pfx)
+		tw.nss[pfx] = ns
+	}
+	sort.Strings(tw.pfxs)
+	bw := bufio.NewWriter(w)
+	for _, pfx := range tw.pfxs {
+		fmt.Fprintf(bw, "@prefix %s: <%s> .\n", pfx, tw.nss[pfx])
+	}
+	if len(tw.pfxs) > 0 {
+		bw.WriteString("\n")
+	}
+	var ls, lp string
+	cnt, err := writeStatements(ctx, ts, &opts.NTriplesOptions, func(sts [][3]string) error {
+		for _, st := range sts {
+			s, p, o := tw.abbreviate(st[0]), tw.abbreviate(st[1]), tw.abbreviate(st[2])
+			if st[1] == "<"+rdfType+">" {
+				p = "a"
+			}
+			var err error
+			switch {
+			case s == ls && p == lp:
+				_, err = bw.WriteString(" ,\n\t\t" + o)
+			case s == ls:
+				_, err = bw.WriteString(" ;\n\t" + p + " " + o)
+			case ls == "":
+				_, err = bw.WriteString(s + " " + p + " " + o)
+			default:
+				_, err = bw.WriteString(" .\n" + s + " " + p + " " + o)
+			}
+			if err != nil {
+				return err
+			}
+			ls, lp = s, p
+		}
+		return nil
+	})
+	if err != nil {
+		return cnt, fmt.Errorf("io.WriteTurtle: %v", err)
+	}
+	if ls != "" {
+		bw.WriteString(" .\n")
+	}
+	return cnt, bw.Flush()
+}
@@ -0,0 +1,233 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+)
+
+// readTurtle returns the sorted serializations of the triples read by
+// ReadTurtle.
+func readTurtle(s string, opts *TurtleOptions) ([]string, error) {
+	c := make(chan *triple.Triple)
+	var (
+		cnt int
+		err error
+	)
+	done := make(chan bool)
+	go func() {
+		cnt, err = ReadTurtle(context.Background(), strings.NewReader(s), literal.DefaultBuilder(), opts, c)
+		close(done)
+	}()
+	var got []string
+	for trpl := range c {
+		got = append(got, trpl.String())
+	}
+	<-done
+	if err == nil && cnt != len(got) {
+		err = fmt.Errorf("io.ReadTurtle returned %d triples but pushed %d", cnt, len(got))
+	}
+	sort.Strings(got)
+	return got, err
+}
+
+func TestReadTurtle(t *testing.T) {
+	in := `# People.
+@prefix ex: <http://example.com/> .
+@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+BASE <http://example.com/people/>
+PREFIX xsd: <http://www.w3.org/2001/XMLSchema#>
+
+<john> a foaf:Person ;
+	foaf:name "John"@en, 'Johnny' ;
+	foaf:age 42 ;
+	ex:height 1.80 ;
+	ex:weight 7.5e1 ;
+	ex:alive true ;
+	ex:born "1970-01-01"^^xsd:date ;
+	ex:bio """He said "hi"
+and left.""" ;
+	foaf:knows ex:mary.
+ex:mary foaf:name "Mary\tÁ" ;
+	.
+`
+	want := []string{
+		`/iri<http://example.com/mary>	"http://xmlns.com/foaf/0.1/name"@[]	"Mary	Á"^^type:text`,
+		`/iri<http://example.com/people/john>	"http://example.com/alive"@[]	"true"^^type:bool`,
+		`/iri<http://example.com/people/john>	"http://example.com/bio"@[]	"He said "hi"
+and left."^^type:text`,
+		`/iri<http://example.com/people/john>	"http://example.com/born"@[]	"1970-01-01"^^type:date`,
+		`/iri<http://example.com/people/john>	"http://example.com/height"@[]	"1.8"^^type:decimal`,
+		`/iri<http://example.com/people/john>	"http://example.com/weight"@[]	"75"^^type:float64`,
+		`/iri<http://example.com/people/john>	"http://www.w3.org/1999/02/22-rdf-syntax-ns#type"@[]	/iri<http://xmlns.com/foaf/0.1/Person>`,
+		`/iri<http://example.com/people/john>	"http://xmlns.com/foaf/0.1/age"@[]	"42"^^type:int64`,
+		`/iri<http://example.com/people/john>	"http://xmlns.com/foaf/0.1/knows"@[]	/iri<http://example.com/mary>`,
+		`/iri<http://example.com/people/john>	"http://xmlns.com/foaf/0.1/name"@[]	"John"^^type:text`,
+		`/iri<http://example.com/people/john>	"http://xmlns.com/foaf/0.1/name"@[]	"Johnny"^^type:text`,
+	}
+	got, err := readTurtle(in, nil)
+	if err != nil {
+		t.Fatalf("io.ReadTurtle failed to read %q with error %v", in, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("io.ReadTurtle(%q) returned\n%s\nwant\n%s", in, strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadTurtleBlankNodes(t *testing.T) {
+	in := `@prefix : <http://example.com/> .
+_:b1 :p [ :q ( 1 2 ) ] .
+[] :r () .`
+	// Anonymous blank nodes are allocated in the order the parser completes
+	// them, which can be replayed with a scope of the same name.
+	scope := node.NewBlankNodeScope("test")
+	b, l2, l1, anon := scope.NewBlankNode(), scope.NewBlankNode(), scope.NewBlankNode(), scope.NewBlankNode()
+	rdf := `"http://www.w3.org/1999/02/22-rdf-syntax-ns#`
+	want := []string{
+		anon.String() + "\t\"http://example.com/r\"@[]\t/iri<http://www.w3.org/1999/02/22-rdf-syntax-ns#nil>",
+		b.String() + "\t\"http://example.com/q\"@[]\t" + l1.String(),
+		l1.String() + "\t" + rdf + "first\"@[]\t\"1\"^^type:int64",
+		l1.String() + "\t" + rdf + "rest\"@[]\t" + l2.String(),
+		l2.String() + "\t" + rdf + "first\"@[]\t\"2\"^^type:int64",
+		l2.String() + "\t" + rdf + "rest\"@[]\t/iri<http://www.w3.org/1999/02/22-rdf-syntax-ns#nil>",
+		scope.Labeled("b1").String() + "\t\"http://example.com/p\"@[]\t" + b.String(),
+	}
+	sort.Strings(want)
+	opts := &TurtleOptions{}
+	opts.Scope = node.NewBlankNodeScope("test")
+	got, err := readTurtle(in, opts)
+	if err != nil {
+		t.Fatalf("io.ReadTurtle failed to read %q with error %v", in, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("io.ReadTurtle(%q) returned\n%s\nwant\n%s", in, strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadTurtleErrors(t *testing.T) {
+	table := []struct {
+		in   string
+		want string
+	}{
+		{in: "<http://example.com/a> ex:b <http://example.com/c> .", want: `line 1: undeclared prefix "ex"`},
+		{in: "\n<http://example.com/a> <http://example.com/b> \"c .", want: "line 2: string is not terminated"},
+		{in: "<http://example.com/a> <http://example.com/b> <http://example.com/c>\n<http://example.com/d> <http://example.com/b> <http://example.com/c> .", want: `line 2: expected '.'`},
+		{in: "<http://example.com/a> <http://example.com/b> [ <http://example.com/c> 1 .", want: `expected ']'`},
+		{in: "@foo <http://example.com/> .", want: "unknown directive"},
+	}
+	for _, entry := range table {
+		_, err := readTurtle(entry.in, nil)
+		if err == nil || !strings.Contains(err.Error(), entry.want) {
+			t.Errorf("io.ReadTurtle(%q) returned error %v; want an error containing %q", entry.in, err, entry.want)
+		}
+	}
+}
+
+func TestWriteTurtle(t *testing.T) {
+	ts := parseTriples(t,
+		`/iri<http://example.com/john>	"http://www.w3.org/1999/02/22-rdf-syntax-ns#type"@[]	/iri<http://xmlns.com/foaf/0.1/Person>`,
+		`/iri<http://example.com/john>	"http://xmlns.com/foaf/0.1/knows"@[]	/iri<http://example.com/mary>`,
+		`/iri<http://example.com/john>	"http://xmlns.com/foaf/0.1/knows"@[]	/iri<http://example.com/peter>`,
+		`/iri<http://example.com/john>	"http://xmlns.com/foaf/0.1/age"@[]	"42"^^type:int64`,
+		`/u<mary>	"met"@[2016-04-10T04:21:00Z]	/iri<http://example.com/john/a%20b>`,
+	)
+	opts := &TurtleOptions{Prefixes: map[string]string{
+		"":     "http://example.com/",
+		"foaf": "http://xmlns.com/foaf/0.1/",
+		"xsd":  "http://www.w3.org/2001/XMLSchema#",
+	}}
+	c := make(chan *triple.Triple, len(ts))
+	for _, trpl := range ts {
+		c <- trpl
+	}
+	close(c)
+	var buffer bytes.Buffer
+	cnt, err := WriteTurtle(context.Background(), &buffer, c, opts)
+	if err != nil {
+		t.Fatalf("io.WriteTurtle failed to write %v with error %v", ts, err)
+	}
+	if cnt != len(ts) {
+		t.Errorf("io.WriteTurtle wrote %d triples; want %d", cnt, len(ts))
+	}
+	want := `@prefix : <http://example.com/> .
+@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+:john a foaf:Person ;
+	foaf:knows :mary ,
+		:peter ;
+	foaf:age "42"^^xsd:integer .
+<urn:badwolf:node:/u#mary> <urn:badwolf:predicate:met@2016-04-10T04:21:00Z> <http://example.com/john/a%20b> .
+`
+	if got := buffer.String(); got != want {
+		t.Errorf("io.WriteTurtle wrote\n%s\nwant\n%s", got, want)
+	}
+
+	bad := make(chan *triple.Triple)
+	close(bad)
+	if _, err := WriteTurtle(context.Background(), &buffer, bad, &TurtleOptions{Prefixes: map[string]string{"a b": "http://example.com/"}}); err == nil {
+		t.Errorf("io.WriteTurtle should have failed for an invalid prefix name")
+	}
+}
+
+func TestTurtleRoundTrip(t *testing.T) {
+	ts := parseTriples(t,
+		`/u<john>	"knows"@[]	/u<mary>`,
+		`/u<john>	"met"@[2016-04-10T04:21:00Z]	/u<mary>`,
+		`/u<john>	"met"@[2017-04-10T04:21:00Z]	/u<mary>`,
+		`/u<john>	"lived_in"@[2010-01-01T00:00:00Z/2015-01-01T00:00:00Z]	/city<Paris>`,
+		`/iri<http://example.com/john>	"http://xmlns.com/foaf/0.1/name"@[]	"John \"Johnny\"\nSmith"^^type:text`,
+		`/iri<http://example.com/john>	"http://example.com/height"@[2016-04-10T04:21:00Z]	"1.8"^^type:float64`,
+		`/_<b1>	"http://example.com/about"@[]	"met"@[2015-04-10T04:21:00Z]`,
+	)
+	var want []string
+	for _, trpl := range ts {
+		want = append(want, trpl.String())
+	}
+	sort.Strings(want)
+	pfxs := map[string]string{"ex": "http://example.com/", "foaf": "http://xmlns.com/foaf/0.1/"}
+	for _, opts := range []*TurtleOptions{
+		{Prefixes: pfxs},
+		{NTriplesOptions: NTriplesOptions{Anchors: AnchorReified}, Prefixes: pfxs},
+	} {
+		c := make(chan *triple.Triple, len(ts))
+		for _, trpl := range ts {
+			c <- trpl
+		}
+		close(c)
+		var buffer bytes.Buffer
+		if _, err := WriteTurtle(context.Background(), &buffer, c, opts); err != nil {
+			t.Fatalf("io.WriteTurtle failed to write %v with error %v", ts, err)
+		}
+		got, err := readTurtle(buffer.String(), opts)
+		if err != nil {
+			t.Errorf("io.ReadTurtle failed to read %q with error %v", buffer.String(), err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("io.ReadTurtle(io.WriteTurtle(%v)) returned %v; want %v", ts, got, want)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// visualValue is a value of an attribute of a node of a visual graph.
+type visualValue struct {
+	v, typ string
+	anchor *time.Time
+	end    *time.Time
+}
+
+// visualNode is a node of a visual graph and its attributes.
+type visualNode struct {
+	n     *node.Node
+	attrs map[string][]*visualValue
+}
+
+// visualEdge is an edge of a visual graph.
+type visualEdge struct {
+	s, o string
+	p    *predicate.Predicate
+}
+
+// visualGraph is a property graph built out of triples for visualization
+// tools. Triples whose object is a node are the edges of the graph, while
+// triples whose object is a literal or a predicate are the attributes of their
+// subjects, named after the ID of the predicate.
+type visualGraph struct {
+	nodes []*visualNode
+	idx   map[string]*visualNode
+	edges []*visualEdge
+	keys  []string
+	types map[string]string
+}
+
+// node returns the visual node for the provided node, adding it if needed.
+func (g *visualGraph) node(n *node.Node) *visualNode {
+	k := n.String()
+	vn, ok := g.idx[k]
+	if !ok {
+		vn = &visualNode{n: n, attrs: make(map[string][]*visualValue)}
+		g.idx[k] = vn
+		g.nodes = append(g.nodes, vn)
+	}
+	return vn
+}
+
+// lexicalValue returns the value of the provided literal and the name of its
+// type for visualization tools.
+func lexicalValue(l *literal.Literal) (string, string) {
+	switch l.Type() {
+	case literal.Text:
+		t, _ := l.Text()
+		return t, "string"
+	case literal.Int64:
+		i, _ := l.Int64()
+		return strconv.FormatInt(i, 10), "long"
+	case literal.Float64:
+		f, _ := l.Float64()
+		return strconv.FormatFloat(f, 'g', -1, 64), "double"
+	case literal.Bool:
+		b, _ := l.Bool()
+		return strconv.FormatBool(b), "boolean"
+	}
+	s := l.String()
+	return s[1:strings.LastIndex(s, `"^^type:`)], "string"
+}
+
+// add adds the provided triple to the graph.
+func (g *visualGraph) add(t *triple.Triple) {
+	s, p := g.node(t.Subject()), t.Predicate()
+	if o, err := t.Object().Node(); err == nil {
+		g.node(o)
+		g.edges = append(g.edges, &visualEdge{s: s.n.String(), o: o.String(), p: p})
+		return
+	}
+	vv := &visualValue{}
+	if l, err := t.Object().Literal(); err == nil {
+		vv.v, vv.typ = lexicalValue(l)
+	} else {
+		op, _ := t.Object().Predicate()
+		vv.v, vv.typ = op.String(), "string"
+	}
+	vv.anchor, _ = p.TimeAnchor()
+	if _, end, err := p.Interval(); err == nil {
+		vv.end = end
+	}
+	k := string(p.ID())
+	typ, ok := g.types[k]
+	switch {
+	case !ok:
+		g.keys = append(g.keys, k)
+		g.types[k] = vv.typ
+	case typ != vv.typ:
+		// Attributes with values of different types are written as strings.
+		g.types[k] = "string"
+	}
+	s.attrs[k] = append(s.attrs[k], vv)
+}
+
+// collectVisualGraph returns the visual graph for the triples read from the
+// channel till it is closed, and the number of triples read.
+func collectVisualGraph(ctx context.Context, ts <-chan *triple.Triple) (*visualGraph, int, error) {
+	g := &visualGraph{
+		idx:   make(map[string]*visualNode),
+		types: make(map[string]string),
+	}
+	cnt := 0
+	var err error
+	for t := range ts {
+		if err != nil {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			continue
+		}
+		g.add(t)
+		cnt++
+	}
+	return g, cnt, err
+}
+
+// latest returns the values of the attribute valid at the latest time anchor.
+// Immutable values are only returned if there are no temporal ones.
+func latest(vs []*visualValue) []*visualValue {
+	var (
+		res []*visualValue
+		ta  *time.Time
+	)
+	for _, v := range vs {
+		switch {
+		case v.anchor == nil && ta == nil:
+			res = append(res, v)
+		case v.anchor == nil:
+		case ta == nil || v.anchor.After(*ta):
+			res, ta = []*visualValue{v}, v.anchor
+		case v.anchor.Equal(*ta):
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// writeXML writes the XML document for the provided value.
+func writeXML(w io.Writer, v interface{}) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	e := xml.NewEncoder(bw)
+	e.Indent("", "  ")
+	if err := e.Encode(v); err != nil {
+		return err
+	}
+	bw.WriteString("\n")
+	return bw.Flush()
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+// WriteGraphML serializes the triples read from the channel, such as the
+// triples of a graph or the ones returned by a CONSTRUCT statement, into the
+// writer as a GraphML document till the channel is closed, so they can be
+// explored using visualization tools. Nodes are identified by their pretty
+// printed form, and have their type and their ID as attributes. Triples whose
+// object is a node are written as edges with the ID of the predicate, its time
+// anchor, and the end of its interval, as attributes. Triples whose object is
+// a literal or a predicate are written as attributes of their subject named
+// after the ID of the predicate. GraphML has no notion of time, so only the
+// values valid at the latest time anchor are written, separated by commas if
+// there are several. It returns the number of triples serialized.
+func WriteGraphML(ctx context.Context, w io.Writer, ts <-chan *triple.Triple) (int, error) {
+	g, cnt, err := collectVisualGraph(ctx, ts)
+	if err != nil {
+		return 0, fmt.Errorf("io.WriteGraphML: %v", err)
+	}
+	doc := &graphML{
+		Keys: []graphMLKey{
+			{ID: "type", For: "node", Name: "type", Type: "string"},
+			{ID: "id", For: "node", Name: "id", Type: "string"},
+			{ID: "predicate", For: "edge", Name: "predicate", Type: "string"},
+			{ID: "anchor", For: "edge", Name: "anchor", Type: "string"},
+			{ID: "end", For: "edge", Name: "end", Type: "string"},
+		},
+		Graph: graphMLGraph{ID: "G", EdgeDefault: "directed"},
+	}
+	keys, types := make(map[string]string), make(map[string]string)
+	for i, k := range g.keys {
+		keys[k], types[k] = "a"+strconv.Itoa(i), g.types[k]
+	}
+	for _, n := range g.nodes {
+		gn := graphMLNode{
+			ID: n.n.String(),
+			Data: []graphMLData{
+				{Key: "type", Value: n.n.Type().String()},
+				{Key: "id", Value: n.n.ID().String()},
+			},
+		}
+		for _, k := range g.keys {
+			vs := latest(n.attrs[k])
+			if len(vs) == 0 {
+				continue
+			}
+			var ss []string
+			for _, v := range vs {
+				ss = append(ss, v.v)
+			}
+			if len(ss) > 1 {
+				types[k] = "string"
+			}
+			gn.Data = append(gn.Data, graphMLData{Key: keys[k], Value: strings.Join(ss, ", ")})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gn)
+	}
+	for _, k := range g.keys {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: keys[k], For: "node", Name: k, Type: types[k]})
+	}
+	for i, e := range g.edges {
+		ge := graphMLEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: e.s,
+			Target: e.o,
+			Data:   []graphMLData{{Key: "predicate", Value: string(e.p.ID())}},
+		}
+		if ta, err := e.p.TimeAnchor(); err == nil {
+			ge.Data = append(ge.Data, graphMLData{Key: "anchor", Value: ta.Format(time.RFC3339Nano)})
+		}
+		if _, end, err := e.p.Interval(); err == nil {
+			ge.Data = append(ge.Data, graphMLData{Key: "end", Value: end.Format(time.RFC3339Nano)})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, ge)
+	}
+	if err := writeXML(w, doc); err != nil {
+		return 0, fmt.Errorf("io.WriteGraphML: %v", err)
+	}
+	return cnt, nil
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Mode       string          `xml:"mode,attr,omitempty"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+	Start string `xml:"start,attr,omitempty"`
+	End   string `xml:"end,attr,omitempty"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+	Label  string `xml:"label,attr"`
+	Start  string `xml:"start,attr,omitempty"`
+	End    string `xml:"end,attr,omitempty"`
+}
+
+type gexfGraph struct {
+	Mode            string           `xml:"mode,attr"`
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	TimeFormat      string           `xml:"timeformat,attr,omitempty"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexf struct {
+	XMLName xml.Name  `xml:"http://www.gexf.net/1.2draft gexf"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+// WriteGEXF serializes the triples read from the channel, such as the triples
+// of a graph or the ones returned by a CONSTRUCT statement, into the writer as
+// a GEXF document till the channel is closed, so they can be explored using
+// visualization tools. Nodes, edges, and attributes are built as done by
+// WriteGraphML, but GEXF supports dynamic graphs, so time anchors are kept.
+// Temporal edges and attribute values start at their time anchor, and the
+// ones of interval predicates also end at the end of their interval, which
+// allows watching the graph evolve over time. It returns the number of triples
+// serialized.
+func WriteGEXF(ctx context.Context, w io.Writer, ts <-chan *triple.Triple) (int, error) {
+	g, cnt, err := collectVisualGraph(ctx, ts)
+	if err != nil {
+		return 0, fmt.Errorf("io.WriteGEXF: %v", err)
+	}
+	dynamic := false
+	span := func(p *predicate.Predicate) (string, string) {
+		var start, end string
+		if ta, err := p.TimeAnchor(); err == nil {
+			start, dynamic = ta.Format(time.RFC3339Nano), true
+		}
+		if _, te, err := p.Interval(); err == nil {
+			end = te.Format(time.RFC3339Nano)
+		}
+		return start, end
+	}
+	doc := &gexf{
+		Version: "1.2",
+		Graph:   gexfGraph{DefaultEdgeType: "directed"},
+	}
+	nas := gexfAttributes{Class: "node", Attributes: []gexfAttribute{{ID: "type", Title: "type", Type: "string"}}}
+	ids := make(map[string]string)
+	for i, k := range g.keys {
+		ids[k] = "a" + strconv.Itoa(i)
+		nas.Attributes = append(nas.Attributes, gexfAttribute{ID: ids[k], Title: k, Type: g.types[k]})
+	}
+	for i, n := range g.nodes {
+		ids[n.n.String()] = "n" + strconv.Itoa(i)
+		gn := gexfNode{
+			ID:        ids[n.n.String()],
+			Label:     n.n.String(),
+			AttValues: []gexfAttValue{{For: "type", Value: n.n.Type().String()}},
+		}
+		for _, k := range g.keys {
+			for _, v := range n.attrs[k] {
+				av := gexfAttValue{For: ids[k], Value: v.v}
+				if v.anchor != nil {
+					av.Start, dynamic = v.anchor.Format(time.RFC3339Nano), true
+				}
+				if v.end != nil {
+					av.End = v.end.Format(time.RFC3339Nano)
+				}
+				gn.AttValues = append(gn.AttValues, av)
+			}
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gn)
+	}
+	for i, e := range g.edges {
+		ge := gexfEdge{
+			ID:     strconv.Itoa(i),
+			Source: ids[e.s],
+			Target: ids[e.o],
+			Label:  string(e.p.ID()),
+		}
+		ge.Start, ge.End = span(e.p)
+		doc.Graph.Edges = append(doc.Graph.Edges, ge)
+	}
+	doc.Graph.Mode = "static"
+	if dynamic {
+		doc.Graph.Mode, doc.Graph.TimeFormat, nas.Mode = "dynamic", "dateTime", "dynamic"
+	}
+	doc.Graph.Attributes = []gexfAttributes{nas}
+	if err := writeXML(w, doc); err != nil {
+		return 0, fmt.Errorf("io.WriteGEXF: %v", err)
+	}
+	return cnt, nil
+}
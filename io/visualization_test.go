@@ -0,0 +1,136 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/triple"
+)
+
+// visualizationTriples are the triples used to test the visualization
+// exporters.
+var visualizationTriples = []string{
+	"/u<john>\t\"knows\"@[]\t/u<mary>",
+	"/u<john>\t\"met\"@[2016-04-10T04:21:00.000000000Z]\t/u<mary>",
+	"/u<john>\t\"lives_in\"@[2015-01-01T00:00:00Z/2016-01-01T00:00:00Z]\t/city<paris>",
+	"/u<john>\t\"age\"@[]\t\"42\"^^type:int64",
+	"/u<mary>\t\"name\"@[]\t\"Mary\"^^type:text",
+	"/u<mary>\t\"name\"@[2017-01-01T00:00:00Z]\t\"Mary Smith\"^^type:text",
+}
+
+// exportVisualization serializes the provided triples using the provided
+// exporter.
+func exportVisualization(t *testing.T, f func(context.Context, *bytes.Buffer, <-chan *triple.Triple) (int, error), ss []string) string {
+	ts := parseTriples(t, ss...)
+	c := make(chan *triple.Triple, len(ts))
+	for _, trpl := range ts {
+		c <- trpl
+	}
+	close(c)
+	var buffer bytes.Buffer
+	cnt, err := f(context.Background(), &buffer, c)
+	if err != nil {
+		t.Fatalf("failed to export %v with error %v", ss, err)
+	}
+	if cnt != len(ts) {
+		t.Errorf("exported %d triples; want %d", cnt, len(ts))
+	}
+	if err := xml.Unmarshal(buffer.Bytes(), new(struct{})); err != nil {
+		t.Errorf("exported an invalid XML document %q; %v", buffer.String(), err)
+	}
+	return buffer.String()
+}
+
+func TestWriteGraphML(t *testing.T) {
+	out := exportVisualization(t, func(ctx context.Context, w *bytes.Buffer, ts <-chan *triple.Triple) (int, error) {
+		return WriteGraphML(ctx, w, ts)
+	}, visualizationTriples)
+	table := []string{
+		`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`,
+		`<key id="a0" for="node" attr.name="age" attr.type="long"></key>`,
+		`<key id="a1" for="node" attr.name="name" attr.type="string"></key>`,
+		`<graph id="G" edgedefault="directed">`,
+		`<node id="/u&lt;john&gt;">`,
+		`<data key="type">/u</data>`,
+		`<data key="a0">42</data>`,
+		`<data key="a1">Mary Smith</data>`,
+		`<node id="/city&lt;paris&gt;">`,
+		`<edge id="e0" source="/u&lt;john&gt;" target="/u&lt;mary&gt;">`,
+		`<data key="predicate">met</data>`,
+		`<data key="anchor">2016-04-10T04:21:00Z</data>`,
+		`<data key="anchor">2015-01-01T00:00:00Z</data>`,
+		`<data key="end">2016-01-01T00:00:00Z</data>`,
+	}
+	for _, want := range table {
+		if !strings.Contains(out, want) {
+			t.Errorf("io.WriteGraphML returned\n%s\nwhich does not contain %q", out, want)
+		}
+	}
+	if strings.Contains(out, `<data key="a1">Mary</data>`) {
+		t.Errorf("io.WriteGraphML returned\n%s\nwhich contains the outdated name", out)
+	}
+}
+
+func TestWriteGEXF(t *testing.T) {
+	table := []struct {
+		in   []string
+		want []string
+		not  []string
+	}{
+		{
+			in: visualizationTriples,
+			want: []string{
+				`<gexf xmlns="http://www.gexf.net/1.2draft" version="1.2">`,
+				`<graph mode="dynamic" defaultedgetype="directed" timeformat="dateTime">`,
+				`<attributes class="node" mode="dynamic">`,
+				`<attribute id="a0" title="age" type="long"></attribute>`,
+				`<node id="n0" label="/u&lt;john&gt;">`,
+				`<attvalue for="a1" value="Mary"></attvalue>`,
+				`<attvalue for="a1" value="Mary Smith" start="2017-01-01T00:00:00Z"></attvalue>`,
+				`<edge id="0" source="n0" target="n1" label="knows"></edge>`,
+				`<edge id="1" source="n0" target="n1" label="met" start="2016-04-10T04:21:00Z"></edge>`,
+				`<edge id="2" source="n0" target="n2" label="lives_in" start="2015-01-01T00:00:00Z" end="2016-01-01T00:00:00Z"></edge>`,
+			},
+		},
+		{
+			in: visualizationTriples[:1],
+			want: []string{
+				`<graph mode="static" defaultedgetype="directed">`,
+				`<attributes class="node">`,
+			},
+			not: []string{"timeformat", "start="},
+		},
+	}
+	for _, entry := range table {
+		out := exportVisualization(t, func(ctx context.Context, w *bytes.Buffer, ts <-chan *triple.Triple) (int, error) {
+			return WriteGEXF(ctx, w, ts)
+		}, entry.in)
+		for _, want := range entry.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("io.WriteGEXF(%v) returned\n%s\nwhich does not contain %q", entry.in, out, want)
+			}
+		}
+		for _, not := range entry.not {
+			if strings.Contains(out, not) {
+				t.Errorf("io.WriteGEXF(%v) returned\n%s\nwhich contains %q", entry.in, out, not)
+			}
+		}
+	}
+}
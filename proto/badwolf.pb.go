@@ -0,0 +1,1502 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Definition of the BadWolf gRPC service, which provides non Go clients with a
+// typed interface to run BQL statements, update graphs and follow their
+// changes. The messages mirror the triple, table and storage packages.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: badwolf.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Change_Op int32
+
+const (
+	Change_ADDED   Change_Op = 0
+	Change_REMOVED Change_Op = 1
+)
+
+// Enum value maps for Change_Op.
+var (
+	Change_Op_name = map[int32]string{
+		0: "ADDED",
+		1: "REMOVED",
+	}
+	Change_Op_value = map[string]int32{
+		"ADDED":   0,
+		"REMOVED": 1,
+	}
+)
+
+func (x Change_Op) Enum() *Change_Op {
+	p := new(Change_Op)
+	*p = x
+	return p
+}
+
+func (x Change_Op) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Change_Op) Descriptor() protoreflect.EnumDescriptor {
+	return file_badwolf_proto_enumTypes[0].Descriptor()
+}
+
+func (Change_Op) Type() protoreflect.EnumType {
+	return &file_badwolf_proto_enumTypes[0]
+}
+
+func (x Change_Op) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Change_Op.Descriptor instead.
+func (Change_Op) EnumDescriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{14, 0}
+}
+
+// Node mirrors node.Node, for instance /u<joe>.
+type Node struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Type of the node, for instance /u.
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// ID of the node, for instance joe.
+	Id string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *Node) Reset() {
+	*x = Node{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Node) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Node) ProtoMessage() {}
+
+func (x *Node) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Node.ProtoReflect.Descriptor instead.
+func (*Node) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Node) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Node) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// Predicate mirrors predicate.Predicate, for instance "parent_of"@[].
+type Predicate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// ID of the predicate, for instance parent_of.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Time anchor of temporal predicates. Immutable predicates have none.
+	Anchor *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=anchor,proto3" json:"anchor,omitempty"`
+	// Exclusive end of the validity interval of interval predicates.
+	End *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=end,proto3" json:"end,omitempty"`
+}
+
+func (x *Predicate) Reset() {
+	*x = Predicate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Predicate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Predicate) ProtoMessage() {}
+
+func (x *Predicate) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Predicate.ProtoReflect.Descriptor instead.
+func (*Predicate) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Predicate) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Predicate) GetAnchor() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Anchor
+	}
+	return nil
+}
+
+func (x *Predicate) GetEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+// Literal mirrors literal.Literal, for instance "1"^^type:int64.
+type Literal struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the type of the literal, as returned by literal.Type.String, for
+	// instance int64 or the name of a custom type.
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// Types that are assignable to Value:
+	//	*Literal_Bool
+	//	*Literal_Int64
+	//	*Literal_Float64
+	//	*Literal_Text
+	//	*Literal_Blob
+	Value isLiteral_Value `protobuf_oneof:"value"`
+}
+
+func (x *Literal) Reset() {
+	*x = Literal{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Literal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Literal) ProtoMessage() {}
+
+func (x *Literal) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Literal.ProtoReflect.Descriptor instead.
+func (*Literal) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Literal) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (m *Literal) GetValue() isLiteral_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *Literal) GetBool() bool {
+	if x, ok := x.GetValue().(*Literal_Bool); ok {
+		return x.Bool
+	}
+	return false
+}
+
+func (x *Literal) GetInt64() int64 {
+	if x, ok := x.GetValue().(*Literal_Int64); ok {
+		return x.Int64
+	}
+	return 0
+}
+
+func (x *Literal) GetFloat64() float64 {
+	if x, ok := x.GetValue().(*Literal_Float64); ok {
+		return x.Float64
+	}
+	return 0
+}
+
+func (x *Literal) GetText() string {
+	if x, ok := x.GetValue().(*Literal_Text); ok {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Literal) GetBlob() []byte {
+	if x, ok := x.GetValue().(*Literal_Blob); ok {
+		return x.Blob
+	}
+	return nil
+}
+
+type isLiteral_Value interface {
+	isLiteral_Value()
+}
+
+type Literal_Bool struct {
+	Bool bool `protobuf:"varint,2,opt,name=bool,proto3,oneof"`
+}
+
+type Literal_Int64 struct {
+	Int64 int64 `protobuf:"varint,3,opt,name=int64,proto3,oneof"`
+}
+
+type Literal_Float64 struct {
+	Float64 float64 `protobuf:"fixed64,4,opt,name=float64,proto3,oneof"`
+}
+
+type Literal_Text struct {
+	// Text of text literals, and the text representation of the values of
+	// decimal, date, and custom literals.
+	Text string `protobuf:"bytes,5,opt,name=text,proto3,oneof"`
+}
+
+type Literal_Blob struct {
+	Blob []byte `protobuf:"bytes,6,opt,name=blob,proto3,oneof"`
+}
+
+func (*Literal_Bool) isLiteral_Value() {}
+
+func (*Literal_Int64) isLiteral_Value() {}
+
+func (*Literal_Float64) isLiteral_Value() {}
+
+func (*Literal_Text) isLiteral_Value() {}
+
+func (*Literal_Blob) isLiteral_Value() {}
+
+// Object mirrors triple.Object.
+type Object struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Value:
+	//	*Object_Node
+	//	*Object_Predicate
+	//	*Object_Literal
+	Value isObject_Value `protobuf_oneof:"value"`
+}
+
+func (x *Object) Reset() {
+	*x = Object{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Object) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Object) ProtoMessage() {}
+
+func (x *Object) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Object.ProtoReflect.Descriptor instead.
+func (*Object) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{3}
+}
+
+func (m *Object) GetValue() isObject_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *Object) GetNode() *Node {
+	if x, ok := x.GetValue().(*Object_Node); ok {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *Object) GetPredicate() *Predicate {
+	if x, ok := x.GetValue().(*Object_Predicate); ok {
+		return x.Predicate
+	}
+	return nil
+}
+
+func (x *Object) GetLiteral() *Literal {
+	if x, ok := x.GetValue().(*Object_Literal); ok {
+		return x.Literal
+	}
+	return nil
+}
+
+type isObject_Value interface {
+	isObject_Value()
+}
+
+type Object_Node struct {
+	Node *Node `protobuf:"bytes,1,opt,name=node,proto3,oneof"`
+}
+
+type Object_Predicate struct {
+	Predicate *Predicate `protobuf:"bytes,2,opt,name=predicate,proto3,oneof"`
+}
+
+type Object_Literal struct {
+	Literal *Literal `protobuf:"bytes,3,opt,name=literal,proto3,oneof"`
+}
+
+func (*Object_Node) isObject_Value() {}
+
+func (*Object_Predicate) isObject_Value() {}
+
+func (*Object_Literal) isObject_Value() {}
+
+// Triple mirrors triple.Triple.
+type Triple struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Subject   *Node      `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	Predicate *Predicate `protobuf:"bytes,2,opt,name=predicate,proto3" json:"predicate,omitempty"`
+	Object    *Object    `protobuf:"bytes,3,opt,name=object,proto3" json:"object,omitempty"`
+}
+
+func (x *Triple) Reset() {
+	*x = Triple{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Triple) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Triple) ProtoMessage() {}
+
+func (x *Triple) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Triple.ProtoReflect.Descriptor instead.
+func (*Triple) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Triple) GetSubject() *Node {
+	if x != nil {
+		return x.Subject
+	}
+	return nil
+}
+
+func (x *Triple) GetPredicate() *Predicate {
+	if x != nil {
+		return x.Predicate
+	}
+	return nil
+}
+
+func (x *Triple) GetObject() *Object {
+	if x != nil {
+		return x.Object
+	}
+	return nil
+}
+
+// Cell mirrors table.Cell, the value bound to a binding in a row.
+type Cell struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Value:
+	//	*Cell_S
+	//	*Cell_Node
+	//	*Cell_Predicate
+	//	*Cell_Literal
+	//	*Cell_Time
+	Value isCell_Value `protobuf_oneof:"value"`
+}
+
+func (x *Cell) Reset() {
+	*x = Cell{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Cell) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cell) ProtoMessage() {}
+
+func (x *Cell) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cell.ProtoReflect.Descriptor instead.
+func (*Cell) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{5}
+}
+
+func (m *Cell) GetValue() isCell_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *Cell) GetS() string {
+	if x, ok := x.GetValue().(*Cell_S); ok {
+		return x.S
+	}
+	return ""
+}
+
+func (x *Cell) GetNode() *Node {
+	if x, ok := x.GetValue().(*Cell_Node); ok {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *Cell) GetPredicate() *Predicate {
+	if x, ok := x.GetValue().(*Cell_Predicate); ok {
+		return x.Predicate
+	}
+	return nil
+}
+
+func (x *Cell) GetLiteral() *Literal {
+	if x, ok := x.GetValue().(*Cell_Literal); ok {
+		return x.Literal
+	}
+	return nil
+}
+
+func (x *Cell) GetTime() *timestamppb.Timestamp {
+	if x, ok := x.GetValue().(*Cell_Time); ok {
+		return x.Time
+	}
+	return nil
+}
+
+type isCell_Value interface {
+	isCell_Value()
+}
+
+type Cell_S struct {
+	S string `protobuf:"bytes,1,opt,name=s,proto3,oneof"`
+}
+
+type Cell_Node struct {
+	Node *Node `protobuf:"bytes,2,opt,name=node,proto3,oneof"`
+}
+
+type Cell_Predicate struct {
+	Predicate *Predicate `protobuf:"bytes,3,opt,name=predicate,proto3,oneof"`
+}
+
+type Cell_Literal struct {
+	Literal *Literal `protobuf:"bytes,4,opt,name=literal,proto3,oneof"`
+}
+
+type Cell_Time struct {
+	Time *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=time,proto3,oneof"`
+}
+
+func (*Cell_S) isCell_Value() {}
+
+func (*Cell_Node) isCell_Value() {}
+
+func (*Cell_Predicate) isCell_Value() {}
+
+func (*Cell_Literal) isCell_Value() {}
+
+func (*Cell_Time) isCell_Value() {}
+
+// Row mirrors table.Row, keyed by binding.
+type Row struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cells map[string]*Cell `protobuf:"bytes,1,rep,name=cells,proto3" json:"cells,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *Row) Reset() {
+	*x = Row{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Row) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Row) ProtoMessage() {}
+
+func (x *Row) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Row.ProtoReflect.Descriptor instead.
+func (*Row) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Row) GetCells() map[string]*Cell {
+	if x != nil {
+		return x.Cells
+	}
+	return nil
+}
+
+type ExecuteQueryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// BQL statement to run.
+	Query string `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+}
+
+func (x *ExecuteQueryRequest) Reset() {
+	*x = ExecuteQueryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExecuteQueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteQueryRequest) ProtoMessage() {}
+
+func (x *ExecuteQueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteQueryRequest.ProtoReflect.Descriptor instead.
+func (*ExecuteQueryRequest) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ExecuteQueryRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+// ExecuteQueryResponse is a chunk of the results of a statement. The first
+// one provides the bindings of the results, and the following ones their rows.
+type ExecuteQueryResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Bindings []string `protobuf:"bytes,1,rep,name=bindings,proto3" json:"bindings,omitempty"`
+	Rows     []*Row   `protobuf:"bytes,2,rep,name=rows,proto3" json:"rows,omitempty"`
+}
+
+func (x *ExecuteQueryResponse) Reset() {
+	*x = ExecuteQueryResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExecuteQueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteQueryResponse) ProtoMessage() {}
+
+func (x *ExecuteQueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteQueryResponse.ProtoReflect.Descriptor instead.
+func (*ExecuteQueryResponse) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ExecuteQueryResponse) GetBindings() []string {
+	if x != nil {
+		return x.Bindings
+	}
+	return nil
+}
+
+func (x *ExecuteQueryResponse) GetRows() []*Row {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+type TriplesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the graph to update, for instance ?family.
+	Graph   string    `protobuf:"bytes,1,opt,name=graph,proto3" json:"graph,omitempty"`
+	Triples []*Triple `protobuf:"bytes,2,rep,name=triples,proto3" json:"triples,omitempty"`
+}
+
+func (x *TriplesRequest) Reset() {
+	*x = TriplesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TriplesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriplesRequest) ProtoMessage() {}
+
+func (x *TriplesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriplesRequest.ProtoReflect.Descriptor instead.
+func (*TriplesRequest) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *TriplesRequest) GetGraph() string {
+	if x != nil {
+		return x.Graph
+	}
+	return ""
+}
+
+func (x *TriplesRequest) GetTriples() []*Triple {
+	if x != nil {
+		return x.Triples
+	}
+	return nil
+}
+
+type TriplesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Number of triples in the request processed.
+	Triples int64 `protobuf:"varint,1,opt,name=triples,proto3" json:"triples,omitempty"`
+}
+
+func (x *TriplesResponse) Reset() {
+	*x = TriplesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TriplesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriplesResponse) ProtoMessage() {}
+
+func (x *TriplesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriplesResponse.ProtoReflect.Descriptor instead.
+func (*TriplesResponse) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TriplesResponse) GetTriples() int64 {
+	if x != nil {
+		return x.Triples
+	}
+	return 0
+}
+
+type ListGraphsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListGraphsRequest) Reset() {
+	*x = ListGraphsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListGraphsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGraphsRequest) ProtoMessage() {}
+
+func (x *ListGraphsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGraphsRequest.ProtoReflect.Descriptor instead.
+func (*ListGraphsRequest) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{11}
+}
+
+type ListGraphsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Sorted names of the graphs in the store.
+	Graphs []string `protobuf:"bytes,1,rep,name=graphs,proto3" json:"graphs,omitempty"`
+}
+
+func (x *ListGraphsResponse) Reset() {
+	*x = ListGraphsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListGraphsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGraphsResponse) ProtoMessage() {}
+
+func (x *ListGraphsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGraphsResponse.ProtoReflect.Descriptor instead.
+func (*ListGraphsResponse) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListGraphsResponse) GetGraphs() []string {
+	if x != nil {
+		return x.Graphs
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the graph to watch.
+	Graph string `protobuf:"bytes,1,opt,name=graph,proto3" json:"graph,omitempty"`
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WatchRequest) GetGraph() string {
+	if x != nil {
+		return x.Graph
+	}
+	return ""
+}
+
+// Change mirrors storage.Change.
+type Change struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Op      Change_Op `protobuf:"varint,1,opt,name=op,proto3,enum=badwolf.v1.Change_Op" json:"op,omitempty"`
+	Triples []*Triple `protobuf:"bytes,2,rep,name=triples,proto3" json:"triples,omitempty"`
+}
+
+func (x *Change) Reset() {
+	*x = Change{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_badwolf_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Change) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Change) ProtoMessage() {}
+
+func (x *Change) ProtoReflect() protoreflect.Message {
+	mi := &file_badwolf_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Change.ProtoReflect.Descriptor instead.
+func (*Change) Descriptor() ([]byte, []int) {
+	return file_badwolf_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Change) GetOp() Change_Op {
+	if x != nil {
+		return x.Op
+	}
+	return Change_ADDED
+}
+
+func (x *Change) GetTriples() []*Triple {
+	if x != nil {
+		return x.Triples
+	}
+	return nil
+}
+
+var File_badwolf_proto protoreflect.FileDescriptor
+
+var file_badwolf_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x0a, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x1a, 0x1f, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x2a, 0x0a, 0x04,
+	0x4e, 0x6f, 0x64, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x7d, 0x0a, 0x09, 0x50, 0x72, 0x65, 0x64,
+	0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x32, 0x0a, 0x06, 0x61, 0x6e, 0x63, 0x68, 0x6f, 0x72, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x52, 0x06, 0x61, 0x6e, 0x63, 0x68, 0x6f, 0x72, 0x12, 0x2c, 0x0a, 0x03, 0x65, 0x6e, 0x64,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x22, 0x9c, 0x01, 0x0a, 0x07, 0x4c, 0x69, 0x74, 0x65,
+	0x72, 0x61, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x08, 0x48, 0x00, 0x52, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x12, 0x16, 0x0a,
+	0x05, 0x69, 0x6e, 0x74, 0x36, 0x34, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x05,
+	0x69, 0x6e, 0x74, 0x36, 0x34, 0x12, 0x1a, 0x0a, 0x07, 0x66, 0x6c, 0x6f, 0x61, 0x74, 0x36, 0x34,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x07, 0x66, 0x6c, 0x6f, 0x61, 0x74, 0x36,
+	0x34, 0x12, 0x14, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x48,
+	0x00, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x12, 0x14, 0x0a, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x42, 0x07, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xa1, 0x01, 0x0a, 0x06, 0x4f, 0x62, 0x6a, 0x65, 0x63,
+	0x74, 0x12, 0x26, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x10, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x6f, 0x64,
+	0x65, 0x48, 0x00, 0x52, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12, 0x35, 0x0a, 0x09, 0x70, 0x72, 0x65,
+	0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x62,
+	0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63,
+	0x61, 0x74, 0x65, 0x48, 0x00, 0x52, 0x09, 0x70, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65,
+	0x12, 0x2f, 0x0a, 0x07, 0x6c, 0x69, 0x74, 0x65, 0x72, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x13, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x4c,
+	0x69, 0x74, 0x65, 0x72, 0x61, 0x6c, 0x48, 0x00, 0x52, 0x07, 0x6c, 0x69, 0x74, 0x65, 0x72, 0x61,
+	0x6c, 0x42, 0x07, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x95, 0x01, 0x0a, 0x06, 0x54,
+	0x72, 0x69, 0x70, 0x6c, 0x65, 0x12, 0x2a, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66,
+	0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63,
+	0x74, 0x12, 0x33, 0x0a, 0x09, 0x70, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76,
+	0x31, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x52, 0x09, 0x70, 0x72, 0x65,
+	0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x2a, 0x0a, 0x06, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66,
+	0x2e, 0x76, 0x31, 0x2e, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x52, 0x06, 0x6f, 0x62, 0x6a, 0x65,
+	0x63, 0x74, 0x22, 0xe1, 0x01, 0x0a, 0x04, 0x43, 0x65, 0x6c, 0x6c, 0x12, 0x0e, 0x0a, 0x01, 0x73,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x01, 0x73, 0x12, 0x26, 0x0a, 0x04, 0x6e,
+	0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x62, 0x61, 0x64, 0x77,
+	0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x48, 0x00, 0x52, 0x04, 0x6e,
+	0x6f, 0x64, 0x65, 0x12, 0x35, 0x0a, 0x09, 0x70, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66,
+	0x2e, 0x76, 0x31, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x48, 0x00, 0x52,
+	0x09, 0x70, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x2f, 0x0a, 0x07, 0x6c, 0x69,
+	0x74, 0x65, 0x72, 0x61, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x62, 0x61,
+	0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x74, 0x65, 0x72, 0x61, 0x6c,
+	0x48, 0x00, 0x52, 0x07, 0x6c, 0x69, 0x74, 0x65, 0x72, 0x61, 0x6c, 0x12, 0x30, 0x0a, 0x04, 0x74,
+	0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x48, 0x00, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x42, 0x07, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x83, 0x01, 0x0a, 0x03, 0x52, 0x6f, 0x77, 0x12, 0x30,
+	0x0a, 0x05, 0x63, 0x65, 0x6c, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x77, 0x2e, 0x43,
+	0x65, 0x6c, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x05, 0x63, 0x65, 0x6c, 0x6c, 0x73,
+	0x1a, 0x4a, 0x0a, 0x0a, 0x43, 0x65, 0x6c, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x26, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x10, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c,
+	0x6c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x2b, 0x0a, 0x13,
+	0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x65, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x71, 0x75, 0x65, 0x72, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x71, 0x75, 0x65, 0x72, 0x79, 0x22, 0x57, 0x0a, 0x14, 0x45, 0x78, 0x65,
+	0x63, 0x75, 0x74, 0x65, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x69, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x08, 0x62, 0x69, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x23, 0x0a,
+	0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x62, 0x61,
+	0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x77, 0x52, 0x04, 0x72, 0x6f,
+	0x77, 0x73, 0x22, 0x54, 0x0a, 0x0e, 0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x61, 0x70, 0x68, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x61, 0x70, 0x68, 0x12, 0x2c, 0x0a, 0x07, 0x74, 0x72,
+	0x69, 0x70, 0x6c, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x62, 0x61,
+	0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x52,
+	0x07, 0x74, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x22, 0x2b, 0x0a, 0x0f, 0x54, 0x72, 0x69, 0x70,
+	0x6c, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x74,
+	0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x74, 0x72,
+	0x69, 0x70, 0x6c, 0x65, 0x73, 0x22, 0x13, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x72, 0x61,
+	0x70, 0x68, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x2c, 0x0a, 0x12, 0x4c, 0x69,
+	0x73, 0x74, 0x47, 0x72, 0x61, 0x70, 0x68, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x67, 0x72, 0x61, 0x70, 0x68, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x06, 0x67, 0x72, 0x61, 0x70, 0x68, 0x73, 0x22, 0x24, 0x0a, 0x0c, 0x57, 0x61, 0x74, 0x63,
+	0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x61, 0x70,
+	0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x61, 0x70, 0x68, 0x22, 0x7b,
+	0x0a, 0x06, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x25, 0x0a, 0x02, 0x6f, 0x70, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76,
+	0x31, 0x2e, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x2e, 0x4f, 0x70, 0x52, 0x02, 0x6f, 0x70, 0x12,
+	0x2c, 0x0a, 0x07, 0x74, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x12, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72,
+	0x69, 0x70, 0x6c, 0x65, 0x52, 0x07, 0x74, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x22, 0x1c, 0x0a,
+	0x02, 0x4f, 0x70, 0x12, 0x09, 0x0a, 0x05, 0x41, 0x44, 0x44, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0b,
+	0x0a, 0x07, 0x52, 0x45, 0x4d, 0x4f, 0x56, 0x45, 0x44, 0x10, 0x01, 0x32, 0xf9, 0x02, 0x0a, 0x07,
+	0x42, 0x61, 0x64, 0x57, 0x6f, 0x6c, 0x66, 0x12, 0x53, 0x0a, 0x0c, 0x45, 0x78, 0x65, 0x63, 0x75,
+	0x74, 0x65, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x1f, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c,
+	0x66, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x65, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f,
+	0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x65, 0x51, 0x75, 0x65,
+	0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x47, 0x0a, 0x0a,
+	0x41, 0x64, 0x64, 0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x12, 0x1a, 0x2e, 0x62, 0x61, 0x64,
+	0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66,
+	0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x28, 0x01, 0x12, 0x4a, 0x0a, 0x0d, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x54,
+	0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x12, 0x1a, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66,
+	0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e,
+	0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28,
+	0x01, 0x12, 0x4b, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x72, 0x61, 0x70, 0x68, 0x73, 0x12,
+	0x1d, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73,
+	0x74, 0x47, 0x72, 0x61, 0x70, 0x68, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e,
+	0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74,
+	0x47, 0x72, 0x61, 0x70, 0x68, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x37,
+	0x0a, 0x05, 0x57, 0x61, 0x74, 0x63, 0x68, 0x12, 0x18, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c,
+	0x66, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x12, 0x2e, 0x62, 0x61, 0x64, 0x77, 0x6f, 0x6c, 0x66, 0x2e, 0x76, 0x31, 0x2e, 0x43,
+	0x68, 0x61, 0x6e, 0x67, 0x65, 0x30, 0x01, 0x42, 0x27, 0x5a, 0x25, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x62, 0x61, 0x64,
+	0x77, 0x6f, 0x6c, 0x66, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3b, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_badwolf_proto_rawDescOnce sync.Once
+	file_badwolf_proto_rawDescData = file_badwolf_proto_rawDesc
+)
+
+func file_badwolf_proto_rawDescGZIP() []byte {
+	file_badwolf_proto_rawDescOnce.Do(func() {
+		file_badwolf_proto_rawDescData = protoimpl.X.CompressGZIP(file_badwolf_proto_rawDescData)
+	})
+	return file_badwolf_proto_rawDescData
+}
+
+var file_badwolf_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_badwolf_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_badwolf_proto_goTypes = []interface{}{
+	(Change_Op)(0),                // 0: badwolf.v1.Change.Op
+	(*Node)(nil),                  // 1: badwolf.v1.Node
+	(*Predicate)(nil),             // 2: badwolf.v1.Predicate
+	(*Literal)(nil),               // 3: badwolf.v1.Literal
+	(*Object)(nil),                // 4: badwolf.v1.Object
+	(*Triple)(nil),                // 5: badwolf.v1.Triple
+	(*Cell)(nil),                  // 6: badwolf.v1.Cell
+	(*Row)(nil),                   // 7: badwolf.v1.Row
+	(*ExecuteQueryRequest)(nil),   // 8: badwolf.v1.ExecuteQueryRequest
+	(*ExecuteQueryResponse)(nil),  // 9: badwolf.v1.ExecuteQueryResponse
+	(*TriplesRequest)(nil),        // 10: badwolf.v1.TriplesRequest
+	(*TriplesResponse)(nil),       // 11: badwolf.v1.TriplesResponse
+	(*ListGraphsRequest)(nil),     // 12: badwolf.v1.ListGraphsRequest
+	(*ListGraphsResponse)(nil),    // 13: badwolf.v1.ListGraphsResponse
+	(*WatchRequest)(nil),          // 14: badwolf.v1.WatchRequest
+	(*Change)(nil),                // 15: badwolf.v1.Change
+	nil,                           // 16: badwolf.v1.Row.CellsEntry
+	(*timestamppb.Timestamp)(nil), // 17: google.protobuf.Timestamp
+}
+var file_badwolf_proto_depIdxs = []int32{
+	17, // 0: badwolf.v1.Predicate.anchor:type_name -> google.protobuf.Timestamp
+	17, // 1: badwolf.v1.Predicate.end:type_name -> google.protobuf.Timestamp
+	1,  // 2: badwolf.v1.Object.node:type_name -> badwolf.v1.Node
+	2,  // 3: badwolf.v1.Object.predicate:type_name -> badwolf.v1.Predicate
+	3,  // 4: badwolf.v1.Object.literal:type_name -> badwolf.v1.Literal
+	1,  // 5: badwolf.v1.Triple.subject:type_name -> badwolf.v1.Node
+	2,  // 6: badwolf.v1.Triple.predicate:type_name -> badwolf.v1.Predicate
+	4,  // 7: badwolf.v1.Triple.object:type_name -> badwolf.v1.Object
+	1,  // 8: badwolf.v1.Cell.node:type_name -> badwolf.v1.Node
+	2,  // 9: badwolf.v1.Cell.predicate:type_name -> badwolf.v1.Predicate
+	3,  // 10: badwolf.v1.Cell.literal:type_name -> badwolf.v1.Literal
+	17, // 11: badwolf.v1.Cell.time:type_name -> google.protobuf.Timestamp
+	16, // 12: badwolf.v1.Row.cells:type_name -> badwolf.v1.Row.CellsEntry
+	7,  // 13: badwolf.v1.ExecuteQueryResponse.rows:type_name -> badwolf.v1.Row
+	5,  // 14: badwolf.v1.TriplesRequest.triples:type_name -> badwolf.v1.Triple
+	0,  // 15: badwolf.v1.Change.op:type_name -> badwolf.v1.Change.Op
+	5,  // 16: badwolf.v1.Change.triples:type_name -> badwolf.v1.Triple
+	6,  // 17: badwolf.v1.Row.CellsEntry.value:type_name -> badwolf.v1.Cell
+	8,  // 18: badwolf.v1.BadWolf.ExecuteQuery:input_type -> badwolf.v1.ExecuteQueryRequest
+	10, // 19: badwolf.v1.BadWolf.AddTriples:input_type -> badwolf.v1.TriplesRequest
+	10, // 20: badwolf.v1.BadWolf.RemoveTriples:input_type -> badwolf.v1.TriplesRequest
+	12, // 21: badwolf.v1.BadWolf.ListGraphs:input_type -> badwolf.v1.ListGraphsRequest
+	14, // 22: badwolf.v1.BadWolf.Watch:input_type -> badwolf.v1.WatchRequest
+	9,  // 23: badwolf.v1.BadWolf.ExecuteQuery:output_type -> badwolf.v1.ExecuteQueryResponse
+	11, // 24: badwolf.v1.BadWolf.AddTriples:output_type -> badwolf.v1.TriplesResponse
+	11, // 25: badwolf.v1.BadWolf.RemoveTriples:output_type -> badwolf.v1.TriplesResponse
+	13, // 26: badwolf.v1.BadWolf.ListGraphs:output_type -> badwolf.v1.ListGraphsResponse
+	15, // 27: badwolf.v1.BadWolf.Watch:output_type -> badwolf.v1.Change
+	23, // [23:28] is the sub-list for method output_type
+	18, // [18:23] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
+}
+
+func init() { file_badwolf_proto_init() }
+func file_badwolf_proto_init() {
+	if File_badwolf_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_badwolf_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Node); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Predicate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Literal); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Object); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Triple); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Cell); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Row); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExecuteQueryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExecuteQueryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TriplesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TriplesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListGraphsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListGraphsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_badwolf_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Change); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_badwolf_proto_msgTypes[2].OneofWrappers = []interface{}{
+		(*Literal_Bool)(nil),
+		(*Literal_Int64)(nil),
+		(*Literal_Float64)(nil),
+		(*Literal_Text)(nil),
+		(*Literal_Blob)(nil),
+	}
+	file_badwolf_proto_msgTypes[3].OneofWrappers = []interface{}{
+		(*Object_Node)(nil),
+		(*Object_Predicate)(nil),
+		(*Object_Literal)(nil),
+	}
+	file_badwolf_proto_msgTypes[5].OneofWrappers = []interface{}{
+		(*Cell_S)(nil),
+		(*Cell_Node)(nil),
+		(*Cell_Predicate)(nil),
+		(*Cell_Literal)(nil),
+		(*Cell_Time)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_badwolf_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_badwolf_proto_goTypes,
+		DependencyIndexes: file_badwolf_proto_depIdxs,
+		EnumInfos:         file_badwolf_proto_enumTypes,
+		MessageInfos:      file_badwolf_proto_msgTypes,
+	}.Build()
+	File_badwolf_proto = out.File
+	file_badwolf_proto_rawDesc = nil
+	file_badwolf_proto_goTypes = nil
+	file_badwolf_proto_depIdxs = nil
+}
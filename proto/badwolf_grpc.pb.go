@@ -0,0 +1,421 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Definition of the BadWolf gRPC service, which provides non Go clients with a
+// typed interface to run BQL statements, update graphs and follow their
+// changes. The messages mirror the triple, table and storage packages.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: badwolf.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	BadWolf_ExecuteQuery_FullMethodName  = "/badwolf.v1.BadWolf/ExecuteQuery"
+	BadWolf_AddTriples_FullMethodName    = "/badwolf.v1.BadWolf/AddTriples"
+	BadWolf_RemoveTriples_FullMethodName = "/badwolf.v1.BadWolf/RemoveTriples"
+	BadWolf_ListGraphs_FullMethodName    = "/badwolf.v1.BadWolf/ListGraphs"
+	BadWolf_Watch_FullMethodName         = "/badwolf.v1.BadWolf/Watch"
+)
+
+// BadWolfClient is the client API for BadWolf service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// BadWolf runs BQL statements and updates the graphs of a storage.Store.
+type BadWolfClient interface {
+	// ExecuteQuery runs a BQL statement, streaming its rows as they are
+	// computed.
+	ExecuteQuery(ctx context.Context, in *ExecuteQueryRequest, opts ...grpc.CallOption) (BadWolf_ExecuteQueryClient, error)
+	// AddTriples adds the triples to the graph in bulk.
+	AddTriples(ctx context.Context, opts ...grpc.CallOption) (BadWolf_AddTriplesClient, error)
+	// RemoveTriples removes the triples from the graph in bulk.
+	RemoveTriples(ctx context.Context, opts ...grpc.CallOption) (BadWolf_RemoveTriplesClient, error)
+	// ListGraphs lists the graphs in the store.
+	ListGraphs(ctx context.Context, in *ListGraphsRequest, opts ...grpc.CallOption) (*ListGraphsResponse, error)
+	// Watch streams the changes done to the graph until the call is
+	// cancelled. It fails if the graph does not implement storage.ChangeFeed.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (BadWolf_WatchClient, error)
+}
+
+type badWolfClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBadWolfClient(cc grpc.ClientConnInterface) BadWolfClient {
+	return &badWolfClient{cc}
+}
+
+func (c *badWolfClient) ExecuteQuery(ctx context.Context, in *ExecuteQueryRequest, opts ...grpc.CallOption) (BadWolf_ExecuteQueryClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BadWolf_ServiceDesc.Streams[0], BadWolf_ExecuteQuery_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &badWolfExecuteQueryClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BadWolf_ExecuteQueryClient interface {
+	Recv() (*ExecuteQueryResponse, error)
+	grpc.ClientStream
+}
+
+type badWolfExecuteQueryClient struct {
+	grpc.ClientStream
+}
+
+func (x *badWolfExecuteQueryClient) Recv() (*ExecuteQueryResponse, error) {
+	m := new(ExecuteQueryResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *badWolfClient) AddTriples(ctx context.Context, opts ...grpc.CallOption) (BadWolf_AddTriplesClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BadWolf_ServiceDesc.Streams[1], BadWolf_AddTriples_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &badWolfAddTriplesClient{ClientStream: stream}
+	return x, nil
+}
+
+type BadWolf_AddTriplesClient interface {
+	Send(*TriplesRequest) error
+	CloseAndRecv() (*TriplesResponse, error)
+	grpc.ClientStream
+}
+
+type badWolfAddTriplesClient struct {
+	grpc.ClientStream
+}
+
+func (x *badWolfAddTriplesClient) Send(m *TriplesRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *badWolfAddTriplesClient) CloseAndRecv() (*TriplesResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(TriplesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *badWolfClient) RemoveTriples(ctx context.Context, opts ...grpc.CallOption) (BadWolf_RemoveTriplesClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BadWolf_ServiceDesc.Streams[2], BadWolf_RemoveTriples_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &badWolfRemoveTriplesClient{ClientStream: stream}
+	return x, nil
+}
+
+type BadWolf_RemoveTriplesClient interface {
+	Send(*TriplesRequest) error
+	CloseAndRecv() (*TriplesResponse, error)
+	grpc.ClientStream
+}
+
+type badWolfRemoveTriplesClient struct {
+	grpc.ClientStream
+}
+
+func (x *badWolfRemoveTriplesClient) Send(m *TriplesRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *badWolfRemoveTriplesClient) CloseAndRecv() (*TriplesResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(TriplesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *badWolfClient) ListGraphs(ctx context.Context, in *ListGraphsRequest, opts ...grpc.CallOption) (*ListGraphsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGraphsResponse)
+	err := c.cc.Invoke(ctx, BadWolf_ListGraphs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *badWolfClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (BadWolf_WatchClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BadWolf_ServiceDesc.Streams[3], BadWolf_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &badWolfWatchClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BadWolf_WatchClient interface {
+	Recv() (*Change, error)
+	grpc.ClientStream
+}
+
+type badWolfWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *badWolfWatchClient) Recv() (*Change, error) {
+	m := new(Change)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BadWolfServer is the server API for BadWolf service.
+// All implementations must embed UnimplementedBadWolfServer
+// for forward compatibility
+//
+// BadWolf runs BQL statements and updates the graphs of a storage.Store.
+type BadWolfServer interface {
+	// ExecuteQuery runs a BQL statement, streaming its rows as they are
+	// computed.
+	ExecuteQuery(*ExecuteQueryRequest, BadWolf_ExecuteQueryServer) error
+	// AddTriples adds the triples to the graph in bulk.
+	AddTriples(BadWolf_AddTriplesServer) error
+	// RemoveTriples removes the triples from the graph in bulk.
+	RemoveTriples(BadWolf_RemoveTriplesServer) error
+	// ListGraphs lists the graphs in the store.
+	ListGraphs(context.Context, *ListGraphsRequest) (*ListGraphsResponse, error)
+	// Watch streams the changes done to the graph until the call is
+	// cancelled. It fails if the graph does not implement storage.ChangeFeed.
+	Watch(*WatchRequest, BadWolf_WatchServer) error
+	mustEmbedUnimplementedBadWolfServer()
+}
+
+// UnimplementedBadWolfServer must be embedded to have forward compatible implementations.
+type UnimplementedBadWolfServer struct {
+}
+
+func (UnimplementedBadWolfServer) ExecuteQuery(*ExecuteQueryRequest, BadWolf_ExecuteQueryServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExecuteQuery not implemented")
+}
+func (UnimplementedBadWolfServer) AddTriples(BadWolf_AddTriplesServer) error {
+	return status.Errorf(codes.Unimplemented, "method AddTriples not implemented")
+}
+func (UnimplementedBadWolfServer) RemoveTriples(BadWolf_RemoveTriplesServer) error {
+	return status.Errorf(codes.Unimplemented, "method RemoveTriples not implemented")
+}
+func (UnimplementedBadWolfServer) ListGraphs(context.Context, *ListGraphsRequest) (*ListGraphsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGraphs not implemented")
+}
+func (UnimplementedBadWolfServer) Watch(*WatchRequest, BadWolf_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedBadWolfServer) mustEmbedUnimplementedBadWolfServer() {}
+
+// UnsafeBadWolfServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BadWolfServer will
+// result in compilation errors.
+type UnsafeBadWolfServer interface {
+	mustEmbedUnimplementedBadWolfServer()
+}
+
+func RegisterBadWolfServer(s grpc.ServiceRegistrar, srv BadWolfServer) {
+	s.RegisterService(&BadWolf_ServiceDesc, srv)
+}
+
+func _BadWolf_ExecuteQuery_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExecuteQueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BadWolfServer).ExecuteQuery(m, &badWolfExecuteQueryServer{ServerStream: stream})
+}
+
+type BadWolf_ExecuteQueryServer interface {
+	Send(*ExecuteQueryResponse) error
+	grpc.ServerStream
+}
+
+type badWolfExecuteQueryServer struct {
+	grpc.ServerStream
+}
+
+func (x *badWolfExecuteQueryServer) Send(m *ExecuteQueryResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _BadWolf_AddTriples_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BadWolfServer).AddTriples(&badWolfAddTriplesServer{ServerStream: stream})
+}
+
+type BadWolf_AddTriplesServer interface {
+	SendAndClose(*TriplesResponse) error
+	Recv() (*TriplesRequest, error)
+	grpc.ServerStream
+}
+
+type badWolfAddTriplesServer struct {
+	grpc.ServerStream
+}
+
+func (x *badWolfAddTriplesServer) SendAndClose(m *TriplesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *badWolfAddTriplesServer) Recv() (*TriplesRequest, error) {
+	m := new(TriplesRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _BadWolf_RemoveTriples_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BadWolfServer).RemoveTriples(&badWolfRemoveTriplesServer{ServerStream: stream})
+}
+
+type BadWolf_RemoveTriplesServer interface {
+	SendAndClose(*TriplesResponse) error
+	Recv() (*TriplesRequest, error)
+	grpc.ServerStream
+}
+
+type badWolfRemoveTriplesServer struct {
+	grpc.ServerStream
+}
+
+func (x *badWolfRemoveTriplesServer) SendAndClose(m *TriplesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *badWolfRemoveTriplesServer) Recv() (*TriplesRequest, error) {
+	m := new(TriplesRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _BadWolf_ListGraphs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGraphsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BadWolfServer).ListGraphs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BadWolf_ListGraphs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BadWolfServer).ListGraphs(ctx, req.(*ListGraphsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BadWolf_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BadWolfServer).Watch(m, &badWolfWatchServer{ServerStream: stream})
+}
+
+type BadWolf_WatchServer interface {
+	Send(*Change) error
+	grpc.ServerStream
+}
+
+type badWolfWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *badWolfWatchServer) Send(m *Change) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// BadWolf_ServiceDesc is the grpc.ServiceDesc for BadWolf service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BadWolf_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "badwolf.v1.BadWolf",
+	HandlerType: (*BadWolfServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListGraphs",
+			Handler:    _BadWolf_ListGraphs_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExecuteQuery",
+			Handler:       _BadWolf_ExecuteQuery_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "AddTriples",
+			Handler:       _BadWolf_AddTriples_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "RemoveTriples",
+			Handler:       _BadWolf_RemoveTriples_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _BadWolf_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "badwolf.proto",
+}
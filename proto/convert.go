@@ -0,0 +1,242 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package proto contains the messages and the gRPC stubs of the BadWolf
+// service, generated from badwolf.proto, and the conversions between the
+// messages and the BadWolf types.
+//
+// The generated files are rebuilt after changing badwolf.proto running
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	    --go-grpc_out=. --go-grpc_opt=paths=source_relative badwolf.proto
+//
+// in this directory.
+package proto
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FromNode returns the message of the provided node.
+func FromNode(n *node.Node) *Node {
+	return &Node{Type: n.Type().String(), Id: n.ID().String()}
+}
+
+// ToNode returns the node of the provided message.
+func ToNode(n *Node) (*node.Node, error) {
+	if n == nil {
+		return nil, fmt.Errorf("proto.ToNode: missing node")
+	}
+	return node.NewNodeFromStrings(n.GetType(), n.GetId())
+}
+
+// FromPredicate returns the message of the provided predicate.
+func FromPredicate(p *predicate.Predicate) *Predicate {
+	pp := &Predicate{Id: string(p.ID())}
+	if ta, err := p.TimeAnchor(); err == nil {
+		pp.Anchor = timestamppb.New(*ta)
+	}
+	if _, end, err := p.Interval(); err == nil {
+		pp.End = timestamppb.New(*end)
+	}
+	return pp
+}
+
+// ToPredicate returns the predicate of the provided message.
+func ToPredicate(p *Predicate) (*predicate.Predicate, error) {
+	switch {
+	case p == nil:
+		return nil, fmt.Errorf("proto.ToPredicate: missing predicate")
+	case p.GetAnchor() == nil:
+		return predicate.NewImmutable(p.GetId())
+	case p.GetEnd() != nil:
+		return predicate.NewInterval(p.GetId(), p.GetAnchor().AsTime(), p.GetEnd().AsTime())
+	default:
+		return predicate.NewTemporal(p.GetId(), p.GetAnchor().AsTime())
+	}
+}
+
+// FromLiteral returns the message of the provided literal. The values of
+// literals without a field of their own are provided as their text.
+func FromLiteral(l *literal.Literal) *Literal {
+	pl := &Literal{Type: l.Type().String()}
+	switch v := l.Interface().(type) {
+	case bool:
+		pl.Value = &Literal_Bool{Bool: v}
+	case int64:
+		pl.Value = &Literal_Int64{Int64: v}
+	case float64:
+		pl.Value = &Literal_Float64{Float64: v}
+	case []byte:
+		pl.Value = &Literal_Blob{Blob: v}
+	default:
+		if t, err := l.Text(); err == nil {
+			pl.Value = &Literal_Text{Text: t}
+			break
+		}
+		// The text of the remaining types never contains quotes.
+		s := l.String()
+		pl.Value = &Literal_Text{Text: s[1:strings.LastIndex(s, `"^^type:`)]}
+	}
+	return pl
+}
+
+// ToLiteral returns the literal of the provided message built using the
+// provided builder.
+func ToLiteral(l *Literal, b literal.Builder) (*literal.Literal, error) {
+	if l == nil {
+		return nil, fmt.Errorf("proto.ToLiteral: missing literal")
+	}
+	switch v := l.GetValue().(type) {
+	case *Literal_Bool:
+		return b.Build(literal.Bool, v.Bool)
+	case *Literal_Int64:
+		return b.Build(literal.Int64, v.Int64)
+	case *Literal_Float64:
+		return b.Build(literal.Float64, v.Float64)
+	case *Literal_Blob:
+		return b.Build(literal.Blob, v.Blob)
+	case *Literal_Text:
+		if l.GetType() == literal.Text.String() {
+			return b.Build(literal.Text, v.Text)
+		}
+		return b.Parse(`"` + v.Text + `"^^type:` + l.GetType())
+	default:
+		return nil, fmt.Errorf("proto.ToLiteral: missing value of %s literal", l.GetType())
+	}
+}
+
+// FromObject returns the message of the provided object.
+func FromObject(o *triple.Object) (*Object, error) {
+	if n, err := o.Node(); err == nil {
+		return &Object{Value: &Object_Node{Node: FromNode(n)}}, nil
+	}
+	if p, err := o.Predicate(); err == nil {
+		return &Object{Value: &Object_Predicate{Predicate: FromPredicate(p)}}, nil
+	}
+	if l, err := o.Literal(); err == nil {
+		return &Object{Value: &Object_Literal{Literal: FromLiteral(l)}}, nil
+	}
+	return nil, fmt.Errorf("proto.FromObject: unknown object type in %s", o)
+}
+
+// ToObject returns the object of the provided message, building literals
+// using the provided builder.
+func ToObject(o *Object, b literal.Builder) (*triple.Object, error) {
+	switch v := o.GetValue().(type) {
+	case *Object_Node:
+		n, err := ToNode(v.Node)
+		if err != nil {
+			return nil, err
+		}
+		return triple.NewNodeObject(n), nil
+	case *Object_Predicate:
+		p, err := ToPredicate(v.Predicate)
+		if err != nil {
+			return nil, err
+		}
+		return triple.NewPredicateObject(p), nil
+	case *Object_Literal:
+		l, err := ToLiteral(v.Literal, b)
+		if err != nil {
+			return nil, err
+		}
+		return triple.NewLiteralObject(l), nil
+	default:
+		return nil, fmt.Errorf("proto.ToObject: missing object")
+	}
+}
+
+// FromTriple returns the message of the provided triple.
+func FromTriple(t *triple.Triple) (*Triple, error) {
+	o, err := FromObject(t.Object())
+	if err != nil {
+		return nil, err
+	}
+	return &Triple{
+		Subject:   FromNode(t.Subject()),
+		Predicate: FromPredicate(t.Predicate()),
+		Object:    o,
+	}, nil
+}
+
+// ToTriple returns the triple of the provided message, building literals
+// using the provided builder.
+func ToTriple(t *Triple, b literal.Builder) (*triple.Triple, error) {
+	s, err := ToNode(t.GetSubject())
+	if err != nil {
+		return nil, err
+	}
+	p, err := ToPredicate(t.GetPredicate())
+	if err != nil {
+		return nil, err
+	}
+	o, err := ToObject(t.GetObject(), b)
+	if err != nil {
+		return nil, err
+	}
+	return triple.New(s, p, o)
+}
+
+// FromCell returns the message of the provided cell.
+func FromCell(c *table.Cell) *Cell {
+	switch {
+	case c.S != nil:
+		return &Cell{Value: &Cell_S{S: *c.S}}
+	case c.N != nil:
+		return &Cell{Value: &Cell_Node{Node: FromNode(c.N)}}
+	case c.P != nil:
+		return &Cell{Value: &Cell_Predicate{Predicate: FromPredicate(c.P)}}
+	case c.L != nil:
+		return &Cell{Value: &Cell_Literal{Literal: FromLiteral(c.L)}}
+	case c.T != nil:
+		return &Cell{Value: &Cell_Time{Time: timestamppb.New(*c.T)}}
+	default:
+		return &Cell{}
+	}
+}
+
+// FromRow returns the message of the provided row.
+func FromRow(r table.Row) *Row {
+	pr := &Row{Cells: make(map[string]*Cell, len(r))}
+	for b, c := range r {
+		pr.Cells[b] = FromCell(c)
+	}
+	return pr
+}
+
+// FromChange returns the message of the provided change.
+func FromChange(c *storage.Change) (*Change, error) {
+	pc := &Change{Op: Change_ADDED}
+	if c.Op == storage.Removed {
+		pc.Op = Change_REMOVED
+	}
+	for _, t := range c.Triples {
+		pt, err := FromTriple(t)
+		if err != nil {
+			return nil, err
+		}
+		pc.Triples = append(pc.Triples, pt)
+	}
+	return pc, nil
+}
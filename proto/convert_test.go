@@ -0,0 +1,55 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proto
+
+import (
+	"testing"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+func TestTripleRoundTrip(t *testing.T) {
+	table := []string{
+		`/u<joe> "parent_of"@[] /u<mary>`,
+		`/u<joe> "met"@[2016-04-10T04:21:00.000000000Z] /u<mary>`,
+		`/u<joe> "knows"@[] "met"@[2016-04-10T04:21:00.000000000Z]`,
+		`/u<joe> "flag"@[] "true"^^type:bool`,
+		`/u<joe> "age"@[] "42"^^type:int64`,
+		`/u<joe> "height"@[] "1.85"^^type:float64`,
+		`/u<joe> "name"@[] "Joe \"the\" Smith"^^type:text`,
+		`/u<joe> "data"@[] "[0 1 255]"^^type:blob`,
+		`/u<joe> "balance"@[] "10.25"^^type:decimal`,
+		`/u<joe> "born"@[] "1980-02-03"^^type:date`,
+		`/u<joe> "embedding"@[] "[0.5,-1,2]"^^type:vector`,
+	}
+	for _, s := range table {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse(%q) failed; %v", s, err)
+		}
+		pt, err := FromTriple(trpl)
+		if err != nil {
+			t.Fatalf("FromTriple(%s) failed; %v", trpl, err)
+		}
+		got, err := ToTriple(pt, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("ToTriple(%v) failed; %v", pt, err)
+		}
+		if !got.Equal(trpl) {
+			t.Errorf("ToTriple(FromTriple(%s)) returned %s", trpl, got)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/storage/auth"
+)
+
+// Authenticator identifies the principal making a request.
+type Authenticator interface {
+	// Authenticate returns the principal making the request, or nil for
+	// anonymous requests. It fails if the request provides credentials that
+	// are not valid.
+	Authenticate(r *http.Request) (*auth.Principal, error)
+}
+
+// Tokens is an Authenticator identifying principals by the bearer token in
+// the Authorization header of the requests. It maps tokens to the names of
+// the principals. Requests without an Authorization header are anonymous.
+type Tokens map[string]string
+
+// Authenticate returns the principal of the bearer token of the request.
+func (ts Tokens) Authenticate(r *http.Request) (*auth.Principal, error) {
+	h := strings.TrimSpace(r.Header.Get("Authorization"))
+	if h == "" {
+		return nil, nil
+	}
+	const scheme = "bearer "
+	if len(h) <= len(scheme) || strings.ToLower(h[:len(scheme)]) != scheme {
+		return nil, fmt.Errorf("unsupported authorization scheme; only bearer tokens are accepted")
+	}
+	tok := []byte(strings.TrimSpace(h[len(scheme):]))
+	// All the tokens are compared to avoid leaking them through timing.
+	name, found := "", false
+	for t, n := range ts {
+		if subtle.ConstantTimeCompare([]byte(t), tok) == 1 {
+			name, found = n, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("invalid bearer token")
+	}
+	return &auth.Principal{Name: name}, nil
+}
+
+// ParseTokens reads the bearer tokens of principals, one per line. Each line
+// contains the token and the name of the principal, separated by spaces. Empty
+// lines and lines starting with # are ignored.
+func ParseTokens(r io.Reader) (Tokens, error) {
+	ts := make(Tokens)
+	s := bufio.NewScanner(r)
+	for ln := 1; s.Scan(); ln++ {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		fs := strings.Fields(l)
+		if len(fs) != 2 {
+			return nil, fmt.Errorf("server.ParseTokens: line %d: expected a token and a principal", ln)
+		}
+		if _, ok := ts[fs[0]]; ok {
+			return nil, fmt.Errorf("server.ParseTokens: line %d: duplicated token", ln)
+		}
+		ts[fs[0]] = fs[1]
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Authenticate returns a handler that identifies the principal making each
+// request using the provided authenticator, and serves the request using next
+// with the principal in its context, as returned by auth.FromContext. Requests
+// failing authentication are rejected with status 401.
+func Authenticate(a Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="badwolf"`)
+			reportError(w, http.StatusUnauthorized, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+	})
+}
+
+// Authorize checks that the principal of the context has the permissions
+// required to run the statement: auth.Read on the graphs it queries, and
+// auth.Write on the graphs it creates, drops, or changes. It returns
+// *auth.ErrPermissionDenied for the first graph missing a permission.
+func Authorize(ctx context.Context, a auth.Authorizer, stm *semantic.Statement) error {
+	perms := make(map[string]auth.Permission)
+	statementPermissions(stm, perms)
+	var names []string
+	for n := range perms {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if err := auth.Check(ctx, a, n, perms[n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statementPermissions adds the permissions required by the statement and its
+// subqueries on each graph to the provided map.
+func statementPermissions(stm *semantic.Statement, perms map[string]auth.Permission) {
+	switch stm.Type() {
+	case semantic.Create, semantic.Drop:
+		for _, g := range stm.GraphNames() {
+			perms[g] |= auth.Write
+		}
+	case semantic.Delete:
+		// Deleting data removes triples from the graphs it reads.
+		for _, g := range stm.InputGraphNames() {
+			perms[g] |= auth.Write
+		}
+	default:
+		for _, g := range stm.InputGraphNames() {
+			perms[g] |= auth.Read
+		}
+		for _, g := range stm.OutputGraphNames() {
+			perms[g] |= auth.Write
+		}
+	}
+	for _, sq := range stm.Subqueries() {
+		statementPermissions(sq, perms)
+	}
+}
+
+// authorize checks that the principal of the context can run the statement if
+// the handler enforces permissions.
+func (h *Handler) authorize(ctx context.Context, stm *semantic.Statement) error {
+	if h.opts.Authorizer == nil {
+		return nil
+	}
+	return Authorize(ctx, h.opts.Authorizer, stm)
+}
+
+// check checks that the principal of the context has the permission on the
+// graph if the handler enforces permissions.
+func (h *Handler) check(ctx context.Context, graph string, perm auth.Permission) error {
+	if h.opts.Authorizer == nil {
+		return nil
+	}
+	return auth.Check(ctx, h.opts.Authorizer, graph, perm)
+}
+
+// reportAuthError reports a failed permission check, with status 403 if the
+// permission was denied.
+func reportAuthError(w http.ResponseWriter, err error) {
+	if _, ok := err.(*auth.ErrPermissionDenied); ok {
+		reportError(w, http.StatusForbidden, err)
+		return
+	}
+	reportError(w, http.StatusInternalServerError, err)
+}
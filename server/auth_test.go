@@ -0,0 +1,129 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage/auth"
+	"github.com/google/badwolf/storage/memory"
+)
+
+const (
+	testTokens = `
+# Tokens of the test principals.
+alice-token alice
+bob-token   bob
+`
+	testACL = `
+alice ?family rw
+alice ?public rw
+*     ?public r
+`
+)
+
+func newAuthTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	s := memory.NewStore()
+	h := New(s, nil)
+	for _, g := range []string{"%3Ffamily", "%3Fpublic"} {
+		if w := do(t, h, http.MethodPut, "/graphs/"+g, "", ""); w.Code != http.StatusCreated {
+			t.Fatalf("PUT /graphs/%s returned %d; %s", g, w.Code, w.Body)
+		}
+		if w := do(t, h, http.MethodPost, "/graphs/"+g+"/triples", "text/plain", testTriples); w.Code != http.StatusOK {
+			t.Fatalf("POST /graphs/%s/triples returned %d; %s", g, w.Code, w.Body)
+		}
+	}
+	ts, err := ParseTokens(strings.NewReader(testTokens))
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl, err := auth.ParseACL(strings.NewReader(testACL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(s, &Options{Authenticator: ts, Authorizer: acl})
+}
+
+func TestParseTokens(t *testing.T) {
+	ts, err := ParseTokens(strings.NewReader(testTokens))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 2 || ts["alice-token"] != "alice" || ts["bob-token"] != "bob" {
+		t.Errorf("ParseTokens returned %v; want the tokens of alice and bob", ts)
+	}
+	for _, s := range []string{"alice-token", "alice-token alice extra", "t alice\nt bob"} {
+		if _, err := ParseTokens(strings.NewReader(s)); err == nil {
+			t.Errorf("ParseTokens(%q) should have failed", s)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	h := newAuthTestHandler(t)
+	query := func(q string) string {
+		return "/query?query=" + url.QueryEscape(q)
+	}
+	table := []struct {
+		method, target, authorization string
+		want                          int
+		body                          string
+	}{
+		{http.MethodGet, "/graphs", "", http.StatusOK, `{"graphs":["?public"]}`},
+		{http.MethodGet, "/graphs", "Bearer bob-token", http.StatusOK, `{"graphs":["?public"]}`},
+		{http.MethodGet, "/graphs", "Bearer alice-token", http.StatusOK, `{"graphs":["?family","?public"]}`},
+		{http.MethodGet, "/graphs", "Bearer eve-token", http.StatusUnauthorized, ""},
+		{http.MethodGet, "/graphs", "Basic YWxpY2U6c2VjcmV0", http.StatusUnauthorized, ""},
+		{http.MethodGet, "/graphs/%3Ffamily", "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodGet, "/graphs/%3Ffamily", "Bearer alice-token", http.StatusOK, `{"graph":"?family","triples":3}`},
+		{http.MethodGet, "/graphs/%3Fpublic", "", http.StatusOK, `{"graph":"?public","triples":3}`},
+		{http.MethodPut, "/graphs/%3Fnew", "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodPost, "/graphs/%3Fpublic/triples", "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodDelete, "/graphs/%3Fpublic", "", http.StatusForbidden, ""},
+		{http.MethodGet, query("select ?s from ?family where {?s ?p ?o}"), "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodGet, query("select ?s from ?public where {?s ?p ?o}"), "Bearer bob-token", http.StatusOK, ""},
+		{http.MethodGet, query("select ?s from ?public where {{?s ?p ?o} union {?s ?p ?o}}"), "", http.StatusOK, ""},
+		{http.MethodGet, query("select ?s from ?public where {{?s ?p ?o} union {?s ?p ?o . {select ?s from ?family where {?s ?p ?o}}}}"), "", http.StatusForbidden, ""},
+		{http.MethodGet, query("select ?s from ?public where {?s ?p ?o . {select ?s from ?family where {?s ?p ?o}}}"), "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodPost, query("insert data into ?public {/u<bob> \"likes\"@[] /u<mary>}"), "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodPost, query("delete data from ?public {/u<joe> \"parent_of\"@[] /u<mary>}"), "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodPost, query("construct {?s \"knows\"@[] ?o} into ?family from ?public where {?s ?p ?o}"), "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodPost, query("create graph ?new"), "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodPost, query("drop graph ?family"), "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodGet, "/sparql?query=" + url.QueryEscape("SELECT ?s WHERE { ?s ?p ?o }"), "Bearer bob-token", http.StatusOK, ""},
+		{http.MethodGet, "/sparql?default-graph-uri=%3Ffamily&query=" + url.QueryEscape("SELECT ?s WHERE { ?s ?p ?o }"), "Bearer bob-token", http.StatusForbidden, ""},
+		{http.MethodPost, query("insert data into ?public {/u<alice> \"likes\"@[] /u<mary>}"), "Bearer alice-token", http.StatusOK, ""},
+		{http.MethodDelete, "/graphs/%3Ffamily", "Bearer alice-token", http.StatusNoContent, ""},
+	}
+	for _, entry := range table {
+		r := httptest.NewRequest(entry.method, entry.target, nil)
+		if entry.authorization != "" {
+			r.Header.Set("Authorization", entry.authorization)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != entry.want {
+			t.Errorf("%s %s with %q returned %d; want %d; %s", entry.method, entry.target, entry.authorization, w.Code, entry.want, w.Body)
+		}
+		if got := strings.TrimSpace(w.Body.String()); entry.body != "" && got != entry.body {
+			t.Errorf("%s %s with %q returned %s; want %s", entry.method, entry.target, entry.authorization, got, entry.body)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/badwolf/bql/planner"
+	"github.com/google/badwolf/bql/table"
+	pb "github.com/google/badwolf/proto"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/auth"
+	"github.com/google/badwolf/triple"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Service implements the BadWolf gRPC service defined in proto/badwolf.proto
+// for a store. It is registered in a gRPC server using
+// pb.RegisterBadWolfServer.
+//
+// It honors the same options as the REST API. If Options.Authenticator is
+// provided, it receives the metadata of the calls as the headers of the
+// request, so clients present bearer tokens in their authorization metadata.
+type Service struct {
+	pb.UnimplementedBadWolfServer
+	store storage.Store
+	opts  Options
+}
+
+// NewService returns the gRPC service for the provided store. The options may
+// be nil.
+func NewService(store storage.Store, opts *Options) *Service {
+	h := New(store, opts)
+	return &Service{store: h.store, opts: h.opts}
+}
+
+// authenticate returns the context of the call carrying its principal.
+func (s *Service) authenticate(ctx context.Context) (context.Context, error) {
+	if s.opts.Authenticator == nil {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Header: make(http.Header)}
+	for k, vs := range md {
+		for _, v := range vs {
+			r.Header.Add(k, v)
+		}
+	}
+	p, err := s.opts.Authenticator.Authenticate(r.WithContext(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return auth.NewContext(ctx, p), nil
+}
+
+// statusError returns the status of the provided error, based on the context
+// of the call when it is done.
+func statusError(ctx context.Context, code codes.Code, err error) error {
+	if _, ok := err.(*auth.ErrPermissionDenied); ok {
+		code = codes.PermissionDenied
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		code = codes.DeadlineExceeded
+	case context.Canceled:
+		code = codes.Canceled
+	}
+	return status.Error(code, err.Error())
+}
+
+// ExecuteQuery runs a BQL statement, streaming its rows as they are computed.
+func (s *Service) ExecuteQuery(req *pb.ExecuteQueryRequest, stream pb.BadWolf_ExecuteQueryServer) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	q := strings.TrimSpace(req.GetQuery())
+	if !strings.HasSuffix(q, ";") {
+		q += ";"
+	}
+	stm, err := parse(q)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if s.opts.Authorizer != nil {
+		if err := Authorize(ctx, s.opts.Authorizer, stm); err != nil {
+			return statusError(ctx, codes.Internal, err)
+		}
+	}
+	pln, err := planner.New(ctx, s.store, stm, s.opts.ChanSize, s.opts.BulkSize, nil)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	var it planner.RowIterator
+	if st, ok := pln.(planner.Streamer); ok {
+		if it, err = st.Stream(ctx); err != nil {
+			return statusError(ctx, codes.Internal, err)
+		}
+	} else {
+		tbl, err := pln.Execute(ctx)
+		if err != nil {
+			return statusError(ctx, codes.Internal, err)
+		}
+		it = &tableIterator{tbl: tbl}
+	}
+	defer it.Close()
+	if err := stream.Send(&pb.ExecuteQueryResponse{Bindings: it.Bindings()}); err != nil {
+		return err
+	}
+	var rows []*pb.Row
+	for {
+		row, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return statusError(ctx, codes.Internal, err)
+		}
+		if rows = append(rows, pb.FromRow(row)); len(rows) == flushRows {
+			if err := stream.Send(&pb.ExecuteQueryResponse{Rows: rows}); err != nil {
+				return err
+			}
+			rows = nil
+		}
+	}
+	if len(rows) > 0 {
+		return stream.Send(&pb.ExecuteQueryResponse{Rows: rows})
+	}
+	return nil
+}
+
+// tableIterator iterates over the rows of an already computed table.
+type tableIterator struct {
+	tbl *table.Table
+	i   int
+}
+
+// Bindings returns the bindings of the table.
+func (it *tableIterator) Bindings() []string {
+	return it.tbl.Bindings()
+}
+
+// Next returns the next row of the table.
+func (it *tableIterator) Next(ctx context.Context) (table.Row, error) {
+	r, ok := it.tbl.Row(it.i)
+	if !ok {
+		return nil, io.EOF
+	}
+	it.i++
+	return r, nil
+}
+
+// Close does nothing.
+func (it *tableIterator) Close() {}
+
+// AddTriples adds the triples to the graph in bulk.
+func (s *Service) AddTriples(stream pb.BadWolf_AddTriplesServer) error {
+	return s.update(stream, func(ctx context.Context, g storage.Graph, ts []*triple.Triple) error {
+		return g.AddTriples(ctx, ts)
+	})
+}
+
+// RemoveTriples removes the triples from the graph in bulk.
+func (s *Service) RemoveTriples(stream pb.BadWolf_RemoveTriplesServer) error {
+	return s.update(stream, func(ctx context.Context, g storage.Graph, ts []*triple.Triple) error {
+		return g.RemoveTriples(ctx, ts)
+	})
+}
+
+// triplesStream is the stream of the calls updating graphs.
+type triplesStream interface {
+	Context() context.Context
+	Recv() (*pb.TriplesRequest, error)
+	SendAndClose(*pb.TriplesResponse) error
+}
+
+// update applies the provided function to the triples of each request of the
+// stream, and replies with the number of triples processed.
+func (s *Service) update(stream triplesStream, f func(ctx context.Context, g storage.Graph, ts []*triple.Triple) error) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	var (
+		n  int64
+		gs = make(map[string]storage.Graph)
+		b  = storage.LiteralBuilder(ctx, s.store)
+	)
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&pb.TriplesResponse{Triples: n})
+		}
+		if err != nil {
+			return err
+		}
+		g, ok := gs[req.GetGraph()]
+		if !ok {
+			if s.opts.Authorizer != nil {
+				if err := auth.Check(ctx, s.opts.Authorizer, req.GetGraph(), auth.Write); err != nil {
+					return statusError(ctx, codes.Internal, err)
+				}
+			}
+			if g, err = s.store.Graph(ctx, req.GetGraph()); err != nil {
+				return statusError(ctx, codes.NotFound, err)
+			}
+			gs[req.GetGraph()] = g
+		}
+		var ts []*triple.Triple
+		for _, pt := range req.GetTriples() {
+			t, err := pb.ToTriple(pt, b)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid triple after processing %d triples; %v", n, err)
+			}
+			ts = append(ts, t)
+		}
+		if err := f(ctx, g, ts); err != nil {
+			return statusError(ctx, codes.Internal, err)
+		}
+		n += int64(len(ts))
+	}
+}
+
+// ListGraphs lists the graphs in the store.
+func (s *Service) ListGraphs(ctx context.Context, req *pb.ListGraphsRequest) (*pb.ListGraphsResponse, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.ListGraphsResponse{}
+	c, errc := make(chan string), make(chan error, 1)
+	go func() {
+		errc <- s.store.GraphNames(ctx, c)
+	}()
+	for n := range c {
+		res.Graphs = append(res.Graphs, n)
+	}
+	if err := <-errc; err != nil {
+		return nil, statusError(ctx, codes.Internal, err)
+	}
+	sort.Strings(res.Graphs)
+	return res, nil
+}
+
+// Watch streams the changes done to the graph until the call is cancelled or
+// the graph is deleted. The headers of the call are sent once the changes are
+// being watched, so no change done after they are received is missed.
+func (s *Service) Watch(req *pb.WatchRequest, stream pb.BadWolf_WatchServer) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	if s.opts.Authorizer != nil {
+		if err := auth.Check(ctx, s.opts.Authorizer, req.GetGraph(), auth.Read); err != nil {
+			return statusError(ctx, codes.Internal, err)
+		}
+	}
+	g, err := s.store.Graph(ctx, req.GetGraph())
+	if err != nil {
+		return statusError(ctx, codes.NotFound, err)
+	}
+	cs, err := storage.Watch(ctx, g)
+	if err == storage.ErrNoChangeFeed {
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+	if err != nil {
+		return statusError(ctx, codes.Internal, err)
+	}
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for c := range cs {
+		pc, err := pb.FromChange(c)
+		if err != nil {
+			return statusError(ctx, codes.Internal, err)
+		}
+		if err := stream.Send(pc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	pb "github.com/google/badwolf/proto"
+	"github.com/google/badwolf/storage/auth"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the service over an in memory connection, and returns
+// a client for it and the function stopping both.
+func newTestClient(t *testing.T, svc *Service) (pb.BadWolfClient, func()) {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterBadWolfServer(srv, svc)
+	go srv.Serve(lis)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pb.NewBadWolfClient(conn), func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func getTestProtoTriples(t *testing.T) []*pb.Triple {
+	t.Helper()
+	var res []*pb.Triple
+	for _, l := range strings.Split(strings.TrimSpace(testTriples), "\n") {
+		trpl, err := triple.Parse(l, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatal(err)
+		}
+		pt, err := pb.FromTriple(trpl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, pt)
+	}
+	return res
+}
+
+// queryRows runs the query and returns its bindings and the subjects bound to
+// ?s by its rows, sorted.
+func queryRows(ctx context.Context, t *testing.T, c pb.BadWolfClient, q string) ([]string, []string, error) {
+	t.Helper()
+	stream, err := c.ExecuteQuery(ctx, &pb.ExecuteQueryRequest{Query: q})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bs, ss []string
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		bs = append(bs, res.GetBindings()...)
+		for _, r := range res.GetRows() {
+			ss = append(ss, r.GetCells()["?s"].GetNode().GetId())
+		}
+	}
+	sort.Strings(ss)
+	return bs, ss, nil
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	if _, err := s.NewGraph(ctx, "?family"); err != nil {
+		t.Fatal(err)
+	}
+	c, stop := newTestClient(t, NewService(s, nil))
+	defer stop()
+
+	gs, err := c.ListGraphs(ctx, &pb.ListGraphsRequest{})
+	if err != nil || !reflect.DeepEqual(gs.GetGraphs(), []string{"?family"}) {
+		t.Fatalf("ListGraphs returned %v, %v; want [?family]", gs, err)
+	}
+
+	// Changes are watched before adding triples.
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w, err := c.Watch(wctx, &pb.WatchRequest{Graph: "?family"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Header(); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := getTestProtoTriples(t)
+	add, err := c.AddTriples(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, trpl := range ts {
+		if err := add.Send(&pb.TriplesRequest{Graph: "?family", Triples: []*pb.Triple{trpl}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if res, err := add.CloseAndRecv(); err != nil || res.GetTriples() != 3 {
+		t.Fatalf("AddTriples returned %v, %v; want 3 triples", res, err)
+	}
+	for i := 0; i < 3; i++ {
+		ch, err := w.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ch.GetOp() != pb.Change_ADDED || len(ch.GetTriples()) != 1 {
+			t.Errorf("Watch returned %v; want one added triple", ch)
+		}
+	}
+
+	bs, ss, err := queryRows(ctx, t, c, `select ?s from ?family where {?s "parent_of"@[] ?o}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"joe", "joe", "peter"}; !reflect.DeepEqual(bs, []string{"?s"}) || !reflect.DeepEqual(ss, want) {
+		t.Errorf("ExecuteQuery returned %v, %v; want [?s], %v", bs, ss, want)
+	}
+
+	rm, err := c.RemoveTriples(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.Send(&pb.TriplesRequest{Graph: "?family", Triples: ts[:2]}); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := rm.CloseAndRecv(); err != nil || res.GetTriples() != 2 {
+		t.Fatalf("RemoveTriples returned %v, %v; want 2 triples", res, err)
+	}
+	for n := 0; n < 2; {
+		ch, err := w.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ch.GetOp() != pb.Change_REMOVED {
+			t.Fatalf("Watch returned %v; want removed triples", ch)
+		}
+		n += len(ch.GetTriples())
+	}
+
+	// Errors are reported with their status codes.
+	if _, _, err := queryRows(ctx, t, c, `select ?s from ?family where`); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ExecuteQuery of an invalid statement returned %v; want InvalidArgument", err)
+	}
+	add, err = c.AddTriples(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := add.Send(&pb.TriplesRequest{Graph: "?missing", Triples: ts}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := add.CloseAndRecv(); status.Code(err) != codes.NotFound {
+		t.Errorf("AddTriples to a missing graph returned %v; want NotFound", err)
+	}
+}
+
+func TestServiceAuth(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	for _, id := range []string{"?family", "?public"} {
+		if _, err := s.NewGraph(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts, err := ParseTokens(strings.NewReader(testTokens))
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl, err := auth.ParseACL(strings.NewReader(testACL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, stop := newTestClient(t, NewService(s, &Options{Authenticator: ts, Authorizer: acl}))
+	defer stop()
+	alice := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer alice-token")
+	bob := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer bob-token")
+
+	table := []struct {
+		ctx    context.Context
+		query  string
+		want   codes.Code
+		graphs []string
+	}{
+		{alice, `select ?s from ?family where {?s ?p ?o}`, codes.OK, []string{"?family", "?public"}},
+		{bob, `select ?s from ?family where {?s ?p ?o}`, codes.PermissionDenied, []string{"?public"}},
+		{bob, `insert data into ?public {/u<bob> "likes"@[] /u<mary>}`, codes.PermissionDenied, []string{"?public"}},
+		{metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer eve-token"), `select ?s from ?public where {?s ?p ?o}`, codes.Unauthenticated, nil},
+	}
+	for _, entry := range table {
+		if _, _, err := queryRows(entry.ctx, t, c, entry.query); status.Code(err) != entry.want {
+			t.Errorf("ExecuteQuery(%q) returned %v; want %v", entry.query, err, entry.want)
+		}
+		gs, err := c.ListGraphs(entry.ctx, &pb.ListGraphsRequest{})
+		if entry.graphs == nil {
+			if status.Code(err) != codes.Unauthenticated {
+				t.Errorf("ListGraphs returned %v; want Unauthenticated", err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(gs.GetGraphs(), entry.graphs) {
+			t.Errorf("ListGraphs returned %v, %v; want %v", gs, err, entry.graphs)
+		}
+	}
+
+	add, err := c.AddTriples(bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := add.Send(&pb.TriplesRequest{Graph: "?public", Triples: getTestProtoTriples(t)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := add.CloseAndRecv(); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("AddTriples by bob returned %v; want PermissionDenied", err)
+	}
+}
@@ -0,0 +1,648 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server provides an HTTP REST API to run BQL statements, manage
+// graphs, and load triples in bulk into a storage.Store.
+//
+// The API is served by the handler returned by New, which can be mounted under
+// any prefix using http.StripPrefix. It provides the following endpoints:
+//
+//	GET    /query                 runs the read only BQL statement in the
+//	                              query parameter.
+//	POST   /query                 runs the BQL statement in the request.
+//	GET    /graphs                lists the graphs in the store.
+//	GET    /graphs/{name}         describes a graph.
+//	PUT    /graphs/{name}         creates a graph.
+//	DELETE /graphs/{name}         deletes a graph.
+//	POST   /graphs/{name}/triples loads the triples in the request into a graph.
+//	POST   /sparql                runs the SPARQL query in the request.
+//	GET    /subscribe             pushes the changes of standing queries over a
+//	                              WebSocket connection.
+//
+// Graph names need to be escaped in the paths, so the graph ?family is
+// available at /graphs/%3Ffamily. Errors are reported as JSON objects with an
+// error field.
+//
+// The API is open to anyone by default. Options.Authenticator identifies the
+// principal making each request, and Options.Authorizer restricts what it can
+// read and write per graph; see the storage/auth package.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/planner"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	bwio "github.com/google/badwolf/io"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/auth"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"golang.org/x/net/websocket"
+)
+
+// Options contains the settings of the API.
+type Options struct {
+	// ChanSize is the size of the channels used to run BQL statements.
+	ChanSize int
+
+	// BulkSize is the number of triples written in each batch by BQL
+	// statements and bulk loads. It defaults to storage.DefaultBulkBatchSize.
+	BulkSize int
+
+	// MaxErrors, if positive, is the number of malformed lines tolerated by a
+	// bulk load before aborting it.
+	MaxErrors int
+
+	// PollInterval is the interval between evaluations of standing queries on
+	// graphs that do not provide a change feed. It defaults to one second.
+	PollInterval time.Duration
+
+	// Authenticator, if not nil, identifies the principal making each request.
+	// Otherwise, the principal is the one already in the context of the
+	// request, if any, as set by Authenticate.
+	Authenticator Authenticator
+
+	// Authorizer, if not nil, decides the permissions of the principals on the
+	// graphs of the store. Requests missing a permission fail with status
+	// 403, and only the graphs a principal can read are listed.
+	Authorizer auth.Authorizer
+
+	// MaxBodyBytes is the maximum size in bytes of the bodies of the requests
+	// running BQL statements and SPARQL queries. Larger bodies fail with
+	// status 413. It defaults to DefaultMaxBodyBytes. The bodies of bulk loads
+	// are streamed into the graphs and are not limited.
+	MaxBodyBytes int64
+}
+
+// DefaultMaxBodyBytes is the default maximum size in bytes of the bodies of
+// the requests running statements.
+const DefaultMaxBodyBytes = 1 << 20
+
+// ErrorTrailer is the HTTP trailer set when a streamed response fails after
+// some of its rows were already sent. It contains the description of the
+// error.
+const ErrorTrailer = "X-Badwolf-Error"
+
+// flushRows is the number of rows of streamed responses buffered before
+// sending them to the client.
+const flushRows = 100
+
+// mediaTypes maps the formats of the query results to their media types.
+var mediaTypes = map[table.Format]string{
+	table.FormatTable:      "text/plain; charset=utf-8",
+	table.FormatCSV:        "text/csv; charset=utf-8",
+	table.FormatTSV:        "text/tab-separated-values; charset=utf-8",
+	table.FormatJSON:       "application/json",
+	table.FormatSPARQLJSON: "application/sparql-results+json",
+	table.FormatNDJSON:     "application/x-ndjson",
+	table.FormatSPARQLXML:  "application/sparql-results+xml",
+}
+
+// loadFormats maps the media types of the bodies of bulk loads to their
+// formats, named as the format query parameter.
+var loadFormats = map[string]string{
+	"text/plain":            "bw",
+	"application/n-triples": "nt",
+	"text/turtle":           "ttl",
+	"application/ld+json":   "jsonld",
+}
+
+// Handler serves the REST API for a store.
+type Handler struct {
+	store   storage.Store
+	opts    Options
+	handler http.Handler
+}
+
+// New returns a handler serving the REST API for the provided store. The
+// options may be nil.
+func New(store storage.Store, opts *Options) *Handler {
+	h := &Handler{store: store}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.BulkSize <= 0 {
+		h.opts.BulkSize = storage.DefaultBulkBatchSize
+	}
+	if h.opts.PollInterval <= 0 {
+		h.opts.PollInterval = time.Second
+	}
+	if h.opts.MaxBodyBytes <= 0 {
+		h.opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if h.opts.Authorizer != nil {
+		h.store = auth.NewStore(store, h.opts.Authorizer)
+	}
+	h.handler = http.HandlerFunc(h.route)
+	if h.opts.Authenticator != nil {
+		h.handler = Authenticate(h.opts.Authenticator, h.handler)
+	}
+	return h
+}
+
+// ServeHTTP serves the request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// route routes the request to the endpoint serving it.
+func (h *Handler) route(w http.ResponseWriter, r *http.Request) {
+	segs, err := pathSegments(r.URL)
+	if err != nil {
+		reportError(w, http.StatusBadRequest, err)
+		return
+	}
+	switch {
+	case len(segs) == 1 && segs[0] == "query":
+		if allow(w, r, http.MethodGet, http.MethodPost) {
+			h.query(w, r)
+		}
+	case len(segs) == 1 && segs[0] == "sparql":
+		if allow(w, r, http.MethodGet, http.MethodPost) {
+			h.sparql(w, r)
+		}
+	case len(segs) == 1 && segs[0] == "subscribe":
+		websocket.Handler(h.subscribe).ServeHTTP(w, r)
+	case len(segs) == 1 && segs[0] == "graphs":
+		if allow(w, r, http.MethodGet) {
+			h.listGraphs(w, r)
+		}
+	case len(segs) == 2 && segs[0] == "graphs":
+		if !allow(w, r, http.MethodGet, http.MethodPut, http.MethodDelete) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			h.describeGraph(w, r, segs[1])
+		case http.MethodPut:
+			h.createGraph(w, r, segs[1])
+		case http.MethodDelete:
+			h.deleteGraph(w, r, segs[1])
+		}
+	case len(segs) == 3 && segs[0] == "graphs" && segs[2] == "triples":
+		if allow(w, r, http.MethodPost) {
+			h.loadTriples(w, r, segs[1])
+		}
+	default:
+		reportError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint %q", r.URL.Path))
+	}
+}
+
+// pathSegments returns the unescaped segments of the path of the URL.
+func pathSegments(u *url.URL) ([]string, error) {
+	var segs []string
+	for _, s := range strings.Split(strings.Trim(u.EscapedPath(), "/"), "/") {
+		us, err := url.PathUnescape(s)
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, us)
+	}
+	return segs, nil
+}
+
+// allow checks that the request uses one of the provided methods. Otherwise,
+// it reports the error and returns false.
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	reportError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed on %q", r.Method, r.URL.Path))
+	return false
+}
+
+// reportError writes the error as a JSON object with the provided status.
+func reportError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeJSON writes the provided value as JSON with the provided status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	e.Encode(v)
+}
+
+// requestContext returns the context to serve the request, which is done when
+// the client goes away or the timeout provided by the timeout query parameter
+// expires.
+func requestContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	t := r.URL.Query().Get("timeout")
+	if t == "" {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
+	}
+	d, err := time.ParseDuration(t)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timeout %q; %v", t, err)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), d)
+	return ctx, cancel, nil
+}
+
+// resultFormat returns the format of the query results requested, either by
+// the format query parameter or the Accept header, defaulting to
+// table.FormatJSON.
+func resultFormat(r *http.Request) (table.Format, error) {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return table.ParseFormat(f)
+	}
+	for _, a := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(a))
+		if err != nil {
+			continue
+		}
+		for f, t := range mediaTypes {
+			if ft, _, _ := mime.ParseMediaType(t); ft == mt {
+				return f, nil
+			}
+		}
+	}
+	return table.FormatJSON, nil
+}
+
+// readBody returns the body of the request, which was limited to the
+// provided number of bytes using http.MaxBytesReader. It also returns the
+// status to report if it cannot be read.
+func readBody(r *http.Request, limit int64) ([]byte, int, error) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		if int64(len(b)) >= limit {
+			return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("request body larger than %d bytes", limit)
+		}
+		return nil, http.StatusBadRequest, err
+	}
+	return b, http.StatusOK, nil
+}
+
+// statement returns the BQL statement of the request, along with the status to
+// report if it cannot be read. It is provided either by the query parameter,
+// or as the body of POST requests that are not forms.
+func statement(r *http.Request, limit int64) (string, int, error) {
+	var q string
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if r.Method == http.MethodPost && ct != "application/x-www-form-urlencoded" && ct != "multipart/form-data" {
+		b, status, err := readBody(r, limit)
+		if err != nil {
+			return "", status, err
+		}
+		q = string(b)
+	}
+	if strings.TrimSpace(q) == "" {
+		if err := r.ParseForm(); err != nil {
+			return "", http.StatusBadRequest, err
+		}
+		q = r.FormValue("query")
+	}
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return "", http.StatusBadRequest, fmt.Errorf("missing BQL statement")
+	}
+	if !strings.HasSuffix(q, ";") {
+		q += ";"
+	}
+	return q, http.StatusOK, nil
+}
+
+// readOnly returns true if running the statement cannot change the store.
+func readOnly(stm *semantic.Statement) bool {
+	switch stm.Type() {
+	case semantic.Query, semantic.Show, semantic.Ask, semantic.Describe:
+		return true
+	}
+	return false
+}
+
+// parse returns the semantic statement of the provided BQL statement.
+func parse(q string) (*semantic.Statement, error) {
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		return nil, err
+	}
+	stm := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), stm); err != nil {
+		return nil, err
+	}
+	return stm, nil
+}
+
+// query runs the BQL statement of the request, and writes its results in the
+// requested format. Results in formats serializing each row independently are
+// streamed as they are computed. Statements changing the store are only run
+// on POST requests, so following a link or prefetching a page never changes
+// it.
+func (h *Handler) query(w http.ResponseWriter, r *http.Request) {
+	f, err := resultFormat(r)
+	if err != nil {
+		reportError(w, http.StatusNotAcceptable, err)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.opts.MaxBodyBytes)
+	q, status, err := statement(r, h.opts.MaxBodyBytes)
+	if err != nil {
+		reportError(w, status, err)
+		return
+	}
+	ctx, cancel, err := requestContext(r)
+	if err != nil {
+		reportError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer cancel()
+
+	stm, err := parse(q)
+	if err != nil {
+		reportError(w, http.StatusBadRequest, err)
+		return
+	}
+	if r.Method != http.MethodPost && !readOnly(stm) {
+		w.Header().Set("Allow", http.MethodPost)
+		reportError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s statements change the store and need to be sent using POST", stm.Type()))
+		return
+	}
+	if err := h.authorize(ctx, stm); err != nil {
+		reportAuthError(w, err)
+		return
+	}
+	pln, err := planner.New(ctx, h.store, stm, h.opts.ChanSize, h.opts.BulkSize, nil)
+	if err != nil {
+		reportError(w, http.StatusBadRequest, err)
+		return
+	}
+	if s, ok := pln.(planner.Streamer); ok && f != table.FormatJSON && f != table.FormatSPARQLJSON && f != table.FormatTable {
+		h.stream(ctx, w, s, f)
+		return
+	}
+	tbl, err := pln.Execute(ctx)
+	if err != nil {
+		reportError(w, executionStatus(ctx), err)
+		return
+	}
+	w.Header().Set("Content-Type", mediaTypes[f])
+	tbl.Write(w, f)
+}
+
+// executionStatus returns the status reported when running a statement fails.
+func executionStatus(ctx context.Context) int {
+	if ctx.Err() == context.DeadlineExceeded {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// stream writes the rows of the query as they are computed. Errors found after
+// the first row is sent are reported in the ErrorTrailer trailer.
+func (h *Handler) stream(ctx context.Context, w http.ResponseWriter, s planner.Streamer, f table.Format) {
+	it, err := s.Stream(ctx)
+	if err != nil {
+		reportError(w, executionStatus(ctx), err)
+		return
+	}
+	defer it.Close()
+	rw, err := table.NewRowWriter(w, f, it.Bindings())
+	if err != nil {
+		reportError(w, http.StatusNotAcceptable, err)
+		return
+	}
+	w.Header().Set("Content-Type", mediaTypes[f])
+	w.Header().Set("Trailer", ErrorTrailer)
+	flush := func() {
+		rw.Flush()
+		if fl, ok := w.(http.Flusher); ok {
+			fl.Flush()
+		}
+	}
+	for n := 1; ; n++ {
+		row, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			flush()
+			w.Header().Set(ErrorTrailer, err.Error())
+			return
+		}
+		if err := rw.Write(row); err != nil {
+			w.Header().Set(ErrorTrailer, err.Error())
+			return
+		}
+		if n%flushRows == 0 {
+			flush()
+		}
+	}
+	flush()
+}
+
+// listGraphs writes the sorted names of the graphs in the store.
+func (h *Handler) listGraphs(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	c, errc := make(chan string), make(chan error, 1)
+	go func() {
+		errc <- h.store.GraphNames(r.Context(), c)
+	}()
+	for n := range c {
+		names = append(names, n)
+	}
+	if err := <-errc; err != nil {
+		reportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sort.Strings(names)
+	if names == nil {
+		names = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"graphs": names})
+}
+
+// describeGraph writes the name of the graph and the number of triples it
+// contains.
+func (h *Handler) describeGraph(w http.ResponseWriter, r *http.Request, name string) {
+	if err := h.check(r.Context(), name, auth.Read); err != nil {
+		reportAuthError(w, err)
+		return
+	}
+	g, err := h.store.Graph(r.Context(), name)
+	if err != nil {
+		reportError(w, http.StatusNotFound, err)
+		return
+	}
+	n, err := storage.CountTriples(r.Context(), g)
+	if err != nil {
+		reportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"graph": name, "triples": n})
+}
+
+// createGraph creates the graph, failing if it already exists.
+func (h *Handler) createGraph(w http.ResponseWriter, r *http.Request, name string) {
+	if err := h.check(r.Context(), name, auth.Write); err != nil {
+		reportAuthError(w, err)
+		return
+	}
+	if _, err := h.store.Graph(r.Context(), name); err == nil {
+		reportError(w, http.StatusConflict, fmt.Errorf("graph %q already exists", name))
+		return
+	}
+	if _, err := h.store.NewGraph(r.Context(), name); err != nil {
+		reportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"graph": name})
+}
+
+// deleteGraph deletes the graph.
+func (h *Handler) deleteGraph(w http.ResponseWriter, r *http.Request, name string) {
+	if err := h.check(r.Context(), name, auth.Write); err != nil {
+		reportAuthError(w, err)
+		return
+	}
+	if _, err := h.store.Graph(r.Context(), name); err != nil {
+		reportError(w, http.StatusNotFound, err)
+		return
+	}
+	if err := h.store.DeleteGraph(r.Context(), name); err != nil {
+		reportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadError describes a malformed line found by a bulk load.
+type loadError struct {
+	Line  int    `json:"line"`
+	Text  string `json:"text"`
+	Error string `json:"error"`
+}
+
+// loadTriples adds the triples in the body of the request to the graph. The
+// format of the body is provided by the format query parameter or the
+// Content-Type header, and bodies compressed using gzip or bzip2 are
+// decompressed.
+func (h *Handler) loadTriples(w http.ResponseWriter, r *http.Request, name string) {
+	f := r.URL.Query().Get("format")
+	if f == "" {
+		ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if f = loadFormats[ct]; f == "" {
+			f = "bw"
+		}
+	}
+	ctx, cancel, err := requestContext(r)
+	if err != nil {
+		reportError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer cancel()
+	if err := h.check(ctx, name, auth.Write); err != nil {
+		reportAuthError(w, err)
+		return
+	}
+	g, err := h.store.Graph(ctx, name)
+	if err != nil {
+		reportError(w, http.StatusNotFound, err)
+		return
+	}
+	b := storage.LiteralBuilder(ctx, h.store)
+	var rep *bwio.LoadReport
+	switch f {
+	case "bw":
+		rep, err = bwio.LoadGraph(ctx, g, r.Body, b, &bwio.LoadOptions{
+			BatchSize: h.opts.BulkSize,
+			MaxErrors: h.opts.MaxErrors,
+		})
+	case "nt", "ttl", "jsonld":
+		rep, err = h.loadRDF(ctx, g, r.Body, b, f)
+	default:
+		reportError(w, http.StatusUnsupportedMediaType, fmt.Errorf("unknown format %q; valid formats are {bw|nt|ttl|jsonld}", f))
+		return
+	}
+	res := map[string]interface{}{"graph": name}
+	if rep != nil {
+		errs := []*loadError{}
+		for _, e := range rep.Errors {
+			errs = append(errs, &loadError{Line: e.Line, Text: e.Text, Error: e.Err.Error()})
+		}
+		res["triples"], res["errors"] = rep.Triples, errs
+	}
+	status := http.StatusOK
+	if err != nil {
+		res["error"] = err.Error()
+		status = http.StatusBadRequest
+		if ctx.Err() != nil {
+			status = executionStatus(ctx)
+		}
+	}
+	writeJSON(w, status, res)
+}
+
+// loadRDF adds the triples of an RDF document in the provided format to the
+// graph in bulk.
+func (h *Handler) loadRDF(ctx context.Context, g storage.Graph, body io.Reader, b literal.Builder, f string) (*bwio.LoadReport, error) {
+	r, err := bwio.Decompress(body)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		ts   = make(chan *triple.Triple, h.opts.BulkSize)
+		done = make(chan struct{})
+		rErr error
+		rep  = &bwio.LoadReport{}
+	)
+	go func() {
+		defer close(done)
+		switch f {
+		case "nt":
+			_, rErr = bwio.ReadNTriples(ctx, r, b, nil, ts)
+		case "ttl":
+			_, rErr = bwio.ReadTurtle(ctx, r, b, nil, ts)
+		case "jsonld":
+			_, rErr = bwio.ReadJSONLD(ctx, r, b, nil, ts)
+		}
+	}()
+	err = storage.BulkLoad(ctx, g, ts, &storage.BulkLoadOptions{
+		BatchSize: h.opts.BulkSize,
+		Progress: func(res *storage.BatchResult) {
+			rep.Triples = int(res.Loaded)
+		},
+	})
+	if err != nil {
+		// Stop the reader, which may be blocked on the channel.
+		cancel()
+	}
+	<-done
+	if err == nil {
+		err = rErr
+	}
+	return rep, err
+}
@@ -0,0 +1,191 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage/memory"
+)
+
+const testTriples = `/u<joe> "parent_of"@[] /u<mary>
+/u<joe> "parent_of"@[] /u<peter>
+/u<peter> "parent_of"@[] /u<john>
+`
+
+// do sends the request to the handler and returns the response.
+func do(t *testing.T, h http.Handler, method, target, ct, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if ct != "" {
+		r.Header.Set("Content-Type", ct)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	h := New(memory.NewStore(), nil)
+	if w := do(t, h, http.MethodPut, "/graphs/%3Ffamily", "", ""); w.Code != http.StatusCreated {
+		t.Fatalf("PUT /graphs/%%3Ffamily returned %d; %s", w.Code, w.Body)
+	}
+	if w := do(t, h, http.MethodPost, "/graphs/%3Ffamily/triples", "text/plain", testTriples); w.Code != http.StatusOK {
+		t.Fatalf("POST /graphs/%%3Ffamily/triples returned %d; %s", w.Code, w.Body)
+	}
+	return h
+}
+
+func TestGraphEndpoints(t *testing.T) {
+	h := newTestHandler(t)
+	table := []struct {
+		method, target string
+		want           int
+		body           string
+	}{
+		{http.MethodGet, "/graphs", http.StatusOK, `{"graphs":["?family"]}`},
+		{http.MethodGet, "/graphs/%3Ffamily", http.StatusOK, `{"graph":"?family","triples":3}`},
+		{http.MethodGet, "/graphs/%3Fmissing", http.StatusNotFound, ""},
+		{http.MethodPut, "/graphs/%3Ffamily", http.StatusConflict, ""},
+		{http.MethodPost, "/graphs/%3Ffamily", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/unknown", http.StatusNotFound, ""},
+		{http.MethodDelete, "/graphs/%3Fmissing", http.StatusNotFound, ""},
+		{http.MethodDelete, "/graphs/%3Ffamily", http.StatusNoContent, ""},
+		{http.MethodGet, "/graphs", http.StatusOK, `{"graphs":[]}`},
+	}
+	for _, entry := range table {
+		w := do(t, h, entry.method, entry.target, "", "")
+		if w.Code != entry.want {
+			t.Errorf("%s %s returned %d; want %d", entry.method, entry.target, w.Code, entry.want)
+		}
+		if got := strings.TrimSpace(w.Body.String()); entry.body != "" && got != entry.body {
+			t.Errorf("%s %s returned %s; want %s", entry.method, entry.target, got, entry.body)
+		}
+	}
+}
+
+func TestLoadTriples(t *testing.T) {
+	h := newTestHandler(t)
+	table := []struct {
+		target, ct, body string
+		want             int
+		triples          int
+		errors           int
+	}{
+		{"/graphs/%3Ffamily/triples", "text/plain", "/u<eve> \"parent_of\"@[] /u<ann>\nnot a triple\n", http.StatusOK, 1, 1},
+		{"/graphs/%3Ffamily/triples", "application/n-triples", "<http://e/a> <http://e/p> <http://e/b> .\n", http.StatusOK, 1, 0},
+		{"/graphs/%3Ffamily/triples?format=ttl", "", "<http://e/a> <http://e/p> \"x\" .\n", http.StatusOK, 1, 0},
+		{"/graphs/%3Ffamily/triples?format=xml", "", "", http.StatusUnsupportedMediaType, 0, 0},
+		{"/graphs/%3Fmissing/triples", "text/plain", testTriples, http.StatusNotFound, 0, 0},
+	}
+	for _, entry := range table {
+		w := do(t, h, http.MethodPost, entry.target, entry.ct, entry.body)
+		if w.Code != entry.want {
+			t.Errorf("POST %s returned %d; want %d; %s", entry.target, w.Code, entry.want, w.Body)
+			continue
+		}
+		if entry.want != http.StatusOK {
+			continue
+		}
+		var res struct {
+			Triples int
+			Errors  []*loadError
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("POST %s returned invalid JSON %s; %v", entry.target, w.Body, err)
+		}
+		if res.Triples != entry.triples || len(res.Errors) != entry.errors {
+			t.Errorf("POST %s loaded %d triples with %d errors; want %d and %d", entry.target, res.Triples, len(res.Errors), entry.triples, entry.errors)
+		}
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	h := New(memory.NewStore(), &Options{MaxBodyBytes: 64})
+	if w := do(t, h, http.MethodPut, "/graphs/%3Ffamily", "", ""); w.Code != http.StatusCreated {
+		t.Fatalf("PUT /graphs/%%3Ffamily returned %d; %s", w.Code, w.Body)
+	}
+	small := `select ?s from ?family where {?s ?p ?o}`
+	large := small + strings.Repeat(" ", 64)
+	sparql := `SELECT ?s WHERE { ?s ?p ?o }`
+	table := []struct {
+		target, ct, body string
+		want             int
+	}{
+		{"/query", "text/plain", small, http.StatusOK},
+		{"/query", "text/plain", large, http.StatusRequestEntityTooLarge},
+		{"/query", "application/x-www-form-urlencoded", "query=" + url.QueryEscape(large), http.StatusBadRequest},
+		{"/sparql", "application/sparql-query", sparql, http.StatusOK},
+		{"/sparql", "application/sparql-query", sparql + strings.Repeat(" ", 64), http.StatusRequestEntityTooLarge},
+		// Bulk loads are not limited.
+		{"/graphs/%3Ffamily/triples", "text/plain", testTriples + testTriples, http.StatusOK},
+	}
+	for _, entry := range table {
+		if w := do(t, h, http.MethodPost, entry.target, entry.ct, entry.body); w.Code != entry.want {
+			t.Errorf("POST %s with a %d bytes body returned %d; want %d; %s", entry.target, len(entry.body), w.Code, entry.want, w.Body)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	h := newTestHandler(t)
+	q := `select ?c from ?family where {/u<peter> "parent_of"@[] ?c}`
+	table := []struct {
+		method, target, ct, accept, body string
+		want                             int
+		ctype                            string
+		contains                         string
+	}{
+		{http.MethodGet, "/query?query=" + url.QueryEscape(q), "", "", "", http.StatusOK, "application/json", "/u<john>"},
+		{http.MethodPost, "/query", "text/plain", "text/csv", q, http.StatusOK, "text/csv; charset=utf-8", "?c\n/u<john>\n"},
+		{http.MethodPost, "/query?format=ndjson", "text/plain", "", q, http.StatusOK, "application/x-ndjson", `{"?c":{"node":"/u<john>"}}`},
+		{http.MethodPost, "/query", "application/x-www-form-urlencoded", "application/sparql-results+json", "query=" + url.QueryEscape(q), http.StatusOK, "application/sparql-results+json", `"vars":["c"]`},
+		{http.MethodPost, "/query?format=xml", "text/plain", "", q, http.StatusNotAcceptable, "application/json", "error"},
+		{http.MethodPost, "/query", "text/plain", "", "select ?c from", http.StatusBadRequest, "application/json", "error"},
+		{http.MethodPost, "/query", "text/plain", "", "", http.StatusBadRequest, "application/json", "missing BQL statement"},
+		{http.MethodPost, "/query?timeout=forever", "text/plain", "", q, http.StatusBadRequest, "application/json", "invalid timeout"},
+		{http.MethodDelete, "/query", "", "", "", http.StatusMethodNotAllowed, "application/json", "error"},
+		{http.MethodGet, "/query?query=" + url.QueryEscape(`drop graph ?family`), "", "", "", http.StatusMethodNotAllowed, "application/json", "POST"},
+		{http.MethodGet, "/query?query=" + url.QueryEscape(`insert data into ?family {/u<mary> "parent_of"@[] /u<ann>}`), "", "", "", http.StatusMethodNotAllowed, "application/json", "POST"},
+		{http.MethodGet, "/query?query=" + url.QueryEscape(`show graphs`), "", "", "", http.StatusOK, "application/json", "?family"},
+		{http.MethodPost, "/query", "text/plain", "", `insert data into ?family {/u<mary> "parent_of"@[] /u<ann>}`, http.StatusOK, "application/json", ""},
+	}
+	for _, entry := range table {
+		r := httptest.NewRequest(entry.method, entry.target, strings.NewReader(entry.body))
+		if entry.ct != "" {
+			r.Header.Set("Content-Type", entry.ct)
+		}
+		if entry.accept != "" {
+			r.Header.Set("Accept", entry.accept)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != entry.want {
+			t.Errorf("%s %s returned %d; want %d; %s", entry.method, entry.target, w.Code, entry.want, w.Body)
+		}
+		if got := w.Header().Get("Content-Type"); got != entry.ctype {
+			t.Errorf("%s %s returned content type %q; want %q", entry.method, entry.target, got, entry.ctype)
+		}
+		if !strings.Contains(w.Body.String(), entry.contains) {
+			t.Errorf("%s %s returned %q; want it to contain %q", entry.method, entry.target, w.Body, entry.contains)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/badwolf/bql/planner"
+	"github.com/google/badwolf/bql/sparql"
+	"github.com/google/badwolf/bql/table"
+)
+
+// sparqlFormat returns the SPARQL results format requested by the Accept
+// header, defaulting to the JSON one.
+func sparqlFormat(r *http.Request) table.Format {
+	for _, a := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(a))
+		if err != nil {
+			continue
+		}
+		switch mt {
+		case "application/sparql-results+json", "application/json":
+			return table.FormatSPARQLJSON
+		case "application/sparql-results+xml", "application/xml", "text/xml":
+			return table.FormatSPARQLXML
+		}
+	}
+	return table.FormatSPARQLJSON
+}
+
+// sparqlQuery returns the SPARQL query of the request, as sent by the query
+// operation of the SPARQL 1.1 protocol. The body of the request was limited to
+// the provided number of bytes.
+func sparqlQuery(r *http.Request, limit int64) (string, int, error) {
+	if r.Method == http.MethodGet {
+		return r.URL.Query().Get("query"), http.StatusOK, nil
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch ct {
+	case "application/sparql-query":
+		b, status, err := readBody(r, limit)
+		return string(b), status, err
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return "", http.StatusBadRequest, err
+		}
+		return r.PostFormValue("query"), http.StatusOK, nil
+	}
+	return "", http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q; use application/sparql-query or application/x-www-form-urlencoded", ct)
+}
+
+// sparql runs the SPARQL query of the request after translating it into BQL,
+// and writes its results in the SPARQL results format requested. Queries
+// without a dataset query all the graphs of the store that BQL can name.
+func (h *Handler) sparql(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.opts.MaxBodyBytes)
+	q, status, err := sparqlQuery(r, h.opts.MaxBodyBytes)
+	if err != nil {
+		reportError(w, status, err)
+		return
+	}
+	if strings.TrimSpace(q) == "" {
+		reportError(w, http.StatusBadRequest, fmt.Errorf("missing SPARQL query"))
+		return
+	}
+	r.ParseForm()
+	if len(r.Form["named-graph-uri"]) > 0 {
+		reportError(w, http.StatusBadRequest, fmt.Errorf("named graphs are not supported"))
+		return
+	}
+	ctx, cancel, err := requestContext(r)
+	if err != nil {
+		reportError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer cancel()
+
+	sq, err := sparql.Translate(q, r.Form["default-graph-uri"])
+	if err == sparql.ErrNoDataset {
+		var graphs []string
+		c, errc := make(chan string), make(chan error, 1)
+		go func() {
+			errc <- h.store.GraphNames(ctx, c)
+		}()
+		for n := range c {
+			if sparql.IsBQLGraphName(n) {
+				graphs = append(graphs, n)
+			}
+		}
+		if err := <-errc; err != nil {
+			reportError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if len(graphs) == 0 {
+			reportError(w, http.StatusBadRequest, fmt.Errorf("the store has no graphs to query"))
+			return
+		}
+		sort.Strings(graphs)
+		sq, err = sparql.Translate(q, graphs)
+	}
+	if err != nil {
+		reportError(w, http.StatusBadRequest, err)
+		return
+	}
+	stm, err := parse(sq.BQL)
+	if err != nil {
+		reportError(w, http.StatusBadRequest, fmt.Errorf("the query is not supported by BQL; %v", err))
+		return
+	}
+	if err := h.authorize(ctx, stm); err != nil {
+		reportAuthError(w, err)
+		return
+	}
+	pln, err := planner.New(ctx, h.store, stm, h.opts.ChanSize, h.opts.BulkSize, nil)
+	if err != nil {
+		reportError(w, http.StatusBadRequest, err)
+		return
+	}
+	tbl, err := pln.Execute(ctx)
+	if err != nil {
+		reportError(w, executionStatus(ctx), err)
+		return
+	}
+
+	f := sparqlFormat(r)
+	w.Header().Set("Content-Type", mediaTypes[f])
+	if !sq.Ask {
+		tbl.Write(w, f)
+		return
+	}
+	ask := false
+	if rows := tbl.Rows(); len(rows) > 0 {
+		if c := rows[0]["?ask"]; c != nil && c.L != nil {
+			ask, _ = c.L.Bool()
+		}
+	}
+	if f == table.FormatSPARQLXML {
+		fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<sparql xmlns=\"http://www.w3.org/2005/sparql-results#\">\n  <head/>\n  <boolean>%v</boolean>\n</sparql>\n", ask)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"head": map[string]interface{}{}, "boolean": ask})
+}
@@ -0,0 +1,68 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSPARQL(t *testing.T) {
+	h := newTestHandler(t)
+	sel := `SELECT ?c WHERE { <urn:badwolf:node:/u#peter> <parent_of> ?c }`
+	table := []struct {
+		method, target, ct, accept, body string
+		want                             int
+		ctype                            string
+		contains                         string
+	}{
+		{http.MethodGet, "/sparql?query=" + url.QueryEscape(sel), "", "", "", http.StatusOK, "application/sparql-results+json",
+			`{"head":{"vars":["c"]},"results":{"bindings":[{"c":{"type":"uri","value":"urn:badwolf:node:/u#john"}}]}}`},
+		{http.MethodPost, "/sparql?default-graph-uri=urn:badwolf:graph:%253Ffamily", "application/sparql-query", "application/sparql-results+xml", sel, http.StatusOK, "application/sparql-results+xml",
+			`<binding name="c"><uri>urn:badwolf:node:/u#john</uri></binding>`},
+		{http.MethodPost, "/sparql", "application/x-www-form-urlencoded", "", "query=" + url.QueryEscape(`ASK FROM <?family> { ?p <parent_of> <urn:badwolf:node:/u#john> }`), http.StatusOK, "application/sparql-results+json",
+			`{"boolean":true,"head":{}}`},
+		{http.MethodGet, "/sparql?query=" + url.QueryEscape(`ASK { ?p <parent_of> <urn:badwolf:node:/u#joe> }`), "", "application/sparql-results+xml", "", http.StatusOK, "application/sparql-results+xml",
+			`<boolean>false</boolean>`},
+		{http.MethodGet, "/sparql?query=" + url.QueryEscape(sel) + "&default-graph-uri=%3Fmissing", "", "", "", http.StatusInternalServerError, "application/json", "error"},
+		{http.MethodGet, "/sparql?query=" + url.QueryEscape(`DESCRIBE <urn:badwolf:node:/u#joe>`), "", "", "", http.StatusBadRequest, "application/json", "only SELECT and ASK"},
+		{http.MethodGet, "/sparql?query=" + url.QueryEscape(sel) + "&named-graph-uri=%3Ffamily", "", "", "", http.StatusBadRequest, "application/json", "named graphs"},
+		{http.MethodGet, "/sparql", "", "", "", http.StatusBadRequest, "application/json", "missing SPARQL query"},
+		{http.MethodPost, "/sparql", "text/plain", "", sel, http.StatusUnsupportedMediaType, "application/json", "unsupported content type"},
+	}
+	for _, entry := range table {
+		r := httptest.NewRequest(entry.method, entry.target, strings.NewReader(entry.body))
+		if entry.ct != "" {
+			r.Header.Set("Content-Type", entry.ct)
+		}
+		if entry.accept != "" {
+			r.Header.Set("Accept", entry.accept)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != entry.want {
+			t.Errorf("%s %s returned %d; want %d; %s", entry.method, entry.target, w.Code, entry.want, w.Body)
+		}
+		if got := w.Header().Get("Content-Type"); got != entry.ctype {
+			t.Errorf("%s %s returned content type %q; want %q", entry.method, entry.target, got, entry.ctype)
+		}
+		if !strings.Contains(w.Body.String(), entry.contains) {
+			t.Errorf("%s %s returned %q; want it to contain %q", entry.method, entry.target, w.Body, entry.contains)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/bql"
+	"github.com/google/badwolf/bql/planner"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"golang.org/x/net/websocket"
+)
+
+// settleTime is the time waited after a change before evaluating a standing
+// query again, so bursts of changes only evaluate it once.
+const settleTime = 100 * time.Millisecond
+
+// subscribeRequest is the message sent by clients to register a standing
+// query, or to cancel it.
+type subscribeRequest struct {
+	ID     string `json:"id"`
+	Query  string `json:"query,omitempty"`
+	Cancel bool   `json:"cancel,omitempty"`
+}
+
+// update is the message sent to clients when the results of a standing query
+// change. The first update of a query contains its bindings and all its rows
+// as added. Rows are encoded as done by the ndjson format.
+type update struct {
+	ID       string            `json:"id"`
+	Time     *time.Time        `json:"time,omitempty"`
+	Bindings []string          `json:"bindings,omitempty"`
+	Added    []json.RawMessage `json:"added,omitempty"`
+	Removed  []json.RawMessage `json:"removed,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// subscribe serves a WebSocket connection where clients register standing
+// queries. The results of each registered query are kept up to date as the
+// graphs it queries change, sending the rows added and removed to the client.
+// Queries are followed until they are cancelled, they fail, or the connection
+// is closed.
+func (h *Handler) subscribe(ws *websocket.Conn) {
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		subs = make(map[string]context.CancelFunc)
+	)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	send := func(u *update) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// Rows are sent as encoded, without escaping HTML characters.
+		var buf bytes.Buffer
+		e := json.NewEncoder(&buf)
+		e.SetEscapeHTML(false)
+		if err := e.Encode(u); err != nil {
+			return err
+		}
+		return websocket.Message.Send(ws, buf.String())
+	}
+	for {
+		req := &subscribeRequest{}
+		if err := websocket.JSON.Receive(ws, req); err != nil {
+			return
+		}
+		mu.Lock()
+		stop, ok := subs[req.ID]
+		mu.Unlock()
+		switch {
+		case req.Cancel:
+			if ok {
+				stop()
+			}
+		case ok:
+			send(&update{ID: req.ID, Error: fmt.Sprintf("query %q is already registered", req.ID)})
+		default:
+			sctx, stop := context.WithCancel(ctx)
+			mu.Lock()
+			subs[req.ID] = stop
+			mu.Unlock()
+			wg.Add(1)
+			go func(req *subscribeRequest) {
+				defer wg.Done()
+				if err := h.standingQuery(sctx, req.ID, req.Query, send); err != nil && sctx.Err() == nil {
+					send(&update{ID: req.ID, Error: err.Error()})
+				}
+				mu.Lock()
+				delete(subs, req.ID)
+				mu.Unlock()
+				stop()
+			}(req)
+		}
+	}
+}
+
+// standingQuery keeps the results of the query up to date as the graphs it
+// queries change, sending their changes, until the context is done or an
+// error is found. The results of the queries supported by bql.View are
+// maintained from the changes of their graph, and the rest of queries are
+// evaluated again every time their graphs change.
+func (h *Handler) standingQuery(ctx context.Context, id, q string, send func(*update) error) error {
+	q = strings.TrimSpace(q)
+	if !strings.HasSuffix(q, ";") {
+		q += ";"
+	}
+	stm, err := parse(q)
+	if err != nil {
+		return err
+	}
+	if stm.Type() != semantic.Query {
+		return fmt.Errorf("only SELECT statements can be registered, got %q", q)
+	}
+	if err := h.authorize(ctx, stm); err != nil {
+		return err
+	}
+	v, err := bql.NewView(q, h.opts.ChanSize, h.opts.BulkSize)
+	if err == nil {
+		if ok, err := h.maintainView(ctx, id, v, send); ok {
+			return err
+		}
+	} else if err != bql.ErrNotIncremental {
+		return err
+	}
+	changed, deleted, err := h.watchGraphs(ctx, stm.InputGraphNames())
+	if err != nil {
+		return err
+	}
+	var tick <-chan time.Time
+	if changed == nil {
+		t := time.NewTicker(h.opts.PollInterval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	var last map[string]int
+	for {
+		// Statements cannot be planned twice, so the query is parsed again
+		// for every evaluation.
+		stm, err := parse(q)
+		if err != nil {
+			return err
+		}
+		pln, err := planner.New(ctx, h.store, stm, h.opts.ChanSize, h.opts.BulkSize, nil)
+		if err != nil {
+			return err
+		}
+		tbl, err := pln.Execute(ctx)
+		if err != nil {
+			return err
+		}
+		rows, err := encodeRows(tbl)
+		if err != nil {
+			return err
+		}
+		if added, removed := diffRows(last, rows); len(added)+len(removed) > 0 || last == nil {
+			now := time.Now()
+			u := &update{ID: id, Time: &now, Added: added, Removed: removed}
+			if last == nil {
+				u.Bindings = tbl.Bindings()
+			}
+			if err := send(u); err != nil {
+				return err
+			}
+		}
+		last = rows
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick:
+		case <-deleted:
+			return fmt.Errorf("a graph queried by %q was deleted", id)
+		case <-changed:
+			settle(ctx, changed)
+		}
+	}
+}
+
+// maintainView sends the changes of the results of the view as the changes of
+// the graph it queries are applied to them, until the context is done or an
+// error is found. It returns false if the graph does not provide a change
+// feed.
+func (h *Handler) maintainView(ctx context.Context, id string, v *bql.View, send func(*update) error) (bool, error) {
+	g, err := h.store.Graph(ctx, v.Graph())
+	if err != nil {
+		return true, err
+	}
+	// The graph is watched before computing the results, so no change is
+	// missed.
+	cs, err := storage.Watch(ctx, g)
+	if err == storage.ErrNoChangeFeed {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	tbl, err := v.Init(ctx, g)
+	if err != nil {
+		return true, err
+	}
+	rows, err := encodeRows(tbl)
+	if err != nil {
+		return true, err
+	}
+	now := time.Now()
+	u := &update{ID: id, Time: &now, Bindings: tbl.Bindings()}
+	u.Added, _ = diffRows(nil, rows)
+	if err := send(u); err != nil {
+		return true, err
+	}
+	for c := range cs {
+		tbl, err := v.Apply(ctx, c)
+		if err != nil {
+			return true, err
+		}
+		rows, err := encodeRows(tbl)
+		if err != nil {
+			return true, err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		now := time.Now()
+		u := &update{ID: id, Time: &now}
+		if c.Op == storage.Added {
+			u.Added, _ = diffRows(nil, rows)
+		} else {
+			_, u.Removed = diffRows(rows, nil)
+		}
+		if err := send(u); err != nil {
+			return true, err
+		}
+	}
+	// The change feed is closed when the context is done or the graph is
+	// deleted.
+	if ctx.Err() != nil {
+		return true, nil
+	}
+	return true, fmt.Errorf("a graph queried by %q was deleted", id)
+}
+
+// watchGraphs returns a channel that receives a value when any of the graphs
+// changes, and a channel that is closed if any of them is deleted. Changes
+// received while the value is not taken are coalesced. It returns nil
+// channels if any of the graphs does not provide a change feed.
+func (h *Handler) watchGraphs(ctx context.Context, names []string) (<-chan struct{}, <-chan struct{}, error) {
+	var feeds []<-chan *storage.Change
+	for _, name := range names {
+		g, err := h.store.Graph(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		cs, err := storage.Watch(ctx, g)
+		if err == storage.ErrNoChangeFeed {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		feeds = append(feeds, cs)
+	}
+	var once sync.Once
+	changed, deleted := make(chan struct{}, 1), make(chan struct{})
+	for _, cs := range feeds {
+		go func(cs <-chan *storage.Change) {
+			for range cs {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+			// Feeds are closed when the context is done or the graph is
+			// deleted; only the latter needs to be reported.
+			if ctx.Err() == nil {
+				once.Do(func() { close(deleted) })
+			}
+		}(cs)
+	}
+	return changed, deleted, nil
+}
+
+// settle waits for settleTime, or until the context is done, and drops the
+// changes received meanwhile.
+func settle(ctx context.Context, changed <-chan struct{}) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(settleTime):
+	}
+	select {
+	case <-changed:
+	default:
+	}
+}
+
+// encodeRows returns the number of times each row of the table appears, keyed
+// by its ndjson encoding.
+func encodeRows(tbl *table.Table) (map[string]int, error) {
+	var buf bytes.Buffer
+	rw, err := table.NewRowWriter(&buf, table.FormatNDJSON, tbl.Bindings())
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]int)
+	for _, r := range tbl.Rows() {
+		buf.Reset()
+		if err := rw.Write(r); err != nil {
+			return nil, err
+		}
+		if err := rw.Flush(); err != nil {
+			return nil, err
+		}
+		res[strings.TrimSpace(buf.String())]++
+	}
+	return res, nil
+}
+
+// diffRows returns the sorted rows added and removed between the provided
+// results.
+func diffRows(last, rows map[string]int) ([]json.RawMessage, []json.RawMessage) {
+	var added, removed []string
+	for r, n := range rows {
+		for i := last[r]; i < n; i++ {
+			added = append(added, r)
+		}
+	}
+	for r, n := range last {
+		for i := rows[r]; i < n; i++ {
+			removed = append(removed, r)
+		}
+	}
+	return rawMessages(added), rawMessages(removed)
+}
+
+// rawMessages sorts the provided JSON texts and returns them as raw messages.
+func rawMessages(ss []string) []json.RawMessage {
+	sort.Strings(ss)
+	var res []json.RawMessage
+	for _, s := range ss {
+		res = append(res, json.RawMessage(s))
+	}
+	return res
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// receive returns the next update sent through the connection.
+func receive(t *testing.T, ws *websocket.Conn) *update {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	u := &update{}
+	if err := websocket.JSON.Receive(ws, u); err != nil {
+		t.Fatalf("websocket.JSON.Receive failed with error %v", err)
+	}
+	return u
+}
+
+func rowStrings(rs []json.RawMessage) []string {
+	var res []string
+	for _, r := range rs {
+		res = append(res, string(r))
+	}
+	return res
+}
+
+func TestSubscribe(t *testing.T) {
+	h := newTestHandler(t)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/subscribe", "", srv.URL)
+	if err != nil {
+		t.Fatalf("websocket.Dial failed with error %v", err)
+	}
+	defer ws.Close()
+
+	q := `select ?c from ?family where {/u<peter> "parent_of"@[] ?c}`
+	if err := websocket.JSON.Send(ws, &subscribeRequest{ID: "children", Query: q}); err != nil {
+		t.Fatal(err)
+	}
+	u := receive(t, ws)
+	if got, want := strings.Join(u.Bindings, ","), "?c"; u.ID != "children" || got != want {
+		t.Errorf("first update has id %q and bindings %q; want %q and %q", u.ID, got, "children", want)
+	}
+	if got, want := strings.Join(rowStrings(u.Added), " "), `{"?c":{"node":"/u<john>"}}`; got != want || len(u.Removed) != 0 {
+		t.Errorf("first update added %s and removed %d rows; want %s and none", got, len(u.Removed), want)
+	}
+
+	if w := do(t, h, http.MethodPost, "/graphs/%3Ffamily/triples", "text/plain", `/u<peter> "parent_of"@[] /u<eve>`); w.Code != http.StatusOK {
+		t.Fatalf("POST /graphs/%%3Ffamily/triples returned %d; %s", w.Code, w.Body)
+	}
+	u = receive(t, ws)
+	if got, want := strings.Join(rowStrings(u.Added), " "), `{"?c":{"node":"/u<eve>"}}`; got != want || len(u.Removed) != 0 || u.Bindings != nil {
+		t.Errorf("update added %s and removed %d rows with bindings %v; want %s, none and none", got, len(u.Removed), u.Bindings, want)
+	}
+
+	// Queries joining clauses are evaluated again when their graphs change.
+	gq := `select ?g from ?family where {/u<joe> "parent_of"@[] ?c . ?c "parent_of"@[] ?g}`
+	if err := websocket.JSON.Send(ws, &subscribeRequest{ID: "grandchildren", Query: gq}); err != nil {
+		t.Fatal(err)
+	}
+	u = receive(t, ws)
+	if got, want := strings.Join(rowStrings(u.Added), " "), `{"?g":{"node":"/u<eve>"}} {"?g":{"node":"/u<john>"}}`; u.ID != "grandchildren" || got != want {
+		t.Errorf("first update of %q added %s; want %s", u.ID, got, want)
+	}
+	if w := do(t, h, http.MethodPost, "/query", "text/plain", `delete data from ?family {/u<peter> "parent_of"@[] /u<eve>}`); w.Code != http.StatusOK {
+		t.Fatalf("POST /query returned %d; %s", w.Code, w.Body)
+	}
+	for i := 0; i < 2; i++ {
+		u := receive(t, ws)
+		want := map[string]string{"children": `{"?c":{"node":"/u<eve>"}}`, "grandchildren": `{"?g":{"node":"/u<eve>"}}`}[u.ID]
+		if got := strings.Join(rowStrings(u.Removed), " "); got != want || len(u.Added) != 0 {
+			t.Errorf("update of %q removed %s and added %d rows; want %s and none", u.ID, got, len(u.Added), want)
+		}
+	}
+
+	table := []struct {
+		req  *subscribeRequest
+		want string
+	}{
+		{&subscribeRequest{ID: "children", Query: q}, "already registered"},
+		{&subscribeRequest{ID: "bad", Query: "select ?c from"}, "unexpected"},
+		{&subscribeRequest{ID: "create", Query: "create graph ?other"}, "only SELECT statements"},
+		{&subscribeRequest{ID: "missing", Query: "select ?s from ?missing where {?s ?p ?o}"}, "does not exist"},
+	}
+	for _, entry := range table {
+		if err := websocket.JSON.Send(ws, entry.req); err != nil {
+			t.Fatal(err)
+		}
+		if u := receive(t, ws); u.ID != entry.req.ID || !strings.Contains(u.Error, entry.want) {
+			t.Errorf("registering %q sent update %+v; want an error containing %q", entry.req.Query, u, entry.want)
+		}
+	}
+}
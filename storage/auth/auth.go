@@ -0,0 +1,644 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth enforces per graph permissions on the graphs of a store.
+//
+// Requests carry the principal making them in their context, as set by
+// NewContext. The store returned by NewStore asks an Authorizer whether the
+// principal of each call may read or write the graphs it accesses, and fails
+// with *ErrPermissionDenied otherwise. Listing the graphs of the store only
+// returns the ones the principal can read.
+package auth
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// Principal identifies who makes a request, such as a user or a service.
+type Principal struct {
+	// Name is the unique name of the principal.
+	Name string
+}
+
+// String returns the name of the principal, or anonymous for nil principals.
+func (p *Principal) String() string {
+	if p == nil {
+		return "anonymous"
+	}
+	return p.Name
+}
+
+// principalKey is the key of the principal in contexts.
+type principalKey struct{}
+
+// NewContext returns a copy of the provided context carrying the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal carried by the context, or nil if the
+// request is anonymous.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Permission is a set of operations allowed on a graph.
+type Permission uint8
+
+const (
+	// Read allows looking up the triples of a graph.
+	Read Permission = 1 << iota
+	// Write allows adding and removing triples, and creating and deleting the
+	// graph.
+	Write
+)
+
+// String returns a readable version of the permission, using r for Read and w
+// for Write.
+func (p Permission) String() string {
+	s := ""
+	if p&Read != 0 {
+		s += "r"
+	}
+	if p&Write != 0 {
+		s += "w"
+	}
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// ParsePermission returns the permission for the provided string, formed by r
+// for Read and w for Write, as in rw.
+func ParsePermission(s string) (Permission, error) {
+	var p Permission
+	for _, c := range s {
+		switch c {
+		case 'r':
+			p |= Read
+		case 'w':
+			p |= Write
+		default:
+			return 0, fmt.Errorf("auth.ParsePermission: invalid permission %q; use r, w, or rw", s)
+		}
+	}
+	if p == 0 {
+		return 0, fmt.Errorf("auth.ParsePermission: empty permission")
+	}
+	return p, nil
+}
+
+// Authorizer decides the permissions of principals on graphs.
+type Authorizer interface {
+	// Allowed returns true if the principal, which is nil for anonymous
+	// requests, has all the provided permissions on the graph with the
+	// provided ID.
+	Allowed(ctx context.Context, p *Principal, graph string, perm Permission) (bool, error)
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface.
+type AuthorizerFunc func(ctx context.Context, p *Principal, graph string, perm Permission) (bool, error)
+
+// Allowed calls the function.
+func (f AuthorizerFunc) Allowed(ctx context.Context, p *Principal, graph string, perm Permission) (bool, error) {
+	return f(ctx, p, graph, perm)
+}
+
+// ErrPermissionDenied is the error returned when a principal does not have
+// the permission required by an operation on a graph.
+type ErrPermissionDenied struct {
+	Principal  *Principal
+	Graph      string
+	Permission Permission
+}
+
+// Error returns a readable description of the denied permission.
+func (e *ErrPermissionDenied) Error() string {
+	return fmt.Sprintf("auth: %s does not have permission %s on graph %q", e.Principal, e.Permission, e.Graph)
+}
+
+// Check returns nil if the principal of the context has the provided
+// permissions on the graph, and *ErrPermissionDenied if it does not.
+func Check(ctx context.Context, a Authorizer, graph string, perm Permission) error {
+	p := FromContext(ctx)
+	ok, err := a.Allowed(ctx, p, graph, perm)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &ErrPermissionDenied{Principal: p, Graph: graph, Permission: perm}
+	}
+	return nil
+}
+
+// Any matches any principal, including anonymous ones, or any graph in the
+// rules of an ACL.
+const Any = "*"
+
+// Rule grants permissions on a graph to a principal.
+type Rule struct {
+	// Principal is the name of the principal the rule applies to. Any applies
+	// it to all principals, and an empty name to anonymous requests only.
+	Principal string
+
+	// Graph is the ID of the graph the rule applies to, or Any.
+	Graph string
+
+	// Permission is the set of permissions granted.
+	Permission Permission
+}
+
+// ACL is an Authorizer granting the permissions of its rules. Permissions not
+// granted by any rule are denied.
+type ACL []*Rule
+
+// Allowed returns true if the rules matching the principal and the graph
+// grant all the provided permissions.
+func (acl ACL) Allowed(ctx context.Context, p *Principal, graph string, perm Permission) (bool, error) {
+	name := ""
+	if p != nil {
+		name = p.Name
+	}
+	var granted Permission
+	for _, r := range acl {
+		if (r.Principal == Any || r.Principal == name) && (r.Graph == Any || r.Graph == graph) {
+			granted |= r.Permission
+		}
+	}
+	return granted&perm == perm, nil
+}
+
+// ParseACL reads the rules of an ACL, one per line. Each rule is formed by the
+// principal, the graph, and the permissions, separated by spaces, as in
+// "alice ?family rw". The principal and the graph can be *, and the principal
+// - stands for anonymous requests. Empty lines and lines starting with # are
+// ignored.
+func ParseACL(r io.Reader) (ACL, error) {
+	acl := ACL{}
+	s := bufio.NewScanner(r)
+	for ln := 1; s.Scan(); ln++ {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		fs := strings.Fields(l)
+		if len(fs) != 3 {
+			return nil, fmt.Errorf("auth.ParseACL: line %d: expected a principal, a graph, and the permissions; got %q", ln, l)
+		}
+		p, err := ParsePermission(fs[2])
+		if err != nil {
+			return nil, fmt.Errorf("auth.ParseACL: line %d: %v", ln, err)
+		}
+		if fs[0] == "-" {
+			fs[0] = ""
+		}
+		acl = append(acl, &Rule{Principal: fs[0], Graph: fs[1], Permission: p})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+// NewStore returns a store enforcing the permissions decided by the provided
+// authorizer on the graphs of the provided store, based on the principal of
+// the context of each call.
+//
+// Creating and deleting graphs requires Write, and accessing them either Read
+// or Write. Lookups then require Read, and adding or removing triples Write.
+// Listing the graphs of the store only returns the ones the principal can
+// read. Quotas follow the permissions of their graph. Blobs and the literal
+// size limit are shared by all graphs, so changing them requires Write, and
+// reading blobs Read, on every graph, as granted by Any.
+// Transactions and bulk loads of the underlying graphs are not exposed, since
+// they would not check the permissions.
+func NewStore(s storage.Store, a Authorizer) storage.Store {
+	return &store{s: s, a: a}
+}
+
+// store enforces the permissions of the graphs of the underlying store.
+type store struct {
+	s storage.Store
+	a Authorizer
+}
+
+// Name returns the ID of the backend being used.
+func (s *store) Name(ctx context.Context) string {
+	return s.s.Name(ctx)
+}
+
+// Version returns the version of the driver implementation.
+func (s *store) Version(ctx context.Context) string {
+	return s.s.Version(ctx)
+}
+
+// NewGraph creates a new graph if the principal can write it.
+func (s *store) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	if err := Check(ctx, s.a, id, Write); err != nil {
+		return nil, err
+	}
+	g, err := s.s.NewGraph(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &graph{Graph: g, a: s.a}, nil
+}
+
+// Graph returns an existing graph if the principal can read or write it.
+func (s *store) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	if err := Check(ctx, s.a, id, Read); err != nil {
+		if werr := Check(ctx, s.a, id, Write); werr != nil {
+			return nil, err
+		}
+	}
+	g, err := s.s.Graph(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &graph{Graph: g, a: s.a}, nil
+}
+
+// DeleteGraph deletes an existing graph if the principal can write it.
+func (s *store) DeleteGraph(ctx context.Context, id string) error {
+	if err := Check(ctx, s.a, id, Write); err != nil {
+		return err
+	}
+	return s.s.DeleteGraph(ctx, id)
+}
+
+// GraphNames returns the IDs of the graphs the principal can read.
+func (s *store) GraphNames(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(names)
+	errs, ids := make(chan error, 1), make(chan string)
+	go func() {
+		errs <- s.s.GraphNames(ctx, ids)
+	}()
+	var all []string
+	for id := range ids {
+		all = append(all, id)
+	}
+	if err := <-errs; err != nil {
+		return err
+	}
+	p := FromContext(ctx)
+	for _, id := range all {
+		ok, err := s.a.Allowed(ctx, p, id, Read)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case names <- id:
+		}
+	}
+	return nil
+}
+
+// GraphMetadata returns the metadata of the graph if the principal can read
+// it.
+func (s *store) GraphMetadata(ctx context.Context, id string) (*storage.GraphMetadata, error) {
+	if err := Check(ctx, s.a, id, Read); err != nil {
+		return nil, err
+	}
+	return storage.GetGraphMetadata(ctx, s.s, id)
+}
+
+// SetGraphMetadata replaces the description and labels of the graph if the
+// principal can write it.
+func (s *store) SetGraphMetadata(ctx context.Context, id string, md *storage.GraphMetadata) error {
+	if err := Check(ctx, s.a, id, Write); err != nil {
+		return err
+	}
+	return storage.SetGraphMetadata(ctx, s.s, id, md)
+}
+
+// GraphQuota returns the quota of the graph if the principal can read it.
+func (s *store) GraphQuota(ctx context.Context, id string) (*storage.GraphQuota, error) {
+	if err := Check(ctx, s.a, id, Read); err != nil {
+		return nil, err
+	}
+	return storage.GetGraphQuota(ctx, s.s, id)
+}
+
+// SetGraphQuota replaces the quota of the graph if the principal can write
+// it.
+func (s *store) SetGraphQuota(ctx context.Context, id string, q *storage.GraphQuota) error {
+	if err := Check(ctx, s.a, id, Write); err != nil {
+		return err
+	}
+	return storage.SetGraphQuota(ctx, s.s, id, q)
+}
+
+// LiteralLimit returns the literal size limit of the underlying store.
+func (s *store) LiteralLimit(ctx context.Context) int {
+	return storage.GetLiteralLimit(ctx, s.s)
+}
+
+// SetLiteralLimit sets the literal size limit of the underlying store if the
+// principal can write all graphs.
+func (s *store) SetLiteralLimit(ctx context.Context, max int) error {
+	if err := Check(ctx, s.a, Any, Write); err != nil {
+		return err
+	}
+	return storage.SetLiteralLimit(ctx, s.s, max)
+}
+
+// WriteBlob stores the contents read from r if the principal can write all
+// graphs.
+func (s *store) WriteBlob(ctx context.Context, r io.Reader) (*literal.Literal, error) {
+	if err := Check(ctx, s.a, Any, Write); err != nil {
+		return nil, err
+	}
+	return storage.WriteBlob(ctx, s.s, r)
+}
+
+// ReadBlob returns a reader streaming the contents of the referenced blob if
+// the principal can read all graphs.
+func (s *store) ReadBlob(ctx context.Context, ref *literal.Literal) (io.ReadCloser, error) {
+	if err := Check(ctx, s.a, Any, Read); err != nil {
+		return nil, err
+	}
+	return storage.ReadBlob(ctx, s.s, ref)
+}
+
+// DeleteBlob deletes the contents of the referenced blob if the principal can
+// write all graphs.
+func (s *store) DeleteBlob(ctx context.Context, ref *literal.Literal) error {
+	if err := Check(ctx, s.a, Any, Write); err != nil {
+		return err
+	}
+	return storage.DeleteBlob(ctx, s.s, ref)
+}
+
+// graph enforces the permissions of a graph of the underlying store. Lookups
+// close the provided channel when denied, as the graphs do when done.
+type graph struct {
+	storage.Graph
+	a Authorizer
+}
+
+// check returns nil if the principal of the context has the provided
+// permissions on the graph.
+func (g *graph) check(ctx context.Context, perm Permission) error {
+	return Check(ctx, g.a, g.Graph.ID(ctx), perm)
+}
+
+// AddTriples adds the triples to the graph if the principal can write it.
+func (g *graph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	if err := g.check(ctx, Write); err != nil {
+		return err
+	}
+	return g.Graph.AddTriples(ctx, ts)
+}
+
+// RemoveTriples removes the triples from the graph if the principal can write
+// it.
+func (g *graph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	if err := g.check(ctx, Write); err != nil {
+		return err
+	}
+	return g.Graph.RemoveTriples(ctx, ts)
+}
+
+// Objects pushes the objects for the subject and the predicate if the
+// principal can read the graph.
+func (g *graph) Objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(objs)
+		return err
+	}
+	return g.Graph.Objects(ctx, s, p, lo, objs)
+}
+
+// Subjects pushes the subjects for the predicate and the object if the
+// principal can read the graph.
+func (g *graph) Subjects(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, subs chan<- *node.Node) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(subs)
+		return err
+	}
+	return g.Graph.Subjects(ctx, p, o, lo, subs)
+}
+
+// PredicatesForSubject pushes the predicates for the subject if the principal
+// can read the graph.
+func (g *graph) PredicatesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(prds)
+		return err
+	}
+	return g.Graph.PredicatesForSubject(ctx, s, lo, prds)
+}
+
+// PredicatesForObject pushes the predicates for the object if the principal
+// can read the graph.
+func (g *graph) PredicatesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(prds)
+		return err
+	}
+	return g.Graph.PredicatesForObject(ctx, o, lo, prds)
+}
+
+// PredicatesForSubjectAndObject pushes the predicates for the subject and the
+// object if the principal can read the graph.
+func (g *graph) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(prds)
+		return err
+	}
+	return g.Graph.PredicatesForSubjectAndObject(ctx, s, o, lo, prds)
+}
+
+// TriplesForSubject pushes the triples for the subject if the principal can
+// read the graph.
+func (g *graph) TriplesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(trpls)
+		return err
+	}
+	return g.Graph.TriplesForSubject(ctx, s, lo, trpls)
+}
+
+// TriplesForPredicate pushes the triples for the predicate if the principal
+// can read the graph.
+func (g *graph) TriplesForPredicate(ctx context.Context, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(trpls)
+		return err
+	}
+	return g.Graph.TriplesForPredicate(ctx, p, lo, trpls)
+}
+
+// TriplesForObject pushes the triples for the object if the principal can
+// read the graph.
+func (g *graph) TriplesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(trpls)
+		return err
+	}
+	return g.Graph.TriplesForObject(ctx, o, lo, trpls)
+}
+
+// TriplesForSubjectAndPredicate pushes the triples for the subject and the
+// predicate if the principal can read the graph.
+func (g *graph) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(trpls)
+		return err
+	}
+	return g.Graph.TriplesForSubjectAndPredicate(ctx, s, p, lo, trpls)
+}
+
+// TriplesForPredicateAndObject pushes the triples for the predicate and the
+// object if the principal can read the graph.
+func (g *graph) TriplesForPredicateAndObject(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(trpls)
+		return err
+	}
+	return g.Graph.TriplesForPredicateAndObject(ctx, p, o, lo, trpls)
+}
+
+// Exist checks if the triple is in the graph if the principal can read it.
+func (g *graph) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
+	if err := g.check(ctx, Read); err != nil {
+		return false, err
+	}
+	return g.Graph.Exist(ctx, t)
+}
+
+// Triples pushes all the triples of the graph if the principal can read it.
+func (g *graph) Triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if err := g.check(ctx, Read); err != nil {
+		close(trpls)
+		return err
+	}
+	return g.Graph.Triples(ctx, lo, trpls)
+}
+
+// CountTriples returns the number of triples in the graph if the principal
+// can read it.
+func (g *graph) CountTriples(ctx context.Context) (int64, error) {
+	if err := g.check(ctx, Read); err != nil {
+		return 0, err
+	}
+	return storage.CountTriples(ctx, g.Graph)
+}
+
+// Statistics returns the statistics of the graph if the principal can read
+// it.
+func (g *graph) Statistics(ctx context.Context) (*storage.GraphStatistics, error) {
+	if err := g.check(ctx, Read); err != nil {
+		return nil, err
+	}
+	return storage.Statistics(ctx, g.Graph)
+}
+
+// Epoch returns the epoch of the graph if the principal can read it.
+func (g *graph) Epoch(ctx context.Context) (uint64, error) {
+	if err := g.check(ctx, Read); err != nil {
+		return 0, err
+	}
+	return storage.Epoch(ctx, g.Graph)
+}
+
+// PredicateIDs returns the sorted list of unique predicate IDs used in the
+// graph if the principal can read it.
+func (g *graph) PredicateIDs(ctx context.Context) ([]string, error) {
+	if err := g.check(ctx, Read); err != nil {
+		return nil, err
+	}
+	return storage.PredicateIDs(ctx, g.Graph)
+}
+
+// Watch returns the changes done to the graph if the principal can read it.
+func (g *graph) Watch(ctx context.Context) (<-chan *storage.Change, error) {
+	if err := g.check(ctx, Read); err != nil {
+		return nil, err
+	}
+	return storage.Watch(ctx, g.Graph)
+}
+
+// Snapshot returns a snapshot of the graph if the principal can read it.
+func (g *graph) Snapshot(ctx context.Context) (storage.GraphSnapshot, error) {
+	if err := g.check(ctx, Read); err != nil {
+		return nil, err
+	}
+	return storage.Snapshot(ctx, g.Graph)
+}
+
+// Expire removes the temporal triples of the graph anchored before the
+// provided time if the principal can write it.
+func (g *graph) Expire(ctx context.Context, before time.Time, predicates []string) (int64, error) {
+	if err := g.check(ctx, Write); err != nil {
+		return 0, err
+	}
+	return storage.Expire(ctx, g.Graph, &storage.RetentionPolicy{Predicates: predicates}, before)
+}
+
+// Compact compacts the graph if the principal can write it.
+func (g *graph) Compact(ctx context.Context) error {
+	if err := g.check(ctx, Write); err != nil {
+		return err
+	}
+	return storage.Compact(ctx, g.Graph)
+}
+
+// NearestObjects returns the vector objects of the predicate most similar to
+// the provided vector if the principal can read the graph.
+func (g *graph) NearestObjects(ctx context.Context, id string, q []float64, k int) ([]*triple.Object, error) {
+	if err := g.check(ctx, Read); err != nil {
+		return nil, err
+	}
+	return storage.NearestObjects(ctx, g.Graph, id, q, k)
+}
+
+// IndexVectors indexes the vectors of the predicate if the principal can
+// write the graph.
+func (g *graph) IndexVectors(ctx context.Context, id string) error {
+	if err := g.check(ctx, Write); err != nil {
+		return err
+	}
+	return storage.IndexVectors(ctx, g.Graph, id)
+}
+
+// DropVectorIndex removes the index of the vectors of the predicate if the
+// principal can write the graph.
+func (g *graph) DropVectorIndex(ctx context.Context, id string) error {
+	if err := g.check(ctx, Write); err != nil {
+		return err
+	}
+	return storage.DropVectorIndex(ctx, g.Graph, id)
+}
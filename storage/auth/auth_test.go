@@ -0,0 +1,353 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+const testACL = `
+# Alice owns the family graph, and everyone can read the public one.
+alice ?family rw
+*     ?public r
+admin *       rw
+-     ?guest  r
+`
+
+func getTestTriples(t *testing.T) []*triple.Triple {
+	trpl, err := triple.Parse("/u<john>\t\"knows\"@[]\t/u<mary>", literal.DefaultBuilder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*triple.Triple{trpl}
+}
+
+func getTestACL(t *testing.T) ACL {
+	acl, err := ParseACL(strings.NewReader(testACL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acl
+}
+
+func principal(name string) *Principal {
+	if name == "" {
+		return nil
+	}
+	return &Principal{Name: name}
+}
+
+func TestParsePermission(t *testing.T) {
+	testTable := []struct {
+		s    string
+		want Permission
+		str  string
+	}{
+		{"r", Read, "r"},
+		{"w", Write, "w"},
+		{"rw", Read | Write, "rw"},
+		{"wr", Read | Write, "rw"},
+	}
+	for _, entry := range testTable {
+		got, err := ParsePermission(entry.s)
+		if err != nil || got != entry.want {
+			t.Errorf("ParsePermission(%q) returned %v, %v; want %v", entry.s, got, err, entry.want)
+		}
+		if got.String() != entry.str {
+			t.Errorf("Permission(%d).String() returned %q; want %q", got, got.String(), entry.str)
+		}
+	}
+	for _, s := range []string{"", "x", "rx"} {
+		if _, err := ParsePermission(s); err == nil {
+			t.Errorf("ParsePermission(%q) should have failed", s)
+		}
+	}
+}
+
+func TestParseACL(t *testing.T) {
+	want := ACL{
+		{Principal: "alice", Graph: "?family", Permission: Read | Write},
+		{Principal: Any, Graph: "?public", Permission: Read},
+		{Principal: "admin", Graph: Any, Permission: Read | Write},
+		{Principal: "", Graph: "?guest", Permission: Read},
+	}
+	if got := getTestACL(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseACL returned %v; want %v", got, want)
+	}
+	for _, s := range []string{"alice ?family", "alice ?family rw extra", "alice ?family x"} {
+		if _, err := ParseACL(strings.NewReader(s)); err == nil {
+			t.Errorf("ParseACL(%q) should have failed", s)
+		}
+	}
+}
+
+func TestACLAllowed(t *testing.T) {
+	ctx, acl := context.Background(), getTestACL(t)
+	testTable := []struct {
+		principal string
+		graph     string
+		perm      Permission
+		want      bool
+	}{
+		{"alice", "?family", Read, true},
+		{"alice", "?family", Read | Write, true},
+		{"alice", "?public", Read, true},
+		{"alice", "?public", Write, false},
+		{"alice", "?guest", Read, false},
+		{"bob", "?family", Read, false},
+		{"bob", "?public", Read, true},
+		{"admin", "?anything", Read | Write, true},
+		{"", "?guest", Read, true},
+		{"", "?public", Read, true},
+		{"", "?family", Read, false},
+		{"", "?guest", Write, false},
+	}
+	for _, entry := range testTable {
+		got, err := acl.Allowed(ctx, principal(entry.principal), entry.graph, entry.perm)
+		if err != nil || got != entry.want {
+			t.Errorf("acl.Allowed(%q, %q, %v) returned %v, %v; want %v", entry.principal, entry.graph, entry.perm, got, err, entry.want)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if p := FromContext(ctx); p != nil {
+		t.Errorf("FromContext returned %v for a context without principal", p)
+	}
+	want := &Principal{Name: "alice"}
+	if got := FromContext(NewContext(ctx, want)); got != want {
+		t.Errorf("FromContext returned %v; want %v", got, want)
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	ms := memory.NewStore()
+	for _, id := range []string{"?family", "?public", "?guest", "?secret"} {
+		g, err := ms.NewGraph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, getTestTriples(t)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewStore(ms, getTestACL(t))
+	alice := NewContext(ctx, &Principal{Name: "alice"})
+	bob := NewContext(ctx, &Principal{Name: "bob"})
+
+	// Graph names are filtered by the principal.
+	testNames := []struct {
+		ctx  context.Context
+		want []string
+	}{
+		{alice, []string{"?family", "?public"}},
+		{bob, []string{"?public"}},
+		{ctx, []string{"?guest", "?public"}},
+		{NewContext(ctx, &Principal{Name: "admin"}), []string{"?family", "?guest", "?public", "?secret"}},
+	}
+	for _, entry := range testNames {
+		names := make(chan string)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.GraphNames(entry.ctx, names)
+		}()
+		var got []string
+		for n := range names {
+			got = append(got, n)
+		}
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("GraphNames for %v returned %v; want %v", FromContext(entry.ctx), got, entry.want)
+		}
+	}
+
+	// Creating and deleting graphs requires Write.
+	if _, err := s.NewGraph(bob, "?family2"); err == nil {
+		t.Error("NewGraph should have failed for bob")
+	} else if _, ok := err.(*ErrPermissionDenied); !ok {
+		t.Errorf("NewGraph returned %T; want *ErrPermissionDenied", err)
+	}
+	if err := s.DeleteGraph(bob, "?family"); err == nil {
+		t.Error("DeleteGraph should have failed for bob")
+	}
+	if _, err := s.Graph(bob, "?family"); err == nil {
+		t.Error("Graph should have failed for bob")
+	}
+	if _, err := ms.Graph(ctx, "?family"); err != nil {
+		t.Errorf("the graph ?family should still exist; %v", err)
+	}
+
+	// Lookups require Read and changes Write.
+	g, err := s.Graph(bob, "?public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trpls := make(chan *triple.Triple)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- g.Triples(bob, storage.DefaultLookup, trpls)
+	}()
+	n := 0
+	for range trpls {
+		n++
+	}
+	if err := <-errs; err != nil || n != 1 {
+		t.Errorf("g.Triples returned %d triples, %v; want 1 triple", n, err)
+	}
+	if err := g.AddTriples(bob, getTestTriples(t)); err == nil {
+		t.Error("AddTriples should have failed for bob on ?public")
+	}
+	if err := g.RemoveTriples(bob, getTestTriples(t)); err == nil {
+		t.Error("RemoveTriples should have failed for bob on ?public")
+	}
+
+	g, err = s.Graph(alice, "?family")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The principal of each call is checked, not the one getting the graph.
+	trpls = make(chan *triple.Triple)
+	if err := g.Triples(bob, storage.DefaultLookup, trpls); err == nil {
+		t.Error("g.Triples should have failed for bob on ?family")
+	}
+	if _, ok := <-trpls; ok {
+		t.Error("g.Triples should have closed the channel when denied")
+	}
+	if err := g.RemoveTriples(alice, getTestTriples(t)); err != nil {
+		t.Errorf("RemoveTriples failed for alice on ?family; %v", err)
+	}
+	if n, err := storage.CountTriples(alice, g); err != nil || n != 0 {
+		t.Errorf("storage.CountTriples returned %d, %v; want 0", n, err)
+	}
+	if err := s.DeleteGraph(alice, "?family"); err != nil {
+		t.Errorf("DeleteGraph failed for alice on ?family; %v", err)
+	}
+}
+
+func TestStoreOptionalInterfaces(t *testing.T) {
+	ctx := context.Background()
+	ms := memory.NewStore()
+	mg, err := ms.NewGraph(ctx, "?family")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sn, err := node.Parse("/item<a>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := predicate.NewImmutable("embedding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := literal.DefaultBuilder().Build(literal.VectorType, []float64{1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trpl, err := triple.New(sn, p, triple.NewLiteralObject(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mg.AddTriples(ctx, []*triple.Triple{trpl}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(ms, getTestACL(t))
+	alice := NewContext(ctx, &Principal{Name: "alice"})
+	bob := NewContext(ctx, &Principal{Name: "bob"})
+	admin := NewContext(ctx, &Principal{Name: "admin"})
+
+	// Vector searches require Read and indexing Write.
+	g, err := s.Graph(alice, "?family")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.IndexVectors(bob, g, "embedding"); err == nil {
+		t.Error("storage.IndexVectors should have failed for bob on ?family")
+	}
+	if err := storage.IndexVectors(alice, g, "embedding"); err != nil {
+		t.Fatalf("storage.IndexVectors failed for alice on ?family; %v", err)
+	}
+	if _, err := storage.NearestObjects(bob, g, "embedding", []float64{1, 1}, 1); err == nil {
+		t.Error("storage.NearestObjects should have failed for bob on ?family")
+	}
+	os, err := storage.NearestObjects(alice, g, "embedding", []float64{1, 1}, 1)
+	if err != nil || len(os) != 1 || !reflect.DeepEqual(os[0], trpl.Object()) {
+		t.Errorf("storage.NearestObjects returned %v, %v; want [%v]", os, err, trpl.Object())
+	}
+	if err := storage.DropVectorIndex(bob, g, "embedding"); err == nil {
+		t.Error("storage.DropVectorIndex should have failed for bob on ?family")
+	}
+
+	// Quotas follow the permissions of their graph.
+	q := &storage.GraphQuota{MaxTriples: 10}
+	if err := storage.SetGraphQuota(bob, s, "?family", q); err == nil {
+		t.Error("storage.SetGraphQuota should have failed for bob on ?family")
+	}
+	if err := storage.SetGraphQuota(alice, s, "?family", q); err != nil {
+		t.Errorf("storage.SetGraphQuota failed for alice on ?family; %v", err)
+	}
+	if got, err := storage.GetGraphQuota(alice, s, "?family"); err != nil || !reflect.DeepEqual(got, q) {
+		t.Errorf("storage.GetGraphQuota returned %v, %v; want %v", got, err, q)
+	}
+
+	// Blobs and the literal size limit are shared by all graphs.
+	if _, err := storage.WriteBlob(alice, s, strings.NewReader("blob")); err == nil {
+		t.Error("storage.WriteBlob should have failed for alice")
+	}
+	ref, err := storage.WriteBlob(admin, s, strings.NewReader("blob"))
+	if err != nil {
+		t.Fatalf("storage.WriteBlob failed for admin; %v", err)
+	}
+	if _, err := storage.ReadBlob(alice, s, ref); err == nil {
+		t.Error("storage.ReadBlob should have failed for alice")
+	}
+	r, err := storage.ReadBlob(admin, s, ref)
+	if err != nil {
+		t.Fatalf("storage.ReadBlob failed for admin; %v", err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(b) != "blob" {
+		t.Errorf("storage.ReadBlob returned %q, %v; want \"blob\"", b, err)
+	}
+	if err := storage.DeleteBlob(alice, s, ref); err == nil {
+		t.Error("storage.DeleteBlob should have failed for alice")
+	}
+	if err := storage.SetLiteralLimit(alice, s, 100); err == nil {
+		t.Error("storage.SetLiteralLimit should have failed for alice")
+	}
+	if err := storage.SetLiteralLimit(admin, s, 100); err != nil {
+		t.Errorf("storage.SetLiteralLimit failed for admin; %v", err)
+	}
+	if got := storage.GetLiteralLimit(alice, s); got != 100 {
+		t.Errorf("storage.GetLiteralLimit returned %d; want 100", got)
+	}
+}
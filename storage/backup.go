@@ -0,0 +1,266 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// backupHeader identifies the format and version of a graph archive.
+const backupHeader = "# badwolf graph archive v1"
+
+// BackupCheckpoint is the number of triples between two checkpoints of a
+// graph archive. Restore adds the triples between checkpoints in a single
+// call to AddTriples.
+const BackupCheckpoint = 1000
+
+// Backup writes an archive of the graph with the provided ID to w. If the
+// graph provides snapshots, the archive contains the triples of a snapshot
+// taken when Backup is called, so the graph can keep changing while it is
+// written; otherwise, changes applied while writing the archive may or may not
+// be included. The metadata of the graph is included if the store keeps it.
+//
+// Archives are text. They start with a header and the metadata of the graph,
+// followed by one triple per line in the same format read by
+// triple.Parse, a checkpoint line every BackupCheckpoint triples, and a
+// trailer with the total number of triples, which allows Restore to detect
+// truncated archives.
+func Backup(ctx context.Context, s Store, id string, w io.Writer) error {
+	g, err := s.Graph(ctx, id)
+	if err != nil {
+		return err
+	}
+	snap, err := Snapshot(ctx, g)
+	switch err {
+	case nil:
+		defer snap.Release(ctx)
+		g = snap
+	case ErrNoSnapshots:
+	default:
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n# graph %s\n", backupHeader, strconv.Quote(id))
+	md, err := GetGraphMetadata(ctx, s, id)
+	switch err {
+	case nil:
+		fmt.Fprintf(bw, "# created %s\n", md.Created.Format(time.RFC3339Nano))
+		fmt.Fprintf(bw, "# description %s\n", strconv.Quote(md.Description))
+		var ks []string
+		for k := range md.Labels {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		for _, k := range ks {
+			fmt.Fprintf(bw, "# label %s %s\n", strconv.Quote(k), strconv.Quote(md.Labels[k]))
+		}
+	case ErrNoMetadata:
+	default:
+		return err
+	}
+
+	var (
+		cnt  int64
+		wErr error
+	)
+	err = scanTriples(ctx, g, func(t *triple.Triple) {
+		if wErr != nil {
+			return
+		}
+		l := t.String()
+		if strings.ContainsAny(l, "\r\n") {
+			wErr = fmt.Errorf("storage.Backup: cannot archive triple %q spanning several lines", l)
+			return
+		}
+		bw.WriteString(l)
+		bw.WriteByte('\n')
+		if cnt++; cnt%BackupCheckpoint == 0 {
+			fmt.Fprintf(bw, "# checkpoint %d\n", cnt)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if wErr != nil {
+		return wErr
+	}
+	fmt.Fprintf(bw, "# end %d\n", cnt)
+	return bw.Flush()
+}
+
+// RestoreOptions configures how Restore reads an archive.
+type RestoreOptions struct {
+	// Resume is the number of triples of the archive already restored by an
+	// interrupted call to Restore, as reported by Progress. If it is zero, the
+	// graph is created; otherwise, the graph must exist and the first Resume
+	// triples of the archive are skipped.
+	Resume int64
+
+	// Progress, if set, is called with the total number of triples of the
+	// archive restored every time a checkpoint is reached.
+	Progress func(restored int64)
+}
+
+// Restore creates the graph with the provided ID out of an archive written by
+// Backup, and returns the number of triples in the archive. The description
+// and labels of the archived graph are restored if the store keeps graph
+// metadata; the creation time is not, since it is set by the store.
+//
+// If Restore fails, the triples added up to the last reported checkpoint are
+// kept, and the restore can be resumed from there using opts.Resume. Archives
+// missing their trailer are rejected once all their triples are added.
+func Restore(ctx context.Context, s Store, id string, r io.Reader, opts *RestoreOptions) (int64, error) {
+	if opts == nil {
+		opts = &RestoreOptions{}
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<24)
+	if !sc.Scan() || sc.Text() != backupHeader {
+		if err := sc.Err(); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("storage.Restore: the input is not a graph archive")
+	}
+
+	var (
+		g      Graph
+		err    error
+		md     = &GraphMetadata{}
+		hasMD  bool
+		cnt    int64
+		batch  []*triple.Triple
+		ending = int64(-1)
+	)
+	open := func() error {
+		if g != nil {
+			return nil
+		}
+		if opts.Resume > 0 {
+			g, err = s.Graph(ctx, id)
+			return err
+		}
+		if g, err = s.NewGraph(ctx, id); err != nil {
+			return err
+		}
+		if hasMD {
+			if err := SetGraphMetadata(ctx, s, id, md); err != nil && err != ErrNoMetadata {
+				return err
+			}
+		}
+		return nil
+	}
+	flush := func() error {
+		if err := open(); err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			if err := g.AddTriples(ctx, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+		if opts.Progress != nil && cnt > opts.Resume {
+			opts.Progress(cnt)
+		}
+		return nil
+	}
+
+	for line := 2; sc.Scan(); line++ {
+		if err := ctx.Err(); err != nil {
+			return cnt, err
+		}
+		text := sc.Text()
+		if ending >= 0 {
+			return cnt, fmt.Errorf("storage.Restore: unexpected content after the end of the archive at line %d", line)
+		}
+		if !strings.HasPrefix(text, "# ") {
+			if cnt++; cnt <= opts.Resume {
+				continue
+			}
+			t, err := triple.Parse(text, literal.DefaultBuilder())
+			if err != nil {
+				return cnt, fmt.Errorf("storage.Restore: invalid triple at line %d; %v", line, err)
+			}
+			batch = append(batch, t)
+			continue
+		}
+		kw, arg := text[2:], ""
+		if i := strings.Index(kw, " "); i >= 0 {
+			kw, arg = kw[:i], kw[i+1:]
+		}
+		switch kw {
+		case "graph":
+		case "created":
+			// The creation time is set by the store.
+		case "description":
+			if md.Description, err = strconv.Unquote(arg); err != nil {
+				return cnt, fmt.Errorf("storage.Restore: invalid description at line %d; %v", line, err)
+			}
+			hasMD = true
+		case "label":
+			k, err := strconv.QuotedPrefix(arg)
+			if err != nil || len(arg) < len(k)+1 {
+				return cnt, fmt.Errorf("storage.Restore: invalid label at line %d", line)
+			}
+			uk, err := strconv.Unquote(k)
+			if err != nil {
+				return cnt, fmt.Errorf("storage.Restore: invalid label at line %d; %v", line, err)
+			}
+			v, err := strconv.Unquote(arg[len(k)+1:])
+			if err != nil {
+				return cnt, fmt.Errorf("storage.Restore: invalid label at line %d; %v", line, err)
+			}
+			if md.Labels == nil {
+				md.Labels = make(map[string]string)
+			}
+			md.Labels[uk] = v
+			hasMD = true
+		case "checkpoint", "end":
+			n, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || n != cnt {
+				return cnt, fmt.Errorf("storage.Restore: the archive %s at line %d does not match the %d triples read", kw, line, cnt)
+			}
+			if err := flush(); err != nil {
+				return cnt, err
+			}
+			if kw == "end" {
+				ending = n
+			}
+		default:
+			return cnt, fmt.Errorf("storage.Restore: unknown archive directive %q at line %d", kw, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return cnt, err
+	}
+	if ending < 0 {
+		if err := flush(); err != nil {
+			return cnt, err
+		}
+		return cnt, fmt.Errorf("storage.Restore: the archive is truncated after %d triples", cnt)
+	}
+	return cnt, nil
+}
@@ -0,0 +1,300 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package badger provides a persistent implementation of the storage.Store and
+// storage.Graph interfaces backed by a Badger database, tuned for the high
+// rate ingestion of temporal triples.
+//
+// Badger is a log-structured merge tree, so the driver avoids reads on the
+// write path: triples are added and removed using write batches, and every
+// index entry holds a copy of the triple, so lookups are a single sequential
+// scan. The keys of each index are ordered by graph, predicate, and time
+// anchor, so triples of the same predicate ingested over time are stored
+// together and time bounded lookups only scan the requested window.
+package badger
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/dgraph-io/badger"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/driver"
+	"github.com/pborman/uuid"
+)
+
+// Key prefixes.
+const (
+	// catalogPrefix prefixes the keys listing the graphs. Their values contain
+	// the current epoch of the graph.
+	catalogPrefix = 'c'
+	// dataPrefix prefixes the keys containing the triples of the graphs.
+	dataPrefix = 'd'
+)
+
+// epochKey is the key of the sequence providing the epochs of all graphs.
+var epochKey = []byte("epoch")
+
+// Store provides a persistent store backed by a Badger database.
+type Store struct {
+	db  *badger.DB
+	seq *badger.Sequence
+}
+
+// NewStore opens the Badger database in the provided directory, creating it
+// if needed.
+func NewStore(dir string) (*Store, error) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		return nil, fmt.Errorf("badger.NewStore(%q): %v", dir, err)
+	}
+	seq, err := db.GetSequence(epochKey, 1000)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("badger.NewStore(%q): %v", dir, err)
+	}
+	return &Store{db: db, seq: seq}, nil
+}
+
+// Close closes the underlying database. Neither the store nor its graphs can
+// be used after closing it.
+func (s *Store) Close() error {
+	if err := s.seq.Release(); err != nil {
+		s.db.Close()
+		return err
+	}
+	return s.db.Close()
+}
+
+// Name returns the ID of the backend being used.
+func (s *Store) Name(ctx context.Context) string {
+	return "BADGER"
+}
+
+// Version returns the version of the driver implementation.
+func (s *Store) Version(ctx context.Context) string {
+	return "0.1.badger"
+}
+
+// catalogKey returns the catalog key of the provided graph.
+func catalogKey(id string) []byte {
+	return append([]byte{catalogPrefix}, id...)
+}
+
+// graphPrefix returns the prefix of all the data keys of the provided graph.
+// Graph IDs are hashed so all keys of the graph have the same length.
+func graphPrefix(id string) []byte {
+	return append([]byte{dataPrefix}, uuid.NewSHA1(uuid.NIL, []byte(id))...)
+}
+
+// nextEpoch returns a new epoch. Epochs are never reused, even after
+// reopening the store.
+func (s *Store) nextEpoch() ([]byte, error) {
+	e, err := s.seq.Next()
+	if err != nil {
+		return nil, err
+	}
+	// Sequences start at zero, which is not a valid epoch for the cache.
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, e+1)
+	return v, nil
+}
+
+// newGraph returns the graph with the provided ID.
+func (s *Store) newGraph(id string) *graph {
+	g := &graph{id: id, s: s}
+	g.OrderedGraph = driver.NewOrderedGraph(id, &kv{db: s.db}, graphPrefix(id), g.bumpEpoch)
+	return g
+}
+
+// NewGraph creates a new graph.
+func (s *Store) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	e, err := s.nextEpoch()
+	if err != nil {
+		return nil, fmt.Errorf("badger.NewGraph(%q): %v", id, err)
+	}
+	err = s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(catalogKey(id)); err == nil {
+			return fmt.Errorf("badger.NewGraph(%q): graph already exists", id)
+		} else if err != badger.ErrKeyNotFound {
+			return fmt.Errorf("badger.NewGraph(%q): %v", id, err)
+		}
+		return txn.Set(catalogKey(id), e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.newGraph(id), nil
+}
+
+// Graph returns an existing graph if available. Getting a non existing
+// graph should return an error.
+func (s *Store) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	err := s.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(catalogKey(id))
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, fmt.Errorf("badger.Graph(%q): graph does not exist", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("badger.Graph(%q): %v", id, err)
+	}
+	return s.newGraph(id), nil
+}
+
+// DeleteGraph deletes an existing graph. Deleting a non existing graph
+// should return an error.
+func (s *Store) DeleteGraph(ctx context.Context, id string) error {
+	if _, err := s.Graph(ctx, id); err != nil {
+		return fmt.Errorf("badger.DeleteGraph(%q): graph does not exist", id)
+	}
+	// The triples are dropped first, so a failure never leaves them around
+	// for a graph recreated with the same ID.
+	if err := s.db.DropPrefix(graphPrefix(id)); err != nil {
+		return fmt.Errorf("badger.DeleteGraph(%q): %v", id, err)
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(catalogKey(id))
+	})
+}
+
+// GraphNames returns the current available graph names in the store.
+func (s *Store) GraphNames(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(names)
+	var ns []string
+	if err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues, opts.Prefix = false, []byte{catalogPrefix}
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			ns = append(ns, string(it.Item().Key()[1:]))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, n := range ns {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case names <- n:
+		}
+	}
+	return nil
+}
+
+// kv provides the ordered key value store API on top of Badger.
+type kv struct {
+	db *badger.DB
+}
+
+// Scan calls the provided function with the keys and values in the range.
+func (s *kv) Scan(r driver.KeyRange, f func(k, v []byte) error) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = r.Prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		start := r.Start
+		if start == nil {
+			start = r.Prefix
+		}
+		for it.Seek(start); it.ValidForPrefix(r.Prefix); it.Next() {
+			k := it.Item().Key()
+			if !r.InRange(k) {
+				break
+			}
+			if err := it.Item().Value(func(v []byte) error {
+				return f(k, v)
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Has returns true if the key exists.
+func (s *kv) Has(k []byte) (bool, error) {
+	err := s.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(k)
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Write sets and deletes the provided keys using a write batch. Batches are
+// committed as they fill up, so a failure may leave some of the changes
+// applied.
+func (s *kv) Write(set []driver.KeyValue, del [][]byte) error {
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, e := range set {
+		if err := wb.Set(e.Key, e.Value); err != nil {
+			return err
+		}
+	}
+	for _, k := range del {
+		if err := wb.Delete(k); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
+// graph provides a Badger-based persistent implementation of the graph API.
+type graph struct {
+	*driver.OrderedGraph
+	id string
+	s  *Store
+}
+
+// bumpEpoch assigns a new epoch to the graph once its triples changed.
+func (g *graph) bumpEpoch() error {
+	e, err := g.s.nextEpoch()
+	if err != nil {
+		return err
+	}
+	return g.s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(catalogKey(g.id)); err != nil {
+			return fmt.Errorf("badger: graph %q does not exist", g.id)
+		}
+		return txn.Set(catalogKey(g.id), e)
+	})
+}
+
+// Epoch returns the current epoch of the graph.
+func (g *graph) Epoch(ctx context.Context) (uint64, error) {
+	var e uint64
+	err := g.s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(catalogKey(g.id))
+		if err != nil {
+			return fmt.Errorf("badger: graph %q does not exist", g.id)
+		}
+		return item.Value(func(v []byte) error {
+			e = binary.BigEndian.Uint64(v)
+			return nil
+		})
+	})
+	return e, err
+}
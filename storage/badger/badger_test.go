@@ -0,0 +1,307 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/storagetest"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// newTestStore returns a store backed by a temporary directory and a function
+// that removes it.
+func newTestStore(t *testing.T) (*Store, string, func()) {
+	dir, err := ioutil.TempDir("", "badwolf_badger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStore(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("NewStore(%q) failed with error %v", dir, err)
+	}
+	return s, dir, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func getTestTriples(t *testing.T) []*triple.Triple {
+	var ts []*triple.Triple
+	for _, s := range []string{
+		"/u<john>\t\"parent_of\"@[]\t/u<mary>",
+		"/u<john>\t\"parent_of\"@[]\t/u<peter>",
+		"/u<john>\t\"parent_of\"@[]\t/u<alice>",
+		"/u<mary>\t\"parent_of\"@[]\t/u<amy>",
+		"/u<john>\t\"met\"@[1960-04-10T04:21:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[1969-12-31T23:59:59.5Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T04:25:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T04:30:00Z]\t/u<peter>",
+		"/u<john>\t\"met\"@[2016-04-10T04:30:00.000000001Z]\t/u<alice>",
+	} {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %q with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+func TestBadgerStore(t *testing.T) {
+	s, _, clean := newTestStore(t)
+	defer clean()
+	ctx := context.Background()
+	if _, err := s.NewGraph(ctx, "?test"); err != nil {
+		t.Errorf("badger.NewGraph: should never fail to create a graph; %v", err)
+	}
+	if _, err := s.NewGraph(ctx, "?test"); err == nil {
+		t.Errorf("badger.NewGraph: should never succeed to create an existing graph")
+	}
+	if _, err := s.NewGraph(ctx, "?other"); err != nil {
+		t.Errorf("badger.NewGraph: should never fail to create a graph; %v", err)
+	}
+	if _, err := s.Graph(ctx, "?test"); err != nil {
+		t.Errorf("badger.Graph: should never fail to get an existing graph; %v", err)
+	}
+	gns := make(chan string, 10)
+	if err := s.GraphNames(ctx, gns); err != nil {
+		t.Errorf("badger.GraphNames failed with error %v", err)
+	}
+	var got []string
+	for n := range gns {
+		got = append(got, n)
+	}
+	if len(got) != 2 || got[0] != "?other" || got[1] != "?test" {
+		t.Errorf("badger.GraphNames returned %v; want [?other ?test]", got)
+	}
+	if err := s.DeleteGraph(ctx, "?test"); err != nil {
+		t.Errorf("badger.DeleteGraph: should never fail to delete an existing graph; %v", err)
+	}
+	if _, err := s.Graph(ctx, "?test"); err == nil {
+		t.Errorf("badger.Graph: should never succeed to get a non existing graph")
+	}
+	if err := s.DeleteGraph(ctx, "?test"); err == nil {
+		t.Errorf("badger.DeleteGraph: should never succeed to delete a non existing graph")
+	}
+}
+
+func TestLookups(t *testing.T) {
+	s, _, clean := newTestStore(t)
+	defer clean()
+	ctx, ts := context.Background(), getTestTriples(t)
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	john, mary := ts[0].Subject(), ts[0].Object()
+	parentOf, met := ts[0].Predicate(), ts[4].Predicate()
+	at := func(s string) *time.Time {
+		ta, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &ta
+	}
+	lo := storage.DefaultLookup
+	testTable := []struct {
+		name   string
+		lookup func(chan<- *triple.Triple) error
+		want   int
+	}{
+		{"Triples", func(c chan<- *triple.Triple) error { return g.Triples(ctx, lo, c) }, 9},
+		{"TriplesForSubject", func(c chan<- *triple.Triple) error { return g.TriplesForSubject(ctx, john, lo, c) }, 8},
+		{"TriplesForObject", func(c chan<- *triple.Triple) error { return g.TriplesForObject(ctx, mary, lo, c) }, 4},
+		{"TriplesForPredicate", func(c chan<- *triple.Triple) error { return g.TriplesForPredicate(ctx, parentOf, lo, c) }, 4},
+		{"TriplesForPredicateAndObject", func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicateAndObject(ctx, parentOf, mary, lo, c)
+		}, 1},
+		{"TriplesForPredicate anchored", func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicate(ctx, met, lo, c)
+		}, 1},
+		{"TriplesForPredicate lower bound", func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicate(ctx, parentOf, &storage.LookupOptions{LowerAnchor: at("2016-04-10T04:30:00Z")}, c)
+		}, 4},
+		{"TriplesForSubjectAndPredicate window", func(c chan<- *triple.Triple) error {
+			p, _ := predicate.NewImmutable("met")
+			return g.TriplesForSubjectAndPredicate(ctx, john, p, &storage.LookupOptions{
+				LowerAnchor: at("1969-12-31T23:59:59Z"),
+				UpperAnchor: at("2016-04-10T04:30:00Z"),
+			}, c)
+		}, 3},
+		{"TriplesForPredicateAndObject before epoch", func(c chan<- *triple.Triple) error {
+			p, _ := predicate.NewImmutable("met")
+			return g.TriplesForPredicateAndObject(ctx, p, mary, &storage.LookupOptions{UpperAnchor: at("1970-01-01T00:00:00Z")}, c)
+		}, 2},
+		{"TriplesForSubjectAndPredicate latest", func(c chan<- *triple.Triple) error {
+			return g.TriplesForSubjectAndPredicate(ctx, john, met, &storage.LookupOptions{LatestAnchor: true}, c)
+		}, 1},
+		{"Triples limit", func(c chan<- *triple.Triple) error {
+			return g.Triples(ctx, &storage.LookupOptions{MaxElements: 4}, c)
+		}, 4},
+		{"TriplesForSubject unknown", func(c chan<- *triple.Triple) error {
+			return g.TriplesForSubject(ctx, node.NewBlankNode(), lo, c)
+		}, 0},
+	}
+	for _, entry := range testTable {
+		if got := storagetest.Collect(t, entry.lookup); len(got) != entry.want {
+			t.Errorf("%s returned %d triples %v; want %d", entry.name, len(got), got, entry.want)
+		}
+	}
+
+	objs := make(chan *triple.Object, 10)
+	if err := g.Objects(ctx, john, parentOf, lo, objs); err != nil {
+		t.Fatalf("g.Objects failed with error %v", err)
+	}
+	if cnt := len(objs); cnt != 3 {
+		t.Errorf("g.Objects(%s, %s) returned %d objects; want 3", john, parentOf, cnt)
+	}
+	subjs := make(chan *node.Node, 10)
+	if err := g.Subjects(ctx, parentOf, mary, lo, subjs); err != nil {
+		t.Fatalf("g.Subjects failed with error %v", err)
+	}
+	if cnt := len(subjs); cnt != 1 {
+		t.Errorf("g.Subjects(%s, %s) returned %d subjects; want 1", parentOf, mary, cnt)
+	}
+	for _, entry := range []struct {
+		name   string
+		lookup func(chan<- *predicate.Predicate) error
+		want   int
+	}{
+		{"PredicatesForSubject", func(c chan<- *predicate.Predicate) error { return g.PredicatesForSubject(ctx, john, lo, c) }, 8},
+		{"PredicatesForObject", func(c chan<- *predicate.Predicate) error { return g.PredicatesForObject(ctx, mary, lo, c) }, 4},
+		{"PredicatesForSubjectAndObject", func(c chan<- *predicate.Predicate) error {
+			return g.PredicatesForSubjectAndObject(ctx, john, mary, lo, c)
+		}, 4},
+	} {
+		prds := make(chan *predicate.Predicate, 10)
+		if err := entry.lookup(prds); err != nil {
+			t.Fatalf("%s failed with error %v", entry.name, err)
+		}
+		if cnt := len(prds); cnt != entry.want {
+			t.Errorf("%s returned %d predicates; want %d", entry.name, cnt, entry.want)
+		}
+	}
+}
+
+func TestAddRemoveExist(t *testing.T) {
+	s, _, clean := newTestStore(t)
+	defer clean()
+	ctx, ts := context.Background(), getTestTriples(t)
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Adding existing triples does not duplicate them.
+	for i := 0; i < 2; i++ {
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatalf("g.AddTriples failed with error %v", err)
+		}
+	}
+	if n, err := storage.CountTriples(ctx, g); err != nil || n != int64(len(ts)) {
+		t.Errorf("storage.CountTriples returned (%d, %v); want (%d, nil)", n, err, len(ts))
+	}
+	if err := g.RemoveTriples(ctx, ts[:3]); err != nil {
+		t.Fatalf("g.RemoveTriples failed with error %v", err)
+	}
+	for i, trpl := range ts {
+		ok, err := g.Exist(ctx, trpl)
+		if err != nil {
+			t.Fatalf("g.Exist(%s) failed with error %v", trpl, err)
+		}
+		if want := i >= 3; ok != want {
+			t.Errorf("g.Exist(%s) returned %v; want %v", trpl, ok, want)
+		}
+	}
+	got := storagetest.Collect(t, func(c chan<- *triple.Triple) error {
+		return g.TriplesForPredicate(ctx, ts[0].Predicate(), storage.DefaultLookup, c)
+	})
+	if len(got) != 1 {
+		t.Errorf("g.TriplesForPredicate(%s) returned %v after removing triples; want 1 triple", ts[0].Predicate(), got)
+	}
+	// Deleting the graph drops its triples.
+	if err := s.DeleteGraph(ctx, "?test"); err != nil {
+		t.Fatal(err)
+	}
+	if g, err = s.NewGraph(ctx, "?test"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := storage.CountTriples(ctx, g); err != nil || n != 0 {
+		t.Errorf("storage.CountTriples returned (%d, %v) for a recreated graph; want (0, nil)", n, err)
+	}
+}
+
+func TestPersistenceAndEpoch(t *testing.T) {
+	// The store is reopened, so it is closed by the test.
+	s, dir, _ := newTestStore(t)
+	defer os.RemoveAll(dir)
+	ctx, ts := context.Background(), getTestTriples(t)
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	epoch := func(g storage.Graph) uint64 {
+		e, err := storage.Epoch(ctx, g)
+		if err != nil {
+			t.Fatalf("storage.Epoch failed with error %v", err)
+		}
+		return e
+	}
+	e0 := epoch(g)
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	e1 := epoch(g)
+	if e1 == e0 {
+		t.Errorf("storage.Epoch did not change after adding triples")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("badger.Close failed with error %v", err)
+	}
+
+	// Reopening the database keeps the graphs, their triples, and epochs.
+	if s, err = NewStore(dir); err != nil {
+		t.Fatalf("NewStore(%q) failed with error %v", dir, err)
+	}
+	defer s.Close()
+	if g, err = s.Graph(ctx, "?test"); err != nil {
+		t.Fatalf("badger.Graph failed to get a persisted graph with error %v", err)
+	}
+	if got := epoch(g); got != e1 {
+		t.Errorf("storage.Epoch returned %d after reopening the store; want %d", got, e1)
+	}
+	got := storagetest.Collect(t, func(c chan<- *triple.Triple) error { return g.Triples(ctx, storage.DefaultLookup, c) })
+	if len(got) != len(ts) {
+		t.Errorf("g.Triples returned %d triples after reopening the store; want %d", len(got), len(ts))
+	}
+	if err := g.RemoveTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	if got := epoch(g); got == e0 || got == e1 {
+		t.Errorf("storage.Epoch returned an already used epoch %d after reopening the store", got)
+	}
+}
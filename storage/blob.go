@@ -0,0 +1,196 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// LiteralLimiter is an optional interface that stores can implement to cap
+// the size of the text and blob literals of the triples of their graphs.
+// Adding triples with bigger literals fails with *ErrQuotaExceeded, without
+// adding any of them. Bigger contents can still be kept using a BlobStore.
+type LiteralLimiter interface {
+	// LiteralLimit returns the maximum size in bytes of the text and blob
+	// literals accepted by the store. Zero means they are not limited.
+	LiteralLimit(ctx context.Context) int
+
+	// SetLiteralLimit sets the maximum size in bytes of the text and blob
+	// literals accepted by the store. Zero removes the limit. Triples already
+	// in the store are not checked against the new limit.
+	SetLiteralLimit(ctx context.Context, max int) error
+}
+
+// ErrNoLiteralLimit is returned when setting the literal size limit of a
+// store that does not implement LiteralLimiter.
+var ErrNoLiteralLimit = errors.New("storage: the store does not limit the size of literals")
+
+// GetLiteralLimit returns the maximum size in bytes of the text and blob
+// literals accepted by the store, or zero if they are not limited.
+func GetLiteralLimit(ctx context.Context, s Store) int {
+	if ll, ok := s.(LiteralLimiter); ok {
+		return ll.LiteralLimit(ctx)
+	}
+	return 0
+}
+
+// SetLiteralLimit sets the maximum size in bytes of the text and blob
+// literals accepted by the store. If the store does not implement
+// LiteralLimiter, ErrNoLiteralLimit is returned.
+func SetLiteralLimit(ctx context.Context, s Store, max int) error {
+	if max < 0 {
+		return fmt.Errorf("storage.SetLiteralLimit: invalid negative limit %d", max)
+	}
+	if ll, ok := s.(LiteralLimiter); ok {
+		return ll.SetLiteralLimit(ctx, max)
+	}
+	return ErrNoLiteralLimit
+}
+
+// LiteralBuilder returns a literal builder that rejects the literals bigger
+// than the literal size limit of the store, so they can be rejected before
+// reaching it.
+func LiteralBuilder(ctx context.Context, s Store) literal.Builder {
+	if max := GetLiteralLimit(ctx, s); max > 0 {
+		return literal.NewBoundedBuilder(max)
+	}
+	return literal.DefaultBuilder()
+}
+
+// CheckLiteralLimit returns *ErrQuotaExceeded if any of the triples has a text
+// or blob literal bigger than max bytes. Zero means literals are not limited.
+// The scope identifies the limited store or graph in the returned error.
+func CheckLiteralLimit(scope string, max int, ts []*triple.Triple) error {
+	if max <= 0 {
+		return nil
+	}
+	for _, t := range ts {
+		l, err := t.Object().Literal()
+		if err != nil {
+			continue
+		}
+		var n int
+		switch l.Type() {
+		case literal.Text:
+			s, _ := l.Text()
+			n = len(s)
+		case literal.Blob:
+			b, _ := l.Blob()
+			n = len(b)
+		}
+		if n > max {
+			return &ErrQuotaExceeded{Scope: scope, Resource: "literal bytes", Limit: int64(max), Requested: int64(n)}
+		}
+	}
+	return nil
+}
+
+// BlobChunkSize is the size of the chunks blobs are split into by WriteChunks.
+const BlobChunkSize = 1 << 20
+
+// BlobStore is an optional interface that stores can implement to keep blobs
+// of arbitrary size out of line. Blobs are content addressed: they are
+// referenced from triples using the literal returned when writing them,
+// which holds a *literal.BlobRef, and writing the same contents twice
+// returns the same reference. Contents are written and read as streams, one
+// chunk at a time, so they are never fully loaded into memory.
+type BlobStore interface {
+	// WriteBlob stores the contents read from r and returns the literal
+	// referencing them.
+	WriteBlob(ctx context.Context, r io.Reader) (*literal.Literal, error)
+
+	// ReadBlob returns a reader streaming the contents of the blob referenced
+	// by the provided literal. The reader must be closed once done.
+	ReadBlob(ctx context.Context, ref *literal.Literal) (io.ReadCloser, error)
+
+	// DeleteBlob deletes the contents of the blob referenced by the provided
+	// literal. Triples referencing it are not changed.
+	DeleteBlob(ctx context.Context, ref *literal.Literal) error
+}
+
+// ErrNoBlobs is returned when accessing the blobs of a store that does not
+// implement BlobStore.
+var ErrNoBlobs = errors.New("storage: the store does not keep blobs")
+
+// WriteBlob stores the contents read from r in the store, and returns the
+// literal referencing them. If the store does not implement BlobStore,
+// ErrNoBlobs is returned.
+func WriteBlob(ctx context.Context, s Store, r io.Reader) (*literal.Literal, error) {
+	if bs, ok := s.(BlobStore); ok {
+		return bs.WriteBlob(ctx, r)
+	}
+	return nil, ErrNoBlobs
+}
+
+// ReadBlob returns a reader streaming the contents of the blob referenced by
+// the provided literal. If the store does not implement BlobStore,
+// ErrNoBlobs is returned.
+func ReadBlob(ctx context.Context, s Store, ref *literal.Literal) (io.ReadCloser, error) {
+	if bs, ok := s.(BlobStore); ok {
+		return bs.ReadBlob(ctx, ref)
+	}
+	return nil, ErrNoBlobs
+}
+
+// DeleteBlob deletes the contents of the blob referenced by the provided
+// literal. If the store does not implement BlobStore, ErrNoBlobs is returned.
+func DeleteBlob(ctx context.Context, s Store, ref *literal.Literal) error {
+	if bs, ok := s.(BlobStore); ok {
+		return bs.DeleteBlob(ctx, ref)
+	}
+	return ErrNoBlobs
+}
+
+// WriteChunks reads r until EOF, calling f with each chunk of at most
+// BlobChunkSize bytes, and returns the reference of the contents read. The
+// chunks passed to f are not reused. Stores implementing BlobStore use it to
+// split the blobs they are given.
+func WriteChunks(ctx context.Context, r io.Reader, f func(chunk []byte) error) (*literal.BlobRef, error) {
+	h, size := sha256.New(), int64(0)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, BlobChunkSize)
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			h.Write(buf[:n])
+			size += int64(n)
+			if ferr := f(buf[:n]); ferr != nil {
+				return nil, ferr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &literal.BlobRef{Digest: hex.EncodeToString(h.Sum(nil)), Size: size}, nil
+}
+
+// BlobRefLiteral returns the literal holding the provided blob reference.
+func BlobRefLiteral(ref *literal.BlobRef) (*literal.Literal, error) {
+	return literal.DefaultBuilder().Build(literal.BlobRefType, ref)
+}
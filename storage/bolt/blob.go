@@ -0,0 +1,212 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple/literal"
+	bolt "go.etcd.io/bbolt"
+)
+
+// literalLimit returns the literal size limit stored in the provided
+// transaction, or zero if literals are not limited.
+func literalLimit(tx *bolt.Tx) int {
+	v := tx.Bucket(configBucket).Get(literalLimitKey)
+	if len(v) != 8 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(v))
+}
+
+// LiteralLimit returns the maximum size in bytes of the text and blob literals
+// accepted by the store, or zero if they are not limited.
+func (s *Store) LiteralLimit(ctx context.Context) int {
+	var max int
+	s.db.View(func(tx *bolt.Tx) error {
+		max = literalLimit(tx)
+		return nil
+	})
+	return max
+}
+
+// SetLiteralLimit sets the maximum size in bytes of the text and blob
+// literals accepted by the store. The limit is kept in the database. Zero
+// removes the limit.
+func (s *Store) SetLiteralLimit(ctx context.Context, max int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		v := make([]byte, 8)
+		binary.BigEndian.PutUint64(v, uint64(max))
+		return tx.Bucket(configBucket).Put(literalLimitKey, v)
+	})
+}
+
+// chunkKey returns the key of the chunk with the provided index of the blob
+// with the provided sequence number.
+func chunkKey(seq []byte, i uint64) []byte {
+	k := make([]byte, 16)
+	copy(k, seq)
+	binary.BigEndian.PutUint64(k[8:], i)
+	return k
+}
+
+// deleteChunks deletes the chunks of the blob with the provided sequence
+// number.
+func deleteChunks(tx *bolt.Tx, seq []byte) error {
+	c := tx.Bucket(chunksBucket).Cursor()
+	for k, _ := c.Seek(seq); k != nil && len(k) == 16 && string(k[:8]) == string(seq); k, _ = c.Seek(seq) {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteBlob stores the contents read from r and returns the literal
+// referencing them. Each chunk is written in its own transaction, so the
+// contents are never fully loaded into memory. The blob is only visible once
+// all its chunks are written.
+func (s *Store) WriteBlob(ctx context.Context, r io.Reader) (*literal.Literal, error) {
+	seq := make([]byte, 8)
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		n, err := tx.Bucket(blobsBucket).NextSequence()
+		binary.BigEndian.PutUint64(seq, n)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	var i uint64
+	ref, err := storage.WriteChunks(ctx, r, func(c []byte) error {
+		defer func() { i++ }()
+		return s.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(chunksBucket).Put(chunkKey(seq, i), c)
+		})
+	})
+	if err == nil {
+		err = s.db.Update(func(tx *bolt.Tx) error {
+			bs := tx.Bucket(blobsBucket)
+			if bs.Get([]byte(ref.Digest)) != nil {
+				// The same contents were already written.
+				return deleteChunks(tx, seq)
+			}
+			return bs.Put([]byte(ref.Digest), seq)
+		})
+	}
+	if err != nil {
+		s.db.Update(func(tx *bolt.Tx) error {
+			return deleteChunks(tx, seq)
+		})
+		return nil, err
+	}
+	return storage.BlobRefLiteral(ref)
+}
+
+// blobSeq returns the sequence number of the blob referenced by the provided
+// literal.
+func (s *Store) blobSeq(op string, l *literal.Literal) ([]byte, *literal.BlobRef, error) {
+	ref, err := l.BlobRef()
+	if err != nil {
+		return nil, nil, fmt.Errorf("bolt.%s: %v", op, err)
+	}
+	var seq []byte
+	s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(blobsBucket).Get([]byte(ref.Digest)); v != nil {
+			seq = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if seq == nil {
+		return nil, nil, fmt.Errorf("bolt.%s: blob %s does not exist", op, ref)
+	}
+	return seq, ref, nil
+}
+
+// ReadBlob returns a reader streaming the contents of the blob referenced by
+// the provided literal. Chunks are read one at a time as the reader needs
+// them.
+func (s *Store) ReadBlob(ctx context.Context, l *literal.Literal) (io.ReadCloser, error) {
+	seq, ref, err := s.blobSeq("ReadBlob", l)
+	if err != nil {
+		return nil, err
+	}
+	return &blobReader{ctx: ctx, db: s.db, seq: seq, size: ref.Size}, nil
+}
+
+// DeleteBlob deletes the contents of the blob referenced by the provided
+// literal.
+func (s *Store) DeleteBlob(ctx context.Context, l *literal.Literal) error {
+	seq, ref, err := s.blobSeq("DeleteBlob", l)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(blobsBucket).Delete([]byte(ref.Digest)); err != nil {
+			return err
+		}
+		return deleteChunks(tx, seq)
+	})
+}
+
+// blobReader streams the chunks of a blob.
+type blobReader struct {
+	ctx context.Context
+	db  *bolt.DB
+	seq []byte
+	// size is the size of the blob, and read the number of bytes read so far.
+	size, read int64
+	// next is the index of the next chunk to read, and buf the unread part
+	// of the last chunk read.
+	next uint64
+	buf  []byte
+}
+
+// Read reads the contents of the blob, loading the next chunk once the
+// previous one is fully read.
+func (r *blobReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if err := r.ctx.Err(); err != nil {
+			return 0, err
+		}
+		err := r.db.View(func(tx *bolt.Tx) error {
+			if v := tx.Bucket(chunksBucket).Get(chunkKey(r.seq, r.next)); v != nil {
+				// Values are only valid during the transaction.
+				r.buf = append(r.buf[:0], v...)
+				return nil
+			}
+			if r.read < r.size {
+				return fmt.Errorf("bolt.ReadBlob: blob deleted after reading %d of its %d bytes", r.read, r.size)
+			}
+			return io.EOF
+		})
+		if err != nil {
+			return 0, err
+		}
+		r.next++
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	r.read += int64(n)
+	return n, nil
+}
+
+// Close releases the reader.
+func (r *blobReader) Close() error {
+	r.buf = nil
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBlobsAndLiteralLimit(t *testing.T) {
+	ctx := context.Background()
+	s, path, cleanup := newTestStore(t)
+	defer cleanup()
+	if err := storage.SetLiteralLimit(ctx, s, 32); err != nil {
+		t.Fatalf("storage.SetLiteralLimit failed with error %v", err)
+	}
+	data := bytes.Repeat([]byte("0123456789"), storage.BlobChunkSize/4)
+	ref, err := storage.WriteBlob(ctx, s, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("storage.WriteBlob failed with error %v", err)
+	}
+	if _, err := storage.WriteBlob(ctx, s, bytes.NewReader(data)); err != nil {
+		t.Fatalf("storage.WriteBlob failed to write the same contents again with error %v", err)
+	}
+
+	// The literal limit and the blobs survive reopening the store.
+	s.Close()
+	if s, err = NewStore(path); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := storage.GetLiteralLimit(ctx, s); got != 32 {
+		t.Errorf("storage.GetLiteralLimit returned %d after reopening the store; want 32", got)
+	}
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	big, err := triple.Parse("/u<john>\t\"bio\"@[]\t\""+strings.Repeat("a", 64)+"\"^^type:text", literal.DefaultBuilder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.AddTriples(ctx, []*triple.Triple{big}).(*storage.ErrQuotaExceeded); !ok {
+		t.Errorf("g.AddTriples should fail with *storage.ErrQuotaExceeded for a literal beyond the limit")
+	}
+	r, err := storage.ReadBlob(ctx, s, ref)
+	if err != nil {
+		t.Fatalf("storage.ReadBlob failed with error %v", err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if !bytes.Equal(got, data) {
+		t.Errorf("storage.ReadBlob returned %d bytes that do not match the %d written", len(got), len(data))
+	}
+
+	// Deleting a blob while reading it fails the reader.
+	r, err = storage.ReadBlob(ctx, s, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.DeleteBlob(ctx, s, ref); err != nil {
+		t.Fatalf("storage.DeleteBlob failed with error %v", err)
+	}
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Errorf("reading a deleted blob should fail")
+	}
+	if _, err := storage.ReadBlob(ctx, s, ref); err == nil {
+		t.Errorf("storage.ReadBlob should fail for a deleted blob")
+	}
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		if n := tx.Bucket(chunksBucket).Stats().KeyN; n != 0 {
+			t.Errorf("the store kept %d chunks after deleting all its blobs", n)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,540 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bolt provides a persistent implementation of the storage.Store and
+// storage.Graph interfaces backed by a single bbolt database file.
+//
+// Each graph is stored in its own bucket. Triples are kept in a master bucket
+// indexed by their UUID and in three index buckets, SPO, POS, and OSP, whose
+// keys are the concatenation of the UUIDs of the subject, the predicate
+// ignoring its time anchor, the object, and the triple in the index order.
+// Every lookup is a prefix scan of one of the indices.
+//
+// Blobs are kept in two buckets shared by all graphs: one mapping their
+// digests to the sequence number they were written with, and one keeping
+// their chunks under that sequence number, so they can be streamed one chunk
+// at a time.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/driver"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+	"github.com/pborman/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Names of the buckets used by the store.
+var (
+	// graphsBucket contains one nested bucket per graph. Its sequence provides
+	// the epochs of all graphs.
+	graphsBucket  = []byte("graphs")
+	triplesBucket = []byte("triples")
+	spoBucket     = []byte("spo")
+	posBucket     = []byte("pos")
+	ospBucket     = []byte("osp")
+	// epochKey stores the epoch of a graph in its bucket.
+	epochKey = []byte("epoch")
+	// configBucket keeps the settings of the store.
+	configBucket = []byte("config")
+	// literalLimitKey stores the literal size limit in the config bucket.
+	literalLimitKey = []byte("literal_limit")
+	// blobsBucket maps the digests of blobs to their sequence number. Its
+	// sequence provides the sequence numbers of new blobs.
+	blobsBucket = []byte("blobs")
+	// chunksBucket keeps the chunks of blobs under their sequence number
+	// followed by the index of the chunk.
+	chunksBucket = []byte("chunks")
+)
+
+// Store provides a persistent store backed by a bbolt database.
+type Store struct {
+	db *bolt.DB
+}
+
+// NewStore opens the bbolt database at the provided path, creating it if
+// needed. The database file is locked until the store is closed.
+func NewStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("bolt.NewStore(%q): %v", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, n := range [][]byte{graphsBucket, configBucket, blobsBucket, chunksBucket} {
+			if _, err := tx.CreateBucketIfNotExists(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("bolt.NewStore(%q): %v", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database. Neither the store nor its graphs can
+// be used after closing it.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Name returns the ID of the backend being used.
+func (s *Store) Name(ctx context.Context) string {
+	return "BOLT"
+}
+
+// Version returns the version of the driver implementation.
+func (s *Store) Version(ctx context.Context) string {
+	return "0.1.bolt"
+}
+
+// NewGraph creates a new graph.
+func (s *Store) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		gs := tx.Bucket(graphsBucket)
+		if gs.Bucket([]byte(id)) != nil {
+			return fmt.Errorf("bolt.NewGraph(%q): graph already exists", id)
+		}
+		b, err := gs.CreateBucket([]byte(id))
+		if err != nil {
+			return fmt.Errorf("bolt.NewGraph(%q): %v", id, err)
+		}
+		for _, n := range [][]byte{triplesBucket, spoBucket, posBucket, ospBucket} {
+			if _, err := b.CreateBucket(n); err != nil {
+				return fmt.Errorf("bolt.NewGraph(%q): %v", id, err)
+			}
+		}
+		return bumpEpoch(gs, b)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &graph{id: id, db: s.db}, nil
+}
+
+// Graph returns an existing graph if available. Getting a non existing
+// graph should return an error.
+func (s *Store) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(graphsBucket).Bucket([]byte(id)) == nil {
+			return fmt.Errorf("bolt.Graph(%q): graph does not exist", id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &graph{id: id, db: s.db}, nil
+}
+
+// DeleteGraph deletes an existing graph. Deleting a non existing graph
+// should return an error.
+func (s *Store) DeleteGraph(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(graphsBucket).DeleteBucket([]byte(id)); err != nil {
+			return fmt.Errorf("bolt.DeleteGraph(%q): graph does not exist", id)
+		}
+		return nil
+	})
+}
+
+// GraphNames returns the current available graph names in the store.
+func (s *Store) GraphNames(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(names)
+	var ns []string
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(graphsBucket).ForEach(func(k, v []byte) error {
+			ns = append(ns, string(k))
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+	for _, n := range ns {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case names <- n:
+		}
+	}
+	return nil
+}
+
+// bumpEpoch assigns a new epoch to the provided graph bucket. Epochs are
+// drawn from the sequence of the graphs bucket, so recreated graphs never
+// reuse an epoch.
+func bumpEpoch(gs, b *bolt.Bucket) error {
+	e, err := gs.NextSequence()
+	if err != nil {
+		return err
+	}
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, e)
+	return b.Put(epochKey, v)
+}
+
+// graph provides a bbolt-based persistent implementation of the graph API.
+type graph struct {
+	id string
+	db *bolt.DB
+}
+
+// ID returns the id for this graph.
+func (g *graph) ID(ctx context.Context) string {
+	return g.id
+}
+
+// bucket returns the bucket of the graph in the provided transaction.
+func (g *graph) bucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	b := tx.Bucket(graphsBucket).Bucket([]byte(g.id))
+	if b == nil {
+		return nil, fmt.Errorf("bolt: graph %q does not exist", g.id)
+	}
+	return b, nil
+}
+
+// keys returns the index keys of the provided triple.
+func keys(t *triple.Triple) (tk, spo, pos, osp []byte) {
+	s, p, o := []byte(t.Subject().UUID()), []byte(t.Predicate().PartialUUID()), []byte(t.Object().UUID())
+	tk = []byte(t.UUID())
+	cat := func(bs ...[]byte) []byte {
+		return bytes.Join(bs, nil)
+	}
+	return tk, cat(s, p, o, tk), cat(p, o, s, tk), cat(o, s, p, tk)
+}
+
+// AddTriples adds the triples to the storage. If any triple has a literal
+// bigger than the literal size limit of the store, it fails without adding
+// any of them.
+func (g *graph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	return g.db.Update(func(tx *bolt.Tx) error {
+		b, err := g.bucket(tx)
+		if err != nil {
+			return err
+		}
+		if err := storage.CheckLiteralLimit("graph "+strconv.Quote(g.id), literalLimit(tx), ts); err != nil {
+			return err
+		}
+		for _, t := range ts {
+			v, err := driver.EncodeTriple(t)
+			if err != nil {
+				return err
+			}
+			tk, spo, pos, osp := keys(t)
+			if err := b.Bucket(triplesBucket).Put(tk, v); err != nil {
+				return err
+			}
+			for _, idx := range []struct {
+				n, k []byte
+			}{{spoBucket, spo}, {posBucket, pos}, {ospBucket, osp}} {
+				if err := b.Bucket(idx.n).Put(idx.k, []byte{}); err != nil {
+					return err
+				}
+			}
+		}
+		return bumpEpoch(tx.Bucket(graphsBucket), b)
+	})
+}
+
+// RemoveTriples removes the triples from the storage.
+func (g *graph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	return g.db.Update(func(tx *bolt.Tx) error {
+		b, err := g.bucket(tx)
+		if err != nil {
+			return err
+		}
+		for _, t := range ts {
+			tk, spo, pos, osp := keys(t)
+			for _, idx := range []struct {
+				n, k []byte
+			}{{triplesBucket, tk}, {spoBucket, spo}, {posBucket, pos}, {ospBucket, osp}} {
+				if err := b.Bucket(idx.n).Delete(idx.k); err != nil {
+					return err
+				}
+			}
+		}
+		return bumpEpoch(tx.Bucket(graphsBucket), b)
+	})
+}
+
+// scan returns the triples whose key in the provided index starts with the
+// concatenation of the provided UUIDs and pass the lookup options. The
+// predicate, if provided, restricts the time anchor of temporal triples. An
+// empty index scans all the triples of the graph.
+func (g *graph) scan(idx []byte, lo *storage.LookupOptions, op *predicate.Predicate, ids ...uuid.UUID) ([]*triple.Triple, error) {
+	var prefix []byte
+	for _, id := range ids {
+		prefix = append(prefix, id...)
+	}
+	var ts []*triple.Triple
+	err := g.db.View(func(tx *bolt.Tx) error {
+		b, err := g.bucket(tx)
+		if err != nil {
+			return err
+		}
+		tb := b.Bucket(triplesBucket)
+		add := func(v []byte) error {
+			t, err := driver.DecodeTriple(v)
+			if err != nil {
+				return err
+			}
+			ts = append(ts, t)
+			return nil
+		}
+		if idx == nil {
+			return tb.ForEach(func(k, v []byte) error {
+				return add(v)
+			})
+		}
+		c := b.Bucket(idx).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			// The UUID of the triple is the suffix of all index keys.
+			v := tb.Get(k[len(k)-len(uuid.NIL):])
+			if v == nil {
+				return fmt.Errorf("bolt: graph %q index %s is inconsistent", g.id, idx)
+			}
+			if err := add(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return driver.Filter(ts, lo, op)
+}
+
+// Objects publishes the objects for the given subject and predicate to the
+// provided channel.
+func (g *graph) Objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	if objs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(objs)
+	ts, err := g.scan(spoBucket, lo, p, s.UUID(), p.PartialUUID())
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case objs <- t.Object():
+		}
+	}
+	return nil
+}
+
+// Subjects publishes the subjects for the given predicate and object to the
+// provided channel.
+func (g *graph) Subjects(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, subjs chan<- *node.Node) error {
+	if subjs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(subjs)
+	ts, err := g.scan(posBucket, lo, p, p.PartialUUID(), o.UUID())
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case subjs <- t.Subject():
+		}
+	}
+	return nil
+}
+
+// PredicatesForSubjectAndObject publishes all predicates available for the
+// given subject and object to the provided channel.
+func (g *graph) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.scan(ospBucket, lo, nil, o.UUID(), s.UUID())
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// PredicatesForSubject publishes all the predicates known for the given
+// subject to the provided channel.
+func (g *graph) PredicatesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.scan(spoBucket, lo, nil, s.UUID())
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// PredicatesForObject publishes all the predicates known for the given object
+// to the provided channel.
+func (g *graph) PredicatesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.scan(ospBucket, lo, nil, o.UUID())
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// TriplesForSubject publishes all triples available for the given subject to
+// the provided channel.
+func (g *graph) TriplesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(spoBucket, lo, nil, s.UUID())
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForPredicate publishes all triples available for the given predicate
+// to the provided channel.
+func (g *graph) TriplesForPredicate(ctx context.Context, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(posBucket, lo, p, p.PartialUUID())
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForObject publishes all triples available for the given object to the
+// provided channel.
+func (g *graph) TriplesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(ospBucket, lo, nil, o.UUID())
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForSubjectAndPredicate publishes all triples available for the given
+// subject and predicate to the provided channel.
+func (g *graph) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(spoBucket, lo, p, s.UUID(), p.PartialUUID())
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForPredicateAndObject publishes all triples available for the given
+// predicate and object to the provided channel.
+func (g *graph) TriplesForPredicateAndObject(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(posBucket, lo, p, p.PartialUUID(), o.UUID())
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// Exist checks if the provided triple exists on the store.
+func (g *graph) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
+	var ok bool
+	err := g.db.View(func(tx *bolt.Tx) error {
+		b, err := g.bucket(tx)
+		if err != nil {
+			return err
+		}
+		ok = b.Bucket(triplesBucket).Get([]byte(t.UUID())) != nil
+		return nil
+	})
+	return ok, err
+}
+
+// Triples allows to iterate over all available triples by pushing them to the
+// provided channel.
+func (g *graph) Triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(nil, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// CountTriples returns the number of triples in the graph.
+func (g *graph) CountTriples(ctx context.Context) (int64, error) {
+	var n int64
+	err := g.db.View(func(tx *bolt.Tx) error {
+		b, err := g.bucket(tx)
+		if err != nil {
+			return err
+		}
+		n = int64(b.Bucket(triplesBucket).Stats().KeyN)
+		return nil
+	})
+	return n, err
+}
+
+// Epoch returns the current epoch of the graph.
+func (g *graph) Epoch(ctx context.Context) (uint64, error) {
+	var e uint64
+	err := g.db.View(func(tx *bolt.Tx) error {
+		b, err := g.bucket(tx)
+		if err != nil {
+			return err
+		}
+		e = binary.BigEndian.Uint64(b.Get(epochKey))
+		return nil
+	})
+	return e, err
+}
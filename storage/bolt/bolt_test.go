@@ -0,0 +1,340 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/storagetest"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// newTestStore returns a store backed by a temporary file and a function that
+// removes it.
+func newTestStore(t *testing.T) (*Store, string, func()) {
+	dir, err := ioutil.TempDir("", "badwolf_bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "test.db")
+	s, err := NewStore(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("NewStore(%q) failed with error %v", path, err)
+	}
+	return s, path, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func getTestTriples(t *testing.T) []*triple.Triple {
+	var ts []*triple.Triple
+	for _, s := range []string{
+		"/u<john>\t\"parent_of\"@[]\t/u<mary>",
+		"/u<john>\t\"parent_of\"@[]\t/u<peter>",
+		"/u<john>\t\"parent_of\"@[]\t/u<alice>",
+		"/u<mary>\t\"parent_of\"@[]\t/u<amy>",
+		"/u<peter>\t\"parent_of\"@[]\t/u<paul>",
+		"/u<john>\t\"height_cm\"@[]\t\"174\"^^type:int64",
+		"/u<mary>\t\"height_cm\"@[]\t\"151\"^^type:int64",
+		"/u<john>\t\"met\"@[2016-04-10T04:21:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T04:25:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T04:30:00Z]\t/u<peter>",
+	} {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %q with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+func TestBoltStore(t *testing.T) {
+	s, _, clean := newTestStore(t)
+	defer clean()
+	ctx := context.Background()
+	if _, err := s.NewGraph(ctx, "?test"); err != nil {
+		t.Errorf("bolt.NewGraph: should never fail to create a graph; %v", err)
+	}
+	if _, err := s.NewGraph(ctx, "?test"); err == nil {
+		t.Errorf("bolt.NewGraph: should never succeed to create an existing graph")
+	}
+	if _, err := s.NewGraph(ctx, "?other"); err != nil {
+		t.Errorf("bolt.NewGraph: should never fail to create a graph; %v", err)
+	}
+	if _, err := s.Graph(ctx, "?test"); err != nil {
+		t.Errorf("bolt.Graph: should never fail to get an existing graph; %v", err)
+	}
+	gns := make(chan string, 10)
+	if err := s.GraphNames(ctx, gns); err != nil {
+		t.Errorf("bolt.GraphNames failed with error %v", err)
+	}
+	var got []string
+	for n := range gns {
+		got = append(got, n)
+	}
+	if len(got) != 2 || got[0] != "?other" || got[1] != "?test" {
+		t.Errorf("bolt.GraphNames returned %v; want [?other ?test]", got)
+	}
+	if err := s.DeleteGraph(ctx, "?test"); err != nil {
+		t.Errorf("bolt.DeleteGraph: should never fail to delete an existing graph; %v", err)
+	}
+	if _, err := s.Graph(ctx, "?test"); err == nil {
+		t.Errorf("bolt.Graph: should never succeed to get a non existing graph")
+	}
+	if err := s.DeleteGraph(ctx, "?test"); err == nil {
+		t.Errorf("bolt.DeleteGraph: should never succeed to delete a non existing graph")
+	}
+}
+
+func TestLookups(t *testing.T) {
+	s, _, clean := newTestStore(t)
+	defer clean()
+	ctx, ts := context.Background(), getTestTriples(t)
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	john, unknown := ts[0].Subject(), node.NewBlankNode()
+	parentOf, met := ts[0].Predicate(), ts[7].Predicate()
+	oMary := ts[0].Object()
+	// objects adapts the objects lookup to return the matching triples.
+	objects := func(s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions) func(chan<- *triple.Triple) error {
+		return func(res chan<- *triple.Triple) error {
+			defer close(res)
+			objs := make(chan *triple.Object, 100)
+			if err := g.Objects(ctx, s, p, lo, objs); err != nil {
+				return err
+			}
+			for o := range objs {
+				trpl, err := triple.New(s, p, o)
+				if err != nil {
+					return err
+				}
+				res <- trpl
+			}
+			return nil
+		}
+	}
+	lo := storage.DefaultLookup
+	testTable := []struct {
+		name   string
+		lookup func(chan<- *triple.Triple) error
+		want   int
+	}{
+		{"Triples", func(c chan<- *triple.Triple) error { return g.Triples(ctx, lo, c) }, 10},
+		{"TriplesForSubject", func(c chan<- *triple.Triple) error { return g.TriplesForSubject(ctx, john, lo, c) }, 7},
+		{"TriplesForPredicate", func(c chan<- *triple.Triple) error { return g.TriplesForPredicate(ctx, parentOf, lo, c) }, 5},
+		{"TriplesForObject", func(c chan<- *triple.Triple) error { return g.TriplesForObject(ctx, oMary, lo, c) }, 3},
+		{"TriplesForSubjectAndPredicate", func(c chan<- *triple.Triple) error {
+			return g.TriplesForSubjectAndPredicate(ctx, john, parentOf, lo, c)
+		}, 3},
+		{"TriplesForPredicateAndObject", func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicateAndObject(ctx, parentOf, oMary, lo, c)
+		}, 1},
+		{"TriplesForSubjectAndPredicate anchored", func(c chan<- *triple.Triple) error {
+			return g.TriplesForSubjectAndPredicate(ctx, john, met, lo, c)
+		}, 1},
+		{"Objects", objects(john, parentOf, lo), 3},
+		{"Objects latest", objects(john, met, &storage.LookupOptions{LatestAnchor: true}), 1},
+		{"Triples limit", func(c chan<- *triple.Triple) error {
+			return g.Triples(ctx, &storage.LookupOptions{MaxElements: 4}, c)
+		}, 4},
+		{"TriplesForSubject time bounds", func(c chan<- *triple.Triple) error {
+			lb := time.Date(2016, 4, 10, 4, 22, 0, 0, time.UTC)
+			return g.TriplesForSubject(ctx, john, &storage.LookupOptions{LowerAnchor: &lb}, c)
+		}, 6},
+		{"TriplesForSubject latest", func(c chan<- *triple.Triple) error {
+			return g.TriplesForSubject(ctx, john, &storage.LookupOptions{LatestAnchor: true}, c)
+		}, 1},
+		{"TriplesForPredicate literal filter", func(c chan<- *triple.Triple) error {
+			l, _ := literal.DefaultBuilder().Build(literal.Int64, int64(160))
+			return g.TriplesForPredicate(ctx, ts[5].Predicate(), &storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Lower: l}}, c)
+		}, 1},
+		{"TriplesForSubject unknown", func(c chan<- *triple.Triple) error {
+			return g.TriplesForSubject(ctx, unknown, lo, c)
+		}, 0},
+	}
+	for _, entry := range testTable {
+		if got := storagetest.Collect(t, entry.lookup); len(got) != entry.want {
+			t.Errorf("%s returned %d triples %v; want %d", entry.name, len(got), got, entry.want)
+		}
+	}
+}
+
+func TestPredicatesAndSubjects(t *testing.T) {
+	s, _, clean := newTestStore(t)
+	defer clean()
+	ctx, ts := context.Background(), getTestTriples(t)
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	john, oMary := ts[0].Subject(), ts[0].Object()
+	lo := storage.DefaultLookup
+	testTable := []struct {
+		name   string
+		lookup func(chan<- *predicate.Predicate) error
+		want   int
+	}{
+		{"PredicatesForSubject", func(c chan<- *predicate.Predicate) error { return g.PredicatesForSubject(ctx, john, lo, c) }, 7},
+		{"PredicatesForObject", func(c chan<- *predicate.Predicate) error { return g.PredicatesForObject(ctx, oMary, lo, c) }, 3},
+		{"PredicatesForSubjectAndObject", func(c chan<- *predicate.Predicate) error {
+			return g.PredicatesForSubjectAndObject(ctx, john, oMary, lo, c)
+		}, 3},
+		{"PredicatesForSubjectAndObject latest", func(c chan<- *predicate.Predicate) error {
+			return g.PredicatesForSubjectAndObject(ctx, john, oMary, &storage.LookupOptions{LatestAnchor: true}, c)
+		}, 1},
+	}
+	for _, entry := range testTable {
+		prds := make(chan *predicate.Predicate, 100)
+		if err := entry.lookup(prds); err != nil {
+			t.Fatalf("%s failed with error %v", entry.name, err)
+		}
+		cnt := 0
+		for range prds {
+			cnt++
+		}
+		if cnt != entry.want {
+			t.Errorf("%s returned %d predicates; want %d", entry.name, cnt, entry.want)
+		}
+	}
+	subjs := make(chan *node.Node, 100)
+	if err := g.Subjects(ctx, ts[0].Predicate(), oMary, lo, subjs); err != nil {
+		t.Fatalf("g.Subjects failed with error %v", err)
+	}
+	var got []string
+	for n := range subjs {
+		got = append(got, n.String())
+	}
+	if len(got) != 1 || got[0] != john.String() {
+		t.Errorf("g.Subjects(%s, %s) returned %v; want [%s]", ts[0].Predicate(), oMary, got, john)
+	}
+}
+
+func TestAddRemoveExist(t *testing.T) {
+	s, _, clean := newTestStore(t)
+	defer clean()
+	ctx, ts := context.Background(), getTestTriples(t)
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Adding existing triples does not duplicate them.
+	for i := 0; i < 2; i++ {
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatalf("g.AddTriples failed with error %v", err)
+		}
+	}
+	if n, err := storage.CountTriples(ctx, g); err != nil || n != int64(len(ts)) {
+		t.Errorf("storage.CountTriples returned (%d, %v); want (%d, nil)", n, err, len(ts))
+	}
+	if err := g.RemoveTriples(ctx, ts[:3]); err != nil {
+		t.Fatalf("g.RemoveTriples failed with error %v", err)
+	}
+	for i, trpl := range ts {
+		ok, err := g.Exist(ctx, trpl)
+		if err != nil {
+			t.Fatalf("g.Exist(%s) failed with error %v", trpl, err)
+		}
+		if want := i >= 3; ok != want {
+			t.Errorf("g.Exist(%s) returned %v; want %v", trpl, ok, want)
+		}
+	}
+	// The indices no longer return the removed triples.
+	got := storagetest.Collect(t, func(c chan<- *triple.Triple) error {
+		return g.TriplesForPredicate(ctx, ts[0].Predicate(), storage.DefaultLookup, c)
+	})
+	if len(got) != 2 {
+		t.Errorf("g.TriplesForPredicate(%s) returned %v after removing triples; want 2 triples", ts[0].Predicate(), got)
+	}
+	// Removing missing triples does not fail.
+	if err := g.RemoveTriples(ctx, ts[:3]); err != nil {
+		t.Errorf("g.RemoveTriples failed to remove missing triples with error %v", err)
+	}
+}
+
+func TestPersistenceAndEpoch(t *testing.T) {
+	// The store is reopened, so it is closed by the test.
+	s, path, _ := newTestStore(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	ctx, ts := context.Background(), getTestTriples(t)
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	epoch := func(g storage.Graph) uint64 {
+		e, err := storage.Epoch(ctx, g)
+		if err != nil {
+			t.Fatalf("storage.Epoch failed with error %v", err)
+		}
+		return e
+	}
+	e0 := epoch(g)
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	e1 := epoch(g)
+	if e1 == e0 {
+		t.Errorf("storage.Epoch did not change after adding triples")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("bolt.Close failed with error %v", err)
+	}
+
+	// Reopening the database keeps the graphs, their triples, and epochs.
+	s, err = NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore(%q) failed with error %v", path, err)
+	}
+	defer s.Close()
+	if g, err = s.Graph(ctx, "?test"); err != nil {
+		t.Fatalf("bolt.Graph failed to get a persisted graph with error %v", err)
+	}
+	if got := epoch(g); got != e1 {
+		t.Errorf("storage.Epoch returned %d after reopening the store; want %d", got, e1)
+	}
+	got := storagetest.Collect(t, func(c chan<- *triple.Triple) error { return g.Triples(ctx, storage.DefaultLookup, c) })
+	if len(got) != len(ts) {
+		t.Errorf("g.Triples returned %d triples after reopening the store; want %d", len(got), len(ts))
+	}
+	// Graphs recreated with the same ID do not reuse epochs.
+	if err := s.DeleteGraph(ctx, "?test"); err != nil {
+		t.Fatal(err)
+	}
+	if g, err = s.NewGraph(ctx, "?test"); err != nil {
+		t.Fatal(err)
+	}
+	if got := epoch(g); got == e0 || got == e1 {
+		t.Errorf("storage.Epoch returned an already used epoch %d for a recreated graph", got)
+	}
+}
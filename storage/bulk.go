@@ -0,0 +1,170 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/badwolf/triple"
+)
+
+// DefaultBulkBatchSize is the number of triples written in each batch by
+// BulkLoad when the options do not set one.
+const DefaultBulkBatchSize = 1000
+
+// BulkLoadOptions configures how BulkLoad writes triples.
+type BulkLoadOptions struct {
+	// BatchSize is the number of triples written in each batch. It defaults to
+	// DefaultBulkBatchSize.
+	BatchSize int
+
+	// Workers is the number of batches written concurrently. It defaults to
+	// one.
+	Workers int
+
+	// Progress, if set, is called once for every batch written, successfully
+	// or not. It is never called concurrently.
+	Progress func(*BatchResult)
+}
+
+// BatchResult reports the outcome of writing a batch of triples during a bulk
+// load.
+type BatchResult struct {
+	// Batch is the sequence number of the batch, starting at zero, in the order
+	// its triples were read. With several workers, batches may finish out of
+	// order.
+	Batch int
+
+	// Triples is the number of triples in the batch.
+	Triples int
+
+	// Loaded is the total number of triples written by the batches that
+	// succeeded so far, including this one.
+	Loaded int64
+
+	// Err is the error returned writing the batch, if any.
+	Err error
+}
+
+// BulkLoader is an optional interface that graphs can implement to provide
+// their own way of loading large amounts of triples.
+type BulkLoader interface {
+	// BulkLoad adds to the graph all the triples received from the channel
+	// until it is closed.
+	BulkLoad(ctx context.Context, ts <-chan *triple.Triple, opts *BulkLoadOptions) error
+}
+
+// BulkLoad adds to the provided graph all the triples received from the
+// channel until it is closed. If the graph does not implement BulkLoader, the
+// triples are grouped in batches that are written with AddTriples by
+// opts.Workers concurrent workers; graphs are safe for concurrent use, and
+// drivers do the expensive work of computing keys before serializing on their
+// indices.
+//
+// BulkLoad returns the first error writing a batch. It stops reading from the
+// channel once a batch fails or the context is done, so producers should stop
+// sending triples when that happens. Batches already written are not undone.
+func BulkLoad(ctx context.Context, g Graph, ts <-chan *triple.Triple, opts *BulkLoadOptions) error {
+	if bl, ok := g.(BulkLoader); ok {
+		return bl.BulkLoad(ctx, ts, opts)
+	}
+	if opts == nil {
+		opts = &BulkLoadOptions{}
+	}
+	size, workers := opts.BatchSize, opts.Workers
+	if size <= 0 {
+		size = DefaultBulkBatchSize
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	pctx := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type batch struct {
+		n  int
+		ts []*triple.Triple
+	}
+	var (
+		mu     sync.Mutex
+		loaded int64
+		first  error
+	)
+	report := func(b *batch, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err == nil {
+			loaded += int64(len(b.ts))
+		} else if first == nil {
+			first = err
+			cancel()
+		}
+		if opts.Progress != nil {
+			opts.Progress(&BatchResult{Batch: b.n, Triples: len(b.ts), Loaded: loaded, Err: err})
+		}
+	}
+
+	var wg sync.WaitGroup
+	batches := make(chan *batch)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				report(b, g.AddTriples(ctx, b.ts))
+			}
+		}()
+	}
+	send := func(b *batch) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case batches <- b:
+			return true
+		}
+	}
+
+	cur := &batch{ts: make([]*triple.Triple, 0, size)}
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case t, ok := <-ts:
+			if !ok {
+				if len(cur.ts) > 0 {
+					send(cur)
+				}
+				break loop
+			}
+			cur.ts = append(cur.ts, t)
+			if len(cur.ts) < size {
+				continue
+			}
+			if !send(cur) {
+				break loop
+			}
+			cur = &batch{n: cur.n + 1, ts: make([]*triple.Triple, 0, size)}
+		}
+	}
+	close(batches)
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	return pctx.Err()
+}
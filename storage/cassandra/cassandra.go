@@ -0,0 +1,526 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cassandra provides a persistent implementation of the
+// storage.Store and storage.Graph interfaces on top of Cassandra, a wide
+// column store able to hold temporal graphs too large for a single machine.
+//
+// Triples are stored once per lookup pattern in tables whose partition keys
+// spread the graph across the cluster and whose clustering keys keep related
+// triples together. All the triples of a subject are stored in the same
+// partition ordered by predicate and time anchor, and so are the triples of a
+// predicate, and of a predicate and object, ordered by time anchor. Lookups
+// bounded by time anchors only read the requested time window of a single
+// partition.
+package cassandra
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gocql/gocql"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/driver"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// schema contains the statements creating the tables used by the driver, if
+// they do not exist yet. Time anchors are encoded with
+// driver.PredicateAnchor, so immutable predicates sort before temporal ones,
+// and temporal ones sort by time anchor, followed by interval ones.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS badwolf_graphs (
+		id text PRIMARY KEY
+	)`,
+	// Triples are spread over the buckets of their graph by the first byte of
+	// their UUID.
+	`CREATE TABLE IF NOT EXISTS badwolf_triples (
+		graph text, bucket int, id blob, data blob,
+		PRIMARY KEY ((graph, bucket), id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS badwolf_triples_by_subject (
+		graph text, subject blob, predicate blob, anchor blob, object blob, id blob, data blob,
+		PRIMARY KEY ((graph, subject), predicate, anchor, object, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS badwolf_triples_by_predicate (
+		graph text, predicate blob, anchor blob, subject blob, object blob, id blob, data blob,
+		PRIMARY KEY ((graph, predicate), anchor, subject, object, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS badwolf_triples_by_object (
+		graph text, object blob, subject blob, predicate blob, anchor blob, id blob, data blob,
+		PRIMARY KEY ((graph, object), subject, predicate, anchor, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS badwolf_triples_by_predicate_object (
+		graph text, predicate blob, object blob, anchor blob, subject blob, id blob, data blob,
+		PRIMARY KEY ((graph, predicate, object), anchor, subject, id)
+	)`,
+}
+
+// buckets is the number of partitions of the triples table used by each
+// graph.
+const buckets = 256
+
+// Store provides a persistent store backed by a Cassandra keyspace.
+type Store struct {
+	session *gocql.Session
+}
+
+// NewStore returns a store using the keyspace of the provided session,
+// creating the tables needed if they do not exist yet. The caller owns the
+// session and needs to close it once the store is no longer used.
+func NewStore(session *gocql.Session) (*Store, error) {
+	for _, stmt := range schema {
+		if err := session.Query(stmt).Exec(); err != nil {
+			return nil, fmt.Errorf("cassandra.NewStore: failed to create the schema; %v", err)
+		}
+	}
+	return &Store{session: session}, nil
+}
+
+// Name returns the ID of the backend being used.
+func (s *Store) Name(ctx context.Context) string {
+	return "CASSANDRA"
+}
+
+// Version returns the version of the driver implementation.
+func (s *Store) Version(ctx context.Context) string {
+	return "0.1.cassandra"
+}
+
+// NewGraph creates a new graph.
+func (s *Store) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	var existing string
+	applied, err := s.session.Query(`INSERT INTO badwolf_graphs (id) VALUES (?) IF NOT EXISTS`, id).WithContext(ctx).ScanCAS(&existing)
+	if err != nil {
+		return nil, fmt.Errorf("cassandra.NewGraph(%q): %v", id, err)
+	}
+	if !applied {
+		return nil, fmt.Errorf("cassandra.NewGraph(%q): graph already exists", id)
+	}
+	return &graph{id: id, s: s}, nil
+}
+
+// Graph returns an existing graph if available. Getting a non existing
+// graph should return an error.
+func (s *Store) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	var existing string
+	err := s.session.Query(`SELECT id FROM badwolf_graphs WHERE id = ?`, id).WithContext(ctx).Scan(&existing)
+	if err == gocql.ErrNotFound {
+		return nil, fmt.Errorf("cassandra.Graph(%q): graph does not exist", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cassandra.Graph(%q): %v", id, err)
+	}
+	return &graph{id: id, s: s}, nil
+}
+
+// DeleteGraph deletes an existing graph. Deleting a non existing graph
+// should return an error. The partitions holding the triples of the graph
+// are deleted before the graph, so a failure never leaves them around for a
+// graph recreated with the same ID.
+func (s *Store) DeleteGraph(ctx context.Context, id string) error {
+	if _, err := s.Graph(ctx, id); err != nil {
+		return fmt.Errorf("cassandra.DeleteGraph(%q): graph does not exist", id)
+	}
+	g := &graph{id: id, s: s}
+	ts, err := g.all(ctx)
+	if err != nil {
+		return fmt.Errorf("cassandra.DeleteGraph(%q): %v", id, err)
+	}
+	parts := make(map[string][]interface{})
+	for _, t := range ts {
+		sb, pb, ob := []byte(t.Subject().UUID()), []byte(t.Predicate().PartialUUID()), []byte(t.Object().UUID())
+		parts[fmt.Sprintf("s%x", sb)] = []interface{}{`DELETE FROM badwolf_triples_by_subject WHERE graph = ? AND subject = ?`, id, sb}
+		parts[fmt.Sprintf("p%x", pb)] = []interface{}{`DELETE FROM badwolf_triples_by_predicate WHERE graph = ? AND predicate = ?`, id, pb}
+		parts[fmt.Sprintf("o%x", ob)] = []interface{}{`DELETE FROM badwolf_triples_by_object WHERE graph = ? AND object = ?`, id, ob}
+		parts[fmt.Sprintf("q%x%x", pb, ob)] = []interface{}{`DELETE FROM badwolf_triples_by_predicate_object WHERE graph = ? AND predicate = ? AND object = ?`, id, pb, ob}
+	}
+	for b := 0; b < buckets; b++ {
+		parts[fmt.Sprintf("t%d", b)] = []interface{}{`DELETE FROM badwolf_triples WHERE graph = ? AND bucket = ?`, id, b}
+	}
+	for _, p := range parts {
+		if err := s.session.Query(p[0].(string), p[1:]...).WithContext(ctx).Exec(); err != nil {
+			return fmt.Errorf("cassandra.DeleteGraph(%q): %v", id, err)
+		}
+	}
+	if err := s.session.Query(`DELETE FROM badwolf_graphs WHERE id = ? IF EXISTS`, id).WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("cassandra.DeleteGraph(%q): %v", id, err)
+	}
+	return nil
+}
+
+// GraphNames returns the current available graph names in the store.
+func (s *Store) GraphNames(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(names)
+	var (
+		ns []string
+		n  string
+	)
+	iter := s.session.Query(`SELECT id FROM badwolf_graphs`).WithContext(ctx).Iter()
+	for iter.Scan(&n) {
+		ns = append(ns, n)
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	sort.Strings(ns)
+	for _, n := range ns {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case names <- n:
+		}
+	}
+	return nil
+}
+
+// graph provides a Cassandra-based persistent implementation of the graph
+// API.
+type graph struct {
+	id string
+	s  *Store
+}
+
+// ID returns the id for this graph.
+func (g *graph) ID(ctx context.Context) string {
+	return g.id
+}
+
+// bucket returns the bucket of the triples table holding the provided triple.
+func bucket(t *triple.Triple) int {
+	return int(t.UUID()[0]) % buckets
+}
+
+// AddTriples adds the triples to the storage. The rows of each triple are
+// written in a logged batch, so either all or none of them are eventually
+// applied.
+func (g *graph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	for _, t := range ts {
+		data, err := driver.EncodeTriple(t)
+		if err != nil {
+			return err
+		}
+		id, a := []byte(t.UUID()), driver.PredicateAnchor(t.Predicate())
+		sb, pb, ob := []byte(t.Subject().UUID()), []byte(t.Predicate().PartialUUID()), []byte(t.Object().UUID())
+		b := g.s.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+		b.Query(`INSERT INTO badwolf_triples (graph, bucket, id, data) VALUES (?, ?, ?, ?)`, g.id, bucket(t), id, data)
+		b.Query(`INSERT INTO badwolf_triples_by_subject (graph, subject, predicate, anchor, object, id, data) VALUES (?, ?, ?, ?, ?, ?, ?)`, g.id, sb, pb, a, ob, id, data)
+		b.Query(`INSERT INTO badwolf_triples_by_predicate (graph, predicate, anchor, subject, object, id, data) VALUES (?, ?, ?, ?, ?, ?, ?)`, g.id, pb, a, sb, ob, id, data)
+		b.Query(`INSERT INTO badwolf_triples_by_object (graph, object, subject, predicate, anchor, id, data) VALUES (?, ?, ?, ?, ?, ?, ?)`, g.id, ob, sb, pb, a, id, data)
+		b.Query(`INSERT INTO badwolf_triples_by_predicate_object (graph, predicate, object, anchor, subject, id, data) VALUES (?, ?, ?, ?, ?, ?, ?)`, g.id, pb, ob, a, sb, id, data)
+		if err := g.s.session.ExecuteBatch(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveTriples removes the triples from the storage. The rows of each triple
+// are deleted in a logged batch, so either all or none of them are
+// eventually deleted.
+func (g *graph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	for _, t := range ts {
+		id, a := []byte(t.UUID()), driver.PredicateAnchor(t.Predicate())
+		sb, pb, ob := []byte(t.Subject().UUID()), []byte(t.Predicate().PartialUUID()), []byte(t.Object().UUID())
+		b := g.s.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+		b.Query(`DELETE FROM badwolf_triples WHERE graph = ? AND bucket = ? AND id = ?`, g.id, bucket(t), id)
+		b.Query(`DELETE FROM badwolf_triples_by_subject WHERE graph = ? AND subject = ? AND predicate = ? AND anchor = ? AND object = ? AND id = ?`, g.id, sb, pb, a, ob, id)
+		b.Query(`DELETE FROM badwolf_triples_by_predicate WHERE graph = ? AND predicate = ? AND anchor = ? AND subject = ? AND object = ? AND id = ?`, g.id, pb, a, sb, ob, id)
+		b.Query(`DELETE FROM badwolf_triples_by_object WHERE graph = ? AND object = ? AND subject = ? AND predicate = ? AND anchor = ? AND id = ?`, g.id, ob, sb, pb, a, id)
+		b.Query(`DELETE FROM badwolf_triples_by_predicate_object WHERE graph = ? AND predicate = ? AND object = ? AND anchor = ? AND subject = ? AND id = ?`, g.id, pb, ob, a, sb, id)
+		if err := g.s.session.ExecuteBatch(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// successor returns the first value that is greater than all the values
+// starting with the provided prefix, or nil if there is none.
+func successor(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			s := append([]byte{}, prefix[:i+1]...)
+			s[i]++
+			return s
+		}
+	}
+	return nil
+}
+
+// anchorClause returns the CQL condition restricting the anchor column to the
+// values in the provided range, and its arguments.
+func anchorClause(r driver.KeyRange) (string, []interface{}) {
+	var (
+		cql  string
+		args []interface{}
+	)
+	if min := r.Start; min != nil || len(r.Prefix) > 0 {
+		if min == nil {
+			min = r.Prefix
+		}
+		cql, args = cql+" AND anchor >= ?", append(args, min)
+	}
+	max := r.End
+	if max == nil {
+		max = r.Prefix
+	}
+	if s := successor(max); s != nil {
+		cql, args = cql+" AND anchor < ?", append(args, s)
+	}
+	return cql, args
+}
+
+// lookup returns the triples of the provided table matching the condition
+// that pass the lookup options. If anchored is true, the condition needs to
+// restrict all the clustering columns before the anchor and the anchors read
+// are restricted to the ones allowed by the predicate and lookup options.
+func (g *graph) lookup(ctx context.Context, table, cond string, args []interface{}, anchored bool, lo *storage.LookupOptions, op *predicate.Predicate) ([]*triple.Triple, error) {
+	rs := []driver.KeyRange{{}}
+	if anchored {
+		rs = driver.AnchorRanges(nil, lo, op)
+	}
+	var ts []*triple.Triple
+	for _, r := range rs {
+		ac, aargs := anchorClause(r)
+		qargs := append(append([]interface{}{g.id}, args...), aargs...)
+		iter := g.s.session.Query(`SELECT data FROM `+table+` WHERE graph = ?`+cond+ac, qargs...).WithContext(ctx).Iter()
+		var data []byte
+		for iter.Scan(&data) {
+			t, err := driver.DecodeTriple(data)
+			if err != nil {
+				iter.Close()
+				return nil, err
+			}
+			ts = append(ts, t)
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return driver.Filter(ts, lo, op)
+}
+
+// all returns all the triples of the graph.
+func (g *graph) all(ctx context.Context) ([]*triple.Triple, error) {
+	var ts []*triple.Triple
+	for b := 0; b < buckets; b++ {
+		bts, err := g.lookup(ctx, "badwolf_triples", " AND bucket = ?", []interface{}{b}, false, storage.DefaultLookup, nil)
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, bts...)
+	}
+	return ts, nil
+}
+
+// Objects publishes the objects for the given subject and predicate to the
+// provided channel.
+func (g *graph) Objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	if objs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(objs)
+	ts, err := g.lookup(ctx, "badwolf_triples_by_subject", " AND subject = ? AND predicate = ?", []interface{}{[]byte(s.UUID()), []byte(p.PartialUUID())}, true, lo, p)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case objs <- t.Object():
+		}
+	}
+	return nil
+}
+
+// Subjects publishes the subjects for the given predicate and object to the
+// provided channel.
+func (g *graph) Subjects(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, subjs chan<- *node.Node) error {
+	if subjs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(subjs)
+	ts, err := g.lookup(ctx, "badwolf_triples_by_predicate_object", " AND predicate = ? AND object = ?", []interface{}{[]byte(p.PartialUUID()), []byte(o.UUID())}, true, lo, p)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case subjs <- t.Subject():
+		}
+	}
+	return nil
+}
+
+// PredicatesForSubjectAndObject publishes all predicates available for the
+// given subject and object to the provided channel.
+func (g *graph) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.lookup(ctx, "badwolf_triples_by_object", " AND object = ? AND subject = ?", []interface{}{[]byte(o.UUID()), []byte(s.UUID())}, false, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// PredicatesForSubject publishes all the predicates known for the given
+// subject to the provided channel.
+func (g *graph) PredicatesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.lookup(ctx, "badwolf_triples_by_subject", " AND subject = ?", []interface{}{[]byte(s.UUID())}, false, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// PredicatesForObject publishes all the predicates known for the given object
+// to the provided channel.
+func (g *graph) PredicatesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.lookup(ctx, "badwolf_triples_by_object", " AND object = ?", []interface{}{[]byte(o.UUID())}, false, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// TriplesForSubject publishes all triples available for the given subject to
+// the provided channel.
+func (g *graph) TriplesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "badwolf_triples_by_subject", " AND subject = ?", []interface{}{[]byte(s.UUID())}, false, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForPredicate publishes all triples available for the given predicate
+// to the provided channel.
+func (g *graph) TriplesForPredicate(ctx context.Context, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "badwolf_triples_by_predicate", " AND predicate = ?", []interface{}{[]byte(p.PartialUUID())}, true, lo, p)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForObject publishes all triples available for the given object to the
+// provided channel.
+func (g *graph) TriplesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "badwolf_triples_by_object", " AND object = ?", []interface{}{[]byte(o.UUID())}, false, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForSubjectAndPredicate publishes all triples available for the given
+// subject and predicate to the provided channel.
+func (g *graph) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "badwolf_triples_by_subject", " AND subject = ? AND predicate = ?", []interface{}{[]byte(s.UUID()), []byte(p.PartialUUID())}, true, lo, p)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForPredicateAndObject publishes all triples available for the given
+// predicate and object to the provided channel.
+func (g *graph) TriplesForPredicateAndObject(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "badwolf_triples_by_predicate_object", " AND predicate = ? AND object = ?", []interface{}{[]byte(p.PartialUUID()), []byte(o.UUID())}, true, lo, p)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// Exist checks if the provided triple exists on the store.
+func (g *graph) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
+	var id []byte
+	err := g.s.session.Query(`SELECT id FROM badwolf_triples WHERE graph = ? AND bucket = ? AND id = ?`, g.id, bucket(t), []byte(t.UUID())).WithContext(ctx).Scan(&id)
+	if err == gocql.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Triples allows to iterate over all available triples by pushing them to the
+// provided channel.
+func (g *graph) Triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.all(ctx)
+	if err != nil {
+		return err
+	}
+	if ts, err = driver.Filter(ts, lo, nil); err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// CountTriples returns the number of triples in the graph.
+func (g *graph) CountTriples(ctx context.Context) (int64, error) {
+	var n int64
+	for b := 0; b < buckets; b++ {
+		var c int64
+		if err := g.s.session.Query(`SELECT count(*) FROM badwolf_triples WHERE graph = ? AND bucket = ?`, g.id, b).WithContext(ctx).Scan(&c); err != nil {
+			return 0, err
+		}
+		n += c
+	}
+	return n, nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassandra
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/driver"
+	"github.com/google/badwolf/storage/internal/storagetest"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+	"github.com/pborman/uuid"
+)
+
+func TestAnchorClauses(t *testing.T) {
+	lower, upper := time.Unix(-10, 500), time.Unix(1460262600, 1)
+	temporal, err := predicate.NewTemporal("met", upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interval, err := predicate.NewInterval("met", lower, upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	immutable := []interface{}{[]byte{0}, []byte{1}}
+	// Interval anchors sort after all the other temporal ones.
+	intervalAnchor := func(ta time.Time) []byte {
+		a := driver.EncodeAnchor(&ta)
+		a[0] = 2
+		return a
+	}
+	const clause = " AND anchor >= ? AND anchor < ?"
+	testTable := []struct {
+		lo   *storage.LookupOptions
+		op   *predicate.Predicate
+		want []string
+		args [][]interface{}
+	}{
+		{storage.DefaultLookup, nil, []string{""}, [][]interface{}{nil}},
+		{
+			lo:   storage.DefaultLookup,
+			op:   temporal,
+			want: []string{" AND anchor >= ? AND anchor < ?", " AND anchor >= ? AND anchor < ?"},
+			args: [][]interface{}{immutable, {driver.EncodeAnchor(&upper), successor(driver.EncodeAnchor(&upper))}},
+		},
+		{
+			lo:   storage.DefaultLookup,
+			op:   interval,
+			want: []string{clause, clause},
+			args: [][]interface{}{immutable, {intervalAnchor(lower), successor(intervalAnchor(lower))}},
+		},
+		{
+			lo:   &storage.LookupOptions{LowerAnchor: &lower},
+			want: []string{clause, clause, clause},
+			args: [][]interface{}{immutable, {driver.EncodeAnchor(&lower), []byte{2}}, {[]byte{2}, []byte{3}}},
+		},
+		{
+			lo:   &storage.LookupOptions{UpperAnchor: &upper},
+			want: []string{clause, clause, clause},
+			args: [][]interface{}{immutable, {[]byte{1}, successor(driver.EncodeAnchor(&upper))}, {[]byte{2}, successor(intervalAnchor(upper))}},
+		},
+		{&storage.LookupOptions{LowerAnchor: &lower, LatestAnchor: true}, temporal, []string{""}, [][]interface{}{nil}},
+	}
+	for _, entry := range testTable {
+		rs := driver.AnchorRanges(nil, entry.lo, entry.op)
+		if len(rs) != len(entry.want) {
+			t.Fatalf("driver.AnchorRanges(%s, %v) returned %d ranges; want %d", entry.lo, entry.op, len(rs), len(entry.want))
+		}
+		for i, r := range rs {
+			got, args := anchorClause(r)
+			if got != entry.want[i] || len(args) != len(entry.args[i]) {
+				t.Errorf("anchorClause(%q) returned (%q, %v); want (%q, %v)", r, got, args, entry.want[i], entry.args[i])
+				continue
+			}
+			for j, a := range args {
+				if !bytes.Equal(a.([]byte), entry.args[i][j].([]byte)) {
+					t.Errorf("anchorClause(%q) returned argument %d %x; want %x", r, j, a, entry.args[i][j])
+				}
+			}
+		}
+	}
+}
+
+// newTestStore returns a store using the keyspace provided by the
+// BADWOLF_CASSANDRA_KEYSPACE environment variable in the cluster whose
+// comma separated hosts are provided by BADWOLF_CASSANDRA_HOSTS, and a unique
+// prefix for the graph IDs of the test. The test is skipped if the variables
+// are not set.
+func newTestStore(t *testing.T) (*Store, string) {
+	hosts, ks := os.Getenv("BADWOLF_CASSANDRA_HOSTS"), os.Getenv("BADWOLF_CASSANDRA_KEYSPACE")
+	if hosts == "" || ks == "" {
+		t.Skip("BADWOLF_CASSANDRA_HOSTS or BADWOLF_CASSANDRA_KEYSPACE are not set")
+	}
+	cluster := gocql.NewCluster(strings.Split(hosts, ",")...)
+	cluster.Keyspace = ks
+	session, err := cluster.CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStore(session)
+	if err != nil {
+		session.Close()
+		t.Fatalf("NewStore failed with error %v", err)
+	}
+	return s, "?" + uuid.New() + "_"
+}
+
+func getTestTriples(t *testing.T) []*triple.Triple {
+	var ts []*triple.Triple
+	for _, s := range []string{
+		"/u<john>\t\"parent_of\"@[]\t/u<mary>",
+		"/u<john>\t\"parent_of\"@[]\t/u<peter>",
+		"/u<john>\t\"parent_of\"@[]\t/u<alice>",
+		"/u<mary>\t\"parent_of\"@[]\t/u<amy>",
+		"/u<john>\t\"met\"@[1960-04-10T04:21:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[1969-12-31T23:59:59.5Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T04:25:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T04:30:00Z]\t/u<peter>",
+		"/u<john>\t\"met\"@[2016-04-10T04:30:00.000000001Z]\t/u<alice>",
+	} {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %q with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+func TestCassandraStore(t *testing.T) {
+	s, prefix := newTestStore(t)
+	defer s.session.Close()
+	ctx, id := context.Background(), prefix+"test"
+	if _, err := s.NewGraph(ctx, id); err != nil {
+		t.Errorf("cassandra.NewGraph: should never fail to create a graph; %v", err)
+	}
+	if _, err := s.NewGraph(ctx, id); err == nil {
+		t.Errorf("cassandra.NewGraph: should never succeed to create an existing graph")
+	}
+	if _, err := s.Graph(ctx, id); err != nil {
+		t.Errorf("cassandra.Graph: should never fail to get an existing graph; %v", err)
+	}
+	if err := s.DeleteGraph(ctx, id); err != nil {
+		t.Errorf("cassandra.DeleteGraph: should never fail to delete an existing graph; %v", err)
+	}
+	if _, err := s.Graph(ctx, id); err == nil {
+		t.Errorf("cassandra.Graph: should never succeed to get a non existing graph")
+	}
+	if err := s.DeleteGraph(ctx, id); err == nil {
+		t.Errorf("cassandra.DeleteGraph: should never succeed to delete a non existing graph")
+	}
+}
+
+func TestLookups(t *testing.T) {
+	s, prefix := newTestStore(t)
+	defer s.session.Close()
+	ctx, ts, id := context.Background(), getTestTriples(t), prefix+"test"
+	g, err := s.NewGraph(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.DeleteGraph(ctx, id)
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	john, mary := ts[0].Subject(), ts[0].Object()
+	parentOf, met := ts[0].Predicate(), ts[4].Predicate()
+	at := func(s string) *time.Time {
+		ta, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &ta
+	}
+	lo := storage.DefaultLookup
+	testTable := []struct {
+		name   string
+		lookup func(chan<- *triple.Triple) error
+		want   int
+	}{
+		{"Triples", func(c chan<- *triple.Triple) error { return g.Triples(ctx, lo, c) }, 9},
+		{"TriplesForSubject", func(c chan<- *triple.Triple) error { return g.TriplesForSubject(ctx, john, lo, c) }, 8},
+		{"TriplesForObject", func(c chan<- *triple.Triple) error { return g.TriplesForObject(ctx, mary, lo, c) }, 4},
+		{"TriplesForPredicate", func(c chan<- *triple.Triple) error { return g.TriplesForPredicate(ctx, parentOf, lo, c) }, 4},
+		{"TriplesForPredicate anchored", func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicate(ctx, met, lo, c)
+		}, 1},
+		{"TriplesForSubjectAndPredicate window", func(c chan<- *triple.Triple) error {
+			p, _ := predicate.NewImmutable("met")
+			return g.TriplesForSubjectAndPredicate(ctx, john, p, &storage.LookupOptions{
+				LowerAnchor: at("1969-12-31T23:59:59Z"),
+				UpperAnchor: at("2016-04-10T04:30:00Z"),
+			}, c)
+		}, 3},
+		{"TriplesForPredicateAndObject before epoch", func(c chan<- *triple.Triple) error {
+			p, _ := predicate.NewImmutable("met")
+			return g.TriplesForPredicateAndObject(ctx, p, mary, &storage.LookupOptions{UpperAnchor: at("1970-01-01T00:00:00Z")}, c)
+		}, 2},
+		{"TriplesForSubject unknown", func(c chan<- *triple.Triple) error {
+			return g.TriplesForSubject(ctx, node.NewBlankNode(), lo, c)
+		}, 0},
+	}
+	for _, entry := range testTable {
+		if got := storagetest.Collect(t, entry.lookup); len(got) != entry.want {
+			t.Errorf("%s returned %d triples %v; want %d", entry.name, len(got), got, entry.want)
+		}
+	}
+	if err := g.RemoveTriples(ctx, ts[:3]); err != nil {
+		t.Fatalf("g.RemoveTriples failed with error %v", err)
+	}
+	for i, trpl := range ts {
+		if ok, err := g.Exist(ctx, trpl); err != nil || ok != (i >= 3) {
+			t.Errorf("g.Exist(%s) returned (%v, %v); want (%v, nil)", trpl, ok, err, i >= 3)
+		}
+	}
+	if n, err := storage.CountTriples(ctx, g); err != nil || n != int64(len(ts)-3) {
+		t.Errorf("storage.CountTriples returned (%d, %v); want (%d, nil)", n, err, len(ts)-3)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/badwolf/triple"
+)
+
+// ChangeOp is the operation done to the triples of a graph by a change.
+type ChangeOp uint8
+
+const (
+	// Added is the operation of the changes adding triples to a graph.
+	Added ChangeOp = iota
+	// Removed is the operation of the changes removing triples from a graph.
+	Removed
+)
+
+// String returns a readable version of the ChangeOp value.
+func (o ChangeOp) String() string {
+	switch o {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// Change describes triples added to or removed from a graph.
+type Change struct {
+	// Op is the operation done to the triples.
+	Op ChangeOp
+
+	// Triples are the triples added or removed. Only the triples that were
+	// not in the graph are reported as added, and only the ones that were in
+	// the graph as removed.
+	Triples []*triple.Triple
+}
+
+// ChangeFeed is an optional interface that graphs can implement to notify the
+// changes done to their triples.
+type ChangeFeed interface {
+	// Watch returns a channel where the changes done to the graph after Watch
+	// returns are pushed in the order they were done. The channel is closed
+	// once the context is done or the graph is deleted. Slow watchers never
+	// block the changes done to the graph; changes are queued for them.
+	Watch(ctx context.Context) (<-chan *Change, error)
+}
+
+// ErrNoChangeFeed is returned when watching a graph that does not implement
+// ChangeFeed.
+var ErrNoChangeFeed = errors.New("storage: the graph does not provide a change feed")
+
+// Watch returns a channel where the changes done to the provided graph are
+// pushed, as done by ChangeFeed. If the graph does not implement ChangeFeed,
+// ErrNoChangeFeed is returned.
+func Watch(ctx context.Context, g Graph) (<-chan *Change, error) {
+	if cf, ok := g.(ChangeFeed); ok {
+		return cf.Watch(ctx)
+	}
+	return nil, ErrNoChangeFeed
+}
+
+// ChangeNotifier dispatches the changes of a graph to its watchers, and can
+// be used by drivers to implement ChangeFeed. The zero value is ready to use,
+// and all the methods of a nil notifier do nothing.
+type ChangeNotifier struct {
+	mu       sync.Mutex
+	watchers map[*changeQueue]bool
+	closed   bool
+}
+
+// changeQueue keeps the changes not pushed to a watcher yet.
+type changeQueue struct {
+	mu      sync.Mutex
+	changes []*Change
+	// ready is signaled when changes are queued, and closed once the
+	// notifier is closed.
+	ready chan struct{}
+}
+
+// Notify queues the provided change for all the current watchers. Changes
+// without triples are dropped. Drivers must notify changes in the order they
+// are applied, usually while holding the locks that serialize them.
+func (n *ChangeNotifier) Notify(c *Change) {
+	if n == nil || len(c.Triples) == 0 {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for q := range n.watchers {
+		q.mu.Lock()
+		q.changes = append(q.changes, c)
+		q.mu.Unlock()
+		select {
+		case q.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Watched returns true if the notifier has watchers, so drivers can skip
+// building the changes nobody watches.
+func (n *ChangeNotifier) Watched() bool {
+	if n == nil {
+		return false
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.watchers) > 0
+}
+
+// Watch registers a new watcher, and returns the channel where the changes
+// notified from now on are pushed until the context is done or the notifier
+// is closed.
+func (n *ChangeNotifier) Watch(ctx context.Context) (<-chan *Change, error) {
+	if n == nil {
+		return nil, ErrNoChangeFeed
+	}
+	q := &changeQueue{ready: make(chan struct{}, 1)}
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return nil, errors.New("storage: the change feed was closed")
+	}
+	if n.watchers == nil {
+		n.watchers = make(map[*changeQueue]bool)
+	}
+	n.watchers[q] = true
+	n.mu.Unlock()
+
+	cs := make(chan *Change)
+	go func() {
+		defer close(cs)
+		defer func() {
+			n.mu.Lock()
+			delete(n.watchers, q)
+			n.mu.Unlock()
+		}()
+		for open := true; open; {
+			select {
+			case <-ctx.Done():
+				return
+			case _, open = <-q.ready:
+			}
+			q.mu.Lock()
+			pending := q.changes
+			q.changes = nil
+			q.mu.Unlock()
+			for _, c := range pending {
+				select {
+				case <-ctx.Done():
+					return
+				case cs <- c:
+				}
+			}
+		}
+	}()
+	return cs, nil
+}
+
+// Close closes the channels of all the watchers once the changes already
+// notified are pushed, and makes new calls to Watch fail. It is used when
+// the graph is deleted.
+func (n *ChangeNotifier) Close() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	n.closed = true
+	for q := range n.watchers {
+		close(q.ready)
+	}
+	n.watchers = nil
+}
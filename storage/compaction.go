@@ -0,0 +1,124 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Compacter is an optional interface that graphs can implement to release the
+// space left behind by removed triples.
+type Compacter interface {
+	// Compact rebuilds the internal structures of the graph so they only take
+	// the space needed by its current triples. Compacting does not change the
+	// triples of the graph.
+	Compact(ctx context.Context) error
+}
+
+// ErrNoCompaction is returned when compacting a graph that does not implement
+// Compacter.
+var ErrNoCompaction = errors.New("storage: the graph cannot be compacted")
+
+// Compact compacts the provided graph. If the graph does not implement
+// Compacter, ErrNoCompaction is returned.
+func Compact(ctx context.Context, g Graph) error {
+	if c, ok := g.(Compacter); ok {
+		return c.Compact(ctx)
+	}
+	return ErrNoCompaction
+}
+
+// Compactor compacts the graphs of a store in the background. Graphs that do
+// not implement Compacter are skipped.
+type Compactor struct {
+	store    Store
+	interval time.Duration
+
+	// OnCompact, if set, is called after compacting a graph with the error
+	// found, if any. It must be set before running the compactor.
+	OnCompact func(id string, err error)
+}
+
+// NewCompactor returns a compactor that compacts all the graphs of the
+// provided store every interval.
+func NewCompactor(s Store, interval time.Duration) *Compactor {
+	return &Compactor{
+		store:    s,
+		interval: interval,
+	}
+}
+
+// CompactAll compacts all the graphs of the store. Graphs deleted while
+// compacting are skipped. It returns the first error found, after compacting
+// all the other graphs.
+func (c *Compactor) CompactAll(ctx context.Context) error {
+	errs, names := make(chan error, 1), make(chan string)
+	go func() {
+		errs <- c.store.GraphNames(ctx, names)
+	}()
+	var ids []string
+	for id := range names {
+		ids = append(ids, id)
+	}
+	if err := <-errs; err != nil {
+		return err
+	}
+	sort.Strings(ids)
+
+	var first error
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		g, err := c.store.Graph(ctx, id)
+		if err != nil {
+			continue
+		}
+		err = Compact(ctx, g)
+		if err == ErrNoCompaction {
+			continue
+		}
+		if err != nil {
+			err = fmt.Errorf("storage: failed to compact graph %q; %v", id, err)
+			if first == nil {
+				first = err
+			}
+		}
+		if c.OnCompact != nil {
+			c.OnCompact(id, err)
+		}
+	}
+	return first
+}
+
+// Run compacts all the graphs of the store every interval until the context
+// is done. Errors are reported through OnCompact and do not stop the
+// compactor.
+func (c *Compactor) Run(ctx context.Context) error {
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			c.CompactAll(ctx)
+		}
+	}
+}
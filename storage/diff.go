@@ -0,0 +1,131 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/badwolf/triple"
+	"github.com/pborman/uuid"
+)
+
+// DiffOptions describes how the triples of two graphs are compared by Diff.
+type DiffOptions struct {
+	// IgnoreAnchors compares triples ignoring the time anchors of their
+	// predicates, so triples that only differ on them are considered equal.
+	// Immutable and temporal triples with the same predicate ID are equal too.
+	IgnoreAnchors bool
+}
+
+// Difference is a triple present in one of the graphs compared by Diff but
+// not in the other.
+type Difference struct {
+	// Triple is the triple only present in one of the graphs.
+	Triple *triple.Triple
+
+	// Left is true if the triple is only present in the first graph, and false
+	// if it is only present in the second one.
+	Left bool
+}
+
+// diffKey returns the key used to compare the provided triple.
+func diffKey(t *triple.Triple, opts *DiffOptions) string {
+	if !opts.IgnoreAnchors {
+		return string(t.UUID())
+	}
+	var buffer bytes.Buffer
+	buffer.Write([]byte(t.Subject().UUID()))
+	buffer.Write([]byte(t.Predicate().PartialUUID()))
+	buffer.Write([]byte(t.Object().UUID()))
+	return string(uuid.NewSHA1(uuid.NIL, buffer.Bytes()))
+}
+
+// Diff compares the triples of the two provided graphs and pushes into the
+// provided channel the triples present in one of them but not in the other,
+// closing it once done. The triples only present in a are pushed first,
+// followed by the ones only present in b. Graphs providing snapshots are
+// compared using a snapshot taken when Diff is called, so they can keep
+// changing while being compared; otherwise, changes applied while comparing
+// them may or may not be taken into account. The options can be nil to use
+// the defaults.
+//
+// Diff keeps the keys of the triples of both graphs in memory, and reads the
+// second graph twice.
+func Diff(ctx context.Context, a, b Graph, opts *DiffOptions, ds chan<- *Difference) error {
+	defer close(ds)
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+	var gs []Graph
+	for _, g := range []Graph{a, b} {
+		snap, err := Snapshot(ctx, g)
+		switch err {
+		case nil:
+			defer snap.Release(ctx)
+			g = snap
+		case ErrNoSnapshots:
+		default:
+			return err
+		}
+		gs = append(gs, g)
+	}
+	a, b = gs[0], gs[1]
+
+	var pErr error
+	push := func(t *triple.Triple, left bool) {
+		if pErr != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			pErr = ctx.Err()
+		case ds <- &Difference{Triple: t, Left: left}:
+		}
+	}
+	keys := func(g Graph, f func(k string, t *triple.Triple)) (map[string]bool, error) {
+		ks := make(map[string]bool)
+		err := scanTriples(ctx, g, func(t *triple.Triple) {
+			k := diffKey(t, opts)
+			ks[k] = true
+			if f != nil {
+				f(k, t)
+			}
+		})
+		return ks, err
+	}
+	inB, err := keys(b, nil)
+	if err != nil {
+		return err
+	}
+	inA, err := keys(a, func(k string, t *triple.Triple) {
+		if !inB[k] {
+			push(t, true)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	// The keys of b are no longer needed to read it again.
+	inB = nil
+	if err := scanTriples(ctx, b, func(t *triple.Triple) {
+		if !inA[diffKey(t, opts)] {
+			push(t, false)
+		}
+	}); err != nil {
+		return err
+	}
+	return pErr
+}
@@ -0,0 +1,142 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"context"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// Filter returns the triples that pass the provided lookup options, in the
+// same order. The predicate, if provided, restricts the time anchor of
+// temporal triples. The returned slice shares the array of the provided one.
+func Filter(ts []*triple.Triple, lo *storage.LookupOptions, op *predicate.Predicate) ([]*triple.Triple, error) {
+	if lo.LatestAnchor {
+		return latest(ts, lo)
+	}
+	ckr, res := NewChecker(lo, op), ts[:0]
+	for _, t := range ts {
+		if ckr.CheckAndUpdateTriple(t) {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// latest returns the temporal triples with the latest time anchor for each
+// predicate ID whose objects pass the literal filter of the lookup options.
+func latest(ts []*triple.Triple, lo *storage.LookupOptions) ([]*triple.Triple, error) {
+	var ids []string
+	trps := make(map[string]*triple.Triple)
+	for _, t := range ts {
+		p := t.Predicate()
+		if p.Type() != predicate.Temporal {
+			continue
+		}
+		ta, err := p.TimeAnchor()
+		if err != nil {
+			return nil, err
+		}
+		id := p.PartialUUID().String()
+		lt, ok := trps[id]
+		if !ok {
+			ids = append(ids, id)
+			trps[id] = t
+			continue
+		}
+		if lta, _ := lt.Predicate().TimeAnchor(); ta.After(*lta) {
+			trps[id] = t
+		}
+	}
+	var res []*triple.Triple
+	for _, id := range ids {
+		if t := trps[id]; lo.AcceptObject(t.Object()) {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// Checker provides the mechanics to check if a predicate/triple should be
+// considered on a certain operation.
+type Checker struct {
+	max bool
+	c   int
+	o   *storage.LookupOptions
+	// tp is the predicate of the lookup if it is temporal.
+	tp *predicate.Predicate
+}
+
+// NewChecker creates a new checker for a given LookupOptions configuration.
+func NewChecker(o *storage.LookupOptions, op *predicate.Predicate) *Checker {
+	var tp *predicate.Predicate
+	if op != nil && op.Type() == predicate.Temporal {
+		tp = op
+	}
+	return &Checker{
+		max: o.MaxElements > 0,
+		c:   o.MaxElements,
+		o:   o,
+		tp:  tp,
+	}
+}
+
+// CheckAndUpdateTriple checks if a triple should be considered and updates
+// the count of elements returned.
+func (c *Checker) CheckAndUpdateTriple(t *triple.Triple) bool {
+	if c.max && c.c <= 0 {
+		return false
+	}
+	if !c.o.AcceptObject(t.Object()) {
+		return false
+	}
+	if p := t.Predicate(); p.Type() == predicate.Temporal {
+		if c.tp != nil && !c.tp.SameAnchor(p) {
+			return false
+		}
+		if !p.Overlaps(c.o.LowerAnchor, c.o.UpperAnchor) {
+			return false
+		}
+	}
+	c.c--
+	return true
+}
+
+// SendPredicates publishes the predicates of the provided triples.
+func SendPredicates(ctx context.Context, ts []*triple.Triple, prds chan<- *predicate.Predicate) error {
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case prds <- t.Predicate():
+		}
+	}
+	return nil
+}
+
+// SendTriples publishes the provided triples.
+func SendTriples(ctx context.Context, ts []*triple.Triple, trpls chan<- *triple.Triple) error {
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case trpls <- t:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,441 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// KeyRange is a range of keys of an ordered key value store. Keys are in the
+// range if they start with the prefix and their first bytes are between the
+// start and the end, both included. Empty bounds are not checked.
+type KeyRange struct {
+	Prefix, Start, End []byte
+}
+
+// KeyValue is an entry of an ordered key value store.
+type KeyValue struct {
+	Key, Value []byte
+}
+
+// KV is the minimal API of an ordered key value store needed to store the
+// triples of graphs.
+type KV interface {
+	// Scan calls the provided function with the keys and values in the range
+	// in key order. Both slices are only valid during the call.
+	Scan(r KeyRange, f func(k, v []byte) error) error
+
+	// Has returns true if the key exists.
+	Has(k []byte) (bool, error)
+
+	// Write sets and deletes the provided keys.
+	Write(set []KeyValue, del [][]byte) error
+}
+
+// Index IDs of the ordered graphs.
+const (
+	// existIdx indexes triples by their UUID. Its entries have no value.
+	existIdx = 't'
+	// The rest of indices hold the triple in their values. Their keys are
+	// the concatenation of the UUIDs of the subject (S), predicate ignoring
+	// its time anchor (P), object (O), and triple (T), and the time anchor of
+	// the predicate (A) in the order listed.
+	pasotIdx = 'p'
+	spaotIdx = 's'
+	ospatIdx = 'o'
+	poastIdx = 'q'
+)
+
+// OrderedGraph implements the storage.Graph lookups on top of an ordered key
+// value store. All the keys of the graph share a prefix. After it, the
+// predicate index orders its keys by predicate and time anchor, so triples of
+// the same predicate ingested over time are stored together, and the subject
+// and predicate-object indices also place the time anchor right after the
+// predicate. Lookups bounded by time anchors on them only scan the requested
+// time window. Each index holds a copy of the triple, so lookups are a single
+// sequential scan.
+type OrderedGraph struct {
+	id      string
+	kv      KV
+	prefix  []byte
+	changed func() error
+}
+
+// NewOrderedGraph returns a graph with the provided ID storing its triples in
+// the keys of the key value store starting with the provided prefix. The
+// changed function, if provided, is called after the triples of the graph
+// change.
+func NewOrderedGraph(id string, kv KV, prefix []byte, changed func() error) *OrderedGraph {
+	if changed == nil {
+		changed = func() error { return nil }
+	}
+	return &OrderedGraph{
+		id:      id,
+		kv:      kv,
+		prefix:  prefix,
+		changed: changed,
+	}
+}
+
+// ID returns the id for this graph.
+func (g *OrderedGraph) ID(ctx context.Context) string {
+	return g.id
+}
+
+// anchorLen is the length of the encoded time anchors.
+const anchorLen = 13
+
+// EncodeAnchor returns the encoding of the provided time anchor. Immutable
+// predicates have no anchor and sort before all temporal ones; temporal ones
+// sort by their anchor.
+func EncodeAnchor(ta *time.Time) []byte {
+	b := make([]byte, anchorLen)
+	if ta == nil {
+		return b
+	}
+	b[0] = 1
+	// Flipping the sign bit makes negative seconds sort before positive ones.
+	binary.BigEndian.PutUint64(b[1:], uint64(ta.Unix())^(1<<63))
+	binary.BigEndian.PutUint32(b[9:], uint32(ta.Nanosecond()))
+	return b
+}
+
+// PredicateAnchor returns the encoded time anchor used to index the triples
+// of the provided predicate. Interval predicates sort after all the other
+// temporal ones by the start of their interval, so lookups bounded in time can
+// scan all the intervals that may overlap their window apart.
+func PredicateAnchor(p *predicate.Predicate) []byte {
+	ta, _ := p.TimeAnchor()
+	b := EncodeAnchor(ta)
+	if p.IsInterval() {
+		b[0] = 2
+	}
+	return b
+}
+
+// key returns the key of the provided index concatenating the provided parts.
+func (g *OrderedGraph) key(idx byte, parts ...[]byte) []byte {
+	k := append(append([]byte{}, g.prefix...), idx)
+	for _, p := range parts {
+		k = append(k, p...)
+	}
+	return k
+}
+
+// keys returns the keys of the provided triple in all the indices.
+func (g *OrderedGraph) keys(t *triple.Triple) [][]byte {
+	s, p, o := []byte(t.Subject().UUID()), []byte(t.Predicate().PartialUUID()), []byte(t.Object().UUID())
+	a, tk := PredicateAnchor(t.Predicate()), []byte(t.UUID())
+	return [][]byte{
+		g.key(existIdx, tk),
+		g.key(pasotIdx, p, a, s, o, tk),
+		g.key(spaotIdx, s, p, a, o, tk),
+		g.key(ospatIdx, o, s, p, a, tk),
+		g.key(poastIdx, p, o, a, s, tk),
+	}
+}
+
+// AddTriples adds the triples to the storage.
+func (g *OrderedGraph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	var set []KeyValue
+	for _, t := range ts {
+		v, err := EncodeTriple(t)
+		if err != nil {
+			return err
+		}
+		for i, k := range g.keys(t) {
+			if i == 0 {
+				set = append(set, KeyValue{Key: k, Value: []byte{}})
+				continue
+			}
+			set = append(set, KeyValue{Key: k, Value: v})
+		}
+	}
+	if err := g.kv.Write(set, nil); err != nil {
+		return err
+	}
+	return g.changed()
+}
+
+// RemoveTriples removes the triples from the storage.
+func (g *OrderedGraph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	var del [][]byte
+	for _, t := range ts {
+		del = append(del, g.keys(t)...)
+	}
+	if err := g.kv.Write(nil, del); err != nil {
+		return err
+	}
+	return g.changed()
+}
+
+// AnchorRanges returns the ranges to scan for the provided prefix, which
+// needs to end right before a time anchor encoded with EncodeAnchor,
+// restricting the anchors to the ones allowed by the predicate and lookup
+// options. Immutable predicates are always scanned, and so are the interval
+// predicates starting before the upper bound of the lookup, since any of them
+// may overlap its time window.
+func AnchorRanges(prefix []byte, lo *storage.LookupOptions, op *predicate.Predicate) []KeyRange {
+	key := func(a []byte) []byte {
+		return append(append([]byte{}, prefix...), a...)
+	}
+	if lo.LatestAnchor {
+		return []KeyRange{{Prefix: prefix}}
+	}
+	immutable := KeyRange{Prefix: key([]byte{0})}
+	if op != nil && op.Type() == predicate.Temporal {
+		a := PredicateAnchor(op)
+		return []KeyRange{immutable, {Prefix: key(a[:1]), Start: key(a), End: key(a)}}
+	}
+	lower, upper := lo.LowerAnchor, lo.UpperAnchor
+	if lower == nil && upper == nil {
+		return []KeyRange{{Prefix: prefix}}
+	}
+	r, ir := KeyRange{Prefix: key([]byte{1})}, KeyRange{Prefix: key([]byte{2})}
+	if lower != nil {
+		r.Start = key(EncodeAnchor(lower))
+	}
+	if upper != nil {
+		r.End = key(EncodeAnchor(upper))
+		ia := EncodeAnchor(upper)
+		ia[0] = 2
+		ir.End = key(ia)
+	}
+	return []KeyRange{immutable, r, ir}
+}
+
+// InRange returns true if the provided key, which needs to start with the
+// prefix of the range, is within its bounds.
+func (r KeyRange) InRange(k []byte) bool {
+	if r.Start != nil && bytes.Compare(k, r.Start) < 0 {
+		return false
+	}
+	if r.End != nil && len(k) >= len(r.End) && bytes.Compare(k[:len(r.End)], r.End) > 0 {
+		return false
+	}
+	return true
+}
+
+// scan returns the triples in the provided ranges of keys that pass the
+// lookup options.
+func (g *OrderedGraph) scan(rs []KeyRange, lo *storage.LookupOptions, op *predicate.Predicate) ([]*triple.Triple, error) {
+	var ts []*triple.Triple
+	for _, r := range rs {
+		err := g.kv.Scan(r, func(k, v []byte) error {
+			t, err := DecodeTriple(v)
+			if err != nil {
+				return err
+			}
+			ts = append(ts, t)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Filter(ts, lo, op)
+}
+
+// Objects publishes the objects for the given subject and predicate to the
+// provided channel.
+func (g *OrderedGraph) Objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	if objs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(objs)
+	ts, err := g.scan(AnchorRanges(g.key(spaotIdx, s.UUID(), p.PartialUUID()), lo, p), lo, p)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case objs <- t.Object():
+		}
+	}
+	return nil
+}
+
+// Subjects publishes the subjects for the given predicate and object to the
+// provided channel.
+func (g *OrderedGraph) Subjects(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, subjs chan<- *node.Node) error {
+	if subjs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(subjs)
+	ts, err := g.scan(AnchorRanges(g.key(poastIdx, p.PartialUUID(), o.UUID()), lo, p), lo, p)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case subjs <- t.Subject():
+		}
+	}
+	return nil
+}
+
+// PredicatesForSubjectAndObject publishes all predicates available for the
+// given subject and object to the provided channel.
+func (g *OrderedGraph) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.scan([]KeyRange{{Prefix: g.key(ospatIdx, o.UUID(), s.UUID())}}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return SendPredicates(ctx, ts, prds)
+}
+
+// PredicatesForSubject publishes all the predicates known for the given
+// subject to the provided channel.
+func (g *OrderedGraph) PredicatesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.scan([]KeyRange{{Prefix: g.key(spaotIdx, s.UUID())}}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return SendPredicates(ctx, ts, prds)
+}
+
+// PredicatesForObject publishes all the predicates known for the given object
+// to the provided channel.
+func (g *OrderedGraph) PredicatesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.scan([]KeyRange{{Prefix: g.key(ospatIdx, o.UUID())}}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return SendPredicates(ctx, ts, prds)
+}
+
+// TriplesForSubject publishes all triples available for the given subject to
+// the provided channel.
+func (g *OrderedGraph) TriplesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan([]KeyRange{{Prefix: g.key(spaotIdx, s.UUID())}}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForPredicate publishes all triples available for the given predicate
+// to the provided channel.
+func (g *OrderedGraph) TriplesForPredicate(ctx context.Context, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(AnchorRanges(g.key(pasotIdx, p.PartialUUID()), lo, p), lo, p)
+	if err != nil {
+		return err
+	}
+	return SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForObject publishes all triples available for the given object to the
+// provided channel.
+func (g *OrderedGraph) TriplesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan([]KeyRange{{Prefix: g.key(ospatIdx, o.UUID())}}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForSubjectAndPredicate publishes all triples available for the given
+// subject and predicate to the provided channel.
+func (g *OrderedGraph) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(AnchorRanges(g.key(spaotIdx, s.UUID(), p.PartialUUID()), lo, p), lo, p)
+	if err != nil {
+		return err
+	}
+	return SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForPredicateAndObject publishes all triples available for the given
+// predicate and object to the provided channel.
+func (g *OrderedGraph) TriplesForPredicateAndObject(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(AnchorRanges(g.key(poastIdx, p.PartialUUID(), o.UUID()), lo, p), lo, p)
+	if err != nil {
+		return err
+	}
+	return SendTriples(ctx, ts, trpls)
+}
+
+// Exist checks if the provided triple exists on the store.
+func (g *OrderedGraph) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
+	return g.kv.Has(g.key(existIdx, t.UUID()))
+}
+
+// Triples allows to iterate over all available triples by pushing them to the
+// provided channel.
+func (g *OrderedGraph) Triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan([]KeyRange{{Prefix: g.key(pasotIdx)}}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return SendTriples(ctx, ts, trpls)
+}
+
+// CountTriples returns the number of triples in the graph.
+func (g *OrderedGraph) CountTriples(ctx context.Context) (int64, error) {
+	var n int64
+	err := g.kv.Scan(KeyRange{Prefix: g.key(existIdx)}, func(k, v []byte) error {
+		n++
+		return nil
+	})
+	return n, err
+}
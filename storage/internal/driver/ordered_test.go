@@ -0,0 +1,205 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// memKV is an in memory ordered key value store that counts the entries read
+// by scans.
+type memKV struct {
+	m       map[string][]byte
+	scanned int
+}
+
+func (kv *memKV) Scan(r KeyRange, f func(k, v []byte) error) error {
+	var ks []string
+	for k := range kv.m {
+		if bytes.HasPrefix([]byte(k), r.Prefix) && (r.Start == nil || k >= string(r.Start)) {
+			ks = append(ks, k)
+		}
+	}
+	sort.Strings(ks)
+	for _, k := range ks {
+		if !r.InRange([]byte(k)) {
+			break
+		}
+		kv.scanned++
+		if err := f([]byte(k), kv.m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (kv *memKV) Has(k []byte) (bool, error) {
+	_, ok := kv.m[string(k)]
+	return ok, nil
+}
+
+func (kv *memKV) Write(set []KeyValue, del [][]byte) error {
+	for _, e := range set {
+		kv.m[string(e.Key)] = e.Value
+	}
+	for _, k := range del {
+		delete(kv.m, string(k))
+	}
+	return nil
+}
+
+func TestEncodeAnchorOrder(t *testing.T) {
+	var prev []byte
+	for _, s := range []string{
+		"1900-01-01T00:00:00Z",
+		"1969-12-31T23:59:59Z",
+		"1969-12-31T23:59:59.5Z",
+		"1970-01-01T00:00:00Z",
+		"2016-04-10T04:30:00Z",
+		"2016-04-10T04:30:00.000000001Z",
+	} {
+		ta, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cur := EncodeAnchor(&ta)
+		if prev == nil && bytes.Compare(EncodeAnchor(nil), cur) >= 0 {
+			t.Errorf("EncodeAnchor(nil) should sort before EncodeAnchor(%s)", s)
+		}
+		if prev != nil && bytes.Compare(prev, cur) >= 0 {
+			t.Errorf("EncodeAnchor(%s) should sort after the previous anchor", s)
+		}
+		prev = cur
+	}
+}
+
+func TestOrderedGraphTimeWindowScans(t *testing.T) {
+	ctx, kv := context.Background(), &memKV{m: make(map[string][]byte)}
+	g := NewOrderedGraph("?test", kv, []byte("g"), nil)
+	s, o := node.NewBlankNode(), triple.NewNodeObject(node.NewBlankNode())
+	base := time.Date(2016, 4, 10, 0, 0, 0, 0, time.UTC)
+	var ts []*triple.Triple
+	for i := 0; i < 100; i++ {
+		p, err := predicate.NewTemporal("met", base.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		trpl, err := triple.New(s, p, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, trpl)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	at := func(h int) *time.Time {
+		ta := base.Add(time.Duration(h) * time.Hour)
+		return &ta
+	}
+	p, _ := predicate.NewImmutable("met")
+	testTable := []struct {
+		lo   *storage.LookupOptions
+		want int
+	}{
+		{storage.DefaultLookup, 100},
+		{&storage.LookupOptions{LowerAnchor: at(10), UpperAnchor: at(19)}, 10},
+		{&storage.LookupOptions{LowerAnchor: at(95)}, 5},
+		{&storage.LookupOptions{UpperAnchor: at(4)}, 5},
+		{&storage.LookupOptions{LowerAnchor: at(200)}, 0},
+	}
+	for _, entry := range testTable {
+		lookups := map[string]func(chan<- *triple.Triple) error{
+			"TriplesForPredicate": func(c chan<- *triple.Triple) error {
+				return g.TriplesForPredicate(ctx, p, entry.lo, c)
+			},
+			"TriplesForSubjectAndPredicate": func(c chan<- *triple.Triple) error {
+				return g.TriplesForSubjectAndPredicate(ctx, s, p, entry.lo, c)
+			},
+			"TriplesForPredicateAndObject": func(c chan<- *triple.Triple) error {
+				return g.TriplesForPredicateAndObject(ctx, p, o, entry.lo, c)
+			},
+		}
+		for name, lookup := range lookups {
+			kv.scanned = 0
+			ch := make(chan *triple.Triple, len(ts))
+			if err := lookup(ch); err != nil {
+				t.Fatalf("%s failed with error %v", name, err)
+			}
+			if got := len(ch); got != entry.want {
+				t.Errorf("%s(%s) returned %d triples; want %d", name, entry.lo, got, entry.want)
+			}
+			// Only the triples in the time window should be read.
+			if kv.scanned != entry.want {
+				t.Errorf("%s(%s) scanned %d entries; want %d", name, entry.lo, kv.scanned, entry.want)
+			}
+		}
+	}
+}
+
+func TestOrderedGraphIntervalScans(t *testing.T) {
+	ctx, kv := context.Background(), &memKV{m: make(map[string][]byte)}
+	g := NewOrderedGraph("?test", kv, []byte("g"), nil)
+	s, o := node.NewBlankNode(), triple.NewNodeObject(node.NewBlankNode())
+	base := time.Date(2016, 4, 10, 0, 0, 0, 0, time.UTC)
+	at := func(h int) *time.Time {
+		ta := base.Add(time.Duration(h) * time.Hour)
+		return &ta
+	}
+	var ts []*triple.Triple
+	for i := 0; i < 20; i++ {
+		p, err := predicate.NewInterval("employed", *at(i), *at(i + 10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		trpl, err := triple.New(s, p, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, trpl)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	p, _ := predicate.NewImmutable("employed")
+	testTable := []struct {
+		lo   *storage.LookupOptions
+		want int
+	}{
+		{storage.DefaultLookup, 20},
+		{&storage.LookupOptions{LowerAnchor: at(25), UpperAnchor: at(27)}, 4},
+		{&storage.LookupOptions{UpperAnchor: at(3)}, 4},
+		{&storage.LookupOptions{LowerAnchor: at(15)}, 14},
+		{&storage.LookupOptions{LowerAnchor: at(29)}, 0},
+	}
+	for _, entry := range testTable {
+		ch := make(chan *triple.Triple, len(ts))
+		if err := g.TriplesForSubjectAndPredicate(ctx, s, p, entry.lo, ch); err != nil {
+			t.Fatalf("TriplesForSubjectAndPredicate failed with error %v", err)
+		}
+		if got := len(ch); got != entry.want {
+			t.Errorf("TriplesForSubjectAndPredicate(%s) returned %d intervals; want %d", entry.lo, got, entry.want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package driver provides the building blocks shared by the persistent
+// storage drivers: the stored representation of triples and the filtering of
+// lookup results according to the lookup options.
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// record is the stored representation of a triple. Unlike the text format of
+// triples, it preserves any text literal and the exact time anchors.
+type record struct {
+	Subject   nodeRecord      `json:"s"`
+	Predicate predicateRecord `json:"p"`
+	Object    objectRecord    `json:"o"`
+}
+
+// nodeRecord is the stored representation of a node.
+type nodeRecord struct {
+	Type string `json:"t"`
+	ID   string `json:"id"`
+}
+
+// predicateRecord is the stored representation of a predicate. Immutable
+// predicates have no anchor.
+type predicateRecord struct {
+	ID     string     `json:"id"`
+	Anchor *time.Time `json:"a,omitempty"`
+	// End is the end of the interval of interval predicates.
+	End *time.Time `json:"e,omitempty"`
+}
+
+// objectRecord is the stored representation of an object. Only one of its
+// fields is set.
+type objectRecord struct {
+	Node      *nodeRecord      `json:"n,omitempty"`
+	Predicate *predicateRecord `json:"p,omitempty"`
+	Literal   *literalRecord   `json:"l,omitempty"`
+}
+
+// literalRecord is the stored representation of a literal.
+type literalRecord struct {
+	Type  literal.Type    `json:"t"`
+	Value json.RawMessage `json:"v"`
+	// Custom is the name of the type of custom literals, whose value is
+	// stored as its text. Type is not used for them, since the type assigned
+	// to custom types may change between processes.
+	Custom string `json:"c,omitempty"`
+}
+
+// newNodeRecord returns the record of the provided node.
+func newNodeRecord(n *node.Node) *nodeRecord {
+	return &nodeRecord{Type: n.Type().String(), ID: n.ID().String()}
+}
+
+// newPredicateRecord returns the record of the provided predicate.
+func newPredicateRecord(p *predicate.Predicate) *predicateRecord {
+	pr := &predicateRecord{ID: string(p.ID())}
+	if ta, err := p.TimeAnchor(); err == nil {
+		pr.Anchor = ta
+	}
+	if _, end, err := p.Interval(); err == nil {
+		pr.End = end
+	}
+	return pr
+}
+
+// newLiteralRecord returns the record of the provided literal.
+func newLiteralRecord(l *literal.Literal) (*literalRecord, error) {
+	if name, text, err := l.Custom(); err == nil {
+		v, err := json.Marshal(text)
+		if err != nil {
+			return nil, fmt.Errorf("driver: cannot encode literal %s; %v", l, err)
+		}
+		return &literalRecord{Value: v, Custom: name}, nil
+	}
+	v, err := json.Marshal(l.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("driver: cannot encode literal %s; %v", l, err)
+	}
+	return &literalRecord{Type: l.Type(), Value: v}, nil
+}
+
+// EncodeTriple returns the stored representation of the provided triple.
+func EncodeTriple(t *triple.Triple) ([]byte, error) {
+	r := &record{
+		Subject:   *newNodeRecord(t.Subject()),
+		Predicate: *newPredicateRecord(t.Predicate()),
+	}
+	o := t.Object()
+	if n, err := o.Node(); err == nil {
+		r.Object.Node = newNodeRecord(n)
+	} else if p, err := o.Predicate(); err == nil {
+		r.Object.Predicate = newPredicateRecord(p)
+	} else if l, err := o.Literal(); err == nil {
+		if r.Object.Literal, err = newLiteralRecord(l); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("driver: unknown object type in triple %s", t)
+	}
+	return json.Marshal(r)
+}
+
+// node returns the node of the record.
+func (r *nodeRecord) node() (*node.Node, error) {
+	return node.NewNodeFromStrings(r.Type, r.ID)
+}
+
+// predicate returns the predicate of the record.
+func (r *predicateRecord) predicate() (*predicate.Predicate, error) {
+	if r.Anchor == nil {
+		return predicate.NewImmutable(r.ID)
+	}
+	if r.End != nil {
+		return predicate.NewInterval(r.ID, *r.Anchor, *r.End)
+	}
+	return predicate.NewTemporal(r.ID, *r.Anchor)
+}
+
+// literal returns the literal of the record.
+func (r *literalRecord) literal() (*literal.Literal, error) {
+	if r.Custom != "" {
+		var text string
+		if err := json.Unmarshal(r.Value, &text); err != nil {
+			return nil, fmt.Errorf("driver: cannot decode %s literal; %v", r.Custom, err)
+		}
+		return literal.ParseCustom(r.Custom, text)
+	}
+	var v interface{}
+	switch r.Type {
+	case literal.Bool:
+		v = new(bool)
+	case literal.Int64:
+		v = new(int64)
+	case literal.Float64:
+		v = new(float64)
+	case literal.Text:
+		v = new(string)
+	case literal.Blob:
+		v = new([]byte)
+	case literal.Decimal:
+		v = new(big.Rat)
+	case literal.Date:
+		v = new(time.Time)
+	default:
+		return nil, fmt.Errorf("driver: unknown literal type %d", r.Type)
+	}
+	if err := json.Unmarshal(r.Value, v); err != nil {
+		return nil, fmt.Errorf("driver: cannot decode %v literal; %v", r.Type, err)
+	}
+	switch v := v.(type) {
+	case *bool:
+		return literal.DefaultBuilder().Build(r.Type, *v)
+	case *int64:
+		return literal.DefaultBuilder().Build(r.Type, *v)
+	case *float64:
+		return literal.DefaultBuilder().Build(r.Type, *v)
+	case *string:
+		return literal.DefaultBuilder().Build(r.Type, *v)
+	case *big.Rat:
+		return literal.DefaultBuilder().Build(r.Type, v)
+	case *time.Time:
+		return literal.DefaultBuilder().Build(r.Type, *v)
+	default:
+		return literal.DefaultBuilder().Build(r.Type, *v.(*[]byte))
+	}
+}
+
+// DecodeTriple returns the triple stored in the provided representation. The
+// nodes and predicates of the triple are interned.
+func DecodeTriple(b []byte) (*triple.Triple, error) {
+	r := &record{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("driver: cannot decode triple; %v", err)
+	}
+	s, err := r.Subject.node()
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.Predicate.predicate()
+	if err != nil {
+		return nil, err
+	}
+	var o *triple.Object
+	switch {
+	case r.Object.Node != nil:
+		n, err := r.Object.Node.node()
+		if err != nil {
+			return nil, err
+		}
+		o = triple.NewNodeObject(n)
+	case r.Object.Predicate != nil:
+		op, err := r.Object.Predicate.predicate()
+		if err != nil {
+			return nil, err
+		}
+		o = triple.NewPredicateObject(op)
+	case r.Object.Literal != nil:
+		l, err := r.Object.Literal.literal()
+		if err != nil {
+			return nil, err
+		}
+		o = triple.NewLiteralObject(l)
+	default:
+		return nil, fmt.Errorf("driver: cannot decode triple without object")
+	}
+	t, err := triple.New(s, p, o)
+	if err != nil {
+		return nil, err
+	}
+	// Lookups decode the same nodes and predicates over and over, so they are
+	// interned to share memory across the rows of a query.
+	return triple.Intern(t), nil
+}
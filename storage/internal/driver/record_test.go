@@ -0,0 +1,76 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/storage/internal/storagetest"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+func TestEncoding(t *testing.T) {
+	b := literal.DefaultBuilder()
+	n, _ := node.NewNodeFromStrings("/some/type", "id with spaces")
+	p, _ := predicate.NewImmutable("p")
+	ta := time.Date(2016, 4, 10, 4, 21, 0, 123456789, time.UTC)
+	tp, _ := predicate.NewTemporal("met \"quoted\"", ta)
+	ip, _ := predicate.NewInterval("employed", ta, ta.Add(time.Hour))
+	var objs []*triple.Object
+	for _, v := range []struct {
+		t literal.Type
+		v interface{}
+	}{
+		{literal.Bool, true},
+		{literal.Int64, int64(-42)},
+		{literal.Float64, 0.1},
+		{literal.Text, "tab\tnew line\n\"quotes\""},
+		{literal.Blob, []byte{0, 1, 255}},
+		{literal.Decimal, big.NewRat(-123456789, 1000)},
+		{literal.Date, time.Date(1969, 7, 20, 0, 0, 0, 0, time.UTC)},
+		{storagetest.Hex, uint64(0xbeef)},
+	} {
+		l, err := b.Build(v.t, v.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objs = append(objs, triple.NewLiteralObject(l))
+	}
+	objs = append(objs, triple.NewNodeObject(n), triple.NewPredicateObject(tp))
+	for _, o := range objs {
+		for _, pr := range []*predicate.Predicate{p, tp, ip} {
+			trpl, err := triple.New(n, pr, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			enc, err := EncodeTriple(trpl)
+			if err != nil {
+				t.Fatalf("EncodeTriple(%s) failed with error %v", trpl, err)
+			}
+			got, err := DecodeTriple(enc)
+			if err != nil {
+				t.Fatalf("DecodeTriple(%s) failed with error %v", enc, err)
+			}
+			if !got.Equal(trpl) {
+				t.Errorf("DecodeTriple(EncodeTriple(%s)) returned %s", trpl, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storagetest contains helpers shared by the tests of the storage
+// drivers.
+package storagetest
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// Hex is a custom literal type for unsigned integers written in hexadecimal.
+// It is registered as type:hex when the package is imported.
+var Hex literal.Type
+
+func init() {
+	var err error
+	Hex, err = literal.Register(literal.CustomType{
+		Name: "hex",
+		Parse: func(s string) (interface{}, error) {
+			return strconv.ParseUint(s, 16, 64)
+		},
+		Format: func(v interface{}) string {
+			return strconv.FormatUint(v.(uint64), 16)
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Collect returns the sorted text of the triples pushed by the provided lookup
+// into the channel it is given. The test fails if the lookup does.
+func Collect(t *testing.T, lookup func(chan<- *triple.Triple) error) []string {
+	ch, res := make(chan *triple.Triple, 100), []string{}
+	if err := lookup(ch); err != nil {
+		t.Fatalf("lookup failed with error %v", err)
+	}
+	for trpl := range ch {
+		res = append(res, trpl.String())
+	}
+	sort.Strings(res)
+	return res
+}
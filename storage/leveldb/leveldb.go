@@ -0,0 +1,287 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package leveldb provides a persistent implementation of the storage.Store
+// and storage.Graph interfaces backed by a LevelDB database.
+//
+// LevelDB keeps its keys sorted, so the driver lays out the indices of each
+// graph ordering their keys by predicate and time anchor. Lookups bounded by
+// time anchors, like the ones generated by BQL queries using BEFORE, AFTER, or
+// BETWEEN, become range scans over the requested time window instead of
+// scans of all the triples of the predicate. Changes to the triples of a
+// graph are written as a single atomic batch.
+package leveldb
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/driver"
+	"github.com/pborman/uuid"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Key prefixes.
+const (
+	// catalogPrefix prefixes the keys listing the graphs. Their values contain
+	// the current epoch of the graph.
+	catalogPrefix = 'c'
+	// dataPrefix prefixes the keys containing the triples of the graphs.
+	dataPrefix = 'd'
+)
+
+// epochKey is the key holding the last epoch assigned to a graph.
+var epochKey = []byte("epoch")
+
+// Store provides a persistent store backed by a LevelDB database.
+type Store struct {
+	db *leveldb.DB
+	// mu serializes the changes to the catalog and the epoch counter.
+	mu sync.Mutex
+}
+
+// NewStore opens the LevelDB database in the provided directory, creating it
+// if needed.
+func NewStore(dir string) (*Store, error) {
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("leveldb.NewStore(%q): %v", dir, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database. Neither the store nor its graphs can
+// be used after closing it.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Name returns the ID of the backend being used.
+func (s *Store) Name(ctx context.Context) string {
+	return "LEVELDB"
+}
+
+// Version returns the version of the driver implementation.
+func (s *Store) Version(ctx context.Context) string {
+	return "0.1.leveldb"
+}
+
+// catalogKey returns the catalog key of the provided graph.
+func catalogKey(id string) []byte {
+	return append([]byte{catalogPrefix}, id...)
+}
+
+// graphPrefix returns the prefix of all the data keys of the provided graph.
+// Graph IDs are hashed so all keys of the graph have the same length.
+func graphPrefix(id string) []byte {
+	return append([]byte{dataPrefix}, uuid.NewSHA1(uuid.NIL, []byte(id))...)
+}
+
+// setEpoch adds to the batch the changes needed to assign a new epoch to the
+// provided graph. Epochs are never reused, even after reopening the store.
+// The caller needs to hold the store lock.
+func (s *Store) setEpoch(b *leveldb.Batch, id string) error {
+	var e uint64
+	v, err := s.db.Get(epochKey, nil)
+	switch {
+	case err == leveldb.ErrNotFound:
+	case err != nil:
+		return err
+	default:
+		e = binary.BigEndian.Uint64(v)
+	}
+	v = make([]byte, 8)
+	binary.BigEndian.PutUint64(v, e+1)
+	b.Put(epochKey, v)
+	b.Put(catalogKey(id), v)
+	return nil
+}
+
+// newGraph returns the graph with the provided ID.
+func (s *Store) newGraph(id string) *graph {
+	g := &graph{id: id, s: s}
+	g.OrderedGraph = driver.NewOrderedGraph(id, &kv{db: s.db}, graphPrefix(id), g.bumpEpoch)
+	return g
+}
+
+// NewGraph creates a new graph.
+func (s *Store) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ok, err := s.db.Has(catalogKey(id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("leveldb.NewGraph(%q): %v", id, err)
+	}
+	if ok {
+		return nil, fmt.Errorf("leveldb.NewGraph(%q): graph already exists", id)
+	}
+	b := new(leveldb.Batch)
+	if err := s.setEpoch(b, id); err != nil {
+		return nil, fmt.Errorf("leveldb.NewGraph(%q): %v", id, err)
+	}
+	if err := s.db.Write(b, nil); err != nil {
+		return nil, fmt.Errorf("leveldb.NewGraph(%q): %v", id, err)
+	}
+	return s.newGraph(id), nil
+}
+
+// Graph returns an existing graph if available. Getting a non existing
+// graph should return an error.
+func (s *Store) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	ok, err := s.db.Has(catalogKey(id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("leveldb.Graph(%q): %v", id, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("leveldb.Graph(%q): graph does not exist", id)
+	}
+	return s.newGraph(id), nil
+}
+
+// DeleteGraph deletes an existing graph. Deleting a non existing graph
+// should return an error.
+func (s *Store) DeleteGraph(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ok, err := s.db.Has(catalogKey(id), nil)
+	if err != nil {
+		return fmt.Errorf("leveldb.DeleteGraph(%q): %v", id, err)
+	}
+	if !ok {
+		return fmt.Errorf("leveldb.DeleteGraph(%q): graph does not exist", id)
+	}
+	b := new(leveldb.Batch)
+	it := s.db.NewIterator(util.BytesPrefix(graphPrefix(id)), nil)
+	for it.Next() {
+		b.Delete(append([]byte{}, it.Key()...))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return fmt.Errorf("leveldb.DeleteGraph(%q): %v", id, err)
+	}
+	b.Delete(catalogKey(id))
+	if err := s.db.Write(b, nil); err != nil {
+		return fmt.Errorf("leveldb.DeleteGraph(%q): %v", id, err)
+	}
+	return nil
+}
+
+// GraphNames returns the current available graph names in the store.
+func (s *Store) GraphNames(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(names)
+	var ns []string
+	it := s.db.NewIterator(util.BytesPrefix([]byte{catalogPrefix}), nil)
+	for it.Next() {
+		ns = append(ns, string(it.Key()[1:]))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	for _, n := range ns {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case names <- n:
+		}
+	}
+	return nil
+}
+
+// kv provides the ordered key value store API on top of LevelDB.
+type kv struct {
+	db *leveldb.DB
+}
+
+// Scan calls the provided function with the keys and values in the range.
+// Iterators read from an implicit snapshot, so concurrent changes are not
+// visible during the scan.
+func (s *kv) Scan(r driver.KeyRange, f func(k, v []byte) error) error {
+	it := s.db.NewIterator(util.BytesPrefix(r.Prefix), nil)
+	defer it.Release()
+	ok := it.First()
+	if r.Start != nil {
+		ok = it.Seek(r.Start)
+	}
+	for ; ok; ok = it.Next() {
+		if !r.InRange(it.Key()) {
+			break
+		}
+		if err := f(it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// Has returns true if the key exists.
+func (s *kv) Has(k []byte) (bool, error) {
+	return s.db.Has(k, nil)
+}
+
+// Write sets and deletes the provided keys atomically.
+func (s *kv) Write(set []driver.KeyValue, del [][]byte) error {
+	b := new(leveldb.Batch)
+	for _, e := range set {
+		b.Put(e.Key, e.Value)
+	}
+	for _, k := range del {
+		b.Delete(k)
+	}
+	return s.db.Write(b, nil)
+}
+
+// graph provides a LevelDB-based persistent implementation of the graph API.
+type graph struct {
+	*driver.OrderedGraph
+	id string
+	s  *Store
+}
+
+// bumpEpoch assigns a new epoch to the graph once its triples changed.
+func (g *graph) bumpEpoch() error {
+	g.s.mu.Lock()
+	defer g.s.mu.Unlock()
+	ok, err := g.s.db.Has(catalogKey(g.id), nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("leveldb: graph %q does not exist", g.id)
+	}
+	b := new(leveldb.Batch)
+	if err := g.s.setEpoch(b, g.id); err != nil {
+		return err
+	}
+	return g.s.db.Write(b, nil)
+}
+
+// Epoch returns the current epoch of the graph.
+func (g *graph) Epoch(ctx context.Context) (uint64, error) {
+	v, err := g.s.db.Get(catalogKey(g.id), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, fmt.Errorf("leveldb: graph %q does not exist", g.id)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
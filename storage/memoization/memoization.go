@@ -834,3 +834,35 @@ func (g *graphMemoizer) Triples(ctx context.Context, lo *storage.LookupOptions,
 	g.mu.Unlock()
 	return err
 }
+
+// CountTriples returns the number of triples in the wrapped graph.
+func (g *graphMemoizer) CountTriples(ctx context.Context) (int64, error) {
+	return storage.CountTriples(ctx, g.g)
+}
+
+// Statistics returns the statistics of the wrapped graph.
+func (g *graphMemoizer) Statistics(ctx context.Context) (*storage.GraphStatistics, error) {
+	return storage.Statistics(ctx, g.g)
+}
+
+// Epoch returns the epoch of the wrapped graph.
+func (g *graphMemoizer) Epoch(ctx context.Context) (uint64, error) {
+	return storage.Epoch(ctx, g.g)
+}
+
+// PredicateIDs returns the sorted list of unique predicate IDs used in the
+// wrapped graph.
+func (g *graphMemoizer) PredicateIDs(ctx context.Context) ([]string, error) {
+	return storage.PredicateIDs(ctx, g.g)
+}
+
+// Snapshot returns a snapshot of the wrapped graph. Snapshots do not change,
+// so their lookups are not memoized.
+func (g *graphMemoizer) Snapshot(ctx context.Context) (storage.GraphSnapshot, error) {
+	return storage.Snapshot(ctx, g.g)
+}
+
+// Watch returns the changes done to the wrapped graph.
+func (g *graphMemoizer) Watch(ctx context.Context) (<-chan *storage.Change, error) {
+	return storage.Watch(ctx, g.g)
+}
@@ -520,3 +520,23 @@ func TestTriples(t *testing.T) {
 		}
 	}
 }
+
+func TestSnapshot(t *testing.T) {
+	ctx, sm := buildtMemoizedStore(t)
+
+	g, err := sm.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := storage.Snapshot(ctx, g)
+	if err != nil {
+		t.Fatalf("storage.Snapshot failed with error %v; want the snapshot of the wrapped graph", err)
+	}
+	defer snap.Release(ctx)
+	if err := g.AddTriples(ctx, createTriples(t, []string{"/u<zoe> \"knows\"@[] /u<alice>"})); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := storage.CountTriples(ctx, snap); err != nil || got != int64(len(buildTriples(t))) {
+		t.Errorf("storage.CountTriples(snapshot) = (%d, %v); want %d", got, err, len(buildTriples(t)))
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// getBackupTriples returns enough triples to write several checkpoints.
+func getBackupTriples(t *testing.T) []*triple.Triple {
+	var ss []string
+	for i := 0; i < 2*storage.BackupCheckpoint+10; i++ {
+		ss = append(ss, fmt.Sprintf("/u<s%d>\t\"knows\"@[]\t/u<o%d>", i, i%7))
+	}
+	return append(createTriples(t, ss), getSnapshotTriples(t)...)
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	ts := getBackupTriples(t)
+	s := NewStore()
+	g, err := s.NewGraph(ctx, "?src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	md := &storage.GraphMetadata{
+		Description: "a \"quoted\"\ndescription",
+		Labels:      map[string]string{"env": "prod", "team name": "a b"},
+	}
+	if err := storage.SetGraphMetadata(ctx, s, "?src", md); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := storage.Backup(ctx, s, "?src", &buf); err != nil {
+		t.Fatalf("storage.Backup failed with error %v", err)
+	}
+	var progress []int64
+	n, err := storage.Restore(ctx, s, "?dst", bytes.NewReader(buf.Bytes()), &storage.RestoreOptions{
+		Progress: func(restored int64) { progress = append(progress, restored) },
+	})
+	if err != nil {
+		t.Fatalf("storage.Restore failed with error %v", err)
+	}
+	if n != int64(len(ts)) {
+		t.Errorf("storage.Restore returned %d triples; want %d", n, len(ts))
+	}
+	if want := []int64{storage.BackupCheckpoint, 2 * storage.BackupCheckpoint, int64(len(ts))}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("storage.Restore reported progress %v; want %v", progress, want)
+	}
+	dst, err := s.Graph(ctx, "?dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := graphContents(ctx, t, dst), graphContents(ctx, t, g); !reflect.DeepEqual(got, want) {
+		t.Errorf("storage.Restore returned a graph with %d triples; want the %d archived ones", len(got), len(want))
+	}
+	got, err := storage.GetGraphMetadata(ctx, s, "?dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Description != md.Description || !reflect.DeepEqual(got.Labels, md.Labels) {
+		t.Errorf("storage.Restore returned metadata %+v; want %+v", got, md)
+	}
+
+	// Restoring a graph that already exists fails.
+	if _, err := storage.Restore(ctx, s, "?dst", bytes.NewReader(buf.Bytes()), nil); err == nil {
+		t.Errorf("storage.Restore should fail to restore an existing graph")
+	}
+}
+
+// graphWriter adds triples to a graph the first time it is written to.
+type graphWriter struct {
+	io.Writer
+	g  storage.Graph
+	ts []*triple.Triple
+}
+
+func (w *graphWriter) Write(p []byte) (int, error) {
+	if w.ts != nil {
+		if err := w.g.AddTriples(context.Background(), w.ts); err != nil {
+			return 0, err
+		}
+		w.ts = nil
+	}
+	return w.Writer.Write(p)
+}
+
+func TestBackupIsConsistent(t *testing.T) {
+	ctx := context.Background()
+	ts := getBackupTriples(t)
+	s := NewStore()
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts[:len(ts)/2]); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := storage.Backup(ctx, s, "?test", &graphWriter{Writer: &buf, g: g, ts: ts[len(ts)/2:]}); err != nil {
+		t.Fatalf("storage.Backup failed with error %v", err)
+	}
+	if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != int64(len(ts)) {
+		t.Fatalf("the graph has %d triples after writing the archive, %v; want %d", cnt, err, len(ts))
+	}
+	n, err := storage.Restore(ctx, NewStore(), "?test", &buf, nil)
+	if err != nil {
+		t.Fatalf("storage.Restore failed with error %v", err)
+	}
+	if want := int64(len(ts) / 2); n != want {
+		t.Errorf("the archive has %d triples; want the %d in the graph when the backup started", n, want)
+	}
+}
+
+func TestRestoreResume(t *testing.T) {
+	ctx := context.Background()
+	ts := getBackupTriples(t)
+	s := NewStore()
+	g, err := s.NewGraph(ctx, "?src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := storage.Backup(ctx, s, "?src", &buf); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.String()
+
+	// Restoring a truncated archive fails, but keeps the restored triples.
+	cut := strings.Index(archive, fmt.Sprintf("# checkpoint %d\n", 2*storage.BackupCheckpoint))
+	var last int64
+	progress := func(restored int64) { last = restored }
+	if _, err := storage.Restore(ctx, s, "?dst", strings.NewReader(archive[:cut]), &storage.RestoreOptions{Progress: progress}); err == nil {
+		t.Fatalf("storage.Restore should fail on a truncated archive")
+	}
+	if want := int64(2 * storage.BackupCheckpoint); last != want {
+		t.Errorf("storage.Restore reported %d restored triples before failing; want %d", last, want)
+	}
+
+	// Resuming restores the rest of the archive into the same graph.
+	n, err := storage.Restore(ctx, s, "?dst", strings.NewReader(archive), &storage.RestoreOptions{Resume: last, Progress: progress})
+	if err != nil {
+		t.Fatalf("storage.Restore failed to resume with error %v", err)
+	}
+	if n != int64(len(ts)) || last != n {
+		t.Errorf("storage.Restore resumed %d triples and reported %d; want %d", n, last, len(ts))
+	}
+	dst, err := s.Graph(ctx, "?dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := graphContents(ctx, t, dst), graphContents(ctx, t, g); !reflect.DeepEqual(got, want) {
+		t.Errorf("the resumed graph has %d triples; want %d", len(got), len(want))
+	}
+}
+
+func TestRestoreInvalidArchives(t *testing.T) {
+	ctx := context.Background()
+	testTable := []struct {
+		name    string
+		archive string
+	}{
+		{"empty", ""},
+		{"no header", "/u<john>\t\"knows\"@[]\t/u<mary>\n# end 1\n"},
+		{"bad triple", "# badwolf graph archive v1\nnot a triple\n# end 1\n"},
+		{"bad count", "# badwolf graph archive v1\n/u<john>\t\"knows\"@[]\t/u<mary>\n# end 2\n"},
+		{"no trailer", "# badwolf graph archive v1\n/u<john>\t\"knows\"@[]\t/u<mary>\n"},
+		{"after trailer", "# badwolf graph archive v1\n# end 0\n/u<john>\t\"knows\"@[]\t/u<mary>\n"},
+		{"bad directive", "# badwolf graph archive v1\n# unknown\n# end 0\n"},
+		{"bad label", "# badwolf graph archive v1\n# label \"env\"\n# end 0\n"},
+	}
+	for _, entry := range testTable {
+		if _, err := storage.Restore(ctx, NewStore(), "?test", strings.NewReader(entry.archive), nil); err == nil {
+			t.Errorf("storage.Restore should fail on archive %q", entry.name)
+		}
+	}
+}
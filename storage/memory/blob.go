@@ -0,0 +1,104 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// blobs keeps the chunks of the blobs of a store by their digest. Chunks are
+// never changed once written, so readers can keep using them after the blob
+// is deleted.
+type blobs struct {
+	mu     sync.RWMutex
+	chunks map[string][][]byte
+}
+
+// newBlobs returns an empty set of blobs.
+func newBlobs() *blobs {
+	return &blobs{chunks: make(map[string][][]byte)}
+}
+
+// blobRef returns the reference held by the provided literal.
+func blobRef(op string, l *literal.Literal) (*literal.BlobRef, error) {
+	ref, err := l.BlobRef()
+	if err != nil {
+		return nil, fmt.Errorf("memory.%s: %v", op, err)
+	}
+	return ref, nil
+}
+
+// WriteBlob stores the contents read from r and returns the literal
+// referencing them.
+func (s *memoryStore) WriteBlob(ctx context.Context, r io.Reader) (*literal.Literal, error) {
+	var cs [][]byte
+	ref, err := storage.WriteChunks(ctx, r, func(c []byte) error {
+		cs = append(cs, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.blobs.mu.Lock()
+	defer s.blobs.mu.Unlock()
+	if _, ok := s.blobs.chunks[ref.Digest]; !ok {
+		s.blobs.chunks[ref.Digest] = cs
+	}
+	return storage.BlobRefLiteral(ref)
+}
+
+// ReadBlob returns a reader streaming the contents of the blob referenced by
+// the provided literal.
+func (s *memoryStore) ReadBlob(ctx context.Context, l *literal.Literal) (io.ReadCloser, error) {
+	ref, err := blobRef("ReadBlob", l)
+	if err != nil {
+		return nil, err
+	}
+	s.blobs.mu.RLock()
+	cs, ok := s.blobs.chunks[ref.Digest]
+	s.blobs.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("memory.ReadBlob: blob %s does not exist", ref)
+	}
+	rs := make([]io.Reader, len(cs))
+	for i, c := range cs {
+		rs[i] = bytes.NewReader(c)
+	}
+	return ioutil.NopCloser(io.MultiReader(rs...)), nil
+}
+
+// DeleteBlob deletes the contents of the blob referenced by the provided
+// literal.
+func (s *memoryStore) DeleteBlob(ctx context.Context, l *literal.Literal) error {
+	ref, err := blobRef("DeleteBlob", l)
+	if err != nil {
+		return err
+	}
+	s.blobs.mu.Lock()
+	defer s.blobs.mu.Unlock()
+	if _, ok := s.blobs.chunks[ref.Digest]; !ok {
+		return fmt.Errorf("memory.DeleteBlob: blob %s does not exist", ref)
+	}
+	delete(s.blobs.chunks, ref.Digest)
+	return nil
+}
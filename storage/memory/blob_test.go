@@ -0,0 +1,164 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+func TestLiteralLimit(t *testing.T) {
+	ctx := context.Background()
+	small, err := triple.Parse("/u<john>\t\"name\"@[]\t\"John\"^^type:text", literal.DefaultBuilder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	big, err := triple.Parse("/u<john>\t\"bio\"@[]\t\""+strings.Repeat("a", 64)+"\"^^type:text", literal.DefaultBuilder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []storage.Store{NewStore(), NewShardedStore(4, AllIndexes)} {
+		g, err := s.NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := storage.GetLiteralLimit(ctx, s); got != 0 {
+			t.Errorf("storage.GetLiteralLimit returned %d for a new store; want 0", got)
+		}
+		if err := storage.SetLiteralLimit(ctx, s, 32); err != nil {
+			t.Fatalf("storage.SetLiteralLimit failed with error %v", err)
+		}
+		if got := storage.GetLiteralLimit(ctx, s); got != 32 {
+			t.Errorf("storage.GetLiteralLimit returned %d; want 32", got)
+		}
+		err = g.AddTriples(ctx, []*triple.Triple{small, big})
+		if qe, ok := err.(*storage.ErrQuotaExceeded); !ok || qe.Resource != "literal bytes" || qe.Limit != 32 || qe.Requested != 64 {
+			t.Errorf("g.AddTriples returned error %v for a literal beyond the limit; want *storage.ErrQuotaExceeded", err)
+		}
+		if n, err := storage.CountTriples(ctx, g); err != nil || n != 0 {
+			t.Errorf("g.AddTriples added %d triples, %v beyond the literal limit; want 0", n, err)
+		}
+		// Graphs created after setting the limit are limited too.
+		g2, err := s.NewGraph(ctx, "?other")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g2.AddTriples(ctx, []*triple.Triple{big}); err == nil {
+			t.Errorf("g2.AddTriples should fail for a literal beyond the limit")
+		}
+		if _, err := storage.LiteralBuilder(ctx, s).Build(literal.Text, strings.Repeat("a", 64)); err == nil {
+			t.Errorf("storage.LiteralBuilder should reject literals beyond the limit")
+		}
+		if err := storage.SetLiteralLimit(ctx, s, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, []*triple.Triple{small, big}); err != nil {
+			t.Errorf("g.AddTriples failed with error %v without literal limit", err)
+		}
+	}
+	if err := storage.SetLiteralLimit(ctx, struct{ storage.Store }{NewStore()}, 1); err != storage.ErrNoLiteralLimit {
+		t.Errorf("storage.SetLiteralLimit returned %v for a store without literal limits; want storage.ErrNoLiteralLimit", err)
+	}
+}
+
+func TestBlobs(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	data := bytes.Repeat([]byte("0123456789"), storage.BlobChunkSize/4)
+	ref, err := storage.WriteBlob(ctx, s, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("storage.WriteBlob failed with error %v", err)
+	}
+	br, err := ref.BlobRef()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.Size != int64(len(data)) {
+		t.Errorf("storage.WriteBlob returned a reference to %d bytes; want %d", br.Size, len(data))
+	}
+	if n := len(s.(*memoryStore).blobs.chunks[br.Digest]); n != 3 {
+		t.Errorf("storage.WriteBlob stored %d chunks; want 3", n)
+	}
+	again, err := storage.WriteBlob(ctx, s, bytes.NewReader(data))
+	if err != nil || again.UUID().String() != ref.UUID().String() {
+		t.Errorf("storage.WriteBlob returned %v, %v for the same contents; want %v", again, err, ref)
+	}
+
+	// Blob references are small literals that can be added to limited graphs.
+	if err := storage.SetLiteralLimit(ctx, s, 16); err != nil {
+		t.Fatal(err)
+	}
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := node.Parse("/doc<report>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := predicate.NewImmutable("attachment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trpl, err := triple.New(n, p, triple.NewLiteralObject(ref))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, []*triple.Triple{trpl}); err != nil {
+		t.Fatalf("g.AddTriples failed to add a blob reference with error %v", err)
+	}
+
+	r, err := storage.ReadBlob(ctx, s, ref)
+	if err != nil {
+		t.Fatalf("storage.ReadBlob failed with error %v", err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if !bytes.Equal(got, data) {
+		t.Errorf("storage.ReadBlob returned %d bytes that do not match the %d written", len(got), len(data))
+	}
+
+	if err := storage.DeleteBlob(ctx, s, ref); err != nil {
+		t.Fatalf("storage.DeleteBlob failed with error %v", err)
+	}
+	if _, err := storage.ReadBlob(ctx, s, ref); err == nil {
+		t.Errorf("storage.ReadBlob should fail for a deleted blob")
+	}
+	if err := storage.DeleteBlob(ctx, s, ref); err == nil {
+		t.Errorf("storage.DeleteBlob should fail for a deleted blob")
+	}
+	text, err := literal.DefaultBuilder().Build(literal.Text, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.ReadBlob(ctx, s, text); err == nil {
+		t.Errorf("storage.ReadBlob should fail for a text literal")
+	}
+	if _, err := storage.WriteBlob(ctx, struct{ storage.Store }{s}, bytes.NewReader(data)); err != storage.ErrNoBlobs {
+		t.Errorf("storage.WriteBlob returned %v for a store without blobs; want storage.ErrNoBlobs", err)
+	}
+}
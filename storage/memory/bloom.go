@@ -0,0 +1,105 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+const (
+	// bloomBitsPerTriple and bloomHashes size bloom filters for a false
+	// positive rate of about 1%.
+	bloomBitsPerTriple = 10
+	bloomHashes        = 7
+	// bloomMinCapacity is the minimum number of triples a bloom filter is
+	// sized for.
+	bloomMinCapacity = 1024
+)
+
+// bloomFilter tells if a triple is definitely not in a graph without looking
+// it up in its indices. Removed triples cannot be taken out of the filter, so
+// it is rebuilt out of the triples of the graph once more triples than it was
+// sized for are added to it.
+type bloomFilter struct {
+	bits []uint64
+	// capacity is the number of triples the filter is sized for, and added the
+	// number of triples added since it was built.
+	capacity, added int
+}
+
+// newBloomFilter returns an empty bloom filter sized for the provided number
+// of triples.
+func newBloomFilter(capacity int) *bloomFilter {
+	if capacity < bloomMinCapacity {
+		capacity = bloomMinCapacity
+	}
+	return &bloomFilter{
+		bits:     make([]uint64, (capacity*bloomBitsPerTriple+63)/64),
+		capacity: capacity,
+	}
+}
+
+// positions calls f with the positions of the bits of the provided hash. The
+// positions are derived from the two halves of the hash.
+func (b *bloomFilter) positions(h uint64, f func(word int, mask uint64) bool) {
+	n := uint64(len(b.bits) * 64)
+	h1, h2 := h, (h>>32)|1
+	for i := uint64(0); i < bloomHashes; i++ {
+		p := (h1 + i*h2) % n
+		if !f(int(p/64), 1<<(p%64)) {
+			return
+		}
+	}
+}
+
+// add adds the triple with the provided hash to the filter.
+func (b *bloomFilter) add(h uint64) {
+	b.positions(h, func(w int, m uint64) bool {
+		b.bits[w] |= m
+		return true
+	})
+	b.added++
+}
+
+// has returns false if the triple with the provided hash was definitely never
+// added to the filter.
+func (b *bloomFilter) has(h uint64) bool {
+	res := true
+	b.positions(h, func(w int, m uint64) bool {
+		res = b.bits[w]&m != 0
+		return res
+	})
+	return res
+}
+
+// full returns true if more triples than the filter was sized for were added
+// to it.
+func (b *bloomFilter) full() bool {
+	return b.added > b.capacity
+}
+
+// clone returns a copy of the filter.
+func (b *bloomFilter) clone() *bloomFilter {
+	c := &bloomFilter{bits: make([]uint64, len(b.bits)), capacity: b.capacity, added: b.added}
+	copy(c.bits, b.bits)
+	return c
+}
+
+// rebuildBloom replaces the bloom filter of the graph with one sized for twice
+// its current triples, which only has the triples currently in the graph. It
+// must be called with the write lock of m held.
+func (m *memory) rebuildBloom() {
+	b := newBloomFilter(2 * len(m.idx))
+	for _, t := range m.idx {
+		b.add(valueKeysOf(t).hash())
+	}
+	m.bloom = b
+}
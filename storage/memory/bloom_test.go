@@ -0,0 +1,142 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+)
+
+func TestBloomFilter(t *testing.T) {
+	b := newBloomFilter(0)
+	if got, want := b.capacity, bloomMinCapacity; got != want {
+		t.Errorf("newBloomFilter(0) returned a filter for %d triples; want %d", got, want)
+	}
+	for i := uint64(0); i < bloomMinCapacity; i++ {
+		b.add(i * 0x9e3779b97f4a7c15)
+	}
+	fp := 0
+	for i := uint64(0); i < bloomMinCapacity; i++ {
+		if !b.has(i * 0x9e3779b97f4a7c15) {
+			t.Fatalf("b.has(%d) returned false for an added hash", i)
+		}
+		if b.has(i*0x9e3779b97f4a7c15 + 1) {
+			fp++
+		}
+	}
+	// The filter is sized for about 1% of false positives.
+	if fp > bloomMinCapacity/20 {
+		t.Errorf("the filter returned %d false positives out of %d lookups", fp, bloomMinCapacity)
+	}
+	if b.full() {
+		t.Errorf("b.full() returned true for a filter at its capacity")
+	}
+	b.add(0)
+	if !b.full() {
+		t.Errorf("b.full() returned false for a filter beyond its capacity")
+	}
+}
+
+func TestBloomFilterExist(t *testing.T) {
+	ctx := context.Background()
+	ts := createTriples(t, []string{
+		"/u<john>\t\"knows\"@[]\t/u<mary>",
+		"/u<john>\t\"knows\"@[]\t/u<peter>",
+		"/u<john>\t\"met\"@[2016-04-10T4:21:00.000000000Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T4:25:00.000000000Z]\t/u<mary>",
+	})
+	for _, s := range []storage.Store{NewStore(), NewShardedStore(4, AllIndexes)} {
+		g, err := s.NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts[:3]); err != nil {
+			t.Fatal(err)
+		}
+		for i, tr := range ts {
+			ok, err := g.Exist(ctx, tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := i < 3; ok != want {
+				t.Errorf("g.Exist(%s) returned %v; want %v", tr, ok, want)
+			}
+		}
+		// Removed triples may still be in the filter, but no longer exist.
+		if err := g.RemoveTriples(ctx, ts[:1]); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := g.Exist(ctx, ts[0]); err != nil || ok {
+			t.Errorf("g.Exist(%s) returned %v, %v for a removed triple; want false", ts[0], ok, err)
+		}
+		if err := g.AddTriples(ctx, ts[:1]); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := storage.CountTriples(ctx, g); err != nil || n != 3 {
+			t.Errorf("adding a removed triple back left %d triples, %v; want 3", n, err)
+		}
+	}
+}
+
+func TestBloomFilterRebuild(t *testing.T) {
+	ctx := context.Background()
+	var ss []string
+	for i := 0; i < 3*bloomMinCapacity; i++ {
+		ss = append(ss, fmt.Sprintf("/u<s%d>\t\"knows\"@[]\t/u<o%d>", i, i%13))
+	}
+	ts := createTriples(t, ss)
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := g.(*memory)
+	for i := 0; i < len(ts); i += 100 {
+		j := i + 100
+		if j > len(ts) {
+			j = len(ts)
+		}
+		if err := g.AddTriples(ctx, ts[i:j]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m.bloom.capacity < len(ts) || m.bloom.full() {
+		t.Errorf("the filter is sized for %d triples with %d added; want at least %d", m.bloom.capacity, m.bloom.added, len(ts))
+	}
+	for _, tr := range ts {
+		if ok, err := g.Exist(ctx, tr); err != nil || !ok {
+			t.Fatalf("g.Exist(%s) returned %v, %v after rebuilding the filter; want true", tr, ok, err)
+		}
+	}
+
+	// Snapshots keep their own filter once the graph changes.
+	snap, err := storage.Snapshot(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Release(ctx)
+	extra := createTriples(t, []string{"/u<extra>\t\"knows\"@[]\t/u<mary>"})
+	if err := g.AddTriples(ctx, extra); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := snap.Exist(ctx, extra[0]); err != nil || ok {
+		t.Errorf("snap.Exist returned %v, %v for a triple added after the snapshot; want false", ok, err)
+	}
+	if ok, err := g.Exist(ctx, extra[0]); err != nil || !ok {
+		t.Errorf("g.Exist returned %v, %v for an added triple; want true", ok, err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// nextChange returns the next change pushed into the channel, failing if none
+// is pushed in time.
+func nextChange(t *testing.T, cs <-chan *storage.Change) (string, []string) {
+	select {
+	case c, ok := <-cs:
+		if !ok {
+			t.Fatal("the change feed was closed; want a change")
+		}
+		var ts []string
+		for _, trpl := range c.Triples {
+			ts = append(ts, trpl.String())
+		}
+		sort.Strings(ts)
+		return c.Op.String(), ts
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change was pushed")
+	}
+	return "", nil
+}
+
+func TestWatch(t *testing.T) {
+	ts := getTestTriples(t)
+	for _, s := range []storage.Store{NewStore(), NewShardedStore(3, AllIndexes)} {
+		ctx, cancel := context.WithCancel(context.Background())
+		g, err := s.NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts[:1]); err != nil {
+			t.Fatal(err)
+		}
+		cs, err := storage.Watch(ctx, g)
+		if err != nil {
+			t.Fatalf("storage.Watch failed with error %v", err)
+		}
+		// Triples already in the graph are not reported as added.
+		if err := g.AddTriples(ctx, ts[:2]); err != nil {
+			t.Fatal(err)
+		}
+		if op, got := nextChange(t, cs); op != "added" || !reflect.DeepEqual(got, []string{ts[1].String()}) {
+			t.Errorf("storage.Watch pushed %s %v; want added %v", op, got, ts[1])
+		}
+		if err := g.RemoveTriples(ctx, []*triple.Triple{ts[0], ts[3]}); err != nil {
+			t.Fatal(err)
+		}
+		if op, got := nextChange(t, cs); op != "removed" || !reflect.DeepEqual(got, []string{ts[0].String()}) {
+			t.Errorf("storage.Watch pushed %s %v; want removed %v", op, got, ts[0])
+		}
+
+		tx, err := storage.BeginGraph(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.AddTriples(ctx, ts[2:3]); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if op, got := nextChange(t, cs); op != "added" || !reflect.DeepEqual(got, []string{ts[2].String()}) {
+			t.Errorf("storage.Watch pushed %s %v on commit; want added %v", op, got, ts[2])
+		}
+
+		snap, err := storage.Snapshot(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := storage.Watch(ctx, snap); err != storage.ErrNoChangeFeed {
+			t.Errorf("storage.Watch(snapshot) returned error %v; want %v", err, storage.ErrNoChangeFeed)
+		}
+		snap.Release(ctx)
+
+		if err := s.DeleteGraph(ctx, "?test"); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case c, ok := <-cs:
+			if ok {
+				t.Errorf("storage.Watch pushed %v after deleting the graph; want the channel closed", c)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("storage.Watch did not close the channel after deleting the graph")
+		}
+		cancel()
+	}
+}
+
+func TestWatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := storage.Watch(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	for range cs {
+	}
+	// Changes done once nobody watches the graph are not queued.
+	if err := g.AddTriples(context.Background(), getTestTriples(t)); err != nil {
+		t.Fatal(err)
+	}
+	if g.(*memory).feed.Watched() {
+		t.Error("the graph is still watched once the context of the watcher is done")
+	}
+}
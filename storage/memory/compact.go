@@ -0,0 +1,94 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/google/badwolf/triple"
+)
+
+// triplesOf returns the triples of the graph. It must be called with the read
+// lock of m held.
+func (m *memory) triplesOf() []*triple.Triple {
+	ts := make([]*triple.Triple, 0, len(m.idx))
+	for _, t := range m.idx {
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+// rebuild returns a graph with the provided triples, whose indices are sized
+// for them, sharing the dictionary of m.
+func (m *memory) rebuild(ctx context.Context, ts []*triple.Triple) (*memory, error) {
+	c := newMemory(m.id, m.indexes, len(ts))
+	c.dict, c.quota, c.feed = m.dict, nil, nil
+	if err := c.AddTriples(ctx, ts); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Compact rebuilds the indices of the graph if triples were removed since they
+// were built. Go maps never shrink, so the indices of a graph keep the space
+// of all the triples they ever held until they are rebuilt. The new indices
+// are built without blocking lookups; changes to the graph wait until they
+// are replaced. Snapshots keep the indices they share. Once the indices are
+// replaced, the memory freed is returned to the operating system.
+//
+// Dictionaries are not compacted, since the transactions and snapshots of the
+// graph rely on their handles.
+func (m *memory) Compact(ctx context.Context) error {
+	m.rwmu.RLock()
+	removed, epoch, ts := m.removed, m.epoch, m.triplesOf()
+	m.rwmu.RUnlock()
+	if removed == 0 {
+		return nil
+	}
+	c, err := m.rebuild(ctx, ts)
+	if err != nil {
+		return err
+	}
+
+	m.rwmu.Lock()
+	if m.epoch != epoch {
+		// The graph changed while rebuilding its indices, so they are rebuilt
+		// again holding the lock.
+		if c, err = m.rebuild(ctx, m.triplesOf()); err != nil {
+			m.rwmu.Unlock()
+			return err
+		}
+	}
+	m.idx, m.idxS, m.idxP, m.idxO = c.idx, c.idxS, c.idxP, c.idxO
+	m.idxSP, m.idxPO, m.idxSO, m.idxT = c.idxSP, c.idxPO, c.idxSO, c.idxT
+	m.bloom, m.bytes, m.removed = c.bloom, c.bytes, 0
+	// The new indices are not shared with any snapshot.
+	m.cow = nil
+	m.rwmu.Unlock()
+
+	debug.FreeOSMemory()
+	return nil
+}
+
+// Compact compacts the shards of the graph.
+func (g *shardedMemory) Compact(ctx context.Context) error {
+	for _, m := range g.shards {
+		if err := m.Compact(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
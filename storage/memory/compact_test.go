@@ -0,0 +1,175 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+)
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 8)
+	for _, s := range []storage.Store{NewStore(), NewShardedStore(4, AllIndexes)} {
+		g, err := s.NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+		snap, err := storage.Snapshot(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := graphContents(ctx, t, g)
+		if err := g.RemoveTriples(ctx, ts[:len(ts)-4]); err != nil {
+			t.Fatal(err)
+		}
+		before := graphContents(ctx, t, g)
+		st, err := storage.Statistics(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := storage.Compact(ctx, g); err != nil {
+			t.Fatalf("storage.Compact failed with error %v", err)
+		}
+		if got := graphContents(ctx, t, g); !reflect.DeepEqual(got, before) {
+			t.Errorf("storage.Compact changed the triples of the graph to %v; want %v", got, before)
+		}
+		for _, m := range shardsOf(g) {
+			if m.removed != 0 {
+				t.Errorf("storage.Compact left %d removed triples in a shard; want 0", m.removed)
+			}
+		}
+		cst, err := storage.Statistics(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cst.Triples != st.Triples || cst.MemoryBytes > st.MemoryBytes {
+			t.Errorf("storage.Compact returned statistics %+v; want the same triples and at most %d bytes", cst, st.MemoryBytes)
+		}
+		for i, tr := range ts {
+			ok, err := g.Exist(ctx, tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := i >= len(ts)-4; ok != want {
+				t.Errorf("g.Exist(%s) returned %v after compacting; want %v", tr, ok, want)
+			}
+		}
+
+		// Snapshots keep the triples they were taken with.
+		if got := graphContents(ctx, t, snap); !reflect.DeepEqual(got, want) {
+			t.Errorf("the snapshot has %d triples after compacting the graph; want %d", len(got), len(want))
+		}
+		if err := snap.Release(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		// The compacted graph can keep changing.
+		if err := g.AddTriples(ctx, ts[:4]); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := storage.CountTriples(ctx, g); err != nil || n != 8 {
+			t.Errorf("storage.CountTriples returned %d, %v after compacting; want 8", n, err)
+		}
+	}
+}
+
+func TestCompactTransaction(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 8)
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RemoveTriples(ctx, ts[:8]); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := storage.BeginGraph(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.RemoveTriples(ctx, ts[8:10]); err != nil {
+		t.Fatal(err)
+	}
+	// Compacting does not change the triples, so it does not invalidate the
+	// transactions of the graph.
+	if err := storage.Compact(ctx, g); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("tx.Commit failed with error %v after compacting the graph", err)
+	}
+	if n, err := storage.CountTriples(ctx, g); err != nil || n != int64(len(ts)-10) {
+		t.Errorf("storage.CountTriples returned %d, %v; want %d", n, err, len(ts)-10)
+	}
+	if m := g.(*memory); m.removed != 2 {
+		t.Errorf("committing the transaction left %d removed triples; want 2", m.removed)
+	}
+}
+
+func TestCompactor(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 8)
+	s := NewStore()
+	for _, id := range []string{"?b", "?a"} {
+		g, err := s.NewGraph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.RemoveTriples(ctx, ts[:4]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := storage.NewCompactor(s, 0)
+	var compacted []string
+	c.OnCompact = func(id string, err error) {
+		if err != nil {
+			t.Errorf("compacting graph %q failed with error %v", id, err)
+		}
+		compacted = append(compacted, id)
+	}
+	if err := c.CompactAll(ctx); err != nil {
+		t.Fatalf("c.CompactAll failed with error %v", err)
+	}
+	if want := []string{"?a", "?b"}; !reflect.DeepEqual(compacted, want) {
+		t.Errorf("c.CompactAll compacted graphs %v; want %v", compacted, want)
+	}
+	for _, id := range compacted {
+		g, err := s.Graph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := g.(*memory); m.removed != 0 || len(m.idx) != len(ts)-4 {
+			t.Errorf("graph %q has %d triples and %d removed after compacting; want %d and 0", id, len(m.idx), m.removed, len(ts)-4)
+		}
+	}
+
+	if err := storage.Compact(ctx, struct{ storage.Graph }{}); err != storage.ErrNoCompaction {
+		t.Errorf("storage.Compact returned %v for a graph without compaction; want storage.ErrNoCompaction", err)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// cowRefs counts the unreleased snapshots sharing the indices of a graph. It
+// is only changed while holding the write lock of the graph.
+type cowRefs struct {
+	n int
+}
+
+// share returns a graph that shares the indices of m, and records that a
+// snapshot uses them. It must be called with the write lock of m held.
+func (m *memory) share() *memory {
+	if m.cow == nil {
+		m.cow = &cowRefs{}
+	}
+	m.cow.n++
+	return &memory{
+		id:      m.id,
+		indexes: m.indexes,
+		idx:     m.idx,
+		idxS:    m.idxS,
+		idxP:    m.idxP,
+		idxO:    m.idxO,
+		idxSP:   m.idxSP,
+		idxPO:   m.idxPO,
+		idxSO:   m.idxSO,
+		idxT:    m.idxT,
+		dict:    m.dict,
+		epoch:   m.epoch,
+		bytes:   m.bytes,
+		bloom:   m.bloom,
+		vectors: m.vectors,
+	}
+}
+
+// unshare copies the indices of the graph if unreleased snapshots share them,
+// so changing them afterwards does not change the snapshots. It must be called
+// with the write lock of m held before changing the indices.
+func (m *memory) unshare() {
+	if m.cow == nil {
+		return
+	}
+	if m.cow.n > 0 {
+		idx := make(map[string]*triple.Triple, len(m.idx))
+		for k, t := range m.idx {
+			idx[k] = t
+		}
+		m.idx = idx
+		m.idxS, m.idxP, m.idxO = copyIndex(m.idxS), copyIndex(m.idxP), copyIndex(m.idxO)
+		m.idxSP, m.idxPO, m.idxSO = copyIndex(m.idxSP), copyIndex(m.idxPO), copyIndex(m.idxSO)
+		idxT := make(map[string]*timeIndex, len(m.idxT))
+		for k, ti := range m.idxT {
+			idxT[k] = ti.clone()
+		}
+		m.idxT = idxT
+		m.bloom = m.bloom.clone()
+	}
+	m.cow = nil
+}
+
+// copyIndex returns a copy of the provided index. Nil indices are not copied.
+func copyIndex(idx map[string]map[string]*triple.Triple) map[string]map[string]*triple.Triple {
+	if idx == nil {
+		return nil
+	}
+	res := make(map[string]map[string]*triple.Triple, len(idx))
+	for k, ts := range idx {
+		cts := make(map[string]*triple.Triple, len(ts))
+		for id, t := range ts {
+			cts[id] = t
+		}
+		res[k] = cts
+	}
+	return res
+}
+
+// clone returns a copy of the time index. Snapshots may be sorting the index
+// concurrently, so its lock is held while copying it.
+func (ti *timeIndex) clone() *timeIndex {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	c := &timeIndex{
+		immutable: make(map[string]*triple.Triple, len(ti.immutable)),
+		intervals: make(map[string]*triple.Triple, len(ti.intervals)),
+		entries:   make([]*anchorEntry, len(ti.entries)),
+		sorted:    ti.sorted,
+	}
+	for id, t := range ti.immutable {
+		c.immutable[id] = t
+	}
+	for id, t := range ti.intervals {
+		c.intervals[id] = t
+	}
+	copy(c.entries, ti.entries)
+	return c
+}
+
+// Snapshot returns a read only view of the current triples of the graph. The
+// snapshot shares the indices of the graph, so taking it does not copy any
+// triple; the graph copies its indices the first time it changes while
+// unreleased snapshots share them.
+func (m *memory) Snapshot(ctx context.Context) (storage.GraphSnapshot, error) {
+	m.rwmu.Lock()
+	defer m.rwmu.Unlock()
+	return &graphSnapshot{
+		memoryGraph: m.share(),
+		parents:     []*memory{m},
+		refs:        []*cowRefs{m.cow},
+	}, nil
+}
+
+// Snapshot returns a read only view of the current triples of all the shards.
+// All the shards are locked while taking the snapshot, so it never sees a
+// change applied to only some of them.
+func (g *shardedMemory) Snapshot(ctx context.Context) (storage.GraphSnapshot, error) {
+	s := &graphSnapshot{parents: g.shards}
+	c := &shardedMemory{id: g.id}
+	for _, m := range g.shards {
+		m.rwmu.Lock()
+		defer m.rwmu.Unlock()
+	}
+	for _, m := range g.shards {
+		c.shards = append(c.shards, m.share())
+		s.refs = append(s.refs, m.cow)
+	}
+	s.memoryGraph = c
+	return s, nil
+}
+
+// memoryGraph is implemented by all the graphs of a memory store.
+type memoryGraph interface {
+	storage.Graph
+	storage.TripleCounter
+	storage.StatisticsProvider
+	storage.EpochProvider
+	storage.PredicateIDLister
+	storage.VectorSearcher
+}
+
+// graphSnapshot is a snapshot of a memory graph. The embedded graph shares
+// the indices of the graphs the snapshot was taken from.
+type graphSnapshot struct {
+	memoryGraph
+
+	// parents are the graphs the snapshot was taken from, and refs the
+	// counters of the snapshots sharing each of their indices.
+	parents []*memory
+	refs    []*cowRefs
+
+	mu       sync.Mutex
+	released bool
+}
+
+// AddTriples fails since snapshots are read only.
+func (s *graphSnapshot) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	return fmt.Errorf("memory: cannot add triples to a snapshot of graph %q", s.ID(ctx))
+}
+
+// RemoveTriples fails since snapshots are read only.
+func (s *graphSnapshot) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	return fmt.Errorf("memory: cannot remove triples from a snapshot of graph %q", s.ID(ctx))
+}
+
+// Snapshot returns another snapshot of the same triples, which needs to be
+// released on its own.
+func (s *graphSnapshot) Snapshot(ctx context.Context) (storage.GraphSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return nil, fmt.Errorf("memory: the snapshot of graph %q was already released", s.ID(ctx))
+	}
+	for i, m := range s.parents {
+		m.rwmu.Lock()
+		s.refs[i].n++
+		m.rwmu.Unlock()
+	}
+	return &graphSnapshot{memoryGraph: s.memoryGraph, parents: s.parents, refs: s.refs}, nil
+}
+
+// Release stops sharing the indices of the graphs, so changing them no longer
+// requires copying the indices.
+func (s *graphSnapshot) Release(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return fmt.Errorf("memory: the snapshot of graph %q was already released", s.ID(ctx))
+	}
+	s.released = true
+	for i, m := range s.parents {
+		m.rwmu.Lock()
+		s.refs[i].n--
+		m.rwmu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+func TestGraphSnapshot(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 8)
+	for _, s := range []storage.Store{NewStore(), NewShardedStore(4, AllIndexes)} {
+		g, err := s.NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts[:12]); err != nil {
+			t.Fatal(err)
+		}
+		snap, err := storage.Snapshot(ctx, g)
+		if err != nil {
+			t.Fatalf("storage.Snapshot failed with error %v", err)
+		}
+		want := graphContents(ctx, t, g)
+		e, err := storage.Epoch(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Changing the graph does not change the snapshot.
+		if err := g.AddTriples(ctx, ts[12:]); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.RemoveTriples(ctx, ts[:6]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := storage.Expire(ctx, g, &storage.RetentionPolicy{}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		got := graphContents(ctx, t, snap)
+		if len(got) != len(want) {
+			t.Fatalf("snapshot returned %d triples after changing the graph; want %d", len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("snapshot returned %v after changing the graph; want %v", got, want)
+				break
+			}
+		}
+		for _, trpl := range ts[:6] {
+			if ok, err := snap.Exist(ctx, trpl); err != nil || !ok {
+				t.Errorf("snapshot.Exist(%s) returned %v, %v after removing it from the graph; want true", trpl, ok, err)
+			}
+		}
+		if got, err := storage.Epoch(ctx, snap); err != nil || got != e {
+			t.Errorf("storage.Epoch(snapshot) returned %d, %v; want %d", got, err, e)
+		}
+		if ok, err := g.Exist(ctx, ts[0]); err != nil || ok {
+			t.Errorf("g.Exist(%s) returned %v, %v after removing it from the graph; want false", ts[0], ok, err)
+		}
+
+		// Snapshots are read only.
+		if err := snap.AddTriples(ctx, ts); err == nil {
+			t.Errorf("snapshot.AddTriples should fail")
+		}
+		if err := snap.RemoveTriples(ctx, ts); err == nil {
+			t.Errorf("snapshot.RemoveTriples should fail")
+		}
+
+		// Nested snapshots outlive the snapshot they were taken from.
+		nested, err := storage.Snapshot(ctx, snap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := snap.Release(ctx); err != nil {
+			t.Errorf("snapshot.Release failed with error %v", err)
+		}
+		if err := snap.Release(ctx); err == nil {
+			t.Errorf("snapshot.Release should fail on an already released snapshot")
+		}
+		if err := g.AddTriples(ctx, ts[:6]); err != nil {
+			t.Fatal(err)
+		}
+		if got := graphContents(ctx, t, nested); len(got) != len(want) {
+			t.Errorf("nested snapshot returned %d triples after changing the graph; want %d", len(got), len(want))
+		}
+		if err := nested.Release(ctx); err != nil {
+			t.Errorf("nested.Release failed with error %v", err)
+		}
+	}
+}
+
+func TestGraphSnapshotRelease(t *testing.T) {
+	ctx := context.Background()
+	ts := getTestTriples(t)
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts[:3]); err != nil {
+		t.Fatal(err)
+	}
+	m := g.(*memory)
+	snap, err := storage.Snapshot(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snap.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Once all the snapshots are released, the indices are no longer copied.
+	idx := m.idx
+	if err := g.AddTriples(ctx, ts[3:]); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx) != len(ts) {
+		t.Errorf("changing a graph without snapshots copied its indices")
+	}
+	if m.cow != nil {
+		t.Errorf("changing a graph should reset the count of snapshots sharing its indices")
+	}
+	// Unreleased snapshots make the next change copy the indices, but only
+	// once.
+	if _, err := storage.Snapshot(ctx, g); err != nil {
+		t.Fatal(err)
+	}
+	idx = m.idx
+	if err := g.RemoveTriples(ctx, ts[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx) != len(ts) || len(m.idx) != len(ts)-2 {
+		t.Errorf("changing a graph with snapshots did not copy its indices; got %d and %d triples", len(idx), len(m.idx))
+	}
+}
+
+func TestGraphSnapshotConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 32)
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < len(ts); i += 4 {
+		wg.Add(2)
+		go func(ts []*triple.Triple) {
+			defer wg.Done()
+			if err := g.AddTriples(ctx, ts); err != nil {
+				t.Error(err)
+			}
+		}(ts[i : i+4])
+		go func() {
+			defer wg.Done()
+			snap, err := storage.Snapshot(ctx, g)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer snap.Release(ctx)
+			want, err := storage.CountTriples(ctx, snap)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			lo := &storage.LookupOptions{LowerAnchor: mustParse("2010-01-01T00:00:00Z")}
+			c := make(chan *triple.Triple)
+			go func() {
+				for range c {
+				}
+			}()
+			if err := snap.TriplesForPredicate(ctx, ts[2].Predicate(), lo, c); err != nil {
+				t.Error(err)
+			}
+			if got, err := storage.CountTriples(ctx, snap); err != nil || got != want {
+				t.Errorf("snapshot changed from %d to %d triples while reading it; %v", want, got, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,278 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sync"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// Kinds of the values interned in a dictionary. Nodes are interned once
+// whether they are used as subjects or objects.
+const (
+	nodeValue byte = iota
+	predicateIDValue
+	objectValue
+)
+
+// handleSize is the size in bytes of the index keys of interned values.
+const handleSize = 4
+
+// dictEntry is a value interned in a dictionary.
+type dictEntry struct {
+	// n is set for nodes.
+	n *node.Node
+	// p is the immutable predicate of a predicate ID.
+	p *predicate.Predicate
+	// o is set for nodes, literals and predicates used as objects.
+	o *triple.Object
+}
+
+// dictionary interns the nodes, predicate IDs and objects of the triples of a
+// graph. Every distinct value is kept once and identified by an integer
+// handle, which the indices use as keys instead of the UUID of the value, and
+// the stored triples are rebuilt out of the interned values so they do not
+// keep their own copy of them.
+//
+// Values are never removed from a dictionary, so handles stay valid for the
+// snapshots and transactions sharing it. The dictionary has its own lock,
+// since it is shared by all of them.
+type dictionary struct {
+	mu      sync.RWMutex
+	handles map[string]uint32
+	entries []*dictEntry
+	// bytes is an approximation of the memory used by the entries.
+	bytes int64
+}
+
+// newDictionary returns an empty dictionary.
+func newDictionary() *dictionary {
+	return &dictionary{handles: make(map[string]uint32)}
+}
+
+// handleKey returns the index key of the provided handle.
+func handleKey(h uint32) string {
+	var b [handleSize]byte
+	binary.BigEndian.PutUint32(b[:], h)
+	return string(b[:])
+}
+
+// lookup returns the index key of the value of the provided kind and UUID, or
+// the empty string if the value was never interned. The empty string is never
+// used as a key, so lookups with it find nothing.
+func (d *dictionary) lookup(kind byte, uuid string) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	h, ok := d.handles[string(kind)+uuid]
+	if !ok {
+		return ""
+	}
+	return handleKey(h)
+}
+
+// intern returns the index key and the entry of the value of the provided kind
+// and UUID. If the value was never interned, the entry returned by mk is
+// added to the dictionary.
+func (d *dictionary) intern(kind byte, uuid string, mk func() (*dictEntry, error)) (string, *dictEntry, error) {
+	k := string(kind) + uuid
+	d.mu.RLock()
+	h, ok := d.handles[k]
+	var e *dictEntry
+	if ok {
+		e = d.entries[h]
+	}
+	d.mu.RUnlock()
+	if ok {
+		return handleKey(h), e, nil
+	}
+	e, err := mk()
+	if err != nil {
+		return "", nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if h, ok := d.handles[k]; ok {
+		return handleKey(h), d.entries[h], nil
+	}
+	h = uint32(len(d.entries))
+	d.handles[k] = h
+	d.entries = append(d.entries, e)
+	d.bytes += entryBytes(k, e)
+	return handleKey(h), e, nil
+}
+
+// len returns the number of values in the dictionary.
+func (d *dictionary) len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.entries)
+}
+
+// node returns the index key of the provided node.
+func (d *dictionary) node(n *node.Node) string {
+	return d.lookup(nodeValue, UUIDToByteString(n.UUID()))
+}
+
+// predicateID returns the index key of the ID of the provided predicate.
+func (d *dictionary) predicateID(p *predicate.Predicate) string {
+	return d.lookup(predicateIDValue, UUIDToByteString(p.PartialUUID()))
+}
+
+// object returns the index key of the provided object.
+func (d *dictionary) object(o *triple.Object) string {
+	if n, err := o.Node(); err == nil {
+		return d.node(n)
+	}
+	return d.lookup(objectValue, UUIDToByteString(o.UUID()))
+}
+
+// anchorKey returns the part of the index key of a triple that identifies the
+// time anchor of its predicate. Immutable predicates have none, and interval
+// ones also include the end of their interval.
+func anchorKey(p *predicate.Predicate) string {
+	ta, err := p.TimeAnchor()
+	if err != nil {
+		return ""
+	}
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(ta.UnixNano()))
+	if _, end, err := p.Interval(); err == nil {
+		binary.BigEndian.PutUint64(b[8:], uint64(end.UnixNano()))
+		return string(b[:])
+	}
+	return string(b[:8])
+}
+
+// valueKeys holds the UUIDs used to look up the values of a triple in a
+// dictionary.
+type valueKeys struct {
+	s, p, o string
+	// oKind is the kind of the object, which is interned as a node if it is
+	// one.
+	oKind  byte
+	anchor string
+}
+
+// valueKeysOf returns the UUIDs used to look up the values of the provided
+// triple.
+func valueKeysOf(t *triple.Triple) *valueKeys {
+	v := &valueKeys{
+		s:      UUIDToByteString(t.Subject().UUID()),
+		p:      UUIDToByteString(t.Predicate().PartialUUID()),
+		o:      UUIDToByteString(t.Object().UUID()),
+		oKind:  objectValue,
+		anchor: anchorKey(t.Predicate()),
+	}
+	if _, err := t.Object().Node(); err == nil {
+		v.oKind = nodeValue
+	}
+	return v
+}
+
+// hash returns the hash of the triple used by bloom filters. It only depends
+// on the UUIDs of the values of the triple, so it can be computed without
+// looking them up.
+func (v *valueKeys) hash() uint64 {
+	h := fnv.New64a()
+	for _, k := range []string{v.s, v.p, v.o, v.anchor} {
+		h.Write([]byte(k))
+	}
+	return h.Sum64()
+}
+
+// find returns the keys of the provided triple, or nil if any of its values
+// was never interned, in which case the triple is not in the graph.
+func (d *dictionary) find(t *triple.Triple) *tripleKeys {
+	return d.findValues(t, valueKeysOf(t))
+}
+
+// findValues returns the keys of the triple with the provided value UUIDs, or
+// nil if any of its values was never interned.
+func (d *dictionary) findValues(t *triple.Triple, v *valueKeys) *tripleKeys {
+	k := &tripleKeys{
+		t: t,
+		s: d.lookup(nodeValue, v.s),
+		p: d.lookup(predicateIDValue, v.p),
+		o: d.lookup(v.oKind, v.o),
+		h: v.hash(),
+	}
+	if k.s == "" || k.p == "" || k.o == "" {
+		return nil
+	}
+	k.id = k.s + k.p + k.o + v.anchor
+	return k
+}
+
+// keys interns the values of the provided triple and returns its keys. The
+// triple of the returned keys is rebuilt out of the interned values.
+func (d *dictionary) keys(t *triple.Triple) (*tripleKeys, error) {
+	s, p, o := t.Subject(), t.Predicate(), t.Object()
+	v := valueKeysOf(t)
+	nodeEntry := func(n *node.Node) func() (*dictEntry, error) {
+		return func() (*dictEntry, error) {
+			return &dictEntry{n: n, o: triple.NewNodeObject(n)}, nil
+		}
+	}
+	sk, se, err := d.intern(nodeValue, v.s, nodeEntry(s))
+	if err != nil {
+		return nil, err
+	}
+	pk, pe, err := d.intern(predicateIDValue, v.p, func() (*dictEntry, error) {
+		if p.Type() == predicate.Immutable {
+			return &dictEntry{p: p}, nil
+		}
+		ip, err := predicate.NewImmutable(string(p.ID()))
+		if err != nil {
+			return nil, err
+		}
+		return &dictEntry{p: ip}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ok, oe, err := d.intern(v.oKind, v.o, func() (*dictEntry, error) {
+		if n, err := o.Node(); err == nil {
+			return nodeEntry(n)()
+		}
+		return &dictEntry{o: o}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ip := pe.p
+	if p.Type() == predicate.Temporal {
+		if ip, err = p.WithID(string(pe.p.ID())); err != nil {
+			return nil, err
+		}
+	}
+	it, err := triple.New(se.n, ip, oe.o)
+	if err != nil {
+		return nil, err
+	}
+	return &tripleKeys{
+		t:  it,
+		id: sk + pk + ok + v.anchor,
+		s:  sk,
+		p:  pk,
+		o:  ok,
+		h:  v.hash(),
+	}, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+func TestDictionaryInterning(t *testing.T) {
+	ctx := context.Background()
+	// Every triple is parsed on its own, so none of them share their values.
+	ts := createTriples(t, []string{
+		"/u<john>\t\"knows\"@[]\t/u<mary>",
+		"/u<john>\t\"knows\"@[]\t/u<peter>",
+		"/u<mary>\t\"knows\"@[]\t/u<john>",
+		"/u<mary>\t\"met\"@[2016-04-10T04:21:00Z]\t/u<peter>",
+		"/u<mary>\t\"met\"@[2016-04-11T04:21:00Z]\t/u<peter>",
+		"/u<mary>\t\"age\"@[]\t\"42\"^^type:int64",
+		"/u<peter>\t\"age\"@[]\t\"42\"^^type:int64",
+		"/u<peter>\t\"parent_of\"@[]\t\"met\"@[2016-04-10T04:21:00Z]",
+	})
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	m := g.(*memory)
+	// Nodes: john, mary, peter. Predicate IDs: knows, met, age, parent_of.
+	// Other objects: the literal and the temporal predicate.
+	if got, want := m.dict.len(), 9; got != want {
+		t.Errorf("the dictionary has %d values; want %d", got, want)
+	}
+	for k := range m.idxS {
+		if len(k) != handleSize {
+			t.Errorf("the subject index uses keys of %d bytes; want %d", len(k), handleSize)
+		}
+	}
+
+	// Stored triples share the interned values.
+	trpls := make(chan *triple.Triple, len(ts))
+	if err := g.TriplesForSubject(ctx, ts[0].Subject(), storage.DefaultLookup, trpls); err != nil {
+		t.Fatal(err)
+	}
+	var stored []*triple.Triple
+	for trpl := range trpls {
+		stored = append(stored, trpl)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("g.TriplesForSubject returned %d triples; want 2", len(stored))
+	}
+	if stored[0].Subject() != stored[1].Subject() || stored[0].Predicate() != stored[1].Predicate() {
+		t.Errorf("stored triples %v do not share their subject and predicate", stored)
+	}
+	objs := make(chan *triple.Object, len(ts))
+	if err := g.Objects(ctx, ts[2].Subject(), ts[2].Predicate(), storage.DefaultLookup, objs); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := (<-objs).Node(); err != nil || n != stored[0].Subject() {
+		t.Errorf("the node used as object %v is not the one used as subject %v; %v", n, stored[0].Subject(), err)
+	}
+
+	// Removing and adding triples again keeps the dictionary.
+	if err := g.RemoveTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != 0 {
+		t.Errorf("g.RemoveTriples left %d triples, %v", cnt, err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.dict.len(), 9; got != want {
+		t.Errorf("the dictionary has %d values after adding the same triples again; want %d", got, want)
+	}
+	for _, trpl := range ts {
+		if ok, err := g.Exist(ctx, trpl); err != nil || !ok {
+			t.Errorf("g.Exist(%s) returned %v, %v; want true", trpl, ok, err)
+		}
+	}
+	got := graphContents(ctx, t, g)
+	for i, trpl := range ts {
+		found := false
+		for _, s := range got {
+			found = found || s == trpl.String()
+		}
+		if !found {
+			t.Errorf("stored triple %d %s was not returned by g.Triples", i, trpl)
+		}
+	}
+}
+
+func TestDictionaryUnknownValues(t *testing.T) {
+	ctx := context.Background()
+	ts := getTestTriples(t)
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts[:1]); err != nil {
+		t.Fatal(err)
+	}
+	// ts[1] only differs on an object never interned.
+	if ok, err := g.Exist(ctx, ts[1]); err != nil || ok {
+		t.Errorf("g.Exist(%s) returned %v, %v; want false", ts[1], ok, err)
+	}
+	if err := g.RemoveTriples(ctx, ts[1:]); err != nil {
+		t.Errorf("g.RemoveTriples of unknown triples failed with error %v", err)
+	}
+	trpls := make(chan *triple.Triple, len(ts))
+	if err := g.TriplesForObject(ctx, ts[1].Object(), storage.DefaultLookup, trpls); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(trpls); n != 0 {
+		t.Errorf("g.TriplesForObject of an unknown object returned %d triples", n)
+	}
+	if got, want := g.(*memory).dict.len(), 3; got != want {
+		t.Errorf("lookups should not intern values; the dictionary has %d values, want %d", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+)
+
+func TestDiff(t *testing.T) {
+	table := []struct {
+		a, b          []string
+		ignoreAnchors bool
+		left, right   []string
+	}{
+		{
+			a: []string{"/u<joe>\t\"knows\"@[]\t/u<mary>"},
+			b: []string{"/u<joe>\t\"knows\"@[]\t/u<mary>"},
+		},
+		{
+			a: []string{
+				"/u<joe>\t\"knows\"@[]\t/u<mary>",
+				"/u<joe>\t\"knows\"@[]\t/u<peter>",
+			},
+			b: []string{
+				"/u<joe>\t\"knows\"@[]\t/u<mary>",
+				"/u<mary>\t\"knows\"@[]\t/u<joe>",
+			},
+			left:  []string{"/u<joe>\t\"knows\"@[]\t/u<peter>"},
+			right: []string{"/u<mary>\t\"knows\"@[]\t/u<joe>"},
+		},
+		{
+			a:     []string{"/u<joe>\t\"met\"@[2016-01-01T00:00:00Z]\t/u<mary>"},
+			b:     []string{"/u<joe>\t\"met\"@[2017-01-01T00:00:00Z]\t/u<mary>"},
+			left:  []string{"/u<joe>\t\"met\"@[2016-01-01T00:00:00Z]\t/u<mary>"},
+			right: []string{"/u<joe>\t\"met\"@[2017-01-01T00:00:00Z]\t/u<mary>"},
+		},
+		{
+			a: []string{
+				"/u<joe>\t\"met\"@[2016-01-01T00:00:00Z]\t/u<mary>",
+				"/u<joe>\t\"met\"@[2016-01-01T00:00:00Z]\t/u<peter>",
+			},
+			b:             []string{"/u<joe>\t\"met\"@[2017-01-01T00:00:00Z]\t/u<mary>"},
+			ignoreAnchors: true,
+			left:          []string{"/u<joe>\t\"met\"@[2016-01-01T00:00:00Z]\t/u<peter>"},
+		},
+		{
+			a:     []string{"/u<joe>\t\"knows\"@[]\t/u<mary>"},
+			b:     []string{"/u<mary>\t\"knows\"@[]\t/u<joe>", "/u<joe>\t\"knows\"@[]\t/u<mary>"},
+			right: []string{"/u<mary>\t\"knows\"@[]\t/u<joe>"},
+		},
+	}
+	for i, entry := range table {
+		ctx := context.Background()
+		s := NewStore()
+		var gs []storage.Graph
+		for j, ss := range [][]string{entry.a, entry.b} {
+			g, err := s.NewGraph(ctx, []string{"?a", "?b"}[j])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := g.AddTriples(ctx, createTriples(t, ss)); err != nil {
+				t.Fatal(err)
+			}
+			gs = append(gs, g)
+		}
+		ds, errs := make(chan *storage.Difference), make(chan error, 1)
+		go func() {
+			errs <- storage.Diff(ctx, gs[0], gs[1], &storage.DiffOptions{IgnoreAnchors: entry.ignoreAnchors}, ds)
+		}()
+		var left, right []string
+		for d := range ds {
+			if d.Left {
+				left = append(left, d.Triple.String())
+			} else {
+				right = append(right, d.Triple.String())
+			}
+		}
+		if err := <-errs; err != nil {
+			t.Errorf("[case %d] storage.Diff failed with error %v", i, err)
+		}
+		sort.Strings(left)
+		sort.Strings(right)
+		if !reflect.DeepEqual(left, entry.left) || !reflect.DeepEqual(right, entry.right) {
+			t.Errorf("[case %d] storage.Diff returned %q and %q; want %q and %q", i, left, right, entry.left, entry.right)
+		}
+	}
+}
@@ -19,7 +19,9 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/badwolf/storage"
@@ -38,14 +40,51 @@ func init() {
 }
 
 type memoryStore struct {
-	graphs map[string]storage.Graph
-	rwmu   sync.RWMutex
+	graphs   map[string]storage.Graph
+	metadata map[string]*storage.GraphMetadata
+	indexes  Indexes
+	shards   int
+	rwmu     sync.RWMutex
+	// literals limits the size of the literals of all the graphs.
+	literals *literalLimit
+	// blobs keeps the chunks of the blobs of the store by digest.
+	blobs *blobs
 }
 
-// NewStore creates a new memory store.
+// Indexes is a set of the secondary indices kept by memory graphs. Graphs
+// always index triples by subject, by predicate, and by object. Secondary
+// indices make lookups on two of them proportional to the number of triples
+// returned, at the cost of the memory needed to keep them. Without them, such
+// lookups intersect the triples of the subject, predicate, or object indices.
+type Indexes uint8
+
+const (
+	// SPOIndex indexes triples by subject and predicate.
+	SPOIndex Indexes = 1 << iota
+	// POSIndex indexes triples by predicate and object.
+	POSIndex
+	// OSPIndex indexes triples by object and subject.
+	OSPIndex
+
+	// AllIndexes keeps all the secondary indices.
+	AllIndexes = SPOIndex | POSIndex | OSPIndex
+)
+
+// NewStore creates a new memory store whose graphs keep all the secondary
+// indices.
 func NewStore() storage.Store {
+	return NewStoreWithIndexes(AllIndexes)
+}
+
+// NewStoreWithIndexes creates a new memory store whose graphs only keep the
+// provided secondary indices.
+func NewStoreWithIndexes(idxs Indexes) storage.Store {
 	return &memoryStore{
-		graphs: make(map[string]storage.Graph),
+		graphs:   make(map[string]storage.Graph),
+		metadata: make(map[string]*storage.GraphMetadata),
+		indexes:  idxs,
+		literals: &literalLimit{},
+		blobs:    newBlobs(),
 	}
 }
 
@@ -61,15 +100,15 @@ func (s *memoryStore) Version(ctx context.Context) string {
 
 // NewGraph creates a new graph.
 func (s *memoryStore) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
-	g := &memory{
-		id:    id,
-		idx:   make(map[string]*triple.Triple, initialAllocation),
-		idxS:  make(map[string]map[string]*triple.Triple, initialAllocation),
-		idxP:  make(map[string]map[string]*triple.Triple, initialAllocation),
-		idxO:  make(map[string]map[string]*triple.Triple, initialAllocation),
-		idxSP: make(map[string]map[string]*triple.Triple, initialAllocation),
-		idxPO: make(map[string]map[string]*triple.Triple, initialAllocation),
-		idxSO: make(map[string]map[string]*triple.Triple, initialAllocation),
+	var g storage.Graph
+	if s.shards > 1 {
+		sg := newShardedMemory(id, s.shards, s.indexes)
+		sg.quota.literals = s.literals
+		g = sg
+	} else {
+		m := newMemory(id, s.indexes, initialAllocation)
+		m.quota.literals = s.literals
+		g = m
 	}
 
 	s.rwmu.Lock()
@@ -78,6 +117,7 @@ func (s *memoryStore) NewGraph(ctx context.Context, id string) (storage.Graph, e
 		return nil, fmt.Errorf("memory.NewGraph(%q): graph already exists", id)
 	}
 	s.graphs[id] = g
+	s.metadata[id] = &storage.GraphMetadata{Created: time.Now()}
 	return g, nil
 }
 
@@ -97,8 +137,16 @@ func (s *memoryStore) Graph(ctx context.Context, id string) (storage.Graph, erro
 func (s *memoryStore) DeleteGraph(ctx context.Context, id string) error {
 	s.rwmu.Lock()
 	defer s.rwmu.Unlock()
-	if _, ok := s.graphs[id]; ok {
+	if g, ok := s.graphs[id]; ok {
+		// The watchers of the graph are done once it is deleted.
+		switch g := g.(type) {
+		case *memory:
+			g.feed.Close()
+		case *shardedMemory:
+			g.feed.Close()
+		}
 		delete(s.graphs, id)
+		delete(s.metadata, id)
 		return nil
 	}
 	return fmt.Errorf("memory.DeleteGraph(%q): graph does not exist", id)
@@ -111,24 +159,218 @@ func (s *memoryStore) GraphNames(ctx context.Context, names chan<- string) error
 	}
 	s.rwmu.RLock()
 	defer s.rwmu.RUnlock()
+	defer close(names)
 	for k := range s.graphs {
-		names <- k
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case names <- k:
+		}
+	}
+	return nil
+}
+
+// GraphMetadata returns the metadata of the graph with the provided ID.
+func (s *memoryStore) GraphMetadata(ctx context.Context, id string) (*storage.GraphMetadata, error) {
+	s.rwmu.RLock()
+	defer s.rwmu.RUnlock()
+	md, ok := s.metadata[id]
+	if !ok {
+		return nil, fmt.Errorf("memory.GraphMetadata(%q): graph does not exist", id)
+	}
+	return copyMetadata(md.Created, md), nil
+}
+
+// SetGraphMetadata replaces the description and labels of the graph with the
+// provided ID.
+func (s *memoryStore) SetGraphMetadata(ctx context.Context, id string, md *storage.GraphMetadata) error {
+	s.rwmu.Lock()
+	defer s.rwmu.Unlock()
+	old, ok := s.metadata[id]
+	if !ok {
+		return fmt.Errorf("memory.SetGraphMetadata(%q): graph does not exist", id)
 	}
-	close(names)
+	s.metadata[id] = copyMetadata(old.Created, md)
+	return nil
+}
+
+// GraphQuota returns the quota of the graph with the provided ID.
+func (s *memoryStore) GraphQuota(ctx context.Context, id string) (*storage.GraphQuota, error) {
+	q, ok := s.quota(id)
+	if !ok {
+		return nil, fmt.Errorf("memory.GraphQuota(%q): graph does not exist", id)
+	}
+	gq := q.get()
+	return &gq, nil
+}
+
+// SetGraphQuota replaces the quota of the graph with the provided ID. A nil
+// quota removes the limits of the graph.
+func (s *memoryStore) SetGraphQuota(ctx context.Context, id string, q *storage.GraphQuota) error {
+	gq, ok := s.quota(id)
+	if !ok {
+		return fmt.Errorf("memory.SetGraphQuota(%q): graph does not exist", id)
+	}
+	gq.set(q)
+	return nil
+}
+
+// LiteralLimit returns the maximum size in bytes of the text and blob literals
+// accepted by the store, or zero if they are not limited.
+func (s *memoryStore) LiteralLimit(ctx context.Context) int {
+	return s.literals.get()
+}
+
+// SetLiteralLimit sets the maximum size in bytes of the text and blob
+// literals accepted by the store. Zero removes the limit.
+func (s *memoryStore) SetLiteralLimit(ctx context.Context, max int) error {
+	s.literals.set(max)
 	return nil
 }
 
+// quota returns the quota of the graph with the provided ID, or false if the
+// graph does not exist.
+func (s *memoryStore) quota(id string) (*graphQuota, bool) {
+	s.rwmu.RLock()
+	defer s.rwmu.RUnlock()
+	switch g := s.graphs[id].(type) {
+	case *memory:
+		return g.quota, true
+	case *shardedMemory:
+		return g.quota, true
+	}
+	return nil, false
+}
+
+// copyMetadata returns a copy of the provided metadata with the provided
+// creation time, so callers cannot change the metadata kept by the store.
+func copyMetadata(created time.Time, md *storage.GraphMetadata) *storage.GraphMetadata {
+	c := &storage.GraphMetadata{Created: created, Description: md.Description}
+	if len(md.Labels) > 0 {
+		c.Labels = make(map[string]string, len(md.Labels))
+		for k, v := range md.Labels {
+			c.Labels[k] = v
+		}
+	}
+	return c
+}
+
 // memory provides an memory-based volatile implementation of the graph API.
 type memory struct {
-	id    string
-	rwmu  sync.RWMutex
-	idx   map[string]*triple.Triple
-	idxS  map[string]map[string]*triple.Triple
-	idxP  map[string]map[string]*triple.Triple
-	idxO  map[string]map[string]*triple.Triple
-	idxSP map[string]map[string]*triple.Triple
-	idxPO map[string]map[string]*triple.Triple
-	idxSO map[string]map[string]*triple.Triple
+	id      string
+	indexes Indexes
+	rwmu    sync.RWMutex
+	idx     map[string]*triple.Triple
+	idxS    map[string]map[string]*triple.Triple
+	idxP    map[string]map[string]*triple.Triple
+	idxO    map[string]map[string]*triple.Triple
+	idxSP   map[string]map[string]*triple.Triple
+	idxPO   map[string]map[string]*triple.Triple
+	idxSO   map[string]map[string]*triple.Triple
+	// idxT keeps the triples of each predicate ID sorted by time anchor.
+	idxT map[string]*timeIndex
+	// epoch is updated every time the triples of the graph change.
+	epoch uint64
+	// dict interns the values of the triples. It is shared with the
+	// transactions and snapshots of the graph.
+	dict *dictionary
+	// cow counts the snapshots that share the indices of the graph. It is nil
+	// if no snapshot was taken since the indices were last copied.
+	cow *cowRefs
+	// bytes is an approximation of the memory used by the triples and the
+	// indices, updated as triples are added and removed.
+	bytes int64
+	// removed counts the triples removed since the indices were built. Compact
+	// only rebuilds the indices of graphs with removed triples.
+	removed int64
+	// quota limits the triples and the memory of the graph. It is nil for the
+	// shards of sharded graphs, which keep the quota of the whole graph.
+	quota *graphQuota
+	// bloom tells which triples are definitely not in the graph. It is shared
+	// with the snapshots of the graph, like the indices.
+	bloom *bloomFilter
+	// vectors keeps the vector indices of the graph. They are shared with the
+	// snapshots of the graph.
+	vectors *vectorIndexes
+	// feed notifies the changes of the graph to its watchers. It is nil for
+	// the copies used by transactions and snapshots, and shared by the shards
+	// of sharded graphs.
+	feed *storage.ChangeNotifier
+}
+
+// newMemory returns a new empty graph with the provided ID that keeps the
+// provided secondary indices. The indices not kept are nil. The indices are
+// allocated for the provided number of entries.
+func newMemory(id string, idxs Indexes, size int) *memory {
+	m := &memory{
+		id:      id,
+		indexes: idxs,
+		idx:     make(map[string]*triple.Triple, size),
+		idxS:    make(map[string]map[string]*triple.Triple, size),
+		idxP:    make(map[string]map[string]*triple.Triple, size),
+		idxO:    make(map[string]map[string]*triple.Triple, size),
+		idxT:    make(map[string]*timeIndex),
+		dict:    newDictionary(),
+		epoch:   nextEpoch(),
+		quota:   &graphQuota{},
+		bloom:   newBloomFilter(size),
+		vectors: newVectorIndexes(),
+		feed:    &storage.ChangeNotifier{},
+	}
+	if idxs&SPOIndex != 0 {
+		m.idxSP = make(map[string]map[string]*triple.Triple, size)
+	}
+	if idxs&POSIndex != 0 {
+		m.idxPO = make(map[string]map[string]*triple.Triple, size)
+	}
+	if idxs&OSPIndex != 0 {
+		m.idxSO = make(map[string]map[string]*triple.Triple, size)
+	}
+	return m
+}
+
+// intersect returns the triples indexed under key ka of index a and under key
+// kb of index b. If the secondary index idx is kept, the triples are indexed
+// under the concatenation of both keys.
+func intersect(idx, a, b map[string]map[string]*triple.Triple, ka, kb string) map[string]*triple.Triple {
+	if idx != nil {
+		return idx[ka+kb]
+	}
+	ta, tb := a[ka], b[kb]
+	if len(tb) < len(ta) {
+		ta, tb = tb, ta
+	}
+	res := make(map[string]*triple.Triple)
+	for k, t := range ta {
+		if _, ok := tb[k]; ok {
+			res[k] = t
+		}
+	}
+	return res
+}
+
+// bySP returns the triples with the provided subject and predicate keys.
+func (m *memory) bySP(s, p string) map[string]*triple.Triple {
+	return intersect(m.idxSP, m.idxS, m.idxP, s, p)
+}
+
+// byPO returns the triples with the provided predicate and object keys.
+func (m *memory) byPO(p, o string) map[string]*triple.Triple {
+	return intersect(m.idxPO, m.idxP, m.idxO, p, o)
+}
+
+// bySO returns the triples with the provided subject and object keys.
+func (m *memory) bySO(s, o string) map[string]*triple.Triple {
+	return intersect(m.idxSO, m.idxS, m.idxO, s, o)
+}
+
+// lastEpoch is the last epoch assigned to any graph. Epochs are shared among
+// all graphs so recreated graphs never reuse an epoch.
+var lastEpoch uint64
+
+// nextEpoch returns a new unique epoch.
+func nextEpoch() uint64 {
+	return atomic.AddUint64(&lastEpoch, 1)
 }
 
 // ID returns the id for this graph.
@@ -136,82 +378,215 @@ func (m *memory) ID(ctx context.Context) string {
 	return m.id
 }
 
-// AddTriples adds the triples to the storage.
-func (m *memory) AddTriples(ctx context.Context, ts []*triple.Triple) error {
-	m.rwmu.Lock()
-	defer m.rwmu.Unlock()
-	for _, t := range ts {
-		tuuid := UUIDToByteString(t.UUID())
-		sUUID := UUIDToByteString(t.Subject().UUID())
-		pUUID := UUIDToByteString(t.Predicate().PartialUUID())
-		oUUID := UUIDToByteString(t.Object().UUID())
-		// Update master index
-		m.idx[tuuid] = t
+// parallelIndexThreshold is the minimum number of triples added at once that
+// makes AddTriples update the indices concurrently.
+const parallelIndexThreshold = 1000
 
-		if _, ok := m.idxS[sUUID]; !ok {
-			m.idxS[sUUID] = make(map[string]*triple.Triple)
-		}
-		m.idxS[sUUID][tuuid] = t
+// tripleKeys holds the keys used to index a triple.
+type tripleKeys struct {
+	t           *triple.Triple
+	id, s, p, o string
+	// h is the hash of the triple in the bloom filter of the graph.
+	h uint64
+}
 
-		if _, ok := m.idxP[pUUID]; !ok {
-			m.idxP[pUUID] = make(map[string]*triple.Triple)
-		}
-		m.idxP[pUUID][tuuid] = t
+// addToIndex adds the triple to the entry of the index with the provided key.
+func addToIndex(idx map[string]map[string]*triple.Triple, key string, k *tripleKeys) {
+	if _, ok := idx[key]; !ok {
+		idx[key] = make(map[string]*triple.Triple)
+	}
+	idx[key][k.id] = k.t
+}
 
-		if _, ok := m.idxO[oUUID]; !ok {
-			m.idxO[oUUID] = make(map[string]*triple.Triple)
+// missing returns the keys of the provided triples not in the graph yet, once
+// each. Triples the bloom filter of the graph does not have are not looked up
+// in the indices. It must be called with the read lock of m held.
+func (m *memory) missing(ks []*tripleKeys) []*tripleKeys {
+	var res []*tripleKeys
+	seen := make(map[string]bool)
+	for _, k := range ks {
+		if seen[k.id] {
+			continue
 		}
-		m.idxO[oUUID][tuuid] = t
-
-		key := sUUID + pUUID
-		if _, ok := m.idxSP[key]; !ok {
-			m.idxSP[key] = make(map[string]*triple.Triple)
+		if m.bloom.has(k.h) {
+			if _, ok := m.idx[k.id]; ok {
+				continue
+			}
 		}
-		m.idxSP[key][tuuid] = t
+		seen[k.id] = true
+		res = append(res, k)
+	}
+	return res
+}
 
-		key = pUUID + oUUID
-		if _, ok := m.idxPO[key]; !ok {
-			m.idxPO[key] = make(map[string]*triple.Triple)
+// AddTriples adds the triples to the storage. If the graph would exceed its
+// quota, or any triple has a literal bigger than the literal size limit of
+// the store, it fails without adding any of them. The keys of the triples are
+// computed before locking the graph, so concurrent calls only serialize on
+// updating the indices. Large batches update each index on its own goroutine.
+func (m *memory) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	if err := m.quota.checkLiterals(m.id, ts); err != nil {
+		return err
+	}
+	ks := make([]*tripleKeys, len(ts))
+	for i, t := range ts {
+		k, err := m.dict.keys(t)
+		if err != nil {
+			return err
 		}
-		m.idxPO[key][tuuid] = t
-
-		key = sUUID + oUUID
-		if _, ok := m.idxSO[key]; !ok {
-			m.idxSO[key] = make(map[string]*triple.Triple)
+		ks[i] = k
+	}
+	m.rwmu.Lock()
+	defer m.rwmu.Unlock()
+	// The time index does not replace existing triples, so only the ones not
+	// in the graph yet are added to it.
+	added := m.missing(ks)
+	var bytes int64
+	for _, k := range added {
+		bytes += m.tripleBytes(k)
+	}
+	if err := m.quota.check(m.id, int64(len(m.idx)+len(added)), m.memoryBytes()+bytes); err != nil {
+		return err
+	}
+	m.unshare()
+	m.epoch = nextEpoch()
+	m.bytes += bytes
+	for _, k := range added {
+		m.bloom.add(k.h)
+	}
+	if m.feed.Watched() {
+		c := &storage.Change{Op: storage.Added}
+		for _, k := range added {
+			c.Triples = append(c.Triples, k.t)
+		}
+		m.feed.Notify(c)
+	}
+	if m.bloom.full() {
+		defer m.rebuildBloom()
+	}
+	updates := []func(){
+		// Update master index
+		func() {
+			for _, k := range ks {
+				m.idx[k.id] = k.t
+			}
+		},
+		func() {
+			for _, k := range ks {
+				addToIndex(m.idxS, k.s, k)
+			}
+		},
+		func() {
+			for _, k := range ks {
+				addToIndex(m.idxP, k.p, k)
+			}
+		},
+		func() {
+			for _, k := range ks {
+				addToIndex(m.idxO, k.o, k)
+			}
+		},
+		func() {
+			for _, k := range added {
+				ti, ok := m.idxT[k.p]
+				if !ok {
+					ti = newTimeIndex()
+					m.idxT[k.p] = ti
+				}
+				ti.add(k.id, k.t)
+			}
+		},
+	}
+	if m.idxSP != nil {
+		updates = append(updates, func() {
+			for _, k := range ks {
+				addToIndex(m.idxSP, k.s+k.p, k)
+			}
+		})
+	}
+	if m.idxPO != nil {
+		updates = append(updates, func() {
+			for _, k := range ks {
+				addToIndex(m.idxPO, k.p+k.o, k)
+			}
+		})
+	}
+	if m.idxSO != nil {
+		updates = append(updates, func() {
+			for _, k := range ks {
+				addToIndex(m.idxSO, k.s+k.o, k)
+			}
+		})
+	}
+	if len(ks) < parallelIndexThreshold {
+		for _, f := range updates {
+			f()
 		}
-		m.idxSO[key][tuuid] = t
+		return nil
+	}
+	var wg sync.WaitGroup
+	for _, f := range updates {
+		wg.Add(1)
+		go func(f func()) {
+			defer wg.Done()
+			f()
+		}(f)
 	}
+	wg.Wait()
 	return nil
 }
 
-// RemoveTriples removes the triples from the storage.
+// RemoveTriples removes the triples from the storage. The removal of each
+// triple is notified on its own, since triples are removed one at a time.
 func (m *memory) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	defer func() {
+		// The epoch changes once all the triples are removed, so results
+		// computed while removing them are never tagged with it.
+		m.rwmu.Lock()
+		m.epoch = nextEpoch()
+		m.rwmu.Unlock()
+	}()
 	for _, t := range ts {
-		suuid := UUIDToByteString(t.UUID())
-		sUUID := UUIDToByteString(t.Subject().UUID())
-		pUUID := UUIDToByteString(t.Predicate().PartialUUID())
-		oUUID := UUIDToByteString(t.Object().UUID())
+		k := m.dict.find(t)
+		if k == nil {
+			continue
+		}
+		id, sKey, pKey, oKey := k.id, k.s, k.p, k.o
 		// Update master index
 		m.rwmu.Lock()
-		delete(m.idx, suuid)
-		delete(m.idxS[sUUID], suuid)
-		delete(m.idxP[pUUID], suuid)
-		delete(m.idxO[oUUID], suuid)
+		m.unshare()
+		if rt, ok := m.idx[id]; ok {
+			m.bytes -= m.tripleBytes(k)
+			m.removed++
+			if m.feed.Watched() {
+				m.feed.Notify(&storage.Change{Op: storage.Removed, Triples: []*triple.Triple{rt}})
+			}
+			if ti, ok := m.idxT[pKey]; ok {
+				ti.remove(id, t)
+				if ti.len() == 0 {
+					delete(m.idxT, pKey)
+				}
+			}
+		}
+		delete(m.idx, id)
+		delete(m.idxS[sKey], id)
+		delete(m.idxP[pKey], id)
+		delete(m.idxO[oKey], id)
 
-		key := sUUID + pUUID
-		delete(m.idxSP[key], suuid)
+		key := sKey + pKey
+		delete(m.idxSP[key], id)
 		if len(m.idxSP[key]) == 0 {
 			delete(m.idxSP, key)
 		}
 
-		key = pUUID + oUUID
-		delete(m.idxPO[key], suuid)
+		key = pKey + oKey
+		delete(m.idxPO[key], id)
 		if len(m.idxPO[key]) == 0 {
 			delete(m.idxPO, key)
 		}
 
-		key = sUUID + oUUID
-		delete(m.idxSO[key], suuid)
+		key = sKey + oKey
+		delete(m.idxSO[key], id)
 		if len(m.idxSO[key]) == 0 {
 			delete(m.idxSO, key)
 		}
@@ -228,26 +603,35 @@ type checker struct {
 	c   int
 	o   *storage.LookupOptions
 	op  *predicate.Predicate
-	ota *time.Time
+	// tp is the predicate of the lookup if it is temporal.
+	tp *predicate.Predicate
 }
 
 // newChecker creates a new checker for a given LookupOptions configuration.
 func newChecker(o *storage.LookupOptions, op *predicate.Predicate) *checker {
-	var ta *time.Time
-	if op != nil {
-		if t, err := op.TimeAnchor(); err == nil {
-			ta = t
-		}
+	var tp *predicate.Predicate
+	if op != nil && op.Type() == predicate.Temporal {
+		tp = op
 	}
 	return &checker{
 		max: o.MaxElements > 0,
 		c:   o.MaxElements,
 		o:   o,
 		op:  op,
-		ota: ta,
+		tp:  tp,
 	}
 }
 
+// CheckAndUpdateTriple checks if a triple should be considered based on its
+// object and predicate, and it also updates the internal state in case counts
+// are needed.
+func (c *checker) CheckAndUpdateTriple(t *triple.Triple) bool {
+	if !c.o.AcceptObject(t.Object()) {
+		return false
+	}
+	return c.CheckAndUpdate(t.Predicate())
+}
+
 // CheckAndUpdate checks if a predicate should be considered and it also updates
 // the internal state in case counts are needed.
 func (c *checker) CheckAndUpdate(p *predicate.Predicate) bool {
@@ -259,16 +643,11 @@ func (c *checker) CheckAndUpdate(p *predicate.Predicate) bool {
 		return true
 	}
 
-	if t, err := p.TimeAnchor(); err == nil {
-		if c.ota != nil && !c.ota.Equal(*t) {
-			return false
-		}
-		if c.o.LowerAnchor != nil && t.Before(*c.o.LowerAnchor) {
-			return false
-		}
-		if c.o.UpperAnchor != nil && t.After(*c.o.UpperAnchor) {
-			return false
-		}
+	if c.tp != nil && !c.tp.SameAnchor(p) {
+		return false
+	}
+	if !p.Overlaps(c.o.LowerAnchor, c.o.UpperAnchor) {
+		return false
 	}
 	c.c--
 	return true
@@ -281,9 +660,8 @@ func (m *memory) Objects(ctx context.Context, s *node.Node, p *predicate.Predica
 		return fmt.Errorf("cannot provide an empty channel")
 	}
 
-	sUUID := UUIDToByteString(s.UUID())
-	pUUID := UUIDToByteString(p.PartialUUID())
-	spIdx := sUUID + pUUID
+	sKey := m.dict.node(s)
+	pKey := m.dict.predicateID(p)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
 	defer close(objs)
@@ -291,7 +669,7 @@ func (m *memory) Objects(ctx context.Context, s *node.Node, p *predicate.Predica
 	if lo.LatestAnchor {
 		lastTA := make(map[string]*time.Time)
 		trps := make(map[string]*triple.Triple)
-		for _, t := range m.idxSP[spIdx] {
+		for _, t := range m.bySP(sKey, pKey) {
 			p := t.Predicate()
 			ppUUID := p.PartialUUID().String()
 			if p.Type() == predicate.Temporal {
@@ -306,19 +684,27 @@ func (m *memory) Objects(ctx context.Context, s *node.Node, p *predicate.Predica
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				objs <- trp.Object()
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case objs <- trp.Object():
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, p)
-	for _, t := range m.idxSP[spIdx] {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			objs <- t.Object()
+	return m.forEach(m.bySP(sKey, pKey), p, pKey, lo, m.idxS[sKey], func(t *triple.Triple) error {
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case objs <- t.Object():
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // Subject publishes the subjects for the give predicate and object to the
@@ -327,9 +713,8 @@ func (m *memory) Subjects(ctx context.Context, p *predicate.Predicate, o *triple
 	if subjs == nil {
 		return fmt.Errorf("cannot provide an empty channel")
 	}
-	pUUID := UUIDToByteString(p.PartialUUID())
-	oUUID := UUIDToByteString(o.UUID())
-	poIdx := pUUID + oUUID
+	pKey := m.dict.predicateID(p)
+	oKey := m.dict.object(o)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
 	defer close(subjs)
@@ -337,7 +722,7 @@ func (m *memory) Subjects(ctx context.Context, p *predicate.Predicate, o *triple
 	if lo.LatestAnchor {
 		lastTA := make(map[string]*time.Time)
 		trps := make(map[string]*triple.Triple)
-		for _, t := range m.idxPO[poIdx] {
+		for _, t := range m.byPO(pKey, oKey) {
 			p := t.Predicate()
 			ppUUID := p.PartialUUID().String()
 			if p.Type() == predicate.Temporal {
@@ -352,19 +737,27 @@ func (m *memory) Subjects(ctx context.Context, p *predicate.Predicate, o *triple
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				subjs <- trp.Subject()
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case subjs <- trp.Subject():
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, p)
-	for _, t := range m.idxPO[poIdx] {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			subjs <- t.Subject()
+	return m.forEach(m.byPO(pKey, oKey), p, pKey, lo, m.idxO[oKey], func(t *triple.Triple) error {
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case subjs <- t.Subject():
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // PredicatesForSubjectAndObject publishes all predicates available for the
@@ -373,9 +766,8 @@ func (m *memory) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node
 	if prds == nil {
 		return fmt.Errorf("cannot provide an empty channel")
 	}
-	sUUID := UUIDToByteString(s.UUID())
-	oUUID := UUIDToByteString(o.UUID())
-	soIdx := sUUID + oUUID
+	sKey := m.dict.node(s)
+	oKey := m.dict.object(o)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
 	defer close(prds)
@@ -383,7 +775,7 @@ func (m *memory) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node
 	if lo.LatestAnchor {
 		lastTA := make(map[string]*time.Time)
 		trps := make(map[string]*triple.Triple)
-		for _, t := range m.idxSO[soIdx] {
+		for _, t := range m.bySO(sKey, oKey) {
 			p := t.Predicate()
 			ppUUID := p.PartialUUID().String()
 			if p.Type() == predicate.Temporal {
@@ -398,16 +790,24 @@ func (m *memory) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				prds <- trp.Predicate()
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case prds <- trp.Predicate():
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, nil)
-	for _, t := range m.idxSO[soIdx] {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			prds <- t.Predicate()
+	for _, t := range m.bySO(sKey, oKey) {
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case prds <- t.Predicate():
+			}
 		}
 	}
 	return nil
@@ -419,7 +819,7 @@ func (m *memory) PredicatesForSubject(ctx context.Context, s *node.Node, lo *sto
 	if prds == nil {
 		return fmt.Errorf("cannot provide an empty channel")
 	}
-	sUUID := UUIDToByteString(s.UUID())
+	sKey := m.dict.node(s)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
 	defer close(prds)
@@ -427,7 +827,7 @@ func (m *memory) PredicatesForSubject(ctx context.Context, s *node.Node, lo *sto
 	if lo.LatestAnchor {
 		lastTA := make(map[string]*time.Time)
 		trps := make(map[string]*triple.Triple)
-		for _, t := range m.idxS[sUUID] {
+		for _, t := range m.idxS[sKey] {
 			p := t.Predicate()
 			ppUUID := p.PartialUUID().String()
 			if p.Type() == predicate.Temporal {
@@ -442,16 +842,24 @@ func (m *memory) PredicatesForSubject(ctx context.Context, s *node.Node, lo *sto
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				prds <- trp.Predicate()
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case prds <- trp.Predicate():
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, nil)
-	for _, t := range m.idxS[sUUID] {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			prds <- t.Predicate()
+	for _, t := range m.idxS[sKey] {
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case prds <- t.Predicate():
+			}
 		}
 	}
 	return nil
@@ -463,7 +871,7 @@ func (m *memory) PredicatesForObject(ctx context.Context, o *triple.Object, lo *
 	if prds == nil {
 		return fmt.Errorf("cannot provide an empty channel")
 	}
-	oUUID := UUIDToByteString(o.UUID())
+	oKey := m.dict.object(o)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
 	defer close(prds)
@@ -471,7 +879,7 @@ func (m *memory) PredicatesForObject(ctx context.Context, o *triple.Object, lo *
 	if lo.LatestAnchor {
 		lastTA := make(map[string]*time.Time)
 		trps := make(map[string]*triple.Triple)
-		for _, t := range m.idxO[oUUID] {
+		for _, t := range m.idxO[oKey] {
 			p := t.Predicate()
 			ppUUID := p.PartialUUID().String()
 			if p.Type() == predicate.Temporal {
@@ -486,16 +894,24 @@ func (m *memory) PredicatesForObject(ctx context.Context, o *triple.Object, lo *
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				prds <- trp.Predicate()
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case prds <- trp.Predicate():
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, nil)
-	for _, t := range m.idxO[oUUID] {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			prds <- t.Predicate()
+	for _, t := range m.idxO[oKey] {
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case prds <- t.Predicate():
+			}
 		}
 	}
 	return nil
@@ -507,7 +923,7 @@ func (m *memory) TriplesForSubject(ctx context.Context, s *node.Node, lo *storag
 	if trpls == nil {
 		return fmt.Errorf("cannot provide an empty channel")
 	}
-	sUUID := UUIDToByteString(s.UUID())
+	sKey := m.dict.node(s)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
 	defer close(trpls)
@@ -515,7 +931,7 @@ func (m *memory) TriplesForSubject(ctx context.Context, s *node.Node, lo *storag
 	if lo.LatestAnchor {
 		lastTA := make(map[string]*time.Time)
 		trps := make(map[string]*triple.Triple)
-		for _, t := range m.idxS[sUUID] {
+		for _, t := range m.idxS[sKey] {
 			p := t.Predicate()
 			ppUUID := p.PartialUUID().String()
 			if p.Type() == predicate.Temporal {
@@ -530,16 +946,24 @@ func (m *memory) TriplesForSubject(ctx context.Context, s *node.Node, lo *storag
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				trpls <- trp
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case trpls <- trp:
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, nil)
-	for _, t := range m.idxS[sUUID] {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			trpls <- t
+	for _, t := range m.idxS[sKey] {
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case trpls <- t:
+			}
 		}
 	}
 	return nil
@@ -551,7 +975,7 @@ func (m *memory) TriplesForPredicate(ctx context.Context, p *predicate.Predicate
 	if trpls == nil {
 		return fmt.Errorf("cannot provide an empty channel")
 	}
-	pUUID := UUIDToByteString(p.PartialUUID())
+	pKey := m.dict.predicateID(p)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
 	defer close(trpls)
@@ -559,7 +983,7 @@ func (m *memory) TriplesForPredicate(ctx context.Context, p *predicate.Predicate
 	if lo.LatestAnchor {
 		lastTA := make(map[string]*time.Time)
 		trps := make(map[string]*triple.Triple)
-		for _, t := range m.idxP[pUUID] {
+		for _, t := range m.idxP[pKey] {
 			p := t.Predicate()
 			ppUUID := p.PartialUUID().String()
 			if p.Type() == predicate.Temporal {
@@ -574,19 +998,27 @@ func (m *memory) TriplesForPredicate(ctx context.Context, p *predicate.Predicate
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				trpls <- trp
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case trpls <- trp:
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, p)
-	for _, t := range m.idxP[pUUID] {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			trpls <- t
+	return m.forEach(m.idxP[pKey], p, pKey, lo, nil, func(t *triple.Triple) error {
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case trpls <- t:
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // TriplesForObject publishes all triples available for the given object to the
@@ -595,7 +1027,7 @@ func (m *memory) TriplesForObject(ctx context.Context, o *triple.Object, lo *sto
 	if trpls == nil {
 		return fmt.Errorf("cannot provide an empty channel")
 	}
-	oUUID := UUIDToByteString(o.UUID())
+	oKey := m.dict.object(o)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
 	defer close(trpls)
@@ -603,7 +1035,7 @@ func (m *memory) TriplesForObject(ctx context.Context, o *triple.Object, lo *sto
 	if lo.LatestAnchor {
 		lastTA := make(map[string]*time.Time)
 		trps := make(map[string]*triple.Triple)
-		for _, t := range m.idxO[oUUID] {
+		for _, t := range m.idxO[oKey] {
 			p := t.Predicate()
 			ppUUID := p.PartialUUID().String()
 			if p.Type() == predicate.Temporal {
@@ -618,16 +1050,24 @@ func (m *memory) TriplesForObject(ctx context.Context, o *triple.Object, lo *sto
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				trpls <- trp
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case trpls <- trp:
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, nil)
-	for _, t := range m.idxO[oUUID] {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			trpls <- t
+	for _, t := range m.idxO[oKey] {
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case trpls <- t:
+			}
 		}
 	}
 	return nil
@@ -639,9 +1079,8 @@ func (m *memory) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node
 	if trpls == nil {
 		return fmt.Errorf("cannot provide an empty channel")
 	}
-	sUUID := UUIDToByteString(s.UUID())
-	pUUID := UUIDToByteString(p.PartialUUID())
-	spIdx := sUUID + pUUID
+	sKey := m.dict.node(s)
+	pKey := m.dict.predicateID(p)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
 	defer close(trpls)
@@ -649,7 +1088,7 @@ func (m *memory) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node
 	if lo.LatestAnchor {
 		lastTA := make(map[string]*time.Time)
 		trps := make(map[string]*triple.Triple)
-		for _, t := range m.idxSP[spIdx] {
+		for _, t := range m.bySP(sKey, pKey) {
 			p := t.Predicate()
 			ppUUID := p.PartialUUID().String()
 			if p.Type() == predicate.Temporal {
@@ -664,19 +1103,27 @@ func (m *memory) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				trpls <- trp
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case trpls <- trp:
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, p)
-	for _, t := range m.idxSP[spIdx] {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			trpls <- t
+	return m.forEach(m.bySP(sKey, pKey), p, pKey, lo, m.idxS[sKey], func(t *triple.Triple) error {
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case trpls <- t:
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // TriplesForPredicateAndObject publishes all triples available for the given
@@ -685,9 +1132,8 @@ func (m *memory) TriplesForPredicateAndObject(ctx context.Context, p *predicate.
 	if trpls == nil {
 		return fmt.Errorf("cannot provide an empty channel")
 	}
-	pUUID := UUIDToByteString(p.PartialUUID())
-	oUUID := UUIDToByteString(o.UUID())
-	poIdx := pUUID + oUUID
+	pKey := m.dict.predicateID(p)
+	oKey := m.dict.object(o)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
 	defer close(trpls)
@@ -695,7 +1141,7 @@ func (m *memory) TriplesForPredicateAndObject(ctx context.Context, p *predicate.
 	if lo.LatestAnchor {
 		lastTA := make(map[string]*time.Time)
 		trps := make(map[string]*triple.Triple)
-		for _, t := range m.idxPO[poIdx] {
+		for _, t := range m.byPO(pKey, oKey) {
 			p := t.Predicate()
 			ppUUID := p.PartialUUID().String()
 			if p.Type() == predicate.Temporal {
@@ -710,27 +1156,42 @@ func (m *memory) TriplesForPredicateAndObject(ctx context.Context, p *predicate.
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				trpls <- trp
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case trpls <- trp:
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, p)
-	for _, t := range m.idxPO[poIdx] {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			trpls <- t
+	return m.forEach(m.byPO(pKey, oKey), p, pKey, lo, m.idxO[oKey], func(t *triple.Triple) error {
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case trpls <- t:
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // Exist checks if the provided triple exists on the store.
 func (m *memory) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
-	suuid := UUIDToByteString(t.UUID())
+	v := valueKeysOf(t)
 	m.rwmu.RLock()
 	defer m.rwmu.RUnlock()
-	_, ok := m.idx[suuid]
+	if !m.bloom.has(v.hash()) {
+		return false, nil
+	}
+	k := m.dict.findValues(t, v)
+	if k == nil {
+		return false, nil
+	}
+	_, ok := m.idx[k.id]
 	return ok, nil
 }
 
@@ -762,17 +1223,259 @@ func (m *memory) Triples(ctx context.Context, lo *storage.LookupOptions, trpls c
 			}
 		}
 		for _, trp := range trps {
-			if trp != nil {
-				trpls <- trp
+			if trp != nil && lo.AcceptObject(trp.Object()) {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case trpls <- trp:
+				}
 			}
 		}
 		return nil
 	}
 	ckr := newChecker(lo, nil)
 	for _, t := range m.idx {
-		if ckr.CheckAndUpdate(t.Predicate()) {
-			trpls <- t
+		if ckr.CheckAndUpdateTriple(t) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case trpls <- t:
+			}
+		}
+	}
+	return nil
+}
+
+// CountTriples returns the number of triples in the graph.
+func (m *memory) CountTriples(ctx context.Context) (int64, error) {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	return int64(len(m.idx)), nil
+}
+
+// Statistics returns the current statistics of the graph computed out of its
+// indices, including an approximation of the memory it uses.
+func (m *memory) Statistics(ctx context.Context) (*storage.GraphStatistics, error) {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	s := &storage.GraphStatistics{
+		Triples:    int64(len(m.idx)),
+		Predicates: make(map[string]int64),
+	}
+	// Removing triples may leave empty entries in the indices; they are not
+	// counted.
+	for _, ts := range m.idxP {
+		for _, t := range ts {
+			s.Predicates[string(t.Predicate().ID())] += int64(len(ts))
+			break
+		}
+	}
+	for _, ts := range m.idxS {
+		if len(ts) > 0 {
+			s.Subjects++
+			s.AddSubjectDegree(int64(len(ts)))
+		}
+	}
+	for _, ts := range m.idxO {
+		if len(ts) > 0 {
+			s.Objects++
+		}
+	}
+	for _, ti := range m.idxT {
+		if es := ti.window(nil, nil); len(es) > 0 {
+			s.AddAnchor(es[0].anchor)
+			s.AddAnchor(es[len(es)-1].anchor)
 		}
+		for _, t := range ti.intervals {
+			ta, _ := t.Predicate().TimeAnchor()
+			s.AddAnchor(*ta)
+		}
+	}
+	s.MemoryBytes = m.memoryBytes()
+	return s, nil
+}
+
+// Epoch returns the current epoch of the graph.
+func (m *memory) Epoch(ctx context.Context) (uint64, error) {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	return m.epoch, nil
+}
+
+// PredicateIDs returns the sorted list of unique predicate IDs used in the
+// graph.
+func (m *memory) PredicateIDs(ctx context.Context) ([]string, error) {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	ids := make(map[string]bool)
+	for _, ts := range m.idxP {
+		for _, t := range ts {
+			ids[string(t.Predicate().ID())] = true
+			break
+		}
+	}
+	var res []string
+	for id := range ids {
+		res = append(res, id)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// Begin starts a transaction on the graph. The transaction works on a private
+// copy of the triples, so its changes are not visible to other users of the
+// graph until it is committed. Committing fails if the graph changed since the
+// transaction began.
+func (m *memory) Begin(ctx context.Context) (storage.GraphTransaction, error) {
+	m.rwmu.RLock()
+	ts, epoch := m.triplesOf(), m.epoch
+	m.rwmu.RUnlock()
+	c, err := m.rebuild(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	// The quota is only checked for the changes done in the transaction.
+	c.quota = m.quota
+	return &memoryTransaction{memory: c, g: m, epoch: epoch, start: c.epoch}, nil
+}
+
+// memoryTransaction is a transaction on a memory graph. All the changes and
+// lookups are done on the embedded copy of the graph.
+type memoryTransaction struct {
+	*memory
+
+	// g is the graph the transaction was started on, and epoch the epoch of g
+	// when the transaction began.
+	g     *memory
+	epoch uint64
+	// start is the epoch of the copy before any change was done.
+	start uint64
+
+	mu   sync.Mutex
+	done bool
+}
+
+// check returns an error if the transaction was already finished.
+func (t *memoryTransaction) check() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return fmt.Errorf("memory: the transaction on graph %q was already committed or rolled back", t.id)
+	}
+	return nil
+}
+
+// Begin starts a transaction nested in the transaction. Committing it applies
+// its changes to the enclosing transaction.
+func (t *memoryTransaction) Begin(ctx context.Context) (storage.GraphTransaction, error) {
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	return t.memory.Begin(ctx)
+}
+
+// AddTriples adds the triples to the transaction.
+func (t *memoryTransaction) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	return t.memory.AddTriples(ctx, ts)
+}
+
+// RemoveTriples removes the triples from the transaction.
+func (t *memoryTransaction) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	return t.memory.RemoveTriples(ctx, ts)
+}
+
+// Expire removes the expired triples from the transaction.
+func (t *memoryTransaction) Expire(ctx context.Context, before time.Time, predicates []string) (int64, error) {
+	if err := t.check(); err != nil {
+		return 0, err
+	}
+	return t.memory.Expire(ctx, before, predicates)
+}
+
+// Commit replaces the triples of the graph with the ones of the transaction
+// in a single step.
+func (t *memoryTransaction) Commit(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return fmt.Errorf("memory: cannot commit the transaction on graph %q; it was already finished", t.id)
+	}
+	t.done = true
+	return commit(t)
+}
+
+// commit applies the changes of the provided transactions at once. The graphs
+// of the transactions that changed are locked in order, and nothing is
+// applied if any of them changed since its transaction began.
+func commit(txs ...*memoryTransaction) error {
+	var changed []*memoryTransaction
+	for _, t := range txs {
+		t.memory.rwmu.RLock()
+		defer t.memory.rwmu.RUnlock()
+		if t.memory.epoch != t.start {
+			changed = append(changed, t)
+		}
+	}
+	for _, t := range changed {
+		t.g.rwmu.Lock()
+		defer t.g.rwmu.Unlock()
+	}
+	for _, t := range changed {
+		if t.g.epoch != t.epoch {
+			return fmt.Errorf("memory: cannot commit the transaction on graph %q; the graph changed since the transaction began", t.id)
+		}
+	}
+	for _, t := range changed {
+		t.g.notifyCommit(t.memory)
+		t.g.idx, t.g.idxS, t.g.idxP, t.g.idxO = t.memory.idx, t.memory.idxS, t.memory.idxP, t.memory.idxO
+		t.g.idxSP, t.g.idxPO, t.g.idxSO, t.g.idxT = t.memory.idxSP, t.memory.idxPO, t.memory.idxSO, t.memory.idxT
+		t.g.cow, t.g.bytes, t.g.bloom = t.memory.cow, t.memory.bytes, t.memory.bloom
+		t.g.removed = t.memory.removed
+		t.g.epoch = nextEpoch()
+	}
+	return nil
+}
+
+// notifyCommit notifies the triples that committing the provided copy of the
+// graph removes and adds. It must be called with the write lock of m held,
+// before its indices are replaced.
+func (m *memory) notifyCommit(c *memory) {
+	if !m.feed.Watched() {
+		return
+	}
+	removed, added := &storage.Change{Op: storage.Removed}, &storage.Change{Op: storage.Added}
+	for id, t := range m.idx {
+		if _, ok := c.idx[id]; !ok {
+			removed.Triples = append(removed.Triples, t)
+		}
+	}
+	for id, t := range c.idx {
+		if _, ok := m.idx[id]; !ok {
+			added.Triples = append(added.Triples, t)
+		}
+	}
+	m.feed.Notify(removed)
+	m.feed.Notify(added)
+}
+
+// Watch returns a channel where the changes done to the graph are pushed.
+func (m *memory) Watch(ctx context.Context) (<-chan *storage.Change, error) {
+	return m.feed.Watch(ctx)
+}
+
+// Rollback discards the changes done in the transaction.
+func (t *memoryTransaction) Rollback(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return fmt.Errorf("memory: cannot roll back the transaction on graph %q; it was already finished", t.id)
 	}
+	t.done = true
 	return nil
 }
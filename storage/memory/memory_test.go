@@ -16,7 +16,9 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -51,6 +53,52 @@ func TestMemoryStore(t *testing.T) {
 	}
 }
 
+func TestGraphMetadata(t *testing.T) {
+	s, ctx := NewStore(), context.Background()
+	start := time.Now()
+	if _, err := s.NewGraph(ctx, "?test"); err != nil {
+		t.Fatal(err)
+	}
+	md, err := storage.GetGraphMetadata(ctx, s, "?test")
+	if err != nil {
+		t.Fatalf("storage.GetGraphMetadata failed with error %v", err)
+	}
+	if md.Created.Before(start) || md.Description != "" || len(md.Labels) != 0 {
+		t.Errorf("storage.GetGraphMetadata returned %+v for a new graph; want only a creation time after %v", md, start)
+	}
+	created := md.Created
+
+	labels := map[string]string{"tenant": "acme"}
+	nmd := &storage.GraphMetadata{Created: time.Time{}, Description: "test graph", Labels: labels}
+	if err := storage.SetGraphMetadata(ctx, s, "?test", nmd); err != nil {
+		t.Fatalf("storage.SetGraphMetadata failed with error %v", err)
+	}
+	// Changing the provided labels does not change the stored ones.
+	labels["tenant"] = "other"
+	md, err = storage.GetGraphMetadata(ctx, s, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &storage.GraphMetadata{Created: created, Description: "test graph", Labels: map[string]string{"tenant": "acme"}}
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("storage.GetGraphMetadata returned %+v; want %+v", md, want)
+	}
+
+	// Deleted and non existing graphs have no metadata.
+	if err := s.DeleteGraph(ctx, "?test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.GetGraphMetadata(ctx, s, "?test"); err == nil {
+		t.Errorf("storage.GetGraphMetadata should fail for a deleted graph")
+	}
+	if err := storage.SetGraphMetadata(ctx, s, "?test", nmd); err == nil {
+		t.Errorf("storage.SetGraphMetadata should fail for a non existing graph")
+	}
+	if _, err := storage.GetGraphMetadata(ctx, struct{ storage.Store }{s}, "?test"); err != storage.ErrNoMetadata {
+		t.Errorf("storage.GetGraphMetadata returned %v for a store without metadata; want %v", err, storage.ErrNoMetadata)
+	}
+}
+
 func TestGraphNames(t *testing.T) {
 	gs, ctx := []string{"?foo", "?bar", "?test"}, context.Background()
 	s := NewStore()
@@ -197,6 +245,35 @@ func TestTemporalExactChecker(t *testing.T) {
 	}
 }
 
+func TestIntervalChecker(t *testing.T) {
+	ipa, err := predicate.Parse("\"foo\"@[2014-01-01T00:00:00Z/2015-01-01T00:00:00Z]")
+	if err != nil {
+		t.Fatalf("Failed to parse fixture predicate with error %v", err)
+	}
+	table := []struct {
+		lo   *storage.LookupOptions
+		want bool
+	}{
+		{&storage.LookupOptions{LowerAnchor: mustParse("2014-06-01T00:00:00Z")}, true},
+		{&storage.LookupOptions{LowerAnchor: mustParse("2015-01-01T00:00:00Z")}, false},
+		{&storage.LookupOptions{UpperAnchor: mustParse("2014-01-01T00:00:00Z")}, true},
+		{&storage.LookupOptions{UpperAnchor: mustParse("2013-12-31T00:00:00Z")}, false},
+		{&storage.LookupOptions{LowerAnchor: mustParse("2014-03-01T00:00:00Z"), UpperAnchor: mustParse("2014-04-01T00:00:00Z")}, true},
+	}
+	for _, entry := range table {
+		if got := newChecker(entry.lo, nil).CheckAndUpdate(ipa); got != entry.want {
+			t.Errorf("checker for %+v returned %v for interval predicate %v; want %v", entry.lo, got, ipa, entry.want)
+		}
+	}
+	spa, err := predicate.Parse("\"foo\"@[2014-01-01T00:00:00Z]")
+	if err != nil {
+		t.Fatalf("Failed to parse fixture predicate with error %v", err)
+	}
+	if newChecker(&storage.LookupOptions{}, ipa).CheckAndUpdate(spa) {
+		t.Errorf("checker for %v should reject the instant predicate %v anchored at its start", ipa, spa)
+	}
+}
+
 func createTriples(t *testing.T, ss []string) []*triple.Triple {
 	ts := []*triple.Triple{}
 	for _, s := range ss {
@@ -834,6 +911,121 @@ func TestTriples(t *testing.T) {
 	}
 }
 
+func TestLiteralFilter(t *testing.T) {
+	ctx := context.Background()
+	ts := createTriples(t, []string{
+		"/u<a>\t\"age\"@[]\t\"10\"^^type:int64",
+		"/u<b>\t\"age\"@[]\t\"20.5\"^^type:float64",
+		"/u<c>\t\"age\"@[]\t\"x\"^^type:text",
+		"/u<d>\t\"age\"@[]\t/u<a>",
+	})
+	g, _ := NewStore().NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	b := literal.DefaultBuilder()
+	ten, _ := b.Build(literal.Int64, int64(10))
+	big, _ := b.Build(literal.Float64, 20.5)
+	txt, _ := b.Build(literal.Text, "a")
+	testTable := []struct {
+		lo   *storage.LookupOptions
+		want int
+	}{
+		{&storage.LookupOptions{}, 4},
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Lower: ten}}, 2},
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Lower: ten, LowerStrict: true}}, 1},
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Lower: ten, Upper: big, UpperStrict: true}}, 1},
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Lower: ten, Upper: ten}}, 1},
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Lower: txt}}, 1},
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Upper: big}, MaxElements: 1}, 1},
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Upper: ten, UpperStrict: true}}, 0},
+	}
+	p := ts[0].Predicate()
+	for _, entry := range testTable {
+		trpls := make(chan *triple.Triple, 100)
+		if err := g.TriplesForPredicate(ctx, p, entry.lo, trpls); err != nil {
+			t.Fatal(err)
+		}
+		cnt := 0
+		for range trpls {
+			cnt++
+		}
+		if cnt != entry.want {
+			t.Errorf("g.TriplesForPredicate(%s, %v) returned %d triples; want %d", p, entry.lo, cnt, entry.want)
+		}
+	}
+}
+
+func TestLiteralFilterDecimal(t *testing.T) {
+	ctx := context.Background()
+	ts := createTriples(t, []string{
+		"/u<a>\t\"price\"@[]\t\"0.1\"^^type:decimal",
+		"/u<b>\t\"price\"@[]\t\"0.3\"^^type:decimal",
+		"/u<c>\t\"price\"@[]\t\"1\"^^type:int64",
+		"/u<d>\t\"price\"@[]\t\"0.2\"^^type:float64",
+	})
+	g, _ := NewStore().NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	b := literal.DefaultBuilder()
+	low, _ := b.Parse(`"0.1"^^type:decimal`)
+	high, _ := b.Parse(`"0.30"^^type:decimal`)
+	one, _ := b.Build(literal.Int64, int64(1))
+	fl, _ := b.Build(literal.Float64, 0.1)
+	testTable := []struct {
+		lo   *storage.LookupOptions
+		want int
+	}{
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Lower: low, Upper: high}}, 3},
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Lower: low, LowerStrict: true, Upper: high, UpperStrict: true}}, 1},
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Lower: one}}, 1},
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Upper: high, UpperStrict: true}}, 2},
+		// The float64 0.1 is slightly above the decimal 0.1.
+		{&storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Upper: fl}}, 1},
+	}
+	p := ts[0].Predicate()
+	for _, entry := range testTable {
+		trpls := make(chan *triple.Triple, 100)
+		if err := g.TriplesForPredicate(ctx, p, entry.lo, trpls); err != nil {
+			t.Fatal(err)
+		}
+		cnt := 0
+		for range trpls {
+			cnt++
+		}
+		if cnt != entry.want {
+			t.Errorf("g.TriplesForPredicate(%s, %v) returned %d triples; want %d", p, entry.lo, cnt, entry.want)
+		}
+	}
+}
+
+func TestLiteralFilterDate(t *testing.T) {
+	ctx := context.Background()
+	ts := createTriples(t, []string{
+		"/u<a>\t\"born\"@[]\t\"1969-07-20\"^^type:date",
+		"/u<b>\t\"born\"@[]\t\"2016-04-10\"^^type:date",
+		"/u<c>\t\"born\"@[]\t\"2016-04-10\"^^type:text",
+	})
+	g, _ := NewStore().NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	since, _ := literal.DefaultBuilder().Parse(`"2000-01-01"^^type:date`)
+	trpls := make(chan *triple.Triple, 100)
+	lo := &storage.LookupOptions{LiteralFilter: &storage.LiteralFilter{Lower: since}}
+	if err := g.TriplesForPredicate(ctx, ts[0].Predicate(), lo, trpls); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for trpl := range trpls {
+		got = append(got, trpl.Subject().String())
+	}
+	if want := []string{"/u<b>"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("g.TriplesForPredicate(%s, %v) returned subjects %v; want %v", ts[0].Predicate(), lo, got, want)
+	}
+}
+
 func TestTriplesLastestTemporal(t *testing.T) {
 	ts, ctx := getTestTemporalTriples(t), context.Background()
 	g, _ := NewStore().NewGraph(ctx, "test")
@@ -859,3 +1051,724 @@ func TestTriplesLastestTemporal(t *testing.T) {
 		t.Errorf("g.TriplesForPredicateAndObject(%s, %s) failed to retrieve 1 predicates, got %d instead", ts[0].Predicate(), ts[0].Object(), cnt)
 	}
 }
+
+func TestCountTriplesAndPredicateIDs(t *testing.T) {
+	ctx := context.Background()
+	ts := append(getTestTriples(t), getTestTemporalTriples(t)...)
+	g, _ := NewStore().NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	cnt, err := storage.CountTriples(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cnt, int64(len(ts)); got != want {
+		t.Errorf("storage.CountTriples returned the wrong number of triples; got %d, want %d", got, want)
+	}
+	ids, err := storage.PredicateIDs(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ids, []string{"knows", "meet"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("storage.PredicateIDs returned the wrong predicate IDs; got %v, want %v", got, want)
+	}
+	if err := g.RemoveTriples(ctx, getTestTriples(t)); err != nil {
+		t.Fatal(err)
+	}
+	ids, err = storage.PredicateIDs(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ids, []string{"meet"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("storage.PredicateIDs returned the wrong predicate IDs after removing triples; got %v, want %v", got, want)
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	ctx := context.Background()
+	g, _ := NewStore().NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, append(getTestTriples(t), getTestTemporalTriples(t)...)); err != nil {
+		t.Fatalf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	got, err := storage.Statistics(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &storage.GraphStatistics{
+		Triples:        16,
+		Subjects:       2,
+		Objects:        5,
+		Predicates:     map[string]int64{"knows": 6, "meet": 10},
+		SubjectDegrees: []int64{0, 1, 0, 1},
+		FirstAnchor:    mustParse("2010-04-10T04:21:00Z"),
+		LastAnchor:     mustParse("2019-04-10T04:21:00Z"),
+	}
+	mb := got.MemoryBytes
+	if mb <= 0 {
+		t.Errorf("storage.Statistics returned %d memory bytes; want a positive estimate", mb)
+	}
+	got.MemoryBytes = 0
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("storage.Statistics returned the wrong statistics; got %+v, want %+v", got, want)
+	}
+	if got, want := got.MedianSubjectDegree(), int64(2); got != want {
+		t.Errorf("MedianSubjectDegree returned the wrong degree; got %d, want %d", got, want)
+	}
+	if err := g.RemoveTriples(ctx, getTestTriples(t)); err != nil {
+		t.Fatal(err)
+	}
+	got, err = storage.Statistics(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = &storage.GraphStatistics{
+		Triples:        10,
+		Subjects:       1,
+		Objects:        1,
+		Predicates:     map[string]int64{"meet": 10},
+		SubjectDegrees: []int64{0, 0, 0, 1},
+		FirstAnchor:    mustParse("2010-04-10T04:21:00Z"),
+		LastAnchor:     mustParse("2019-04-10T04:21:00Z"),
+	}
+	if got.MemoryBytes <= 0 || got.MemoryBytes >= mb {
+		t.Errorf("storage.Statistics returned %d memory bytes after removing triples; want less than %d", got.MemoryBytes, mb)
+	}
+	got.MemoryBytes = 0
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("storage.Statistics returned the wrong statistics after removing triples; got %+v, want %+v", got, want)
+	}
+	if got, want := got.MedianSubjectDegree(), int64(8); got != want {
+		t.Errorf("MedianSubjectDegree returned the wrong degree after removing triples; got %d, want %d", got, want)
+	}
+}
+
+func TestScanStatistics(t *testing.T) {
+	ctx := context.Background()
+	g, _ := NewStore().NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, append(getTestTriples(t), getTestTemporalTriples(t)...)); err != nil {
+		t.Fatal(err)
+	}
+	want, err := storage.ScanStatistics(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Hiding the Statistics method of the graph scans its triples.
+	got, err := storage.ScanStatistics(ctx, struct{ storage.Graph }{g})
+	if err != nil {
+		t.Fatalf("storage.ScanStatistics failed with error %v", err)
+	}
+	if got.MemoryBytes != 0 {
+		t.Errorf("storage.ScanStatistics returned %d memory bytes; want 0", got.MemoryBytes)
+	}
+	want.MemoryBytes = 0
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("storage.ScanStatistics returned the wrong statistics; got %+v, want %+v", got, want)
+	}
+}
+
+func TestEpoch(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	epoch := func() uint64 {
+		e, err := storage.Epoch(ctx, g)
+		if err != nil {
+			t.Fatalf("storage.Epoch failed with error %v", err)
+		}
+		return e
+	}
+	e0 := epoch()
+	if got := epoch(); got != e0 {
+		t.Errorf("storage.Epoch changed without modifying the graph; got %d, want %d", got, e0)
+	}
+	if err := g.AddTriples(ctx, getTestTriples(t)); err != nil {
+		t.Fatal(err)
+	}
+	e1 := epoch()
+	if e1 == e0 {
+		t.Errorf("storage.Epoch did not change after adding triples")
+	}
+	if err := g.RemoveTriples(ctx, getTestTriples(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got := epoch(); got == e1 || got == e0 {
+		t.Errorf("storage.Epoch returned an already used epoch %d after removing triples", got)
+	}
+	// Graphs recreated with the same ID do not reuse epochs.
+	if err := s.DeleteGraph(ctx, "?test"); err != nil {
+		t.Fatal(err)
+	}
+	if g, err = s.NewGraph(ctx, "?test"); err != nil {
+		t.Fatal(err)
+	}
+	if got := epoch(); got == e0 || got == e1 {
+		t.Errorf("storage.Epoch returned an already used epoch %d for a recreated graph", got)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	ctx := context.Background()
+	ts := getTestTriples(t)
+	s := NewStore()
+	g, err := s.NewGraph(ctx, "?existing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts[:1]); err != nil {
+		t.Fatal(err)
+	}
+	countTriples := func(id string) int64 {
+		g, err := s.Graph(ctx, id)
+		if err != nil {
+			t.Fatalf("s.Graph(%q) failed with error %v", id, err)
+		}
+		cnt, err := storage.CountTriples(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cnt
+	}
+
+	// Rolling back undoes all the changes.
+	tx, err := storage.Begin(ctx, s)
+	if err != nil {
+		t.Fatalf("storage.Begin failed with error %v", err)
+	}
+	ng, err := tx.NewGraph(ctx, "?new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ng.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	eg, err := tx.Graph(ctx, "?existing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := eg.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := countTriples("?existing"), int64(len(ts)); got != want {
+		t.Errorf("transaction changes should be visible before commit; got %d triples, want %d", got, want)
+	}
+	if err := tx.DeleteGraph(ctx, "?existing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("tx.Rollback failed with error %v", err)
+	}
+	if _, err := s.Graph(ctx, "?new"); err == nil {
+		t.Errorf("tx.Rollback should have removed the graph created in the transaction")
+	}
+	if got, want := countTriples("?existing"), int64(1); got != want {
+		t.Errorf("tx.Rollback failed to restore the original triples; got %d triples, want %d", got, want)
+	}
+	if _, err := tx.Graph(ctx, "?existing"); err == nil {
+		t.Errorf("a rolled back transaction should not be usable")
+	}
+	if err := tx.Commit(ctx); err == nil {
+		t.Errorf("tx.Commit should fail for a rolled back transaction")
+	}
+
+	// Committing keeps all the changes.
+	tx, err = storage.Begin(ctx, s)
+	if err != nil {
+		t.Fatalf("storage.Begin failed with error %v", err)
+	}
+	eg, err = tx.Graph(ctx, "?existing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := eg.RemoveTriples(ctx, ts[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.NewGraph(ctx, "?new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("tx.Commit failed with error %v", err)
+	}
+	if got, want := countTriples("?existing"), int64(0); got != want {
+		t.Errorf("tx.Commit failed to keep the removed triples; got %d triples, want %d", got, want)
+	}
+	if _, err := s.Graph(ctx, "?new"); err != nil {
+		t.Errorf("tx.Commit failed to keep the created graph; %v", err)
+	}
+	if err := tx.Rollback(ctx); err == nil {
+		t.Errorf("tx.Rollback should fail for a committed transaction")
+	}
+}
+
+func TestGraphTransaction(t *testing.T) {
+	ctx := context.Background()
+	ts := getTestTriples(t)
+	testTable := []struct {
+		name     string
+		isolated bool
+		graph    func(g storage.Graph) storage.Graph
+	}{
+		{"memory", true, func(g storage.Graph) storage.Graph { return g }},
+		// Hiding the Begin method of the graph uses the undo log transactions.
+		{"undo", false, func(g storage.Graph) storage.Graph { return struct{ storage.Graph }{g} }},
+	}
+	for _, entry := range testTable {
+		g, err := NewStore().NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts[:1]); err != nil {
+			t.Fatal(err)
+		}
+		g = entry.graph(g)
+		count := func(g storage.Graph) int64 {
+			cnt, err := storage.CountTriples(ctx, g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return cnt
+		}
+
+		// Rolling back undoes all the changes.
+		tx, err := storage.BeginGraph(ctx, g)
+		if err != nil {
+			t.Fatalf("%s: storage.BeginGraph failed with error %v", entry.name, err)
+		}
+		if err := tx.AddTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.RemoveTriples(ctx, ts[:1]); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := count(tx), int64(len(ts)-1); got != want {
+			t.Errorf("%s: transaction changes should be visible to the transaction; got %d triples, want %d", entry.name, got, want)
+		}
+		want := int64(len(ts) - 1)
+		if entry.isolated {
+			want = 1
+		}
+		if got := count(g); got != want {
+			t.Errorf("%s: graph has %d triples before commit; want %d", entry.name, got, want)
+		}
+		if err := tx.Rollback(ctx); err != nil {
+			t.Fatalf("%s: tx.Rollback failed with error %v", entry.name, err)
+		}
+		if ok, err := g.Exist(ctx, ts[0]); err != nil || !ok {
+			t.Errorf("%s: tx.Rollback failed to restore the removed triple; %v", entry.name, err)
+		}
+		if got := count(g); got != 1 {
+			t.Errorf("%s: tx.Rollback left %d triples; want 1", entry.name, got)
+		}
+		if err := tx.AddTriples(ctx, ts); err == nil {
+			t.Errorf("%s: a rolled back transaction should not be usable", entry.name)
+		}
+		if err := tx.Commit(ctx); err == nil {
+			t.Errorf("%s: tx.Commit should fail for a rolled back transaction", entry.name)
+		}
+
+		// Committing keeps all the changes.
+		if tx, err = storage.BeginGraph(ctx, g); err != nil {
+			t.Fatalf("%s: storage.BeginGraph failed with error %v", entry.name, err)
+		}
+		if err := tx.AddTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.RemoveTriples(ctx, ts[:1]); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatalf("%s: tx.Commit failed with error %v", entry.name, err)
+		}
+		if got, want := count(g), int64(len(ts)-1); got != want {
+			t.Errorf("%s: tx.Commit kept %d triples; want %d", entry.name, got, want)
+		}
+		if ok, err := g.Exist(ctx, ts[0]); err != nil || ok {
+			t.Errorf("%s: tx.Commit failed to keep the removed triple removed; %v", entry.name, err)
+		}
+		if err := tx.Rollback(ctx); err == nil {
+			t.Errorf("%s: tx.Rollback should fail for a committed transaction", entry.name)
+		}
+	}
+}
+
+// partialGraph is a graph without transactions whose changes fail on demand.
+type partialGraph struct {
+	storage.Graph
+
+	// addLimit, if positive, is the number of triples added before failing.
+	addLimit int
+
+	// failRemove makes all removals fail.
+	failRemove bool
+}
+
+// AddTriples adds the triples, failing after adding addLimit of them.
+func (g *partialGraph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	if g.addLimit > 0 && len(ts) > g.addLimit {
+		if err := g.Graph.AddTriples(ctx, ts[:g.addLimit]); err != nil {
+			return err
+		}
+		return errors.New("partialGraph: AddTriples failed partway")
+	}
+	return g.Graph.AddTriples(ctx, ts)
+}
+
+// RemoveTriples removes the triples unless removals are set to fail.
+func (g *partialGraph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	if g.failRemove {
+		return errors.New("partialGraph: RemoveTriples failed")
+	}
+	return g.Graph.RemoveTriples(ctx, ts)
+}
+
+func TestGraphTransactionRollbackFailures(t *testing.T) {
+	ctx := context.Background()
+	ts := getTestTriples(t)
+	mg, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mg.AddTriples(ctx, ts[:1]); err != nil {
+		t.Fatal(err)
+	}
+	count := func() int64 {
+		cnt, err := storage.CountTriples(ctx, mg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cnt
+	}
+
+	// The triples added by a call failing partway are removed on rollback.
+	g := &partialGraph{Graph: mg, addLimit: 3}
+	tx, err := storage.BeginGraph(ctx, g)
+	if err != nil {
+		t.Fatalf("storage.BeginGraph failed with error %v", err)
+	}
+	if err := tx.AddTriples(ctx, ts[1:]); err == nil {
+		t.Fatalf("tx.AddTriples should have failed partway")
+	}
+	if got, want := count(), int64(4); got != want {
+		t.Fatalf("graph has %d triples after the failed call; want %d", got, want)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("tx.Rollback failed with error %v", err)
+	}
+	if got, want := count(), int64(1); got != want {
+		t.Errorf("tx.Rollback left %d triples after a call failing partway; want %d", got, want)
+	}
+
+	// All the undo steps are applied even if some of them fail.
+	g = &partialGraph{Graph: mg}
+	if tx, err = storage.BeginGraph(ctx, g); err != nil {
+		t.Fatalf("storage.BeginGraph failed with error %v", err)
+	}
+	if err := tx.RemoveTriples(ctx, ts[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.AddTriples(ctx, ts[len(ts)-1:]); err != nil {
+		t.Fatal(err)
+	}
+	g.failRemove = true
+	if err := tx.Rollback(ctx); err == nil {
+		t.Errorf("tx.Rollback should have failed to remove the added triple")
+	}
+	if ok, err := mg.Exist(ctx, ts[0]); err != nil || !ok {
+		t.Errorf("tx.Rollback failed to restore the removed triple after a failing undo step; %v", err)
+	}
+}
+
+func TestGraphTransactionConflict(t *testing.T) {
+	ctx := context.Background()
+	ts := getTestTriples(t)
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := storage.BeginGraph(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.AddTriples(ctx, ts[1:]); err != nil {
+		t.Fatal(err)
+	}
+	// Changing the graph outside the transaction makes its commit fail.
+	if err := g.AddTriples(ctx, ts[:1]); err != nil {
+		t.Fatal(err)
+	}
+	e, err := storage.Epoch(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err == nil {
+		t.Errorf("tx.Commit should fail if the graph changed since the transaction began")
+	}
+	if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != 1 {
+		t.Errorf("a failed commit should not change the graph; got %d triples, %v", cnt, err)
+	}
+	if got, err := storage.Epoch(ctx, g); err != nil || got != e {
+		t.Errorf("a failed commit should not change the graph epoch; got %d, want %d", got, e)
+	}
+}
+
+// getBulkTriples returns n different triples.
+func getBulkTriples(t *testing.T, n int) []*triple.Triple {
+	s := node.NewBlankNode()
+	p, err := predicate.NewImmutable("value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ts []*triple.Triple
+	for i := 0; i < n; i++ {
+		l, err := literal.DefaultBuilder().Build(literal.Int64, int64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		trpl, err := triple.New(s, p, triple.NewLiteralObject(l))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+// failingGraph fails to add triples after the first failAfter calls.
+type failingGraph struct {
+	storage.Graph
+	mu        sync.Mutex
+	failAfter int
+}
+
+func (g *failingGraph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.failAfter == 0 {
+		return errors.New("failingGraph: cannot add triples")
+	}
+	g.failAfter--
+	return g.Graph.AddTriples(ctx, ts)
+}
+
+func TestBulkLoad(t *testing.T) {
+	ctx := context.Background()
+	testTable := []struct {
+		triples, batchSize, workers, batches int
+	}{
+		{0, 10, 1, 0},
+		{25, 10, 1, 3},
+		{25, 5, 4, 5},
+		{3000, 0, 2, 3},
+		// Batches above parallelIndexThreshold update the indices concurrently.
+		{2500, 2500, 1, 1},
+	}
+	for _, entry := range testTable {
+		g, err := NewStore().NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts := getBulkTriples(t, entry.triples)
+		ch := make(chan *triple.Triple)
+		go func() {
+			for _, trpl := range ts {
+				ch <- trpl
+			}
+			close(ch)
+		}()
+		var (
+			batches int
+			loaded  int64
+		)
+		opts := &storage.BulkLoadOptions{
+			BatchSize: entry.batchSize,
+			Workers:   entry.workers,
+			Progress: func(r *storage.BatchResult) {
+				batches++
+				if r.Err != nil {
+					t.Errorf("batch %d failed with error %v", r.Batch, r.Err)
+				}
+				if r.Loaded <= loaded {
+					t.Errorf("batch %d reported %d loaded triples; want more than %d", r.Batch, r.Loaded, loaded)
+				}
+				loaded = r.Loaded
+			},
+		}
+		if err := storage.BulkLoad(ctx, g, ch, opts); err != nil {
+			t.Fatalf("storage.BulkLoad failed with error %v", err)
+		}
+		if batches != entry.batches {
+			t.Errorf("storage.BulkLoad(%+v) reported %d batches; want %d", entry, batches, entry.batches)
+		}
+		if loaded != int64(entry.triples) {
+			t.Errorf("storage.BulkLoad(%+v) reported %d loaded triples; want %d", entry, loaded, entry.triples)
+		}
+		if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != int64(entry.triples) {
+			t.Errorf("storage.BulkLoad(%+v) left %d triples in the graph; want %d", entry, cnt, entry.triples)
+		}
+		for _, trpl := range ts {
+			if ok, err := g.Exist(ctx, trpl); err != nil || !ok {
+				t.Errorf("storage.BulkLoad(%+v) failed to add triple %s", entry, trpl)
+				break
+			}
+		}
+		// All the lookups must find the triples of the parallel index updates.
+		if len(ts) > 0 {
+			sp := make(chan *triple.Triple, len(ts))
+			if err := g.TriplesForSubjectAndPredicate(ctx, ts[0].Subject(), ts[0].Predicate(), storage.DefaultLookup, sp); err != nil {
+				t.Fatal(err)
+			}
+			if len(sp) != len(ts) {
+				t.Errorf("g.TriplesForSubjectAndPredicate returned %d triples after bulk load; want %d", len(sp), len(ts))
+			}
+		}
+	}
+}
+
+func TestBulkLoadErrors(t *testing.T) {
+	ctx := context.Background()
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan *triple.Triple)
+	stop := make(chan bool)
+	go func() {
+		defer close(ch)
+		for _, trpl := range getBulkTriples(t, 100) {
+			select {
+			case ch <- trpl:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	var failed []int
+	opts := &storage.BulkLoadOptions{
+		BatchSize: 10,
+		Progress: func(r *storage.BatchResult) {
+			if r.Err != nil {
+				failed = append(failed, r.Batch)
+			}
+		},
+	}
+	err = storage.BulkLoad(ctx, &failingGraph{Graph: g, failAfter: 2}, ch, opts)
+	close(stop)
+	if err == nil {
+		t.Fatalf("storage.BulkLoad should have failed")
+	}
+	if len(failed) != 1 || failed[0] != 2 {
+		t.Errorf("storage.BulkLoad reported failed batches %v; want [2]", failed)
+	}
+	if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != 20 {
+		t.Errorf("storage.BulkLoad should keep the batches written before the error; got %d triples, want 20", cnt)
+	}
+}
+
+func TestSecondaryIndexes(t *testing.T) {
+	ctx := context.Background()
+	ts := getTestTriples(t)
+	john, mary := ts[0].Subject(), ts[3].Subject()
+	p, o := ts[0].Predicate(), ts[2].Object()
+	lookups := []struct {
+		name string
+		want int
+		f    func(g storage.Graph) (int, error)
+	}{
+		{"Objects", 3, func(g storage.Graph) (int, error) {
+			c := make(chan *triple.Object, len(ts))
+			err := g.Objects(ctx, john, p, storage.DefaultLookup, c)
+			return len(c), err
+		}},
+		{"Subjects", 2, func(g storage.Graph) (int, error) {
+			c := make(chan *node.Node, len(ts))
+			err := g.Subjects(ctx, p, o, storage.DefaultLookup, c)
+			return len(c), err
+		}},
+		{"PredicatesForSubjectAndObject", 1, func(g storage.Graph) (int, error) {
+			c := make(chan *predicate.Predicate, len(ts))
+			err := g.PredicatesForSubjectAndObject(ctx, mary, o, storage.DefaultLookup, c)
+			return len(c), err
+		}},
+		{"TriplesForSubjectAndPredicate", 3, func(g storage.Graph) (int, error) {
+			c := make(chan *triple.Triple, len(ts))
+			err := g.TriplesForSubjectAndPredicate(ctx, mary, p, storage.DefaultLookup, c)
+			return len(c), err
+		}},
+		{"TriplesForPredicateAndObject", 2, func(g storage.Graph) (int, error) {
+			c := make(chan *triple.Triple, len(ts))
+			err := g.TriplesForPredicateAndObject(ctx, p, o, storage.DefaultLookup, c)
+			return len(c), err
+		}},
+	}
+	for _, idxs := range []Indexes{AllIndexes, 0, SPOIndex, POSIndex | OSPIndex} {
+		g, err := NewStoreWithIndexes(idxs).NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := g.(*memory)
+		if got, want := m.idxSP != nil, idxs&SPOIndex != 0; got != want {
+			t.Errorf("NewStoreWithIndexes(%d) kept the SPO index: %v; want %v", idxs, got, want)
+		}
+		if got, want := m.idxPO != nil, idxs&POSIndex != 0; got != want {
+			t.Errorf("NewStoreWithIndexes(%d) kept the POS index: %v; want %v", idxs, got, want)
+		}
+		if got, want := m.idxSO != nil, idxs&OSPIndex != 0; got != want {
+			t.Errorf("NewStoreWithIndexes(%d) kept the OSP index: %v; want %v", idxs, got, want)
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range lookups {
+			got, err := l.f(g)
+			if err != nil {
+				t.Fatalf("%s with indexes %d failed with error %v", l.name, idxs, err)
+			}
+			if got != l.want {
+				t.Errorf("%s with indexes %d returned %d elements; want %d", l.name, idxs, got, l.want)
+			}
+		}
+		// Removing the triples empties all the lookups.
+		if err := g.RemoveTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range lookups {
+			if got, err := l.f(g); err != nil || got != 0 {
+				t.Errorf("%s with indexes %d returned %d elements after removing all triples; %v", l.name, idxs, got, err)
+			}
+		}
+	}
+}
+
+func TestCanceledLookups(t *testing.T) {
+	ctx := context.Background()
+	ts := getTestTriples(t)
+	g, err := NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	// Nobody reads from the unbuffered channels, so lookups can only finish
+	// because the context was canceled.
+	trpls := make(chan *triple.Triple)
+	if err := g.Triples(cctx, storage.DefaultLookup, trpls); err != context.Canceled {
+		t.Errorf("g.Triples should have been canceled; got error %v", err)
+	}
+	if _, ok := <-trpls; ok {
+		t.Errorf("g.Triples should have closed the channel after being canceled")
+	}
+	objs := make(chan *triple.Object)
+	if err := g.Objects(cctx, ts[0].Subject(), ts[0].Predicate(), storage.DefaultLookup, objs); err != context.Canceled {
+		t.Errorf("g.Objects should have been canceled; got error %v", err)
+	}
+	trpls = make(chan *triple.Triple)
+	if err := g.TriplesForSubject(cctx, ts[0].Subject(), storage.DefaultLookup, trpls); err != context.Canceled {
+		t.Errorf("g.TriplesForSubject should have been canceled; got error %v", err)
+	}
+}
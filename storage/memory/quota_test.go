@@ -0,0 +1,161 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+)
+
+func TestGraphQuotaTriples(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 8)
+	for _, s := range []storage.Store{NewStore(), NewShardedStore(4, AllIndexes)} {
+		g, err := s.NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.SetGraphQuota(ctx, s, "?test", &storage.GraphQuota{MaxTriples: 10}); err != nil {
+			t.Fatalf("storage.SetGraphQuota failed with error %v", err)
+		}
+		if q, err := storage.GetGraphQuota(ctx, s, "?test"); err != nil || q.MaxTriples != 10 || q.MaxMemoryBytes != 0 {
+			t.Errorf("storage.GetGraphQuota returned %+v, %v; want 10 triples", q, err)
+		}
+		if err := g.AddTriples(ctx, ts[:8]); err != nil {
+			t.Fatal(err)
+		}
+		err = g.AddTriples(ctx, ts[4:12])
+		if qe, ok := err.(*storage.ErrQuotaExceeded); !ok || qe.Resource != "triples" || qe.Limit != 10 || qe.Requested != 12 {
+			t.Errorf("g.AddTriples returned error %v beyond the quota; want *storage.ErrQuotaExceeded for 12 triples", err)
+		}
+		if n, err := storage.CountTriples(ctx, g); err != nil || n != 8 {
+			t.Errorf("g.AddTriples left %d triples, %v after exceeding the quota; want 8", n, err)
+		}
+		// Triples already in the graph do not count against the quota.
+		if err := g.AddTriples(ctx, ts[:10]); err != nil {
+			t.Errorf("g.AddTriples failed with error %v within the quota", err)
+		}
+		if err := g.RemoveTriples(ctx, ts[:5]); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts[10:15]); err != nil {
+			t.Errorf("g.AddTriples failed with error %v after removing triples", err)
+		}
+
+		// Transactions check the quota of the graph.
+		tx, err := storage.BeginGraph(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.AddTriples(ctx, ts[15:16]); err == nil {
+			t.Errorf("tx.AddTriples should fail beyond the quota of the graph")
+		}
+		if err := tx.Rollback(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		// Removing the quota removes the limits.
+		if err := storage.SetGraphQuota(ctx, s, "?test", nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Errorf("g.AddTriples failed with error %v without quota", err)
+		}
+	}
+}
+
+func TestGraphQuotaMemory(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 8)
+	for _, s := range []storage.Store{NewStore(), NewShardedStore(4, AllIndexes)} {
+		g, err := s.NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts[:12]); err != nil {
+			t.Fatal(err)
+		}
+		st, err := storage.Statistics(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.SetGraphQuota(ctx, s, "?test", &storage.GraphQuota{MaxMemoryBytes: st.MemoryBytes + 100}); err != nil {
+			t.Fatal(err)
+		}
+		err = g.AddTriples(ctx, ts[12:])
+		if qe, ok := err.(*storage.ErrQuotaExceeded); !ok || qe.Resource != "memory bytes" || qe.Requested <= qe.Limit {
+			t.Errorf("g.AddTriples returned error %v beyond the memory quota; want *storage.ErrQuotaExceeded", err)
+		}
+		if n, err := storage.CountTriples(ctx, g); err != nil || n != 12 {
+			t.Errorf("g.AddTriples left %d triples, %v after exceeding the quota; want 12", n, err)
+		}
+	}
+}
+
+func TestGraphQuotaUnknownGraph(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	if _, err := storage.GetGraphQuota(ctx, s, "?missing"); err == nil {
+		t.Errorf("storage.GetGraphQuota should fail for a graph that does not exist")
+	}
+	if err := storage.SetGraphQuota(ctx, s, "?missing", &storage.GraphQuota{MaxTriples: 1}); err == nil {
+		t.Errorf("storage.SetGraphQuota should fail for a graph that does not exist")
+	}
+	if err := storage.SetGraphQuota(ctx, struct{ storage.Store }{s}, "?missing", nil); err != storage.ErrNoQuotas {
+		t.Errorf("storage.SetGraphQuota returned %v for a store without quotas; want storage.ErrNoQuotas", err)
+	}
+}
+
+func TestMemoryBytesTracking(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 8)
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := g.(*memory)
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	// Adding the same triples again does not change the estimate.
+	b := m.bytes
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	if m.bytes != b || b <= 0 {
+		t.Errorf("adding existing triples changed the memory estimate from %d to %d", b, m.bytes)
+	}
+	tx, err := storage.BeginGraph(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.RemoveTriples(ctx, ts[:4]); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m.bytes >= b {
+		t.Errorf("committing a transaction removing triples left the memory estimate at %d; want less than %d", m.bytes, b)
+	}
+	if err := g.RemoveTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	if m.bytes != 0 {
+		t.Errorf("removing all the triples left a memory estimate of %d for them; want 0", m.bytes)
+	}
+}
@@ -0,0 +1,588 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// NewShardedStore creates a new memory store whose graphs partition their
+// triples by subject into the provided number of shards. Each shard keeps its
+// own indices and lock, so writers and readers working on different subjects
+// do not wait for each other. Lookups bound to a subject only use its shard;
+// other lookups are run on all the shards concurrently. A store with a single
+// shard behaves like the one returned by NewStoreWithIndexes.
+func NewShardedStore(shards int, idxs Indexes) storage.Store {
+	s := NewStoreWithIndexes(idxs).(*memoryStore)
+	s.shards = shards
+	return s
+}
+
+// shardedMemory is a memory graph whose triples are partitioned by subject
+// among several memory graphs.
+type shardedMemory struct {
+	id     string
+	shards []*memory
+	// quota limits the triples and the memory of all the shards.
+	quota *graphQuota
+	// feed notifies the changes of all the shards to the watchers of the
+	// graph.
+	feed *storage.ChangeNotifier
+}
+
+// newShardedMemory returns a new empty graph with the provided ID and number
+// of shards that keep the provided secondary indices.
+func newShardedMemory(id string, n int, idxs Indexes) *shardedMemory {
+	g := &shardedMemory{id: id, shards: make([]*memory, n), quota: &graphQuota{}, feed: &storage.ChangeNotifier{}}
+	for i := range g.shards {
+		g.shards[i] = newMemory(id, idxs, initialAllocation/n)
+		g.shards[i].quota, g.shards[i].feed = nil, g.feed
+	}
+	return g
+}
+
+// shardsOf returns the memory graphs holding the triples of a graph created by
+// a memory store.
+func shardsOf(g storage.Graph) []*memory {
+	switch g := g.(type) {
+	case *memory:
+		return []*memory{g}
+	case *shardedMemory:
+		return g.shards
+	}
+	return nil
+}
+
+// shard returns the index of the shard that keeps the triples of the provided
+// subject. Node UUIDs are hashes, so their first bytes are evenly distributed.
+func (g *shardedMemory) shard(s *node.Node) int {
+	return int(binary.BigEndian.Uint32(s.UUID()[:4]) % uint32(len(g.shards)))
+}
+
+// ID returns the id for this graph.
+func (g *shardedMemory) ID(ctx context.Context) string {
+	return g.id
+}
+
+// partition groups the provided triples by the shard of their subject.
+func (g *shardedMemory) partition(ts []*triple.Triple) [][]*triple.Triple {
+	res := make([][]*triple.Triple, len(g.shards))
+	for _, t := range ts {
+		i := g.shard(t.Subject())
+		res[i] = append(res[i], t)
+	}
+	return res
+}
+
+// update concurrently calls f for every shard with the triples that belong to
+// it, and returns the first error found.
+func (g *shardedMemory) update(ts []*triple.Triple, f func(m *memory, ts []*triple.Triple) error) error {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+	for i, sts := range g.partition(ts) {
+		if len(sts) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(m *memory, sts []*triple.Triple) {
+			defer wg.Done()
+			if err := f(m, sts); err != nil {
+				mu.Lock()
+				if first == nil {
+					first = err
+				}
+				mu.Unlock()
+			}
+		}(g.shards[i], sts)
+	}
+	wg.Wait()
+	return first
+}
+
+// AddTriples adds the triples to the shards of their subjects. If the graph
+// would exceed its quota, it fails without adding any of them; writes to
+// limited graphs are serialized to check the usage of all the shards.
+func (g *shardedMemory) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	if err := g.quota.checkLiterals(g.id, ts); err != nil {
+		return err
+	}
+	if g.quota.limited() {
+		g.quota.wmu.Lock()
+		defer g.quota.wmu.Unlock()
+		var triples, bytes int64
+		for i, sts := range g.partition(ts) {
+			t, b, err := g.shards[i].usage(sts)
+			if err != nil {
+				return err
+			}
+			triples, bytes = triples+t, bytes+b
+		}
+		if err := g.quota.check(g.id, triples, bytes); err != nil {
+			return err
+		}
+	}
+	return g.update(ts, func(m *memory, ts []*triple.Triple) error {
+		return m.AddTriples(ctx, ts)
+	})
+}
+
+// RemoveTriples removes the triples from the shards of their subjects.
+func (g *shardedMemory) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	return g.update(ts, func(m *memory, ts []*triple.Triple) error {
+		return m.RemoveTriples(ctx, ts)
+	})
+}
+
+// errLimit stops a lookup run on all the shards once it returned the maximum
+// number of elements requested.
+var errLimit = errors.New("memory: lookup limit reached")
+
+// shardLookup is a triple lookup run on a single shard. It must close the
+// channel when done.
+type shardLookup func(m *memory, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error
+
+// collect runs the lookup on all the shards concurrently and calls f for each
+// triple found until f returns an error. It returns the first error returned
+// by f or the lookups.
+func (g *shardedMemory) collect(ctx context.Context, lo *storage.LookupOptions, lookup shardLookup, f func(*triple.Triple) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	merged := make(chan *triple.Triple)
+	errs := make(chan error, len(g.shards))
+	for _, m := range g.shards {
+		c := make(chan *triple.Triple)
+		go func(m *memory) {
+			errs <- lookup(m, lo, c)
+		}(m)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range c {
+				select {
+				case <-ctx.Done():
+				case merged <- t:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	var ferr error
+	for t := range merged {
+		if ferr != nil {
+			continue
+		}
+		if ferr = f(t); ferr != nil {
+			cancel()
+		}
+	}
+	for range g.shards {
+		if err := <-errs; err != nil && ferr == nil {
+			ferr = err
+		}
+	}
+	return ferr
+}
+
+// fanOut runs the lookup on all the shards and calls emit for each of the
+// triples that match the lookup options. The latest anchor of a predicate can
+// be on any shard, so latest anchor lookups first find the latest triples
+// among all the shards and then filter their objects.
+func (g *shardedMemory) fanOut(ctx context.Context, lo *storage.LookupOptions, lookup shardLookup, emit func(*triple.Triple) error) error {
+	if lo.LatestAnchor {
+		latest := make(map[string]*triple.Triple)
+		anchors := make(map[string]*time.Time)
+		err := g.collect(ctx, &storage.LookupOptions{LatestAnchor: true}, lookup, func(t *triple.Triple) error {
+			ta, err := t.Predicate().TimeAnchor()
+			if err != nil {
+				return err
+			}
+			id := string(t.Predicate().ID())
+			if lta := anchors[id]; lta == nil || ta.After(*lta) {
+				latest[id], anchors[id] = t, ta
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, t := range latest {
+			if lo.AcceptObject(t.Object()) {
+				if err := emit(t); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	n := 0
+	err := g.collect(ctx, lo, lookup, func(t *triple.Triple) error {
+		if err := emit(t); err != nil {
+			return err
+		}
+		n++
+		if lo.MaxElements > 0 && n >= lo.MaxElements {
+			return errLimit
+		}
+		return nil
+	})
+	if err == errLimit {
+		return nil
+	}
+	return err
+}
+
+// Objects pushes to the provided channel the objects for the given subject
+// and predicate. The lookup only uses the shard of the subject.
+func (g *shardedMemory) Objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	return g.shards[g.shard(s)].Objects(ctx, s, p, lo, objs)
+}
+
+// Subjects pushes to the provided channel the subjects for the given
+// predicate and object. The lookup is run on all the shards.
+func (g *shardedMemory) Subjects(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, subjs chan<- *node.Node) error {
+	if subjs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(subjs)
+	return g.fanOut(ctx, lo, func(m *memory, lo *storage.LookupOptions, c chan<- *triple.Triple) error {
+		return m.TriplesForPredicateAndObject(ctx, p, o, lo, c)
+	}, func(t *triple.Triple) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case subjs <- t.Subject():
+			return nil
+		}
+	})
+}
+
+// PredicatesForSubjectAndObject pushes to the provided channel all the
+// predicates known for the given subject and object. The lookup only uses the
+// shard of the subject.
+func (g *shardedMemory) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	return g.shards[g.shard(s)].PredicatesForSubjectAndObject(ctx, s, o, lo, prds)
+}
+
+// PredicatesForSubject pushes to the provided channel all the predicates
+// known for the given subject. The lookup only uses the shard of the subject.
+func (g *shardedMemory) PredicatesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	return g.shards[g.shard(s)].PredicatesForSubject(ctx, s, lo, prds)
+}
+
+// PredicatesForObject pushes to the provided channel all the predicates known
+// for the given object. The lookup is run on all the shards.
+func (g *shardedMemory) PredicatesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	return g.fanOut(ctx, lo, func(m *memory, lo *storage.LookupOptions, c chan<- *triple.Triple) error {
+		return m.TriplesForObject(ctx, o, lo, c)
+	}, func(t *triple.Triple) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case prds <- t.Predicate():
+			return nil
+		}
+	})
+}
+
+// TriplesForSubject pushes to the provided channel all triples available for
+// the given subject. The lookup only uses the shard of the subject.
+func (g *shardedMemory) TriplesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.shards[g.shard(s)].TriplesForSubject(ctx, s, lo, trpls)
+}
+
+// triples runs a triple lookup on all the shards and pushes the triples found
+// to the provided channel.
+func (g *shardedMemory) triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple, lookup shardLookup) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	return g.fanOut(ctx, lo, lookup, func(t *triple.Triple) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case trpls <- t:
+			return nil
+		}
+	})
+}
+
+// TriplesForPredicate pushes to the provided channel all triples available
+// for the given predicate. The lookup is run on all the shards.
+func (g *shardedMemory) TriplesForPredicate(ctx context.Context, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.triples(ctx, lo, trpls, func(m *memory, lo *storage.LookupOptions, c chan<- *triple.Triple) error {
+		return m.TriplesForPredicate(ctx, p, lo, c)
+	})
+}
+
+// TriplesForObject pushes to the provided channel all triples available for
+// the given object. The lookup is run on all the shards.
+func (g *shardedMemory) TriplesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.triples(ctx, lo, trpls, func(m *memory, lo *storage.LookupOptions, c chan<- *triple.Triple) error {
+		return m.TriplesForObject(ctx, o, lo, c)
+	})
+}
+
+// TriplesForSubjectAndPredicate pushes to the provided channel all triples
+// available for the given subject and predicate. The lookup only uses the
+// shard of the subject.
+func (g *shardedMemory) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.shards[g.shard(s)].TriplesForSubjectAndPredicate(ctx, s, p, lo, trpls)
+}
+
+// TriplesForPredicateAndObject pushes to the provided channel all triples
+// available for the given predicate and object. The lookup is run on all the
+// shards.
+func (g *shardedMemory) TriplesForPredicateAndObject(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.triples(ctx, lo, trpls, func(m *memory, lo *storage.LookupOptions, c chan<- *triple.Triple) error {
+		return m.TriplesForPredicateAndObject(ctx, p, o, lo, c)
+	})
+}
+
+// Exist checks if the provided triple exists on the shard of its subject.
+func (g *shardedMemory) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
+	return g.shards[g.shard(t.Subject())].Exist(ctx, t)
+}
+
+// Triples pushes to the provided channel the triples of all the shards.
+func (g *shardedMemory) Triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.triples(ctx, lo, trpls, func(m *memory, lo *storage.LookupOptions, c chan<- *triple.Triple) error {
+		return m.Triples(ctx, lo, c)
+	})
+}
+
+// CountTriples returns the number of triples in the graph.
+func (g *shardedMemory) CountTriples(ctx context.Context) (int64, error) {
+	var n int64
+	for _, m := range g.shards {
+		c, err := m.CountTriples(ctx)
+		if err != nil {
+			return 0, err
+		}
+		n += c
+	}
+	return n, nil
+}
+
+// Statistics returns the merged statistics of the shards. Subjects are never
+// shared among shards; objects are counted once even if several shards have
+// triples with them.
+func (g *shardedMemory) Statistics(ctx context.Context) (*storage.GraphStatistics, error) {
+	res := &storage.GraphStatistics{Predicates: make(map[string]int64)}
+	objs := make(map[string]bool)
+	for _, m := range g.shards {
+		s, err := m.Statistics(ctx)
+		if err != nil {
+			return nil, err
+		}
+		res.Merge(s)
+		// Index keys are only unique within a shard, so objects are
+		// identified by UUID.
+		m.rwmu.RLock()
+		for _, ts := range m.idxO {
+			for _, t := range ts {
+				objs[UUIDToByteString(t.Object().UUID())] = true
+				break
+			}
+		}
+		m.rwmu.RUnlock()
+	}
+	res.Objects = int64(len(objs))
+	return res, nil
+}
+
+// Epoch returns the current epoch of the graph. Epochs are unique across all
+// graphs, so the latest epoch of the shards changes every time any of them
+// does.
+func (g *shardedMemory) Epoch(ctx context.Context) (uint64, error) {
+	var res uint64
+	for _, m := range g.shards {
+		e, err := m.Epoch(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if e > res {
+			res = e
+		}
+	}
+	return res, nil
+}
+
+// PredicateIDs returns the sorted list of unique predicate IDs used in the
+// graph.
+func (g *shardedMemory) PredicateIDs(ctx context.Context) ([]string, error) {
+	ids := make(map[string]bool)
+	for _, m := range g.shards {
+		sids, err := m.PredicateIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range sids {
+			ids[id] = true
+		}
+	}
+	var res []string
+	for id := range ids {
+		res = append(res, id)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// Watch returns a channel where the changes done to all the shards are pushed.
+// Changes to different shards are only ordered by the time they were done.
+func (g *shardedMemory) Watch(ctx context.Context) (<-chan *storage.Change, error) {
+	return g.feed.Watch(ctx)
+}
+
+// Expire removes the temporal triples anchored before the provided time from
+// all the shards.
+func (g *shardedMemory) Expire(ctx context.Context, before time.Time, predicates []string) (int64, error) {
+	var n int64
+	for _, m := range g.shards {
+		c, err := m.Expire(ctx, before, predicates)
+		n += c
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Begin starts a transaction on the graph. Every shard is copied as in a
+// memory graph transaction, and committing applies the changes of all the
+// shards at once.
+func (g *shardedMemory) Begin(ctx context.Context) (storage.GraphTransaction, error) {
+	t := &shardedTransaction{shardedMemory: &shardedMemory{id: g.id, quota: g.quota}}
+	for _, m := range g.shards {
+		mt, err := m.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		t.txs = append(t.txs, mt.(*memoryTransaction))
+		t.shards = append(t.shards, mt.(*memoryTransaction).memory)
+	}
+	return t, nil
+}
+
+// shardedTransaction is a transaction on a sharded memory graph. All the
+// changes and lookups are done on the copies of the shards.
+type shardedTransaction struct {
+	*shardedMemory
+
+	// txs are the transactions on each of the shards.
+	txs []*memoryTransaction
+
+	mu   sync.Mutex
+	done bool
+}
+
+// check returns an error if the transaction was already finished.
+func (t *shardedTransaction) check() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return fmt.Errorf("memory: the transaction on graph %q was already committed or rolled back", t.id)
+	}
+	return nil
+}
+
+// Begin starts a transaction nested in the transaction. Committing it applies
+// its changes to the enclosing transaction.
+func (t *shardedTransaction) Begin(ctx context.Context) (storage.GraphTransaction, error) {
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	return t.shardedMemory.Begin(ctx)
+}
+
+// AddTriples adds the triples to the transaction.
+func (t *shardedTransaction) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	return t.shardedMemory.AddTriples(ctx, ts)
+}
+
+// RemoveTriples removes the triples from the transaction.
+func (t *shardedTransaction) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	return t.shardedMemory.RemoveTriples(ctx, ts)
+}
+
+// Expire removes the expired triples from the transaction.
+func (t *shardedTransaction) Expire(ctx context.Context, before time.Time, predicates []string) (int64, error) {
+	if err := t.check(); err != nil {
+		return 0, err
+	}
+	return t.shardedMemory.Expire(ctx, before, predicates)
+}
+
+// Commit replaces the triples of the changed shards with the ones of the
+// transaction in a single step.
+func (t *shardedTransaction) Commit(ctx context.Context) error {
+	if err := t.finish("commit"); err != nil {
+		return err
+	}
+	return commit(t.txs...)
+}
+
+// Rollback discards the changes done in the transaction.
+func (t *shardedTransaction) Rollback(ctx context.Context) error {
+	return t.finish("roll back")
+}
+
+// finish marks the transaction and the ones of its shards as finished.
+func (t *shardedTransaction) finish(op string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return fmt.Errorf("memory: cannot %s the transaction on graph %q; it was already finished", op, t.id)
+	}
+	t.done = true
+	for _, mt := range t.txs {
+		mt.mu.Lock()
+		mt.done = true
+		mt.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// getShardedTriples returns triples of n subjects that share their objects,
+// with immutable and temporal predicates.
+func getShardedTriples(t *testing.T, n int) []*triple.Triple {
+	var ss []string
+	for i := 0; i < n; i++ {
+		ss = append(ss,
+			fmt.Sprintf("/u<s%d>\t\"knows\"@[]\t/u<o%d>", i, i%3),
+			fmt.Sprintf("/u<s%d>\t\"knows\"@[]\t/u<s%d>", i, (i+1)%n),
+			fmt.Sprintf("/u<s%d>\t\"met\"@[20%02d-04-10T04:21:00Z]\t/u<o%d>", i, i, i%3),
+		)
+	}
+	return createTriples(t, ss)
+}
+
+func TestShardedLookups(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 16)
+	s, o := ts[0].Subject(), ts[0].Object()
+	knows, met := ts[0].Predicate(), ts[2].Predicate()
+	lower, upper := mustParse("2004-01-01T00:00:00Z"), mustParse("2011-01-01T00:00:00Z")
+	los := map[string]*storage.LookupOptions{
+		"default":   storage.DefaultLookup,
+		"bounded":   {LowerAnchor: lower, UpperAnchor: upper},
+		"latest":    {LatestAnchor: true},
+		"limited":   {MaxElements: 5},
+		"unlimited": {MaxElements: 1000},
+	}
+	lookups := map[string]func(g storage.Graph, lo *storage.LookupOptions) ([]string, error){
+		"Objects": func(g storage.Graph, lo *storage.LookupOptions) ([]string, error) {
+			c := make(chan *triple.Object, len(ts))
+			err := g.Objects(ctx, s, knows, lo, c)
+			var res []string
+			for o := range c {
+				res = append(res, o.String())
+			}
+			return res, err
+		},
+		"Subjects": func(g storage.Graph, lo *storage.LookupOptions) ([]string, error) {
+			c := make(chan *node.Node, len(ts))
+			err := g.Subjects(ctx, met, o, lo, c)
+			var res []string
+			for n := range c {
+				res = append(res, n.String())
+			}
+			return res, err
+		},
+		"PredicatesForObject": func(g storage.Graph, lo *storage.LookupOptions) ([]string, error) {
+			c := make(chan *predicate.Predicate, len(ts))
+			err := g.PredicatesForObject(ctx, o, lo, c)
+			var res []string
+			for p := range c {
+				res = append(res, p.String())
+			}
+			return res, err
+		},
+		"TriplesForSubject": func(g storage.Graph, lo *storage.LookupOptions) ([]string, error) {
+			c := make(chan *triple.Triple, len(ts))
+			err := g.TriplesForSubject(ctx, s, lo, c)
+			return tripleStrings(c), err
+		},
+		"TriplesForPredicate": func(g storage.Graph, lo *storage.LookupOptions) ([]string, error) {
+			c := make(chan *triple.Triple, len(ts))
+			err := g.TriplesForPredicate(ctx, met, lo, c)
+			return tripleStrings(c), err
+		},
+		"TriplesForObject": func(g storage.Graph, lo *storage.LookupOptions) ([]string, error) {
+			c := make(chan *triple.Triple, len(ts))
+			err := g.TriplesForObject(ctx, o, lo, c)
+			return tripleStrings(c), err
+		},
+		"TriplesForPredicateAndObject": func(g storage.Graph, lo *storage.LookupOptions) ([]string, error) {
+			c := make(chan *triple.Triple, len(ts))
+			err := g.TriplesForPredicateAndObject(ctx, knows, o, lo, c)
+			return tripleStrings(c), err
+		},
+		"Triples": func(g storage.Graph, lo *storage.LookupOptions) ([]string, error) {
+			c := make(chan *triple.Triple, len(ts))
+			err := g.Triples(ctx, lo, c)
+			return tripleStrings(c), err
+		},
+	}
+
+	want, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := want.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{1, 4, 7} {
+		g, err := NewShardedStore(n, AllIndexes).NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(shardsOf(g)); got != n {
+			t.Errorf("NewShardedStore(%d) created a graph with %d shards", n, got)
+		}
+		used := 0
+		for _, m := range shardsOf(g) {
+			if len(m.idx) > 0 {
+				used++
+			}
+		}
+		if n > 1 && used < 2 {
+			t.Errorf("NewShardedStore(%d) put all the triples in %d shards", n, used)
+		}
+		for ln, l := range lookups {
+			for on, lo := range los {
+				w, err := l(want, lo)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := l(g, lo)
+				if err != nil {
+					t.Fatalf("%s(%s) on %d shards failed with error %v", ln, on, n, err)
+				}
+				if lo.MaxElements > 0 && len(got) > lo.MaxElements {
+					t.Errorf("%s(%s) on %d shards returned %d elements; want at most %d", ln, on, n, len(got), lo.MaxElements)
+				}
+				if len(got) != len(w) {
+					t.Errorf("%s(%s) on %d shards returned %d elements; want %d", ln, on, n, len(got), len(w))
+					continue
+				}
+				if on == "limited" {
+					// Limited lookups may return any of the elements.
+					continue
+				}
+				sort.Strings(w)
+				sort.Strings(got)
+				for i := range w {
+					if got[i] != w[i] {
+						t.Errorf("%s(%s) on %d shards returned %v; want %v", ln, on, n, got, w)
+						break
+					}
+				}
+			}
+		}
+		for _, trpl := range ts {
+			if ok, err := g.Exist(ctx, trpl); err != nil || !ok {
+				t.Errorf("g.Exist(%s) on %d shards returned %v, %v; want true", trpl, n, ok, err)
+			}
+		}
+		if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != int64(len(ts)) {
+			t.Errorf("storage.CountTriples on %d shards returned %d, %v; want %d", n, cnt, err, len(ts))
+		}
+		ws, err := storage.Statistics(ctx, want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gs, err := storage.Statistics(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gs.Triples != ws.Triples || gs.Subjects != ws.Subjects || gs.Objects != ws.Objects || len(gs.Predicates) != len(ws.Predicates) ||
+			!gs.FirstAnchor.Equal(*ws.FirstAnchor) || !gs.LastAnchor.Equal(*ws.LastAnchor) || gs.MemoryBytes <= 0 {
+			t.Errorf("storage.Statistics on %d shards returned %+v; want %+v", n, gs, ws)
+		}
+	}
+}
+
+// tripleStrings returns the string representation of the triples in the
+// channel.
+func tripleStrings(c <-chan *triple.Triple) []string {
+	var res []string
+	for t := range c {
+		res = append(res, t.String())
+	}
+	return res
+}
+
+func TestShardedTransaction(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 8)
+	g, err := NewShardedStore(4, AllIndexes).NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts[:6]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rolled back changes are never visible.
+	tx, err := storage.BeginGraph(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.AddTriples(ctx, ts[6:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.AddTriples(ctx, ts); err == nil {
+		t.Errorf("tx.AddTriples should fail after the transaction was rolled back")
+	}
+	if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != 6 {
+		t.Errorf("a rolled back transaction changed the graph; got %d triples, %v", cnt, err)
+	}
+
+	// Committed changes to all the shards are visible at once.
+	tx, err = storage.BeginGraph(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.AddTriples(ctx, ts[6:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.RemoveTriples(ctx, ts[:3]); err != nil {
+		t.Fatal(err)
+	}
+	if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != 6 {
+		t.Errorf("changes of a transaction are visible before commit; got %d triples, %v", cnt, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("tx.Commit failed with error %v", err)
+	}
+	if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != int64(len(ts)-3) {
+		t.Errorf("tx.Commit left %d triples, %v; want %d", cnt, err, len(ts)-3)
+	}
+	if err := tx.Commit(ctx); err == nil {
+		t.Errorf("tx.Commit should fail on an already committed transaction")
+	}
+
+	// Changing any shard outside the transaction makes its commit fail.
+	tx, err = storage.BeginGraph(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.AddTriples(ctx, ts[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts[1:3]); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err == nil {
+		t.Errorf("tx.Commit should fail if the graph changed since the transaction began")
+	}
+	if ok, err := g.Exist(ctx, ts[0]); err != nil || ok {
+		t.Errorf("a failed commit should not change the graph; g.Exist returned %v, %v", ok, err)
+	}
+}
+
+func TestShardedConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 64)
+	g, err := NewShardedStore(8, AllIndexes).NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < len(ts); i += 8 {
+		wg.Add(2)
+		go func(ts []*triple.Triple) {
+			defer wg.Done()
+			if err := g.AddTriples(ctx, ts); err != nil {
+				t.Error(err)
+			}
+		}(ts[i : i+8])
+		go func() {
+			defer wg.Done()
+			c := make(chan *triple.Triple)
+			go func() {
+				for range c {
+				}
+			}()
+			if err := g.Triples(ctx, storage.DefaultLookup, c); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != int64(len(ts)) {
+		t.Errorf("concurrent writes left %d triples, %v; want %d", cnt, err, len(ts))
+	}
+}
+
+func TestShardedSnapshot(t *testing.T) {
+	ctx := context.Background()
+	ts := getShardedTriples(t, 16)
+	var snaps [][]byte
+	for _, s := range []storage.Store{NewStore(), NewShardedStore(5, AllIndexes)} {
+		g, err := s.NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := s.(Snapshotter).Save(ctx, &buf); err != nil {
+			t.Fatalf("Save failed with error %v", err)
+		}
+		snaps = append(snaps, buf.Bytes())
+	}
+	if !bytes.Equal(snaps[0], snaps[1]) {
+		t.Errorf("Save of a sharded store returned a different snapshot than an unsharded one")
+	}
+	s := NewShardedStore(3, AllIndexes)
+	if err := s.(Snapshotter).Load(ctx, bytes.NewReader(snaps[0])); err != nil {
+		t.Fatalf("Load failed with error %v", err)
+	}
+	g, err := s.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != int64(len(ts)) {
+		t.Errorf("Load restored %d triples, %v; want %d", cnt, err, len(ts))
+	}
+}
@@ -0,0 +1,501 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// Snapshotter is implemented by the stores returned by NewStore. It allows
+// saving all the graphs of a store to a binary snapshot and loading them back.
+type Snapshotter interface {
+	// Save writes a snapshot of all the graphs in the store to the provided
+	// writer.
+	Save(ctx context.Context, w io.Writer) error
+
+	// Load adds all the graphs in the provided snapshot to the store. It fails
+	// without modifying the store if any of the graphs already exists.
+	Load(ctx context.Context, r io.Reader) error
+}
+
+// snapshotMagic identifies the format and version of a snapshot.
+const snapshotMagic = "BWSNAP\x01"
+
+// Tags of the kinds of objects in a snapshot.
+const (
+	nodeTag byte = iota
+	predicateTag
+	literalTag
+)
+
+// customLiteral replaces the type of custom literals in a snapshot, since
+// the type assigned to custom types may change between processes. It is
+// followed by the name of the type and the text of the value.
+const customLiteral byte = 0xff
+
+// secondsPerDay converts the days of date literals to Unix time.
+const secondsPerDay = 24 * 60 * 60
+
+// Save writes a snapshot of all the graphs in the store to the provided
+// writer. Graphs and triples are written in a stable order, so saving the same
+// store twice produces the same snapshot.
+//
+// A snapshot starts with a magic string and the number of graphs. Each graph
+// is written as its ID, the number of its triples, and the triples. Node
+// types, node IDs and predicate IDs are written once and referenced by index
+// afterwards, since they are usually repeated across triples.
+func (s *memoryStore) Save(ctx context.Context, w io.Writer) error {
+	s.rwmu.RLock()
+	defer s.rwmu.RUnlock()
+	var ids []string
+	for id := range s.graphs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	sw := &snapshotWriter{w: bufio.NewWriter(w), strs: make(map[string]uint64)}
+	sw.w.WriteString(snapshotMagic)
+	sw.uvarint(uint64(len(ids)))
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ts := make(map[string]*triple.Triple)
+		for _, m := range shardsOf(s.graphs[id]) {
+			m.rwmu.RLock()
+			// Index keys depend on the order values were interned, so
+			// triples are sorted by UUID instead.
+			for _, t := range m.idx {
+				ts[UUIDToByteString(t.UUID())] = t
+			}
+			m.rwmu.RUnlock()
+		}
+		var keys []string
+		for k := range ts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sw.bytes([]byte(id))
+		sw.uvarint(uint64(len(keys)))
+		for _, k := range keys {
+			sw.triple(ts[k])
+		}
+		if sw.err != nil {
+			return fmt.Errorf("memory.Save: failed to write graph %q; %v", id, sw.err)
+		}
+	}
+	if err := sw.w.Flush(); err != nil {
+		return fmt.Errorf("memory.Save: failed to write snapshot; %v", err)
+	}
+	return nil
+}
+
+// Load adds all the graphs in the provided snapshot to the store. The whole
+// snapshot is decoded before any graph is created, so a corrupted snapshot or
+// a graph that already exists leaves the store untouched.
+func (s *memoryStore) Load(ctx context.Context, r io.Reader) error {
+	sr := &snapshotReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(sr.r, magic); err != nil || string(magic) != snapshotMagic {
+		return fmt.Errorf("memory.Load: not a badwolf memory snapshot")
+	}
+	n := sr.uvarint()
+	var ids []string
+	graphs := make(map[string][]*triple.Triple)
+	for i := uint64(0); i < n && sr.err == nil; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		id := string(sr.bytes())
+		cnt := sr.uvarint()
+		var ts []*triple.Triple
+		for j := uint64(0); j < cnt && sr.err == nil; j++ {
+			ts = append(ts, sr.triple())
+		}
+		if sr.err != nil {
+			return fmt.Errorf("memory.Load: failed to read graph %q; %v", id, sr.err)
+		}
+		if _, ok := graphs[id]; ok {
+			return fmt.Errorf("memory.Load: graph %q appears twice in the snapshot", id)
+		}
+		ids = append(ids, id)
+		graphs[id] = ts
+	}
+	if sr.err != nil {
+		return fmt.Errorf("memory.Load: failed to read snapshot; %v", sr.err)
+	}
+
+	s.rwmu.RLock()
+	for _, id := range ids {
+		if _, ok := s.graphs[id]; ok {
+			s.rwmu.RUnlock()
+			return fmt.Errorf("memory.Load: graph %q already exists", id)
+		}
+	}
+	s.rwmu.RUnlock()
+	for _, id := range ids {
+		g, err := s.NewGraph(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := g.AddTriples(ctx, graphs[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotWriter writes the elements of a snapshot. Once a write fails, the
+// following ones are ignored and err holds the first error.
+type snapshotWriter struct {
+	w    *bufio.Writer
+	strs map[string]uint64
+	buf  [binary.MaxVarintLen64]byte
+	err  error
+}
+
+func (sw *snapshotWriter) write(b []byte) {
+	if sw.err == nil {
+		_, sw.err = sw.w.Write(b)
+	}
+}
+
+func (sw *snapshotWriter) uvarint(v uint64) {
+	sw.write(sw.buf[:binary.PutUvarint(sw.buf[:], v)])
+}
+
+func (sw *snapshotWriter) varint(v int64) {
+	sw.write(sw.buf[:binary.PutVarint(sw.buf[:], v)])
+}
+
+func (sw *snapshotWriter) bytes(b []byte) {
+	sw.uvarint(uint64(len(b)))
+	sw.write(b)
+}
+
+// str writes a 0 followed by the string the first time it is written, and the
+// index of the string plus one afterwards.
+func (sw *snapshotWriter) str(s string) {
+	if i, ok := sw.strs[s]; ok {
+		sw.uvarint(i + 1)
+		return
+	}
+	sw.strs[s] = uint64(len(sw.strs))
+	sw.uvarint(0)
+	sw.bytes([]byte(s))
+}
+
+func (sw *snapshotWriter) node(n *node.Node) {
+	sw.str(n.Type().String())
+	sw.str(n.ID().String())
+}
+
+// predicate writes the ID of the predicate followed by its anchor, if any.
+// Anchors keep their nanoseconds and their zone offset.
+func (sw *snapshotWriter) predicate(p *predicate.Predicate) {
+	sw.str(string(p.ID()))
+	ta, err := p.TimeAnchor()
+	if err != nil {
+		sw.write([]byte{0})
+		return
+	}
+	_, end, err := p.Interval()
+	if err != nil {
+		sw.write([]byte{1})
+		sw.time(*ta)
+		return
+	}
+	sw.write([]byte{2})
+	sw.time(*ta)
+	sw.time(*end)
+}
+
+// time writes the provided time keeping its time zone offset.
+func (sw *snapshotWriter) time(t time.Time) {
+	_, off := t.Zone()
+	sw.varint(t.Unix())
+	sw.uvarint(uint64(t.Nanosecond()))
+	sw.varint(int64(off))
+}
+
+func (sw *snapshotWriter) literal(l *literal.Literal) {
+	if name, text, err := l.Custom(); err == nil {
+		sw.write([]byte{customLiteral})
+		sw.bytes([]byte(name))
+		sw.bytes([]byte(text))
+		return
+	}
+	sw.write([]byte{byte(l.Type())})
+	switch v := l.Interface().(type) {
+	case bool:
+		if v {
+			sw.write([]byte{1})
+		} else {
+			sw.write([]byte{0})
+		}
+	case int64:
+		sw.varint(v)
+	case float64:
+		binary.BigEndian.PutUint64(sw.buf[:8], math.Float64bits(v))
+		sw.write(sw.buf[:8])
+	case string:
+		sw.bytes([]byte(v))
+	case []byte:
+		sw.bytes(v)
+	case *big.Rat:
+		sw.bytes([]byte(v.RatString()))
+	case time.Time:
+		// Dates are kept as the number of days since the Unix epoch.
+		sw.varint(v.Unix() / secondsPerDay)
+	default:
+		if sw.err == nil {
+			sw.err = fmt.Errorf("unknown literal type %v", l.Type())
+		}
+	}
+}
+
+func (sw *snapshotWriter) triple(t *triple.Triple) {
+	sw.node(t.Subject())
+	sw.predicate(t.Predicate())
+	o := t.Object()
+	if n, err := o.Node(); err == nil {
+		sw.write([]byte{nodeTag})
+		sw.node(n)
+	} else if p, err := o.Predicate(); err == nil {
+		sw.write([]byte{predicateTag})
+		sw.predicate(p)
+	} else if l, err := o.Literal(); err == nil {
+		sw.write([]byte{literalTag})
+		sw.literal(l)
+	} else if sw.err == nil {
+		sw.err = fmt.Errorf("unknown object type in triple %s", t)
+	}
+}
+
+// snapshotReader reads the elements of a snapshot. Once a read fails, the
+// following ones return zero values and err holds the first error.
+type snapshotReader struct {
+	r    *bufio.Reader
+	strs []string
+	err  error
+}
+
+func (sr *snapshotReader) fail(err error) {
+	if sr.err == nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		sr.err = err
+	}
+}
+
+func (sr *snapshotReader) byte() byte {
+	if sr.err != nil {
+		return 0
+	}
+	b, err := sr.r.ReadByte()
+	sr.fail(err)
+	return b
+}
+
+func (sr *snapshotReader) uvarint() uint64 {
+	if sr.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(sr.r)
+	sr.fail(err)
+	return v
+}
+
+func (sr *snapshotReader) varint() int64 {
+	if sr.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(sr.r)
+	sr.fail(err)
+	return v
+}
+
+func (sr *snapshotReader) bytes() []byte {
+	n := sr.uvarint()
+	if sr.err != nil {
+		return nil
+	}
+	// Read in bounded chunks, so a corrupted length does not allocate an
+	// arbitrarily large buffer.
+	var b []byte
+	for n > 0 && sr.err == nil {
+		c := n
+		if c > 1<<16 {
+			c = 1 << 16
+		}
+		chunk := make([]byte, c)
+		_, err := io.ReadFull(sr.r, chunk)
+		sr.fail(err)
+		b = append(b, chunk...)
+		n -= c
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b
+}
+
+func (sr *snapshotReader) str() string {
+	i := sr.uvarint()
+	if sr.err != nil {
+		return ""
+	}
+	if i == 0 {
+		s := string(sr.bytes())
+		sr.strs = append(sr.strs, s)
+		return s
+	}
+	if i > uint64(len(sr.strs)) {
+		sr.fail(fmt.Errorf("invalid string reference %d", i))
+		return ""
+	}
+	return sr.strs[i-1]
+}
+
+func (sr *snapshotReader) node() *node.Node {
+	t, id := sr.str(), sr.str()
+	if sr.err != nil {
+		return nil
+	}
+	n, err := node.NewNodeFromStrings(t, id)
+	sr.fail(err)
+	return n
+}
+
+func (sr *snapshotReader) predicate() *predicate.Predicate {
+	id := sr.str()
+	var (
+		p   *predicate.Predicate
+		err error
+	)
+	switch sr.byte() {
+	case 0:
+		p, err = predicate.NewImmutable(id)
+	case 1:
+		p, err = predicate.NewTemporal(id, sr.time())
+	case 2:
+		start, end := sr.time(), sr.time()
+		if sr.err != nil {
+			return nil
+		}
+		p, err = predicate.NewInterval(id, start, end)
+	default:
+		err = fmt.Errorf("invalid anchor flag for predicate %q", id)
+	}
+	if sr.err != nil {
+		return nil
+	}
+	sr.fail(err)
+	return p
+}
+
+// time reads a time written by snapshotWriter.time.
+func (sr *snapshotReader) time() time.Time {
+	sec, nsec, off := sr.varint(), sr.uvarint(), sr.varint()
+	t := time.Unix(sec, int64(nsec)).UTC()
+	if off != 0 {
+		t = t.In(time.FixedZone("", int(off)))
+	}
+	return t
+}
+
+func (sr *snapshotReader) literal() *literal.Literal {
+	tb := sr.byte()
+	if tb == customLiteral {
+		name, text := sr.bytes(), sr.bytes()
+		if sr.err != nil {
+			return nil
+		}
+		l, err := literal.ParseCustom(string(name), string(text))
+		sr.fail(err)
+		return l
+	}
+	t := literal.Type(tb)
+	var v interface{}
+	switch t {
+	case literal.Bool:
+		v = sr.byte() != 0
+	case literal.Int64:
+		v = sr.varint()
+	case literal.Float64:
+		var b [8]byte
+		if sr.err == nil {
+			_, err := io.ReadFull(sr.r, b[:])
+			sr.fail(err)
+		}
+		v = math.Float64frombits(binary.BigEndian.Uint64(b[:]))
+	case literal.Text:
+		v = string(sr.bytes())
+	case literal.Blob:
+		v = sr.bytes()
+	case literal.Decimal:
+		s := sr.bytes()
+		r, ok := new(big.Rat).SetString(string(s))
+		if !ok {
+			sr.fail(fmt.Errorf("invalid decimal literal %q", s))
+		}
+		v = r
+	case literal.Date:
+		v = time.Unix(sr.varint()*secondsPerDay, 0).UTC()
+	default:
+		sr.fail(fmt.Errorf("unknown literal type %d", t))
+	}
+	if sr.err != nil {
+		return nil
+	}
+	l, err := literal.DefaultBuilder().Build(t, v)
+	sr.fail(err)
+	return l
+}
+
+func (sr *snapshotReader) triple() *triple.Triple {
+	s, p := sr.node(), sr.predicate()
+	var o *triple.Object
+	switch sr.byte() {
+	case nodeTag:
+		o = triple.NewNodeObject(sr.node())
+	case predicateTag:
+		o = triple.NewPredicateObject(sr.predicate())
+	case literalTag:
+		o = triple.NewLiteralObject(sr.literal())
+	default:
+		sr.fail(fmt.Errorf("unknown object tag"))
+	}
+	if sr.err != nil {
+		return nil
+	}
+	t, err := triple.New(s, p, o)
+	sr.fail(err)
+	return t
+}
@@ -0,0 +1,184 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	// Registers the hex custom literal type used by the snapshots.
+	_ "github.com/google/badwolf/storage/internal/storagetest"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+func getSnapshotTriples(t *testing.T) []*triple.Triple {
+	ss := []string{
+		"/u<john>\t\"knows\"@[]\t/u<mary>",
+		"/u<john>\t\"knows\"@[]\t/u<peter>",
+		"/u<john>\t\"met\"@[2006-01-02T15:04:05.999999999Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2006-01-02T15:04:05.123-07:00]\t/u<peter>",
+		"/u<john>\t\"employed_by\"@[2006-01-02T15:04:05Z/2007-01-02T15:04:05Z]\t/org<acme>",
+		"/u<john>\t\"parent_of\"@[]\t\"met\"@[2006-01-02T15:04:05Z]",
+		"/u<john>\t\"alive\"@[]\t\"true\"^^type:bool",
+		"/u<john>\t\"age\"@[]\t\"-42\"^^type:int64",
+		"/u<john>\t\"height\"@[]\t\"1.85\"^^type:float64",
+		"/u<john>\t\"name\"@[]\t\"John \\\"Doe\\\"\"^^type:text",
+		"/u<john>\t\"nick\"@[]\t\"\"^^type:text",
+		"/u<john>\t\"photo\"@[]\t\"[1 2 3]\"^^type:blob",
+		"/u<john>\t\"balance\"@[]\t\"-1234.56\"^^type:decimal",
+		"/u<john>\t\"born\"@[]\t\"1969-07-20\"^^type:date",
+		"/u<john>\t\"badge\"@[]\t\"beef\"^^type:hex",
+	}
+	var ts []*triple.Triple
+	for _, s := range ss {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %s with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+// graphContents returns the sorted string representation of all the triples
+// in the graph.
+func graphContents(ctx context.Context, t *testing.T, g storage.Graph) []string {
+	ch, errs := make(chan *triple.Triple, 100), make(chan error, 1)
+	go func() {
+		errs <- g.Triples(ctx, storage.DefaultLookup, ch)
+	}()
+	var res []string
+	for trpl := range ch {
+		res = append(res, trpl.String())
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	ts := getSnapshotTriples(t)
+	graphs := map[string][]*triple.Triple{
+		"?full":  ts,
+		"?half":  ts[:len(ts)/2],
+		"?empty": nil,
+	}
+	for id, gts := range graphs {
+		g, err := s.NewGraph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, gts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.(Snapshotter).Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed with error %v", err)
+	}
+	ns := NewStore()
+	if err := ns.(Snapshotter).Load(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Load failed with error %v", err)
+	}
+	for id, gts := range graphs {
+		og, err := s.Graph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := ns.Graph(ctx, id)
+		if err != nil {
+			t.Fatalf("Load did not restore graph %q; %v", id, err)
+		}
+		want, got := graphContents(ctx, t, og), graphContents(ctx, t, g)
+		if len(got) != len(gts) {
+			t.Errorf("Load restored %d triples in graph %q; want %d", len(got), id, len(gts))
+		}
+		for i := range want {
+			if i >= len(got) || got[i] != want[i] {
+				t.Errorf("Load restored graph %q with %v; want %v", id, got, want)
+				break
+			}
+		}
+		for _, trpl := range gts {
+			if ok, err := g.Exist(ctx, trpl); err != nil || !ok {
+				t.Errorf("Load did not restore triple %s in graph %q; %v", trpl, id, err)
+			}
+		}
+	}
+
+	// Saving the same graphs again produces the same snapshot.
+	var nbuf bytes.Buffer
+	if err := ns.(Snapshotter).Save(ctx, &nbuf); err != nil {
+		t.Fatalf("Save failed with error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), nbuf.Bytes()) {
+		t.Errorf("Save of a loaded store returned a different snapshot")
+	}
+}
+
+func TestSnapshotLoadErrors(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, getSnapshotTriples(t)); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := s.(Snapshotter).Save(ctx, &buf); err != nil {
+		t.Fatalf("Save failed with error %v", err)
+	}
+	snap := buf.Bytes()
+
+	testTable := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", append([]byte("NOTSNAP"), snap[len(snapshotMagic):]...)},
+		{"truncated header", snap[:len(snapshotMagic)]},
+		{"truncated triples", snap[:len(snap)-1]},
+		{"trailing half", snap[:len(snap)/2]},
+	}
+	for _, entry := range testTable {
+		ns := NewStore()
+		if err := ns.(Snapshotter).Load(ctx, bytes.NewReader(entry.data)); err == nil {
+			t.Errorf("Load(%s) should have failed", entry.name)
+		}
+		names := make(chan string, 10)
+		if err := ns.GraphNames(ctx, names); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(names); n != 0 {
+			t.Errorf("Load(%s) failed but created %d graphs", entry.name, n)
+		}
+	}
+
+	// Loading a snapshot with an existing graph fails.
+	if err := s.(Snapshotter).Load(ctx, bytes.NewReader(snap)); err == nil {
+		t.Errorf("Load should fail to restore an already existing graph")
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// anchorEntry is a temporal triple in a time index.
+type anchorEntry struct {
+	anchor time.Time
+	id     string
+	t      *triple.Triple
+}
+
+// before returns true if the entry sorts before the provided anchor and
+// triple ID.
+func (e *anchorEntry) before(ta time.Time, id string) bool {
+	return e.anchor.Before(ta) || e.anchor.Equal(ta) && e.id < id
+}
+
+// timeIndex keeps the triples of a predicate ID. Temporal triples are sorted
+// by time anchor, so the ones within a time window can be found without
+// checking all of them. Immutable triples are kept apart, since lookups always
+// return them, and so are the triples of interval predicates, since any of
+// them may overlap the time window of a lookup.
+type timeIndex struct {
+	immutable map[string]*triple.Triple
+	intervals map[string]*triple.Triple
+
+	// entries are only sorted when needed by a lookup. Lookups hold the read
+	// lock of the graph, so mu serializes sorting among them.
+	mu      sync.Mutex
+	entries []*anchorEntry
+	sorted  bool
+}
+
+// newTimeIndex returns an empty time index.
+func newTimeIndex() *timeIndex {
+	return &timeIndex{
+		immutable: make(map[string]*triple.Triple),
+		intervals: make(map[string]*triple.Triple),
+		sorted:    true,
+	}
+}
+
+// len returns the number of triples in the index.
+func (ti *timeIndex) len() int {
+	return len(ti.immutable) + len(ti.intervals) + len(ti.entries)
+}
+
+// add adds a triple that is not already in the index.
+func (ti *timeIndex) add(id string, t *triple.Triple) {
+	ta, err := t.Predicate().TimeAnchor()
+	if err != nil {
+		ti.immutable[id] = t
+		return
+	}
+	if t.Predicate().IsInterval() {
+		ti.intervals[id] = t
+		return
+	}
+	ti.entries = append(ti.entries, &anchorEntry{anchor: *ta, id: id, t: t})
+	ti.sorted = false
+}
+
+// remove removes a triple from the index, if present.
+func (ti *timeIndex) remove(id string, t *triple.Triple) {
+	ta, err := t.Predicate().TimeAnchor()
+	if err != nil {
+		delete(ti.immutable, id)
+		return
+	}
+	if t.Predicate().IsInterval() {
+		delete(ti.intervals, id)
+		return
+	}
+	ti.sort()
+	i := sort.Search(len(ti.entries), func(i int) bool {
+		return !ti.entries[i].before(*ta, id)
+	})
+	if i < len(ti.entries) && ti.entries[i].id == id {
+		copy(ti.entries[i:], ti.entries[i+1:])
+		ti.entries[len(ti.entries)-1] = nil
+		ti.entries = ti.entries[:len(ti.entries)-1]
+	}
+}
+
+// sort sorts the entries by time anchor if needed.
+func (ti *timeIndex) sort() {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	if ti.sorted {
+		return
+	}
+	sort.Slice(ti.entries, func(i, j int) bool {
+		return ti.entries[i].before(ti.entries[j].anchor, ti.entries[j].id)
+	})
+	ti.sorted = true
+}
+
+// window returns the temporal triples whose anchor is within the provided
+// bounds. Nil bounds are not checked.
+func (ti *timeIndex) window(lower, upper *time.Time) []*anchorEntry {
+	ti.sort()
+	es := ti.entries
+	if lower != nil {
+		es = es[sort.Search(len(es), func(i int) bool {
+			return !es[i].anchor.Before(*lower)
+		}):]
+	}
+	if upper != nil {
+		es = es[:sort.Search(len(es), func(i int) bool {
+			return es[i].anchor.After(*upper)
+		})]
+	}
+	return es
+}
+
+// timeBounds returns the time window of a lookup on the provided predicate.
+// Temporal predicates only match their own anchor. It returns false if the
+// lookup is not bounded in time.
+func timeBounds(lo *storage.LookupOptions, p *predicate.Predicate) (*time.Time, *time.Time, bool) {
+	lower, upper := lo.LowerAnchor, lo.UpperAnchor
+	if ta, err := p.TimeAnchor(); err == nil {
+		if lower == nil || ta.After(*lower) {
+			lower = ta
+		}
+		if upper == nil || ta.Before(*upper) {
+			upper = ta
+		}
+	}
+	return lower, upper, lower != nil || upper != nil
+}
+
+// forEach calls f for each of the provided triples of a lookup on predicate p
+// until f returns an error. If the lookup is bounded in time and the time
+// index of the predicate has fewer candidates than ts, f is called for the
+// immutable and interval triples and the temporal ones within the time window
+// that are also in keep, or all of them if keep is nil. f still needs to
+// check the lookup options.
+func (m *memory) forEach(ts map[string]*triple.Triple, p *predicate.Predicate, pKey string, lo *storage.LookupOptions, keep map[string]*triple.Triple, f func(*triple.Triple) error) error {
+	if ti, ok := m.idxT[pKey]; ok {
+		if lower, upper, ok := timeBounds(lo, p); ok {
+			if es := ti.window(lower, upper); len(es)+len(ti.immutable)+len(ti.intervals) < len(ts) {
+				for _, its := range []map[string]*triple.Triple{ti.immutable, ti.intervals} {
+					for id, t := range its {
+						if _, ok := keep[id]; keep != nil && !ok {
+							continue
+						}
+						if err := f(t); err != nil {
+							return err
+						}
+					}
+				}
+				for _, e := range es {
+					if _, ok := keep[e.id]; keep != nil && !ok {
+						continue
+					}
+					if err := f(e.t); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+	}
+	for _, t := range ts {
+		if err := f(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Expire removes the temporal triples anchored before the provided time. The
+// expired triples are found in the time indices of the predicates, without
+// checking the triples of the graph one by one.
+func (m *memory) Expire(ctx context.Context, before time.Time, predicates []string) (int64, error) {
+	var keys []string
+	for _, id := range predicates {
+		p, err := predicate.NewImmutable(id)
+		if err != nil {
+			return 0, err
+		}
+		keys = append(keys, m.dict.predicateID(p))
+	}
+	m.rwmu.RLock()
+	if len(predicates) == 0 {
+		for k := range m.idxT {
+			keys = append(keys, k)
+		}
+	}
+	var expired []*triple.Triple
+	for _, k := range keys {
+		ti, ok := m.idxT[k]
+		if !ok {
+			continue
+		}
+		for _, e := range ti.window(nil, &before) {
+			if e.anchor.Before(before) {
+				expired = append(expired, e.t)
+			}
+		}
+		for _, t := range ti.intervals {
+			if ta, _ := t.Predicate().TimeAnchor(); ta.Before(before) {
+				expired = append(expired, t)
+			}
+		}
+	}
+	m.rwmu.RUnlock()
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	if err := m.RemoveTriples(ctx, expired); err != nil {
+		return 0, err
+	}
+	return int64(len(expired)), nil
+}
@@ -0,0 +1,318 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// getHourlyTriples returns n triples of the temporal predicate "met" anchored
+// one hour apart from base, plus an immutable "met" triple.
+func getHourlyTriples(t *testing.T, base time.Time, n int) []*triple.Triple {
+	s, o := node.NewBlankNode(), triple.NewNodeObject(node.NewBlankNode())
+	var ts []*triple.Triple
+	for i := 0; i < n; i++ {
+		p, err := predicate.NewTemporal("met", base.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		trpl, err := triple.New(s, p, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, trpl)
+	}
+	p, err := predicate.NewImmutable("met")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trpl, err := triple.New(s, p, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(ts, trpl)
+}
+
+func TestTimeBoundedLookups(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2016, 4, 10, 0, 0, 0, 0, time.UTC)
+	at := func(h int) *time.Time {
+		ta := base.Add(time.Duration(h) * time.Hour)
+		return &ta
+	}
+	ts := getHourlyTriples(t, base, 100)
+	s, o := ts[0].Subject(), ts[0].Object()
+	p, err := predicate.NewImmutable("met")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ap, err := predicate.NewTemporal("met", *at(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	// Removing triples also removes them from the time index.
+	if err := g.RemoveTriples(ctx, ts[50:60]); err != nil {
+		t.Fatal(err)
+	}
+
+	testTable := []struct {
+		p    *predicate.Predicate
+		lo   *storage.LookupOptions
+		want int
+		// visited is the number of triples the lookup checks.
+		visited int
+	}{
+		{p, storage.DefaultLookup, 91, 91},
+		{p, &storage.LookupOptions{LowerAnchor: at(10), UpperAnchor: at(19)}, 11, 11},
+		{p, &storage.LookupOptions{LowerAnchor: at(95)}, 6, 6},
+		{p, &storage.LookupOptions{UpperAnchor: at(4)}, 6, 6},
+		{p, &storage.LookupOptions{LowerAnchor: at(45), UpperAnchor: at(64)}, 11, 11},
+		{p, &storage.LookupOptions{LowerAnchor: at(200)}, 1, 1},
+		{ap, storage.DefaultLookup, 2, 2},
+		{ap, &storage.LookupOptions{LowerAnchor: at(43)}, 1, 1},
+	}
+	for _, entry := range testTable {
+		lookups := map[string]func() (int, error){
+			"Objects": func() (int, error) {
+				c := make(chan *triple.Object, len(ts))
+				err := g.Objects(ctx, s, entry.p, entry.lo, c)
+				return len(c), err
+			},
+			"Subjects": func() (int, error) {
+				c := make(chan *node.Node, len(ts))
+				err := g.Subjects(ctx, entry.p, o, entry.lo, c)
+				return len(c), err
+			},
+			"TriplesForPredicate": func() (int, error) {
+				c := make(chan *triple.Triple, len(ts))
+				err := g.TriplesForPredicate(ctx, entry.p, entry.lo, c)
+				return len(c), err
+			},
+			"TriplesForSubjectAndPredicate": func() (int, error) {
+				c := make(chan *triple.Triple, len(ts))
+				err := g.TriplesForSubjectAndPredicate(ctx, s, entry.p, entry.lo, c)
+				return len(c), err
+			},
+			"TriplesForPredicateAndObject": func() (int, error) {
+				c := make(chan *triple.Triple, len(ts))
+				err := g.TriplesForPredicateAndObject(ctx, entry.p, o, entry.lo, c)
+				return len(c), err
+			},
+		}
+		for name, lookup := range lookups {
+			got, err := lookup()
+			if err != nil {
+				t.Fatalf("%s failed with error %v", name, err)
+			}
+			if got != entry.want {
+				t.Errorf("%s(%s, %s) returned %d elements; want %d", name, entry.p, entry.lo, got, entry.want)
+			}
+		}
+
+		m := g.(*memory)
+		pKey := m.dict.predicateID(entry.p)
+		visited := 0
+		m.forEach(m.idxP[pKey], entry.p, pKey, entry.lo, nil, func(*triple.Triple) error {
+			visited++
+			return nil
+		})
+		if visited != entry.visited {
+			t.Errorf("lookup on %s with options %s checked %d triples; want %d", entry.p, entry.lo, visited, entry.visited)
+		}
+	}
+}
+
+func TestTimeIndexWindow(t *testing.T) {
+	base := time.Date(2016, 4, 10, 0, 0, 0, 0, time.UTC)
+	at := func(h int) *time.Time {
+		ta := base.Add(time.Duration(h) * time.Hour)
+		return &ta
+	}
+	ti := newTimeIndex()
+	ts := getHourlyTriples(t, base, 10)
+	// Add them out of order.
+	for i := len(ts) - 1; i >= 0; i-- {
+		ti.add(UUIDToByteString(ts[i].UUID()), ts[i])
+	}
+	if got, want := ti.len(), len(ts); got != want {
+		t.Errorf("timeIndex.len() = %d; want %d", got, want)
+	}
+	testTable := []struct {
+		lower, upper *time.Time
+		want         []int
+	}{
+		{nil, nil, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{at(3), at(5), []int{3, 4, 5}},
+		{at(8), nil, []int{8, 9}},
+		{nil, at(0), []int{0}},
+		{at(5), at(4), nil},
+		{at(20), nil, nil},
+	}
+	for _, entry := range testTable {
+		es := ti.window(entry.lower, entry.upper)
+		if len(es) != len(entry.want) {
+			t.Errorf("timeIndex.window(%v, %v) returned %d entries; want %d", entry.lower, entry.upper, len(es), len(entry.want))
+			continue
+		}
+		for i, e := range es {
+			if !e.anchor.Equal(*at(entry.want[i])) {
+				t.Errorf("timeIndex.window(%v, %v) returned anchor %v at position %d; want %v", entry.lower, entry.upper, e.anchor, i, at(entry.want[i]))
+			}
+		}
+	}
+	ti.remove(UUIDToByteString(ts[4].UUID()), ts[4])
+	ti.remove(UUIDToByteString(ts[len(ts)-1].UUID()), ts[len(ts)-1])
+	if got := len(ti.window(at(3), at(5))); got != 2 {
+		t.Errorf("timeIndex.window returned %d entries after removing one; want 2", got)
+	}
+	if got, want := ti.len(), len(ts)-2; got != want {
+		t.Errorf("timeIndex.len() = %d after removing two triples; want %d", got, want)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2016, 4, 10, 0, 0, 0, 0, time.UTC)
+	now := base.Add(100 * time.Hour)
+	other, err := predicate.NewTemporal("left", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTable := []struct {
+		name    string
+		graph   func(g storage.Graph) storage.Graph
+		policy  *storage.RetentionPolicy
+		removed int64
+	}{
+		{"native", func(g storage.Graph) storage.Graph { return g }, &storage.RetentionPolicy{MaxAge: 10 * time.Hour}, 91},
+		{"native", func(g storage.Graph) storage.Graph { return g }, &storage.RetentionPolicy{MaxAge: 10 * time.Hour, Predicates: []string{"met"}}, 90},
+		{"native", func(g storage.Graph) storage.Graph { return g }, &storage.RetentionPolicy{MaxAge: 1000 * time.Hour}, 0},
+		// Hiding the Expire method of the graph retrieves the expired triples.
+		{"scan", func(g storage.Graph) storage.Graph { return struct{ storage.Graph }{g} }, &storage.RetentionPolicy{MaxAge: 10 * time.Hour}, 91},
+		{"scan", func(g storage.Graph) storage.Graph { return struct{ storage.Graph }{g} }, &storage.RetentionPolicy{MaxAge: 10 * time.Hour, Predicates: []string{"met"}}, 90},
+		{"scan", func(g storage.Graph) storage.Graph { return struct{ storage.Graph }{g} }, &storage.RetentionPolicy{MaxAge: 1000 * time.Hour}, 0},
+	}
+	for _, entry := range testTable {
+		g, err := NewStore().NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts := getHourlyTriples(t, base, 100)
+		left, err := triple.New(ts[0].Subject(), other, ts[0].Object())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, append(ts, left)); err != nil {
+			t.Fatal(err)
+		}
+		g = entry.graph(g)
+		n, err := storage.Expire(ctx, g, entry.policy, now)
+		if err != nil {
+			t.Fatalf("%s: storage.Expire(%s) failed with error %v", entry.name, entry.policy, err)
+		}
+		if n != entry.removed {
+			t.Errorf("%s: storage.Expire(%s) removed %d triples; want %d", entry.name, entry.policy, n, entry.removed)
+		}
+		if cnt, err := storage.CountTriples(ctx, g); err != nil || cnt != int64(len(ts)+1)-entry.removed {
+			t.Errorf("%s: storage.Expire(%s) left %d triples; want %d", entry.name, entry.policy, cnt, int64(len(ts)+1)-entry.removed)
+		}
+		// Immutable triples and the ones anchored at the cutoff are kept.
+		for _, trpl := range []*triple.Triple{ts[len(ts)-1], ts[90]} {
+			if ok, err := g.Exist(ctx, trpl); err != nil || !ok {
+				t.Errorf("%s: storage.Expire(%s) removed triple %s", entry.name, entry.policy, trpl)
+			}
+		}
+	}
+}
+
+func TestJanitor(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	old := time.Now().Add(-48 * time.Hour)
+	for _, id := range []string{"?short", "?long", "?none"} {
+		g, err := s.NewGraph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, getHourlyTriples(t, old, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j := storage.NewJanitor(s, time.Millisecond)
+	removed := make(map[string]int64)
+	j.OnExpire = func(id string, n int64, err error) {
+		if err != nil {
+			t.Errorf("janitor failed to expire graph %q; %v", id, err)
+		}
+		removed[id] += n
+	}
+	j.SetPolicy("?short", &storage.RetentionPolicy{MaxAge: time.Hour})
+	j.SetPolicy("?long", &storage.RetentionPolicy{MaxAge: 24 * 365 * time.Hour})
+	j.SetPolicy("?missing", &storage.RetentionPolicy{MaxAge: time.Hour})
+	j.SetPolicy("?none", &storage.RetentionPolicy{MaxAge: time.Hour})
+	j.SetPolicy("?none", nil)
+	if p := j.Policy("?none"); p != nil {
+		t.Errorf("j.Policy(?none) = %s after removing it; want nil", p)
+	}
+	if err := j.Enforce(ctx); err != nil {
+		t.Fatalf("j.Enforce failed with error %v", err)
+	}
+	want := map[string]int64{"?short": 10, "?long": 0}
+	if len(removed) != len(want) || removed["?short"] != want["?short"] || removed["?long"] != want["?long"] {
+		t.Errorf("j.Enforce removed %v; want %v", removed, want)
+	}
+
+	// Running the janitor keeps enforcing the policies until it is stopped.
+	g, err := s.Graph(ctx, "?short")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, getHourlyTriples(t, old, 5)); err != nil {
+		t.Fatal(err)
+	}
+	rctx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- j.Run(rctx)
+	}()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if cnt, err := storage.CountTriples(ctx, g); err == nil && cnt == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("j.Run failed to expire the new triples")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("j.Run returned %v after being stopped; want %v", err, context.Canceled)
+	}
+}
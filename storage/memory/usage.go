@@ -0,0 +1,203 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// Approximate sizes in bytes of the structures kept by a graph, used to
+// estimate its memory usage. They are in the ballpark of what a 64 bit
+// runtime uses and are not meant to be exact.
+const (
+	// mapEntryBytes is the overhead of a map entry with a string key, not
+	// counting the bytes of the key itself.
+	mapEntryBytes = 48
+	// mapBytes is the overhead of an empty map.
+	mapBytes = 48
+	// tripleBytes is the size of a triple, which only points to its values.
+	tripleBytes = 32
+	// predicateBytes is the size of a temporal predicate, whose ID is shared
+	// with the interned one.
+	predicateBytes = 48
+	// anchorEntryBytes is the size of an entry of a time index.
+	anchorEntryBytes = 64
+	// dictEntryBytes is the size of a dictionary entry and its handle, not
+	// counting the value.
+	dictEntryBytes = 96
+	// valueBytes is the overhead of an interned value, not counting the bytes
+	// of its strings.
+	valueBytes = 64
+)
+
+// tripleBytes returns an approximation of the memory used to keep the triple
+// with the provided keys in the graph and its indices. The entries of the
+// indices shared by several triples are not counted.
+func (m *memory) tripleBytes(k *tripleKeys) int64 {
+	entry := int64(mapEntryBytes + len(k.id))
+	// The triple is kept in the triple index, and in the subject, predicate,
+	// and object indices.
+	n := int64(4)
+	if m.idxSP != nil {
+		n++
+	}
+	if m.idxPO != nil {
+		n++
+	}
+	if m.idxSO != nil {
+		n++
+	}
+	res := n*entry + tripleBytes
+	if k.t.Predicate().Type() == predicate.Temporal {
+		return res + predicateBytes + anchorEntryBytes
+	}
+	return res + entry
+}
+
+// memoryBytes returns an approximation of the memory used by the graph. The
+// dictionary is counted in full even if it is shared with snapshots. It must
+// be called with the read lock of m held.
+func (m *memory) memoryBytes() int64 {
+	return mapBytes + m.bytes + m.dict.memoryBytes()
+}
+
+// usage returns the number of triples and the approximate memory the graph
+// would use after adding the provided triples.
+func (m *memory) usage(ts []*triple.Triple) (int64, int64, error) {
+	ks := make([]*tripleKeys, len(ts))
+	for i, t := range ts {
+		k, err := m.dict.keys(t)
+		if err != nil {
+			return 0, 0, err
+		}
+		ks[i] = k
+	}
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	added := m.missing(ks)
+	bytes := m.memoryBytes()
+	for _, k := range added {
+		bytes += m.tripleBytes(k)
+	}
+	return int64(len(m.idx) + len(added)), bytes, nil
+}
+
+// entryBytes returns an approximation of the memory used by the provided
+// dictionary entry, including its key.
+func entryBytes(key string, e *dictEntry) int64 {
+	res := int64(dictEntryBytes + valueBytes + len(key))
+	switch {
+	case e.n != nil:
+		res += int64(len(e.n.Type().String()) + len(e.n.ID().String()))
+	case e.p != nil:
+		res += int64(len(e.p.ID()))
+	case e.o != nil:
+		res += int64(len(e.o.String()))
+	}
+	return res
+}
+
+// memoryBytes returns an approximation of the memory used by the dictionary.
+func (d *dictionary) memoryBytes() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return mapBytes + d.bytes
+}
+
+// graphQuota keeps the quota of a graph. It is shared by the graph and its
+// transactions, which check it when adding triples.
+type graphQuota struct {
+	mu sync.RWMutex
+	q  storage.GraphQuota
+	// wmu serializes the writes to sharded graphs while they are limited, so
+	// the usage of all the shards does not change while checking it.
+	wmu sync.Mutex
+	// literals is the literal size limit of the store of the graph.
+	literals *literalLimit
+}
+
+// get returns the current quota. Nil quotas do not limit anything.
+func (q *graphQuota) get() storage.GraphQuota {
+	if q == nil {
+		return storage.GraphQuota{}
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.q
+}
+
+// set replaces the quota.
+func (q *graphQuota) set(gq *storage.GraphQuota) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.q = storage.GraphQuota{}
+	if gq != nil {
+		q.q = *gq
+	}
+}
+
+// limited returns true if the quota limits anything.
+func (q *graphQuota) limited() bool {
+	gq := q.get()
+	return gq.MaxTriples > 0 || gq.MaxMemoryBytes > 0
+}
+
+// checkLiterals returns an error if any of the triples has a literal bigger
+// than the literal size limit of the store of the graph with the provided ID.
+func (q *graphQuota) checkLiterals(id string, ts []*triple.Triple) error {
+	if q == nil {
+		return nil
+	}
+	return storage.CheckLiteralLimit("graph "+strconv.Quote(id), q.literals.get(), ts)
+}
+
+// literalLimit keeps the literal size limit of a store. It is shared by the
+// quotas of all the graphs of the store.
+type literalLimit struct {
+	max int64
+}
+
+// get returns the maximum size of text and blob literals, or zero if they
+// are not limited.
+func (l *literalLimit) get() int {
+	if l == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&l.max))
+}
+
+// set replaces the maximum size of text and blob literals.
+func (l *literalLimit) set(max int) {
+	atomic.StoreInt64(&l.max, int64(max))
+}
+
+// check returns an error if the graph with the provided ID would exceed the
+// quota with the provided number of triples and approximate memory.
+func (q *graphQuota) check(id string, triples, bytes int64) error {
+	gq := q.get()
+	if gq.MaxTriples > 0 && triples > gq.MaxTriples {
+		return &storage.ErrQuotaExceeded{Scope: "graph " + strconv.Quote(id), Resource: "triples", Limit: gq.MaxTriples, Requested: triples}
+	}
+	if gq.MaxMemoryBytes > 0 && bytes > gq.MaxMemoryBytes {
+		return &storage.ErrQuotaExceeded{Scope: "graph " + strconv.Quote(id), Resource: "memory bytes", Limit: gq.MaxMemoryBytes, Requested: bytes}
+	}
+	return nil
+}
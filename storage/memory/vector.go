@@ -0,0 +1,363 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+const (
+	// lshTables is the number of hash tables of a vector index. Each table
+	// hashes the vectors with its own random hyperplanes.
+	lshTables = 8
+	// lshBits is the number of hyperplanes of each hash table, and so the
+	// number of bits of their hashes.
+	lshBits = 12
+	// exactSearchSize is the number of vectors up to which searches compare
+	// the query with all the vectors instead of only the ones hashed to the
+	// same buckets.
+	exactSearchSize = 1024
+)
+
+// vectorIndexes keeps the vector indices of the predicate IDs of a graph. The
+// indices are rebuilt lazily by the first search after the triples of the
+// graph change, and are shared with the snapshots of the graph. Searches hold
+// the read lock of the graph before locking the indices.
+type vectorIndexes struct {
+	mu sync.Mutex
+	// ids contains the indexed predicate IDs. Their index is nil until a
+	// search builds it.
+	ids map[string]*vectorIndex
+}
+
+// newVectorIndexes returns an empty set of vector indices.
+func newVectorIndexes() *vectorIndexes {
+	return &vectorIndexes{ids: make(map[string]*vectorIndex)}
+}
+
+// vectorIndex indexes the distinct vectors of a predicate ID, grouped by
+// their number of dimensions, as of an epoch of the graph.
+type vectorIndex struct {
+	epoch  uint64
+	byDims map[int]*lshIndex
+}
+
+// vectorEntry is an indexed vector, normalized to unit length, and the object
+// holding it.
+type vectorEntry struct {
+	v []float64
+	o *triple.Object
+}
+
+// lshIndex finds the vectors with the same number of dimensions most similar
+// to a query. It uses locality sensitive hashing: each hash table hashes the
+// vectors with the side of a set of random hyperplanes they fall in, so
+// vectors pointing in close directions are likely to share a bucket in some
+// of the tables.
+type lshIndex struct {
+	entries []*vectorEntry
+	planes  [lshTables][lshBits][]float64
+	buckets [lshTables]map[uint64][]int
+}
+
+// normalize returns the provided vector scaled to unit length. It returns
+// false for vectors whose components are all zero.
+func normalize(v []float64) ([]float64, bool) {
+	var n float64
+	for _, f := range v {
+		n += f * f
+	}
+	if n == 0 {
+		return nil, false
+	}
+	n = math.Sqrt(n)
+	res := make([]float64, len(v))
+	for i, f := range v {
+		res[i] = f / n
+	}
+	return res, true
+}
+
+// dot returns the dot product of two vectors with the same dimensions.
+func dot(a, b []float64) float64 {
+	var d float64
+	for i := range a {
+		d += a[i] * b[i]
+	}
+	return d
+}
+
+// newLSHIndex returns an index of the provided normalized vectors, which have
+// the provided number of dimensions. The hyperplanes only depend on the
+// number of dimensions, so indices are reproducible.
+func newLSHIndex(dims int, es []*vectorEntry) *lshIndex {
+	idx := &lshIndex{entries: es}
+	rnd := rand.New(rand.NewSource(int64(dims)))
+	for t := range idx.planes {
+		for b := range idx.planes[t] {
+			p := make([]float64, dims)
+			for i := range p {
+				p[i] = rnd.NormFloat64()
+			}
+			idx.planes[t][b] = p
+		}
+		idx.buckets[t] = make(map[uint64][]int)
+		for i, e := range es {
+			h := idx.hash(t, e.v)
+			idx.buckets[t][h] = append(idx.buckets[t][h], i)
+		}
+	}
+	return idx
+}
+
+// hash returns the hash of the vector in the provided table.
+func (idx *lshIndex) hash(t int, v []float64) uint64 {
+	var h uint64
+	for b, p := range idx.planes[t] {
+		if dot(p, v) >= 0 {
+			h |= 1 << uint(b)
+		}
+	}
+	return h
+}
+
+// candidates returns the positions of the entries that share a bucket with
+// the query, or whose bucket only differs in one bit, in any of the tables.
+func (idx *lshIndex) candidates(q []float64) map[int]bool {
+	res := make(map[int]bool)
+	for t := range idx.buckets {
+		h := idx.hash(t, q)
+		for b := -1; b < lshBits; b++ {
+			ph := h
+			if b >= 0 {
+				ph ^= 1 << uint(b)
+			}
+			for _, i := range idx.buckets[t][ph] {
+				res[i] = true
+			}
+		}
+	}
+	return res
+}
+
+// nearest returns the entries most similar to the provided normalized query.
+// Small indices and queries with too few candidates are solved comparing the
+// query with all the entries.
+func (idx *lshIndex) nearest(q []float64, k int) []*vectorEntry {
+	es := idx.entries
+	if len(es) > exactSearchSize {
+		if cs := idx.candidates(q); len(cs) >= k {
+			es = make([]*vectorEntry, 0, len(cs))
+			for i := range cs {
+				es = append(es, idx.entries[i])
+			}
+		}
+	}
+	return rankVectors(q, es, k)
+}
+
+// rankVectors returns up to k of the provided entries sorted from the most to
+// the least similar to the normalized query. Ties are sorted by object so
+// results are stable.
+func rankVectors(q []float64, es []*vectorEntry, k int) []*vectorEntry {
+	sims := make(map[*vectorEntry]float64, len(es))
+	for _, e := range es {
+		sims[e] = dot(q, e.v)
+	}
+	res := append([]*vectorEntry(nil), es...)
+	sort.Slice(res, func(i, j int) bool {
+		si, sj := sims[res[i]], sims[res[j]]
+		if si != sj {
+			return si > sj
+		}
+		return res[i].o.String() < res[j].o.String()
+	})
+	if len(res) > k {
+		res = res[:k]
+	}
+	return res
+}
+
+// vectorEntries returns the distinct vectors of the triples whose predicate
+// has the provided ID, grouped by number of dimensions. It must be called with
+// the read lock of m held.
+func (m *memory) vectorEntries(id string) map[int][]*vectorEntry {
+	res := make(map[int][]*vectorEntry)
+	seen := make(map[string]bool)
+	for _, ts := range m.idxP {
+		for _, t := range ts {
+			if string(t.Predicate().ID()) != id {
+				break
+			}
+			o := t.Object()
+			l, err := o.Literal()
+			if err != nil || l.Type() != literal.VectorType {
+				continue
+			}
+			k := o.UUID().String()
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			v, _ := l.Vector()
+			if nv, ok := normalize(v); ok {
+				res[len(nv)] = append(res[len(nv)], &vectorEntry{v: nv, o: o})
+			}
+		}
+	}
+	return res
+}
+
+// vectorIndex returns the index of the vectors of the provided predicate ID as
+// of the current epoch of the graph, building it if needed. It must be called
+// with the read lock of m held.
+func (m *memory) vectorIndex(id string) (*vectorIndex, error) {
+	m.vectors.mu.Lock()
+	defer m.vectors.mu.Unlock()
+	vi, ok := m.vectors.ids[id]
+	if !ok {
+		return nil, storage.ErrNoVectorIndex
+	}
+	if vi != nil && vi.epoch == m.epoch {
+		return vi, nil
+	}
+	vi = &vectorIndex{epoch: m.epoch, byDims: make(map[int]*lshIndex)}
+	for dims, es := range m.vectorEntries(id) {
+		vi.byDims[dims] = newLSHIndex(dims, es)
+	}
+	m.vectors.ids[id] = vi
+	return vi, nil
+}
+
+// IndexVectors indexes the vectors of the triples whose predicate has the
+// provided ID. The index is built right away, and rebuilt by the first search
+// after the triples of the graph change.
+func (m *memory) IndexVectors(ctx context.Context, id string) error {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	m.vectors.mu.Lock()
+	if _, ok := m.vectors.ids[id]; !ok {
+		m.vectors.ids[id] = nil
+	}
+	m.vectors.mu.Unlock()
+	_, err := m.vectorIndex(id)
+	return err
+}
+
+// DropVectorIndex removes the index of the vectors of the triples whose
+// predicate has the provided ID.
+func (m *memory) DropVectorIndex(ctx context.Context, id string) error {
+	m.vectors.mu.Lock()
+	defer m.vectors.mu.Unlock()
+	delete(m.vectors.ids, id)
+	return nil
+}
+
+// nearestEntries returns the indexed vectors of the predicate ID most similar
+// to the normalized query.
+func (m *memory) nearestEntries(id string, q []float64, k int) ([]*vectorEntry, error) {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	vi, err := m.vectorIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	idx, ok := vi.byDims[len(q)]
+	if !ok {
+		return nil, nil
+	}
+	return idx.nearest(q, k), nil
+}
+
+// NearestObjects returns up to k distinct vector literal objects of the
+// triples whose predicate has the provided ID, ordered from the most to the
+// least similar to the provided vector. Indices with more than
+// exactSearchSize vectors are searched approximately.
+func (m *memory) NearestObjects(ctx context.Context, id string, q []float64, k int) ([]*triple.Object, error) {
+	nq, ok := normalize(q)
+	if !ok {
+		return nil, literal.ErrZeroVector
+	}
+	es, err := m.nearestEntries(id, nq, k)
+	if err != nil {
+		return nil, err
+	}
+	return entryObjects(es), nil
+}
+
+// entryObjects returns the objects of the provided entries.
+func entryObjects(es []*vectorEntry) []*triple.Object {
+	var res []*triple.Object
+	for _, e := range es {
+		res = append(res, e.o)
+	}
+	return res
+}
+
+// IndexVectors indexes the vectors of the predicate ID in all the shards.
+func (g *shardedMemory) IndexVectors(ctx context.Context, id string) error {
+	for _, m := range g.shards {
+		if err := m.IndexVectors(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropVectorIndex removes the index of the vectors of the predicate ID from
+// all the shards.
+func (g *shardedMemory) DropVectorIndex(ctx context.Context, id string) error {
+	for _, m := range g.shards {
+		if err := m.DropVectorIndex(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NearestObjects searches the vectors of the predicate ID in all the shards
+// and returns the k most similar among the ones found. The same vector may be
+// held by triples in different shards, so they are deduplicated.
+func (g *shardedMemory) NearestObjects(ctx context.Context, id string, q []float64, k int) ([]*triple.Object, error) {
+	nq, ok := normalize(q)
+	if !ok {
+		return nil, literal.ErrZeroVector
+	}
+	var (
+		es   []*vectorEntry
+		seen = make(map[string]bool)
+	)
+	for _, m := range g.shards {
+		ses, err := m.nearestEntries(id, nq, k)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range ses {
+			if u := e.o.UUID().String(); !seen[u] {
+				seen[u] = true
+				es = append(es, e)
+			}
+		}
+	}
+	return entryObjects(rankVectors(nq, es, k)), nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// vectorTriple returns a triple binding the provided vector to the subject
+// using the "embedding" predicate.
+func vectorTriple(t *testing.T, s string, v []float64) *triple.Triple {
+	t.Helper()
+	sn, err := node.Parse("/item<" + s + ">")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := predicate.NewImmutable("embedding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := literal.DefaultBuilder().Build(literal.VectorType, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trpl, err := triple.New(sn, p, triple.NewLiteralObject(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trpl
+}
+
+// vectorsOf returns the vectors of the provided objects.
+func vectorsOf(t *testing.T, os []*triple.Object) [][]float64 {
+	t.Helper()
+	var res [][]float64
+	for _, o := range os {
+		l, err := o.Literal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := l.Vector()
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+func TestVectorIndex(t *testing.T) {
+	ctx := context.Background()
+	for _, s := range []storage.Store{NewStore(), NewShardedStore(4, AllIndexes)} {
+		g, err := s.NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts := []*triple.Triple{
+			vectorTriple(t, "a", []float64{1, 0}),
+			vectorTriple(t, "b", []float64{1, 1}),
+			vectorTriple(t, "c", []float64{0, 1}),
+			vectorTriple(t, "d", []float64{-1, 0}),
+			// The same vector bound to another subject is only returned once.
+			vectorTriple(t, "e", []float64{1, 1}),
+			vectorTriple(t, "f", []float64{1, 2, 3}),
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := storage.NearestObjects(ctx, g, "embedding", []float64{1, 0}, 2); err != storage.ErrNoVectorIndex {
+			t.Errorf("storage.NearestObjects returned error %v before indexing the predicate; want storage.ErrNoVectorIndex", err)
+		}
+		if err := storage.IndexVectors(ctx, g, "embedding"); err != nil {
+			t.Fatalf("storage.IndexVectors failed with error %v", err)
+		}
+		os, err := storage.NearestObjects(ctx, g, "embedding", []float64{2, 0.1}, 3)
+		if err != nil {
+			t.Fatalf("storage.NearestObjects failed with error %v", err)
+		}
+		if got, want := vectorsOf(t, os), [][]float64{{1, 0}, {1, 1}, {0, 1}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("storage.NearestObjects returned %v; want %v", got, want)
+		}
+		if _, err := storage.NearestObjects(ctx, g, "embedding", []float64{0, 0}, 3); err != literal.ErrZeroVector {
+			t.Errorf("storage.NearestObjects returned error %v for a zero vector; want literal.ErrZeroVector", err)
+		}
+
+		// Snapshots keep searching the vectors they were taken with.
+		snap, err := storage.Snapshot(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, []*triple.Triple{vectorTriple(t, "g", []float64{1, 0.01})}); err != nil {
+			t.Fatal(err)
+		}
+		os, err = storage.NearestObjects(ctx, g, "embedding", []float64{1, 0}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := vectorsOf(t, os), [][]float64{{1, 0}, {1, 0.01}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("storage.NearestObjects returned %v after adding a triple; want %v", got, want)
+		}
+		os, err = storage.NearestObjects(ctx, snap, "embedding", []float64{1, 0}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := vectorsOf(t, os), [][]float64{{1, 0}, {1, 1}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("storage.NearestObjects returned %v for the snapshot; want %v", got, want)
+		}
+		snap.Release(ctx)
+
+		if err := storage.DropVectorIndex(ctx, g, "embedding"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := storage.NearestObjects(ctx, g, "embedding", []float64{1, 0}, 2); err != storage.ErrNoVectorIndex {
+			t.Errorf("storage.NearestObjects returned error %v after dropping the index; want storage.ErrNoVectorIndex", err)
+		}
+	}
+}
+
+func TestVectorIndexApproximateSearch(t *testing.T) {
+	ctx := context.Background()
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	var (
+		ts []*triple.Triple
+		vs [][]float64
+	)
+	for i := 0; i < 3*exactSearchSize; i++ {
+		v := make([]float64, 16)
+		for j := range v {
+			v[j] = rnd.NormFloat64()
+		}
+		vs = append(vs, v)
+		ts = append(ts, vectorTriple(t, fmt.Sprintf("%d", i), v))
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.IndexVectors(ctx, g, "embedding"); err != nil {
+		t.Fatal(err)
+	}
+	// Indexed vectors always share their buckets, so they are always found.
+	for _, v := range vs[:100] {
+		os, err := storage.NearestObjects(ctx, g, "embedding", v, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := vectorsOf(t, os); len(got) != 1 || !reflect.DeepEqual(got[0], v) {
+			t.Errorf("storage.NearestObjects(%v) returned %v; want the indexed vector", v, got)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package namespace partitions the graphs of a store into namespaces, so a
+// single store can host the graphs of many independent tenants.
+//
+// The graphs of a namespace are kept in the underlying store using IDs of the
+// form "namespace/graph". Each namespace is accessed through its own store,
+// which only sees the graphs of the namespace and uses their IDs without the
+// namespace prefix, so tenants cannot reach the graphs of other namespaces.
+package namespace
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// Separator separates the namespace from the graph ID in the IDs of the
+// underlying store.
+const Separator = "/"
+
+// ID returns the ID used in the underlying store for the graph with the
+// provided ID in the namespace.
+func ID(ns, id string) string {
+	return ns + Separator + id
+}
+
+// Split returns the namespace and the graph ID of the provided ID of the
+// underlying store. It returns false if the ID does not belong to a namespace.
+func Split(id string) (string, string, bool) {
+	i := strings.Index(id, Separator)
+	if i <= 0 {
+		return "", "", false
+	}
+	return id[:i], id[i+len(Separator):], true
+}
+
+// validate returns an error if the provided name cannot be used as a
+// namespace.
+func validate(ns string) error {
+	if ns == "" || strings.Contains(ns, Separator) {
+		return fmt.Errorf("namespace: invalid namespace %q; namespaces cannot be empty or contain %q", ns, Separator)
+	}
+	return nil
+}
+
+// Quota limits the resources used by a namespace. Zero values are not
+// limited.
+type Quota struct {
+	// MaxGraphs is the maximum number of graphs in the namespace.
+	MaxGraphs int64
+
+	// MaxTriples is the maximum number of triples in all the graphs of the
+	// namespace.
+	MaxTriples int64
+}
+
+// Manager hosts namespaces on a shared store and keeps their quotas.
+type Manager struct {
+	s storage.Store
+
+	mu     sync.Mutex
+	quotas map[string]*Quota
+	// locks serialize the changes to a namespace that check its quota.
+	locks map[string]*sync.Mutex
+}
+
+// New returns a manager of the namespaces of the provided store.
+func New(s storage.Store) *Manager {
+	return &Manager{
+		s:      s,
+		quotas: make(map[string]*Quota),
+		locks:  make(map[string]*sync.Mutex),
+	}
+}
+
+// SetQuota sets the quota of the provided namespace. A nil quota removes the
+// limits of the namespace. Quotas are only checked when changing the
+// namespace; namespaces already over their new quota are not changed.
+func (m *Manager) SetQuota(ns string, q *Quota) error {
+	if err := validate(ns); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if q == nil {
+		delete(m.quotas, ns)
+		return nil
+	}
+	cq := *q
+	m.quotas[ns] = &cq
+	return nil
+}
+
+// quota returns the quota of the namespace and the lock serializing the
+// changes that check it. The quota is nil if the namespace is not limited.
+func (m *Manager) quota(ns string) (*Quota, *sync.Mutex) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.locks[ns]
+	if !ok {
+		l = &sync.Mutex{}
+		m.locks[ns] = l
+	}
+	return m.quotas[ns], l
+}
+
+// Namespace returns the store holding the graphs of the provided namespace.
+// Namespaces do not need to be created; a namespace exists as long as it has
+// graphs.
+func (m *Manager) Namespace(ns string) (storage.Store, error) {
+	if err := validate(ns); err != nil {
+		return nil, err
+	}
+	return &store{m: m, ns: ns}, nil
+}
+
+// Namespaces returns the sorted list of namespaces with at least one graph.
+func (m *Manager) Namespaces(ctx context.Context) ([]string, error) {
+	ids, err := graphNames(ctx, m.s)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var res []string
+	for _, id := range ids {
+		if ns, _, ok := Split(id); ok && !seen[ns] {
+			seen[ns] = true
+			res = append(res, ns)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// graphNames returns the IDs of all the graphs in the provided store.
+func graphNames(ctx context.Context, s storage.Store) ([]string, error) {
+	errs, names := make(chan error, 1), make(chan string)
+	go func() {
+		errs <- s.GraphNames(ctx, names)
+	}()
+	var ids []string
+	for id := range names {
+		ids = append(ids, id)
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// store is the view of a single namespace of the underlying store.
+type store struct {
+	m  *Manager
+	ns string
+}
+
+// Name returns the ID of the backend being used.
+func (s *store) Name(ctx context.Context) string {
+	return s.m.s.Name(ctx)
+}
+
+// Version returns the version of the driver implementation.
+func (s *store) Version(ctx context.Context) string {
+	return s.m.s.Version(ctx)
+}
+
+// scope returns the scope of the quota errors of the namespace.
+func (s *store) scope() string {
+	return "namespace " + strconv.Quote(s.ns)
+}
+
+// graphs returns the IDs of the graphs in the namespace, without the namespace
+// prefix.
+func (s *store) graphs(ctx context.Context) ([]string, error) {
+	ids, err := graphNames(ctx, s.m.s)
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, id := range ids {
+		if ns, gid, ok := Split(id); ok && ns == s.ns {
+			res = append(res, gid)
+		}
+	}
+	return res, nil
+}
+
+// NewGraph creates a new graph in the namespace. It fails with
+// *storage.ErrQuotaExceeded if the namespace already has as many graphs as
+// its quota allows.
+func (s *store) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	q, l := s.m.quota(s.ns)
+	if q != nil && q.MaxGraphs > 0 {
+		l.Lock()
+		defer l.Unlock()
+		ids, err := s.graphs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if n := int64(len(ids)) + 1; n > q.MaxGraphs {
+			return nil, &storage.ErrQuotaExceeded{Scope: s.scope(), Resource: "graphs", Limit: q.MaxGraphs, Requested: n}
+		}
+	}
+	g, err := s.m.s.NewGraph(ctx, ID(s.ns, id))
+	if err != nil {
+		return nil, err
+	}
+	return &graph{Graph: g, id: id, s: s}, nil
+}
+
+// Graph returns an existing graph of the namespace.
+func (s *store) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	g, err := s.m.s.Graph(ctx, ID(s.ns, id))
+	if err != nil {
+		return nil, err
+	}
+	return &graph{Graph: g, id: id, s: s}, nil
+}
+
+// DeleteGraph deletes an existing graph of the namespace.
+func (s *store) DeleteGraph(ctx context.Context, id string) error {
+	return s.m.s.DeleteGraph(ctx, ID(s.ns, id))
+}
+
+// GraphNames returns the IDs of the graphs in the namespace.
+func (s *store) GraphNames(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(names)
+	ids, err := s.graphs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case names <- id:
+		}
+	}
+	return nil
+}
+
+// GraphMetadata returns the metadata of the graph with the provided ID.
+func (s *store) GraphMetadata(ctx context.Context, id string) (*storage.GraphMetadata, error) {
+	return storage.GetGraphMetadata(ctx, s.m.s, ID(s.ns, id))
+}
+
+// SetGraphMetadata replaces the description and labels of the graph with the
+// provided ID.
+func (s *store) SetGraphMetadata(ctx context.Context, id string, md *storage.GraphMetadata) error {
+	return storage.SetGraphMetadata(ctx, s.m.s, ID(s.ns, id), md)
+}
+
+// triples returns the number of triples in all the graphs of the namespace.
+func (s *store) triples(ctx context.Context) (int64, error) {
+	ids, err := s.graphs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var res int64
+	for _, id := range ids {
+		g, err := s.m.s.Graph(ctx, ID(s.ns, id))
+		if err != nil {
+			// The graph was deleted after listing it.
+			continue
+		}
+		n, err := storage.CountTriples(ctx, g)
+		if err != nil {
+			return 0, err
+		}
+		res += n
+	}
+	return res, nil
+}
+
+// graph is a graph of a namespace. Lookups go straight to the graph of the
+// underlying store. Transactions and bulk loads of the underlying graph are
+// not exposed, since they would not check the quota of the namespace.
+type graph struct {
+	storage.Graph
+	id string
+	s  *store
+}
+
+// ID returns the ID of the graph in its namespace.
+func (g *graph) ID(ctx context.Context) string {
+	return g.id
+}
+
+// AddTriples adds the triples to the graph. If the namespace limits the number
+// of triples, it fails with *storage.ErrQuotaExceeded without adding any
+// triple if the triples not already in the graph would exceed the quota.
+func (g *graph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	q, l := g.s.m.quota(g.s.ns)
+	if q == nil || q.MaxTriples <= 0 {
+		return g.Graph.AddTriples(ctx, ts)
+	}
+	l.Lock()
+	defer l.Unlock()
+	used, err := g.s.triples(ctx)
+	if err != nil {
+		return err
+	}
+	if used+int64(len(ts)) > q.MaxTriples {
+		// Triples already in the graph do not count against the quota.
+		added, seen := int64(0), make(map[string]bool)
+		for _, t := range ts {
+			k := t.UUID().String()
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			ok, err := g.Graph.Exist(ctx, t)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				added++
+			}
+		}
+		if used+added > q.MaxTriples {
+			return &storage.ErrQuotaExceeded{Scope: g.s.scope(), Resource: "triples", Limit: q.MaxTriples, Requested: used + added}
+		}
+	}
+	return g.Graph.AddTriples(ctx, ts)
+}
+
+// CountTriples returns the number of triples in the graph.
+func (g *graph) CountTriples(ctx context.Context) (int64, error) {
+	return storage.CountTriples(ctx, g.Graph)
+}
+
+// Statistics returns the statistics of the graph.
+func (g *graph) Statistics(ctx context.Context) (*storage.GraphStatistics, error) {
+	return storage.Statistics(ctx, g.Graph)
+}
+
+// Epoch returns the epoch of the graph.
+func (g *graph) Epoch(ctx context.Context) (uint64, error) {
+	return storage.Epoch(ctx, g.Graph)
+}
+
+// PredicateIDs returns the sorted list of unique predicate IDs used in the
+// graph.
+func (g *graph) PredicateIDs(ctx context.Context) ([]string, error) {
+	return storage.PredicateIDs(ctx, g.Graph)
+}
+
+// Expire removes the temporal triples of the graph anchored before the
+// provided time.
+func (g *graph) Expire(ctx context.Context, before time.Time, predicates []string) (int64, error) {
+	return storage.Expire(ctx, g.Graph, &storage.RetentionPolicy{Predicates: predicates}, before)
+}
+
+// Compact compacts the graph.
+func (g *graph) Compact(ctx context.Context) error {
+	return storage.Compact(ctx, g.Graph)
+}
+
+// Watch returns the changes done to the graph.
+func (g *graph) Watch(ctx context.Context) (<-chan *storage.Change, error) {
+	return storage.Watch(ctx, g.Graph)
+}
+
+// Snapshot returns a snapshot of the graph.
+func (g *graph) Snapshot(ctx context.Context) (storage.GraphSnapshot, error) {
+	snap, err := storage.Snapshot(ctx, g.Graph)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot{GraphSnapshot: snap, id: g.id}, nil
+}
+
+// snapshot is a snapshot of a graph of a namespace.
+type snapshot struct {
+	storage.GraphSnapshot
+	id string
+}
+
+// ID returns the ID of the graph in its namespace.
+func (s *snapshot) ID(ctx context.Context) string {
+	return s.id
+}
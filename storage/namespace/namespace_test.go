@@ -0,0 +1,233 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+func getTestTriples(t *testing.T, n int) []*triple.Triple {
+	var ts []*triple.Triple
+	for i := 0; i < n; i++ {
+		trpl, err := triple.Parse(fmt.Sprintf("/u<s%d>\t\"knows\"@[]\t/u<o%d>", i, i), literal.DefaultBuilder())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+func sortedNames(t *testing.T, s storage.Store) []string {
+	ids, err := graphNames(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestSplit(t *testing.T) {
+	testTable := []struct {
+		id     string
+		ns, gn string
+		ok     bool
+	}{
+		{"acme/?family", "acme", "?family", true},
+		{"acme/?a/b", "acme", "?a/b", true},
+		{"?family", "", "", false},
+		{"/?family", "", "", false},
+	}
+	for _, entry := range testTable {
+		ns, gn, ok := Split(entry.id)
+		if ns != entry.ns || gn != entry.gn || ok != entry.ok {
+			t.Errorf("Split(%q) returned %q, %q, %v; want %q, %q, %v", entry.id, ns, gn, ok, entry.ns, entry.gn, entry.ok)
+		}
+		if ok && ID(ns, gn) != entry.id {
+			t.Errorf("ID(%q, %q) returned %q; want %q", ns, gn, ID(ns, gn), entry.id)
+		}
+	}
+}
+
+func TestNamespaceIsolation(t *testing.T) {
+	ctx := context.Background()
+	ms := memory.NewStore()
+	m := New(ms)
+	acme, err := m.Namespace("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	globex, err := m.Namespace("globex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Namespace("a/b"); err == nil {
+		t.Errorf("m.Namespace should fail for a namespace containing %q", Separator)
+	}
+	if _, err := ms.NewGraph(ctx, "?shared"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both namespaces can use the same graph IDs.
+	ts := getTestTriples(t, 3)
+	for i, s := range []storage.Store{acme, globex} {
+		g, err := s.NewGraph(ctx, "?family")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := g.ID(ctx), "?family"; got != want {
+			t.Errorf("g.ID returned %q; want %q", got, want)
+		}
+		if err := g.AddTriples(ctx, ts[:i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := acme.NewGraph(ctx, "?social"); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range []storage.Store{acme, globex} {
+		g, err := s.Graph(ctx, "?family")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := storage.CountTriples(ctx, g); err != nil || n != int64(i+1) {
+			t.Errorf("storage.CountTriples returned %d, %v for namespace %d; want %d", n, err, i, i+1)
+		}
+	}
+	if got, want := sortedNames(t, acme), []string{"?family", "?social"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("acme.GraphNames returned %v; want %v", got, want)
+	}
+	if got, want := sortedNames(t, globex), []string{"?family"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("globex.GraphNames returned %v; want %v", got, want)
+	}
+	if got, want := sortedNames(t, ms), []string{"?shared", "acme/?family", "acme/?social", "globex/?family"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the underlying store has graphs %v; want %v", got, want)
+	}
+	got, err := m.Namespaces(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"acme", "globex"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("m.Namespaces returned %v; want %v", got, want)
+	}
+
+	// Graphs of other namespaces and outside namespaces cannot be reached.
+	for _, id := range []string{"?social", "?shared", "acme/?social", "../acme/?social"} {
+		if _, err := globex.Graph(ctx, id); err == nil {
+			t.Errorf("globex.Graph(%q) should fail", id)
+		}
+	}
+	if err := globex.DeleteGraph(ctx, "?social"); err == nil {
+		t.Errorf("globex.DeleteGraph should fail for a graph of another namespace")
+	}
+
+	// Metadata and snapshots use the IDs of the namespace.
+	md := &storage.GraphMetadata{Description: "acme family"}
+	if err := storage.SetGraphMetadata(ctx, acme, "?family", md); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := storage.GetGraphMetadata(ctx, ms, "acme/?family"); err != nil || got.Description != md.Description {
+		t.Errorf("the underlying store returned metadata %+v, %v; want %+v", got, err, md)
+	}
+	g, err := acme.Graph(ctx, "?family")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := storage.Snapshot(ctx, g)
+	if err != nil {
+		t.Fatalf("storage.Snapshot failed with error %v", err)
+	}
+	defer snap.Release(ctx)
+	if got, want := snap.ID(ctx), "?family"; got != want {
+		t.Errorf("snapshot.ID returned %q; want %q", got, want)
+	}
+}
+
+func TestNamespaceQuotas(t *testing.T) {
+	ctx := context.Background()
+	m := New(memory.NewStore())
+	if err := m.SetQuota("acme", &Quota{MaxGraphs: 2, MaxTriples: 5}); err != nil {
+		t.Fatal(err)
+	}
+	acme, err := m.Namespace("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	globex, err := m.Namespace("globex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := getTestTriples(t, 10)
+
+	g1, err := acme.NewGraph(ctx, "?a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, err := acme.NewGraph(ctx, "?b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = acme.NewGraph(ctx, "?c")
+	if qe, ok := err.(*storage.ErrQuotaExceeded); !ok || qe.Resource != "graphs" || qe.Limit != 2 || qe.Requested != 3 {
+		t.Errorf("acme.NewGraph returned error %v beyond the graph quota; want *storage.ErrQuotaExceeded", err)
+	}
+
+	// The triple quota applies to all the graphs of the namespace.
+	if err := g1.AddTriples(ctx, ts[:3]); err != nil {
+		t.Fatal(err)
+	}
+	err = g2.AddTriples(ctx, ts[3:6])
+	if qe, ok := err.(*storage.ErrQuotaExceeded); !ok || qe.Resource != "triples" || qe.Limit != 5 || qe.Requested != 6 {
+		t.Errorf("g2.AddTriples returned error %v beyond the triple quota; want *storage.ErrQuotaExceeded", err)
+	}
+	if n, err := storage.CountTriples(ctx, g2); err != nil || n != 0 {
+		t.Errorf("g2.AddTriples added %d triples, %v beyond the triple quota", n, err)
+	}
+	// Triples already in the graph do not count.
+	if err := g1.AddTriples(ctx, ts[:5]); err != nil {
+		t.Errorf("g1.AddTriples failed with error %v within the triple quota", err)
+	}
+
+	// Other namespaces are not limited.
+	for _, id := range []string{"?a", "?b", "?c"} {
+		g, err := globex.NewGraph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Errorf("globex graph %s failed to add triples with error %v", id, err)
+		}
+	}
+
+	// Removing the quota removes the limits.
+	if err := m.SetQuota("acme", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acme.NewGraph(ctx, "?c"); err != nil {
+		t.Errorf("acme.NewGraph failed with error %v without quota", err)
+	}
+	if err := g2.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g2.AddTriples failed with error %v without quota", err)
+	}
+}
@@ -0,0 +1,643 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package postgres provides a persistent implementation of the storage.Store
+// and storage.Graph interfaces on top of a PostgreSQL database.
+//
+// Graphs are rows of a graph table, and triples rows of a triple table with
+// one column per UUID of their subject, predicate, and object, plus the time
+// anchor of the predicate. The triple table is indexed to serve all lookups
+// of the graph API, including the ones bounded by time anchors. All changes
+// are applied in SQL transactions, and the store implements
+// storage.Transactor, so several changes can be grouped in a single one.
+//
+// The package only depends on database/sql. Users need to register a
+// PostgreSQL driver, like github.com/lib/pq, and provide the opened database.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/driver"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// schema contains the statements creating the tables and indices used by the
+// driver, if they do not exist yet. Time anchors are stored as seconds and
+// nanoseconds since the Unix epoch, since Postgres timestamps only have
+// microsecond precision; immutable predicates have no anchor. The exclusive
+// end of the interval of interval predicates is stored the same way, and it
+// is empty for the rest.
+var schema = []string{
+	`CREATE SEQUENCE IF NOT EXISTS badwolf_epochs`,
+	`CREATE TABLE IF NOT EXISTS badwolf_graphs (
+		id TEXT PRIMARY KEY,
+		epoch BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS badwolf_triples (
+		graph TEXT NOT NULL REFERENCES badwolf_graphs (id) ON DELETE CASCADE,
+		id UUID NOT NULL,
+		subject UUID NOT NULL,
+		predicate UUID NOT NULL,
+		anchor_s BIGINT,
+		anchor_ns INTEGER,
+		end_s BIGINT,
+		end_ns INTEGER,
+		object UUID NOT NULL,
+		data BYTEA NOT NULL,
+		PRIMARY KEY (graph, id)
+	)`,
+	// Tables created before interval predicates were supported lack their end.
+	`ALTER TABLE badwolf_triples ADD COLUMN IF NOT EXISTS end_s BIGINT`,
+	`ALTER TABLE badwolf_triples ADD COLUMN IF NOT EXISTS end_ns INTEGER`,
+	`CREATE INDEX IF NOT EXISTS badwolf_triples_spa ON badwolf_triples (graph, subject, predicate, anchor_s, anchor_ns)`,
+	`CREATE INDEX IF NOT EXISTS badwolf_triples_pa ON badwolf_triples (graph, predicate, anchor_s, anchor_ns)`,
+	`CREATE INDEX IF NOT EXISTS badwolf_triples_poa ON badwolf_triples (graph, predicate, object, anchor_s, anchor_ns)`,
+	`CREATE INDEX IF NOT EXISTS badwolf_triples_os ON badwolf_triples (graph, object, subject)`,
+}
+
+// querier contains the methods shared by databases and transactions.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Store provides a persistent store backed by a PostgreSQL database.
+type Store struct {
+	db *sql.DB
+	// tx is the transaction the store belongs to, if any. All the
+	// statements of a transaction are serialized, since they are run on a
+	// single connection.
+	tx *sql.Tx
+	mu sync.Mutex
+}
+
+// NewStore returns a store using the provided database, creating the tables
+// and indices needed if they do not exist yet. The caller owns the database
+// and needs to close it once the store is no longer used.
+func NewStore(db *sql.DB) (*Store, error) {
+	ctx := context.Background()
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("postgres.NewStore: failed to create the schema; %v", err)
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+// Name returns the ID of the backend being used.
+func (s *Store) Name(ctx context.Context) string {
+	return "POSTGRES"
+}
+
+// Version returns the version of the driver implementation.
+func (s *Store) Version(ctx context.Context) string {
+	return "0.1.postgres"
+}
+
+// q returns the querier to use for the statements of the store and locks it
+// if needed. The returned function releases it.
+func (s *Store) q() (querier, func()) {
+	if s.tx == nil {
+		return s.db, func() {}
+	}
+	s.mu.Lock()
+	return s.tx, s.mu.Unlock
+}
+
+// update runs the provided function in a transaction. If the store already
+// belongs to one, it is used instead.
+func (s *Store) update(ctx context.Context, f func(q querier) error) error {
+	if s.tx != nil {
+		q, unlock := s.q()
+		defer unlock()
+		return f(q)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// NewGraph creates a new graph.
+func (s *Store) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	q, unlock := s.q()
+	defer unlock()
+	res, err := q.ExecContext(ctx, `INSERT INTO badwolf_graphs (id, epoch) VALUES ($1, nextval('badwolf_epochs')) ON CONFLICT (id) DO NOTHING`, id)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.NewGraph(%q): %v", id, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("postgres.NewGraph(%q): %v", id, err)
+	} else if n == 0 {
+		return nil, fmt.Errorf("postgres.NewGraph(%q): graph already exists", id)
+	}
+	return &graph{id: id, s: s}, nil
+}
+
+// Graph returns an existing graph if available. Getting a non existing
+// graph should return an error.
+func (s *Store) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	q, unlock := s.q()
+	defer unlock()
+	var e int64
+	err := q.QueryRowContext(ctx, `SELECT epoch FROM badwolf_graphs WHERE id = $1`, id).Scan(&e)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("postgres.Graph(%q): graph does not exist", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("postgres.Graph(%q): %v", id, err)
+	}
+	return &graph{id: id, s: s}, nil
+}
+
+// DeleteGraph deletes an existing graph. Deleting a non existing graph
+// should return an error. Its triples are deleted in cascade.
+func (s *Store) DeleteGraph(ctx context.Context, id string) error {
+	q, unlock := s.q()
+	defer unlock()
+	res, err := q.ExecContext(ctx, `DELETE FROM badwolf_graphs WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("postgres.DeleteGraph(%q): %v", id, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("postgres.DeleteGraph(%q): %v", id, err)
+	} else if n == 0 {
+		return fmt.Errorf("postgres.DeleteGraph(%q): graph does not exist", id)
+	}
+	return nil
+}
+
+// GraphNames returns the current available graph names in the store.
+func (s *Store) GraphNames(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(names)
+	ns, err := s.graphNames(ctx)
+	if err != nil {
+		return err
+	}
+	for _, n := range ns {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case names <- n:
+		}
+	}
+	return nil
+}
+
+// graphNames returns the sorted names of the graphs in the store.
+func (s *Store) graphNames(ctx context.Context) ([]string, error) {
+	q, unlock := s.q()
+	defer unlock()
+	rows, err := q.QueryContext(ctx, `SELECT id FROM badwolf_graphs ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ns []string
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		ns = append(ns, n)
+	}
+	return ns, rows.Err()
+}
+
+// Begin starts a new SQL transaction. The changes done through the returned
+// transaction, and the graphs obtained from it, are not visible to other
+// users of the database until it is committed.
+func (s *Store) Begin(ctx context.Context) (storage.Transaction, error) {
+	if s.tx != nil {
+		return nil, errors.New("postgres.Begin: nested transactions are not supported")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.Begin: %v", err)
+	}
+	return &transaction{Store: &Store{db: s.db, tx: tx}}, nil
+}
+
+// transaction wraps a SQL transaction.
+type transaction struct {
+	*Store
+}
+
+// Commit commits the SQL transaction.
+func (t *transaction) Commit(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tx.Commit()
+}
+
+// Rollback rolls back the SQL transaction.
+func (t *transaction) Rollback(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tx.Rollback()
+}
+
+// graph provides a PostgreSQL-based persistent implementation of the graph
+// API.
+type graph struct {
+	id string
+	s  *Store
+}
+
+// ID returns the id for this graph.
+func (g *graph) ID(ctx context.Context) string {
+	return g.id
+}
+
+// anchor returns the seconds and nanoseconds of the time anchor of the
+// provided predicate, or nil values for immutable ones.
+func anchor(p *predicate.Predicate) (interface{}, interface{}) {
+	ta, err := p.TimeAnchor()
+	if err != nil {
+		return nil, nil
+	}
+	return ta.Unix(), ta.Nanosecond()
+}
+
+// intervalEnd returns the seconds and nanoseconds of the end of the interval
+// of the provided predicate, or nil values if it is not an interval one.
+func intervalEnd(p *predicate.Predicate) (interface{}, interface{}) {
+	_, end, err := p.Interval()
+	if err != nil {
+		return nil, nil
+	}
+	return end.Unix(), end.Nanosecond()
+}
+
+// bumpEpoch assigns a new epoch to the graph once its triples changed.
+func (g *graph) bumpEpoch(ctx context.Context, q querier) error {
+	res, err := q.ExecContext(ctx, `UPDATE badwolf_graphs SET epoch = nextval('badwolf_epochs') WHERE id = $1`, g.id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("postgres: graph %q does not exist", g.id)
+	}
+	return nil
+}
+
+// AddTriples adds the triples to the storage. Either all of them are added
+// or none of them is.
+func (g *graph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	return g.s.update(ctx, func(q querier) error {
+		stmt, err := q.PrepareContext(ctx, `INSERT INTO badwolf_triples (graph, id, subject, predicate, anchor_s, anchor_ns, end_s, end_ns, object, data)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (graph, id) DO NOTHING`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, t := range ts {
+			data, err := driver.EncodeTriple(t)
+			if err != nil {
+				return err
+			}
+			as, ans := anchor(t.Predicate())
+			es, ens := intervalEnd(t.Predicate())
+			if _, err := stmt.ExecContext(ctx, g.id, t.UUID().String(), t.Subject().UUID().String(),
+				t.Predicate().PartialUUID().String(), as, ans, es, ens, t.Object().UUID().String(), data); err != nil {
+				return err
+			}
+		}
+		return g.bumpEpoch(ctx, q)
+	})
+}
+
+// RemoveTriples removes the triples from the storage. Either all of them are
+// removed or none of them is.
+func (g *graph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	return g.s.update(ctx, func(q querier) error {
+		stmt, err := q.PrepareContext(ctx, `DELETE FROM badwolf_triples WHERE graph = $1 AND id = $2`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, t := range ts {
+			if _, err := stmt.ExecContext(ctx, g.id, t.UUID().String()); err != nil {
+				return err
+			}
+		}
+		return g.bumpEpoch(ctx, q)
+	})
+}
+
+// anchorClause returns the SQL condition restricting the time anchors of
+// temporal triples to the ones allowed by the predicate and lookup options,
+// and its arguments, numbered starting at n. Immutable triples always pass the
+// condition, and interval ones pass it if their interval overlaps the bounds
+// of the lookup.
+func anchorClause(lo *storage.LookupOptions, op *predicate.Predicate, n int) (string, []interface{}) {
+	if lo.LatestAnchor {
+		return "", nil
+	}
+	var (
+		cs   []string
+		args []interface{}
+	)
+	bound := func(cmp string, ta *time.Time) {
+		if ta == nil {
+			return
+		}
+		cs = append(cs, fmt.Sprintf("(anchor_s, anchor_ns) %s ($%d, $%d)", cmp, n, n+1))
+		args = append(args, ta.Unix(), ta.Nanosecond())
+		n += 2
+	}
+	if op != nil {
+		if ta, err := op.TimeAnchor(); err == nil {
+			bound("=", ta)
+			if _, end, err := op.Interval(); err == nil {
+				cs = append(cs, fmt.Sprintf("(end_s, end_ns) = ($%d, $%d)", n, n+1))
+				args = append(args, end.Unix(), end.Nanosecond())
+				n += 2
+			} else {
+				cs = append(cs, "end_s IS NULL")
+			}
+		}
+	}
+	if ta := lo.LowerAnchor; ta != nil {
+		// Intervals only need to end after the lower bound.
+		cs = append(cs, fmt.Sprintf("(end_s IS NULL AND (anchor_s, anchor_ns) >= ($%d, $%d) OR (end_s, end_ns) > ($%d, $%d))", n, n+1, n, n+1))
+		args = append(args, ta.Unix(), ta.Nanosecond())
+		n += 2
+	}
+	bound("<=", lo.UpperAnchor)
+	if len(cs) == 0 {
+		return "", nil
+	}
+	return " AND (anchor_s IS NULL OR (" + strings.Join(cs, " AND ") + "))", args
+}
+
+// lookup returns the triples of the graph matching the provided condition,
+// whose arguments start at $2, that pass the lookup options.
+func (g *graph) lookup(ctx context.Context, cond string, args []interface{}, lo *storage.LookupOptions, op *predicate.Predicate) ([]*triple.Triple, error) {
+	query := `SELECT data FROM badwolf_triples WHERE graph = $1`
+	if cond != "" {
+		query += " AND " + cond
+	}
+	args = append([]interface{}{g.id}, args...)
+	ac, aargs := anchorClause(lo, op, len(args)+1)
+	query, args = query+ac, append(args, aargs...)
+	// Time anchors are fully checked by the query, so the limit can be pushed
+	// down unless the results need further filtering.
+	if lo.MaxElements > 0 && lo.LiteralFilter == nil && !lo.LatestAnchor {
+		query += fmt.Sprintf(" LIMIT %d", lo.MaxElements)
+	}
+	q, unlock := g.s.q()
+	defer unlock()
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ts []*triple.Triple
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		t, err := driver.DecodeTriple(data)
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return driver.Filter(ts, lo, op)
+}
+
+// Objects publishes the objects for the given subject and predicate to the
+// provided channel.
+func (g *graph) Objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	if objs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(objs)
+	ts, err := g.lookup(ctx, "subject = $2 AND predicate = $3", []interface{}{s.UUID().String(), p.PartialUUID().String()}, lo, p)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case objs <- t.Object():
+		}
+	}
+	return nil
+}
+
+// Subjects publishes the subjects for the given predicate and object to the
+// provided channel.
+func (g *graph) Subjects(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, subjs chan<- *node.Node) error {
+	if subjs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(subjs)
+	ts, err := g.lookup(ctx, "predicate = $2 AND object = $3", []interface{}{p.PartialUUID().String(), o.UUID().String()}, lo, p)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case subjs <- t.Subject():
+		}
+	}
+	return nil
+}
+
+// PredicatesForSubjectAndObject publishes all predicates available for the
+// given subject and object to the provided channel.
+func (g *graph) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.lookup(ctx, "object = $2 AND subject = $3", []interface{}{o.UUID().String(), s.UUID().String()}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// PredicatesForSubject publishes all the predicates known for the given
+// subject to the provided channel.
+func (g *graph) PredicatesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.lookup(ctx, "subject = $2", []interface{}{s.UUID().String()}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// PredicatesForObject publishes all the predicates known for the given object
+// to the provided channel.
+func (g *graph) PredicatesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.lookup(ctx, "object = $2", []interface{}{o.UUID().String()}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// TriplesForSubject publishes all triples available for the given subject to
+// the provided channel.
+func (g *graph) TriplesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "subject = $2", []interface{}{s.UUID().String()}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForPredicate publishes all triples available for the given predicate
+// to the provided channel.
+func (g *graph) TriplesForPredicate(ctx context.Context, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "predicate = $2", []interface{}{p.PartialUUID().String()}, lo, p)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForObject publishes all triples available for the given object to the
+// provided channel.
+func (g *graph) TriplesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "object = $2", []interface{}{o.UUID().String()}, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForSubjectAndPredicate publishes all triples available for the given
+// subject and predicate to the provided channel.
+func (g *graph) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "subject = $2 AND predicate = $3", []interface{}{s.UUID().String(), p.PartialUUID().String()}, lo, p)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForPredicateAndObject publishes all triples available for the given
+// predicate and object to the provided channel.
+func (g *graph) TriplesForPredicateAndObject(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "predicate = $2 AND object = $3", []interface{}{p.PartialUUID().String(), o.UUID().String()}, lo, p)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// Exist checks if the provided triple exists on the store.
+func (g *graph) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
+	q, unlock := g.s.q()
+	defer unlock()
+	var ok bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM badwolf_triples WHERE graph = $1 AND id = $2)`, g.id, t.UUID().String()).Scan(&ok)
+	return ok, err
+}
+
+// Triples allows to iterate over all available triples by pushing them to the
+// provided channel.
+func (g *graph) Triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.lookup(ctx, "", nil, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// CountTriples returns the number of triples in the graph.
+func (g *graph) CountTriples(ctx context.Context) (int64, error) {
+	q, unlock := g.s.q()
+	defer unlock()
+	var n int64
+	err := q.QueryRowContext(ctx, `SELECT count(*) FROM badwolf_triples WHERE graph = $1`, g.id).Scan(&n)
+	return n, err
+}
+
+// Epoch returns the current epoch of the graph.
+func (g *graph) Epoch(ctx context.Context) (uint64, error) {
+	q, unlock := g.s.q()
+	defer unlock()
+	var e int64
+	err := q.QueryRowContext(ctx, `SELECT epoch FROM badwolf_graphs WHERE id = $1`, g.id).Scan(&e)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("postgres: graph %q does not exist", g.id)
+	}
+	return uint64(e), err
+}
@@ -0,0 +1,316 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/storagetest"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+	"github.com/pborman/uuid"
+
+	_ "github.com/lib/pq"
+)
+
+func TestAnchorClause(t *testing.T) {
+	lower := time.Unix(-10, 500)
+	upper := time.Unix(1460262600, 1)
+	temporal, err := predicate.NewTemporal("met", upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	immutable, err := predicate.NewImmutable("met")
+	if err != nil {
+		t.Fatal(err)
+	}
+	interval, err := predicate.NewInterval("met", lower, upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTable := []struct {
+		lo   *storage.LookupOptions
+		op   *predicate.Predicate
+		want string
+		args []interface{}
+	}{
+		{storage.DefaultLookup, nil, "", nil},
+		{storage.DefaultLookup, immutable, "", nil},
+		{
+			lo:   storage.DefaultLookup,
+			op:   temporal,
+			want: " AND (anchor_s IS NULL OR ((anchor_s, anchor_ns) = ($3, $4) AND end_s IS NULL))",
+			args: []interface{}{int64(1460262600), 1},
+		},
+		{
+			lo:   &storage.LookupOptions{LowerAnchor: &lower},
+			want: " AND (anchor_s IS NULL OR ((end_s IS NULL AND (anchor_s, anchor_ns) >= ($3, $4) OR (end_s, end_ns) > ($3, $4))))",
+			args: []interface{}{int64(-10), 500},
+		},
+		{
+			lo:   &storage.LookupOptions{LowerAnchor: &lower, UpperAnchor: &upper},
+			op:   immutable,
+			want: " AND (anchor_s IS NULL OR ((end_s IS NULL AND (anchor_s, anchor_ns) >= ($3, $4) OR (end_s, end_ns) > ($3, $4)) AND (anchor_s, anchor_ns) <= ($5, $6)))",
+			args: []interface{}{int64(-10), 500, int64(1460262600), 1},
+		},
+		{
+			lo:   storage.DefaultLookup,
+			op:   interval,
+			want: " AND (anchor_s IS NULL OR ((anchor_s, anchor_ns) = ($3, $4) AND (end_s, end_ns) = ($5, $6)))",
+			args: []interface{}{int64(-10), 500, int64(1460262600), 1},
+		},
+		{&storage.LookupOptions{LowerAnchor: &lower, LatestAnchor: true}, temporal, "", nil},
+	}
+	for _, entry := range testTable {
+		got, args := anchorClause(entry.lo, entry.op, 3)
+		if got != entry.want || !reflect.DeepEqual(args, entry.args) {
+			t.Errorf("anchorClause(%s, %v) returned (%q, %v); want (%q, %v)", entry.lo, entry.op, got, args, entry.want, entry.args)
+		}
+	}
+}
+
+// newTestStore returns a store using the database provided by the
+// BADWOLF_POSTGRES_DSN environment variable, and a unique prefix for the
+// graph IDs of the test. The test is skipped if the variable is not set.
+func newTestStore(t *testing.T) (*Store, string) {
+	dsn := os.Getenv("BADWOLF_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("BADWOLF_POSTGRES_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStore(db)
+	if err != nil {
+		db.Close()
+		t.Fatalf("NewStore failed with error %v", err)
+	}
+	return s, "?" + uuid.New() + "_"
+}
+
+// cleanTestStore deletes the graphs created by the test and closes the
+// database.
+func cleanTestStore(t *testing.T, s *Store, prefix string) {
+	if _, err := s.db.Exec(`DELETE FROM badwolf_graphs WHERE left(id, length($1)) = $1`, prefix); err != nil {
+		t.Errorf("failed to delete the test graphs; %v", err)
+	}
+	s.db.Close()
+}
+
+func getTestTriples(t *testing.T) []*triple.Triple {
+	var ts []*triple.Triple
+	for _, s := range []string{
+		"/u<john>\t\"parent_of\"@[]\t/u<mary>",
+		"/u<john>\t\"parent_of\"@[]\t/u<peter>",
+		"/u<john>\t\"parent_of\"@[]\t/u<alice>",
+		"/u<mary>\t\"parent_of\"@[]\t/u<amy>",
+		"/u<john>\t\"met\"@[1960-04-10T04:21:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[1969-12-31T23:59:59.5Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T04:25:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T04:30:00Z]\t/u<peter>",
+		"/u<john>\t\"met\"@[2016-04-10T04:30:00.000000001Z]\t/u<alice>",
+	} {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %q with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+func TestPostgresStore(t *testing.T) {
+	s, prefix := newTestStore(t)
+	defer cleanTestStore(t, s, prefix)
+	ctx, id := context.Background(), prefix+"test"
+	if _, err := s.NewGraph(ctx, id); err != nil {
+		t.Errorf("postgres.NewGraph: should never fail to create a graph; %v", err)
+	}
+	if _, err := s.NewGraph(ctx, id); err == nil {
+		t.Errorf("postgres.NewGraph: should never succeed to create an existing graph")
+	}
+	if _, err := s.Graph(ctx, id); err != nil {
+		t.Errorf("postgres.Graph: should never fail to get an existing graph; %v", err)
+	}
+	gns := make(chan string, 100)
+	if err := s.GraphNames(ctx, gns); err != nil {
+		t.Errorf("postgres.GraphNames failed with error %v", err)
+	}
+	found := false
+	for n := range gns {
+		found = found || n == id
+	}
+	if !found {
+		t.Errorf("postgres.GraphNames did not return %q", id)
+	}
+	if err := s.DeleteGraph(ctx, id); err != nil {
+		t.Errorf("postgres.DeleteGraph: should never fail to delete an existing graph; %v", err)
+	}
+	if _, err := s.Graph(ctx, id); err == nil {
+		t.Errorf("postgres.Graph: should never succeed to get a non existing graph")
+	}
+	if err := s.DeleteGraph(ctx, id); err == nil {
+		t.Errorf("postgres.DeleteGraph: should never succeed to delete a non existing graph")
+	}
+}
+
+func TestLookups(t *testing.T) {
+	s, prefix := newTestStore(t)
+	defer cleanTestStore(t, s, prefix)
+	ctx, ts := context.Background(), getTestTriples(t)
+	g, err := s.NewGraph(ctx, prefix+"test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	john, mary := ts[0].Subject(), ts[0].Object()
+	parentOf, met := ts[0].Predicate(), ts[4].Predicate()
+	at := func(s string) *time.Time {
+		ta, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &ta
+	}
+	lo := storage.DefaultLookup
+	testTable := []struct {
+		name   string
+		lookup func(chan<- *triple.Triple) error
+		want   int
+	}{
+		{"Triples", func(c chan<- *triple.Triple) error { return g.Triples(ctx, lo, c) }, 9},
+		{"TriplesForSubject", func(c chan<- *triple.Triple) error { return g.TriplesForSubject(ctx, john, lo, c) }, 8},
+		{"TriplesForObject", func(c chan<- *triple.Triple) error { return g.TriplesForObject(ctx, mary, lo, c) }, 4},
+		{"TriplesForPredicate", func(c chan<- *triple.Triple) error { return g.TriplesForPredicate(ctx, parentOf, lo, c) }, 4},
+		{"TriplesForPredicateAndObject", func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicateAndObject(ctx, parentOf, mary, lo, c)
+		}, 1},
+		{"TriplesForPredicate anchored", func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicate(ctx, met, lo, c)
+		}, 1},
+		{"TriplesForSubjectAndPredicate window", func(c chan<- *triple.Triple) error {
+			p, _ := predicate.NewImmutable("met")
+			return g.TriplesForSubjectAndPredicate(ctx, john, p, &storage.LookupOptions{
+				LowerAnchor: at("1969-12-31T23:59:59Z"),
+				UpperAnchor: at("2016-04-10T04:30:00Z"),
+			}, c)
+		}, 3},
+		{"TriplesForPredicateAndObject before epoch", func(c chan<- *triple.Triple) error {
+			p, _ := predicate.NewImmutable("met")
+			return g.TriplesForPredicateAndObject(ctx, p, mary, &storage.LookupOptions{UpperAnchor: at("1970-01-01T00:00:00Z")}, c)
+		}, 2},
+		{"TriplesForSubjectAndPredicate latest", func(c chan<- *triple.Triple) error {
+			return g.TriplesForSubjectAndPredicate(ctx, john, met, &storage.LookupOptions{LatestAnchor: true}, c)
+		}, 1},
+		{"Triples limit", func(c chan<- *triple.Triple) error {
+			return g.Triples(ctx, &storage.LookupOptions{MaxElements: 4}, c)
+		}, 4},
+		{"TriplesForSubject unknown", func(c chan<- *triple.Triple) error {
+			return g.TriplesForSubject(ctx, node.NewBlankNode(), lo, c)
+		}, 0},
+	}
+	for _, entry := range testTable {
+		if got := storagetest.Collect(t, entry.lookup); len(got) != entry.want {
+			t.Errorf("%s returned %d triples %v; want %d", entry.name, len(got), got, entry.want)
+		}
+	}
+	prds := make(chan *predicate.Predicate, 10)
+	if err := g.PredicatesForSubjectAndObject(ctx, john, mary, lo, prds); err != nil {
+		t.Fatalf("g.PredicatesForSubjectAndObject failed with error %v", err)
+	}
+	if cnt := len(prds); cnt != 4 {
+		t.Errorf("g.PredicatesForSubjectAndObject(%s, %s) returned %d predicates; want 4", john, mary, cnt)
+	}
+	if n, err := storage.CountTriples(ctx, g); err != nil || n != int64(len(ts)) {
+		t.Errorf("storage.CountTriples returned (%d, %v); want (%d, nil)", n, err, len(ts))
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	s, prefix := newTestStore(t)
+	defer cleanTestStore(t, s, prefix)
+	ctx, ts := context.Background(), getTestTriples(t)
+	id := prefix + "test"
+	g, err := s.NewGraph(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e0, err := storage.Epoch(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rolled back changes are never visible.
+	tx, err := storage.Begin(ctx, s)
+	if err != nil {
+		t.Fatalf("storage.Begin failed with error %v", err)
+	}
+	tg, err := tx.Graph(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	if ok, err := tg.Exist(ctx, ts[0]); err != nil || !ok {
+		t.Errorf("g.Exist returned (%v, %v) inside the transaction; want (true, nil)", ok, err)
+	}
+	if ok, err := g.Exist(ctx, ts[0]); err != nil || ok {
+		t.Errorf("g.Exist returned (%v, %v) outside the transaction; want (false, nil)", ok, err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("tx.Rollback failed with error %v", err)
+	}
+	if n, err := storage.CountTriples(ctx, g); err != nil || n != 0 {
+		t.Errorf("storage.CountTriples returned (%d, %v) after rolling back; want (0, nil)", n, err)
+	}
+	if e, err := storage.Epoch(ctx, g); err != nil || e != e0 {
+		t.Errorf("storage.Epoch returned (%d, %v) after rolling back; want (%d, nil)", e, err, e0)
+	}
+
+	// Committed changes are all visible.
+	if tx, err = storage.Begin(ctx, s); err != nil {
+		t.Fatalf("storage.Begin failed with error %v", err)
+	}
+	if tg, err = tx.Graph(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	if err := tg.RemoveTriples(ctx, ts[:1]); err != nil {
+		t.Fatalf("g.RemoveTriples failed with error %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("tx.Commit failed with error %v", err)
+	}
+	if n, err := storage.CountTriples(ctx, g); err != nil || n != int64(len(ts)-1) {
+		t.Errorf("storage.CountTriples returned (%d, %v) after committing; want (%d, nil)", n, err, len(ts)-1)
+	}
+	if e, err := storage.Epoch(ctx, g); err != nil || e == e0 {
+		t.Errorf("storage.Epoch returned (%d, %v) after committing; want a new epoch", e, err)
+	}
+}
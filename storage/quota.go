@@ -0,0 +1,89 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrQuotaExceeded is the error returned when a change is rejected because it
+// would exceed a quota. The change is not applied.
+type ErrQuotaExceeded struct {
+	// Scope identifies what the quota applies to, such as a graph or a
+	// namespace.
+	Scope string
+
+	// Resource is the name of the limited resource, such as "graphs" or
+	// "triples".
+	Resource string
+
+	// Limit is the maximum amount of the resource allowed, and Requested the
+	// amount the change would have required.
+	Limit, Requested int64
+}
+
+// Error returns a readable description of the exceeded quota.
+func (e *ErrQuotaExceeded) Error() string {
+	return fmt.Sprintf("storage: quota of %d %s exceeded for %s; %d requested", e.Limit, e.Resource, e.Scope, e.Requested)
+}
+
+// GraphQuota limits the resources used by a graph. Zero values are not
+// limited.
+type GraphQuota struct {
+	// MaxTriples is the maximum number of triples in the graph.
+	MaxTriples int64
+
+	// MaxMemoryBytes is the maximum approximate memory used by the graph, as
+	// reported in the MemoryBytes field of its statistics.
+	MaxMemoryBytes int64
+}
+
+// QuotaProvider is an optional interface that stores can implement to limit
+// the resources used by each of their graphs. Adding triples to a graph fails
+// with *ErrQuotaExceeded, without adding any of them, if the graph would
+// exceed its quota.
+type QuotaProvider interface {
+	// GraphQuota returns the quota of the graph with the provided ID.
+	GraphQuota(ctx context.Context, id string) (*GraphQuota, error)
+
+	// SetGraphQuota replaces the quota of the graph with the provided ID. Graphs
+	// already exceeding the new quota keep their triples, but adding triples to
+	// them fails until they are within their quota again.
+	SetGraphQuota(ctx context.Context, id string, q *GraphQuota) error
+}
+
+// ErrNoQuotas is returned when accessing the quotas of the graphs of a store
+// that does not implement QuotaProvider.
+var ErrNoQuotas = errors.New("storage: the store does not enforce graph quotas")
+
+// GetGraphQuota returns the quota of the graph with the provided ID. If the
+// store does not implement QuotaProvider, ErrNoQuotas is returned.
+func GetGraphQuota(ctx context.Context, s Store, id string) (*GraphQuota, error) {
+	if qp, ok := s.(QuotaProvider); ok {
+		return qp.GraphQuota(ctx, id)
+	}
+	return nil, ErrNoQuotas
+}
+
+// SetGraphQuota replaces the quota of the graph with the provided ID. If the
+// store does not implement QuotaProvider, ErrNoQuotas is returned.
+func SetGraphQuota(ctx context.Context, s Store, id string, q *GraphQuota) error {
+	if qp, ok := s.(QuotaProvider); ok {
+		return qp.SetGraphQuota(ctx, id, q)
+	}
+	return ErrNoQuotas
+}
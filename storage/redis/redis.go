@@ -0,0 +1,590 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redis provides an implementation of the storage.Store and
+// storage.Graph interfaces backed by a Redis server, so several stateless
+// BadWolf frontends can share the same graphs without each of them holding
+// the graphs in process memory.
+//
+// All the keys used by a store start with its namespace. The graphs are the
+// fields of a hash holding their current epochs. Each graph uses a hash from
+// triple UUIDs to the encoded triples, and a sorted set whose members are the
+// keys of the lookup indices, all with the same score so they are sorted
+// lexicographically. The index keys order the triples of a predicate by time
+// anchor, so time bounded lookups only read the requested time window.
+// Changes are applied by Lua scripts, so they are atomic.
+package redis
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/driver"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+	"github.com/pborman/uuid"
+)
+
+// Index IDs. The keys of each index concatenate the index ID with the UUIDs of
+// the subject (S), predicate ignoring its time anchor (P), object (O), and
+// triple (T), and the time anchor of the predicate (A) in the order listed.
+const (
+	pasotIdx = 'p'
+	spaotIdx = 's'
+	ospatIdx = 'o'
+	poastIdx = 'q'
+)
+
+// Scripts applying the changes to the graphs. KEYS are the graph hash, the
+// epoch counter, the triple hash, and the index sorted set of a graph.
+var (
+	// addScript takes the graph ID followed by, for each triple, its UUID,
+	// its encoding, and its index keys.
+	addScript = redigo.NewScript(4, `
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return redis.error_reply('graph does not exist')
+end
+for i = 2, #ARGV, 6 do
+	redis.call('HSET', KEYS[3], ARGV[i], ARGV[i+1])
+	redis.call('ZADD', KEYS[4], 0, ARGV[i+2], 0, ARGV[i+3], 0, ARGV[i+4], 0, ARGV[i+5])
+end
+redis.call('HSET', KEYS[1], ARGV[1], redis.call('INCR', KEYS[2]))
+return 0
+`)
+	// removeScript takes the graph ID followed by, for each triple, its UUID
+	// and its index keys.
+	removeScript = redigo.NewScript(4, `
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return redis.error_reply('graph does not exist')
+end
+for i = 2, #ARGV, 5 do
+	redis.call('HDEL', KEYS[3], ARGV[i])
+	redis.call('ZREM', KEYS[4], ARGV[i+1], ARGV[i+2], ARGV[i+3], ARGV[i+4])
+end
+redis.call('HSET', KEYS[1], ARGV[1], redis.call('INCR', KEYS[2]))
+return 0
+`)
+	// newScript takes the graph ID.
+	newScript = redigo.NewScript(2, `
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	return redis.error_reply('graph already exists')
+end
+redis.call('HSET', KEYS[1], ARGV[1], redis.call('INCR', KEYS[2]))
+return 0
+`)
+	// deleteScript takes the graph ID.
+	deleteScript = redigo.NewScript(4, `
+if redis.call('HDEL', KEYS[1], ARGV[1]) == 0 then
+	return redis.error_reply('graph does not exist')
+end
+redis.call('DEL', KEYS[3], KEYS[4])
+return 0
+`)
+)
+
+// Store provides a store backed by a Redis server.
+type Store struct {
+	pool      *redigo.Pool
+	namespace string
+}
+
+// NewStore returns a store using the connections of the provided pool. All
+// the keys used by the store start with the provided namespace, so several
+// stores can share the same Redis server.
+func NewStore(pool *redigo.Pool, namespace string) (*Store, error) {
+	conn := pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("PING"); err != nil {
+		return nil, fmt.Errorf("redis.NewStore: %v", err)
+	}
+	return &Store{pool: pool, namespace: namespace}, nil
+}
+
+// Name returns the ID of the backend being used.
+func (s *Store) Name(ctx context.Context) string {
+	return "REDIS"
+}
+
+// Version returns the version of the driver implementation.
+func (s *Store) Version(ctx context.Context) string {
+	return "0.1.redis"
+}
+
+// graphsKey returns the key of the hash listing the graphs.
+func (s *Store) graphsKey() string {
+	return s.namespace + "graphs"
+}
+
+// epochKey returns the key of the counter providing the epochs of all graphs.
+// Epochs are never reused, even after deleting a graph.
+func (s *Store) epochKey() string {
+	return s.namespace + "epoch"
+}
+
+// do runs the provided function with a connection of the pool.
+func (s *Store) do(ctx context.Context, f func(conn redigo.Conn) error) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return f(conn)
+}
+
+// newGraph returns the graph with the provided ID. Graph IDs are hashed and
+// used as hash tags, so all the keys of a graph are placed together.
+func (s *Store) newGraph(id string) *graph {
+	tag := s.namespace + "{" + hex.EncodeToString(uuid.NewSHA1(uuid.NIL, []byte(id))) + "}"
+	return &graph{
+		id:         id,
+		s:          s,
+		triplesKey: tag + ":t",
+		indexKey:   tag + ":i",
+	}
+}
+
+// NewGraph creates a new graph.
+func (s *Store) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	err := s.do(ctx, func(conn redigo.Conn) error {
+		_, err := newScript.Do(conn, s.graphsKey(), s.epochKey(), id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis.NewGraph(%q): %v", id, err)
+	}
+	return s.newGraph(id), nil
+}
+
+// Graph returns an existing graph if available. Getting a non existing
+// graph should return an error.
+func (s *Store) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	var ok bool
+	err := s.do(ctx, func(conn redigo.Conn) error {
+		var err error
+		ok, err = redigo.Bool(conn.Do("HEXISTS", s.graphsKey(), id))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis.Graph(%q): %v", id, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("redis.Graph(%q): graph does not exist", id)
+	}
+	return s.newGraph(id), nil
+}
+
+// DeleteGraph deletes an existing graph. Deleting a non existing graph
+// should return an error.
+func (s *Store) DeleteGraph(ctx context.Context, id string) error {
+	g := s.newGraph(id)
+	err := s.do(ctx, func(conn redigo.Conn) error {
+		_, err := deleteScript.Do(conn, s.graphsKey(), s.epochKey(), g.triplesKey, g.indexKey, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("redis.DeleteGraph(%q): %v", id, err)
+	}
+	return nil
+}
+
+// GraphNames returns the current available graph names in the store.
+func (s *Store) GraphNames(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(names)
+	var ns []string
+	if err := s.do(ctx, func(conn redigo.Conn) error {
+		var err error
+		ns, err = redigo.Strings(conn.Do("HKEYS", s.graphsKey()))
+		return err
+	}); err != nil {
+		return err
+	}
+	sort.Strings(ns)
+	for _, n := range ns {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case names <- n:
+		}
+	}
+	return nil
+}
+
+// graph provides a Redis-based implementation of the graph API.
+type graph struct {
+	id         string
+	s          *Store
+	triplesKey string
+	indexKey   string
+}
+
+// ID returns the id for this graph.
+func (g *graph) ID(ctx context.Context) string {
+	return g.id
+}
+
+// key returns the index key with the provided ID concatenating the provided
+// parts.
+func key(idx byte, parts ...[]byte) []byte {
+	k := []byte{idx}
+	for _, p := range parts {
+		k = append(k, p...)
+	}
+	return k
+}
+
+// keys returns the keys of the provided triple in all the indices.
+func keys(t *triple.Triple) []interface{} {
+	s, p, o := []byte(t.Subject().UUID()), []byte(t.Predicate().PartialUUID()), []byte(t.Object().UUID())
+	a, tk := driver.PredicateAnchor(t.Predicate()), []byte(t.UUID())
+	return []interface{}{
+		key(pasotIdx, p, a, s, o, tk),
+		key(spaotIdx, s, p, a, o, tk),
+		key(ospatIdx, o, s, p, a, tk),
+		key(poastIdx, p, o, a, s, tk),
+	}
+}
+
+// AddTriples adds the triples to the storage.
+func (g *graph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	args := []interface{}{g.s.graphsKey(), g.s.epochKey(), g.triplesKey, g.indexKey, g.id}
+	for _, t := range ts {
+		v, err := driver.EncodeTriple(t)
+		if err != nil {
+			return err
+		}
+		args = append(append(args, []byte(t.UUID()), v), keys(t)...)
+	}
+	return g.s.do(ctx, func(conn redigo.Conn) error {
+		_, err := addScript.Do(conn, args...)
+		return err
+	})
+}
+
+// RemoveTriples removes the triples from the storage.
+func (g *graph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	args := []interface{}{g.s.graphsKey(), g.s.epochKey(), g.triplesKey, g.indexKey, g.id}
+	for _, t := range ts {
+		args = append(append(args, []byte(t.UUID())), keys(t)...)
+	}
+	return g.s.do(ctx, func(conn redigo.Conn) error {
+		_, err := removeScript.Do(conn, args...)
+		return err
+	})
+}
+
+// successor returns the first key that is greater than all the keys starting
+// with the provided prefix, or nil if there is none.
+func successor(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			s := append([]byte{}, prefix[:i+1]...)
+			s[i]++
+			return s
+		}
+	}
+	return nil
+}
+
+// lexRange returns the arguments of ZRANGEBYLEX to get the members in the
+// provided range.
+func lexRange(r driver.KeyRange) (interface{}, interface{}) {
+	start, end := r.Start, r.End
+	if start == nil {
+		start = r.Prefix
+	}
+	if end == nil {
+		end = r.Prefix
+	}
+	max := interface{}("+")
+	if s := successor(end); s != nil {
+		max = append([]byte("("), s...)
+	}
+	return append([]byte("["), start...), max
+}
+
+// scan returns the triples whose index keys are in the provided ranges that
+// pass the lookup options.
+func (g *graph) scan(ctx context.Context, rs []driver.KeyRange, lo *storage.LookupOptions, op *predicate.Predicate) ([]*triple.Triple, error) {
+	var ts []*triple.Triple
+	err := g.s.do(ctx, func(conn redigo.Conn) error {
+		var ids []interface{}
+		for _, r := range rs {
+			min, max := lexRange(r)
+			ks, err := redigo.ByteSlices(conn.Do("ZRANGEBYLEX", g.indexKey, min, max))
+			if err != nil {
+				return err
+			}
+			for _, k := range ks {
+				ids = append(ids, k[len(k)-len(uuid.NIL):])
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		vs, err := redigo.ByteSlices(conn.Do("HMGET", append([]interface{}{g.triplesKey}, ids...)...))
+		if err != nil {
+			return err
+		}
+		for _, v := range vs {
+			// Triples removed since reading the index are skipped.
+			if v == nil {
+				continue
+			}
+			t, err := driver.DecodeTriple(v)
+			if err != nil {
+				return err
+			}
+			ts = append(ts, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return driver.Filter(ts, lo, op)
+}
+
+// prefix returns the range of all the keys with the provided prefix.
+func prefix(idx byte, parts ...[]byte) []driver.KeyRange {
+	return []driver.KeyRange{{Prefix: key(idx, parts...)}}
+}
+
+// Objects publishes the objects for the given subject and predicate to the
+// provided channel.
+func (g *graph) Objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	if objs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(objs)
+	ts, err := g.scan(ctx, driver.AnchorRanges(key(spaotIdx, s.UUID(), p.PartialUUID()), lo, p), lo, p)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case objs <- t.Object():
+		}
+	}
+	return nil
+}
+
+// Subjects publishes the subjects for the given predicate and object to the
+// provided channel.
+func (g *graph) Subjects(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, subjs chan<- *node.Node) error {
+	if subjs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(subjs)
+	ts, err := g.scan(ctx, driver.AnchorRanges(key(poastIdx, p.PartialUUID(), o.UUID()), lo, p), lo, p)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case subjs <- t.Subject():
+		}
+	}
+	return nil
+}
+
+// PredicatesForSubjectAndObject publishes all predicates available for the
+// given subject and object to the provided channel.
+func (g *graph) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.scan(ctx, prefix(ospatIdx, o.UUID(), s.UUID()), lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// PredicatesForSubject publishes all the predicates known for the given
+// subject to the provided channel.
+func (g *graph) PredicatesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.scan(ctx, prefix(spaotIdx, s.UUID()), lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// PredicatesForObject publishes all the predicates known for the given object
+// to the provided channel.
+func (g *graph) PredicatesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	if prds == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(prds)
+	ts, err := g.scan(ctx, prefix(ospatIdx, o.UUID()), lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendPredicates(ctx, ts, prds)
+}
+
+// TriplesForSubject publishes all triples available for the given subject to
+// the provided channel.
+func (g *graph) TriplesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(ctx, prefix(spaotIdx, s.UUID()), lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForPredicate publishes all triples available for the given predicate
+// to the provided channel.
+func (g *graph) TriplesForPredicate(ctx context.Context, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(ctx, driver.AnchorRanges(key(pasotIdx, p.PartialUUID()), lo, p), lo, p)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForObject publishes all triples available for the given object to the
+// provided channel.
+func (g *graph) TriplesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(ctx, prefix(ospatIdx, o.UUID()), lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForSubjectAndPredicate publishes all triples available for the given
+// subject and predicate to the provided channel.
+func (g *graph) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(ctx, driver.AnchorRanges(key(spaotIdx, s.UUID(), p.PartialUUID()), lo, p), lo, p)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// TriplesForPredicateAndObject publishes all triples available for the given
+// predicate and object to the provided channel.
+func (g *graph) TriplesForPredicateAndObject(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	ts, err := g.scan(ctx, driver.AnchorRanges(key(poastIdx, p.PartialUUID(), o.UUID()), lo, p), lo, p)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// Exist checks if the provided triple exists on the store.
+func (g *graph) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
+	var ok bool
+	err := g.s.do(ctx, func(conn redigo.Conn) error {
+		var err error
+		ok, err = redigo.Bool(conn.Do("HEXISTS", g.triplesKey, []byte(t.UUID())))
+		return err
+	})
+	return ok, err
+}
+
+// Triples allows to iterate over all available triples by pushing them to the
+// provided channel.
+func (g *graph) Triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	var ts []*triple.Triple
+	if err := g.s.do(ctx, func(conn redigo.Conn) error {
+		vs, err := redigo.ByteSlices(conn.Do("HVALS", g.triplesKey))
+		if err != nil {
+			return err
+		}
+		for _, v := range vs {
+			t, err := driver.DecodeTriple(v)
+			if err != nil {
+				return err
+			}
+			ts = append(ts, t)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	ts, err := driver.Filter(ts, lo, nil)
+	if err != nil {
+		return err
+	}
+	return driver.SendTriples(ctx, ts, trpls)
+}
+
+// CountTriples returns the number of triples in the graph.
+func (g *graph) CountTriples(ctx context.Context) (int64, error) {
+	var n int64
+	err := g.s.do(ctx, func(conn redigo.Conn) error {
+		var err error
+		n, err = redigo.Int64(conn.Do("HLEN", g.triplesKey))
+		return err
+	})
+	return n, err
+}
+
+// Epoch returns the current epoch of the graph.
+func (g *graph) Epoch(ctx context.Context) (uint64, error) {
+	var e uint64
+	err := g.s.do(ctx, func(conn redigo.Conn) error {
+		var err error
+		e, err = redigo.Uint64(conn.Do("HGET", g.s.graphsKey(), g.id))
+		if err == redigo.ErrNil {
+			return fmt.Errorf("redis: graph %q does not exist", g.id)
+		}
+		return err
+	})
+	return e, err
+}
@@ -0,0 +1,349 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/internal/driver"
+	"github.com/google/badwolf/storage/internal/storagetest"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// newPool returns a pool of connections to the provided server.
+func newPool(m *miniredis.Miniredis) *redigo.Pool {
+	return &redigo.Pool{
+		Dial: func() (redigo.Conn, error) {
+			return redigo.Dial("tcp", m.Addr())
+		},
+	}
+}
+
+// newTestStore returns a store backed by an in memory Redis server, the
+// server, and a function that stops it.
+func newTestStore(t *testing.T) (*Store, *miniredis.Miniredis, func()) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := newPool(m)
+	s, err := NewStore(pool, "badwolf:")
+	if err != nil {
+		m.Close()
+		t.Fatalf("NewStore failed with error %v", err)
+	}
+	return s, m, func() {
+		pool.Close()
+		m.Close()
+	}
+}
+
+func getTestTriples(t *testing.T) []*triple.Triple {
+	var ts []*triple.Triple
+	for _, s := range []string{
+		"/u<john>\t\"parent_of\"@[]\t/u<mary>",
+		"/u<john>\t\"parent_of\"@[]\t/u<peter>",
+		"/u<john>\t\"parent_of\"@[]\t/u<alice>",
+		"/u<mary>\t\"parent_of\"@[]\t/u<amy>",
+		"/u<john>\t\"met\"@[1960-04-10T04:21:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[1969-12-31T23:59:59.5Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T04:25:00Z]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-04-10T04:30:00Z]\t/u<peter>",
+		"/u<john>\t\"met\"@[2016-04-10T04:30:00.000000001Z]\t/u<alice>",
+	} {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %q with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+func TestRedisStore(t *testing.T) {
+	s, _, clean := newTestStore(t)
+	defer clean()
+	ctx := context.Background()
+	if _, err := s.NewGraph(ctx, "?test"); err != nil {
+		t.Errorf("redis.NewGraph: should never fail to create a graph; %v", err)
+	}
+	if _, err := s.NewGraph(ctx, "?test"); err == nil {
+		t.Errorf("redis.NewGraph: should never succeed to create an existing graph")
+	}
+	if _, err := s.NewGraph(ctx, "?other"); err != nil {
+		t.Errorf("redis.NewGraph: should never fail to create a graph; %v", err)
+	}
+	if _, err := s.Graph(ctx, "?test"); err != nil {
+		t.Errorf("redis.Graph: should never fail to get an existing graph; %v", err)
+	}
+	gns := make(chan string, 10)
+	if err := s.GraphNames(ctx, gns); err != nil {
+		t.Errorf("redis.GraphNames failed with error %v", err)
+	}
+	var got []string
+	for n := range gns {
+		got = append(got, n)
+	}
+	if len(got) != 2 || got[0] != "?other" || got[1] != "?test" {
+		t.Errorf("redis.GraphNames returned %v; want [?other ?test]", got)
+	}
+	if err := s.DeleteGraph(ctx, "?test"); err != nil {
+		t.Errorf("redis.DeleteGraph: should never fail to delete an existing graph; %v", err)
+	}
+	if _, err := s.Graph(ctx, "?test"); err == nil {
+		t.Errorf("redis.Graph: should never succeed to get a non existing graph")
+	}
+	if err := s.DeleteGraph(ctx, "?test"); err == nil {
+		t.Errorf("redis.DeleteGraph: should never succeed to delete a non existing graph")
+	}
+}
+
+func TestLexRange(t *testing.T) {
+	testTable := []struct {
+		r        driver.KeyRange
+		min, max string
+	}{
+		{driver.KeyRange{Prefix: []byte("ab")}, "[ab", "(ac"},
+		{driver.KeyRange{Prefix: []byte("a\xff")}, "[a\xff", "(b"},
+		{driver.KeyRange{Prefix: []byte("\xff")}, "[\xff", "+"},
+		{driver.KeyRange{Prefix: []byte("a"), Start: []byte("ab"), End: []byte("ad")}, "[ab", "(ae"},
+		{driver.KeyRange{Prefix: []byte("a"), Start: []byte("ab")}, "[ab", "(b"},
+	}
+	for _, entry := range testTable {
+		min, max := lexRange(entry.r)
+		if bs, ok := max.([]byte); ok {
+			max = string(bs)
+		}
+		if string(min.([]byte)) != entry.min || max != entry.max {
+			t.Errorf("lexRange(%q) returned (%q, %q); want (%q, %q)", entry.r, min, max, entry.min, entry.max)
+		}
+	}
+}
+
+func TestLookups(t *testing.T) {
+	s, _, clean := newTestStore(t)
+	defer clean()
+	ctx, ts := context.Background(), getTestTriples(t)
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	john, mary := ts[0].Subject(), ts[0].Object()
+	parentOf, met := ts[0].Predicate(), ts[4].Predicate()
+	unknown, err := node.Parse("/u<nobody>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) *time.Time {
+		ta, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &ta
+	}
+	lo := storage.DefaultLookup
+	testTable := []struct {
+		name   string
+		lookup func(chan<- *triple.Triple) error
+		want   int
+	}{
+		{"Triples", func(c chan<- *triple.Triple) error { return g.Triples(ctx, lo, c) }, 9},
+		{"TriplesForSubject", func(c chan<- *triple.Triple) error { return g.TriplesForSubject(ctx, john, lo, c) }, 8},
+		{"TriplesForObject", func(c chan<- *triple.Triple) error { return g.TriplesForObject(ctx, mary, lo, c) }, 4},
+		{"TriplesForPredicate", func(c chan<- *triple.Triple) error { return g.TriplesForPredicate(ctx, parentOf, lo, c) }, 4},
+		{"TriplesForPredicateAndObject", func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicateAndObject(ctx, parentOf, mary, lo, c)
+		}, 1},
+		{"TriplesForPredicate anchored", func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicate(ctx, met, lo, c)
+		}, 1},
+		{"TriplesForPredicate lower bound", func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicate(ctx, parentOf, &storage.LookupOptions{LowerAnchor: at("2016-04-10T04:30:00Z")}, c)
+		}, 4},
+		{"TriplesForSubjectAndPredicate window", func(c chan<- *triple.Triple) error {
+			p, _ := predicate.NewImmutable("met")
+			return g.TriplesForSubjectAndPredicate(ctx, john, p, &storage.LookupOptions{
+				LowerAnchor: at("1969-12-31T23:59:59Z"),
+				UpperAnchor: at("2016-04-10T04:30:00Z"),
+			}, c)
+		}, 3},
+		{"TriplesForPredicateAndObject before epoch", func(c chan<- *triple.Triple) error {
+			p, _ := predicate.NewImmutable("met")
+			return g.TriplesForPredicateAndObject(ctx, p, mary, &storage.LookupOptions{UpperAnchor: at("1970-01-01T00:00:00Z")}, c)
+		}, 2},
+		{"TriplesForSubjectAndPredicate latest", func(c chan<- *triple.Triple) error {
+			return g.TriplesForSubjectAndPredicate(ctx, john, met, &storage.LookupOptions{LatestAnchor: true}, c)
+		}, 1},
+		{"Triples limit", func(c chan<- *triple.Triple) error {
+			return g.Triples(ctx, &storage.LookupOptions{MaxElements: 4}, c)
+		}, 4},
+		{"TriplesForSubject unknown", func(c chan<- *triple.Triple) error {
+			// The unknown subject is fixed, since miniredis returns all the
+			// members of a sorted set when none is above the lower bound of
+			// a lexicographical range, unlike Redis.
+			return g.TriplesForSubject(ctx, unknown, lo, c)
+		}, 0},
+	}
+	for _, entry := range testTable {
+		if got := storagetest.Collect(t, entry.lookup); len(got) != entry.want {
+			t.Errorf("%s returned %d triples %v; want %d", entry.name, len(got), got, entry.want)
+		}
+	}
+
+	objs := make(chan *triple.Object, 10)
+	if err := g.Objects(ctx, john, parentOf, lo, objs); err != nil {
+		t.Fatalf("g.Objects failed with error %v", err)
+	}
+	if cnt := len(objs); cnt != 3 {
+		t.Errorf("g.Objects(%s, %s) returned %d objects; want 3", john, parentOf, cnt)
+	}
+	subjs := make(chan *node.Node, 10)
+	if err := g.Subjects(ctx, parentOf, mary, lo, subjs); err != nil {
+		t.Fatalf("g.Subjects failed with error %v", err)
+	}
+	if cnt := len(subjs); cnt != 1 {
+		t.Errorf("g.Subjects(%s, %s) returned %d subjects; want 1", parentOf, mary, cnt)
+	}
+	for _, entry := range []struct {
+		name   string
+		lookup func(chan<- *predicate.Predicate) error
+		want   int
+	}{
+		{"PredicatesForSubject", func(c chan<- *predicate.Predicate) error { return g.PredicatesForSubject(ctx, john, lo, c) }, 8},
+		{"PredicatesForObject", func(c chan<- *predicate.Predicate) error { return g.PredicatesForObject(ctx, mary, lo, c) }, 4},
+		{"PredicatesForSubjectAndObject", func(c chan<- *predicate.Predicate) error {
+			return g.PredicatesForSubjectAndObject(ctx, john, mary, lo, c)
+		}, 4},
+	} {
+		prds := make(chan *predicate.Predicate, 10)
+		if err := entry.lookup(prds); err != nil {
+			t.Fatalf("%s failed with error %v", entry.name, err)
+		}
+		if cnt := len(prds); cnt != entry.want {
+			t.Errorf("%s returned %d predicates; want %d", entry.name, cnt, entry.want)
+		}
+	}
+}
+
+func TestAddRemoveExist(t *testing.T) {
+	s, _, clean := newTestStore(t)
+	defer clean()
+	ctx, ts := context.Background(), getTestTriples(t)
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e0, err := storage.Epoch(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Adding existing triples does not duplicate them.
+	for i := 0; i < 2; i++ {
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatalf("g.AddTriples failed with error %v", err)
+		}
+	}
+	if e, err := storage.Epoch(ctx, g); err != nil || e == e0 {
+		t.Errorf("storage.Epoch returned (%d, %v) after adding triples; want a new epoch", e, err)
+	}
+	if n, err := storage.CountTriples(ctx, g); err != nil || n != int64(len(ts)) {
+		t.Errorf("storage.CountTriples returned (%d, %v); want (%d, nil)", n, err, len(ts))
+	}
+	if err := g.RemoveTriples(ctx, ts[:3]); err != nil {
+		t.Fatalf("g.RemoveTriples failed with error %v", err)
+	}
+	for i, trpl := range ts {
+		ok, err := g.Exist(ctx, trpl)
+		if err != nil {
+			t.Fatalf("g.Exist(%s) failed with error %v", trpl, err)
+		}
+		if want := i >= 3; ok != want {
+			t.Errorf("g.Exist(%s) returned %v; want %v", trpl, ok, want)
+		}
+	}
+	got := storagetest.Collect(t, func(c chan<- *triple.Triple) error {
+		return g.TriplesForPredicate(ctx, ts[0].Predicate(), storage.DefaultLookup, c)
+	})
+	if len(got) != 1 {
+		t.Errorf("g.TriplesForPredicate(%s) returned %v after removing triples; want 1 triple", ts[0].Predicate(), got)
+	}
+	// Deleting the graph drops its triples, and changes to it fail.
+	if err := s.DeleteGraph(ctx, "?test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err == nil {
+		t.Errorf("g.AddTriples should fail for a deleted graph")
+	}
+	if g, err = s.NewGraph(ctx, "?test"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := storage.CountTriples(ctx, g); err != nil || n != 0 {
+		t.Errorf("storage.CountTriples returned (%d, %v) for a recreated graph; want (0, nil)", n, err)
+	}
+}
+
+func TestSharedGraphs(t *testing.T) {
+	s, m, clean := newTestStore(t)
+	defer clean()
+	ctx, ts := context.Background(), getTestTriples(t)
+	pool := newPool(m)
+	defer pool.Close()
+
+	// Stores sharing the server and namespace see the same graphs.
+	other, err := NewStore(pool, "badwolf:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	og, err := other.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatalf("redis.Graph failed to get a graph created by another store with error %v", err)
+	}
+	if n, err := storage.CountTriples(ctx, og); err != nil || n != int64(len(ts)) {
+		t.Errorf("storage.CountTriples returned (%d, %v) on another store; want (%d, nil)", n, err, len(ts))
+	}
+
+	// Stores on other namespaces do not.
+	isolated, err := NewStore(pool, "other:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := isolated.Graph(ctx, "?test"); err == nil {
+		t.Errorf("redis.Graph should fail to get a graph of another namespace")
+	}
+	for _, k := range m.Keys() {
+		if !strings.HasPrefix(k, "badwolf:") {
+			t.Errorf("store used key %q outside of its namespace", k)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// RetentionPolicy describes which triples of a graph expire. Only temporal
+// triples expire; immutable triples are kept forever.
+type RetentionPolicy struct {
+	// MaxAge is the maximum age of the time anchor of a temporal triple.
+	// Triples anchored before that are removed.
+	MaxAge time.Duration
+
+	// Predicates, if not empty, restricts the policy to the triples with these
+	// predicate IDs.
+	Predicates []string
+}
+
+// String returns a readable version of the RetentionPolicy instance.
+func (p *RetentionPolicy) String() string {
+	if len(p.Predicates) == 0 {
+		return fmt.Sprintf("<MaxAge=%v>", p.MaxAge)
+	}
+	return fmt.Sprintf("<MaxAge=%v, Predicates=%v>", p.MaxAge, p.Predicates)
+}
+
+// Expirer is an optional interface that graphs can implement to remove
+// expired triples without retrieving them first.
+type Expirer interface {
+	// Expire removes the temporal triples anchored before the provided time
+	// and returns how many were removed. If predicate IDs are provided, only
+	// the triples with those predicates are removed.
+	Expire(ctx context.Context, before time.Time, predicates []string) (int64, error)
+}
+
+// Expire removes from the provided graph the triples that expired according
+// to the policy at the provided time, and returns how many were removed. If
+// the graph does not implement Expirer, the triples anchored before the
+// cutoff are retrieved and then removed.
+func Expire(ctx context.Context, g Graph, p *RetentionPolicy, now time.Time) (int64, error) {
+	before := now.Add(-p.MaxAge)
+	if e, ok := g.(Expirer); ok {
+		return e.Expire(ctx, before, p.Predicates)
+	}
+	var expired []*triple.Triple
+	collect := func(t *triple.Triple) {
+		if ta, err := t.Predicate().TimeAnchor(); err == nil && ta.Before(before) {
+			expired = append(expired, t)
+		}
+	}
+	lo := &LookupOptions{UpperAnchor: &before}
+	if len(p.Predicates) == 0 {
+		if err := scan(func(c chan<- *triple.Triple) error {
+			return g.Triples(ctx, lo, c)
+		}, collect); err != nil {
+			return 0, err
+		}
+	}
+	for _, id := range p.Predicates {
+		pred, err := predicate.NewImmutable(id)
+		if err != nil {
+			return 0, err
+		}
+		if err := scan(func(c chan<- *triple.Triple) error {
+			return g.TriplesForPredicate(ctx, pred, lo, c)
+		}, collect); err != nil {
+			return 0, err
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	if err := g.RemoveTriples(ctx, expired); err != nil {
+		return 0, err
+	}
+	return int64(len(expired)), nil
+}
+
+// scan calls f for each triple published by the provided lookup.
+func scan(lookup func(chan<- *triple.Triple) error, f func(*triple.Triple)) error {
+	errs := make(chan error, 1)
+	trpls := make(chan *triple.Triple)
+	go func() {
+		errs <- lookup(trpls)
+	}()
+	for t := range trpls {
+		f(t)
+	}
+	return <-errs
+}
+
+// Janitor enforces the retention policies of the graphs of a store. Policies
+// are kept by the janitor, not by the store, so they need to be set again
+// every time the janitor is created.
+type Janitor struct {
+	store    Store
+	interval time.Duration
+
+	// OnExpire, if set, is called after enforcing the policy of a graph with
+	// the number of triples removed or the error found. It must be set before
+	// running the janitor.
+	OnExpire func(id string, removed int64, err error)
+
+	mu       sync.Mutex
+	policies map[string]*RetentionPolicy
+}
+
+// NewJanitor returns a janitor that enforces the retention policies of the
+// graphs of the provided store every interval.
+func NewJanitor(s Store, interval time.Duration) *Janitor {
+	return &Janitor{
+		store:    s,
+		interval: interval,
+		policies: make(map[string]*RetentionPolicy),
+	}
+}
+
+// SetPolicy sets the retention policy of the graph with the provided ID. A nil
+// policy removes the policy of the graph.
+func (j *Janitor) SetPolicy(id string, p *RetentionPolicy) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if p == nil {
+		delete(j.policies, id)
+		return
+	}
+	j.policies[id] = p
+}
+
+// Policy returns the retention policy of the graph with the provided ID, or
+// nil if it has none.
+func (j *Janitor) Policy(id string) *RetentionPolicy {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.policies[id]
+}
+
+// Enforce removes the expired triples of all the graphs with a retention
+// policy. Graphs that no longer exist are skipped. It returns the first error
+// found, after enforcing the policies of all the other graphs.
+func (j *Janitor) Enforce(ctx context.Context) error {
+	j.mu.Lock()
+	var ids []string
+	policies := make(map[string]*RetentionPolicy, len(j.policies))
+	for id, p := range j.policies {
+		ids = append(ids, id)
+		policies[id] = p
+	}
+	j.mu.Unlock()
+	sort.Strings(ids)
+
+	var first error
+	now := time.Now()
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		g, err := j.store.Graph(ctx, id)
+		if err != nil {
+			continue
+		}
+		n, err := Expire(ctx, g, policies[id], now)
+		if err != nil {
+			err = fmt.Errorf("storage: failed to expire triples of graph %q; %v", id, err)
+			if first == nil {
+				first = err
+			}
+		}
+		if j.OnExpire != nil {
+			j.OnExpire(id, n, err)
+		}
+	}
+	return first
+}
+
+// Run enforces the retention policies every interval until the context is
+// done. Errors are reported through OnExpire and do not stop the janitor.
+func (j *Janitor) Run(ctx context.Context) error {
+	t := time.NewTicker(j.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			j.Enforce(ctx)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// GraphSnapshot is a read only view of the triples of a graph at the time the
+// snapshot was taken. Changes done to the graph afterwards are not visible
+// through the snapshot, and changing the snapshot fails.
+type GraphSnapshot interface {
+	Graph
+
+	// Release frees the resources held by the snapshot. The snapshot must not
+	// be used once released.
+	Release(ctx context.Context) error
+}
+
+// SnapshotProvider is an optional interface that graphs can implement to
+// provide consistent read views of their triples.
+type SnapshotProvider interface {
+	// Snapshot returns a snapshot of the current triples of the graph. Taking
+	// a snapshot should be much cheaper than copying the triples of the graph.
+	Snapshot(ctx context.Context) (GraphSnapshot, error)
+}
+
+// ErrNoSnapshots is returned when requesting a snapshot of a graph that does
+// not implement SnapshotProvider.
+var ErrNoSnapshots = errors.New("storage: the graph does not provide snapshots")
+
+// Snapshot returns a snapshot of the provided graph. Graphs are never copied
+// to emulate snapshots; if the graph does not implement SnapshotProvider,
+// ErrNoSnapshots is returned.
+func Snapshot(ctx context.Context, g Graph) (GraphSnapshot, error) {
+	if sp, ok := g.(SnapshotProvider); ok {
+		return sp.Snapshot(ctx)
+	}
+	return nil, ErrNoSnapshots
+}
@@ -18,11 +18,14 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
 	"github.com/google/badwolf/triple/node"
 	"github.com/google/badwolf/triple/predicate"
 	"github.com/pborman/uuid"
@@ -43,6 +46,87 @@ type LookupOptions struct {
 	// LatestAnchor only. If set, it will ignore the time boundaries provided and
 	// just use the last available anchor.
 	LatestAnchor bool
+
+	// LiteralFilter, if provided, only keeps the triples whose object is a
+	// literal accepted by the filter. When combined with LatestAnchor, the
+	// filter is applied to the triples with the latest anchor.
+	LiteralFilter *LiteralFilter
+}
+
+// LiteralFilter accepts the literals whose value is within a range. Numeric
+// literals are compared by value regardless of being int64 or float64. Other
+// literals are only accepted if they have the same type as the bounds.
+type LiteralFilter struct {
+	// Lower, if provided, is the lower bound of the accepted values.
+	Lower *literal.Literal
+	// LowerStrict excludes the lower bound from the accepted values.
+	LowerStrict bool
+	// Upper, if provided, is the upper bound of the accepted values.
+	Upper *literal.Literal
+	// UpperStrict excludes the upper bound from the accepted values.
+	UpperStrict bool
+}
+
+// String returns a readable version of the LiteralFilter instance.
+func (f *LiteralFilter) String() string {
+	var b bytes.Buffer
+	if f.LowerStrict {
+		b.WriteString("(")
+	} else {
+		b.WriteString("[")
+	}
+	if f.Lower != nil {
+		b.WriteString(f.Lower.String())
+	}
+	b.WriteString(", ")
+	if f.Upper != nil {
+		b.WriteString(f.Upper.String())
+	}
+	if f.UpperStrict {
+		b.WriteString(")")
+	} else {
+		b.WriteString("]")
+	}
+	return b.String()
+}
+
+// Accept returns true if the provided object is a literal within the range of
+// the filter.
+func (f *LiteralFilter) Accept(o *triple.Object) bool {
+	l, err := o.Literal()
+	if err != nil {
+		return false
+	}
+	if f.Lower != nil {
+		c, ok := CompareLiterals(l, f.Lower)
+		if !ok || c < 0 || (c == 0 && f.LowerStrict) {
+			return false
+		}
+	}
+	if f.Upper != nil {
+		c, ok := CompareLiterals(l, f.Upper)
+		if !ok || c > 0 || (c == 0 && f.UpperStrict) {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareLiterals returns -1, 0, or 1 if the first literal is respectively
+// lower, equal, or greater than the second one. The boolean is false if the
+// literals are not comparable. Numeric literals are compared by value; other
+// literals need to be of the same type. See literal.Compare for the order.
+func CompareLiterals(a, b *literal.Literal) (int, bool) {
+	if !literal.Comparable(a, b) {
+		return 0, false
+	}
+	return literal.Compare(a, b), true
+}
+
+// AcceptObject returns true if the provided object passes the literal filter
+// of the lookup options, if any.
+func (l *LookupOptions) AcceptObject(o *triple.Object) bool {
+	return l.LiteralFilter == nil || l.LiteralFilter.Accept(o)
 }
 
 // String returns a readable version of the LookupOptions instance.
@@ -62,6 +146,10 @@ func (l *LookupOptions) String() string {
 		b.WriteString("nil")
 	}
 	b.WriteString(fmt.Sprintf(", LatestAnchor=%v", l.LatestAnchor))
+	if l.LiteralFilter != nil {
+		b.WriteString(", literal_filter=")
+		b.WriteString(l.LiteralFilter.String())
+	}
 	b.WriteString(">")
 	return b.String()
 }
@@ -256,3 +344,278 @@ type Graph interface {
 	// elements in the channel.
 	Triples(ctx context.Context, lo *LookupOptions, trpls chan<- *triple.Triple) error
 }
+
+// TripleCounter is an optional interface that graphs can implement to count
+// their triples without retrieving them.
+type TripleCounter interface {
+	// CountTriples returns the number of triples in the graph.
+	CountTriples(ctx context.Context) (int64, error)
+}
+
+// PredicateIDLister is an optional interface that graphs can implement to
+// list the predicate IDs they use without retrieving all their triples.
+type PredicateIDLister interface {
+	// PredicateIDs returns the sorted list of unique predicate IDs used in the
+	// graph.
+	PredicateIDs(ctx context.Context) ([]string, error)
+}
+
+// GraphStatistics summarizes the shape of a graph. Query planners use them to
+// estimate how many triples a graph pattern clause will match.
+type GraphStatistics struct {
+	// Triples is the number of triples in the graph.
+	Triples int64
+	// Subjects is the number of distinct subjects in the graph.
+	Subjects int64
+	// Objects is the number of distinct objects in the graph.
+	Objects int64
+	// Predicates contains the number of triples for each predicate ID used in
+	// the graph. Temporal predicates sharing the same ID are counted together.
+	Predicates map[string]int64
+	// SubjectDegrees is a histogram of the number of triples per subject.
+	// Bucket i counts the subjects with between 2^i and 2^(i+1)-1 triples.
+	SubjectDegrees []int64
+	// FirstAnchor and LastAnchor are the earliest and the latest time anchors
+	// of the temporal triples in the graph. Both are nil if the graph has no
+	// temporal triples.
+	FirstAnchor, LastAnchor *time.Time
+	// MemoryBytes is an approximation of the memory used by the graph. It is
+	// zero if the driver does not track it.
+	MemoryBytes int64
+}
+
+// AddAnchor extends the anchor time span of the statistics to include the
+// provided time anchor.
+func (s *GraphStatistics) AddAnchor(ta time.Time) {
+	if s.FirstAnchor == nil || ta.Before(*s.FirstAnchor) {
+		s.FirstAnchor = &ta
+	}
+	if s.LastAnchor == nil || ta.After(*s.LastAnchor) {
+		s.LastAnchor = &ta
+	}
+}
+
+// AddSubjectDegree records in the subject degree histogram a subject with the
+// provided number of triples.
+func (s *GraphStatistics) AddSubjectDegree(d int64) {
+	if d <= 0 {
+		return
+	}
+	b := 0
+	for ; d > 1; d >>= 1 {
+		b++
+	}
+	for len(s.SubjectDegrees) <= b {
+		s.SubjectDegrees = append(s.SubjectDegrees, 0)
+	}
+	s.SubjectDegrees[b]++
+}
+
+// MedianSubjectDegree returns an estimate of the median number of triples per
+// subject based on the subject degree histogram. Unlike the average, the
+// median is not skewed by a few subjects with lots of triples. It returns 0
+// for empty graphs.
+func (s *GraphStatistics) MedianSubjectDegree() int64 {
+	var total int64
+	for _, c := range s.SubjectDegrees {
+		total += c
+	}
+	var acc int64
+	for b, c := range s.SubjectDegrees {
+		acc += c
+		if 2*acc >= total && c > 0 {
+			return int64(1) << uint(b)
+		}
+	}
+	return 0
+}
+
+// Merge adds the statistics of another graph to the current ones. Since
+// graphs may share subjects and objects, the merged distinct counts are upper
+// bounds.
+func (s *GraphStatistics) Merge(o *GraphStatistics) {
+	s.Triples += o.Triples
+	s.Subjects += o.Subjects
+	s.Objects += o.Objects
+	if s.Predicates == nil {
+		s.Predicates = make(map[string]int64)
+	}
+	for id, c := range o.Predicates {
+		s.Predicates[id] += c
+	}
+	for b, c := range o.SubjectDegrees {
+		if b >= len(s.SubjectDegrees) {
+			s.SubjectDegrees = append(s.SubjectDegrees, 0)
+		}
+		s.SubjectDegrees[b] += c
+	}
+	if o.FirstAnchor != nil {
+		s.AddAnchor(*o.FirstAnchor)
+	}
+	if o.LastAnchor != nil {
+		s.AddAnchor(*o.LastAnchor)
+	}
+	s.MemoryBytes += o.MemoryBytes
+}
+
+// StatisticsProvider is an optional interface that graphs can implement to
+// provide statistics about their triples.
+type StatisticsProvider interface {
+	// Statistics returns the current statistics of the graph. Computing them
+	// should be much cheaper than retrieving all the triples in the graph.
+	Statistics(ctx context.Context) (*GraphStatistics, error)
+}
+
+// ErrNoStatistics is returned when requesting the statistics of a graph that
+// does not implement StatisticsProvider.
+var ErrNoStatistics = errors.New("storage: the graph does not provide statistics")
+
+// Statistics returns the statistics of the provided graph. Since statistics
+// are meant to be cheap, the graph is never scanned to compute them; if it
+// does not implement StatisticsProvider, ErrNoStatistics is returned.
+func Statistics(ctx context.Context, g Graph) (*GraphStatistics, error) {
+	if sp, ok := g.(StatisticsProvider); ok {
+		return sp.Statistics(ctx)
+	}
+	return nil, ErrNoStatistics
+}
+
+// ScanStatistics returns the statistics of the provided graph, computing them
+// out of all its triples if the graph does not implement StatisticsProvider.
+// Memory usage is only reported by graphs providing their own statistics.
+func ScanStatistics(ctx context.Context, g Graph) (*GraphStatistics, error) {
+	if sp, ok := g.(StatisticsProvider); ok {
+		return sp.Statistics(ctx)
+	}
+	s := &GraphStatistics{Predicates: make(map[string]int64)}
+	sbjs, objs := make(map[string]int64), make(map[string]bool)
+	if err := scanTriples(ctx, g, func(t *triple.Triple) {
+		s.Triples++
+		sbjs[t.Subject().String()]++
+		objs[t.Object().String()] = true
+		p := t.Predicate()
+		s.Predicates[string(p.ID())]++
+		if ta, err := p.TimeAnchor(); err == nil {
+			s.AddAnchor(*ta)
+		}
+	}); err != nil {
+		return nil, err
+	}
+	s.Subjects, s.Objects = int64(len(sbjs)), int64(len(objs))
+	for _, d := range sbjs {
+		s.AddSubjectDegree(d)
+	}
+	return s, nil
+}
+
+// GraphMetadata describes a graph.
+type GraphMetadata struct {
+	// Created is the time the graph was created.
+	Created time.Time
+
+	// Description is a free form description of the graph.
+	Description string
+
+	// Labels are arbitrary key/value pairs used to annotate and find graphs.
+	Labels map[string]string
+}
+
+// MetadataProvider is an optional interface that stores can implement to
+// keep metadata about their graphs.
+type MetadataProvider interface {
+	// GraphMetadata returns the metadata of the graph with the provided ID.
+	GraphMetadata(ctx context.Context, id string) (*GraphMetadata, error)
+
+	// SetGraphMetadata replaces the description and labels of the graph with
+	// the provided ID. The creation time of the graph cannot be changed.
+	SetGraphMetadata(ctx context.Context, id string, md *GraphMetadata) error
+}
+
+// ErrNoMetadata is returned when accessing the metadata of the graphs of a
+// store that does not implement MetadataProvider.
+var ErrNoMetadata = errors.New("storage: the store does not keep graph metadata")
+
+// GetGraphMetadata returns the metadata of the graph with the provided ID. If
+// the store does not implement MetadataProvider, ErrNoMetadata is returned.
+func GetGraphMetadata(ctx context.Context, s Store, id string) (*GraphMetadata, error) {
+	if mp, ok := s.(MetadataProvider); ok {
+		return mp.GraphMetadata(ctx, id)
+	}
+	return nil, ErrNoMetadata
+}
+
+// SetGraphMetadata replaces the description and labels of the graph with the
+// provided ID. If the store does not implement MetadataProvider, ErrNoMetadata
+// is returned.
+func SetGraphMetadata(ctx context.Context, s Store, id string, md *GraphMetadata) error {
+	if mp, ok := s.(MetadataProvider); ok {
+		return mp.SetGraphMetadata(ctx, id, md)
+	}
+	return ErrNoMetadata
+}
+
+// EpochProvider is an optional interface that graphs can implement to expose
+// a version of their triples. Caches use it to know when results computed out
+// of a graph are no longer valid.
+type EpochProvider interface {
+	// Epoch returns the current epoch of the graph. The epoch changes every
+	// time triples are added to or removed from the graph, and graphs
+	// recreated with the same ID never reuse the epochs of the previous ones.
+	Epoch(ctx context.Context) (uint64, error)
+}
+
+// ErrNoEpoch is returned when requesting the epoch of a graph that does not
+// implement EpochProvider.
+var ErrNoEpoch = errors.New("storage: the graph does not provide an epoch")
+
+// Epoch returns the current epoch of the provided graph. If the graph does
+// not implement EpochProvider, ErrNoEpoch is returned.
+func Epoch(ctx context.Context, g Graph) (uint64, error) {
+	if ep, ok := g.(EpochProvider); ok {
+		return ep.Epoch(ctx)
+	}
+	return 0, ErrNoEpoch
+}
+
+// CountTriples returns the number of triples in the provided graph. If the
+// graph does not implement TripleCounter, all its triples are retrieved to
+// count them.
+func CountTriples(ctx context.Context, g Graph) (int64, error) {
+	if tc, ok := g.(TripleCounter); ok {
+		return tc.CountTriples(ctx)
+	}
+	var cnt int64
+	err := scanTriples(ctx, g, func(*triple.Triple) {
+		cnt++
+	})
+	return cnt, err
+}
+
+// PredicateIDs returns the sorted list of unique predicate IDs used in the
+// provided graph. If the graph does not implement PredicateIDLister, all its
+// triples are retrieved to collect them.
+func PredicateIDs(ctx context.Context, g Graph) ([]string, error) {
+	if pl, ok := g.(PredicateIDLister); ok {
+		return pl.PredicateIDs(ctx)
+	}
+	m := make(map[string]bool)
+	err := scanTriples(ctx, g, func(t *triple.Triple) {
+		m[string(t.Predicate().ID())] = true
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// scanTriples calls f for each triple in the provided graph.
+func scanTriples(ctx context.Context, g Graph, f func(*triple.Triple)) error {
+	return scan(func(trpls chan<- *triple.Triple) error {
+		return g.Triples(ctx, &LookupOptions{}, trpls)
+	}, f)
+}
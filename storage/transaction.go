@@ -0,0 +1,336 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/badwolf/triple"
+)
+
+// Transaction groups a sequence of changes to a store that are either all
+// applied or none of them is. The transaction is itself a store; all the
+// changes need to be done through it and are visible to it before they are
+// committed.
+type Transaction interface {
+	Store
+
+	// Commit makes the changes done in the transaction permanent. The
+	// transaction cannot be used after it is committed.
+	Commit(ctx context.Context) error
+
+	// Rollback undoes all the changes done in the transaction. The transaction
+	// cannot be used after it is rolled back.
+	Rollback(ctx context.Context) error
+}
+
+// Transactor is an optional interface that stores can implement to provide
+// their own transactions.
+type Transactor interface {
+	// Begin starts a new transaction on the store.
+	Begin(ctx context.Context) (Transaction, error)
+}
+
+// Begin starts a new transaction on the provided store. If the store does not
+// implement Transactor, the returned transaction applies the changes to the
+// store as they are made and keeps a log to undo them on rollback. Such
+// transactions guarantee that either all or none of the changes are applied,
+// but other users of the store may observe the changes before they are
+// committed.
+func Begin(ctx context.Context, s Store) (Transaction, error) {
+	if t, ok := s.(Transactor); ok {
+		return t.Begin(ctx)
+	}
+	return &undoTransaction{store: s}, nil
+}
+
+// GraphTransaction groups a sequence of changes to a single graph that are
+// either all applied or none of them is. The transaction is itself a graph;
+// all the changes need to be done through it and are visible to it before
+// they are committed.
+type GraphTransaction interface {
+	Graph
+
+	// Commit makes the changes done in the transaction permanent. The
+	// transaction cannot be changed after it is committed.
+	Commit(ctx context.Context) error
+
+	// Rollback undoes all the changes done in the transaction. The transaction
+	// cannot be changed after it is rolled back.
+	Rollback(ctx context.Context) error
+}
+
+// GraphTransactor is an optional interface that graphs can implement to
+// provide their own transactions.
+type GraphTransactor interface {
+	// Begin starts a new transaction on the graph.
+	Begin(ctx context.Context) (GraphTransaction, error)
+}
+
+// BeginGraph starts a new transaction on the provided graph. If the graph does
+// not implement GraphTransactor, the returned transaction applies the changes
+// to the graph as they are made and keeps a log to undo them on rollback, with
+// the same guarantees as the transactions returned by Begin for stores that do
+// not implement Transactor.
+func BeginGraph(ctx context.Context, g Graph) (GraphTransaction, error) {
+	if t, ok := g.(GraphTransactor); ok {
+		return t.Begin(ctx)
+	}
+	return &undoGraphTransaction{&undoGraph{Graph: g, tx: &undoTransaction{}}}, nil
+}
+
+// undoTransaction implements a transaction that records how to undo each of
+// the changes applied to the underlying store.
+type undoTransaction struct {
+	store Store
+
+	mu   sync.Mutex
+	done bool
+	undo []func(ctx context.Context) error
+}
+
+// record adds a new step to the undo log.
+func (t *undoTransaction) record(f func(ctx context.Context) error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.undo = append(t.undo, f)
+}
+
+// check returns an error if the transaction was already finished.
+func (t *undoTransaction) check() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return errors.New("storage: the transaction was already committed or rolled back")
+	}
+	return nil
+}
+
+// Name returns the ID of the backend being used.
+func (t *undoTransaction) Name(ctx context.Context) string {
+	return t.store.Name(ctx)
+}
+
+// Version returns the version of the driver implementation.
+func (t *undoTransaction) Version(ctx context.Context) string {
+	return t.store.Version(ctx)
+}
+
+// NewGraph creates a new graph that will be deleted on rollback.
+func (t *undoTransaction) NewGraph(ctx context.Context, id string) (Graph, error) {
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	g, err := t.store.NewGraph(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	t.record(func(ctx context.Context) error {
+		return t.store.DeleteGraph(ctx, id)
+	})
+	return &undoGraph{Graph: g, tx: t}, nil
+}
+
+// Graph returns an existing graph whose changes are recorded in the
+// transaction.
+func (t *undoTransaction) Graph(ctx context.Context, id string) (Graph, error) {
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	g, err := t.store.Graph(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &undoGraph{Graph: g, tx: t}, nil
+}
+
+// DeleteGraph deletes an existing graph. Its triples are retrieved before
+// deleting it to be able to recreate it on rollback.
+func (t *undoTransaction) DeleteGraph(ctx context.Context, id string) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	g, err := t.store.Graph(ctx, id)
+	if err != nil {
+		return err
+	}
+	var ts []*triple.Triple
+	if err := scanTriples(ctx, g, func(tr *triple.Triple) {
+		ts = append(ts, tr)
+	}); err != nil {
+		return err
+	}
+	if err := t.store.DeleteGraph(ctx, id); err != nil {
+		return err
+	}
+	t.record(func(ctx context.Context) error {
+		g, err := t.store.NewGraph(ctx, id)
+		if err != nil {
+			return err
+		}
+		return g.AddTriples(ctx, ts)
+	})
+	return nil
+}
+
+// GraphNames returns the current available graph names in the store.
+func (t *undoTransaction) GraphNames(ctx context.Context, names chan<- string) error {
+	return t.store.GraphNames(ctx, names)
+}
+
+// Commit discards the undo log.
+func (t *undoTransaction) Commit(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return errors.New("storage: cannot commit a transaction that was already finished")
+	}
+	t.done, t.undo = true, nil
+	return nil
+}
+
+// Rollback applies the undo log in reverse order. All the steps are applied
+// even if some of them fail, and the errors found are returned together.
+func (t *undoTransaction) Rollback(ctx context.Context) error {
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return errors.New("storage: cannot roll back a transaction that was already finished")
+	}
+	undo := t.undo
+	t.done, t.undo = true, nil
+	t.mu.Unlock()
+	var errs []string
+	for i := len(undo) - 1; i >= 0; i-- {
+		if err := undo[i](ctx); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("storage: failed to roll back transaction; %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// undoGraph records in the transaction how to undo the changes applied to the
+// graph. All lookups are served by the underlying graph.
+type undoGraph struct {
+	Graph
+	tx *undoTransaction
+}
+
+// AddTriples adds the triples to the graph. Only the triples that were not
+// already present are removed on rollback. The undo step is recorded before
+// adding them, so the triples added by a call that fails partway are also
+// removed.
+func (g *undoGraph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	if err := g.tx.check(); err != nil {
+		return err
+	}
+	added, err := g.filter(ctx, ts, false)
+	if err != nil {
+		return err
+	}
+	if len(added) > 0 {
+		g.tx.record(func(ctx context.Context) error {
+			return g.undo(ctx, func(ug Graph) error {
+				return ug.RemoveTriples(ctx, added)
+			})
+		})
+	}
+	return g.Graph.AddTriples(ctx, ts)
+}
+
+// RemoveTriples removes the triples from the graph. Only the triples that
+// were present are added back on rollback. As done by AddTriples, the undo
+// step is recorded before removing them.
+func (g *undoGraph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	if err := g.tx.check(); err != nil {
+		return err
+	}
+	removed, err := g.filter(ctx, ts, true)
+	if err != nil {
+		return err
+	}
+	if len(removed) > 0 {
+		g.tx.record(func(ctx context.Context) error {
+			return g.undo(ctx, func(ug Graph) error {
+				return ug.AddTriples(ctx, removed)
+			})
+		})
+	}
+	return g.Graph.RemoveTriples(ctx, ts)
+}
+
+// Statistics returns the statistics of the underlying graph.
+func (g *undoGraph) Statistics(ctx context.Context) (*GraphStatistics, error) {
+	return Statistics(ctx, g.Graph)
+}
+
+// Epoch returns the epoch of the underlying graph.
+func (g *undoGraph) Epoch(ctx context.Context) (uint64, error) {
+	return Epoch(ctx, g.Graph)
+}
+
+// undo applies the provided function to the graph with the same ID in the
+// underlying store. The graph is retrieved again since it may have been
+// deleted and recreated by later changes that were already undone. Graph
+// transactions have no underlying store and apply it to the graph itself.
+func (g *undoGraph) undo(ctx context.Context, f func(Graph) error) error {
+	if g.tx.store == nil {
+		return f(g.Graph)
+	}
+	ug, err := g.tx.store.Graph(ctx, g.ID(ctx))
+	if err != nil {
+		return err
+	}
+	return f(ug)
+}
+
+// filter returns the triples whose existence in the graph matches the
+// provided one.
+func (g *undoGraph) filter(ctx context.Context, ts []*triple.Triple, exist bool) ([]*triple.Triple, error) {
+	var res []*triple.Triple
+	for _, t := range ts {
+		ok, err := g.Graph.Exist(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		if ok == exist {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// undoGraphTransaction implements a graph transaction that records how to
+// undo each of the changes applied to the graph.
+type undoGraphTransaction struct {
+	*undoGraph
+}
+
+// Commit discards the undo log.
+func (t *undoGraphTransaction) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+// Rollback applies the undo log in reverse order.
+func (t *undoGraphTransaction) Rollback(ctx context.Context) error {
+	return t.tx.Rollback(ctx)
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/badwolf/triple"
+)
+
+// VectorSearcher is an optional interface that graphs can implement to find
+// the vector literals most similar to a given one, as measured by their cosine
+// similarity, among the objects of the triples of a predicate. Searches may be
+// approximate, so they are allowed to miss some of the most similar vectors.
+type VectorSearcher interface {
+	// NearestObjects returns up to k distinct vector literal objects of the
+	// triples whose predicate has the provided ID, ordered from the most to
+	// the least similar to the provided vector. Vectors with a different number
+	// of dimensions are ignored. It returns ErrNoVectorIndex if the vectors of
+	// the predicate are not indexed.
+	NearestObjects(ctx context.Context, id string, q []float64, k int) ([]*triple.Object, error)
+}
+
+// VectorIndexer is an optional interface that graphs can implement to index
+// the vector literals bound as objects of the triples of a predicate, so they
+// can be searched by similarity.
+type VectorIndexer interface {
+	VectorSearcher
+
+	// IndexVectors indexes the vectors of the triples whose predicate has the
+	// provided ID. The index is kept up to date as triples change.
+	IndexVectors(ctx context.Context, id string) error
+
+	// DropVectorIndex removes the index of the vectors of the triples whose
+	// predicate has the provided ID. Dropping a missing index is a no-op.
+	DropVectorIndex(ctx context.Context, id string) error
+}
+
+// ErrNoVectorIndex is returned when searching the vectors of a predicate that
+// are not indexed, or when indexing vectors in a graph that does not
+// implement VectorIndexer.
+var ErrNoVectorIndex = errors.New("storage: the graph has no vector index for the predicate")
+
+// IndexVectors indexes the vectors of the triples whose predicate has the
+// provided ID. If the graph does not implement VectorIndexer, ErrNoVectorIndex
+// is returned.
+func IndexVectors(ctx context.Context, g Graph, id string) error {
+	if vi, ok := g.(VectorIndexer); ok {
+		return vi.IndexVectors(ctx, id)
+	}
+	return ErrNoVectorIndex
+}
+
+// DropVectorIndex removes the index of the vectors of the triples whose
+// predicate has the provided ID. If the graph does not implement
+// VectorIndexer, ErrNoVectorIndex is returned.
+func DropVectorIndex(ctx context.Context, g Graph, id string) error {
+	if vi, ok := g.(VectorIndexer); ok {
+		return vi.DropVectorIndex(ctx, id)
+	}
+	return ErrNoVectorIndex
+}
+
+// NearestObjects returns up to k distinct vector literal objects of the
+// triples whose predicate has the provided ID, ordered from the most to the
+// least similar to the provided vector. If the graph does not implement
+// VectorSearcher, ErrNoVectorIndex is returned.
+func NearestObjects(ctx context.Context, g Graph, id string, q []float64, k int) ([]*triple.Object, error) {
+	if vs, ok := g.(VectorSearcher); ok {
+		return vs.NearestObjects(ctx, id, q, k)
+	}
+	return nil, ErrNoVectorIndex
+}
@@ -20,7 +20,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
+
+	"github.com/google/badwolf/bql/table"
 )
 
 // Command is an implementation of a BadWolf command. It is model after the
@@ -67,3 +71,83 @@ func (c *Command) Usage() int {
 func (c *Command) Runnable() bool {
 	return c.Run != nil
 }
+
+// WithInterrupt returns a copy of the provided context that gets canceled when
+// the process receives an interrupt signal, for instance when the user hits
+// Ctrl-C. The returned function stops listening for the signal and releases
+// the context; it needs to be called once the context is no longer used.
+func WithInterrupt(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	sig, done := make(chan os.Signal, 1), make(chan bool)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sig)
+		close(done)
+		cancel()
+	}
+}
+
+// Defaults contains the values used by the commands when the corresponding
+// flags are not provided, usually set by the profile of the configuration file
+// in use.
+type Defaults struct {
+	// Graph contains the graph names, separated by commas, used by the commands
+	// that read or write graphs.
+	Graph string
+
+	// Format is the format used to print the results of the queries.
+	Format table.Format
+
+	// Addr is the address the server listens on.
+	Addr string
+
+	// TLSCert and TLSKey are the certificate and key files the server uses to
+	// serve HTTPS.
+	TLSCert string
+	TLSKey  string
+}
+
+// defaultsKey is the context key used to store the command defaults.
+type defaultsKey struct{}
+
+// WithDefaults returns a copy of the provided context carrying the provided
+// defaults for the commands run with it.
+func WithDefaults(ctx context.Context, d Defaults) context.Context {
+	return context.WithValue(ctx, defaultsKey{}, d)
+}
+
+// DefaultsFrom returns the command defaults carried by the provided context,
+// or the zero value if it carries none.
+func DefaultsFrom(ctx context.Context) Defaults {
+	d, _ := ctx.Value(defaultsKey{}).(Defaults)
+	return d
+}
+
+// FormatFlag extracts the --format flag from the provided arguments. It
+// returns the requested format of the query results, the default format
+// carried by the context or table.FormatTable if the flag is not provided, and
+// the rest of the arguments.
+func FormatFlag(ctx context.Context, args []string) (table.Format, []string, error) {
+	f, rest := DefaultsFrom(ctx).Format, make([]string, 0, len(args))
+	if f == "" {
+		f = table.FormatTable
+	}
+	for _, a := range args {
+		if !strings.HasPrefix(a, "--format=") {
+			rest = append(rest, a)
+			continue
+		}
+		var err error
+		if f, err = table.ParseFormat(strings.TrimPrefix(a, "--format=")); err != nil {
+			return "", nil, err
+		}
+	}
+	return f, rest, nil
+}
@@ -28,12 +28,15 @@ import (
 	"github.com/google/badwolf/tools/vcli/bw/assert"
 	"github.com/google/badwolf/tools/vcli/bw/benchmark"
 	"github.com/google/badwolf/tools/vcli/bw/command"
+	"github.com/google/badwolf/tools/vcli/bw/diff"
 	"github.com/google/badwolf/tools/vcli/bw/export"
 	"github.com/google/badwolf/tools/vcli/bw/load"
 	"github.com/google/badwolf/tools/vcli/bw/repl"
 	"github.com/google/badwolf/tools/vcli/bw/run"
 	"github.com/google/badwolf/tools/vcli/bw/server"
+	"github.com/google/badwolf/tools/vcli/bw/validate"
 	"github.com/google/badwolf/tools/vcli/bw/version"
+	"github.com/google/badwolf/tools/vcli/bw/watch"
 	"github.com/google/badwolf/triple/literal"
 )
 
@@ -106,12 +109,15 @@ func InitializeCommands(driver storage.Store, chanSize, bulkTripleOpSize, builde
 	return []*command.Command{
 		assert.New(driver, literal.DefaultBuilder(), chanSize, bulkTripleOpSize),
 		benchmark.New(driver, chanSize, bulkTripleOpSize),
+		diff.New(driver, chanSize),
 		export.New(driver, bulkTripleOpSize),
 		load.New(driver, bulkTripleOpSize, builderSize),
 		run.New(driver, chanSize, bulkTripleOpSize),
 		repl.New(driver, chanSize, bulkTripleOpSize, builderSize, rl, done),
 		server.New(driver, chanSize, bulkTripleOpSize),
+		validate.New(driver),
 		version.New(),
+		watch.New(driver, chanSize, bulkTripleOpSize),
 	}
 }
 
@@ -140,12 +146,13 @@ func Eval(ctx context.Context, args []string, cmds []*command.Command) int {
 	return 1
 }
 
-// Run executes the main of the command line tool.
-func Run(driverName string, args []string, drivers map[string]StoreGenerator, chanSize, bulkTripleOpSize, builderSize int, rl repl.ReadLiner) int {
+// Run executes the main of the command line tool. The commands use the defaults
+// carried by the provided context.
+func Run(ctx context.Context, driverName string, args []string, drivers map[string]StoreGenerator, chanSize, bulkTripleOpSize, builderSize int, rl repl.ReadLiner) int {
 	driver, err := InitializeDriver(driverName, drivers)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 2
 	}
-	return Eval(context.Background(), args, InitializeCommands(driver, chanSize, bulkTripleOpSize, builderSize, rl, make(chan bool)))
+	return Eval(ctx, args, InitializeCommands(driver, chanSize, bulkTripleOpSize, builderSize, rl, make(chan bool)))
 }
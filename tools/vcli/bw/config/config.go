@@ -0,0 +1,327 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config reads the configuration file of the command line tool. The
+// file defines named profiles, so the same set of flags does not need to be
+// repeated on every invocation of the tool.
+//
+// The configuration file uses a subset of TOML. Each profile is a table named
+// profile.<name>, and the optional top level profile key names the profile
+// used when none is requested. Keys are bare, and values are single line
+// strings or bare values such as numbers and booleans. Other tables, arrays,
+// inline tables, multi-line strings, and dotted or quoted keys are rejected.
+//
+//	# Profile used by default.
+//	profile = "local"
+//
+//	[profile.local]
+//	driver = "BOLT"
+//	bolt_path = "/tmp/badwolf.db"
+//	graph = "?test"
+//
+//	[profile.prod]
+//	driver = "REDIS"
+//	redis_addr = "redis.example.com:6379"
+//	addr = ":8443"
+//	tls_cert = "/etc/badwolf/cert.pem"
+//	tls_key = "/etc/badwolf/key.pem"
+//	format = "json"
+//
+// The settings of a profile are either the names of the flags of the tool,
+// such as driver or bolt_path, or the defaults of the commands: graph, the
+// graph names used by the load and export commands; format, the format used to
+// print query results; and addr, tls_cert, and tls_key, the address the server
+// listens on and the certificate and key files used to serve HTTPS.
+package config
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/tools/vcli/bw/command"
+)
+
+// Profile is a named set of settings.
+type Profile struct {
+	// Name of the profile.
+	Name string
+
+	// Settings of the profile keyed by their names.
+	Settings map[string]string
+}
+
+// Config contains the profiles defined by a configuration file.
+type Config struct {
+	// Default is the name of the profile used when none is requested.
+	Default string
+
+	// Profiles contains the profiles defined keyed by their names.
+	Profiles map[string]*Profile
+}
+
+// DefaultPath returns the path of the configuration file in the home directory
+// of the user, ~/.badwolf/config.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".badwolf", "config")
+}
+
+// Load reads the configuration file at the provided path. The error returned
+// when the file cannot be opened is the one returned by os.Open, so
+// os.IsNotExist can be used to check if the file exists.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration file %q; %v", path, err)
+	}
+	return c, nil
+}
+
+// profilePrefix is the prefix of the names of the tables defining profiles.
+const profilePrefix = "profile."
+
+// subset describes the subset of TOML supported, so errors about unsupported
+// constructs can say what is supported instead.
+const subset = "configuration files support a subset of TOML made of [profile.<name>] tables with bare key = value settings, whose values are single line strings, numbers, or booleans"
+
+// Parse reads a configuration from the provided reader. The whole file is
+// checked, so no profile is returned if any of its lines is invalid.
+func Parse(r io.Reader) (*Config, error) {
+	c := &Config{
+		Profiles: make(map[string]*Profile),
+	}
+	var p *Profile
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		if strings.HasPrefix(l, "[") {
+			name, err := parseTable(l)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			if _, ok := c.Profiles[name]; ok {
+				return nil, fmt.Errorf("line %d: profile %q defined twice", n, name)
+			}
+			p = &Profile{
+				Name:     name,
+				Settings: make(map[string]string),
+			}
+			c.Profiles[name] = p
+			continue
+		}
+		k, v, err := parseKeyValue(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		switch {
+		case p != nil:
+			if _, ok := p.Settings[k]; ok {
+				return nil, fmt.Errorf("line %d: setting %q defined twice in profile %q", n, k, p.Name)
+			}
+			p.Settings[k] = v
+		case k == "profile":
+			c.Default = v
+		default:
+			return nil, fmt.Errorf("line %d: unknown top level key %q; %s", n, k, subset)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := c.Profiles[c.Default]; c.Default != "" && !ok {
+		return nil, fmt.Errorf("default profile %q is not defined", c.Default)
+	}
+	return c, nil
+}
+
+// parseTable returns the name of the profile defined by a table header line.
+func parseTable(l string) (string, error) {
+	if !strings.HasSuffix(l, "]") {
+		return "", fmt.Errorf("missing ] closing table %q", l)
+	}
+	if strings.HasPrefix(l, "[[") {
+		return "", fmt.Errorf("arrays of tables are not supported; %s", subset)
+	}
+	t := strings.TrimSpace(l[1 : len(l)-1])
+	if !strings.HasPrefix(t, profilePrefix) {
+		return "", fmt.Errorf("unknown table %q; %s", t, subset)
+	}
+	name := strings.TrimPrefix(t, profilePrefix)
+	if uq, err := strconv.Unquote(name); err == nil {
+		name = uq
+	} else if strings.Contains(name, ".") {
+		return "", fmt.Errorf("nested table %q is not supported; %s", t, subset)
+	}
+	if name == "" {
+		return "", fmt.Errorf("missing profile name in table %q", t)
+	}
+	return name, nil
+}
+
+// parseKeyValue returns the key and the value of a key/value pair line.
+// Values are either quoted strings or bare values, such as numbers and
+// booleans, that are kept as written.
+func parseKeyValue(l string) (string, string, error) {
+	idx := strings.Index(l, "=")
+	if idx < 0 {
+		return "", "", fmt.Errorf("expected key = value, got %q", l)
+	}
+	k, v := strings.TrimSpace(l[:idx]), strings.TrimSpace(l[idx+1:])
+	if k == "" {
+		return "", "", fmt.Errorf("missing key in %q", l)
+	}
+	if !isBareKey(k) {
+		return "", "", fmt.Errorf("key %q is not a bare key; %s", k, subset)
+	}
+	switch {
+	case strings.HasPrefix(v, `"""`), strings.HasPrefix(v, "'''"):
+		return "", "", fmt.Errorf("multi-line string value for key %q is not supported; %s", k, subset)
+	case strings.HasPrefix(v, "["), strings.HasPrefix(v, "{"):
+		return "", "", fmt.Errorf("array or inline table value for key %q is not supported; %s", k, subset)
+	case strings.HasPrefix(v, `"`):
+		end := strings.LastIndex(v, `"`)
+		if end == 0 || !isComment(v[end+1:]) {
+			return "", "", fmt.Errorf("malformed string value for key %q", k)
+		}
+		s, err := strconv.Unquote(v[:end+1])
+		if err != nil {
+			return "", "", fmt.Errorf("malformed string value for key %q; %v", k, err)
+		}
+		return k, s, nil
+	case strings.HasPrefix(v, "'"):
+		end := strings.LastIndex(v, "'")
+		if end == 0 || !isComment(v[end+1:]) {
+			return "", "", fmt.Errorf("malformed string value for key %q", k)
+		}
+		return k, v[1:end], nil
+	}
+	if idx := strings.Index(v, "#"); idx >= 0 {
+		v = strings.TrimSpace(v[:idx])
+	}
+	if v == "" {
+		return "", "", fmt.Errorf("missing value for key %q", k)
+	}
+	return k, v, nil
+}
+
+// isBareKey returns true if the provided key is a bare TOML key, formed by
+// letters, digits, underscores, and dashes.
+func isBareKey(k string) bool {
+	for _, c := range k {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// isComment returns true if the provided text after a value is blank or a
+// comment.
+func isComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || strings.HasPrefix(s, "#")
+}
+
+// Profile returns the profile with the provided name, or the default profile
+// of the configuration if no name is provided. If neither is available, an
+// empty profile is returned.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" {
+		return &Profile{Settings: map[string]string{}}, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		var ns []string
+		for n := range c.Profiles {
+			ns = append(ns, n)
+		}
+		sort.Strings(ns)
+		return nil, fmt.Errorf("unknown profile %q; available profiles %q", name, ns)
+	}
+	return p, nil
+}
+
+// Apply sets the flags of the provided flag set to the settings of the profile,
+// unless they were already set explicitly, and returns the command defaults
+// defined by the profile. Settings that are neither flags nor command settings
+// are rejected, so typos do not go unnoticed, before any flag is set.
+func (p *Profile) Apply(fs *flag.FlagSet) (command.Defaults, error) {
+	var d command.Defaults
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	var ks []string
+	for k := range p.Settings {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	var flags []string
+	for _, k := range ks {
+		v := p.Settings[k]
+		switch k {
+		case "graph":
+			d.Graph = v
+		case "format":
+			f, err := table.ParseFormat(v)
+			if err != nil {
+				return d, fmt.Errorf("invalid format in profile %q; %v", p.Name, err)
+			}
+			d.Format = f
+		case "addr":
+			d.Addr = v
+		case "tls_cert":
+			d.TLSCert = v
+		case "tls_key":
+			d.TLSKey = v
+		case "config", "profile":
+			return d, fmt.Errorf("setting %q cannot be used in profile %q", k, p.Name)
+		default:
+			if fs.Lookup(k) == nil {
+				return d, fmt.Errorf("unknown setting %q in profile %q", k, p.Name)
+			}
+			if !set[k] {
+				flags = append(flags, k)
+			}
+		}
+	}
+	for _, k := range flags {
+		if err := fs.Set(k, p.Settings[k]); err != nil {
+			return d, fmt.Errorf("invalid setting %q in profile %q; %v", k, p.Name, err)
+		}
+	}
+	return d, nil
+}
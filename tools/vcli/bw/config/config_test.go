@@ -0,0 +1,280 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/tools/vcli/bw/command"
+)
+
+func TestParse(t *testing.T) {
+	table := []struct {
+		text string
+		want *Config
+	}{
+		{
+			text: "",
+			want: &Config{Profiles: map[string]*Profile{}},
+		},
+		{
+			text: `
+# Profile used by default.
+profile = "local"
+
+[profile.local]
+driver = "BOLT"   # The driver.
+bolt_path = '/tmp/badwolf.db'
+graph = "?a,?b"
+
+  [ profile."prod.eu" ]
+redis_addr = "redis.example.com:6379"
+bulk_size = 1000 # Triples per batch.
+verbose = true
+name = "with # and \" inside"
+`,
+			want: &Config{
+				Default: "local",
+				Profiles: map[string]*Profile{
+					"local": {
+						Name: "local",
+						Settings: map[string]string{
+							"driver":    "BOLT",
+							"bolt_path": "/tmp/badwolf.db",
+							"graph":     "?a,?b",
+						},
+					},
+					"prod.eu": {
+						Name: "prod.eu",
+						Settings: map[string]string{
+							"redis_addr": "redis.example.com:6379",
+							"bulk_size":  "1000",
+							"verbose":    "true",
+							"name":       `with # and " inside`,
+						},
+					},
+				},
+			},
+		},
+		{
+			text: "[profile.empty]\n",
+			want: &Config{
+				Profiles: map[string]*Profile{
+					"empty": {Name: "empty", Settings: map[string]string{}},
+				},
+			},
+		},
+	}
+	for _, entry := range table {
+		got, err := Parse(strings.NewReader(entry.text))
+		if err != nil {
+			t.Errorf("Parse(%q) failed with error %v", entry.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("Parse(%q) returned %+v; want %+v", entry.text, got, entry.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	table := []struct {
+		text string
+		err  string
+	}{
+		{"driver = \"BOLT\"", "line 1"},
+		{"profile = \"missing\"", "not defined"},
+		{"[profile.a]\n[profile.a]", "line 2"},
+		{"[profile.a\ndriver = \"BOLT\"", "line 1"},
+		{"[settings]", "unknown table"},
+		{"[profile.]", "missing profile name"},
+		{"[profile.\"\"]", "missing profile name"},
+		{"[profile.]\ndriver = \"BOLT\"", "line 1: missing profile name"},
+		{"[profile.a]\ndriver = \"BOLT\"\n[settings]\nformat = \"json\"", "line 3: unknown table \"settings\"; configuration files support a subset of TOML"},
+		{"[profile.a.b]", "nested table"},
+		{"[[profile.a]]", "arrays of tables"},
+		{"[profile.a]\ndriver.name = \"BOLT\"", "not a bare key"},
+		{"[profile.a]\n\"driver\" = \"BOLT\"", "not a bare key"},
+		{"[profile.a]\ngraph = [\"?a\", \"?b\"]", "array or inline table"},
+		{"[profile.a]\ngraph = {name = \"?a\"}", "array or inline table"},
+		{"[profile.a]\ngraph = \"\"\"?a", "multi-line string"},
+		{"driver = \"BOLT\"\n[profile.a]", "line 1: unknown top level key \"driver\"; configuration files support a subset of TOML"},
+		{"[profile.a]\ndriver = \"BOLT\"\ndriver = \"REDIS\"", "line 3"},
+		{"[profile.a]\ndriver", "line 2"},
+		{"[profile.a]\n= \"BOLT\"", "missing key"},
+		{"[profile.a]\ndriver =", "missing value"},
+		{"[profile.a]\ndriver = # comment", "missing value"},
+		{"[profile.a]\ndriver = \"BOLT", "malformed string"},
+		{"[profile.a]\ndriver = \"BOLT\" trailing", "malformed string"},
+		{"[profile.a]\ndriver = 'BOLT", "malformed string"},
+		{"[profile.a]\ndriver = \"\\q\"", "malformed string"},
+	}
+	for _, entry := range table {
+		got, err := Parse(strings.NewReader(entry.text))
+		if err == nil {
+			t.Errorf("Parse(%q) should have failed; got %+v", entry.text, got)
+			continue
+		}
+		if !strings.Contains(err.Error(), entry.err) {
+			t.Errorf("Parse(%q) failed with error %q; want it to contain %q", entry.text, err, entry.err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badwolf_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	if _, err := Load(path); !os.IsNotExist(err) {
+		t.Errorf("Load(%q) returned error %v for a missing file; want a not exist error", path, err)
+	}
+	if err := ioutil.WriteFile(path, []byte("[profile.a]\ndriver = \"BOLT\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load(%q) failed with error %v", path, err)
+	}
+	if got, want := c.Profiles["a"].Settings["driver"], "BOLT"; got != want {
+		t.Errorf("Load(%q) returned driver %q; want %q", path, got, want)
+	}
+	if err := ioutil.WriteFile(path, []byte("driver = \"BOLT\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || os.IsNotExist(err) {
+		t.Errorf("Load(%q) returned error %v for a malformed file", path, err)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	c := &Config{
+		Default: "local",
+		Profiles: map[string]*Profile{
+			"local": {Name: "local"},
+			"prod":  {Name: "prod"},
+		},
+	}
+	table := []struct {
+		c    *Config
+		name string
+		want string
+		err  bool
+	}{
+		{c: c, name: "", want: "local"},
+		{c: c, name: "prod", want: "prod"},
+		{c: c, name: "unknown", err: true},
+		{c: &Config{}, name: "", want: ""},
+		{c: &Config{}, name: "unknown", err: true},
+	}
+	for _, entry := range table {
+		p, err := entry.c.Profile(entry.name)
+		if entry.err {
+			if err == nil {
+				t.Errorf("Profile(%q) should have failed; got %+v", entry.name, p)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Profile(%q) failed with error %v", entry.name, err)
+			continue
+		}
+		if p.Name != entry.want {
+			t.Errorf("Profile(%q) returned profile %q; want %q", entry.name, p.Name, entry.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	newFlagSet := func(args ...string) (*flag.FlagSet, map[string]*string) {
+		fs := flag.NewFlagSet("bw", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		vs := map[string]*string{
+			"driver":    fs.String("driver", "VOLATILE", ""),
+			"bolt_path": fs.String("bolt_path", "", ""),
+		}
+		fs.Int("bulk_size", 1000, "")
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return fs, vs
+	}
+
+	p := &Profile{
+		Name: "local",
+		Settings: map[string]string{
+			"driver":    "BOLT",
+			"bolt_path": "/tmp/badwolf.db",
+			"graph":     "?a",
+			"format":    "json",
+			"addr":      ":8443",
+			"tls_cert":  "cert.pem",
+			"tls_key":   "key.pem",
+		},
+	}
+	fs, vs := newFlagSet("--driver=REDIS")
+	d, err := p.Apply(fs)
+	if err != nil {
+		t.Fatalf("Apply failed with error %v", err)
+	}
+	want := command.Defaults{
+		Graph:   "?a",
+		Format:  table.FormatJSON,
+		Addr:    ":8443",
+		TLSCert: "cert.pem",
+		TLSKey:  "key.pem",
+	}
+	if d != want {
+		t.Errorf("Apply returned defaults %+v; want %+v", d, want)
+	}
+	// Flags set explicitly take precedence over the profile.
+	if got, want := *vs["driver"], "REDIS"; got != want {
+		t.Errorf("Apply set flag driver to %q; want %q", got, want)
+	}
+	if got, want := *vs["bolt_path"], "/tmp/badwolf.db"; got != want {
+		t.Errorf("Apply set flag bolt_path to %q; want %q", got, want)
+	}
+
+	// Invalid profiles do not set any flag.
+	fs, vs = newFlagSet()
+	if _, err := (&Profile{Name: "bad", Settings: map[string]string{"bolt_path": "/tmp/other.db", "unknown": "value"}}).Apply(fs); err == nil {
+		t.Error("Apply should have failed for the unknown setting")
+	}
+	if got := *vs["bolt_path"]; got != "" {
+		t.Errorf("Apply set flag bolt_path to %q before failing; want it unset", got)
+	}
+
+	for _, s := range []map[string]string{
+		{"unknown": "value"},
+		{"format": "xml"},
+		{"bulk_size": "many"},
+		{"config": "other"},
+		{"profile": "other"},
+	} {
+		fs, _ := newFlagSet()
+		if d, err := (&Profile{Name: "bad", Settings: s}).Apply(fs); err == nil {
+			t.Errorf("Apply(%v) should have failed; got %+v", s, d)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff contains the command allowing to compare the triples of two
+// graphs.
+package diff
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/tools/vcli/bw/command"
+)
+
+// New creates the diff command.
+func New(store storage.Store, chanSize int) *command.Command {
+	cmd := &command.Command{
+		UsageLine: "diff [--ignore_anchors] [--summary] <graph_name> <graph_name>",
+		Short:     "prints the triples present in one graph but not the other.",
+		Long: `Compares the triples of the two provided graphs, and prints the triples
+only present in the first graph prefixed by < and the ones only present in the
+second graph prefixed by >, followed by the number of triples only present in
+each of them. Triples only differing on the time anchors of their predicates
+are considered equal if --ignore_anchors is provided. If --summary is provided,
+only the number of differing triples is printed. Graphs providing snapshots are
+compared using a snapshot, so they can keep changing while being compared.
+
+The command exits with status 0 if the graphs contain the same triples, 1 if
+they differ, and 2 if they could not be compared.
+`,
+	}
+	cmd.Run = func(ctx context.Context, args []string) int {
+		return Eval(ctx, cmd.UsageLine+"\n\n"+cmd.Long, args, store, chanSize)
+	}
+	return cmd
+}
+
+// Eval compares the graphs as indicated by the command.
+func Eval(ctx context.Context, usage string, args []string, store storage.Store, chanSize int) int {
+	var ignoreAnchors, summary bool
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.BoolVar(&ignoreAnchors, "ignore_anchors", false, "ignore the time anchors of the predicates")
+	fs.BoolVar(&summary, "summary", false, "only print the number of differing triples")
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	// Flags may appear before or after the graph names.
+	var names []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			log.Printf("[ERROR] %v.\n\n%s", err, usage)
+			return 2
+		}
+		if args = fs.Args(); len(args) == 0 {
+			break
+		}
+		names, args = append(names, args[0]), args[1:]
+	}
+	if len(names) != 2 {
+		log.Printf("[ERROR] Wrong number of graph names; expected 2, got %q.\n\n%s", names, usage)
+		return 2
+	}
+	var gs []storage.Graph
+	for _, name := range names {
+		g, err := store.Graph(ctx, name)
+		if err != nil {
+			log.Printf("[ERROR] Failed to retrieve graph %q with error %v.\n\n", name, err)
+			return 2
+		}
+		gs = append(gs, g)
+	}
+
+	ds, errs := make(chan *storage.Difference, chanSize), make(chan error, 1)
+	go func() {
+		errs <- storage.Diff(ctx, gs[0], gs[1], &storage.DiffOptions{IgnoreAnchors: ignoreAnchors}, ds)
+	}()
+	left, right := 0, 0
+	for d := range ds {
+		prefix := ">"
+		if d.Left {
+			left++
+			prefix = "<"
+		} else {
+			right++
+		}
+		if !summary {
+			fmt.Printf("%s %s\n", prefix, d.Triple)
+		}
+	}
+	if err := <-errs; err != nil {
+		log.Printf("[ERROR] Failed to compare graphs %s with error %v.\n\n", strings.Join(names, " and "), err)
+		return 2
+	}
+	fmt.Printf("%d triples only in graph %q.\n%d triples only in graph %q.\n", left, names[0], right, names[1])
+	if left+right > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,178 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// populate creates the graphs in the store with the provided triples.
+func populate(ctx context.Context, t *testing.T, s storage.Store, data map[string][]string) {
+	for id, ls := range data {
+		g, err := s.NewGraph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ts []*triple.Triple
+		for _, l := range ls {
+			tr, err := triple.Parse(l, literal.DefaultBuilder())
+			if err != nil {
+				t.Fatal(err)
+			}
+			ts = append(ts, tr)
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// capture returns the status returned by the provided function and what it
+// printed to the standard output.
+func capture(t *testing.T, f func() int) (int, string) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		out <- buf.String()
+	}()
+	status := f()
+	os.Stdout = stdout
+	w.Close()
+	return status, <-out
+}
+
+func TestEval(t *testing.T) {
+	ctx, s := context.Background(), memory.NewStore()
+	populate(ctx, t, s, map[string][]string{
+		"?left": {
+			"/u<joe>\t\"parent_of\"@[]\t/u<mary>",
+			"/u<mary>\t\"parent_of\"@[]\t/u<peter>",
+			"/u<joe>\t\"met\"@[2016-01-01T00:00:00Z]\t/u<peter>",
+		},
+		"?right": {
+			"/u<joe>\t\"parent_of\"@[]\t/u<mary>",
+			"/u<peter>\t\"parent_of\"@[]\t/u<john>",
+			"/u<joe>\t\"met\"@[2017-01-01T00:00:00Z]\t/u<peter>",
+		},
+		"?same": {
+			"/u<joe>\t\"parent_of\"@[]\t/u<mary>",
+			"/u<mary>\t\"parent_of\"@[]\t/u<peter>",
+			"/u<joe>\t\"met\"@[2016-01-01T00:00:00Z]\t/u<peter>",
+		},
+	})
+
+	table := []struct {
+		args   []string
+		want   int
+		lines  []string
+		absent []string
+	}{
+		{
+			args: []string{"diff", "?left", "?same"},
+			want: 0,
+			lines: []string{
+				`0 triples only in graph "?left".`,
+				`0 triples only in graph "?same".`,
+			},
+		},
+		{
+			args: []string{"diff", "?left", "?right"},
+			want: 1,
+			lines: []string{
+				"< /u<mary>\t\"parent_of\"@[]\t/u<peter>",
+				"> /u<peter>\t\"parent_of\"@[]\t/u<john>",
+				"< /u<joe>\t\"met\"@[2016-01-01T00:00:00Z]\t/u<peter>",
+				"> /u<joe>\t\"met\"@[2017-01-01T00:00:00Z]\t/u<peter>",
+				`2 triples only in graph "?left".`,
+				`2 triples only in graph "?right".`,
+			},
+		},
+		{
+			args: []string{"diff", "--ignore_anchors", "?left", "?right"},
+			want: 1,
+			lines: []string{
+				"< /u<mary>\t\"parent_of\"@[]\t/u<peter>",
+				"> /u<peter>\t\"parent_of\"@[]\t/u<john>",
+				`1 triples only in graph "?left".`,
+				`1 triples only in graph "?right".`,
+			},
+			absent: []string{"met"},
+		},
+		{
+			args: []string{"diff", "?left", "?right", "--summary"},
+			want: 1,
+			lines: []string{
+				`2 triples only in graph "?left".`,
+				`2 triples only in graph "?right".`,
+			},
+			absent: []string{"parent_of", "met"},
+		},
+		{
+			args: []string{"diff", "?left"},
+			want: 2,
+		},
+		{
+			args: []string{"diff", "?left", "?right", "?same"},
+			want: 2,
+		},
+		{
+			args: []string{"diff", "?left", "?unknown"},
+			want: 2,
+		},
+		{
+			args: []string{"diff", "--unknown", "?left", "?right"},
+			want: 2,
+		},
+	}
+	for _, entry := range table {
+		got, out := capture(t, func() int {
+			return Eval(ctx, "", entry.args, s, 10)
+		})
+		if got != entry.want {
+			t.Errorf("Eval(%q) returned status %d; want %d", entry.args, got, entry.want)
+		}
+		ls := make(map[string]bool)
+		for _, l := range strings.Split(out, "\n") {
+			ls[l] = true
+		}
+		for _, l := range entry.lines {
+			if !ls[l] {
+				t.Errorf("Eval(%q) did not print line %q; got\n%s", entry.args, l, out)
+			}
+		}
+		for _, a := range entry.absent {
+			if strings.Contains(out, a) {
+				t.Errorf("Eval(%q) should not have printed %q; got\n%s", entry.args, a, out)
+			}
+		}
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2016 Google Inc. All rights reserved.
+// Copyright 2015 Google Inc. All rights reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -17,25 +17,58 @@
 package export
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
+	"flag"
 	"fmt"
+	stdio "io"
+	"io/ioutil"
 	"log"
 	"os"
 	"strings"
-	"sync"
 
+	bwio "github.com/google/badwolf/io"
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/tools/vcli/bw/command"
+	"github.com/google/badwolf/tools/vcli/bw/io"
 	"github.com/google/badwolf/triple"
 )
 
-// New creates the help command.
+// formats are the formats supported by the export command.
+var formats = []string{io.FormatBadWolf, io.FormatNTriples, io.FormatNQuads, io.FormatTurtle, io.FormatJSONLD, io.FormatGraphML, io.FormatGEXF}
+
+// New creates the export command.
 func New(store storage.Store, bulkSize int) *command.Command {
 	cmd := &command.Command{
-		UsageLine: "export <graph_names_separated_by_commas> <file_path>",
+		UsageLine: "export [--graph=<graph_names>] [--format=bw|nt|nq|ttl|jsonld|graphml|gexf] [-o <file_path>] [<graph_names_separated_by_commas> <file_path>]",
 		Short:     "export triples in bulk from graphs into a file.",
-		Long: `Export all the triples in the provided graphs into the provided
-text file.`,
+		Long: `Export all the triples in the provided graphs into the provided file.
+Graph names need to be separated by commas with no whitespaces, and can be
+provided either with the --graph flag or before the file path, defaulting to
+the graph of the profile in use. The file path
+can also be provided with the -o flag; if no file path is provided, or it is -,
+the triples are written to the standard output. Files ending in .gz are
+compressed using gzip.
+
+The --format flag indicates how the triples are serialized:
+
+	bw       one triple per line, using the format read by the load command.
+	nt       N-Triples.
+	nq       N-Quads, using the graph names as the graph of their triples.
+	ttl      Turtle.
+	jsonld   JSON-LD.
+	graphml  GraphML, to visualize the graphs.
+	gexf     GEXF, to visualize the graphs and how they change over time.
+
+The ntriples, nquads, and turtle names are also accepted. If no format is
+provided, it is guessed from the file extension (.nt, .nq, .ttl, .jsonld, .json,
+.graphml, or .gexf), defaulting to bw.
+
+Graphs providing snapshots are exported out of a snapshot taken before writing
+them, so they can keep changing while they are exported. If the export fails,
+the partially written file is removed.
+`,
 	}
 	cmd.Run = func(ctx context.Context, args []string) int {
 		return Eval(ctx, cmd.UsageLine+"\n\n"+cmd.Long, args, store, bulkSize)
@@ -43,56 +76,220 @@ text file.`,
 	return cmd
 }
 
-// Eval loads the triples in the file against as indicated by the command.
-func Eval(ctx context.Context, usage string, args []string, store storage.Store, bulkSize int) int {
-	if len(args) < 3 {
-		log.Printf("[ERROR] Missing required file path and/or graph names.\n\n%s", usage)
-		return 2
+// options contains the parsed arguments of the export command.
+type options struct {
+	path   string
+	graphs []string
+	format string
+}
+
+// parseArgs parses the arguments of the export command. The default graph names
+// are used if none are provided.
+func parseArgs(args []string, defaultGraph string) (*options, error) {
+	opts, graphs := &options{}, ""
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.StringVar(&graphs, "graph", "", "graph names separated by commas")
+	fs.StringVar(&opts.format, "format", "", "format of the file")
+	fs.StringVar(&opts.path, "o", "", "file to write the triples to")
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	// Flags may appear before or after the graph names and file path.
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if args = fs.Args(); len(args) == 0 {
+			break
+		}
+		rest, args = append(rest, args[0]), args[1:]
+	}
+	switch {
+	case len(rest) == 0:
+	case len(rest) == 1 && graphs == "":
+		graphs = rest[0]
+	case len(rest) == 1 && opts.path == "":
+		opts.path = rest[0]
+	case len(rest) == 2 && graphs == "" && opts.path == "":
+		graphs, opts.path = rest[0], rest[1]
+	default:
+		return nil, fmt.Errorf("unexpected arguments %q", rest)
 	}
-	graphs, path := strings.Split(args[len(args)-2], ","), args[len(args)-1]
-	f, err := os.Create(path)
+	if graphs == "" {
+		graphs = defaultGraph
+	}
+	if graphs == "" {
+		return nil, fmt.Errorf("missing required graph names")
+	}
+	opts.graphs = strings.Split(graphs, ",")
+	if opts.path == "-" {
+		opts.path = ""
+	}
+	if opts.format == "" {
+		opts.format = io.FormatFromPath(opts.path)
+	}
+	f, err := io.ParseFormat(opts.format, formats...)
 	if err != nil {
-		log.Printf("[ERROR] Failed to open target file %q with error %v.\n\n", path, err)
+		return nil, err
+	}
+	opts.format = f
+	return opts, nil
+}
+
+// Eval exports the triples of the graphs as indicated by the command.
+func Eval(ctx context.Context, usage string, args []string, store storage.Store, bulkSize int) int {
+	opts, err := parseArgs(args, command.DefaultsFrom(ctx).Graph)
+	if err != nil {
+		log.Printf("[ERROR] %v.\n\n%s", err, usage)
 		return 2
 	}
-	defer f.Close()
-	var sgs []storage.Graph
-	for _, gr := range graphs {
-		g, err := store.Graph(ctx, gr)
+	// Reports go to the standard error when the triples are written to the
+	// standard output.
+	var (
+		out    stdio.Writer = os.Stdout
+		report stdio.Writer = os.Stdout
+		target              = "standard output"
+	)
+	if opts.path == "" {
+		report = os.Stderr
+	} else {
+		f, err := os.Create(opts.path)
 		if err != nil {
-			log.Printf("[ERROR] Failed to retrieve graph %q with error %v.\n\n", gr, err)
+			log.Printf("[ERROR] Failed to open target file %q with error %v.\n\n", opts.path, err)
 			return 2
 		}
-		sgs = append(sgs, g)
+		defer f.Close()
+		out, target = f, fmt.Sprintf("file %q", opts.path)
+	}
+	cnt, err := export(ctx, out, store, opts, bulkSize)
+	if err != nil {
+		log.Printf("[ERROR] Failed to export triples with error %v.\n\n", err)
+		if opts.path != "" {
+			os.Remove(opts.path)
+		}
+		return 2
 	}
+	fmt.Fprintf(report, "Successfully written %d triples to %s.\nTriples exported from graphs:\n\t- %s\n", cnt, target, strings.Join(opts.graphs, "\n\t- "))
+	return 0
+}
 
-	cnt := 0
-	var errs []error
-	var mu sync.Mutex
-	chn := make(chan *triple.Triple, bulkSize)
-	for _, vg := range sgs {
-		go func(g storage.Graph) {
-			err := g.Triples(ctx, storage.DefaultLookup, chn)
-			mu.Lock()
-			errs = append(errs, err)
-			mu.Unlock()
-		}(vg)
+// export writes the triples of the graphs into the writer using the format of
+// the options. It returns the number of triples written.
+func export(ctx context.Context, w stdio.Writer, store storage.Store, opts *options, bulkSize int) (int, error) {
+	var zw *gzip.Writer
+	if strings.HasSuffix(strings.ToLower(opts.path), ".gz") {
+		zw = gzip.NewWriter(w)
+		w = zw
+	}
+	bw := bufio.NewWriter(w)
+	cnt, err := write(ctx, bw, store, opts, bulkSize)
+	if err != nil {
+		return cnt, err
 	}
+	if err := bw.Flush(); err != nil {
+		return cnt, err
+	}
+	if zw != nil {
+		return cnt, zw.Close()
+	}
+	return cnt, nil
+}
 
-	for t := range chn {
-		if _, err := f.WriteString(t.String() + "\n"); err != nil {
-			log.Printf("[ERROR] Failed to write triple %s to file %q, %v.\n\n", t.String(), path, err)
-			return 2
-		}
-		cnt++
+// write serializes the triples of the graphs into the writer.
+func write(ctx context.Context, w stdio.Writer, store storage.Store, opts *options, bulkSize int) (int, error) {
+	if opts.format == io.FormatNQuads {
+		// WriteNQuads takes the snapshots of the graphs itself.
+		return bwio.WriteNQuads(ctx, w, store, opts.graphs, nil)
 	}
-	for _, err := range errs {
+	var gs []storage.Graph
+	for _, id := range opts.graphs {
+		g, err := snapshot(ctx, store, id)
 		if err != nil {
-			log.Printf("[ERROR] Failed to retrieve triples with error %v.\n\n", err)
-			return 2
+			return 0, err
+		}
+		if snap, ok := g.(storage.GraphSnapshot); ok {
+			defer snap.Release(ctx)
+		}
+		gs = append(gs, g)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ts, rErr := make(chan *triple.Triple, bulkSize), make(chan error, 1)
+	go func() {
+		rErr <- triples(ctx, gs, ts)
+	}()
+	var (
+		cnt int
+		err error
+	)
+	switch opts.format {
+	case io.FormatBadWolf:
+		for t := range ts {
+			if err == nil {
+				_, err = fmt.Fprintln(w, t.String())
+				cnt++
+			}
 		}
+	case io.FormatNTriples:
+		cnt, err = bwio.WriteNTriples(ctx, w, ts, nil)
+	case io.FormatTurtle:
+		cnt, err = bwio.WriteTurtle(ctx, w, ts, nil)
+	case io.FormatJSONLD:
+		cnt, err = bwio.WriteJSONLD(ctx, w, ts, nil)
+	case io.FormatGraphML:
+		cnt, err = bwio.WriteGraphML(ctx, w, ts)
+	case io.FormatGEXF:
+		cnt, err = bwio.WriteGEXF(ctx, w, ts)
 	}
+	if err != nil {
+		// Stop reading the graphs, and drain the triples already read.
+		cancel()
+		for range ts {
+		}
+		return cnt, err
+	}
+	return cnt, <-rErr
+}
 
-	fmt.Printf("Successfully written %d triples to file %q.\nTriples exported from graphs:\n\t- %s\n", cnt, path, strings.Join(graphs, "\n\t- "))
-	return 0
+// snapshot returns a snapshot of the graph with the provided ID, or the graph
+// itself if it does not provide snapshots.
+func snapshot(ctx context.Context, store storage.Store, id string) (storage.Graph, error) {
+	g, err := store.Graph(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve graph %q; %v", id, err)
+	}
+	snap, err := storage.Snapshot(ctx, g)
+	switch err {
+	case nil:
+		return snap, nil
+	case storage.ErrNoSnapshots:
+		log.Printf("[WARNING] Graph %q does not provide snapshots; changes done while exporting it may or may not be exported.\n", id)
+		return g, nil
+	}
+	return nil, fmt.Errorf("failed to take a snapshot of graph %q; %v", id, err)
+}
+
+// triples pushes the triples of all the graphs into the provided channel, one
+// graph after the other, and closes it once done.
+func triples(ctx context.Context, gs []storage.Graph, ts chan<- *triple.Triple) error {
+	defer close(ts)
+	for _, g := range gs {
+		gts := make(chan *triple.Triple, cap(ts))
+		errc := make(chan error, 1)
+		go func() {
+			errc <- g.Triples(ctx, storage.DefaultLookup, gts)
+		}()
+		for t := range gts {
+			select {
+			case <-ctx.Done():
+			case ts <- t:
+			}
+		}
+		if err := <-errc; err != nil {
+			return fmt.Errorf("failed to retrieve triples of graph %q; %v", g.ID(ctx), err)
+		}
+	}
+	return ctx.Err()
 }
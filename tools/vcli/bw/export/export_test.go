@@ -0,0 +1,174 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/tools/vcli/bw/io"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+func TestParseArgs(t *testing.T) {
+	table := []struct {
+		args         []string
+		defaultGraph string
+		want         *options
+	}{
+		{
+			args: []string{"export", "?a,?b", "out.bw"},
+			want: &options{path: "out.bw", graphs: []string{"?a", "?b"}, format: io.FormatBadWolf},
+		},
+		{
+			args: []string{"export", "?a"},
+			want: &options{graphs: []string{"?a"}, format: io.FormatBadWolf},
+		},
+		{
+			args: []string{"export", "?a", "-", "--format=turtle"},
+			want: &options{graphs: []string{"?a"}, format: io.FormatTurtle},
+		},
+		{
+			args: []string{"export", "--graph=?a", "out.nt.gz"},
+			want: &options{path: "out.nt.gz", graphs: []string{"?a"}, format: io.FormatNTriples},
+		},
+		{
+			args: []string{"export", "-o", "out.graphml", "?a"},
+			want: &options{path: "out.graphml", graphs: []string{"?a"}, format: io.FormatGraphML},
+		},
+		{
+			args:         []string{"export", "-o", "out.gexf"},
+			defaultGraph: "?default",
+			want:         &options{path: "out.gexf", graphs: []string{"?default"}, format: io.FormatGEXF},
+		},
+	}
+	for _, entry := range table {
+		got, err := parseArgs(entry.args, entry.defaultGraph)
+		if err != nil {
+			t.Errorf("parseArgs(%q) failed with error %v", entry.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("parseArgs(%q) returned %+v; want %+v", entry.args, got, entry.want)
+		}
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	table := [][]string{
+		{"export"},
+		{"export", "-o", "out.bw"},
+		{"export", "--graph=?a", "-o", "out.bw", "extra"},
+		{"export", "?a", "out.bw", "extra"},
+		{"export", "--format=xml", "?a"},
+		{"export", "--unknown", "?a"},
+	}
+	for _, args := range table {
+		if got, err := parseArgs(args, ""); err == nil {
+			t.Errorf("parseArgs(%q) should have failed; got %+v", args, got)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	ctx, s := context.Background(), memory.NewStore()
+	data := map[string][]string{
+		"?a": {
+			"/u<joe>\t\"parent_of\"@[]\t/u<mary>",
+			"/u<mary>\t\"parent_of\"@[]\t/u<peter>",
+		},
+		"?b": {
+			"/u<peter>\t\"parent_of\"@[]\t/u<john>",
+		},
+	}
+	var all []string
+	for id, ls := range data {
+		g, err := s.NewGraph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range ls {
+			tr, err := triple.Parse(l, literal.DefaultBuilder())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := g.AddTriples(ctx, []*triple.Triple{tr}); err != nil {
+				t.Fatal(err)
+			}
+			all = append(all, tr.String())
+		}
+	}
+	sort.Strings(all)
+
+	table := []struct {
+		opts  *options
+		lines []string
+	}{
+		{
+			opts:  &options{graphs: []string{"?a", "?b"}, format: io.FormatBadWolf},
+			lines: all,
+		},
+		{
+			opts:  &options{path: "out.bw.gz", graphs: []string{"?a", "?b"}, format: io.FormatBadWolf},
+			lines: all,
+		},
+		{
+			opts: &options{graphs: []string{"?b"}, format: io.FormatNTriples},
+			lines: []string{
+				"<urn:badwolf:node:/u#peter> <urn:badwolf:predicate:parent_of> <urn:badwolf:node:/u#john> .",
+			},
+		},
+	}
+	for _, entry := range table {
+		var buf bytes.Buffer
+		cnt, err := export(ctx, &buf, s, entry.opts, 10)
+		if err != nil {
+			t.Errorf("export(%+v) failed with error %v", entry.opts, err)
+			continue
+		}
+		if got, want := cnt, len(entry.lines); got != want {
+			t.Errorf("export(%+v) returned %d triples; want %d", entry.opts, got, want)
+		}
+		out := buf.String()
+		if strings.HasSuffix(entry.opts.path, ".gz") {
+			zr, err := gzip.NewReader(&buf)
+			if err != nil {
+				t.Fatalf("export(%+v) did not write a gzip stream; %v", entry.opts, err)
+			}
+			bs, err := ioutil.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = string(bs)
+		}
+		got := strings.Split(strings.TrimSpace(out), "\n")
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.lines) {
+			t.Errorf("export(%+v) wrote\n%s\nwant\n%s", entry.opts, strings.Join(got, "\n"), strings.Join(entry.lines, "\n"))
+		}
+	}
+
+	if _, err := export(ctx, ioutil.Discard, s, &options{graphs: []string{"?unknown"}, format: io.FormatBadWolf}, 10); err == nil {
+		t.Errorf("export should have failed for an unknown graph")
+	}
+}
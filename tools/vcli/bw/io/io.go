@@ -18,12 +18,26 @@ package io
 
 import (
 	"bufio"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"unicode"
+
+	bwio "github.com/google/badwolf/io"
 )
 
-// GetStatementsFromFile returns the statements found in the provided file.
+// stdin is the reader the statements are read from when the path is -.
+var stdin io.Reader = os.Stdin
+
+// GetStatementsFromFile returns the statements found in the provided file. If
+// the path is -, the statements are read from the standard input.
 func GetStatementsFromFile(path string) ([]string, error) {
+	if path == "-" {
+		return readStatements(stdin)
+	}
 	stms, err := ReadLines(path)
 	if err != nil {
 		return nil, err
@@ -31,46 +45,179 @@ func GetStatementsFromFile(path string) ([]string, error) {
 	return stms, nil
 }
 
-// ReadLines from a file into a string array.
+// ReadLines from a file into a string array. Files compressed using gzip or
+// bzip2 are decompressed.
 func ReadLines(path string) ([]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
+	return readStatements(f)
+}
 
-	var lines []string
-	scanner := bufio.NewScanner(f)
-	line := ""
-	for scanner.Scan() {
-		l := strings.TrimSpace(scanner.Text())
+// Statement is a statement read from a file.
+type Statement struct {
+	// Text of the statement. It keeps the line breaks and indentation of the
+	// file, so the locations reported by the parser can be mapped to it.
+	// Commented lines inside the statement are left blank.
+	Text string
+
+	// Line of the file where the statement starts, starting at 1.
+	Line int
+}
+
+// ReadStatements returns the statements found in the provided file, or in the
+// standard input if the path is -, along with the lines where they start.
+// Files compressed using gzip or bzip2 are decompressed.
+func ReadStatements(path string) ([]*Statement, error) {
+	if path == "-" {
+		return scanStatements(stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return scanStatements(f)
+}
+
+// scanStatements reads the statements found in the provided reader, which
+// may be compressed using gzip or bzip2. Statements may span several lines and
+// end with a ;. Lines starting with #, including the shebang line of
+// executable scripts, are ignored.
+func scanStatements(rd io.Reader) ([]*Statement, error) {
+	r, err := bwio.Decompress(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		stms []*Statement
+		cur  *Statement
+	)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		raw := strings.TrimRightFunc(scanner.Text(), unicode.IsSpace)
+		l := strings.TrimSpace(raw)
 		if len(l) == 0 || strings.Index(l, "#") == 0 {
+			if cur != nil {
+				cur.Text += "\n"
+			}
 			continue
 		}
-		line += " " + l
+		if cur == nil {
+			cur = &Statement{Text: raw, Line: n}
+		} else {
+			cur.Text += "\n" + raw
+		}
 		if l[len(l)-1:] == ";" {
-			lines = append(lines, strings.TrimSpace(line))
-			line = ""
+			stms = append(stms, cur)
+			cur = nil
 		}
 	}
-	if line != "" {
-		lines = append(lines, strings.TrimSpace(line))
+	if cur != nil {
+		stms = append(stms, cur)
 	}
-	return lines, scanner.Err()
+	return stms, scanner.Err()
 }
 
-// ProcessLines from a file using the provided call back. The error of the
-// callback will be passed through. Returns the number of processed errors
-// before the error. Returns the line where the error occurred or the total
-// numbers of lines processed.
+// readStatements reads the statements found in the provided reader, joining
+// the lines of each one into a single line.
+func readStatements(rd io.Reader) ([]string, error) {
+	stms, err := scanStatements(rd)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(stms))
+	for _, stm := range stms {
+		var ls []string
+		for _, l := range strings.Split(stm.Text, "\n") {
+			if l = strings.TrimSpace(l); l != "" {
+				ls = append(ls, l)
+			}
+		}
+		lines = append(lines, strings.Join(ls, " "))
+	}
+	return lines, nil
+}
+
+// Variables implements flag.Value collecting the name=value definitions of
+// the variables referenced by the statements.
+type Variables map[string]string
+
+// String returns the definitions of the variables.
+func (v Variables) String() string {
+	var res []string
+	for n, val := range v {
+		res = append(res, n+"="+val)
+	}
+	sort.Strings(res)
+	return strings.Join(res, " ")
+}
+
+// Set adds the provided name=value definition.
+func (v Variables) Set(s string) error {
+	idx := strings.Index(s, "=")
+	if idx <= 0 {
+		return fmt.Errorf("invalid variable definition %q; expected <name>=<value>", s)
+	}
+	v[s[:idx]] = s[idx+1:]
+	return nil
+}
+
+// Lookup returns the value of the variable, falling back to the environment
+// variables.
+func (v Variables) Lookup(name string) (string, bool) {
+	if val, ok := v[name]; ok {
+		return val, true
+	}
+	return os.LookupEnv(name)
+}
+
+// ExpandVariables replaces the ${name} references found in the provided
+// statement with the values returned by the lookup function. It fails if a
+// referenced variable is not defined.
+func ExpandVariables(stm string, lookup func(name string) (string, bool)) (string, error) {
+	var res strings.Builder
+	for {
+		idx := strings.Index(stm, "${")
+		if idx < 0 {
+			res.WriteString(stm)
+			return res.String(), nil
+		}
+		end := strings.Index(stm[idx:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("missing } closing variable reference %q", stm[idx:])
+		}
+		name := stm[idx+2 : idx+end]
+		v, ok := lookup(name)
+		if !ok {
+			return "", fmt.Errorf("undefined variable %q", name)
+		}
+		res.WriteString(stm[:idx])
+		res.WriteString(v)
+		stm = stm[idx+end+1:]
+	}
+}
+
+// ProcessLines from a file using the provided call back. Files compressed
+// using gzip or bzip2 are decompressed. The error of the callback will be
+// passed through. Returns the number of processed errors before the error.
+// Returns the line where the error occurred or the total numbers of lines
+// processed.
 func ProcessLines(path string, fp func(line string) error) (int, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return 0, err
 	}
 	defer f.Close()
+	r, err := bwio.Decompress(f)
+	if err != nil {
+		return 0, err
+	}
 
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(r)
 	cnt := 0
 	for scanner.Scan() {
 		l := strings.TrimSpace(scanner.Text())
@@ -84,3 +231,62 @@ func ProcessLines(path string, fp func(line string) error) (int, error) {
 	}
 	return cnt, scanner.Err()
 }
+
+// The names of the triple serialization formats used by the load and export
+// commands.
+const (
+	FormatBadWolf  = "bw"
+	FormatNTriples = "nt"
+	FormatNQuads   = "nq"
+	FormatTurtle   = "ttl"
+	FormatJSONLD   = "jsonld"
+	FormatGraphML  = "graphml"
+	FormatGEXF     = "gexf"
+)
+
+// formatAliases maps the long names accepted for some formats to them.
+var formatAliases = map[string]string{
+	"ntriples": FormatNTriples,
+	"nquads":   FormatNQuads,
+	"turtle":   FormatTurtle,
+}
+
+// ParseFormat returns the format with the provided name or alias, or an error
+// if the format is not one of the allowed ones.
+func ParseFormat(name string, allowed ...string) (string, error) {
+	f := strings.ToLower(name)
+	if a, ok := formatAliases[f]; ok {
+		f = a
+	}
+	for _, a := range allowed {
+		if f == a {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q; valid formats are %s", name, strings.Join(allowed, ", "))
+}
+
+// FormatFromPath guesses the format of a file from its extension, ignoring the
+// .gz and .bz2 compression ones. Files without a known extension are assumed
+// to use the bw format.
+func FormatFromPath(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".gz" || ext == ".bz2" {
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))))
+	}
+	switch ext {
+	case ".nt":
+		return FormatNTriples
+	case ".nq":
+		return FormatNQuads
+	case ".ttl":
+		return FormatTurtle
+	case ".jsonld", ".json":
+		return FormatJSONLD
+	case ".graphml":
+		return FormatGraphML
+	case ".gexf":
+		return FormatGEXF
+	}
+	return FormatBadWolf
+}
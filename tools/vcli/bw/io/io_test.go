@@ -0,0 +1,248 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// statements returns the text and line of the provided statements.
+func statements(stms []*Statement) string {
+	var res []string
+	for _, s := range stms {
+		res = append(res, fmt.Sprintf("%d:%q", s.Line, s.Text))
+	}
+	return "[" + strings.Join(res, " ") + "]"
+}
+
+func TestScanStatements(t *testing.T) {
+	table := []struct {
+		text string
+		want []*Statement
+	}{
+		{
+			text: "",
+			want: nil,
+		},
+		{
+			text: "#!/usr/bin/env bw run\ncreate graph ?a;\n\nselect ?s\n  from ?a\n# comment\n  where {?s ?p ?o};  \n",
+			want: []*Statement{
+				{Text: "create graph ?a;", Line: 2},
+				{Text: "select ?s\n  from ?a\n\n  where {?s ?p ?o};", Line: 4},
+			},
+		},
+		{
+			text: "drop graph ?a;\nselect ?s from ?a",
+			want: []*Statement{
+				{Text: "drop graph ?a;", Line: 1},
+				{Text: "select ?s from ?a", Line: 2},
+			},
+		},
+	}
+	for _, entry := range table {
+		got, err := scanStatements(strings.NewReader(entry.text))
+		if err != nil {
+			t.Errorf("scanStatements(%q) failed with error %v", entry.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("scanStatements(%q) returned %s; want %s", entry.text, statements(got), statements(entry.want))
+		}
+	}
+}
+
+func TestReadStatements(t *testing.T) {
+	text := "select ?s\n  from ?a\n# comment\n  where {?s ?p ?o};\ncreate graph ?b;\n"
+	got, err := readStatements(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("readStatements(%q) failed with error %v", text, err)
+	}
+	want := []string{"select ?s from ?a where {?s ?p ?o};", "create graph ?b;"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readStatements(%q) returned %q; want %q", text, got, want)
+	}
+}
+
+func TestGetStatementsFromFile(t *testing.T) {
+	script := "#!/usr/bin/env -S bw run\ncreate graph ${graph};\n# comment\nselect ?s\n  from ${graph}\n  where {?s ?p ?o};\n"
+	f, err := ioutil.TempFile("", "bw_io_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(script); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	defer func(r io.Reader) {
+		stdin = r
+	}(stdin)
+	stdin = strings.NewReader(script)
+
+	want := []string{"create graph ${graph};", "select ?s from ${graph} where {?s ?p ?o};"}
+	table := []struct {
+		path string
+		want []string
+		err  bool
+	}{
+		{path: "-", want: want},
+		{path: f.Name(), want: want},
+		{path: f.Name() + ".missing", err: true},
+	}
+	for _, entry := range table {
+		got, err := GetStatementsFromFile(entry.path)
+		if entry.err {
+			if err == nil {
+				t.Errorf("GetStatementsFromFile(%q) should have failed; got %q", entry.path, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("GetStatementsFromFile(%q) failed with error %v", entry.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("GetStatementsFromFile(%q) returned %q; want %q", entry.path, got, entry.want)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	v := Variables{}
+	for _, d := range []string{"graph=?a", "empty=", "eq=a=b"} {
+		if err := v.Set(d); err != nil {
+			t.Errorf("Variables.Set(%q) failed with error %v", d, err)
+		}
+	}
+	for _, d := range []string{"", "graph", "=?a"} {
+		if err := v.Set(d); err == nil {
+			t.Errorf("Variables.Set(%q) should have failed", d)
+		}
+	}
+	if got, want := v.String(), "empty= eq=a=b graph=?a"; got != want {
+		t.Errorf("Variables.String returned %q; want %q", got, want)
+	}
+	if got, ok := v.Lookup("eq"); !ok || got != "a=b" {
+		t.Errorf("Variables.Lookup(%q) returned (%q, %v); want (%q, true)", "eq", got, ok, "a=b")
+	}
+}
+
+func TestExpandVariables(t *testing.T) {
+	vars := map[string]string{
+		"graph": "?a",
+		"node":  "/u<joe>",
+		"empty": "",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+	table := []struct {
+		stm  string
+		want string
+		err  bool
+	}{
+		{stm: "select ?s from ?a;", want: "select ?s from ?a;"},
+		{stm: "select ?s from ${graph};", want: "select ?s from ?a;"},
+		{stm: "select ?o from ${graph} where {${node} ?p ?o}${empty};", want: "select ?o from ?a where {/u<joe> ?p ?o};"},
+		{stm: "select $s from ?a;", want: "select $s from ?a;"},
+		{stm: "select ?s from ${unknown};", err: true},
+		{stm: "select ?s from ${graph;", err: true},
+	}
+	for _, entry := range table {
+		got, err := ExpandVariables(entry.stm, lookup)
+		if entry.err {
+			if err == nil {
+				t.Errorf("ExpandVariables(%q) should have failed; got %q", entry.stm, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ExpandVariables(%q) failed with error %v", entry.stm, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("ExpandVariables(%q) returned %q; want %q", entry.stm, got, entry.want)
+		}
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	table := []struct {
+		path string
+		want string
+	}{
+		{"", FormatBadWolf},
+		{"data", FormatBadWolf},
+		{"data.bw", FormatBadWolf},
+		{"data.bw.gz", FormatBadWolf},
+		{"data.nt", FormatNTriples},
+		{"data.NT.bz2", FormatNTriples},
+		{"data.nq.gz", FormatNQuads},
+		{"data.ttl", FormatTurtle},
+		{"data.json", FormatJSONLD},
+		{"data.jsonld", FormatJSONLD},
+		{"data.graphml", FormatGraphML},
+		{"data.gexf", FormatGEXF},
+		{"data.gz", FormatBadWolf},
+	}
+	for _, entry := range table {
+		if got := FormatFromPath(entry.path); got != entry.want {
+			t.Errorf("FormatFromPath(%q) returned %q; want %q", entry.path, got, entry.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	allowed := []string{FormatBadWolf, FormatNTriples, FormatNQuads, FormatTurtle}
+	table := []struct {
+		name string
+		want string
+		err  bool
+	}{
+		{name: "bw", want: FormatBadWolf},
+		{name: "NT", want: FormatNTriples},
+		{name: "ntriples", want: FormatNTriples},
+		{name: "NQuads", want: FormatNQuads},
+		{name: "turtle", want: FormatTurtle},
+		{name: "jsonld", err: true},
+		{name: "xml", err: true},
+		{name: "", err: true},
+	}
+	for _, entry := range table {
+		got, err := ParseFormat(entry.name, allowed...)
+		if entry.err {
+			if err == nil {
+				t.Errorf("ParseFormat(%q) should have failed; got %q", entry.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseFormat(%q) failed with error %v", entry.name, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("ParseFormat(%q) returned %q; want %q", entry.name, got, entry.want)
+		}
+	}
+}
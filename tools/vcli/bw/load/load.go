@@ -1,4 +1,4 @@
-// Copyright 2016 Google Inc. All rights reserved.
+// Copyright 2015 Google Inc. All rights reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -12,16 +12,21 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package load contains the command allowing to run a sequence of BQL commands
-// from the provided file.
+// Package load contains the command allowing to load triples in bulk stored in
+// a file.
 package load
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
 	"strings"
-	"context"
+	"time"
 
+	bwio "github.com/google/badwolf/io"
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/tools/vcli/bw/command"
 	"github.com/google/badwolf/tools/vcli/bw/io"
@@ -29,18 +34,51 @@ import (
 	"github.com/google/badwolf/triple/literal"
 )
 
-// New creates the help command.
+// formats are the formats supported by the load command.
+var formats = []string{io.FormatBadWolf, io.FormatNTriples, io.FormatNQuads, io.FormatTurtle, io.FormatJSONLD}
+
+// progressInterval is the minimum time elapsed between two progress reports.
+const progressInterval = time.Second
+
+// New creates the load command.
 func New(store storage.Store, bulkSize, builderSize int) *command.Command {
 	cmd := &command.Command{
-		UsageLine: "load <file_path> <graph_names_separated_by_commas>",
+		UsageLine: "load [--graph=<graph_names>] [--format=bw|nt|nq|ttl|jsonld] [--dry-run] [--errors=<file>] [--max_errors=<n>] [--offset=<n>] [--workers=<n>] <file_path> [<graph_names_separated_by_commas>]",
 		Short:     "load triples in bulk stored in a file.",
 		Long: `Loads all the triples stored in a file into the provided graphs.
-Graph names need to be separated by commands with no whitespaces. Each triple
-needs to placed in a single line. Each triple needs to be formated so it can be
-parsed as indicated in the documetation (see https://github.com/google/badwolf).
-All data in the file will be treated as triples. A line starting with # will
-be treated as a commented line. If the load fails you may end up with partially
-loaded data.
+Graph names need to be separated by commas with no whitespaces, and can be
+provided either with the --graph flag or after the file path, defaulting to the
+graph of the profile in use. Files compressed with gzip or bzip2 are
+decompressed on the fly.
+
+The --format flag indicates how the triples are serialized:
+
+	bw      one triple per line, formated so it can be parsed as indicated
+	        in the documetation (see https://github.com/google/badwolf).
+	        A line starting with # is treated as a commented line.
+	nt      N-Triples.
+	nq      N-Quads; triples are loaded into the graphs named by the file,
+	        and --graph sets the graph of the lines without one.
+	ttl     Turtle.
+	jsonld  JSON-LD.
+
+The ntriples, nquads, and turtle names are also accepted. If no format is provided, it is guessed from the file extension (.nt, .nq,
+.ttl, .jsonld, or .json), defaulting to bw.
+
+Triples are written in batches of the bulk triple operation size, several
+batches at once, and the progress is reported periodically. When using the bw
+format, lines are parsed by --workers concurrent workers, and malformed lines
+do not stop the load; they are reported once done and written to the file
+provided by --errors, one tab separated line number, error, and line text per
+line. If --max_errors is positive, the load is aborted once more malformed
+lines are found. If the load fails you may end up with partially loaded data; the load can then be
+resumed passing the reported line as --offset. Other formats stop at the first
+error found, which is also written to the --errors file using 0 as its line
+number. The command exits with status 1 if malformed lines were skipped, and 2
+if the load failed.
+
+The --dry-run flag parses the file and reports the triples and errors found
+without writing anything.
 `,
 	}
 	cmd.Run = func(ctx context.Context, args []string) int {
@@ -49,73 +87,292 @@ loaded data.
 	return cmd
 }
 
+// options contains the parsed arguments of the load command.
+type options struct {
+	path      string
+	graphs    []string
+	format    string
+	dryRun    bool
+	errors    string
+	maxErrors int
+	offset    int
+	workers   int
+}
+
+// parseArgs parses the arguments of the load command. The default graph names
+// are used if none are provided.
+func parseArgs(args []string, defaultGraph string) (*options, error) {
+	opts, graphs := &options{}, ""
+	fs := flag.NewFlagSet("load", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.StringVar(&graphs, "graph", "", "graph names separated by commas")
+	fs.StringVar(&opts.format, "format", "", "format of the file")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "parse the file without loading it")
+	fs.StringVar(&opts.errors, "errors", "", "file to write malformed lines to")
+	fs.IntVar(&opts.maxErrors, "max_errors", 0, "number of malformed lines tolerated")
+	fs.IntVar(&opts.offset, "offset", 0, "number of lines to skip")
+	fs.IntVar(&opts.workers, "workers", 0, "number of parsing workers")
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	// Flags may appear before or after the file path and graph names.
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if args = fs.Args(); len(args) == 0 {
+			break
+		}
+		rest, args = append(rest, args[0]), args[1:]
+	}
+	switch {
+	case len(rest) == 1:
+		opts.path = rest[0]
+	case len(rest) == 2 && graphs == "":
+		opts.path, graphs = rest[0], rest[1]
+	case len(rest) == 0:
+		return nil, fmt.Errorf("missing required file path")
+	default:
+		return nil, fmt.Errorf("unexpected arguments %q", rest)
+	}
+	if graphs == "" {
+		graphs = defaultGraph
+	}
+	if graphs != "" {
+		opts.graphs = strings.Split(graphs, ",")
+	}
+	if opts.format == "" {
+		opts.format = io.FormatFromPath(opts.path)
+	}
+	f, err := io.ParseFormat(opts.format, formats...)
+	if err != nil {
+		return nil, err
+	}
+	opts.format = f
+	switch {
+	case f != io.FormatNQuads && len(opts.graphs) == 0:
+		return nil, fmt.Errorf("missing required graph names")
+	case f == io.FormatNQuads && len(opts.graphs) > 1:
+		return nil, fmt.Errorf("N-Quads files accept a single default graph, got %q", opts.graphs)
+	}
+	return opts, nil
+}
+
+// graphs adds the triples to all the provided graphs.
+type graphs struct {
+	storage.Graph
+	all []storage.Graph
+}
+
+// AddTriples adds the triples to all the graphs.
+func (g *graphs) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	for _, gr := range g.all {
+		if err := gr.AddTriples(ctx, ts); err != nil {
+			return fmt.Errorf("failed to load triples into graph %q; %v", gr.ID(ctx), err)
+		}
+	}
+	return nil
+}
+
+// discard is the graph used on dry runs; it drops all the triples added.
+type discard struct {
+	storage.Graph
+}
+
+// AddTriples drops the provided triples.
+func (discard) AddTriples(context.Context, []*triple.Triple) error {
+	return nil
+}
+
+// progress returns a function that reports the triples loaded so far at most
+// once every progressInterval.
+func progress(start time.Time) func(*storage.BatchResult) {
+	last := start
+	return func(res *storage.BatchResult) {
+		if now := time.Now(); res.Err == nil && now.Sub(last) >= progressInterval {
+			last = now
+			log.Printf("Loaded %d triples (%.0f triples/sec)\n", res.Loaded, float64(res.Loaded)/now.Sub(start).Seconds())
+		}
+	}
+}
+
 // Eval loads the triples in the file against as indicated by the command.
 func Eval(ctx context.Context, usage string, args []string, store storage.Store, bulkSize, builderSize int) int {
-	if len(args) < 3 {
-		log.Printf("[ERROR] Missing required file path and/or graph names.\n\n%s", usage)
+	opts, err := parseArgs(args, command.DefaultsFrom(ctx).Graph)
+	if err != nil {
+		log.Printf("[ERROR] %v.\n\n%s", err, usage)
 		return 2
 	}
-	graphs, lb := strings.Split(args[len(args)-1], ","), literal.NewBoundedBuilder(builderSize)
-	trplsChan, errChan, doneChan := make(chan *triple.Triple), make(chan error), make(chan bool)
-	path := args[len(args)-2]
-	go storeTriple(ctx, store, graphs, bulkSize, trplsChan, errChan, doneChan)
-	cnt, err := io.ProcessLines(path, func(line string) error {
-		t, err := triple.Parse(line, lb)
-		if err != nil {
-			return err
+	lb := literal.NewBoundedBuilder(builderSize)
+	if storage.GetLiteralLimit(ctx, store) > 0 {
+		// The literal size limit of the store takes precedence.
+		lb = storage.LiteralBuilder(ctx, store)
+	}
+	var g storage.Graph = discard{}
+	if !opts.dryRun && opts.format != io.FormatNQuads {
+		all := &graphs{}
+		for _, graph := range opts.graphs {
+			gr, err := store.Graph(ctx, graph)
+			if err != nil {
+				log.Printf("[ERROR] Failed to load triples into graph %q. %v\n", graph, err)
+				return 2
+			}
+			all.all = append(all.all, gr)
 		}
-		trplsChan <- t
-		return <-errChan
-	})
+		all.Graph, g = all.all[0], all
+	}
+	f, err := os.Open(opts.path)
 	if err != nil {
-		log.Printf("[ERROR] Failed to process file %q. Ivalid triple on line %d. %v\n", path, cnt, err)
+		log.Printf("[ERROR] Failed to open file %q. %v\n", opts.path, err)
 		return 2
 	}
-	doneChan <- true
-	if err := <-errChan; err != nil {
-		log.Printf("[ERROR] Failed to process file %q. Ivalid triple on line %d. %v\n", path, cnt, err)
+	defer f.Close()
+
+	start := time.Now()
+	var (
+		rep  *bwio.LoadReport
+		lErr error
+	)
+	switch opts.format {
+	case io.FormatBadWolf:
+		rep, lErr = bwio.LoadGraph(ctx, g, f, lb, &bwio.LoadOptions{
+			Workers:   opts.workers,
+			BatchSize: bulkSize,
+			Offset:    opts.offset,
+			MaxErrors: opts.maxErrors,
+			Progress:  progress(start),
+		})
+	case io.FormatNQuads:
+		rep, lErr = loadNQuads(ctx, store, f, lb, opts)
+	default:
+		rep, lErr = loadRDF(ctx, g, f, lb, bulkSize, opts.format, progress(start))
+	}
+	if opts.errors != "" && (len(rep.Errors) > 0 || lErr != nil) {
+		if err := writeErrors(opts.errors, rep.Errors, lErr); err != nil {
+			log.Printf("[ERROR] Failed to write the error report %q. %v\n", opts.errors, err)
+		}
+	}
+	for _, le := range rep.Errors {
+		log.Printf("[WARNING] Malformed triple in file %q. %v\n", opts.path, le)
+	}
+	if lErr != nil {
+		log.Printf("[ERROR] Failed to process file %q. %v\n", opts.path, lErr)
+		if opts.format == io.FormatBadWolf && !opts.dryRun {
+			log.Printf("[ERROR] Lines before line %d were loaded; resume the load with --offset=%d.\n", rep.Resume+1, rep.Resume)
+		}
 		return 2
 	}
-	fmt.Printf("Successfully processed %d lines from file %q.\nTriples loaded into graphs:\n\t- %s\n", cnt, path, strings.Join(graphs, "\n\t- "))
+	verb, target := "loaded into graphs", opts.graphs
+	if opts.format == io.FormatNQuads {
+		target = nil
+	}
+	if opts.dryRun {
+		verb = "found (dry run, nothing was loaded)"
+	}
+	if opts.format == io.FormatBadWolf {
+		fmt.Printf("Successfully processed %d lines from file %q in %v.\n", rep.Lines, opts.path, time.Since(start).Round(time.Millisecond))
+	} else {
+		fmt.Printf("Successfully processed file %q in %v.\n", opts.path, time.Since(start).Round(time.Millisecond))
+	}
+	fmt.Printf("%d triples %s", rep.Triples, verb)
+	switch {
+	case opts.dryRun:
+		fmt.Println(".")
+	case len(target) == 0:
+		fmt.Println(" named in the file.")
+	default:
+		fmt.Printf(":\n\t- %s\n", strings.Join(target, "\n\t- "))
+	}
+	if len(rep.Errors) > 0 {
+		fmt.Printf("%d malformed lines were skipped.\n", len(rep.Errors))
+		return 1
+	}
 	return 0
 }
 
-var workingTrpls []*triple.Triple
-
-func flush(ctx context.Context, graphs []string, store storage.Store) error {
-	defer func() {
-		workingTrpls = nil
-	}()
-	if len(workingTrpls) > 0 {
-		for _, graph := range graphs {
-			g, err := store.Graph(ctx, graph)
-			if err != nil {
-				return err
-			}
-			if err := g.AddTriples(ctx, workingTrpls); err != nil {
-				return err
-			}
+// loadRDF loads the triples of an N-Triples, Turtle, or JSON-LD file into the
+// graph. These formats stop at the first error found.
+func loadRDF(ctx context.Context, g storage.Graph, f *os.File, b literal.Builder, bulkSize int, format string, p func(*storage.BatchResult)) (*bwio.LoadReport, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		ts   = make(chan *triple.Triple, bulkSize)
+		done = make(chan struct{})
+		rErr error
+		rep  = &bwio.LoadReport{}
+	)
+	go func() {
+		defer close(done)
+		switch format {
+		case io.FormatNTriples:
+			_, rErr = bwio.ReadNTriples(ctx, f, b, nil, ts)
+		case io.FormatTurtle:
+			_, rErr = bwio.ReadTurtle(ctx, f, b, nil, ts)
+		case io.FormatJSONLD:
+			_, rErr = bwio.ReadJSONLD(ctx, f, b, nil, ts)
 		}
+	}()
+	err := storage.BulkLoad(ctx, g, ts, &storage.BulkLoadOptions{
+		BatchSize: bulkSize,
+		Progress: func(res *storage.BatchResult) {
+			rep.Triples = int(res.Loaded)
+			p(res)
+		},
+	})
+	if err != nil {
+		// Stop the reader, which may be blocked on the channel.
+		cancel()
 	}
-	return nil
+	<-done
+	if err == nil {
+		err = rErr
+	}
+	return rep, err
 }
 
-func storeTriple(ctx context.Context, store storage.Store, graphs []string, bulkSize int, trplChan <-chan *triple.Triple, errChan chan<- error, doneChan <-chan bool) {
-	for {
-		select {
-		case trpl := <-trplChan:
-			if len(workingTrpls) < bulkSize {
-				workingTrpls = append(workingTrpls, trpl)
-				errChan <- nil
-			} else {
-				err := flush(ctx, graphs, store)
-				workingTrpls = append(workingTrpls, trpl)
-				errChan <- err
+// loadNQuads loads the quads of an N-Quads file into the graphs they name,
+// using the provided graph, if any, as the default one. On dry runs the quads
+// are only counted.
+func loadNQuads(ctx context.Context, store storage.Store, f *os.File, b literal.Builder, opts *options) (*bwio.LoadReport, error) {
+	nqo := &bwio.NQuadsOptions{}
+	if len(opts.graphs) > 0 {
+		nqo.DefaultGraph = opts.graphs[0]
+	}
+	var (
+		rep = &bwio.LoadReport{}
+		err error
+	)
+	if opts.dryRun {
+		qs := make(chan *bwio.Quad)
+		go func() {
+			for range qs {
 			}
-		case <-doneChan:
-			err := flush(ctx, graphs, store)
-			errChan <- err
-			return
+		}()
+		rep.Triples, err = bwio.ReadNQuads(ctx, f, b, nqo, qs)
+	} else {
+		rep.Triples, err = bwio.LoadNQuads(ctx, store, f, b, nqo)
+	}
+	return rep, err
+}
+
+// writeErrors writes the malformed lines found to the provided file, one tab
+// separated line number, error, and line text per line. The error that stopped
+// the load, if any, is written last using 0 as its line number.
+func writeErrors(path string, errs []*bwio.LineError, lErr error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if lErr != nil {
+		errs = append(errs, &bwio.LineError{Err: lErr})
+	}
+	for _, le := range errs {
+		text := strings.TrimRight(le.Text, "\r\n")
+		if _, err := fmt.Fprintf(f, "%d\t%v\t%s\n", le.Line, le.Err, text); err != nil {
+			f.Close()
+			return err
 		}
 	}
+	return f.Close()
 }
@@ -0,0 +1,169 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package load
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/tools/vcli/bw/io"
+)
+
+func TestParseArgs(t *testing.T) {
+	table := []struct {
+		args         []string
+		defaultGraph string
+		want         *options
+	}{
+		{
+			args: []string{"load", "data.bw", "?a,?b"},
+			want: &options{path: "data.bw", graphs: []string{"?a", "?b"}, format: io.FormatBadWolf},
+		},
+		{
+			args: []string{"load", "--graph=?a", "data.nt", "--dry-run"},
+			want: &options{path: "data.nt", graphs: []string{"?a"}, format: io.FormatNTriples, dryRun: true},
+		},
+		{
+			args:         []string{"load", "data.ttl.gz", "--max_errors=3", "--offset=10", "--workers=2", "--errors=bad.txt"},
+			defaultGraph: "?default",
+			want:         &options{path: "data.ttl.gz", graphs: []string{"?default"}, format: io.FormatTurtle, errors: "bad.txt", maxErrors: 3, offset: 10, workers: 2},
+		},
+		{
+			args: []string{"load", "--format=NTRIPLES", "data.txt", "?a"},
+			want: &options{path: "data.txt", graphs: []string{"?a"}, format: io.FormatNTriples},
+		},
+		{
+			args: []string{"load", "data.nq"},
+			want: &options{path: "data.nq", format: io.FormatNQuads},
+		},
+		{
+			args: []string{"load", "data.nq", "?a"},
+			want: &options{path: "data.nq", graphs: []string{"?a"}, format: io.FormatNQuads},
+		},
+	}
+	for _, entry := range table {
+		got, err := parseArgs(entry.args, entry.defaultGraph)
+		if err != nil {
+			t.Errorf("parseArgs(%q) failed with error %v", entry.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("parseArgs(%q) returned %+v; want %+v", entry.args, got, entry.want)
+		}
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	table := [][]string{
+		{"load"},
+		{"load", "data.bw"},
+		{"load", "--graph=?a", "data.bw", "?b"},
+		{"load", "data.bw", "?a", "extra"},
+		{"load", "--format=graphml", "data.graphml", "?a"},
+		{"load", "data.nq", "?a,?b"},
+		{"load", "--unknown", "data.bw", "?a"},
+		{"load", "--max_errors=many", "data.bw", "?a"},
+	}
+	for _, args := range table {
+		if got, err := parseArgs(args, ""); err == nil {
+			t.Errorf("parseArgs(%q) should have failed; got %+v", args, got)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badwolf_load")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "data.bw")
+	data := strings.Join([]string{
+		"/u<joe>\t\"parent_of\"@[]\t/u<mary>",
+		"not a triple",
+		"/u<mary>\t\"parent_of\"@[]\t/u<peter>",
+		"",
+	}, "\n")
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	errPath := filepath.Join(dir, "errors.txt")
+
+	table := []struct {
+		args    []string
+		want    int
+		triples int64
+		errors  bool
+	}{
+		{
+			args:    []string{"load", path, "?a,?b"},
+			want:    1,
+			triples: 2,
+		},
+		{
+			args:    []string{"load", "--dry-run", "--max_errors=1", path, "?a,?b"},
+			want:    1,
+			triples: 0,
+		},
+		{
+			args:    []string{"load", "--max_errors=1", "--errors=" + errPath, path, "?a,?b"},
+			want:    1,
+			triples: 2,
+			errors:  true,
+		},
+		{
+			args: []string{"load", filepath.Join(dir, "missing.bw"), "?a"},
+			want: 2,
+		},
+	}
+	for _, entry := range table {
+		ctx, s := context.Background(), memory.NewStore()
+		var gs []storage.Graph
+		for _, name := range []string{"?a", "?b"} {
+			g, err := s.NewGraph(ctx, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gs = append(gs, g)
+		}
+		os.Remove(errPath)
+		if got := Eval(ctx, "", entry.args, s, 10, 0); got != entry.want {
+			t.Errorf("Eval(%q) returned status %d; want %d", entry.args, got, entry.want)
+		}
+		for _, g := range gs {
+			n, err := storage.CountTriples(ctx, g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != entry.triples {
+				t.Errorf("Eval(%q) loaded %d triples into graph %q; want %d", entry.args, n, g.ID(ctx), entry.triples)
+			}
+		}
+		bs, err := ioutil.ReadFile(errPath)
+		if got := err == nil; got != entry.errors {
+			t.Errorf("Eval(%q) wrote the error report %v; want %v", entry.args, got, entry.errors)
+		}
+		if entry.errors && !strings.HasPrefix(string(bs), "2\t") {
+			t.Errorf("Eval(%q) wrote error report %q; want the malformed line 2", entry.args, bs)
+		}
+	}
+}
@@ -20,13 +20,28 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/gocql/gocql"
+	redigo "github.com/gomodule/redigo/redis"
 	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/badger"
+	"github.com/google/badwolf/storage/bolt"
+	"github.com/google/badwolf/storage/cassandra"
+	"github.com/google/badwolf/storage/leveldb"
 	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/storage/postgres"
+	"github.com/google/badwolf/storage/redis"
+	"github.com/google/badwolf/tools/vcli/bw/command"
 	"github.com/google/badwolf/tools/vcli/bw/common"
-	"github.com/google/badwolf/tools/vcli/bw/repl"
+	"github.com/google/badwolf/tools/vcli/bw/config"
+
+	_ "github.com/lib/pq"
 )
 
 var (
@@ -34,12 +49,23 @@ var (
 	registeredDrivers map[string]common.StoreGenerator
 
 	// Available flags.
-	driver                = flag.String("driver", "VOLATILE", "The storage driver to use {VOLATILE}.")
+	driver                = flag.String("driver", "VOLATILE", "The storage driver to use {VOLATILE|BOLT|BADGER|LEVELDB|POSTGRES|REDIS|CASSANDRA}.")
 	bqlChannelSize        = flag.Int("bql_channel_size", 0, "Internal channel size to use on BQL queries.")
 	bulkTripleOpSize      = flag.Int("bulk_triple_op_size", 1000, "Number of triples to use in bulk load operations.")
-	bulkTripleBuilderSize = flag.Int("bulk_triple_builder_size_in_bytes", 1000, "Maximum size of literals when parsing a triple.")
+	bulkTripleBuilderSize = flag.Int("bulk_triple_builder_size_in_bytes", 1000, "Maximum size of literals when parsing a triple, unless the store limits the size of literals.")
+	configPath            = flag.String("config", "", "The configuration file defining the profiles; ~/.badwolf/config if not provided.")
+	profile               = flag.String("profile", "", "The profile of the configuration file setting the flags not provided; the default profile of the file if not provided.")
 
 	// Add your driver flags below.
+	volatileShards = flag.Int("volatile_shards", 1, "The number of shards the graphs of the VOLATILE driver partition their triples into.")
+	boltPath       = flag.String("bolt_path", "badwolf.db", "The database file used by the BOLT driver.")
+	badgerDir      = flag.String("badger_dir", "badwolf.badger", "The database directory used by the BADGER driver.")
+	levelDBDir     = flag.String("leveldb_dir", "badwolf.leveldb", "The database directory used by the LEVELDB driver.")
+	postgresDSN    = flag.String("postgres_dsn", "", "The connection string of the database used by the POSTGRES driver.")
+	redisAddr      = flag.String("redis_addr", "localhost:6379", "The address of the server used by the REDIS driver.")
+	redisNS        = flag.String("redis_namespace", "badwolf:", "The prefix of all the keys used by the REDIS driver.")
+	cqlHosts       = flag.String("cassandra_hosts", "localhost", "The comma separated hosts of the cluster used by the CASSANDRA driver.")
+	cqlKeyspace    = flag.String("cassandra_keyspace", "badwolf", "The keyspace used by the CASSANDRA driver.")
 )
 
 // Registers the available drivers.
@@ -47,13 +73,114 @@ func registerDrivers() {
 	registeredDrivers = map[string]common.StoreGenerator{
 		// Memory only storage driver.
 		"VOLATILE": func() (storage.Store, error) {
-			return memory.NewStore(), nil
+			return memory.NewShardedStore(*volatileShards, memory.AllIndexes), nil
+		},
+		// Persistent single file storage driver.
+		"BOLT": func() (storage.Store, error) {
+			return bolt.NewStore(*boltPath)
+		},
+		// Persistent storage driver for write heavy temporal ingestion.
+		"BADGER": func() (storage.Store, error) {
+			return badger.NewStore(*badgerDir)
+		},
+		// Persistent storage driver with range scans on time anchors.
+		"LEVELDB": func() (storage.Store, error) {
+			return leveldb.NewStore(*levelDBDir)
+		},
+		// Persistent storage driver on top of a PostgreSQL database.
+		"POSTGRES": func() (storage.Store, error) {
+			db, err := sql.Open("postgres", *postgresDSN)
+			if err != nil {
+				return nil, err
+			}
+			return postgres.NewStore(db)
 		},
+		// Storage driver sharing graphs among frontends through a Redis server.
+		"REDIS": func() (storage.Store, error) {
+			pool := &redigo.Pool{
+				Dial: func() (redigo.Conn, error) {
+					return redigo.Dial("tcp", *redisAddr)
+				},
+			}
+			return redis.NewStore(pool, *redisNS)
+		},
+		// Persistent storage driver for graphs too large for a single machine.
+		"CASSANDRA": func() (storage.Store, error) {
+			cluster := gocql.NewCluster(strings.Split(*cqlHosts, ",")...)
+			cluster.Keyspace = *cqlKeyspace
+			session, err := cluster.CreateSession()
+			if err != nil {
+				return nil, err
+			}
+			return cassandra.NewStore(session)
+		},
+	}
+}
+
+// parseCommandFlags parses the flags of the tool provided after the name of the
+// command, so `bw server --driver=BOLT` works as `bw --driver=BOLT server`. It
+// returns the rest of the arguments, which are left to the command.
+func parseCommandFlags(args []string) []string {
+	var flags, rest []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		name := strings.TrimLeft(a, "-")
+		if idx := strings.Index(name, "="); idx >= 0 {
+			name = name[:idx]
+		}
+		f := flag.Lookup(name)
+		if !strings.HasPrefix(a, "-") || f == nil {
+			rest = append(rest, a)
+			continue
+		}
+		flags = append(flags, a)
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+			continue
+		}
+		if !strings.Contains(a, "=") && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
 	}
+	flag.CommandLine.Parse(flags)
+	return rest
+}
+
+// applyProfile sets the flags not provided to the settings of the requested
+// profile and returns the command defaults it defines. A missing configuration
+// file is only an error if it or a profile were explicitly requested.
+func applyProfile() (command.Defaults, error) {
+	path := *configPath
+	if path == "" {
+		path = config.DefaultPath()
+	}
+	cfg, err := config.Load(path)
+	if os.IsNotExist(err) && *configPath == "" && *profile == "" {
+		return command.Defaults{}, nil
+	}
+	if err != nil {
+		return command.Defaults{}, err
+	}
+	p, err := cfg.Profile(*profile)
+	if err != nil {
+		return command.Defaults{}, fmt.Errorf("%v in configuration file %q", err, path)
+	}
+	return p.Apply(flag.CommandLine)
 }
 
 func main() {
 	flag.Parse()
+	args := flag.Args()
+	if len(args) > 0 {
+		args = append(args[:1:1], parseCommandFlags(args[1:])...)
+	}
+	defaults, err := applyProfile()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 	registerDrivers()
-	os.Exit(common.Run(*driver, flag.Args(), registeredDrivers, *bqlChannelSize, *bulkTripleOpSize, *bulkTripleBuilderSize, repl.SimpleReadLine))
+	ctx := command.WithDefaults(context.Background(), defaults)
+	// A nil ReadLiner makes the REPL use repl.TerminalReadLine.
+	os.Exit(common.Run(ctx, *driver, args, registeredDrivers, *bqlChannelSize, *bulkTripleOpSize, *bulkTripleBuilderSize, nil))
 }
@@ -0,0 +1,104 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repl
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/storage"
+)
+
+// Completer returns the lines completing the last word of the provided line.
+type Completer func(line string) []string
+
+// consoleCommands lists the commands of the REPL that are not BQL statements.
+var consoleCommands = []string{
+	"desc", "disable", "enable", "export", "help", "load", "memoization",
+	"quit", "run", "start", "stop", "tracing",
+}
+
+// backslashCommands lists the backslash commands of the REPL.
+var backslashCommands = []string{
+	`\format`, `\graphs`, `\help`, `\history`, `\q`, `\r`, `\timing`,
+}
+
+// NewCompleter returns a completer for BQL keywords, console commands, and
+// the names of the graphs in the store returned by the provided function.
+// Graph names are completed for words starting with ?, and backslash commands
+// for words starting with \.
+func NewCompleter(ctx context.Context, store func() storage.Store) Completer {
+	return func(line string) []string {
+		return complete(line, func() []string {
+			names, _ := graphNames(ctx, store())
+			return names
+		})
+	}
+}
+
+// complete returns the lines completing the last word of the provided line,
+// retrieving the graph names only if needed.
+func complete(line string, graphs func() []string) []string {
+	start := strings.LastIndexAny(line, " \t(,{") + 1
+	head, word := line[:start], line[start:]
+	var cands []string
+	switch {
+	case strings.HasPrefix(word, `\`):
+		cands = backslashCommands
+	case strings.HasPrefix(word, "?"):
+		cands = graphs()
+	case word == "":
+		return nil
+	default:
+		cands = append(lexer.Keywords(), consoleCommands...)
+	}
+	upper := word == strings.ToUpper(word) && word != strings.ToLower(word)
+	var res []string
+	for _, c := range cands {
+		if !strings.HasPrefix(strings.ToLower(c), strings.ToLower(word)) {
+			continue
+		}
+		if upper {
+			c = strings.ToUpper(c)
+		} else {
+			c = word + c[len(word):]
+		}
+		res = append(res, head+c)
+	}
+	sort.Strings(res)
+	// Some console commands are also BQL keywords.
+	for i := len(res) - 1; i > 0; i-- {
+		if res[i] == res[i-1] {
+			res = append(res[:i], res[i+1:]...)
+		}
+	}
+	return res
+}
+
+// graphNames returns the sorted names of the graphs in the store.
+func graphNames(ctx context.Context, store storage.Store) ([]string, error) {
+	var names []string
+	c, errc := make(chan string), make(chan error, 1)
+	go func() {
+		errc <- store.GraphNames(ctx, c)
+	}()
+	for n := range c {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names, <-errc
+}
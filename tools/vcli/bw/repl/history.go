@@ -0,0 +1,137 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DefaultHistoryFile is the name of the file in the home directory of the
+	// user where the REPL keeps the statements run across sessions.
+	DefaultHistoryFile = ".bw_history"
+
+	// DefaultHistorySize is the maximum number of statements kept in the
+	// history.
+	DefaultHistorySize = 1000
+)
+
+// History keeps the statements run in the REPL across sessions. Statements
+// are appended to a file, one per line, as they are added.
+type History struct {
+	path    string
+	size    int
+	entries []string
+}
+
+// DefaultHistoryPath returns the path of DefaultHistoryFile in the home
+// directory of the user, or an empty path if the home directory is unknown.
+func DefaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, DefaultHistoryFile)
+}
+
+// NewHistory returns the history kept in the file with the provided path,
+// keeping at most the provided number of statements. The file is created
+// once the first statement is added. An empty path keeps the history in
+// memory only.
+func NewHistory(path string, size int) (*History, error) {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	h := &History{path: path, size: size}
+	if path == "" {
+		return h, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file %q; %v", path, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if l := strings.TrimSpace(scanner.Text()); l != "" {
+			h.entries = append(h.entries, l)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file %q; %v", path, err)
+	}
+	if len(h.entries) > size {
+		// Compact the file so it does not grow forever.
+		h.entries = h.entries[len(h.entries)-size:]
+		if err := h.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// rewrite replaces the content of the history file with the current entries.
+func (h *History) rewrite() error {
+	content := strings.Join(h.entries, "\n") + "\n"
+	if err := ioutil.WriteFile(h.path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write history file %q; %v", h.path, err)
+	}
+	return nil
+}
+
+// Add adds a statement to the history. Statements spanning several lines are
+// kept in a single one. Empty statements and repetitions of the last one are
+// ignored. If the history file cannot be written, the error is returned and
+// the history is only kept in memory from then on.
+func (h *History) Add(stm string) error {
+	stm = strings.Join(strings.Fields(stm), " ")
+	if stm == "" || len(h.entries) > 0 && h.entries[len(h.entries)-1] == stm {
+		return nil
+	}
+	h.entries = append(h.entries, stm)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+	if h.path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		path := h.path
+		h.path = ""
+		return fmt.Errorf("failed to open history file %q; %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(stm + "\n"); err != nil {
+		path := h.path
+		h.path = ""
+		return fmt.Errorf("failed to write history file %q; %v", path, err)
+	}
+	return nil
+}
+
+// Entries returns the statements in the history, oldest first.
+func (h *History) Entries() []string {
+	return append([]string{}, h.entries...)
+}
@@ -46,12 +46,19 @@ const prompt = "bql> "
 func New(driver storage.Store, chanSize, bulkSize, builderSize int, rl ReadLiner, done chan bool) *command.Command {
 	return &command.Command{
 		Run: func(ctx context.Context, args []string) int {
-			REPL(driver, os.Stdin, rl, chanSize, bulkSize, builderSize, done)
+			format, _, err := command.FormatFlag(ctx, args)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			startREPL(ctx, driver, rl, format, chanSize, bulkSize, builderSize, done)
 			return 0
 		},
-		UsageLine: "bql",
+		UsageLine: "bql [--format=table|csv|tsv|json|sparql-json|ndjson|sparql-xml]",
 		Short:     "starts a REPL to run BQL statements.",
-		Long:      "Starts a REPL from the command line to accept BQL statements. Type quit; to leave the REPL.",
+		Long: `Starts a REPL from the command line to accept BQL statements. Type quit; to
+leave the REPL. The --format flag sets the initial format used to print query
+results, which can be changed using the \format command.`,
 	}
 }
 
@@ -59,39 +66,56 @@ func New(driver storage.Store, chanSize, bulkSize, builderSize int, rl ReadLiner
 type ReadLiner func(done chan bool) <-chan string
 
 // SimpleReadLine reads a line from the provided file. This does not support
-// any advanced terminal capabilities.
+// any advanced terminal capabilities. Statements can span several lines until
+// they end with ;, and backslash commands are sent as soon as they are read.
 //
 // This function can be replaced with more advanced functionality, as shown
-// https://github.com/xllora/bwdrivers/blob/master/bw/main.go.
+// https://github.com/xllora/bwdrivers/blob/master/bw/main.go, or by
+// TerminalReadLine.
 func SimpleReadLine(done chan bool) <-chan string {
 	c := make(chan string)
 	go func() {
 		defer close(c)
 		scanner := bufio.NewScanner(os.Stdin)
-		cmd := ""
-		fmt.Print("bql> ")
+		stm := ""
+		fmt.Print(prompt)
 		for {
 			if !scanner.Scan() {
 				break
 			}
-			cmd = strings.TrimSpace(cmd + " " + strings.TrimSpace(scanner.Text()))
-			if strings.HasSuffix(cmd, ";") {
-				c <- cmd
-				if <-done {
-					break
+			cmd, ok := accumulate(&stm, scanner.Text())
+			if !ok {
+				if stm != "" {
+					fmt.Print(continuationPrompt)
+				} else {
+					fmt.Print(prompt)
 				}
-				cmd = ""
-				fmt.Print("bql> ")
+				continue
+			}
+			c <- cmd
+			if <-done {
+				break
 			}
+			fmt.Print(prompt)
 		}
 	}()
 	return c
 }
 
-// REPL starts a read-evaluation-print-loop to run BQL commands.
+// REPL starts a read-evaluation-print-loop to run BQL commands. The statements
+// run are kept in the history file in the home directory of the user. If no
+// ReadLiner is provided, TerminalReadLine is used with that history and
+// completion of keywords, commands, and graph names.
 func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, builderSize int, done chan bool) int {
+	return startREPL(context.Background(), od, rl, table.FormatTable, chanSize, bulkSize, builderSize, done)
+}
+
+// startREPL starts a REPL printing query results using the provided format.
+// The commands run by the REPL use the defaults carried by the context.
+func startREPL(ctx context.Context, od storage.Store, rl ReadLiner, format table.Format, chanSize, bulkSize, builderSize int, done chan bool) int {
 	var tracer io.Writer
-	ctx, isTracingToFile, sessionStart := context.Background(), false, time.Now()
+	isTracingToFile, sessionStart := false, time.Now()
+	timing := true
 
 	driverPlain := func() storage.Store {
 		return od
@@ -103,6 +127,23 @@ func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, bu
 
 	driver := driverWithMemoization
 
+	history, err := NewHistory(DefaultHistoryPath(), DefaultHistorySize)
+	if err != nil {
+		fmt.Printf("[WARNING] History will not be kept across sessions; %v\n", err)
+		history, _ = NewHistory("", DefaultHistorySize)
+	}
+	if rl == nil {
+		rl = TerminalReadLine(history, NewCompleter(ctx, func() storage.Store {
+			return driver()
+		}))
+	}
+	// spent prints the time spent running a command if timing is on.
+	spent := func(now time.Time) {
+		if timing {
+			fmt.Println("[OK] Time spent: ", time.Now().Sub(now))
+		}
+	}
+
 	stopTracing := func() {
 		if tracer != nil {
 			if isTracingToFile {
@@ -124,10 +165,61 @@ func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, bu
 	}()
 
 	for l := range rl(done) {
-		if strings.HasPrefix(l, "quit") {
+		if err := history.Add(l); err != nil {
+			fmt.Printf("[WARNING] %v\n", err)
+		}
+		if strings.HasPrefix(l, "quit") || l == `\q` {
 			done <- true
 			break
 		}
+		if strings.HasPrefix(l, `\`) {
+			args := strings.Fields(l)
+			switch args[0] {
+			case `\timing`:
+				switch {
+				case len(args) == 1:
+					timing = !timing
+				case args[1] == "on" || args[1] == "off":
+					timing = args[1] == "on"
+				default:
+					fmt.Println("Invalid syntax\n\t\\timing [on|off]")
+				}
+				if timing {
+					fmt.Println("Timing is on.")
+				} else {
+					fmt.Println("Timing is off.")
+				}
+			case `\format`:
+				if len(args) > 1 {
+					f, err := table.ParseFormat(args[1])
+					if err != nil {
+						fmt.Printf("[ERROR] %s\n", err)
+					} else {
+						format = f
+					}
+				}
+				fmt.Printf("Output format is %s.\n", format)
+			case `\graphs`:
+				names, err := graphNames(ctx, driver())
+				if err != nil {
+					fmt.Printf("[ERROR] %s\n", err)
+				}
+				for _, n := range names {
+					fmt.Println(n)
+				}
+				fmt.Printf("[OK] %d graphs.\n", len(names))
+			case `\history`:
+				for i, h := range history.Entries() {
+					fmt.Printf("%5d  %s\n", i+1, h)
+				}
+			case `\help`, `\?`:
+				printHelp()
+			default:
+				fmt.Printf("[ERROR] Unknown command %s. Type \\help for help.\n", args[0])
+			}
+			done <- false
+			continue
+		}
 		if strings.HasPrefix(l, "help") {
 			printHelp()
 			done <- false
@@ -177,19 +269,19 @@ func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, bu
 		}
 		if strings.HasPrefix(l, "export") {
 			now := time.Now()
-			args := strings.Split("bw "+strings.TrimSpace(l)[:len(l)-1], " ")
-			usage := "Wrong syntax\n\n\tload <graph_names_separated_by_commas> <file_path>\n"
+			args := strings.Fields(l[:len(l)-1])
+			usage := "Wrong syntax\n\n\texport [--graph=<graph_names>] [--format=bw|nt|nq|ttl|jsonld|graphml|gexf] [-o <file_path>] [<graph_names_separated_by_commas> <file_path>]\n"
 			export.Eval(ctx, usage, args, driver(), bulkSize)
-			fmt.Println("[OK] Time spent: ", time.Now().Sub(now))
+			spent(now)
 			done <- false
 			continue
 		}
 		if strings.HasPrefix(l, "load") {
 			now := time.Now()
-			args := strings.Split("bw "+strings.TrimSpace(l[:len(l)-1]), " ")
-			usage := "Wrong syntax\n\n\tload <file_path> <graph_names_separated_by_commas>\n"
+			args := strings.Fields(l[:len(l)-1])
+			usage := "Wrong syntax\n\n\tload [--graph=<graph_names>] [--format=bw|nt|nq|ttl|jsonld] [--dry-run] [--errors=<file>] <file_path> [<graph_names_separated_by_commas>]\n"
 			load.Eval(ctx, usage, args, driver(), bulkSize, builderSize)
-			fmt.Println("[OK] Time spent: ", time.Now().Sub(now))
+			spent(now)
 			done <- false
 			continue
 		}
@@ -208,34 +300,50 @@ func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, bu
 		}
 		if strings.HasPrefix(l, "run") {
 			now := time.Now()
-			path, cmds, err := runBQLFromFile(ctx, driver(), chanSize, bulkSize, strings.TrimSpace(l[:len(l)-1]), tracer)
+			rctx, stop := command.WithInterrupt(ctx)
+			path, cmds, err := runBQLFromFile(rctx, driver(), chanSize, bulkSize, strings.TrimSpace(l[:len(l)-1]), tracer)
+			stop()
 			if err != nil {
 				fmt.Printf("[ERROR] %s\n\n", err)
 			} else {
 				fmt.Printf("Loaded %q and run %d BQL commands successfully\n\n", path, cmds)
 			}
-			fmt.Println("Time spent: ", time.Now().Sub(now))
+			if timing {
+				fmt.Println("Time spent: ", time.Now().Sub(now))
+			}
 			done <- false
 			continue
 		}
 
 		now := time.Now()
-		table, err := runBQL(ctx, l, driver(), chanSize, bulkSize, tracer)
+		// Hitting Ctrl-C while the statement runs aborts it.
+		qctx, stop := command.WithInterrupt(ctx)
+		tbl, err := runBQL(qctx, l, driver(), chanSize, bulkSize, tracer)
+		stop()
 		bqlDiff := time.Now().Sub(now)
 		if err != nil {
 			fmt.Printf("[ERROR] %s\n", err)
-			fmt.Println("Time spent: ", time.Now().Sub(now))
+			if timing {
+				fmt.Println("Time spent: ", time.Now().Sub(now))
+			}
 			fmt.Println()
 		} else {
-			if table == nil {
-				fmt.Printf("[OK] 0 rows retrieved. BQL time: %v. Display time: %v\n",
-					bqlDiff, time.Now().Sub(now)-bqlDiff)
-			} else {
-				if len(table.Bindings()) > 0 {
-					fmt.Println(table.String())
+			rows := 0
+			if tbl != nil {
+				rows = tbl.NumRows()
+				if len(tbl.Bindings()) > 0 {
+					if format == table.FormatTable {
+						fmt.Println(tbl.String())
+					} else if err := tbl.Write(os.Stdout, format); err != nil {
+						fmt.Printf("[ERROR] %s\n", err)
+					}
 				}
+			}
+			if timing {
 				fmt.Printf("[OK] %d rows retrieved. BQL time: %v. Display time: %v\n",
-					table.NumRows(), bqlDiff, time.Now().Sub(now)-bqlDiff)
+					rows, bqlDiff, time.Now().Sub(now)-bqlDiff)
+			} else {
+				fmt.Printf("[OK] %d rows retrieved.\n", rows)
 			}
 		}
 		done <- false
@@ -257,6 +365,16 @@ func printHelp() {
 	fmt.Println("stop tracing                                          - stops tracing queries.")
 	fmt.Println("quit                                                  - quits the console.")
 	fmt.Println()
+	fmt.Println("Statements may span several lines and end with ;. Backslash commands do not need ;.")
+	fmt.Println()
+	fmt.Println(`\format [table|csv|tsv|json|sparql-json|ndjson|sparql-xml]      - sets the format used to print query results.`)
+	fmt.Println(`\graphs                                               - lists the graphs in the store.`)
+	fmt.Println(`\help                                                 - prints help for the bw console.`)
+	fmt.Println(`\history                                              - prints the history of statements.`)
+	fmt.Println(`\q                                                    - quits the console.`)
+	fmt.Println(`\r                                                    - discards the statement being typed.`)
+	fmt.Println(`\timing [on|off]                                      - toggles printing the time spent running statements.`)
+	fmt.Println()
 }
 
 // runBQLFromFile loads all the statements in the file and runs them.
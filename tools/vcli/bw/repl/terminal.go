@@ -0,0 +1,306 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+// continuationPrompt is the prompt used while reading the lines of a
+// statement after the first one.
+const continuationPrompt = "  -> "
+
+// stty runs stty against the standard input with the provided arguments. It
+// fails if the standard input is not a terminal.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// rawTerminal puts the terminal on the standard input in raw mode, so keys are
+// read as they are pressed without being echoed, and returns the function that
+// restores its previous mode.
+func rawTerminal() (func(), error) {
+	mode, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("-icanon", "-echo", "-isig", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(mode)
+	}, nil
+}
+
+// accumulate adds the provided line to the statement being read, and returns
+// the statement once it is complete. Statements end with ;, while backslash
+// commands are complete on their own line. The \r command resets the
+// statement being read.
+func accumulate(stm *string, line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, `\`) {
+		cmd := strings.TrimSpace(strings.TrimSuffix(line, ";"))
+		if cmd == `\r` {
+			*stm = ""
+			fmt.Println("Statement buffer reset.")
+			return "", false
+		}
+		if *stm == "" {
+			return cmd, true
+		}
+	}
+	*stm = strings.TrimSpace(*stm + "\n" + line)
+	if !strings.HasSuffix(*stm, ";") {
+		return "", false
+	}
+	res := *stm
+	*stm = ""
+	return res, true
+}
+
+// lineEditor edits lines read from a terminal in raw mode.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  *History
+	complete Completer
+}
+
+// readLine reads a line from the terminal. The line can be edited using the
+// cursor keys, Home, End, Delete, Backspace, and the usual Ctrl-A, Ctrl-E,
+// Ctrl-K, and Ctrl-U shortcuts. The up and down keys browse the history, and
+// the tab key completes the word before the cursor. Ctrl-C discards the line,
+// and Ctrl-D on an empty line returns io.EOF.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	var (
+		buf, saved []rune
+		pos        int
+		hist       = e.history.Entries()
+		hpos       = len(hist)
+	)
+	redraw := func() {
+		fmt.Fprintf(e.out, "\r\x1b[K%s%s", prompt, string(buf))
+		if n := len(buf) - pos; n > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", n)
+		}
+	}
+	set := func(rs []rune) {
+		buf, pos = append([]rune{}, rs...), len(rs)
+		redraw()
+	}
+	redraw()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\r\n")
+			set(nil)
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+				redraw()
+			}
+		case 127, 8: // Backspace
+			if pos > 0 {
+				buf, pos = append(buf[:pos-1], buf[pos:]...), pos-1
+				redraw()
+			}
+		case 1: // Ctrl-A
+			pos = 0
+			redraw()
+		case 5: // Ctrl-E
+			pos = len(buf)
+			redraw()
+		case 11: // Ctrl-K
+			buf = buf[:pos]
+			redraw()
+		case 21: // Ctrl-U
+			buf, pos = buf[pos:], 0
+			redraw()
+		case '\t':
+			buf, pos = e.tab(buf, pos, redraw)
+			redraw()
+		case 27: // Escape sequences for the cursor and editing keys.
+			switch e.escape() {
+			case "A":
+				if hpos > 0 {
+					if hpos == len(hist) {
+						saved = buf
+					}
+					hpos--
+					set([]rune(hist[hpos]))
+				}
+			case "B":
+				if hpos < len(hist) {
+					hpos++
+					if hpos == len(hist) {
+						set(saved)
+					} else {
+						set([]rune(hist[hpos]))
+					}
+				}
+			case "C":
+				if pos < len(buf) {
+					pos++
+					redraw()
+				}
+			case "D":
+				if pos > 0 {
+					pos--
+					redraw()
+				}
+			case "H", "1~", "7~":
+				pos = 0
+				redraw()
+			case "F", "4~", "8~":
+				pos = len(buf)
+				redraw()
+			case "3~":
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+					redraw()
+				}
+			}
+		default:
+			if unicode.IsPrint(r) {
+				buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+				pos++
+				redraw()
+			}
+		}
+	}
+}
+
+// escape reads the rest of an escape sequence and returns its final part,
+// such as A for the up key or 3~ for the delete key.
+func (e *lineEditor) escape() string {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != '[' && r != 'O' {
+		return ""
+	}
+	var seq []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return ""
+		}
+		seq = append(seq, r)
+		if r < '0' || r > '9' {
+			return string(seq)
+		}
+	}
+}
+
+// tab completes the text before the cursor. A single candidate replaces it,
+// several ones are extended to their common prefix or, if that does not
+// extend the text, listed below the line.
+func (e *lineEditor) tab(buf []rune, pos int, redraw func()) ([]rune, int) {
+	if e.complete == nil {
+		return buf, pos
+	}
+	line, rest := string(buf[:pos]), string(buf[pos:])
+	cands := e.complete(line)
+	if len(cands) == 0 {
+		fmt.Fprint(e.out, "\a")
+		return buf, pos
+	}
+	prefix := cands[0]
+	for _, c := range cands[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(cands) == 1 {
+		prefix += " "
+	}
+	if len(prefix) > len(line) {
+		nb := []rune(prefix + rest)
+		return nb, len([]rune(prefix))
+	}
+	var words []string
+	for _, c := range cands {
+		words = append(words, c[strings.LastIndexAny(c, " \t(,{")+1:])
+	}
+	fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(words, "  "))
+	return buf, pos
+}
+
+// TerminalReadLine returns a ReadLiner for interactive terminals. Lines can be
+// edited, the statements in the provided history browsed with the up and down
+// keys, and words completed with the tab key using the provided completer.
+// Statements can span several lines until they end with ;. Backslash commands
+// are sent as soon as they are entered. If the standard input is not a
+// terminal, it behaves as SimpleReadLine.
+func TerminalReadLine(h *History, complete Completer) ReadLiner {
+	return func(done chan bool) <-chan string {
+		if _, err := stty("-g"); err != nil {
+			return SimpleReadLine(done)
+		}
+		c := make(chan string)
+		go func() {
+			defer close(c)
+			e := &lineEditor{
+				in:       bufio.NewReader(os.Stdin),
+				out:      os.Stdout,
+				history:  h,
+				complete: complete,
+			}
+			stm := ""
+			for {
+				p := prompt
+				if stm != "" {
+					p = continuationPrompt
+				}
+				restore, err := rawTerminal()
+				if err != nil {
+					return
+				}
+				l, err := e.readLine(p)
+				restore()
+				if err != nil {
+					return
+				}
+				cmd, ok := accumulate(&stm, l)
+				if !ok {
+					continue
+				}
+				c <- cmd
+				if <-done {
+					return
+				}
+			}
+		}()
+		return c
+	}
+}
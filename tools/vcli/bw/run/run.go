@@ -18,9 +18,13 @@ package run
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/google/badwolf/bql/grammar"
 	"github.com/google/badwolf/bql/planner"
@@ -34,11 +38,30 @@ import (
 // New creates the help command.
 func New(store storage.Store, chanSize, bulkSize int) *command.Command {
 	cmd := &command.Command{
-		UsageLine: "run file_path",
+		UsageLine: "run [--format=table|csv|tsv|json|sparql-json|ndjson|sparql-xml] [--timing] [--var=<name>=<value>]... file_path [<name>=<value>]...",
 		Short:     "runs BQL statements.",
 		Long: `Runs all the commands listed in the provided file. Lines in the
 the file starting with # will be ignored. All statements will be run
-sequentially.
+sequentially. If the file path is -, the statements are read from the standard
+input.
+
+Statements may reference variables as ${name}. Their values are provided by the
+--var flags or the name=value arguments after the file path, falling back to the
+environment variables. Since the shebang line is ignored, files starting with
+
+	#!/usr/bin/env -S bw run
+
+can be made executable and run directly, passing the values of the variables
+as arguments.
+
+The --format flag sets the format used to print the results of the
+statements. Unless the format is table, only the results are printed to the
+standard output, while the progress and the errors are printed to the
+standard error, so the output can be consumed by other programs. The --timing
+flag prints the time spent running each statement and all of them.
+
+The exit status is 0 if all the statements succeed, 1 if any of them fail,
+and 2 if the statements cannot be read.
 `,
 	}
 	cmd.Run = func(ctx context.Context, args []string) int {
@@ -47,25 +70,101 @@ sequentially.
 	return cmd
 }
 
+// parseArgs parses the arguments of the run command, returning the path of the
+// file, the variables defined, and whether timing is requested.
+func parseArgs(args []string) (string, io.Variables, bool, error) {
+	vars, timing := io.Variables{}, false
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.Var(vars, "var", "variable definition")
+	fs.BoolVar(&timing, "timing", false, "print the time spent on each statement")
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	// Flags may appear before or after the file path and variables.
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return "", nil, false, err
+		}
+		if args = fs.Args(); len(args) == 0 {
+			break
+		}
+		rest, args = append(rest, args[0]), args[1:]
+	}
+	if len(rest) == 0 {
+		return "", nil, false, fmt.Errorf("missing required file path")
+	}
+	for _, a := range rest[1:] {
+		if err := vars.Set(a); err != nil {
+			return "", nil, false, err
+		}
+	}
+	return strings.TrimSpace(rest[0]), vars, timing, nil
+}
+
 // runCommand runs all the BQL statements available in the file.
 func runCommand(ctx context.Context, cmd *command.Command, args []string, store storage.Store, chanSize, bulkSize int) int {
-	if len(args) < 2 {
-		log.Printf("[ERROR] Missing required file path. ")
+	format, args, err := command.FormatFlag(ctx, args)
+	if err != nil {
+		log.Printf("[ERROR] %v\n\n", err)
+		cmd.Usage()
+		return 2
+	}
+	info := os.Stdout
+	if format != table.FormatTable {
+		info = os.Stderr
+	}
+	file, vars, timing, err := parseArgs(args)
+	if err != nil {
+		log.Printf("[ERROR] %v.\n\n", err)
 		cmd.Usage()
 		return 2
 	}
-	file := strings.TrimSpace(args[len(args)-1])
 	lines, err := io.GetStatementsFromFile(file)
 	if err != nil {
 		log.Printf("[ERROR] Failed to read file %s\n\n\t%v\n\n", file, err)
 		return 2
 	}
-	fmt.Printf("Processing file %s\n\n", args[len(args)-1])
 	for idx, stm := range lines {
-		fmt.Printf("Processing statement (%d/%d):\n%s\n\n", idx+1, len(lines), stm)
+		if lines[idx], err = io.ExpandVariables(stm, vars.Lookup); err != nil {
+			log.Printf("[ERROR] Failed to expand statement %d of file %s\n\n\t%v\n\n", idx+1, file, err)
+			return 2
+		}
+	}
+	if file == "-" {
+		file = "standard input"
+	}
+	fmt.Fprintf(info, "Processing file %s\n\n", file)
+	// Hitting Ctrl-C aborts the statement being run and skips the rest.
+	ctx, stop := command.WithInterrupt(ctx)
+	defer stop()
+	start, failed := time.Now(), 0
+	for idx, stm := range lines {
+		if err := ctx.Err(); err != nil {
+			fmt.Fprintf(info, "[FAIL] Aborted with %d statements left; %v\n\n", len(lines)-idx, err)
+			return 1
+		}
+		fmt.Fprintf(info, "Processing statement (%d/%d):\n%s\n\n", idx+1, len(lines), stm)
+		now := time.Now()
 		tbl, err := BQL(ctx, stm, store, chanSize, bulkSize)
+		if timing {
+			fmt.Fprintf(info, "Time spent: %v\n", time.Since(now))
+		}
 		if err != nil {
-			fmt.Printf("[FAIL] %v\n\n", err)
+			failed++
+			fmt.Fprintf(info, "[FAIL] %v\n\n", err)
+			continue
+		}
+		if format != table.FormatTable {
+			if len(tbl.Bindings()) > 0 {
+				if err := tbl.Write(os.Stdout, format); err != nil {
+					failed++
+					fmt.Fprintf(info, "[FAIL] %v\n\n", err)
+					continue
+				}
+			}
+			fmt.Fprintf(info, "OK\n\n")
 			continue
 		}
 		fmt.Println("Result:")
@@ -74,6 +173,13 @@ func runCommand(ctx context.Context, cmd *command.Command, args []string, store
 		}
 		fmt.Printf("OK\n\n")
 	}
+	if timing {
+		fmt.Fprintf(info, "Ran %d statements in %v\n", len(lines), time.Since(start))
+	}
+	if failed > 0 {
+		fmt.Fprintf(info, "[FAIL] %d of %d statements failed\n", failed, len(lines))
+		return 1
+	}
 	return 0
 }
 
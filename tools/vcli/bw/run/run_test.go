@@ -0,0 +1,168 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package run
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage/memory"
+	bwio "github.com/google/badwolf/tools/vcli/bw/io"
+)
+
+// capture returns the status returned by the provided function and what it
+// printed to the standard output.
+func capture(t *testing.T, f func() int) (int, string) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		out <- buf.String()
+	}()
+	status := f()
+	os.Stdout = stdout
+	w.Close()
+	return status, <-out
+}
+
+// script writes the provided statements to a temporary file and returns its
+// path.
+func script(t *testing.T, text string) string {
+	f, err := ioutil.TempFile("", "bw_run_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestParseArgs(t *testing.T) {
+	table := []struct {
+		args   []string
+		file   string
+		vars   bwio.Variables
+		timing bool
+		err    bool
+	}{
+		{args: []string{"run", "-"}, file: "-", vars: bwio.Variables{}},
+		{args: []string{"run", "--timing", "f.bql", "graph=?a"}, file: "f.bql", vars: bwio.Variables{"graph": "?a"}, timing: true},
+		{args: []string{"run", "--var=graph=?a", "f.bql", "--timing", "node=/u<joe>"}, file: "f.bql", vars: bwio.Variables{"graph": "?a", "node": "/u<joe>"}, timing: true},
+		{args: []string{"run"}, err: true},
+		{args: []string{"run", "f.bql", "graph"}, err: true},
+		{args: []string{"run", "--unknown", "f.bql"}, err: true},
+	}
+	for _, entry := range table {
+		file, vars, timing, err := parseArgs(entry.args)
+		if entry.err {
+			if err == nil {
+				t.Errorf("parseArgs(%q) should have failed", entry.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseArgs(%q) failed with error %v", entry.args, err)
+			continue
+		}
+		if file != entry.file || !reflect.DeepEqual(vars, entry.vars) || timing != entry.timing {
+			t.Errorf("parseArgs(%q) returned (%q, %v, %v); want (%q, %v, %v)", entry.args, file, vars, timing, entry.file, entry.vars, entry.timing)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	path := script(t, `#!/usr/bin/env -S bw run
+create graph ${graph};
+insert data into ${graph} {/u<joe> "knows"@[] /u<mary>};
+# Lists who ${graph} knows.
+select ?o
+  from ${graph}
+  where {/u<joe> "knows"@[] ?o};
+`)
+	defer os.Remove(path)
+	table := []struct {
+		args     []string
+		status   int
+		contains []string
+		missing  []string
+		counts   map[string]int
+	}{
+		{
+			args:     []string{"run", path, "graph=?a"},
+			status:   0,
+			contains: []string{"Processing statement (1/3):\ncreate graph ?a;", "Processing statement (3/3):\nselect ?o from ?a where", "/u<mary>"},
+			missing:  []string{"#!", "Time spent", "[FAIL]"},
+		},
+		{
+			args:     []string{"run", "--timing", "--var=graph=?b", path},
+			status:   0,
+			contains: []string{"Ran 3 statements in "},
+			counts:   map[string]int{"Time spent: ": 3},
+		},
+		{
+			// Graphs cannot be created twice.
+			args:     []string{"run", path, "graph=?a"},
+			status:   1,
+			contains: []string{"[FAIL] 1 of 3 statements failed"},
+		},
+		{
+			args:   []string{"run", path},
+			status: 2,
+		},
+		{
+			args:   []string{"run", path + ".missing", "graph=?c"},
+			status: 2,
+		},
+	}
+	ctx, s := context.Background(), memory.NewStore()
+	cmd := New(s, 0, 10)
+	for _, entry := range table {
+		status, out := capture(t, func() int {
+			return runCommand(ctx, cmd, entry.args, s, 0, 10)
+		})
+		if status != entry.status {
+			t.Errorf("runCommand(%q) returned status %d; want %d; output:\n%s", entry.args, status, entry.status, out)
+		}
+		for _, c := range entry.contains {
+			if !strings.Contains(out, c) {
+				t.Errorf("runCommand(%q) output should contain %q; got:\n%s", entry.args, c, out)
+			}
+		}
+		for _, m := range entry.missing {
+			if strings.Contains(out, m) {
+				t.Errorf("runCommand(%q) output should not contain %q; got:\n%s", entry.args, m, out)
+			}
+		}
+		for c, want := range entry.counts {
+			if got := strings.Count(out, c); got != want {
+				t.Errorf("runCommand(%q) output contains %q %d times; want %d; got:\n%s", entry.args, c, got, want, out)
+			}
+		}
+	}
+}
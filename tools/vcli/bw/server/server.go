@@ -21,8 +21,10 @@ import (
 	"fmt"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -31,17 +33,44 @@ import (
 	"github.com/google/badwolf/bql/planner"
 	"github.com/google/badwolf/bql/semantic"
 	"github.com/google/badwolf/bql/table"
+	pb "github.com/google/badwolf/proto"
+	api "github.com/google/badwolf/server"
 	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/auth"
 	"github.com/google/badwolf/tools/vcli/bw/command"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // New creates the help command.
 func New(store storage.Store, chanSize, bulkSize int) *command.Command {
 	cmd := &command.Command{
-		UsageLine: "server port",
+		UsageLine: "server [--addr=host:port] [--tls_cert=<file> --tls_key=<file>] [--tokens=<file>] [--acl=<file>] [--grpc_addr=host:port] [port]",
 		Short:     "runs a BQL endpoint.",
 		Long: `Runs a BQL endpoint with the provided driver. It allows running
-all BQL queries and returns a JSON table with the results.`,
+all BQL queries and returns a JSON table with the results. It also serves the
+REST API of the server package under /v1/, which allows running queries with
+streamed results, managing graphs, loading triples in bulk, following the
+results of standing queries over WebSocket connections, and running SPARQL
+queries translated into BQL.
+
+The server listens on the address provided by the --addr flag, for instance
+--addr=:8080, or on the provided port of all interfaces, defaulting to the
+address of the profile in use. If the --tls_cert and --tls_key flags provide a
+certificate and its private key, it serves HTTPS instead of HTTP. It runs until
+it receives an interrupt signal, waiting for the requests in progress to finish
+before exiting.
+
+If the --grpc_addr flag provides an address, for instance --grpc_addr=:9090,
+the server also serves the BadWolf gRPC service defined in proto/badwolf.proto
+on it, using the same certificate and access control as the HTTP endpoints.
+
+Anyone reaching the server can read and change all graphs unless the --acl flag
+provides the rules granting read and write permissions on graphs to principals,
+one "principal graph permissions" rule per line, as in "alice ?family rw". The
+--tokens flag provides the bearer tokens identifying the principals, one
+"token principal" pair per line, expected in the Authorization header of the
+requests. Requests without a token are anonymous.`,
 	}
 	cmd.Run = func(ctx context.Context, args []string) int {
 		return runServer(ctx, cmd, args, store, chanSize, bulkSize)
@@ -56,39 +85,231 @@ type serverConfig struct {
 	bulkSize int
 }
 
+// shutdownTimeout is the time given to the requests in progress to finish
+// when the server is stopped.
+const shutdownTimeout = 30 * time.Second
+
+// listenConfig contains where and how the server listens for requests, and
+// the files controlling who can access the graphs.
+type listenConfig struct {
+	addr       string
+	certFile   string
+	keyFile    string
+	tokensFile string
+	aclFile    string
+	grpcAddr   string
+}
+
+// parseListenConfig returns where and how to listen as provided by the
+// arguments of the command, either using the --addr flag or a port number, and
+// the --tls_cert and --tls_key flags, the --tokens and --acl flags, and the
+// --grpc_addr flag. The
+// provided defaults are used for the flags not present in the arguments.
+func parseListenConfig(args []string, d command.Defaults) (*listenConfig, error) {
+	lc := &listenConfig{
+		addr:     d.Addr,
+		certFile: d.TLSCert,
+		keyFile:  d.TLSKey,
+	}
+	flags := map[string]*string{
+		"--addr":      &lc.addr,
+		"--tls_cert":  &lc.certFile,
+		"--tls_key":   &lc.keyFile,
+		"--tokens":    &lc.tokensFile,
+		"--acl":       &lc.aclFile,
+		"--grpc_addr": &lc.grpcAddr,
+	}
+	for i := 1; i < len(args); i++ {
+		a := strings.TrimSpace(args[i])
+		name, val := a, ""
+		if idx := strings.Index(a, "="); idx >= 0 {
+			name, val = a[:idx], a[idx+1:]
+		}
+		v, ok := flags[name]
+		switch {
+		case ok && name == a:
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("missing value after %s", name)
+			}
+			i++
+			*v = strings.TrimSpace(args[i])
+		case ok:
+			*v = val
+		default:
+			if _, err := strconv.Atoi(a); err != nil {
+				return nil, fmt.Errorf("invalid port number %q; %v", a, err)
+			}
+			lc.addr = ":" + a
+		}
+	}
+	if lc.addr == "" {
+		return nil, fmt.Errorf("missing required address or port number")
+	}
+	if (lc.certFile == "") != (lc.keyFile == "") {
+		return nil, fmt.Errorf("serving HTTPS requires both --tls_cert and --tls_key")
+	}
+	return lc, nil
+}
+
 // runServer runs the simple BQL endpoint.
 func runServer(ctx context.Context, cmd *command.Command, args []string, store storage.Store, chanSize, bulkSize int) int {
 	// Check parameters.
-	if len(args) < 2 {
-		log.Printf("[%v] Missing required port number. ", time.Now())
+	lc, err := parseListenConfig(args, command.DefaultsFrom(ctx))
+	if err != nil {
+		log.Printf("[%v] %v.\n", time.Now(), err)
 		cmd.Usage()
 		return 2
 	}
 
-	// Validate port number.
-	p := strings.TrimSpace(args[len(args)-1])
-	port, err := strconv.Atoi(p)
+	tokens, acl, err := loadAccessControl(lc)
 	if err != nil {
-		log.Printf("[%v] Invalid port number %q; %v\n", time.Now(), p, err)
+		log.Printf("[%v] %v.\n", time.Now(), err)
 		return 2
 	}
 
 	// Start the server.
-	log.Printf("[%v] Starting server at port %d\n", time.Now(), port)
 	s := &serverConfig{
 		store:    store,
 		chanSize: chanSize,
 		bulkSize: bulkSize,
 	}
-	http.HandleFunc("/bql", s.bqlHandler)
-	http.HandleFunc("/", defaultHandler)
-	if err := http.ListenAndServe(":"+p, nil); err != nil {
-		log.Printf("[%v] Failed to start server on port %s; %v", time.Now(), p, err)
+	opts := &api.Options{
+		ChanSize: chanSize,
+		BulkSize: bulkSize,
+	}
+	if acl != nil {
+		s.store = auth.NewStore(store, acl)
+		opts.Authorizer = acl
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bql", s.bqlHandler)
+	mux.Handle("/v1/", http.StripPrefix("/v1", api.New(store, opts)))
+	mux.HandleFunc("/", defaultHandler)
+	var h http.Handler = mux
+	if tokens != nil {
+		h = api.Authenticate(tokens, mux)
+	}
+	addr := lc.addr
+	srv := &http.Server{Addr: addr, Handler: h}
+
+	var gsrv *grpc.Server
+	if lc.grpcAddr != "" {
+		gopts := *opts
+		if tokens != nil {
+			gopts.Authenticator = tokens
+		}
+		gsrv, err = newGRPCServer(lc, api.NewService(store, &gopts))
+		if err != nil {
+			log.Printf("[%v] Failed to start gRPC server at %s; %v", time.Now(), lc.grpcAddr, err)
+			return 2
+		}
+		defer gsrv.Stop()
+	}
+
+	// Stop the server gracefully when interrupted.
+	ictx, stop := command.WithInterrupt(ctx)
+	defer stop()
+	stopped := make(chan error, 1)
+	go func() {
+		<-ictx.Done()
+		log.Printf("[%v] Stopping server at %s\n", time.Now(), addr)
+		sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if gsrv != nil {
+			stopGRPCServer(sctx, gsrv)
+		}
+		stopped <- srv.Shutdown(sctx)
+	}()
+
+	log.Printf("[%v] Starting server at %s using driver %s/%s\n", time.Now(), addr, store.Name(ctx), store.Version(ctx))
+	serve := srv.ListenAndServe
+	if lc.certFile != "" {
+		serve = func() error {
+			return srv.ListenAndServeTLS(lc.certFile, lc.keyFile)
+		}
+	}
+	if err := serve(); err != http.ErrServerClosed {
+		log.Printf("[%v] Failed to start server at %s; %v", time.Now(), addr, err)
 		return 2
 	}
+	if err := <-stopped; err != nil {
+		log.Printf("[%v] Failed to stop server at %s gracefully; %v", time.Now(), addr, err)
+		return 1
+	}
 	return 0
 }
 
+// newGRPCServer starts serving the provided gRPC service at the address
+// provided by the configuration, using its certificate if any.
+func newGRPCServer(lc *listenConfig, svc *api.Service) (*grpc.Server, error) {
+	var sopts []grpc.ServerOption
+	if lc.certFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(lc.certFile, lc.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		sopts = append(sopts, grpc.Creds(creds))
+	}
+	lis, err := net.Listen("tcp", lc.grpcAddr)
+	if err != nil {
+		return nil, err
+	}
+	gsrv := grpc.NewServer(sopts...)
+	pb.RegisterBadWolfServer(gsrv, svc)
+	log.Printf("[%v] Starting gRPC server at %s\n", time.Now(), lc.grpcAddr)
+	go func() {
+		if err := gsrv.Serve(lis); err != nil {
+			log.Printf("[%v] gRPC server at %s failed; %v", time.Now(), lc.grpcAddr, err)
+		}
+	}()
+	return gsrv, nil
+}
+
+// stopGRPCServer waits for the calls in progress to finish, or stops them
+// once the context is done.
+func stopGRPCServer(ctx context.Context, gsrv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		gsrv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		gsrv.Stop()
+	}
+}
+
+// loadAccessControl returns the bearer tokens and the ACL in the files
+// provided by the configuration, which are nil if not provided.
+func loadAccessControl(lc *listenConfig) (api.Tokens, auth.ACL, error) {
+	var (
+		tokens api.Tokens
+		acl    auth.ACL
+	)
+	if lc.tokensFile != "" {
+		f, err := os.Open(lc.tokensFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		if tokens, err = api.ParseTokens(f); err != nil {
+			return nil, nil, fmt.Errorf("failed to load the tokens in %q; %v", lc.tokensFile, err)
+		}
+	}
+	if lc.aclFile != "" {
+		f, err := os.Open(lc.aclFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		if acl, err = auth.ParseACL(f); err != nil {
+			return nil, nil, fmt.Errorf("failed to load the ACL in %q; %v", lc.aclFile, err)
+		}
+	}
+	return tokens, acl, nil
+}
+
 // bqlHandler imPathUnescapeplements the handler to server BQL requests.
 func (s *serverConfig) bqlHandler(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
@@ -107,13 +328,14 @@ func (s *serverConfig) bqlHandler(w http.ResponseWriter, r *http.Request) {
 		ctx    context.Context
 		cancel context.CancelFunc
 	)
+	// Queries are aborted if the client goes away before they finish.
 	timeout, err := time.ParseDuration(r.FormValue("timeout"))
 	if err == nil {
 		// The request has a timeout, so create a context that is
 		// canceled automatically when the timeout expires.
-		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		ctx, cancel = context.WithTimeout(r.Context(), timeout)
 	} else {
-		ctx, cancel = context.WithCancel(context.Background())
+		ctx, cancel = context.WithCancel(r.Context())
 	}
 	defer cancel() // Cancel ctx as soon as handleSearch returns.
 
@@ -0,0 +1,227 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validate contains the command allowing to check BQL statements
+// without running them.
+package validate
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	stdio "io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/tools/vcli/bw/command"
+	"github.com/google/badwolf/tools/vcli/bw/io"
+)
+
+// New creates the validate command.
+func New(store storage.Store) *command.Command {
+	cmd := &command.Command{
+		UsageLine: "validate [--check_graphs] [--strict] [--var=<name>=<value>]... <file_path>...",
+		Short:     "checks BQL statements without running them.",
+		Long: `Parses all the statements in the provided files, or the standard input if
+the file path is -, and checks them for problems without running them. Besides
+syntax errors, it reports bindings holding values of incompatible kinds, for
+instance a binding used both as a subject and as a predicate, and type errors
+in filters and expressions, such as comparing nodes with numbers. Problems are
+printed one per line as file:line:column: message.
+
+Statements may reference variables as ${name}, as accepted by the run command;
+their values are provided by the --var flags, falling back to the environment
+variables.
+
+If --check_graphs is provided, the graphs used by the statements are looked up
+in the store, and a warning is printed for each one that does not exist and is
+not created by a previous statement of the file. If --strict is provided,
+warnings are treated as errors.
+
+The command exits with status 0 if no errors are found, 1 if any are found,
+and 2 if the files cannot be read.
+`,
+	}
+	cmd.Run = func(ctx context.Context, args []string) int {
+		return Eval(ctx, cmd.UsageLine+"\n\n"+cmd.Long, args, store)
+	}
+	return cmd
+}
+
+// validator checks the statements of files, printing the problems found to
+// the provided writer, and keeps count of them.
+type validator struct {
+	out         stdio.Writer
+	store       storage.Store
+	parser      *grammar.Parser
+	vars        io.Variables
+	checkGraphs bool
+	strict      bool
+	errors      int
+	warnings    int
+}
+
+// report prints a problem located at the provided line and column of a file.
+// The column is omitted if it is 0.
+func (v *validator) report(path string, line, col int, warning bool, format string, args ...interface{}) {
+	kind := "error"
+	if warning && !v.strict {
+		kind = "warning"
+		v.warnings++
+	} else {
+		v.errors++
+	}
+	loc := fmt.Sprintf("%s:%d", path, line)
+	if col > 0 {
+		loc = fmt.Sprintf("%s:%d", loc, col)
+	}
+	fmt.Fprintf(v.out, "%s: %s: %s\n", loc, kind, fmt.Sprintf(format, args...))
+}
+
+// validateFile checks all the statements of the file, returning an error if
+// they cannot be read.
+func (v *validator) validateFile(ctx context.Context, path string) (int, error) {
+	stms, err := io.ReadStatements(path)
+	if err != nil {
+		return 0, err
+	}
+	name := path
+	if path == "-" {
+		name = "<stdin>"
+	}
+	// created tracks the graphs created or dropped by previous statements.
+	created := make(map[string]bool)
+	for _, stm := range stms {
+		text, err := io.ExpandVariables(stm.Text, v.vars.Lookup)
+		if err != nil {
+			v.report(name, stm.Line, 0, false, "%v", err)
+			continue
+		}
+		st := &semantic.Statement{}
+		if err := v.parser.Parse(grammar.NewLLk(text, 1), st); err != nil {
+			if pErr, ok := err.(*grammar.ParseError); ok && pErr.Line > 0 {
+				// Report the error relative to the file instead of the
+				// statement.
+				loc := *pErr
+				loc.Line = 0
+				v.report(name, stm.Line+pErr.Line-1, pErr.Col, false, "%v", &loc)
+			} else {
+				v.report(name, stm.Line, 0, false, "%v", err)
+			}
+			continue
+		}
+		for _, err := range st.Check() {
+			v.report(name, stm.Line, 0, false, "%v", err)
+		}
+		if v.checkGraphs {
+			v.validateGraphs(ctx, name, stm.Line, st, created)
+		}
+	}
+	return len(stms), nil
+}
+
+// validateGraphs checks that the graphs used by the statement exist, either in
+// the store or because a previous statement created them.
+func (v *validator) validateGraphs(ctx context.Context, path string, line int, st *semantic.Statement, created map[string]bool) {
+	switch st.Type() {
+	case semantic.Create:
+		for _, g := range st.GraphNames() {
+			created[g] = true
+		}
+		return
+	case semantic.Drop:
+		for _, g := range st.GraphNames() {
+			created[g] = false
+		}
+		return
+	}
+	var names []string
+	names = append(names, st.GraphNames()...)
+	names = append(names, st.InputGraphNames()...)
+	names = append(names, st.OutputGraphNames()...)
+	seen := make(map[string]bool)
+	for _, g := range names {
+		if seen[g] || semantic.IsParameter(g) {
+			continue
+		}
+		seen[g] = true
+		if exists, ok := created[g]; ok {
+			if !exists {
+				v.report(path, line, 0, true, "graph %s was dropped by a previous statement", g)
+			}
+			continue
+		}
+		if _, err := v.store.Graph(ctx, g); err != nil {
+			v.report(path, line, 0, true, "cannot access graph %s; %v", g, err)
+		}
+	}
+}
+
+// Eval validates the statements of the files as indicated by the command.
+func Eval(ctx context.Context, usage string, args []string, store storage.Store) int {
+	v := &validator{
+		out:   os.Stdout,
+		store: store,
+		vars:  io.Variables{},
+	}
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.BoolVar(&v.checkGraphs, "check_graphs", false, "check that the graphs used exist")
+	fs.BoolVar(&v.strict, "strict", false, "treat warnings as errors")
+	fs.Var(v.vars, "var", "variable definition")
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	// Flags may appear before or after the file paths.
+	var paths []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			log.Printf("[ERROR] %v.\n\n%s", err, usage)
+			return 2
+		}
+		if args = fs.Args(); len(args) == 0 {
+			break
+		}
+		paths, args = append(paths, args[0]), args[1:]
+	}
+	if len(paths) == 0 {
+		log.Printf("[ERROR] Missing required file path.\n\n%s", usage)
+		return 2
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		log.Printf("[ERROR] Failed to initialize a valid BQL parser; %v.\n\n", err)
+		return 2
+	}
+	v.parser = p
+
+	stms := 0
+	for _, path := range paths {
+		n, err := v.validateFile(ctx, path)
+		if err != nil {
+			log.Printf("[ERROR] Failed to read file %s; %v.\n\n", path, err)
+			return 2
+		}
+		stms += n
+	}
+	fmt.Printf("Checked %d statements in %d files: %d errors, %d warnings.\n", stms, len(paths), v.errors, v.warnings)
+	if v.errors > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,179 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/tools/vcli/bw/io"
+)
+
+const script = `# Statements checked by the tests.
+create graph ?new;
+select ?s from ?g where {?s "foo"@[] ?o . ?o ?s ?x};
+select ?s
+from ?g wher {?s ?p ?o};
+select ?s from ?new where {?s ?p ?o};
+select ?s from ?missing where {?s ?p ?o};
+drop graph ?new;
+select ?s from ?new where {?s ?p ?o};
+select ?s from ${graph} where {?s ?p ?o};
+select ?s from ${undefined_test_graph} where {?s ?p ?o};
+`
+
+func TestValidateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badwolf_validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "script.bql")
+	if err := ioutil.WriteFile(path, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, s := context.Background(), memory.NewStore()
+	if _, err := s.NewGraph(ctx, "?g"); err != nil {
+		t.Fatal(err)
+	}
+
+	table := []struct {
+		checkGraphs bool
+		strict      bool
+		errors      int
+		warnings    int
+		lines       []string
+	}{
+		{
+			errors: 3,
+			lines: []string{
+				":3: error: binding ?s holds both node and predicate values",
+				":5:9: error: grammar:",
+				`:11: error: undefined variable "undefined_test_graph"`,
+			},
+		},
+		{
+			checkGraphs: true,
+			errors:      3,
+			warnings:    2,
+			lines: []string{
+				":3: error: binding ?s holds both node and predicate values",
+				":5:9: error: grammar:",
+				":7: warning: cannot access graph ?missing",
+				":9: warning: graph ?new was dropped by a previous statement",
+				`:11: error: undefined variable "undefined_test_graph"`,
+			},
+		},
+		{
+			checkGraphs: true,
+			strict:      true,
+			errors:      5,
+			lines: []string{
+				":3: error: binding ?s holds both node and predicate values",
+				":5:9: error: grammar:",
+				":7: error: cannot access graph ?missing",
+				":9: error: graph ?new was dropped by a previous statement",
+				`:11: error: undefined variable "undefined_test_graph"`,
+			},
+		},
+	}
+	for _, entry := range table {
+		var buf bytes.Buffer
+		v := &validator{
+			out:         &buf,
+			store:       s,
+			parser:      p,
+			vars:        io.Variables{"graph": "?g"},
+			checkGraphs: entry.checkGraphs,
+			strict:      entry.strict,
+		}
+		n, err := v.validateFile(ctx, path)
+		if err != nil {
+			t.Fatalf("validateFile(%q) failed with error %v", path, err)
+		}
+		if got, want := n, 9; got != want {
+			t.Errorf("validateFile(%q) checked %d statements; want %d", path, got, want)
+		}
+		if v.errors != entry.errors || v.warnings != entry.warnings {
+			t.Errorf("validateFile(%q) with check_graphs=%v and strict=%v found %d errors and %d warnings; want %d and %d", path, entry.checkGraphs, entry.strict, v.errors, v.warnings, entry.errors, entry.warnings)
+		}
+		got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(got) != len(entry.lines) {
+			t.Errorf("validateFile(%q) reported\n%s\nwant %d problems", path, buf.String(), len(entry.lines))
+			continue
+		}
+		for i, l := range got {
+			if !strings.HasPrefix(l, path+entry.lines[i]) {
+				t.Errorf("validateFile(%q) reported %q; want it to start with %q", path, l, path+entry.lines[i])
+			}
+		}
+	}
+
+	if _, err := (&validator{}).validateFile(ctx, filepath.Join(dir, "missing.bql")); err == nil {
+		t.Errorf("validateFile should have failed for a missing file")
+	}
+}
+
+func TestEval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badwolf_validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	valid := filepath.Join(dir, "valid.bql")
+	if err := ioutil.WriteFile(valid, []byte("select ?s from ?g where {?s ?p ?o};\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.bql")
+	if err := ioutil.WriteFile(invalid, []byte("select ?s from ?g wher {?s ?p ?o};\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout, _ = os.Open(os.DevNull)
+	defer func() {
+		os.Stdout.Close()
+		os.Stdout = stdout
+	}()
+
+	ctx, s := context.Background(), memory.NewStore()
+	table := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"validate", valid}, 0},
+		{[]string{"validate", valid, "--check_graphs"}, 0},
+		{[]string{"validate", "--check_graphs", "--strict", valid}, 1},
+		{[]string{"validate", valid, invalid}, 1},
+		{[]string{"validate"}, 2},
+		{[]string{"validate", "--unknown", valid}, 2},
+		{[]string{"validate", valid, filepath.Join(dir, "missing.bql")}, 2},
+	}
+	for _, entry := range table {
+		if got := Eval(ctx, "", entry.args, s); got != entry.want {
+			t.Errorf("Eval(%q) returned status %d; want %d", entry.args, got, entry.want)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package watch contains the command allowing to keep watching the results of
+// a BQL query as the graphs it queries change.
+package watch
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/bql"
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/tools/vcli/bw/command"
+	"github.com/google/badwolf/tools/vcli/bw/run"
+)
+
+// settleTime is the time waited after a change before evaluating the query
+// again, so bursts of changes only evaluate it once.
+const settleTime = 100 * time.Millisecond
+
+// New creates the watch command.
+func New(store storage.Store, chanSize, bulkSize int) *command.Command {
+	cmd := &command.Command{
+		UsageLine: "watch [--interval=<duration>] <BQL_query>",
+		Short:     "prints the changes of the results of a query.",
+		Long: `Evaluates the provided BQL query, and keeps its results up to date as the
+graphs it queries change until Ctrl-C is hit. The rows of the results are
+printed prefixed by + when they are added and by - when they are removed,
+preceded by the time of the update. The first update prints all the rows of
+the results as added. Only SELECT statements can be watched.
+
+The results of queries with a single non optional clause over a single graph,
+without subqueries, unions, aggregations, HAVING, LIMIT, or OFFSET, are
+maintained incrementally from the triples added to and removed from the graph.
+The results of the rest of queries are evaluated again every time the graphs
+change. If any of the graphs does not provide a change feed, the query is
+evaluated every --interval instead, 1s by default, and only the changes are
+printed.
+`,
+	}
+	cmd.Run = func(ctx context.Context, args []string) int {
+		return Eval(ctx, cmd.UsageLine+"\n\n"+cmd.Long, args, store, chanSize, bulkSize)
+	}
+	return cmd
+}
+
+// Eval watches the query as indicated by the command.
+func Eval(ctx context.Context, usage string, args []string, store storage.Store, chanSize, bulkSize int) int {
+	interval := time.Second
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.DurationVar(&interval, "interval", interval, "polling interval")
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	if err := fs.Parse(args); err != nil || interval <= 0 {
+		log.Printf("[ERROR] Invalid flags; %v.\n\n%s", err, usage)
+		return 2
+	}
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if query == "" {
+		log.Printf("[ERROR] Missing required BQL query.\n\n%s", usage)
+		return 2
+	}
+	if !strings.HasSuffix(query, ";") {
+		query += ";"
+	}
+	names, err := inputGraphs(query)
+	if err != nil {
+		log.Printf("[ERROR] %v\n\n%s", err, usage)
+		return 2
+	}
+
+	// Hitting Ctrl-C stops watching the query.
+	ctx, stop := command.WithInterrupt(ctx)
+	defer stop()
+	v, err := bql.NewView(query, chanSize, bulkSize)
+	if err == nil {
+		if code, ok := watchView(ctx, v, store); ok {
+			return code
+		}
+	} else if err != bql.ErrNotIncremental {
+		log.Printf("[ERROR] %v\n\n", err)
+		return 2
+	}
+	changed, deleted, err := watchGraphs(ctx, store, names)
+	if err != nil {
+		log.Printf("[ERROR] %v\n\n", err)
+		return 2
+	}
+	var tick <-chan time.Time
+	if changed == nil {
+		log.Printf("[WARNING] Some of the graphs %s do not provide a change feed; evaluating the query every %v.\n", strings.Join(names, ", "), interval)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	var last map[string]int
+	for {
+		tbl, err := run.BQL(ctx, query, store, chanSize, bulkSize)
+		if ctx.Err() != nil {
+			return 0
+		}
+		if err != nil {
+			log.Printf("%v\n\n", err)
+			return 2
+		}
+		if last == nil {
+			fmt.Printf("Watching %s\n\t%s\n\n", strings.Join(names, ", "), strings.Join(tbl.Bindings(), "\t"))
+		}
+		rows := countRows(tbl)
+		if added, removed := diffRows(last, rows); len(added)+len(removed) > 0 || last == nil {
+			printChanges(added, removed)
+		}
+		last = rows
+
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-tick:
+		case <-deleted:
+			log.Printf("[ERROR] A watched graph was deleted.\n\n")
+			return 2
+		case <-changed:
+			settle(ctx, changed)
+		}
+	}
+}
+
+// watchView prints the changes of the results of the view as the changes of
+// the graph it queries are applied to them. It returns false if the graph
+// does not provide a change feed.
+func watchView(ctx context.Context, v *bql.View, store storage.Store) (int, bool) {
+	g, err := store.Graph(ctx, v.Graph())
+	if err != nil {
+		log.Printf("[ERROR] Failed to retrieve graph %q with error %v\n\n", v.Graph(), err)
+		return 2, true
+	}
+	// The graph is watched before computing the results, so no change is
+	// missed.
+	cs, err := storage.Watch(ctx, g)
+	if err == storage.ErrNoChangeFeed {
+		return 0, false
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to watch graph %q with error %v\n\n", v.Graph(), err)
+		return 2, true
+	}
+	tbl, err := v.Init(ctx, g)
+	if ctx.Err() != nil {
+		return 0, true
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to evaluate the query with error %v\n\n", err)
+		return 2, true
+	}
+	fmt.Printf("Watching %s\n\t%s\n\n", v.Graph(), strings.Join(tbl.Bindings(), "\t"))
+	rows, _ := diffRows(nil, countRows(tbl))
+	printChanges(rows, nil)
+	for c := range cs {
+		tbl, err := v.Apply(ctx, c)
+		if ctx.Err() != nil {
+			return 0, true
+		}
+		if err != nil {
+			log.Printf("[ERROR] Failed to apply the changes of graph %q with error %v\n\n", v.Graph(), err)
+			return 2, true
+		}
+		rows, _ := diffRows(nil, countRows(tbl))
+		switch {
+		case len(rows) == 0:
+		case c.Op == storage.Added:
+			printChanges(rows, nil)
+		default:
+			printChanges(nil, rows)
+		}
+	}
+	// The change feed is closed when the context is done or the graph is
+	// deleted.
+	if ctx.Err() != nil {
+		return 0, true
+	}
+	log.Printf("[ERROR] A watched graph was deleted.\n\n")
+	return 2, true
+}
+
+// printChanges prints the rows removed and added to the results, preceded by
+// the current time.
+func printChanges(added, removed []string) {
+	fmt.Printf("# %s\n", time.Now().Format(time.RFC3339))
+	for _, r := range removed {
+		fmt.Printf("-\t%s\n", r)
+	}
+	for _, r := range added {
+		fmt.Printf("+\t%s\n", r)
+	}
+}
+
+// inputGraphs returns the names of the graphs queried by the provided BQL
+// statement, which must be a query.
+func inputGraphs(bql string) ([]string, error) {
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initilize a valid BQL parser; %v", err)
+	}
+	stm := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(bql, 1), stm); err != nil {
+		return nil, fmt.Errorf("failed to parse BQL statement with error %v", err)
+	}
+	if stm.Type() != semantic.Query {
+		return nil, fmt.Errorf("only SELECT statements can be watched, got %q", bql)
+	}
+	return stm.InputGraphNames(), nil
+}
+
+// watchGraphs returns a channel that receives a value when any of the graphs
+// changes, and a channel that is closed if any of them is deleted. Changes
+// received while the value is not taken are coalesced. It returns nil
+// channels if any of the graphs does not provide a change feed.
+func watchGraphs(ctx context.Context, store storage.Store, names []string) (<-chan struct{}, <-chan struct{}, error) {
+	var feeds []<-chan *storage.Change
+	for _, name := range names {
+		g, err := store.Graph(ctx, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to retrieve graph %q with error %v", name, err)
+		}
+		cs, err := storage.Watch(ctx, g)
+		if err == storage.ErrNoChangeFeed {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to watch graph %q with error %v", name, err)
+		}
+		feeds = append(feeds, cs)
+	}
+	var once sync.Once
+	changed, deleted := make(chan struct{}, 1), make(chan struct{})
+	for _, cs := range feeds {
+		go func(cs <-chan *storage.Change) {
+			for range cs {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+			// Feeds are closed when the context is done or the graph is
+			// deleted; only the latter needs to be reported.
+			if ctx.Err() == nil {
+				once.Do(func() { close(deleted) })
+			}
+		}(cs)
+	}
+	return changed, deleted, nil
+}
+
+// settle waits for settleTime, or until the context is done, and drops the
+// changes received meanwhile.
+func settle(ctx context.Context, changed <-chan struct{}) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(settleTime):
+	}
+	select {
+	case <-changed:
+	default:
+	}
+}
+
+// countRows returns the number of times each row of the table appears, using
+// its text line as key.
+func countRows(tbl *table.Table) map[string]int {
+	res, bs := make(map[string]int), tbl.Bindings()
+	for _, r := range tbl.Rows() {
+		var buf bytes.Buffer
+		r.ToTextLine(&buf, bs, "\t")
+		res[buf.String()]++
+	}
+	return res
+}
+
+// diffRows returns the sorted rows added and removed between the provided
+// results.
+func diffRows(last, rows map[string]int) ([]string, []string) {
+	var added, removed []string
+	for r, n := range rows {
+		for i := last[r]; i < n; i++ {
+			added = append(added, r)
+		}
+	}
+	for r, n := range last {
+		for i := rows[r]; i < n; i++ {
+			removed = append(removed, r)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
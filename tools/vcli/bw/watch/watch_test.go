@@ -0,0 +1,202 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+)
+
+func TestDiffRows(t *testing.T) {
+	table := []struct {
+		last    map[string]int
+		rows    map[string]int
+		added   []string
+		removed []string
+	}{
+		{},
+		{
+			rows:  map[string]int{"b": 1, "a": 2},
+			added: []string{"a", "a", "b"},
+		},
+		{
+			last:    map[string]int{"a": 1, "b": 1},
+			rows:    map[string]int{"a": 1, "b": 1},
+			added:   nil,
+			removed: nil,
+		},
+		{
+			last:    map[string]int{"a": 1, "b": 2},
+			rows:    map[string]int{"b": 1, "c": 1},
+			added:   []string{"c"},
+			removed: []string{"a", "b"},
+		},
+		{
+			last:    map[string]int{"a": 1},
+			rows:    map[string]int{"a": 3},
+			added:   []string{"a", "a"},
+			removed: nil,
+		},
+		{
+			last:    map[string]int{"b": 1, "a": 1},
+			removed: []string{"a", "b"},
+		},
+	}
+	for _, entry := range table {
+		added, removed := diffRows(entry.last, entry.rows)
+		if !reflect.DeepEqual(added, entry.added) || !reflect.DeepEqual(removed, entry.removed) {
+			t.Errorf("diffRows(%v, %v) returned added %q and removed %q; want added %q and removed %q", entry.last, entry.rows, added, removed, entry.added, entry.removed)
+		}
+	}
+}
+
+func TestCountRows(t *testing.T) {
+	tbl, err := table.New([]string{"?a", "?b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	joe, mary := node.NewBlankNode(), node.NewBlankNode()
+	for _, r := range []table.Row{
+		{"?a": &table.Cell{N: joe}, "?b": &table.Cell{N: mary}},
+		{"?a": &table.Cell{N: joe}, "?b": &table.Cell{N: mary}},
+		{"?a": &table.Cell{N: mary}},
+	} {
+		tbl.AddRow(r)
+	}
+	want := map[string]int{
+		joe.String() + "\t" + mary.String(): 2,
+		mary.String() + "\t<NULL>":          1,
+	}
+	if got := countRows(tbl); !reflect.DeepEqual(got, want) {
+		t.Errorf("countRows returned %v; want %v", got, want)
+	}
+}
+
+func TestInputGraphs(t *testing.T) {
+	table := []struct {
+		query string
+		want  []string
+		err   bool
+	}{
+		{
+			query: "select ?s from ?a where {?s ?p ?o};",
+			want:  []string{"?a"},
+		},
+		{
+			query: "select ?s from ?a, ?b where {?s ?p ?o};",
+			want:  []string{"?a", "?b"},
+		},
+		{
+			query: "create graph ?a;",
+			err:   true,
+		},
+		{
+			query: "select ?s from where {?s ?p ?o};",
+			err:   true,
+		},
+	}
+	for _, entry := range table {
+		got, err := inputGraphs(entry.query)
+		if entry.err {
+			if err == nil {
+				t.Errorf("inputGraphs(%q) should have failed; got %q", entry.query, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("inputGraphs(%q) failed with error %v", entry.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("inputGraphs(%q) returned %q; want %q", entry.query, got, entry.want)
+		}
+	}
+}
+
+func TestWatchGraphs(t *testing.T) {
+	ctx, s := context.Background(), memory.NewStore()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	g, err := s.NewGraph(ctx, "?a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.NewGraph(ctx, "?b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := watchGraphs(ctx, s, []string{"?a", "?unknown"}); err == nil {
+		t.Errorf("watchGraphs should have failed for an unknown graph")
+	}
+	changed, deleted, err := watchGraphs(ctx, s, []string{"?a", "?b"})
+	if err != nil {
+		t.Fatalf("watchGraphs failed with error %v", err)
+	}
+	if changed == nil || deleted == nil {
+		t.Fatalf("watchGraphs returned nil channels for graphs providing change feeds")
+	}
+	tr, err := triple.Parse("/u<joe>\t\"parent_of\"@[]\t/u<mary>", literal.DefaultBuilder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Several changes received meanwhile are coalesced into a single value.
+	for i := 0; i < 3; i++ {
+		if err := g.AddTriples(ctx, []*triple.Triple{tr}); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.RemoveTriples(ctx, []*triple.Triple{tr}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watchGraphs did not report the changes of graph ?a")
+	}
+	if err := s.DeleteGraph(ctx, "?b"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-deleted:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watchGraphs did not report the deletion of graph ?b")
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	ctx, s := context.Background(), memory.NewStore()
+	if _, err := s.NewGraph(ctx, "?a"); err != nil {
+		t.Fatal(err)
+	}
+	table := [][]string{
+		{"watch"},
+		{"watch", "--interval=0s", "select ?s from ?a where {?s ?p ?o};"},
+		{"watch", "--unknown", "select ?s from ?a where {?s ?p ?o};"},
+		{"watch", "create graph ?b;"},
+		{"watch", "select ?s from ?unknown where {?s ?p ?o};"},
+	}
+	for _, args := range table {
+		if got, want := Eval(ctx, "", args, s, 10, 10), 2; got != want {
+			t.Errorf("Eval(%q) returned status %d; want %d", args, got, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BlobRef references the contents of a blob kept out of line by a store, in
+// chunks that can be written and read as streams. It allows storing binary
+// attachments bigger than the literal size limit of a store without loading
+// them fully into memory. Blob references are built and parsed as literals of
+// type BlobRefType, printed as "digest:size"^^type:blobref.
+type BlobRef struct {
+	// Digest is the hex encoded SHA-256 digest of the contents of the blob.
+	Digest string
+
+	// Size is the size of the contents of the blob in bytes.
+	Size int64
+}
+
+// String returns the text representing the blob reference.
+func (r *BlobRef) String() string {
+	return r.Digest + ":" + strconv.FormatInt(r.Size, 10)
+}
+
+// ParseBlobRef returns the blob reference represented by the provided text.
+func ParseBlobRef(s string) (*BlobRef, error) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("literal.ParseBlobRef: invalid blob reference %q; missing size", s)
+	}
+	d, err := hex.DecodeString(s[:i])
+	if err != nil || len(d) != 32 || strings.ToLower(s[:i]) != s[:i] {
+		return nil, fmt.Errorf("literal.ParseBlobRef: invalid blob reference %q; the digest should be a lowercase hex encoded SHA-256 digest", s)
+	}
+	n, err := strconv.ParseInt(s[i+1:], 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("literal.ParseBlobRef: invalid blob reference %q; invalid size", s)
+	}
+	return &BlobRef{Digest: s[:i], Size: n}, nil
+}
+
+// BlobRefType is the type of the literals holding a *BlobRef. It is
+// registered as the custom type "blobref".
+var BlobRefType Type
+
+func init() {
+	var err error
+	BlobRefType, err = Register(CustomType{
+		Name: "blobref",
+		Parse: func(s string) (interface{}, error) {
+			return ParseBlobRef(s)
+		},
+		Format: func(v interface{}) string {
+			return v.(*BlobRef).String()
+		},
+		Compare: func(a, b interface{}) int {
+			ar, br := a.(*BlobRef), b.(*BlobRef)
+			if c := strings.Compare(ar.Digest, br.Digest); c != 0 {
+				return c
+			}
+			switch {
+			case ar.Size < br.Size:
+				return -1
+			case ar.Size > br.Size:
+				return 1
+			}
+			return 0
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// BlobRef returns the blob reference held by the literal.
+func (l *Literal) BlobRef() (*BlobRef, error) {
+	if l.t != BlobRefType {
+		return nil, fmt.Errorf("literal.BlobRef: literal is of type %v; not a blob reference", l.t)
+	}
+	r, ok := l.v.(*BlobRef)
+	if !ok {
+		return nil, fmt.Errorf("literal.BlobRef: literal of type %v does not hold a *BlobRef", l.t)
+	}
+	return r, nil
+}
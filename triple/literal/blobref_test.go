@@ -0,0 +1,68 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBlobRef(t *testing.T) {
+	digest := strings.Repeat("0a", 32)
+	l, err := DefaultBuilder().Parse(`"` + digest + `:1048577"^^type:blobref`)
+	if err != nil {
+		t.Fatalf("literal.Parse failed to parse a blob reference with error %v", err)
+	}
+	if l.Type() != BlobRefType || l.Type().String() != "blobref" {
+		t.Errorf("literal.Parse returned a literal of type %v; want blobref", l.Type())
+	}
+	ref, err := l.BlobRef()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BlobRef{Digest: digest, Size: 1048577}); *ref != want {
+		t.Errorf("l.BlobRef() returned %+v; want %+v", *ref, want)
+	}
+	if got, want := l.String(), `"`+digest+`:1048577"^^type:blobref`; got != want {
+		t.Errorf("l.String() = %q; want %q", got, want)
+	}
+	other, err := DefaultBuilder().Build(BlobRefType, &BlobRef{Digest: digest, Size: 1048577})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := CompareCustom(l, other); !ok || c != 0 || l.UUID().String() != other.UUID().String() {
+		t.Errorf("blob references with the same digest and size should be equal")
+	}
+	text, err := DefaultBuilder().Build(Text, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := text.BlobRef(); err == nil {
+		t.Errorf("text.BlobRef() should fail for a text literal")
+	}
+
+	for _, s := range []string{
+		digest,
+		digest + ":-1",
+		digest + ":x",
+		"0a:10",
+		strings.Repeat("0A", 32) + ":10",
+		strings.Repeat("zz", 32) + ":10",
+	} {
+		if got, err := ParseBlobRef(s); err == nil {
+			t.Errorf("literal.ParseBlobRef(%q) should have failed; got %+v", s, got)
+		}
+	}
+}
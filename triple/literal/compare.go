@@ -0,0 +1,162 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"time"
+)
+
+// rank returns the position of the kind of the literal in the order across
+// kinds used by Compare.
+func rank(l *Literal) int {
+	switch l.t {
+	case Bool:
+		return 0
+	case Int64, Float64, Decimal:
+		return 1
+	case Date:
+		return 2
+	case Text:
+		return 3
+	case Blob:
+		return 4
+	}
+	return 5
+}
+
+// sign returns -1, 0, or 1 if the first value is respectively lower, equal,
+// or greater than the second one.
+func sign(lt, eq bool) int {
+	switch {
+	case eq:
+		return 0
+	case lt:
+		return -1
+	}
+	return 1
+}
+
+// isNumeric returns true if the literal is an int64, a float64, or a decimal.
+func isNumeric(l *Literal) bool {
+	return l.t == Int64 || l.t == Float64 || l.t == Decimal
+}
+
+// Compare returns -1, 0, or 1 if the first literal is respectively lower,
+// equal, or greater than the second one. It implements a total order across
+// all literals:
+//
+//   - Literals of different kinds are ordered by kind: booleans, numbers,
+//     dates, texts, blobs, and finally custom literals.
+//   - Booleans are ordered with false before true.
+//   - Numbers are ordered by value regardless of their type, so int64,
+//     float64, and decimal literals with the same value are equal. NaN is
+//     lower than any other number.
+//   - Dates are ordered chronologically.
+//   - Texts and blobs are ordered lexicographically by their bytes.
+//   - Custom literals of different types are ordered by the name of their
+//     type. Literals of the same custom type are ordered with the Compare
+//     function of the type, or by their text if the type provides none.
+func Compare(a, b *Literal) int {
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return sign(ra < rb, false)
+	}
+	switch a.t {
+	case Bool:
+		ab, bb := a.v.(bool), b.v.(bool)
+		return sign(!ab && bb, ab == bb)
+	case Int64, Float64, Decimal:
+		return compareNumbers(a, b)
+	case Date:
+		ad, bd := a.v.(time.Time), b.v.(time.Time)
+		return sign(ad.Before(bd), ad.Equal(bd))
+	case Text:
+		return strings.Compare(a.v.(string), b.v.(string))
+	case Blob:
+		return bytes.Compare(a.v.([]byte), b.v.([]byte))
+	}
+	an, as, _ := a.Custom()
+	bn, bs, _ := b.Custom()
+	if an != bn {
+		return strings.Compare(an, bn)
+	}
+	if c, ok := CompareCustom(a, b); ok {
+		return sign(c < 0, c == 0)
+	}
+	return strings.Compare(as, bs)
+}
+
+// compareNumbers compares two numeric literals by value.
+func compareNumbers(a, b *Literal) int {
+	if a.t == Int64 && b.t == Int64 {
+		ai, bi := a.v.(int64), b.v.(int64)
+		return sign(ai < bi, ai == bi)
+	}
+	if a.t == Float64 && b.t == Float64 {
+		return compareFloats(a.v.(float64), b.v.(float64))
+	}
+	// Mixed types are compared exactly, unless one of them is an infinite or
+	// NaN float64.
+	if ar, ok := a.Rat(); ok {
+		if br, ok := b.Rat(); ok {
+			return ar.Cmp(br)
+		}
+	}
+	return compareFloats(toFloat64(a), toFloat64(b))
+}
+
+// compareFloats compares two float64 values, ordering NaN before any other
+// value.
+func compareFloats(a, b float64) int {
+	an, bn := math.IsNaN(a), math.IsNaN(b)
+	if an || bn {
+		return sign(an && !bn, an && bn)
+	}
+	return sign(a < b, a == b)
+}
+
+// toFloat64 returns the value of a numeric literal as a float64.
+func toFloat64(l *Literal) float64 {
+	if r, ok := l.Rat(); ok {
+		f, _ := r.Float64()
+		return f
+	}
+	return l.v.(float64)
+}
+
+// Less returns true if the literal is lower than the provided one in the
+// order implemented by Compare.
+func (l *Literal) Less(o *Literal) bool {
+	return Compare(l, o) < 0
+}
+
+// Comparable returns true if comparing the provided literals is meaningful
+// beyond the order across kinds. This is the case for two numeric literals,
+// and for two literals of the same type, as long as custom types provide a
+// Compare function.
+func Comparable(a, b *Literal) bool {
+	if isNumeric(a) && isNumeric(b) {
+		return true
+	}
+	if a.t != b.t {
+		return false
+	}
+	if ct := customType(a.t); ct != nil {
+		return ct.Compare != nil
+	}
+	return true
+}
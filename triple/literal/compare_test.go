@@ -0,0 +1,108 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	b := DefaultBuilder()
+	build := func(t Type, v interface{}) *Literal {
+		l, err := b.Build(t, v)
+		if err != nil {
+			panic(err)
+		}
+		return l
+	}
+	custom := func(name, s string) *Literal {
+		l, err := ParseCustom(name, s)
+		if err != nil {
+			panic(err)
+		}
+		return l
+	}
+	// Literals in the same group are equal; groups are in ascending order.
+	groups := [][]*Literal{
+		{build(Bool, false)},
+		{build(Bool, true)},
+		{build(Float64, math.NaN())},
+		{build(Float64, math.Inf(-1))},
+		{build(Int64, int64(math.MinInt64))},
+		{build(Decimal, big.NewRat(-3, 2)), build(Float64, -1.5)},
+		{build(Int64, int64(0)), build(Float64, 0.0), build(Decimal, new(big.Rat))},
+		{build(Decimal, big.NewRat(1, 10))},
+		{build(Int64, int64(2)), build(Float64, 2.0), build(Decimal, big.NewRat(4, 2))},
+		{build(Int64, int64(10))},
+		{build(Int64, int64(math.MaxInt64))},
+		{build(Float64, math.Inf(1))},
+		{build(Date, time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC))},
+		{build(Date, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))},
+		{build(Text, "")},
+		{build(Text, "B")},
+		{build(Text, "a")},
+		{build(Text, "ab")},
+		{build(Blob, []byte{})},
+		{build(Blob, []byte{0, 1})},
+		{build(Blob, []byte{1})},
+		{custom("blobref", "0000000000000000000000000000000000000000000000000000000000000000:1")},
+		{custom("version", "1.2")},
+		{custom("version", "1.10")},
+	}
+	for i, gi := range groups {
+		for j, gj := range groups {
+			want := sign(i < j, i == j)
+			for _, a := range gi {
+				for _, b := range gj {
+					if got := Compare(a, b); got != want {
+						t.Errorf("Compare(%v, %v) = %d; want %d", a, b, got, want)
+					}
+					if got, want := a.Less(b), want < 0; got != want {
+						t.Errorf("%v.Less(%v) = %v; want %v", a, b, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestComparable(t *testing.T) {
+	b := DefaultBuilder()
+	i, _ := b.Build(Int64, int64(1))
+	f, _ := b.Build(Float64, 1.0)
+	s, _ := b.Build(Text, "1")
+	d, _ := b.Build(Date, time.Unix(0, 0))
+	v, _ := ParseCustom("version", "1.0")
+	testTable := []struct {
+		a, b *Literal
+		want bool
+	}{
+		{i, f, true},
+		{s, s, true},
+		{d, d, true},
+		{v, v, true},
+		{i, s, false},
+		{s, d, false},
+		{s, v, false},
+	}
+	for _, entry := range testTable {
+		if got := Comparable(entry.a, entry.b); got != entry.want {
+			t.Errorf("Comparable(%v, %v) = %v; want %v", entry.a, entry.b, got, entry.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CustomType describes a literal type defined outside of this package.
+// Literals of custom types are built, parsed, and printed like the ones of
+// the built-in types, so they survive the round trip through BQL queries,
+// stores, and result tables.
+type CustomType struct {
+	// Name is the name of the type, as used in "value"^^type:name. It can only
+	// contain lowercase letters and digits.
+	Name string
+
+	// Parse returns the value represented by the provided text.
+	Parse func(s string) (interface{}, error)
+
+	// Format returns the text representing the provided value. Parse must
+	// return an equivalent value for it.
+	Format func(v interface{}) string
+
+	// Compare, if set, returns a negative number, zero, or a positive number if
+	// the first value is respectively smaller, equal, or bigger than the
+	// second one. Literals of types without Compare are not ordered.
+	Compare func(a, b interface{}) int
+}
+
+// firstCustom is the first type assigned to custom types. Custom types are
+// assigned in registration order, so they may differ between processes;
+// their name is what identifies them outside of a process.
+const firstCustom Type = 128
+
+// maxCustomTypes is the maximum number of custom types that can be
+// registered.
+const maxCustomTypes = 127
+
+var custom struct {
+	mu     sync.RWMutex
+	types  []*CustomType
+	byName map[string]Type
+}
+
+// builtinTypes maps the names of the built-in types to their type.
+var builtinTypes = map[string]Type{
+	"bool":    Bool,
+	"int64":   Int64,
+	"float64": Float64,
+	"text":    Text,
+	"blob":    Blob,
+	"decimal": Decimal,
+	"date":    Date,
+}
+
+// Register registers a new custom type and returns the type assigned to it.
+// Types are usually registered when the embedding program starts, before
+// any literal of the type is built or parsed.
+func Register(ct CustomType) (Type, error) {
+	if ct.Name == "" {
+		return 0, fmt.Errorf("literal.Register: custom types require a name")
+	}
+	for _, r := range ct.Name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			return 0, fmt.Errorf("literal.Register: invalid type name %q; only lowercase letters and digits are allowed", ct.Name)
+		}
+	}
+	if ct.Parse == nil || ct.Format == nil {
+		return 0, fmt.Errorf("literal.Register: custom type %q requires Parse and Format functions", ct.Name)
+	}
+	custom.mu.Lock()
+	defer custom.mu.Unlock()
+	if _, ok := builtinTypes[ct.Name]; ok {
+		return 0, fmt.Errorf("literal.Register: type %q is already registered", ct.Name)
+	}
+	if _, ok := custom.byName[ct.Name]; ok {
+		return 0, fmt.Errorf("literal.Register: type %q is already registered", ct.Name)
+	}
+	if len(custom.types) >= maxCustomTypes {
+		return 0, fmt.Errorf("literal.Register: cannot register more than %d custom types", maxCustomTypes)
+	}
+	if custom.byName == nil {
+		custom.byName = make(map[string]Type)
+	}
+	t := firstCustom + Type(len(custom.types))
+	custom.types = append(custom.types, &ct)
+	custom.byName[ct.Name] = t
+	return t, nil
+}
+
+// TypeByName returns the built-in or custom type with the provided name.
+func TypeByName(name string) (Type, bool) {
+	if t, ok := builtinTypes[name]; ok {
+		return t, true
+	}
+	custom.mu.RLock()
+	defer custom.mu.RUnlock()
+	t, ok := custom.byName[name]
+	return t, ok
+}
+
+// IsCustom returns true if the type was registered using Register.
+func (t Type) IsCustom() bool {
+	return customType(t) != nil
+}
+
+// customType returns the description of the provided custom type, or nil if
+// the type is not a registered custom type.
+func customType(t Type) *CustomType {
+	if t < firstCustom {
+		return nil
+	}
+	custom.mu.RLock()
+	defer custom.mu.RUnlock()
+	if i := int(t - firstCustom); i < len(custom.types) {
+		return custom.types[i]
+	}
+	return nil
+}
+
+// Custom returns the name of the custom type of the literal and the text
+// representing its value.
+func (l *Literal) Custom() (string, string, error) {
+	ct := customType(l.t)
+	if ct == nil {
+		return "", "", fmt.Errorf("literal.Custom: literal is of type %v; not a custom type", l.t)
+	}
+	return ct.Name, ct.Format(l.v), nil
+}
+
+// ParseCustom returns the literal of the custom type with the provided name
+// represented by the provided text.
+func ParseCustom(name, s string) (*Literal, error) {
+	t, ok := TypeByName(name)
+	ct := customType(t)
+	if !ok || ct == nil {
+		return nil, fmt.Errorf("literal.ParseCustom: unknown custom type %q", name)
+	}
+	v, err := ct.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("literal.ParseCustom: could not convert value %q to %s; %v", s, name, err)
+	}
+	return defaultBuilder.Build(t, v)
+}
+
+// CompareCustom returns a negative number, zero, or a positive number if the
+// first literal is respectively smaller, equal, or bigger than the second
+// one. It returns false if the literals are not of the same custom type, or
+// if the type provides no Compare function.
+func CompareCustom(a, b *Literal) (int, bool) {
+	if a.t != b.t {
+		return 0, false
+	}
+	ct := customType(a.t)
+	if ct == nil || ct.Compare == nil {
+		return 0, false
+	}
+	return ct.Compare(a.v, b.v), true
+}
@@ -0,0 +1,176 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// version is a custom type for dotted version numbers, ordered numerically.
+var version Type
+
+func init() {
+	var err error
+	version, err = Register(CustomType{
+		Name: "version",
+		Parse: func(s string) (interface{}, error) {
+			var v []int
+			for _, p := range strings.Split(s, ".") {
+				n, err := strconv.Atoi(p)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid version %q", s)
+				}
+				v = append(v, n)
+			}
+			return v, nil
+		},
+		Format: func(v interface{}) string {
+			var ps []string
+			for _, n := range v.([]int) {
+				ps = append(ps, strconv.Itoa(n))
+			}
+			return strings.Join(ps, ".")
+		},
+		Compare: func(a, b interface{}) int {
+			av, bv := a.([]int), b.([]int)
+			for i := 0; i < len(av) && i < len(bv); i++ {
+				if av[i] != bv[i] {
+					return av[i] - bv[i]
+				}
+			}
+			return len(av) - len(bv)
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	parse := func(s string) (interface{}, error) { return s, nil }
+	format := func(v interface{}) string { return v.(string) }
+	testTable := []CustomType{
+		{Name: "", Parse: parse, Format: format},
+		{Name: "Point", Parse: parse, Format: format},
+		{Name: "a_b", Parse: parse, Format: format},
+		{Name: "int64", Parse: parse, Format: format},
+		{Name: "version", Parse: parse, Format: format},
+		{Name: "noparse", Format: format},
+		{Name: "noformat", Parse: parse},
+	}
+	for _, entry := range testTable {
+		if _, err := Register(entry); err == nil {
+			t.Errorf("Register should have failed for type %q", entry.Name)
+		}
+	}
+}
+
+func TestCustomTypes(t *testing.T) {
+	if !version.IsCustom() || Int64.IsCustom() {
+		t.Errorf("IsCustom returned %v, %v for types version and int64; want true, false", version.IsCustom(), Int64.IsCustom())
+	}
+	if got, ok := TypeByName("version"); !ok || got != version {
+		t.Errorf("TypeByName(%q) returned %v, %v; want %v", "version", got, ok, version)
+	}
+	if got, ok := TypeByName("date"); !ok || got != Date {
+		t.Errorf("TypeByName(%q) returned %v, %v; want %v", "date", got, ok, Date)
+	}
+	if _, ok := TypeByName("unknown"); ok {
+		t.Errorf("TypeByName(%q) should have failed", "unknown")
+	}
+	if got, want := version.String(), "version"; got != want {
+		t.Errorf("version.String() returned %q; want %q", got, want)
+	}
+
+	b := DefaultBuilder()
+	l, err := b.Parse(`"1.10.2"^^type:version`)
+	if err != nil {
+		t.Fatalf("Parse failed to parse a custom literal with error %v", err)
+	}
+	if got, want := l.String(), `"1.10.2"^^type:version`; got != want {
+		t.Errorf("String returned %q; want %q", got, want)
+	}
+	name, text, err := l.Custom()
+	if err != nil || name != "version" || text != "1.10.2" {
+		t.Errorf("Custom returned %q, %q, %v; want %q, %q", name, text, err, "version", "1.10.2")
+	}
+	bl, err := b.Build(version, []int{1, 10, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.UUID().String() != bl.UUID().String() {
+		t.Errorf("parsed and built literals of the same value should have the same UUID")
+	}
+	if pl, err := ParseCustom("version", "1.10.2"); err != nil || pl.UUID().String() != l.UUID().String() {
+		t.Errorf("ParseCustom returned %v, %v; want %v", pl, err, l)
+	}
+	tl, err := b.Build(Text, "1.10.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.UUID().String() == tl.UUID().String() {
+		t.Errorf("a custom literal should not have the UUID of the text with the same characters")
+	}
+
+	if _, err := b.Parse(`"1.x"^^type:version`); err == nil {
+		t.Errorf("Parse should have failed for an invalid version")
+	}
+	if _, err := ParseCustom("int64", "1"); err == nil {
+		t.Errorf("ParseCustom should have failed for a built-in type")
+	}
+	if _, err := b.Build(version, nil); err == nil {
+		t.Errorf("Build should have failed for a custom type without value")
+	}
+	if _, _, err := tl.Custom(); err == nil {
+		t.Errorf("Custom should have failed for a text literal")
+	}
+}
+
+func TestCompareCustom(t *testing.T) {
+	b := DefaultBuilder()
+	parse := func(s string) *Literal {
+		l, err := b.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	testTable := []struct {
+		a, b string
+		cmp  int
+		ok   bool
+	}{
+		{`"1.9"^^type:version`, `"1.10"^^type:version`, -1, true},
+		{`"1.10"^^type:version`, `"1.9"^^type:version`, 1, true},
+		{`"1.10"^^type:version`, `"1.10"^^type:version`, 0, true},
+		{`"1.10.1"^^type:version`, `"1.10"^^type:version`, 1, true},
+		{`"1.10"^^type:version`, `"1.10"^^type:text`, 0, false},
+		{`"1"^^type:int64`, `"2"^^type:int64`, 0, false},
+	}
+	for _, entry := range testTable {
+		cmp, ok := CompareCustom(parse(entry.a), parse(entry.b))
+		if cmp < 0 {
+			cmp = -1
+		} else if cmp > 0 {
+			cmp = 1
+		}
+		if cmp != entry.cmp || ok != entry.ok {
+			t.Errorf("CompareCustom(%s, %s) returned %d, %v; want %d, %v", entry.a, entry.b, cmp, ok, entry.cmp, entry.ok)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateLayout is the layout of the values of date literals.
+const DateLayout = "2006-01-02"
+
+// toDate returns midnight UTC of the calendar day of the provided time in its
+// own location.
+func toDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Date returns the value of a literal as a calendar day. The returned time is
+// midnight UTC of the day.
+func (l *Literal) Date() (time.Time, error) {
+	if l.t != Date {
+		return time.Time{}, fmt.Errorf("literal.Date: literal is of type %v; cannot be converted to a date", l.t)
+	}
+	return l.v.(time.Time), nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+var (
+	bigTwo  = big.NewInt(2)
+	bigFive = big.NewInt(5)
+)
+
+// decimalScale returns the number of digits after the decimal point needed to
+// write the provided rational number exactly. The boolean is false if the
+// number has no finite decimal representation, like 1/3.
+func decimalScale(r *big.Rat) (int, bool) {
+	d := new(big.Int).Set(r.Denom())
+	m := new(big.Int)
+	twos, fives := 0, 0
+	for {
+		q, rem := new(big.Int).QuoRem(d, bigTwo, m)
+		if rem.Sign() != 0 {
+			break
+		}
+		d, twos = q, twos+1
+	}
+	for {
+		q, rem := new(big.Int).QuoRem(d, bigFive, m)
+		if rem.Sign() != 0 {
+			break
+		}
+		d, fives = q, fives+1
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+// decimalString returns the shortest decimal representation of the provided
+// decimal value.
+func decimalString(r *big.Rat) string {
+	s, _ := decimalScale(r)
+	return r.FloatString(s)
+}
+
+// parseDecimal parses a decimal number, like "-12.50" or "1e-3". Fractions are
+// not accepted.
+func parseDecimal(s string) (*big.Rat, error) {
+	if strings.Contains(s, "/") {
+		return nil, fmt.Errorf("literal.Parse: could not convert value %q to decimal", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("literal.Parse: could not convert value %q to decimal", s)
+	}
+	return r, nil
+}
+
+// Decimal returns the value of a literal as an exact decimal number. The
+// returned value is a copy, so changing it does not change the literal.
+func (l *Literal) Decimal() (*big.Rat, error) {
+	if l.t != Decimal {
+		return nil, fmt.Errorf("literal.Decimal: literal is of type %v; cannot be converted to a decimal", l.t)
+	}
+	return new(big.Rat).Set(l.v.(*big.Rat)), nil
+}
+
+// Rat returns the exact value of a numeric literal. Int64, decimal, and finite
+// float64 literals are numeric. The boolean is false for any other literal.
+func (l *Literal) Rat() (*big.Rat, bool) {
+	switch v := l.v.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(v), true
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(v), true
+	case *big.Rat:
+		return new(big.Rat).Set(v), true
+	}
+	return nil, false
+}
@@ -20,8 +20,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pborman/uuid"
 )
@@ -40,6 +42,12 @@ const (
 	Text
 	// Blob indicates that the type contained in the literal is a []byte.
 	Blob
+	// Decimal indicates that the type contained in the literal is an exact
+	// decimal number, kept as a *big.Rat.
+	Decimal
+	// Date indicates that the type contained in the literal is a calendar
+	// day, kept as a time.Time at midnight UTC.
+	Date
 )
 
 // Strings returns the pretty printing version of the type
@@ -55,7 +63,14 @@ func (t Type) String() string {
 		return "text"
 	case Blob:
 		return "blob"
+	case Decimal:
+		return "decimal"
+	case Date:
+		return "date"
 	default:
+		if ct := customType(t); ct != nil {
+			return ct.Name
+		}
 		return "UNKNOWN"
 	}
 }
@@ -73,6 +88,15 @@ func (l *Literal) Type() Type {
 
 // String returns a string representation of the literal.
 func (l *Literal) String() string {
+	if ct := customType(l.t); ct != nil {
+		return fmt.Sprintf("\"%s\"^^type:%s", ct.Format(l.v), ct.Name)
+	}
+	switch l.t {
+	case Decimal:
+		return fmt.Sprintf("\"%s\"^^type:%v", decimalString(l.v.(*big.Rat)), l.Type())
+	case Date:
+		return fmt.Sprintf("\"%s\"^^type:%v", l.v.(time.Time).Format(DateLayout), l.Type())
+	}
 	return fmt.Sprintf("\"%v\"^^type:%v", l.Interface(), l.Type())
 }
 
@@ -84,6 +108,15 @@ func (l *Literal) ToComparableString() string {
 		s = fmt.Sprintf("\"%032d\"^^type:%v", l.Interface(), l.Type())
 	case Float64:
 		s = fmt.Sprintf("\"%032f\"^^type:%v", l.Interface(), l.Type())
+	case Decimal:
+		// The integer part is padded like the one of int64 literals, and the
+		// fractional part kept exact.
+		ds := decimalString(l.v.(*big.Rat))
+		ip, fp := ds, ""
+		if i := strings.Index(ds, "."); i >= 0 {
+			ip, fp = ds[:i], ds[i:]
+		}
+		s = fmt.Sprintf("\"%032s%s\"^^type:%v", ip, fp, l.Type())
 	default:
 		s = l.String()
 	}
@@ -155,7 +188,18 @@ type unboundBuilder struct{}
 
 // Build creates a new unbound literal from a type and a value.
 func (b *unboundBuilder) Build(t Type, v interface{}) (*Literal, error) {
-	switch v.(type) {
+	if t.IsCustom() {
+		// Values of custom types are only known to the functions of their
+		// type.
+		if v == nil {
+			return nil, fmt.Errorf("literal.Build: type %v requires a value", t)
+		}
+		return &Literal{
+			t: t,
+			v: v,
+		}, nil
+	}
+	switch tv := v.(type) {
 	case bool:
 		if t != Bool {
 			return nil, fmt.Errorf("literal.Build: type %v does not match type of value %v", t, v)
@@ -176,6 +220,22 @@ func (b *unboundBuilder) Build(t Type, v interface{}) (*Literal, error) {
 		if t != Blob {
 			return nil, fmt.Errorf("literal.Build: type %v does not match type of value %v", t, v)
 		}
+	case *big.Rat:
+		if t != Decimal {
+			return nil, fmt.Errorf("literal.Build: type %v does not match type of value %v", t, v)
+		}
+		if _, ok := decimalScale(tv); !ok {
+			return nil, fmt.Errorf("literal.Build: %v has no exact decimal representation", v)
+		}
+		// Decimals are copied, so changing the provided value does not change
+		// the literal.
+		v = new(big.Rat).Set(tv)
+	case time.Time:
+		if t != Date {
+			return nil, fmt.Errorf("literal.Build: type %v does not match type of value %v", t, v)
+		}
+		// Dates only keep the calendar day of the provided time.
+		v = toDate(tv)
 	default:
 		return nil, fmt.Errorf("literal.Build: type %T is not supported when building literals", v)
 	}
@@ -219,6 +279,18 @@ func (b *unboundBuilder) Parse(s string) (*Literal, error) {
 			return nil, fmt.Errorf("literal.Parse: could not convert value %q to float64", v)
 		}
 		return b.Build(Float64, float64(pv))
+	case "decimal":
+		pv, err := parseDecimal(v)
+		if err != nil {
+			return nil, err
+		}
+		return b.Build(Decimal, pv)
+	case "date":
+		pv, err := time.Parse(DateLayout, v)
+		if err != nil {
+			return nil, fmt.Errorf("literal.Parse: could not convert value %q to date", v)
+		}
+		return b.Build(Date, pv)
 	case "text":
 		return b.Build(Text, v)
 	case "blob":
@@ -236,6 +308,13 @@ func (b *unboundBuilder) Parse(s string) (*Literal, error) {
 		}
 		return b.Build(Blob, bs)
 	default:
+		if ct, ok := TypeByName(t); ok {
+			pv, err := customType(ct).Parse(v)
+			if err != nil {
+				return nil, fmt.Errorf("literal.Parse: could not convert value %q to %s; %v", v, t, err)
+			}
+			return b.Build(ct, pv)
+		}
 		return nil, nil
 	}
 }
@@ -298,6 +377,15 @@ func NewBoundedBuilder(max int) Builder {
 func (l *Literal) UUID() uuid.UUID {
 	var buffer bytes.Buffer
 
+	if ct := customType(l.t); ct != nil {
+		// Custom types are identified by their name, since the type assigned
+		// to them may change between processes.
+		buffer.WriteByte(0xff)
+		buffer.WriteString(ct.Name)
+		buffer.WriteByte(0)
+		buffer.WriteString(ct.Format(l.v))
+		return uuid.NewSHA1(uuid.NIL, buffer.Bytes())
+	}
 	switch v := l.v.(type) {
 	case bool:
 		if v {
@@ -318,6 +406,14 @@ func (l *Literal) UUID() uuid.UUID {
 		buffer.Write([]byte(v))
 	case []byte:
 		buffer.Write(v)
+	case *big.Rat:
+		// Decimals and dates are prefixed by their type, so they do not share
+		// the UUID of the text with the same characters.
+		buffer.WriteByte(byte(Decimal))
+		buffer.WriteString(decimalString(v))
+	case time.Time:
+		buffer.WriteByte(byte(Date))
+		buffer.WriteString(v.Format(DateLayout))
 	}
 
 	return uuid.NewSHA1(uuid.NIL, buffer.Bytes())
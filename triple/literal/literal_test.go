@@ -15,8 +15,10 @@
 package literal
 
 import (
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestDefaultBuilder(t *testing.T) {
@@ -38,12 +40,18 @@ func TestDefaultBuilder(t *testing.T) {
 		{Text, "some random string", &Literal{Text, interface{}("some random string")}},
 		{Blob, []byte{}, &Literal{Blob, []byte{}}},
 		{Blob, []byte("some random bytes"), &Literal{Blob, interface{}([]byte("some random bytes"))}},
+		{Decimal, big.NewRat(25, 2), &Literal{Decimal, big.NewRat(25, 2)}},
+		{Date, time.Date(2016, 4, 10, 23, 30, 0, 0, time.FixedZone("PDT", -7*3600)), &Literal{Date, time.Date(2016, 4, 10, 0, 0, 0, 0, time.UTC)}},
 		// Invalid cases.
 		{Bool, 1, nil},
 		{Int64, 2, nil},
 		{Float64, 3, nil},
 		{Text, 4, nil},
 		{Blob, 5, nil},
+		{Decimal, 6.5, nil},
+		{Decimal, big.NewRat(1, 3), nil},
+		{Float64, big.NewRat(1, 2), nil},
+		{Text, time.Now(), nil},
 	}
 	for _, tc := range table {
 		got, err := DefaultBuilder().Build(tc.t, tc.v)
@@ -100,6 +108,10 @@ func TestPrettyPrinting(t *testing.T) {
 		{Text, "some random string", `"some random string"^^type:text`},
 		{Blob, []byte{}, `"[]"^^type:blob`},
 		{Blob, []byte("some random bytes"), `"[115 111 109 101 32 114 97 110 100 111 109 32 98 121 116 101 115]"^^type:blob`},
+		{Decimal, big.NewRat(-25, 2), `"-12.5"^^type:decimal`},
+		{Decimal, big.NewRat(1, 1000), `"0.001"^^type:decimal`},
+		{Decimal, big.NewRat(42, 1), `"42"^^type:decimal`},
+		{Date, time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC), `"1969-07-20"^^type:date`},
 	}
 	for _, tc := range table {
 		lit, err := DefaultBuilder().Build(tc.t, tc.v)
@@ -127,6 +139,8 @@ func TestToComparableString(t *testing.T) {
 		{Float64, float64(-1), `"-000000000000000000000001.000000"^^type:float64`},
 		{Float64, float64(0), `"0000000000000000000000000.000000"^^type:float64`},
 		{Float64, float64(1), `"0000000000000000000000001.000000"^^type:float64`},
+		{Decimal, big.NewRat(42, 1), `"00000000000000000000000000000042"^^type:decimal`},
+		{Decimal, big.NewRat(1999, 100), `"00000000000000000000000000000019.99"^^type:decimal`},
 		{Text, "", `""^^type:text`},
 		{Text, "some random string", `"some random string"^^type:text`},
 		{Blob, []byte{}, `"[]"^^type:blob`},
@@ -162,6 +176,9 @@ func TestParse(t *testing.T) {
 		{Text, "some random string", `"some random string"^^type:text`},
 		{Blob, []byte{}, `"[]"^^type:blob`},
 		{Blob, []byte("some random bytes"), `"[115 111 109 101 32 114 97 110 100 111 109 32 98 121 116 101 115]"^^type:blob`},
+		{Decimal, big.NewRat(25, 2), `"12.50"^^type:decimal`},
+		{Decimal, big.NewRat(-1, 1000), `"-1e-3"^^type:decimal`},
+		{Date, time.Date(2016, 2, 29, 0, 0, 0, 0, time.UTC), `"2016-02-29"^^type:date`},
 	}
 	for _, tc := range table {
 		want, err := DefaultBuilder().Build(tc.t, tc.v)
@@ -177,3 +194,114 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestDecimal(t *testing.T) {
+	b := DefaultBuilder()
+	for _, s := range []string{`"1/3"^^type:decimal`, `"1/4"^^type:decimal`, `"twelve"^^type:decimal`, `""^^type:decimal`} {
+		if l, err := b.Parse(s); err == nil {
+			t.Errorf("Parse(%q) should have failed; got %v", s, l)
+		}
+	}
+
+	// Decimals are immutable and equal by value.
+	r := big.NewRat(1999, 100)
+	l, err := b.Build(Decimal, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetInt64(0)
+	d, err := l.Decimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.RatString(), "1999/100"; got != want {
+		t.Errorf("l.Decimal() returned %s after changing the built value; want %s", got, want)
+	}
+	d.SetInt64(0)
+	if got, want := l.String(), `"19.99"^^type:decimal`; got != want {
+		t.Errorf("l.String() returned %s after changing the returned value; want %s", got, want)
+	}
+	p, err := b.Parse(`"19.990"^^type:decimal`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(l.UUID(), p.UUID()) {
+		t.Errorf("equal decimals %v and %v have different UUIDs", l, p)
+	}
+	txt, err := b.Build(Text, "19.99")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(l.UUID(), txt.UUID()) {
+		t.Errorf("decimal %v and text %v have the same UUID", l, txt)
+	}
+	if _, err := txt.Decimal(); err == nil {
+		t.Errorf("txt.Decimal() should fail for a text literal")
+	}
+}
+
+func TestRat(t *testing.T) {
+	b := DefaultBuilder()
+	table := []struct {
+		t    Type
+		v    interface{}
+		want string
+		ok   bool
+	}{
+		{Int64, int64(-3), "-3", true},
+		{Float64, 0.5, "1/2", true},
+		{Decimal, big.NewRat(1999, 100), "1999/100", true},
+		{Text, "1", "", false},
+		{Bool, true, "", false},
+	}
+	for _, tc := range table {
+		l, err := b.Build(tc.t, tc.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, ok := l.Rat()
+		if ok != tc.ok || (ok && r.RatString() != tc.want) {
+			t.Errorf("%v.Rat() returned %v, %v; want %s, %v", l, r, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestDate(t *testing.T) {
+	b := DefaultBuilder()
+	for _, s := range []string{`"2015-02-29"^^type:date`, `"2016-04-10T00:00:00Z"^^type:date`, `"10/04/2016"^^type:date`, `""^^type:date`} {
+		if l, err := b.Parse(s); err == nil {
+			t.Errorf("Parse(%q) should have failed; got %v", s, l)
+		}
+	}
+
+	// Dates only keep the calendar day, so times of the same day build the
+	// same literal.
+	morning, err := b.Build(Date, time.Date(2016, 4, 10, 8, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	evening, err := b.Build(Date, time.Date(2016, 4, 10, 22, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(morning.UUID(), evening.UUID()) {
+		t.Errorf("dates %v and %v of the same day have different UUIDs", morning, evening)
+	}
+	d, err := morning.Date()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2016, 4, 10, 0, 0, 0, 0, time.UTC); !d.Equal(want) {
+		t.Errorf("morning.Date() returned %v; want %v", d, want)
+	}
+	txt, err := b.Build(Text, "2016-04-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(morning.UUID(), txt.UUID()) {
+		t.Errorf("date %v and text %v have the same UUID", morning, txt)
+	}
+	if _, err := txt.Date(); err == nil {
+		t.Errorf("txt.Date() should fail for a text literal")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// VectorType is the type of the literals holding a []float64 vector, such as
+// the embeddings attached to the nodes of a graph. It is registered as the
+// custom type "vector", printed as "[0.5,-1,2]"^^type:vector.
+var VectorType Type
+
+// ParseVector returns the vector represented by the provided text: a non
+// empty list of finite numbers separated by commas between square brackets.
+func ParseVector(s string) ([]float64, error) {
+	raw := strings.TrimSpace(s)
+	if len(raw) < 2 || raw[0] != '[' || raw[len(raw)-1] != ']' {
+		return nil, fmt.Errorf("literal.ParseVector: invalid vector %q; it should be enclosed in square brackets", s)
+	}
+	raw = strings.TrimSpace(raw[1 : len(raw)-1])
+	if raw == "" {
+		return nil, fmt.Errorf("literal.ParseVector: invalid vector %q; vectors cannot be empty", s)
+	}
+	var v []float64
+	for _, c := range strings.Split(raw, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(c), 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("literal.ParseVector: invalid vector %q; %q is not a finite number", s, strings.TrimSpace(c))
+		}
+		v = append(v, f)
+	}
+	return v, nil
+}
+
+// formatVector returns the text representing the provided vector.
+func formatVector(v []float64) string {
+	ps := make([]string, len(v))
+	for i, f := range v {
+		ps[i] = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return "[" + strings.Join(ps, ",") + "]"
+}
+
+func init() {
+	var err error
+	VectorType, err = Register(CustomType{
+		Name: "vector",
+		Parse: func(s string) (interface{}, error) {
+			return ParseVector(s)
+		},
+		Format: func(v interface{}) string {
+			return formatVector(v.([]float64))
+		},
+		Compare: func(a, b interface{}) int {
+			av, bv := a.([]float64), b.([]float64)
+			for i := 0; i < len(av) && i < len(bv); i++ {
+				if c := compareFloats(av[i], bv[i]); c != 0 {
+					return c
+				}
+			}
+			return sign(len(av) < len(bv), len(av) == len(bv))
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Vector returns a copy of the vector held by the literal.
+func (l *Literal) Vector() ([]float64, error) {
+	if l.t != VectorType {
+		return nil, fmt.Errorf("literal.Vector: literal is of type %v; not a vector", l.t)
+	}
+	v, ok := l.v.([]float64)
+	if !ok {
+		return nil, fmt.Errorf("literal.Vector: literal of type %v does not hold a []float64", l.t)
+	}
+	return append([]float64(nil), v...), nil
+}
+
+// ErrZeroVector is returned when computing the cosine similarity of a vector
+// whose components are all zero, since it has no direction.
+var ErrZeroVector = errors.New("literal: the cosine similarity of a zero vector is undefined")
+
+// CosineSimilarity returns the cosine of the angle between the provided
+// vectors, from -1 for opposite vectors to 1 for vectors pointing in the same
+// direction. Both vectors need to have the same number of dimensions.
+func CosineSimilarity(a, b []float64) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("literal.CosineSimilarity: vectors have %d and %d dimensions", len(a), len(b))
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0, ErrZeroVector
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb)), nil
+}
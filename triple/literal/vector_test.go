@@ -0,0 +1,93 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestVector(t *testing.T) {
+	l, err := DefaultBuilder().Parse(`"[ 0.5, -1 ,2e3]"^^type:vector`)
+	if err != nil {
+		t.Fatalf("literal.Parse failed to parse a vector with error %v", err)
+	}
+	if l.Type() != VectorType || l.Type().String() != "vector" {
+		t.Errorf("literal.Parse returned a literal of type %v; want vector", l.Type())
+	}
+	v, err := l.Vector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{0.5, -1, 2000}; !reflect.DeepEqual(v, want) {
+		t.Errorf("l.Vector() = %v; want %v", v, want)
+	}
+	v[0] = 10
+	if v2, _ := l.Vector(); v2[0] != 0.5 {
+		t.Errorf("changing the vector returned by l.Vector() should not change the literal")
+	}
+	if got, want := l.String(), `"[0.5,-1,2000]"^^type:vector`; got != want {
+		t.Errorf("l.String() = %q; want %q", got, want)
+	}
+	short, err := ParseCustom("vector", "[0.5]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Compare(short, l); got >= 0 {
+		t.Errorf("Compare(%v, %v) = %d; want a negative number", short, l, got)
+	}
+	text, err := DefaultBuilder().Build(Text, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := text.Vector(); err == nil {
+		t.Errorf("text.Vector() should fail for a text literal")
+	}
+
+	for _, s := range []string{"", "[]", "[ ]", "1,2", "[1,]", "[1,x]", "[NaN]", "[1,+Inf]"} {
+		if got, err := ParseVector(s); err == nil {
+			t.Errorf("literal.ParseVector(%q) should have failed; got %v", s, got)
+		}
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	testTable := []struct {
+		a, b []float64
+		want float64
+	}{
+		{[]float64{1, 0}, []float64{2, 0}, 1},
+		{[]float64{1, 0}, []float64{0, 3}, 0},
+		{[]float64{1, 1}, []float64{-1, -1}, -1},
+		{[]float64{1, 0}, []float64{1, 1}, 1 / math.Sqrt2},
+	}
+	for _, entry := range testTable {
+		got, err := CosineSimilarity(entry.a, entry.b)
+		if err != nil {
+			t.Errorf("CosineSimilarity(%v, %v) failed with error %v", entry.a, entry.b, err)
+			continue
+		}
+		if math.Abs(got-entry.want) > 1e-12 {
+			t.Errorf("CosineSimilarity(%v, %v) = %v; want %v", entry.a, entry.b, got, entry.want)
+		}
+	}
+	if _, err := CosineSimilarity([]float64{1}, []float64{1, 2}); err == nil {
+		t.Errorf("CosineSimilarity should fail for vectors with different dimensions")
+	}
+	if _, err := CosineSimilarity([]float64{0, 0}, []float64{1, 2}); err != ErrZeroVector {
+		t.Errorf("CosineSimilarity should fail with ErrZeroVector for a zero vector; got %v", err)
+	}
+}
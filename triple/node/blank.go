@@ -0,0 +1,76 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/pborman/uuid"
+)
+
+// BlankNodeScope allocates the blank nodes of a graph or an import session.
+// Blank nodes allocated by different scopes never collide, while the same
+// label always returns the same blank node within a scope, which is what
+// importers need to map the blank node labels of their input to nodes.
+//
+// BlankNodeScope is safe for concurrent use.
+type BlankNodeScope struct {
+	id uuid.UUID
+
+	mu   sync.Mutex
+	next uint64
+}
+
+// NewBlankNodeScope returns a new scope for the provided name. Scopes with the
+// same name allocate the same blank nodes for the same labels and calls, so
+// rerunning an import with the same name reuses its blank nodes instead of
+// duplicating them. Use a name unique to the import session, such as the graph
+// ID followed by the session ID, to keep the blank nodes of different sessions
+// apart. An empty name returns a scope whose blank nodes are unique in
+// BadWolf.
+func NewBlankNodeScope(name string) *BlankNodeScope {
+	id := uuid.NewRandom()
+	if name != "" {
+		id = uuid.NewSHA1(uuid.NIL, []byte("/_"+name))
+	}
+	return &BlankNodeScope{id: id}
+}
+
+// newNode returns the blank node of the scope for the provided key.
+func (s *BlankNodeScope) newNode(key string) *Node {
+	id := ID(uuid.NewSHA1(s.id, []byte(key)).String())
+	return &Node{
+		t:  &tBlank,
+		id: &id,
+	}
+}
+
+// NewBlankNode returns a new blank node of the scope, different from all the
+// other blank nodes allocated by the scope.
+func (s *BlankNodeScope) NewBlankNode() *Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	return s.newNode("#" + strconv.FormatUint(s.next, 10))
+}
+
+// Labeled returns the blank node of the scope for the provided label, such as
+// the b1 of _:b1. All the calls for the same label return the same blank node.
+// Labeled blank nodes are derived from the label, so scopes do not need to
+// remember them no matter how many labels an import uses.
+func (s *BlankNodeScope) Labeled(label string) *Node {
+	return s.newNode("_:" + label)
+}
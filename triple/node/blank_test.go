@@ -0,0 +1,80 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBlankNodeScope(t *testing.T) {
+	s := NewBlankNodeScope("?family/import-1")
+	a, b := s.NewBlankNode(), s.NewBlankNode()
+	if a.String() == b.String() {
+		t.Errorf("s.NewBlankNode returned %s twice", a)
+	}
+	for _, n := range []*Node{a, b, s.Labeled("b1")} {
+		if got, want := n.Type().String(), "/_"; got != want {
+			t.Errorf("blank node %s has type %q; want %q", n, got, want)
+		}
+	}
+	if got, want := s.Labeled("b1").String(), s.Labeled("b1").String(); got != want {
+		t.Errorf("s.Labeled returned %s and %s for the same label", got, want)
+	}
+	if s.Labeled("b1").String() == s.Labeled("b2").String() {
+		t.Errorf("s.Labeled returned the same node for different labels")
+	}
+
+	// Scopes with the same name allocate the same nodes.
+	o := NewBlankNodeScope("?family/import-1")
+	if got, want := o.NewBlankNode().String(), a.String(); got != want {
+		t.Errorf("the first blank node of a scope with the same name is %s; want %s", got, want)
+	}
+	if got, want := o.Labeled("b1").String(), s.Labeled("b1").String(); got != want {
+		t.Errorf("o.Labeled returned %s; want %s", got, want)
+	}
+
+	// Scopes with different names never share nodes.
+	for _, d := range []*BlankNodeScope{NewBlankNodeScope("?family/import-2"), NewBlankNodeScope(""), NewBlankNodeScope("")} {
+		if d.NewBlankNode().String() == a.String() || d.Labeled("b1").String() == s.Labeled("b1").String() {
+			t.Errorf("scopes with different names allocated the same blank nodes")
+		}
+	}
+}
+
+func TestBlankNodeScopeConcurrency(t *testing.T) {
+	s := NewBlankNodeScope("")
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				n := s.NewBlankNode()
+				mu.Lock()
+				seen[n.String()] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := len(seen), 800; got != want {
+		t.Errorf("concurrent calls to s.NewBlankNode allocated %d different nodes; want %d", got, want)
+	}
+}
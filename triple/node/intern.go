@@ -0,0 +1,104 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import "sync"
+
+// DefaultPoolSize is the number of nodes kept by the pool used by Intern.
+const DefaultPoolSize = 1 << 20
+
+// Pool interns nodes, so equal nodes share a single copy of their type and ID
+// instead of each keeping its own. Pools are bounded: once a pool keeps its
+// maximum number of nodes, it forgets all of them and starts over. Nodes
+// returned before that remain valid; they are just not shared with the ones
+// interned afterwards. Pools are safe for concurrent use.
+type Pool struct {
+	mu    sync.RWMutex
+	max   int
+	nodes map[nodeKey]*Node
+	types map[Type]*Type
+}
+
+// nodeKey identifies a node in a pool.
+type nodeKey struct {
+	t  Type
+	id ID
+}
+
+// NewPool returns an empty pool that keeps up to max nodes.
+func NewPool(max int) *Pool {
+	return &Pool{
+		max:   max,
+		nodes: make(map[nodeKey]*Node),
+		types: make(map[Type]*Type),
+	}
+}
+
+// clone returns a copy of the provided string, so interned values do not
+// keep alive the larger strings they may have been sliced out of.
+func clone(s string) string {
+	return string(append([]byte(nil), s...))
+}
+
+// Intern returns the node of the pool equal to the provided one, adding it to
+// the pool if needed.
+func (p *Pool) Intern(n *Node) *Node {
+	if n == nil {
+		return nil
+	}
+	k := nodeKey{t: *n.t, id: *n.id}
+	p.mu.RLock()
+	c, ok := p.nodes[k]
+	p.mu.RUnlock()
+	if ok {
+		return c
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.nodes[k]; ok {
+		return c
+	}
+	if len(p.nodes) >= p.max {
+		p.nodes = make(map[nodeKey]*Node)
+		p.types = make(map[Type]*Type)
+	}
+	t, ok := p.types[k.t]
+	if !ok {
+		nt := Type(clone(string(k.t)))
+		t = &nt
+		p.types[nt] = t
+	}
+	id := ID(clone(string(k.id)))
+	c = &Node{t: t, id: &id}
+	p.nodes[nodeKey{t: *t, id: id}] = c
+	return c
+}
+
+// Len returns the number of nodes in the pool.
+func (p *Pool) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.nodes)
+}
+
+// pool is the pool used by Intern.
+var pool = NewPool(DefaultPoolSize)
+
+// Intern returns the node equal to the provided one kept by a process wide
+// pool of DefaultPoolSize nodes, so the duplicate nodes produced while
+// importing triples or joining them share memory.
+func Intern(n *Node) *Node {
+	return pool.Intern(n)
+}
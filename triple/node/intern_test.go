@@ -0,0 +1,70 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPoolIntern(t *testing.T) {
+	p := NewPool(4)
+	if p.Intern(nil) != nil {
+		t.Errorf("p.Intern(nil) should return nil")
+	}
+	a, err := Parse("/u<john>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse("/u<john>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Parse("/u<mary>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ia, ib, ic := p.Intern(a), p.Intern(b), p.Intern(c)
+	if ia != ib {
+		t.Errorf("p.Intern returned different nodes for equal nodes %v and %v", a, b)
+	}
+	if ia == ic || ia.String() != a.String() || ic.String() != c.String() {
+		t.Errorf("p.Intern returned %v and %v; want %v and %v", ia, ic, a, c)
+	}
+	if ia.Type() != ic.Type() {
+		t.Errorf("p.Intern should share the type of nodes %v and %v", ia, ic)
+	}
+	if got, want := p.Len(), 2; got != want {
+		t.Errorf("p.Len() = %d; want %d", got, want)
+	}
+
+	// Full pools start over.
+	for i := 0; i < 3; i++ {
+		n, err := NewNodeFromStrings("/u", fmt.Sprintf("n%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Intern(n)
+	}
+	if got, want := p.Len(), 1; got != want {
+		t.Errorf("p.Len() = %d after filling the pool; want %d", got, want)
+	}
+	if p.Intern(a) == ia || p.Intern(a).String() != a.String() {
+		t.Errorf("p.Intern should return a new copy of %v after the pool started over", a)
+	}
+	if Intern(a) != Intern(b) {
+		t.Errorf("Intern returned different nodes for equal nodes %v and %v", a, b)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// IRIType is the type of the nodes identified by an IRI. The ID of an IRI node
+// is the IRI itself, so data coming from RDF sources keeps its identifiers
+// as they are instead of mangling them into types and IDs.
+const IRIType = "/iri"
+
+// iriExcluded lists the characters IRIs cannot contain.
+const iriExcluded = "<>\"{}|^`\\"
+
+// ValidateIRI returns an error if the provided string is not an absolute IRI.
+// Absolute IRIs start with a scheme followed by ':' and cannot contain spaces,
+// control characters, or any of the characters <>"{}|^`\.
+func ValidateIRI(iri string) error {
+	i := strings.Index(iri, ":")
+	if i <= 0 || i == len(iri)-1 {
+		return fmt.Errorf("node.ValidateIRI(%q) requires an absolute IRI starting with a scheme", iri)
+	}
+	for j, r := range iri[:i] {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) && (j == 0 || !unicode.IsDigit(r) && !strings.ContainsRune("+-.", r)) {
+			return fmt.Errorf("node.ValidateIRI(%q) has an invalid scheme %q", iri, iri[:i])
+		}
+	}
+	for _, r := range iri {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(iriExcluded, r) {
+			return fmt.Errorf("node.ValidateIRI(%q) does not allow spaces, control characters, or any of %s", iri, iriExcluded)
+		}
+	}
+	return nil
+}
+
+// NewIRI returns the node for the provided absolute IRI.
+func NewIRI(iri string) (*Node, error) {
+	if err := ValidateIRI(iri); err != nil {
+		return nil, err
+	}
+	t, id := Type(IRIType), ID(iri)
+	return NewNode(&t, &id), nil
+}
+
+// IsIRI returns true if the node is identified by an IRI.
+func (n *Node) IsIRI() bool {
+	return n.t.String() == IRIType
+}
+
+// IRI returns the IRI identifying the node. It fails if the node is not an IRI
+// node.
+func (n *Node) IRI() (string, error) {
+	if !n.IsIRI() {
+		return "", fmt.Errorf("node.IRI: %s is not an IRI node", n)
+	}
+	return n.id.String(), nil
+}
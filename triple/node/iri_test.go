@@ -0,0 +1,81 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import "testing"
+
+func TestValidateIRI(t *testing.T) {
+	table := []struct {
+		iri string
+		v   bool
+	}{
+		{"http://xmlns.com/foaf/0.1/Person", true},
+		{"https://example.com/a?b=c#d", true},
+		{"urn:isbn:0451450523", true},
+		{"mailto:john@example.com", true},
+		{"http://example.com/café", true},
+		{"", false},
+		{"foaf_Person", false},
+		{":Person", false},
+		{"http:", false},
+		{"1http://example.com", false},
+		{"ht_tp://example.com", false},
+		{"http://example.com/a b", false},
+		{"http://example.com/<a>", false},
+		{"http://example.com/\"a\"", false},
+		{"http://example.com/{a}", false},
+		{"http://example.com/a\\b", false},
+		{"http://example.com/a\nb", false},
+	}
+	for _, entry := range table {
+		if err := ValidateIRI(entry.iri); (err == nil) != entry.v {
+			t.Errorf("node.ValidateIRI(%q) returned error %v; want valid %v", entry.iri, err, entry.v)
+		}
+	}
+}
+
+func TestIRI(t *testing.T) {
+	n, err := NewIRI("http://xmlns.com/foaf/0.1/Person")
+	if err != nil {
+		t.Fatalf("node.NewIRI failed with error %v", err)
+	}
+	if !n.IsIRI() {
+		t.Errorf("node.NewIRI returned %s; it should be an IRI node", n)
+	}
+	if got, err := n.IRI(); err != nil || got != "http://xmlns.com/foaf/0.1/Person" {
+		t.Errorf("n.IRI returned %q, %v; want http://xmlns.com/foaf/0.1/Person", got, err)
+	}
+	if got, want := n.String(), "/iri<http://xmlns.com/foaf/0.1/Person>"; got != want {
+		t.Errorf("n.String returned %q; want %q", got, want)
+	}
+	pn, err := Parse(n.String())
+	if err != nil || pn.UUID().String() != n.UUID().String() {
+		t.Errorf("node.Parse(%q) returned %v, %v; want %v", n.String(), pn, err, n)
+	}
+	if _, err := NewIRI("foaf:Person bar"); err == nil {
+		t.Errorf("node.NewIRI should fail for an IRI with spaces")
+	}
+
+	o, err := Parse("/foaf/Person<john>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.IsIRI() {
+		t.Errorf("%s should not be an IRI node", o)
+	}
+	if _, err := o.IRI(); err == nil {
+		t.Errorf("o.IRI should fail for %s", o)
+	}
+}
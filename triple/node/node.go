@@ -30,6 +30,7 @@ import (
 const (
 	slash      = byte('/')
 	underscore = byte('_')
+	lt         = byte('<')
 )
 
 // Type describes the type of the node.
@@ -79,7 +80,8 @@ func (n *Node) String() string {
 	return fmt.Sprintf("%s<%s>", n.t.String(), n.id.String())
 }
 
-// Parse returns a node given a pretty printed representation of a Node or a BlankNode.
+// Parse returns a node given a pretty printed representation of a Node or a
+// BlankNode. IRI nodes can also be written as <iri>.
 func Parse(s string) (*Node, error) {
 	raw := strings.TrimSpace(s)
 	switch raw[0] {
@@ -99,7 +101,22 @@ func Parse(s string) (*Node, error) {
 		if err != nil {
 			return nil, fmt.Errorf("node.Parse: invalid ID in %q, %v", raw, err)
 		}
+		if t.String() == IRIType {
+			if err := ValidateIRI(id.String()); err != nil {
+				return nil, fmt.Errorf("node.Parse: invalid IRI in %q, %v", raw, err)
+			}
+		}
 		return NewNode(t, id), nil
+	case lt:
+		// IRIs can be written on their own as <iri>.
+		if raw[len(raw)-1] != '>' {
+			return nil, fmt.Errorf("node.Parse: IRIs should finish with '>' in %q", raw)
+		}
+		n, err := NewIRI(raw[1 : len(raw)-1])
+		if err != nil {
+			return nil, fmt.Errorf("node.Parse: invalid IRI in %q, %v", raw, err)
+		}
+		return n, nil
 	case underscore:
 		id, err := NewID(raw[2:len(raw)])
 		if err != nil {
@@ -108,7 +125,7 @@ func Parse(s string) (*Node, error) {
 		t, _ := NewType("/_")
 		return NewNode(t, id), nil
 	default:
-		return nil, fmt.Errorf("node.Parse: node representation should start with '/' or '_' in %v", raw)
+		return nil, fmt.Errorf("node.Parse: node representation should start with '/', '_', or '<' in %v", raw)
 	}
 }
 
@@ -122,7 +122,31 @@ func TestParse(t *testing.T) {
 			id: "v1",
 			v:  true,
 		},
+		{
+			s:  "/iri<http://xmlns.com/foaf/0.1/Person>",
+			t:  "/iri",
+			id: "http://xmlns.com/foaf/0.1/Person",
+			v:  true,
+		},
+		{
+			s:  "<urn:isbn:0451450523>",
+			t:  "/iri",
+			id: "urn:isbn:0451450523",
+			v:  true,
+		},
 		// Invalid text nodes.
+		{
+			s:  "/iri<foaf_Person>",
+			t:  "",
+			id: "",
+			v:  false,
+		},
+		{
+			s:  "<http://example.com/a b>",
+			t:  "",
+			id: "",
+			v:  false,
+		},
 		{
 			s:  "/foo<123",
 			t:  "",
@@ -0,0 +1,658 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triple
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+const (
+	// NodeIRIPrefix prefixes the IRIs standing for the nodes that are not IRI
+	// nodes in N-Triples. The prefix is followed by the escaped node type, a #,
+	// and the escaped node ID, as in urn:badwolf:node:/user#joe.
+	NodeIRIPrefix = "urn:badwolf:node:"
+
+	// PredicateIRIPrefix prefixes the IRIs standing for predicates in
+	// N-Triples when their ID is not an IRI, when they are temporal, or when
+	// they are the object of a triple. The prefix is followed by the escaped
+	// predicate ID and, for temporal predicates, an @ and the time anchor, as
+	// in urn:badwolf:predicate:met@2016-04-10T04:21:00Z. The anchor of
+	// interval predicates is the start and the end of their interval separated
+	// by a /.
+	PredicateIRIPrefix = "urn:badwolf:predicate:"
+
+	// TypeIRIPrefix prefixes the datatype IRIs of the literals of custom types
+	// in N-Triples. The prefix is followed by the name of the type, as in
+	// urn:badwolf:type:point.
+	TypeIRIPrefix = "urn:badwolf:type:"
+
+	// XSD is the namespace of the XML schema datatypes used by the literals.
+	XSD = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// iriEscape percent-encodes all the characters of s but letters, digits, and
+// any of -._~/:+, so the result can be safely embedded into an IRI.
+func iriEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r < utf8.RuneSelf && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-._~/:+", r)):
+			b.WriteRune(r)
+		case r >= utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		default:
+			var buf [utf8.UTFMax]byte
+			n := utf8.EncodeRune(buf[:], r)
+			for _, c := range buf[:n] {
+				fmt.Fprintf(&b, "%%%02X", c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// isBlankLabel returns true if the provided ID can be written as the label of
+// an N-Triples blank node. Labels are formed by letters, digits, underscores,
+// and dashes, but cannot start with a dash.
+func isBlankLabel(id string) bool {
+	for i, r := range id {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && (r != '-' || i == 0) {
+			return false
+		}
+	}
+	return id != ""
+}
+
+// NodeTerm returns the N-Triples term of the provided node, either an IRI
+// or a blank node.
+func NodeTerm(n *node.Node) string {
+	if iri, err := n.IRI(); err == nil && !strings.HasPrefix(iri, NodeIRIPrefix) && !strings.HasPrefix(iri, PredicateIRIPrefix) {
+		return "<" + iri + ">"
+	}
+	if n.Type().String() == "/_" && isBlankLabel(n.ID().String()) {
+		return "_:" + n.ID().String()
+	}
+	return "<" + NodeIRIPrefix + iriEscape(n.Type().String()) + "#" + iriEscape(n.ID().String()) + ">"
+}
+
+// PredicateTerm returns the N-Triples IRI of the provided predicate. IDs
+// which are IRIs of immutable predicates are used as they are unless the
+// predicate is the object of a triple.
+func PredicateTerm(p *predicate.Predicate, object bool) string {
+	id := string(p.ID())
+	ta, err := p.TimeAnchor()
+	if err != nil {
+		if !object && node.ValidateIRI(id) == nil && !strings.HasPrefix(id, NodeIRIPrefix) && !strings.HasPrefix(id, PredicateIRIPrefix) {
+			return "<" + id + ">"
+		}
+		return "<" + PredicateIRIPrefix + iriEscape(id) + ">"
+	}
+	a := iriEscape(ta.Format(time.RFC3339Nano))
+	if _, end, err := p.Interval(); err == nil {
+		a += predicate.IntervalSeparator + iriEscape(end.Format(time.RFC3339Nano))
+	}
+	return "<" + PredicateIRIPrefix + iriEscape(id) + "@" + a + ">"
+}
+
+// lexical returns the lexical form of the value of the provided literal.
+func lexical(l *literal.Literal) string {
+	s := l.String()
+	return s[1:strings.LastIndex(s, "\"^^type:")]
+}
+
+// LiteralValue returns the lexical form and the datatype IRI of the RDF
+// literal standing for the provided literal. Text literals have no datatype.
+func LiteralValue(l *literal.Literal) (string, string, error) {
+	var v, dt string
+	switch l.Type() {
+	case literal.Text:
+		t, _ := l.Text()
+		return t, "", nil
+	case literal.Bool:
+		v, dt = lexical(l), "boolean"
+	case literal.Int64:
+		v, dt = lexical(l), "integer"
+	case literal.Float64:
+		f, _ := l.Float64()
+		switch {
+		case math.IsInf(f, 1):
+			v = "INF"
+		case math.IsInf(f, -1):
+			v = "-INF"
+		case math.IsNaN(f):
+			v = "NaN"
+		default:
+			v = strconv.FormatFloat(f, 'E', -1, 64)
+		}
+		dt = "double"
+	case literal.Decimal:
+		v, dt = lexical(l), "decimal"
+	case literal.Date:
+		v, dt = lexical(l), "date"
+	case literal.Blob:
+		bs, _ := l.Blob()
+		v, dt = base64.StdEncoding.EncodeToString(bs), "base64Binary"
+	default:
+		if name, text, err := l.Custom(); err == nil {
+			return text, TypeIRIPrefix + iriEscape(name), nil
+		}
+		return "", "", fmt.Errorf("triple.ToNTriple cannot serialize literals of type %v", l.Type())
+	}
+	return v, XSD + dt, nil
+}
+
+// literalTerm returns the N-Triples term of the provided literal.
+func literalTerm(l *literal.Literal) (string, error) {
+	v, dt, err := LiteralValue(l)
+	if err != nil {
+		return "", err
+	}
+	if dt == "" {
+		return quoteNTriples(v), nil
+	}
+	return quoteNTriples(v) + "^^<" + dt + ">", nil
+}
+
+// quoteNTriples returns the N-Triples string for s using the escaping of
+// canonical N-Triples.
+func quoteNTriples(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// ToNTriple returns the triple as a canonical N-Triples line, without the
+// final new line, so it can be read by any RDF tool.
+//
+// IRI nodes are written as their IRI and blank nodes as blank nodes. Any other
+// node, and any predicate that is temporal or whose ID is not an IRI, is written
+// as an IRI starting with NodeIRIPrefix or PredicateIRIPrefix, so
+// ParseNTriple can recover it. Literals are written using the XML schema
+// datatype matching their type, and text literals as plain strings.
+func (t *Triple) ToNTriple() (string, error) {
+	s, p, o, err := t.NTriplesTerms()
+	if err != nil {
+		return "", err
+	}
+	return s + " " + p + " " + o + " .", nil
+}
+
+// NTriplesTerms returns the N-Triples terms of the subject, the predicate, and
+// the object of the triple, as written by ToNTriple. They are also valid
+// Turtle terms.
+func (t *Triple) NTriplesTerms() (string, string, string, error) {
+	var o string
+	switch {
+	case t.o.n != nil:
+		o = NodeTerm(t.o.n)
+	case t.o.p != nil:
+		o = PredicateTerm(t.o.p, true)
+	case t.o.l != nil:
+		lt, err := literalTerm(t.o.l)
+		if err != nil {
+			return "", "", "", err
+		}
+		o = lt
+	default:
+		return "", "", "", fmt.Errorf("triple.ToNTriple cannot serialize invalid object in %s", t)
+	}
+	return NodeTerm(t.s), PredicateTerm(t.p, false), o, nil
+}
+
+// ntScanner scans the terms of an N-Triples line.
+type ntScanner struct {
+	line string
+	pos  int
+}
+
+// skipSpace skips the spaces and tabs at the current position.
+func (s *ntScanner) skipSpace() {
+	for s.pos < len(s.line) && (s.line[s.pos] == ' ' || s.line[s.pos] == '\t') {
+		s.pos++
+	}
+}
+
+// errorf returns an error locating the current position of the line.
+func (s *ntScanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("triple.ParseNTriple: %s at column %d of %q", fmt.Sprintf(format, args...), s.pos+1, s.line)
+}
+
+// unescape reads the escape sequence at the current position, right after the
+// backslash. Escaped characters are only allowed in strings.
+func (s *ntScanner) unescape(str bool) (rune, error) {
+	if s.pos >= len(s.line) {
+		return 0, s.errorf("unterminated escape sequence")
+	}
+	c := s.line[s.pos]
+	s.pos++
+	n := 0
+	switch c {
+	case 'u':
+		n = 4
+	case 'U':
+		n = 8
+	default:
+		if !str {
+			return 0, s.errorf("invalid escape sequence \\%c in IRI", c)
+		}
+		if i := strings.IndexByte(`tbnrf"'\`, c); i >= 0 {
+			return rune("\t\b\n\r\f\"'\\"[i]), nil
+		}
+		return 0, s.errorf("invalid escape sequence \\%c", c)
+	}
+	if s.pos+n > len(s.line) {
+		return 0, s.errorf("unterminated escape sequence")
+	}
+	v, err := strconv.ParseUint(s.line[s.pos:s.pos+n], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(v)) {
+		return 0, s.errorf("invalid escape sequence \\%c%s", c, s.line[s.pos:s.pos+n])
+	}
+	s.pos += n
+	return rune(v), nil
+}
+
+// iri reads the IRI enclosed between < and > at the current position.
+func (s *ntScanner) iri() (string, error) {
+	s.pos++
+	var b strings.Builder
+	for s.pos < len(s.line) {
+		c := s.line[s.pos]
+		s.pos++
+		switch c {
+		case '>':
+			return b.String(), nil
+		case '\\':
+			r, err := s.unescape(false)
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", s.errorf("IRI is not terminated with >")
+}
+
+// blank reads the label of the blank node at the current position.
+func (s *ntScanner) blank() (string, error) {
+	s.pos += 2
+	start := s.pos
+	for s.pos < len(s.line) && s.line[s.pos] != ' ' && s.line[s.pos] != '\t' {
+		s.pos++
+	}
+	// Labels cannot end with a dot, so it is the end of the triple.
+	for s.pos > start && s.line[s.pos-1] == '.' {
+		s.pos--
+	}
+	if s.pos == start {
+		return "", s.errorf("empty blank node label")
+	}
+	return s.line[start:s.pos], nil
+}
+
+// node reads the subject or object node at the current position. Objects can
+// also be predicates.
+func (s *ntScanner) node(object bool) (*node.Node, *predicate.Predicate, error) {
+	if strings.HasPrefix(s.line[s.pos:], "_:") {
+		l, err := s.blank()
+		if err != nil {
+			return nil, nil, err
+		}
+		n, err := node.NewNodeFromStrings("/_", l)
+		return n, nil, err
+	}
+	if s.pos >= len(s.line) || s.line[s.pos] != '<' {
+		return nil, nil, s.errorf("expected an IRI or a blank node")
+	}
+	iri, err := s.iri()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case strings.HasPrefix(iri, NodeIRIPrefix):
+		parts := strings.Split(iri[len(NodeIRIPrefix):], "#")
+		if len(parts) != 2 {
+			return nil, nil, s.errorf("invalid node IRI %q", iri)
+		}
+		t, err := url.PathUnescape(parts[0])
+		if err != nil {
+			return nil, nil, s.errorf("invalid node type in %q; %v", iri, err)
+		}
+		id, err := url.PathUnescape(parts[1])
+		if err != nil {
+			return nil, nil, s.errorf("invalid node ID in %q; %v", iri, err)
+		}
+		n, err := node.NewNodeFromStrings(t, id)
+		return n, nil, err
+	case strings.HasPrefix(iri, PredicateIRIPrefix):
+		if !object {
+			return nil, nil, s.errorf("predicate %q cannot be the subject of a triple", iri)
+		}
+		p, err := parsePredicateIRI(iri)
+		return nil, p, err
+	}
+	n, err := node.NewIRI(iri)
+	return n, nil, err
+}
+
+// parsePredicateIRI returns the predicate for the provided IRI.
+func parsePredicateIRI(iri string) (*predicate.Predicate, error) {
+	if !strings.HasPrefix(iri, PredicateIRIPrefix) {
+		return predicate.NewImmutable(iri)
+	}
+	rest := iri[len(PredicateIRIPrefix):]
+	i := strings.LastIndex(rest, "@")
+	eid := rest
+	if i >= 0 {
+		eid = rest[:i]
+	}
+	id, err := url.PathUnescape(eid)
+	if err != nil {
+		return nil, fmt.Errorf("triple.ParseNTriple: invalid predicate ID in %q; %v", iri, err)
+	}
+	if i < 0 {
+		return predicate.NewImmutable(id)
+	}
+	a, err := url.PathUnescape(rest[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("triple.ParseNTriple: invalid time anchor in %q; %v", iri, err)
+	}
+	var end string
+	if j := strings.Index(a, predicate.IntervalSeparator); j >= 0 {
+		a, end = a[:j], a[j+len(predicate.IntervalSeparator):]
+	}
+	ta, err := time.Parse(time.RFC3339Nano, a)
+	if err != nil {
+		return nil, fmt.Errorf("triple.ParseNTriple: invalid time anchor in %q; %v", iri, err)
+	}
+	if end == "" {
+		return predicate.NewTemporal(id, ta)
+	}
+	te, err := time.Parse(time.RFC3339Nano, end)
+	if err != nil {
+		return nil, fmt.Errorf("triple.ParseNTriple: invalid interval end in %q; %v", iri, err)
+	}
+	return predicate.NewInterval(id, ta, te)
+}
+
+// literal reads the literal at the current position.
+func (s *ntScanner) literal(b literal.Builder) (*literal.Literal, error) {
+	s.pos++
+	var v strings.Builder
+	for {
+		if s.pos >= len(s.line) {
+			return nil, s.errorf("string is not terminated with \"")
+		}
+		c := s.line[s.pos]
+		s.pos++
+		if c == '"' {
+			break
+		}
+		if c == '\\' {
+			r, err := s.unescape(true)
+			if err != nil {
+				return nil, err
+			}
+			v.WriteRune(r)
+			continue
+		}
+		v.WriteByte(c)
+	}
+	dt := XSD + "string"
+	switch {
+	case strings.HasPrefix(s.line[s.pos:], "^^<"):
+		s.pos += 2
+		iri, err := s.iri()
+		if err != nil {
+			return nil, err
+		}
+		dt = iri
+	case strings.HasPrefix(s.line[s.pos:], "@"):
+		// Language tags are dropped.
+		for s.pos < len(s.line) && s.line[s.pos] != ' ' && s.line[s.pos] != '\t' && s.line[s.pos] != '.' {
+			s.pos++
+		}
+	}
+	l, err := buildLiteral(b, v.String(), dt)
+	if err != nil {
+		return nil, s.errorf("%v", err)
+	}
+	return l, nil
+}
+
+// buildLiteral returns the literal for the provided lexical form and datatype.
+func buildLiteral(b literal.Builder, v, dt string) (*literal.Literal, error) {
+	if strings.HasPrefix(dt, TypeIRIPrefix) {
+		if t, ok := literal.TypeByName(dt[len(TypeIRIPrefix):]); ok && t.IsCustom() {
+			return literal.ParseCustom(dt[len(TypeIRIPrefix):], v)
+		}
+	}
+	switch strings.TrimPrefix(dt, XSD) {
+	case "boolean":
+		switch v {
+		case "true", "1":
+			return b.Build(literal.Bool, true)
+		case "false", "0":
+			return b.Build(literal.Bool, false)
+		}
+		return nil, fmt.Errorf("invalid boolean %q", v)
+	case "integer", "long", "int", "short", "byte", "nonNegativeInteger", "nonPositiveInteger", "positiveInteger", "negativeInteger", "unsignedLong", "unsignedInt", "unsignedShort", "unsignedByte":
+		i, err := strconv.ParseInt(strings.TrimPrefix(v, "+"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q; %v", dt, v, err)
+		}
+		return b.Build(literal.Int64, i)
+	case "double", "float":
+		f, err := strconv.ParseFloat(v, 64)
+		if ne, ok := err.(*strconv.NumError); err != nil && (!ok || ne.Err != strconv.ErrRange) {
+			return nil, fmt.Errorf("invalid %s %q; %v", dt, v, err)
+		}
+		return b.Build(literal.Float64, f)
+	case "decimal":
+		if strings.ContainsAny(v, "/eE") {
+			return nil, fmt.Errorf("invalid decimal %q", v)
+		}
+		r, ok := new(big.Rat).SetString(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid decimal %q", v)
+		}
+		return b.Build(literal.Decimal, r)
+	case "date":
+		d, err := time.Parse(literal.DateLayout, v)
+		if err != nil {
+			if d, err = time.Parse(literal.DateLayout+"Z07:00", v); err != nil {
+				return nil, fmt.Errorf("invalid date %q; %v", v, err)
+			}
+		}
+		return b.Build(literal.Date, d)
+	case "base64Binary":
+		bs, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64Binary %q; %v", v, err)
+		}
+		return b.Build(literal.Blob, bs)
+	}
+	// Strings and the datatypes without a matching literal type are kept as
+	// text.
+	return b.Build(literal.Text, v)
+}
+
+// object reads the object at the current position.
+func (s *ntScanner) object(b literal.Builder) (*Object, error) {
+	if s.pos < len(s.line) && s.line[s.pos] == '"' {
+		l, err := s.literal(b)
+		if err != nil {
+			return nil, err
+		}
+		return NewLiteralObject(l), nil
+	}
+	n, p, err := s.node(true)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		return NewPredicateObject(p), nil
+	}
+	return NewNodeObject(n), nil
+}
+
+// ParseObjectTerm parses the provided N-Triples term, an IRI, a blank node, or
+// a literal, into the object it stands for, as done for the objects of the
+// lines parsed by ParseNTriple.
+func ParseObjectTerm(term string, b literal.Builder) (*Object, error) {
+	s := &ntScanner{line: term}
+	s.skipSpace()
+	o, err := s.object(b)
+	if err != nil {
+		return nil, err
+	}
+	s.skipSpace()
+	if s.pos < len(s.line) {
+		return nil, s.errorf("unexpected content after the end of the term")
+	}
+	return o, nil
+}
+
+// ParsePredicateTerm parses the provided N-Triples IRI into the predicate it
+// stands for, as done for the predicates of the lines parsed by ParseNTriple.
+func ParsePredicateTerm(term string) (*predicate.Predicate, error) {
+	s := &ntScanner{line: term}
+	s.skipSpace()
+	if s.pos >= len(s.line) || s.line[s.pos] != '<' {
+		return nil, s.errorf("expected a predicate IRI")
+	}
+	iri, err := s.iri()
+	if err != nil {
+		return nil, err
+	}
+	s.skipSpace()
+	if s.pos < len(s.line) {
+		return nil, s.errorf("unexpected content after the end of the term")
+	}
+	return parsePredicateIRI(iri)
+}
+
+// ParseNTriple parses the provided N-Triples line into a triple. It assumes
+// that the line contains one triple, like the ones written by ToNTriple, and
+// it reverses the mapping done by ToNTriple. Strings with a language tag and
+// literals with a datatype that has no matching literal type are parsed as
+// text literals. The nodes and predicates of the returned triple are
+// interned.
+func ParseNTriple(line string, b literal.Builder) (*Triple, error) {
+	t, _, err := parseStatement(line, b, false)
+	return t, err
+}
+
+// ParseNQuad parses the provided N-Quads line into a triple and the name of its
+// graph. Triples are parsed as done by ParseNTriple. The graph name is the IRI
+// of the graph, or the _: prefixed label for graphs named by blank nodes, and it
+// is empty if the line has no graph name.
+func ParseNQuad(line string, b literal.Builder) (*Triple, string, error) {
+	return parseStatement(line, b, true)
+}
+
+// parseStatement parses the provided N-Triples, or N-Quads, line.
+func parseStatement(line string, b literal.Builder, quad bool) (*Triple, string, error) {
+	s := &ntScanner{line: line}
+	s.skipSpace()
+	sn, _, err := s.node(false)
+	if err != nil {
+		return nil, "", err
+	}
+	s.skipSpace()
+	if s.pos >= len(s.line) || s.line[s.pos] != '<' {
+		return nil, "", s.errorf("expected a predicate IRI")
+	}
+	piri, err := s.iri()
+	if err != nil {
+		return nil, "", err
+	}
+	p, err := parsePredicateIRI(piri)
+	if err != nil {
+		return nil, "", err
+	}
+	s.skipSpace()
+	o, err := s.object(b)
+	if err != nil {
+		return nil, "", err
+	}
+	s.skipSpace()
+	var g string
+	if quad && s.pos < len(s.line) && s.line[s.pos] == '<' {
+		if g, err = s.iri(); err != nil {
+			return nil, "", err
+		}
+		s.skipSpace()
+	} else if quad && strings.HasPrefix(s.line[s.pos:], "_:") {
+		l, err := s.blank()
+		if err != nil {
+			return nil, "", err
+		}
+		g = "_:" + l
+		s.skipSpace()
+	}
+	if s.pos >= len(s.line) || s.line[s.pos] != '.' {
+		return nil, "", s.errorf("expected . at the end of the triple")
+	}
+	s.pos++
+	s.skipSpace()
+	if rest := strings.TrimRight(s.line[s.pos:], "\r\n"); rest != "" && rest[0] != '#' {
+		return nil, "", s.errorf("unexpected content after the end of the triple")
+	}
+	t, err := New(sn, p, o)
+	if err != nil {
+		return nil, "", err
+	}
+	return Intern(t), g, nil
+}
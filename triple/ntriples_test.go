@@ -0,0 +1,224 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triple
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/badwolf/triple/literal"
+)
+
+func init() {
+	// A custom literal type for unsigned integers written in hexadecimal.
+	_, err := literal.Register(literal.CustomType{
+		Name: "hex",
+		Parse: func(s string) (interface{}, error) {
+			return strconv.ParseUint(s, 16, 64)
+		},
+		Format: func(v interface{}) string {
+			return strconv.FormatUint(v.(uint64), 16)
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestToNTriple(t *testing.T) {
+	table := []struct {
+		t  string
+		nt string
+	}{
+		{`/iri<http://example.com/john> "http://xmlns.com/foaf/0.1/knows"@[] /iri<http://example.com/mary>`,
+			`<http://example.com/john> <http://xmlns.com/foaf/0.1/knows> <http://example.com/mary> .`},
+		{`/u<john> "knows"@[] /_<b1>`,
+			`<urn:badwolf:node:/u#john> <urn:badwolf:predicate:knows> _:b1 .`},
+		{`/u<john smith> "met"@[2016-04-10T04:21:00.000000000Z] /u<mary#1>`,
+			`<urn:badwolf:node:/u#john%20smith> <urn:badwolf:predicate:met@2016-04-10T04:21:00Z> <urn:badwolf:node:/u#mary%231> .`},
+		{`/u<john> "employed_by"@[2016-01-01T00:00:00Z/2017-01-01T00:00:00Z] /org<acme>`,
+			`<urn:badwolf:node:/u#john> <urn:badwolf:predicate:employed_by@2016-01-01T00:00:00Z/2017-01-01T00:00:00Z> <urn:badwolf:node:/org#acme> .`},
+		{`/u<john> "says"@[] "a "quoted"\ line` + "\n\t" + `"^^type:text`,
+			`<urn:badwolf:node:/u#john> <urn:badwolf:predicate:says> "a \"quoted\"\\ line\n\t" .`},
+		{`/u<john> "age"@[] "42"^^type:int64`,
+			`<urn:badwolf:node:/u#john> <urn:badwolf:predicate:age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .`},
+		{`/u<john> "height"@[] "1.85"^^type:float64`,
+			`<urn:badwolf:node:/u#john> <urn:badwolf:predicate:height> "1.85E+00"^^<http://www.w3.org/2001/XMLSchema#double> .`},
+		{`/u<john> "alive"@[] "true"^^type:bool`,
+			`<urn:badwolf:node:/u#john> <urn:badwolf:predicate:alive> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .`},
+		{`/u<john> "balance"@[] "-12.50"^^type:decimal`,
+			`<urn:badwolf:node:/u#john> <urn:badwolf:predicate:balance> "-12.5"^^<http://www.w3.org/2001/XMLSchema#decimal> .`},
+		{`/u<john> "born"@[] "1980-05-17"^^type:date`,
+			`<urn:badwolf:node:/u#john> <urn:badwolf:predicate:born> "1980-05-17"^^<http://www.w3.org/2001/XMLSchema#date> .`},
+		{`/u<john> "photo"@[] "[1 2 3]"^^type:blob`,
+			`<urn:badwolf:node:/u#john> <urn:badwolf:predicate:photo> "AQID"^^<http://www.w3.org/2001/XMLSchema#base64Binary> .`},
+		{`/u<john> "badge"@[] "beef"^^type:hex`,
+			`<urn:badwolf:node:/u#john> <urn:badwolf:predicate:badge> "beef"^^<urn:badwolf:type:hex> .`},
+		{`/_<b1> "_predicate"@[] "http://xmlns.com/foaf/0.1/knows"@[]`,
+			`_:b1 <urn:badwolf:predicate:_predicate> <urn:badwolf:predicate:http://xmlns.com/foaf/0.1/knows> .`},
+		{`/iri<urn:badwolf:node:/u#john> "knows"@[] /_<-b1>`,
+			`<urn:badwolf:node:/iri#urn:badwolf:node:/u%23john> <urn:badwolf:predicate:knows> <urn:badwolf:node:/_#-b1> .`},
+	}
+	for _, entry := range table {
+		trpl, err := Parse(entry.t, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse(%q) failed with error %v", entry.t, err)
+		}
+		got, err := trpl.ToNTriple()
+		if err != nil {
+			t.Errorf("%s.ToNTriple failed with error %v", trpl, err)
+			continue
+		}
+		if got != entry.nt {
+			t.Errorf("%s.ToNTriple returned\n%s\nwant\n%s", trpl, got, entry.nt)
+		}
+		rt, err := ParseNTriple(got, literal.DefaultBuilder())
+		if err != nil {
+			t.Errorf("triple.ParseNTriple(%q) failed with error %v", got, err)
+			continue
+		}
+		if !rt.Equal(trpl) {
+			t.Errorf("triple.ParseNTriple(%q) returned %s; want %s", got, rt, trpl)
+		}
+	}
+}
+
+func TestParseNTriple(t *testing.T) {
+	table := []struct {
+		nt string
+		t  string
+	}{
+		{`<http://example.com/john>	<http://xmlns.com/foaf/0.1/name>	"John"@en .  # a comment`,
+			`/iri<http://example.com/john>	"http://xmlns.com/foaf/0.1/name"@[]	"John"^^type:text`},
+		{`_:john <http://example.com/p> "café \U0001F600"^^<http://www.w3.org/2001/XMLSchema#string>.`,
+			`/_<john>	"http://example.com/p"@[]	"café 😀"^^type:text`},
+		{`_:john <http://example.com/p> "2016-04-10T04:21:00Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .`,
+			`/_<john>	"http://example.com/p"@[]	"2016-04-10T04:21:00Z"^^type:text`},
+		{`_:john <http://example.com/p> "7"^^<http://www.w3.org/2001/XMLSchema#int> .`,
+			`/_<john>	"http://example.com/p"@[]	"7"^^type:int64`},
+		{`_:john <http://example.com/p> "INF"^^<http://www.w3.org/2001/XMLSchema#double> .`,
+			`/_<john>	"http://example.com/p"@[]	"+Inf"^^type:float64`},
+		{`_:john <http://example.com/p> "ff"^^<urn:badwolf:type:unknown> .`,
+			`/_<john>	"http://example.com/p"@[]	"ff"^^type:text`},
+		{`_:john <http://example.com/p> _:mary.`,
+			`/_<john>	"http://example.com/p"@[]	/_<mary>`},
+		{`<http://example.com/a b> <http://example.com/p> _:mary .`, ""},
+		{`<http://example.com/john> <http://example.com/p> "7"^^<http://www.w3.org/2001/XMLSchema#integer>`, ""},
+		{`<http://example.com/john> <http://example.com/p> "seven"^^<http://www.w3.org/2001/XMLSchema#integer> .`, ""},
+		{`<http://example.com/john> <http://example.com/p> "unterminated .`, ""},
+		{`<http://example.com/john> "p" _:mary .`, ""},
+		{`<http://example.com/john> <http://example.com/p> "xyz"^^<urn:badwolf:type:hex> .`, ""},
+		{`<urn:badwolf:predicate:knows> <http://example.com/p> _:mary .`, ""},
+		{`<http://example.com/john> <http://example.com/p> _:mary . _:extra`, ""},
+		{`<http://example.com/john> <http://example.com/p> "bad \q escape" .`, ""},
+	}
+	for _, entry := range table {
+		got, err := ParseNTriple(entry.nt, literal.DefaultBuilder())
+		if entry.t == "" {
+			if err == nil {
+				t.Errorf("triple.ParseNTriple(%q) should have failed; got %s", entry.nt, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("triple.ParseNTriple(%q) failed with error %v", entry.nt, err)
+			continue
+		}
+		want, err := Parse(entry.t, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse(%q) failed with error %v", entry.t, err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("triple.ParseNTriple(%q) returned %s; want %s", entry.nt, got, want)
+		}
+	}
+}
+
+func TestParseNQuad(t *testing.T) {
+	table := []struct {
+		nq string
+		t  string
+		g  string
+	}{
+		{`<http://example.com/john> <http://example.com/p> _:mary <http://example.com/g> .`,
+			`/iri<http://example.com/john>	"http://example.com/p"@[]	/_<mary>`, "http://example.com/g"},
+		{`<http://example.com/john> <http://example.com/p> "7"^^<http://www.w3.org/2001/XMLSchema#int> _:g.`,
+			`/iri<http://example.com/john>	"http://example.com/p"@[]	"7"^^type:int64`, "_:g"},
+		{`<http://example.com/john> <http://example.com/p> <http://example.com/mary> .`,
+			`/iri<http://example.com/john>	"http://example.com/p"@[]	/iri<http://example.com/mary>`, ""},
+		{`<http://example.com/john> <http://example.com/p> _:mary "g" .`, "", ""},
+		{`<http://example.com/john> <http://example.com/p> _:mary <http://example.com/g> <http://example.com/h> .`, "", ""},
+	}
+	for _, entry := range table {
+		got, g, err := ParseNQuad(entry.nq, literal.DefaultBuilder())
+		if entry.t == "" {
+			if err == nil {
+				t.Errorf("triple.ParseNQuad(%q) should have failed; got %s", entry.nq, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("triple.ParseNQuad(%q) failed with error %v", entry.nq, err)
+			continue
+		}
+		want, err := Parse(entry.t, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse(%q) failed with error %v", entry.t, err)
+		}
+		if !got.Equal(want) || g != entry.g {
+			t.Errorf("triple.ParseNQuad(%q) returned %s in graph %q; want %s in graph %q", entry.nq, got, g, want, entry.g)
+		}
+	}
+}
+
+func TestParseTerms(t *testing.T) {
+	table := []struct {
+		term string
+		obj  string
+		pred string
+	}{
+		{`<http://example.com/p>`, `/iri<http://example.com/p>`, `"http://example.com/p"@[]`},
+		{`<urn:badwolf:node:/u#joe>`, `/u<joe>`, `"urn:badwolf:node:/u#joe"@[]`},
+		{`<urn:badwolf:predicate:met@2016-04-10T04:21:00Z>`, `"met"@[2016-04-10T04:21:00Z]`, `"met"@[2016-04-10T04:21:00Z]`},
+		{`_:mary`, `/_<mary>`, ""},
+		{`"7"^^<http://www.w3.org/2001/XMLSchema#int>`, `"7"^^type:int64`, ""},
+		{` "café"@fr `, `"café"^^type:text`, ""},
+		{`<http://example.com/p> .`, "", ""},
+		{`"unterminated`, "", ""},
+	}
+	for _, entry := range table {
+		o, err := ParseObjectTerm(entry.term, literal.DefaultBuilder())
+		if entry.obj == "" {
+			if err == nil {
+				t.Errorf("triple.ParseObjectTerm(%q) should have failed; got %s", entry.term, o)
+			}
+		} else if err != nil {
+			t.Errorf("triple.ParseObjectTerm(%q) failed with error %v", entry.term, err)
+		} else if got := o.String(); got != entry.obj {
+			t.Errorf("triple.ParseObjectTerm(%q) returned %s; want %s", entry.term, got, entry.obj)
+		}
+
+		p, err := ParsePredicateTerm(entry.term)
+		if entry.pred == "" {
+			if err == nil {
+				t.Errorf("triple.ParsePredicateTerm(%q) should have failed; got %s", entry.term, p)
+			}
+		} else if err != nil {
+			t.Errorf("triple.ParsePredicateTerm(%q) failed with error %v", entry.term, err)
+		} else if got := p.String(); got != entry.pred {
+			t.Errorf("triple.ParsePredicateTerm(%q) returned %s; want %s", entry.term, got, entry.pred)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package predicate
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultPoolSize is the number of predicates kept by the pool used by
+// Intern.
+const DefaultPoolSize = 1 << 20
+
+// Pool interns predicates, so equal predicates share a single copy instead of
+// each keeping its own, and predicates with the same ID share the ID even if
+// they are anchored at different times. Pools are bounded: once a pool keeps
+// its maximum number of predicates, it forgets all of them and starts over.
+// Predicates returned before that remain valid; they are just not shared with
+// the ones interned afterwards. Pools are safe for concurrent use.
+type Pool struct {
+	mu    sync.RWMutex
+	max   int
+	preds map[predicateKey]*Predicate
+	ids   map[ID]ID
+}
+
+// predicateKey identifies a predicate in a pool. Anchors are compared as
+// values, so predicates anchored at the same instant in different locations
+// are kept apart, since they are printed differently.
+type predicateKey struct {
+	id                 ID
+	anchor, end        time.Time
+	temporal, interval bool
+}
+
+// NewPool returns an empty pool that keeps up to max predicates.
+func NewPool(max int) *Pool {
+	return &Pool{
+		max:   max,
+		preds: make(map[predicateKey]*Predicate),
+		ids:   make(map[ID]ID),
+	}
+}
+
+// Intern returns the predicate of the pool equal to the provided one, adding
+// it to the pool if needed.
+func (pl *Pool) Intern(p *Predicate) *Predicate {
+	if p == nil {
+		return nil
+	}
+	k := predicateKey{id: p.id}
+	if p.anchor != nil {
+		k.anchor, k.temporal = *p.anchor, true
+	}
+	if p.end != nil {
+		k.end, k.interval = *p.end, true
+	}
+	pl.mu.RLock()
+	c, ok := pl.preds[k]
+	pl.mu.RUnlock()
+	if ok {
+		return c
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if c, ok := pl.preds[k]; ok {
+		return c
+	}
+	if len(pl.preds) >= pl.max {
+		pl.preds = make(map[predicateKey]*Predicate)
+		pl.ids = make(map[ID]ID)
+	}
+	id, ok := pl.ids[p.id]
+	if !ok {
+		// The ID is copied, so it does not keep alive the larger string it
+		// may have been sliced out of.
+		id = ID(append([]byte(nil), p.id...))
+		pl.ids[id] = id
+	}
+	c = &Predicate{id: id, anchor: p.anchor, end: p.end}
+	k.id = id
+	pl.preds[k] = c
+	return c
+}
+
+// Len returns the number of predicates in the pool.
+func (pl *Pool) Len() int {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	return len(pl.preds)
+}
+
+// pool is the pool used by Intern.
+var pool = NewPool(DefaultPoolSize)
+
+// Intern returns the predicate equal to the provided one kept by a process
+// wide pool of DefaultPoolSize predicates, so the duplicate predicates
+// produced while importing triples or joining them share memory.
+func Intern(p *Predicate) *Predicate {
+	return pool.Intern(p)
+}
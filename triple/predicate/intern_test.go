@@ -0,0 +1,80 @@
+// Copyright 2015 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package predicate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPoolIntern(t *testing.T) {
+	pl := NewPool(8)
+	if pl.Intern(nil) != nil {
+		t.Errorf("pl.Intern(nil) should return nil")
+	}
+	ta := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	mustParse := func(s string) *Predicate {
+		p, err := Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	imm, tmp := mustParse(`"knows"@[]`), mustParse(`"knows"@[2016-01-01T00:00:00Z]`)
+	intv, err := NewInterval("knows", ta, ta.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := []*Predicate{imm, tmp, intv}
+	var got []*Predicate
+	for _, p := range ps {
+		got = append(got, pl.Intern(p))
+	}
+	for i, p := range ps {
+		if ip := pl.Intern(p); ip != got[i] {
+			t.Errorf("pl.Intern returned different predicates for %v", p)
+		}
+		if got[i].String() != p.String() {
+			t.Errorf("pl.Intern(%v) returned %v", p, got[i])
+		}
+		for j := range ps {
+			if i != j && got[i] == got[j] {
+				t.Errorf("pl.Intern returned the same predicate for %v and %v", ps[i], ps[j])
+			}
+		}
+	}
+	if got, want := pl.Len(), 3; got != want {
+		t.Errorf("pl.Len() = %d; want %d", got, want)
+	}
+	if pl.Intern(mustParse(`"knows"@[]`)) != got[0] {
+		t.Errorf("pl.Intern returned a different predicate for an equal immutable predicate")
+	}
+
+	// Full pools start over.
+	for i := 0; i < 6; i++ {
+		p, err := NewImmutable(fmt.Sprintf("p%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl.Intern(p)
+	}
+	if got, want := pl.Len(), 1; got != want {
+		t.Errorf("pl.Len() = %d after filling the pool; want %d", got, want)
+	}
+	if Intern(tmp) != Intern(mustParse(`"knows"@[2016-01-01T00:00:00Z]`)) {
+		t.Errorf("Intern returned different predicates for equal predicates")
+	}
+}
@@ -61,13 +61,23 @@ func (i *ID) String() string {
 type Predicate struct {
 	id     ID
 	anchor *time.Time
+	// end is the exclusive end of the validity interval of interval
+	// predicates, whose anchor is the start of the interval.
+	end *time.Time
 }
 
+// IntervalSeparator separates the start and the end of the validity interval
+// of interval predicates in their pretty printed form.
+const IntervalSeparator = "/"
+
 // String returns the pretty printed version of the predicate.
 func (p *Predicate) String() string {
 	if p.anchor == nil {
 		return fmt.Sprintf("%q@[]", p.id)
 	}
+	if p.end != nil {
+		return fmt.Sprintf("%q@[%s%s%s]", p.id, p.anchor.Format(time.RFC3339Nano), IntervalSeparator, p.end.Format(time.RFC3339Nano))
+	}
 	return fmt.Sprintf("%q@[%s]", p.id, p.anchor.Format(time.RFC3339Nano))
 }
 
@@ -101,6 +111,17 @@ func Parse(s string) (*Predicate, error) {
 	if ta[len(ta)-1] == '"' {
 		ta = ta[:len(ta)-1]
 	}
+	if i := strings.Index(ta, IntervalSeparator); i >= 0 {
+		start, err := time.Parse(time.RFC3339Nano, ta[:i])
+		if err != nil {
+			return nil, fmt.Errorf("predicate.Parse failed to parse interval start %s in %s with error %v", ta[:i], raw, err)
+		}
+		end, err := time.Parse(time.RFC3339Nano, ta[i+len(IntervalSeparator):])
+		if err != nil {
+			return nil, fmt.Errorf("predicate.Parse failed to parse interval end %s in %s with error %v", ta[i+len(IntervalSeparator):], raw, err)
+		}
+		return NewInterval(id, start, end)
+	}
 	pta, err := time.Parse(time.RFC3339Nano, ta)
 	if err != nil {
 		return nil, fmt.Errorf("predicate.Parse failed to parse time anchor %s in %s with error %v", ta, raw, err)
@@ -125,7 +146,8 @@ func (p *Predicate) Type() Type {
 }
 
 // TimeAnchor attempts to return the time anchor of a predicate if its type is
-// temporal.
+// temporal. The time anchor of interval predicates is the start of their
+// interval.
 func (p *Predicate) TimeAnchor() (*time.Time, error) {
 	if p.anchor == nil {
 		return nil, fmt.Errorf("predicate.TimeAnchor cannot return anchor for immutable predicate %v", p)
@@ -133,6 +155,63 @@ func (p *Predicate) TimeAnchor() (*time.Time, error) {
 	return p.anchor, nil
 }
 
+// IsInterval returns true if the predicate is valid during an interval of
+// time instead of at a single instant.
+func (p *Predicate) IsInterval() bool {
+	return p.end != nil
+}
+
+// Interval attempts to return the start and the exclusive end of the validity
+// interval of an interval predicate.
+func (p *Predicate) Interval() (*time.Time, *time.Time, error) {
+	if p.end == nil {
+		return nil, nil, fmt.Errorf("predicate.Interval cannot return interval for predicate %v", p)
+	}
+	return p.anchor, p.end, nil
+}
+
+// Overlaps returns true if the predicate is valid at some point of the
+// provided time window, whose bounds are inclusive. Nil bounds are not
+// checked. Immutable predicates are always valid, temporal ones only at their
+// time anchor, and interval ones from the start of their interval up to, but
+// not including, its end.
+func (p *Predicate) Overlaps(lower, upper *time.Time) bool {
+	if p.anchor == nil {
+		return true
+	}
+	if upper != nil && p.anchor.After(*upper) {
+		return false
+	}
+	if lower == nil {
+		return true
+	}
+	if p.end != nil {
+		return p.end.After(*lower)
+	}
+	return !p.anchor.Before(*lower)
+}
+
+// SameAnchor returns true if both predicates have the same time anchor and,
+// for interval predicates, the same interval. Their IDs are not compared.
+func (p *Predicate) SameAnchor(o *Predicate) bool {
+	eq := func(a, b *time.Time) bool {
+		return a == nil && b == nil || a != nil && b != nil && a.Equal(*b)
+	}
+	return eq(p.anchor, o.anchor) && eq(p.end, o.end)
+}
+
+// WithID returns a predicate with the provided ID and the same time anchor,
+// or interval, as the predicate.
+func (p *Predicate) WithID(id string) (*Predicate, error) {
+	switch {
+	case p.end != nil:
+		return NewInterval(id, *p.anchor, *p.end)
+	case p.anchor != nil:
+		return NewTemporal(id, *p.anchor)
+	}
+	return NewImmutable(id)
+}
+
 // NewImmutable creates a new immutable predicate.
 func NewImmutable(id string) (*Predicate, error) {
 	if id == "" {
@@ -154,6 +233,22 @@ func NewTemporal(id string, t time.Time) (*Predicate, error) {
 	}, nil
 }
 
+// NewInterval creates a new temporal predicate valid from start up to, but not
+// including, end.
+func NewInterval(id string, start, end time.Time) (*Predicate, error) {
+	if id == "" {
+		return nil, fmt.Errorf("predicate.NewInterval(%q, %v, %v) cannot create an interval predicate with empty ID", id, start, end)
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("predicate.NewInterval(%q, %v, %v) requires the start of the interval to be before its end", id, start, end)
+	}
+	return &Predicate{
+		id:     ID(id),
+		anchor: &start,
+		end:    &end,
+	}, nil
+}
+
 // UUID returns a global unique identifier for the given predicate. It is
 // implemented as the SHA1 UUID of the predicate values.
 func (p *Predicate) UUID() uuid.UUID {
@@ -166,6 +261,12 @@ func (p *Predicate) UUID() uuid.UUID {
 		b := make([]byte, 16)
 		binary.PutVarint(b, p.anchor.UnixNano())
 		buffer.Write(b)
+		if p.end != nil {
+			e := make([]byte, 16)
+			binary.PutVarint(e, p.end.UnixNano())
+			buffer.WriteString(IntervalSeparator)
+			buffer.Write(e)
+		}
 	}
 
 	return uuid.NewSHA1(uuid.NIL, buffer.Bytes())
@@ -164,3 +164,94 @@ func TestPartialUUID(t *testing.T) {
 		t.Errorf("predicates %v and %v should have identical partial UUID; got %q=%q", p1, p2, uuid1.String(), uuid2.String())
 	}
 }
+
+func TestInterval(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got, err := NewInterval("employed", end, start); err == nil {
+		t.Errorf("predicate.NewInterval should reject intervals ending before they start, but instead returned %v", got)
+	}
+	if got, err := NewInterval("employed", start, start); err == nil {
+		t.Errorf("predicate.NewInterval should reject empty intervals, but instead returned %v", got)
+	}
+	p, err := NewInterval("employed", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const pretty = "\"employed\"@[2016-01-01T00:00:00Z/2017-01-01T00:00:00Z]"
+	if got, want := p.String(), pretty; got != want {
+		t.Errorf("predicate.String() = %v; want %v", got, want)
+	}
+	pp, err := Parse(pretty)
+	if err != nil {
+		t.Fatalf("predicate.Parse failed to parse interval predicate %s with error %v", pretty, err)
+	}
+	if !pp.IsInterval() || pp.Type() != Temporal || !reflect.DeepEqual(pp.UUID(), p.UUID()) {
+		t.Errorf("predicate.Parse(%s) returned %v; want %v", pretty, pp, p)
+	}
+	gs, ge, err := pp.Interval()
+	if err != nil || !gs.Equal(start) || !ge.Equal(end) {
+		t.Errorf("predicate.Interval returned %v, %v, %v; want %v, %v", gs, ge, err, start, end)
+	}
+	if _, err := Parse("\"employed\"@[2017-01-01T00:00:00Z/2016-01-01T00:00:00Z]"); err == nil {
+		t.Errorf("predicate.Parse should reject intervals ending before they start")
+	}
+
+	instant, err := NewTemporal("employed", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instant.IsInterval() || p.SameAnchor(instant) || !p.SameAnchor(pp) {
+		t.Errorf("predicate.SameAnchor should only match predicates with the same interval")
+	}
+	if _, _, err := instant.Interval(); err == nil {
+		t.Errorf("predicate.Interval should fail for %v", instant)
+	}
+	if reflect.DeepEqual(instant.UUID(), p.UUID()) {
+		t.Errorf("predicates %v and %v should have different UUID", instant, p)
+	}
+	rp, err := p.WithID("_predicate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp.ID() != "_predicate" || !rp.SameAnchor(p) {
+		t.Errorf("predicate.WithID returned %v; want the interval of %v", rp, p)
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	at := func(y int) *time.Time {
+		t := time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	interval, err := NewInterval("employed", *at(2016), *at(2018))
+	if err != nil {
+		t.Fatal(err)
+	}
+	instant, err := NewTemporal("met", *at(2016))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := []struct {
+		p            *Predicate
+		lower, upper *time.Time
+		want         bool
+	}{
+		{immutFoo, at(2000), at(2001), true},
+		{interval, nil, nil, true},
+		{interval, at(2017), nil, true},
+		{interval, at(2018), nil, false},
+		{interval, nil, at(2016), true},
+		{interval, nil, at(2015), false},
+		{interval, at(2010), at(2020), true},
+		{interval, at(2017), at(2017), true},
+		{instant, at(2016), at(2016), true},
+		{instant, at(2017), nil, false},
+		{instant, nil, at(2015), false},
+	}
+	for _, tc := range table {
+		if got := tc.p.Overlaps(tc.lower, tc.upper); got != tc.want {
+			t.Errorf("%v.Overlaps(%v, %v) = %v; want %v", tc.p, tc.lower, tc.upper, got, tc.want)
+		}
+	}
+}
@@ -143,6 +143,25 @@ func New(s *node.Node, p *predicate.Predicate, o *Object) (*Triple, error) {
 	}, nil
 }
 
+// Intern returns a triple equal to the provided one whose nodes and
+// predicates are interned using node.Intern and predicate.Intern, so they
+// share memory with the equal nodes and predicates of other triples.
+// Literals are not interned.
+func Intern(t *Triple) *Triple {
+	o := t.o
+	switch {
+	case o.n != nil:
+		o = NewNodeObject(node.Intern(o.n))
+	case o.p != nil:
+		o = NewPredicateObject(predicate.Intern(o.p))
+	}
+	return &Triple{
+		s: node.Intern(t.s),
+		p: predicate.Intern(t.p),
+		o: o,
+	}
+}
+
 // Subject returns the subject of the triple.
 func (t *Triple) Subject() *node.Node {
 	return t.s
@@ -179,7 +198,8 @@ func init() {
 }
 
 // Parse process the provided text and tries to create a triple. It assumes
-// that the provided text contains only one triple.
+// that the provided text contains only one triple. The nodes and predicates of
+// the returned triple are interned.
 func Parse(line string, b literal.Builder) (*Triple, error) {
 	raw := strings.TrimSpace(line)
 	idxp := pSplit.FindIndex([]byte(raw))
@@ -200,48 +220,51 @@ func Parse(line string, b literal.Builder) (*Triple, error) {
 	if err != nil {
 		return nil, fmt.Errorf("triple.Parse failed to parse object %s with error %v", so, err)
 	}
-	return New(s, p, o)
+	t, err := New(s, p, o)
+	if err != nil {
+		return nil, err
+	}
+	return Intern(t), nil
 }
 
 // Reify given the current triple it returns the original triple and the newly
-// reified ones. It also returns the newly created blank node.
+// reified ones. It also returns the blank node the reified triples describe.
+// The ID of the blank node is the UUID of the triple, so reifying the same
+// triple always returns the same blank node, and statements about a triple
+// can be attached to it no matter who reified it. The reification predicates
+// share the time anchor, or the interval, of the predicate of the triple.
 func (t *Triple) Reify() ([]*Triple, *node.Node, error) {
-	// Function that creates the proper reification predicates.
-	rp := func(id string, p *predicate.Predicate) (*predicate.Predicate, error) {
-		if p.Type() == predicate.Temporal {
-			ta, _ := p.TimeAnchor()
-			return predicate.NewTemporal(id, *ta)
-		}
-		return predicate.NewImmutable(id)
+	b, err := node.NewNodeFromStrings("/_", t.UUID().String())
+	if err != nil {
+		return nil, nil, err
 	}
-	b := node.NewBlankNode()
-	s, err := rp("_subject", t.p)
+	s, err := t.p.WithID("_subject")
 	if err != nil {
 		return nil, nil, err
 	}
 	ts, _ := New(b, s, NewNodeObject(t.s))
-	p, err := rp("_predicate", t.p)
+	p, err := t.p.WithID("_predicate")
 	if err != nil {
 		return nil, nil, err
 	}
 	tp, _ := New(b, p, NewPredicateObject(t.p))
 	var to *Triple
 	if t.o.l != nil {
-		o, err := rp("_object", t.p)
+		o, err := t.p.WithID("_object")
 		if err != nil {
 			return nil, nil, err
 		}
 		to, _ = New(b, o, NewLiteralObject(t.o.l))
 	}
 	if t.o.n != nil {
-		o, err := rp("_object", t.p)
+		o, err := t.p.WithID("_object")
 		if err != nil {
 			return nil, nil, err
 		}
 		to, _ = New(b, o, NewNodeObject(t.o.n))
 	}
 	if t.o.p != nil {
-		o, err := rp("_object", t.p)
+		o, err := t.p.WithID("_object")
 		if err != nil {
 			return nil, nil, err
 		}
@@ -120,6 +120,39 @@ func TestReifyTemporal(t *testing.T) {
 	}
 }
 
+func TestReifyIsStable(t *testing.T) {
+	tr, err := Parse("/u<john>\t\"met\"@[2015-01-01T00:00:00-09:00]\t/u<mary>", literal.DefaultBuilder())
+	if err != nil {
+		t.Fatalf("triple.Parse failed to parse valid triple with error %v", err)
+	}
+	_, b1, err := tr.Reify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rts, b2, err := tr.Reify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b1.String(), "/_<"+tr.UUID().String()+">"; got != want {
+		t.Errorf("tr.Reify returned blank node %s; want %s", got, want)
+	}
+	if b1.String() != b2.String() {
+		t.Errorf("tr.Reify returned blank nodes %s and %s for the same triple", b1, b2)
+	}
+	for _, rt := range rts[1:] {
+		if rt.Subject().String() != b1.String() {
+			t.Errorf("reified triple %s does not describe %s", rt, b1)
+		}
+	}
+	ot, err := Parse("/u<john>\t\"met\"@[2016-01-01T00:00:00-09:00]\t/u<mary>", literal.DefaultBuilder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ob, err := ot.Reify(); err != nil || ob.String() == b1.String() {
+		t.Errorf("different triples were reified into the same blank node %s, %v", ob, err)
+	}
+}
+
 func TestUUID(t *testing.T) {
 	testTable := []struct {
 		t1 string
@@ -181,3 +214,40 @@ func TestUUID(t *testing.T) {
 		}
 	}
 }
+
+func TestParseInterns(t *testing.T) {
+	b := literal.DefaultBuilder()
+	t1, err := Parse(`/u<john> "knows"@[] /u<mary>`, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := Parse(`/u<mary> "knows"@[] /u<john>`, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o1, _ := t1.Object().Node()
+	o2, _ := t2.Object().Node()
+	if t1.Subject() != o2 || t2.Subject() != o1 || t1.Predicate() != t2.Predicate() {
+		t.Errorf("triple.Parse should return interned nodes and predicates for %s and %s", t1, t2)
+	}
+	n, err := node.Parse("/u<john>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := predicate.NewImmutable("knows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := b.Parse(`"42"^^type:int64`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t3, err := New(n, p, NewLiteralObject(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := Intern(t3)
+	if it.Subject() != t1.Subject() || it.Predicate() != t1.Predicate() || !it.Equal(t3) {
+		t.Errorf("triple.Intern(%s) returned %s without interning its node and predicate", t3, it)
+	}
+}